@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminToken requires "Authorization: Bearer <token>" on the wrapped routes.
+// An empty token rejects every request, so a missing setting fails closed.
+func AdminToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasAdminToken(c, token) {
+			c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "admin token required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// HasAdminToken reports whether the request carries the admin token, for
+// handlers where only some requests need it. An empty token never matches.
+func HasAdminToken(c *gin.Context, token string) bool {
+	provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return token != "" && ok && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
@@ -2,7 +2,11 @@ package middleware
 
 import (
 	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -81,14 +85,156 @@ func (rl *IPRateLimiter) cleanupStaleLimiters() {
 // Middleware returns a Gin middleware for rate limiting
 func (rl *IPRateLimiter) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ip := c.ClientIP()
-		limiter := rl.getLimiter(ip)
-
-		if !limiter.Allow() {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": fmt.Sprintf("Rate limit exceeded. Please try again later."),
-			})
-			c.Abort()
+		if !rl.allow(c) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// allow consumes a token for the client and writes the rate limit headers.
+// It aborts the request with 429 and returns false when the limit is exceeded.
+func (rl *IPRateLimiter) allow(c *gin.Context) bool {
+	ip := c.ClientIP()
+	limiter := rl.getLimiter(ip)
+
+	allowed := limiter.Allow()
+
+	remaining := int(limiter.Tokens())
+	if remaining < 0 {
+		remaining = 0
+	}
+	c.Header("X-RateLimit-Limit", strconv.Itoa(rl.config.BurstSize))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+
+	if !allowed {
+		retryAfter := 1
+		if rl.config.RequestsPerSecond > 0 && rl.config.RequestsPerSecond < 1 {
+			retryAfter = int(math.Ceil(1 / rl.config.RequestsPerSecond))
+		}
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": fmt.Sprintf("Rate limit exceeded. Please try again later."),
+		})
+		c.Abort()
+		return false
+	}
+
+	return true
+}
+
+// RouteClass groups API endpoints that share a rate limit
+type RouteClass string
+
+const (
+	RouteClassRead   RouteClass = "read"   // Queries and listings
+	RouteClassWrite  RouteClass = "write"  // Endpoints that change state
+	RouteClassStream RouteClass = "stream" // Long-lived streams, only connection setup counts
+)
+
+// RouteClasses gives the class of the routes that are not in their method's
+// default class, keyed by method and full route path, see Set. GET and HEAD
+// routes default to read and every other method to write, so the API's POST
+// queries are listed here and a new mutating route is limited as a write
+// without being listed.
+type RouteClasses map[string]RouteClass
+
+// Set assigns class to the route registered for method and path
+func (r RouteClasses) Set(method, path string, class RouteClass) {
+	r[method+" "+path] = class
+}
+
+// ClassifyRoute returns the class of the matched route. Only the route
+// decides; nothing in the request can move it to another class.
+func ClassifyRoute(c *gin.Context, classes RouteClasses) RouteClass {
+	method := c.Request.Method
+	if class, ok := classes[method+" "+c.FullPath()]; ok && c.FullPath() != "" {
+		return class
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return RouteClassRead
+	}
+	return RouteClassWrite
+}
+
+// Config holds the settings of the route-class aware rate limiter
+type Config struct {
+	AllowCIDRs []string                         // Client networks that bypass limiting entirely
+	Classes    map[RouteClass]RateLimiterConfig // Limits per route class
+	Routes     RouteClasses                     // Routes outside their method's default class
+}
+
+// RateLimiter applies per-IP limits per route class and skips allow-listed networks
+type RateLimiter struct {
+	limiters  map[RouteClass]*IPRateLimiter
+	allowNets []*net.IPNet
+	routes    RouteClasses
+}
+
+// NewRateLimiter creates a rate limiter from the given configuration
+func NewRateLimiter(config Config) (*RateLimiter, error) {
+	rl := &RateLimiter{
+		limiters: make(map[RouteClass]*IPRateLimiter),
+		routes:   config.Routes,
+	}
+
+	for _, cidr := range config.AllowCIDRs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		// Accept bare addresses as single-host networks
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allow-listed CIDR %q: %w", cidr, err)
+		}
+		rl.allowNets = append(rl.allowNets, ipNet)
+	}
+
+	for class, classConfig := range config.Classes {
+		if classConfig.CleanupInterval <= 0 {
+			classConfig.CleanupInterval = 5 * time.Minute
+		}
+		rl.limiters[class] = NewIPRateLimiter(classConfig)
+	}
+
+	return rl, nil
+}
+
+// allowListed reports whether the client IP is inside an allow-listed network
+func (rl *RateLimiter) allowListed(clientIP string) bool {
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range rl.allowNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware returns a Gin middleware applying the limit of the route's class
+func (rl *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rl.allowListed(c.ClientIP()) {
+			c.Next()
+			return
+		}
+
+		limiter, ok := rl.limiters[ClassifyRoute(c, rl.routes)]
+		if !ok {
+			limiter, ok = rl.limiters[RouteClassRead]
+		}
+		if ok && !limiter.allow(c) {
 			return
 		}
 
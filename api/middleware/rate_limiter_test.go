@@ -0,0 +1,210 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newLimitedRouter serves /api/v1/monitor/list (read) and /api/v1/monitor/add
+// (write) behind the limiter, trusting proxies from trusted
+func newLimitedRouter(t *testing.T, config Config, trusted ...string) *gin.Engine {
+	t.Helper()
+	config.Routes = make(RouteClasses)
+	config.Routes.Set(http.MethodPost, "/api/v1/monitor/list", RouteClassRead)
+	limiter, err := NewRateLimiter(config)
+	if err != nil {
+		t.Fatalf("NewRateLimiter: %v", err)
+	}
+	router := gin.New()
+	if err := router.SetTrustedProxies(trusted); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+	api := router.Group("/api/v1", limiter.Middleware())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	api.POST("/monitor/list", ok)
+	api.POST("/monitor/add", ok)
+	return router
+}
+
+// request sends a POST from remoteAddr, with X-Forwarded-For if forwarded is set
+func request(router *gin.Engine, path, remoteAddr, forwarded string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	req.RemoteAddr = remoteAddr
+	if forwarded != "" {
+		req.Header.Set("X-Forwarded-For", forwarded)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func limits(rps float64, burst int) map[RouteClass]RateLimiterConfig {
+	return map[RouteClass]RateLimiterConfig{
+		RouteClassRead:  {RequestsPerSecond: rps, BurstSize: burst},
+		RouteClassWrite: {RequestsPerSecond: rps, BurstSize: burst},
+	}
+}
+
+func TestRateLimiterRejectsAndRefills(t *testing.T) {
+	router := newLimitedRouter(t, Config{Classes: limits(20, 2)})
+
+	for i, want := range []string{"1", "0"} {
+		w := request(router, "/api/v1/monitor/list", "203.0.113.1:1000", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i, w.Code)
+		}
+		if got := w.Header().Get("X-RateLimit-Remaining"); got != want {
+			t.Errorf("request %d: X-RateLimit-Remaining = %s, want %s", i, got, want)
+		}
+		if got := w.Header().Get("X-RateLimit-Limit"); got != "2" {
+			t.Errorf("request %d: X-RateLimit-Limit = %s, want 2", i, got)
+		}
+	}
+
+	w := request(router, "/api/v1/monitor/list", "203.0.113.1:1000", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Fatalf("over the burst: status = %d Retry-After %q, want 429 with Retry-After 1", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// Other clients have their own bucket
+	if w := request(router, "/api/v1/monitor/list", "203.0.113.2:1000", ""); w.Code != http.StatusOK {
+		t.Errorf("another client: status = %d, want 200", w.Code)
+	}
+
+	// 20 per second refills a token every 50ms
+	time.Sleep(60 * time.Millisecond)
+	if w := request(router, "/api/v1/monitor/list", "203.0.113.1:1000", ""); w.Code != http.StatusOK {
+		t.Errorf("after refill: status = %d, want 200", w.Code)
+	}
+}
+
+func TestRateLimiterClassesAreSeparate(t *testing.T) {
+	router := newLimitedRouter(t, Config{Classes: map[RouteClass]RateLimiterConfig{
+		RouteClassRead:  {RequestsPerSecond: 0.001, BurstSize: 5},
+		RouteClassWrite: {RequestsPerSecond: 0.001, BurstSize: 1},
+	}})
+
+	request(router, "/api/v1/monitor/add", "203.0.113.1:1000", "")
+	w := request(router, "/api/v1/monitor/add", "203.0.113.1:1000", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second write: status = %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1000" {
+		t.Errorf("Retry-After = %s, want 1000 for one request per 1000s", got)
+	}
+	if w := request(router, "/api/v1/monitor/list", "203.0.113.1:1000", ""); w.Code != http.StatusOK {
+		t.Errorf("read after the write limit: status = %d, want 200", w.Code)
+	}
+}
+
+func TestRateLimiterAllowCIDRs(t *testing.T) {
+	router := newLimitedRouter(t, Config{
+		AllowCIDRs: []string{"10.0.0.0/8", " 192.0.2.7 ", "2001:db8::/32"},
+		Classes:    limits(0.001, 1),
+	})
+
+	for _, addr := range []string{"10.1.2.3:1000", "192.0.2.7:1000", "[2001:db8::1]:1000"} {
+		for i := 0; i < 3; i++ {
+			if w := request(router, "/api/v1/monitor/list", addr, ""); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Remaining") != "" {
+				t.Errorf("%s request %d: status = %d, want 200 without rate limit headers", addr, i, w.Code)
+			}
+		}
+	}
+
+	request(router, "/api/v1/monitor/list", "192.0.2.8:1000", "")
+	if w := request(router, "/api/v1/monitor/list", "192.0.2.8:1000", ""); w.Code != http.StatusTooManyRequests {
+		t.Errorf("client next to an allow-listed address: status = %d, want 429", w.Code)
+	}
+
+	if _, err := NewRateLimiter(Config{AllowCIDRs: []string{"10.0.0.0/33"}}); err == nil {
+		t.Error("invalid CIDR accepted")
+	}
+}
+
+func TestRateLimiterForwardedFor(t *testing.T) {
+	router := newLimitedRouter(t, Config{
+		AllowCIDRs: []string{"10.0.0.0/8"},
+		Classes:    limits(0.001, 1),
+	}, "127.0.0.1")
+
+	// Behind the trusted proxy each forwarded client has its own bucket
+	for _, client := range []string{"203.0.113.1", "203.0.113.2"} {
+		if w := request(router, "/api/v1/monitor/list", "127.0.0.1:1000", client); w.Code != http.StatusOK {
+			t.Errorf("%s via the proxy: status = %d, want 200", client, w.Code)
+		}
+	}
+	if w := request(router, "/api/v1/monitor/list", "127.0.0.1:1000", "203.0.113.1"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("repeat via the proxy: status = %d, want 429", w.Code)
+	}
+
+	// The allow-list applies to the forwarded client, not the proxy
+	for i := 0; i < 3; i++ {
+		if w := request(router, "/api/v1/monitor/list", "127.0.0.1:1000", "10.0.0.5"); w.Code != http.StatusOK {
+			t.Errorf("allow-listed client via the proxy: status = %d, want 200", w.Code)
+		}
+	}
+
+	// An untrusted peer can't pick its IP with the header
+	request(router, "/api/v1/monitor/list", "198.51.100.9:1000", "10.0.0.5")
+	if w := request(router, "/api/v1/monitor/list", "198.51.100.9:1000", "10.0.0.6"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("spoofed X-Forwarded-For: status = %d, want 429", w.Code)
+	}
+}
+
+// The class comes from the route: listed routes have their own class, other
+// GET routes are reads and every other method is a write
+func TestClassifyRoute(t *testing.T) {
+	classes := make(RouteClasses)
+	classes.Set(http.MethodPost, "/api/v1/monitor/list", RouteClassRead)
+	classes.Set(http.MethodGet, "/api/v1/monitor/check/events", RouteClassStream)
+
+	router := gin.New()
+	classify := func(c *gin.Context) { c.String(http.StatusOK, string(ClassifyRoute(c, classes))) }
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPost, "/api/v1/monitor/list"},
+		{http.MethodPost, "/api/v1/monitor/add"},
+		{http.MethodPost, "/api/v1/alert/rule/snooze/cancel"},
+		{http.MethodPut, "/api/v1/config/loglevel"},
+		{http.MethodGet, "/api/v1/config"},
+		{http.MethodGet, "/api/v1/monitor/check/events"},
+		{http.MethodGet, "/api/v1/monitor/check/status/:token"},
+		{http.MethodPost, "/api/v1/monitor/events"},
+	} {
+		router.Handle(route.method, route.path, classify)
+	}
+
+	for _, tc := range []struct {
+		method, path, accept string
+		want                 RouteClass
+	}{
+		{http.MethodPost, "/api/v1/monitor/list", "", RouteClassRead},
+		{http.MethodPost, "/api/v1/monitor/add", "", RouteClassWrite},
+		{http.MethodPost, "/api/v1/alert/rule/snooze/cancel", "", RouteClassWrite},
+		{http.MethodPut, "/api/v1/config/loglevel", "", RouteClassWrite},
+		{http.MethodGet, "/api/v1/config", "", RouteClassRead},
+		{http.MethodGet, "/api/v1/monitor/check/events", "", RouteClassStream},
+		{http.MethodGet, "/api/v1/monitor/check/status/abc", "", RouteClassRead},
+		// Neither the path nor a header picks the stream class
+		{http.MethodPost, "/api/v1/monitor/events", "", RouteClassWrite},
+		{http.MethodGet, "/api/v1/config", "text/event-stream", RouteClassRead},
+		{http.MethodPost, "/api/v1/monitor/add", "text/event-stream", RouteClassWrite},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if got := RouteClass(w.Body.String()); got != tc.want {
+			t.Errorf("%s %s (Accept %q) = %s, want %s", tc.method, tc.path, tc.accept, got, tc.want)
+		}
+	}
+}
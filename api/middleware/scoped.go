@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrInvalidToken is returned by a token resolver for a token that is
+// unknown, expired or revoked
+var ErrInvalidToken = errors.New("invalid api token")
+
+const tokenScopeKey = "token_scope"
+
+// TokenScope is what a scoped API token can read: one target, or every
+// target carrying a tag
+type TokenScope struct {
+	TargetID uint32 // 0 for a tag token
+	Tag      string
+}
+
+// ScopedToken requires a scoped API token, from "Authorization: Bearer <token>"
+// or, for images embedded in pages that cannot set headers, the token query
+// parameter. resolve returns what the token is bound to; handlers read it
+// with Scope and must restrict their queries to it.
+func ScopedToken(resolve func(token string) (TokenScope, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok {
+			token = c.Query("token")
+		}
+		if token == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="embed"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "api token required"})
+			return
+		}
+
+		scope, err := resolve(token)
+		if errors.Is(err, ErrInvalidToken) {
+			c.Header("WWW-Authenticate", `Bearer realm="embed", error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid, expired or revoked api token"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check api token"})
+			return
+		}
+		c.Set(tokenScopeKey, scope)
+		c.Next()
+	}
+}
+
+// Scope returns what the request's scoped token is bound to
+func Scope(c *gin.Context) (TokenScope, bool) {
+	value, ok := c.Get(tokenScopeKey)
+	if !ok {
+		return TokenScope{}, false
+	}
+	scope, ok := value.(TokenScope)
+	return scope, ok
+}
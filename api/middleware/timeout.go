@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// timeoutBody is the error envelope sent when a handler misses its deadline
+const timeoutBody = `{"error":"request timed out"}`
+
+// Timeout gives every request a deadline on its context and answers 504 if
+// the handler has not started writing when the deadline passes. routes
+// overrides the budget by route path (gin FullPath); a budget of 0 disables
+// the timeout, for streaming endpoints.
+//
+// The handler keeps running after the 504 until it notices the cancelled
+// context; anything it writes after that is discarded. Database and ES calls
+// in handlers must use the request context for the deadline to stop them.
+func Timeout(budget time.Duration, routes map[string]time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d := budget
+		if override, ok := routes[c.FullPath()]; ok {
+			d = override
+		}
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		tw := &timeoutWriter{ResponseWriter: c.Writer, ctx: ctx, header: c.Writer.Header().Clone()}
+		c.Writer = tw
+		defer func() {
+			// Also on panic: the gin writer is reused once this returns
+			tw.mu.Lock()
+			tw.done = true
+			tw.mu.Unlock()
+			c.Writer = tw.ResponseWriter
+		}()
+
+		stop := context.AfterFunc(ctx, func() {
+			if ctx.Err() == context.DeadlineExceeded {
+				tw.timeout()
+			}
+		})
+		defer stop()
+
+		c.Next()
+		tw.finish()
+	}
+}
+
+// timeoutWriter holds back the status and headers of the handler until its
+// first write, so that the 504 can still be sent in their place. The handler
+// works on a private copy of the headers, copied back when the response starts.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx context.Context
+
+	mu       sync.Mutex
+	header   http.Header
+	status   int
+	started  bool
+	timedOut bool
+	done     bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if code > 0 && !w.started {
+		w.status = code
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.start()
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.start() {
+		return 0, http.ErrHandlerTimeout
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.start() {
+		return 0, http.ErrHandlerTimeout
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *timeoutWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.start() {
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	w.started = true
+	return w.ResponseWriter.Hijack()
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.started && w.status != 0 {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.started || w.timedOut
+}
+
+// start sends the held back status and headers; it reports false once the
+// request has timed out. Called with mu held.
+func (w *timeoutWriter) start() bool {
+	if w.timedOut {
+		return false
+	}
+	// The handler may see the cancelled context before the AfterFunc runs;
+	// past the deadline its response is replaced all the same
+	if !w.started && w.ctx.Err() == context.DeadlineExceeded {
+		w.writeTimeout()
+		return false
+	}
+	if !w.started {
+		w.started = true
+		dst := w.ResponseWriter.Header()
+		for key := range dst {
+			delete(dst, key)
+		}
+		for key, values := range w.header {
+			dst[key] = values
+		}
+		if w.status != 0 {
+			w.ResponseWriter.WriteHeader(w.status)
+		}
+		w.ResponseWriter.WriteHeaderNow()
+	}
+	return true
+}
+
+// timeout sends the 504 unless the handler has started the response or returned
+func (w *timeoutWriter) timeout() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.started || w.done {
+		return
+	}
+	w.writeTimeout()
+}
+
+// writeTimeout sends the 504 in place of the handler's response. Called with mu held.
+func (w *timeoutWriter) writeTimeout() {
+	w.timedOut = true
+
+	header := w.ResponseWriter.Header()
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	header.Set("Content-Length", strconv.Itoa(len(timeoutBody)))
+	// The connection stays busy until the handler returns; don't let the client reuse it
+	header.Set("Connection", "close")
+	w.ResponseWriter.WriteHeader(http.StatusGatewayTimeout)
+	w.ResponseWriter.WriteString(timeoutBody)
+	w.ResponseWriter.Flush()
+}
+
+// finish sends the status and headers of a handler that returned without
+// writing a body, e.g. a 304
+func (w *timeoutWriter) finish() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.start()
+}
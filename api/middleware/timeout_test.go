@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTimeoutRouter serves path with handler behind a 20ms budget
+func newTimeoutRouter(routes map[string]time.Duration, path string, handler gin.HandlerFunc) *gin.Engine {
+	router := gin.New()
+	router.Use(Timeout(20*time.Millisecond, routes))
+	router.GET(path, handler)
+	return router
+}
+
+func serve(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestTimeoutAnswers504(t *testing.T) {
+	finished := make(chan error, 1)
+	router := newTimeoutRouter(nil, "/slow", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		// Too late: the 504 has been sent
+		c.Header("X-Late", "1")
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+		_, err := c.Writer.Write([]byte("late"))
+		finished <- err
+	})
+
+	w := serve(router, "/slow")
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504", w.Code)
+	}
+	if body := w.Body.String(); body != timeoutBody {
+		t.Errorf("body = %s, want %s", body, timeoutBody)
+	}
+	if w.Header().Get("X-Late") != "" || w.Header().Get("Connection") != "close" {
+		t.Errorf("headers = %v, want Connection: close and none of the handler's", w.Header())
+	}
+	if err := <-finished; err != http.ErrHandlerTimeout {
+		t.Errorf("late write returned %v, want ErrHandlerTimeout", err)
+	}
+}
+
+func TestTimeoutPassesFastResponses(t *testing.T) {
+	router := newTimeoutRouter(nil, "/fast", func(c *gin.Context) {
+		c.Header("X-Handler", "1")
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+
+	w := serve(router, "/fast")
+	if w.Code != http.StatusCreated || w.Body.String() != `{"ok":true}` || w.Header().Get("X-Handler") != "1" {
+		t.Errorf("response = %d %v %s, want the handler's", w.Code, w.Header(), w.Body.String())
+	}
+}
+
+func TestTimeoutKeepsStartedResponses(t *testing.T) {
+	router := newTimeoutRouter(nil, "/stream", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		c.Writer.WriteString("first ")
+		c.Writer.Flush()
+		<-c.Request.Context().Done()
+		c.Writer.WriteString("second")
+	})
+
+	// The deadline still cancels the context, but a started response can't become a 504
+	w := serve(router, "/stream")
+	if w.Code != http.StatusOK || w.Body.String() != "first second" {
+		t.Errorf("response = %d %q, want the handler's", w.Code, w.Body.String())
+	}
+}
+
+func TestTimeoutBodylessResponse(t *testing.T) {
+	router := newTimeoutRouter(nil, "/cached", func(c *gin.Context) {
+		c.Header("ETag", `"v1"`)
+		c.Status(http.StatusNotModified)
+	})
+
+	w := serve(router, "/cached")
+	if w.Code != http.StatusNotModified || w.Header().Get("ETag") != `"v1"` {
+		t.Errorf("response = %d %v, want 304 with the ETag", w.Code, w.Header())
+	}
+}
+
+func TestTimeoutRouteOverrides(t *testing.T) {
+	slow := func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+		case <-time.After(50 * time.Millisecond):
+		}
+		c.String(http.StatusOK, "done")
+	}
+
+	// A longer budget for the route lets the handler finish
+	router := newTimeoutRouter(map[string]time.Duration{"/report/:id": time.Second}, "/report/:id", slow)
+	if w := serve(router, "/report/1"); w.Code != http.StatusOK {
+		t.Errorf("route with a longer budget: status = %d, want 200", w.Code)
+	}
+
+	// A budget of 0 disables the deadline, for streams
+	router = newTimeoutRouter(map[string]time.Duration{"/events": 0}, "/events", func(c *gin.Context) {
+		if _, ok := c.Request.Context().Deadline(); ok {
+			t.Error("stream route has a deadline")
+		}
+		slow(c)
+	})
+	if w := serve(router, "/events"); w.Code != http.StatusOK {
+		t.Errorf("route without a budget: status = %d, want 200", w.Code)
+	}
+}
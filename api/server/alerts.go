@@ -0,0 +1,471 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"monitor/internal/alert"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// registerAlertRoutes registers alert channel and rule management
+func (s *Server) registerAlertRoutes(api *gin.RouterGroup) {
+	// Alert Channels - using POST
+	api.POST("/alert/channel/add", s.addAlertChannel)
+	api.POST("/alert/channel/list", s.listAlertChannels)
+	api.POST("/alert/channel/get", s.getAlertChannel)
+	api.POST("/alert/channel/update", s.updateAlertChannel)
+	api.POST("/alert/channel/remove", s.removeAlertChannel)
+	api.POST("/alert/channel/test", s.testAlertChannel)
+
+	// Alert Rules - using POST
+	api.POST("/alert/rule/add", s.addAlertRule)
+	api.POST("/alert/rule/list", s.listAlertRules)
+	api.POST("/alert/rule/get", s.getAlertRule)
+	api.POST("/alert/rule/update", s.updateAlertRule)
+	api.POST("/alert/rule/remove", s.removeAlertRule)
+	api.POST("/alert/rule/listByTarget", s.listAlertRulesByTarget)
+	api.POST("/alert/rule/simulate", s.simulateAlertRule)
+	api.POST("/alert/rule/snooze", s.snoozeAlertRule)
+	api.POST("/alert/rule/snooze/cancel", s.cancelAlertRuleSnooze)
+}
+
+func (s *Server) addAlertChannel(c *gin.Context) {
+	var req AlertChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	channel := models.AlertChannel{
+		Name:           req.Name,
+		Type:           req.Type,
+		Enabled:        req.Enabled,
+		Config:         req.Config,
+		Health:         alert.ChannelHealthy,
+		SuppressHealth: req.SuppressHealth,
+	}
+
+	db := s.requestDB(c)
+	if err := db.Create(&channel).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create alert channel"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreatedResponse{ID: channel.ID, Message: "Alert channel created successfully"})
+}
+
+func (s *Server) listAlertChannels(c *gin.Context) {
+	db := s.requestDB(c)
+	var channels []models.AlertChannel
+	if err := db.Find(&channels).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list alert channels"})
+		return
+	}
+	if isV2(c) {
+		stats, err := s.alertService.ChannelDeliveryStats(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load alert channel delivery stats"})
+			return
+		}
+		c.JSON(http.StatusOK, ListAlertChannelsResponse{Channels: newAlertChannelResponses(channels, stats)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"channels": channels})
+}
+
+func (s *Server) getAlertChannel(c *gin.Context) {
+	var req IDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := s.requestDB(c)
+	var channel models.AlertChannel
+	if err := db.First(&channel, req.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert channel not found"})
+		return
+	}
+	if isV2(c) {
+		stats, err := s.alertService.ChannelDeliveryStats(c.Request.Context(), channel.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load alert channel delivery stats"})
+			return
+		}
+		c.JSON(http.StatusOK, newAlertChannelResponse(channel, stats[channel.ID]))
+		return
+	}
+	c.JSON(http.StatusOK, channel)
+}
+
+func (s *Server) updateAlertChannel(c *gin.Context) {
+	var req UpdateAlertChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := s.requestDB(c)
+	var channel models.AlertChannel
+	if err := db.First(&channel, req.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert channel not found"})
+		return
+	}
+
+	channel.Name = req.Name
+	channel.Type = req.Type
+	channel.Enabled = req.Enabled
+	channel.Config = req.Config
+	channel.SuppressHealth = req.SuppressHealth
+
+	if err := db.Save(&channel).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update alert channel"})
+		return
+	}
+	s.alertService.InvalidateChannel(uint(channel.ID))
+
+	// 修改后的配置可能已经修好了渠道，重新开始计算健康状态
+	if channel.Health == alert.ChannelDegraded {
+		if err := s.alertService.RecoverChannel(channel.ID, "渠道配置已更新"); err != nil {
+			logger.Warn("Failed to recover alert channel", zap.Uint32("channel_id", channel.ID), zap.Error(err))
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Alert channel updated successfully"})
+}
+
+func (s *Server) removeAlertChannel(c *gin.Context) {
+	var req IDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := s.requestDB(c)
+	if err := db.Delete(&models.AlertChannel{}, req.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete alert channel"})
+		return
+	}
+	s.alertService.InvalidateChannel(uint(req.ID))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Alert channel deleted successfully"})
+}
+
+func (s *Server) testAlertChannel(c *gin.Context) {
+	var req IDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.alertService.TestAlertChannel(uint(req.ID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send test alert"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Test alert sent successfully"})
+}
+
+// Alert Rule API handlers
+
+func (s *Server) addAlertRule(c *gin.Context) {
+	var req struct {
+		TargetID       uint32 `json:"target_id" binding:"required"`
+		ChannelID      uint   `json:"channel_id" binding:"required"`
+		ThresholdType  string `json:"threshold_type" binding:"required"`
+		ThresholdValue int    `json:"threshold_value" binding:"required"`
+		Enabled        bool   `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule := models.AlertRule{
+		TargetID:       req.TargetID,
+		ChannelID:      req.ChannelID,
+		ThresholdType:  req.ThresholdType,
+		ThresholdValue: req.ThresholdValue,
+		Enabled:        req.Enabled,
+	}
+
+	db := s.requestDB(c)
+	if err := db.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create alert rule"})
+		return
+	}
+	s.alertService.InvalidateRules(rule.TargetID)
+
+	c.JSON(http.StatusCreated, gin.H{"id": rule.ID, "message": "Alert rule created successfully"})
+}
+
+func (s *Server) listAlertRules(c *gin.Context) {
+	db := s.requestDB(c)
+	var rules []models.AlertRule
+	if err := db.Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list alert rules"})
+		return
+	}
+	if err := s.alertService.AttachSnoozes(rules); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load alert rule snoozes"})
+		return
+	}
+	if isV2(c) {
+		c.JSON(http.StatusOK, gin.H{"rules": newAlertRuleResponses(rules)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+func (s *Server) getAlertRule(c *gin.Context) {
+	var req IDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := s.requestDB(c)
+	var rule models.AlertRule
+	if err := db.First(&rule, req.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert rule not found"})
+		return
+	}
+	rules := []models.AlertRule{rule}
+	if err := s.alertService.AttachSnoozes(rules); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load alert rule snoozes"})
+		return
+	}
+	rule = rules[0]
+	if isV2(c) {
+		c.JSON(http.StatusOK, newAlertRuleResponse(rule))
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+func (s *Server) updateAlertRule(c *gin.Context) {
+	var req struct {
+		IDRequest
+		TargetID       uint32 `json:"target_id" binding:"required"`
+		ChannelID      uint   `json:"channel_id" binding:"required"`
+		ThresholdType  string `json:"threshold_type" binding:"required"`
+		ThresholdValue int    `json:"threshold_value" binding:"required"`
+		Enabled        bool   `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := s.requestDB(c)
+	var rule models.AlertRule
+	if err := db.First(&rule, req.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert rule not found"})
+		return
+	}
+
+	previousTarget := rule.TargetID
+	rule.TargetID = req.TargetID
+	rule.ChannelID = req.ChannelID
+	rule.ThresholdType = req.ThresholdType
+	rule.ThresholdValue = req.ThresholdValue
+	rule.Enabled = req.Enabled
+
+	// Only write the edited columns so a concurrent alert dispatch doesn't get its
+	// last_alert_time/alert_open state overwritten by the stale copy read above
+	if err := db.Model(&rule).Select("target_id", "channel_id", "threshold_type", "threshold_value", "enabled").
+		Updates(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update alert rule"})
+		return
+	}
+	s.alertService.InvalidateRules(previousTarget, rule.TargetID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Alert rule updated successfully"})
+}
+
+func (s *Server) removeAlertRule(c *gin.Context) {
+	var req IDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := s.requestDB(c)
+	if err := db.Delete(&models.AlertRule{}, req.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete alert rule"})
+		return
+	}
+	s.alertService.InvalidateAllRules()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Alert rule deleted successfully"})
+}
+
+func (s *Server) listAlertRulesByTarget(c *gin.Context) {
+	var req struct {
+		TargetID uint32 `json:"target_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rules, err := s.alertService.ListAlertRulesByTarget(req.TargetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list alert rules"})
+		return
+	}
+	if err := s.alertService.AttachSnoozes(rules); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load alert rule snoozes"})
+		return
+	}
+
+	if isV2(c) {
+		c.JSON(http.StatusOK, gin.H{"rules": newAlertRuleResponses(rules)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// snoozeAlertRule 暂时静默一条告警规则，同一目标的其他规则不受影响
+func (s *Server) snoozeAlertRule(c *gin.Context) {
+	var req SnoozeAlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil || duration <= 0 || duration > alert.MaxSnoozeDuration {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid duration, expected a positive value up to %s such as 2h", alert.MaxSnoozeDuration)})
+		return
+	}
+
+	snooze, err := s.alertService.SnoozeRule(req.RuleID, duration, req.Note, c.ClientIP())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Alert rule not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to snooze alert rule"})
+		return
+	}
+
+	if isV2(c) {
+		c.JSON(http.StatusOK, newAlertRuleSnooze(snooze))
+		return
+	}
+	c.JSON(http.StatusOK, snooze)
+}
+
+// cancelAlertRuleSnooze 提前结束告警规则的静默
+func (s *Server) cancelAlertRuleSnooze(c *gin.Context) {
+	var req CancelRuleSnoozeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	snooze, err := s.alertService.CancelSnooze(req.RuleID, c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Alert rule not found"})
+		case errors.Is(err, alert.ErrNotSnoozed):
+			c.JSON(http.StatusConflict, gin.H{"error": "Alert rule is not snoozed"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel alert rule snooze"})
+		}
+		return
+	}
+
+	if isV2(c) {
+		c.JSON(http.StatusOK, newAlertRuleSnooze(snooze))
+		return
+	}
+	c.JSON(http.StatusOK, snooze)
+}
+
+// SimulateAlertRuleRequest 告警规则模拟请求，规则可以是已保存的（rule_id）或临时定义的
+type SimulateAlertRuleRequest struct {
+	RuleID          *uint                 `json:"rule_id,omitempty"`
+	TargetID        uint32                `json:"target_id"`
+	ThresholdType   string                `json:"threshold_type,omitempty"`
+	ThresholdValue  int                   `json:"threshold_value,omitempty"`
+	Conditions      *alert.AlertCondition `json:"conditions,omitempty"`       // 优先于 threshold_type/threshold_value
+	CooldownSeconds *int                  `json:"cooldown_seconds,omitempty"` // 默认 300 秒
+	StartTime       *int64                `json:"start_time,omitempty"`       // Unix timestamp，默认 end_time 前 7 天
+	EndTime         *int64                `json:"end_time,omitempty"`         // Unix timestamp，默认当前时间
+}
+
+func (s *Server) simulateAlertRule(c *gin.Context) {
+	var req SimulateAlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cooldown := 300
+	if req.RuleID != nil {
+		rule, err := s.alertService.GetAlertRule(*req.RuleID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Alert rule not found"})
+			return
+		}
+		if req.TargetID == 0 {
+			req.TargetID = rule.TargetID
+		}
+		if req.ThresholdType == "" {
+			req.ThresholdType = rule.ThresholdType
+			req.ThresholdValue = rule.ThresholdValue
+		}
+		cooldown = rule.CooldownSeconds
+	}
+	if req.CooldownSeconds != nil {
+		cooldown = *req.CooldownSeconds
+	}
+
+	if req.TargetID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target_id is required"})
+		return
+	}
+
+	var conditions alert.AlertCondition
+	if req.Conditions != nil {
+		conditions = *req.Conditions
+	} else {
+		var err error
+		conditions, err = alert.ConditionsFromThreshold(req.ThresholdType, req.ThresholdValue)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	endTime := time.Now()
+	if req.EndTime != nil {
+		endTime = time.Unix(*req.EndTime, 0)
+	}
+	startTime := endTime.Add(-7 * 24 * time.Hour)
+	if req.StartTime != nil {
+		startTime = time.Unix(*req.StartTime, 0)
+	}
+
+	result, err := s.alertService.SimulateRule(alert.SimulationRule{
+		Conditions:      conditions,
+		CooldownSeconds: cooldown,
+	}, req.TargetID, startTime, endTime)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"monitor/internal/models"
+)
+
+func (s *Server) createChannel(t *testing.T, url string) uint32 {
+	t.Helper()
+	config, _ := json.Marshal(map[string]string{"webhook_url": url})
+	var created CreatedResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v1/alert/channel/add", AlertChannelRequest{
+		Name: "hook", Type: "wechat", Enabled: true, Config: string(config),
+	}), http.StatusCreated, &created)
+	return created.ID
+}
+
+func TestAlertChannelCRUD(t *testing.T) {
+	s := newTestServer(t)
+	id := s.createChannel(t, "http://127.0.0.1:1/hook")
+
+	var channel AlertChannelResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v2/alert/channel/get", IDRequest{ID: id}), http.StatusOK, &channel)
+	if channel.Name != "hook" || channel.Type != "wechat" || channel.Health != "healthy" {
+		t.Errorf("channel = %+v", channel)
+	}
+
+	update := UpdateAlertChannelRequest{IDRequest: IDRequest{ID: id}, AlertChannelRequest: AlertChannelRequest{
+		Name: "renamed", Type: "wechat", Config: `{"webhook_url":"http://127.0.0.1:1/hook"}`,
+	}}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/alert/channel/update", update), http.StatusOK, nil)
+	var list ListAlertChannelsResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v2/alert/channel/list", nil), http.StatusOK, &list)
+	if len(list.Channels) != 1 || list.Channels[0].Name != "renamed" || list.Channels[0].Enabled {
+		t.Errorf("channels after update = %+v", list.Channels)
+	}
+
+	decode(t, s.do(t, http.MethodPost, "/api/v1/alert/channel/remove", IDRequest{ID: id}), http.StatusOK, nil)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/alert/channel/get", IDRequest{ID: id}), http.StatusNotFound, nil)
+
+	// config is required
+	decode(t, s.do(t, http.MethodPost, "/api/v1/alert/channel/add", AlertChannelRequest{Name: "x", Type: "wechat"}), http.StatusBadRequest, nil)
+}
+
+func TestTestAlertChannel(t *testing.T) {
+	s := newTestServer(t)
+	var received atomic.Int32
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		w.Write([]byte(`{"errcode":0}`))
+	}))
+	defer hook.Close()
+
+	id := s.createChannel(t, hook.URL)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/alert/channel/test", IDRequest{ID: id}), http.StatusOK, nil)
+	if received.Load() != 1 {
+		t.Errorf("webhook received %d requests, want 1", received.Load())
+	}
+
+	decode(t, s.do(t, http.MethodPost, "/api/v1/alert/channel/test", IDRequest{ID: id + 1}), http.StatusInternalServerError, nil)
+}
+
+func TestAlertRuleCRUD(t *testing.T) {
+	s := newTestServer(t)
+	target := createTarget(t, models.MonitorTarget{Name: "db"})
+	channel := s.createChannel(t, "http://127.0.0.1:1/hook")
+
+	rule := map[string]interface{}{
+		"target_id": target.ID, "channel_id": channel, "threshold_type": "failure_count", "threshold_value": 3, "enabled": true,
+	}
+	var created CreatedResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v1/alert/rule/add", rule), http.StatusCreated, &created)
+
+	var got AlertRuleResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v2/alert/rule/get", IDRequest{ID: created.ID}), http.StatusOK, &got)
+	if got.TargetID != target.ID || got.ThresholdType != "failure_count" || got.ThresholdValue != 3 {
+		t.Errorf("rule = %+v", got)
+	}
+
+	rule["id"], rule["threshold_value"] = created.ID, 5
+	decode(t, s.do(t, http.MethodPost, "/api/v1/alert/rule/update", rule), http.StatusOK, nil)
+	var byTarget struct {
+		Rules []AlertRuleResponse `json:"rules"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/v2/alert/rule/listByTarget", map[string]uint32{"target_id": target.ID}), http.StatusOK, &byTarget)
+	if len(byTarget.Rules) != 1 || byTarget.Rules[0].ThresholdValue != 5 {
+		t.Errorf("rules of the target = %+v, want the updated rule", byTarget.Rules)
+	}
+
+	decode(t, s.do(t, http.MethodPost, "/api/v1/alert/rule/remove", IDRequest{ID: created.ID}), http.StatusOK, nil)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/alert/rule/get", IDRequest{ID: created.ID}), http.StatusNotFound, nil)
+
+	delete(rule, "threshold_type")
+	decode(t, s.do(t, http.MethodPost, "/api/v1/alert/rule/add", rule), http.StatusBadRequest, nil)
+}
+
+func TestSnoozeAlertRule(t *testing.T) {
+	s := newTestServer(t)
+	target := createTarget(t, models.MonitorTarget{Name: "db"})
+	rule := models.AlertRule{TargetID: target.ID, ChannelID: 1, ThresholdType: "failure_count", ThresholdValue: 3, Enabled: true}
+	s.db.Create(&rule)
+
+	var snooze AlertRuleSnooze
+	decode(t, s.do(t, http.MethodPost, "/api/v2/alert/rule/snooze", SnoozeAlertRuleRequest{RuleID: rule.ID, Duration: "2h", Note: "deploy"}), http.StatusOK, &snooze)
+	if snooze.RuleID != rule.ID || snooze.Note != "deploy" {
+		t.Errorf("snooze = %+v", snooze)
+	}
+
+	for _, duration := range []string{"soon", "-1h", "169h"} {
+		decode(t, s.do(t, http.MethodPost, "/api/v1/alert/rule/snooze", SnoozeAlertRuleRequest{RuleID: rule.ID, Duration: duration}), http.StatusBadRequest, nil)
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/alert/rule/snooze", SnoozeAlertRuleRequest{RuleID: rule.ID + 1, Duration: "1h"}), http.StatusNotFound, nil)
+
+	decode(t, s.do(t, http.MethodPost, "/api/v1/alert/rule/snooze/cancel", CancelRuleSnoozeRequest{RuleID: rule.ID}), http.StatusOK, nil)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/alert/rule/snooze/cancel", CancelRuleSnoozeRequest{RuleID: rule.ID}), http.StatusConflict, nil)
+}
+
+func TestSimulateAlertRule(t *testing.T) {
+	s := newTestServer(t)
+	target := createTarget(t, models.MonitorTarget{Name: "db"})
+
+	start, end := int64(1000), int64(2000)
+	req := SimulateAlertRuleRequest{TargetID: target.ID, ThresholdType: "failure_count", ThresholdValue: 2, StartTime: &start, EndTime: &end}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/alert/rule/simulate", req), http.StatusOK, nil)
+
+	req.ThresholdType = "loudness"
+	decode(t, s.do(t, http.MethodPost, "/api/v1/alert/rule/simulate", req), http.StatusBadRequest, nil)
+	req.ThresholdType, req.TargetID = "failure_count", 0
+	decode(t, s.do(t, http.MethodPost, "/api/v1/alert/rule/simulate", req), http.StatusBadRequest, nil)
+	req.TargetID, req.EndTime = target.ID, &start
+	decode(t, s.do(t, http.MethodPost, "/api/v1/alert/rule/simulate", req), http.StatusBadRequest, nil)
+}
+
+// Editing a rule must not reset the cooldown state written by alert dispatch
+func TestUpdateAlertRuleKeepsAlertState(t *testing.T) {
+	s := newTestServer(t)
+	target := createTarget(t, models.MonitorTarget{Name: "db"})
+	lastAlert := time.Now().UTC().Truncate(time.Second)
+	rule := models.AlertRule{TargetID: target.ID, ChannelID: 1, ThresholdType: "failure_count", ThresholdValue: 3, Enabled: true,
+		CooldownSeconds: 300, LastAlertTime: &lastAlert, AlertOpen: true, AlertCount: 2}
+	s.db.Create(&rule)
+
+	update := map[string]interface{}{
+		"id": rule.ID, "target_id": target.ID, "channel_id": 1, "threshold_type": "failure_count", "threshold_value": 5, "enabled": true,
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/alert/rule/update", update), http.StatusOK, nil)
+
+	var stored models.AlertRule
+	s.db.First(&stored, rule.ID)
+	if stored.ThresholdValue != 5 {
+		t.Errorf("threshold_value = %d, want 5", stored.ThresholdValue)
+	}
+	if !stored.AlertOpen || stored.AlertCount != 2 || stored.LastAlertTime == nil || !stored.LastAlertTime.Equal(lastAlert) {
+		t.Errorf("alert state = open %v count %d last %v, want it unchanged", stored.AlertOpen, stored.AlertCount, stored.LastAlertTime)
+	}
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListArchivesRequest 列出一个监控的响应存档
+type ListArchivesRequest struct {
+	TargetID uint32 `json:"target_id" binding:"required"`
+}
+
+// GetArchiveRequest 按存档 ID 或 down 结果的历史记录 ID 获取存档，两者填一个
+type GetArchiveRequest struct {
+	ID        uint `json:"id"`
+	HistoryID uint `json:"history_id"`
+}
+
+// listResponseArchives 返回监控的存档列表（不含响应体），最新的在前
+func (s *Server) listResponseArchives(c *gin.Context) {
+	var req ListArchivesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	archives, err := s.monitorService.ListResponseArchives(c.Request.Context(), req.TargetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list response archives"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"archives": archives})
+}
+
+// getResponseArchive 返回完整的存档，响应头和响应体按当前的脱敏规则处理
+func (s *Server) getResponseArchive(c *gin.Context) {
+	var req GetArchiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (req.ID == 0) == (req.HistoryID == 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one of id and history_id is required"})
+		return
+	}
+
+	archive, err := s.monitorService.GetResponseArchive(c.Request.Context(), req.ID, req.HistoryID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Response archive not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get response archive"})
+		return
+	}
+	c.JSON(http.StatusOK, archive)
+}
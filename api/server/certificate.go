@@ -0,0 +1,38 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"monitor/internal/monitor"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListCertificatesRequest 证书清单的过滤条件，请求体可以省略
+type ListCertificatesRequest struct {
+	ExpiringWithinDays int    `json:"expiring_within_days" binding:"gte=0"` // 只返回这么多天内到期的证书，0 为全部
+	TargetID           uint32 `json:"target_id"`                            // 只返回该目标出示过的证书
+	IncludeReplaced    bool   `json:"include_replaced"`                     // 同时返回已没有目标在使用的证书
+}
+
+// listCertificates 返回证书清单及出示每张证书的目标，最早到期的在前
+func (s *Server) listCertificates(c *gin.Context) {
+	var req ListCertificatesRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	certificates, err := s.monitorService.ListCertificates(c.Request.Context(), monitor.CertificateQuery{
+		ExpiringWithinDays: req.ExpiringWithinDays,
+		TargetID:           req.TargetID,
+		IncludeReplaced:    req.IncludeReplaced,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list certificates"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"certificates": certificates})
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"monitor/internal/monitor"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkEventsHeartbeat 事件流的心跳间隔，避免代理断开空闲连接
+const checkEventsHeartbeat = 15 * time.Second
+
+// triggerCheck 立即检查一次监控目标，返回可用于查询进度的 token；
+// wait 为 true 时在请求中完成检查并返回结果
+func (s *Server) triggerCheck(c *gin.Context) {
+	var req TriggerCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Wait {
+		s.runCheckNow(c, req.ID)
+		return
+	}
+
+	job, err := s.monitorService.TriggerCheck(req.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, monitor.ErrTooManyCheckJobs), errors.Is(err, monitor.ErrCheckQueueFull):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Monitor not found or disabled"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, struct {
+		TriggerCheckResponse
+		Job monitor.CheckJob `json:"job"`
+	}{
+		TriggerCheckResponse{
+			Token:     job.Token,
+			State:     job.State,
+			StatusURL: fmt.Sprintf("/api/v%d/monitor/check/status/%s", c.GetInt(apiVersionKey), job.Token),
+		},
+		job,
+	})
+}
+
+// runCheckNow 同步检查：不经过工作队列，结果与定时检查一样保存并触发告警
+func (s *Server) runCheckNow(c *gin.Context, id uint32) {
+	result, err := s.monitorService.RunCheck(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, monitor.ErrTargetNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Monitor not found or disabled"})
+	case errors.Is(err, monitor.ErrServiceStopped):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"target_id": id, "result": result})
+	}
+}
+
+// getCheckStatus 查询手动检查的进度，完成后 5 分钟内可取回检查结果
+func (s *Server) getCheckStatus(c *gin.Context) {
+	job, ok := s.monitorService.GetCheckJob(c.Param("token"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Check not found or expired"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// streamCheckEvents 以 SSE 推送手动检查的状态变化（queued、running、finished）。
+// 可用 token 或 target_id 过滤；指定 token 时先推送当前状态，完成后关闭连接。
+func (s *Server) streamCheckEvents(c *gin.Context) {
+	token := c.Query("token")
+	var targetID uint32
+	if v := c.Query("target_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid target_id"})
+			return
+		}
+		targetID = uint32(id)
+	}
+
+	events, cancel, err := s.monitorService.SubscribeCheckJobs()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	if token != "" {
+		job, ok := s.monitorService.GetCheckJob(token)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Check not found or expired"})
+			return
+		}
+		c.SSEvent("check", job)
+		if job.State == monitor.CheckJobFinished {
+			return
+		}
+		c.Writer.Flush()
+	}
+
+	heartbeat := time.NewTicker(checkEventsHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-s.closing:
+			return false
+		case <-heartbeat.C:
+			io.WriteString(w, ": heartbeat\n\n")
+			return true
+		case job := <-events:
+			if (token != "" && job.Token != token) || (targetID != 0 && job.TargetID != targetID) {
+				return true
+			}
+			c.SSEvent("check", job)
+			return token == "" || job.State != monitor.CheckJobFinished
+		}
+	})
+}
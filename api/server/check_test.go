@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"monitor/internal/monitor"
+)
+
+// A triggered check is polled through its status URL until it finishes;
+// wait runs the check during the request
+func TestTriggerCheck(t *testing.T) {
+	s := newTestServer(t)
+	useFileLog(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	req := AddMonitorRequest{Name: "db", Type: "tcp", Address: "127.0.0.1", Port: int32(ln.Addr().(*net.TCPAddr).Port), Interval: 3600, Enabled: true}
+	var created CreatedResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/add", req), http.StatusCreated, &created)
+
+	var accepted TriggerCheckResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v2/monitor/check", TriggerCheckRequest{IDRequest: IDRequest{ID: created.ID}}), http.StatusAccepted, &accepted)
+	if accepted.Token == "" || accepted.StatusURL != "/api/v2/monitor/check/status/"+accepted.Token {
+		t.Fatalf("trigger response %+v", accepted)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var job monitor.CheckJob
+		decode(t, s.do(t, http.MethodGet, accepted.StatusURL, nil), http.StatusOK, &job)
+		if job.State == monitor.CheckJobFinished {
+			if job.Result == nil || job.Result.Status != "up" || job.TargetID != created.ID {
+				t.Fatalf("finished job %+v, want an up result", job)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job still %s", job.State)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var waited struct {
+		TargetID uint32                  `json:"target_id"`
+		Result   *monitor.CheckJobResult `json:"result"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/check", TriggerCheckRequest{IDRequest: IDRequest{ID: created.ID}, Wait: true}), http.StatusOK, &waited)
+	if waited.TargetID != created.ID || waited.Result == nil || waited.Result.Status != "up" {
+		t.Errorf("waited check %+v", waited)
+	}
+
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/check", TriggerCheckRequest{IDRequest: IDRequest{ID: created.ID + 1}}), http.StatusNotFound, nil)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/check", TriggerCheckRequest{IDRequest: IDRequest{ID: created.ID + 1}, Wait: true}), http.StatusNotFound, nil)
+	decode(t, s.do(t, http.MethodGet, "/api/v1/monitor/check/status/unknown", nil), http.StatusNotFound, nil)
+}
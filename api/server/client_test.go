@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"monitor/internal/models"
+	"monitor/pkg/apitypes"
+	"monitor/pkg/client"
+)
+
+// newTestClient serves a test server over HTTP and returns a client for it
+func newTestClient(t *testing.T) (*Server, *client.Client) {
+	t.Helper()
+	s := newTestServer(t)
+	ts := httptest.NewServer(s.router)
+	t.Cleanup(ts.Close)
+	return s, client.NewClient(ts.URL, "", client.WithRetries(0, 0))
+}
+
+// The client and the server share pkg/apitypes; a round trip through both
+// must carry every field
+func TestClientMonitorRoundTrip(t *testing.T) {
+	_, c := newTestClient(t)
+	ctx := context.Background()
+
+	req := apitypes.AddMonitorRequest{
+		Name: "db", Type: "tcp", Address: "127.0.0.1", Port: 5432, Interval: 30,
+		Tags: []string{"prod", "team:db"}, Notes: "primary", RunbookURL: "https://wiki.example.com/db",
+	}
+	created, err := c.CreateMonitor(ctx, req)
+	if err != nil {
+		t.Fatalf("CreateMonitor: %v", err)
+	}
+
+	monitors, err := c.ListMonitors(ctx, apitypes.ListMonitorsRequest{Tags: []string{"team:db"}})
+	if err != nil {
+		t.Fatalf("ListMonitors: %v", err)
+	}
+	if len(monitors) != 1 {
+		t.Fatalf("listed %d monitors, want 1", len(monitors))
+	}
+	got := monitors[0]
+	if got.ID != created.ID || got.Name != req.Name || got.Type != req.Type || got.Address != req.Address ||
+		got.Port != req.Port || got.Interval != req.Interval || got.Notes != req.Notes || got.RunbookURL != req.RunbookURL ||
+		len(got.Tags) != 2 || got.Tags[0] != "prod" || got.Tags[1] != "team:db" {
+		t.Errorf("listed monitor = %+v, want the fields of %+v", got, req)
+	}
+
+	req.Name, req.Interval = "primary db", 120
+	if err := c.UpdateMonitor(ctx, apitypes.UpdateMonitorRequest{IDRequest: apitypes.IDRequest{ID: created.ID}, AddMonitorRequest: req}); err != nil {
+		t.Fatalf("UpdateMonitor: %v", err)
+	}
+	monitors, _ = c.ListMonitors(ctx, apitypes.ListMonitorsRequest{})
+	if len(monitors) != 1 || monitors[0].Name != "primary db" || monitors[0].Interval != 120 {
+		t.Errorf("monitors after update = %+v", monitors)
+	}
+
+	tags, err := c.ListTags(ctx)
+	if err != nil || len(tags) != 2 {
+		t.Errorf("ListTags = %+v, %v", tags, err)
+	}
+
+	if err := c.DeleteMonitor(ctx, created.ID); err != nil {
+		t.Fatalf("DeleteMonitor: %v", err)
+	}
+	if err := c.UpdateMonitor(ctx, apitypes.UpdateMonitorRequest{IDRequest: apitypes.IDRequest{ID: created.ID}, AddMonitorRequest: req}); !client.IsNotFound(err) {
+		t.Errorf("update of a removed monitor: %v, want not found", err)
+	}
+}
+
+func TestClientStatus(t *testing.T) {
+	s, c := newTestClient(t)
+	ctx := context.Background()
+	target := createTarget(t, models.MonitorTarget{Name: "db"})
+	s.db.Create(&models.MonitorStatus{TargetID: target.ID, Status: "down", Message: "refused", ResponseTime: 3})
+
+	status, err := c.GetStatus(ctx, target.ID)
+	if err != nil {
+		t.Fatalf("GetStatus: %v", err)
+	}
+	if status.TargetID != target.ID || status.Status != "down" || status.Message != "refused" {
+		t.Errorf("status = %+v", status)
+	}
+
+	statuses, err := c.ListStatus(ctx, apitypes.ListStatusRequest{})
+	if err != nil || len(statuses) != 1 || statuses[0].TargetName != "db" {
+		t.Errorf("ListStatus = %+v, %v", statuses, err)
+	}
+
+	if _, err := c.GetStatus(ctx, target.ID+1); !client.IsNotFound(err) {
+		t.Errorf("status of an unknown monitor: %v, want not found", err)
+	}
+}
+
+func TestClientAlertChannels(t *testing.T) {
+	_, c := newTestClient(t)
+	ctx := context.Background()
+
+	req := apitypes.AlertChannelRequest{Name: "ops", Type: "wechat", Enabled: true, Config: `{"webhook_url":"http://127.0.0.1:1/hook"}`}
+	created, err := c.CreateAlertChannel(ctx, req)
+	if err != nil {
+		t.Fatalf("CreateAlertChannel: %v", err)
+	}
+	channel, err := c.GetAlertChannel(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetAlertChannel: %v", err)
+	}
+	if channel.Name != req.Name || channel.Type != req.Type || channel.Config != req.Config || !channel.Enabled {
+		t.Errorf("channel = %+v, want the fields of %+v", channel, req)
+	}
+
+	req.Enabled = false
+	if err := c.UpdateAlertChannel(ctx, apitypes.UpdateAlertChannelRequest{IDRequest: apitypes.IDRequest{ID: created.ID}, AlertChannelRequest: req}); err != nil {
+		t.Fatalf("UpdateAlertChannel: %v", err)
+	}
+	channels, err := c.ListAlertChannels(ctx)
+	if err != nil || len(channels) != 1 || channels[0].Enabled {
+		t.Errorf("ListAlertChannels = %+v, %v", channels, err)
+	}
+
+	if err := c.DeleteAlertChannel(ctx, created.ID); err != nil {
+		t.Fatalf("DeleteAlertChannel: %v", err)
+	}
+	if _, err := c.GetAlertChannel(ctx, created.ID); !client.IsNotFound(err) {
+		t.Errorf("removed channel: %v, want not found", err)
+	}
+}
+
+func TestClientValidationError(t *testing.T) {
+	_, c := newTestClient(t)
+	_, err := c.CreateMonitor(context.Background(), apitypes.AddMonitorRequest{Name: "x", Type: "tcp", Address: "127.0.0.1"})
+	apiErr, ok := err.(*client.APIError)
+	if !ok || apiErr.StatusCode != 400 || apiErr.Message == "" {
+		t.Errorf("CreateMonitor without a port: %v, want an APIError 400 with the message", err)
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"time"
 
 	"monitor/internal/config"
+	"monitor/internal/logger"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 // GetConfigResponse 获取配置响应
@@ -29,6 +31,55 @@ func (s *Server) getConfig(c *gin.Context) {
 	})
 }
 
+// getEffectiveConfig 返回启动时的生效配置，每项带来源，敏感项已脱敏
+func (s *Server) getEffectiveConfig(c *gin.Context) {
+	if s.effectiveConfig == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Configuration not loaded"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"config": s.effectiveConfig})
+}
+
+// LogLevelRequest 修改日志级别请求
+type LogLevelRequest struct {
+	Level    string `json:"level" binding:"required"` // debug, info, warn, error
+	Duration string `json:"duration"`                 // 如 15m，到期后恢复为修改前的级别；留空则一直生效
+}
+
+// updateLogLevel 立即修改日志级别，无需重启
+func (s *Server) updateLogLevel(c *gin.Context) {
+	var req LogLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	level, err := logger.ParseLevel(req.Level)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var duration time.Duration
+	if req.Duration != "" {
+		duration, err = time.ParseDuration(req.Duration)
+		if err != nil || duration <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid duration, expected a positive value such as 15m"})
+			return
+		}
+	}
+
+	status := logger.SetLevel(level, duration, "api", zap.String("client_ip", c.ClientIP()))
+	c.JSON(http.StatusOK, status)
+}
+
+// adminToken 返回管理接口的令牌；为空时 AdminToken 中间件拒绝所有请求
+func (s *Server) adminToken() string {
+	if s.config == nil {
+		return ""
+	}
+	return s.config.Debug.AdminToken
+}
+
 // updateConfig 更新系统配置
 func (s *Server) updateConfig(c *gin.Context) {
 	var req UpdateConfigRequest
@@ -53,6 +104,11 @@ func (s *Server) updateConfig(c *gin.Context) {
 		return
 	}
 
+	// 管理员令牌不会通过接口返回，保留当前值以免保存时被清空
+	if s.config != nil {
+		req.Config.Debug.AdminToken = s.config.Debug.AdminToken
+	}
+
 	// 保存配置到文件
 	if err := config.SaveToFile(s.configPath, req.Config); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to save config: %v", err)})
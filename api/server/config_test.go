@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"monitor/internal/logger"
+)
+
+func TestUpdateLogLevel(t *testing.T) {
+	s := newTestServer(t)
+	t.Cleanup(func() { logger.ResetLevel("test") })
+	admin := []string{"Authorization", "Bearer " + testAdminToken}
+
+	decode(t, s.do(t, http.MethodPut, "/api/v1/config/loglevel", LogLevelRequest{Level: "debug"}), http.StatusUnauthorized, nil)
+
+	var status logger.LevelStatus
+	decode(t, s.do(t, http.MethodPut, "/api/v1/config/loglevel", LogLevelRequest{Level: "debug", Duration: "15m"}, admin...), http.StatusOK, &status)
+	if status.Level != "debug" || status.Configured != "error" || status.RevertTo != "error" || status.RevertAt == nil || status.ChangedBy != "api" {
+		t.Errorf("status %+v", status)
+	}
+
+	// The verbose health check shows the change
+	var health struct {
+		LogLevel logger.LevelStatus `json:"log_level"`
+	}
+	decode(t, s.do(t, http.MethodGet, "/health?verbose=1", nil), http.StatusOK, &health)
+	if health.LogLevel.Level != "debug" || health.LogLevel.RevertAt == nil {
+		t.Errorf("health log_level %+v", health.LogLevel)
+	}
+
+	for _, req := range []LogLevelRequest{{Level: "trace"}, {Level: "info", Duration: "soon"}, {Level: "info", Duration: "-5m"}, {}} {
+		decode(t, s.do(t, http.MethodPut, "/api/v1/config/loglevel", req, admin...), http.StatusBadRequest, nil)
+	}
+	if logger.GetLevelStatus().Level != "debug" {
+		t.Error("a rejected request changed the level")
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+
+	"monitor/internal/models"
+)
+
+// jsonKeys returns the sorted top-level keys of a JSON object
+func jsonKeys(t *testing.T, data []byte) []string {
+	t.Helper()
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// modelKeys returns the keys v1 exposes for a model: its own JSON plus extra
+func modelKeys(t *testing.T, model interface{}, extra ...string) []string {
+	t.Helper()
+	data, err := json.Marshal(model)
+	if err != nil {
+		t.Fatalf("encode %T: %v", model, err)
+	}
+	keys := append(jsonKeys(t, data), extra...)
+	sort.Strings(keys)
+	return keys
+}
+
+// decodeStrict decodes a v2 response into its apitypes type, failing on
+// fields the type does not declare
+func decodeStrict(t *testing.T, data []byte, v interface{}) {
+	t.Helper()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		t.Errorf("response does not match %T: %v\n%s", v, err, data)
+	}
+}
+
+// v1 返回数据库模型本身，字段集合必须与模型一致，否则旧客户端会受影响
+func TestV1ResponsesAreModels(t *testing.T) {
+	s := newTestServer(t)
+	target := createTarget(t, models.MonitorTarget{Name: "db", Tags: `["prod"]`})
+	var stored models.MonitorTarget
+	s.db.First(&stored, target.ID)
+
+	w := s.do(t, http.MethodPost, "/api/v1/monitor/get", IDRequest{ID: target.ID})
+	if got, want := jsonKeys(t, w.Body.Bytes()), modelKeys(t, stored, "alerting", "effective_sinks", "lint"); !reflect.DeepEqual(got, want) {
+		t.Errorf("v1 monitor/get keys\n got %v\nwant %v", got, want)
+	}
+
+	var list struct {
+		Targets []json.RawMessage `json:"targets"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/list", nil), http.StatusOK, &list)
+	if len(list.Targets) != 1 {
+		t.Fatalf("listed %d monitors, want 1", len(list.Targets))
+	}
+	if got, want := jsonKeys(t, list.Targets[0]), modelKeys(t, stored); !reflect.DeepEqual(got, want) {
+		t.Errorf("v1 monitor/list item keys\n got %v\nwant %v", got, want)
+	}
+
+	var providers struct {
+		Providers []json.RawMessage `json:"providers"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/dns/provider/list", nil), http.StatusOK, &providers)
+	if len(providers.Providers) == 0 {
+		t.Fatal("no DNS providers listed")
+	}
+	if got, want := jsonKeys(t, providers.Providers[0]), modelKeys(t, models.DNSProvider{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("v1 dns/provider/list item keys\n got %v\nwant %v", got, want)
+	}
+}
+
+// v2 只返回 pkg/apitypes 中声明的字段，pkg/client 依赖这些类型解码
+func TestV2ResponsesMatchAPITypes(t *testing.T) {
+	s := newTestServer(t)
+	target := createTarget(t, models.MonitorTarget{Name: "db", Tags: `["prod"]`})
+	s.db.Create(&models.MonitorStatus{TargetID: target.ID, Status: "up", ResponseTime: 12})
+	channel := s.createChannel(t, "http://127.0.0.1:1/hook")
+	rule := models.AlertRule{TargetID: target.ID, ChannelID: uint(channel), ThresholdType: "failure_count", ThresholdValue: 3, Enabled: true}
+	s.db.Create(&rule)
+
+	for _, tc := range []struct {
+		path string
+		body interface{}
+		into interface{}
+	}{
+		{"/monitor/list", nil, &ListMonitorsResponse{}},
+		{"/monitor/get", IDRequest{ID: target.ID}, &MonitorDetailResponse{}},
+		{"/monitor/status/list", ListStatusRequest{}, &ListStatusResponse{}},
+		{"/monitor/status/get", GetStatusRequest{IDRequest: IDRequest{ID: target.ID}}, &StatusResponse{}},
+		{"/tag/list", nil, &ListTagsResponse{}},
+		{"/alert/channel/list", nil, &ListAlertChannelsResponse{}},
+		{"/alert/channel/get", IDRequest{ID: channel}, &AlertChannelResponse{}},
+		{"/alert/rule/get", IDRequest{ID: uint32(rule.ID)}, &AlertRuleResponse{}},
+		{"/alert/rule/list", nil, &struct {
+			Rules []AlertRuleResponse `json:"rules"`
+		}{}},
+		{"/dns/provider/list", nil, &struct {
+			Providers []DNSProviderResponse `json:"providers"`
+		}{}},
+	} {
+		w := s.do(t, http.MethodPost, "/api/v2"+tc.path, tc.body)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status = %d; body %s", tc.path, w.Code, w.Body.String())
+			continue
+		}
+		decodeStrict(t, w.Body.Bytes(), tc.into)
+	}
+}
@@ -2,7 +2,6 @@ package server
 
 import (
 	"encoding/json"
-	"fmt"
 	"strings"
 
 	"monitor/internal/models"
@@ -29,6 +28,36 @@ func ConvertAddRequestToModel(req AddMonitorRequest) (*models.MonitorTarget, err
 		httpHeaders = string(bytes)
 	}
 
+	expectedHeaders, err := encodeStringMap(req.ExpectedHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	scriptArgs, err := encodeScriptArgs(req.ScriptArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	dnsExpectedValues, err := encodeStringList(trimStrings(req.DNSExpectedValues))
+	if err != nil {
+		return nil, err
+	}
+
+	steps, err := encodeHTTPSteps(req.Steps)
+	if err != nil {
+		return nil, err
+	}
+
+	sinks, err := monitor.ParseSinks(req.Sinks)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := monitor.EncodeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+
 	target := &models.MonitorTarget{
 		Name:     req.Name,
 		Type:     req.Type,
@@ -37,18 +66,45 @@ func ConvertAddRequestToModel(req AddMonitorRequest) (*models.MonitorTarget, err
 		Interval: req.Interval,
 		Metadata: metadata,
 		Enabled:  req.Enabled,
+		// Deadline of one check
+		TimeoutSeconds: req.TimeoutSeconds,
+		// Retries of a failed check
+		RetryCount:           req.RetryCount,
+		RetryIntervalSeconds: req.RetryIntervalSeconds,
 		// HTTP/HTTPS specific fields
 		HTTPMethod:          req.HTTPMethod,
 		HTTPHeaders:         httpHeaders,
 		HTTPBody:            req.HTTPBody,
 		ResolvedHost:        req.ResolvedHost,
+		UnixSocketPath:      strings.TrimSpace(req.UnixSocketPath),
 		FollowRedirects:     req.FollowRedirects,
 		MaxRedirects:        req.MaxRedirects,
 		ExpectedStatusCodes: req.ExpectedStatusCodes,
+		ExpectedHeaders:     expectedHeaders,
+		BodyMustContain:     req.BodyMustContain,
+		BodyMustNotContain:  req.BodyMustNotContain,
+		BodyRegex:           req.BodyRegex,
+		JSONPath:            strings.TrimSpace(req.JSONPath),
+		JSONExpectedValue:   req.JSONExpectedValue,
+		JSONOperator:        req.JSONOperator,
+		Steps:               steps,
+		AuthType:            req.AuthType,
+		AuthUsername:        req.AuthUsername,
+		AuthPassword:        req.AuthPassword,
+		AuthToken:           req.AuthToken,
+		// Content change detection
+		TrackContentHash:     req.TrackContentHash,
+		ContentHashRegex:     req.ContentHashRegex,
+		ContentHashNormalize: req.ContentHashNormalize,
 		// DNS specific fields
 		DNSServer:     req.DNSServer,
 		DNSServerName: req.DNSServerName,
 		DNSServerType: req.DNSServerType,
+		DNSServers:    strings.TrimSpace(req.DNSServers),
+		DNSConsensus:  req.DNSConsensus,
+		DNSQueryType:      strings.TrimSpace(req.DNSQueryType),
+		DNSExpectedValues: dnsExpectedValues,
+		DNSMatchMode:      req.DNSMatchMode,
 		// PING specific fields
 		PingCount:   req.PingCount,
 		PingSize:    req.PingSize,
@@ -66,13 +122,57 @@ func ConvertAddRequestToModel(req AddMonitorRequest) (*models.MonitorTarget, err
 		SNMPVersion:      req.SNMPVersion,
 		SNMPExpectedValue: req.SNMPExpectedValue,
 		SNMPOperator:     req.SNMPOperator,
+		// TCP specific fields
+		TCPSendString:   req.TCPSendString,
+		TCPExpectString: req.TCPExpectString,
+		TCPUseTLS:       req.TCPUseTLS,
+		// UDP specific fields
+		UDPPreset:         req.UDPPreset,
+		UDPSendPayload:    strings.TrimSpace(req.UDPSendPayload),
+		UDPPayloadFormat:  req.UDPPayloadFormat,
+		UDPExpectResponse: req.UDPExpectResponse,
+		UDPExpectPattern:  req.UDPExpectPattern,
 		// SSL/TLS specific fields
 		SSLWarnDays:     req.SSLWarnDays,
 		SSLCriticalDays: req.SSLCriticalDays,
 		SSLCheck:       req.SSLCheck,
 		SSLGetChain:    req.SSLGetChain,
+		// Mutual TLS
+		TLSClientCertPEM: strings.TrimSpace(req.TLSClientCertPEM),
+		TLSClientKeyPEM:  strings.TrimSpace(req.TLSClientKeyPEM),
+		TLSCACertPEM:     strings.TrimSpace(req.TLSCACertPEM),
+		TLSSkipVerify:    req.TLSSkipVerify,
+		TLSPinSHA256:     monitor.NormalizeTLSPin(req.TLSPinSHA256),
+		// Script specific fields
+		ScriptPath:    strings.TrimSpace(req.ScriptPath),
+		ScriptArgs:    scriptArgs,
+		ScriptTimeout: req.ScriptTimeout,
+		// Comparison mode
+		SecondaryAddress:        strings.TrimSpace(req.SecondaryAddress),
+		CompareLatencyTolerance: req.CompareLatencyTolerance,
+		CompareBody:             req.CompareBody,
+		// Response time thresholds
+		DegradedThresholdMs: req.DegradedThresholdMs,
+		DownThresholdMs:     req.DownThresholdMs,
+		// Operator notes
+		Notes:      req.Notes,
+		RunbookURL: strings.TrimSpace(req.RunbookURL),
+		// Result sinks, stored in canonical form
+		Sinks: sinks.String(),
+		// Tags, stored normalized
+		Tags: tags,
+		// Monitor group
+		GroupID: req.GroupID,
+		// Dependency suppression
+		DependsOnTargetID: req.DependsOnTargetID,
 	}
 
+	// 代理密码单独保存，不在查询结果中返回
+	target.ProxyURL, target.ProxyPassword = monitor.SplitProxyURL(strings.TrimSpace(req.ProxyURL))
+
+	// GORM 的 default 标签不会作用于显式的零值，这里补上默认阈值
+	target.SSLWarnDays, target.SSLCriticalDays = monitor.SSLThresholds(target.SSLWarnDays, target.SSLCriticalDays)
+
 	return target, nil
 }
 
@@ -84,6 +184,9 @@ func UpdateModelFromRequest(target *models.MonitorTarget, req AddMonitorRequest)
 	target.Port = req.Port
 	target.Interval = req.Interval
 	target.Enabled = req.Enabled
+	target.TimeoutSeconds = req.TimeoutSeconds
+	target.RetryCount = req.RetryCount
+	target.RetryIntervalSeconds = req.RetryIntervalSeconds
 
 	var metadata string
 	if req.Metadata != nil {
@@ -109,13 +212,63 @@ func UpdateModelFromRequest(target *models.MonitorTarget, req AddMonitorRequest)
 	target.HTTPMethod = req.HTTPMethod
 	target.HTTPBody = req.HTTPBody
 	target.ResolvedHost = req.ResolvedHost
+	target.UnixSocketPath = strings.TrimSpace(req.UnixSocketPath)
+	// 代理 URL 不变且省略密码时保留原密码
+	proxyURL, proxyPassword := monitor.SplitProxyURL(strings.TrimSpace(req.ProxyURL))
+	if proxyPassword == "" && proxyURL == target.ProxyURL {
+		proxyPassword = target.ProxyPassword
+	}
+	target.ProxyURL = proxyURL
+	target.ProxyPassword = proxyPassword
 	target.FollowRedirects = req.FollowRedirects
 	target.MaxRedirects = req.MaxRedirects
 	target.ExpectedStatusCodes = req.ExpectedStatusCodes
+	expectedHeaders, err := encodeStringMap(req.ExpectedHeaders)
+	if err != nil {
+		return err
+	}
+	target.ExpectedHeaders = expectedHeaders
+	target.BodyMustContain = req.BodyMustContain
+	target.BodyMustNotContain = req.BodyMustNotContain
+	target.BodyRegex = req.BodyRegex
+	target.JSONPath = strings.TrimSpace(req.JSONPath)
+	target.JSONExpectedValue = req.JSONExpectedValue
+	target.JSONOperator = req.JSONOperator
+	target.TrackContentHash = req.TrackContentHash
+	target.ContentHashRegex = req.ContentHashRegex
+	target.ContentHashNormalize = req.ContentHashNormalize
+	steps, err := encodeHTTPSteps(req.Steps)
+	if err != nil {
+		return err
+	}
+	target.Steps = steps
+	// 密码和令牌不在查询结果中返回，认证方式不变时留空表示保留原值
+	password, token := req.AuthPassword, req.AuthToken
+	if req.AuthType == target.AuthType {
+		if password == "" {
+			password = target.AuthPassword
+		}
+		if token == "" {
+			token = target.AuthToken
+		}
+	}
+	target.AuthType = req.AuthType
+	target.AuthUsername = req.AuthUsername
+	target.AuthPassword = password
+	target.AuthToken = token
 	// DNS specific fields
 	target.DNSServer = req.DNSServer
 	target.DNSServerName = req.DNSServerName
 	target.DNSServerType = req.DNSServerType
+	target.DNSServers = strings.TrimSpace(req.DNSServers)
+	target.DNSConsensus = req.DNSConsensus
+	dnsExpectedValues, err := encodeStringList(trimStrings(req.DNSExpectedValues))
+	if err != nil {
+		return err
+	}
+	target.DNSQueryType = strings.TrimSpace(req.DNSQueryType)
+	target.DNSExpectedValues = dnsExpectedValues
+	target.DNSMatchMode = req.DNSMatchMode
 	// PING specific fields
 	target.PingCount = req.PingCount
 	target.PingSize = req.PingSize
@@ -133,91 +286,126 @@ func UpdateModelFromRequest(target *models.MonitorTarget, req AddMonitorRequest)
 	target.SNMPVersion = req.SNMPVersion
 	target.SNMPExpectedValue = req.SNMPExpectedValue
 	target.SNMPOperator = req.SNMPOperator
+	// TCP specific fields
+	target.TCPSendString = req.TCPSendString
+	target.TCPExpectString = req.TCPExpectString
+	target.TCPUseTLS = req.TCPUseTLS
+	// UDP specific fields
+	target.UDPPreset = req.UDPPreset
+	target.UDPSendPayload = strings.TrimSpace(req.UDPSendPayload)
+	target.UDPPayloadFormat = req.UDPPayloadFormat
+	target.UDPExpectResponse = req.UDPExpectResponse
+	target.UDPExpectPattern = req.UDPExpectPattern
 	// SSL/TLS specific fields
-	target.SSLWarnDays = req.SSLWarnDays
-	target.SSLCriticalDays = req.SSLCriticalDays
+	target.SSLWarnDays, target.SSLCriticalDays = monitor.SSLThresholds(req.SSLWarnDays, req.SSLCriticalDays)
 	target.SSLCheck = req.SSLCheck
 	target.SSLGetChain = req.SSLGetChain
+	// Mutual TLS: 私钥不在查询结果中返回，留空时保留原值，清空证书时一起清除
+	clientCert, clientKey := strings.TrimSpace(req.TLSClientCertPEM), strings.TrimSpace(req.TLSClientKeyPEM)
+	if clientKey == "" && clientCert != "" {
+		clientKey = target.TLSClientKeyPEM
+	}
+	target.TLSClientCertPEM = clientCert
+	target.TLSClientKeyPEM = clientKey
+	target.TLSCACertPEM = strings.TrimSpace(req.TLSCACertPEM)
+	target.TLSSkipVerify = req.TLSSkipVerify
+	target.TLSPinSHA256 = monitor.NormalizeTLSPin(req.TLSPinSHA256)
+	// Script specific fields
+	scriptArgs, err := encodeScriptArgs(req.ScriptArgs)
+	if err != nil {
+		return err
+	}
+	target.ScriptPath = strings.TrimSpace(req.ScriptPath)
+	target.ScriptArgs = scriptArgs
+	target.ScriptTimeout = req.ScriptTimeout
+	// Comparison mode
+	target.SecondaryAddress = strings.TrimSpace(req.SecondaryAddress)
+	target.CompareLatencyTolerance = req.CompareLatencyTolerance
+	target.CompareBody = req.CompareBody
+	// Response time thresholds
+	target.DegradedThresholdMs = req.DegradedThresholdMs
+	target.DownThresholdMs = req.DownThresholdMs
+	// Operator notes
+	target.Notes = req.Notes
+	target.RunbookURL = strings.TrimSpace(req.RunbookURL)
+	// Result sinks
+	sinks, err := monitor.ParseSinks(req.Sinks)
+	if err != nil {
+		return err
+	}
+	target.Sinks = sinks.String()
+	// Tags
+	tags, err := monitor.EncodeTags(req.Tags)
+	if err != nil {
+		return err
+	}
+	target.Tags = tags
+	// Monitor group
+	target.GroupID = req.GroupID
+	// Dependency suppression
+	target.DependsOnTargetID = req.DependsOnTargetID
 
 	return nil
 }
 
-// ConvertModelToMonitorTarget 将数据库模型转换为监控目标
-func ConvertModelToMonitorTarget(target models.MonitorTarget) (*monitor.MonitorTarget, error) {
-	var metadata map[string]string
-	if target.Metadata != "" {
-		if err := json.Unmarshal([]byte(target.Metadata), &metadata); err != nil {
-			return nil, err
-		}
+// encodeScriptArgs 参数列表以 JSON 数组保存，没有参数时为空字符串
+func encodeScriptArgs(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", nil
+	}
+	bytes, err := json.Marshal(args)
+	if err != nil {
+		return "", err
 	}
+	return string(bytes), nil
+}
 
-	// Parse HTTP headers
-	var httpHeaders map[string]string
-	if target.HTTPHeaders != "" {
-		if err := json.Unmarshal([]byte(target.HTTPHeaders), &httpHeaders); err != nil {
-			return nil, err
-		}
+// encodeStringMap stores a map of strings as a JSON object, "" when it is empty
+func encodeStringMap(m map[string]string) (string, error) {
+	if len(m) == 0 {
+		return "", nil
 	}
+	bytes, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
+}
 
-	// Parse expected status codes
-	var expectedStatusCodes []int
-	if target.ExpectedStatusCodes != "" {
-		codesStr := strings.Split(target.ExpectedStatusCodes, ",")
-		for _, codeStr := range codesStr {
-			codeStr = strings.TrimSpace(codeStr)
-			if codeStr != "" {
-				var code int
-				if _, err := fmt.Sscanf(codeStr, "%d", &code); err == nil {
-					expectedStatusCodes = append(expectedStatusCodes, code)
-				}
-			}
-		}
+// encodeStringList stores a list of strings as a JSON array, "" when it is empty
+func encodeStringList(values []string) (string, error) {
+	if len(values) == 0 {
+		return "", nil
 	}
+	bytes, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
+}
 
-	monitorTarget := &monitor.MonitorTarget{
-		ID:       target.ID,
-		Name:     target.Name,
-		Type:     target.Type,
-		Address:  target.Address,
-		Port:     target.Port,
-		Interval: target.Interval,
-		Metadata: metadata,
-		Enabled:  target.Enabled,
-		// HTTP/HTTPS specific fields
-		HTTPMethod:          target.HTTPMethod,
-		HTTPHeaders:         httpHeaders,
-		HTTPBody:            target.HTTPBody,
-		ResolvedHost:        target.ResolvedHost,
-		FollowRedirects:     target.FollowRedirects,
-		MaxRedirects:        target.MaxRedirects,
-		ExpectedStatusCodes: expectedStatusCodes,
-		// DNS specific fields
-		DNSServer:     target.DNSServer,
-		DNSServerName: target.DNSServerName,
-		DNSServerType: target.DNSServerType,
-		// PING specific fields
-		PingCount:   target.PingCount,
-		PingSize:    target.PingSize,
-		PingTimeout: target.PingTimeout,
-		// SMTP specific fields
-		SMTPUsername:      target.SMTPUsername,
-		SMTPPassword:      target.SMTPPassword,
-		SMTPUseTLS:        target.SMTPUseTLS,
-		SMTPMailFrom:      target.SMTPMailFrom,
-		SMTPMailTo:        target.SMTPMailTo,
-		SMTPCheckStartTLS: target.SMTPCheckStartTLS,
-		// SNMP specific fields
-		SNMPCommunity:    target.SNMPCommunity,
-		SNMPOID:          target.SNMPOID,
-		SNMPVersion:      target.SNMPVersion,
-		SNMPExpectedValue: target.SNMPExpectedValue,
-		SNMPOperator:     target.SNMPOperator,
-		// SSL/TLS specific fields
-		SSLWarnDays:     target.SSLWarnDays,
-		SSLCriticalDays: target.SSLCriticalDays,
-		SSLCheck:       target.SSLCheck,
-		SSLGetChain:    target.SSLGetChain,
+// trimStrings trims the spaces around each value
+func trimStrings(values []string) []string {
+	trimmed := make([]string, 0, len(values))
+	for _, value := range values {
+		trimmed = append(trimmed, strings.TrimSpace(value))
 	}
+	return trimmed
+}
 
-	return monitorTarget, nil
+// encodeHTTPSteps 事务的步骤以 JSON 数组保存，没有步骤时为空字符串
+func encodeHTTPSteps(steps []HTTPStep) (string, error) {
+	if len(steps) == 0 {
+		return "", nil
+	}
+	bytes, err := json.Marshal(steps)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
 }
+
+// ConvertModelToMonitorTarget 将数据库模型转换为监控目标
+func ConvertModelToMonitorTarget(target models.MonitorTarget) (*monitor.MonitorTarget, error) {
+	return monitor.NewTargetFromModel(target)
+}
\ No newline at end of file
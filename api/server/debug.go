@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"monitor/internal/monitor"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InjectFailureRequest 故障注入请求
+type InjectFailureRequest struct {
+	TargetID uint32 `json:"target_id" binding:"required"`
+	Status   string `json:"status" binding:"required"` // up, down, degraded
+	Count    int    `json:"count"`                     // 注入次数，默认 1
+	Interval int    `json:"interval"`                  // 每次注入间隔（秒），默认 0
+}
+
+// injectFailure 生成合成检查结果并走完整的状态与告警流程，用于预发环境演练
+func (s *Server) injectFailure(c *gin.Context) {
+	var req InjectFailureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Count == 0 {
+		req.Count = 1
+	}
+
+	if _, err := s.monitorService.GetTarget(req.TargetID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Monitor not found"})
+		return
+	}
+
+	err := s.monitorService.InjectResults(monitor.InjectRequest{
+		TargetID: req.TargetID,
+		Status:   req.Status,
+		Count:    req.Count,
+		Interval: time.Duration(req.Interval) * time.Second,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":   "Synthetic results are being injected",
+		"target_id": req.TargetID,
+		"status":    req.Status,
+		"count":     req.Count,
+		"interval":  req.Interval,
+	})
+}
+
+// purgeSynthetic 删除所有合成结果并根据真实历史重建受影响目标的状态
+func (s *Server) purgeSynthetic(c *gin.Context) {
+	report, err := s.monitorService.PurgeSynthetic()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "report": report})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"monitor/internal/config"
+	"monitor/internal/models"
+	"monitor/internal/monitor"
+)
+
+func withFailureInjection(cfg *config.Config) {
+	cfg.Debug.FailureInjection = true
+}
+
+func TestFailureInjectionIsOptIn(t *testing.T) {
+	s := newTestServer(t)
+	admin := []string{"Authorization", "Bearer " + testAdminToken}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/debug/inject", InjectFailureRequest{TargetID: 1, Status: "down"}, admin...), http.StatusNotFound, nil)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/debug/purge", nil, admin...), http.StatusNotFound, nil)
+}
+
+func TestInjectFailure(t *testing.T) {
+	s := newTestServer(t, withFailureInjection)
+	useFileLog(t)
+	admin := []string{"Authorization", "Bearer " + testAdminToken}
+	target := createTarget(t, models.MonitorTarget{Name: "db", Interval: 3600})
+	if err := s.monitorService.AddTarget(&monitor.MonitorTarget{ID: target.ID, Name: "db", Type: "tcp", Address: "127.0.0.1", Port: 1, Interval: 3600}); err != nil {
+		t.Fatalf("AddTarget: %v", err)
+	}
+
+	req := InjectFailureRequest{TargetID: target.ID, Status: "down"}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/debug/inject", req), http.StatusUnauthorized, nil)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/debug/inject", req, admin...), http.StatusAccepted, nil)
+	waitForStatus(t, s, target.ID, "down")
+
+	decode(t, s.do(t, http.MethodPost, "/api/v1/debug/inject", InjectFailureRequest{TargetID: target.ID, Status: "exploded"}, admin...), http.StatusBadRequest, nil)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/debug/inject", InjectFailureRequest{TargetID: target.ID + 1, Status: "down"}, admin...), http.StatusNotFound, nil)
+
+	var report monitor.SyntheticPurgeReport
+	decode(t, s.do(t, http.MethodPost, "/api/v1/debug/purge", nil, admin...), http.StatusOK, &report)
+	if report.HistoryDeleted != 1 {
+		t.Errorf("purge report = %+v, want the injected result deleted", report)
+	}
+	var left int64
+	s.db.Model(&models.MonitorStatus{}).Where("target_id = ?", target.ID).Count(&left)
+	if left != 0 {
+		t.Error("status row of a target with only synthetic results kept")
+	}
+}
+
+// waitForStatus waits for the background injection to save a status
+func waitForStatus(t *testing.T, s *Server, targetID uint32, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var status models.MonitorStatus
+		if s.db.Where("target_id = ?", targetID).First(&status).Error == nil && status.Status == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("status %q not saved within 5s", want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
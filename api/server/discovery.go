@@ -0,0 +1,256 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"monitor/api/middleware"
+	"monitor/internal/discovery"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// MaxDiscoveryReview 单次审核的候选数上限
+const MaxDiscoveryReview = 500
+
+// registerDiscoveryRoutes registers monitor discovery, only when discovery.enabled is set
+func (s *Server) registerDiscoveryRoutes(api *gin.RouterGroup) {
+	if s.discovery == nil {
+		return
+	}
+	group := api.Group("/discovery", middleware.AdminToken(s.adminToken()))
+	group.POST("/scan", s.startDiscoveryScan)
+	group.POST("/scan/list", s.listDiscoveryScans)
+	group.POST("/scan/get", s.getDiscoveryScan)
+	group.POST("/candidate/list", s.listDiscoveryCandidates)
+	group.POST("/candidate/remove", s.removeDiscoveryCandidate)
+	group.POST("/review", s.reviewDiscoveryCandidates)
+}
+
+// startDiscoveryScan 在后台开始扫描，立即返回扫描 ID；同一时间只运行一个扫描
+func (s *Server) startDiscoveryScan(c *gin.Context) {
+	var req discovery.ScanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scan, err := s.discovery.Start(req, c.ClientIP())
+	var scanErr *discovery.ScanError
+	switch {
+	case errors.Is(err, discovery.ErrScanRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "scan": scan})
+		return
+	case errors.As(err, &scanErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, scan)
+}
+
+func (s *Server) listDiscoveryScans(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"scans": s.discovery.Scans()})
+}
+
+func (s *Server) getDiscoveryScan(c *gin.Context) {
+	var req struct {
+		ID string `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scan, ok := s.discovery.Scan(req.ID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Discovery scan not found"})
+		return
+	}
+	c.JSON(http.StatusOK, scan)
+}
+
+// DiscoveryCandidateListRequest 候选列表的过滤条件
+type DiscoveryCandidateListRequest struct {
+	Status string `json:"status"`  // pending（默认）、approved、rejected 或 all
+	ScanID string `json:"scan_id"` // 只列出该扫描首次发现的候选
+}
+
+func (s *Server) listDiscoveryCandidates(c *gin.Context) {
+	var req DiscoveryCandidateListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		// 没有请求体时列出待审核的
+	}
+
+	query := s.requestDB(c).Order("id")
+	switch req.Status {
+	case "":
+		query = query.Where("status = ?", discovery.CandidatePending)
+	case discovery.CandidatePending, discovery.CandidateApproved, discovery.CandidateRejected:
+		query = query.Where("status = ?", req.Status)
+	case "all":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid status %q", req.Status)})
+		return
+	}
+	if req.ScanID != "" {
+		query = query.Where("scan_id = ?", req.ScanID)
+	}
+	var candidates []models.DiscoveryCandidate
+	if err := query.Find(&candidates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list discovery candidates"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"candidates": candidates})
+}
+
+// removeDiscoveryCandidate 删除候选，之后的扫描会再次列出同一主机和端口
+func (s *Server) removeDiscoveryCandidate(c *gin.Context) {
+	var req IDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.requestDB(c).Delete(&models.DiscoveryCandidate{}, req.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete discovery candidate"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Discovery candidate deleted successfully"})
+}
+
+// DiscoveryReviewRequest 批准（创建监控）或拒绝待审核的候选
+type DiscoveryReviewRequest struct {
+	IDs      []uint32 `json:"ids" binding:"required"`
+	Action   string   `json:"action" binding:"required,oneof=approve reject"`
+	Interval int64    `json:"interval"` // 批准时创建的监控的检查间隔（秒），默认 60
+}
+
+// DiscoveryReviewResult 单个候选的审核结果
+type DiscoveryReviewResult struct {
+	ID       uint32  `json:"id"`
+	Status   string  `json:"status,omitempty"`    // 审核后的状态，失败时为空
+	TargetID *uint32 `json:"target_id,omitempty"` // 批准时创建的监控
+	Error    string  `json:"error,omitempty"`
+}
+
+// reviewDiscoveryCandidates 审核候选：批准时按候选的类型和地址创建监控，
+// 拒绝的候选保留下来，之后的扫描不再列出
+func (s *Server) reviewDiscoveryCandidates(c *gin.Context) {
+	var req DiscoveryReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.IDs) == 0 || len(req.IDs) > MaxDiscoveryReview {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ids must list 1 to %d candidates", MaxDiscoveryReview)})
+		return
+	}
+	if req.Interval <= 0 {
+		req.Interval = 60
+	}
+
+	db := s.requestDB(c)
+	var candidates []models.DiscoveryCandidate
+	if err := db.Where("id IN ?", req.IDs).Find(&candidates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load discovery candidates"})
+		return
+	}
+	byID := make(map[uint32]models.DiscoveryCandidate, len(candidates))
+	var pending int64
+	for _, candidate := range candidates {
+		byID[candidate.ID] = candidate
+		if candidate.Status == discovery.CandidatePending {
+			pending++
+		}
+	}
+
+	// 超出监控数上限时整批都不创建
+	if req.Action == "approve" {
+		if err := s.monitorService.CheckQuota(pending, pending*s.fastCount(req.Interval)); err != nil {
+			respondQuotaError(c, err)
+			return
+		}
+	}
+
+	results := make([]DiscoveryReviewResult, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		result := DiscoveryReviewResult{ID: id}
+		candidate, ok := byID[id]
+		switch {
+		case !ok:
+			result.Error = "candidate not found"
+		case candidate.Status != discovery.CandidatePending:
+			result.Error = fmt.Sprintf("candidate is already %s", candidate.Status)
+		default:
+			if err := s.reviewDiscoveryCandidate(c, &candidate, req); err != nil {
+				result.Error = err.Error()
+			} else {
+				// 同一请求中重复的 ID 只审核一次
+				byID[id] = candidate
+				result.Status = candidate.Status
+				result.TargetID = candidate.TargetID
+			}
+		}
+		results = append(results, result)
+	}
+	c.JSON(http.StatusOK, gin.H{"action": req.Action, "results": results})
+}
+
+// reviewDiscoveryCandidate 审核一个待审核的候选并记录审计日志
+func (s *Server) reviewDiscoveryCandidate(c *gin.Context, candidate *models.DiscoveryCandidate, req DiscoveryReviewRequest) error {
+	status := discovery.CandidateRejected
+	if req.Action == "approve" {
+		monitorReq := AddMonitorRequest{
+			Name:     candidate.Name,
+			Type:     candidate.Type,
+			Address:  candidate.Address,
+			Interval: req.Interval,
+			Enabled:  true,
+		}
+		if candidate.Type != "http" && candidate.Type != "https" {
+			monitorReq.Port = int32(candidate.Port)
+		}
+		if err := validateImportedMonitor(&monitorReq); err != nil {
+			return err
+		}
+		id, err := s.importCreate(monitorReq, false)
+		if id == 0 && err != nil {
+			return err
+		}
+		// 监控已写入数据库，只是没能启动检查，仍然记为批准，避免再次批准时重复创建
+		if err != nil {
+			logger.Warn("Failed to start discovered monitor", zap.Uint32("target_id", id), zap.Error(err))
+		}
+		status = discovery.CandidateApproved
+		candidate.TargetID = &id
+	}
+	now := time.Now()
+	candidate.Status = status
+	candidate.ReviewedBy = c.ClientIP()
+	candidate.ReviewedAt = &now
+	if err := s.requestDB(c).Save(candidate).Error; err != nil {
+		return fmt.Errorf("failed to save candidate: %w", err)
+	}
+
+	fields := []zap.Field{
+		zap.String("action", req.Action),
+		zap.Uint32("candidate_id", candidate.ID),
+		zap.String("type", candidate.Type),
+		zap.String("address", candidate.Address),
+		zap.Int("port", candidate.Port),
+		zap.String("client_ip", c.ClientIP()),
+	}
+	if candidate.TargetID != nil {
+		fields = append(fields, zap.Uint32("target_id", *candidate.TargetID))
+	}
+	logger.Info("Discovery candidate reviewed", fields...)
+	return nil
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"monitor/internal/config"
+	"monitor/internal/discovery"
+	"monitor/internal/models"
+)
+
+func withDiscovery(cfg *config.Config) {
+	cfg.Discovery = config.DiscoveryConfig{Enabled: true, AllowedCIDRs: []string{"10.1.0.0/24"}, Ports: []int{22}, MaxHosts: 256, MaxConcurrent: 4, Timeout: 1}
+}
+
+func TestDiscoveryReview(t *testing.T) {
+	s := newTestServer(t, withDiscovery)
+	admin := []string{"Authorization", "Bearer " + testAdminToken}
+	candidates := []models.DiscoveryCandidate{
+		{Fingerprint: "10.1.0.5:22", Source: "10.1.0.0/24", Name: "git:22", Type: "tcp", Address: "10.1.0.5", Port: 22, Status: discovery.CandidatePending},
+		{Fingerprint: "10.1.0.6:8080", Source: "10.1.0.0/24", Name: "wiki:8080", Type: "http", Address: "http://10.1.0.6:8080/", Port: 8080, Status: discovery.CandidatePending},
+		{Fingerprint: "10.1.0.7:22", Source: "10.1.0.0/24", Name: "old:22", Type: "tcp", Address: "10.1.0.7", Port: 22, Status: discovery.CandidatePending},
+	}
+	for i := range candidates {
+		if err := s.db.Create(&candidates[i]).Error; err != nil {
+			t.Fatalf("create candidate: %v", err)
+		}
+	}
+
+	decode(t, s.do(t, http.MethodPost, "/api/v1/discovery/candidate/list", nil), http.StatusUnauthorized, nil)
+
+	var review struct {
+		Results []DiscoveryReviewResult `json:"results"`
+	}
+	ids := []uint32{candidates[0].ID, candidates[1].ID, candidates[0].ID, 999}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/discovery/review", DiscoveryReviewRequest{IDs: ids, Action: "approve", Interval: 300}, admin...), http.StatusOK, &review)
+	if len(review.Results) != 4 || review.Results[0].TargetID == nil || review.Results[1].Status != discovery.CandidateApproved {
+		t.Fatalf("review results %+v", review.Results)
+	}
+	// A repeated ID is reviewed once, an unknown one is reported
+	if review.Results[2].Error != "candidate is already approved" || review.Results[3].Error != "candidate not found" {
+		t.Errorf("repeated and unknown results %+v %+v", review.Results[2], review.Results[3])
+	}
+
+	var tcp, web models.MonitorTarget
+	s.db.First(&tcp, *review.Results[0].TargetID)
+	s.db.First(&web, *review.Results[1].TargetID)
+	if tcp.Type != "tcp" || tcp.Address != "10.1.0.5" || tcp.Port != 22 || tcp.Interval != 300 {
+		t.Errorf("tcp monitor %+v", tcp)
+	}
+	// HTTP monitors carry the port in their URL
+	if web.Type != "http" || web.Address != "http://10.1.0.6:8080/" || web.Port != 0 {
+		t.Errorf("http monitor %+v", web)
+	}
+
+	var rejected struct {
+		Results []DiscoveryReviewResult `json:"results"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/discovery/review", DiscoveryReviewRequest{IDs: []uint32{candidates[2].ID}, Action: "reject"}, admin...), http.StatusOK, &rejected)
+	if rejected.Results[0].Status != discovery.CandidateRejected || rejected.Results[0].TargetID != nil {
+		t.Errorf("reject result %+v", rejected.Results[0])
+	}
+
+	var list struct {
+		Candidates []models.DiscoveryCandidate `json:"candidates"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/discovery/candidate/list", DiscoveryCandidateListRequest{Status: "rejected"}, admin...), http.StatusOK, &list)
+	if len(list.Candidates) != 1 || list.Candidates[0].ID != candidates[2].ID || list.Candidates[0].ReviewedAt == nil {
+		t.Errorf("rejected candidates %+v", list.Candidates)
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/discovery/candidate/list", nil, admin...), http.StatusOK, &list)
+	if len(list.Candidates) != 0 {
+		t.Errorf("pending candidates %+v, want none left", list.Candidates)
+	}
+
+	decode(t, s.do(t, http.MethodPost, "/api/v1/discovery/review", DiscoveryReviewRequest{IDs: []uint32{1}, Action: "merge"}, admin...), http.StatusBadRequest, nil)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/discovery/candidate/list", DiscoveryCandidateListRequest{Status: "lost"}, admin...), http.StatusBadRequest, nil)
+}
+
+func TestDiscoveryScanRequests(t *testing.T) {
+	s := newTestServer(t, withDiscovery)
+	admin := []string{"Authorization", "Bearer " + testAdminToken}
+
+	decode(t, s.do(t, http.MethodPost, "/api/v1/discovery/scan", discovery.ScanRequest{CIDR: "10.2.0.0/24"}, admin...), http.StatusBadRequest, nil)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/discovery/scan", discovery.ScanRequest{Zone: "corp.example.com"}, admin...), http.StatusBadRequest, nil)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/discovery/scan/get", map[string]string{"id": "unknown"}, admin...), http.StatusNotFound, nil)
+}
+
+// Without discovery.enabled the endpoints do not exist
+func TestDiscoveryDisabled(t *testing.T) {
+	s := newTestServer(t)
+	admin := []string{"Authorization", "Bearer " + testAdminToken}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/discovery/scan", discovery.ScanRequest{CIDR: "10.1.0.0/24"}, admin...), http.StatusNotFound, nil)
+}
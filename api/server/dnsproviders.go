@@ -0,0 +1,156 @@
+package server
+
+import (
+	"net/http"
+
+	"monitor/internal/models"
+	"monitor/internal/monitor"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerDNSProviderRoutes registers DNS provider management
+func (s *Server) registerDNSProviderRoutes(api *gin.RouterGroup) {
+	// DNS Providers - using POST
+	api.POST("/dns/provider/add", s.addDNSProvider)
+	api.POST("/dns/provider/list", s.listDNSProviders)
+	api.POST("/dns/provider/get", s.getDNSProvider)
+	api.POST("/dns/provider/update", s.updateDNSProvider)
+	api.POST("/dns/provider/remove", s.removeDNSProvider)
+}
+
+type DNSProviderRequest struct {
+	Name       string `json:"name" binding:"required"`        // Provider name
+	Server     string `json:"server" binding:"required"`      // DNS server address
+	ServerType string `json:"server_type" binding:"required"` // DNS protocol: udp, tcp, doh, dot
+	IsDefault  bool   `json:"is_default"`                     // Mark as default
+}
+
+func (s *Server) addDNSProvider(c *gin.Context) {
+	var req DNSProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := s.requestDB(c)
+
+	// If setting as default, unset other defaults
+	if req.IsDefault {
+		db.Model(&models.DNSProvider{}).Where("is_default = ?", true).Update("is_default", false)
+	}
+
+	provider := models.DNSProvider{
+		Name:       req.Name,
+		Server:     req.Server,
+		ServerType: req.ServerType,
+		IsDefault:  req.IsDefault,
+	}
+
+	if err := db.Create(&provider).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create DNS provider"})
+		return
+	}
+	monitor.InvalidateDNSProviders()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":      provider.ID,
+		"message": "DNS provider created successfully",
+	})
+}
+
+func (s *Server) listDNSProviders(c *gin.Context) {
+	db := s.requestDB(c)
+
+	var providers []models.DNSProvider
+	if err := db.Find(&providers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list DNS providers"})
+		return
+	}
+
+	if isV2(c) {
+		c.JSON(http.StatusOK, gin.H{"providers": newDNSProviderResponses(providers)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"providers": providers})
+}
+
+func (s *Server) getDNSProvider(c *gin.Context) {
+	var req IDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := s.requestDB(c)
+
+	var provider models.DNSProvider
+	if err := db.First(&provider, req.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "DNS provider not found"})
+		return
+	}
+
+	if isV2(c) {
+		c.JSON(http.StatusOK, newDNSProviderResponse(provider))
+		return
+	}
+	c.JSON(http.StatusOK, provider)
+}
+
+func (s *Server) updateDNSProvider(c *gin.Context) {
+	var req struct {
+		IDRequest
+		DNSProviderRequest
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := s.requestDB(c)
+
+	var provider models.DNSProvider
+	if err := db.First(&provider, req.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "DNS provider not found"})
+		return
+	}
+
+	// If setting as default, unset other defaults
+	if req.IsDefault {
+		db.Model(&models.DNSProvider{}).Where("is_default = ? AND id != ?", true, req.ID).Update("is_default", false)
+	}
+
+	provider.Name = req.Name
+	provider.Server = req.Server
+	provider.ServerType = req.ServerType
+	provider.IsDefault = req.IsDefault
+
+	if err := db.Save(&provider).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update DNS provider"})
+		return
+	}
+	monitor.InvalidateDNSProviders()
+
+	c.JSON(http.StatusOK, gin.H{"message": "DNS provider updated successfully"})
+}
+
+func (s *Server) removeDNSProvider(c *gin.Context) {
+	var req IDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := s.requestDB(c)
+
+	if err := db.Delete(&models.DNSProvider{}, req.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete DNS provider"})
+		return
+	}
+	monitor.InvalidateDNSProviders()
+
+	c.JSON(http.StatusOK, gin.H{"message": "DNS provider deleted successfully"})
+}
+
+// Alert Channel API handlers
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"monitor/internal/models"
+	"monitor/internal/monitor"
+)
+
+func TestDNSProviderCRUD(t *testing.T) {
+	s := newTestServer(t)
+
+	var first, second CreatedResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v1/dns/provider/add", DNSProviderRequest{Name: "google", Server: "8.8.8.8:53", ServerType: "udp", IsDefault: true}), http.StatusCreated, &first)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/dns/provider/add", DNSProviderRequest{Name: "cloudflare", Server: "1.1.1.1:853", ServerType: "dot", IsDefault: true}), http.StatusCreated, &second)
+
+	// Only the latest default stays the default
+	var providers []models.DNSProvider
+	s.db.Where("is_default = ?", true).Find(&providers)
+	if len(providers) != 1 || uint32(providers[0].ID) != second.ID {
+		t.Errorf("default providers = %+v, want only cloudflare", providers)
+	}
+
+	// Checks read the providers through a cache that the handlers invalidate
+	ref := []monitor.DNSServerRef{{ProviderID: uint(first.ID)}}
+	if specs, err := monitor.ResolveDNSServers(context.Background(), ref); err != nil || specs[0].Server != "8.8.8.8:53" {
+		t.Fatalf("ResolveDNSServers = %+v, %v", specs, err)
+	}
+	update := map[string]interface{}{"id": first.ID, "name": "google", "server": "8.8.4.4:53", "server_type": "tcp", "is_default": true}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/dns/provider/update", update), http.StatusOK, nil)
+	if specs, err := monitor.ResolveDNSServers(context.Background(), ref); err != nil || specs[0].Server != "8.8.4.4:53" || specs[0].Type != "tcp" {
+		t.Errorf("ResolveDNSServers after update = %+v, %v", specs, err)
+	}
+	var got DNSProviderResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v2/dns/provider/get", IDRequest{ID: first.ID}), http.StatusOK, &got)
+	if got.Server != "8.8.4.4:53" || got.ServerType != "tcp" || !got.IsDefault {
+		t.Errorf("updated provider = %+v", got)
+	}
+
+	decode(t, s.do(t, http.MethodPost, "/api/v1/dns/provider/remove", IDRequest{ID: second.ID}), http.StatusOK, nil)
+	var list struct {
+		Providers []DNSProviderResponse `json:"providers"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/v2/dns/provider/list", nil), http.StatusOK, &list)
+	for _, p := range list.Providers {
+		if uint32(p.ID) == second.ID {
+			t.Errorf("removed provider still listed: %+v", p)
+		}
+	}
+
+	decode(t, s.do(t, http.MethodPost, "/api/v1/dns/provider/add", DNSProviderRequest{Name: "no server", ServerType: "udp"}), http.StatusBadRequest, nil)
+	update["id"] = second.ID
+	decode(t, s.do(t, http.MethodPost, "/api/v1/dns/provider/update", update), http.StatusNotFound, nil)
+}
@@ -0,0 +1,467 @@
+package server
+
+import (
+	"encoding/json"
+
+	"monitor/internal/alert"
+	"monitor/internal/elasticsearch"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+	"monitor/internal/monitor"
+	"monitor/pkg/apitypes"
+
+	"github.com/gin-gonic/gin"
+)
+
+// /api/v2 与 /api/v1 注册同一组 handler；返回数据库模型的接口在 v2 下改为返回
+// 下面的响应 DTO。DTO 的字段名和类型与对应的写入请求一致，客户端可以把读到的
+// 对象改完直接提交；可选字段在没有值时省略，而不是返回空字符串或 null。
+
+const apiVersionKey = "api_version"
+
+// apiVersions 已注册的 API 版本，路由前缀为 /api/v<N>
+var apiVersions = []int{1, 2}
+
+// apiVersion 记录请求的 API 版本，供 handler 选择响应格式
+func apiVersion(v int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiVersionKey, v)
+		c.Next()
+	}
+}
+
+// isV2 请求是否来自 /api/v2
+func isV2(c *gin.Context) bool {
+	return c.GetInt(apiVersionKey) >= 2
+}
+
+// 请求和 v2 响应的结构定义在 pkg/apitypes，与 pkg/client 共用
+type (
+	IDRequest                 = apitypes.IDRequest
+	CreatedResponse           = apitypes.CreatedResponse
+	AddMonitorRequest         = apitypes.AddMonitorRequest
+	HTTPStep                  = apitypes.HTTPStep
+	UpdateMonitorRequest      = apitypes.UpdateMonitorRequest
+	ListMonitorsRequest       = apitypes.ListMonitorsRequest
+	MonitorResponse           = apitypes.MonitorResponse
+	NotScheduled              = apitypes.NotScheduled
+	ListMonitorsResponse      = apitypes.ListMonitorsResponse
+	TagCount                  = apitypes.TagCount
+	ListTagsResponse          = apitypes.ListTagsResponse
+	TriggerCheckRequest       = apitypes.TriggerCheckRequest
+	TriggerCheckResponse      = apitypes.TriggerCheckResponse
+	ListStatusRequest         = apitypes.ListStatusRequest
+	StatusResponse            = apitypes.StatusResponse
+	StatusSSL                 = apitypes.StatusSSL
+	GetStatusRequest          = apitypes.GetStatusRequest
+	ListStatusResponse        = apitypes.ListStatusResponse
+	LogSearchRequest          = apitypes.LogSearchRequest
+	LogSearchResponse         = apitypes.LogSearchResponse
+	LogHitResponse            = apitypes.LogHitResponse
+	DNSProviderResponse       = apitypes.DNSProviderResponse
+	AlertChannelRequest       = apitypes.AlertChannelRequest
+	UpdateAlertChannelRequest = apitypes.UpdateAlertChannelRequest
+	AlertChannelResponse      = apitypes.AlertChannelResponse
+	ListAlertChannelsResponse = apitypes.ListAlertChannelsResponse
+	AlertChannelDelivery      = apitypes.AlertChannelDelivery
+	AlertRuleResponse         = apitypes.AlertRuleResponse
+	SnoozeAlertRuleRequest    = apitypes.SnoozeAlertRuleRequest
+	CancelRuleSnoozeRequest   = apitypes.CancelRuleSnoozeRequest
+	AlertRuleSnooze           = apitypes.AlertRuleSnooze
+)
+
+// MonitorDetailResponse v2 的 /monitor/get 响应
+type MonitorDetailResponse struct {
+	MonitorResponse
+	Alerting       []alert.RuleCoverage  `json:"alerting"`
+	EffectiveSinks []monitor.SinkState   `json:"effective_sinks"`
+	Lint           []monitor.LintFinding `json:"lint"`
+}
+
+// newMonitorResponse configErrors 为 monitorService.ConfigErrors() 的结果
+func newMonitorResponse(t models.MonitorTarget, configErrors map[uint32]*monitor.TargetConfigError) MonitorResponse {
+	resp := MonitorResponse{
+		ID:                   t.ID,
+		Name:                 t.Name,
+		Type:                 t.Type,
+		Address:              t.Address,
+		Port:                 t.Port,
+		Interval:             t.Interval,
+		Enabled:              t.Enabled,
+		TimeoutSeconds:       t.TimeoutSeconds,
+		RetryCount:           t.RetryCount,
+		RetryIntervalSeconds: t.RetryIntervalSeconds,
+		Metadata:             decodeStringMap(t.Metadata),
+		Notes:                t.Notes,
+		RunbookURL:           t.RunbookURL,
+		Sinks:                t.Sinks,
+		CreatedAt:            t.CreatedAt,
+		UpdatedAt:            t.UpdatedAt,
+	}
+	if !t.Enabled {
+		resp.NotScheduled = &NotScheduled{Reason: "disabled"}
+	} else if err, ok := configErrors[t.ID]; ok {
+		resp.NotScheduled = &NotScheduled{Reason: err.Reason, Message: err.Error()}
+	}
+	resp.Tags, _ = monitor.ParseTags(t.Tags)
+	resp.GroupID = t.GroupID
+	resp.DependsOnTargetID = t.DependsOnTargetID
+
+	// 别名（如 tls）按规范类型处理
+	typ := t.Type
+	if spec, ok := monitor.LookupType(t.Type); ok {
+		typ = spec.Type
+	}
+
+	switch typ {
+	case "http", "https":
+		resp.HTTPMethod = t.HTTPMethod
+		resp.HTTPHeaders = decodeStringMap(t.HTTPHeaders)
+		resp.HTTPBody = t.HTTPBody
+		resp.ResolvedHost = t.ResolvedHost
+		resp.UnixSocketPath = t.UnixSocketPath
+		if t.ProxyURL != "" {
+			resp.ProxyURL = t.ProxyURL
+			resp.ProxyPasswordSet = boolPtr(t.ProxyPassword != "")
+		}
+		resp.FollowRedirects = boolPtr(t.FollowRedirects)
+		resp.MaxRedirects = t.MaxRedirects
+		resp.ExpectedStatusCodes = t.ExpectedStatusCodes
+		resp.ExpectedHeaders = decodeStringMap(t.ExpectedHeaders)
+		resp.BodyMustContain = t.BodyMustContain
+		resp.BodyMustNotContain = t.BodyMustNotContain
+		resp.BodyRegex = t.BodyRegex
+		resp.JSONPath = t.JSONPath
+		resp.JSONExpectedValue = t.JSONExpectedValue
+		resp.JSONOperator = t.JSONOperator
+		resp.Steps = decodeHTTPSteps(t.Steps)
+		resp.TrackContentHash = t.TrackContentHash
+		resp.ContentHashRegex = t.ContentHashRegex
+		resp.ContentHashNormalize = t.ContentHashNormalize
+		if t.AuthType != "" && t.AuthType != monitor.AuthTypeNone {
+			resp.AuthType = t.AuthType
+			resp.AuthUsername = t.AuthUsername
+			resp.AuthPasswordSet = boolPtr(t.AuthPassword != "")
+			resp.AuthTokenSet = boolPtr(t.AuthToken != "")
+		}
+	case "dns":
+		resp.DNSServer = t.DNSServer
+		resp.DNSServerName = t.DNSServerName
+		resp.DNSServerType = t.DNSServerType
+		resp.DNSServers = t.DNSServers
+		resp.DNSConsensus = t.DNSConsensus
+		resp.DNSQueryType = t.DNSQueryType
+		resp.DNSExpectedValues, _ = monitor.ParseDNSExpectedValues(t.DNSExpectedValues)
+		resp.DNSMatchMode = t.DNSMatchMode
+	case "ping":
+		resp.PingCount = t.PingCount
+		resp.PingSize = t.PingSize
+		resp.PingTimeout = t.PingTimeout
+	case "smtp":
+		resp.SMTPUsername = t.SMTPUsername
+		resp.SMTPPasswordSet = boolPtr(t.SMTPPassword != "")
+		resp.SMTPUseTLS = boolPtr(t.SMTPUseTLS)
+		resp.SMTPMailFrom = t.SMTPMailFrom
+		resp.SMTPMailTo = t.SMTPMailTo
+		resp.SMTPCheckStartTLS = boolPtr(t.SMTPCheckStartTLS)
+	case "snmp":
+		resp.SNMPCommunity = t.SNMPCommunity
+		resp.SNMPOID = t.SNMPOID
+		resp.SNMPVersion = t.SNMPVersion
+		resp.SNMPExpectedValue = t.SNMPExpectedValue
+		resp.SNMPOperator = t.SNMPOperator
+	case "tcp":
+		resp.TCPSendString = t.TCPSendString
+		resp.TCPExpectString = t.TCPExpectString
+		resp.TCPUseTLS = boolPtr(t.TCPUseTLS)
+	case "udp":
+		resp.UDPPreset = t.UDPPreset
+		resp.UDPSendPayload = t.UDPSendPayload
+		resp.UDPPayloadFormat = t.UDPPayloadFormat
+		resp.UDPExpectResponse = boolPtr(t.UDPExpectResponse)
+		resp.UDPExpectPattern = t.UDPExpectPattern
+	case monitor.TypeScript:
+		resp.ScriptPath = t.ScriptPath
+		resp.ScriptArgs, _ = monitor.ParseScriptArgs(t.ScriptArgs)
+		resp.ScriptTimeout = t.ScriptTimeout
+	}
+	if t.SecondaryAddress != "" {
+		resp.SecondaryAddress = t.SecondaryAddress
+		resp.CompareLatencyTolerance = t.CompareLatencyTolerance
+		resp.CompareBody = boolPtr(t.CompareBody)
+	}
+	resp.DegradedThresholdMs = t.DegradedThresholdMs
+	resp.DownThresholdMs = t.DownThresholdMs
+	if typ == "https" || typ == "ssl" {
+		resp.SSLWarnDays = t.SSLWarnDays
+		resp.SSLCriticalDays = t.SSLCriticalDays
+		resp.SSLCheck = boolPtr(t.SSLCheck)
+		resp.SSLGetChain = boolPtr(t.SSLGetChain)
+	}
+	if t.TLSClientCertPEM != "" || t.TLSClientKeyPEM != "" || t.TLSCACertPEM != "" {
+		resp.TLSClientCertPEM = t.TLSClientCertPEM
+		resp.TLSClientKeyPEMSet = boolPtr(t.TLSClientKeyPEM != "")
+		resp.TLSCACertPEM = t.TLSCACertPEM
+	}
+	if typ == "http" || typ == "https" || typ == "ssl" {
+		resp.TLSSkipVerify = boolPtr(t.TLSSkipVerify)
+		resp.TLSPinSHA256 = t.TLSPinSHA256
+	}
+	return resp
+}
+
+func newMonitorResponses(targets []models.MonitorTarget, configErrors map[uint32]*monitor.TargetConfigError) []MonitorResponse {
+	resp := make([]MonitorResponse, 0, len(targets))
+	for _, t := range targets {
+		resp = append(resp, newMonitorResponse(t, configErrors))
+	}
+	return resp
+}
+
+func newStatusResponse(s monitor.StatusWithTarget) StatusResponse {
+	resp := StatusResponse{
+		TargetID:           s.TargetID,
+		TargetName:         s.TargetName,
+		TargetType:         s.TargetType,
+		TargetAddress:      s.TargetAddress,
+		TargetDeleted:      s.TargetDeleted,
+		InMaintenance:      s.InMaintenance,
+		Status:             s.Status,
+		ResponseTime:       s.ResponseTime,
+		Message:            s.Message,
+		CheckedAt:          s.CheckedAt,
+		UptimePercentage:   s.UptimePercentage,
+		Uptime24h:          s.Uptime24h,
+		Uptime7d:           s.Uptime7d,
+		Uptime30d:          s.Uptime30d,
+		LastStatusChangeAt: s.LastStatusChangeAt,
+		Synthetic:          s.Synthetic,
+		Flapping:           s.Flapping,
+		SuppressedBy:       s.SuppressedBy,
+		ResolvedIP:         stringValue(s.ResolvedIP),
+		ContentHash:        s.ContentHash,
+		DNSRecords:         rawJSON(s.DNSRecords),
+		Data:               rawJSON(s.Data),
+	}
+	if p := s.ResponseTimes; p != nil {
+		resp.ResponseTimes = &apitypes.ResponsePercentiles{From: p.From, To: p.To, Samples: p.Samples, P50: p.P50, P95: p.P95, P99: p.P99}
+	}
+
+	ssl := StatusSSL{
+		DaysUntilExpiry: s.SSLDaysUntilExpiry,
+		Issuer:          stringValue(s.SSLIssuer),
+		Subject:         stringValue(s.SSLSubject),
+		Serial:          stringValue(s.SSLEserial),
+	}
+	if ssl != (StatusSSL{}) {
+		resp.SSL = &ssl
+	}
+	return resp
+}
+
+func newStatusResponses(statuses []monitor.StatusWithTarget) []StatusResponse {
+	resp := make([]StatusResponse, 0, len(statuses))
+	for _, s := range statuses {
+		resp = append(resp, newStatusResponse(s))
+	}
+	return resp
+}
+
+func newDNSProviderResponse(p models.DNSProvider) DNSProviderResponse {
+	return DNSProviderResponse{
+		ID:         p.ID,
+		Name:       p.Name,
+		Server:     p.Server,
+		ServerType: p.ServerType,
+		IsDefault:  p.IsDefault,
+		CreatedAt:  p.CreatedAt,
+		UpdatedAt:  p.UpdatedAt,
+	}
+}
+
+func newDNSProviderResponses(providers []models.DNSProvider) []DNSProviderResponse {
+	resp := make([]DNSProviderResponse, 0, len(providers))
+	for _, p := range providers {
+		resp = append(resp, newDNSProviderResponse(p))
+	}
+	return resp
+}
+
+func newAlertChannelResponse(ch models.AlertChannel, stats alert.DeliveryStats) AlertChannelResponse {
+	health := ch.Health
+	if health == "" {
+		health = alert.ChannelHealthy
+	}
+	delivery := AlertChannelDelivery{SuccessRate: 100}
+	if stats.Attempts > 0 {
+		delivery = AlertChannelDelivery{Attempts: stats.Attempts, Failures: stats.Failures, SuccessRate: stats.SuccessRate}
+	}
+	return AlertChannelResponse{
+		ID:        ch.ID,
+		Name:      ch.Name,
+		Type:      ch.Type,
+		Enabled:   ch.Enabled,
+		Config:    ch.Config,
+		CreatedAt: ch.CreatedAt,
+		UpdatedAt: ch.UpdatedAt,
+
+		Health:          health,
+		HealthReason:    ch.HealthReason,
+		HealthChangedAt: ch.HealthChangedAt,
+		SuppressHealth:  ch.SuppressHealth,
+		Delivery:        delivery,
+	}
+}
+
+func newAlertChannelResponses(channels []models.AlertChannel, stats map[uint32]alert.DeliveryStats) []AlertChannelResponse {
+	resp := make([]AlertChannelResponse, 0, len(channels))
+	for _, ch := range channels {
+		resp = append(resp, newAlertChannelResponse(ch, stats[ch.ID]))
+	}
+	return resp
+}
+
+func newAlertRuleResponse(r models.AlertRule) AlertRuleResponse {
+	return AlertRuleResponse{
+		ID:              r.ID,
+		TargetID:        r.TargetID,
+		ChannelID:       r.ChannelID,
+		ThresholdType:   r.ThresholdType,
+		ThresholdValue:  r.ThresholdValue,
+		Enabled:         r.Enabled,
+		ConditionLogic:  r.ConditionLogic,
+		CooldownSeconds: r.CooldownSeconds,
+		AlertOpen:       r.AlertOpen,
+		AlertCount:      r.AlertCount,
+		LastAlertTime:   r.LastAlertTime,
+		Snooze:          newAlertRuleSnooze(r.Snooze),
+		CreatedAt:       r.CreatedAt,
+		UpdatedAt:       r.UpdatedAt,
+	}
+}
+
+func newAlertRuleSnooze(sn *models.AlertRuleSnooze) *AlertRuleSnooze {
+	if sn == nil {
+		return nil
+	}
+	return &AlertRuleSnooze{
+		ID:          sn.ID,
+		RuleID:      sn.RuleID,
+		TargetID:    sn.TargetID,
+		Note:        sn.Note,
+		StartsAt:    sn.StartsAt,
+		ExpiresAt:   sn.ExpiresAt,
+		CancelledAt: sn.CancelledAt,
+		CreatedBy:   sn.CreatedBy,
+	}
+}
+
+func newAlertRuleResponses(rules []models.AlertRule) []AlertRuleResponse {
+	resp := make([]AlertRuleResponse, 0, len(rules))
+	for _, r := range rules {
+		resp = append(resp, newAlertRuleResponse(r))
+	}
+	return resp
+}
+
+func newESLogHits(entries []elasticsearch.LogEntry) []LogHitResponse {
+	hits := make([]LogHitResponse, 0, len(entries))
+	for _, e := range entries {
+		hit := LogHitResponse{
+			TargetID:     e.TargetID,
+			TargetName:   e.TargetName,
+			TargetType:   e.TargetType,
+			Address:      e.Address,
+			Status:       e.Status,
+			ResponseTime: e.ResponseTime,
+			Message:      e.Message,
+			Synthetic:    e.Synthetic,
+			ClockSkewMs:  e.ClockSkewMs,
+			CheckedAt:    e.Timestamp,
+			Seq:          e.Seq,
+			Request:      e.Request,
+			Response:     e.Response,
+		}
+		if e.Timings != nil {
+			hit.Timings = e.Timings
+		}
+		if e.Error.Type != "" || e.Error.Message != "" {
+			hit.Error = e.Error
+		}
+		hits = append(hits, hit)
+	}
+	return hits
+}
+
+func newFileLogHits(entries []*logger.CheckLogEntry) []LogHitResponse {
+	hits := make([]LogHitResponse, 0, len(entries))
+	for _, e := range entries {
+		hit := LogHitResponse{
+			TargetID:     uint32(e.TargetID),
+			TargetName:   e.TargetName,
+			TargetType:   e.Type,
+			Address:      e.Address,
+			Status:       e.Status,
+			ResponseTime: e.ResponseTime,
+			Message:      e.Message,
+			Synthetic:    e.Synthetic,
+			CheckedAt:    e.Timestamp,
+			Seq:          e.Seq,
+		}
+		// 避免把 nil map 包进 interface{}，否则 omitempty 不生效
+		if e.Request != nil {
+			hit.Request = e.Request
+		}
+		if e.Response != nil {
+			hit.Response = e.Response
+		}
+		if e.Timings != nil {
+			hit.Timings = e.Timings
+		}
+		hits = append(hits, hit)
+	}
+	return hits
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// rawJSON 把数据库里的 JSON 字符串原样嵌入响应；不是合法 JSON 时省略
+func rawJSON(s *string) json.RawMessage {
+	if s == nil || *s == "" || !json.Valid([]byte(*s)) {
+		return nil
+	}
+	return json.RawMessage(*s)
+}
+
+// decodeStringMap 解析以 JSON 字符串保存的 map，空或无法解析时返回 nil
+// decodeHTTPSteps 保存的 steps JSON 数组，无法解析时为空
+func decodeHTTPSteps(s string) []HTTPStep {
+	if s == "" {
+		return nil
+	}
+	var steps []HTTPStep
+	if err := json.Unmarshal([]byte(s), &steps); err != nil {
+		return nil
+	}
+	return steps
+}
+
+func decodeStringMap(s string) map[string]string {
+	if s == "" {
+		return nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal([]byte(s), &m); err != nil || len(m) == 0 {
+		return nil
+	}
+	return m
+}
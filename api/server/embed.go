@@ -0,0 +1,201 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"time"
+
+	"monitor/api/middleware"
+	"monitor/internal/models"
+	"monitor/internal/monitor"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Limits of /embed/history
+const (
+	defaultEmbedHistoryHours = 24
+	maxEmbedHistoryHours     = 30 * 24
+	maxEmbedHistoryRows      = 1000
+)
+
+// registerEmbedRoutes 用绑定单个监控（或一个标签）的令牌读取状态、历史、热力图和徽章，公开监听地址上同样提供
+func (s *Server) registerEmbedRoutes(rateLimit gin.HandlerFunc) {
+	scoped := middleware.ScopedToken(s.resolveAPIToken)
+	s.publicGET("/embed/status", rateLimit, scoped, s.embedStatus)
+	s.publicGET("/embed/history", rateLimit, scoped, s.embedHistory)
+	s.publicGET("/embed/badge.svg", rateLimit, scoped, s.embedBadge)
+	s.publicGET("/embed/heatmap", rateLimit, scoped, s.embedHeatmap)
+}
+
+// embedTarget 返回请求读取的监控，只在令牌的范围内查找。绑定监控的令牌只能
+// 读取该监控，另带的 target_id 与令牌不符时返回 404；标签令牌必须用 target_id
+// 指定监控，监控当前没有该标签时同样返回 404。与监控不存在相同，不透露其他监控是否存在
+func (s *Server) embedTarget(c *gin.Context) (*models.MonitorTarget, bool) {
+	scope, ok := middleware.Scope(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "api token required"})
+		return nil, false
+	}
+
+	targetID := scope.TargetID
+	if raw := c.Query("target_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil || scope.TargetID != 0 && uint32(id) != scope.TargetID {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Monitor not found"})
+			return nil, false
+		}
+		targetID = uint32(id)
+	}
+	if targetID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target_id is required with a tag token"})
+		return nil, false
+	}
+
+	var target models.MonitorTarget
+	err := s.requestDB(c).Select("id", "name", "type", "tags").Where("id = ?", targetID).First(&target).Error
+	if err == nil && scope.Tag != "" && !monitor.HasTags(target.Tags, []string{scope.Tag}) {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Monitor not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load monitor"})
+		return nil, false
+	}
+	return &target, true
+}
+
+// embedStatus 返回监控的当前状态，没有检查结果时为 unknown
+func (s *Server) embedStatus(c *gin.Context) {
+	target, ok := s.embedTarget(c)
+	if !ok {
+		return
+	}
+
+	var statuses []models.MonitorStatus
+	if err := s.requestDB(c).Where("target_id = ?", target.ID).Limit(1).Find(&statuses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get status"})
+		return
+	}
+
+	resp := gin.H{
+		"target_id": target.ID,
+		"name":      target.Name,
+		"type":      target.Type,
+		"status":    "unknown",
+	}
+	if len(statuses) > 0 {
+		status := statuses[0]
+		resp["status"] = status.Status
+		resp["response_time"] = status.ResponseTime
+		resp["uptime_percentage"] = status.UptimePercentage
+		resp["uptime_24h"] = status.Uptime24h
+		resp["uptime_7d"] = status.Uptime7d
+		resp["uptime_30d"] = status.Uptime30d
+		resp["checked_at"] = status.CheckedAt
+		resp["last_status_change_at"] = status.LastStatusChangeAt
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// embedHistory 返回最近 hours 小时的检查结果，最新的在前，不含合成结果
+func (s *Server) embedHistory(c *gin.Context) {
+	target, ok := s.embedTarget(c)
+	if !ok {
+		return
+	}
+
+	hours := defaultEmbedHistoryHours
+	if raw := c.Query("hours"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxEmbedHistoryHours {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("hours must be between 1 and %d", maxEmbedHistoryHours)})
+			return
+		}
+		hours = n
+	}
+
+	since := time.Now().UTC().Add(-time.Duration(hours) * time.Hour)
+	var history []models.MonitorHistory
+	if err := s.requestDB(c).Select("status", "response_time", "checked_at").
+		Where("target_id = ? AND checked_at >= ? AND synthetic = ?", target.ID, since, false).
+		Order("checked_at DESC").Limit(maxEmbedHistoryRows).Find(&history).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get history"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(history))
+	for _, h := range history {
+		items = append(items, gin.H{
+			"status":        h.Status,
+			"response_time": h.ResponseTime,
+			"checked_at":    h.CheckedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"target_id": target.ID,
+		"hours":     hours,
+		"items":     items,
+		"truncated": len(history) == maxEmbedHistoryRows,
+	})
+}
+
+var badgeColors = map[string]string{
+	"up":       "#4c1",
+	"down":     "#e05d44",
+	"degraded": "#dfb317",
+}
+
+// embedBadge 返回 shields 风格的 SVG 状态徽章，label 参数替换左侧文字（默认为监控名称）
+func (s *Server) embedBadge(c *gin.Context) {
+	target, ok := s.embedTarget(c)
+	if !ok {
+		return
+	}
+
+	var statuses []models.MonitorStatus
+	if err := s.requestDB(c).Select("status").Where("target_id = ?", target.ID).Limit(1).Find(&statuses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get status"})
+		return
+	}
+	status := "unknown"
+	if len(statuses) > 0 {
+		status = statuses[0].Status
+	}
+	color, ok := badgeColors[status]
+	if !ok {
+		color = "#9f9f9f"
+	}
+
+	label := c.DefaultQuery("label", target.Name)
+	if r := []rune(label); len(r) > 64 {
+		label = string(r[:64])
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", []byte(renderBadge(label, status, color)))
+}
+
+// renderBadge 按每个字符约 7 像素估算宽度
+func renderBadge(label, value, color string) string {
+	labelWidth := 7*len([]rune(label)) + 10
+	valueWidth := 7*len([]rune(value)) + 10
+	width := labelWidth + valueWidth
+	label, value = html.EscapeString(label), html.EscapeString(value)
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`+
+		`<title>%s: %s</title>`+
+		`<rect width="%d" height="20" fill="#555"/><rect x="%d" width="%d" height="20" fill="%s"/>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%d" y="14">%s</text><text x="%d" y="14">%s</text></g></svg>`,
+		width, label, value,
+		label, value,
+		labelWidth, labelWidth, valueWidth, color,
+		labelWidth/2, label, labelWidth+valueWidth/2, value)
+}
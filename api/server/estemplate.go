@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"monitor/internal/elasticsearch"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReindexRequest 按当前模板重建今天之前 days 天的索引
+type ReindexRequest struct {
+	Days int `json:"days" binding:"required,min=1"`
+}
+
+// applyIndexTemplate 强制写入当前版本的索引模板，不比较集群中的版本
+func (s *Server) applyIndexTemplate(c *gin.Context) {
+	if s.es == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Elasticsearch is not enabled"})
+		return
+	}
+
+	status, err := s.es.ApplyIndexTemplate(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "template": status})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// reindexLogs 把最近的索引按当前模板重建，已有数据使用新的 mapping
+func (s *Server) reindexLogs(c *gin.Context) {
+	var req ReindexRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if s.es == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Elasticsearch is not enabled"})
+		return
+	}
+	if req.Days > elasticsearch.MaxReindexDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must not exceed 31"})
+		return
+	}
+
+	// 模板不是当前版本时重建没有意义
+	if status := s.es.TemplateStatus(); status.Installed != elasticsearch.TemplateVersion {
+		c.JSON(http.StatusConflict, gin.H{"error": "Index template is not at the current version, apply it first", "template": status})
+		return
+	}
+
+	results, err := s.es.ReindexRecent(c.Request.Context(), req.Days, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "indices": results})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"template_version": elasticsearch.TemplateVersion, "indices": results})
+}
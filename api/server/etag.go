@@ -0,0 +1,46 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusETag 由状态版本和请求参数组成，不同参数的响应不会共用 ETag
+func statusETag(version string, scope string) string {
+	return fmt.Sprintf(`"%s-%s"`, version, scope)
+}
+
+// statusScope v1 与 v2 的响应格式不同，ETag 加上版本区分
+func statusScope(c *gin.Context, scope string) string {
+	if isV2(c) {
+		return "v2." + scope
+	}
+	return scope
+}
+
+// notModified 设置 ETag；If-None-Match 命中时返回 304 并返回 true
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	// 客户端每次都要带 If-None-Match 重新验证
+	c.Header("Cache-Control", "no-cache")
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// etagMatches 按弱比较判断 If-None-Match 是否包含 etag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"monitor/internal/models"
+)
+
+func TestEtagMatches(t *testing.T) {
+	for header, want := range map[string]bool{
+		`"v1"`:              true,
+		`W/"v1"`:            true,
+		`"v0", "v1"`:        true,
+		`*`:                 true,
+		`"v2"`:              false,
+		``:                  false,
+		`v1`:                false,
+		`"v0" , W/"v1" , x`: true,
+	} {
+		if got := etagMatches(header, `"v1"`); got != want {
+			t.Errorf("etagMatches(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+// The status endpoints answer a matching If-None-Match with 304 until a
+// status changes, and never share an ETag between parameters or versions
+func TestStatusETag(t *testing.T) {
+	s := newTestServer(t)
+	target := createTarget(t, models.MonitorTarget{Name: "db"})
+	s.db.Create(&models.MonitorStatus{TargetID: target.ID, Status: "up"})
+
+	list := s.do(t, http.MethodPost, "/api/v2/monitor/status/list", ListStatusRequest{})
+	etag := list.Header().Get("ETag")
+	if list.Code != http.StatusOK || etag == "" || list.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("list = %d %v, want an ETag", list.Code, list.Header())
+	}
+	if w := s.do(t, http.MethodPost, "/api/v2/monitor/status/list", ListStatusRequest{}, "If-None-Match", etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("revalidation = %d %q, want an empty 304", w.Code, w.Body.String())
+	}
+
+	v1 := s.do(t, http.MethodPost, "/api/v1/monitor/status/list", ListStatusRequest{}, "If-None-Match", etag)
+	get := s.do(t, http.MethodPost, "/api/v2/monitor/status/get", GetStatusRequest{IDRequest: IDRequest{ID: target.ID}}, "If-None-Match", etag)
+	if v1.Code != http.StatusOK || get.Code != http.StatusOK || v1.Header().Get("ETag") == etag || get.Header().Get("ETag") == etag {
+		t.Errorf("v1 list %d %s, get %d %s, want full responses with their own ETags", v1.Code, v1.Header().Get("ETag"), get.Code, get.Header().Get("ETag"))
+	}
+
+	s.monitorService.InvalidateStatus()
+	w := s.do(t, http.MethodPost, "/api/v2/monitor/status/list", ListStatusRequest{}, "If-None-Match", etag)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("after a change = %d %s, want 200 with a new ETag", w.Code, w.Header().Get("ETag"))
+	}
+
+	// Adding a monitor is a change too
+	etag = w.Header().Get("ETag")
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/add", tcpMonitor), http.StatusCreated, nil)
+	if w := s.do(t, http.MethodPost, "/api/v2/monitor/status/list", ListStatusRequest{}, "If-None-Match", etag); w.Code != http.StatusOK {
+		t.Errorf("after adding a monitor = %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"monitor/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// registerGroupRoutes registers monitor group management and the group status rollup
+func (s *Server) registerGroupRoutes(api *gin.RouterGroup) {
+	api.POST("/group/add", s.addMonitorGroup)
+	api.POST("/group/list", s.listMonitorGroups)
+	api.POST("/group/get", s.getMonitorGroup)
+	api.POST("/group/update", s.updateMonitorGroup)
+	api.POST("/group/remove", s.removeMonitorGroup)
+	api.POST("/group/status", s.monitorGroupStatus)
+}
+
+// MonitorGroupRequest 分组的名称在所有分组中唯一
+type MonitorGroupRequest struct {
+	Name        string `json:"name" binding:"required,max=255"`
+	Description string `json:"description" binding:"max=500"`
+	SortOrder   int    `json:"sort_order"`
+}
+
+// MonitorGroupResponse 附带分组中的监控数
+type MonitorGroupResponse struct {
+	models.MonitorGroup
+	Monitors int64 `json:"monitors"`
+}
+
+// applyMonitorGroupRequest 校验请求并写入 g，返回的状态码用于错误响应
+func (s *Server) applyMonitorGroupRequest(c *gin.Context, req MonitorGroupRequest, g *models.MonitorGroup) (int, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return http.StatusBadRequest, errors.New("name must not be empty")
+	}
+
+	var count int64
+	if err := s.requestDB(c).Model(&models.MonitorGroup{}).Where("name = ? AND id <> ?", name, g.ID).Count(&count).Error; err != nil {
+		return http.StatusInternalServerError, errors.New("Failed to load monitor groups")
+	}
+	if count > 0 {
+		return http.StatusConflict, fmt.Errorf("monitor group %q already exists", name)
+	}
+
+	g.Name = name
+	g.Description = req.Description
+	g.SortOrder = req.SortOrder
+	return http.StatusOK, nil
+}
+
+// checkMonitorGroup 检查监控引用的分组存在，nil 表示未分组
+func checkMonitorGroup(db *gorm.DB, groupID *uint32) error {
+	if groupID == nil {
+		return nil
+	}
+	var group models.MonitorGroup
+	if err := db.Select("id").First(&group, *groupID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("monitor group %d not found", *groupID)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Server) addMonitorGroup(c *gin.Context) {
+	var req MonitorGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var group models.MonitorGroup
+	if code, err := s.applyMonitorGroupRequest(c, req, &group); err != nil {
+		c.JSON(code, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.requestDB(c).Create(&group).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create monitor group"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":      group.ID,
+		"message": "Monitor group created successfully",
+	})
+}
+
+func (s *Server) listMonitorGroups(c *gin.Context) {
+	db := s.requestDB(c)
+	var groups []models.MonitorGroup
+	if err := db.Order("sort_order, name").Find(&groups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list monitor groups"})
+		return
+	}
+
+	var counts []struct {
+		GroupID  uint32
+		Monitors int64
+	}
+	if err := db.Model(&models.MonitorTarget{}).Select("group_id, COUNT(*) AS monitors").
+		Where("group_id IS NOT NULL").Group("group_id").Scan(&counts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list monitor groups"})
+		return
+	}
+	members := make(map[uint32]int64, len(counts))
+	for _, row := range counts {
+		members[row.GroupID] = row.Monitors
+	}
+
+	items := make([]MonitorGroupResponse, 0, len(groups))
+	for _, g := range groups {
+		items = append(items, MonitorGroupResponse{MonitorGroup: g, Monitors: members[g.ID]})
+	}
+	c.JSON(http.StatusOK, gin.H{"groups": items})
+}
+
+func (s *Server) getMonitorGroup(c *gin.Context) {
+	var req IDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := s.requestDB(c)
+	var group models.MonitorGroup
+	if err := db.First(&group, req.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Monitor group not found"})
+		return
+	}
+	var monitors int64
+	if err := db.Model(&models.MonitorTarget{}).Where("group_id = ?", group.ID).Count(&monitors).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load monitor group"})
+		return
+	}
+	c.JSON(http.StatusOK, MonitorGroupResponse{MonitorGroup: group, Monitors: monitors})
+}
+
+func (s *Server) updateMonitorGroup(c *gin.Context) {
+	var req struct {
+		IDRequest
+		MonitorGroupRequest
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := s.requestDB(c)
+	var group models.MonitorGroup
+	if err := db.First(&group, req.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Monitor group not found"})
+		return
+	}
+	if code, err := s.applyMonitorGroupRequest(c, req.MonitorGroupRequest, &group); err != nil {
+		c.JSON(code, gin.H{"error": err.Error()})
+		return
+	}
+	if err := db.Save(&group).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update monitor group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Monitor group updated successfully"})
+}
+
+// removeMonitorGroup 删除分组，其中的监控在同一事务中变为未分组
+func (s *Server) removeMonitorGroup(c *gin.Context) {
+	var req IDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var ungrouped int64
+	err := s.requestDB(c).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.MonitorTarget{}).Where("group_id = ?", req.ID).Update("group_id", nil)
+		if result.Error != nil {
+			return result.Error
+		}
+		ungrouped = result.RowsAffected
+		return tx.Delete(&models.MonitorGroup{}, req.ID).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete monitor group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Monitor group deleted successfully",
+		"ungrouped": ungrouped,
+	})
+}
+
+// monitorGroupStatus 返回每个分组的汇总状态：最差状态、各状态的监控数和平均响应时间
+func (s *Server) monitorGroupStatus(c *gin.Context) {
+	rollups, err := s.monitorService.GroupRollups(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load monitor group status"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"groups": rollups})
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"monitor/internal/models"
+	"monitor/internal/monitor"
+)
+
+// groupUpdate is the body of /group/update
+type groupUpdate struct {
+	IDRequest
+	MonitorGroupRequest
+}
+
+func TestMonitorGroups(t *testing.T) {
+	s := newTestServer(t)
+
+	var web, data CreatedResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v1/group/add", MonitorGroupRequest{Name: " web ", SortOrder: 2}), http.StatusCreated, &web)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/group/add", MonitorGroupRequest{Name: "data", SortOrder: 1}), http.StatusCreated, &data)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/group/add", MonitorGroupRequest{Name: "web"}), http.StatusConflict, nil)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/group/add", MonitorGroupRequest{Name: "  "}), http.StatusBadRequest, nil)
+
+	// Monitors join a group when added or updated, and only an existing one
+	req := tcpMonitor
+	req.GroupID = &web.ID
+	var created CreatedResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/add", req), http.StatusCreated, &created)
+	missing := uint32(999)
+	req.Name, req.GroupID = "db2", &missing
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/add", req), http.StatusBadRequest, nil)
+	other := createTarget(t, models.MonitorTarget{Name: "cache", GroupID: &web.ID})
+
+	var list struct {
+		Groups []MonitorGroupResponse `json:"groups"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/group/list", nil), http.StatusOK, &list)
+	if len(list.Groups) != 2 || list.Groups[0].Name != "data" || list.Groups[1].Name != "web" || list.Groups[1].Monitors != 2 || list.Groups[0].Monitors != 0 {
+		t.Fatalf("groups %+v, want data then web with 2 monitors", list.Groups)
+	}
+
+	decode(t, s.do(t, http.MethodPost, "/api/v1/group/update", groupUpdate{IDRequest{ID: data.ID}, MonitorGroupRequest{Name: "web"}}), http.StatusConflict, nil)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/group/update", groupUpdate{IDRequest{ID: data.ID}, MonitorGroupRequest{Name: "databases", SortOrder: 3}}), http.StatusOK, nil)
+	var group MonitorGroupResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v1/group/get", IDRequest{ID: data.ID}), http.StatusOK, &group)
+	if group.Name != "databases" || group.SortOrder != 3 {
+		t.Errorf("updated group %+v", group)
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/group/get", IDRequest{ID: missing}), http.StatusNotFound, nil)
+
+	s.db.Create(&models.MonitorStatus{TargetID: created.ID, Status: "up", ResponseTime: 20})
+	s.db.Create(&models.MonitorStatus{TargetID: other.ID, Status: "down"})
+	var status struct {
+		Groups []monitor.GroupRollup `json:"groups"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/group/status", nil), http.StatusOK, &status)
+	if len(status.Groups) != 3 || status.Groups[0].Name != "web" || status.Groups[0].WorstStatus != "down" || status.Groups[0].AvgResponseTime != 20 {
+		t.Errorf("status %+v, want web down first", status.Groups)
+	}
+
+	// Removing a group leaves its monitors without a group
+	var removed struct {
+		Ungrouped int64 `json:"ungrouped"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/group/remove", IDRequest{ID: web.ID}), http.StatusOK, &removed)
+	if removed.Ungrouped != 2 {
+		t.Errorf("ungrouped %d, want 2", removed.Ungrouped)
+	}
+	var target models.MonitorTarget
+	s.db.First(&target, created.ID)
+	if target.GroupID != nil {
+		t.Errorf("group_id %d left on a monitor of a removed group", *target.GroupID)
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"monitor/internal/monitor"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// HeatmapRequest 按天统计一个监控的可用率，用于日历热力图
+type HeatmapRequest struct {
+	TargetID uint32 `json:"target_id" binding:"required"`
+	Days     int    `json:"days"`     // 包括今天在内的天数，默认且最多 90
+	Timezone string `json:"timezone"` // 按该 IANA 时区划分日期，默认服务器本地时区
+}
+
+// HeatmapResponse 每天一项，最早的在前，最后一项是今天
+type HeatmapResponse struct {
+	TargetID uint32               `json:"target_id"`
+	Timezone string               `json:"timezone"`
+	Days     []monitor.HeatmapDay `json:"days"`
+}
+
+func (s *Server) monitorHeatmap(c *gin.Context) {
+	var req HeatmapRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	s.writeHeatmap(c, req)
+}
+
+// writeHeatmap 校验参数并返回热力图，嵌入接口共用
+func (s *Server) writeHeatmap(c *gin.Context, req HeatmapRequest) {
+	if req.Days == 0 {
+		req.Days = monitor.MaxHeatmapDays
+	}
+	if req.Days < 1 || req.Days > monitor.MaxHeatmapDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days must be between 1 and %d", monitor.MaxHeatmapDays)})
+		return
+	}
+	loc := time.Local
+	if req.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(req.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown timezone %q", req.Timezone)})
+			return
+		}
+	}
+
+	days, err := s.monitorService.Heatmap(c.Request.Context(), req.TargetID, req.Days, loc)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Monitor not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute heatmap"})
+		return
+	}
+	c.JSON(http.StatusOK, HeatmapResponse{TargetID: req.TargetID, Timezone: loc.String(), Days: days})
+}
+
+// embedHeatmap 令牌绑定监控的热力图，参数为查询字符串 days 和 timezone
+func (s *Server) embedHeatmap(c *gin.Context) {
+	target, ok := s.embedTarget(c)
+	if !ok {
+		return
+	}
+
+	req := HeatmapRequest{TargetID: target.ID, Timezone: c.Query("timezone")}
+	if raw := c.Query("days"); raw != "" {
+		days, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days must be between 1 and %d", monitor.MaxHeatmapDays)})
+			return
+		}
+		req.Days = days
+	}
+	s.writeHeatmap(c, req)
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"monitor/internal/models"
+	"monitor/internal/monitor"
+)
+
+func TestMonitorHeatmap(t *testing.T) {
+	s := newTestServer(t)
+	target := createTarget(t, models.MonitorTarget{Name: "payments"})
+
+	var resp HeatmapResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/heatmap", HeatmapRequest{TargetID: target.ID, Days: 7, Timezone: "Asia/Tokyo"}), http.StatusOK, &resp)
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	today := time.Now().In(tokyo).Format(time.DateOnly)
+	if resp.TargetID != target.ID || resp.Timezone != "Asia/Tokyo" || len(resp.Days) != 7 || resp.Days[6].Date != today || resp.Days[6].WorstStatus != "unknown" {
+		t.Errorf("heatmap %+v, want 7 empty days up to %s", resp, today)
+	}
+	// All of MaxHeatmapDays by default
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/heatmap", HeatmapRequest{TargetID: target.ID}), http.StatusOK, &resp)
+	if len(resp.Days) != monitor.MaxHeatmapDays {
+		t.Errorf("%d days by default, want %d", len(resp.Days), monitor.MaxHeatmapDays)
+	}
+
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/heatmap", HeatmapRequest{TargetID: target.ID, Days: monitor.MaxHeatmapDays + 1}), http.StatusBadRequest, nil)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/heatmap", HeatmapRequest{TargetID: target.ID, Timezone: "Mars/Olympus"}), http.StatusBadRequest, nil)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/heatmap", HeatmapRequest{TargetID: target.ID + 1}), http.StatusNotFound, nil)
+
+	// The embed endpoint reads the target from its token
+	token := s.createToken(t, CreateAPITokenRequest{Name: "wiki", TargetID: target.ID, ExpiresAt: time.Now().Add(time.Hour)})
+	decode(t, s.do(t, http.MethodGet, "/embed/heatmap?days=3&token="+token.Token, nil), http.StatusOK, &resp)
+	if resp.TargetID != target.ID || len(resp.Days) != 3 {
+		t.Errorf("embed heatmap %+v", resp)
+	}
+	decode(t, s.do(t, http.MethodGet, "/embed/heatmap?days=many&token="+token.Token, nil), http.StatusBadRequest, nil)
+}
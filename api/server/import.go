@@ -0,0 +1,326 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"monitor/internal/logger"
+	"monitor/internal/models"
+	"monitor/internal/monitor"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxImportBytes 导入请求体上限，Uptime Kuma 的 SQLite 备份可能有几 MB
+	MaxImportBytes = 32 << 20
+	// MaxImportEntries 单次导入的监控项上限
+	MaxImportEntries = 5000
+)
+
+// ImportMonitorsRequest 导入请求
+type ImportMonitorsRequest struct {
+	SourceFormat   string `json:"source_format" binding:"required,oneof=uptime_kuma blackbox"`
+	Data           string `json:"data" binding:"required"` // uptime_kuma: JSON 备份原文或 base64 编码的 kuma.db；blackbox: file_sd 目标文件（JSON/YAML）
+	BlackboxConfig string `json:"blackbox_config"`         // blackbox: blackbox.yml 内容
+	Module         string `json:"module"`                  // blackbox: 目标没有 module 标签时使用的模块
+	Interval       int64  `json:"interval"`                // blackbox: 检查间隔（秒），默认 60
+	DryRun         bool   `json:"dry_run"`                 // 只返回转换和匹配结果，不写入
+	Upsert         bool   `json:"upsert"`                  // 同名监控已存在时更新，否则跳过
+}
+
+// importCandidate 从源格式转换出的一条待导入监控
+type importCandidate struct {
+	Source   string
+	Monitor  AddMonitorRequest
+	Warnings []string // 无法映射的设置
+	Err      error    // 整条无法转换
+}
+
+func (c *importCandidate) warn(msg string) {
+	c.Warnings = append(c.Warnings, msg)
+}
+
+// ImportResult 一条监控的导入结果
+type ImportResult struct {
+	Source   string             `json:"source"`
+	Name     string             `json:"name"`
+	Action   string             `json:"action"` // create, update, skip, error
+	TargetID uint32             `json:"target_id,omitempty"`
+	Warnings []string           `json:"warnings,omitempty"`
+	Error    string             `json:"error,omitempty"`
+	Monitor  *AddMonitorRequest `json:"monitor,omitempty"`
+}
+
+// ImportSummary 导入结果统计
+type ImportSummary struct {
+	Total   int `json:"total"`
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+}
+
+// importMonitors 从其他监控系统的备份或配置导入监控
+func (s *Server) importMonitors(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxImportBytes)
+
+	var req ImportMonitorsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var candidates []importCandidate
+	var err error
+	switch req.SourceFormat {
+	case "uptime_kuma":
+		candidates, err = parseKumaBackup(req.Data)
+	case "blackbox":
+		candidates, err = parseBlackbox(req.Data, req.BlackboxConfig, req.Module, req.Interval)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(candidates) > MaxImportEntries {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("too many monitors: %d (max %d)", len(candidates), MaxImportEntries)})
+		return
+	}
+
+	// 先按 dry run 计算要创建的数量，超出上限时整个导入都不写入
+	results, summary, err := s.applyImport(candidates, true, req.Upsert)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var creates, fastCreates int64
+	for _, result := range results {
+		if result.Action == "create" {
+			creates++
+			fastCreates += s.fastCount(result.Monitor.Interval)
+		}
+	}
+	if err := s.monitorService.CheckQuota(creates, fastCreates); err != nil {
+		respondQuotaError(c, err)
+		return
+	}
+
+	if !req.DryRun {
+		if results, summary, err = s.applyImport(candidates, false, req.Upsert); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	logger.Info("Monitors imported",
+		zap.String("source_format", req.SourceFormat),
+		zap.Bool("dry_run", req.DryRun),
+		zap.Int("created", summary.Created),
+		zap.Int("updated", summary.Updated),
+		zap.Int("skipped", summary.Skipped),
+		zap.Int("failed", summary.Failed))
+
+	c.JSON(http.StatusOK, gin.H{
+		"source_format": req.SourceFormat,
+		"dry_run":       req.DryRun,
+		"summary":       summary,
+		"entries":       results,
+	})
+}
+
+// applyImport 按名称匹配已有监控：不存在时创建，存在时 upsert 则更新、否则跳过。
+// dryRun 时只计算每条的动作，不写数据库也不启动检查。
+func (s *Server) applyImport(candidates []importCandidate, dryRun, upsert bool) ([]ImportResult, ImportSummary, error) {
+	db := s.db
+	summary := ImportSummary{Total: len(candidates)}
+	results := make([]ImportResult, 0, len(candidates))
+
+	names := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		if c.Err == nil {
+			names = append(names, c.Monitor.Name)
+		}
+	}
+	existing := make(map[string]models.MonitorTarget)
+	if len(names) > 0 {
+		var targets []models.MonitorTarget
+		if err := db.Where("name IN ?", names).Find(&targets).Error; err != nil {
+			return nil, summary, err
+		}
+		for _, t := range targets {
+			existing[t.Name] = t
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i := range candidates {
+		c := &candidates[i]
+		result := ImportResult{Source: c.Source, Name: c.Monitor.Name, Warnings: c.Warnings}
+
+		fail := func(err error) {
+			result.Action = "error"
+			result.Error = err.Error()
+			summary.Failed++
+		}
+
+		if c.Err != nil {
+			fail(c.Err)
+		} else if err := validateImportedMonitor(&c.Monitor); err != nil {
+			fail(err)
+		} else if seen[c.Monitor.Name] {
+			result.Action = "skip"
+			result.Warnings = append(result.Warnings, "duplicate name in this import")
+			summary.Skipped++
+		} else if target, ok := existing[c.Monitor.Name]; ok {
+			seen[c.Monitor.Name] = true
+			result.TargetID = target.ID
+			if !upsert {
+				result.Action = "skip"
+				result.Warnings = append(result.Warnings, "a monitor with this name already exists")
+				summary.Skipped++
+			} else if err := s.importUpdate(target, c.Monitor, dryRun); err != nil {
+				fail(err)
+			} else {
+				result.Action = "update"
+				summary.Updated++
+			}
+		} else {
+			seen[c.Monitor.Name] = true
+			id, err := s.importCreate(c.Monitor, dryRun)
+			if err != nil {
+				fail(err)
+			} else {
+				result.Action = "create"
+				result.TargetID = id
+				summary.Created++
+			}
+		}
+
+		if c.Err == nil {
+			monitorReq := c.Monitor
+			result.Monitor = &monitorReq
+		}
+		results = append(results, result)
+	}
+
+	return results, summary, nil
+}
+
+// validateImportedMonitor 补齐默认值并做与 add/update 相同的校验
+func validateImportedMonitor(req *AddMonitorRequest) error {
+	if req.Name == "" {
+		return fmt.Errorf("name is empty")
+	}
+	if req.Address == "" {
+		return fmt.Errorf("address is empty")
+	}
+	if req.Interval <= 0 {
+		req.Interval = 60
+	}
+	if err := validateMonitorSettings(*req); err != nil {
+		return err
+	}
+	warnDays, criticalDays := monitor.SSLThresholds(req.SSLWarnDays, req.SSLCriticalDays)
+	if err := monitor.ValidateSSLThresholds(warnDays, criticalDays); err != nil {
+		return err
+	}
+	if _, err := monitor.ParseSinks(req.Sinks); err != nil {
+		return err
+	}
+	if _, err := monitor.NormalizeTags(req.Tags); err != nil {
+		return err
+	}
+	// 导入时 socket 不存在只是警告，不影响导入
+	if _, err := monitor.ValidateUnixSocketPath(strings.TrimSpace(req.UnixSocketPath), req.SSLCheck); err != nil {
+		return err
+	}
+	if err := monitor.ValidateHTTPAuth(req.Type, req.AuthType, req.AuthUsername, req.AuthPassword, req.AuthToken); err != nil {
+		return err
+	}
+	if err := monitor.ValidateTLSClientConfig(req.Type, strings.TrimSpace(req.TLSClientCertPEM), strings.TrimSpace(req.TLSClientKeyPEM), strings.TrimSpace(req.TLSCACertPEM)); err != nil {
+		return err
+	}
+	if err := monitor.ValidateTLSVerification(req.Type, req.TLSSkipVerify, req.TLSPinSHA256, strings.TrimSpace(req.TLSCACertPEM)); err != nil {
+		return err
+	}
+	if err := monitor.ValidateProxyURL(req.Type, strings.TrimSpace(req.ProxyURL), strings.TrimSpace(req.UnixSocketPath)); err != nil {
+		return err
+	}
+	return monitor.ValidateNotes(req.Notes, req.RunbookURL)
+}
+
+// importCreate 创建监控并启动检查
+func (s *Server) importCreate(req AddMonitorRequest, dryRun bool) (uint32, error) {
+	target, err := ConvertAddRequestToModel(req)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkMonitorGroup(s.db, req.GroupID); err != nil {
+		return 0, err
+	}
+	if err := monitor.ValidateDependency(s.db, 0, req.DependsOnTargetID); err != nil {
+		return 0, err
+	}
+	if dryRun {
+		return 0, nil
+	}
+
+	if err := s.db.Create(target).Error; err != nil {
+		return 0, err
+	}
+	monitorTarget, err := ConvertModelToMonitorTarget(*target)
+	if err != nil {
+		return target.ID, err
+	}
+	return target.ID, s.monitorService.AddTarget(monitorTarget)
+}
+
+// importUpdate 用导入的设置更新已有监控，告警渠道等导入源没有的设置保持不变；
+// 导入源没有提供备注、处理手册链接、写入目的地、标签、分组和依赖时保留原有的
+func (s *Server) importUpdate(target models.MonitorTarget, req AddMonitorRequest, dryRun bool) error {
+	before := target
+	if err := monitor.ValidateTypeChange(target.Type, req.Type); err != nil {
+		return err
+	}
+	if req.Notes == "" {
+		req.Notes = target.Notes
+	}
+	if req.RunbookURL == "" {
+		req.RunbookURL = target.RunbookURL
+	}
+	if req.Sinks == "" {
+		req.Sinks = target.Sinks
+	}
+	if len(req.Tags) == 0 {
+		req.Tags, _ = monitor.ParseTags(target.Tags)
+	}
+	if req.GroupID == nil {
+		req.GroupID = target.GroupID
+	}
+	if err := checkMonitorGroup(s.db, req.GroupID); err != nil {
+		return err
+	}
+	if req.DependsOnTargetID == nil {
+		req.DependsOnTargetID = target.DependsOnTargetID
+	}
+	if err := monitor.ValidateDependency(s.db, target.ID, req.DependsOnTargetID); err != nil {
+		return err
+	}
+	if err := UpdateModelFromRequest(&target, req); err != nil {
+		return err
+	}
+	if err := s.monitorService.CheckQuota(0, s.fastCount(target.Interval)-s.fastCount(before.Interval)); err != nil {
+		return err
+	}
+	if dryRun {
+		return nil
+	}
+
+	if err := s.db.Save(&target).Error; err != nil {
+		return err
+	}
+	return s.reloadTarget(before, target)
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"monitor/internal/monitor"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileSDGroup Prometheus file_sd 文件中的一组目标
+type fileSDGroup struct {
+	Targets []string          `yaml:"targets"`
+	Labels  map[string]string `yaml:"labels"`
+}
+
+// blackboxConfig blackbox_exporter 配置文件，只解析 modules
+type blackboxConfig struct {
+	Modules map[string]blackboxModule `yaml:"modules"`
+}
+
+// blackboxModule blackbox_exporter 的一个模块，只列出能映射或需要提示的字段
+type blackboxModule struct {
+	Prober  string `yaml:"prober"`
+	Timeout string `yaml:"timeout"`
+	HTTP    struct {
+		ValidStatusCodes           []int             `yaml:"valid_status_codes"`
+		Method                     string            `yaml:"method"`
+		Headers                    map[string]string `yaml:"headers"`
+		Body                       string            `yaml:"body"`
+		NoFollowRedirects          bool              `yaml:"no_follow_redirects"`
+		FailIfSSL                  bool              `yaml:"fail_if_ssl"`
+		FailIfNotSSL               bool              `yaml:"fail_if_not_ssl"`
+		FailIfBodyMatchesRegexp    []string          `yaml:"fail_if_body_matches_regexp"`
+		FailIfBodyNotMatchesRegexp []string          `yaml:"fail_if_body_not_matches_regexp"`
+		BasicAuth                  map[string]string `yaml:"basic_auth"`
+		ProxyURL                   string            `yaml:"proxy_url"`
+		TLSConfig                  struct {
+			InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+		} `yaml:"tls_config"`
+	} `yaml:"http"`
+	TCP struct {
+		QueryResponse []map[string]interface{} `yaml:"query_response"`
+		TLS           bool                     `yaml:"tls"`
+	} `yaml:"tcp"`
+	DNS struct {
+		QueryName         string `yaml:"query_name"`
+		QueryType         string `yaml:"query_type"`
+		TransportProtocol string `yaml:"transport_protocol"`
+	} `yaml:"dns"`
+}
+
+// parseBlackbox 解析 file_sd 目标文件（JSON 或 YAML）和 blackbox 配置。
+// 每个目标使用标签 module / __param_module 指定的模块，没有时使用 defaultModule。
+func parseBlackbox(targets, config, defaultModule string, interval int64) ([]importCandidate, error) {
+	if strings.TrimSpace(targets) == "" {
+		return nil, fmt.Errorf("data is empty")
+	}
+	if strings.TrimSpace(config) == "" {
+		return nil, fmt.Errorf("blackbox_config is required for source_format blackbox")
+	}
+
+	// YAML 是 JSON 的超集，两种 file_sd 格式都能解析
+	var groups []fileSDGroup
+	if err := yaml.Unmarshal([]byte(targets), &groups); err != nil {
+		return nil, fmt.Errorf("invalid file_sd targets: %w", err)
+	}
+	var cfg blackboxConfig
+	if err := yaml.Unmarshal([]byte(config), &cfg); err != nil {
+		return nil, fmt.Errorf("invalid blackbox config: %w", err)
+	}
+
+	candidates := make([]importCandidate, 0)
+	for _, group := range groups {
+		moduleName := group.Labels["module"]
+		if moduleName == "" {
+			moduleName = group.Labels["__param_module"]
+		}
+		if moduleName == "" {
+			moduleName = defaultModule
+		}
+
+		for _, target := range group.Targets {
+			name := target
+			if len(group.Targets) == 1 && group.Labels["name"] != "" {
+				name = group.Labels["name"]
+			}
+
+			c := importCandidate{Source: fmt.Sprintf("blackbox %s (%s)", target, moduleName)}
+			c.Monitor.Name = name
+			module, ok := cfg.Modules[moduleName]
+			if moduleName == "" {
+				c.Err = fmt.Errorf("no module label and no default module given")
+			} else if !ok {
+				c.Err = fmt.Errorf("module %q is not defined in the blackbox config", moduleName)
+			} else {
+				convertBlackboxTarget(&c, name, target, module)
+				c.Monitor.Interval = interval
+				c.Monitor.RunbookURL = group.Labels["runbook_url"]
+				c.reportLabels(group.Labels)
+			}
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates, nil
+}
+
+// convertBlackboxTarget 按模块的 prober 把目标映射为 AddMonitorRequest
+func convertBlackboxTarget(c *importCandidate, name, target string, module blackboxModule) {
+	req := AddMonitorRequest{Name: name, Enabled: true}
+
+	switch module.Prober {
+	case "http":
+		address := target
+		if !strings.Contains(address, "://") {
+			// blackbox_exporter 对没有协议的目标默认使用 http
+			address = "http://" + address
+		}
+		u, err := url.Parse(address)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			c.Err = fmt.Errorf("invalid HTTP target %q", target)
+			return
+		}
+		req.Type = u.Scheme
+		req.Address = address
+
+		probe := module.HTTP
+		req.HTTPMethod = strings.ToUpper(probe.Method)
+		req.HTTPHeaders = probe.Headers
+		req.HTTPBody = probe.Body
+		req.FollowRedirects = !probe.NoFollowRedirects
+		if req.FollowRedirects {
+			req.MaxRedirects = 10
+		}
+		var codes []string
+		for _, code := range probe.ValidStatusCodes {
+			codes = append(codes, strconv.Itoa(code))
+		}
+		req.ExpectedStatusCodes = strings.Join(codes, ",")
+
+		importBlackboxBodyRegexps(c, &req, probe.FailIfBodyMatchesRegexp, probe.FailIfBodyNotMatchesRegexp)
+		if probe.FailIfSSL || probe.FailIfNotSSL {
+			c.warn("fail_if_ssl / fail_if_not_ssl are not supported")
+		}
+		if len(probe.BasicAuth) > 0 {
+			if probe.BasicAuth["password_file"] != "" {
+				c.warn("basic_auth password_file was not imported; set auth_password on the monitor")
+			}
+			req.AuthType = monitor.AuthTypeBasic
+			req.AuthUsername = probe.BasicAuth["username"]
+			req.AuthPassword = probe.BasicAuth["password"]
+		}
+		req.ProxyURL = probe.ProxyURL
+		req.TLSSkipVerify = probe.TLSConfig.InsecureSkipVerify
+	case "tcp":
+		host, port, err := splitHostPort(target)
+		if err != nil {
+			c.Err = err
+			return
+		}
+		req.Type = "tcp"
+		req.Address = host
+		req.Port = port
+		if module.TCP.TLS {
+			c.warn("TLS on TCP probes is not supported, imported as a plain TCP check")
+		}
+		if len(module.TCP.QueryResponse) > 0 {
+			c.warn("query_response exchanges are not supported")
+		}
+	case "icmp":
+		req.Type = "ping"
+		req.Address = target
+	case "dns":
+		// blackbox 的 DNS 目标是 DNS 服务器，要解析的域名在模块的 query_name 中
+		if module.DNS.QueryName == "" {
+			c.Err = fmt.Errorf("dns module has no query_name")
+			return
+		}
+		req.Type = "dns"
+		req.Address = module.DNS.QueryName
+		req.DNSServer = target
+		if module.DNS.TransportProtocol == "tcp" {
+			req.DNSServerType = "tcp"
+		}
+		if t := strings.ToUpper(module.DNS.QueryType); t != "" && t != "A" && t != "AAAA" && t != "ANY" {
+			c.warn(fmt.Sprintf("record type %s is not supported, the check resolves A/AAAA records", t))
+		}
+	default:
+		c.Err = fmt.Errorf("prober %q has no equivalent", module.Prober)
+		return
+	}
+
+	if module.Timeout != "" {
+		c.warn(fmt.Sprintf("timeout %s is not configurable per monitor", module.Timeout))
+	}
+
+	c.Monitor = req
+}
+
+// reportLabels 提示没有被使用的标签，它们在导入后会丢失
+func (c *importCandidate) reportLabels(labels map[string]string) {
+	var unused []string
+	for key := range labels {
+		switch key {
+		case "module", "__param_module", "name", "runbook_url":
+		default:
+			unused = append(unused, key)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		c.warn(fmt.Sprintf("labels not imported: %s", strings.Join(unused, ", ")))
+	}
+}
+
+// splitHostPort 解析 host:port 形式的目标
+func splitHostPort(target string) (string, int32, error) {
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		return "", 0, fmt.Errorf("target %q is not host:port", target)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("target %q has an invalid port", target)
+	}
+	return host, int32(port), nil
+}
+
+// importBlackboxBodyRegexps maps the body regexps of a blackbox http module to
+// the body assertions. One fail_if_body_not_matches_regexp becomes body_regex;
+// a fail_if_body_matches_regexp without metacharacters is plain text and
+// becomes body_must_not_contain. The rest cannot be expressed and is warned.
+func importBlackboxBodyRegexps(c *importCandidate, req *AddMonitorRequest, failIfMatches, failIfNotMatches []string) {
+	for i, re := range failIfNotMatches {
+		if i == 0 {
+			req.BodyRegex = re
+			continue
+		}
+		c.warn(fmt.Sprintf("fail_if_body_not_matches_regexp %q is not checked: only the first one is imported", re))
+	}
+	for _, re := range failIfMatches {
+		if req.BodyMustNotContain == "" && re != "" && regexp.QuoteMeta(re) == re {
+			req.BodyMustNotContain = re
+			continue
+		}
+		c.warn(fmt.Sprintf("fail_if_body_matches_regexp %q is not checked: only one plain-text pattern is imported", re))
+	}
+}
@@ -0,0 +1,367 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"monitor/internal/monitor"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// kumaBool Uptime Kuma 的布尔字段在旧版备份中是 0/1，新版是 true/false
+type kumaBool bool
+
+func (b *kumaBool) UnmarshalJSON(data []byte) error {
+	switch string(bytes.TrimSpace(data)) {
+	case "true", "1":
+		*b = true
+	case "false", "0", "null":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+	return nil
+}
+
+// kumaBackup Uptime Kuma 的 JSON 备份文件
+type kumaBackup struct {
+	Version     string        `json:"version"`
+	MonitorList []kumaMonitor `json:"monitorList"`
+}
+
+// kumaMonitor Uptime Kuma 的一个监控项，只列出能映射或需要提示的字段
+type kumaMonitor struct {
+	ID                  int      `json:"id"`
+	Name                string   `json:"name"`
+	Description         string   `json:"description"`
+	Type                string   `json:"type"`
+	URL                 string   `json:"url"`
+	Method              string   `json:"method"`
+	Body                string   `json:"body"`
+	Headers             string   `json:"headers"`
+	Hostname            string   `json:"hostname"`
+	Port                int32    `json:"port"`
+	Interval            int64    `json:"interval"`
+	Active              kumaBool `json:"active"`
+	Keyword             string   `json:"keyword"`
+	InvertKeyword       kumaBool `json:"invertKeyword"`
+	JSONPath            string   `json:"jsonPath"`
+	JSONPathOperator    string   `json:"jsonPathOperator"`
+	ExpectedValue       string   `json:"expectedValue"`
+	IgnoreTLS           kumaBool `json:"ignoreTls"`
+	UpsideDown          kumaBool `json:"upsideDown"`
+	ExpiryNotification  kumaBool `json:"expiryNotification"`
+	MaxRedirects        int      `json:"maxredirects"`
+	MaxRetries          int      `json:"maxretries"`
+	AcceptedStatusCodes []string `json:"accepted_statuscodes"`
+	DNSResolveType      string   `json:"dns_resolve_type"`
+	DNSResolveServer    string   `json:"dns_resolve_server"`
+	BasicAuthUser       string   `json:"basic_auth_user"`
+	BasicAuthPass       string   `json:"basic_auth_pass"`
+	AuthMethod          string   `json:"authMethod"`
+	TLSCert             string   `json:"tlsCert"`
+	TLSKey              string   `json:"tlsKey"`
+	TLSCa               string   `json:"tlsCa"`
+}
+
+// parseKumaBackup 解析 Uptime Kuma 备份：JSON 备份原文，或 base64 编码的 kuma.db（SQLite）
+func parseKumaBackup(data string) ([]importCandidate, error) {
+	data = strings.TrimSpace(data)
+	if data == "" {
+		return nil, fmt.Errorf("data is empty")
+	}
+
+	var monitors []kumaMonitor
+	if strings.HasPrefix(data, "{") {
+		var backup kumaBackup
+		if err := json.Unmarshal([]byte(data), &backup); err != nil {
+			return nil, fmt.Errorf("invalid Uptime Kuma JSON backup: %w", err)
+		}
+		monitors = backup.MonitorList
+	} else {
+		raw, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, fmt.Errorf("data is neither a JSON backup nor a base64-encoded SQLite database")
+		}
+		if !bytes.HasPrefix(raw, []byte("SQLite format 3\x00")) {
+			return nil, fmt.Errorf("decoded data is not a SQLite database")
+		}
+		if monitors, err = readKumaDatabase(raw); err != nil {
+			return nil, err
+		}
+	}
+
+	candidates := make([]importCandidate, 0, len(monitors))
+	for _, m := range monitors {
+		candidates = append(candidates, convertKumaMonitor(m))
+	}
+	return candidates, nil
+}
+
+// readKumaDatabase 从 kuma.db 的 monitor 表读取监控项。不同版本的列不同，所以按列名逐个读取
+func readKumaDatabase(raw []byte) ([]kumaMonitor, error) {
+	file, err := os.CreateTemp("", "kuma-import-*.db")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(raw); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(sqlite.Open(file.Name()), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Uptime Kuma database: %w", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	var rows []map[string]interface{}
+	if err := db.Table("monitor").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read Uptime Kuma monitor table: %w", err)
+	}
+
+	monitors := make([]kumaMonitor, 0, len(rows))
+	for _, row := range rows {
+		m := kumaMonitor{
+			ID:                 int(rowInt(row, "id")),
+			Name:               rowString(row, "name"),
+			Description:        rowString(row, "description"),
+			Type:               rowString(row, "type"),
+			URL:                rowString(row, "url"),
+			Method:             rowString(row, "method"),
+			Body:               rowString(row, "body"),
+			Headers:            rowString(row, "headers"),
+			Hostname:           rowString(row, "hostname"),
+			Port:               int32(rowInt(row, "port")),
+			Interval:           rowInt(row, "interval"),
+			Active:             rowInt(row, "active") != 0,
+			Keyword:            rowString(row, "keyword"),
+			InvertKeyword:      rowInt(row, "invert_keyword") != 0,
+			JSONPath:           rowString(row, "json_path"),
+			JSONPathOperator:   rowString(row, "json_path_operator"),
+			ExpectedValue:      rowString(row, "expected_value"),
+			IgnoreTLS:          rowInt(row, "ignore_tls") != 0,
+			UpsideDown:         rowInt(row, "upside_down") != 0,
+			ExpiryNotification: rowInt(row, "expiry_notification") != 0,
+			MaxRedirects:       int(rowInt(row, "maxredirects")),
+			MaxRetries:         int(rowInt(row, "maxretries")),
+			DNSResolveType:     rowString(row, "dns_resolve_type"),
+			DNSResolveServer:   rowString(row, "dns_resolve_server"),
+			BasicAuthUser:      rowString(row, "basic_auth_user"),
+			BasicAuthPass:      rowString(row, "basic_auth_pass"),
+			AuthMethod:         rowString(row, "auth_method"),
+			TLSCert:            rowString(row, "tls_cert"),
+			TLSKey:             rowString(row, "tls_key"),
+			TLSCa:              rowString(row, "tls_ca"),
+		}
+		if codes := rowString(row, "accepted_statuscodes_json"); codes != "" {
+			_ = json.Unmarshal([]byte(codes), &m.AcceptedStatusCodes)
+		}
+		monitors = append(monitors, m)
+	}
+	return monitors, nil
+}
+
+func rowString(row map[string]interface{}, key string) string {
+	switch v := row[key].(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func rowInt(row map[string]interface{}, key string) int64 {
+	switch v := row[key].(type) {
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case float64:
+		return int64(v)
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	default:
+		var n int64
+		fmt.Sscanf(rowString(row, key), "%d", &n)
+		return n
+	}
+}
+
+// convertKumaMonitor 把 Uptime Kuma 监控项映射为 AddMonitorRequest，无法表达的设置记为警告
+func convertKumaMonitor(m kumaMonitor) importCandidate {
+	c := importCandidate{Source: fmt.Sprintf("uptime_kuma #%d", m.ID)}
+	c.Monitor.Name = m.Name
+	req := AddMonitorRequest{
+		Name:     m.Name,
+		Interval: m.Interval,
+		Enabled:  bool(m.Active),
+		Notes:    m.Description,
+	}
+
+	switch m.Type {
+	case "http", "keyword", "json-query":
+		u, err := url.Parse(m.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			c.Err = fmt.Errorf("invalid URL %q", m.URL)
+			return c
+		}
+		req.Type = u.Scheme
+		req.Address = m.URL
+		req.HTTPMethod = strings.ToUpper(m.Method)
+		req.HTTPBody = m.Body
+		req.FollowRedirects = m.MaxRedirects > 0
+		req.MaxRedirects = m.MaxRedirects
+		if strings.TrimSpace(m.Headers) != "" {
+			if err := json.Unmarshal([]byte(m.Headers), &req.HTTPHeaders); err != nil {
+				c.warn("headers are not a JSON object of strings and were not imported")
+			}
+		}
+		codes, warnings := kumaStatusCodes(m.AcceptedStatusCodes)
+		c.Warnings = append(c.Warnings, warnings...)
+		req.ExpectedStatusCodes = codes
+		if req.Type == "https" && m.ExpiryNotification {
+			req.SSLCheck = true
+			req.SSLGetChain = true
+		}
+		// Uptime Kuma 的关键字区分大小写，反转时要求不包含
+		if m.Type == "keyword" && m.Keyword != "" {
+			if m.InvertKeyword {
+				req.BodyMustNotContain = m.Keyword
+			} else {
+				req.BodyMustContain = m.Keyword
+			}
+		}
+		if m.Type == "json-query" {
+			importKumaJSONQuery(&c, &req, m)
+		}
+		req.TLSSkipVerify = bool(m.IgnoreTLS)
+		// 旧备份没有 authMethod，有用户名即为 basic 认证
+		switch {
+		case m.AuthMethod == "ntlm":
+			c.warn("ntlm authentication is not supported")
+		case m.AuthMethod == "mtls":
+			req.TLSClientCertPEM = m.TLSCert
+			req.TLSClientKeyPEM = m.TLSKey
+			// 不验证证书时 CA 没有作用
+			if !req.TLSSkipVerify {
+				req.TLSCACertPEM = m.TLSCa
+			}
+		case m.BasicAuthUser != "":
+			req.AuthType = monitor.AuthTypeBasic
+			req.AuthUsername = m.BasicAuthUser
+			req.AuthPassword = m.BasicAuthPass
+		}
+	case "port":
+		req.Type = "tcp"
+		req.Address = m.Hostname
+		req.Port = m.Port
+	case "ping":
+		req.Type = "ping"
+		req.Address = m.Hostname
+	case "dns":
+		req.Type = "dns"
+		req.Address = m.Hostname
+		req.DNSServer = m.DNSResolveServer
+		if req.DNSServer != "" && m.Port != 0 && m.Port != 53 {
+			req.DNSServer = fmt.Sprintf("%s:%d", m.DNSResolveServer, m.Port)
+		}
+		if m.DNSResolveType != "" && m.DNSResolveType != "A" && m.DNSResolveType != "AAAA" {
+			c.warn(fmt.Sprintf("record type %s is not supported, the check resolves A/AAAA records", m.DNSResolveType))
+		}
+	default:
+		c.Err = fmt.Errorf("monitor type %q has no equivalent", m.Type)
+		return c
+	}
+
+	if m.UpsideDown {
+		c.warn("upside down mode is not supported, the imported check reports the normal status")
+	}
+	if m.MaxRetries > 0 {
+		c.warn(fmt.Sprintf("retries (%d) are not supported", m.MaxRetries))
+	}
+
+	c.Monitor = req
+	return c
+}
+
+// kumaStatusCodes 将 ["200-299", "301"] 转为逗号分隔的状态码列表，无法解析的项跳过并返回警告。
+// 只有 200-299 时返回空字符串，即使用默认的 2xx 判定。
+func kumaStatusCodes(ranges []string) (string, []string) {
+	if len(ranges) == 0 || (len(ranges) == 1 && ranges[0] == "200-299") {
+		return "", nil
+	}
+
+	var codes, warnings []string
+	for _, r := range ranges {
+		var from, to int
+		n, _ := fmt.Sscanf(r, "%d-%d", &from, &to)
+		if n == 1 {
+			to = from
+		}
+		if n == 0 || from < 100 || to > 599 || from > to {
+			warnings = append(warnings, fmt.Sprintf("status code %q is invalid and was skipped", r))
+			continue
+		}
+		codes = append(codes, r)
+	}
+	return strings.Join(codes, ","), warnings
+}
+
+// kumaPlainJSONPath 只由字段名和数组下标组成的 JSONata 表达式，与 json_path 含义相同
+var kumaPlainJSONPath = regexp.MustCompile(`^(\$\.)?[A-Za-z0-9_-]+(\[\d+\])*(\.[A-Za-z0-9_-]+(\[\d+\])*)*$`)
+
+// kumaJSONOperators Uptime Kuma 的 jsonPathOperator 对应的 json_operator；旧版没有该字段，按 == 比较
+var kumaJSONOperators = map[string]string{
+	"":         "eq",
+	"==":       "eq",
+	"!=":       "ne",
+	">":        "gt",
+	"<":        "lt",
+	"contains": "contains",
+}
+
+// importKumaJSONQuery 把 json-query 监控的简单字段路径导入为 JSON 字段断言，
+// 函数、过滤等 JSONata 表达式无法表达，记为警告
+func importKumaJSONQuery(c *importCandidate, req *AddMonitorRequest, m kumaMonitor) {
+	path := strings.TrimSpace(m.JSONPath)
+	if !kumaPlainJSONPath.MatchString(path) {
+		c.warn(fmt.Sprintf("JSON query %q is not a plain field path, imported as a plain HTTP check", m.JSONPath))
+		return
+	}
+	op, ok := kumaJSONOperators[m.JSONPathOperator]
+	if !ok {
+		c.warn(fmt.Sprintf("JSON query operator %q is not supported, imported as a plain HTTP check", m.JSONPathOperator))
+		return
+	}
+	if err := monitor.ValidateJSONAssertion(req.Type, path, m.ExpectedValue, op); err != nil {
+		c.warn(fmt.Sprintf("JSON query is imported as a plain HTTP check: %v", err))
+		return
+	}
+	req.JSONPath = path
+	req.JSONOperator = op
+	req.JSONExpectedValue = m.ExpectedValue
+}
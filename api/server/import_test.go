@@ -0,0 +1,305 @@
+package server
+
+import (
+	"encoding/base64"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"monitor/internal/models"
+	"monitor/internal/monitor"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+const kumaBackupJSON = `{
+	"version": "1.23.0",
+	"monitorList": [
+		{"id": 1, "name": "site", "type": "keyword", "url": "https://example.com/health", "method": "get",
+		 "interval": 30, "active": 1, "keyword": "OK", "maxredirects": 5, "expiryNotification": true,
+		 "accepted_statuscodes": ["200-299", "301"], "basic_auth_user": "probe", "basic_auth_pass": "secret",
+		 "description": "Public site", "maxretries": 2, "upsideDown": false},
+		{"id": 2, "name": "db", "type": "port", "hostname": "db.internal", "port": 5432, "interval": 60, "active": true},
+		{"id": 3, "name": "resolver", "type": "dns", "hostname": "example.com", "dns_resolve_server": "1.1.1.1",
+		 "port": 5353, "interval": 60, "active": false},
+		{"id": 4, "name": "push", "type": "push", "interval": 60, "active": true}
+	]
+}`
+
+func TestParseKumaBackupJSON(t *testing.T) {
+	candidates, err := parseKumaBackup(kumaBackupJSON)
+	if err != nil {
+		t.Fatalf("parseKumaBackup: %v", err)
+	}
+	if len(candidates) != 4 {
+		t.Fatalf("%d candidates, want 4", len(candidates))
+	}
+
+	site := candidates[0]
+	if site.Err != nil {
+		t.Fatalf("site: %v", site.Err)
+	}
+	m := site.Monitor
+	if m.Type != "https" || m.Address != "https://example.com/health" || m.HTTPMethod != "GET" || m.Interval != 30 || !m.Enabled {
+		t.Errorf("site monitor %+v", m)
+	}
+	if m.BodyMustContain != "OK" || m.ExpectedStatusCodes != "200-299,301" || !m.FollowRedirects || m.MaxRedirects != 5 {
+		t.Errorf("site checks: keyword %q codes %q redirects %v/%d", m.BodyMustContain, m.ExpectedStatusCodes, m.FollowRedirects, m.MaxRedirects)
+	}
+	if m.AuthType != monitor.AuthTypeBasic || m.AuthUsername != "probe" || m.AuthPassword != "secret" {
+		t.Errorf("site auth %q %q %q", m.AuthType, m.AuthUsername, m.AuthPassword)
+	}
+	if !m.SSLCheck || m.Notes != "Public site" {
+		t.Errorf("site ssl_check %v notes %q", m.SSLCheck, m.Notes)
+	}
+	if !slices.Equal(site.Warnings, []string{"retries (2) are not supported"}) {
+		t.Errorf("site warnings %q", site.Warnings)
+	}
+
+	if db := candidates[1].Monitor; db.Type != "tcp" || db.Address != "db.internal" || db.Port != 5432 || !db.Enabled {
+		t.Errorf("port monitor %+v", db)
+	}
+	if dns := candidates[2].Monitor; dns.Type != "dns" || dns.Address != "example.com" || dns.DNSServer != "1.1.1.1:5353" || dns.Enabled {
+		t.Errorf("dns monitor %+v", dns)
+	}
+	if push := candidates[3]; push.Err == nil || push.Monitor.Name != "push" {
+		t.Errorf("push monitor converted: %+v", push)
+	}
+}
+
+// mtls monitors bring their certificate, key and CA, without the CA when
+// verification is off
+func TestParseKumaMTLS(t *testing.T) {
+	candidates, err := parseKumaBackup(`{"monitorList": [
+		{"id": 1, "name": "a", "type": "http", "url": "https://a.internal", "interval": 60, "active": true,
+		 "authMethod": "mtls", "tlsCert": "CERT", "tlsKey": "KEY", "tlsCa": "CA", "basic_auth_user": "stale"},
+		{"id": 2, "name": "b", "type": "http", "url": "https://b.internal", "interval": 60, "active": true,
+		 "authMethod": "mtls", "tlsCert": "CERT", "tlsKey": "KEY", "tlsCa": "CA", "ignoreTls": true}
+	]}`)
+	if err != nil || len(candidates) != 2 {
+		t.Fatalf("parseKumaBackup: %d candidates, %v", len(candidates), err)
+	}
+	a, b := candidates[0].Monitor, candidates[1].Monitor
+	if a.TLSClientCertPEM != "CERT" || a.TLSClientKeyPEM != "KEY" || a.TLSCACertPEM != "CA" || a.AuthType != "" {
+		t.Errorf("mtls monitor: cert %q key %q ca %q auth %q", a.TLSClientCertPEM, a.TLSClientKeyPEM, a.TLSCACertPEM, a.AuthType)
+	}
+	if !b.TLSSkipVerify || b.TLSCACertPEM != "" || b.TLSClientCertPEM != "CERT" {
+		t.Errorf("mtls monitor without verification: skip %v ca %q", b.TLSSkipVerify, b.TLSCACertPEM)
+	}
+}
+
+func TestKumaStatusCodes(t *testing.T) {
+	if codes, warnings := kumaStatusCodes([]string{"200-299"}); codes != "" || warnings != nil {
+		t.Errorf("default range gives %q %q, want the default", codes, warnings)
+	}
+	codes, warnings := kumaStatusCodes([]string{"200", "300-399", "abc", "500-400"})
+	if codes != "200,300-399" || len(warnings) != 2 {
+		t.Errorf("got %q, warnings %q", codes, warnings)
+	}
+}
+
+// Plain field paths of a json-query monitor become a JSON assertion; other
+// JSONata expressions are imported as a plain HTTP check with a warning
+func TestImportKumaJSONQuery(t *testing.T) {
+	for _, tc := range []struct {
+		path, op, expected string
+		wantPath, wantOp   string
+	}{
+		{"$.data.status", "", "ok", "$.data.status", "eq"},
+		{"items[0].count", ">", "3", "items[0].count", "gt"},
+		{"status", "contains", "up", "status", "contains"},
+		{"$count(items)", "==", "3", "", ""},
+		{"status", "~=", "up", "", ""},
+		{"count", "<", "many", "", ""},
+	} {
+		c := importCandidate{Monitor: AddMonitorRequest{Type: "http"}}
+		importKumaJSONQuery(&c, &c.Monitor, kumaMonitor{JSONPath: tc.path, JSONPathOperator: tc.op, ExpectedValue: tc.expected})
+		m := c.Monitor
+		if m.JSONPath != tc.wantPath || m.JSONOperator != tc.wantOp {
+			t.Errorf("%s %s %s: imported %q %q", tc.path, tc.op, tc.expected, m.JSONPath, m.JSONOperator)
+		}
+		if warned := len(c.Warnings) > 0; warned != (tc.wantPath == "") {
+			t.Errorf("%s %s %s: warnings %q", tc.path, tc.op, tc.expected, c.Warnings)
+		}
+	}
+}
+
+// An uploaded kuma.db is read column by column, so a table from an older
+// version with fewer columns still imports
+func TestParseKumaBackupSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kuma.db")
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE monitor (id INTEGER PRIMARY KEY, name TEXT, type TEXT, url TEXT, hostname TEXT, port INTEGER,
+			interval INTEGER, active BOOLEAN, upside_down BOOLEAN, accepted_statuscodes_json TEXT)`,
+		`INSERT INTO monitor VALUES (1, 'api', 'http', 'http://api.internal/ping', NULL, NULL, 20, 1, 1, '["200-204"]')`,
+		`INSERT INTO monitor VALUES (2, 'gw', 'ping', NULL, '10.0.0.1', NULL, 60, 0, 0, NULL)`,
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read sqlite: %v", err)
+	}
+
+	candidates, err := parseKumaBackup(base64.StdEncoding.EncodeToString(raw))
+	if err != nil {
+		t.Fatalf("parseKumaBackup: %v", err)
+	}
+	if len(candidates) != 2 {
+		t.Fatalf("%d candidates, want 2", len(candidates))
+	}
+	api := candidates[0]
+	if api.Monitor.Type != "http" || api.Monitor.Interval != 20 || !api.Monitor.Enabled || api.Monitor.ExpectedStatusCodes != "200-204" {
+		t.Errorf("api monitor %+v", api.Monitor)
+	}
+	if len(api.Warnings) != 1 || !strings.HasPrefix(api.Warnings[0], "upside down mode") {
+		t.Errorf("api warnings %q", api.Warnings)
+	}
+	if gw := candidates[1].Monitor; gw.Type != "ping" || gw.Address != "10.0.0.1" || gw.Enabled {
+		t.Errorf("gw monitor %+v", gw)
+	}
+
+	if _, err := parseKumaBackup(base64.StdEncoding.EncodeToString([]byte("not a database"))); err == nil {
+		t.Error("base64 data that is not SQLite accepted")
+	}
+}
+
+const blackboxTargets = `
+- targets: ["https://example.com"]
+  labels: {module: http_2xx, name: site, runbook_url: "https://wiki.example.com/site", team: web}
+- targets: ["db.internal:5432", "cache.internal:6379"]
+  labels: {__param_module: tcp_connect}
+- targets: ["10.0.0.1"]
+- targets: ["1.1.1.1"]
+  labels: {module: dns_example}
+- targets: ["other.example.com"]
+  labels: {module: missing}
+`
+
+const blackboxYAML = `
+modules:
+  http_2xx:
+    prober: http
+    timeout: 5s
+    http:
+      valid_status_codes: [200, 204]
+      no_follow_redirects: true
+      fail_if_body_not_matches_regexp: ["healthy"]
+  tcp_connect:
+    prober: tcp
+  icmp:
+    prober: icmp
+  dns_example:
+    prober: dns
+    dns:
+      query_name: example.com
+      transport_protocol: tcp
+`
+
+func TestParseBlackbox(t *testing.T) {
+	candidates, err := parseBlackbox(blackboxTargets, blackboxYAML, "icmp", 30)
+	if err != nil {
+		t.Fatalf("parseBlackbox: %v", err)
+	}
+	if len(candidates) != 6 {
+		t.Fatalf("%d candidates, want 6", len(candidates))
+	}
+
+	site := candidates[0]
+	m := site.Monitor
+	if m.Name != "site" || m.Type != "https" || m.ExpectedStatusCodes != "200,204" || m.FollowRedirects || m.Interval != 30 {
+		t.Errorf("site monitor %+v", m)
+	}
+	if m.BodyRegex != "healthy" || m.RunbookURL != "https://wiki.example.com/site" {
+		t.Errorf("site body_regex %q runbook %q", m.BodyRegex, m.RunbookURL)
+	}
+	if !slices.Equal(site.Warnings, []string{"timeout 5s is not configurable per monitor", "labels not imported: team"}) {
+		t.Errorf("site warnings %q", site.Warnings)
+	}
+
+	// A name label only applies to a group with a single target
+	if db := candidates[1].Monitor; db.Name != "db.internal:5432" || db.Type != "tcp" || db.Address != "db.internal" || db.Port != 5432 {
+		t.Errorf("tcp monitor %+v", db)
+	}
+	if gw := candidates[3].Monitor; gw.Type != "ping" || gw.Address != "10.0.0.1" {
+		t.Errorf("default module monitor %+v", gw)
+	}
+	if dns := candidates[4].Monitor; dns.Type != "dns" || dns.Address != "example.com" || dns.DNSServer != "1.1.1.1" || dns.DNSServerType != "tcp" {
+		t.Errorf("dns monitor %+v", dns)
+	}
+	if missing := candidates[5]; missing.Err == nil || !strings.Contains(missing.Err.Error(), `module "missing"`) {
+		t.Errorf("undefined module: %v", missing.Err)
+	}
+
+	if _, err := parseBlackbox(blackboxTargets, "", "icmp", 30); err == nil {
+		t.Error("missing blackbox config accepted")
+	}
+}
+
+func TestImportMonitors(t *testing.T) {
+	s := newTestServer(t)
+	req := ImportMonitorsRequest{SourceFormat: "uptime_kuma", Data: kumaBackupJSON, DryRun: true}
+
+	var result struct {
+		Summary ImportSummary  `json:"summary"`
+		Entries []ImportResult `json:"entries"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/import", req), http.StatusOK, &result)
+	if result.Summary != (ImportSummary{Total: 4, Created: 3, Failed: 1}) {
+		t.Errorf("dry run summary %+v", result.Summary)
+	}
+	var count int64
+	s.db.Model(&models.MonitorTarget{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("dry run created %d monitors", count)
+	}
+
+	req.DryRun = false
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/import", req), http.StatusOK, &result)
+	s.db.Model(&models.MonitorTarget{}).Count(&count)
+	if result.Summary.Created != 3 || count != 3 {
+		t.Fatalf("summary %+v, %d monitors stored, want 3 created", result.Summary, count)
+	}
+	if result.Entries[3].Action != "error" || result.Entries[3].Error == "" {
+		t.Errorf("push entry %+v, want an error", result.Entries[3])
+	}
+
+	// Existing monitors are skipped unless upsert is set; an update keeps the
+	// notes when the source has none
+	var site models.MonitorTarget
+	s.db.Where("name = ?", "site").First(&site)
+	s.db.Model(&site).Update("notes", "Edited by hand")
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/import", req), http.StatusOK, &result)
+	if result.Summary.Skipped != 3 || result.Summary.Created != 0 {
+		t.Errorf("re-import summary %+v, want 3 skipped", result.Summary)
+	}
+
+	req.Upsert = true
+	req.Data = strings.Replace(kumaBackupJSON, `"description": "Public site", `, "", 1)
+	req.Data = strings.Replace(req.Data, `"interval": 30`, `"interval": 45`, 1)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/import", req), http.StatusOK, &result)
+	if result.Summary.Updated != 3 {
+		t.Errorf("upsert summary %+v, want 3 updated", result.Summary)
+	}
+	s.db.First(&site, site.ID)
+	if site.Interval != 45 || site.Notes != "Edited by hand" {
+		t.Errorf("updated site interval %d notes %q", site.Interval, site.Notes)
+	}
+
+	bad := ImportMonitorsRequest{SourceFormat: "nagios", Data: "x"}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/import", bad), http.StatusBadRequest, nil)
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+
+	"monitor/internal/monitor"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerLintRoutes registers the configuration lint
+func (s *Server) registerLintRoutes(api *gin.RouterGroup) {
+	api.GET("/lint", s.lintConfiguration)
+}
+
+// LintResponse 配置检查的结果，最严重的在前；counts 按严重程度计数，
+// 包括为 0 的，仪表盘据此显示配置警告的数量
+type LintResponse struct {
+	Findings []monitor.LintFinding `json:"findings"`
+	Counts   map[string]int        `json:"counts"`
+}
+
+// lintConfiguration 检查所有监控、告警规则和告警渠道的配置，找出能保存但不会按预期工作的设置
+func (s *Server) lintConfiguration(c *gin.Context) {
+	cfg, err := monitor.LoadLintConfig(s.requestDB(c), 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load configuration"})
+		return
+	}
+
+	findings := monitor.Lint(cfg)
+	counts := map[string]int{monitor.LintError: 0, monitor.LintWarning: 0, monitor.LintInfo: 0}
+	for _, f := range findings {
+		counts[f.Severity]++
+	}
+	c.JSON(http.StatusOK, LintResponse{Findings: findings, Counts: counts})
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"monitor/internal/models"
+	"monitor/internal/monitor"
+)
+
+func TestLintConfiguration(t *testing.T) {
+	s := newTestServer(t)
+	cert := createTarget(t, models.MonitorTarget{Name: "cert", Type: "ssl", Address: "example.com", Port: 443, Interval: 60, SSLWarnDays: 7, SSLCriticalDays: 30})
+	db := createTarget(t, models.MonitorTarget{Name: "db", Interval: 60})
+
+	var resp LintResponse
+	decode(t, s.do(t, http.MethodGet, "/api/v1/lint", nil), http.StatusOK, &resp)
+	// ssl_thresholds, certificate_interval and no_alert_rules for both
+	if resp.Counts[monitor.LintError] != 1 || resp.Counts[monitor.LintWarning] != 1 || resp.Counts[monitor.LintInfo] != 2 {
+		t.Errorf("counts %v, want 1 error, 1 warning and 2 infos", resp.Counts)
+	}
+	if len(resp.Findings) != 4 || resp.Findings[0].Lint != "ssl_thresholds" || resp.Findings[0].TargetID != cert.ID {
+		t.Errorf("findings %+v, want the ssl_thresholds error first", resp.Findings)
+	}
+
+	// /monitor/get lists the findings of that target only
+	for _, version := range []string{"v1", "v2"} {
+		var detail struct {
+			Lint []monitor.LintFinding `json:"lint"`
+		}
+		decode(t, s.do(t, http.MethodPost, "/api/"+version+"/monitor/get", IDRequest{ID: db.ID}), http.StatusOK, &detail)
+		if len(detail.Lint) != 1 || detail.Lint[0].Lint != "no_alert_rules" {
+			t.Errorf("%s lint of db: %+v", version, detail.Lint)
+		}
+	}
+
+	// Nothing to say about an empty configuration, and every count is there
+	s.db.Where("1 = 1").Delete(&models.MonitorTarget{})
+	var empty LintResponse
+	decode(t, s.do(t, http.MethodGet, "/api/v1/lint", nil), http.StatusOK, &empty)
+	if empty.Findings == nil || len(empty.Findings) != 0 || len(empty.Counts) != 3 {
+		t.Errorf("empty configuration: %+v", empty)
+	}
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+
+	"monitor/internal/config"
+	"monitor/internal/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// listenerKind 请求到达的监听地址
+type listenerKind int
+
+const (
+	adminListener  listenerKind = iota // host:http_port，提供全部路由
+	publicListener                     // server.public，只提供用 publicGET 注册的路由
+)
+
+func (k listenerKind) String() string {
+	if k == publicListener {
+		return "public"
+	}
+	return "admin"
+}
+
+type listenerKey struct{}
+
+// onPublicListener reports whether the request arrived on the public listener
+func onPublicListener(c *gin.Context) bool {
+	kind, _ := c.Request.Context().Value(listenerKey{}).(listenerKind)
+	return kind == publicListener
+}
+
+// publicGET registers a route that is served on the public listener as well
+// as the admin one
+func (s *Server) publicGET(path string, handlers ...gin.HandlerFunc) {
+	s.publicRoutes[path] = true
+	s.router.GET(path, handlers...)
+}
+
+// listenerGuard answers 404 on the public listener for every route not
+// registered with publicGET, the same as for a route that does not exist
+func (s *Server) listenerGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if onPublicListener(c) && !s.publicRoutes[c.FullPath()] {
+			c.String(http.StatusNotFound, "404 page not found")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Start listens on host:http_port and, when server.public is enabled, on the
+// public address, then serves both until Shutdown. Errors binding the
+// addresses or loading the certificates are returned.
+func (s *Server) Start() error {
+	if s.config == nil {
+		return fmt.Errorf("server config is required to start listening")
+	}
+
+	cfg := s.config.Server
+	if err := s.listen(adminListener, net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.HTTPPort)), cfg.TLS); err != nil {
+		return err
+	}
+	if public := cfg.Public; public.Enabled {
+		if err := s.listen(publicListener, net.JoinHostPort(public.Host, strconv.Itoa(public.Port)), public.TLS); err != nil {
+			s.Shutdown(context.Background())
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) listen(kind listenerKind, addr string, tlsCfg config.TLSConfig) error {
+	tlsConfig, err := newTLSConfig(tlsCfg)
+	if err != nil {
+		return fmt.Errorf("%s listener: %w", kind, err)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("%s listener: %w", kind, err)
+	}
+
+	srv := s.newHTTPServer(kind, tlsConfig)
+	s.listenMu.Lock()
+	s.httpServers = append(s.httpServers, srv)
+	s.listenMu.Unlock()
+
+	logger.Info("Starting HTTP server",
+		zap.String("listener", kind.String()),
+		zap.String("address", addr),
+		zap.Bool("tls", tlsConfig != nil),
+		zap.Bool("client_cert_required", tlsConfig != nil && tlsConfig.ClientAuth == tls.RequireAndVerifyClientCert))
+
+	go func() {
+		var err error
+		if tlsConfig != nil {
+			err = srv.ServeTLS(ln, "", "")
+		} else {
+			err = srv.Serve(ln)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("HTTP server failed", zap.String("listener", kind.String()), zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// newHTTPServer returns the server of one listener; its requests carry the
+// listener kind
+func (s *Server) newHTTPServer(kind listenerKind, tlsConfig *tls.Config) *http.Server {
+	return &http.Server{
+		Handler:   s.router.Handler(),
+		TLSConfig: tlsConfig,
+		// 路由按请求到达的监听地址过滤，见 listenerGuard
+		BaseContext: func(net.Listener) context.Context {
+			return context.WithValue(context.Background(), listenerKey{}, kind)
+		},
+	}
+}
+
+// Shutdown stops both listeners and waits for the requests in flight until
+// ctx is done. Open event streams are ended right away.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.closingOnce.Do(func() { close(s.closing) })
+
+	s.listenMu.Lock()
+	servers := s.httpServers
+	s.httpServers = nil
+	s.listenMu.Unlock()
+
+	var errs []error
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			srv.Close()
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// newTLSConfig returns nil when TLS is disabled. With a client CA file the
+// listener requires a client certificate signed by one of its CAs.
+func newTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in client CA file %s", cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"monitor/internal/config"
+)
+
+// testCA issues certificates for the listener tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a leaf certificate for 127.0.0.1 with the given usage
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// writeTLSFiles writes the server certificate, its key and the client CA as
+// the listener config reads them
+func writeTLSFiles(t *testing.T, server tls.Certificate, clientCA []byte) config.TLSConfig {
+	t.Helper()
+	dir := t.TempDir()
+	keyDER, err := x509.MarshalPKCS8PrivateKey(server.PrivateKey)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	cfg := config.TLSConfig{
+		Enabled:  true,
+		CertFile: filepath.Join(dir, "server.pem"),
+		KeyFile:  filepath.Join(dir, "server-key.pem"),
+	}
+	write := func(path string, data []byte) {
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	write(cfg.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate[0]}))
+	write(cfg.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+	if clientCA != nil {
+		cfg.ClientCAFile = filepath.Join(dir, "client-ca.pem")
+		write(cfg.ClientCAFile, clientCA)
+	}
+	return cfg
+}
+
+// serveListener serves the router as the given listener would, over TLS
+// when tlsCfg is enabled
+func (s *Server) serveListener(t *testing.T, kind listenerKind, tlsCfg config.TLSConfig) *httptest.Server {
+	t.Helper()
+	tlsConfig, err := newTLSConfig(tlsCfg)
+	if err != nil {
+		t.Fatalf("newTLSConfig: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(nil)
+	srv.Config = s.newHTTPServer(kind, tlsConfig)
+	if tlsConfig != nil {
+		srv.TLS = tlsConfig
+		srv.StartTLS()
+	} else {
+		srv.Start()
+	}
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// tlsClient trusts roots and presents cert unless it is empty
+func tlsClient(roots *x509.CertPool, cert tls.Certificate) *http.Client {
+	config := &tls.Config{RootCAs: roots}
+	if len(cert.Certificate) > 0 {
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+}
+
+// With a client CA the listener accepts only client certificates it signed
+func TestListenerClientCertificate(t *testing.T) {
+	s := newTestServer(t)
+	serverCA, clientCA, otherCA := newTestCA(t, "server CA"), newTestCA(t, "client CA"), newTestCA(t, "other CA")
+	tlsCfg := writeTLSFiles(t, serverCA.issue(t, "monitor", x509.ExtKeyUsageServerAuth), clientCA.pem)
+	srv := s.serveListener(t, adminListener, tlsCfg)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA.cert)
+	get := func(cert tls.Certificate) (int, error) {
+		resp, err := tlsClient(roots, cert).Get(srv.URL + "/api/v1/version")
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode, nil
+	}
+
+	if code, err := get(clientCA.issue(t, "probe", x509.ExtKeyUsageClientAuth)); err != nil || code != http.StatusOK {
+		t.Errorf("signed client certificate: %d, %v", code, err)
+	}
+	if _, err := get(tls.Certificate{}); err == nil {
+		t.Error("request without a client certificate accepted")
+	}
+	if _, err := get(otherCA.issue(t, "probe", x509.ExtKeyUsageClientAuth)); err == nil {
+		t.Error("client certificate of an unknown CA accepted")
+	}
+
+	// Without a client CA no certificate is asked for
+	plain := s.serveListener(t, adminListener, writeTLSFiles(t, serverCA.issue(t, "monitor", x509.ExtKeyUsageServerAuth), nil))
+	resp, err := tlsClient(roots, tls.Certificate{}).Get(plain.URL + "/api/v1/version")
+	if err != nil {
+		t.Fatalf("TLS without a client CA: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("TLS without a client CA: %d", resp.StatusCode)
+	}
+
+	bad := tlsCfg
+	bad.ClientCAFile = tlsCfg.CertFile + ".missing"
+	if _, err := newTLSConfig(bad); err == nil || !strings.Contains(err.Error(), "client CA") {
+		t.Errorf("missing client CA file: err = %v", err)
+	}
+	bad.ClientCAFile = tlsCfg.KeyFile
+	if _, err := newTLSConfig(bad); err == nil || !strings.Contains(err.Error(), "no PEM certificates") {
+		t.Errorf("client CA file without certificates: err = %v", err)
+	}
+}
+
+// The public listener serves the public routes only; every admin route
+// answers 404 there like an unknown path
+func TestPublicListenerHidesAdminRoutes(t *testing.T) {
+	s := newTestServer(t)
+	public := s.serveListener(t, publicListener, config.TLSConfig{})
+	admin := s.serveListener(t, adminListener, config.TLSConfig{})
+
+	send := func(base, method, path string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(method, base+path, strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	_, unknown := send(public.URL, http.MethodGet, "/no/such/route")
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/version"},
+		{http.MethodGet, "/api/v2/config"},
+		{http.MethodPost, "/api/v1/monitor/list"},
+		{http.MethodPost, "/api/v2/monitor/add"},
+		{http.MethodGet, "/api/v1/config/effective"},
+		{http.MethodGet, "/"},
+	} {
+		code, body := send(public.URL, route.method, route.path)
+		if code != http.StatusNotFound || body != unknown {
+			t.Errorf("public %s %s: %d %q, want the 404 of an unknown route", route.method, route.path, code, body)
+		}
+		if code, _ := send(admin.URL, route.method, route.path); code == http.StatusNotFound {
+			t.Errorf("admin %s %s: 404", route.method, route.path)
+		}
+	}
+
+	code, body := send(public.URL, http.MethodGet, "/health?verbose=1")
+	if code != http.StatusOK || strings.Contains(body, "version") {
+		t.Errorf("public /health?verbose=1: %d %s, want the status without details", code, body)
+	}
+	if _, body := send(admin.URL, http.MethodGet, "/health?verbose=1"); !strings.Contains(body, "version") {
+		t.Errorf("admin /health?verbose=1: %s, want the details", body)
+	}
+}
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"monitor/api/middleware"
+	"monitor/internal/elasticsearch"
+	"monitor/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerLogRoutes registers log search and the Elasticsearch maintenance endpoints
+func (s *Server) registerLogRoutes(api *gin.RouterGroup) {
+	// Logs - using POST
+	api.POST("/logs/search", s.searchLogs)
+	api.POST("/logs/stats", s.getLogStats)
+	api.POST("/logs/reingest", middleware.AdminToken(s.adminToken()), s.reingestLogs)
+	api.POST("/logs/purge", middleware.AdminToken(s.adminToken()), s.purgeLogs)
+	api.GET("/logs/purge/:id", middleware.AdminToken(s.adminToken()), s.getPurgeJob)
+
+	// Elasticsearch index template maintenance
+	api.POST("/elasticsearch/template/apply", middleware.AdminToken(s.adminToken()), s.applyIndexTemplate)
+	api.POST("/elasticsearch/reindex", middleware.AdminToken(s.adminToken()), s.reindexLogs)
+}
+
+func (s *Server) searchLogs(c *gin.Context) {
+	var req LogSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// after 是上一页返回的 next_after，不能与 from 同时使用
+	var after *logger.LogCursor
+	if req.After != "" {
+		if req.From != 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from and after cannot be used together"})
+			return
+		}
+		cursor, err := logger.ParseLogCursor(req.After)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		after = &cursor
+	}
+
+	// If ES is enabled, use ES; otherwise use file-based logs
+	if s.es != nil {
+		// 构建查询
+		query := &elasticsearch.SearchQuery{
+			TargetID:  req.TargetID,
+			Status:    req.Status,
+			Size:      req.Size,
+			From:      req.From,
+			After:     after,
+			QueryText: req.QueryText,
+			Synthetic: req.Synthetic,
+		}
+
+		// 转换时间
+		if req.StartTime != nil {
+			t := time.Unix(*req.StartTime, 0)
+			query.StartTime = &t
+		}
+		if req.EndTime != nil {
+			t := time.Unix(*req.EndTime, 0)
+			query.EndTime = &t
+		}
+
+		// 执行搜索
+		result, err := s.es.SearchLogs(c.Request.Context(), query)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if isV2(c) {
+			c.JSON(http.StatusOK, LogSearchResponse{Total: result.Total, Hits: newESLogHits(result.Hits), NextAfter: result.NextAfter})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"total":      result.Total,
+			"hits":       result.Hits,
+			"next_after": result.NextAfter,
+		})
+	} else {
+		// Use file-based logs
+		fileLogReq := &logger.LogQueryRequest{
+			Status:    req.Status,
+			Limit:     req.Size,
+			Offset:    req.From,
+			After:     after,
+			Synthetic: req.Synthetic,
+		}
+
+		// Convert TargetID from *uint32 to *int
+		if req.TargetID != nil {
+			id := int(*req.TargetID)
+			fileLogReq.TargetID = &id
+		}
+
+		// 转换时间
+		if req.StartTime != nil {
+			t := time.Unix(*req.StartTime, 0)
+			fileLogReq.StartTime = &t
+		}
+		if req.EndTime != nil {
+			t := time.Unix(*req.EndTime, 0)
+			fileLogReq.EndTime = &t
+		}
+
+		// Set default limit
+		if fileLogReq.Limit <= 0 {
+			fileLogReq.Limit = 100
+		}
+
+		// Query from file logs
+		result, err := logger.QueryCheckLogs(fileLogReq)
+		if errors.Is(err, logger.ErrFileLogDisabled) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error":    err.Error(),
+				"file_log": logger.GetFileLogStatus(),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if isV2(c) {
+			c.JSON(http.StatusOK, LogSearchResponse{Total: int64(result.Total), Hits: newFileLogHits(result.Logs), NextAfter: result.NextAfter})
+			return
+		}
+
+		// Convert file log entries to response format
+		hits := make([]map[string]interface{}, 0)
+		for _, entry := range result.Logs {
+			source := map[string]interface{}{
+				"target_id":     entry.TargetID,
+				"target_name":   entry.TargetName,
+				"target_type":   entry.Type,
+				"address":       entry.Address,
+				"status":        entry.Status,
+				"response_time": entry.ResponseTime,
+				"message":       entry.Message,
+				"@timestamp":    entry.Timestamp.Format(time.RFC3339),
+			}
+			if entry.Synthetic {
+				source["synthetic"] = true
+			}
+			if entry.Seq != 0 {
+				source["seq"] = entry.Seq
+			}
+
+			// Add request details if available
+			if entry.Request != nil {
+				source["request"] = entry.Request
+			}
+
+			// Add response details if available
+			if entry.Response != nil {
+				source["response"] = entry.Response
+			}
+
+			hit := map[string]interface{}{
+				"_source": source,
+			}
+			hits = append(hits, hit)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"total":      result.Total,
+			"hits":       hits,
+			"next_after": result.NextAfter,
+		})
+	}
+}
+
+type LogStatsRequest struct {
+	TargetID  uint32 `json:"target_id" binding:"required"`
+	StartTime int64  `json:"start_time"` // Unix timestamp
+	EndTime   int64  `json:"end_time"`   // Unix timestamp
+}
+
+func (s *Server) getLogStats(c *gin.Context) {
+	if s.es == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":    "Elasticsearch is not enabled",
+			"file_log": logger.GetFileLogStatus(),
+		})
+		return
+	}
+
+	var req LogStatsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 转换时间（默认最近24小时）
+	startTime := time.Unix(req.StartTime, 0)
+	if req.StartTime == 0 {
+		startTime = time.Now().Add(-24 * time.Hour)
+	}
+
+	endTime := time.Unix(req.EndTime, 0)
+	if req.EndTime == 0 {
+		endTime = time.Now()
+	}
+
+	// 获取统计
+	stats, err := s.es.GetLogStats(c.Request.Context(), req.TargetID, startTime, endTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"monitor/internal/logger"
+)
+
+// useFileLog points the file sink at a fresh directory for the test
+func useFileLog(t *testing.T) {
+	t.Helper()
+	if err := logger.InitLogFileLog(t.TempDir()); err != nil {
+		t.Fatalf("init file log: %v", err)
+	}
+	t.Cleanup(func() { logger.InitLogFileLog(logger.DefaultLogDir) })
+}
+
+func TestSearchFileLogs(t *testing.T) {
+	s := newTestServer(t)
+	useFileLog(t)
+	now := time.Now()
+	for i, status := range []string{"up", "down", "down"} {
+		if err := logger.WriteCheckLog(&logger.CheckLogEntry{
+			Timestamp: now.Add(time.Duration(i) * time.Second), TargetID: 7, TargetName: "db", Status: status,
+		}); err != nil {
+			t.Fatalf("write check log: %v", err)
+		}
+	}
+
+	target := uint32(7)
+	var page LogSearchResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v2/logs/search", LogSearchRequest{TargetID: &target, Status: "down", Size: 1}), http.StatusOK, &page)
+	if page.Total != 2 || len(page.Hits) != 1 || page.Hits[0].Status != "down" || page.NextAfter == "" {
+		t.Fatalf("first page = %+v", page)
+	}
+	first := page.Hits[0].Seq
+	decode(t, s.do(t, http.MethodPost, "/api/v2/logs/search", LogSearchRequest{TargetID: &target, Status: "down", Size: 1, After: page.NextAfter}), http.StatusOK, &page)
+	if len(page.Hits) != 1 || page.Hits[0].Seq == first {
+		t.Errorf("second page = %+v, want the other down check", page)
+	}
+
+	decode(t, s.do(t, http.MethodPost, "/api/v1/logs/search", LogSearchRequest{From: 1, After: "x"}), http.StatusBadRequest, nil)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/logs/search", LogSearchRequest{After: "not a cursor"}), http.StatusBadRequest, nil)
+}
+
+// An unwritable log directory disables the file sink: search answers 503 with
+// the sink status instead of an empty page, and health reports degraded
+func TestSearchFileLogsDisabled(t *testing.T) {
+	s := newTestServer(t)
+	blocker := filepath.Join(t.TempDir(), "blocker")
+	os.WriteFile(blocker, nil, 0644)
+	if err := logger.InitLogFileLog(filepath.Join(blocker, "logs")); err == nil {
+		t.Fatal("file log enabled on an unwritable directory")
+	}
+	t.Cleanup(func() { logger.InitLogFileLog(logger.DefaultLogDir) })
+
+	var resp struct {
+		FileLog logger.FileLogStatus `json:"file_log"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/logs/search", LogSearchRequest{}), http.StatusServiceUnavailable, &resp)
+	if resp.FileLog.Enabled || resp.FileLog.Error == "" {
+		t.Errorf("file_log = %+v, want disabled with the error", resp.FileLog)
+	}
+
+	var health struct {
+		Status  string               `json:"status"`
+		FileLog logger.FileLogStatus `json:"file_log"`
+	}
+	decode(t, s.do(t, http.MethodGet, "/health", nil), http.StatusOK, &health)
+	if health.Status != "degraded" || health.FileLog.Enabled {
+		t.Errorf("health = %+v, want degraded", health)
+	}
+}
+
+func TestLogEndpointsWithoutElasticsearch(t *testing.T) {
+	s := newTestServer(t)
+	admin := []string{"Authorization", "Bearer " + testAdminToken}
+
+	decode(t, s.do(t, http.MethodPost, "/api/v1/logs/stats", LogStatsRequest{TargetID: 1}), http.StatusServiceUnavailable, nil)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/elasticsearch/template/apply", nil, admin...), http.StatusBadRequest, nil)
+	decode(t, s.do(t, http.MethodGet, "/api/v1/logs/purge/missing", nil, admin...), http.StatusNotFound, nil)
+
+	// The maintenance endpoints need the admin token
+	decode(t, s.do(t, http.MethodPost, "/api/v1/elasticsearch/template/apply", nil), http.StatusUnauthorized, nil)
+	decode(t, s.do(t, http.MethodGet, "/api/v1/logs/purge/missing", nil, "Authorization", "Bearer wrong"), http.StatusUnauthorized, nil)
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"monitor/internal/logger"
+	"monitor/internal/models"
+	"monitor/internal/monitor"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// registerMaintenanceRoutes registers maintenance window management
+func (s *Server) registerMaintenanceRoutes(api *gin.RouterGroup) {
+	api.POST("/maintenance/add", s.addMaintenanceWindow)
+	api.POST("/maintenance/list", s.listMaintenanceWindows)
+	api.POST("/maintenance/get", s.getMaintenanceWindow)
+	api.POST("/maintenance/update", s.updateMaintenanceWindow)
+	api.POST("/maintenance/remove", s.removeMaintenanceWindow)
+}
+
+// MaintenanceWindowRequest 维护窗口：从 schedule 的每次触发（或 weekdays 各天的 start_time）开始，
+// 到之后第一个 end_time 结束；end_time 不晚于开始时间时跨过午夜
+type MaintenanceWindowRequest struct {
+	Name      string  `json:"name" binding:"required"`
+	TargetID  *uint32 `json:"target_id"` // 留空表示所有监控
+	Schedule  string  `json:"schedule"`  // cron 表达式，如 "0 2 * * sun"；与 weekdays、start_time 二选一
+	Weekdays  string  `json:"weekdays"`  // 逗号分隔，如 "saturday,sunday"；留空为每天
+	StartTime string  `json:"start_time"`
+	EndTime   string  `json:"end_time" binding:"required"`
+	Timezone  string  `json:"timezone"` // IANA 时区，如 Europe/Berlin；默认服务器本地时区
+	Enabled   *bool   `json:"enabled"`  // 默认 true
+}
+
+// MaintenanceWindowResponse 附带窗口当前是否生效和下一次开始时间
+type MaintenanceWindowResponse struct {
+	models.MaintenanceWindow
+	Active    bool       `json:"active"`
+	NextStart *time.Time `json:"next_start,omitempty"`
+}
+
+func newMaintenanceWindowResponse(m models.MaintenanceWindow, now time.Time) MaintenanceWindowResponse {
+	resp := MaintenanceWindowResponse{MaintenanceWindow: m}
+	w, err := monitor.ParseMaintenanceWindow(m)
+	if err != nil || !m.Enabled {
+		return resp
+	}
+	resp.Active = w.Active(now)
+	if next, ok := w.NextStart(now); ok {
+		resp.NextStart = &next
+	}
+	return resp
+}
+
+// applyMaintenanceWindowRequest 校验请求并写入 m，返回的状态码用于错误响应
+func (s *Server) applyMaintenanceWindowRequest(c *gin.Context, req MaintenanceWindowRequest, m *models.MaintenanceWindow) (int, error) {
+	m.Name = req.Name
+	m.TargetID = req.TargetID
+	m.Schedule = req.Schedule
+	m.Weekdays = req.Weekdays
+	m.StartTime = req.StartTime
+	m.EndTime = req.EndTime
+	m.Timezone = req.Timezone
+	m.Enabled = req.Enabled == nil || *req.Enabled
+	if _, err := monitor.ParseMaintenanceWindow(*m); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	if req.TargetID != nil {
+		var target models.MonitorTarget
+		if err := s.requestDB(c).Select("id").First(&target, *req.TargetID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return http.StatusNotFound, errors.New("Monitor not found")
+			}
+			return http.StatusInternalServerError, errors.New("Failed to load monitor")
+		}
+	}
+	return http.StatusOK, nil
+}
+
+// reloadMaintenanceWindows 让修改立即作用于检查
+func (s *Server) reloadMaintenanceWindows() {
+	if err := s.monitorService.LoadMaintenanceWindows(); err != nil {
+		logger.Warn("Failed to reload maintenance windows", zap.Error(err))
+	}
+}
+
+func (s *Server) addMaintenanceWindow(c *gin.Context) {
+	var req MaintenanceWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var window models.MaintenanceWindow
+	if code, err := s.applyMaintenanceWindowRequest(c, req, &window); err != nil {
+		c.JSON(code, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.requestDB(c).Create(&window).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create maintenance window"})
+		return
+	}
+	s.reloadMaintenanceWindows()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":      window.ID,
+		"message": "Maintenance window created successfully",
+	})
+}
+
+// MaintenanceWindowListRequest 维护窗口列表的过滤条件
+type MaintenanceWindowListRequest struct {
+	TargetID *uint32 `json:"target_id"` // 只列出作用于该监控的窗口，包括作用于所有监控的
+}
+
+func (s *Server) listMaintenanceWindows(c *gin.Context) {
+	var req MaintenanceWindowListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		// 没有请求体时列出全部
+	}
+
+	query := s.requestDB(c).Order("id")
+	if req.TargetID != nil {
+		query = query.Where("target_id = ? OR target_id IS NULL", *req.TargetID)
+	}
+	var windows []models.MaintenanceWindow
+	if err := query.Find(&windows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list maintenance windows"})
+		return
+	}
+
+	now := time.Now()
+	items := make([]MaintenanceWindowResponse, 0, len(windows))
+	for _, w := range windows {
+		items = append(items, newMaintenanceWindowResponse(w, now))
+	}
+	c.JSON(http.StatusOK, gin.H{"windows": items})
+}
+
+func (s *Server) getMaintenanceWindow(c *gin.Context) {
+	var req IDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var window models.MaintenanceWindow
+	if err := s.requestDB(c).First(&window, req.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Maintenance window not found"})
+		return
+	}
+	c.JSON(http.StatusOK, newMaintenanceWindowResponse(window, time.Now()))
+}
+
+func (s *Server) updateMaintenanceWindow(c *gin.Context) {
+	var req struct {
+		IDRequest
+		MaintenanceWindowRequest
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := s.requestDB(c)
+	var window models.MaintenanceWindow
+	if err := db.First(&window, req.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Maintenance window not found"})
+		return
+	}
+	if code, err := s.applyMaintenanceWindowRequest(c, req.MaintenanceWindowRequest, &window); err != nil {
+		c.JSON(code, gin.H{"error": err.Error()})
+		return
+	}
+	if err := db.Save(&window).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update maintenance window"})
+		return
+	}
+	s.reloadMaintenanceWindows()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Maintenance window updated successfully"})
+}
+
+func (s *Server) removeMaintenanceWindow(c *gin.Context) {
+	var req IDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.requestDB(c).Delete(&models.MaintenanceWindow{}, req.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete maintenance window"})
+		return
+	}
+	s.reloadMaintenanceWindows()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Maintenance window deleted successfully"})
+}
@@ -0,0 +1,738 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"monitor/internal/alert"
+	"monitor/internal/models"
+	"monitor/internal/monitor"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// registerMonitorRoutes registers monitor management, manual checks, status, archived responses and certificates
+func (s *Server) registerMonitorRoutes(api *gin.RouterGroup) {
+	// Monitor management - all using POST
+	api.POST("/monitor/add", s.addMonitor)
+	api.POST("/monitor/list", s.listMonitors)
+	api.POST("/monitor/get", s.getMonitor)
+	api.POST("/monitor/update", s.updateMonitor)
+	api.POST("/monitor/remove", s.removeMonitor)
+	api.POST("/monitor/recompute", s.recomputeMonitor)
+	api.POST("/monitor/import", s.importMonitors)
+	api.POST("/monitor/test", s.testMonitor)
+	api.GET("/monitor/types", s.listMonitorTypes)
+
+	// Tags in use, for filtering /monitor/list and /monitor/status/list
+	api.POST("/tag/list", s.listTags)
+
+	// Manual checks
+	api.POST("/monitor/check", s.triggerCheck)
+	api.GET("/monitor/check/status/:token", s.getCheckStatus)
+	api.GET("/monitor/check/events", s.streamCheckEvents)
+
+	// Monitor status - using POST
+	api.POST("/monitor/status/get", s.getMonitorStatus)
+	api.POST("/monitor/status/list", s.listMonitorStatus)
+
+	// Per-day availability for calendar heatmaps
+	api.POST("/monitor/heatmap", s.monitorHeatmap)
+
+	// Response time and status series, downsampled for long ranges
+	api.POST("/monitor/history/series", s.monitorHistorySeries)
+
+	// Full responses archived when a monitor went down
+	api.POST("/monitor/archive/list", s.listResponseArchives)
+	api.POST("/monitor/archive/get", s.getResponseArchive)
+
+	// Certificates presented by TLS checks, grouped by fingerprint
+	api.POST("/certificate/list", s.listCertificates)
+}
+
+func (s *Server) addMonitor(c *gin.Context) {
+	var req AddMonitorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	target, socketWarning, ok := s.monitorFromRequest(c, req)
+	if !ok {
+		return
+	}
+
+	if err := s.monitorService.CheckQuota(1, s.fastCount(target.Interval)); err != nil {
+		respondQuotaError(c, err)
+		return
+	}
+
+	db := s.requestDB(c)
+	if err := db.Create(target).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create monitor"})
+		return
+	}
+	auditScriptMonitor(c, "add", target)
+
+	// Convert model to monitor target
+	monitorTarget, err := ConvertModelToMonitorTarget(*target)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert monitor target"})
+		return
+	}
+
+	if err := s.monitorService.AddTarget(monitorTarget); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add monitor"})
+		return
+	}
+
+	// Trigger immediate check after adding monitor
+	s.monitorService.TriggerCheckAfter(monitorTarget.ID, 500*time.Millisecond) // Small delay to ensure monitor is fully initialized
+
+	response := CreatedResponse{
+		ID:      target.ID,
+		Message: "Monitor created successfully",
+	}
+	if socketWarning != "" {
+		response.Warnings = []string{socketWarning}
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// monitorFromRequest 校验添加监控的请求并转换为数据库模型，失败时已写入错误响应
+func (s *Server) monitorFromRequest(c *gin.Context, req AddMonitorRequest) (*models.MonitorTarget, string, bool) {
+	if !s.requireScriptAdmin(c, req.Type) {
+		return nil, "", false
+	}
+
+	if err := validateMonitorSettings(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+
+	if _, err := monitor.ParseSinks(req.Sinks); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+
+	if _, err := monitor.NormalizeTags(req.Tags); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+
+	if err := checkMonitorGroup(s.requestDB(c), req.GroupID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+
+	if err := monitor.ValidateDependency(s.requestDB(c), 0, req.DependsOnTargetID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+
+	// Convert request to database model
+	target, err := ConvertAddRequestToModel(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert request"})
+		return nil, "", false
+	}
+
+	if target.Interval == 0 {
+		target.Interval = monitor.DefaultInterval
+	}
+
+	if err := monitor.ValidateSSLThresholds(target.SSLWarnDays, target.SSLCriticalDays); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+
+	if err := monitor.ValidateNotes(target.Notes, target.RunbookURL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+
+	if err := monitor.ValidateHTTPAuth(target.Type, target.AuthType, target.AuthUsername, target.AuthPassword, target.AuthToken); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+
+	if err := monitor.ValidateTLSClientConfig(target.Type, target.TLSClientCertPEM, target.TLSClientKeyPEM, target.TLSCACertPEM); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+
+	if err := monitor.ValidateTLSVerification(target.Type, target.TLSSkipVerify, target.TLSPinSHA256, target.TLSCACertPEM); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+
+	if err := monitor.ValidateProxyURL(target.Type, target.ProxyURL, target.UnixSocketPath); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+
+	socketWarning, err := monitor.ValidateUnixSocketPath(target.UnixSocketPath, target.SSLCheck)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+
+	if err := validateDNSProviders(c, target.DNSServers); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+
+	return target, socketWarning, true
+}
+
+// testMonitor 按添加监控的请求体检查一次而不保存，用于保存前试用配置；
+// 结果不写入状态、历史、ES 或文件日志，也不触发告警
+func (s *Server) testMonitor(c *gin.Context) {
+	var req AddMonitorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 保存的监控会跳过无效的状态码，试用时直接报告
+	if err := monitor.ValidateExpectedStatusCodes(req.ExpectedStatusCodes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	target, socketWarning, ok := s.monitorFromRequest(c, req)
+	if !ok {
+		return
+	}
+	monitorTarget, err := ConvertModelToMonitorTarget(*target)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := s.monitorService.DryRunCheck(c.Request.Context(), monitorTarget)
+	switch {
+	case errors.Is(err, monitor.ErrServiceStopped):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"result": result}
+	if socketWarning != "" {
+		response["warnings"] = []string{socketWarning}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (s *Server) listMonitors(c *gin.Context) {
+	// 请求体可以为空，表示不过滤
+	var req ListMonitorsRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tags, err := monitor.NormalizeTags(req.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := s.requestDB(c)
+	if req.Type != "" {
+		db = db.Where("type = ?", req.Type)
+	}
+	if req.Enabled != nil {
+		db = db.Where("enabled = ?", *req.Enabled)
+	}
+	configErrors := s.monitorService.ConfigErrors()
+	if req.ConfigError != nil {
+		ids := make([]uint32, 0, len(configErrors))
+		for id := range configErrors {
+			ids = append(ids, id)
+		}
+		switch {
+		case *req.ConfigError:
+			db = db.Where("id IN ?", append(ids, 0)) // 0 保证 IN 列表非空
+		case len(ids) > 0:
+			db = db.Where("id NOT IN ?", ids)
+		}
+	}
+
+	var targets []models.MonitorTarget
+	if err := db.Find(&targets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list monitors"})
+		return
+	}
+	if len(tags) > 0 {
+		matched := targets[:0]
+		for _, target := range targets {
+			if monitor.HasTags(target.Tags, tags) {
+				matched = append(matched, target)
+			}
+		}
+		targets = matched
+	}
+
+	if isV2(c) {
+		c.JSON(http.StatusOK, ListMonitorsResponse{Targets: newMonitorResponses(targets, configErrors)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"targets": targets})
+}
+
+// listTags 返回所有监控用到的标签及带有该标签的监控数
+func (s *Server) listTags(c *gin.Context) {
+	var stored []string
+	if err := s.requestDB(c).Model(&models.MonitorTarget{}).Where("tags <> ''").Pluck("tags", &stored).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tags"})
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, value := range stored {
+		tags, _ := monitor.ParseTags(value)
+		for _, tag := range tags {
+			counts[tag]++
+		}
+	}
+	result := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		result = append(result, TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Tag < result[j].Tag })
+	c.JSON(http.StatusOK, ListTagsResponse{Tags: result})
+}
+
+func (s *Server) getMonitor(c *gin.Context) {
+	var req IDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := s.requestDB(c)
+
+	var target models.MonitorTarget
+	if err := db.First(&target, req.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Monitor not found"})
+		return
+	}
+
+	// 告警覆盖情况：哪些规则、渠道会在目标故障时发出通知
+	alerting, err := s.alertService.TargetCoverage(target.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load alert rules"})
+		return
+	}
+
+	// 实际写入的目的地：目标设置与全局启用的目的地取交集
+	sinks, err := monitor.ParseSinks(target.Sinks)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 配置检查中与该监控有关的结果，包括它的告警规则
+	lintConfig, err := monitor.LoadLintConfig(db, target.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load configuration"})
+		return
+	}
+	lint := monitor.LintTarget(monitor.Lint(lintConfig), target.ID)
+
+	if isV2(c) {
+		c.JSON(http.StatusOK, MonitorDetailResponse{newMonitorResponse(target, s.monitorService.ConfigErrors()), alerting, s.monitorService.ResolveSinks(sinks), lint})
+		return
+	}
+	c.JSON(http.StatusOK, struct {
+		models.MonitorTarget
+		Alerting       []alert.RuleCoverage  `json:"alerting"`
+		EffectiveSinks []monitor.SinkState   `json:"effective_sinks"`
+		Lint           []monitor.LintFinding `json:"lint"`
+	}{target, alerting, s.monitorService.ResolveSinks(sinks), lint})
+}
+
+func (s *Server) updateMonitor(c *gin.Context) {
+	var req UpdateMonitorRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := s.requestDB(c)
+
+	var target models.MonitorTarget
+	if err := db.First(&target, req.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Monitor not found"})
+		return
+	}
+
+	before := target
+
+	if !s.requireScriptAdmin(c, target.Type, req.Type) {
+		return
+	}
+
+	if err := monitor.ValidateTypeChange(target.Type, req.Type); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validateMonitorSettings(req.AddMonitorRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := monitor.ParseSinks(req.Sinks); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := monitor.NormalizeTags(req.Tags); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := checkMonitorGroup(s.requestDB(c), req.GroupID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 依赖链不能回到这个监控
+	if err := monitor.ValidateDependency(db, req.ID, req.DependsOnTargetID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Update model from request
+	if err := UpdateModelFromRequest(&target, req.AddMonitorRequest); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update monitor"})
+		return
+	}
+
+	if err := monitor.ValidateSSLThresholds(target.SSLWarnDays, target.SSLCriticalDays); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := monitor.ValidateNotes(target.Notes, target.RunbookURL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 在合并保留的密码、令牌和私钥之后校验
+	if err := monitor.ValidateHTTPAuth(target.Type, target.AuthType, target.AuthUsername, target.AuthPassword, target.AuthToken); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := monitor.ValidateTLSClientConfig(target.Type, target.TLSClientCertPEM, target.TLSClientKeyPEM, target.TLSCACertPEM); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := monitor.ValidateTLSVerification(target.Type, target.TLSSkipVerify, target.TLSPinSHA256, target.TLSCACertPEM); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := monitor.ValidateProxyURL(target.Type, target.ProxyURL, target.UnixSocketPath); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	socketWarning, err := monitor.ValidateUnixSocketPath(target.UnixSocketPath, target.SSLCheck)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validateDNSProviders(c, target.DNSServers); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 改成更短的检查间隔时计入 max_fast_targets
+	if err := s.monitorService.CheckQuota(0, s.fastCount(target.Interval)-s.fastCount(before.Interval)); err != nil {
+		respondQuotaError(c, err)
+		return
+	}
+
+	if err := db.Save(&target).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update monitor"})
+		return
+	}
+	auditScriptMonitor(c, "update", &target)
+
+	if err := s.reloadTarget(before, target); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert monitor target"})
+		return
+	}
+
+	response := gin.H{"message": "Monitor updated successfully"}
+	if socketWarning != "" {
+		response["warnings"] = []string{socketWarning}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// reloadTarget 只在检查用到的字段变化时重启运行中的目标；
+// 只修改备注、处理手册链接或写入目的地时不打断正在进行的检查
+func (s *Server) reloadTarget(before, after models.MonitorTarget) error {
+	monitorTarget, err := ConvertModelToMonitorTarget(after)
+	if err != nil {
+		return err
+	}
+
+	// 写入目的地直接生效，下一次检查结果即按新设置写入
+	s.monitorService.SetSinks(after.ID, monitorTarget.Sinks)
+
+	// 状态列表带有名称和地址；未启用的目标不会经过 ReplaceTarget
+	s.monitorService.InvalidateStatus()
+
+	if previous, err := ConvertModelToMonitorTarget(before); err == nil {
+		previous.Sinks = monitorTarget.Sinks
+		if reflect.DeepEqual(previous, monitorTarget) {
+			return nil
+		}
+	}
+	// 替换时停止旧的调度，不会为同一目标留下两个定时器
+	s.monitorService.ReplaceTarget(monitorTarget)
+	return nil
+}
+
+func (s *Server) removeMonitor(c *gin.Context) {
+	var req IDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := s.requestDB(c)
+
+	// Start transaction
+	tx := db.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+
+	// Delete related status records
+	if err := tx.Where("target_id = ?", req.ID).Delete(&models.MonitorStatus{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete monitor status"})
+		return
+	}
+
+	// Delete related history records
+	if err := tx.Where("target_id = ?", req.ID).Delete(&models.MonitorHistory{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete monitor history"})
+		return
+	}
+
+	// Delete its hourly and daily rollups
+	if err := tx.Where("target_id = ?", req.ID).Delete(&models.MonitorHistoryHourly{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete monitor history rollups"})
+		return
+	}
+	if err := tx.Where("target_id = ?", req.ID).Delete(&models.MonitorHistoryDaily{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete monitor history rollups"})
+		return
+	}
+
+	// Delete the archived responses of its down transitions
+	if err := tx.Where("target_id = ?", req.ID).Delete(&models.ResponseArchive{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete response archives"})
+		return
+	}
+
+	// Delete its certificate associations; the certificates stay in the inventory
+	if err := tx.Where("target_id = ?", req.ID).Delete(&models.CertificateTarget{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete certificate associations"})
+		return
+	}
+
+	// Delete its embed tokens, they cannot read anything else
+	if err := tx.Where("target_id = ?", req.ID).Delete(&models.APIToken{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete api tokens"})
+		return
+	}
+
+	// Delete its own maintenance windows; the ones for all targets stay
+	if err := tx.Where("target_id = ?", req.ID).Delete(&models.MaintenanceWindow{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete maintenance windows"})
+		return
+	}
+
+	// Monitors that depend on it no longer have a dependency
+	if err := tx.Model(&models.MonitorTarget{}).Where("depends_on_target_id = ?", req.ID).Update("depends_on_target_id", nil).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear monitor dependencies"})
+		return
+	}
+
+	// Delete the monitor target
+	if err := tx.Delete(&models.MonitorTarget{}, req.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete monitor"})
+		return
+	}
+
+	// Commit transaction
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	// Remove from monitoring service
+	s.monitorService.RemoveTarget(req.ID)
+	s.reloadMaintenanceWindows()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Monitor deleted successfully"})
+}
+
+type RecomputeRequest struct {
+	ID  uint32 `json:"id"`
+	All bool   `json:"all"`
+}
+
+// recomputeMonitor rebuilds uptime and status of one or all targets from history
+func (s *Server) recomputeMonitor(c *gin.Context) {
+	var req RecomputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !req.All && req.ID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Either id or all is required"})
+		return
+	}
+
+	var reports []*monitor.RepairReport
+	if req.All {
+		var err error
+		reports, err = s.monitorService.RecomputeAll()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to recompute monitors"})
+			return
+		}
+	} else {
+		db := s.requestDB(c)
+		var target models.MonitorTarget
+		if err := db.First(&target, req.ID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Monitor not found"})
+			return
+		}
+
+		report, err := s.monitorService.RecomputeTarget(req.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to recompute monitor"})
+			return
+		}
+		reports = []*monitor.RepairReport{report}
+	}
+	monitor.LogRepairs(reports, zap.String("source", "api"), zap.String("client_ip", c.ClientIP()))
+
+	c.JSON(http.StatusOK, gin.H{
+		"repaired": reports,
+		"message":  "Monitor status recomputed successfully",
+	})
+}
+
+func (s *Server) getMonitorStatus(c *gin.Context) {
+	var req GetStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	window := monitor.DefaultPercentileWindow
+	if req.WindowHours > 0 {
+		window = time.Duration(req.WindowHours) * time.Hour
+	}
+
+	// 版本在查询之前读取，见 StatusVersion
+	etag := statusETag(s.monitorService.StatusVersion(), statusScope(c, fmt.Sprintf("t%d.w%d", req.ID, window/time.Hour)))
+	if notModified(c, etag) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	status, err := s.monitorService.GetStatus(ctx, req.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Status not found"})
+		return
+	}
+	now := time.Now()
+	if status.ResponseTimes, err = s.monitorService.ResponsePercentiles(ctx, req.ID, now.Add(-window), now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute response time percentiles"})
+		return
+	}
+
+	if isV2(c) {
+		c.JSON(http.StatusOK, newStatusResponse(*status))
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+func (s *Server) listMonitorStatus(c *gin.Context) {
+	var req ListStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		// If binding fails, continue without filters (backward compatibility)
+	}
+
+	limit := 0
+	if req.Limit != nil {
+		limit = *req.Limit
+	}
+	tags, err := monitor.NormalizeTags(req.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Pollers send If-None-Match and get 304 until a status changes; the
+	// version is read before the query, see StatusVersion
+	scope := fmt.Sprintf("all.l%d", limit)
+	if req.TargetID != nil {
+		scope = fmt.Sprintf("t%d.l%d", *req.TargetID, limit)
+	}
+	if len(tags) > 0 {
+		scope += ".tags=" + strings.Join(tags, ",")
+	}
+	if notModified(c, statusETag(s.monitorService.StatusVersion(), statusScope(c, scope))) {
+		return
+	}
+
+	// Without a target filter this returns the latest status per target
+	statuses, err := s.monitorService.QueryStatus(c.Request.Context(), req.TargetID, tags, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list monitor status"})
+		return
+	}
+
+	if isV2(c) {
+		c.JSON(http.StatusOK, ListStatusResponse{Statuses: newStatusResponses(statuses)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"statuses": statuses})
+}
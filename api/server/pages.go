@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerPageRoutes serves the frontend pages and their static assets, without rate limiting
+func (s *Server) registerPageRoutes(router *gin.Engine) {
+	router.Static("/static", "./web/static")
+
+	// Load HTML template
+	router.LoadHTMLFiles(
+		"./web/templates/base.html",
+		"./web/templates/index.html",
+		"./web/templates/pages/dashboard.html",
+		"./web/templates/pages/logs.html",
+		"./web/templates/pages/alerts.html",
+		"./web/templates/pages/settings.html",
+	)
+
+	router.GET("/", func(c *gin.Context) {
+		c.Redirect(http.StatusFound, "/dashboard")
+	})
+	router.GET("/dashboard", s.dashboardPage)
+	router.GET("/logs", s.logsPage)
+	router.GET("/alerts", s.alertsPage)
+	router.GET("/settings", s.settingsPage)
+}
+
+// Frontend page handlers
+func (s *Server) dashboardPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "index.html", gin.H{
+		"Title":     "监控列表 - Monitor Dashboard",
+		"ActiveTab": "monitors",
+	})
+}
+
+func (s *Server) logsPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "index.html", gin.H{
+		"Title":     "日志查询 - Monitor Dashboard",
+		"ActiveTab": "logs",
+	})
+}
+
+func (s *Server) alertsPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "index.html", gin.H{
+		"Title":     "告警管理 - Monitor Dashboard",
+		"ActiveTab": "alerts",
+	})
+}
+
+func (s *Server) settingsPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "index.html", gin.H{
+		"Title":     "系统设置 - Monitor Dashboard",
+		"ActiveTab": "settings",
+	})
+}
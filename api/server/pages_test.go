@@ -0,0 +1,28 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPages(t *testing.T) {
+	s := newTestServer(t)
+
+	w := s.do(t, http.MethodGet, "/", nil)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/dashboard" {
+		t.Errorf("/ = %d to %q, want a redirect to /dashboard", w.Code, w.Header().Get("Location"))
+	}
+
+	// Every tab serves the same single page app
+	for _, path := range []string{"/dashboard", "/logs", "/alerts", "/settings"} {
+		w := s.do(t, http.MethodGet, path, nil)
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+			t.Errorf("%s = %d %s", path, w.Code, w.Header().Get("Content-Type"))
+			continue
+		}
+		if !strings.Contains(w.Body.String(), "<title>Monitor Dashboard</title>") {
+			t.Errorf("%s did not render index.html", path)
+		}
+	}
+}
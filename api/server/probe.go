@@ -0,0 +1,262 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"monitor/internal/config"
+	"monitor/internal/logger"
+	"monitor/internal/monitor"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Prober 执行 blackbox_exporter 风格的按需探测：不保存结果、不触发告警，
+// 用自己的并发上限而不是监控的检查队列
+type Prober struct {
+	modules map[string]AddMonitorRequest // 模块名 -> 请求模板，address 在探测时填入
+	timeout time.Duration
+	slots   chan struct{}
+	policy  *monitor.NetworkPolicy
+}
+
+// NewProber 按监控类型目录校验每个模块并创建 Prober
+func NewProber(cfg config.ProbeConfig) (*Prober, error) {
+	policy, err := monitor.NewNetworkPolicy(cfg.AllowPrivate, cfg.AllowCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Prober{
+		modules: make(map[string]AddMonitorRequest, len(cfg.Modules)),
+		timeout: time.Duration(cfg.Timeout) * time.Second,
+		slots:   make(chan struct{}, cfg.MaxConcurrent),
+		policy:  policy,
+	}
+	for name, module := range cfg.Modules {
+		req, err := probeRequest(name, module)
+		if err != nil {
+			return nil, fmt.Errorf("probe module %s: %w", name, err)
+		}
+		p.modules[name] = req
+	}
+	return p, nil
+}
+
+// probeRequest 把模块参数转换成添加监控的请求，用同样的规则校验
+func probeRequest(name string, module config.ProbeModule) (AddMonitorRequest, error) {
+	settings := make(map[string]interface{}, len(module.Settings)+3)
+	for key, value := range module.Settings {
+		settings[key] = value
+	}
+	settings["name"] = "probe:" + name
+	settings["type"] = module.Type
+	settings["address"] = "probe.invalid" // 占位，探测时替换为 target
+
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		return AddMonitorRequest{}, err
+	}
+	var req AddMonitorRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return AddMonitorRequest{}, err
+	}
+	// 端口可以由 target 的 host:port 给出，校验模块时先用占位值
+	check := req
+	if check.Port == 0 {
+		check.Port = 1
+	}
+	if err := validateMonitorSettings(check); err != nil {
+		return AddMonitorRequest{}, err
+	}
+	// 重定向的目的地址不经过网络策略检查
+	if req.FollowRedirects {
+		return AddMonitorRequest{}, fmt.Errorf("follow_redirects is not supported for probes")
+	}
+	// 证书链用于计算 probe_ssl_earliest_cert_expiry
+	req.SSLGetChain = true
+	return req, nil
+}
+
+// probe 处理 GET /probe?target=...&module=...，返回 Prometheus 文本格式的指标
+func (s *Server) probe(c *gin.Context) {
+	target := strings.TrimSpace(c.Query("target"))
+	moduleName := c.Query("module")
+	if target == "" {
+		c.String(http.StatusBadRequest, "target parameter is missing\n")
+		return
+	}
+	req, ok := s.prober.modules[moduleName]
+	if !ok {
+		c.String(http.StatusBadRequest, fmt.Sprintf("unknown module %q\n", moduleName))
+		return
+	}
+
+	// 与 blackbox_exporter 一样，留出余量在 Prometheus 放弃抓取之前返回
+	timeout := s.prober.timeout
+	if scrape, err := strconv.ParseFloat(c.GetHeader("X-Prometheus-Scrape-Timeout-Seconds"), 64); err == nil && scrape > 0 {
+		if d := time.Duration((scrape - 0.5) * float64(time.Second)); d > 0 && d < timeout {
+			timeout = d
+		}
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	monitorTarget, host, err := probeTarget(req, target)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error()+"\n")
+		return
+	}
+	// DNS 探测只向模块配置的 DNS 服务器发查询，不连接 target
+	if monitorTarget.Type != "dns" {
+		if err := s.prober.policy.CheckHost(ctx, host); err != nil {
+			c.String(http.StatusForbidden, err.Error()+"\n")
+			return
+		}
+		// 检查连接时会重新解析，结果可能不同（DNS 重绑定），连接时按实际地址再检查一次
+		monitorTarget.NetworkPolicy = s.prober.policy
+	}
+
+	select {
+	case s.prober.slots <- struct{}{}:
+	default:
+		c.String(http.StatusServiceUnavailable, "too many concurrent probes\n")
+		return
+	}
+
+	start := time.Now()
+	result := s.prober.run(ctx, monitorTarget)
+	duration := time.Since(start)
+
+	logger.Debug("Probe completed",
+		zap.String("target", target),
+		zap.String("module", moduleName),
+		zap.String("status", result.Status),
+		zap.Duration("duration", duration),
+	)
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(probeMetrics(monitorTarget.Type, result, duration)))
+}
+
+// run 执行检查，超时后不再等待；检查结束前一直占用并发名额
+func (p *Prober) run(ctx context.Context, target *monitor.MonitorTarget) *monitor.CheckResult {
+	done := make(chan *monitor.CheckResult, 1)
+	go func() {
+		defer func() { <-p.slots }()
+
+		checker, err := monitor.NewChecker(target.Type)
+		if err != nil {
+			done <- &monitor.CheckResult{Status: "down", Message: err.Error()}
+			return
+		}
+		result, err := checker.Check(ctx, target)
+		if err != nil || result == nil {
+			result = &monitor.CheckResult{Status: "down", Message: fmt.Sprintf("check failed: %v", err)}
+		}
+		done <- result
+	}()
+
+	select {
+	case result := <-done:
+		return result
+	case <-ctx.Done():
+		return &monitor.CheckResult{Status: "down", Message: "probe timed out"}
+	}
+}
+
+// probeTarget 用模块模板和 target 生成检查目标，返回要经过网络策略检查的主机名。
+// http/https 的 target 可以是完整 URL；其他类型接受 host 或 host:port。
+func probeTarget(req AddMonitorRequest, target string) (*monitor.MonitorTarget, string, error) {
+	// /probe 不需要令牌，不能用来执行本机程序
+	if req.Type == monitor.TypeScript {
+		return nil, "", fmt.Errorf("script modules are not supported by /probe")
+	}
+	req.Address = target
+	host := target
+
+	if strings.Contains(target, "://") {
+		u, err := neturl.Parse(target)
+		if err != nil || u.Hostname() == "" {
+			return nil, "", fmt.Errorf("invalid target %q", target)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, "", fmt.Errorf("unsupported scheme %q", u.Scheme)
+		}
+		host = u.Hostname()
+	} else if h, port, err := net.SplitHostPort(target); err == nil {
+		host = h
+		if req.Type != "http" && req.Type != "https" {
+			n, err := strconv.Atoi(port)
+			if err != nil || n < 1 || n > 65535 {
+				return nil, "", fmt.Errorf("invalid port in target %q", target)
+			}
+			req.Address = h
+			req.Port = int32(n)
+		}
+	}
+	if err := validateMonitorSettings(req); err != nil {
+		return nil, "", err
+	}
+
+	model, err := ConvertAddRequestToModel(req)
+	if err != nil {
+		return nil, "", err
+	}
+	monitorTarget, err := ConvertModelToMonitorTarget(*model)
+	if err != nil {
+		return nil, "", err
+	}
+	return monitorTarget, host, nil
+}
+
+// probeMetrics 按 blackbox_exporter 的指标名输出结果；证书快过期（warning/critical）仍算成功
+func probeMetrics(typ string, result *monitor.CheckResult, duration time.Duration) string {
+	var b strings.Builder
+	gauge := func(name, help string, value float64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", name, help, name, name, strconv.FormatFloat(value, 'g', -1, 64))
+	}
+
+	success := 0.0
+	if result.Status != "down" {
+		success = 1
+	}
+	gauge("probe_success", "Displays whether or not the probe was a success", success)
+	gauge("probe_duration_seconds", "Returns how long the probe took to complete in seconds", duration.Seconds())
+
+	if typ == "http" || typ == "https" {
+		gauge("probe_http_status_code", "Response HTTP status code", float64(result.Response.StatusCode))
+	}
+	if expiry, ok := earliestCertExpiry(result); ok {
+		gauge("probe_ssl_earliest_cert_expiry", "Returns last SSL chain expiry in unixtime", float64(expiry.Unix()))
+	}
+	return b.String()
+}
+
+// earliestCertExpiry 返回证书链中最早的到期时间
+func earliestCertExpiry(result *monitor.CheckResult) (time.Time, bool) {
+	var earliest time.Time
+	consider := func(value interface{}) {
+		s, _ := value.(string)
+		if t, err := time.Parse(time.RFC3339, s); err == nil && (earliest.IsZero() || t.Before(earliest)) {
+			earliest = t
+		}
+	}
+
+	if chain, ok := result.Data["certificate_chain"].([]map[string]interface{}); ok {
+		for _, cert := range chain {
+			consider(cert["not_after"])
+		}
+	}
+	if notAfter, ok := result.Response.Headers["not_after"]; ok {
+		consider(notAfter)
+	}
+	return earliest, !earliest.IsZero()
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"monitor/internal/config"
+	"monitor/internal/monitor"
+)
+
+func withProbe(cfg *config.Config) {
+	cfg.Probe.Enabled = true
+	cfg.Probe.AllowCIDRs = []string{"127.0.0.1"}
+	cfg.Probe.Modules = map[string]config.ProbeModule{
+		"tcp_connect": {Type: "tcp"},
+		"http_2xx":    {Type: "http", Settings: map[string]interface{}{"expected_status_codes": "200"}},
+	}
+}
+
+func (s *Server) probeMetrics(t *testing.T, query url.Values, status int) string {
+	t.Helper()
+	w := s.do(t, http.MethodGet, "/probe?"+query.Encode(), nil)
+	if w.Code != status {
+		t.Fatalf("probe %s = %d %q, want %d", query.Encode(), w.Code, w.Body.String(), status)
+	}
+	return w.Body.String()
+}
+
+func TestProbe(t *testing.T) {
+	s := newTestServer(t, withProbe)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer web.Close()
+
+	body := s.probeMetrics(t, url.Values{"module": {"tcp_connect"}, "target": {ln.Addr().String()}}, http.StatusOK)
+	if !strings.Contains(body, "\nprobe_success 1\n") || !strings.Contains(body, "# TYPE probe_duration_seconds gauge") {
+		t.Errorf("tcp probe metrics:\n%s", body)
+	}
+
+	body = s.probeMetrics(t, url.Values{"module": {"http_2xx"}, "target": {web.URL}}, http.StatusOK)
+	if !strings.Contains(body, "\nprobe_success 1\n") || !strings.Contains(body, "\nprobe_http_status_code 200\n") {
+		t.Errorf("http probe metrics:\n%s", body)
+	}
+
+	// A failed check is still a 200 scrape
+	closed := ln.Addr().String()
+	ln.Close()
+	body = s.probeMetrics(t, url.Values{"module": {"tcp_connect"}, "target": {closed}}, http.StatusOK)
+	if !strings.Contains(body, "\nprobe_success 0\n") {
+		t.Errorf("closed port metrics:\n%s", body)
+	}
+
+	s.probeMetrics(t, url.Values{"module": {"tcp_connect"}}, http.StatusBadRequest)
+	s.probeMetrics(t, url.Values{"module": {"icmp"}, "target": {"127.0.0.1"}}, http.StatusBadRequest)
+	s.probeMetrics(t, url.Values{"module": {"tcp_connect"}, "target": {"127.0.0.1:70000"}}, http.StatusBadRequest)
+	s.probeMetrics(t, url.Values{"module": {"http_2xx"}, "target": {"ftp://127.0.0.1/"}}, http.StatusBadRequest)
+	// Only 127.0.0.1 is allowed, other private addresses are not
+	s.probeMetrics(t, url.Values{"module": {"tcp_connect"}, "target": {"10.0.0.1:22"}}, http.StatusForbidden)
+	s.probeMetrics(t, url.Values{"module": {"http_2xx"}, "target": {"http://192.168.1.1/"}}, http.StatusForbidden)
+}
+
+func TestProbeDisabled(t *testing.T) {
+	s := newTestServer(t)
+	if w := s.do(t, http.MethodGet, "/probe?module=tcp_connect&target=127.0.0.1:1", nil); w.Code == http.StatusOK {
+		t.Errorf("/probe answered %d with probing disabled", w.Code)
+	}
+}
+
+func TestNewProberRejectsModules(t *testing.T) {
+	for name, module := range map[string]config.ProbeModule{
+		"unknown type":     {Type: "gopher"},
+		"invalid setting":  {Type: "http", Settings: map[string]interface{}{"http_method": "FETCH"}},
+		"follow redirects": {Type: "http", Settings: map[string]interface{}{"follow_redirects": true}},
+	} {
+		if _, err := NewProber(config.ProbeConfig{MaxConcurrent: 1, Modules: map[string]config.ProbeModule{"m": module}}); err == nil {
+			t.Errorf("%s: module accepted", name)
+		}
+	}
+	if _, err := NewProber(config.ProbeConfig{AllowCIDRs: []string{"10.0.0.0/33"}}); err == nil {
+		t.Error("invalid allowed CIDR accepted")
+	}
+}
+
+func TestProbeMetricsCertExpiry(t *testing.T) {
+	earliest := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	result := &monitor.CheckResult{Status: "warning", Data: map[string]interface{}{
+		"certificate_chain": []map[string]interface{}{
+			{"not_after": earliest.Add(48 * time.Hour).Format(time.RFC3339)},
+			{"not_after": earliest.Format(time.RFC3339)},
+		},
+	}}
+	body := probeMetrics("ssl", result, time.Second)
+	// A certificate about to expire is a successful probe
+	if !strings.Contains(body, "\nprobe_success 1\n") || strings.Contains(body, "probe_http_status_code") ||
+		!strings.Contains(body, "\nprobe_ssl_earliest_cert_expiry 1.7775936e+09\n") {
+		t.Errorf("ssl metrics:\n%s", body)
+	}
+}
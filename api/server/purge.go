@@ -0,0 +1,271 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"monitor/internal/elasticsearch"
+	"monitor/internal/logger"
+	"monitor/internal/monitor"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Log purge job states
+const (
+	PurgeJobRunning  = "running"
+	PurgeJobFinished = "finished"
+	PurgeJobFailed   = "failed"
+)
+
+// maxPurgeJobs 保留的清除任务数，超出后丢弃最早结束的
+const maxPurgeJobs = 50
+
+// PurgeRequest 从保留的日志中删除或脱敏匹配的条目
+type PurgeRequest struct {
+	TargetID  *int       `json:"target_id"`
+	StartTime *time.Time `json:"start_time"`
+	EndTime   *time.Time `json:"end_time"`
+	Pattern   string     `json:"pattern"`   // Go 正则，匹配目标名称、地址、消息、请求和响应中的任意文本
+	FileMode  string     `json:"file_mode"` // 文件日志的处理方式：remove（默认）删除整行，redact 只替换匹配的文本
+	Confirm   bool       `json:"confirm"`   // pattern 为空或能匹配空串时必须设置，表示确认删除范围内的所有条目
+}
+
+// PurgeJob 清除任务的状态和各存储的统计
+type PurgeJob struct {
+	ID         string                     `json:"id"`
+	State      string                     `json:"state"`
+	Request    PurgeRequest               `json:"request"`
+	StartedAt  time.Time                  `json:"started_at"`
+	FinishedAt *time.Time                 `json:"finished_at,omitempty"`
+	Files      *logger.FilePurgeResult    `json:"files,omitempty"`
+	Archives   int64                      `json:"archives_deleted"`        // 响应存档即使在 redact 模式下也整条删除
+	ES         *elasticsearch.PurgeResult `json:"elasticsearch,omitempty"` // ES 未启用时为空
+	Error      string                     `json:"error,omitempty"`
+}
+
+// purgeJobs 内存中的清除任务，重启后丢失；同一时间只运行一个
+type purgeJobs struct {
+	mu   sync.Mutex
+	jobs map[string]*PurgeJob
+	// running 正在运行的任务 ID
+	running string
+}
+
+func newPurgeJobs() *purgeJobs {
+	return &purgeJobs{jobs: make(map[string]*PurgeJob)}
+}
+
+// start 登记新任务；已有任务在运行时返回该任务和 false
+func (p *purgeJobs) start(req PurgeRequest) (PurgeJob, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.running != "" {
+		return *p.jobs[p.running], false
+	}
+	p.evict()
+
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	job := &PurgeJob{
+		ID:        hex.EncodeToString(buf),
+		State:     PurgeJobRunning,
+		Request:   req,
+		StartedAt: time.Now(),
+	}
+	p.jobs[job.ID] = job
+	p.running = job.ID
+	return *job, true
+}
+
+func (p *purgeJobs) update(id string, fn func(job *PurgeJob)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if job, ok := p.jobs[id]; ok {
+		fn(job)
+		if job.FinishedAt != nil && p.running == id {
+			p.running = ""
+		}
+	}
+}
+
+func (p *purgeJobs) get(id string) (PurgeJob, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	job, ok := p.jobs[id]
+	if !ok {
+		return PurgeJob{}, false
+	}
+	copied := *job
+	if job.Files != nil {
+		files := *job.Files
+		copied.Files = &files
+	}
+	if job.ES != nil {
+		es := *job.ES
+		copied.ES = &es
+	}
+	return copied, true
+}
+
+// evict 丢弃最早结束的任务直到有空位；调用时持有 p.mu
+func (p *purgeJobs) evict() {
+	for len(p.jobs) >= maxPurgeJobs {
+		var oldest *PurgeJob
+		for _, job := range p.jobs {
+			if job.FinishedAt != nil && (oldest == nil || job.FinishedAt.Before(*oldest.FinishedAt)) {
+				oldest = job
+			}
+		}
+		if oldest == nil {
+			return
+		}
+		delete(p.jobs, oldest.ID)
+	}
+}
+
+// purgeLogs 校验条件后在后台清除文件日志和 ES 中匹配的条目，返回 202 和任务状态地址
+func (s *Server) purgeLogs(c *gin.Context) {
+	var req PurgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch req.FileMode {
+	case "":
+		req.FileMode = "remove"
+	case "remove", "redact":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file_mode must be remove or redact"})
+		return
+	}
+	if req.StartTime != nil && req.EndTime != nil && req.EndTime.Before(*req.StartTime) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_time must not be before start_time"})
+		return
+	}
+
+	filter := &logger.PurgeFilter{TargetID: req.TargetID, StartTime: req.StartTime, EndTime: req.EndTime}
+	if req.Pattern != "" {
+		re, err := regexp.Compile(req.Pattern)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pattern: " + err.Error()})
+			return
+		}
+		filter.Pattern = re
+	}
+	// 能匹配空串的正则匹配所有条目
+	matchesAll := filter.Pattern == nil || filter.Pattern.MatchString("")
+	if matchesAll && req.FileMode == "redact" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Redacting requires a pattern that does not match the empty string"})
+		return
+	}
+	if matchesAll && !req.Confirm {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Pattern matches every entry in range; set confirm to true to delete them all"})
+		return
+	}
+
+	job, started := s.purges.start(req)
+	if !started {
+		c.JSON(http.StatusConflict, gin.H{"error": "Another purge is running", "job": job})
+		return
+	}
+
+	// 没有单独的审计日志，清除操作以 warn 级别记录。正则本身可能含有要删除的个人数据，只记录摘要
+	patternSum := sha256.Sum256([]byte(req.Pattern))
+	fields := []zap.Field{
+		zap.String("job_id", job.ID),
+		zap.String("client_ip", c.ClientIP()),
+		zap.String("pattern_sha256", hex.EncodeToString(patternSum[:])),
+		zap.String("file_mode", req.FileMode),
+		zap.Bool("confirm", req.Confirm),
+	}
+	if req.TargetID != nil {
+		fields = append(fields, zap.Int("target_id", *req.TargetID))
+	}
+	if req.StartTime != nil {
+		fields = append(fields, zap.Time("start_time", *req.StartTime))
+	}
+	if req.EndTime != nil {
+		fields = append(fields, zap.Time("end_time", *req.EndTime))
+	}
+	logger.Warn("Log purge started", fields...)
+	go s.runPurge(job.ID, filter, req.FileMode == "redact")
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"job":        job,
+		"status_url": fmt.Sprintf("/api/v%d/logs/purge/%s", c.GetInt(apiVersionKey), job.ID),
+	})
+}
+
+// runPurge 依次处理文件日志、响应存档和 ES；一个存储失败时其他存储的统计仍然保留
+func (s *Server) runPurge(id string, filter *logger.PurgeFilter, redact bool) {
+	ctx := context.Background()
+	var errs []string
+
+	files, err := logger.PurgeCheckLogs(ctx, filter, redact)
+	if err != nil {
+		errs = append(errs, "files: "+err.Error())
+	}
+	s.purges.update(id, func(job *PurgeJob) { job.Files = &files })
+
+	archives, err := monitor.PurgeResponseArchives(ctx, filter)
+	if err != nil {
+		errs = append(errs, "response archives: "+err.Error())
+	}
+	s.purges.update(id, func(job *PurgeJob) { job.Archives = archives })
+
+	var es *elasticsearch.PurgeResult
+	if s.es != nil {
+		result, err := s.es.PurgeLogs(ctx, filter)
+		if err != nil {
+			errs = append(errs, "elasticsearch: "+err.Error())
+		}
+		es = &result
+	}
+
+	now := time.Now()
+	s.purges.update(id, func(job *PurgeJob) {
+		job.ES = es
+		job.FinishedAt = &now
+		job.State = PurgeJobFinished
+		if len(errs) > 0 {
+			job.State = PurgeJobFailed
+			job.Error = strings.Join(errs, "; ")
+		}
+	})
+
+	fields := []zap.Field{
+		zap.String("job_id", id),
+		zap.Int("files_rewritten", files.FilesRewritten),
+		zap.Int("file_entries_removed", files.Removed),
+		zap.Int("file_entries_redacted", files.Redacted),
+		zap.Strings("file_errors", files.Errors),
+		zap.Int64("archives_deleted", archives),
+		zap.Strings("errors", errs),
+	}
+	if es != nil {
+		fields = append(fields, zap.Int64("es_deleted", es.Deleted))
+	}
+	logger.Warn("Log purge finished", fields...)
+}
+
+// getPurgeJob 返回清除任务的状态
+func (s *Server) getPurgeJob(c *gin.Context) {
+	job, ok := s.purges.get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Purge job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
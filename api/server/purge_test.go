@@ -0,0 +1,96 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"monitor/internal/logger"
+)
+
+// A pattern that matches every entry needs confirm, and can't be used to redact
+func TestPurgeLogsMatchAllGuard(t *testing.T) {
+	s := newTestServer(t)
+	admin := []string{"Authorization", "Bearer " + testAdminToken}
+
+	for _, req := range []PurgeRequest{
+		{},
+		{Pattern: ".*"},
+		{Pattern: "x*"},
+		{Pattern: ".*", FileMode: "redact", Confirm: true},
+		{FileMode: "redact", Confirm: true},
+		{Pattern: "(", Confirm: true},
+	} {
+		w := s.do(t, http.MethodPost, "/api/v1/logs/purge", req, admin...)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("purge %+v: status %d, want 400; body %s", req, w.Code, w.Body.String())
+		}
+	}
+	if s.purges.running != "" || len(s.purges.jobs) != 0 {
+		t.Errorf("a refused purge started a job: %+v", s.purges.jobs)
+	}
+}
+
+// Only one purge runs at a time; a finished job reports what it removed
+func TestPurgeLogsJob(t *testing.T) {
+	s := newTestServer(t)
+	useFileLog(t)
+	admin := []string{"Authorization", "Bearer " + testAdminToken}
+	now := time.Now()
+	for i, message := range []string{"token secret-abc", "ok"} {
+		if err := logger.WriteCheckLog(&logger.CheckLogEntry{Timestamp: now, TargetID: i + 1, Status: "up", Message: message}); err != nil {
+			t.Fatalf("write check log: %v", err)
+		}
+	}
+
+	running, _ := s.purges.start(PurgeRequest{Pattern: "other"})
+	var conflict struct {
+		Job PurgeJob `json:"job"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/logs/purge", PurgeRequest{Pattern: `secret-\w+`}, admin...), http.StatusConflict, &conflict)
+	if conflict.Job.ID != running.ID || conflict.Job.State != PurgeJobRunning {
+		t.Errorf("conflict job = %+v, want the running job %s", conflict.Job, running.ID)
+	}
+	s.purges.update(running.ID, func(job *PurgeJob) {
+		finished := time.Now()
+		job.FinishedAt = &finished
+		job.State = PurgeJobFinished
+	})
+
+	var accepted struct {
+		Job       PurgeJob `json:"job"`
+		StatusURL string   `json:"status_url"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/logs/purge", PurgeRequest{Pattern: `secret-\w+`}, admin...), http.StatusAccepted, &accepted)
+	if accepted.Job.State != PurgeJobRunning || accepted.StatusURL != "/api/v1/logs/purge/"+accepted.Job.ID {
+		t.Fatalf("accepted = %+v", accepted)
+	}
+
+	var job PurgeJob
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		decode(t, s.do(t, http.MethodGet, accepted.StatusURL, nil, admin...), http.StatusOK, &job)
+		if job.State != PurgeJobRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("purge did not finish within 5s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if job.State != PurgeJobFinished || job.FinishedAt == nil || job.Error != "" || job.ES != nil {
+		t.Errorf("finished job = %+v, want finished without ES", job)
+	}
+	if job.Files == nil || job.Files.FilesRewritten != 1 || job.Files.Removed != 1 || job.Files.Redacted != 0 {
+		t.Errorf("file result = %+v, want 1 entry removed", job.Files)
+	}
+
+	entries, err := logger.ReadCheckLogDay(now)
+	if err != nil {
+		t.Fatalf("ReadCheckLogDay: %v", err)
+	}
+	if len(entries) != 1 || strings.Contains(entries[0].Message, "secret") {
+		t.Errorf("entries after purge = %+v, want only the clean one", entries)
+	}
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"monitor/internal/monitor"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getQuota 返回监控数量的使用情况和上限
+func (s *Server) getQuota(c *gin.Context) {
+	usage, err := s.monitorService.Quota()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count monitors"})
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
+// respondQuotaError 超出上限时返回 422 以及上限和当前用量，其他错误返回 500
+func respondQuotaError(c *gin.Context, err error) {
+	var quotaErr *monitor.QuotaError
+	if errors.As(err, &quotaErr) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "quota": quotaErr})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check monitor quota"})
+}
+
+// fastCount 检查间隔计入 max_fast_targets 时返回 1
+func (s *Server) fastCount(interval int64) int64 {
+	if s.monitorService.IsFast(interval) {
+		return 1
+	}
+	return 0
+}
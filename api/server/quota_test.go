@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"monitor/internal/monitor"
+)
+
+func TestMonitorQuota(t *testing.T) {
+	s := newTestServer(t)
+	s.monitorService.SetLimits(monitor.Limits{MaxTargets: 2, MaxFastTargets: 1, FastInterval: 15})
+
+	fast := tcpMonitor
+	fast.Interval = 5
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/add", fast), http.StatusCreated, nil)
+
+	// A second fast monitor is over max_fast_targets
+	var rejected struct {
+		Error string             `json:"error"`
+		Quota monitor.QuotaError `json:"quota"`
+	}
+	fast.Name = "db2"
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/add", fast), http.StatusUnprocessableEntity, &rejected)
+	if rejected.Quota != (monitor.QuotaError{Limit: "max_fast_targets", Max: 1, Current: 1, Requested: 1}) {
+		t.Errorf("quota error %+v", rejected.Quota)
+	}
+
+	slow := tcpMonitor
+	slow.Name = "db2"
+	var created CreatedResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/add", slow), http.StatusCreated, &created)
+
+	// Speeding up an existing monitor counts against the fast limit too
+	update := UpdateMonitorRequest{IDRequest: IDRequest{ID: created.ID}, AddMonitorRequest: slow}
+	update.Interval = 5
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/update", update), http.StatusUnprocessableEntity, nil)
+
+	slow.Name = "db3"
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/add", slow), http.StatusUnprocessableEntity, &rejected)
+	if rejected.Quota.Limit != "max_targets" {
+		t.Errorf("quota error %+v, want max_targets", rejected.Quota)
+	}
+
+	var usage monitor.QuotaUsage
+	decode(t, s.do(t, http.MethodGet, "/api/v1/quota", nil), http.StatusOK, &usage)
+	if usage.Targets != 2 || usage.FastTargets != 1 || usage.MaxTargets != 2 {
+		t.Errorf("usage %+v", usage)
+	}
+}
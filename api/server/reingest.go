@@ -0,0 +1,128 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"monitor/internal/elasticsearch"
+	"monitor/internal/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// reingestBatchSize 每次批量写入的条数
+	reingestBatchSize = 500
+	// maxReingestDays 一次导入的最大天数
+	maxReingestDays = 31
+)
+
+// ReingestRequest 重新导入文件日志的日期范围（含首尾，本机时区的 YYYY-MM-DD）
+type ReingestRequest struct {
+	StartDate        string `json:"start_date" binding:"required"`
+	EndDate          string `json:"end_date" binding:"required"`
+	IncludeSynthetic bool   `json:"include_synthetic"` // 默认跳过故障注入的合成结果，它们可能已被清除
+}
+
+// ReingestResponse 导入结果；已在 ES 中的检查计入 conflicts，可以重复执行
+type ReingestResponse struct {
+	Days    int `json:"days"`
+	Files   int `json:"files"`
+	Read    int `json:"read"`
+	Skipped int `json:"skipped"` // 跳过的合成结果
+	elasticsearch.BulkResult
+	FileErrors []string `json:"file_errors,omitempty"`
+}
+
+// reingestLogs 把日期范围内的文件日志批量写入 ES，文档 ID 由检查决定，重复导入不会产生重复文档
+func (s *Server) reingestLogs(c *gin.Context) {
+	var req ReingestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if s.es == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Elasticsearch is not enabled"})
+		return
+	}
+
+	start, err := time.ParseInLocation("2006-01-02", req.StartDate, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date, expected YYYY-MM-DD"})
+		return
+	}
+	end, err := time.ParseInLocation("2006-01-02", req.EndDate, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date, expected YYYY-MM-DD"})
+		return
+	}
+	if end.Before(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must not be before start_date"})
+		return
+	}
+	if end.Sub(start) >= maxReingestDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Date range must not exceed 31 days"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var resp ReingestResponse
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		resp.Days++
+		entries, err := logger.ReadCheckLogDay(day)
+		if err != nil {
+			// 读到的部分仍然导入
+			resp.FileErrors = append(resp.FileErrors, day.Format("2006-01-02")+": "+err.Error())
+		}
+		if entries == nil {
+			continue
+		}
+		resp.Files++
+
+		batch := make([]*elasticsearch.LogEntry, 0, reingestBatchSize)
+		flush := func() error {
+			result, err := s.es.BulkCreate(ctx, batch)
+			if err != nil {
+				return err
+			}
+			resp.BulkResult.Add(result)
+			batch = batch[:0]
+			return nil
+		}
+		for _, entry := range entries {
+			resp.Read++
+			if entry.Synthetic && !req.IncludeSynthetic {
+				resp.Skipped++
+				continue
+			}
+			batch = append(batch, elasticsearch.LogEntryFromCheckLog(entry))
+			if len(batch) == reingestBatchSize {
+				if err := flush(); err != nil {
+					respondReingestError(c, resp, err)
+					return
+				}
+			}
+		}
+		if err := flush(); err != nil {
+			respondReingestError(c, resp, err)
+			return
+		}
+	}
+
+	logger.Info("File logs re-ingested into Elasticsearch",
+		zap.String("start_date", req.StartDate),
+		zap.String("end_date", req.EndDate),
+		zap.Int("read", resp.Read),
+		zap.Int("indexed", resp.Indexed),
+		zap.Int("conflicts", resp.Conflicts),
+		zap.Int("failed", resp.Failed),
+	)
+	c.JSON(http.StatusOK, resp)
+}
+
+// respondReingestError 批量请求本身失败时停止，并返回已完成部分的统计；重新执行不会重复写入
+func respondReingestError(c *gin.Context, resp ReingestResponse, err error) {
+	logger.Warn("Re-ingesting file logs failed", zap.Error(err))
+	c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "progress": resp})
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"monitor/internal/config"
+	"monitor/internal/elasticsearch"
+	"monitor/internal/logger"
+)
+
+// bulkES answers the info request and creates bulk documents by ID, once
+type bulkES struct {
+	mu  sync.Mutex
+	ids map[string]int // "<index>/<id>" -> create attempts
+}
+
+func (f *bulkES) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Path != "/_bulk" {
+		json.NewEncoder(w).Encode(map[string]interface{}{"version": map[string]string{"number": "8.11.0"}})
+		return
+	}
+	var items []interface{}
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 1<<20), 1<<20)
+	for scanner.Scan() {
+		var action map[string]struct {
+			Index string `json:"_index"`
+			ID    string `json:"_id"`
+		}
+		json.Unmarshal(scanner.Bytes(), &action)
+		scanner.Scan()
+		meta := action["create"]
+		key := meta.Index + "/" + meta.ID
+		item := map[string]interface{}{"_id": meta.ID, "status": http.StatusCreated}
+		if f.ids[key] > 0 {
+			item["status"] = http.StatusConflict
+			item["error"] = map[string]string{"type": "version_conflict_engine_exception"}
+		}
+		f.ids[key]++
+		items = append(items, map[string]interface{}{"create": item})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+}
+
+func (f *bulkES) documents() map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ids := make(map[string]int, len(f.ids))
+	for k, v := range f.ids {
+		ids[k] = v
+	}
+	return ids
+}
+
+// Re-ingesting a day twice creates each check once under the same IDs; a
+// malformed line and a partial line in the file are skipped, and the entry
+// written after the partial line is still imported
+func TestReingestLogsTwice(t *testing.T) {
+	s := newTestServer(t)
+	useFileLog(t)
+	fake := &bulkES{ids: make(map[string]int)}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	es, err := elasticsearch.NewClient(config.ElasticsearchConfig{Enabled: true, Addresses: []string{srv.URL}, IndexPrefix: "monitor-logs"})
+	if err != nil {
+		t.Fatalf("elasticsearch client: %v", err)
+	}
+	s.es = es
+
+	// Checks at noon, so that none falls on another day than the file
+	y, m, d := time.Now().Date()
+	now := time.Date(y, m, d, 12, 0, 0, 0, time.Local)
+	write := func(entry *logger.CheckLogEntry) {
+		t.Helper()
+		if err := logger.WriteCheckLog(entry); err != nil {
+			t.Fatalf("write check log: %v", err)
+		}
+	}
+	write(&logger.CheckLogEntry{Timestamp: now, TargetID: 1, Status: "up", Nonce: "a"})
+	write(&logger.CheckLogEntry{Timestamp: now, TargetID: 2, Status: "down", Nonce: "b"})
+	file, err := os.OpenFile(filepath.Join(logger.GetFileLogStatus().Dir, "check-"+now.Format("2006-01-02")+".jsonl"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("{not json}\n" + `{"target_id": 3, "status": "u`)
+	file.Close()
+	write(&logger.CheckLogEntry{Timestamp: now.Add(time.Second), TargetID: 1, Status: "up", Nonce: "c"})
+
+	admin := []string{"Authorization", "Bearer " + testAdminToken}
+	day := now.Format("2006-01-02")
+	var first, second ReingestResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v1/logs/reingest", ReingestRequest{StartDate: day, EndDate: day}, admin...), http.StatusOK, &first)
+	decode(t, s.do(t, http.MethodPost, "/api/v1/logs/reingest", ReingestRequest{StartDate: day, EndDate: day}, admin...), http.StatusOK, &second)
+	if first.Read != 3 || first.Indexed != 3 || first.Conflicts != 0 || first.Failed != 0 || len(first.FileErrors) != 0 {
+		t.Errorf("first import %+v, want 3 read and indexed", first)
+	}
+	if second.Read != 3 || second.Indexed != 0 || second.Conflicts != 3 || second.Failed != 0 {
+		t.Errorf("second import %+v, want 3 conflicts", second)
+	}
+
+	docs := fake.documents()
+	if len(docs) != 3 {
+		t.Errorf("%d documents, want 3: %v", len(docs), docs)
+	}
+	for _, check := range []struct {
+		target uint32
+		at     time.Time
+		nonce  string
+	}{{1, now, "a"}, {2, now, "b"}, {1, now.Add(time.Second), "c"}} {
+		key := "monitor-logs-" + check.at.Format("2006.01.02") + "/" + elasticsearch.DocumentID(check.target, check.at, check.nonce)
+		if docs[key] != 2 {
+			t.Errorf("document %s created %d times, want 2 attempts under the same ID", key, docs[key])
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"net/http"
+
+	"monitor/internal/alert"
+	"monitor/internal/monitor"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getRuntimeStats 返回 goroutine 数、堆内存以及各内存缓冲区的大小和上限
+func (s *Server) getRuntimeStats(c *gin.Context) {
+	stats := s.monitorService.RuntimeStats()
+	cache := s.alertService.CacheStats()
+	stats.Buffers = append(stats.Buffers,
+		lookupBuffer("alert_channel_cache", cache.Channels),
+		lookupBuffer("alert_rule_cache", cache.Rules),
+		monitor.BufferStats{Name: "dns_provider_cache", Len: monitor.GetDNSProviderCacheStats().Entries},
+	)
+	c.JSON(http.StatusOK, stats)
+}
+
+func lookupBuffer(name string, stats alert.LookupStats) monitor.BufferStats {
+	return monitor.BufferStats{Name: name, Len: stats.Entries, Cap: stats.Cap}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"monitor/internal/schedule"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPreviewCount = 10
+	maxPreviewCount     = 100
+)
+
+// SchedulePreviewRequest 预览一个 cron 表达式
+type SchedulePreviewRequest struct {
+	Expression string `json:"expression" binding:"required"`
+	Timezone   string `json:"timezone"` // IANA 时区，如 Europe/Berlin；默认服务器本地时区
+	Feature    string `json:"feature"`  // 按该功能的最小间隔检查，如 digest；留空不检查
+	Count      int    `json:"count"`    // 返回的次数，默认 10，最多 100
+}
+
+// previewSchedule 返回表达式接下来的触发时间和警告（从不触发、比功能允许的更频繁、夏令时调整等）
+func (s *Server) previewSchedule(c *gin.Context) {
+	var req SchedulePreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc := time.Local
+	if req.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(req.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown timezone %q", req.Timezone)})
+			return
+		}
+	}
+
+	var minInterval time.Duration
+	if req.Feature != "" {
+		var ok bool
+		if minInterval, ok = schedule.MinIntervals[req.Feature]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown feature %q, expected one of: %s", req.Feature, strings.Join(schedule.Features(), ", "))})
+			return
+		}
+	}
+
+	count := req.Count
+	if count == 0 {
+		count = defaultPreviewCount
+	}
+	if count < 1 || count > maxPreviewCount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "count must be between 1 and 100"})
+		return
+	}
+
+	sched, err := schedule.Parse(req.Expression)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, schedule.NewPreview(sched, time.Now().In(loc), count, minInterval))
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"monitor/internal/schedule"
+)
+
+func TestPreviewSchedule(t *testing.T) {
+	s := newTestServer(t)
+
+	var preview schedule.Preview
+	decode(t, s.do(t, http.MethodPost, "/api/v1/schedule/preview", SchedulePreviewRequest{Expression: "0 9 * * mon", Timezone: "Asia/Shanghai", Count: 3}), http.StatusOK, &preview)
+	if preview.Timezone != "Asia/Shanghai" || len(preview.Next) != 3 || len(preview.Warnings) != 0 {
+		t.Errorf("preview %+v", preview)
+	}
+	for _, next := range preview.Next {
+		if _, offset := next.Zone(); offset != 8*3600 || next.Hour() != 9 {
+			t.Errorf("occurrence %v, want 09:00 in +08:00", next)
+		}
+	}
+
+	decode(t, s.do(t, http.MethodPost, "/api/v1/schedule/preview", SchedulePreviewRequest{Expression: "*/5 * * * *", Feature: "digest"}), http.StatusOK, &preview)
+	if len(preview.Next) != defaultPreviewCount || len(preview.Warnings) != 1 {
+		t.Errorf("digest preview %+v, want the minimum interval warning", preview)
+	}
+
+	for _, req := range []SchedulePreviewRequest{
+		{Expression: "0 9 * *"},
+		{Expression: "0 9 * * *", Timezone: "Mars/Olympus"},
+		{Expression: "0 9 * * *", Feature: "backup"},
+		{Expression: "0 9 * * *", Count: maxPreviewCount + 1},
+		{},
+	} {
+		decode(t, s.do(t, http.MethodPost, "/api/v1/schedule/preview", req), http.StatusBadRequest, nil)
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"monitor/internal/monitor"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// HistorySeriesRequest 一个监控在时间范围内的响应时间和状态序列，用于图表
+type HistorySeriesRequest struct {
+	TargetID    uint32    `json:"target_id" binding:"required"`
+	From        time.Time `json:"from" binding:"required"` // RFC 3339
+	To          time.Time `json:"to"`                      // 默认当前时间
+	Granularity string    `json:"granularity"`             // auto（默认，按范围选择）、raw、hourly 或 daily
+}
+
+// HistorySeriesResponse 点按时间排序，最早的在前
+type HistorySeriesResponse struct {
+	TargetID    uint32                `json:"target_id"`
+	Granularity string                `json:"granularity"`
+	From        time.Time             `json:"from"`
+	To          time.Time             `json:"to"`
+	Points      []monitor.SeriesPoint `json:"points"`
+}
+
+// monitorHistorySeries 按时间范围选择原始历史、小时汇总或天汇总
+func (s *Server) monitorHistorySeries(c *gin.Context) {
+	var req HistorySeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.To.IsZero() {
+		req.To = time.Now()
+	}
+	granularity, err := monitor.SeriesGranularity(req.Granularity, req.From, req.To)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	points, err := s.monitorService.HistorySeries(c.Request.Context(), req.TargetID, req.From, req.To, granularity)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Monitor not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load history series"})
+		return
+	}
+	c.JSON(http.StatusOK, HistorySeriesResponse{
+		TargetID:    req.TargetID,
+		Granularity: granularity,
+		From:        req.From,
+		To:          req.To,
+		Points:      points,
+	})
+}
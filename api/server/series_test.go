@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"monitor/internal/models"
+	"monitor/internal/monitor"
+)
+
+func TestMonitorHistorySeries(t *testing.T) {
+	s := newTestServer(t)
+	target := createTarget(t, models.MonitorTarget{Name: "db", Interval: 60})
+	now := time.Now().UTC()
+	for i, status := range []string{"up", "down", "up"} {
+		s.db.Create(&models.MonitorHistory{TargetID: target.ID, Status: status, ResponseTime: 10, CheckedAt: now.Add(time.Duration(i-3) * time.Minute)})
+	}
+	if err := s.monitorService.RollupHistory(context.Background()); err != nil {
+		t.Fatalf("RollupHistory: %v", err)
+	}
+
+	var series HistorySeriesResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/history/series", HistorySeriesRequest{TargetID: target.ID, From: now.Add(-time.Hour)}), http.StatusOK, &series)
+	if series.Granularity != monitor.GranularityRaw || len(series.Points) != 3 || series.Points[1].Status != "down" || series.To.IsZero() {
+		t.Errorf("auto series %+v, want 3 raw points", series)
+	}
+	// A week is hourly
+	series = HistorySeriesResponse{}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/history/series", HistorySeriesRequest{TargetID: target.ID, From: now.Add(-7 * 24 * time.Hour), To: now.Add(time.Minute)}), http.StatusOK, &series)
+	var checks int64
+	for _, p := range series.Points {
+		checks += p.Checks
+	}
+	if series.Granularity != monitor.GranularityHourly || checks != 3 {
+		t.Errorf("hourly series %+v, want 3 checks", series)
+	}
+
+	for name, req := range map[string]HistorySeriesRequest{
+		"no from":        {TargetID: target.ID},
+		"reversed":       {TargetID: target.ID, From: now, To: now.Add(-time.Hour)},
+		"granularity":    {TargetID: target.ID, From: now.Add(-time.Hour), Granularity: "minutely"},
+		"raw too long":   {TargetID: target.ID, From: now.Add(-30 * 24 * time.Hour), Granularity: monitor.GranularityRaw},
+		"range too long": {TargetID: target.ID, From: now.AddDate(-3, 0, 0)},
+	} {
+		if w := s.do(t, http.MethodPost, "/api/v1/monitor/history/series", req); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", name, w.Code)
+		}
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/history/series", HistorySeriesRequest{TargetID: 99, From: now.Add(-time.Hour)}), http.StatusNotFound, nil)
+
+	// Removing the monitor deletes its rollups
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/remove", IDRequest{ID: target.ID}), http.StatusOK, nil)
+	for _, model := range []interface{}{&models.MonitorHistoryHourly{}, &models.MonitorHistoryDaily{}} {
+		var n int64
+		s.db.Model(model).Where("target_id = ?", target.ID).Count(&n)
+		if n != 0 {
+			t.Errorf("%T: %d rollups left after remove", model, n)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"monitor/internal/alert"
+	"monitor/internal/config"
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+	"monitor/internal/monitor"
+	"monitor/internal/version"
+)
+
+const testAdminToken = "test-admin-token"
+
+// TestMain initializes logger.Log once: handlers log through it, and
+// reassigning it per test races with goroutines of earlier tests
+func TestMain(m *testing.M) {
+	logger.Init("error", "stderr")
+	os.Exit(m.Run())
+}
+
+// newTestServer returns a server on a fresh in-memory database, with
+// testAdminToken as debug.admin_token; configure edits the config first
+func newTestServer(t *testing.T, configure ...func(*config.Config)) *Server {
+	t.Helper()
+	// The page templates are loaded relative to the repository root
+	t.Chdir("../..")
+	if err := database.InitMemoryDB(t.Name()); err != nil {
+		t.Fatalf("InitMemoryDB: %v", err)
+	}
+	db := database.GetDB()
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	service := monitor.NewService(nil, monitor.ServiceOptions{Workers: 1, StartupJitter: time.Hour})
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		service.Stop(ctx)
+	})
+
+	cfg := config.Load()
+	cfg.Alert.Enabled = false
+	cfg.Debug.AdminToken = testAdminToken
+	for _, f := range configure {
+		f(cfg)
+	}
+	return NewServer(service, nil, alert.NewService(), "", cfg)
+}
+
+// do sends a request with an optional JSON body and returns the recorder;
+// header pairs are added to the request
+func (s *Server) do(t *testing.T, method, path string, body interface{}, header ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("marshal request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// decode unmarshals a JSON response into v after checking its status code
+func decode(t *testing.T, w *httptest.ResponseRecorder, wantStatus int, v interface{}) {
+	t.Helper()
+	if w.Code != wantStatus {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, wantStatus, w.Body.String())
+	}
+	if v == nil {
+		return
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode response %q: %v", w.Body.String(), err)
+	}
+}
+
+// createTarget inserts a monitor directly, bypassing the API
+func createTarget(t *testing.T, target models.MonitorTarget) models.MonitorTarget {
+	t.Helper()
+	if target.Type == "" {
+		target.Type, target.Address, target.Port = "tcp", "127.0.0.1", 1
+	}
+	if err := database.GetDB().Create(&target).Error; err != nil {
+		t.Fatalf("create target: %v", err)
+	}
+	return target
+}
+
+func TestVersion(t *testing.T) {
+	s := newTestServer(t)
+	version.SetFeatures(version.Features{Alerting: true})
+	t.Cleanup(func() { version.SetFeatures(version.Features{}) })
+
+	var info version.Info
+	decode(t, s.do(t, http.MethodGet, "/api/v1/version", nil), http.StatusOK, &info)
+	if info != version.Get() || !info.Features.Alerting {
+		t.Errorf("version = %+v, want %+v", info, version.Get())
+	}
+
+	// Only the verbose health check carries the version
+	var health map[string]interface{}
+	decode(t, s.do(t, http.MethodGet, "/health", nil), http.StatusOK, &health)
+	if _, ok := health["version"]; ok {
+		t.Errorf("health = %v, want no version without verbose", health)
+	}
+	decode(t, s.do(t, http.MethodGet, "/health?verbose=1", nil), http.StatusOK, &health)
+	if v, ok := health["version"].(map[string]interface{}); !ok || v["version"] != version.Version {
+		t.Errorf("verbose health = %v, want the version info", health)
+	}
+}
+
+// Every route given a rate limit class exists, and mutating routes that are
+// not listed are limited as writes
+func TestAPIRouteClasses(t *testing.T) {
+	s := newTestServer(t, withDiscovery)
+	registered := make(map[string]bool)
+	for _, route := range s.router.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+	classes := apiRouteClasses()
+	for route := range classes {
+		if !registered[route] {
+			t.Errorf("%s has a rate limit class but is not registered", route)
+		}
+	}
+
+	for _, route := range []string{
+		"POST /api/v1/alert/rule/snooze",
+		"POST /api/v1/alert/rule/snooze/cancel",
+		"PUT /api/v1/config/loglevel",
+		"POST /api/v1/token/create",
+		"POST /api/v1/token/revoke",
+		"POST /api/v1/elasticsearch/reindex",
+		"POST /api/v1/elasticsearch/template/apply",
+		"POST /api/v1/logs/reingest",
+		"POST /api/v2/discovery/scan",
+		"POST /api/v2/discovery/review",
+	} {
+		if !registered[route] {
+			t.Errorf("%s is not registered", route)
+		}
+		if class, ok := classes[route]; ok {
+			t.Errorf("%s is limited as %s, want write", route, class)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"monitor/internal/models"
+)
+
+// clientCertPEM returns a self-signed client certificate and its key
+func clientCertPEM(t *testing.T) (certPEM, keyPEM string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "probe"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+}
+
+// The private key is never returned; an update without it keeps it, and
+// clearing the certificate clears it
+func TestMonitorClientCertificate(t *testing.T) {
+	s := newTestServer(t)
+	certPEM, keyPEM := clientCertPEM(t)
+	req := AddMonitorRequest{Name: "api", Type: "https", Address: "https://127.0.0.1", Interval: 60,
+		TLSClientCertPEM: certPEM, TLSClientKeyPEM: keyPEM}
+
+	bad := req
+	bad.TLSClientKeyPEM = ""
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/add", bad), http.StatusBadRequest, nil)
+	bad.TLSClientCertPEM, bad.TLSCACertPEM = "", "garbage"
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/add", bad), http.StatusBadRequest, nil)
+
+	var created CreatedResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/add", req), http.StatusCreated, &created)
+	for _, version := range []string{"v1", "v2"} {
+		w := s.do(t, http.MethodPost, "/api/"+version+"/monitor/get", IDRequest{ID: created.ID})
+		if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "PRIVATE KEY") {
+			t.Errorf("%s: %d %s", version, w.Code, w.Body.String())
+		}
+	}
+	var detail MonitorDetailResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v2/monitor/get", IDRequest{ID: created.ID}), http.StatusOK, &detail)
+	if detail.TLSClientKeyPEMSet == nil || !*detail.TLSClientKeyPEMSet || detail.TLSClientCertPEM == "" {
+		t.Errorf("v2 tls_client_key_pem_set %v", detail.TLSClientKeyPEMSet)
+	}
+
+	stored := func() models.MonitorTarget {
+		var target models.MonitorTarget
+		s.db.First(&target, created.ID)
+		return target
+	}
+	update := UpdateMonitorRequest{IDRequest: IDRequest{ID: created.ID}, AddMonitorRequest: req}
+	update.TLSClientKeyPEM = ""
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/update", update), http.StatusOK, nil)
+	if target := stored(); target.TLSClientKeyPEM == "" {
+		t.Error("key dropped by an update without one")
+	}
+	update.TLSClientCertPEM = ""
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/update", update), http.StatusOK, nil)
+	if target := stored(); target.TLSClientKeyPEM != "" || target.TLSClientCertPEM != "" {
+		t.Error("key kept after the certificate was cleared")
+	}
+}
+
+// The pin is stored as lowercase hex, and settings that cannot apply are
+// rejected
+func TestMonitorTLSVerification(t *testing.T) {
+	s := newTestServer(t)
+	req := AddMonitorRequest{Name: "api", Type: "https", Address: "https://127.0.0.1", Interval: 60,
+		TLSPinSHA256: strings.TrimSuffix(strings.Repeat("AB:", 32), ":")}
+	var created CreatedResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/add", req), http.StatusCreated, &created)
+	var detail MonitorDetailResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v2/monitor/get", IDRequest{ID: created.ID}), http.StatusOK, &detail)
+	if detail.TLSPinSHA256 != strings.Repeat("ab", 32) || detail.TLSSkipVerify == nil || *detail.TLSSkipVerify {
+		t.Errorf("v2 tls_pin_sha256 %q tls_skip_verify %v", detail.TLSPinSHA256, detail.TLSSkipVerify)
+	}
+
+	bad := req
+	bad.TLSPinSHA256 = "abcd"
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/add", bad), http.StatusBadRequest, nil)
+	bad = req
+	bad.TLSSkipVerify, bad.TLSCACertPEM = true, "CA"
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/add", bad), http.StatusBadRequest, nil)
+	tcp := tcpMonitor
+	tcp.TLSSkipVerify = true
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/add", tcp), http.StatusBadRequest, nil)
+
+	update := UpdateMonitorRequest{IDRequest: IDRequest{ID: created.ID}, AddMonitorRequest: req}
+	update.TLSPinSHA256, update.TLSSkipVerify = "", true
+	decode(t, s.do(t, http.MethodPost, "/api/v1/monitor/update", update), http.StatusOK, nil)
+	var target models.MonitorTarget
+	s.db.First(&target, created.ID)
+	if !target.TLSSkipVerify || target.TLSPinSHA256 != "" {
+		t.Errorf("stored skip %v pin %q after the update", target.TLSSkipVerify, target.TLSPinSHA256)
+	}
+}
@@ -0,0 +1,264 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"monitor/api/middleware"
+	"monitor/internal/models"
+	"monitor/internal/monitor"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// apiTokenPrefix starts every scoped token so that leaked ones are easy to search for
+const apiTokenPrefix = "agt_"
+
+// MaxAPITokenLifetime caps how far in the future a scoped token may expire
+const MaxAPITokenLifetime = 365 * 24 * time.Hour
+
+// registerTokenRoutes 管理只读嵌入令牌，需要管理员令牌
+func (s *Server) registerTokenRoutes(api *gin.RouterGroup) {
+	tokens := api.Group("/token", middleware.AdminToken(s.adminToken()))
+	tokens.POST("/create", s.createAPIToken)
+	tokens.POST("/list", s.listAPITokens)
+	tokens.POST("/revoke", s.revokeAPIToken)
+}
+
+// CreateAPITokenRequest 创建绑定单个监控或一个标签的只读令牌，target_id 和 tag 二选一
+type CreateAPITokenRequest struct {
+	Name      string    `json:"name" binding:"required"`
+	TargetID  uint32    `json:"target_id"`
+	Tag       string    `json:"tag"`                           // 可以读取当前带有这个标签的监控
+	ExpiresAt time.Time `json:"expires_at" binding:"required"` // RFC 3339，最长一年
+}
+
+// APITokenResponse 令牌本身只在创建时返回
+type APITokenResponse struct {
+	ID         uint32     `json:"id"`
+	Name       string     `json:"name"`
+	Token      string     `json:"token,omitempty"`
+	Prefix     string     `json:"prefix"`
+	TargetID   uint32     `json:"target_id,omitempty"`
+	TargetName string     `json:"target_name,omitempty"`
+	Tag        string     `json:"tag,omitempty"`
+	State      string     `json:"state"` // active, expired, revoked
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	UseCount   int64      `json:"use_count"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newAPITokenResponse(t models.APIToken, targetName string, now time.Time) APITokenResponse {
+	state := "active"
+	switch {
+	case t.RevokedAt != nil:
+		state = "revoked"
+	case !now.Before(t.ExpiresAt):
+		state = "expired"
+	}
+	return APITokenResponse{
+		ID:         t.ID,
+		Name:       t.Name,
+		Prefix:     t.Prefix,
+		TargetID:   t.TargetID,
+		TargetName: targetName,
+		Tag:        t.Tag,
+		State:      state,
+		ExpiresAt:  t.ExpiresAt,
+		RevokedAt:  t.RevokedAt,
+		LastUsedAt: t.LastUsedAt,
+		UseCount:   t.UseCount,
+		CreatedBy:  t.CreatedBy,
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
+// hashAPIToken 数据库只保存令牌的 SHA-256
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// createAPIToken 创建令牌，响应中的 token 之后无法再次获取
+func (s *Server) createAPIToken(c *gin.Context) {
+	var req CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now().UTC()
+	if !req.ExpiresAt.After(now) || req.ExpiresAt.Sub(now) > MaxAPITokenLifetime {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("expires_at must be in the future and at most %s from now", MaxAPITokenLifetime)})
+		return
+	}
+
+	if (req.TargetID == 0) == (req.Tag == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "exactly one of target_id and tag is required"})
+		return
+	}
+
+	db := s.requestDB(c)
+	var target models.MonitorTarget
+	var tag string
+	if req.TargetID != 0 {
+		if err := db.First(&target, req.TargetID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Monitor not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load monitor"})
+			return
+		}
+	} else {
+		// 标签不必已被使用，之后加上标签的监控同样可以读取
+		tags, err := monitor.NormalizeTags([]string{req.Tag})
+		if err != nil || len(tags) != 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid tag %q", req.Tag)})
+			return
+		}
+		tag = tags[0]
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	plain := apiTokenPrefix + hex.EncodeToString(secret)
+
+	token := models.APIToken{
+		Name:      req.Name,
+		TokenHash: hashAPIToken(plain),
+		Prefix:    plain[:len(apiTokenPrefix)+8],
+		TargetID:  target.ID,
+		Tag:       tag,
+		ExpiresAt: req.ExpiresAt.UTC(),
+		CreatedBy: c.ClientIP(),
+	}
+	if err := db.Create(&token).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	resp := newAPITokenResponse(token, target.Name, now)
+	resp.Token = plain
+	c.JSON(http.StatusOK, resp)
+}
+
+// APITokenListRequest 令牌列表的过滤条件
+type APITokenListRequest struct {
+	TargetID uint32 `json:"target_id"` // 0 为全部
+	Tag      string `json:"tag"`       // 只列出这个标签的令牌
+}
+
+// listAPITokens 列出令牌及其使用情况，便于清理不再使用的嵌入
+func (s *Server) listAPITokens(c *gin.Context) {
+	var req APITokenListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := s.requestDB(c)
+	query := db.Order("id")
+	if req.TargetID != 0 {
+		query = query.Where("target_id = ?", req.TargetID)
+	}
+	if req.Tag != "" {
+		query = query.Where("tag = ?", strings.ToLower(strings.TrimSpace(req.Tag)))
+	}
+	var tokens []models.APIToken
+	if err := query.Find(&tokens).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tokens"})
+		return
+	}
+
+	targetIDs := make([]uint32, 0, len(tokens))
+	for _, t := range tokens {
+		if t.TargetID != 0 {
+			targetIDs = append(targetIDs, t.TargetID)
+		}
+	}
+	names := make(map[uint32]string)
+	if len(targetIDs) > 0 {
+		var targets []models.MonitorTarget
+		if err := db.Select("id", "name").Where("id IN ?", targetIDs).Find(&targets).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load monitors"})
+			return
+		}
+		for _, t := range targets {
+			names[t.ID] = t.Name
+		}
+	}
+
+	now := time.Now().UTC()
+	items := make([]APITokenResponse, 0, len(tokens))
+	for _, t := range tokens {
+		items = append(items, newAPITokenResponse(t, names[t.TargetID], now))
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": len(items)})
+}
+
+// revokeAPIToken 吊销令牌，之后使用它的请求返回 401
+func (s *Server) revokeAPIToken(c *gin.Context) {
+	var req IDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := s.requestDB(c)
+	var token models.APIToken
+	if err := db.First(&token, req.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load token"})
+		return
+	}
+
+	now := time.Now().UTC()
+	if token.RevokedAt == nil {
+		if err := db.Model(&token).Update("revoked_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+			return
+		}
+		token.RevokedAt = &now
+	}
+	c.JSON(http.StatusOK, newAPITokenResponse(token, "", now))
+}
+
+// resolveAPIToken 返回令牌绑定的监控或标签，并记录一次使用
+func (s *Server) resolveAPIToken(plain string) (middleware.TokenScope, error) {
+	var token models.APIToken
+	if err := s.db.Where("token_hash = ?", hashAPIToken(plain)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return middleware.TokenScope{}, middleware.ErrInvalidToken
+		}
+		return middleware.TokenScope{}, err
+	}
+
+	now := time.Now().UTC()
+	if token.RevokedAt != nil || !now.Before(token.ExpiresAt) {
+		return middleware.TokenScope{}, middleware.ErrInvalidToken
+	}
+	if err := s.db.Model(&models.APIToken{}).Where("id = ?", token.ID).Updates(map[string]interface{}{
+		"last_used_at": now,
+		"use_count":    gorm.Expr("use_count + 1"),
+	}).Error; err != nil {
+		return middleware.TokenScope{}, err
+	}
+	return middleware.TokenScope{TargetID: token.TargetID, Tag: token.Tag}, nil
+}
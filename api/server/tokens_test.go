@@ -0,0 +1,113 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"monitor/internal/models"
+)
+
+func (s *Server) createToken(t *testing.T, req CreateAPITokenRequest) APITokenResponse {
+	t.Helper()
+	var resp APITokenResponse
+	decode(t, s.do(t, http.MethodPost, "/api/v1/token/create", req, "Authorization", "Bearer "+testAdminToken), http.StatusOK, &resp)
+	return resp
+}
+
+func TestCreateAPITokenValidation(t *testing.T) {
+	s := newTestServer(t)
+	target := createTarget(t, models.MonitorTarget{Name: "payments"})
+	expires := time.Now().Add(24 * time.Hour)
+
+	tests := []struct {
+		name   string
+		req    CreateAPITokenRequest
+		header []string
+		want   int
+	}{
+		{"no admin token", CreateAPITokenRequest{Name: "w", TargetID: target.ID, ExpiresAt: expires}, nil, http.StatusUnauthorized},
+		{"target and tag", CreateAPITokenRequest{Name: "w", TargetID: target.ID, Tag: "prod", ExpiresAt: expires}, nil, http.StatusBadRequest},
+		{"neither target nor tag", CreateAPITokenRequest{Name: "w", ExpiresAt: expires}, nil, http.StatusBadRequest},
+		{"invalid tag", CreateAPITokenRequest{Name: "w", Tag: "no spaces", ExpiresAt: expires}, nil, http.StatusBadRequest},
+		{"expires too late", CreateAPITokenRequest{Name: "w", TargetID: target.ID, ExpiresAt: time.Now().Add(2 * MaxAPITokenLifetime)}, nil, http.StatusBadRequest},
+		{"missing target", CreateAPITokenRequest{Name: "w", TargetID: target.ID + 100, ExpiresAt: expires}, nil, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		header := []string{"Authorization", "Bearer " + testAdminToken}
+		if tt.want == http.StatusUnauthorized {
+			header = nil
+		}
+		if w := s.do(t, http.MethodPost, "/api/v1/token/create", tt.req, header...); w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d; body %s", tt.name, w.Code, tt.want, w.Body.String())
+		}
+	}
+}
+
+func TestTargetTokenReadsOnlyItsTarget(t *testing.T) {
+	s := newTestServer(t)
+	target := createTarget(t, models.MonitorTarget{Name: "payments"})
+	other := createTarget(t, models.MonitorTarget{Name: "internal"})
+	token := s.createToken(t, CreateAPITokenRequest{Name: "wiki", TargetID: target.ID, ExpiresAt: time.Now().Add(time.Hour)})
+
+	var status struct {
+		TargetID uint32 `json:"target_id"`
+		Status   string `json:"status"`
+	}
+	decode(t, s.do(t, http.MethodGet, "/embed/status?token="+token.Token, nil), http.StatusOK, &status)
+	if status.TargetID != target.ID || status.Status != "unknown" {
+		t.Errorf("embed status = %+v, want target %d with status unknown", status, target.ID)
+	}
+
+	path := fmt.Sprintf("/embed/status?target_id=%d", other.ID)
+	decode(t, s.do(t, http.MethodGet, path, nil, "Authorization", "Bearer "+token.Token), http.StatusNotFound, nil)
+	decode(t, s.do(t, http.MethodGet, "/embed/status?token=agt_unknown", nil), http.StatusUnauthorized, nil)
+}
+
+func TestTagTokenReadsTaggedTargets(t *testing.T) {
+	s := newTestServer(t)
+	tagged := createTarget(t, models.MonitorTarget{Name: "payments api", Tags: `["prod","team:payments"]`})
+	untagged := createTarget(t, models.MonitorTarget{Name: "search", Tags: `["prod"]`})
+	token := s.createToken(t, CreateAPITokenRequest{Name: "team page", Tag: " Team:Payments ", ExpiresAt: time.Now().Add(time.Hour)})
+	if token.Tag != "team:payments" || token.TargetID != 0 {
+		t.Fatalf("created token scope: tag %q target %d, want the normalized tag only", token.Tag, token.TargetID)
+	}
+
+	read := func(targetID uint32) int {
+		path := fmt.Sprintf("/embed/status?token=%s&target_id=%d", token.Token, targetID)
+		return s.do(t, http.MethodGet, path, nil).Code
+	}
+	if code := read(tagged.ID); code != http.StatusOK {
+		t.Errorf("tagged target: status %d, want 200", code)
+	}
+	if code := read(untagged.ID); code != http.StatusNotFound {
+		t.Errorf("target without the tag: status %d, want 404", code)
+	}
+	decode(t, s.do(t, http.MethodGet, "/embed/status?token="+token.Token, nil), http.StatusBadRequest, nil)
+
+	// Taking the tag away takes the access away
+	if err := s.db.Model(&models.MonitorTarget{}).Where("id = ?", tagged.ID).Update("tags", `["prod"]`).Error; err != nil {
+		t.Fatalf("update tags: %v", err)
+	}
+	if code := read(tagged.ID); code != http.StatusNotFound {
+		t.Errorf("target after removing the tag: status %d, want 404", code)
+	}
+
+	var list struct {
+		Items []APITokenResponse `json:"items"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/api/v1/token/list", APITokenListRequest{Tag: "team:payments"}, "Authorization", "Bearer "+testAdminToken), http.StatusOK, &list)
+	if len(list.Items) != 1 || list.Items[0].UseCount != 4 {
+		t.Errorf("token list = %+v, want the tag token used 4 times", list.Items)
+	}
+}
+
+func TestRevokedTokenIsRejected(t *testing.T) {
+	s := newTestServer(t)
+	target := createTarget(t, models.MonitorTarget{Name: "payments"})
+	token := s.createToken(t, CreateAPITokenRequest{Name: "wiki", TargetID: target.ID, ExpiresAt: time.Now().Add(time.Hour)})
+
+	decode(t, s.do(t, http.MethodPost, "/api/v1/token/revoke", IDRequest{ID: token.ID}, "Authorization", "Bearer "+testAdminToken), http.StatusOK, nil)
+	decode(t, s.do(t, http.MethodGet, "/embed/badge.svg?token="+token.Token, nil), http.StatusUnauthorized, nil)
+}
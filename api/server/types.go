@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"monitor/api/middleware"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+	"monitor/internal/monitor"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// listMonitorTypes 返回已注册的监控类型及其字段、默认值和结果字段，供前端生成表单
+func (s *Server) listMonitorTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"types": monitor.Types()})
+}
+
+// validateMonitorSettings 按监控类型的目录定义校验请求
+func validateMonitorSettings(req AddMonitorRequest) error {
+	raw, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	var settings map[string]interface{}
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return err
+	}
+	if err := monitor.ValidateSettings(req.Type, settings); err != nil {
+		return err
+	}
+	if err := monitor.ValidateTimeout(req.TimeoutSeconds, req.Interval); err != nil {
+		return err
+	}
+	if err := monitor.ValidateRetries(req.RetryCount, req.RetryIntervalSeconds, req.TimeoutSeconds); err != nil {
+		return err
+	}
+	if req.Type == monitor.TypeScript {
+		if err := monitor.ValidateScript(strings.TrimSpace(req.ScriptPath)); err != nil {
+			return err
+		}
+	}
+	if err := monitor.ValidateComparison(req.Type, strings.TrimSpace(req.SecondaryAddress), strings.TrimSpace(req.UnixSocketPath),
+		req.CompareLatencyTolerance, req.CompareBody); err != nil {
+		return err
+	}
+	if err := monitor.ValidateResponseTimeThresholds(req.Type, req.DegradedThresholdMs, req.DownThresholdMs, req.TimeoutSeconds); err != nil {
+		return err
+	}
+	if err := monitor.ValidateDNSExpectation(req.Type, strings.TrimSpace(req.DNSQueryType), req.DNSExpectedValues, req.DNSMatchMode); err != nil {
+		return err
+	}
+	if err := monitor.ValidateTCPExchange(req.Type, req.TCPSendString, req.TCPExpectString, req.TCPUseTLS); err != nil {
+		return err
+	}
+	if err := monitor.ValidateUDPProbe(req.Type, req.UDPPreset, strings.TrimSpace(req.UDPSendPayload), req.UDPPayloadFormat,
+		req.UDPExpectResponse, req.UDPExpectPattern); err != nil {
+		return err
+	}
+	if err := monitor.ValidateExpectedHeaders(req.Type, req.ExpectedHeaders); err != nil {
+		return err
+	}
+	if err := monitor.ValidateBodyAssertions(req.Type, req.BodyMustContain, req.BodyMustNotContain, req.BodyRegex); err != nil {
+		return err
+	}
+	if err := monitor.ValidateJSONAssertion(req.Type, strings.TrimSpace(req.JSONPath), req.JSONExpectedValue, req.JSONOperator); err != nil {
+		return err
+	}
+	if err := monitor.ValidateContentHash(req.Type, req.TrackContentHash, req.ContentHashRegex, req.ContentHashNormalize); err != nil {
+		return err
+	}
+	steps, err := encodeHTTPSteps(req.Steps)
+	if err != nil {
+		return err
+	}
+	if err := monitor.ValidateHTTPSteps(req.Type, steps, strings.TrimSpace(req.SecondaryAddress)); err != nil {
+		return err
+	}
+	_, err = monitor.ParseDNSServers(req.DNSServers)
+	return err
+}
+
+// requireScriptAdmin 添加或修改 script 监控需要管理员令牌，因为它会在本机执行程序。
+// 没有令牌时返回 401 并返回 false。
+func (s *Server) requireScriptAdmin(c *gin.Context, types ...string) bool {
+	script := false
+	for _, typ := range types {
+		script = script || typ == monitor.TypeScript
+	}
+	if !script {
+		return true
+	}
+	if !middleware.HasAdminToken(c, s.adminToken()) {
+		c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "admin token required for script monitors"})
+		return false
+	}
+	return true
+}
+
+// auditScriptMonitor 记录 script 监控的创建和修改，与每次执行的日志一起构成审计记录
+func auditScriptMonitor(c *gin.Context, action string, target *models.MonitorTarget) {
+	if target.Type != monitor.TypeScript {
+		return
+	}
+	logger.Info("Script monitor saved",
+		zap.String("action", action),
+		zap.Uint32("target_id", target.ID),
+		zap.String("script_path", target.ScriptPath),
+		zap.String("script_args", target.ScriptArgs),
+		zap.Bool("enabled", target.Enabled),
+		zap.String("client_ip", c.ClientIP()),
+	)
+}
+
+// validateDNSProviders 检查 dns_servers 中引用的 DNS 服务商是否存在
+func validateDNSProviders(c *gin.Context, dnsServers string) error {
+	refs, err := monitor.ParseDNSServers(dnsServers)
+	if err != nil {
+		return err
+	}
+	_, err = monitor.ResolveDNSServers(c.Request.Context(), refs)
+	return err
+}
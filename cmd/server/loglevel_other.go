@@ -0,0 +1,6 @@
+//go:build !unix
+
+package main
+
+// watchLogLevelSignals 没有 SIGUSR1/SIGUSR2 的平台只能通过 API 修改日志级别
+func watchLogLevelSignals() {}
@@ -0,0 +1,28 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"monitor/internal/logger"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// watchLogLevelSignals SIGUSR1 切换到 debug，SIGUSR2 恢复为配置文件中的级别
+func watchLogLevelSignals() {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for sig := range sigChan {
+			if sig == syscall.SIGUSR1 {
+				logger.SetLevel(zapcore.DebugLevel, 0, "signal")
+			} else {
+				logger.ResetLevel("signal")
+			}
+		}
+	}()
+}
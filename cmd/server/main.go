@@ -1,34 +1,43 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"runtime/debug"
 	"sync"
 	"syscall"
+	"time"
 
 	"monitor/api/server"
+	"monitor/internal/alert"
+	"monitor/internal/archiver"
 	"monitor/internal/config"
 	"monitor/internal/database"
 	"monitor/internal/elasticsearch"
 	"monitor/internal/grpc"
 	"monitor/internal/logger"
 	"monitor/internal/monitor"
+	"monitor/internal/version"
 
 	"go.uber.org/zap"
 )
 
 var (
 	configFile = flag.String("config", "etc/config.yaml", "Path to configuration file")
-	version    = "1.0.0"
 )
 
+// shutdownTimeout 收到退出信号后等待进行中的 HTTP 请求、检查和日志写入的最长时间
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	flag.Parse()
 
 	// 加载配置
 	var cfg *config.Config
+	loadedFrom := "file"
 
 	// 优先从配置文件加载，如果失败则从环境变量加载
 	if _, err := os.Stat(*configFile); err == nil {
@@ -37,10 +46,12 @@ func main() {
 			fmt.Printf("Failed to load config from file: %v\n", err)
 			fmt.Println("Falling back to environment variables...")
 			cfg = config.Load()
+			loadedFrom = "environment"
 		}
 	} else {
 		fmt.Println("Config file not found, loading from environment variables...")
 		cfg = config.Load()
+		loadedFrom = "environment"
 	}
 
 	// 初始化日志系统
@@ -49,11 +60,22 @@ func main() {
 		os.Exit(1)
 	}
 	defer logger.Sync()
+	watchLogLevelSignals()
+
+	// 初始化检查结果文件日志；目录不可写时以停用状态启动并已记录警告，之后自动重试
+	_ = logger.InitLogFileLog(cfg.Logger.FileLogDir)
 
 	logger.Info("Starting Monitor Service",
-		zap.String("version", version),
+		zap.String("version", version.Version),
+		zap.String("git_commit", version.GitCommit),
+		zap.String("build_date", version.BuildDate),
 		zap.String("config_file", *configFile),
 	)
+	// 启动时记录一次生效配置：敏感项已脱敏，每项标注来源（file/env/default）
+	logger.Info("Effective configuration",
+		zap.String("loaded_from", loadedFrom),
+		zap.Any("config", cfg.Effective()),
+	)
 
 	// 初始化数据库
 	if err := database.InitDB(database.Config{
@@ -86,21 +108,154 @@ func main() {
 		logger.Info("Elasticsearch is disabled")
 	}
 
-	// 创建索引模板（如果 ES 启用）
+	// 创建或升级索引模板（如果 ES 启用）
 	if esClient != nil {
-		if err := esClient.CreateIndexTemplate(); err != nil {
-			logger.Warn("Failed to create index template", zap.Error(err))
+		if _, err := esClient.ReconcileIndexTemplate(context.Background()); err != nil {
+			logger.Warn("Failed to reconcile index template", zap.Error(err))
 		}
 	}
 
+	version.SetFeatures(version.Features{
+		Elasticsearch: esClient != nil,
+		Alerting:      cfg.Alert.Enabled,
+		GRPC:          true,
+	})
+
 	// 初始化监控服务
-	monitorService := monitor.NewService(esClient)
+	monitorService := monitor.NewService(esClient, monitor.ServiceOptions{
+		Workers:       cfg.Monitor.Workers,
+		QueueSize:     cfg.Monitor.QueueSize,
+		ESBufferSize:  cfg.Monitor.ESBufferSize,
+		StartupJitter: time.Duration(cfg.Monitor.StartupJitter) * time.Second,
+
+		HistoryBatchSize:     cfg.Monitor.HistoryBatchSize,
+		HistoryFlushInterval: time.Duration(cfg.Monitor.HistoryFlushInterval) * time.Second,
+		UptimeInterval:       time.Duration(cfg.Monitor.UptimeInterval) * time.Second,
+	})
+	redactor, err := monitor.NewRedactor(cfg.Monitor.Redaction.Keys, cfg.Monitor.Redaction.Patterns, cfg.Monitor.Redaction.MaxBodyBytes)
+	if err != nil {
+		logger.Fatal("Invalid redaction config", zap.Error(err))
+	}
+	monitorService.SetRedactor(redactor)
+	monitorService.SetIncludeSyntheticInUptime(cfg.Debug.IncludeSyntheticInUptime)
+	monitorService.SetLimits(monitor.Limits{
+		MaxTargets:     cfg.Monitor.Limits.MaxTargets,
+		MaxFastTargets: cfg.Monitor.Limits.MaxFastTargets,
+		FastInterval:   int64(cfg.Monitor.Limits.FastInterval),
+	})
+	monitor.SetClockSkewPolicy(monitor.ClockSkewPolicy{
+		Threshold: time.Duration(cfg.Monitor.ClockSkew.Threshold) * time.Second,
+		Warn:      cfg.Monitor.ClockSkew.Warn,
+	})
+	monitor.SetResponseBodyPolicy(monitor.ResponseBodyPolicy{
+		MaxStoredBytes:      cfg.Monitor.ResponseBody.MaxStoredBytes,
+		Archive:             cfg.Monitor.ResponseBody.Archive.Enabled,
+		ArchiveMaxBytes:     cfg.Monitor.ResponseBody.Archive.MaxBytes,
+		ArchiveRetention:    time.Duration(cfg.Monitor.ResponseBody.Archive.RetentionDays) * 24 * time.Hour,
+		ArchiveMaxPerTarget: cfg.Monitor.ResponseBody.Archive.MaxPerTarget,
+	})
+	monitor.SetScriptPolicy(monitor.ScriptPolicy{
+		Enabled:        cfg.Monitor.Script.Enabled,
+		AllowedPaths:   cfg.Monitor.Script.AllowedPaths,
+		MaxOutputBytes: cfg.Monitor.Script.MaxOutputBytes,
+	})
+	monitor.SetPingMode(cfg.Monitor.Ping.Mode)
+	if cfg.Debug.FailureInjection {
+		if cfg.Debug.AdminToken == "" {
+			logger.Warn("Failure injection is enabled but debug.admin_token is empty; the debug endpoints will reject every request")
+		} else {
+			logger.Warn("Failure injection endpoints are enabled; do not use this in production")
+		}
+	}
+	// 检查结果保存后交给告警规则；与 HTTP 服务共用同一个告警服务，通过 API 修改规则后立即生效
+	alertService := alert.NewService()
+	if cfg.Alert.Enabled {
+		monitorService.SetAlertHandler(alertOnResult(alertService))
+	}
+	// 状态频繁变化的目标标记为抖动，告警只发一次
+	if flapping := cfg.Alert.Flapping; cfg.Alert.Enabled && flapping.Enabled {
+		monitorService.SetFlappingPolicy(monitor.FlappingPolicy{
+			Enabled:     true,
+			Transitions: flapping.Transitions,
+			Window:      time.Duration(flapping.WindowSeconds) * time.Second,
+			Cooldown:    time.Duration(flapping.CooldownSeconds) * time.Second,
+		})
+	}
+	// 数据库或 ES 不可用时暂存检查结果，恢复后补写；先于检查启动，上次运行留下的结果最先补写
+	if cfg.Monitor.Spool.Enabled {
+		if err := monitorService.StartSpool(context.Background(), monitor.SpoolPolicy{
+			Dir:            cfg.Monitor.Spool.Dir,
+			MaxBytes:       int64(cfg.Monitor.Spool.MaxSizeMB) << 20,
+			ReplayInterval: time.Duration(cfg.Monitor.Spool.ReplayInterval) * time.Second,
+		}); err != nil {
+			logger.Fatal("Failed to open the result spool", zap.Error(err))
+		}
+	}
+	// 维护窗口先于检查加载，窗口内的结果记为 maintenance，不计入可用率也不告警
+	if err := monitorService.StartMaintenance(context.Background()); err != nil {
+		logger.Warn("Failed to load maintenance windows", zap.Error(err))
+	}
 	if err := monitorService.LoadTargetsFromDB(); err != nil {
 		logger.Warn("Failed to load targets from database", zap.Error(err))
-	} else {
-		logger.Info("Monitor targets loaded")
 	}
 
+	// 启动时修复状态数据的一致性
+	reports, err := monitorService.RepairConsistency()
+	monitor.LogRepairs(reports, zap.String("source", "startup"))
+	if err != nil {
+		logger.Warn("Failed to repair monitor status consistency", zap.Error(err))
+	} else if len(reports) > 0 {
+		logger.Info("Monitor status consistency repaired", zap.Int("targets", len(reports)))
+	}
+
+	// 启动证书到期周报
+	if cfg.Alert.Enabled && cfg.Alert.Digest.Enabled {
+		digestJob, err := alert.NewDigestJob(cfg.Alert.Digest)
+		if err != nil {
+			logger.Fatal("Invalid certificate digest config", zap.Error(err))
+		}
+		digestJob.Start(context.Background())
+	}
+
+	// 启动检查历史的归档和保留期清理；启用归档时只删除已验证导出的记录
+	retention := monitor.HistoryRetention{
+		Retention: time.Duration(cfg.Monitor.HistoryRetentionDays) * 24 * time.Hour,
+		Interval:  time.Duration(cfg.Monitor.HistorySweepInterval) * time.Second,
+	}
+	if cfg.HistoryArchive.Enabled {
+		var alerts *alert.Service
+		if cfg.Alert.Enabled {
+			alerts = alert.NewService()
+		}
+		historyArchiver, err := archiver.New(cfg.HistoryArchive, retention.Retention, alerts)
+		if err != nil {
+			logger.Fatal("Invalid history archive config", zap.Error(err))
+		}
+		historyArchiver.Start(context.Background())
+		retention.DeletableThrough = archiver.ExportedThrough
+	}
+	monitorService.StartHistoryRetention(context.Background(), retention)
+
+	// 长时间范围图表使用的小时和天汇总，每 10 分钟重新计算最近的小时
+	monitorService.StartHistoryRollup(context.Background(), 10*time.Minute)
+
+	// 内存软上限和占用告警
+	if limit := cfg.Monitor.Memory.SoftLimitMB; limit > 0 {
+		debug.SetMemoryLimit(int64(limit) << 20)
+		logger.Info("Soft memory limit set", zap.Int("soft_limit_mb", limit))
+	}
+	var notifyMemory func(title, message string)
+	if cfg.Alert.Enabled {
+		alerts, channelID := alert.NewService(), cfg.Monitor.Memory.AlertChannelID
+		notifyMemory = func(title, message string) {
+			alerts.NotifyOperators(channelID, title, message)
+		}
+	}
+	monitorService.StartMemoryWatch(context.Background(), monitor.MemoryPolicy{
+		Threshold: uint64(cfg.Monitor.Memory.AlertThresholdMB) << 20,
+		Interval:  time.Duration(cfg.Monitor.Memory.CheckInterval) * time.Second,
+	}, notifyMemory)
+
 	// 创建等待组
 	var wg sync.WaitGroup
 
@@ -108,17 +263,11 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	// 启动HTTP服务器
-	httpAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.HTTPPort)
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		httpServer := server.NewServer(monitorService, esClient, *configFile, cfg)
-		logger.Info("Starting HTTP server", zap.String("address", httpAddr))
-		if err := httpServer.Run(httpAddr); err != nil {
-			logger.Fatal("HTTP server failed", zap.Error(err))
-		}
-	}()
+	// 启动HTTP服务器：管理接口，以及启用 server.public 时的公开监听地址
+	httpServer := server.NewServer(monitorService, esClient, alertService, *configFile, cfg)
+	if err := httpServer.Start(); err != nil {
+		logger.Fatal("HTTP server failed", zap.Error(err))
+	}
 
 	// 启动gRPC服务器
 	grpcAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.GRPCPort)
@@ -140,6 +289,40 @@ func main() {
 	sig := <-sigChan
 	logger.Info("Received signal, shutting down...", zap.String("signal", sig.String()))
 
-	// 优雅关闭
-	logger.Info("Monitor service stopped")
+	// 优雅关闭：先停止接受 HTTP 请求，再等待进行中的检查保存结果，并写完排队的 ES 日志
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		logger.Warn("HTTP server did not stop cleanly", zap.Error(err))
+	}
+	if err := monitorService.Stop(ctx); err != nil {
+		logger.Warn("Monitor service did not stop cleanly", zap.Error(err))
+	}
+}
+
+// alertOnResult 将保存后的检查结果送入告警规则与通知渠道
+func alertOnResult(alerts *alert.Service) monitor.AlertHandler {
+	return func(target *monitor.MonitorTarget, result *monitor.CheckResult, previousStatus string) {
+		event := alert.CheckEvent{
+			TargetID:       target.ID,
+			Status:         result.Status,
+			PreviousStatus: previousStatus,
+			ResponseTime:   result.ResponseTime,
+			Synthetic:      result.Synthetic,
+			Metadata: map[string]string{
+				"message":       result.Message,
+				"response_time": fmt.Sprintf("%d", result.ResponseTime),
+			},
+		}
+		event.Divergence, _ = result.Data["divergence"].(bool)
+		event.ContentChanged, _ = result.Data["content_changed"].(bool)
+		event.Jitter, _ = result.Data["jitter"].(float64)
+		event.Flapping = result.Flapping
+		event.FlappingStarted = result.FlappingChange == monitor.FlappingStarted
+		if err := alerts.SendAlert(context.Background(), event); err != nil {
+			logger.Warn("Failed to process check result for alerting",
+				zap.Uint32("target_id", target.ID),
+				zap.Error(err))
+		}
+	}
 }
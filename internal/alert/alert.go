@@ -8,6 +8,7 @@ import (
 	"net/smtp"
 	"time"
 
+	"monitor/internal/clock"
 	"monitor/internal/logger"
 )
 
@@ -102,6 +103,10 @@ type AlertCondition struct {
 	DownConsecutiveTimes int     `json:"down_consecutive_times"` // 连续失败次数
 	SlowResponseThreshold int64  `json:"slow_response_threshold"` // 响应时间阈值（毫秒）
 	StatusChanged        bool    `json:"status_changed"`           // 状态改变时告警
+	Divergence           bool    `json:"divergence"`               // 对比模式下第二个地址的结果与主地址不一致时告警
+	Degraded             bool    `json:"degraded"`                 // 结果为 degraded 时告警
+	ContentChanged       bool    `json:"content_changed"`          // track_content_hash 的响应体哈希变化时告警
+	JitterThreshold      float64 `json:"jitter_threshold"`         // ping 往返时间抖动阈值（毫秒）
 }
 
 // Manager 告警管理器
@@ -109,6 +114,7 @@ type Manager struct {
 	channels    map[uint32]*AlertChannel
 	rules       map[uint32]*AlertRule
 	eventBuffer map[uint32][]AlertEvent // 每个目标的事件缓冲
+	clock       clock.Clock             // 冷却计时使用的时钟
 }
 
 // NewManager 创建告警管理器
@@ -117,9 +123,15 @@ func NewManager() *Manager {
 		channels:    make(map[uint32]*AlertChannel),
 		rules:       make(map[uint32]*AlertRule),
 		eventBuffer: make(map[uint32][]AlertEvent),
+		clock:       clock.Real,
 	}
 }
 
+// SetClock 替换冷却计时使用的时钟
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
 // AddChannel 添加告警渠道
 func (m *Manager) AddChannel(channel *AlertChannel) {
 	m.channels[channel.ID] = channel
@@ -170,7 +182,7 @@ func (m *Manager) ProcessEvent(event AlertEvent) {
 
 		// 检查冷却时间
 		if rule.CooldownSeconds > 0 && !rule.LastAlertTime.IsZero() {
-			if time.Since(rule.LastAlertTime) < time.Duration(rule.CooldownSeconds)*time.Second {
+			if m.clock.Now().Sub(rule.LastAlertTime) < time.Duration(rule.CooldownSeconds)*time.Second {
 				continue
 			}
 		}
@@ -178,18 +190,27 @@ func (m *Manager) ProcessEvent(event AlertEvent) {
 		// 检查告警条件
 		if m.shouldAlert(event, rule) {
 			m.sendAlert(event, rule)
-			rule.LastAlertTime = time.Now()
+			rule.LastAlertTime = m.clock.Now()
 		}
 	}
 }
 
 // shouldAlert 检查是否应该触发告警
 func (m *Manager) shouldAlert(event AlertEvent, rule *AlertRule) bool {
-	conditions := rule.Conditions
+	fire, _ := EvaluateConditions(m.eventBuffer[event.TargetID], rule.Conditions)
+	return fire
+}
+
+// EvaluateConditions 根据目标的事件序列（最后一个为当前事件）判断是否满足告警条件，
+// 返回是否触发及触发原因。不依赖任何全局状态，可用于离线回放历史数据。
+func EvaluateConditions(events []AlertEvent, conditions AlertCondition) (bool, string) {
+	if len(events) == 0 {
+		return false, ""
+	}
+	event := events[len(events)-1]
 
 	// 检查连续失败次数
 	if conditions.DownConsecutiveTimes > 0 && event.Status == "down" {
-		events := m.eventBuffer[event.TargetID]
 		consecutiveDown := 0
 
 		for i := len(events) - 1; i >= 0; i-- {
@@ -201,27 +222,50 @@ func (m *Manager) shouldAlert(event AlertEvent, rule *AlertRule) bool {
 		}
 
 		if consecutiveDown >= conditions.DownConsecutiveTimes {
-			return true
+			return true, fmt.Sprintf("down for %d consecutive checks", consecutiveDown)
 		}
 	}
 
 	// 检查响应时间阈值
 	if conditions.SlowResponseThreshold > 0 && event.ResponseTime > conditions.SlowResponseThreshold {
-		return true
+		return true, fmt.Sprintf("response time %dms > %dms", event.ResponseTime, conditions.SlowResponseThreshold)
 	}
 
 	// 检查状态改变
-	if conditions.StatusChanged {
-		events := m.eventBuffer[event.TargetID]
-		if len(events) >= 2 {
-			lastEvent := events[len(events)-2]
-			if lastEvent.Status != event.Status {
-				return true
-			}
+	if conditions.StatusChanged && len(events) >= 2 {
+		lastEvent := events[len(events)-2]
+		if lastEvent.Status != event.Status {
+			return true, fmt.Sprintf("status changed from %s to %s", lastEvent.Status, event.Status)
+		}
+	}
+
+	// 检查性能下降
+	if conditions.Degraded && event.Status == "degraded" {
+		return true, "target is degraded"
+	}
+
+	// 检查对比模式的不一致
+	if conditions.Divergence {
+		if diverged, _ := event.Metadata["divergence"].(bool); diverged {
+			return true, "secondary address diverged from the primary"
+		}
+	}
+
+	// 检查响应体内容变化
+	if conditions.ContentChanged {
+		if changed, _ := event.Metadata["content_changed"].(bool); changed {
+			return true, "content changed"
+		}
+	}
+
+	// 检查 ping 的抖动
+	if conditions.JitterThreshold > 0 {
+		if jitter, _ := event.Metadata["jitter"].(float64); jitter > conditions.JitterThreshold {
+			return true, fmt.Sprintf("jitter %.1fms > %.0fms", jitter, conditions.JitterThreshold)
 		}
 	}
 
-	return false
+	return false, ""
 }
 
 // sendAlert 发送告警
@@ -0,0 +1,150 @@
+package alert
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"monitor/internal/database"
+	"monitor/internal/lru"
+	"monitor/internal/models"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// CacheStats counters of the channel and rule caches used by SendAlert
+type CacheStats struct {
+	Channels LookupStats `json:"channels"`
+	Rules    LookupStats `json:"rules"` // enabled rules by target
+}
+
+// LookupStats counters of one cache. Loads is lower than Misses when
+// concurrent misses of the same key shared one database read.
+type LookupStats struct {
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Loads   int64 `json:"loads"`
+	Entries int   `json:"entries"`
+	Cap     int   `json:"cap"` // least recently used entries are evicted beyond it
+}
+
+// Caps of the lookup caches; channels are few, rules are cached per target
+const (
+	maxCachedChannels = 1000
+	maxCachedRuleSets = 10000
+)
+
+// readThrough caches database lookups by key until they are invalidated.
+// Errors, including "not found", are not cached.
+type readThrough[K comparable, V any] struct {
+	load func(key K) (V, error)
+
+	mu      sync.Mutex
+	entries *lru.Cache[K, V]
+	gen     uint64 // bumped by invalidate so that loads started before it are neither stored nor joined
+	group   singleflight.Group
+
+	hits, misses, loads atomic.Int64
+}
+
+func newReadThrough[K comparable, V any](max int, load func(key K) (V, error)) *readThrough[K, V] {
+	return &readThrough[K, V]{load: load, entries: lru.New[K, V](max)}
+}
+
+func (c *readThrough[K, V]) get(key K) (V, error) {
+	c.mu.Lock()
+	value, ok := c.entries.Get(key)
+	gen := c.gen
+	c.mu.Unlock()
+	if ok {
+		c.hits.Add(1)
+		return value, nil
+	}
+	c.misses.Add(1)
+
+	// A burst of checks after an invalidation waits for one read instead of each querying
+	v, err, _ := c.group.Do(fmt.Sprintf("%d:%v", gen, key), func() (interface{}, error) {
+		c.loads.Add(1)
+		value, err := c.load(key)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		if c.gen == gen {
+			c.entries.Add(key, value)
+		}
+		c.mu.Unlock()
+		return value, nil
+	})
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	return v.(V), nil
+}
+
+func (c *readThrough[K, V]) invalidate(key K) {
+	c.mu.Lock()
+	c.entries.Remove(key)
+	c.gen++
+	c.mu.Unlock()
+}
+
+func (c *readThrough[K, V]) invalidateAll() {
+	c.mu.Lock()
+	c.entries = lru.New[K, V](c.entries.Cap())
+	c.gen++
+	c.mu.Unlock()
+}
+
+func (c *readThrough[K, V]) stats() LookupStats {
+	c.mu.Lock()
+	entries, max := c.entries.Len(), c.entries.Cap()
+	c.mu.Unlock()
+	return LookupStats{
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+		Loads:   c.loads.Load(),
+		Entries: entries,
+		Cap:     max,
+	}
+}
+
+func loadChannel(id uint) (models.AlertChannel, error) {
+	var channel models.AlertChannel
+	err := database.GetDB().First(&channel, id).Error
+	return channel, err
+}
+
+func loadEnabledRules(targetID uint32) ([]models.AlertRule, error) {
+	var rules []models.AlertRule
+	err := database.GetDB().Where("target_id = ? AND enabled = ?", targetID, true).Find(&rules).Error
+	return rules, err
+}
+
+// InvalidateChannel drops a cached channel; call it after the channel is updated or deleted
+func (s *Service) InvalidateChannel(id uint) {
+	s.channels.invalidate(id)
+}
+
+// InvalidateRules drops the cached rules of targets; call it after a rule of the
+// target is created, updated or deleted
+func (s *Service) InvalidateRules(targetIDs ...uint32) {
+	for _, id := range targetIDs {
+		s.rules.invalidate(id)
+	}
+}
+
+// InvalidateAllRules drops the cached rules of every target, for changes where
+// the target is not known
+func (s *Service) InvalidateAllRules() {
+	s.rules.invalidateAll()
+}
+
+// CacheStats returns the hit/miss counters of the lookup caches
+func (s *Service) CacheStats() CacheStats {
+	return CacheStats{
+		Channels: s.channels.stats(),
+		Rules:    s.rules.stats(),
+	}
+}
@@ -0,0 +1,190 @@
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+// fakeStore is a loader over a map, counting the loads
+type fakeStore struct {
+	mu     sync.Mutex
+	values map[int]string
+	loads  atomic.Int32
+}
+
+func (f *fakeStore) set(key int, value string) {
+	f.mu.Lock()
+	f.values[key] = value
+	f.mu.Unlock()
+}
+
+func (f *fakeStore) load(key int) (string, error) {
+	f.loads.Add(1)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	value, ok := f.values[key]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return value, nil
+}
+
+func TestReadThroughHitMissInvalidate(t *testing.T) {
+	store := &fakeStore{values: map[int]string{1: "a"}}
+	c := newReadThrough(10, store.load)
+
+	for i := 0; i < 3; i++ {
+		if got, err := c.get(1); err != nil || got != "a" {
+			t.Fatalf("get %d = %q, %v", i, got, err)
+		}
+	}
+	if stats := c.stats(); stats.Hits != 2 || stats.Misses != 1 || stats.Loads != 1 || stats.Entries != 1 || stats.Cap != 10 {
+		t.Errorf("stats after one miss and two hits = %+v", stats)
+	}
+
+	// The cached value stays until it is invalidated
+	store.set(1, "b")
+	if got, _ := c.get(1); got != "a" {
+		t.Errorf("get before invalidate = %q, want the cached a", got)
+	}
+	c.invalidate(1)
+	if got, _ := c.get(1); got != "b" {
+		t.Errorf("get after invalidate = %q, want b", got)
+	}
+	if n := store.loads.Load(); n != 2 {
+		t.Errorf("%d loads, want 2", n)
+	}
+
+	// Errors are not cached
+	for i := 0; i < 2; i++ {
+		if _, err := c.get(2); err == nil {
+			t.Fatal("get of a missing key succeeded")
+		}
+	}
+	if n := store.loads.Load(); n != 4 {
+		t.Errorf("%d loads after two failed gets, want 4", n)
+	}
+
+	c.invalidateAll()
+	if stats := c.stats(); stats.Entries != 0 {
+		t.Errorf("%d entries after invalidateAll", stats.Entries)
+	}
+}
+
+// A load that started before an invalidation is neither stored nor joined
+// by gets after it, so it can't bring back the old value
+func TestReadThroughInvalidateDuringLoad(t *testing.T) {
+	store := &fakeStore{values: map[int]string{1: "old"}}
+	started, release := make(chan struct{}), make(chan struct{})
+	var blocked atomic.Bool
+	c := newReadThrough(10, func(key int) (string, error) {
+		value, err := store.load(key)
+		if blocked.CompareAndSwap(false, true) {
+			close(started)
+			<-release
+		}
+		return value, err
+	})
+
+	first := make(chan string)
+	go func() {
+		value, _ := c.get(1)
+		first <- value
+	}()
+	<-started
+
+	store.set(1, "new")
+	c.invalidate(1)
+	if got, err := c.get(1); err != nil || got != "new" {
+		t.Fatalf("get after invalidate = %q, %v, want new without waiting for the old load", got, err)
+	}
+
+	close(release)
+	if got := <-first; got != "old" {
+		t.Errorf("get in flight = %q, want the value it read", got)
+	}
+	if got, _ := c.get(1); got != "new" {
+		t.Errorf("get after the old load finished = %q, want new", got)
+	}
+	if stats := c.stats(); stats.Loads != 2 || stats.Hits != 1 {
+		t.Errorf("stats = %+v, want 2 loads and the last get a hit", stats)
+	}
+}
+
+// Concurrent misses of one key share a load
+func TestReadThroughSharesLoad(t *testing.T) {
+	store := &fakeStore{values: map[int]string{1: "a"}}
+	release := make(chan struct{})
+	c := newReadThrough(10, func(key int) (string, error) {
+		<-release
+		return store.load(key)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := c.get(1); err != nil || got != "a" {
+				t.Errorf("get = %q, %v", got, err)
+			}
+		}()
+	}
+	// Let the gets reach the shared load before it returns
+	for c.stats().Misses < 10 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	if n := store.loads.Load(); n != 1 {
+		t.Errorf("%d loads for 10 concurrent misses, want 1", n)
+	}
+}
+
+// An updated channel is used by the next alert once the handler invalidates it
+func TestSendAlertUsesUpdatedChannel(t *testing.T) {
+	h := newAlertHarness(t)
+	h.addRule(t, models.AlertRule{ThresholdType: "failure_count", ThresholdValue: 1, CooldownSeconds: 60})
+
+	var moved atomic.Int32
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		moved.Add(1)
+	}))
+	t.Cleanup(hook.Close)
+
+	h.send(t, CheckEvent{Status: "down"})
+	h.waitHistory(t, 1)
+	if stats := h.s.CacheStats(); stats.Channels.Misses != 1 || stats.Rules.Misses == 0 {
+		t.Errorf("cache stats after the first alert = %+v", stats)
+	}
+
+	config, _ := json.Marshal(map[string]string{"webhook_url": hook.URL})
+	if err := database.GetDB().Model(&h.channel).Update("config", string(config)).Error; err != nil {
+		t.Fatalf("update channel: %v", err)
+	}
+
+	// Without the invalidation the cached channel is used
+	h.clock.Advance(2 * time.Minute)
+	h.send(t, CheckEvent{Status: "down"})
+	h.waitHistory(t, 2)
+	if stats := h.s.CacheStats(); stats.Channels.Hits != 1 || stats.Channels.Loads != 1 {
+		t.Errorf("channel cache stats = %+v, want a hit", stats.Channels)
+	}
+
+	h.s.InvalidateChannel(uint(h.channel.ID))
+	h.clock.Advance(2 * time.Minute)
+	h.send(t, CheckEvent{Status: "down"})
+	h.waitHistory(t, 3)
+	if old, updated := h.received.Load(), moved.Load(); old != 2 || updated != 1 {
+		t.Errorf("old webhook got %d alerts and the new one %d, want 2 and 1", old, updated)
+	}
+}
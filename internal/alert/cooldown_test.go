@@ -0,0 +1,92 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"monitor/internal/clock"
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+
+	"go.uber.org/zap"
+)
+
+var epoch = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestManagerCooldownBoundary(t *testing.T) {
+	logger.Log = zap.NewNop()
+	fake := clock.NewFake(epoch)
+	m := NewManager()
+	m.SetClock(fake)
+
+	rule := &AlertRule{ID: 1, Enabled: true, Severity: "critical", CooldownSeconds: 60,
+		Conditions: AlertCondition{DownConsecutiveTimes: 1}}
+	m.AddRule(rule)
+	down := AlertEvent{TargetID: 7, Status: "down"}
+
+	m.ProcessEvent(down)
+	if !rule.LastAlertTime.Equal(epoch) {
+		t.Fatalf("first alert at %v, want %v", rule.LastAlertTime, epoch)
+	}
+
+	fake.Advance(60*time.Second - time.Nanosecond)
+	m.ProcessEvent(down)
+	if !rule.LastAlertTime.Equal(epoch) {
+		t.Fatalf("alert sent %v before the cooldown ended", epoch.Add(time.Minute).Sub(rule.LastAlertTime))
+	}
+
+	fake.Advance(time.Nanosecond)
+	m.ProcessEvent(down)
+	if want := epoch.Add(time.Minute); !rule.LastAlertTime.Equal(want) {
+		t.Fatalf("alert at the end of the cooldown: last alert %v, want %v", rule.LastAlertTime, want)
+	}
+}
+
+func TestClaimCooldownBoundary(t *testing.T) {
+	if err := database.InitMemoryDB(t.Name()); err != nil {
+		t.Fatalf("InitMemoryDB: %v", err)
+	}
+	db := database.GetDB()
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	rule := models.AlertRule{TargetID: 1, ChannelID: 1, Enabled: true, CooldownSeconds: 300}
+	if err := db.Create(&rule).Error; err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+
+	fake := clock.NewFake(epoch)
+	steps := []struct {
+		advance time.Duration
+		want    bool
+	}{
+		{0, true},                  // never alerted
+		{0, false},                 // second worker racing the first
+		{299 * time.Second, false}, // one second left
+		{time.Second, true},        // cooldown just elapsed
+		{time.Second, false},       // the new cooldown started
+	}
+	for i, step := range steps {
+		fake.Advance(step.advance)
+		claimed, err := claimCooldown(db, rule, fake.Now())
+		if err != nil {
+			t.Fatalf("step %d: claimCooldown: %v", i, err)
+		}
+		if claimed != step.want {
+			t.Errorf("step %d at %v: claimed = %v, want %v", i, fake.Now().Sub(epoch), claimed, step.want)
+		}
+	}
+
+	if err := db.First(&rule, rule.ID).Error; err != nil {
+		t.Fatalf("reload rule: %v", err)
+	}
+	if rule.AlertCount != 2 || !rule.AlertOpen {
+		t.Errorf("alert_count = %d, alert_open = %v, want 2 and true", rule.AlertCount, rule.AlertOpen)
+	}
+	if want := epoch.Add(300 * time.Second); rule.LastAlertTime == nil || !rule.LastAlertTime.Equal(want) {
+		t.Errorf("last_alert_time = %v, want %v", rule.LastAlertTime, want)
+	}
+}
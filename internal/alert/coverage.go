@@ -0,0 +1,196 @@
+package alert
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+// RuleCoverage describes, read-only, how one rule would handle the target right now
+type RuleCoverage struct {
+	RuleID          uint                    `json:"rule_id"`
+	Scope           string                  `json:"scope"` // target: the rule is attached to this target
+	Enabled         bool                    `json:"enabled"`
+	Summary         string                  `json:"summary"`
+	CooldownSeconds int                     `json:"cooldown_seconds"`
+	Channels        []ChannelCoverage       `json:"channels"`
+	AlertOpen       bool                    `json:"alert_open"`
+	LastAlertTime   *time.Time              `json:"last_alert_time,omitempty"`
+	LastDelivery    *DeliveryOutcome        `json:"last_delivery,omitempty"`
+	NextEligibleAt  *time.Time              `json:"next_eligible_at,omitempty"` // set while the cooldown is running
+	Snooze          *models.AlertRuleSnooze `json:"snooze,omitempty"`           // set while the rule is snoozed
+	WouldNotify     bool                    `json:"would_notify"`               // a failure now would reach at least one channel
+	Blockers        []string                `json:"blockers,omitempty"`         // why WouldNotify is false
+}
+
+// ChannelCoverage a channel a rule routes to
+type ChannelCoverage struct {
+	ID      uint32 `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Enabled bool   `json:"enabled"`
+	Missing bool   `json:"missing,omitempty"` // the rule points at a deleted channel
+}
+
+// DeliveryOutcome the latest alert history entry of a rule
+type DeliveryOutcome struct {
+	ChannelID uint32    `json:"channel_id"`
+	Status    string    `json:"status"` // sent, failed, skipped, snoozed
+	Severity  string    `json:"severity"`
+	Synthetic bool      `json:"synthetic"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// TargetCoverage lists every rule that applies to the target with its channels,
+// last delivery and cooldown, as SendAlert would see them at this moment.
+// Only rules attached directly to the target exist; there are no tag or global rules.
+func (s *Service) TargetCoverage(targetID uint32) ([]RuleCoverage, error) {
+	db := database.GetDB()
+
+	var rules []models.AlertRule
+	if err := db.Where("target_id = ?", targetID).Order("id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	channelIDs := make([]uint, 0, len(rules))
+	for _, rule := range rules {
+		channelIDs = append(channelIDs, rule.ChannelID)
+	}
+	channels := make(map[uint32]models.AlertChannel)
+	if len(channelIDs) > 0 {
+		var found []models.AlertChannel
+		if err := db.Where("id IN ?", channelIDs).Find(&found).Error; err != nil {
+			return nil, err
+		}
+		for _, channel := range found {
+			channels[channel.ID] = channel
+		}
+	}
+
+	ruleIDs := make([]uint, 0, len(rules))
+	for _, rule := range rules {
+		ruleIDs = append(ruleIDs, rule.ID)
+	}
+	snoozes, err := s.ActiveSnoozes(ruleIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	coverage := make([]RuleCoverage, 0, len(rules))
+	for _, rule := range rules {
+		// 静默的开始和取消不是发送结果
+		var last *models.AlertHistory
+		var history []models.AlertHistory
+		if err := db.Where("rule_id = ? AND severity <> ?", rule.ID, historySeveritySnooze).
+			Order("sent_at DESC").Limit(1).Find(&history).Error; err != nil {
+			return nil, err
+		}
+		if len(history) > 0 {
+			last = &history[0]
+		}
+		coverage = append(coverage, ruleCoverage(rule, channels, last, snoozes[rule.ID], now))
+	}
+	return coverage, nil
+}
+
+// ruleCoverage assembles the view of one rule; it mirrors the checks SendAlert
+// makes before sending, so keep the two in step
+func ruleCoverage(rule models.AlertRule, channels map[uint32]models.AlertChannel, last *models.AlertHistory, snooze *models.AlertRuleSnooze, now time.Time) RuleCoverage {
+	rc := RuleCoverage{
+		RuleID:          rule.ID,
+		Scope:           "target",
+		Enabled:         rule.Enabled,
+		Summary:         describeRule(rule),
+		CooldownSeconds: rule.CooldownSeconds,
+		AlertOpen:       rule.AlertOpen,
+		LastAlertTime:   rule.LastAlertTime,
+		Snooze:          snooze,
+	}
+
+	channelID := uint32(rule.ChannelID)
+	channel, ok := channels[channelID]
+	if ok {
+		rc.Channels = []ChannelCoverage{{ID: channel.ID, Name: channel.Name, Type: channel.Type, Enabled: channel.Enabled}}
+	} else {
+		rc.Channels = []ChannelCoverage{{ID: channelID, Missing: true}}
+	}
+
+	if last != nil {
+		rc.LastDelivery = &DeliveryOutcome{
+			ChannelID: last.ChannelID,
+			Status:    last.Status,
+			Severity:  last.Severity,
+			Synthetic: last.Synthetic,
+			SentAt:    last.SentAt,
+		}
+	}
+
+	if rule.LastAlertTime != nil && rule.CooldownSeconds > 0 {
+		next := rule.LastAlertTime.Add(time.Duration(rule.CooldownSeconds) * time.Second)
+		if next.After(now) {
+			rc.NextEligibleAt = &next
+		}
+	}
+
+	if !rule.Enabled {
+		rc.Blockers = append(rc.Blockers, "rule is disabled")
+	}
+	if !ok {
+		rc.Blockers = append(rc.Blockers, fmt.Sprintf("channel %d does not exist", channelID))
+	} else if !channel.Enabled {
+		rc.Blockers = append(rc.Blockers, fmt.Sprintf("channel %q is disabled", channel.Name))
+	}
+	if rc.NextEligibleAt != nil {
+		rc.Blockers = append(rc.Blockers, "cooldown is running")
+	}
+	if snooze != nil {
+		rc.Blockers = append(rc.Blockers, fmt.Sprintf("rule is snoozed until %s", snooze.ExpiresAt.Format(time.RFC3339)))
+	}
+	rc.WouldNotify = len(rc.Blockers) == 0
+	return rc
+}
+
+// describeRule summarizes, in the words an operator would use, when shouldTriggerAlert
+// fires for the rule. It must describe what the engine does, not what the fields suggest.
+func describeRule(rule models.AlertRule) string {
+	var parts []string
+	switch rule.ThresholdType {
+	case "":
+		parts = append(parts, "检查结果为 down 时触发")
+	case "failure_count":
+		parts = append(parts, fmt.Sprintf("连续 %d 次检查结果为 down 时触发，服务重启后重新计数", max(rule.ThresholdValue, 1)))
+	case "response_time":
+		if rule.ThresholdValue > 0 {
+			parts = append(parts, fmt.Sprintf("响应时间超过 %d ms 时触发", rule.ThresholdValue))
+		} else {
+			parts = append(parts, "响应时间阈值未设置，不会触发")
+		}
+	case "status_change":
+		parts = append(parts, "状态变为 down 或 degraded 时触发")
+	case "degraded":
+		parts = append(parts, "检查结果为 degraded 时触发（如响应时间达到 degraded_threshold_ms），down 不触发")
+	case "divergence":
+		parts = append(parts, "对比模式下第二个地址的结果与主地址不一致时触发")
+	case "content_changed":
+		parts = append(parts, "开启 track_content_hash 的监控响应体哈希与上次不同时触发，目标的第一个哈希不触发")
+	case "jitter":
+		if rule.ThresholdValue > 0 {
+			parts = append(parts, fmt.Sprintf("ping 监控的往返时间抖动超过 %d ms 时触发，没有收到回复的检查不触发", rule.ThresholdValue))
+		} else {
+			parts = append(parts, "抖动阈值未设置，不会触发")
+		}
+	default:
+		parts = append(parts, fmt.Sprintf("未知的阈值类型 %q，不会触发", rule.ThresholdType))
+	}
+	if strings.TrimSpace(rule.ConditionLogic) != "" {
+		parts = append(parts, "condition_logic 目前不参与判断")
+	}
+	if rule.CooldownSeconds > 0 {
+		parts = append(parts, fmt.Sprintf("两次告警至少间隔 %s", time.Duration(rule.CooldownSeconds)*time.Second))
+	}
+	return strings.Join(parts, "；")
+}
@@ -0,0 +1,90 @@
+package alert
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+func coverageOf(t *testing.T, h *alertHarness, ruleID uint) RuleCoverage {
+	t.Helper()
+	coverage, err := h.s.TargetCoverage(h.target.ID)
+	if err != nil {
+		t.Fatalf("TargetCoverage: %v", err)
+	}
+	for _, rc := range coverage {
+		if rc.RuleID == ruleID {
+			return rc
+		}
+	}
+	t.Fatalf("rule %d not in the coverage %+v", ruleID, coverage)
+	return RuleCoverage{}
+}
+
+// Coverage follows a rule through an alert and its cooldown, and names each
+// thing that would stop a notification
+func TestTargetCoverage(t *testing.T) {
+	h := newAlertHarness(t)
+	db := database.GetDB()
+	rule := h.addRule(t, models.AlertRule{ThresholdType: "failure_count", ThresholdValue: 2, CooldownSeconds: 300})
+
+	rc := coverageOf(t, h, rule.ID)
+	if !rc.WouldNotify || rc.Blockers != nil || rc.LastDelivery != nil || rc.NextEligibleAt != nil {
+		t.Fatalf("fresh rule coverage %+v, want it to notify", rc)
+	}
+	if len(rc.Channels) != 1 || rc.Channels[0].Name != "hook" || !rc.Channels[0].Enabled {
+		t.Errorf("channels %+v", rc.Channels)
+	}
+	if rc.Summary != "连续 2 次检查结果为 down 时触发，服务重启后重新计数；两次告警至少间隔 5m0s" {
+		t.Errorf("summary %q", rc.Summary)
+	}
+
+	h.send(t, CheckEvent{Status: "down"})
+	h.send(t, CheckEvent{Status: "down", PreviousStatus: "down"})
+	h.waitHistory(t, 1)
+	h.clock.Advance(time.Minute)
+	rc = coverageOf(t, h, rule.ID)
+	if !rc.AlertOpen || rc.LastDelivery == nil || rc.LastDelivery.Status != "sent" || rc.LastDelivery.ChannelID != h.channel.ID {
+		t.Errorf("after an alert: open %v last delivery %+v", rc.AlertOpen, rc.LastDelivery)
+	}
+	if rc.NextEligibleAt == nil || !rc.NextEligibleAt.Equal(epoch.Add(5*time.Minute)) || rc.WouldNotify {
+		t.Errorf("next eligible %v would notify %v, want cooldown until %v", rc.NextEligibleAt, rc.WouldNotify, epoch.Add(5*time.Minute))
+	}
+
+	h.clock.Advance(5 * time.Minute)
+	db.Model(&h.channel).Update("enabled", false)
+	db.Model(&models.AlertRule{}).Where("id = ?", rule.ID).Update("enabled", false)
+	rc = coverageOf(t, h, rule.ID)
+	want := []string{"rule is disabled", `channel "hook" is disabled`}
+	if !slices.Equal(rc.Blockers, want) || rc.NextEligibleAt != nil {
+		t.Errorf("blockers %q next eligible %v, want %q", rc.Blockers, rc.NextEligibleAt, want)
+	}
+
+	db.Delete(&h.channel)
+	rc = coverageOf(t, h, rule.ID)
+	if !rc.Channels[0].Missing || !slices.Contains(rc.Blockers, fmt.Sprintf("channel %d does not exist", h.channel.ID)) {
+		t.Errorf("deleted channel: channels %+v blockers %q", rc.Channels, rc.Blockers)
+	}
+}
+
+func TestDescribeRule(t *testing.T) {
+	for _, tc := range []struct {
+		rule models.AlertRule
+		want string
+	}{
+		{models.AlertRule{}, "检查结果为 down 时触发"},
+		{models.AlertRule{ThresholdType: "response_time"}, "响应时间阈值未设置，不会触发"},
+		{models.AlertRule{ThresholdType: "response_time", ThresholdValue: 800}, "响应时间超过 800 ms 时触发"},
+		{models.AlertRule{ThresholdType: "degraded"}, "检查结果为 degraded 时触发（如响应时间达到 degraded_threshold_ms），down 不触发"},
+		{models.AlertRule{ThresholdType: "bogus"}, `未知的阈值类型 "bogus"，不会触发`},
+		{models.AlertRule{ConditionLogic: "a && b"}, "检查结果为 down 时触发；condition_logic 目前不参与判断"},
+	} {
+		if got := describeRule(tc.rule); got != tc.want {
+			t.Errorf("describeRule(%+v) = %q, want %q", tc.rule, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,439 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"text/template"
+	"time"
+
+	"monitor/internal/clock"
+	"monitor/internal/config"
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+	"monitor/internal/schedule"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// DigestEntry 周报中的一张证书。多个目标出示同一张证书（指纹相同）时合并为一条，
+// TargetID/TargetName 为其中第一个目标，Targets 列出全部
+type DigestEntry struct {
+	TargetID    uint32         `json:"target_id"`
+	TargetName  string         `json:"target_name"`
+	Subject     string         `json:"subject"`
+	Fingerprint string         `json:"fingerprint,omitempty"` // 证书清单中的 SHA-256 指纹，未知时为空
+	ExpiresAt   time.Time      `json:"expires_at"`
+	DaysLeft    int            `json:"days_left"`
+	Critical    bool           `json:"critical"` // 任一目标当前已处于 critical/down 状态
+	Targets     []DigestTarget `json:"targets"`
+}
+
+// DigestTarget 出示该证书的一个目标
+type DigestTarget struct {
+	ID       uint32 `json:"id"`
+	Name     string `json:"name"`
+	Critical bool   `json:"critical"`
+}
+
+// digestData 渲染模板时使用的数据
+type digestData struct {
+	WindowDays  int
+	GeneratedAt time.Time
+	Entries     []DigestEntry
+}
+
+// 每种渠道格式一个模板：邮件用定宽的表格，即时通讯渠道在手机上看，用逐条列表
+var digestTemplates = template.Must(template.New("digest").Parse(`
+{{define "table"}}{{if .Entries}}未来 {{.WindowDays}} 天内到期的证书共 {{len .Entries}} 个（按紧急程度排序）
+
+剩余天数  到期日期    监控目标（证书主题）
+{{range .Entries}}{{printf "%5d" .DaysLeft}} 天  {{.ExpiresAt.Format "2006-01-02"}}  {{if .Critical}}[严重] {{end}}{{.TargetName}}（{{.Subject}}）
+{{if gt (len .Targets) 1}}                      共用此证书的目标：{{range $i, $t := .Targets}}{{if $i}}、{{end}}{{$t.Name}}{{end}}
+{{end}}{{end}}{{else}}一切正常：未来 {{.WindowDays}} 天内没有证书到期。
+{{end}}{{end}}
+{{define "list"}}{{if .Entries}}未来 {{.WindowDays}} 天内到期的证书共 {{len .Entries}} 个（按紧急程度排序）
+{{range .Entries}}
+{{if .Critical}}[严重] {{end}}{{.TargetName}}（{{.Subject}}）
+  剩余 {{.DaysLeft}} 天，{{.ExpiresAt.Format "2006-01-02"}} 到期
+{{if gt (len .Targets) 1}}  共用此证书的目标：{{range $i, $t := .Targets}}{{if $i}}、{{end}}{{$t.Name}}{{end}}
+{{end}}{{end}}{{else}}一切正常：未来 {{.WindowDays}} 天内没有证书到期。
+{{end}}{{end}}`))
+
+// digestFormats 渠道类型对应的模板，未列出的渠道使用 list
+var digestFormats = map[string]string{
+	"email": "table",
+}
+
+// DigestJob 按计划发送证书到期周报
+type DigestJob struct {
+	channelID  uint32
+	windowDays int
+	sendEmpty  bool
+	schedule   *schedule.Schedule // 本地时区
+	quiet      bool
+	quietStart time.Duration
+	quietEnd   time.Duration
+	factory    *NotifierFactory
+	clock      clock.Clock
+}
+
+// NewDigestJob 校验配置并创建周报任务
+func NewDigestJob(cfg config.DigestConfig) (*DigestJob, error) {
+	sched, err := DigestSchedule(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.WindowDays < 1 {
+		return nil, fmt.Errorf("digest window_days must be at least 1")
+	}
+
+	job := &DigestJob{
+		channelID:  cfg.ChannelID,
+		windowDays: cfg.WindowDays,
+		sendEmpty:  cfg.SendEmpty,
+		schedule:   sched,
+		factory:    NewNotifierFactory(),
+		clock:      clock.Real,
+	}
+
+	if cfg.QuietHours.Start != "" || cfg.QuietHours.End != "" {
+		if job.quietStart, err = schedule.ParseTimeOfDay(cfg.QuietHours.Start); err != nil {
+			return nil, fmt.Errorf("invalid quiet hours start: %w", err)
+		}
+		if job.quietEnd, err = schedule.ParseTimeOfDay(cfg.QuietHours.End); err != nil {
+			return nil, fmt.Errorf("invalid quiet hours end: %w", err)
+		}
+		job.quiet = job.quietStart != job.quietEnd
+	}
+
+	return job, nil
+}
+
+// DigestSchedule 返回周报的发送计划：设置了 schedule 时使用该 cron 表达式，否则每周 weekday 的 time
+func DigestSchedule(cfg config.DigestConfig) (*schedule.Schedule, error) {
+	if cfg.Schedule != "" {
+		sched, err := schedule.Validate(cfg.Schedule, time.Local, schedule.MinIntervals["digest"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid digest schedule: %w", err)
+		}
+		return sched, nil
+	}
+	sched, err := schedule.Weekly(cfg.Weekday, cfg.Time)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest weekday or time: %w", err)
+	}
+	return sched, nil
+}
+
+// SetClock 替换计划与到期计算使用的时间源
+func (j *DigestJob) SetClock(c clock.Clock) {
+	j.clock = c
+}
+
+// Start 在后台按计划发送周报，直到 ctx 结束
+func (j *DigestJob) Start(ctx context.Context) {
+	go func() {
+		for {
+			now := j.clock.Now()
+			next := j.NextRun(now)
+			logger.Info("Next certificate digest scheduled", zap.Time("at", next))
+
+			timer := j.clock.NewTimer(next.Sub(now))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
+
+			if err := j.Run(); err != nil {
+				logger.Warn("Failed to send certificate digest", zap.Error(err))
+			}
+		}
+	}()
+}
+
+// NextRun 返回 now 之后的下一次发送时间；落在免打扰时段内时推迟到时段结束
+func (j *DigestJob) NextRun(now time.Time) time.Time {
+	// 校验时已确认计划会触发
+	next, _ := j.schedule.Next(now)
+	return j.afterQuietHours(next)
+}
+
+// afterQuietHours 如果 t 落在免打扰时段内，返回时段结束的时刻，否则原样返回
+func (j *DigestJob) afterQuietHours(t time.Time) time.Time {
+	if !j.quiet {
+		return t
+	}
+
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+	if j.quietStart < j.quietEnd {
+		if offset >= j.quietStart && offset < j.quietEnd {
+			return midnight.Add(j.quietEnd)
+		}
+		return t
+	}
+
+	// 跨午夜的时段，如 22:00-08:00
+	if offset >= j.quietStart {
+		return midnight.AddDate(0, 0, 1).Add(j.quietEnd)
+	}
+	if offset < j.quietEnd {
+		return midnight.Add(j.quietEnd)
+	}
+	return t
+}
+
+// Run 立即生成并发送一次周报，并记录到告警历史
+func (j *DigestJob) Run() error {
+	db := database.GetDB()
+	now := j.clock.Now()
+
+	entries, err := CollectCertDigest(db, now, j.windowDays)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 && !j.sendEmpty {
+		logger.Info("Certificate digest skipped: nothing expires within the window",
+			zap.Int("window_days", j.windowDays))
+		return nil
+	}
+
+	var channel models.AlertChannel
+	if err := db.First(&channel, j.channelID).Error; err != nil {
+		return fmt.Errorf("failed to load digest channel %d: %w", j.channelID, err)
+	}
+	if !channel.Enabled {
+		return fmt.Errorf("digest channel %d is disabled", j.channelID)
+	}
+
+	var channelConfig map[string]interface{}
+	if err := json.Unmarshal([]byte(channel.Config), &channelConfig); err != nil {
+		return fmt.Errorf("failed to parse channel config: %w", err)
+	}
+	notifier, err := j.factory.CreateNotifier(channel.Type, channelConfig)
+	if err != nil {
+		return err
+	}
+
+	message, err := renderCertDigest(channel.Type, digestData{
+		WindowDays:  j.windowDays,
+		GeneratedAt: now,
+		Entries:     entries,
+	})
+	if err != nil {
+		return err
+	}
+
+	title := fmt.Sprintf("证书到期周报 (%s)", now.Format("2006-01-02"))
+	history := models.AlertHistory{
+		ChannelID: channel.ID,
+		Severity:  "digest",
+		Status:    "sent",
+		Message:   message,
+	}
+	sendErr := notifier.Send(title, message)
+	if sendErr != nil {
+		history.Status = "failed"
+	}
+	history.SentAt = j.clock.Now()
+	if err := db.Create(&history).Error; err != nil {
+		logger.Warn("Failed to record certificate digest in alert history", zap.Error(err))
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+
+	logger.Info("Certificate digest sent",
+		zap.Uint32("channel_id", channel.ID),
+		zap.Int("certificates", len(entries)))
+	return nil
+}
+
+// renderCertDigest 按渠道类型选择模板渲染周报
+func renderCertDigest(channelType string, data digestData) (string, error) {
+	format, ok := digestFormats[channelType]
+	if !ok {
+		format = "list"
+	}
+
+	var buf bytes.Buffer
+	if err := digestTemplates.ExecuteTemplate(&buf, format, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// CollectCertDigest 从每个目标的最新状态中找出 windowDays 天内到期的证书，按到期时间排序。
+// 有证书链时取链中最早到期的一张（中间证书先过期同样会导致故障），否则使用终端证书的剩余天数。
+// 证书清单中当前证书即将到期的目标（如 SMTP）也会列出；出示同一张证书的目标合并为一条。
+func CollectCertDigest(db *gorm.DB, now time.Time, windowDays int) ([]DigestEntry, error) {
+	var statuses []models.MonitorStatus
+	if err := db.Preload("Target").
+		Where("ssl_days_until_expiry IS NOT NULL OR data IS NOT NULL").
+		Find(&statuses).Error; err != nil {
+		return nil, err
+	}
+
+	// 每个目标当前出示的证书
+	var current []struct {
+		TargetID    uint32
+		Fingerprint string
+		Subject     string
+		NotAfter    time.Time
+	}
+	if err := db.Table("certificate_targets").
+		Select("certificate_targets.target_id, certificates.fingerprint, certificates.subject, certificates.not_after").
+		Joins("JOIN certificates ON certificates.id = certificate_targets.certificate_id").
+		Where("certificate_targets.is_current = ?", true).
+		Scan(&current).Error; err != nil {
+		return nil, err
+	}
+	fingerprints := make(map[uint32]string, len(current))
+	for _, cert := range current {
+		fingerprints[cert.TargetID] = cert.Fingerprint
+	}
+
+	cutoff := now.AddDate(0, 0, windowDays)
+	entries := make([]DigestEntry, 0)
+	listed := make(map[uint32]bool)
+	add := func(target *models.MonitorTarget, status, subject, fingerprint string, expiresAt time.Time) {
+		critical := status == "critical" || status == "down"
+		entries = append(entries, DigestEntry{
+			TargetID:    target.ID,
+			TargetName:  target.Name,
+			Subject:     subject,
+			Fingerprint: fingerprint,
+			ExpiresAt:   expiresAt,
+			DaysLeft:    int(expiresAt.Sub(now).Hours() / 24),
+			Critical:    critical,
+			Targets:     []DigestTarget{{ID: target.ID, Name: target.Name, Critical: critical}},
+		})
+		listed[target.ID] = true
+	}
+
+	for _, status := range statuses {
+		if status.Target == nil || !status.Target.Enabled {
+			continue
+		}
+
+		subject, expiresAt, ok := statusCertExpiry(status)
+		if !ok || expiresAt.After(cutoff) {
+			continue
+		}
+		add(status.Target, status.Status, subject, fingerprints[status.TargetID], expiresAt)
+	}
+
+	// 状态中没有证书信息的目标，使用证书清单中的当前证书
+	var extra []uint32
+	for _, cert := range current {
+		if !listed[cert.TargetID] && !cert.NotAfter.After(cutoff) {
+			extra = append(extra, cert.TargetID)
+		}
+	}
+	if len(extra) > 0 {
+		var targets []models.MonitorTarget
+		if err := db.Where("id IN ? AND enabled = ?", extra, true).Find(&targets).Error; err != nil {
+			return nil, err
+		}
+		var rows []models.MonitorStatus
+		if err := db.Where("target_id IN ?", extra).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		targetStatus := make(map[uint32]string, len(rows))
+		for _, row := range rows {
+			targetStatus[row.TargetID] = row.Status
+		}
+		byID := make(map[uint32]*models.MonitorTarget, len(targets))
+		for i := range targets {
+			byID[targets[i].ID] = &targets[i]
+		}
+		for _, cert := range current {
+			if target, ok := byID[cert.TargetID]; ok && !listed[cert.TargetID] {
+				add(target, targetStatus[cert.TargetID], cert.Subject, cert.Fingerprint, cert.NotAfter)
+			}
+		}
+	}
+
+	entries = groupByCertificate(entries)
+	sort.SliceStable(entries, func(a, b int) bool {
+		if !entries[a].ExpiresAt.Equal(entries[b].ExpiresAt) {
+			return entries[a].ExpiresAt.Before(entries[b].ExpiresAt)
+		}
+		return entries[a].TargetID < entries[b].TargetID
+	})
+	return entries, nil
+}
+
+// groupByCertificate 合并指纹相同的条目：到期时间取最早的一个，目标按 ID 排序。
+// 指纹未知的条目保持独立。
+func groupByCertificate(entries []DigestEntry) []DigestEntry {
+	grouped := make([]DigestEntry, 0, len(entries))
+	index := make(map[string]int)
+	for _, entry := range entries {
+		i, ok := index[entry.Fingerprint]
+		if entry.Fingerprint == "" || !ok {
+			if entry.Fingerprint != "" {
+				index[entry.Fingerprint] = len(grouped)
+			}
+			grouped = append(grouped, entry)
+			continue
+		}
+		group := &grouped[i]
+		group.Targets = append(group.Targets, entry.Targets...)
+		group.Critical = group.Critical || entry.Critical
+		if entry.ExpiresAt.Before(group.ExpiresAt) {
+			group.Subject, group.ExpiresAt, group.DaysLeft = entry.Subject, entry.ExpiresAt, entry.DaysLeft
+		}
+	}
+	for i := range grouped {
+		targets := grouped[i].Targets
+		sort.Slice(targets, func(a, b int) bool { return targets[a].ID < targets[b].ID })
+		grouped[i].TargetID, grouped[i].TargetName = targets[0].ID, targets[0].Name
+	}
+	return grouped
+}
+
+// statusCertExpiry 读取状态中最早到期的证书
+func statusCertExpiry(status models.MonitorStatus) (string, time.Time, bool) {
+	if status.Data != nil {
+		var data struct {
+			CertificateChain []struct {
+				SubjectCN string `json:"subject_cn"`
+				NotAfter  string `json:"not_after"`
+			} `json:"certificate_chain"`
+		}
+		if err := json.Unmarshal([]byte(*status.Data), &data); err == nil {
+			var subject string
+			var earliest time.Time
+			for _, cert := range data.CertificateChain {
+				notAfter, err := time.Parse(time.RFC3339, cert.NotAfter)
+				if err != nil {
+					continue
+				}
+				if earliest.IsZero() || notAfter.Before(earliest) {
+					subject, earliest = cert.SubjectCN, notAfter
+				}
+			}
+			if !earliest.IsZero() {
+				return subject, earliest, true
+			}
+		}
+	}
+
+	if status.SSLDaysUntilExpiry != nil {
+		subject := ""
+		if status.SSLSubject != nil {
+			subject = *status.SSLSubject
+		}
+		return subject, status.CheckedAt.AddDate(0, 0, *status.SSLDaysUntilExpiry), true
+	}
+
+	return "", time.Time{}, false
+}
@@ -0,0 +1,197 @@
+package alert
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"monitor/internal/config"
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+func createTarget(t *testing.T, name string, enabled bool) models.MonitorTarget {
+	t.Helper()
+	db := database.GetDB()
+	target := models.MonitorTarget{Name: name, Type: "https", Address: name + ".example.com", Port: 443, Interval: 60}
+	if err := db.Create(&target).Error; err != nil {
+		t.Fatalf("create target: %v", err)
+	}
+	// enabled defaults to true on create
+	if !enabled {
+		db.Model(&target).Update("enabled", false)
+	}
+	return target
+}
+
+func createStatus(t *testing.T, status models.MonitorStatus) {
+	t.Helper()
+	if status.CheckedAt.IsZero() {
+		status.CheckedAt = epoch
+	}
+	if err := database.GetDB().Create(&status).Error; err != nil {
+		t.Fatalf("create status: %v", err)
+	}
+}
+
+func TestCollectCertDigest(t *testing.T) {
+	initTestDB(t)
+	db := database.GetDB()
+	days := func(n int) *int { return &n }
+	subject := "mail.example.com"
+
+	// The intermediate expires before the leaf and is the one listed
+	web := createTarget(t, "web", true)
+	chain, _ := json.Marshal(map[string]interface{}{"certificate_chain": []map[string]string{
+		{"subject_cn": "web.example.com", "not_after": epoch.AddDate(0, 0, 60).Format(time.RFC3339)},
+		{"subject_cn": "Example Intermediate", "not_after": epoch.AddDate(0, 0, 20).Format(time.RFC3339)},
+	}})
+	data := string(chain)
+	createStatus(t, models.MonitorStatus{TargetID: web.ID, Status: "up", Data: &data})
+
+	mail := createTarget(t, "mail", true)
+	createStatus(t, models.MonitorStatus{TargetID: mail.ID, Status: "critical", SSLDaysUntilExpiry: days(10), SSLSubject: &subject})
+
+	// Disabled, and outside the window
+	old := createTarget(t, "old", false)
+	createStatus(t, models.MonitorStatus{TargetID: old.ID, Status: "up", SSLDaysUntilExpiry: days(5)})
+	far := createTarget(t, "far", true)
+	createStatus(t, models.MonitorStatus{TargetID: far.ID, Status: "up", SSLDaysUntilExpiry: days(90)})
+
+	// Two targets presenting the same certificate, known only from the inventory
+	cert := models.Certificate{Fingerprint: "ab12", Subject: "*.api.example.com", NotAfter: epoch.AddDate(0, 0, 30)}
+	db.Create(&cert)
+	for _, name := range []string{"api-a", "api-b"} {
+		target := createTarget(t, name, true)
+		db.Create(&models.CertificateTarget{CertificateID: cert.ID, TargetID: target.ID, Current: true})
+	}
+
+	entries, err := CollectCertDigest(db, epoch, 45)
+	if err != nil {
+		t.Fatalf("CollectCertDigest: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("%d entries, want 3: %+v", len(entries), entries)
+	}
+
+	if e := entries[0]; e.TargetName != "mail" || e.DaysLeft != 10 || !e.Critical || e.Subject != subject {
+		t.Errorf("first entry %+v, want mail, 10 days, critical", e)
+	}
+	if e := entries[1]; e.TargetName != "web" || e.DaysLeft != 20 || e.Critical || e.Subject != "Example Intermediate" {
+		t.Errorf("second entry %+v, want the web intermediate at 20 days", e)
+	}
+	e := entries[2]
+	if e.DaysLeft != 30 || e.Fingerprint != "ab12" || len(e.Targets) != 2 {
+		t.Fatalf("third entry %+v, want the shared certificate at 30 days", e)
+	}
+	if e.TargetName != "api-a" || e.Targets[1].Name != "api-b" {
+		t.Errorf("shared certificate targets %+v, want api-a then api-b", e.Targets)
+	}
+}
+
+func TestRenderCertDigest(t *testing.T) {
+	data := digestData{WindowDays: 45, GeneratedAt: epoch, Entries: []DigestEntry{{
+		TargetName: "mail", Subject: "mail.example.com", ExpiresAt: epoch.AddDate(0, 0, 10), DaysLeft: 10, Critical: true,
+		Targets: []DigestTarget{{Name: "mail"}, {Name: "smtp"}},
+	}}}
+
+	table, err := renderCertDigest("email", data)
+	if err != nil {
+		t.Fatalf("render email: %v", err)
+	}
+	if !strings.Contains(table, "剩余天数") || !strings.Contains(table, "   10 天  2026-03-11  [严重] mail（mail.example.com）") {
+		t.Errorf("email digest:\n%s", table)
+	}
+	if !strings.Contains(table, "共用此证书的目标：mail、smtp") {
+		t.Errorf("email digest does not list the sharing targets:\n%s", table)
+	}
+
+	list, err := renderCertDigest("wechat", data)
+	if err != nil {
+		t.Fatalf("render wechat: %v", err)
+	}
+	if strings.Contains(list, "剩余天数") || !strings.Contains(list, "剩余 10 天，2026-03-11 到期") {
+		t.Errorf("wechat digest:\n%s", list)
+	}
+
+	empty, _ := renderCertDigest("email", digestData{WindowDays: 45})
+	if !strings.HasPrefix(empty, "一切正常") {
+		t.Errorf("empty digest %q", empty)
+	}
+}
+
+func TestDigestNextRunQuietHours(t *testing.T) {
+	cfg := config.DigestConfig{Weekday: "monday", Time: "09:00", WindowDays: 45}
+	// 2026-03-01 is a Sunday
+	sunday := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+
+	job, err := NewDigestJob(cfg)
+	if err != nil {
+		t.Fatalf("NewDigestJob: %v", err)
+	}
+	if next := job.NextRun(sunday); !next.Equal(time.Date(2026, 3, 2, 9, 0, 0, 0, time.Local)) {
+		t.Errorf("next run %v, want Monday 09:00", next)
+	}
+
+	cfg.QuietHours = config.QuietHoursConfig{Start: "08:00", End: "10:30"}
+	job, _ = NewDigestJob(cfg)
+	if next := job.NextRun(sunday); !next.Equal(time.Date(2026, 3, 2, 10, 30, 0, 0, time.Local)) {
+		t.Errorf("next run %v, want Monday 10:30 after quiet hours", next)
+	}
+
+	// Quiet hours across midnight
+	cfg.QuietHours = config.QuietHoursConfig{Start: "22:00", End: "09:30"}
+	job, _ = NewDigestJob(cfg)
+	if next := job.NextRun(sunday); !next.Equal(time.Date(2026, 3, 2, 9, 30, 0, 0, time.Local)) {
+		t.Errorf("next run %v, want Monday 09:30", next)
+	}
+
+	cfg.WindowDays = 0
+	if _, err := NewDigestJob(cfg); err == nil {
+		t.Error("window_days 0 accepted")
+	}
+	cfg.WindowDays, cfg.Time = 45, "25:00"
+	if _, err := NewDigestJob(cfg); err == nil {
+		t.Error("time 25:00 accepted")
+	}
+}
+
+func TestDigestRun(t *testing.T) {
+	h := newAlertHarness(t)
+	job, err := NewDigestJob(config.DigestConfig{ChannelID: h.channel.ID, Weekday: "monday", Time: "09:00", WindowDays: 45})
+	if err != nil {
+		t.Fatalf("NewDigestJob: %v", err)
+	}
+	job.SetClock(h.clock)
+
+	// Nothing expires and send_empty is off: no message
+	if err := job.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if h.received.Load() != 0 {
+		t.Fatal("empty digest sent with send_empty off")
+	}
+
+	days := 10
+	createStatus(t, models.MonitorStatus{TargetID: h.target.ID, Status: "up", SSLDaysUntilExpiry: &days})
+	if err := job.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	history := h.waitHistory(t, 1)
+	if h.received.Load() != 1 || history[0].Severity != "digest" || history[0].Status != "sent" {
+		t.Fatalf("received %d, history %+v, want one sent digest", h.received.Load(), history[0])
+	}
+	if !strings.Contains(history[0].Message, "剩余 10 天") {
+		t.Errorf("digest message %q", history[0].Message)
+	}
+
+	// A failed send is returned and recorded
+	h.failing.Store(true)
+	if err := job.Run(); err == nil {
+		t.Error("Run succeeded against a failing channel")
+	}
+	if history := h.waitHistory(t, 2); history[1].Status != "failed" {
+		t.Errorf("history status %q, want failed", history[1].Status)
+	}
+}
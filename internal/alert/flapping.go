@@ -0,0 +1,92 @@
+package alert
+
+import (
+	"fmt"
+	"strings"
+
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// historySeverityFlapping 目标开始抖动时发出的告警在告警历史中使用的 severity
+const historySeverityFlapping = "flapping"
+
+// sendFlappingAlert sends one flapping alert for a target that started
+// flapping, once per channel of its enabled rules. Thresholds and cooldowns do
+// not apply; snoozed rules and disabled or degraded channels are skipped as
+// for other alerts.
+func (s *Service) sendFlappingAlert(target models.MonitorTarget, rules []models.AlertRule, metadata map[string]string, synthetic bool) {
+	db := database.GetDB()
+	now := s.clock.Now()
+
+	title := fmt.Sprintf("监控抖动: %s", target.Name)
+	if synthetic {
+		title = "[演练] " + title
+	}
+	var message strings.Builder
+	message.WriteString("监控目标状态频繁变化，在状态稳定之前不再发送单次的故障和恢复告警。")
+	if len(metadata) > 0 {
+		message.WriteString("\n\n详细信息:")
+		for k, v := range metadata {
+			message.WriteString(fmt.Sprintf("\n%s: %s", k, v))
+		}
+	}
+	formatted := FormatAlertMessage(AlertMessage{
+		Title:      title,
+		Message:    message.String(),
+		Target:     target.Name,
+		Status:     historySeverityFlapping,
+		Metadata:   metadata,
+		Notes:      target.Notes,
+		RunbookURL: target.RunbookURL,
+	})
+
+	sent := make(map[uint]bool)
+	for _, rule := range rules {
+		if sent[rule.ChannelID] {
+			continue
+		}
+		channel, err := s.channels.get(rule.ChannelID)
+		if err != nil || !channel.Enabled {
+			continue
+		}
+		history := models.AlertHistory{
+			RuleID:    uint32(rule.ID),
+			TargetID:  target.ID,
+			ChannelID: channel.ID,
+			Severity:  historySeverityFlapping,
+			Message:   formatted,
+			Synthetic: synthetic,
+		}
+
+		// Another rule on the same channel may not be snoozed
+		if ruleSnoozed(rule, now) {
+			history.Status = "snoozed"
+			history.SnoozeID = s.activeSnoozeID(rule.ID, now)
+			history.SentAt = now
+			if err := db.Create(&history).Error; err != nil {
+				logger.Warn("Failed to record alert history", zap.Uint32("rule_id", history.RuleID), zap.Error(err))
+			}
+			continue
+		}
+		sent[rule.ChannelID] = true
+
+		if s.health.Enabled && channel.Health == ChannelDegraded {
+			history.Status = "skipped"
+			history.SentAt = now
+			if err := db.Create(&history).Error; err != nil {
+				logger.Warn("Failed to record alert history", zap.Uint32("rule_id", history.RuleID), zap.Error(err))
+			}
+			continue
+		}
+		notifier, err := s.notifierFor(channel)
+		if err != nil {
+			logger.Warn("Failed to create notifier", zap.Uint32("channel_id", channel.ID), zap.Error(err))
+			continue
+		}
+		go s.deliver(notifier, title, formatted, history)
+	}
+}
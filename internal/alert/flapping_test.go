@@ -0,0 +1,46 @@
+package alert
+
+import (
+	"testing"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+// A target that starts flapping gets one flapping alert per channel, then no
+// alerts while it flaps
+func TestFlappingAlert(t *testing.T) {
+	h := newAlertHarness(t)
+	backup, backupReceived := h.addChannel(t, "backup")
+	h.addRule(t, models.AlertRule{})
+	h.addRule(t, models.AlertRule{ThresholdType: "status_change"})
+	if err := database.GetDB().Create(&models.AlertRule{TargetID: h.target.ID, ChannelID: uint(backup.ID), Enabled: true}).Error; err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+
+	h.send(t, CheckEvent{Status: "down", PreviousStatus: "up", Flapping: true, FlappingStarted: true})
+	history := h.waitHistory(t, 2)
+	channels := map[uint32]bool{}
+	for _, entry := range history {
+		if entry.Severity != historySeverityFlapping || entry.Status != "sent" {
+			t.Errorf("flapping alert %+v", entry)
+		}
+		channels[entry.ChannelID] = true
+	}
+	if len(channels) != 2 || h.received.Load() != 1 || backupReceived.Load() != 1 {
+		t.Errorf("flapping alerts on channels %v, %d and %d deliveries, want one per channel", channels, h.received.Load(), backupReceived.Load())
+	}
+
+	// Still flapping: nothing, whatever the rules say
+	h.send(t, CheckEvent{Status: "up", PreviousStatus: "down", Flapping: true})
+	h.send(t, CheckEvent{Status: "down", PreviousStatus: "up", Flapping: true})
+
+	// Stable again, the rules apply; the flapping alert took no cooldown
+	h.send(t, CheckEvent{Status: "down", PreviousStatus: "up"})
+	history = h.waitHistory(t, 5)
+	for _, entry := range history[2:] {
+		if entry.Severity != "down" {
+			t.Errorf("alert after flapping %+v, want down", entry)
+		}
+	}
+}
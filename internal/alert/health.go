@@ -0,0 +1,387 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Delivery health of an alert channel
+const (
+	ChannelHealthy  = "healthy"
+	ChannelDegraded = "degraded"
+)
+
+// historySeverityChannel 渠道健康相关的告警历史（状态变化和通知）使用的 severity
+const historySeverityChannel = "channel"
+
+// deliveryStatuses are the alert history statuses of a delivery attempt
+var deliveryStatuses = []string{"sent", "failed"}
+
+// ChannelHealthPolicy decides when a channel whose deliveries keep failing is
+// marked degraded. A degraded channel gets no alerts, only a probe every
+// ProbeInterval, and is healthy again once a probe gets through.
+type ChannelHealthPolicy struct {
+	Enabled             bool
+	ConsecutiveFailures int           // failed deliveries in a row; 0 disables the check
+	Window              time.Duration // rolling window of FailurePercent
+	FailurePercent      int           // failed share of the deliveries in Window; 0 disables the check
+	MinAttempts         int           // deliveries in Window before FailurePercent applies
+	ProbeInterval       time.Duration
+}
+
+// SetChannelHealthPolicy enables channel health tracking; it must be called before alerts are sent
+func (s *Service) SetChannelHealthPolicy(policy ChannelHealthPolicy) {
+	s.health = policy
+}
+
+// DeliveryStats counts the deliveries through a channel in the health window
+type DeliveryStats struct {
+	Attempts    int64   `json:"attempts"`
+	Failures    int64   `json:"failures"`
+	SuccessRate float64 `json:"success_rate"` // percent, 100 when there were no attempts
+}
+
+// ChannelDeliveryStats returns the delivery counts of the given channels over
+// the health window (the last hour when tracking is disabled). Alerts, digests
+// and channel notifications are counted; tests and probes are not recorded.
+func (s *Service) ChannelDeliveryStats(ctx context.Context, channelIDs ...uint32) (map[uint32]DeliveryStats, error) {
+	window := s.health.Window
+	if window <= 0 {
+		window = time.Hour
+	}
+
+	var rows []struct {
+		ChannelID uint32
+		Status    string
+		Count     int64
+	}
+	query := database.GetDB().WithContext(ctx).Model(&models.AlertHistory{}).
+		Select("channel_id, status, COUNT(*) AS count").
+		Where("status IN ? AND sent_at >= ?", deliveryStatuses, s.clock.Now().Add(-window))
+	if len(channelIDs) > 0 {
+		query = query.Where("channel_id IN ?", channelIDs)
+	}
+	if err := query.Group("channel_id, status").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	stats := make(map[uint32]DeliveryStats)
+	for _, row := range rows {
+		st := stats[row.ChannelID]
+		st.Attempts += row.Count
+		if row.Status == "failed" {
+			st.Failures += row.Count
+		}
+		stats[row.ChannelID] = st
+	}
+	for id, st := range stats {
+		st.SuccessRate = float64(st.Attempts-st.Failures) * 100 / float64(st.Attempts)
+		stats[id] = st
+	}
+	return stats, nil
+}
+
+// deliver sends a message through a channel, records the attempt in the alert
+// history and re-evaluates the health of the channel
+func (s *Service) deliver(n Notifier, title, message string, history models.AlertHistory) {
+	history.Status = "sent"
+	if err := n.Send(title, message); err != nil {
+		logger.Warn("Failed to send alert", zap.Uint32("channel_id", history.ChannelID), zap.Error(err))
+		history.Status = "failed"
+	}
+	history.SentAt = s.clock.Now()
+	if err := database.GetDB().Create(&history).Error; err != nil {
+		logger.Warn("Failed to record alert history", zap.Uint32("rule_id", history.RuleID), zap.Error(err))
+	}
+	s.checkChannelHealth(history.ChannelID)
+}
+
+// checkChannelHealth marks the channel degraded if its recent deliveries fail
+// too often. Only deliveries since the last health change count, so a
+// recovered channel starts over.
+func (s *Service) checkChannelHealth(channelID uint32) {
+	policy := s.health
+	if !policy.Enabled {
+		return
+	}
+
+	db := database.GetDB()
+	var channel models.AlertChannel
+	if err := db.First(&channel, channelID).Error; err != nil {
+		return
+	}
+	if channel.SuppressHealth || channel.Health == ChannelDegraded {
+		return
+	}
+
+	since := s.clock.Now().Add(-policy.Window)
+	if channel.HealthChangedAt != nil && channel.HealthChangedAt.After(since) {
+		since = *channel.HealthChangedAt
+	}
+	reason, err := deliveryFailure(db, channelID, since, policy)
+	if err != nil {
+		logger.Warn("Failed to evaluate alert channel health", zap.Uint32("channel_id", channelID), zap.Error(err))
+		return
+	}
+	if reason != "" {
+		s.degradeChannel(channel, reason)
+	}
+}
+
+// deliveryFailure returns why the deliveries of a channel since a time fail
+// too often, or "" if they do not
+func deliveryFailure(db *gorm.DB, channelID uint32, since time.Time, policy ChannelHealthPolicy) (string, error) {
+	deliveries := func() *gorm.DB {
+		return db.Model(&models.AlertHistory{}).
+			Where("channel_id = ? AND status IN ? AND sent_at >= ?", channelID, deliveryStatuses, since)
+	}
+
+	if policy.ConsecutiveFailures > 0 {
+		var statuses []string
+		if err := deliveries().Order("sent_at DESC, id DESC").Limit(policy.ConsecutiveFailures).
+			Pluck("status", &statuses).Error; err != nil {
+			return "", err
+		}
+		failed := 0
+		for _, status := range statuses {
+			if status != "failed" {
+				break
+			}
+			failed++
+		}
+		if failed >= policy.ConsecutiveFailures {
+			return fmt.Sprintf("连续 %d 次发送失败", failed), nil
+		}
+	}
+
+	if policy.FailurePercent > 0 {
+		var attempts, failures int64
+		if err := deliveries().Count(&attempts).Error; err != nil {
+			return "", err
+		}
+		if attempts < int64(policy.MinAttempts) {
+			return "", nil
+		}
+		if err := deliveries().Where("status = ?", "failed").Count(&failures).Error; err != nil {
+			return "", err
+		}
+		if failures*100 > attempts*int64(policy.FailurePercent) {
+			return fmt.Sprintf("最近 %s 内 %d 次发送有 %d 次失败", policy.Window, attempts, failures), nil
+		}
+	}
+	return "", nil
+}
+
+// degradeChannel marks a channel degraded and tells the other channels about it
+func (s *Service) degradeChannel(channel models.AlertChannel, reason string) {
+	db := database.GetDB()
+	now := s.clock.Now()
+
+	// 条件更新：同时失败的多次发送只有一次完成状态切换
+	result := db.Model(&models.AlertChannel{}).
+		Where("id = ? AND (health IS NULL OR health <> ?) AND suppress_health = ?", channel.ID, ChannelDegraded, false).
+		Updates(map[string]interface{}{
+			"health":            ChannelDegraded,
+			"health_reason":     reason,
+			"health_changed_at": now,
+		})
+	if result.Error != nil {
+		logger.Warn("Failed to mark alert channel degraded", zap.Uint32("channel_id", channel.ID), zap.Error(result.Error))
+		return
+	}
+	if result.RowsAffected != 1 {
+		return
+	}
+	s.InvalidateChannel(uint(channel.ID))
+
+	logger.Warn("Alert channel degraded",
+		zap.Uint32("channel_id", channel.ID),
+		zap.String("name", channel.Name),
+		zap.String("reason", reason),
+	)
+	s.recordHealthChange(channel.ID, ChannelDegraded, fmt.Sprintf("告警渠道 %s 已标记为异常：%s", channel.Name, reason), now)
+	s.notifyChannels(channel.ID,
+		fmt.Sprintf("告警渠道异常: %s", channel.Name),
+		fmt.Sprintf("告警渠道 %s（%s）%s，已暂停向其发送告警，在恢复前发往该渠道的告警会丢失。\n\n系统每 %s 向该渠道发送一次探测消息，发送成功后自动恢复。",
+			channel.Name, channel.Type, reason, s.health.ProbeInterval),
+	)
+}
+
+// RecoverChannel marks a degraded channel healthy again; it does nothing for a
+// healthy channel
+func (s *Service) RecoverChannel(id uint32, reason string) error {
+	db := database.GetDB()
+	var channel models.AlertChannel
+	if err := db.First(&channel, id).Error; err != nil {
+		return err
+	}
+	now := s.clock.Now()
+
+	result := db.Model(&models.AlertChannel{}).
+		Where("id = ? AND health = ?", id, ChannelDegraded).
+		Updates(map[string]interface{}{
+			"health":            ChannelHealthy,
+			"health_reason":     "",
+			"health_changed_at": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return nil
+	}
+	s.InvalidateChannel(uint(id))
+
+	logger.Info("Alert channel recovered",
+		zap.Uint32("channel_id", id),
+		zap.String("name", channel.Name),
+		zap.String("reason", reason),
+	)
+	s.recordHealthChange(id, ChannelHealthy, fmt.Sprintf("告警渠道 %s 已恢复：%s", channel.Name, reason), now)
+	if s.health.Enabled {
+		s.notifyChannels(id,
+			fmt.Sprintf("告警渠道恢复: %s", channel.Name),
+			fmt.Sprintf("告警渠道 %s（%s）已恢复（%s），之后的告警会正常发送到该渠道。", channel.Name, channel.Type, reason),
+		)
+	}
+	return nil
+}
+
+// recordHealthChange writes a health transition of a channel to the alert history
+func (s *Service) recordHealthChange(channelID uint32, health, message string, at time.Time) {
+	history := models.AlertHistory{
+		ChannelID: channelID,
+		Severity:  historySeverityChannel,
+		Status:    health,
+		Message:   message,
+		SentAt:    at,
+	}
+	if err := database.GetDB().Create(&history).Error; err != nil {
+		logger.Warn("Failed to record alert channel health change", zap.Uint32("channel_id", channelID), zap.Error(err))
+	}
+}
+
+// notifyChannels sends a message about a channel through every other enabled, healthy channel
+func (s *Service) notifyChannels(exceptID uint32, title, message string) {
+	channels, err := healthyChannels(exceptID)
+	if err != nil {
+		logger.Warn("Failed to list alert channels for a channel notification", zap.Error(err))
+		return
+	}
+	if len(channels) == 0 {
+		logger.Warn("No healthy alert channel left to send a channel notification", zap.String("title", title))
+		return
+	}
+	s.broadcast(channels, "告警渠道", historySeverityChannel, title, message)
+}
+
+// healthyChannels lists the enabled channels that are not degraded, except exceptID
+func healthyChannels(exceptID uint32) ([]models.AlertChannel, error) {
+	var channels []models.AlertChannel
+	err := database.GetDB().
+		Where("id <> ? AND enabled = ? AND (health IS NULL OR health <> ?)", exceptID, true, ChannelDegraded).
+		Find(&channels).Error
+	return channels, err
+}
+
+// broadcast sends a message that is not about a monitor target through channels,
+// recording it in the alert history with the given severity
+func (s *Service) broadcast(channels []models.AlertChannel, target, severity, title, message string) {
+	for _, channel := range channels {
+		notifier, err := s.notifierFor(channel)
+		if err != nil {
+			logger.Warn("Failed to create notifier", zap.Uint32("channel_id", channel.ID), zap.Error(err))
+			continue
+		}
+		msg := AlertMessage{
+			Title:   title,
+			Message: message,
+			Target:  target,
+			Status:  severity,
+		}
+		formatted := FormatAlertMessage(msg)
+		history := models.AlertHistory{
+			ChannelID: channel.ID,
+			Severity:  severity,
+			Message:   formatted,
+		}
+		go s.deliver(notifier, title, formatted, history)
+	}
+}
+
+// notifierFor creates the notifier of a channel from its config
+func (s *Service) notifierFor(channel models.AlertChannel) (Notifier, error) {
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(channel.Config), &config); err != nil {
+		return nil, fmt.Errorf("failed to parse channel config: %w", err)
+	}
+	return s.factory.CreateNotifier(channel.Type, config)
+}
+
+// StartHealthProbe probes the degraded channels every ProbeInterval until ctx
+// is done. It does nothing when channel health tracking is disabled.
+func (s *Service) StartHealthProbe(ctx context.Context) {
+	if !s.health.Enabled || s.health.ProbeInterval <= 0 {
+		return
+	}
+	go func() {
+		ticker := s.clock.NewTicker(s.health.ProbeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				s.ProbeDegradedChannels()
+			}
+		}
+	}()
+}
+
+// ProbeDegradedChannels sends a probe message through every enabled degraded
+// channel and recovers those where it gets through. Probes are not recorded
+// as deliveries.
+func (s *Service) ProbeDegradedChannels() {
+	var channels []models.AlertChannel
+	if err := database.GetDB().Where("health = ? AND enabled = ?", ChannelDegraded, true).Find(&channels).Error; err != nil {
+		logger.Warn("Failed to list degraded alert channels", zap.Error(err))
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, channel := range channels {
+		notifier, err := s.notifierFor(channel)
+		if err != nil {
+			logger.Warn("Failed to create notifier", zap.Uint32("channel_id", channel.ID), zap.Error(err))
+			continue
+		}
+		wg.Add(1)
+		go func(channel models.AlertChannel, n Notifier) {
+			defer wg.Done()
+			msg := AlertMessage{
+				Title:   "告警渠道探测",
+				Message: fmt.Sprintf("告警渠道 %s 因发送失败已暂停使用，收到这条探测消息说明渠道已经恢复。", channel.Name),
+				Target:  channel.Name,
+				Status:  ChannelDegraded,
+			}
+			if err := n.Send(msg.Title, FormatAlertMessage(msg)); err != nil {
+				logger.Debug("Alert channel probe failed", zap.Uint32("channel_id", channel.ID), zap.Error(err))
+				return
+			}
+			if err := s.RecoverChannel(channel.ID, "探测消息发送成功"); err != nil {
+				logger.Warn("Failed to recover alert channel", zap.Uint32("channel_id", channel.ID), zap.Error(err))
+			}
+		}(channel, notifier)
+	}
+	wg.Wait()
+}
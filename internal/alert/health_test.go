@@ -0,0 +1,165 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+// addChannel stores a second WeChat channel and counts what it receives
+func (h *alertHarness) addChannel(t *testing.T, name string) (models.AlertChannel, *atomic.Int32) {
+	t.Helper()
+	var received atomic.Int32
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+	}))
+	t.Cleanup(hook.Close)
+	config, _ := json.Marshal(map[string]string{"webhook_url": hook.URL})
+	channel := models.AlertChannel{Name: name, Type: "wechat", Enabled: true, Config: string(config)}
+	if err := database.GetDB().Create(&channel).Error; err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	return channel, &received
+}
+
+func waitChannelHealth(t *testing.T, id uint32, health string) models.AlertChannel {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var channel models.AlertChannel
+		database.GetDB().First(&channel, id)
+		if channel.Health == health {
+			return channel
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("channel health %q, want %q", channel.Health, health)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func historyStatuses(t *testing.T, channelID uint32) []string {
+	t.Helper()
+	var statuses []string
+	database.GetDB().Model(&models.AlertHistory{}).Where("channel_id = ?", channelID).Order("id").Pluck("status", &statuses)
+	return statuses
+}
+
+// Consecutive failures degrade the channel: other channels are told, its
+// alerts are skipped, and a probe that gets through recovers it
+func TestChannelDegradesAndRecovers(t *testing.T) {
+	h := newAlertHarness(t)
+	h.s.SetChannelHealthPolicy(ChannelHealthPolicy{Enabled: true, ConsecutiveFailures: 2, Window: time.Hour, ProbeInterval: 5 * time.Minute})
+	other, otherReceived := h.addChannel(t, "backup")
+	h.addRule(t, models.AlertRule{ThresholdType: "failure_count", ThresholdValue: 1, CooldownSeconds: 1})
+	h.failing.Store(true)
+
+	h.send(t, CheckEvent{Status: "down"})
+	h.waitHistory(t, 1)
+	h.clock.Advance(2 * time.Second)
+	h.send(t, CheckEvent{Status: "down", PreviousStatus: "down"})
+
+	degraded := waitChannelHealth(t, h.channel.ID, ChannelDegraded)
+	if degraded.HealthReason != "连续 2 次发送失败" || degraded.HealthChangedAt == nil {
+		t.Errorf("degraded channel %+v", degraded)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for otherReceived.Load() != 1 || len(historyStatuses(t, other.ID)) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("the other channel was not told about the degraded one")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// While degraded the alert is recorded but not sent
+	received := h.received.Load()
+	h.clock.Advance(2 * time.Second)
+	h.send(t, CheckEvent{Status: "down", PreviousStatus: "down"})
+	if got := historyStatuses(t, h.channel.ID); strings.Join(got, ",") != "failed,failed,degraded,skipped" {
+		t.Errorf("channel history %v", got)
+	}
+	if h.received.Load() != received {
+		t.Error("an alert was sent to the degraded channel")
+	}
+
+	h.s.ProbeDegradedChannels()
+	waitChannelHealth(t, h.channel.ID, ChannelDegraded)
+	if h.received.Load() != received+1 {
+		t.Errorf("webhook received %d requests, want the probe", h.received.Load()-received)
+	}
+
+	// With no other channel left the recovery is only logged, and the test
+	// does not leave a delivery running
+	database.GetDB().Model(&other).Update("enabled", false)
+	h.failing.Store(false)
+	h.s.ProbeDegradedChannels()
+	recovered := waitChannelHealth(t, h.channel.ID, ChannelHealthy)
+	if recovered.HealthReason != "" {
+		t.Errorf("recovered channel keeps the reason %q", recovered.HealthReason)
+	}
+	if got := historyStatuses(t, h.channel.ID); got[len(got)-1] != ChannelHealthy {
+		t.Errorf("channel history %v, want the recovery last", got)
+	}
+
+	// Only deliveries count: probes are not recorded
+	stats, err := h.s.ChannelDeliveryStats(context.Background(), h.channel.ID, other.ID)
+	if err != nil {
+		t.Fatalf("ChannelDeliveryStats: %v", err)
+	}
+	if st := stats[h.channel.ID]; st.Attempts != 2 || st.Failures != 2 || st.SuccessRate != 0 {
+		t.Errorf("channel stats %+v", st)
+	}
+}
+
+func TestDeliveryFailurePercent(t *testing.T) {
+	h := newAlertHarness(t)
+	policy := ChannelHealthPolicy{Enabled: true, Window: time.Hour, FailurePercent: 50, MinAttempts: 4}
+	db := database.GetDB()
+	record := func(status string, at time.Time) {
+		db.Create(&models.AlertHistory{ChannelID: h.channel.ID, Status: status, SentAt: at})
+	}
+	record("failed", epoch.Add(-2*time.Hour)) // outside the window
+	record("failed", epoch)
+	record("sent", epoch)
+	record("failed", epoch)
+
+	since := epoch.Add(-policy.Window)
+	if reason, err := deliveryFailure(db, h.channel.ID, since, policy); reason != "" || err != nil {
+		t.Errorf("below min_attempts: %q, %v", reason, err)
+	}
+	record("sent", epoch)
+	if reason, _ := deliveryFailure(db, h.channel.ID, since, policy); reason != "" {
+		t.Errorf("exactly 50%% failed: %q", reason)
+	}
+	record("failed", epoch)
+	if reason, _ := deliveryFailure(db, h.channel.ID, since, policy); reason != "最近 1h0m0s 内 5 次发送有 3 次失败" {
+		t.Errorf("60%% failed: %q", reason)
+	}
+}
+
+// A channel with suppress_health set is never degraded
+func TestChannelSuppressHealth(t *testing.T) {
+	h := newAlertHarness(t)
+	h.s.SetChannelHealthPolicy(ChannelHealthPolicy{Enabled: true, ConsecutiveFailures: 1, Window: time.Hour})
+	database.GetDB().Model(&h.channel).Update("suppress_health", true)
+	h.addRule(t, models.AlertRule{ThresholdType: "failure_count", ThresholdValue: 1})
+	h.failing.Store(true)
+
+	h.send(t, CheckEvent{Status: "down"})
+	h.waitHistory(t, 1)
+	h.s.checkChannelHealth(h.channel.ID)
+	if got := historyStatuses(t, h.channel.ID); len(got) != 1 || got[0] != "failed" {
+		t.Errorf("channel history %v, want only the failed delivery", got)
+	}
+	if channel := waitChannelHealth(t, h.channel.ID, ChannelHealthy); channel.HealthChangedAt != nil {
+		t.Errorf("channel health changed at %v", channel.HealthChangedAt)
+	}
+}
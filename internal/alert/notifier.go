@@ -232,6 +232,10 @@ type AlertMessage struct {
 	Target   string
 	Status   string
 	Metadata map[string]string
+
+	// Operator notes of the target, so whoever gets paged has the runbook at hand
+	Notes      string
+	RunbookURL string
 }
 
 // FormatAlertMessage formats an alert message
@@ -252,6 +256,13 @@ func FormatAlertMessage(msg AlertMessage) string {
 
 	sb.WriteString(fmt.Sprintf("\n%s", msg.Message))
 
+	if msg.RunbookURL != "" {
+		sb.WriteString(fmt.Sprintf("\n\n处理手册: %s", msg.RunbookURL))
+	}
+	if msg.Notes != "" {
+		sb.WriteString(fmt.Sprintf("\n\n备注:\n%s", msg.Notes))
+	}
+
 	return sb.String()
 }
 
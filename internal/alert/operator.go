@@ -0,0 +1,41 @@
+package alert
+
+import (
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// historySeverityOperator 发给运维人员的系统通知（如历史归档失败）在告警历史中使用的 severity
+const historySeverityOperator = "operator"
+
+// NotifyOperators sends a message about the monitor itself rather than a
+// target. It goes to channelID if that channel is enabled and healthy, and
+// otherwise, or when channelID is 0, to every enabled, healthy channel.
+func (s *Service) NotifyOperators(channelID uint32, title, message string) {
+	var channels []models.AlertChannel
+	if channelID != 0 {
+		var channel models.AlertChannel
+		if err := database.GetDB().First(&channel, channelID).Error; err != nil {
+			logger.Warn("Operator alert channel not found, sending to all channels", zap.Uint32("channel_id", channelID), zap.Error(err))
+		} else if !channel.Enabled || channel.Health == ChannelDegraded {
+			logger.Warn("Operator alert channel is disabled or degraded, sending to all channels", zap.Uint32("channel_id", channelID))
+		} else {
+			channels = append(channels, channel)
+		}
+	}
+	if len(channels) == 0 {
+		var err error
+		if channels, err = healthyChannels(0); err != nil {
+			logger.Warn("Failed to list alert channels for an operator notification", zap.Error(err))
+			return
+		}
+	}
+	if len(channels) == 0 {
+		logger.Warn("No healthy alert channel to send an operator notification", zap.String("title", title))
+		return
+	}
+	s.broadcast(channels, "系统", historySeverityOperator, title, message)
+}
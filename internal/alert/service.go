@@ -6,33 +6,63 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
+	"monitor/internal/clock"
 	"monitor/internal/database"
 	"monitor/internal/models"
+
+	"gorm.io/gorm"
 )
 
 // Service manages alert notifications
 type Service struct {
 	factory *NotifierFactory
 	mu      sync.RWMutex
+	clock   clock.Clock
+	health  ChannelHealthPolicy
+
+	// SendAlert reads channels and rules through these caches
+	channels *readThrough[uint, models.AlertChannel]
+	rules    *readThrough[uint32, []models.AlertRule]
+
+	// Consecutive down results per target, for failure_count rules
+	streaks *downStreaks
 }
 
 // NewService creates a new alert service
 func NewService() *Service {
 	return &Service{
-		factory: NewNotifierFactory(),
+		factory:  NewNotifierFactory(),
+		clock:    clock.Real,
+		channels: newReadThrough(maxCachedChannels, loadChannel),
+		rules:    newReadThrough(maxCachedRuleSets, loadEnabledRules),
+		streaks:  newDownStreaks(maxCachedRuleSets),
 	}
 }
 
-// SendAlert sends an alert notification
-func (s *Service) SendAlert(ctx context.Context, targetID uint32, status string, metadata map[string]string) error {
+// SetClock replaces the time source used for cooldown checks
+func (s *Service) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// SendAlert evaluates the target's rules against a saved check result and
+// sends the alerts that fire. It is called for every result, in order per target.
+func (s *Service) SendAlert(ctx context.Context, event CheckEvent) error {
 	db := database.GetDB()
+	targetID, status := event.TargetID, event.Status
+
+	// Counted even without rules, so a rule added during an outage sees the streak
+	streak := s.streaks.record(event)
 
 	// Get alert rules for this target
-	var rules []models.AlertRule
-	if err := db.Where("target_id = ? AND enabled = ?", targetID, true).Find(&rules).Error; err != nil {
+	rules, err := s.rules.get(targetID)
+	if err != nil {
 		return err
 	}
+	if len(rules) == 0 {
+		return nil
+	}
 
 	// Get target info
 	var target models.MonitorTarget
@@ -40,12 +70,46 @@ func (s *Service) SendAlert(ctx context.Context, targetID uint32, status string,
 		return err
 	}
 
+	metadata := make(map[string]string, len(event.Metadata)+2)
+	for k, v := range event.Metadata {
+		metadata[k] = v
+	}
+	if event.PreviousStatus != "" {
+		metadata["previous_status"] = event.PreviousStatus
+	}
+	// Alerts triggered by injected results are labelled so nobody mistakes a rehearsal for an outage
+	synthetic := event.Synthetic
+	if synthetic {
+		metadata["synthetic"] = "true"
+	}
+
+	// A flapping target gets one flapping alert, then none until it is stable again
+	if event.Flapping {
+		if event.FlappingStarted {
+			s.sendFlappingAlert(target, rules, metadata, synthetic)
+		}
+		return nil
+	}
+
 	// Send alerts for each matching rule
 	for _, rule := range rules {
-		if s.shouldTriggerAlert(rule, status, metadata) {
+		fire, reason := s.shouldTriggerAlert(rule, event, streak)
+		if !fire && status == "up" && rule.AlertOpen {
+			// Target recovered: close the open alert but keep last_alert_time so
+			// the cooldown still applies if it flaps straight back down
+			if err := db.Model(&models.AlertRule{}).Where("id = ?", rule.ID).
+				Updates(map[string]interface{}{"alert_open": false, "alert_count": 0}).Error; err != nil {
+				log.Printf("Failed to reset alert state of rule %d: %v", rule.ID, err)
+			}
+			// The cached rule still has alert_open set
+			s.InvalidateRules(targetID)
+			continue
+		}
+
+		if fire {
 			// Get channel
-			var channel models.AlertChannel
-			if err := db.First(&channel, rule.ChannelID).Error; err != nil {
+			channel, err := s.channels.get(rule.ChannelID)
+			if err != nil {
 				log.Printf("Failed to get alert channel %d: %v", rule.ChannelID, err)
 				continue
 			}
@@ -68,50 +132,147 @@ func (s *Service) SendAlert(ctx context.Context, targetID uint32, status string,
 				continue
 			}
 
+			claimed, err := claimCooldown(db, rule, s.clock.Now())
+			if err != nil {
+				log.Printf("Failed to update alert state of rule %d: %v", rule.ID, err)
+				continue
+			}
+			if !claimed {
+				// Still in cooldown, or another worker already sent this alert
+				continue
+			}
+			// The cooldown is checked against the database, but the cached rules
+			// must show alert_open for the recovery to close the alert
+			if !rule.AlertOpen {
+				s.InvalidateRules(targetID)
+			}
+
 			// Format and send alert
+			title := fmt.Sprintf("监控告警: %s", target.Name)
+			if synthetic {
+				title = "[演练] " + title
+			}
 			msg := AlertMessage{
-				Title:    fmt.Sprintf("监控告警: %s", target.Name),
-				Message:  s.formatAlertMessage(status, metadata),
+				Title:    title,
+				Message:  s.formatAlertMessage(status, reason, metadata),
 				Target:   target.Name,
 				Status:   status,
 				Metadata: metadata,
+
+				Notes:      target.Notes,
+				RunbookURL: target.RunbookURL,
 			}
 
 			formattedMsg := FormatAlertMessage(msg)
 
-			// Send notification asynchronously
-			go func(n Notifier, title, message string) {
-				if err := n.Send(title, message); err != nil {
-					log.Printf("Failed to send alert: %v", err)
+			history := models.AlertHistory{
+				RuleID:    uint32(rule.ID),
+				TargetID:  targetID,
+				ChannelID: channel.ID,
+				Severity:  status,
+				Message:   formattedMsg,
+				Synthetic: synthetic,
+			}
+
+			// A snoozed rule sends nothing; the alert is recorded with the snooze that suppressed it
+			if now := s.clock.Now(); ruleSnoozed(rule, now) {
+				history.Status = "snoozed"
+				history.SnoozeID = s.activeSnoozeID(rule.ID, now)
+				history.SentAt = now
+				if err := db.Create(&history).Error; err != nil {
+					log.Printf("Failed to record alert history for rule %d: %v", history.RuleID, err)
+				}
+				continue
+			}
+
+			// A degraded channel only gets probes; the alert is recorded as skipped
+			if s.health.Enabled && channel.Health == ChannelDegraded {
+				history.Status = "skipped"
+				history.SentAt = s.clock.Now()
+				if err := db.Create(&history).Error; err != nil {
+					log.Printf("Failed to record alert history for rule %d: %v", history.RuleID, err)
 				}
-			}(notifier, msg.Title, formattedMsg)
+				continue
+			}
+
+			// Send notification asynchronously
+			go s.deliver(notifier, msg.Title, formattedMsg, history)
 		}
 	}
 
 	return nil
 }
 
-// shouldTriggerAlert determines if an alert should be sent based on rules
-func (s *Service) shouldTriggerAlert(rule models.AlertRule, status string, metadata map[string]string) bool {
-	// Simple implementation: trigger on any "down" status
-	if status == "down" {
-		return true
+// claimCooldown records a dispatched alert on the rule row if its cooldown has
+// elapsed. The check and the update are a single conditional UPDATE, so when two
+// workers race only one of them sees a row affected and sends the alert.
+func claimCooldown(db *gorm.DB, rule models.AlertRule, now time.Time) (bool, error) {
+	query := db.Model(&models.AlertRule{}).Where("id = ?", rule.ID)
+	if rule.CooldownSeconds > 0 {
+		cutoff := now.Add(-time.Duration(rule.CooldownSeconds) * time.Second)
+		query = query.Where("last_alert_time IS NULL OR last_alert_time <= ?", cutoff)
 	}
 
-	// Check threshold-based rules
-	if rule.ThresholdType == "response_time" {
-		if _, ok := metadata["response_time"]; ok {
-			// Compare response time with threshold
-			// This is a simplified check
-			return true
-		}
+	result := query.Updates(map[string]interface{}{
+		"last_alert_time": now,
+		"alert_open":      true,
+		"alert_count":     gorm.Expr("alert_count + 1"),
+	})
+	if result.Error != nil {
+		return false, result.Error
 	}
+	return result.RowsAffected == 1, nil
+}
 
-	return false
+// shouldTriggerAlert determines if an alert should be sent for the rule and
+// why. streak is the number of consecutive down results including this one.
+// describeRule in coverage.go explains this logic to users; change both together.
+func (s *Service) shouldTriggerAlert(rule models.AlertRule, event CheckEvent, streak int) (bool, string) {
+	switch rule.ThresholdType {
+	case "":
+		if event.Status == "down" {
+			return true, "target is down"
+		}
+	case "failure_count":
+		if streak >= max(rule.ThresholdValue, 1) {
+			return true, fmt.Sprintf("down for %d consecutive checks", streak)
+		}
+	case "response_time":
+		if rule.ThresholdValue > 0 && event.ResponseTime > int64(rule.ThresholdValue) {
+			return true, fmt.Sprintf("response time %dms > %dms", event.ResponseTime, rule.ThresholdValue)
+		}
+	case "status_change":
+		// A change back to up closes the open alert instead
+		if event.Status != event.PreviousStatus && event.Status != "up" {
+			from := event.PreviousStatus
+			if from == "" {
+				from = "unknown"
+			}
+			return true, fmt.Sprintf("status changed from %s to %s", from, event.Status)
+		}
+	case "degraded":
+		// Only degraded itself: a down result is for the rules on down
+		if event.Status == "degraded" {
+			return true, "target is degraded"
+		}
+	case "divergence":
+		if event.Divergence {
+			return true, "secondary address diverged from the primary"
+		}
+	case "content_changed":
+		if event.ContentChanged {
+			return true, "content changed"
+		}
+	case "jitter":
+		if rule.ThresholdValue > 0 && event.Jitter > float64(rule.ThresholdValue) {
+			return true, fmt.Sprintf("jitter %.1fms > %dms", event.Jitter, rule.ThresholdValue)
+		}
+	}
+	return false, ""
 }
 
 // formatAlertMessage formats alert message details
-func (s *Service) formatAlertMessage(status string, metadata map[string]string) string {
+func (s *Service) formatAlertMessage(status, reason string, metadata map[string]string) string {
 	var msg string
 	if status == "down" {
 		msg = "监控目标已宕机，请及时处理！"
@@ -120,6 +281,9 @@ func (s *Service) formatAlertMessage(status string, metadata map[string]string)
 	} else {
 		msg = "监控目标状态异常"
 	}
+	if reason != "" {
+		msg += "\n触发原因: " + reason
+	}
 
 	if len(metadata) > 0 {
 		msg += "\n\n详细信息:"
@@ -156,18 +320,21 @@ func (s *Service) ListAlertChannels() ([]models.AlertChannel, error) {
 // UpdateAlertChannel updates an alert channel
 func (s *Service) UpdateAlertChannel(channel *models.AlertChannel) error {
 	db := database.GetDB()
+	defer s.InvalidateChannel(uint(channel.ID))
 	return db.Save(channel).Error
 }
 
 // DeleteAlertChannel deletes an alert channel
 func (s *Service) DeleteAlertChannel(id uint) error {
 	db := database.GetDB()
+	defer s.InvalidateChannel(id)
 	return db.Delete(&models.AlertChannel{}, id).Error
 }
 
 // CreateAlertRule creates a new alert rule
 func (s *Service) CreateAlertRule(rule *models.AlertRule) error {
 	db := database.GetDB()
+	defer s.InvalidateRules(rule.TargetID)
 	return db.Create(rule).Error
 }
 
@@ -183,7 +350,7 @@ func (s *Service) GetAlertRule(id uint) (*models.AlertRule, error) {
 func (s *Service) ListAlertRules() ([]models.AlertRule, error) {
 	db := database.GetDB()
 	var rules []models.AlertRule
-	err := db.Preload("Channel").Find(&rules).Error
+	err := db.Find(&rules).Error
 	return rules, err
 }
 
@@ -191,19 +358,22 @@ func (s *Service) ListAlertRules() ([]models.AlertRule, error) {
 func (s *Service) ListAlertRulesByTarget(targetID uint32) ([]models.AlertRule, error) {
 	db := database.GetDB()
 	var rules []models.AlertRule
-	err := db.Where("target_id = ?", targetID).Preload("Channel").Find(&rules).Error
+	err := db.Where("target_id = ?", targetID).Find(&rules).Error
 	return rules, err
 }
 
-// UpdateAlertRule updates an alert rule
+// UpdateAlertRule updates an alert rule. The rule may have moved to another
+// target, so every target's cached rules are dropped.
 func (s *Service) UpdateAlertRule(rule *models.AlertRule) error {
 	db := database.GetDB()
+	defer s.InvalidateAllRules()
 	return db.Save(rule).Error
 }
 
 // DeleteAlertRule deletes an alert rule
 func (s *Service) DeleteAlertRule(id uint) error {
 	db := database.GetDB()
+	defer s.InvalidateAllRules()
 	return db.Delete(&models.AlertRule{}, id).Error
 }
 
@@ -238,5 +408,13 @@ func (s *Service) TestAlertChannel(id uint) error {
 	}
 
 	formattedMsg := FormatAlertMessage(msg)
-	return notifier.Send(msg.Title, formattedMsg)
+	if err := notifier.Send(msg.Title, formattedMsg); err != nil {
+		return err
+	}
+
+	// A test that gets through is as good as a probe
+	if err := s.RecoverChannel(channel.ID, "测试消息发送成功"); err != nil {
+		log.Printf("Failed to recover alert channel %d: %v", channel.ID, err)
+	}
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,188 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"monitor/internal/clock"
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// alertHarness is an alert service on a fresh database with one target and
+// one WeChat channel posting to a local webhook
+type alertHarness struct {
+	s       *Service
+	clock   *clock.Fake
+	target  models.MonitorTarget
+	channel models.AlertChannel
+
+	received atomic.Int32 // webhook requests
+	failing  atomic.Bool  // the webhook answers 500
+}
+
+func newAlertHarness(t *testing.T) *alertHarness {
+	t.Helper()
+	logger.Log = zap.NewNop()
+	initTestDB(t)
+	db := database.GetDB()
+
+	h := &alertHarness{clock: clock.NewFake(epoch)}
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.received.Add(1)
+		if h.failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(hook.Close)
+
+	h.target = models.MonitorTarget{Name: "db", Type: "tcp", Address: "127.0.0.1", Port: 1, Interval: 60}
+	if err := db.Create(&h.target).Error; err != nil {
+		t.Fatalf("create target: %v", err)
+	}
+	config, _ := json.Marshal(map[string]string{"webhook_url": hook.URL})
+	h.channel = models.AlertChannel{Name: "hook", Type: "wechat", Enabled: true, Config: string(config)}
+	if err := db.Create(&h.channel).Error; err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+
+	h.s = h.newService()
+	return h
+}
+
+// newService returns a service sharing the harness clock and database, as
+// after a restart
+func (h *alertHarness) newService() *Service {
+	s := NewService()
+	s.SetClock(h.clock)
+	return s
+}
+
+// addRule stores a rule for the harness target and channel
+func (h *alertHarness) addRule(t *testing.T, rule models.AlertRule) models.AlertRule {
+	t.Helper()
+	rule.TargetID, rule.ChannelID, rule.Enabled = h.target.ID, uint(h.channel.ID), true
+	if err := database.GetDB().Create(&rule).Error; err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+	return rule
+}
+
+func (h *alertHarness) send(t *testing.T, event CheckEvent) {
+	t.Helper()
+	event.TargetID = h.target.ID
+	if err := h.s.SendAlert(context.Background(), event); err != nil {
+		t.Fatalf("SendAlert: %v", err)
+	}
+}
+
+// waitHistory waits until n alerts are recorded and returns them in order;
+// deliveries run in their own goroutine
+func (h *alertHarness) waitHistory(t *testing.T, n int) []models.AlertHistory {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var history []models.AlertHistory
+		if err := database.GetDB().Order("id").Find(&history).Error; err != nil {
+			t.Fatalf("load alert history: %v", err)
+		}
+		if len(history) >= n || time.Now().After(deadline) {
+			if len(history) != n {
+				t.Fatalf("%d alerts recorded, want %d", len(history), n)
+			}
+			return history
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func reloadRule(t *testing.T, id uint) models.AlertRule {
+	t.Helper()
+	var rule models.AlertRule
+	if err := database.GetDB().First(&rule, id).Error; err != nil {
+		t.Fatalf("reload rule: %v", err)
+	}
+	return rule
+}
+
+// A restarted service reads the cooldown from the rule row instead of
+// alerting again for a target that is still down
+func TestCooldownSurvivesRestart(t *testing.T) {
+	h := newAlertHarness(t)
+	rule := h.addRule(t, models.AlertRule{ThresholdType: "failure_count", ThresholdValue: 1, CooldownSeconds: 300})
+
+	h.send(t, CheckEvent{Status: "down"})
+	h.waitHistory(t, 1)
+	if got := reloadRule(t, rule.ID); !got.AlertOpen || got.AlertCount != 1 {
+		t.Fatalf("alert_open %v alert_count %d, want an open alert", got.AlertOpen, got.AlertCount)
+	}
+
+	h.s = h.newService()
+	h.clock.Advance(100 * time.Second)
+	h.send(t, CheckEvent{Status: "down"})
+	if got := reloadRule(t, rule.ID); got.AlertCount != 1 {
+		t.Errorf("alert_count = %d after a restart inside the cooldown, want 1", got.AlertCount)
+	}
+
+	h.clock.Advance(200 * time.Second)
+	h.send(t, CheckEvent{Status: "down"})
+	h.waitHistory(t, 2)
+	if got := reloadRule(t, rule.ID); got.AlertCount != 2 {
+		t.Errorf("alert_count = %d after the cooldown, want 2", got.AlertCount)
+	}
+}
+
+// Recovery closes the alert but keeps last_alert_time, so a target that goes
+// straight back down is still in cooldown
+func TestRecoveryClosesAlertAndKeepsCooldown(t *testing.T) {
+	h := newAlertHarness(t)
+	rule := h.addRule(t, models.AlertRule{ThresholdType: "failure_count", ThresholdValue: 1, CooldownSeconds: 300})
+
+	h.send(t, CheckEvent{Status: "down"})
+	h.waitHistory(t, 1)
+
+	h.clock.Advance(time.Minute)
+	h.send(t, CheckEvent{Status: "up", PreviousStatus: "down"})
+	got := reloadRule(t, rule.ID)
+	if got.AlertOpen || got.AlertCount != 0 {
+		t.Errorf("alert_open %v alert_count %d after recovery, want closed", got.AlertOpen, got.AlertCount)
+	}
+	if got.LastAlertTime == nil || !got.LastAlertTime.Equal(epoch) {
+		t.Errorf("last_alert_time = %v, want %v", got.LastAlertTime, epoch)
+	}
+
+	h.clock.Advance(time.Minute)
+	h.send(t, CheckEvent{Status: "down", PreviousStatus: "up"})
+	if got := reloadRule(t, rule.ID); got.AlertOpen {
+		t.Error("alert sent inside the cooldown after a recovery")
+	}
+	if n := h.received.Load(); n != 1 {
+		t.Errorf("webhook received %d alerts, want 1", n)
+	}
+}
+
+// Whoever gets paged sees the target's runbook link and notes
+func TestAlertCarriesOperatorNotes(t *testing.T) {
+	h := newAlertHarness(t)
+	database.GetDB().Model(&h.target).Updates(map[string]interface{}{
+		"notes": "Fail over to the replica", "runbook_url": "https://wiki.example.com/runbooks/db",
+	})
+	h.addRule(t, models.AlertRule{ThresholdType: "failure_count", ThresholdValue: 1})
+
+	h.send(t, CheckEvent{Status: "down"})
+	message := h.waitHistory(t, 1)[0].Message
+	for _, want := range []string{"处理手册: https://wiki.example.com/runbooks/db", "备注:\nFail over to the replica"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("alert message %q does not contain %q", message, want)
+		}
+	}
+}
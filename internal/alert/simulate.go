@@ -0,0 +1,144 @@
+package alert
+
+import (
+	"fmt"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+// 回放的时间窗口和记录数上限，避免一次模拟扫描过多历史数据
+const (
+	MaxSimulationWindow = 31 * 24 * time.Hour
+	MaxSimulationRows   = 20000
+)
+
+// eventBufferSize 与 Manager 保留的事件数一致
+const eventBufferSize = 100
+
+// SimulationRule 待模拟的规则，不需要事先保存
+type SimulationRule struct {
+	Conditions      AlertCondition `json:"conditions"`
+	CooldownSeconds int            `json:"cooldown_seconds"`
+}
+
+// SimulationFiring 模拟中规则会触发告警的时刻
+type SimulationFiring struct {
+	Time         time.Time `json:"time"`
+	Status       string    `json:"status"`
+	ResponseTime int64     `json:"response_time"`
+	Reason       string    `json:"reason"`
+}
+
+// SimulationResult 模拟结果
+type SimulationResult struct {
+	TargetID  uint32             `json:"target_id"`
+	StartTime time.Time          `json:"start_time"`
+	EndTime   time.Time          `json:"end_time"`
+	Evaluated int                `json:"evaluated"` // 回放的检查记录数
+	Truncated bool               `json:"truncated"` // 记录数超过上限，只回放了前 MaxSimulationRows 条
+	Firings   []SimulationFiring `json:"firings"`
+}
+
+// Simulate 按时间顺序回放事件，返回规则会触发告警的时刻。
+// 冷却时间按事件时间计算，而不是当前时间。
+func Simulate(rule SimulationRule, events []AlertEvent) []SimulationFiring {
+	firings := make([]SimulationFiring, 0)
+	cooldown := time.Duration(rule.CooldownSeconds) * time.Second
+
+	var lastAlert time.Time
+	buffer := make([]AlertEvent, 0, eventBufferSize)
+	for _, event := range events {
+		buffer = append(buffer, event)
+		if len(buffer) > eventBufferSize {
+			buffer = buffer[1:]
+		}
+
+		if cooldown > 0 && !lastAlert.IsZero() && event.Timestamp.Sub(lastAlert) < cooldown {
+			continue
+		}
+
+		if fire, reason := EvaluateConditions(buffer, rule.Conditions); fire {
+			firings = append(firings, SimulationFiring{
+				Time:         event.Timestamp,
+				Status:       event.Status,
+				ResponseTime: event.ResponseTime,
+				Reason:       reason,
+			})
+			lastAlert = event.Timestamp
+		}
+	}
+
+	return firings
+}
+
+// ConditionsFromThreshold 将数据库规则的 threshold_type/threshold_value 转换为告警条件
+func ConditionsFromThreshold(thresholdType string, thresholdValue int) (AlertCondition, error) {
+	switch thresholdType {
+	case "failure_count":
+		return AlertCondition{DownConsecutiveTimes: thresholdValue}, nil
+	case "response_time":
+		return AlertCondition{SlowResponseThreshold: int64(thresholdValue)}, nil
+	case "status_change":
+		return AlertCondition{StatusChanged: true}, nil
+	case "degraded":
+		return AlertCondition{Degraded: true}, nil
+	case "divergence":
+		return AlertCondition{Divergence: true}, nil
+	case "content_changed":
+		return AlertCondition{ContentChanged: true}, nil
+	case "jitter":
+		return AlertCondition{JitterThreshold: float64(thresholdValue)}, nil
+	default:
+		return AlertCondition{}, fmt.Errorf("unsupported threshold type: %s", thresholdType)
+	}
+}
+
+// SimulateRule 用目标在 [start, end) 内的 MonitorHistory 回放规则
+func (s *Service) SimulateRule(rule SimulationRule, targetID uint32, start, end time.Time) (*SimulationResult, error) {
+	if !end.After(start) {
+		return nil, fmt.Errorf("end_time must be after start_time")
+	}
+	if end.Sub(start) > MaxSimulationWindow {
+		return nil, fmt.Errorf("time range exceeds the maximum of %s", MaxSimulationWindow)
+	}
+
+	db := database.GetDB()
+
+	var history []models.MonitorHistory
+	if err := db.Where("target_id = ? AND checked_at >= ? AND checked_at < ?", targetID, start, end).
+		Order("checked_at ASC").Limit(MaxSimulationRows + 1).Find(&history).Error; err != nil {
+		return nil, err
+	}
+
+	result := &SimulationResult{
+		TargetID:  targetID,
+		StartTime: start,
+		EndTime:   end,
+	}
+	if len(history) > MaxSimulationRows {
+		history = history[:MaxSimulationRows]
+		result.Truncated = true
+	}
+
+	events := make([]AlertEvent, 0, len(history))
+	for _, h := range history {
+		metadata := map[string]interface{}{"divergence": h.Divergence, "content_changed": h.ContentChanged}
+		if h.Jitter != nil {
+			metadata["jitter"] = *h.Jitter
+		}
+		events = append(events, AlertEvent{
+			TargetID:     h.TargetID,
+			Status:       h.Status,
+			ResponseTime: h.ResponseTime,
+			Message:      h.Message,
+			Timestamp:    h.CheckedAt,
+			Metadata:     metadata,
+		})
+	}
+
+	result.Evaluated = len(events)
+	result.Firings = Simulate(rule, events)
+	return result, nil
+}
@@ -0,0 +1,168 @@
+package alert
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+// initTestDB opens a fresh in-memory database, closed at the end of the test
+func initTestDB(t *testing.T) {
+	t.Helper()
+	if err := database.InitMemoryDB(t.Name()); err != nil {
+		t.Fatalf("InitMemoryDB: %v", err)
+	}
+	db := database.GetDB()
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+// events returns one event per status, a minute apart from epoch
+func events(statuses ...string) []AlertEvent {
+	out := make([]AlertEvent, len(statuses))
+	for i, status := range statuses {
+		out[i] = AlertEvent{TargetID: 1, Status: status, Timestamp: epoch.Add(time.Duration(i) * time.Minute)}
+	}
+	return out
+}
+
+func firingTimes(firings []SimulationFiring) []time.Duration {
+	out := make([]time.Duration, len(firings))
+	for i, f := range firings {
+		out[i] = f.Time.Sub(epoch)
+	}
+	return out
+}
+
+func TestSimulateConsecutiveDowns(t *testing.T) {
+	rule := SimulationRule{Conditions: AlertCondition{DownConsecutiveTimes: 2}}
+	firings := Simulate(rule, events("down", "up", "down", "down", "down", "up", "down", "down"))
+
+	// Fires on every check of a run once the threshold is reached
+	want := []time.Duration{3 * time.Minute, 4 * time.Minute, 7 * time.Minute}
+	if got := firingTimes(firings); !slices.Equal(got, want) {
+		t.Fatalf("fired at %v, want %v", got, want)
+	}
+	if firings[0].Reason != "down for 2 consecutive checks" || firings[1].Reason != "down for 3 consecutive checks" {
+		t.Errorf("reasons %q, %q", firings[0].Reason, firings[1].Reason)
+	}
+}
+
+// The cooldown runs on the event timestamps, not the wall clock
+func TestSimulateCooldownUsesEventTime(t *testing.T) {
+	rule := SimulationRule{Conditions: AlertCondition{DownConsecutiveTimes: 1}, CooldownSeconds: 120}
+	firings := Simulate(rule, events("down", "down", "down", "down", "down"))
+
+	want := []time.Duration{0, 2 * time.Minute, 4 * time.Minute}
+	if got := firingTimes(firings); !slices.Equal(got, want) {
+		t.Fatalf("fired at %v, want %v", got, want)
+	}
+}
+
+func TestSimulateRuleReplaysHistory(t *testing.T) {
+	initTestDB(t)
+	db := database.GetDB()
+	jitter := 40.0
+	for i, h := range []models.MonitorHistory{
+		{Status: "down"}, // before the window
+		{Status: "up", ResponseTime: 900},
+		{Status: "up", ResponseTime: 100, Jitter: &jitter},
+		{Status: "up", ResponseTime: 1200},
+		{Status: "up", ResponseTime: 2000}, // at the end, excluded
+	} {
+		h.TargetID = 1
+		h.CheckedAt = epoch.Add(time.Duration(i-1) * time.Minute)
+		if err := db.Create(&h).Error; err != nil {
+			t.Fatalf("create history: %v", err)
+		}
+	}
+	// Another target's history is not replayed
+	db.Create(&models.MonitorHistory{TargetID: 2, Status: "up", ResponseTime: 5000, CheckedAt: epoch})
+
+	s := NewService()
+	start, end := epoch, epoch.Add(3*time.Minute)
+	result, err := s.SimulateRule(SimulationRule{Conditions: AlertCondition{SlowResponseThreshold: 800}}, 1, start, end)
+	if err != nil {
+		t.Fatalf("SimulateRule: %v", err)
+	}
+	if result.Evaluated != 3 || result.Truncated {
+		t.Errorf("evaluated %d truncated %v, want 3 rows", result.Evaluated, result.Truncated)
+	}
+	if got := firingTimes(result.Firings); !slices.Equal(got, []time.Duration{0, 2 * time.Minute}) {
+		t.Errorf("fired at %v, want 0s and 2m", got)
+	}
+
+	result, err = s.SimulateRule(SimulationRule{Conditions: AlertCondition{JitterThreshold: 30}}, 1, start, end)
+	if err != nil {
+		t.Fatalf("SimulateRule: %v", err)
+	}
+	if got := firingTimes(result.Firings); !slices.Equal(got, []time.Duration{time.Minute}) {
+		t.Errorf("jitter rule fired at %v, want 1m", got)
+	}
+
+	if _, err := s.SimulateRule(SimulationRule{}, 1, end, start); err == nil {
+		t.Error("end before start accepted")
+	}
+	if _, err := s.SimulateRule(SimulationRule{}, 1, start, start.Add(MaxSimulationWindow+time.Second)); err == nil {
+		t.Error("window over the maximum accepted")
+	}
+}
+
+func TestConditionsFromThreshold(t *testing.T) {
+	cond, err := ConditionsFromThreshold("failure_count", 3)
+	if err != nil || cond.DownConsecutiveTimes != 3 {
+		t.Errorf("failure_count: %+v %v", cond, err)
+	}
+	cond, err = ConditionsFromThreshold("response_time", 500)
+	if err != nil || cond.SlowResponseThreshold != 500 {
+		t.Errorf("response_time: %+v %v", cond, err)
+	}
+	if _, err := ConditionsFromThreshold("loudness", 1); err == nil {
+		t.Error("unknown threshold type accepted")
+	}
+
+	// A degraded rule fires on degraded results only
+	cond, err = ConditionsFromThreshold("degraded", 0)
+	if err != nil || !cond.Degraded {
+		t.Fatalf("degraded: %+v %v", cond, err)
+	}
+	for status, want := range map[string]bool{"degraded": true, "down": false, "up": false} {
+		if fire, _ := EvaluateConditions(events("up", status), cond); fire != want {
+			t.Errorf("degraded rule on %s: fire %v", status, fire)
+		}
+	}
+	// A content_changed rule fires on a changed body hash whatever the status
+	cond, err = ConditionsFromThreshold("content_changed", 0)
+	if err != nil || !cond.ContentChanged {
+		t.Fatalf("content_changed: %+v %v", cond, err)
+	}
+	changed := events("up", "up")
+	changed[1].Metadata = map[string]interface{}{"content_changed": true}
+	if fire, reason := EvaluateConditions(changed, cond); !fire || reason != "content changed" {
+		t.Errorf("content_changed rule on a change: fire %v %q", fire, reason)
+	}
+	if fire, _ := EvaluateConditions(events("up", "up"), cond); fire {
+		t.Error("content_changed rule fired without a change")
+	}
+	// A jitter rule fires above its threshold in milliseconds
+	cond, err = ConditionsFromThreshold("jitter", 10)
+	if err != nil || cond.JitterThreshold != 10 {
+		t.Fatalf("jitter: %+v %v", cond, err)
+	}
+	for jitter, want := range map[float64]bool{10.5: true, 10: false} {
+		evs := events("up")
+		evs[0].Metadata = map[string]interface{}{"jitter": jitter}
+		if fire, _ := EvaluateConditions(evs, cond); fire != want {
+			t.Errorf("jitter rule at %vms: fire %v", jitter, fire)
+		}
+	}
+	if fire, _ := EvaluateConditions(events("up"), cond); fire {
+		t.Error("jitter rule fired on a result without jitter")
+	}
+}
@@ -0,0 +1,185 @@
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// historySeveritySnooze 静默事件（开始、取消）在告警历史中使用的 severity
+const historySeveritySnooze = "snooze"
+
+// MaxSnoozeDuration bounds a snooze so that a forgotten one does not silence a rule for good
+const MaxSnoozeDuration = 7 * 24 * time.Hour
+
+// ErrNotSnoozed is returned by CancelSnooze for a rule without an active snooze
+var ErrNotSnoozed = errors.New("alert rule is not snoozed")
+
+// ruleSnoozed reports whether SendAlert skips the rule at now. The snooze
+// expires by itself; snoozed_until is only cleared when it is cancelled.
+func ruleSnoozed(rule models.AlertRule, now time.Time) bool {
+	return rule.SnoozedUntil != nil && now.Before(*rule.SnoozedUntil)
+}
+
+// SnoozeRule suppresses the alerts of one rule for duration; the other rules of
+// the target are not affected. A new snooze replaces an active one, which is
+// recorded as cancelled.
+func (s *Service) SnoozeRule(ruleID uint, duration time.Duration, note, createdBy string) (*models.AlertRuleSnooze, error) {
+	if duration <= 0 || duration > MaxSnoozeDuration {
+		return nil, fmt.Errorf("snooze duration must be between 0 and %s", MaxSnoozeDuration)
+	}
+
+	db := database.GetDB()
+	var rule models.AlertRule
+	if err := db.First(&rule, ruleID).Error; err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	snooze := &models.AlertRuleSnooze{
+		RuleID:    rule.ID,
+		TargetID:  rule.TargetID,
+		Note:      note,
+		StartsAt:  now,
+		ExpiresAt: now.Add(duration),
+		CreatedBy: createdBy,
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := activeSnoozes(tx, now, rule.ID).Update("cancelled_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(snooze).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.AlertRule{}).Where("id = ?", rule.ID).Update("snoozed_until", snooze.ExpiresAt).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.InvalidateRules(rule.TargetID)
+
+	logger.Info("Alert rule snoozed",
+		zap.Uint("rule_id", rule.ID),
+		zap.Uint32("target_id", rule.TargetID),
+		zap.Uint("snooze_id", snooze.ID),
+		zap.Time("expires_at", snooze.ExpiresAt),
+		zap.String("note", note),
+		zap.String("created_by", createdBy),
+	)
+	message := fmt.Sprintf("告警规则 %d 静默至 %s", rule.ID, snooze.ExpiresAt.Format(time.RFC3339))
+	if note != "" {
+		message += "：" + note
+	}
+	s.recordSnoozeEvent(rule, snooze.ID, "snoozed", message, now)
+	return snooze, nil
+}
+
+// CancelSnooze ends the active snooze of a rule before it expires and returns it
+func (s *Service) CancelSnooze(ruleID uint, cancelledBy string) (*models.AlertRuleSnooze, error) {
+	db := database.GetDB()
+	var rule models.AlertRule
+	if err := db.First(&rule, ruleID).Error; err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	var snooze models.AlertRuleSnooze
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var found []models.AlertRuleSnooze
+		if err := activeSnoozes(tx, now, rule.ID).Order("id DESC").Limit(1).Find(&found).Error; err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			return ErrNotSnoozed
+		}
+		snooze = found[0]
+		snooze.CancelledAt = &now
+		if err := tx.Model(&snooze).Update("cancelled_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.AlertRule{}).Where("id = ?", rule.ID).Update("snoozed_until", nil).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.InvalidateRules(rule.TargetID)
+
+	logger.Info("Alert rule snooze cancelled",
+		zap.Uint("rule_id", rule.ID),
+		zap.Uint32("target_id", rule.TargetID),
+		zap.Uint("snooze_id", snooze.ID),
+		zap.String("cancelled_by", cancelledBy),
+	)
+	s.recordSnoozeEvent(rule, snooze.ID, "cancelled", fmt.Sprintf("告警规则 %d 的静默已提前取消", rule.ID), now)
+	return &snooze, nil
+}
+
+// ActiveSnoozes returns the snoozes in effect for the given rules, by rule ID
+func (s *Service) ActiveSnoozes(ruleIDs ...uint) (map[uint]*models.AlertRuleSnooze, error) {
+	snoozes := make(map[uint]*models.AlertRuleSnooze)
+	if len(ruleIDs) == 0 {
+		return snoozes, nil
+	}
+	var found []models.AlertRuleSnooze
+	if err := activeSnoozes(database.GetDB(), s.clock.Now(), ruleIDs...).Order("id").Find(&found).Error; err != nil {
+		return nil, err
+	}
+	for i := range found {
+		snoozes[found[i].RuleID] = &found[i]
+	}
+	return snoozes, nil
+}
+
+// AttachSnoozes fills in the Snooze of rules that are snoozed
+func (s *Service) AttachSnoozes(rules []models.AlertRule) error {
+	ids := make([]uint, 0, len(rules))
+	for _, rule := range rules {
+		ids = append(ids, rule.ID)
+	}
+	snoozes, err := s.ActiveSnoozes(ids...)
+	if err != nil {
+		return err
+	}
+	for i := range rules {
+		rules[i].Snooze = snoozes[rules[i].ID]
+	}
+	return nil
+}
+
+// activeSnoozeID returns the ID of the snooze that suppresses a rule's alert, 0 if none is found
+func (s *Service) activeSnoozeID(ruleID uint, now time.Time) uint {
+	var ids []uint
+	if err := activeSnoozes(database.GetDB(), now, ruleID).Order("id DESC").Limit(1).Pluck("id", &ids).Error; err != nil || len(ids) == 0 {
+		return 0
+	}
+	return ids[0]
+}
+
+func activeSnoozes(db *gorm.DB, now time.Time, ruleIDs ...uint) *gorm.DB {
+	return db.Model(&models.AlertRuleSnooze{}).
+		Where("rule_id IN ? AND cancelled_at IS NULL AND starts_at <= ? AND expires_at > ?", ruleIDs, now, now)
+}
+
+// recordSnoozeEvent writes the start or early end of a snooze to the alert history
+func (s *Service) recordSnoozeEvent(rule models.AlertRule, snoozeID uint, status, message string, at time.Time) {
+	history := models.AlertHistory{
+		RuleID:    uint32(rule.ID),
+		TargetID:  rule.TargetID,
+		ChannelID: uint32(rule.ChannelID),
+		Severity:  historySeveritySnooze,
+		Status:    status,
+		Message:   message,
+		SnoozeID:  snoozeID,
+		SentAt:    at,
+	}
+	if err := database.GetDB().Create(&history).Error; err != nil {
+		logger.Warn("Failed to record alert rule snooze", zap.Uint("rule_id", rule.ID), zap.Error(err))
+	}
+}
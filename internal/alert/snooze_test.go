@@ -0,0 +1,110 @@
+package alert
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+func snoozeHistory(t *testing.T, ruleID uint) []models.AlertHistory {
+	t.Helper()
+	var history []models.AlertHistory
+	database.GetDB().Where("rule_id = ?", ruleID).Order("id").Find(&history)
+	return history
+}
+
+// A snoozed rule records its alerts as snoozed without sending them, and
+// fires again once the snooze expires; other rules of the target still fire
+func TestSnoozeRule(t *testing.T) {
+	h := newAlertHarness(t)
+	snoozed := h.addRule(t, models.AlertRule{ThresholdType: "failure_count", ThresholdValue: 1, CooldownSeconds: 1})
+	other := h.addRule(t, models.AlertRule{ThresholdType: "failure_count", ThresholdValue: 1, CooldownSeconds: 1})
+
+	snooze, err := h.s.SnoozeRule(snoozed.ID, time.Hour, "deploy", "api")
+	if err != nil {
+		t.Fatalf("SnoozeRule: %v", err)
+	}
+	if !snooze.ExpiresAt.Equal(epoch.Add(time.Hour)) || reloadRule(t, snoozed.ID).SnoozedUntil == nil {
+		t.Errorf("snooze %+v", snooze)
+	}
+
+	h.send(t, CheckEvent{Status: "down"})
+	history := snoozeHistory(t, snoozed.ID)
+	if len(history) != 2 || history[0].Status != "snoozed" || history[0].Severity != historySeveritySnooze ||
+		history[1].Status != "snoozed" || history[1].SnoozeID != snooze.ID {
+		t.Errorf("snoozed rule history %+v", history)
+	}
+	h.waitHistory(t, 3)
+	if got := snoozeHistory(t, other.ID); len(got) != 1 || got[0].Status != "sent" {
+		t.Errorf("other rule history %+v", got)
+	}
+	if n := h.received.Load(); n != 1 {
+		t.Errorf("webhook received %d alerts, want the other rule's only", n)
+	}
+
+	active, _ := h.s.ActiveSnoozes(snoozed.ID, other.ID)
+	if len(active) != 1 || active[snoozed.ID] == nil || active[snoozed.ID].Note != "deploy" {
+		t.Errorf("active snoozes %+v", active)
+	}
+
+	// The snooze expires by itself
+	h.clock.Advance(time.Hour)
+	h.send(t, CheckEvent{Status: "down", PreviousStatus: "down"})
+	h.waitHistory(t, 5)
+	if got := snoozeHistory(t, snoozed.ID); got[len(got)-1].Status != "sent" {
+		t.Errorf("after expiry the rule's last alert is %s", got[len(got)-1].Status)
+	}
+	if active, _ := h.s.ActiveSnoozes(snoozed.ID); len(active) != 0 {
+		t.Errorf("expired snooze still active: %+v", active)
+	}
+}
+
+func TestSnoozeReplaceAndCancel(t *testing.T) {
+	h := newAlertHarness(t)
+	rule := h.addRule(t, models.AlertRule{ThresholdType: "failure_count", ThresholdValue: 1})
+
+	for _, d := range []time.Duration{0, -time.Hour, MaxSnoozeDuration + time.Second} {
+		if _, err := h.s.SnoozeRule(rule.ID, d, "", "api"); err == nil {
+			t.Errorf("snooze of %s accepted", d)
+		}
+	}
+
+	first, _ := h.s.SnoozeRule(rule.ID, time.Hour, "", "api")
+	second, err := h.s.SnoozeRule(rule.ID, 2*time.Hour, "longer", "api")
+	if err != nil {
+		t.Fatalf("second SnoozeRule: %v", err)
+	}
+	var replaced models.AlertRuleSnooze
+	database.GetDB().First(&replaced, first.ID)
+	if replaced.CancelledAt == nil {
+		t.Error("the replaced snooze was not cancelled")
+	}
+
+	h.clock.Advance(time.Minute)
+	cancelled, err := h.s.CancelSnooze(rule.ID, "api")
+	if err != nil || cancelled.ID != second.ID || cancelled.CancelledAt == nil || !cancelled.CancelledAt.Equal(epoch.Add(time.Minute)) {
+		t.Fatalf("CancelSnooze = %+v, %v", cancelled, err)
+	}
+	if reloadRule(t, rule.ID).SnoozedUntil != nil {
+		t.Error("snoozed_until kept after cancelling")
+	}
+	if _, err := h.s.CancelSnooze(rule.ID, "api"); !errors.Is(err, ErrNotSnoozed) {
+		t.Errorf("second CancelSnooze: err = %v", err)
+	}
+
+	var statuses []string
+	database.GetDB().Model(&models.AlertHistory{}).Where("severity = ?", historySeveritySnooze).Order("id").Pluck("status", &statuses)
+	if len(statuses) != 3 || statuses[0] != "snoozed" || statuses[1] != "snoozed" || statuses[2] != "cancelled" {
+		t.Errorf("snooze events %v", statuses)
+	}
+
+	// The alert goes out again right away
+	h.send(t, CheckEvent{Status: "down"})
+	h.waitHistory(t, 4)
+	if n := h.received.Load(); n != 1 {
+		t.Errorf("webhook received %d alerts after the cancel, want 1", n)
+	}
+}
@@ -0,0 +1,55 @@
+package alert
+
+import (
+	"sync"
+
+	"monitor/internal/lru"
+)
+
+// CheckEvent is a saved check result handed to SendAlert
+type CheckEvent struct {
+	TargetID       uint32
+	Status         string  // up, down, degraded
+	PreviousStatus string  // status before this result, empty for the first result of the target
+	ResponseTime   int64   // milliseconds
+	Divergence     bool    // compare mode: the secondary address disagreed with the primary
+	ContentChanged bool    // track_content_hash: the body hash differs from the previous one
+	Jitter         float64 // ping: jitter of the round trip times in milliseconds, 0 when not measured
+	Synthetic      bool    // injected by the failure injection endpoint
+	Metadata       map[string]string
+
+	// The target is flapping: its rules are not evaluated. The result that
+	// started it sends a single flapping alert instead.
+	Flapping        bool
+	FlappingStarted bool
+}
+
+// downStreaks counts the consecutive down results of each target for
+// failure_count rules. Counts live in memory and start over after a restart.
+type downStreaks struct {
+	mu      sync.Mutex
+	streaks *lru.Cache[uint32, int]
+}
+
+func newDownStreaks(max int) *downStreaks {
+	return &downStreaks{streaks: lru.New[uint32, int](max)}
+}
+
+// record adds the event to the target's streak and returns the streak,
+// 0 when the event is not down
+func (d *downStreaks) record(event CheckEvent) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if event.Status != "down" {
+		d.streaks.Remove(event.TargetID)
+		return 0
+	}
+	streak := 1
+	if event.PreviousStatus == "down" {
+		if n, ok := d.streaks.Get(event.TargetID); ok {
+			streak = n + 1
+		}
+	}
+	d.streaks.Add(event.TargetID, streak)
+	return streak
+}
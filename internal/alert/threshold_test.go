@@ -0,0 +1,68 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"monitor/internal/models"
+)
+
+func TestShouldTriggerAlert(t *testing.T) {
+	s := NewService()
+	for _, tc := range []struct {
+		name   string
+		rule   models.AlertRule
+		event  CheckEvent
+		streak int
+		fire   bool
+	}{
+		{"down", models.AlertRule{}, CheckEvent{Status: "down"}, 1, true},
+		{"degraded is not down", models.AlertRule{}, CheckEvent{Status: "degraded"}, 0, false},
+		{"streak short", models.AlertRule{ThresholdType: "failure_count", ThresholdValue: 3}, CheckEvent{Status: "down"}, 2, false},
+		{"streak reached", models.AlertRule{ThresholdType: "failure_count", ThresholdValue: 3}, CheckEvent{Status: "down"}, 3, true},
+		{"streak of at least one", models.AlertRule{ThresholdType: "failure_count"}, CheckEvent{Status: "down"}, 1, true},
+		{"slow", models.AlertRule{ThresholdType: "response_time", ThresholdValue: 500}, CheckEvent{Status: "up", ResponseTime: 501}, 0, true},
+		{"at the threshold", models.AlertRule{ThresholdType: "response_time", ThresholdValue: 500}, CheckEvent{Status: "up", ResponseTime: 500}, 0, false},
+		{"no response time threshold", models.AlertRule{ThresholdType: "response_time"}, CheckEvent{Status: "up", ResponseTime: 9000}, 0, false},
+		{"changed to down", models.AlertRule{ThresholdType: "status_change"}, CheckEvent{Status: "down", PreviousStatus: "up"}, 1, true},
+		{"first result down", models.AlertRule{ThresholdType: "status_change"}, CheckEvent{Status: "down"}, 1, true},
+		{"still down", models.AlertRule{ThresholdType: "status_change"}, CheckEvent{Status: "down", PreviousStatus: "down"}, 2, false},
+		{"back up", models.AlertRule{ThresholdType: "status_change"}, CheckEvent{Status: "up", PreviousStatus: "down"}, 0, false},
+		{"degraded", models.AlertRule{ThresholdType: "degraded"}, CheckEvent{Status: "degraded"}, 0, true},
+		{"down is not degraded", models.AlertRule{ThresholdType: "degraded"}, CheckEvent{Status: "down"}, 1, false},
+		{"diverged while up", models.AlertRule{ThresholdType: "divergence"}, CheckEvent{Status: "up", Divergence: true}, 0, true},
+		{"content changed", models.AlertRule{ThresholdType: "content_changed"}, CheckEvent{Status: "up", ContentChanged: true}, 0, true},
+		{"jitter", models.AlertRule{ThresholdType: "jitter", ThresholdValue: 10}, CheckEvent{Status: "up", Jitter: 10.5}, 0, true},
+		{"low jitter", models.AlertRule{ThresholdType: "jitter", ThresholdValue: 10}, CheckEvent{Status: "up", Jitter: 10}, 0, false},
+		{"unknown type", models.AlertRule{ThresholdType: "loudness"}, CheckEvent{Status: "down"}, 1, false},
+	} {
+		fire, reason := s.shouldTriggerAlert(tc.rule, tc.event, tc.streak)
+		if fire != tc.fire || (fire && reason == "") {
+			t.Errorf("%s: fire %v reason %q, want %v", tc.name, fire, reason, tc.fire)
+		}
+	}
+}
+
+// A status_change rule fires once per change and an up result closes it
+func TestStatusChangeRule(t *testing.T) {
+	h := newAlertHarness(t)
+	rule := h.addRule(t, models.AlertRule{ThresholdType: "status_change", CooldownSeconds: 1})
+
+	h.send(t, CheckEvent{Status: "up"})
+	h.send(t, CheckEvent{Status: "down", PreviousStatus: "up"})
+	h.waitHistory(t, 1)
+	if got := reloadRule(t, rule.ID); !got.AlertOpen {
+		t.Error("alert not open after the change to down")
+	}
+
+	h.clock.Advance(2 * time.Second)
+	h.send(t, CheckEvent{Status: "down", PreviousStatus: "down"})
+	h.send(t, CheckEvent{Status: "up", PreviousStatus: "down"})
+	if got := reloadRule(t, rule.ID); got.AlertOpen {
+		t.Error("alert still open after the change back to up")
+	}
+	h.send(t, CheckEvent{Status: "degraded", PreviousStatus: "up"})
+	if history := h.waitHistory(t, 2); history[1].Severity != "degraded" {
+		t.Errorf("second alert %+v, want the change to degraded", history[1])
+	}
+}
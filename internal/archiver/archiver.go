@@ -0,0 +1,358 @@
+// Package archiver exports monitor history that is older than the local
+// retention to S3-compatible object storage, so the retention sweep can delete
+// it from the database without losing it.
+package archiver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"monitor/internal/alert"
+	"monitor/internal/clock"
+	"monitor/internal/config"
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Export formats
+const (
+	Format        = "arrowgo-history-export"
+	FormatVersion = 1
+)
+
+// SourceMonitorHistory 导出的表，同时用作 history_exports.source 和对象键的一段
+const SourceMonitorHistory = "monitor_history"
+
+// Export statuses
+const (
+	StatusVerified = "verified"
+	StatusFailed   = "failed"
+)
+
+// Header is the first line of every exported object. Columns map the JSON
+// fields of the rows to database columns, so the rows can be re-imported into
+// the schema version they were exported from.
+type Header struct {
+	Format         string    `json:"format"`
+	FormatVersion  int       `json:"format_version"`
+	SchemaVersion  int       `json:"schema_version"`
+	Table          string    `json:"table"`
+	Columns        []Column  `json:"columns"`
+	FromID         uint      `json:"from_id"`
+	ToID           uint      `json:"to_id"`
+	Rows           int       `json:"rows"`
+	FirstCheckedAt time.Time `json:"first_checked_at"`
+	LastCheckedAt  time.Time `json:"last_checked_at"`
+	ExportedAt     time.Time `json:"exported_at"`
+}
+
+// Column is one column of the exported table
+type Column struct {
+	Name string `json:"name"` // database column
+	JSON string `json:"json"` // field in the row lines
+	Type string `json:"type"` // gorm data type: uint, int, string, bool, time
+}
+
+// etagMD5 S3 对单次上传的对象返回内容的 MD5 作为 ETag
+var etagMD5 = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// Archiver periodically exports history rows older than the retention cutoff.
+// Each object holds a contiguous ID range following the previous verified
+// export, so a failed run is retried from the same row.
+type Archiver struct {
+	client         *s3Client
+	prefix         string
+	interval       time.Duration
+	batchSize      int
+	retention      time.Duration
+	alertChannelID uint32
+	alerts         *alert.Service // nil when alerting is disabled
+	clock          clock.Clock
+	failing        bool // 上一次运行失败，恢复时发送通知
+}
+
+// notify alerts the operators when alerting is enabled
+func (a *Archiver) notify(title, message string) {
+	if a.alerts != nil {
+		a.alerts.NotifyOperators(a.alertChannelID, title, message)
+	}
+}
+
+// New creates an archiver from the history_archive config; retention is the
+// local history retention, only rows older than it are exported
+func New(cfg config.HistoryArchiveConfig, retention time.Duration, alerts *alert.Service) (*Archiver, error) {
+	if retention <= 0 {
+		return nil, fmt.Errorf("history archive needs a history retention")
+	}
+	client, err := newS3Client(cfg.Endpoint, cfg.Region, cfg.Bucket, cfg.AccessKey, cfg.SecretKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Archiver{
+		client:         client,
+		prefix:         cfg.Prefix,
+		interval:       time.Duration(cfg.IntervalMinutes) * time.Minute,
+		batchSize:      cfg.BatchSize,
+		retention:      retention,
+		alertChannelID: cfg.AlertChannelID,
+		alerts:         alerts,
+		clock:          clock.Real,
+	}, nil
+}
+
+// SetClock replaces the time source
+func (a *Archiver) SetClock(c clock.Clock) {
+	a.clock = c
+}
+
+// Start runs the archiver now and then every interval until ctx is done
+func (a *Archiver) Start(ctx context.Context) {
+	go func() {
+		ticker := a.clock.NewTicker(a.interval)
+		defer ticker.Stop()
+		for {
+			a.Run(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+			}
+		}
+	}()
+}
+
+// Run exports every complete batch of rows older than the cutoff, and the
+// remaining rows as a smaller last object. It stops at the first failure,
+// which is alerted once until a run succeeds again.
+func (a *Archiver) Run(ctx context.Context) error {
+	exported, rows, err := a.run(ctx)
+	if err != nil {
+		logger.Error("History archive failed", zap.Int("objects_exported", exported), zap.Error(err))
+		if !a.failing {
+			a.failing = true
+			a.notify("历史归档失败",
+				fmt.Sprintf("检查历史导出到对象存储 %s 失败：%v。失败的记录不会被保留期清理删除，本地数据库会持续增长，请检查对象存储后等待下次重试。", a.client.bucket, err))
+		}
+		return err
+	}
+	if a.failing {
+		a.failing = false
+		a.notify("历史归档恢复", fmt.Sprintf("检查历史已恢复导出到对象存储 %s。", a.client.bucket))
+	}
+	if exported > 0 {
+		logger.Info("History archived", zap.Int("objects", exported), zap.Int("rows", rows))
+	}
+	return nil
+}
+
+func (a *Archiver) run(ctx context.Context) (objects, rows int, err error) {
+	db := database.GetDB().WithContext(ctx)
+	cutoff := a.clock.Now().Add(-a.retention)
+	for {
+		lastID, err := ExportedThrough(ctx)
+		if err != nil {
+			return objects, rows, err
+		}
+
+		var batch []models.MonitorHistory
+		if err := db.Where("id > ?", lastID).Order("id").Limit(a.batchSize).Find(&batch).Error; err != nil {
+			return objects, rows, err
+		}
+		// 只导出连续的一段：遇到第一条未过期的记录就停止，之后的记录留到下次
+		full := len(batch) == a.batchSize
+		for i, row := range batch {
+			if !row.CheckedAt.Before(cutoff) {
+				batch, full = batch[:i], false
+				break
+			}
+		}
+		if len(batch) == 0 {
+			return objects, rows, nil
+		}
+
+		if err := a.export(ctx, db, batch); err != nil {
+			return objects, rows, err
+		}
+		objects++
+		rows += len(batch)
+		if !full {
+			return objects, rows, nil
+		}
+	}
+}
+
+// export writes one object and records it in history_exports
+func (a *Archiver) export(ctx context.Context, db *gorm.DB, batch []models.MonitorHistory) error {
+	now := a.clock.Now()
+	record := models.HistoryExport{
+		Source:     SourceMonitorHistory,
+		FromID:     batch[0].ID,
+		ToID:       batch[len(batch)-1].ID,
+		Rows:       len(batch),
+		ExportedAt: now,
+	}
+	record.ObjectKey = ObjectKey(a.prefix, SourceMonitorHistory, batch[0].CheckedAt, record.FromID, record.ToID)
+
+	err := func() error {
+		body, err := encode(db, batch, now)
+		if err != nil {
+			return err
+		}
+		record.Bytes = int64(len(body))
+		record.SHA256 = sha256Hex(body)
+
+		metadata := map[string]string{
+			"sha256":  record.SHA256,
+			"rows":    strconv.Itoa(record.Rows),
+			"from-id": strconv.FormatUint(uint64(record.FromID), 10),
+			"to-id":   strconv.FormatUint(uint64(record.ToID), 10),
+		}
+		put, err := a.client.PutObject(ctx, record.ObjectKey, body, "application/x-ndjson", metadata)
+		if err != nil {
+			return err
+		}
+		sum := md5.Sum(body)
+		return a.verify(ctx, record, hex.EncodeToString(sum[:]), put.ETag)
+	}()
+	if err != nil {
+		record.Status = StatusFailed
+		record.Error = err.Error()
+	} else {
+		verifiedAt := a.clock.Now()
+		record.Status = StatusVerified
+		record.VerifiedAt = &verifiedAt
+	}
+	if createErr := db.Create(&record).Error; createErr != nil && err == nil {
+		// 没有记录的导出不算完成，下次会重新导出同一段
+		err = fmt.Errorf("failed to record export of %s: %w", record.ObjectKey, createErr)
+	}
+	if err != nil {
+		return fmt.Errorf("export of history rows %d-%d: %w", record.FromID, record.ToID, err)
+	}
+
+	logger.Info("History rows exported",
+		zap.String("object", record.ObjectKey),
+		zap.Uint("from_id", record.FromID),
+		zap.Uint("to_id", record.ToID),
+		zap.Int("rows", record.Rows),
+		zap.Int64("bytes", record.Bytes),
+	)
+	return nil
+}
+
+// verify reads the object back and compares its size, checksum metadata and
+// ETag with what was uploaded
+func (a *Archiver) verify(ctx context.Context, record models.HistoryExport, md5Hex, putETag string) error {
+	if etagMD5.MatchString(putETag) && putETag != md5Hex {
+		return fmt.Errorf("upload ETag %s does not match MD5 %s", putETag, md5Hex)
+	}
+	info, err := a.client.HeadObject(ctx, record.ObjectKey)
+	if err != nil {
+		return fmt.Errorf("verify: %w", err)
+	}
+	if info.Size != record.Bytes {
+		return fmt.Errorf("verify: stored object has %d bytes, uploaded %d", info.Size, record.Bytes)
+	}
+	if info.Metadata["sha256"] != record.SHA256 {
+		return fmt.Errorf("verify: stored object sha256 %q does not match %s", info.Metadata["sha256"], record.SHA256)
+	}
+	if etagMD5.MatchString(info.ETag) && info.ETag != md5Hex {
+		return fmt.Errorf("verify: stored ETag %s does not match MD5 %s", info.ETag, md5Hex)
+	}
+	return nil
+}
+
+// encode returns the gzip-compressed NDJSON object: a Header line, then one
+// line per row with the row's JSON
+func encode(db *gorm.DB, batch []models.MonitorHistory, exportedAt time.Time) ([]byte, error) {
+	columns, err := tableColumns(db, &models.MonitorHistory{})
+	if err != nil {
+		return nil, err
+	}
+	header := Header{
+		Format:         Format,
+		FormatVersion:  FormatVersion,
+		SchemaVersion:  database.SchemaVersion,
+		Table:          SourceMonitorHistory,
+		Columns:        columns,
+		FromID:         batch[0].ID,
+		ToID:           batch[len(batch)-1].ID,
+		Rows:           len(batch),
+		FirstCheckedAt: batch[0].CheckedAt.UTC(),
+		LastCheckedAt:  batch[len(batch)-1].CheckedAt.UTC(),
+		ExportedAt:     exportedAt.UTC(),
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(zw)
+	if err := enc.Encode(header); err != nil {
+		return nil, err
+	}
+	for _, row := range batch {
+		if err := enc.Encode(row); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// tableColumns describes the columns of a model from its gorm schema
+func tableColumns(db *gorm.DB, model interface{}) ([]Column, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	var columns []Column
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(field.StructField.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		columns = append(columns, Column{Name: field.DBName, JSON: name, Type: string(field.DataType)})
+	}
+	return columns, nil
+}
+
+// ObjectKey returns the key of an export: the prefix, the table, the UTC date
+// of the first row, and the zero-padded ID range, so keys sort by ID
+func ObjectKey(prefix, table string, firstCheckedAt time.Time, fromID, toID uint) string {
+	name := fmt.Sprintf("%s-%012d-%012d.ndjson.gz", table, fromID, toID)
+	return path.Join(prefix, table, firstCheckedAt.UTC().Format("2006/01/02"), name)
+}
+
+// ExportedThrough returns the highest history ID covered by verified exports;
+// the retention sweep does not delete rows above it
+func ExportedThrough(ctx context.Context) (uint, error) {
+	var ids []uint
+	err := database.GetDB().WithContext(ctx).Model(&models.HistoryExport{}).
+		Where("source = ? AND status = ?", SourceMonitorHistory, StatusVerified).
+		Order("to_id DESC").Limit(1).Pluck("to_id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[0], nil
+}
@@ -0,0 +1,246 @@
+package archiver
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"monitor/internal/clock"
+	"monitor/internal/config"
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+	"monitor/internal/monitor"
+
+	"go.uber.org/zap"
+)
+
+var epoch = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// archiveHarness is an archiver of a fresh database to a fake S3, with five
+// history rows two days old and one row from now
+type archiveHarness struct {
+	a       *Archiver
+	fake    *fakeS3
+	monitor *monitor.Service
+	clock   *clock.Fake
+}
+
+func newArchiveHarness(t *testing.T) *archiveHarness {
+	t.Helper()
+	logger.Log = zap.NewNop()
+	if err := database.InitMemoryDB(t.Name()); err != nil {
+		t.Fatalf("InitMemoryDB: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := database.GetDB().DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	for i := 0; i < 5; i++ {
+		createHistory(t, epoch.Add(-48*time.Hour+time.Duration(i)*time.Minute))
+	}
+	createHistory(t, epoch)
+
+	fake, srv := newFakeS3(t)
+	a, err := New(config.HistoryArchiveConfig{
+		Endpoint:        srv.URL,
+		Region:          "us-east-1",
+		Bucket:          "history",
+		AccessKey:       "key",
+		SecretKey:       "secret",
+		Prefix:          "arrowgo",
+		IntervalMinutes: 60,
+		BatchSize:       2,
+	}, 24*time.Hour, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	h := &archiveHarness{a: a, fake: fake, clock: clock.NewFake(epoch)}
+	a.SetClock(h.clock)
+
+	h.monitor = monitor.NewService(nil, monitor.ServiceOptions{Workers: 1, StartupJitter: time.Hour})
+	h.monitor.SetClock(h.clock)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		h.monitor.Stop(ctx)
+	})
+	return h
+}
+
+func createHistory(t *testing.T, checkedAt time.Time) {
+	t.Helper()
+	row := models.MonitorHistory{TargetID: 1, Status: "up", CheckedAt: checkedAt}
+	if err := database.GetDB().Create(&row).Error; err != nil {
+		t.Fatalf("create history: %v", err)
+	}
+}
+
+// sweep runs the retention sweep the way main wires it to the archiver
+func (h *archiveHarness) sweep(t *testing.T) int64 {
+	t.Helper()
+	deleted, err := h.monitor.SweepHistory(context.Background(), monitor.HistoryRetention{
+		Retention:        24 * time.Hour,
+		DeletableThrough: ExportedThrough,
+	})
+	if err != nil {
+		t.Fatalf("SweepHistory: %v", err)
+	}
+	return deleted
+}
+
+func exports(t *testing.T) []models.HistoryExport {
+	t.Helper()
+	var records []models.HistoryExport
+	if err := database.GetDB().Order("id").Find(&records).Error; err != nil {
+		t.Fatalf("history_exports: %v", err)
+	}
+	return records
+}
+
+func historyCount(t *testing.T) int64 {
+	t.Helper()
+	var count int64
+	database.GetDB().Model(&models.MonitorHistory{}).Count(&count)
+	return count
+}
+
+// The expired rows are uploaded in batches, verified, recorded and only then
+// deleted by the retention sweep; the row inside the retention stays
+func TestRunExportsThenSweepDeletes(t *testing.T) {
+	h := newArchiveHarness(t)
+	ctx := context.Background()
+
+	if err := h.a.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	day := epoch.Add(-48 * time.Hour)
+	want := []string{
+		"/history/" + ObjectKey("arrowgo", SourceMonitorHistory, day, 1, 2),
+		"/history/" + ObjectKey("arrowgo", SourceMonitorHistory, day, 3, 4),
+		"/history/" + ObjectKey("arrowgo", SourceMonitorHistory, day, 5, 5),
+	}
+	if puts := h.fake.uploaded(); strings.Join(puts, " ") != strings.Join(want, " ") {
+		t.Errorf("uploaded %v, want %v", puts, want)
+	}
+	records := exports(t)
+	if len(records) != 3 {
+		t.Fatalf("%d export records, want 3", len(records))
+	}
+	for _, r := range records {
+		if r.Status != StatusVerified || r.VerifiedAt == nil || r.SHA256 == "" {
+			t.Errorf("export %d-%d: %+v", r.FromID, r.ToID, r)
+		}
+	}
+	if through, _ := ExportedThrough(ctx); through != 5 {
+		t.Errorf("ExportedThrough = %d, want 5", through)
+	}
+
+	body, _ := h.fake.object(want[1])
+	header, rows := decodeObject(t, body)
+	if header.Format != Format || header.FromID != 3 || header.ToID != 4 || header.Rows != 2 || len(header.Columns) == 0 {
+		t.Errorf("header %+v", header)
+	}
+	if len(rows) != 2 || rows[0].ID != 3 || rows[1].ID != 4 {
+		t.Errorf("rows %+v, want 3 and 4", rows)
+	}
+
+	if deleted := h.sweep(t); deleted != 5 {
+		t.Errorf("sweep deleted %d rows, want the 5 exported", deleted)
+	}
+	if n := historyCount(t); n != 1 {
+		t.Errorf("%d rows left, want the one inside the retention", n)
+	}
+
+	// Nothing new is old enough
+	if err := h.a.Run(ctx); err != nil || len(h.fake.uploaded()) != 3 {
+		t.Errorf("second Run: %v, %d uploads", err, len(h.fake.uploaded()))
+	}
+}
+
+// A failed upload or verification is recorded as failed and keeps the rows
+// from the sweep; the next run resumes from the same row under the same key
+func TestRunResumesAfterFailedExport(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		fault func(f *fakeS3)
+		error string
+	}{
+		{"lost write", func(f *fakeS3) { f.failHead = true }, "404"},
+		{"size", func(f *fakeS3) { f.headSize = 1 }, "stored object has 1 bytes"},
+		{"stored etag", func(f *fakeS3) { f.headETag = strings.Repeat("0", 32) }, "stored ETag"},
+		{"upload etag", func(f *fakeS3) { f.putETag = strings.Repeat("0", 32) }, "upload ETag"},
+		{"upload", func(f *fakeS3) { f.failPut = true }, "InternalError"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newArchiveHarness(t)
+			ctx := context.Background()
+			h.fake.set(tc.fault)
+
+			err := h.a.Run(ctx)
+			if err == nil || !strings.Contains(err.Error(), tc.error) || !strings.Contains(err.Error(), "rows 1-2") {
+				t.Fatalf("Run: err = %v, want %q for rows 1-2", err, tc.error)
+			}
+			records := exports(t)
+			if len(records) != 1 || records[0].Status != StatusFailed || records[0].VerifiedAt != nil || !strings.Contains(records[0].Error, tc.error) {
+				t.Fatalf("export records %+v, want one failed for rows 1-2", records)
+			}
+			if through, _ := ExportedThrough(ctx); through != 0 {
+				t.Errorf("ExportedThrough = %d after the failure, want 0", through)
+			}
+			if deleted := h.sweep(t); deleted != 0 {
+				t.Errorf("sweep deleted %d unverified rows", deleted)
+			}
+
+			h.fake.set(func(f *fakeS3) {
+				f.failPut, f.failHead, f.headSize, f.headETag, f.putETag = false, false, 0, "", ""
+				f.puts = nil
+			})
+			if err := h.a.Run(ctx); err != nil {
+				t.Fatalf("Run after the store recovered: %v", err)
+			}
+			day := epoch.Add(-48 * time.Hour)
+			first := "/history/" + ObjectKey("arrowgo", SourceMonitorHistory, day, 1, 2)
+			if puts := h.fake.uploaded(); len(puts) != 3 || puts[0] != first {
+				t.Errorf("resumed with %v, want %s first", puts, first)
+			}
+			if through, _ := ExportedThrough(ctx); through != 5 {
+				t.Errorf("ExportedThrough = %d after the retry, want 5", through)
+			}
+			if deleted := h.sweep(t); deleted != 5 {
+				t.Errorf("sweep after the retry deleted %d rows, want 5", deleted)
+			}
+		})
+	}
+}
+
+func decodeObject(t *testing.T, body []byte) (Header, []models.MonitorHistory) {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	scanner := bufio.NewScanner(zr)
+	var header Header
+	var rows []models.MonitorHistory
+	for i := 0; scanner.Scan(); i++ {
+		var err error
+		if i == 0 {
+			err = json.Unmarshal(scanner.Bytes(), &header)
+		} else {
+			var row models.MonitorHistory
+			err = json.Unmarshal(scanner.Bytes(), &row)
+			rows = append(rows, row)
+		}
+		if err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+	}
+	return header, rows
+}
@@ -0,0 +1,195 @@
+package archiver
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// s3Client 最小的 S3 兼容客户端：path-style 地址、AWS Signature Version 4，
+// 只实现归档需要的 PUT 和 HEAD
+type s3Client struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	http      *http.Client
+	now       func() time.Time
+}
+
+// objectInfo 上传或 HEAD 返回的对象信息
+type objectInfo struct {
+	Size     int64
+	ETag     string
+	Metadata map[string]string // x-amz-meta-*，键为小写且不含前缀
+}
+
+func newS3Client(endpoint, region, bucket, accessKey, secretKey string) (*s3Client, error) {
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %q", endpoint)
+	}
+	return &s3Client{
+		endpoint:  u,
+		region:    region,
+		bucket:    bucket,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		http:      &http.Client{Timeout: 5 * time.Minute},
+		now:       time.Now,
+	}, nil
+}
+
+// PutObject uploads body under key. The body is sent with Content-MD5 and a
+// signed SHA-256, so the store rejects an upload that is corrupted on the way.
+func (c *s3Client) PutObject(ctx context.Context, key string, body []byte, contentType string, metadata map[string]string) (*objectInfo, error) {
+	sum := md5.Sum(body)
+	headers := map[string]string{
+		"Content-Type": contentType,
+		"Content-MD5":  base64.StdEncoding.EncodeToString(sum[:]),
+	}
+	for name, value := range metadata {
+		headers["X-Amz-Meta-"+name] = value
+	}
+	resp, err := c.do(ctx, http.MethodPut, key, body, headers)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("PUT", key, resp)
+	}
+	return &objectInfo{Size: int64(len(body)), ETag: strings.Trim(resp.Header.Get("ETag"), `"`)}, nil
+}
+
+// HeadObject returns the size, ETag and metadata of a stored object
+func (c *s3Client) HeadObject(ctx context.Context, key string) (*objectInfo, error) {
+	resp, err := c.do(ctx, http.MethodHead, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("HEAD", key, resp)
+	}
+	info := &objectInfo{
+		ETag:     strings.Trim(resp.Header.Get("ETag"), `"`),
+		Metadata: make(map[string]string),
+	}
+	info.Size, _ = strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	for name, values := range resp.Header {
+		if meta, ok := strings.CutPrefix(strings.ToLower(name), "x-amz-meta-"); ok && len(values) > 0 {
+			info.Metadata[meta] = values[0]
+		}
+	}
+	return info, nil
+}
+
+func (c *s3Client) do(ctx context.Context, method, key string, body []byte, headers map[string]string) (*http.Response, error) {
+	path := strings.TrimRight(c.endpoint.Path, "/") + "/" + c.bucket + "/" + key
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint.Scheme+"://"+c.endpoint.Host+escapePath(path), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	c.sign(req, escapePath(path), body)
+	return c.http.Do(req)
+}
+
+// sign adds the AWS Signature Version 4 headers to req
+func (c *s3Client) sign(req *http.Request, canonicalURI string, body []byte) {
+	now := c.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// 签名的请求头：Host 加上所有已设置的请求头
+	values := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		values[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + values[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		"", // 没有查询参数
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + c.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+c.secretKey), date)
+	key = hmacSHA256(key, c.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKey, scope, signedHeaders, signature))
+}
+
+// escapePath URI-encodes every byte of p except the unreserved characters and '/'
+func escapePath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		ch := p[i]
+		if ch == '/' || ch == '-' || ch == '_' || ch == '.' || ch == '~' ||
+			(ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9') {
+			b.WriteByte(ch)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", ch)
+	}
+	return b.String()
+}
+
+func responseError(method, key string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	msg := strings.TrimSpace(string(body))
+	if msg == "" {
+		return fmt.Errorf("%s %s: %s", method, key, resp.Status)
+	}
+	return fmt.Errorf("%s %s: %s: %s", method, key, resp.Status, msg)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,146 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock for tests. Its time only moves with Advance and Set, and
+// the timers and tickers created from it fire when the time reaches their
+// deadline, so time-dependent logic can be tested at exact boundaries.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// NewFake returns a fake clock showing now
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the time of the fake clock
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance moves the clock forward by d and fires the timers and tickers due
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	f.fire()
+}
+
+// Set moves the clock to t, which must not be before the current time, and
+// fires the timers and tickers due
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if t.Before(f.now) {
+		panic("clock: Fake.Set moves the clock backwards")
+	}
+	f.now = t
+	f.fire()
+}
+
+// Waiters returns the number of timers and tickers not yet fired or stopped,
+// which lets a test wait until a goroutine has started waiting on the clock
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, w := range f.waiters {
+		if w.active {
+			n++
+		}
+	}
+	return n
+}
+
+// NewTimer returns a timer firing once the clock has advanced by d
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	return f.add(d, 0)
+}
+
+// NewTicker returns a ticker firing every d of fake time. Like a
+// time.Ticker it drops ticks the receiver is not ready for.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	return fakeTicker{f.add(d, d)}
+}
+
+func (f *Fake) add(d, period time.Duration) *fakeWaiter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &fakeWaiter{clock: f, when: f.now.Add(d), period: period, active: true, c: make(chan time.Time, 1)}
+	f.waiters = append(f.waiters, w)
+	f.fire()
+	return w
+}
+
+// fire delivers the ticks due and drops the timers that fired; f.mu is held
+func (f *Fake) fire() {
+	waiting := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.active && !w.when.After(f.now) {
+			select {
+			case w.c <- w.when:
+			default:
+			}
+			if w.period > 0 {
+				for !w.when.After(f.now) {
+					w.when = w.when.Add(w.period)
+				}
+			} else {
+				w.active = false
+			}
+		}
+		if w.active {
+			waiting = append(waiting, w)
+		}
+	}
+	f.waiters = waiting
+}
+
+// fakeWaiter is a timer (period 0) or ticker of a Fake
+type fakeWaiter struct {
+	clock  *Fake
+	when   time.Time
+	period time.Duration
+	active bool
+	c      chan time.Time
+}
+
+func (w *fakeWaiter) C() <-chan time.Time { return w.c }
+
+func (w *fakeWaiter) Stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+	wasActive := w.active
+	w.active = false
+	return wasActive
+}
+
+func (w *fakeWaiter) Reset(d time.Duration) bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+	wasActive := w.active
+	w.when = w.clock.now.Add(d)
+	if !wasActive {
+		w.active = true
+		w.clock.waiters = append(w.clock.waiters, w)
+	}
+	w.clock.fire()
+	return wasActive
+}
+
+// fakeTicker hides the result of Stop, which Ticker doesn't have
+type fakeTicker struct{ w *fakeWaiter }
+
+func (t fakeTicker) C() <-chan time.Time { return t.w.c }
+func (t fakeTicker) Stop()               { t.w.Stop() }
@@ -0,0 +1,87 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func fired(c <-chan time.Time) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestFakeTimerFiresAtDeadline(t *testing.T) {
+	f := NewFake(epoch)
+	timer := f.NewTimer(time.Minute)
+
+	f.Advance(time.Minute - time.Nanosecond)
+	if fired(timer.C()) {
+		t.Fatal("timer fired before its deadline")
+	}
+	f.Advance(time.Nanosecond)
+	if !fired(timer.C()) {
+		t.Fatal("timer did not fire at its deadline")
+	}
+	if f.Waiters() != 0 {
+		t.Errorf("fired timer still waiting")
+	}
+	if timer.Stop() {
+		t.Error("Stop of a fired timer returned true")
+	}
+}
+
+func TestFakeTimerStopAndReset(t *testing.T) {
+	f := NewFake(epoch)
+	timer := f.NewTimer(time.Second)
+	if !timer.Stop() {
+		t.Fatal("Stop of an active timer returned false")
+	}
+	f.Advance(time.Hour)
+	if fired(timer.C()) {
+		t.Fatal("stopped timer fired")
+	}
+
+	timer.Reset(time.Second)
+	f.Advance(time.Second)
+	if !fired(timer.C()) {
+		t.Fatal("reset timer did not fire")
+	}
+}
+
+func TestFakeTickerDropsMissedTicks(t *testing.T) {
+	f := NewFake(epoch)
+	ticker := f.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	f.Advance(3*time.Minute + time.Second)
+	if !fired(ticker.C()) {
+		t.Fatal("ticker did not tick")
+	}
+	if fired(ticker.C()) {
+		t.Fatal("ticker kept ticks the receiver missed")
+	}
+	f.Advance(time.Minute - time.Second - time.Nanosecond)
+	if fired(ticker.C()) {
+		t.Fatal("ticker ticked before the next period")
+	}
+	f.Advance(time.Nanosecond)
+	if !fired(ticker.C()) {
+		t.Fatal("ticker did not tick at the next period")
+	}
+}
+
+func TestFakeSetBackwardsPanics(t *testing.T) {
+	f := NewFake(epoch)
+	defer func() {
+		if recover() == nil {
+			t.Error("Set to an earlier time did not panic")
+		}
+	}()
+	f.Set(epoch.Add(-time.Second))
+}
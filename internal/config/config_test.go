@@ -0,0 +1,169 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func effectiveValues(c *Config) map[string]EffectiveEntry {
+	entries := make(map[string]EffectiveEntry)
+	for _, entry := range c.Effective() {
+		entries[entry.Key] = entry
+	}
+	return entries
+}
+
+// A value written in the file is from the file, unless it is the zero value
+// that setDefaults replaces; keys not in the file are defaults. The
+// environment is not read when a file is loaded.
+func TestLoadFromFileSources(t *testing.T) {
+	t.Setenv("HTTP_PORT", "7000")
+	t.Setenv("DB_DRIVER", "mysql")
+	path := writeConfigFile(t, `
+server:
+  http_port: 9000
+  host: 127.0.0.1
+database:
+  driver: ""
+  password: hunter2
+monitor:
+  workers: 0
+`)
+	c, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+
+	for _, tc := range []struct {
+		key    string
+		value  interface{}
+		source Source
+	}{
+		{"server.http_port", 9000, SourceFile},
+		{"server.host", "127.0.0.1", SourceFile},
+		{"database.driver", "sqlite", SourceDefault}, // written as zero, replaced
+		{"monitor.workers", 100, SourceDefault},
+		{"server.grpc_port", 9090, SourceDefault}, // not in the file
+		{"database.dbname", "monitor.db", SourceDefault},
+		{"logger.level", "info", SourceDefault},
+	} {
+		entry := effectiveValues(c)[tc.key]
+		if entry.Value != tc.value || entry.Source != tc.source || c.Source(tc.key) != tc.source {
+			t.Errorf("%s = %v from %s, want %v from %s", tc.key, entry.Value, entry.Source, tc.value, tc.source)
+		}
+	}
+	if c.Server.HTTPPort != 9000 || c.Database.Driver != "sqlite" {
+		t.Errorf("environment read with a config file: http_port %d driver %q", c.Server.HTTPPort, c.Database.Driver)
+	}
+
+	if _, err := LoadFromFile(writeConfigFile(t, "server: [")); err == nil {
+		t.Error("invalid YAML accepted")
+	}
+}
+
+// Without a file a set variable wins over the default; an empty or
+// unparsable one leaves the default
+func TestLoadEnvSources(t *testing.T) {
+	t.Setenv("HTTP_PORT", "7000")
+	t.Setenv("HOST", "")
+	t.Setenv("MONITOR_WORKERS", "many")
+	t.Setenv("MONITOR_QUEUE_SIZE", "1000") // same as the default, still from env
+	t.Setenv("ES_ENABLED", "true")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.1, 10.0.0.2")
+	t.Setenv("RATE_LIMIT_ALLOW_CIDRS", " , ")
+	c := Load()
+
+	for _, tc := range []struct {
+		key    string
+		value  interface{}
+		source Source
+	}{
+		{"server.http_port", 7000, SourceEnv},
+		{"server.host", "0.0.0.0", SourceDefault},
+		{"monitor.workers", 100, SourceDefault},
+		{"monitor.queue_size", 1000, SourceEnv},
+		{"elasticsearch.enabled", true, SourceEnv},
+		{"rate_limit.allow_cidrs", []string(nil), SourceDefault},
+	} {
+		entry := effectiveValues(c)[tc.key]
+		if !equalValue(entry.Value, tc.value) || entry.Source != tc.source {
+			t.Errorf("%s = %#v from %s, want %#v from %s", tc.key, entry.Value, entry.Source, tc.value, tc.source)
+		}
+	}
+	if proxies := c.Server.TrustedProxies; len(proxies) != 2 || proxies[1] != "10.0.0.2" || c.Source("server.trusted_proxies") != SourceEnv {
+		t.Errorf("server.trusted_proxies = %q from %s", proxies, c.Source("server.trusted_proxies"))
+	}
+}
+
+func equalValue(a, b interface{}) bool {
+	as, aok := a.([]string)
+	bs, bok := b.([]string)
+	if aok || bok {
+		return aok && bok && len(as) == 0 && len(bs) == 0
+	}
+	return a == b
+}
+
+// Fields tagged secret are redacted when set and shown empty when not;
+// nested ones inside maps are found too
+func TestEffectiveRedactsSecrets(t *testing.T) {
+	path := writeConfigFile(t, `
+database:
+  user: monitor
+  password: hunter2
+elasticsearch:
+  username: elastic
+history_archive:
+  access_key: AKIA
+  secret_key: s3cret
+probe:
+  modules:
+    http_auth:
+      type: http
+      settings:
+        headers: {Authorization: Bearer abc}
+    plain:
+      type: tcp
+`)
+	c, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+	entries := effectiveValues(c)
+	for key, want := range map[string]interface{}{
+		"database.password":                redactedValue,
+		"history_archive.secret_key":       redactedValue,
+		"probe.modules.http_auth.settings": redactedValue,
+		"elasticsearch.password":           "",
+		"debug.admin_token":                "",
+		"database.user":                    "monitor",
+		"history_archive.access_key":       "AKIA",
+		"probe.modules.http_auth.type":     "http",
+		"probe.modules.plain.type":         "tcp",
+	} {
+		if got := entries[key].Value; got != want {
+			t.Errorf("%s = %#v, want %#v", key, got, want)
+		}
+	}
+	if entries["database.password"].Source != SourceFile {
+		t.Errorf("database.password source %s", entries["database.password"].Source)
+	}
+	if c.Database.Password != "hunter2" {
+		t.Error("Effective changed the config")
+	}
+
+	t.Setenv("DEBUG_ADMIN_TOKEN", "token")
+	if got := effectiveValues(Load())["debug.admin_token"]; got.Value != redactedValue || got.Source != SourceEnv {
+		t.Errorf("debug.admin_token from env = %+v", got)
+	}
+}
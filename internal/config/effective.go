@@ -0,0 +1,180 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Source 配置项的来源
+type Source string
+
+const (
+	SourceFile    Source = "file"    // 配置文件中写了该项
+	SourceEnv     Source = "env"     // 来自环境变量
+	SourceDefault Source = "default" // 未配置，或配置为零值后被默认值替换
+)
+
+// redactedValue 替代敏感配置项的值；未设置的敏感项仍显示为空，便于区分
+const redactedValue = "<redacted>"
+
+// EffectiveEntry 一个配置项的生效值和来源，key 为 yaml 路径，如 database.host
+type EffectiveEntry struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Source Source      `json:"source"`
+}
+
+// Source 返回配置项的来源；加载时没有记录的项视为默认值
+func (c *Config) Source(key string) Source {
+	if source, ok := c.sources[key]; ok {
+		return source
+	}
+	return SourceDefault
+}
+
+// Effective 按结构体字段顺序返回所有配置项，标记了 secret 的字段已脱敏
+func (c *Config) Effective() []EffectiveEntry {
+	var entries []EffectiveEntry
+	walkConfig(reflect.ValueOf(c).Elem(), "", func(key string, field reflect.StructField, value reflect.Value) {
+		entry := EffectiveEntry{Key: key, Value: value.Interface(), Source: c.Source(key)}
+		if field.Tag.Get("secret") == "true" && !value.IsZero() {
+			entry.Value = redactedValue
+		}
+		entries = append(entries, entry)
+	})
+	return entries
+}
+
+// walkConfig 依次访问结构体的叶子字段（标量、切片、map），key 由 yaml 标签拼接；
+// 值为结构体的 map 逐项展开
+func walkConfig(v reflect.Value, prefix string, visit func(key string, field reflect.StructField, value reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		value := v.Field(i)
+		switch {
+		case value.Kind() == reflect.Struct:
+			walkConfig(value, key, visit)
+		case value.Kind() == reflect.Map && value.Type().Elem().Kind() == reflect.Struct:
+			// 如 probe.modules.<name>.type，按名称排序
+			names := value.MapKeys()
+			sort.Slice(names, func(a, b int) bool { return names[a].String() < names[b].String() })
+			for _, name := range names {
+				walkConfig(value.MapIndex(name), key+"."+name.String(), visit)
+			}
+		default:
+			visit(key, field, value)
+		}
+	}
+}
+
+// fileKeys 返回配置文件中出现的所有 key（包括中间层级）
+func fileKeys(data []byte) (map[string]bool, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool)
+	if len(root.Content) > 0 {
+		collectKeys(root.Content[0], "", keys)
+	}
+	return keys, nil
+}
+
+func collectKeys(node *yaml.Node, prefix string, keys map[string]bool) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		keys[key] = true
+		collectKeys(node.Content[i+1], key, keys)
+	}
+}
+
+// snapshot 记录每个叶子字段的当前值，用于找出 setDefaults 修改过的项
+func snapshot(c *Config) map[string]string {
+	values := make(map[string]string)
+	walkConfig(reflect.ValueOf(c).Elem(), "", func(key string, _ reflect.StructField, value reflect.Value) {
+		values[key] = fmt.Sprintf("%#v", value.Interface())
+	})
+	return values
+}
+
+// applyDefaults 调用 setDefaults，并把被默认值替换的项标记为 default
+func applyDefaults(c *Config) {
+	before := snapshot(c)
+	setDefaults(c)
+	for key, value := range snapshot(c) {
+		if before[key] != value {
+			c.setSource(key, SourceDefault)
+		}
+	}
+}
+
+func (c *Config) setSource(key string, source Source) {
+	if c.sources == nil {
+		c.sources = make(map[string]Source)
+	}
+	c.sources[key] = source
+}
+
+// envLoader 读取环境变量，并记录哪些配置项来自环境变量
+type envLoader struct {
+	config *Config
+}
+
+func (l envLoader) str(key, env, defaultVal string) string {
+	if os.Getenv(env) != "" {
+		l.config.setSource(key, SourceEnv)
+	}
+	return getEnv(env, defaultVal)
+}
+
+// int 只有环境变量能解析为整数时才记为 env
+func (l envLoader) int(key, env string, defaultVal int) int {
+	if _, err := strconv.Atoi(os.Getenv(env)); err == nil {
+		l.config.setSource(key, SourceEnv)
+	}
+	return getEnvInt(env, defaultVal)
+}
+
+func (l envLoader) bool(key, env string, defaultVal bool) bool {
+	if os.Getenv(env) != "" {
+		l.config.setSource(key, SourceEnv)
+	}
+	return getEnvBool(env, defaultVal)
+}
+
+func (l envLoader) slice(key, env string, defaultVal []string) []string {
+	val := getEnvSlice(env, nil)
+	if val == nil {
+		return defaultVal
+	}
+	l.config.setSource(key, SourceEnv)
+	return val
+}
@@ -2,27 +2,60 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+
+	"monitor/internal/schedule"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config 应用配置
 type Config struct {
-	Server        ServerConfig        `yaml:"server"`
-	Database      DatabaseConfig      `yaml:"database"`
-	Monitor       MonitorConfig       `yaml:"monitor"`
-	Logger        LoggerConfig        `yaml:"logger"`
-	Elasticsearch ElasticsearchConfig `yaml:"elasticsearch"`
-	Alert         AlertConfig         `yaml:"alert"`
-	SNMP          SNMPConfig          `yaml:"snmp"`
+	Server         ServerConfig         `yaml:"server"`
+	Database       DatabaseConfig       `yaml:"database"`
+	Monitor        MonitorConfig        `yaml:"monitor"`
+	Logger         LoggerConfig         `yaml:"logger"`
+	Elasticsearch  ElasticsearchConfig  `yaml:"elasticsearch"`
+	Alert          AlertConfig          `yaml:"alert"`
+	SNMP           SNMPConfig           `yaml:"snmp"`
+	RateLimit      RateLimitConfig      `yaml:"rate_limit"`
+	Debug          DebugConfig          `yaml:"debug"`
+	Probe          ProbeConfig          `yaml:"probe"`
+	HistoryArchive HistoryArchiveConfig `yaml:"history_archive"`
+	Discovery      DiscoveryConfig      `yaml:"discovery"`
+
+	sources map[string]Source // 配置项的来源，加载时记录，见 Effective
 }
 
 type ServerConfig struct {
-	HTTPPort int    `yaml:"http_port"`
-	GRPCPort int    `yaml:"grpc_port"`
-	Host     string `yaml:"host"`
+	HTTPPort       int                  `yaml:"http_port"`
+	GRPCPort       int                  `yaml:"grpc_port"`
+	Host           string               `yaml:"host"`
+	TrustedProxies []string             `yaml:"trusted_proxies"` // 可信反向代理，用于解析 X-Forwarded-For 中的客户端 IP
+	TLS            TLSConfig            `yaml:"tls"`             // host:http_port 上的管理接口的 TLS 和客户端证书认证
+	Public         PublicListenerConfig `yaml:"public"`          // 只提供公开接口的第二个监听地址
+}
+
+// TLSConfig 监听地址的 TLS；设置 client_ca_file 时要求并校验客户端证书（mTLS）
+type TLSConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	CertFile     string `yaml:"cert_file"`      // PEM 证书链
+	KeyFile      string `yaml:"key_file"`       // PEM 私钥
+	ClientCAFile string `yaml:"client_ca_file"` // 签发客户端证书的 CA（PEM，可多个），为空时不要求客户端证书
+}
+
+// PublicListenerConfig 公开监听地址，只提供健康检查等公开接口；启用后管理接口只在 host:http_port 上提供
+type PublicListenerConfig struct {
+	Enabled bool      `yaml:"enabled"`
+	Host    string    `yaml:"host"`
+	Port    int       `yaml:"port"`
+	TLS     TLSConfig `yaml:"tls"`
 }
 
 type DatabaseConfig struct {
@@ -30,26 +63,104 @@ type DatabaseConfig struct {
 	Host     string `yaml:"host"`
 	Port     int    `yaml:"port"`
 	User     string `yaml:"user"`
-	Password string `yaml:"password"`
+	Password string `yaml:"password" secret:"true"`
 	DBName   string `yaml:"dbname"`
 	SSLMode  string `yaml:"sslmode"`
 }
 
 type MonitorConfig struct {
-	CheckInterval int `yaml:"check_interval"` // seconds
-	Workers       int `yaml:"workers"`
+	CheckInterval        int                `yaml:"check_interval"` // seconds
+	Workers              int                `yaml:"workers"`
+	QueueSize            int                `yaml:"queue_size"`             // 等待 worker 的检查数上限，超出时推迟定时检查
+	ESBufferSize         int                `yaml:"es_buffer_size"`         // 等待写入 ES 的结果数上限，超出时丢弃
+	StartupJitter        int                `yaml:"startup_jitter"`         // 启动时从数据库加载的目标在这个时间窗口（秒）内随机完成首次检查
+	HistoryBatchSize     int                `yaml:"history_batch_size"`     // 检查历史攒够这么多条时批量写入
+	HistoryFlushInterval int                `yaml:"history_flush_interval"` // 不足一批时每隔这么多秒写入一次
+	UptimeInterval       int                `yaml:"uptime_interval"`        // 有新历史的目标每隔这么多秒重新计算可用率
+	Redaction            RedactionConfig    `yaml:"redaction"`              // 保存请求详情前的脱敏规则
+	Limits               LimitsConfig       `yaml:"limits"`                 // 监控数量上限
+	ClockSkew            ClockSkewConfig    `yaml:"clock_skew"`             // HTTP/HTTPS 检查的时钟偏差检测
+	ResponseBody         ResponseBodyConfig `yaml:"response_body"`          // HTTP/HTTPS 响应体的保存
+	HistoryRetentionDays int                `yaml:"history_retention_days"` // 检查历史保留天数，0 表示一直保留；启用 history_archive 时只删除已归档的记录
+	HistorySweepInterval int                `yaml:"history_sweep_interval"` // 删除过期检查历史的间隔（秒），默认每天一次
+	Script               ScriptConfig       `yaml:"script"`                 // script 类型监控，默认关闭
+	Ping                 PingConfig         `yaml:"ping"`                   // ping 类型监控的检查方式
+	Memory               MemoryConfig       `yaml:"memory"`                 // 进程内存的软上限和告警
+	Spool                SpoolConfig        `yaml:"spool"`                  // 数据库或 ES 不可用时暂存检查结果
+}
+
+// SpoolConfig 检查历史或 ES 日志写入失败时，把结果暂存到本地目录，存储恢复后按顺序补写
+type SpoolConfig struct {
+	Enabled        bool   `yaml:"enabled"`         // 默认开启；关闭时写入失败的结果只记录日志后丢弃
+	Dir            string `yaml:"dir"`             // 暂存目录，默认 spool
+	MaxSizeMB      int    `yaml:"max_size_mb"`     // 每个存储的暂存上限（MB），默认 100，超出时丢弃最早的结果
+	ReplayInterval int    `yaml:"replay_interval"` // 尝试补写的间隔（秒），默认 30
+}
+
+// PingConfig ping 监控的检查方式
+type PingConfig struct {
+	Mode string `yaml:"mode"` // auto（默认，程序自己发送 ICMP 回显请求，无法打开 ICMP socket 时执行 ping 命令）、native（只自己发送）、exec（执行 ping 命令）
+}
+
+// MemoryConfig 进程内存的软上限，以及超过阈值时通知运维
+type MemoryConfig struct {
+	SoftLimitMB      int    `yaml:"soft_limit_mb"`      // Go 运行时的软内存上限（MB，debug.SetMemoryLimit），接近时更频繁地 GC；0 不设置
+	AlertThresholdMB int    `yaml:"alert_threshold_mb"` // Go 运行时占用的内存超过该值（MB）时通知运维，0 关闭
+	AlertChannelID   uint32 `yaml:"alert_channel_id"`   // 通知的告警渠道，0 表示所有正常的渠道
+	CheckInterval    int    `yaml:"check_interval"`     // 检查内存占用的间隔（秒），默认 60
+}
+
+// ScriptConfig script 监控在本机执行命令，只能执行 allowed_paths 中列出的程序
+type ScriptConfig struct {
+	Enabled        bool     `yaml:"enabled"`          // 是否允许 script 类型的监控
+	AllowedPaths   []string `yaml:"allowed_paths"`    // 可执行程序的绝对路径，监控的 script_path 必须与其中一项完全相同
+	MaxOutputBytes int      `yaml:"max_output_bytes"` // 保存的标准输出上限，默认 65536，超出截断
+}
+
+// ResponseBodyConfig 每次检查保存截断的响应体，目标从 up 变为 down 时可另外存档完整响应
+type ResponseBodyConfig struct {
+	MaxStoredBytes int                   `yaml:"max_stored_bytes"` // 每次检查保存的（解码后）响应体上限，默认 102400
+	Archive        ResponseArchiveConfig `yaml:"archive"`
+}
+
+// ResponseArchiveConfig up 变为 down 时把完整的响应体、响应头和耗时存入 response_archives 表
+type ResponseArchiveConfig struct {
+	Enabled       bool `yaml:"enabled"`        // 是否存档
+	MaxBytes      int  `yaml:"max_bytes"`      // 存档的响应体上限，默认 5MB，最大 10MB
+	RetentionDays int  `yaml:"retention_days"` // 存档保留天数，默认 30
+	MaxPerTarget  int  `yaml:"max_per_target"` // 每个监控保留的存档数，默认 20，超出时删除最早的
+}
+
+// ClockSkewConfig 比较响应的 Date 头和本机时钟
+type ClockSkewConfig struct {
+	Threshold int  `yaml:"threshold"` // 秒，超过时在结果消息中注明，默认 30，负数关闭
+	Warn      bool `yaml:"warn"`      // 超过阈值时把正常的结果标记为 warning
+}
+
+// LimitsConfig 监控数量上限，负数表示不限制
+type LimitsConfig struct {
+	MaxTargets     int `yaml:"max_targets"`      // 监控总数上限（包括已禁用的）
+	MaxFastTargets int `yaml:"max_fast_targets"` // 检查间隔小于 fast_interval 的监控数上限
+	FastInterval   int `yaml:"fast_interval"`    // 秒，默认 15
+}
+
+type RedactionConfig struct {
+	Keys         []string `yaml:"keys"`           // 额外的敏感字段名/请求头名正则（默认已包含 password、token、secret 等）
+	Patterns     []string `yaml:"patterns"`       // 对请求体应用的正则，匹配内容（或捕获组）替换为 ***
+	MaxBodyBytes int      `yaml:"max_body_bytes"` // 请求体保存上限（字节），超出部分截断
 }
 
 type LoggerConfig struct {
-	Level  string `yaml:"level"`  // debug, info, warn, error
-	Output string `yaml:"output"` // stdout, stderr, or file path
+	Level      string `yaml:"level"`        // debug, info, warn, error
+	Output     string `yaml:"output"`       // stdout, stderr, or file path
+	FileLogDir string `yaml:"file_log_dir"` // 检查结果文件日志目录
 }
 
 type ElasticsearchConfig struct {
 	Enabled  bool   `yaml:"enabled"`  // 是否启用 Elasticsearch
 	Addresses []string `yaml:"addresses"` // ES 节点地址，如 ["http://localhost:9200"]
 	Username string `yaml:"username"` // ES 用户名
-	Password string `yaml:"password"` // ES 密码
+	Password string `yaml:"password" secret:"true"` // ES 密码
 	IndexPrefix string `yaml:"index_prefix"` // 索引前缀，如 "monitor-logs"
 }
 
@@ -58,6 +169,46 @@ type AlertConfig struct {
 	CooldownSeconds  int  `yaml:"cooldown_seconds"`   // 告警冷却时间（秒）
 	RetryTimes       int  `yaml:"retry_times"`        // 失败重试次数
 	RetryInterval    int  `yaml:"retry_interval"`    // 重试间隔（秒）
+	Digest           DigestConfig `yaml:"digest"`     // 证书到期周报
+	ChannelHealth    ChannelHealthConfig `yaml:"channel_health"` // 告警渠道发送健康检测
+	Flapping         FlappingConfig      `yaml:"flapping"`       // 抖动检测
+}
+
+// FlappingConfig 目标在 window_seconds 内状态变化超过 transitions 次即为抖动：只发一次抖动告警，
+// 之后不再发送单次的故障和恢复告警，直到状态持续 cooldown_seconds 没有变化
+type FlappingConfig struct {
+	Enabled         bool `yaml:"enabled"`          // 是否启用
+	Transitions     int  `yaml:"transitions"`      // 窗口内状态变化超过多少次视为抖动
+	WindowSeconds   int  `yaml:"window_seconds"`   // 统计状态变化的滚动窗口（秒）
+	CooldownSeconds int  `yaml:"cooldown_seconds"` // 状态持续多久没有变化后结束抖动（秒）
+}
+
+// ChannelHealthConfig 告警渠道连续失败或失败率过高时标记为 degraded，暂停向其发送告警并定期探测，探测成功后自动恢复
+type ChannelHealthConfig struct {
+	Enabled              bool `yaml:"enabled"`                // 是否启用
+	ConsecutiveFailures  int  `yaml:"consecutive_failures"`   // 连续失败多少次标记为 degraded，0 表示不按连续失败判断
+	WindowSeconds        int  `yaml:"window_seconds"`         // 计算失败率的滚动窗口（秒）
+	FailurePercent       int  `yaml:"failure_percent"`        // 窗口内失败比例超过该百分比标记为 degraded，0 表示不按失败率判断
+	MinAttempts          int  `yaml:"min_attempts"`           // 窗口内至少发送多少次才按失败率判断
+	ProbeIntervalSeconds int  `yaml:"probe_interval_seconds"` // degraded 渠道的探测间隔（秒）
+}
+
+// DigestConfig 证书到期周报：按固定的星期和时间把即将到期的证书汇总发送到一个告警渠道
+type DigestConfig struct {
+	Enabled    bool             `yaml:"enabled"`     // 是否启用周报
+	ChannelID  uint32           `yaml:"channel_id"`  // 发送到的告警渠道 ID
+	Schedule   string           `yaml:"schedule"`    // cron 表达式（本地时区），如 "0 9 * * 1-5"；设置后代替 weekday 和 time
+	Weekday    string           `yaml:"weekday"`     // 发送日，如 monday
+	Time       string           `yaml:"time"`        // 发送时间（本地时区），如 "09:00"
+	WindowDays int              `yaml:"window_days"` // 汇总未来多少天内到期的证书，默认 45
+	SendEmpty  bool             `yaml:"send_empty"`  // 没有即将到期的证书时仍发送"一切正常"，否则跳过
+	QuietHours QuietHoursConfig `yaml:"quiet_hours"` // 免打扰时段，落在其中的周报推迟到时段结束
+}
+
+// QuietHoursConfig 免打扰时段，可跨午夜（如 22:00-08:00），留空表示不启用
+type QuietHoursConfig struct {
+	Start string `yaml:"start"` // 如 "22:00"
+	End   string `yaml:"end"`   // 如 "08:00"
 }
 
 type SNMPConfig struct {
@@ -66,6 +217,69 @@ type SNMPConfig struct {
 	DefaultTimeout   int    `yaml:"default_timeout"`    // 默认超时时间（毫秒）
 }
 
+type RateLimitConfig struct {
+	AllowCIDRs []string      `yaml:"allow_cidrs"` // 不限流的客户端网段，如 ["10.0.0.0/8"]
+	Read       RateLimitRule `yaml:"read"`        // 查询类接口
+	Write      RateLimitRule `yaml:"write"`       // 增删改类接口
+	Stream     RateLimitRule `yaml:"stream"`      // 流式接口（仅限制建立连接）
+}
+
+type RateLimitRule struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second"` // 每个 IP 每秒请求数
+	Burst             int     `yaml:"burst"`               // 突发请求数
+}
+
+// DebugConfig 调试/演练功能，仅用于预发环境
+type DebugConfig struct {
+	FailureInjection         bool   `yaml:"failure_injection"`           // 启用故障注入接口 /api/v1/debug/inject 与 /purge
+	AdminToken               string `yaml:"admin_token" json:"-" secret:"true"`      // 调试接口的管理员令牌（Authorization: Bearer <token>）
+	IncludeSyntheticInUptime bool   `yaml:"include_synthetic_in_uptime"` // 合成结果是否计入可用率（默认不计入）
+}
+
+// ProbeConfig blackbox_exporter 风格的 GET /probe：按需执行一次检查，返回 Prometheus 指标，不保存任何结果
+type ProbeConfig struct {
+	Enabled       bool                   `yaml:"enabled"`        // 是否注册 /probe
+	Timeout       int                    `yaml:"timeout"`        // 单次探测时限（秒），默认 10；请求头 X-Prometheus-Scrape-Timeout-Seconds 更短时以其为准
+	MaxConcurrent int                    `yaml:"max_concurrent"` // 同时执行的探测数上限，默认 10，超出返回 503；不占用监控的检查队列
+	RateLimit     RateLimitRule          `yaml:"rate_limit"`     // 每个客户端 IP 的限流，默认 2/s，突发 10
+	AllowPrivate  bool                   `yaml:"allow_private"`  // 允许探测回环、内网、链路本地等地址，默认禁止
+	AllowCIDRs    []string               `yaml:"allow_cidrs"`    // 始终允许探测的目标网段，如 ["10.1.0.0/16"]
+	Modules       map[string]ProbeModule `yaml:"modules"`        // 模块名 -> 检查类型和参数
+}
+
+// ProbeModule 探测模块：检查类型加上与添加监控请求同名的参数，如 http_method、expected_status_codes
+type ProbeModule struct {
+	Type     string                 `yaml:"type"`
+	Settings map[string]interface{} `yaml:"settings" secret:"true"` // 可能包含认证请求头
+}
+
+// HistoryArchiveConfig 定期把超过保留期的检查历史导出到 S3 兼容的对象存储（如 MinIO），校验上传成功后才允许删除本地记录
+type HistoryArchiveConfig struct {
+	Enabled         bool   `yaml:"enabled"`  // 是否启用，需要 monitor.history_retention_days
+	Endpoint        string `yaml:"endpoint"` // 如 http://minio:9000，使用 path-style 地址
+	Region          string `yaml:"region"`   // 签名使用的区域，默认 us-east-1
+	Bucket          string `yaml:"bucket"`   // 存储桶，需要事先创建
+	AccessKey       string `yaml:"access_key"`
+	SecretKey       string `yaml:"secret_key" secret:"true"`
+	Prefix          string `yaml:"prefix"`           // 对象键前缀，默认 arrowgo
+	IntervalMinutes int    `yaml:"interval_minutes"` // 两次导出的间隔（分钟），默认 60
+	BatchSize       int    `yaml:"batch_size"`       // 每个对象最多包含的记录数，默认 50000
+	AlertChannelID  uint32 `yaml:"alert_channel_id"` // 导出失败时通知的告警渠道，0 表示所有正常的渠道
+}
+
+// DiscoveryConfig 通过 DNS 区域传送或网段端口扫描发现监控，发现的候选经审核后才创建。
+// 只能传送、扫描这里列出的区域和网段
+type DiscoveryConfig struct {
+	Enabled        bool     `yaml:"enabled"`         // 是否注册 /discovery 接口，默认关闭
+	AllowedZones   []string `yaml:"allowed_zones"`   // 允许区域传送的区域，如 ["corp.example.com"]
+	AllowedServers []string `yaml:"allowed_servers"` // 允许发起 AXFR 的 DNS 服务器 host:port，第一个为默认
+	AllowedCIDRs   []string `yaml:"allowed_cidrs"`   // 允许扫描的网段；探测时只放行这些内网地址
+	Ports          []int    `yaml:"ports"`           // 扫描请求未指定端口时探测的端口
+	MaxHosts       int      `yaml:"max_hosts"`       // 单次扫描最多的主机数，默认 1024
+	MaxConcurrent  int      `yaml:"max_concurrent"`  // 同时进行的连接数，默认 16
+	Timeout        int      `yaml:"timeout"`         // 单次连接时限（秒），默认 2
+}
+
 // Load 从文件加载配置
 func LoadFromFile(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
@@ -78,8 +292,17 @@ func LoadFromFile(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
-	// 设置默认值
-	setDefaults(&config)
+	// 记录文件中写了哪些配置项
+	keys, err := fileKeys(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	for key := range keys {
+		config.setSource(key, SourceFile)
+	}
+
+	// 设置默认值，被默认值替换的项改记为 default
+	applyDefaults(&config)
 
 	return &config, nil
 }
@@ -98,50 +321,190 @@ func SaveToFile(path string, config *Config) error {
 	return nil
 }
 
-// Load 从环境变量加载配置
+// Load 从环境变量加载配置，记录哪些配置项来自环境变量
 func Load() *Config {
-	return &Config{
-		Server: ServerConfig{
-			HTTPPort: getEnvInt("HTTP_PORT", 8080),
-			GRPCPort: getEnvInt("GRPC_PORT", 9090),
-			Host:     getEnv("HOST", "0.0.0.0"),
+	config := &Config{}
+	env := envLoader{config: config}
+
+	config.Server = ServerConfig{
+		HTTPPort:       env.int("server.http_port", "HTTP_PORT", 8080),
+		GRPCPort:       env.int("server.grpc_port", "GRPC_PORT", 9090),
+		Host:           env.str("server.host", "HOST", "0.0.0.0"),
+		TrustedProxies: env.slice("server.trusted_proxies", "TRUSTED_PROXIES", nil),
+		TLS: TLSConfig{
+			Enabled:      env.bool("server.tls.enabled", "HTTP_TLS", false),
+			CertFile:     env.str("server.tls.cert_file", "HTTP_TLS_CERT_FILE", ""),
+			KeyFile:      env.str("server.tls.key_file", "HTTP_TLS_KEY_FILE", ""),
+			ClientCAFile: env.str("server.tls.client_ca_file", "HTTP_TLS_CLIENT_CA_FILE", ""),
 		},
-		Database: DatabaseConfig{
-			Driver:   getEnv("DB_DRIVER", "sqlite"),
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnvInt("DB_PORT", 3306),
-			User:     getEnv("DB_USER", "root"),
-			Password: getEnv("DB_PASSWORD", ""),
-			DBName:   getEnv("DB_NAME", "monitor.db"),
-			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+		Public: PublicListenerConfig{
+			Enabled: env.bool("server.public.enabled", "PUBLIC_HTTP", false),
+			Host:    env.str("server.public.host", "PUBLIC_HTTP_HOST", "0.0.0.0"),
+			Port:    env.int("server.public.port", "PUBLIC_HTTP_PORT", 8081),
+			TLS: TLSConfig{
+				Enabled:      env.bool("server.public.tls.enabled", "PUBLIC_HTTP_TLS", false),
+				CertFile:     env.str("server.public.tls.cert_file", "PUBLIC_HTTP_TLS_CERT_FILE", ""),
+				KeyFile:      env.str("server.public.tls.key_file", "PUBLIC_HTTP_TLS_KEY_FILE", ""),
+				ClientCAFile: env.str("server.public.tls.client_ca_file", "PUBLIC_HTTP_TLS_CLIENT_CA_FILE", ""),
+			},
 		},
-		Monitor: MonitorConfig{
-			CheckInterval: getEnvInt("MONITOR_INTERVAL", 60),
-			Workers:       getEnvInt("MONITOR_WORKERS", 10),
+	}
+	config.Database = DatabaseConfig{
+		Driver:   env.str("database.driver", "DB_DRIVER", "sqlite"),
+		Host:     env.str("database.host", "DB_HOST", "localhost"),
+		Port:     env.int("database.port", "DB_PORT", 3306),
+		User:     env.str("database.user", "DB_USER", "root"),
+		Password: env.str("database.password", "DB_PASSWORD", ""),
+		DBName:   env.str("database.dbname", "DB_NAME", "monitor.db"),
+		SSLMode:  env.str("database.sslmode", "DB_SSLMODE", "disable"),
+	}
+	config.Monitor = MonitorConfig{
+		CheckInterval: env.int("monitor.check_interval", "MONITOR_INTERVAL", 60),
+		Workers:       env.int("monitor.workers", "MONITOR_WORKERS", 100),
+		QueueSize:     env.int("monitor.queue_size", "MONITOR_QUEUE_SIZE", 1000),
+		ESBufferSize:  env.int("monitor.es_buffer_size", "MONITOR_ES_BUFFER_SIZE", 500),
+		StartupJitter: env.int("monitor.startup_jitter", "MONITOR_STARTUP_JITTER", 10),
+
+		HistoryBatchSize:     env.int("monitor.history_batch_size", "MONITOR_HISTORY_BATCH_SIZE", 200),
+		HistoryFlushInterval: env.int("monitor.history_flush_interval", "MONITOR_HISTORY_FLUSH_INTERVAL", 2),
+		UptimeInterval:       env.int("monitor.uptime_interval", "MONITOR_UPTIME_INTERVAL", 60),
+		Redaction: RedactionConfig{
+			MaxBodyBytes: env.int("monitor.redaction.max_body_bytes", "MONITOR_MAX_BODY_BYTES", 4096),
 		},
-		Logger: LoggerConfig{
-			Level:  getEnv("LOG_LEVEL", "info"),
-			Output: getEnv("LOG_OUTPUT", "stdout"),
+		Limits: LimitsConfig{
+			MaxTargets:     env.int("monitor.limits.max_targets", "MONITOR_MAX_TARGETS", 5000),
+			MaxFastTargets: env.int("monitor.limits.max_fast_targets", "MONITOR_MAX_FAST_TARGETS", 200),
+			FastInterval:   env.int("monitor.limits.fast_interval", "MONITOR_FAST_INTERVAL", 15),
 		},
-		Elasticsearch: ElasticsearchConfig{
-			Enabled:     getEnvBool("ES_ENABLED", false),
-			Addresses:   getEnvSlice("ES_ADDRESSES", []string{"http://localhost:9200"}),
-			Username:    getEnv("ES_USERNAME", ""),
-			Password:    getEnv("ES_PASSWORD", ""),
-			IndexPrefix: getEnv("ES_INDEX_PREFIX", "monitor-logs"),
+		ClockSkew: ClockSkewConfig{
+			Threshold: env.int("monitor.clock_skew.threshold", "MONITOR_CLOCK_SKEW_THRESHOLD", 30),
+			Warn:      env.bool("monitor.clock_skew.warn", "MONITOR_CLOCK_SKEW_WARN", false),
 		},
-		Alert: AlertConfig{
-			Enabled:         getEnvBool("ALERT_ENABLED", true),
-			CooldownSeconds: getEnvInt("ALERT_COOLDOWN", 300),
-			RetryTimes:      getEnvInt("ALERT_RETRY_TIMES", 3),
-			RetryInterval:   getEnvInt("ALERT_RETRY_INTERVAL", 60),
+		ResponseBody: ResponseBodyConfig{
+			MaxStoredBytes: env.int("monitor.response_body.max_stored_bytes", "MONITOR_MAX_STORED_BODY_BYTES", 102400),
+			Archive: ResponseArchiveConfig{
+				Enabled:       env.bool("monitor.response_body.archive.enabled", "MONITOR_RESPONSE_ARCHIVE", false),
+				MaxBytes:      env.int("monitor.response_body.archive.max_bytes", "MONITOR_RESPONSE_ARCHIVE_MAX_BYTES", 5<<20),
+				RetentionDays: env.int("monitor.response_body.archive.retention_days", "MONITOR_RESPONSE_ARCHIVE_RETENTION_DAYS", 30),
+				MaxPerTarget:  env.int("monitor.response_body.archive.max_per_target", "MONITOR_RESPONSE_ARCHIVE_MAX_PER_TARGET", 20),
+			},
 		},
-		SNMP: SNMPConfig{
-			DefaultCommunity: getEnv("SNMP_COMMUNITY", "public"),
-			DefaultVersion:   getEnv("SNMP_VERSION", "v2c"),
-			DefaultTimeout:   getEnvInt("SNMP_TIMEOUT", 5000),
+		HistoryRetentionDays: env.int("monitor.history_retention_days", "MONITOR_HISTORY_RETENTION_DAYS", 0),
+		HistorySweepInterval: env.int("monitor.history_sweep_interval", "MONITOR_HISTORY_SWEEP_INTERVAL", 86400),
+		Script: ScriptConfig{
+			Enabled:        env.bool("monitor.script.enabled", "MONITOR_SCRIPT_ENABLED", false),
+			AllowedPaths:   env.slice("monitor.script.allowed_paths", "MONITOR_SCRIPT_ALLOWED_PATHS", nil),
+			MaxOutputBytes: env.int("monitor.script.max_output_bytes", "MONITOR_SCRIPT_MAX_OUTPUT_BYTES", 65536),
+		},
+		Ping: PingConfig{
+			Mode: env.str("monitor.ping.mode", "MONITOR_PING_MODE", "auto"),
+		},
+		Memory: MemoryConfig{
+			SoftLimitMB:      env.int("monitor.memory.soft_limit_mb", "MONITOR_MEMORY_SOFT_LIMIT_MB", 0),
+			AlertThresholdMB: env.int("monitor.memory.alert_threshold_mb", "MONITOR_MEMORY_ALERT_THRESHOLD_MB", 0),
+			AlertChannelID:   uint32(env.int("monitor.memory.alert_channel_id", "MONITOR_MEMORY_ALERT_CHANNEL_ID", 0)),
+			CheckInterval:    env.int("monitor.memory.check_interval", "MONITOR_MEMORY_CHECK_INTERVAL", 60),
+		},
+		Spool: SpoolConfig{
+			Enabled:        env.bool("monitor.spool.enabled", "MONITOR_SPOOL_ENABLED", true),
+			Dir:            env.str("monitor.spool.dir", "MONITOR_SPOOL_DIR", "spool"),
+			MaxSizeMB:      env.int("monitor.spool.max_size_mb", "MONITOR_SPOOL_MAX_SIZE_MB", 100),
+			ReplayInterval: env.int("monitor.spool.replay_interval", "MONITOR_SPOOL_REPLAY_INTERVAL", 30),
 		},
 	}
+	config.Logger = LoggerConfig{
+		Level:      env.str("logger.level", "LOG_LEVEL", "info"),
+		Output:     env.str("logger.output", "LOG_OUTPUT", "stdout"),
+		FileLogDir: env.str("logger.file_log_dir", "LOG_FILE_DIR", "logs"),
+	}
+	config.Elasticsearch = ElasticsearchConfig{
+		Enabled:     env.bool("elasticsearch.enabled", "ES_ENABLED", false),
+		Addresses:   env.slice("elasticsearch.addresses", "ES_ADDRESSES", []string{"http://localhost:9200"}),
+		Username:    env.str("elasticsearch.username", "ES_USERNAME", ""),
+		Password:    env.str("elasticsearch.password", "ES_PASSWORD", ""),
+		IndexPrefix: env.str("elasticsearch.index_prefix", "ES_INDEX_PREFIX", "monitor-logs"),
+	}
+	config.Alert = AlertConfig{
+		Enabled:         env.bool("alert.enabled", "ALERT_ENABLED", true),
+		CooldownSeconds: env.int("alert.cooldown_seconds", "ALERT_COOLDOWN", 300),
+		RetryTimes:      env.int("alert.retry_times", "ALERT_RETRY_TIMES", 3),
+		RetryInterval:   env.int("alert.retry_interval", "ALERT_RETRY_INTERVAL", 60),
+		Digest: DigestConfig{
+			Enabled:    env.bool("alert.digest.enabled", "ALERT_DIGEST_ENABLED", false),
+			ChannelID:  uint32(env.int("alert.digest.channel_id", "ALERT_DIGEST_CHANNEL_ID", 0)),
+			Schedule:   env.str("alert.digest.schedule", "ALERT_DIGEST_SCHEDULE", ""),
+			Weekday:    env.str("alert.digest.weekday", "ALERT_DIGEST_WEEKDAY", "monday"),
+			Time:       env.str("alert.digest.time", "ALERT_DIGEST_TIME", "09:00"),
+			WindowDays: env.int("alert.digest.window_days", "ALERT_DIGEST_WINDOW_DAYS", 45),
+			SendEmpty:  env.bool("alert.digest.send_empty", "ALERT_DIGEST_SEND_EMPTY", true),
+			QuietHours: QuietHoursConfig{
+				Start: env.str("alert.digest.quiet_hours.start", "ALERT_QUIET_HOURS_START", ""),
+				End:   env.str("alert.digest.quiet_hours.end", "ALERT_QUIET_HOURS_END", ""),
+			},
+		},
+		ChannelHealth: ChannelHealthConfig{
+			Enabled:              env.bool("alert.channel_health.enabled", "ALERT_CHANNEL_HEALTH_ENABLED", true),
+			ConsecutiveFailures:  env.int("alert.channel_health.consecutive_failures", "ALERT_CHANNEL_HEALTH_CONSECUTIVE_FAILURES", 10),
+			WindowSeconds:        env.int("alert.channel_health.window_seconds", "ALERT_CHANNEL_HEALTH_WINDOW", 3600),
+			FailurePercent:       env.int("alert.channel_health.failure_percent", "ALERT_CHANNEL_HEALTH_FAILURE_PERCENT", 90),
+			MinAttempts:          env.int("alert.channel_health.min_attempts", "ALERT_CHANNEL_HEALTH_MIN_ATTEMPTS", 5),
+			ProbeIntervalSeconds: env.int("alert.channel_health.probe_interval_seconds", "ALERT_CHANNEL_HEALTH_PROBE_INTERVAL", 600),
+		},
+		Flapping: FlappingConfig{
+			Enabled:         env.bool("alert.flapping.enabled", "ALERT_FLAPPING_ENABLED", true),
+			Transitions:     env.int("alert.flapping.transitions", "ALERT_FLAPPING_TRANSITIONS", 5),
+			WindowSeconds:   env.int("alert.flapping.window_seconds", "ALERT_FLAPPING_WINDOW", 600),
+			CooldownSeconds: env.int("alert.flapping.cooldown_seconds", "ALERT_FLAPPING_COOLDOWN", 600),
+		},
+	}
+	config.SNMP = SNMPConfig{
+		DefaultCommunity: env.str("snmp.default_community", "SNMP_COMMUNITY", "public"),
+		DefaultVersion:   env.str("snmp.default_version", "SNMP_VERSION", "v2c"),
+		DefaultTimeout:   env.int("snmp.default_timeout", "SNMP_TIMEOUT", 5000),
+	}
+	config.RateLimit = RateLimitConfig{
+		AllowCIDRs: env.slice("rate_limit.allow_cidrs", "RATE_LIMIT_ALLOW_CIDRS", nil),
+		Read:       RateLimitRule{RequestsPerSecond: 100, Burst: 200},
+		Write:      RateLimitRule{RequestsPerSecond: 20, Burst: 40},
+		Stream:     RateLimitRule{RequestsPerSecond: 1, Burst: 10},
+	}
+	config.Debug = DebugConfig{
+		FailureInjection:         env.bool("debug.failure_injection", "DEBUG_FAILURE_INJECTION", false),
+		AdminToken:               env.str("debug.admin_token", "DEBUG_ADMIN_TOKEN", ""),
+		IncludeSyntheticInUptime: env.bool("debug.include_synthetic_in_uptime", "DEBUG_INCLUDE_SYNTHETIC_IN_UPTIME", false),
+	}
+	config.Probe = ProbeConfig{
+		Enabled:       env.bool("probe.enabled", "PROBE_ENABLED", false),
+		Timeout:       env.int("probe.timeout", "PROBE_TIMEOUT", 10),
+		MaxConcurrent: env.int("probe.max_concurrent", "PROBE_MAX_CONCURRENT", 10),
+		RateLimit:     RateLimitRule{RequestsPerSecond: 2, Burst: 10},
+		AllowPrivate:  env.bool("probe.allow_private", "PROBE_ALLOW_PRIVATE", false),
+		AllowCIDRs:    env.slice("probe.allow_cidrs", "PROBE_ALLOW_CIDRS", nil),
+	}
+	config.HistoryArchive = HistoryArchiveConfig{
+		Enabled:         env.bool("history_archive.enabled", "HISTORY_ARCHIVE_ENABLED", false),
+		Endpoint:        env.str("history_archive.endpoint", "HISTORY_ARCHIVE_ENDPOINT", ""),
+		Region:          env.str("history_archive.region", "HISTORY_ARCHIVE_REGION", "us-east-1"),
+		Bucket:          env.str("history_archive.bucket", "HISTORY_ARCHIVE_BUCKET", ""),
+		AccessKey:       env.str("history_archive.access_key", "HISTORY_ARCHIVE_ACCESS_KEY", ""),
+		SecretKey:       env.str("history_archive.secret_key", "HISTORY_ARCHIVE_SECRET_KEY", ""),
+		Prefix:          env.str("history_archive.prefix", "HISTORY_ARCHIVE_PREFIX", "arrowgo"),
+		IntervalMinutes: env.int("history_archive.interval_minutes", "HISTORY_ARCHIVE_INTERVAL_MINUTES", 60),
+		BatchSize:       env.int("history_archive.batch_size", "HISTORY_ARCHIVE_BATCH_SIZE", 50000),
+		AlertChannelID:  uint32(env.int("history_archive.alert_channel_id", "HISTORY_ARCHIVE_ALERT_CHANNEL_ID", 0)),
+	}
+	config.Discovery = DiscoveryConfig{
+		Enabled:        env.bool("discovery.enabled", "DISCOVERY_ENABLED", false),
+		AllowedZones:   env.slice("discovery.allowed_zones", "DISCOVERY_ALLOWED_ZONES", nil),
+		AllowedServers: env.slice("discovery.allowed_servers", "DISCOVERY_ALLOWED_SERVERS", nil),
+		AllowedCIDRs:   env.slice("discovery.allowed_cidrs", "DISCOVERY_ALLOWED_CIDRS", nil),
+		Ports:          []int{22, 25, 80, 443, 8080, 8443},
+		MaxHosts:       env.int("discovery.max_hosts", "DISCOVERY_MAX_HOSTS", 1024),
+		MaxConcurrent:  env.int("discovery.max_concurrent", "DISCOVERY_MAX_CONCURRENT", 16),
+		Timeout:        env.int("discovery.timeout", "DISCOVERY_TIMEOUT", 2),
+	}
+
+	return config
 }
 
 // setDefaults 设置默认值
@@ -155,6 +518,12 @@ func setDefaults(config *Config) {
 	if config.Server.Host == "" {
 		config.Server.Host = "0.0.0.0"
 	}
+	if config.Server.Public.Host == "" {
+		config.Server.Public.Host = "0.0.0.0"
+	}
+	if config.Server.Public.Port == 0 {
+		config.Server.Public.Port = 8081
+	}
 	if config.Database.Driver == "" {
 		config.Database.Driver = "sqlite"
 	}
@@ -165,7 +534,73 @@ func setDefaults(config *Config) {
 		config.Monitor.CheckInterval = 60
 	}
 	if config.Monitor.Workers == 0 {
-		config.Monitor.Workers = 10
+		config.Monitor.Workers = 100
+	}
+	if config.Monitor.QueueSize == 0 {
+		config.Monitor.QueueSize = 1000
+	}
+	if config.Monitor.ESBufferSize == 0 {
+		config.Monitor.ESBufferSize = 500
+	}
+	if config.Monitor.StartupJitter == 0 {
+		config.Monitor.StartupJitter = 10
+	}
+	if config.Monitor.HistoryBatchSize == 0 {
+		config.Monitor.HistoryBatchSize = 200
+	}
+	if config.Monitor.HistoryFlushInterval == 0 {
+		config.Monitor.HistoryFlushInterval = 2
+	}
+	if config.Monitor.UptimeInterval == 0 {
+		config.Monitor.UptimeInterval = 60
+	}
+	if config.Monitor.HistorySweepInterval == 0 {
+		config.Monitor.HistorySweepInterval = 86400
+	}
+	if config.Monitor.Redaction.MaxBodyBytes == 0 {
+		config.Monitor.Redaction.MaxBodyBytes = 4096
+	}
+	if config.Monitor.Limits.MaxTargets == 0 {
+		config.Monitor.Limits.MaxTargets = 5000
+	}
+	if config.Monitor.Limits.MaxFastTargets == 0 {
+		config.Monitor.Limits.MaxFastTargets = 200
+	}
+	if config.Monitor.Limits.FastInterval == 0 {
+		config.Monitor.Limits.FastInterval = 15
+	}
+	if config.Monitor.ClockSkew.Threshold == 0 {
+		config.Monitor.ClockSkew.Threshold = 30
+	}
+	if config.Monitor.Script.MaxOutputBytes == 0 {
+		config.Monitor.Script.MaxOutputBytes = 65536
+	}
+	if config.Monitor.Ping.Mode == "" {
+		config.Monitor.Ping.Mode = "auto"
+	}
+	if config.Monitor.ResponseBody.MaxStoredBytes == 0 {
+		config.Monitor.ResponseBody.MaxStoredBytes = 102400
+	}
+	if config.Monitor.ResponseBody.Archive.MaxBytes == 0 {
+		config.Monitor.ResponseBody.Archive.MaxBytes = 5 << 20
+	}
+	if config.Monitor.ResponseBody.Archive.RetentionDays == 0 {
+		config.Monitor.ResponseBody.Archive.RetentionDays = 30
+	}
+	if config.Monitor.ResponseBody.Archive.MaxPerTarget == 0 {
+		config.Monitor.ResponseBody.Archive.MaxPerTarget = 20
+	}
+	if config.Monitor.Memory.CheckInterval == 0 {
+		config.Monitor.Memory.CheckInterval = 60
+	}
+	if config.Monitor.Spool.Dir == "" {
+		config.Monitor.Spool.Dir = "spool"
+	}
+	if config.Monitor.Spool.MaxSizeMB == 0 {
+		config.Monitor.Spool.MaxSizeMB = 100
+	}
+	if config.Monitor.Spool.ReplayInterval == 0 {
+		config.Monitor.Spool.ReplayInterval = 30
 	}
 	if config.Logger.Level == "" {
 		config.Logger.Level = "info"
@@ -173,6 +608,9 @@ func setDefaults(config *Config) {
 	if config.Logger.Output == "" {
 		config.Logger.Output = "stdout"
 	}
+	if config.Logger.FileLogDir == "" {
+		config.Logger.FileLogDir = "logs"
+	}
 	if config.Alert.CooldownSeconds == 0 {
 		config.Alert.CooldownSeconds = 300
 	}
@@ -182,6 +620,33 @@ func setDefaults(config *Config) {
 	if config.Alert.RetryInterval == 0 {
 		config.Alert.RetryInterval = 60
 	}
+	if config.Alert.Digest.Weekday == "" {
+		config.Alert.Digest.Weekday = "monday"
+	}
+	if config.Alert.Digest.Time == "" {
+		config.Alert.Digest.Time = "09:00"
+	}
+	if config.Alert.Digest.WindowDays == 0 {
+		config.Alert.Digest.WindowDays = 45
+	}
+	if config.Alert.ChannelHealth.WindowSeconds == 0 {
+		config.Alert.ChannelHealth.WindowSeconds = 3600
+	}
+	if config.Alert.ChannelHealth.MinAttempts == 0 {
+		config.Alert.ChannelHealth.MinAttempts = 5
+	}
+	if config.Alert.ChannelHealth.ProbeIntervalSeconds == 0 {
+		config.Alert.ChannelHealth.ProbeIntervalSeconds = 600
+	}
+	if config.Alert.Flapping.Transitions == 0 {
+		config.Alert.Flapping.Transitions = 5
+	}
+	if config.Alert.Flapping.WindowSeconds == 0 {
+		config.Alert.Flapping.WindowSeconds = 600
+	}
+	if config.Alert.Flapping.CooldownSeconds == 0 {
+		config.Alert.Flapping.CooldownSeconds = 600
+	}
 	if config.SNMP.DefaultCommunity == "" {
 		config.SNMP.DefaultCommunity = "public"
 	}
@@ -191,6 +656,50 @@ func setDefaults(config *Config) {
 	if config.SNMP.DefaultTimeout == 0 {
 		config.SNMP.DefaultTimeout = 5000
 	}
+	setRateLimitDefaults(&config.RateLimit.Read, 100, 200)
+	setRateLimitDefaults(&config.RateLimit.Write, 20, 40)
+	setRateLimitDefaults(&config.RateLimit.Stream, 1, 10)
+	if config.Probe.Timeout == 0 {
+		config.Probe.Timeout = 10
+	}
+	if config.Probe.MaxConcurrent == 0 {
+		config.Probe.MaxConcurrent = 10
+	}
+	setRateLimitDefaults(&config.Probe.RateLimit, 2, 10)
+	if config.HistoryArchive.Region == "" {
+		config.HistoryArchive.Region = "us-east-1"
+	}
+	if config.HistoryArchive.Prefix == "" {
+		config.HistoryArchive.Prefix = "arrowgo"
+	}
+	if config.HistoryArchive.IntervalMinutes == 0 {
+		config.HistoryArchive.IntervalMinutes = 60
+	}
+	if config.HistoryArchive.BatchSize == 0 {
+		config.HistoryArchive.BatchSize = 50000
+	}
+	if len(config.Discovery.Ports) == 0 {
+		config.Discovery.Ports = []int{22, 25, 80, 443, 8080, 8443}
+	}
+	if config.Discovery.MaxHosts == 0 {
+		config.Discovery.MaxHosts = 1024
+	}
+	if config.Discovery.MaxConcurrent == 0 {
+		config.Discovery.MaxConcurrent = 16
+	}
+	if config.Discovery.Timeout == 0 {
+		config.Discovery.Timeout = 2
+	}
+}
+
+// setRateLimitDefaults 为未配置的限流规则设置默认值
+func setRateLimitDefaults(rule *RateLimitRule, rps float64, burst int) {
+	if rule.RequestsPerSecond == 0 {
+		rule.RequestsPerSecond = rps
+	}
+	if rule.Burst == 0 {
+		rule.Burst = burst
+	}
 }
 
 func getEnv(key, defaultVal string) string {
@@ -260,6 +769,26 @@ func (c *Config) Validate() error {
 	if c.Server.Host == "" {
 		return fmt.Errorf("server host cannot be empty")
 	}
+	if err := c.Server.TLS.validate("server.tls"); err != nil {
+		return err
+	}
+	if public := c.Server.Public; public.Enabled {
+		if public.Port < 1 || public.Port > 65535 {
+			return fmt.Errorf("invalid public HTTP port: %d", public.Port)
+		}
+		if err := public.TLS.validate("server.public.tls"); err != nil {
+			return err
+		}
+		// 与管理接口共用地址时无法区分请求来自哪个监听地址，管理接口会暴露在公开地址上
+		if sameListenAddress(public.Host, public.Port, c.Server.Host, c.Server.HTTPPort) {
+			return fmt.Errorf("server.public listens on %s, which overlaps the admin API on %s; admin routes would be exposed on the public listener",
+				listenAddress(public.Host, public.Port), listenAddress(c.Server.Host, c.Server.HTTPPort))
+		}
+		if sameListenAddress(public.Host, public.Port, c.Server.Host, c.Server.GRPCPort) {
+			return fmt.Errorf("server.public listens on %s, which overlaps the gRPC server on %s",
+				listenAddress(public.Host, public.Port), listenAddress(c.Server.Host, c.Server.GRPCPort))
+		}
+	}
 
 	// 验证数据库配置
 	validDrivers := map[string]bool{
@@ -297,6 +826,58 @@ func (c *Config) Validate() error {
 	if c.Monitor.Workers < 1 {
 		return fmt.Errorf("monitor workers must be at least 1")
 	}
+	if c.Monitor.QueueSize < 1 || c.Monitor.ESBufferSize < 1 {
+		return fmt.Errorf("monitor queue_size and es_buffer_size must be at least 1")
+	}
+	if c.Monitor.StartupJitter < 1 {
+		return fmt.Errorf("monitor startup_jitter must be at least 1 second")
+	}
+	if c.Monitor.HistoryBatchSize < 1 {
+		return fmt.Errorf("monitor history_batch_size must be at least 1")
+	}
+	if c.Monitor.HistoryFlushInterval < 1 || c.Monitor.UptimeInterval < 1 {
+		return fmt.Errorf("monitor history_flush_interval and uptime_interval must be at least 1 second")
+	}
+	if c.Monitor.Limits.FastInterval < 1 {
+		return fmt.Errorf("monitor limits fast_interval must be at least 1 second")
+	}
+	if c.Monitor.ResponseBody.MaxStoredBytes < 1 {
+		return fmt.Errorf("monitor response_body max_stored_bytes must be at least 1")
+	}
+	if archive := c.Monitor.ResponseBody.Archive; archive.Enabled {
+		if archive.MaxBytes < 1 || archive.MaxBytes > 10<<20 {
+			return fmt.Errorf("monitor response_body archive max_bytes must be between 1 and %d", 10<<20)
+		}
+		if archive.RetentionDays < 1 || archive.MaxPerTarget < 1 {
+			return fmt.Errorf("monitor response_body archive retention_days and max_per_target must be at least 1")
+		}
+	}
+	if c.Monitor.Script.MaxOutputBytes < 1 {
+		return fmt.Errorf("monitor script max_output_bytes must be at least 1")
+	}
+	for _, path := range c.Monitor.Script.AllowedPaths {
+		if !filepath.IsAbs(path) || filepath.Clean(path) != path {
+			return fmt.Errorf("monitor script allowed_paths: %q must be a clean absolute path", path)
+		}
+	}
+	switch c.Monitor.Ping.Mode {
+	case "auto", "native", "exec":
+	default:
+		return fmt.Errorf("monitor ping mode must be auto, native or exec, got %q", c.Monitor.Ping.Mode)
+	}
+	if memory := c.Monitor.Memory; memory.SoftLimitMB < 0 || memory.AlertThresholdMB < 0 || memory.CheckInterval < 1 {
+		return fmt.Errorf("monitor memory soft_limit_mb and alert_threshold_mb cannot be negative, check_interval must be at least 1 second")
+	}
+	if spool := c.Monitor.Spool; spool.Enabled && (spool.MaxSizeMB < 1 || spool.ReplayInterval < 1) {
+		return fmt.Errorf("monitor spool max_size_mb must be at least 1 and replay_interval at least 1 second")
+	}
+	// 可用率按最近 30 天的历史计算
+	if days := c.Monitor.HistoryRetentionDays; days != 0 && days < 30 {
+		return fmt.Errorf("monitor history_retention_days must be 0 (keep forever) or at least 30")
+	}
+	if c.Monitor.HistorySweepInterval < 60 {
+		return fmt.Errorf("monitor history_sweep_interval must be at least 60 seconds")
+	}
 
 	// 验证日志配置
 	validLogLevels := map[string]bool{
@@ -327,6 +908,35 @@ func (c *Config) Validate() error {
 		if c.Alert.RetryInterval < 0 {
 			return fmt.Errorf("alert retry interval cannot be negative")
 		}
+		if c.Alert.Digest.Enabled {
+			if c.Alert.Digest.ChannelID == 0 {
+				return fmt.Errorf("alert digest channel_id is required when the digest is enabled")
+			}
+			if c.Alert.Digest.WindowDays < 1 {
+				return fmt.Errorf("alert digest window_days must be at least 1")
+			}
+			if digest := c.Alert.Digest; digest.Schedule != "" {
+				if _, err := schedule.Validate(digest.Schedule, time.Local, schedule.MinIntervals["digest"]); err != nil {
+					return fmt.Errorf("alert digest schedule: %w", err)
+				}
+			} else if _, err := schedule.Weekly(digest.Weekday, digest.Time); err != nil {
+				return fmt.Errorf("alert digest weekday or time: %w", err)
+			}
+		}
+		if h := c.Alert.ChannelHealth; h.Enabled {
+			if h.ConsecutiveFailures < 0 || h.FailurePercent < 0 || h.FailurePercent > 100 {
+				return fmt.Errorf("alert channel_health consecutive_failures cannot be negative and failure_percent must be 0-100")
+			}
+			if h.ConsecutiveFailures == 0 && h.FailurePercent == 0 {
+				return fmt.Errorf("alert channel_health needs consecutive_failures or failure_percent when enabled")
+			}
+			if h.WindowSeconds < 1 || h.MinAttempts < 1 || h.ProbeIntervalSeconds < 1 {
+				return fmt.Errorf("alert channel_health window_seconds, min_attempts and probe_interval_seconds must be at least 1")
+			}
+		}
+		if f := c.Alert.Flapping; f.Enabled && (f.Transitions < 1 || f.WindowSeconds < 1 || f.CooldownSeconds < 1) {
+			return fmt.Errorf("alert flapping transitions, window_seconds and cooldown_seconds must be at least 1")
+		}
 	}
 
 	// 验证SNMP配置
@@ -342,5 +952,119 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("SNMP timeout cannot be negative")
 	}
 
+	// 验证调试配置
+	if c.Debug.FailureInjection && c.Debug.AdminToken == "" {
+		return fmt.Errorf("debug admin token is required when failure injection is enabled")
+	}
+
+	// 验证探测配置，模块参数在注册 /probe 时按监控类型校验
+	if c.Probe.Enabled {
+		if c.Probe.Timeout < 1 || c.Probe.Timeout > 25 {
+			return fmt.Errorf("probe timeout must be between 1 and 25 seconds")
+		}
+		if c.Probe.MaxConcurrent < 1 {
+			return fmt.Errorf("probe max_concurrent must be at least 1")
+		}
+		if len(c.Probe.Modules) == 0 {
+			return fmt.Errorf("probe modules cannot be empty when the probe endpoint is enabled")
+		}
+	}
+
+	// 验证历史归档配置
+	if a := c.HistoryArchive; a.Enabled {
+		if c.Monitor.HistoryRetentionDays == 0 {
+			return fmt.Errorf("history_archive needs monitor.history_retention_days: only history older than the retention is exported")
+		}
+		if u, err := url.Parse(a.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("history_archive endpoint must be an http or https URL")
+		}
+		if a.Bucket == "" || a.AccessKey == "" || a.SecretKey == "" {
+			return fmt.Errorf("history_archive bucket, access_key and secret_key are required when enabled")
+		}
+		if a.IntervalMinutes < 1 || a.BatchSize < 1 {
+			return fmt.Errorf("history_archive interval_minutes and batch_size must be at least 1")
+		}
+	}
+
+	// 验证发现配置
+	if d := c.Discovery; d.Enabled {
+		if len(d.AllowedZones) == 0 && len(d.AllowedCIDRs) == 0 {
+			return fmt.Errorf("discovery needs allowed_zones or allowed_cidrs when enabled")
+		}
+		if len(d.AllowedZones) > 0 && len(d.AllowedServers) == 0 {
+			return fmt.Errorf("discovery allowed_servers cannot be empty when allowed_zones is set")
+		}
+		for _, server := range d.AllowedServers {
+			if _, _, err := net.SplitHostPort(server); err != nil {
+				return fmt.Errorf("invalid discovery server %q: must be host:port", server)
+			}
+		}
+		for _, cidr := range d.AllowedCIDRs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil && net.ParseIP(cidr) == nil {
+				return fmt.Errorf("invalid discovery allowed CIDR: %s", cidr)
+			}
+		}
+		for _, port := range d.Ports {
+			if port < 1 || port > 65535 {
+				return fmt.Errorf("invalid discovery port: %d", port)
+			}
+		}
+		if d.MaxHosts < 1 || d.MaxConcurrent < 1 {
+			return fmt.Errorf("discovery max_hosts and max_concurrent must be at least 1")
+		}
+		if d.Timeout < 1 || d.Timeout > 30 {
+			return fmt.Errorf("discovery timeout must be between 1 and 30 seconds")
+		}
+	}
+
+	// 验证限流配置
+	for _, cidr := range c.RateLimit.AllowCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil && net.ParseIP(cidr) == nil {
+			return fmt.Errorf("invalid rate limit allow CIDR: %s", cidr)
+		}
+	}
+	for name, rule := range map[string]RateLimitRule{
+		"read":   c.RateLimit.Read,
+		"write":  c.RateLimit.Write,
+		"stream": c.RateLimit.Stream,
+	} {
+		if rule.RequestsPerSecond <= 0 || rule.Burst < 1 {
+			return fmt.Errorf("invalid %s rate limit: requests_per_second and burst must be positive", name)
+		}
+	}
+
+	return nil
+}
+// validate 检查启用 TLS 时的证书配置；name 是错误信息中的配置路径
+func (t TLSConfig) validate(name string) error {
+	if !t.Enabled {
+		if t.ClientCAFile != "" {
+			return fmt.Errorf("%s client_ca_file requires %s enabled", name, name)
+		}
+		return nil
+	}
+	if t.CertFile == "" || t.KeyFile == "" {
+		return fmt.Errorf("%s cert_file and key_file are required when enabled", name)
+	}
 	return nil
-}
\ No newline at end of file
+}
+
+func listenAddress(host string, port int) string {
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// sameListenAddress 判断两个监听地址是否会冲突：端口相同，且主机相同或其中一个监听所有地址
+func sameListenAddress(hostA string, portA int, hostB string, portB int) bool {
+	if portA != portB {
+		return false
+	}
+	wildcard := func(host string) bool {
+		ip := net.ParseIP(host)
+		return host == "" || (ip != nil && ip.IsUnspecified())
+	}
+	if wildcard(hostA) || wildcard(hostB) || hostA == hostB {
+		return true
+	}
+	ipA, ipB := net.ParseIP(hostA), net.ParseIP(hostB)
+	return ipA != nil && ipB != nil && ipA.Equal(ipB)
+}
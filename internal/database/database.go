@@ -2,12 +2,10 @@ package database
 
 import (
 	"fmt"
+	"monitor/internal/database/dialect"
 	"monitor/internal/models"
 	"time"
 
-	"gorm.io/driver/mysql"
-	"gorm.io/driver/postgres"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -20,30 +18,65 @@ type Config struct {
 	Password string
 	DBName   string
 	SSLMode  string
+	// DSN 不为空时直接用于连接，忽略上面的连接参数
+	DSN string
 }
 
+// SchemaVersion 数据库结构版本，模型或 sql/*_init.sql 发生变化时递增
+const SchemaVersion = 44
+
 var DB *gorm.DB
 
+// appModels 由 AutoMigrate 维护的表
+var appModels = []interface{}{
+	&models.MonitorTarget{},
+	&models.MonitorStatus{},
+	&models.MonitorHistory{},
+	&models.ResponseArchive{},
+	&models.HistoryExport{},
+	&models.Certificate{},
+	&models.CertificateTarget{},
+	&models.IPGeoCache{},
+	&models.DNSProvider{},
+	&models.AlertChannel{},
+	&models.AlertRule{},
+	&models.AlertCondition{},
+	&models.AlertRuleGroup{},
+	&models.AlertHistory{},
+	&models.AlertRuleSnooze{},
+	&models.APIToken{},
+	&models.MaintenanceWindow{},
+	&models.DiscoveryCandidate{},
+	&models.MonitorGroup{},
+	&models.MonitorHistoryHourly{},
+	&models.MonitorHistoryDaily{},
+}
+
 func InitDB(config Config) error {
-	var dialector gorm.Dialector
-
-	switch config.Driver {
-	case "mysql":
-		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-			config.User, config.Password, config.Host, config.Port, config.DBName)
-		dialector = mysql.Open(dsn)
-	case "postgres":
-		dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-			config.Host, config.Port, config.User, config.Password, config.DBName, config.SSLMode)
-		dialector = postgres.Open(dsn)
-	case "sqlite":
-		dialector = sqlite.Open(config.DBName)
-	default:
-		return fmt.Errorf("unsupported database driver: %s", config.Driver)
+	dsn := config.DSN
+	if dsn == "" {
+		switch config.Driver {
+		case "mysql":
+			dsn = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4",
+				config.User, config.Password, config.Host, config.Port, config.DBName)
+		case "postgres":
+			dsn = fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+				config.Host, config.Port, config.User, config.Password, config.DBName, config.SSLMode)
+		case "sqlite":
+			dsn = config.DBName
+		}
+	}
+	// 时间统一按 UTC 写入和读取：MySQL 会话时区设为 UTC，PostgreSQL 使用 TimeZone=UTC
+	dialector, err := dialect.Open(config.Driver, dsn)
+	if err != nil {
+		return err
 	}
 
 	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
+		NowFunc: func() time.Time {
+			return time.Now().UTC()
+		},
 	})
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
@@ -62,20 +95,15 @@ func InitDB(config Config) error {
 
 	DB = db
 
-	if err := DB.AutoMigrate(
-		&models.MonitorTarget{},
-		&models.MonitorStatus{},
-		&models.MonitorHistory{},
-		&models.IPGeoCache{},
-		&models.DNSProvider{},
-		&models.AlertChannel{},
-		&models.AlertRule{},
-		&models.AlertCondition{},
-		&models.AlertRuleGroup{},
-	); err != nil {
+	if err := DB.AutoMigrate(append(appModels, &schemaMigration{})...); err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	// 旧版本按本地时间写入的时间戳转换为 UTC（只执行一次）
+	if err := migrateTimestampsToUTC(time.Local); err != nil {
+		return fmt.Errorf("failed to convert timestamps to UTC: %w", err)
+	}
+
 	// Initialize default DNS providers
 	if err := initDefaultDNSProviders(); err != nil {
 		return fmt.Errorf("failed to initialize default DNS providers: %w", err)
@@ -0,0 +1,139 @@
+// Package dialect hides the differences between MySQL, PostgreSQL and SQLite
+// that raw SQL runs into, mostly around timestamps.
+//
+// Convention: every timestamp is stored in UTC. The dialector returned by UTC
+// converts time arguments before they reach the driver, so gorm queries and
+// db.Raw/db.Exec alike compare UTC against UTC on every driver. Raw SQL that
+// does arithmetic on a timestamp column must use the helpers below instead of
+// driver-specific functions.
+package dialect
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Dialect names as reported by gorm.Dialector.Name
+const (
+	MySQL    = "mysql"
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+)
+
+// Of returns the dialect of the connection
+func Of(db *gorm.DB) string {
+	return db.Dialector.Name()
+}
+
+// Time normalizes a time for use as a query argument. Arguments are converted
+// automatically; use it when a time is formatted into SQL or compared in Go
+// against values read from the database.
+func Time(t time.Time) time.Time {
+	return t.UTC()
+}
+
+// UnixSeconds returns an expression for the Unix time, in whole seconds, of a
+// timestamp column. Use it for bucketing and differences instead of
+// UNIX_TIMESTAMP, EXTRACT(EPOCH ...) or strftime('%s', ...).
+func UnixSeconds(db *gorm.DB, column string) (string, error) {
+	quoted := db.Statement.Quote(column)
+	switch Of(db) {
+	case MySQL:
+		// the session time zone is UTC, see the DSN built by database.InitDB
+		return fmt.Sprintf("UNIX_TIMESTAMP(%s)", quoted), nil
+	case Postgres:
+		return fmt.Sprintf("CAST(EXTRACT(EPOCH FROM %s) AS BIGINT)", quoted), nil
+	case SQLite:
+		return fmt.Sprintf("CAST(strftime('%%s', %s) AS INTEGER)", quoted), nil
+	default:
+		return "", fmt.Errorf("unsupported dialect: %s", Of(db))
+	}
+}
+
+// Open returns the UTC dialector for a DSN of the given driver. MySQL DSNs
+// get parseTime, loc=UTC and the session time zone '+00:00', and PostgreSQL
+// key=value DSNs TimeZone=UTC unless they set one, so that a DSN from the
+// environment follows the convention like one built from the config.
+func Open(driver, dsn string) (gorm.Dialector, error) {
+	switch driver {
+	case MySQL:
+		cfg, err := gomysql.ParseDSN(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mysql dsn: %w", err)
+		}
+		cfg.ParseTime = true
+		cfg.Loc = time.UTC
+		if cfg.Params == nil {
+			cfg.Params = make(map[string]string)
+		}
+		cfg.Params["time_zone"] = "'+00:00'"
+		return UTC(mysql.Open(cfg.FormatDSN())), nil
+	case Postgres:
+		if !strings.Contains(dsn, "://") && !strings.Contains(dsn, "TimeZone=") {
+			dsn += " TimeZone=UTC"
+		}
+		return UTC(postgres.Open(dsn)), nil
+	case SQLite:
+		return UTC(sqlite.Open(dsn)), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", driver)
+	}
+}
+
+// UTC wraps a dialector so that every time.Time bound to a statement is
+// converted to UTC. SQLite stores times as text with the offset of the value
+// and compares them as strings, and MySQL DATETIME drops the offset entirely,
+// so a value in any other zone would be stored or compared wrongly.
+func UTC(d gorm.Dialector) gorm.Dialector {
+	return utcDialector{d}
+}
+
+type utcDialector struct {
+	gorm.Dialector
+}
+
+// BindVarTo is called right after the argument is appended to stmt.Vars
+func (d utcDialector) BindVarTo(writer clause.Writer, stmt *gorm.Statement, v interface{}) {
+	if n := len(stmt.Vars); n > 0 {
+		switch t := stmt.Vars[n-1].(type) {
+		case time.Time:
+			stmt.Vars[n-1] = t.UTC()
+		case *time.Time:
+			if t != nil {
+				utc := t.UTC()
+				stmt.Vars[n-1] = &utc
+			}
+		}
+	}
+	d.Dialector.BindVarTo(writer, stmt, v)
+}
+
+// SavePoint and RollbackTo keep nested transactions working
+func (d utcDialector) SavePoint(tx *gorm.DB, name string) error {
+	if sp, ok := d.Dialector.(gorm.SavePointerDialectorInterface); ok {
+		return sp.SavePoint(tx, name)
+	}
+	return gorm.ErrUnsupportedDriver
+}
+
+func (d utcDialector) RollbackTo(tx *gorm.DB, name string) error {
+	if sp, ok := d.Dialector.(gorm.SavePointerDialectorInterface); ok {
+		return sp.RollbackTo(tx, name)
+	}
+	return gorm.ErrUnsupportedDriver
+}
+
+func (d utcDialector) Translate(err error) error {
+	if translator, ok := d.Dialector.(gorm.ErrorTranslator); ok {
+		return translator.Translate(err)
+	}
+	return err
+}
@@ -0,0 +1,189 @@
+package dialect
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type event struct {
+	ID uint
+	At time.Time
+}
+
+// testDSNs are the databases the tests run against besides SQLite, which
+// always runs; CI sets the variables to cover MySQL and PostgreSQL
+var testDSNs = []struct{ driver, env string }{
+	{MySQL, "MONITOR_TEST_MYSQL_DSN"},
+	{Postgres, "MONITOR_TEST_POSTGRES_DSN"},
+}
+
+// forEachDriver runs test in a subtest per database, each with an empty
+// events table
+func forEachDriver(t *testing.T, test func(t *testing.T, db *gorm.DB)) {
+	t.Helper()
+	t.Run(SQLite, func(t *testing.T) {
+		test(t, openDB(t, SQLite, "file:"+t.Name()+"?mode=memory&cache=shared"))
+	})
+	for _, d := range testDSNs {
+		dsn := os.Getenv(d.env)
+		t.Run(d.driver, func(t *testing.T) {
+			if dsn == "" {
+				t.Skipf("%s not set", d.env)
+			}
+			test(t, openDB(t, d.driver, dsn))
+		})
+	}
+}
+
+func openDB(t *testing.T, driver, dsn string) *gorm.DB {
+	t.Helper()
+	dialector, err := Open(driver, dsn)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	// A shared server keeps the table between runs
+	db.Migrator().DropTable(&event{})
+	t.Cleanup(func() {
+		db.Migrator().DropTable(&event{})
+		sqlDB.Close()
+	})
+	if err := db.AutoMigrate(&event{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// Times in another zone are stored as UTC, so SQLite's string comparison
+// orders them by instant and MySQL DATETIME keeps the right wall clock
+func TestUTCBindsTimesAsUTC(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		shanghai := time.FixedZone("CST", 8*3600)
+		at := time.Date(2026, 3, 1, 9, 0, 0, 0, shanghai)
+		if err := db.Create(&event{At: at}).Error; err != nil {
+			t.Fatalf("create: %v", err)
+		}
+
+		if Of(db) == SQLite {
+			var stored string
+			db.Raw("SELECT CAST(at AS TEXT) FROM events").Scan(&stored)
+			if stored != "2026-03-01 01:00:00+00:00" {
+				t.Errorf("stored %q, want the UTC text", stored)
+			}
+		}
+		var got event
+		if err := db.First(&got).Error; err != nil {
+			t.Fatalf("read back: %v", err)
+		}
+		if !got.At.Equal(at) {
+			t.Errorf("read back %v, want %v", got.At, at)
+		}
+
+		// 01:00 UTC is before 02:00 UTC although "09:00+08:00" sorts after "02:00+00:00"
+		var count int64
+		db.Model(&event{}).Where("at < ?", time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC)).Count(&count)
+		if count != 1 {
+			t.Errorf("gorm query matched %d rows, want 1", count)
+		}
+		later := time.Date(2026, 3, 1, 1, 30, 0, 0, time.FixedZone("", -3600))
+		db.Raw("SELECT count(*) FROM events WHERE at < ?", &later).Scan(&count)
+		if count != 1 {
+			t.Errorf("raw query with *time.Time matched %d rows, want 1", count)
+		}
+	})
+}
+
+func TestUnixSeconds(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.FixedZone("", -5*3600))
+		db.Create(&event{At: at})
+
+		expr, err := UnixSeconds(db, "at")
+		if err != nil {
+			t.Fatalf("UnixSeconds: %v", err)
+		}
+		var seconds int64
+		if err := db.Raw("SELECT " + expr + " FROM events").Scan(&seconds).Error; err != nil {
+			t.Fatalf("select %s: %v", expr, err)
+		}
+		if seconds != at.Unix() {
+			t.Errorf("%s = %d, want %d", expr, seconds, at.Unix())
+		}
+	})
+
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.FixedZone("", -5*3600))
+	if Time(at).Location() != time.UTC || !Time(at).Equal(at) {
+		t.Errorf("Time(%v) = %v", at, Time(at))
+	}
+}
+
+func TestUTCNestedTransactions(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&event{At: time.Now()}).Error; err != nil {
+				return err
+			}
+			// The inner transaction is a savepoint and rolls back on its own
+			tx.Transaction(func(inner *gorm.DB) error {
+				inner.Create(&event{At: time.Now()})
+				return gorm.ErrInvalidData
+			})
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("transaction: %v", err)
+		}
+		var count int64
+		db.Model(&event{}).Count(&count)
+		if count != 1 {
+			t.Errorf("%d rows, want the outer one only", count)
+		}
+	})
+}
+
+// DSNs from the environment get the UTC session settings
+func TestOpenSetsUTC(t *testing.T) {
+	d, err := Open(MySQL, "monitor:secret@tcp(db:3306)/monitor?loc=Local&time_zone=SYSTEM")
+	if err != nil {
+		t.Fatalf("Open mysql: %v", err)
+	}
+	cfg, err := gomysql.ParseDSN(d.(utcDialector).Dialector.(*mysql.Dialector).DSN)
+	if err != nil {
+		t.Fatalf("parse the resulting dsn: %v", err)
+	}
+	if !cfg.ParseTime || cfg.Loc != time.UTC || cfg.Params["time_zone"] != "'+00:00'" || cfg.Passwd != "secret" {
+		t.Errorf("mysql dsn %+v, want parseTime, loc UTC and time_zone '+00:00'", cfg)
+	}
+
+	for _, tc := range []struct{ in, want string }{
+		{"host=db user=monitor dbname=monitor", "host=db user=monitor dbname=monitor TimeZone=UTC"},
+		{"host=db TimeZone=Asia/Shanghai", "host=db TimeZone=Asia/Shanghai"},
+	} {
+		d, err := Open(Postgres, tc.in)
+		if err != nil {
+			t.Fatalf("Open postgres: %v", err)
+		}
+		if got := d.(utcDialector).Dialector.(*postgres.Dialector).DSN; got != tc.want {
+			t.Errorf("Open(%q) dsn %q, want %q", tc.in, got, tc.want)
+		}
+	}
+
+	if _, err := Open("oracle", ""); err == nil || !strings.Contains(err.Error(), "unsupported") {
+		t.Errorf("Open of an unknown driver: err = %v", err)
+	}
+}
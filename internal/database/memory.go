@@ -0,0 +1,26 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm/logger"
+)
+
+// InitMemoryDB 初始化一个 SQLite 内存数据库作为全局 DB，供测试使用。
+// 同名的数据库在进程内共享，不同的 name 互不影响；只使用一个连接，
+// 避免共享缓存模式下并发写入的表锁错误。
+func InitMemoryDB(name string) error {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", name)
+	if err := InitDB(Config{Driver: "sqlite", DBName: dsn}); err != nil {
+		return err
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database instance: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	sqlDB.SetConnMaxLifetime(0)
+	sqlDB.SetConnMaxIdleTime(0)
+	DB.Logger = logger.Default.LogMode(logger.Silent)
+	return nil
+}
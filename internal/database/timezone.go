@@ -0,0 +1,127 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"monitor/internal/database/dialect"
+	"monitor/internal/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// utcTimestampsMigration 标记本地时间戳已转换为 UTC
+const utcTimestampsMigration = "utc_timestamps"
+
+// schemaMigration 记录已执行过的一次性数据迁移
+type schemaMigration struct {
+	Name      string `gorm:"primaryKey;size:100"`
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// migrateTimestampsToUTC 把旧版本按 local 时区写入的时间戳改写为 UTC。
+// 只处理不带时区的列：SQLite 的文本时间、MySQL DATETIME、PostgreSQL timestamp；
+// MySQL TIMESTAMP 和 PostgreSQL timestamptz 存的本来就是绝对时间。
+func migrateTimestampsToUTC(local *time.Location) error {
+	var count int64
+	if err := DB.Model(&schemaMigration{}).Where("name = ?", utcTimestampsMigration).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	now := time.Now()
+	_, offset := now.In(local).Zone()
+	_, jan := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, local).Zone()
+	_, jul := time.Date(now.Year(), 7, 1, 0, 0, 0, 0, local).Zone()
+	if jan != jul {
+		logger.Warn("Local time zone observes daylight saving time, timestamps written in the other half of the year are converted with the current offset",
+			zap.String("zone", local.String()),
+			zap.Int("offset_seconds", offset),
+		)
+	}
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for _, model := range appModels {
+			stmt := &gorm.Statement{DB: tx}
+			if err := stmt.Parse(model); err != nil {
+				return err
+			}
+
+			columnTypes := make(map[string]string)
+			if dialect.Of(tx) != dialect.SQLite {
+				types, err := tx.Migrator().ColumnTypes(model)
+				if err != nil {
+					return err
+				}
+				for _, ct := range types {
+					columnTypes[ct.Name()] = strings.ToLower(ct.DatabaseTypeName())
+				}
+			}
+
+			for _, field := range stmt.Schema.Fields {
+				if field.DBName == "" || field.IndirectFieldType != reflect.TypeOf(time.Time{}) {
+					continue
+				}
+				converted, err := convertTimestampColumn(tx, stmt.Schema, field.DBName, columnTypes[field.DBName], offset)
+				if err != nil {
+					return fmt.Errorf("%s.%s: %w", stmt.Schema.Table, field.DBName, err)
+				}
+				if converted > 0 {
+					logger.Info("Converted timestamps to UTC",
+						zap.String("table", stmt.Schema.Table),
+						zap.String("column", field.DBName),
+						zap.Int64("rows", converted),
+					)
+				}
+			}
+		}
+
+		return tx.Create(&schemaMigration{Name: utcTimestampsMigration, AppliedAt: time.Now().UTC()}).Error
+	})
+}
+
+// convertTimestampColumn 转换一列，返回改写的行数
+func convertTimestampColumn(tx *gorm.DB, sch *schema.Schema, column, columnType string, offset int) (int64, error) {
+	table := tx.Statement.Quote(sch.Table)
+	col := tx.Statement.Quote(column)
+
+	var result *gorm.DB
+	switch dialect.Of(tx) {
+	case dialect.SQLite:
+		// go-sqlite3 以 "2006-01-02 15:04:05.999999999-07:00" 文本存储，带各自的偏移，
+		// 按字符串比较；CURRENT_TIMESTAMP 默认值（19 个字符）本来就是 UTC
+		normalized := fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:%%M:%%f', %s)", col)
+		result = tx.Exec(fmt.Sprintf("UPDATE %s SET %s = %s || '+00:00' WHERE length(%s) > 19 AND substr(%s, -6) <> '+00:00' AND %s IS NOT NULL",
+			table, col, normalized, col, col, normalized))
+	case dialect.MySQL:
+		if columnType != "datetime" || offset == 0 {
+			return 0, nil
+		}
+		// 显式保留 ON UPDATE CURRENT_TIMESTAMP 列，避免被刷新
+		keep := ""
+		if field := sch.LookUpField("updated_at"); field != nil && field.DBName != column {
+			keep = fmt.Sprintf(", %[1]s = %[1]s", tx.Statement.Quote(field.DBName))
+		}
+		result = tx.Exec(fmt.Sprintf("UPDATE %s SET %s = DATE_SUB(%s, INTERVAL ? SECOND)%s WHERE %s IS NOT NULL",
+			table, col, col, keep, col), offset)
+	case dialect.Postgres:
+		if columnType != "timestamp" || offset == 0 {
+			return 0, nil
+		}
+		result = tx.Exec(fmt.Sprintf("UPDATE %s SET %s = %s - (? * INTERVAL '1 second') WHERE %s IS NOT NULL",
+			table, col, col, col), offset)
+	default:
+		return 0, nil
+	}
+	return result.RowsAffected, result.Error
+}
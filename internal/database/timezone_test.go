@@ -0,0 +1,166 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"monitor/internal/database/dialect"
+
+	"gorm.io/gorm/logger"
+)
+
+// testDSNs are the databases the tests run against besides SQLite, which
+// always runs; CI sets the variables to cover MySQL and PostgreSQL
+var testDSNs = []struct{ driver, env string }{
+	{dialect.MySQL, "MONITOR_TEST_MYSQL_DSN"},
+	{dialect.Postgres, "MONITOR_TEST_POSTGRES_DSN"},
+}
+
+// forEachDriver runs test in a subtest per database, with DB set to a freshly
+// migrated, empty schema
+func forEachDriver(t *testing.T, test func(t *testing.T)) {
+	t.Helper()
+	t.Run(dialect.SQLite, func(t *testing.T) {
+		if err := InitMemoryDB(t.Name()); err != nil {
+			t.Fatalf("InitMemoryDB: %v", err)
+		}
+		closeDB(t, false)
+		test(t)
+	})
+	for _, d := range testDSNs {
+		dsn := os.Getenv(d.env)
+		t.Run(d.driver, func(t *testing.T) {
+			if dsn == "" {
+				t.Skipf("%s not set", d.env)
+			}
+			if err := InitDB(Config{Driver: d.driver, DSN: dsn}); err != nil {
+				t.Fatalf("InitDB: %v", err)
+			}
+			DB.Logger = logger.Default.LogMode(logger.Silent)
+			// A shared server keeps the tables between runs
+			dropTables(t)
+			if err := DB.AutoMigrate(append(appModels, &schemaMigration{})...); err != nil {
+				t.Fatalf("migrate: %v", err)
+			}
+			closeDB(t, true)
+			test(t)
+		})
+	}
+}
+
+// closeDB closes DB when the test ends, dropping its tables first if drop
+func closeDB(t *testing.T, drop bool) {
+	db := DB
+	t.Cleanup(func() {
+		if drop {
+			DB = db
+			dropTables(t)
+		}
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+func dropTables(t *testing.T) {
+	t.Helper()
+	if err := DB.Migrator().DropTable(append(appModels, &schemaMigration{})...); err != nil {
+		t.Fatalf("drop tables: %v", err)
+	}
+}
+
+// Rows an older version wrote with a local offset are rewritten as UTC once;
+// CURRENT_TIMESTAMP defaults and UTC rows are left alone. SQLite text keeps
+// each row's own offset, MySQL DATETIME the local wall clock, and PostgreSQL
+// timestamptz an absolute instant that needs no conversion.
+func TestMigrateTimestampsToUTC(t *testing.T) {
+	forEachDriver(t, func(t *testing.T) {
+		if err := DB.Exec("DELETE FROM schema_migrations").Error; err != nil {
+			t.Fatalf("reset migrations: %v", err)
+		}
+		insert := func(ip, createdAt string) {
+			t.Helper()
+			if err := DB.Exec("INSERT INTO ip_geo_cache (ip, created_at, updated_at) VALUES (?, '"+createdAt+"', '"+createdAt+"')", ip).Error; err != nil {
+				t.Fatalf("insert %s: %v", createdAt, err)
+			}
+		}
+		// Each row is 01:00 UTC once converted
+		rows := map[string][]string{
+			dialect.SQLite:   {"2026-03-01 09:00:00.5+08:00", "2026-03-01 01:00:00+00:00", "2026-03-01 01:00:00"},
+			dialect.MySQL:    {"2026-03-01 09:00:00.5"},
+			dialect.Postgres: {"2026-03-01 09:00:00.5+08:00", "2026-03-01 01:00:00+00:00"},
+		}[dialect.Of(DB)]
+		for i, createdAt := range rows {
+			insert(fmt.Sprintf("10.0.0.%d", i+1), createdAt)
+		}
+
+		local := time.FixedZone("CST", 8*3600)
+		if err := migrateTimestampsToUTC(local); err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+		if dialect.Of(DB) == dialect.SQLite {
+			want := []string{"2026-03-01 01:00:00.500+00:00", "2026-03-01 01:00:00+00:00", "2026-03-01 01:00:00"}
+			if got := geoCacheText(t); len(got) != 3 || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+				t.Errorf("times after migration %q, want %q", got, want)
+			}
+		}
+		got := geoCacheTimes(t)
+		for i, at := range got[:len(rows)] {
+			want := time.Date(2026, 3, 1, 1, 0, 0, 0, time.UTC)
+			if i == 0 {
+				want = want.Add(500 * time.Millisecond)
+			}
+			if !at.Equal(want) {
+				t.Errorf("row %d after migration %v, want %v", i+1, at, want)
+			}
+		}
+
+		var applied []schemaMigration
+		DB.Find(&applied)
+		if len(applied) != 1 || applied[0].Name != utcTimestampsMigration {
+			t.Fatalf("schema_migrations %+v", applied)
+		}
+
+		// A second start does not convert again
+		later := map[string]string{
+			dialect.SQLite:   "2026-03-01 09:00:00+08:00",
+			dialect.MySQL:    "2026-03-01 09:00:00",
+			dialect.Postgres: "2026-03-01 09:00:00+00:00",
+		}[dialect.Of(DB)]
+		insert("9.9.9.9", later)
+		before := geoCacheTimes(t)
+		if err := migrateTimestampsToUTC(local); err != nil {
+			t.Fatalf("second migrate: %v", err)
+		}
+		if got := geoCacheTimes(t); !got[len(rows)].Equal(before[len(rows)]) {
+			t.Errorf("row written after the migration became %v, was %v", got[len(rows)], before[len(rows)])
+		}
+		// SQLite would keep the instant, but not the text
+		if dialect.Of(DB) == dialect.SQLite {
+			if got := geoCacheText(t); got[len(rows)] != later {
+				t.Errorf("row written after the migration became %q", got[len(rows)])
+			}
+		}
+	})
+}
+
+// geoCacheText returns created_at of ip_geo_cache as SQLite stores it
+func geoCacheText(t *testing.T) []string {
+	t.Helper()
+	var times []string
+	if err := DB.Raw("SELECT CAST(created_at AS TEXT) FROM ip_geo_cache ORDER BY id").Scan(&times).Error; err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	return times
+}
+
+func geoCacheTimes(t *testing.T) []time.Time {
+	t.Helper()
+	var times []time.Time
+	if err := DB.Raw("SELECT created_at FROM ip_geo_cache ORDER BY id").Scan(&times).Error; err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	return times
+}
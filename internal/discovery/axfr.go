@@ -0,0 +1,142 @@
+package discovery
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// transferTimeout bounds a whole zone transfer
+const transferTimeout = 30 * time.Second
+
+// host is a host to probe: name is empty for hosts of a CIDR scan
+type host struct {
+	name string
+	ips  []net.IP
+}
+
+// transferZone fetches the A and AAAA records of zone from server with AXFR
+// over TCP; zone has no trailing dot. The transfer is refused once it names
+// more than maxHosts hosts.
+func transferZone(ctx context.Context, server, zone string, maxHosts int) ([]host, error) {
+	fqdn, err := dnsmessage.NewName(zone + ".")
+	if err != nil {
+		return nil, fmt.Errorf("invalid zone %q: %w", zone, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, transferTimeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	var id [2]byte
+	rand.Read(id[:])
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: binary.BigEndian.Uint16(id[:])},
+		Questions: []dnsmessage.Question{{Name: fqdn, Type: dnsmessage.TypeAXFR, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.AppendPack(make([]byte, 2, 514))
+	if err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint16(packed, uint16(len(packed)-2))
+	if _, err := conn.Write(packed); err != nil {
+		return nil, err
+	}
+
+	// The transfer is a series of messages that starts and ends with the SOA record
+	hosts := make(map[string][]net.IP)
+	suffix := "." + strings.ToLower(fqdn.String())
+	soas := 0
+	buf := make([]byte, 65535)
+	for soas < 2 {
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return nil, fmt.Errorf("zone transfer ended early: %w", err)
+		}
+		msg := buf[:binary.BigEndian.Uint16(length[:])]
+		if _, err := io.ReadFull(conn, msg); err != nil {
+			return nil, fmt.Errorf("zone transfer ended early: %w", err)
+		}
+
+		var p dnsmessage.Parser
+		header, err := p.Start(msg)
+		if err != nil {
+			return nil, err
+		}
+		if header.ID != query.Header.ID {
+			return nil, fmt.Errorf("zone transfer answer has the wrong ID")
+		}
+		if header.RCode != dnsmessage.RCodeSuccess {
+			return nil, fmt.Errorf("zone transfer refused: %s", strings.TrimPrefix(header.RCode.String(), "RCode"))
+		}
+		if err := p.SkipAllQuestions(); err != nil {
+			return nil, err
+		}
+		for soas < 2 {
+			rh, err := p.AnswerHeader()
+			if err == dnsmessage.ErrSectionDone {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			name := strings.ToLower(rh.Name.String())
+			var ip net.IP
+			switch rh.Type {
+			case dnsmessage.TypeSOA:
+				soas++
+			case dnsmessage.TypeA:
+				r, err := p.AResource()
+				if err != nil {
+					return nil, err
+				}
+				ip = net.IP(r.A[:])
+			case dnsmessage.TypeAAAA:
+				r, err := p.AAAAResource()
+				if err != nil {
+					return nil, err
+				}
+				ip = net.IP(r.AAAA[:])
+			}
+			if ip == nil {
+				if err := p.SkipAnswer(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			// Wildcards and names outside the zone are not hosts of it
+			if strings.HasPrefix(name, "*.") || (name != suffix[1:] && !strings.HasSuffix(name, suffix)) {
+				continue
+			}
+			if _, ok := hosts[name]; !ok && len(hosts) == maxHosts {
+				return nil, &ScanError{fmt.Sprintf("zone has more than %d hosts (max_hosts)", maxHosts)}
+			}
+			hosts[name] = append(hosts[name], ip)
+		}
+		if soas == 0 {
+			return nil, fmt.Errorf("zone transfer did not start with the SOA record")
+		}
+	}
+
+	result := make([]host, 0, len(hosts))
+	for name, ips := range hosts {
+		result = append(result, host{name: strings.TrimSuffix(name, "."), ips: ips})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+	return result, nil
+}
@@ -0,0 +1,135 @@
+package discovery
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// zoneRecord is a record a fake AXFR server sends: an A or AAAA record, or
+// the SOA record when ip is empty
+type zoneRecord struct {
+	name string
+	ip   string
+}
+
+// startAXFRServer serves one zone transfer per connection, one message per
+// entry of messages
+func startAXFRServer(t *testing.T, rcode dnsmessage.RCode, messages ...[]zoneRecord) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveAXFR(conn, rcode, messages)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func serveAXFR(conn net.Conn, rcode dnsmessage.RCode, messages [][]zoneRecord) {
+	defer conn.Close()
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return
+	}
+	var query dnsmessage.Message
+	if err := query.Unpack(buf); err != nil || len(query.Questions) != 1 || query.Questions[0].Type != dnsmessage.TypeAXFR {
+		return
+	}
+	zone := query.Questions[0].Name
+
+	for _, records := range messages {
+		msg := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true, RCode: rcode},
+			Questions: query.Questions,
+		}
+		for _, r := range records {
+			header := dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(r.name), Class: dnsmessage.ClassINET, TTL: 300}
+			var body dnsmessage.ResourceBody = &dnsmessage.SOAResource{NS: zone, MBox: zone, Serial: 1, Refresh: 3600, Retry: 600, Expire: 86400, MinTTL: 60}
+			if r.ip != "" {
+				if addr := netip.MustParseAddr(r.ip); addr.Is4() {
+					body = &dnsmessage.AResource{A: addr.As4()}
+				} else {
+					body = &dnsmessage.AAAAResource{AAAA: addr.As16()}
+				}
+			}
+			msg.Answers = append(msg.Answers, dnsmessage.Resource{Header: header, Body: body})
+		}
+		packed, err := msg.AppendPack(make([]byte, 2))
+		if err != nil {
+			return
+		}
+		binary.BigEndian.PutUint16(packed, uint16(len(packed)-2))
+		conn.Write(packed)
+	}
+}
+
+func TestTransferZone(t *testing.T) {
+	server := startAXFRServer(t, dnsmessage.RCodeSuccess,
+		[]zoneRecord{
+			{"corp.example.com.", ""},
+			{"corp.example.com.", "10.0.0.1"},
+			{"WWW.corp.example.com.", "10.0.0.2"},
+			{"*.corp.example.com.", "10.0.0.9"},
+			{"mail.other.example.", "10.0.0.8"},
+		},
+		[]zoneRecord{
+			{"www.corp.example.com.", "fd00::2"},
+			{"corp.example.com.", ""},
+		},
+	)
+
+	hosts, err := transferZone(context.Background(), server, "corp.example.com", 10)
+	if err != nil {
+		t.Fatalf("transferZone: %v", err)
+	}
+	// Sorted by name, wildcards and names outside the zone left out
+	if len(hosts) != 2 || hosts[0].name != "corp.example.com" || hosts[1].name != "www.corp.example.com" {
+		t.Fatalf("hosts %+v", hosts)
+	}
+	if len(hosts[1].ips) != 2 || !hosts[1].ips[0].Equal(net.ParseIP("10.0.0.2")) || !hosts[1].ips[1].Equal(net.ParseIP("fd00::2")) {
+		t.Errorf("www addresses %v", hosts[1].ips)
+	}
+
+	var scanErr *ScanError
+	if _, err := transferZone(context.Background(), server, "corp.example.com", 1); !errors.As(err, &scanErr) {
+		t.Errorf("transfer over max_hosts: err = %v", err)
+	}
+}
+
+func TestTransferZoneFailures(t *testing.T) {
+	refused := startAXFRServer(t, dnsmessage.RCodeRefused, []zoneRecord{})
+	if _, err := transferZone(context.Background(), refused, "corp.example.com", 10); err == nil || err.Error() != "zone transfer refused: Refused" {
+		t.Errorf("refused transfer: err = %v", err)
+	}
+
+	noSOA := startAXFRServer(t, dnsmessage.RCodeSuccess, []zoneRecord{{"www.corp.example.com.", "10.0.0.2"}})
+	if _, err := transferZone(context.Background(), noSOA, "corp.example.com", 10); err == nil {
+		t.Error("transfer without a SOA record accepted")
+	}
+
+	// The server stops before the closing SOA record
+	cut := startAXFRServer(t, dnsmessage.RCodeSuccess, []zoneRecord{{"corp.example.com.", ""}, {"www.corp.example.com.", "10.0.0.2"}})
+	if _, err := transferZone(context.Background(), cut, "corp.example.com", 10); err == nil {
+		t.Error("transfer cut short accepted")
+	}
+}
@@ -0,0 +1,538 @@
+// Package discovery finds monitor candidates with a DNS zone transfer or a
+// port scan of a network. Candidates wait in a review queue and only become
+// monitors once an operator approves them; rejected ones are remembered so
+// later scans do not list them again.
+package discovery
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"monitor/internal/config"
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+	"monitor/internal/monitor"
+
+	"go.uber.org/zap"
+)
+
+// Scan states
+const (
+	ScanRunning  = "running"
+	ScanFinished = "finished"
+	ScanFailed   = "failed"
+)
+
+// Candidate statuses
+const (
+	CandidatePending  = "pending"
+	CandidateApproved = "approved"
+	CandidateRejected = "rejected"
+)
+
+// maxScans 保留的扫描记录数，超出后丢弃最早结束的
+const maxScans = 50
+
+// MaxScanPorts bounds the ports probed on each host
+const MaxScanPorts = 32
+
+// scanTimeout bounds a whole scan
+const scanTimeout = 30 * time.Minute
+
+// ErrScanRunning is returned by Start while another scan runs
+var ErrScanRunning = errors.New("a discovery scan is already running")
+
+// ScanError is a scan request the configuration does not allow
+type ScanError struct {
+	Message string
+}
+
+func (e *ScanError) Error() string {
+	return e.Message
+}
+
+// ScanRequest 区域传送（zone，可选 server）或网段扫描（cidr），二选一
+type ScanRequest struct {
+	Zone   string `json:"zone"`
+	Server string `json:"server"` // AXFR 服务器，默认 allowed_servers 的第一个
+	CIDR   string `json:"cidr"`
+	Ports  []int  `json:"ports"` // 默认 discovery.ports
+}
+
+// Scan 扫描的进度和结果，保存在内存中，重启后丢失
+type Scan struct {
+	ID          string      `json:"id"`
+	State       string      `json:"state"`
+	Request     ScanRequest `json:"request"` // 补齐了默认值
+	RequestedBy string      `json:"requested_by"`
+	StartedAt   time.Time   `json:"started_at"`
+	FinishedAt  *time.Time  `json:"finished_at,omitempty"`
+	Hosts       int         `json:"hosts"`
+	Refused     int         `json:"refused"`    // 网络策略不允许探测的主机
+	OpenPorts   int         `json:"open_ports"` // 探测到的开放端口
+	Candidates  int         `json:"candidates"` // 新加入审核队列的候选
+	Known       int         `json:"known"`      // 已是候选（包括拒绝的）或已有监控的端口
+	Error       string      `json:"error,omitempty"`
+}
+
+// Service runs one scan at a time with at most max_concurrent connections
+type Service struct {
+	zones    map[string]bool
+	servers  []string
+	networks []*net.IPNet
+	ports    []int
+	maxHosts int
+	timeout  time.Duration
+	policy   *monitor.NetworkPolicy
+	slots    chan struct{}
+
+	mu      sync.Mutex
+	scans   map[string]*Scan
+	running string
+}
+
+// New creates the service. Only the allowed networks pass the network policy
+// among the loopback, private and link-local addresses.
+func New(cfg config.DiscoveryConfig) (*Service, error) {
+	policy, err := monitor.NewNetworkPolicy(false, cfg.AllowedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	s := &Service{
+		zones:    make(map[string]bool, len(cfg.AllowedZones)),
+		servers:  cfg.AllowedServers,
+		ports:    cfg.Ports,
+		maxHosts: cfg.MaxHosts,
+		timeout:  time.Duration(cfg.Timeout) * time.Second,
+		policy:   policy,
+		slots:    make(chan struct{}, cfg.MaxConcurrent),
+		scans:    make(map[string]*Scan),
+	}
+	for _, zone := range cfg.AllowedZones {
+		s.zones[normalizeZone(zone)] = true
+	}
+	for _, cidr := range cfg.AllowedCIDRs {
+		network, err := parseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		s.networks = append(s.networks, network)
+	}
+	return s, nil
+}
+
+func normalizeZone(zone string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(zone), "."))
+}
+
+// parseCIDR parses a network; a bare address is a single host
+func parseCIDR(cidr string) (*net.IPNet, error) {
+	cidr = strings.TrimSpace(cidr)
+	if ip := net.ParseIP(cidr); ip != nil {
+		bits := 128
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q", cidr)
+	}
+	return network, nil
+}
+
+// Start checks the request against the configuration and starts the scan in
+// the background. While another scan runs it returns that scan and
+// ErrScanRunning.
+func (s *Service) Start(req ScanRequest, requestedBy string) (Scan, error) {
+	if err := s.prepare(&req); err != nil {
+		return Scan{}, err
+	}
+
+	s.mu.Lock()
+	if s.running != "" {
+		defer s.mu.Unlock()
+		return *s.scans[s.running], ErrScanRunning
+	}
+	s.evict()
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	scan := &Scan{
+		ID:          hex.EncodeToString(buf),
+		State:       ScanRunning,
+		Request:     req,
+		RequestedBy: requestedBy,
+		StartedAt:   time.Now(),
+	}
+	s.scans[scan.ID] = scan
+	s.running = scan.ID
+	s.mu.Unlock()
+
+	logger.Info("Discovery scan started",
+		zap.String("scan_id", scan.ID),
+		zap.String("zone", req.Zone),
+		zap.String("server", req.Server),
+		zap.String("cidr", req.CIDR),
+		zap.Ints("ports", req.Ports),
+		zap.String("client_ip", requestedBy))
+	go s.run(*scan)
+	return *scan, nil
+}
+
+// prepare fills in the defaults and refuses what the configuration does not list
+func (s *Service) prepare(req *ScanRequest) error {
+	req.Zone = normalizeZone(req.Zone)
+	req.CIDR = strings.TrimSpace(req.CIDR)
+	req.Server = strings.TrimSpace(req.Server)
+	switch {
+	case (req.Zone == "") == (req.CIDR == ""):
+		return &ScanError{"set either zone or cidr"}
+	case req.Zone != "":
+		if !s.zones[req.Zone] {
+			return &ScanError{fmt.Sprintf("zone %q is not in discovery.allowed_zones", req.Zone)}
+		}
+		if req.Server == "" {
+			req.Server = s.servers[0]
+		}
+		allowed := false
+		for _, server := range s.servers {
+			allowed = allowed || server == req.Server
+		}
+		if !allowed {
+			return &ScanError{fmt.Sprintf("server %q is not in discovery.allowed_servers", req.Server)}
+		}
+	default:
+		network, err := parseCIDR(req.CIDR)
+		if err != nil {
+			return &ScanError{err.Error()}
+		}
+		if !s.allowedNetwork(network) {
+			return &ScanError{fmt.Sprintf("%s is not within discovery.allowed_cidrs", network)}
+		}
+		if n := hostCount(network); n < 0 || n > s.maxHosts {
+			return &ScanError{fmt.Sprintf("%s has more than %d hosts (max_hosts)", network, s.maxHosts)}
+		}
+		req.CIDR = network.String()
+		req.Server = ""
+	}
+
+	if len(req.Ports) == 0 {
+		req.Ports = s.ports
+	}
+	seen := make(map[int]bool, len(req.Ports))
+	var ports []int
+	for _, port := range req.Ports {
+		if port < 1 || port > 65535 {
+			return &ScanError{fmt.Sprintf("invalid port %d", port)}
+		}
+		if !seen[port] {
+			seen[port] = true
+			ports = append(ports, port)
+		}
+	}
+	if len(ports) > MaxScanPorts {
+		return &ScanError{fmt.Sprintf("too many ports: %d (max %d)", len(ports), MaxScanPorts)}
+	}
+	req.Ports = ports
+	return nil
+}
+
+// allowedNetwork reports whether network lies within one of the allowed networks
+func (s *Service) allowedNetwork(network *net.IPNet) bool {
+	ones, _ := network.Mask.Size()
+	for _, allowed := range s.networks {
+		allowedOnes, _ := allowed.Mask.Size()
+		if allowed.Contains(network.IP) && allowedOnes <= ones && len(allowed.IP) == len(network.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// Scan returns a scan by ID
+func (s *Service) Scan(id string) (Scan, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	scan, ok := s.scans[id]
+	if !ok {
+		return Scan{}, false
+	}
+	return *scan, true
+}
+
+// Scans returns the kept scans, newest first
+func (s *Service) Scans() []Scan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	scans := make([]Scan, 0, len(s.scans))
+	for _, scan := range s.scans {
+		scans = append(scans, *scan)
+	}
+	sort.Slice(scans, func(i, j int) bool { return scans[i].StartedAt.After(scans[j].StartedAt) })
+	return scans
+}
+
+func (s *Service) update(id string, fn func(scan *Scan)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if scan, ok := s.scans[id]; ok {
+		fn(scan)
+	}
+}
+
+// evict 超出 maxScans 时丢弃最早结束的扫描，调用方持有锁
+func (s *Service) evict() {
+	for len(s.scans) >= maxScans {
+		var oldest *Scan
+		for _, scan := range s.scans {
+			if scan.FinishedAt != nil && (oldest == nil || scan.FinishedAt.Before(*oldest.FinishedAt)) {
+				oldest = scan
+			}
+		}
+		if oldest == nil {
+			return
+		}
+		delete(s.scans, oldest.ID)
+	}
+}
+
+// run performs a scan and records how it ended
+func (s *Service) run(scan Scan) {
+	ctx, cancel := context.WithTimeout(context.Background(), scanTimeout)
+	defer cancel()
+
+	err := s.scan(ctx, scan)
+
+	s.mu.Lock()
+	finished := time.Now()
+	result := s.scans[scan.ID]
+	result.FinishedAt = &finished
+	result.State = ScanFinished
+	if err != nil {
+		result.State = ScanFailed
+		result.Error = err.Error()
+	}
+	s.running = ""
+	scan = *result
+	s.mu.Unlock()
+
+	logger.Info("Discovery scan finished",
+		zap.String("scan_id", scan.ID),
+		zap.String("state", scan.State),
+		zap.Int("hosts", scan.Hosts),
+		zap.Int("refused", scan.Refused),
+		zap.Int("open_ports", scan.OpenPorts),
+		zap.Int("candidates", scan.Candidates),
+		zap.Int("known", scan.Known),
+		zap.String("error", scan.Error),
+		zap.String("client_ip", scan.RequestedBy))
+}
+
+// openPort is a port that answered during a scan
+type openPort struct {
+	host    host
+	ip      net.IP
+	port    int
+	service service
+}
+
+func (s *Service) scan(ctx context.Context, scan Scan) error {
+	req := scan.Request
+	var hosts []host
+	if req.Zone != "" {
+		var err error
+		if hosts, err = transferZone(ctx, req.Server, req.Zone, s.maxHosts); err != nil {
+			return err
+		}
+	} else {
+		network, _ := parseCIDR(req.CIDR)
+		hosts = networkHosts(network)
+	}
+	s.update(scan.ID, func(scan *Scan) { scan.Hosts = len(hosts) })
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var found []openPort
+	refused := 0
+probe:
+	for _, h := range hosts {
+		ip := s.allowedIP(h.ips)
+		if ip == nil {
+			refused++
+			continue
+		}
+		for _, port := range req.Ports {
+			select {
+			case s.slots <- struct{}{}:
+			case <-ctx.Done():
+				break probe
+			}
+			wg.Add(1)
+			go func(h host, ip net.IP, port int) {
+				defer func() {
+					<-s.slots
+					wg.Done()
+				}()
+				if svc, ok := probePort(ctx, ip, port, s.timeout); ok {
+					mu.Lock()
+					found = append(found, openPort{host: h, ip: ip, port: port, service: svc})
+					mu.Unlock()
+				}
+			}(h, ip, port)
+		}
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("scan did not finish in %s", scanTimeout)
+	}
+	s.update(scan.ID, func(scan *Scan) {
+		scan.Refused = refused
+		scan.OpenPorts = len(found)
+	})
+
+	sort.Slice(found, func(i, j int) bool {
+		if !found[i].ip.Equal(found[j].ip) {
+			return string(found[i].ip.To16()) < string(found[j].ip.To16())
+		}
+		return found[i].port < found[j].port
+	})
+	source := req.Zone
+	if source == "" {
+		source = req.CIDR
+	}
+	names := make(map[string]string)
+	for _, open := range found {
+		added, err := s.addCandidate(ctx, scan.ID, source, open, names)
+		if err != nil {
+			return err
+		}
+		s.update(scan.ID, func(scan *Scan) {
+			if added {
+				scan.Candidates++
+			} else {
+				scan.Known++
+			}
+		})
+	}
+	return nil
+}
+
+// allowedIP picks the address to probe a host at, IPv4 first; nil if the
+// network policy refuses all of them
+func (s *Service) allowedIP(ips []net.IP) net.IP {
+	var allowed net.IP
+	for _, ip := range ips {
+		if !s.policy.Allowed(ip) {
+			continue
+		}
+		if ip.To4() != nil {
+			return ip
+		}
+		if allowed == nil {
+			allowed = ip
+		}
+	}
+	return allowed
+}
+
+// hostCount returns the number of hosts networkHosts lists, -1 if it is
+// too large to list
+func hostCount(network *net.IPNet) int {
+	ones, bits := network.Mask.Size()
+	if bits-ones > 30 {
+		return -1
+	}
+	if bits == 32 && bits-ones > 1 {
+		return 1<<(bits-ones) - 2
+	}
+	return 1 << (bits - ones)
+}
+
+// networkHosts lists the addresses of a network, without the network and
+// broadcast addresses of IPv4 networks larger than /31
+func networkHosts(network *net.IPNet) []host {
+	ones, bits := network.Mask.Size()
+	first := new(big.Int).SetBytes(network.IP.Mask(network.Mask))
+	start, end := 0, 1<<(bits-ones)
+	if bits == 32 && bits-ones > 1 {
+		start, end = 1, end-1
+	}
+	hosts := make([]host, 0, end-start)
+	for i := start; i < end; i++ {
+		n := new(big.Int).Add(first, big.NewInt(int64(i))).FillBytes(make([]byte, bits/8))
+		hosts = append(hosts, host{ips: []net.IP{net.IP(n)}})
+	}
+	return hosts
+}
+
+// addCandidate adds an open port to the review queue unless it is already
+// a candidate or a monitor. names caches the PTR names of addresses.
+func (s *Service) addCandidate(ctx context.Context, scanID, source string, open openPort, names map[string]string) (bool, error) {
+	// Hosts of a zone are monitored by name, hosts of a network by address
+	address := open.host.name
+	name := open.host.name
+	if address == "" {
+		address = open.ip.String()
+		if _, ok := names[address]; !ok {
+			names[address] = address
+			if ptr, err := net.DefaultResolver.LookupAddr(ctx, address); err == nil && len(ptr) > 0 {
+				names[address] = strings.TrimSuffix(ptr[0], ".")
+			}
+		}
+		name = names[address]
+	}
+	hostPort := net.JoinHostPort(address, strconv.Itoa(open.port))
+
+	candidate := models.DiscoveryCandidate{
+		ScanID:      scanID,
+		Fingerprint: strings.ToLower(hostPort),
+		Source:      source,
+		Name:        name + ":" + strconv.Itoa(open.port),
+		Type:        open.service.typ,
+		Address:     address,
+		Port:        open.port,
+		IP:          open.ip.String(),
+		Banner:      open.service.banner,
+		Status:      CandidatePending,
+	}
+	monitorPort := open.port
+	if candidate.Type == "http" || candidate.Type == "https" {
+		candidate.Address = candidate.Type + "://" + hostPort + "/"
+		if (candidate.Type == "http" && open.port == 80) || (candidate.Type == "https" && open.port == 443) {
+			candidate.Address = candidate.Type + "://" + address + "/"
+		}
+		monitorPort = 0
+	}
+
+	db := database.GetDB().WithContext(ctx)
+	var count int64
+	if err := db.Model(&models.DiscoveryCandidate{}).Where("fingerprint = ?", candidate.Fingerprint).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	addresses := []string{candidate.Address, strings.TrimSuffix(candidate.Address, "/")}
+	if err := db.Model(&models.MonitorTarget{}).Where("address IN ? AND port = ?", addresses, monitorPort).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	if err := db.Create(&candidate).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,190 @@
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"monitor/internal/config"
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+
+	"go.uber.org/zap"
+)
+
+func newTestService(t *testing.T, cfg config.DiscoveryConfig) *Service {
+	t.Helper()
+	if cfg.MaxHosts == 0 {
+		cfg.MaxHosts = 1024
+	}
+	cfg.MaxConcurrent, cfg.Timeout = 4, 1
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return s
+}
+
+func TestPrepare(t *testing.T) {
+	s := newTestService(t, config.DiscoveryConfig{
+		AllowedZones:   []string{"Corp.Example.com."},
+		AllowedServers: []string{"10.0.0.53:53", "10.0.0.54:53"},
+		AllowedCIDRs:   []string{"10.1.0.0/16", "fd00::/64"},
+		Ports:          []int{22, 80},
+		MaxHosts:       256,
+	})
+
+	req := ScanRequest{Zone: " corp.example.COM. "}
+	if err := s.prepare(&req); err != nil {
+		t.Fatalf("prepare zone: %v", err)
+	}
+	if req.Zone != "corp.example.com" || req.Server != "10.0.0.53:53" || !slices.Equal(req.Ports, []int{22, 80}) {
+		t.Errorf("prepared zone request %+v, want the default server and ports", req)
+	}
+
+	req = ScanRequest{CIDR: "10.1.2.3", Ports: []int{443, 443, 22}}
+	if err := s.prepare(&req); err != nil {
+		t.Fatalf("prepare address: %v", err)
+	}
+	if req.CIDR != "10.1.2.3/32" || !slices.Equal(req.Ports, []int{443, 22}) {
+		t.Errorf("prepared address request %+v", req)
+	}
+
+	manyPorts := make([]int, MaxScanPorts+1)
+	for i := range manyPorts {
+		manyPorts[i] = i + 1
+	}
+	for name, req := range map[string]ScanRequest{
+		"neither":          {},
+		"both":             {Zone: "corp.example.com", CIDR: "10.1.0.0/24"},
+		"zone not allowed": {Zone: "example.org"},
+		"server":           {Zone: "corp.example.com", Server: "8.8.8.8:53"},
+		"outside":          {CIDR: "10.2.0.0/24"},
+		"wider":            {CIDR: "10.0.0.0/8"},
+		"family":           {CIDR: "::ffff:10.1.0.0/120"},
+		"too many hosts":   {CIDR: "10.1.0.0/23"},
+		"invalid":          {CIDR: "10.1.0.0/33"},
+		"port":             {CIDR: "10.1.0.0/24", Ports: []int{0}},
+		"too many ports":   {CIDR: "10.1.0.0/24", Ports: manyPorts},
+	} {
+		var scanErr *ScanError
+		if err := s.prepare(&req); !errors.As(err, &scanErr) {
+			t.Errorf("%s: err = %v, want a ScanError", name, err)
+		}
+	}
+}
+
+func TestNetworkHosts(t *testing.T) {
+	for _, tc := range []struct {
+		cidr        string
+		count       int
+		first, last string
+	}{
+		{"10.0.0.0/30", 2, "10.0.0.1", "10.0.0.2"},
+		{"10.0.0.0/31", 2, "10.0.0.0", "10.0.0.1"},
+		{"10.0.0.7/32", 1, "10.0.0.7", "10.0.0.7"},
+		{"fd00::/126", 4, "fd00::", "fd00::3"},
+	} {
+		network, err := parseCIDR(tc.cidr)
+		if err != nil {
+			t.Fatalf("parseCIDR(%s): %v", tc.cidr, err)
+		}
+		hosts := networkHosts(network)
+		if hostCount(network) != tc.count || len(hosts) != tc.count ||
+			!hosts[0].ips[0].Equal(net.ParseIP(tc.first)) || !hosts[len(hosts)-1].ips[0].Equal(net.ParseIP(tc.last)) {
+			t.Errorf("%s: count %d, hosts %v, want %d from %s to %s", tc.cidr, hostCount(network), hosts, tc.count, tc.first, tc.last)
+		}
+	}
+	network, _ := parseCIDR("fd00::/64")
+	if hostCount(network) != -1 {
+		t.Error("a /64 is not too large to list")
+	}
+}
+
+func waitScan(t *testing.T, s *Service, id string) Scan {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		scan, ok := s.Scan(id)
+		if !ok {
+			t.Fatalf("scan %s not found", id)
+		}
+		if scan.State != ScanRunning {
+			return scan
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("scan still running: %+v", scan)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// A scan queues each open port once; ports already queued or monitored are
+// counted as known by later scans
+func TestScanQueuesCandidates(t *testing.T) {
+	logger.Log = zap.NewNop()
+	if err := database.InitMemoryDB(t.Name()); err != nil {
+		t.Fatalf("InitMemoryDB: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := database.GetDB().DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	ssh := listenBanner(t, "SSH-2.0-OpenSSH_9.6\r\n")
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer web.Close()
+	webPort := web.Listener.Addr().(*net.TCPAddr).Port
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	closed := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	s := newTestService(t, config.DiscoveryConfig{AllowedCIDRs: []string{"127.0.0.1/32"}, Ports: []int{ssh, webPort, closed}})
+	scan, err := s.Start(ScanRequest{CIDR: "127.0.0.1/32"}, "192.0.2.1")
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	// The HTTP probe waits for a banner first, so the scan is still running
+	if running, err := s.Start(ScanRequest{CIDR: "127.0.0.1"}, "192.0.2.1"); !errors.Is(err, ErrScanRunning) || running.ID != scan.ID {
+		t.Errorf("second scan: %+v, %v, want the running scan", running, err)
+	}
+	scan = waitScan(t, s, scan.ID)
+	if scan.State != ScanFinished || scan.Hosts != 1 || scan.OpenPorts != 2 || scan.Candidates != 2 || scan.Known != 0 || scan.RequestedBy != "192.0.2.1" {
+		t.Fatalf("scan %+v", scan)
+	}
+
+	var candidates []models.DiscoveryCandidate
+	database.GetDB().Order("port").Find(&candidates)
+	byPort := map[int]models.DiscoveryCandidate{}
+	for _, c := range candidates {
+		byPort[c.Port] = c
+	}
+	if c := byPort[ssh]; c.Type != "tcp" || c.Address != "127.0.0.1" || c.Banner != "SSH-2.0-OpenSSH_9.6" || c.Status != CandidatePending || c.Source != "127.0.0.1/32" {
+		t.Errorf("ssh candidate %+v", c)
+	}
+	if c := byPort[webPort]; c.Type != "http" || c.Address != fmt.Sprintf("http://127.0.0.1:%d/", webPort) {
+		t.Errorf("web candidate %+v", c)
+	}
+
+	// Rejected candidates stay known, and so do monitored ports
+	database.GetDB().Model(&models.DiscoveryCandidate{}).Where("port = ?", ssh).Update("status", CandidateRejected)
+	database.GetDB().Where("port = ?", webPort).Delete(&models.DiscoveryCandidate{})
+	database.GetDB().Create(&models.MonitorTarget{Name: "web", Type: "http", Address: fmt.Sprintf("http://127.0.0.1:%d", webPort), Interval: 60})
+	again, err := s.Start(ScanRequest{CIDR: "127.0.0.1/32"}, "192.0.2.1")
+	if err != nil {
+		t.Fatalf("second Start: %v", err)
+	}
+	if again = waitScan(t, s, again.ID); again.Candidates != 0 || again.Known != 2 {
+		t.Errorf("second scan %+v, want both ports known", again)
+	}
+	if scans := s.Scans(); len(scans) != 2 || scans[0].ID != again.ID {
+		t.Errorf("scans %+v, want the newest first", scans)
+	}
+}
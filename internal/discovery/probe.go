@@ -0,0 +1,99 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// bannerWait is how long a probe waits for the server to speak first
+const bannerWait = 500 * time.Millisecond
+
+// maxBannerLength bounds the banner kept with a candidate
+const maxBannerLength = 200
+
+// portTypes guesses the monitor type from the port when the banner does not tell
+var portTypes = map[int]string{
+	80:   "http",
+	8000: "http",
+	8080: "http",
+	443:  "https",
+	8443: "https",
+	25:   "smtp",
+	587:  "smtp",
+}
+
+// service is an open port and what answered on it
+type service struct {
+	typ    string
+	banner string
+}
+
+// probePort connects to ip:port and guesses the monitor type from what the
+// server sends first, or from its answer to an HTTP request if it waits for
+// the client. ok is false if the port is closed or filtered.
+func probePort(ctx context.Context, ip net.IP, port int, timeout time.Duration) (service, bool) {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+	if err != nil {
+		return service{}, false
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 512)
+	wait := bannerWait
+	if timeout < wait {
+		wait = timeout
+	}
+	conn.SetReadDeadline(time.Now().Add(wait))
+	n, _ := conn.Read(buf)
+	banner := firstLine(buf[:n])
+
+	switch {
+	case strings.HasPrefix(banner, "SSH-"):
+		return service{typ: "tcp", banner: banner}, true
+	case strings.HasPrefix(banner, "220") && strings.Contains(strings.ToUpper(banner), "SMTP"):
+		return service{typ: "smtp", banner: banner}, true
+	case banner != "":
+		return service{typ: guessByPort(port), banner: banner}, true
+	}
+
+	// A TLS port would answer a plain request with an error page, so it is not asked
+	if portTypes[port] == "https" {
+		return service{typ: "https"}, true
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := fmt.Fprintf(conn, "HEAD / HTTP/1.0\r\nHost: %s\r\n\r\n", ip); err == nil {
+		n, _ := conn.Read(buf)
+		if line := firstLine(buf[:n]); strings.HasPrefix(line, "HTTP/") {
+			return service{typ: "http", banner: line}, true
+		}
+	}
+	return service{typ: guessByPort(port)}, true
+}
+
+func guessByPort(port int) string {
+	if typ, ok := portTypes[port]; ok {
+		return typ
+	}
+	return "tcp"
+}
+
+// firstLine returns the first line of data with unprintable characters removed
+func firstLine(data []byte) string {
+	line, _, _ := strings.Cut(string(data), "\n")
+	line = strings.Map(func(r rune) rune {
+		if unicode.IsPrint(r) {
+			return r
+		}
+		return -1
+	}, line)
+	if len(line) > maxBannerLength {
+		line = strings.ToValidUTF8(line[:maxBannerLength], "")
+	}
+	return strings.TrimSpace(line)
+}
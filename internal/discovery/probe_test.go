@@ -0,0 +1,77 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenBanner accepts connections and writes banner to each, if any, then
+// keeps them open until the test ends
+func listenBanner(t *testing.T, banner string) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if banner != "" {
+				conn.Write([]byte(banner))
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestProbePort(t *testing.T) {
+	loopback := net.ParseIP("127.0.0.1")
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer web.Close()
+	webPort := web.Listener.Addr().(*net.TCPAddr).Port
+
+	for _, tc := range []struct {
+		name   string
+		port   int
+		typ    string
+		banner string
+	}{
+		{"ssh", listenBanner(t, "SSH-2.0-OpenSSH_9.6\r\n"), "tcp", "SSH-2.0-OpenSSH_9.6"},
+		{"smtp", listenBanner(t, "220 mail.example.com ESMTP Postfix\r\n"), "smtp", "220 mail.example.com ESMTP Postfix"},
+		{"other banner", listenBanner(t, "+OK \x00ready\r\n"), "tcp", "+OK ready"},
+		{"http", webPort, "http", "HTTP/1.0 200 OK"},
+		{"silent", listenBanner(t, ""), "tcp", ""},
+	} {
+		svc, ok := probePort(context.Background(), loopback, tc.port, time.Second)
+		if !ok || svc.typ != tc.typ || svc.banner != tc.banner {
+			t.Errorf("%s: %+v, %v, want %s with banner %q", tc.name, svc, ok, tc.typ, tc.banner)
+		}
+	}
+
+	// A port freed by closing its listener
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	closed := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	if svc, ok := probePort(context.Background(), loopback, closed, time.Second); ok {
+		t.Errorf("closed port reported open: %+v", svc)
+	}
+}
+
+func TestFirstLine(t *testing.T) {
+	if got := firstLine([]byte("  220 ready\x07\r\nsecond line")); got != "220 ready" {
+		t.Errorf("firstLine = %q", got)
+	}
+	if got := firstLine([]byte(strings.Repeat("é", maxBannerLength))); len(got) > maxBannerLength || !strings.HasPrefix(got, "é") || strings.ContainsRune(got, '�') {
+		t.Errorf("long banner cut to %d bytes: %q", len(got), got)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"time"
 
 	"monitor/internal/config"
@@ -22,7 +23,12 @@ type LogEntry struct {
 	Status       string                 `json:"status"` // up, down, unknown
 	ResponseTime int64                  `json:"response_time"` // milliseconds
 	Message      string                 `json:"message"`
-	Timestamp    time.Time              `json:"@timestamp"`
+	Synthetic    bool                   `json:"synthetic,omitempty"` // 故障注入产生的合成结果
+	ClockSkewMs  *int64                 `json:"clock_skew_ms,omitempty"` // 服务器 Date 头与本机时钟之差，仅 HTTP/HTTPS
+	Timings      map[string]interface{} `json:"timings,omitempty"`       // HTTP/HTTPS 各阶段耗时（毫秒）
+	Timestamp    time.Time              `json:"@timestamp"` // 检查完成时间
+	Nonce        string                 `json:"-"`          // 检查的随机标识，参与生成文档 ID
+	Seq          int64                  `json:"seq,omitempty"` // 与文件日志相同的序号，时间相同时排序
 
 	// 请求信息
 	Request struct {
@@ -38,6 +44,9 @@ type LogEntry struct {
 		Headers       map[string]string `json:"headers,omitempty"`
 		Body          string            `json:"body,omitempty"`
 		ContentLength int64             `json:"content_length,omitempty"`
+		// 传输中的响应体字节数和解码后的字节数
+		BytesReceived    int64 `json:"bytes_received,omitempty"`
+		DecodedBodyBytes int64 `json:"decoded_body_bytes,omitempty"`
 	} `json:"response"`
 
 	// 错误信息
@@ -51,9 +60,11 @@ type LogEntry struct {
 }
 
 type Client struct {
-	es         *elasticsearch.Client
-	config     config.ElasticsearchConfig
-	indexName  string
+	es     *elasticsearch.Client
+	config config.ElasticsearchConfig
+
+	// 索引模板协调的结果，见 template.go
+	template templateState
 }
 
 func NewClient(cfg config.ElasticsearchConfig) (*Client, error) {
@@ -83,13 +94,9 @@ func NewClient(cfg config.ElasticsearchConfig) (*Client, error) {
 		return nil, fmt.Errorf("elasticsearch returned error: %s", res.String())
 	}
 
-	// 生成索引名称（按日期滚动）
-	indexName := fmt.Sprintf("%s-%s", cfg.IndexPrefix, time.Now().Format("2006.01.02"))
-
 	client := &Client{
-		es:        es,
-		config:    cfg,
-		indexName: indexName,
+		es:     es,
+		config: cfg,
 	}
 
 	logger.Log.Info("Elasticsearch client initialized successfully")
@@ -104,11 +111,13 @@ func (c *Client) IndexLog(entry *LogEntry) error {
 		return nil // ES 未启用，跳过
 	}
 
-	// 更新索引名称（支持按日期滚动）
-	c.indexName = fmt.Sprintf("%s-%s", c.config.IndexPrefix, time.Now().Format("2006.01.02"))
-
-	// 设置时间戳
-	entry.Timestamp = time.Now().UTC()
+	// 时间戳为检查完成时间，未设置时使用当前时间
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	entry.Timestamp = entry.Timestamp.UTC()
+	// 索引按检查完成的日期滚动，重新导入的文档与原文档落在同一个索引中
+	index := c.indexFor(entry.Timestamp)
 
 	// 序列化为 JSON
 	body, err := json.Marshal(entry)
@@ -116,10 +125,10 @@ func (c *Client) IndexLog(entry *LogEntry) error {
 		return fmt.Errorf("failed to marshal log entry: %w", err)
 	}
 
-	// 索引文档
+	// 索引文档；文档 ID 由检查本身决定，重复写入同一次检查的结果不会产生重复文档
 	req := esapi.IndexRequest{
-		Index:      c.indexName,
-		DocumentID: "", // 让 ES 自动生成 ID
+		Index:      index,
+		DocumentID: DocumentID(entry.TargetID, entry.Timestamp, entry.Nonce),
 		Body:       bytes.NewReader(body),
 		Refresh:    "true",
 	}
@@ -135,7 +144,7 @@ func (c *Client) IndexLog(entry *LogEntry) error {
 	}
 
 	logger.Log.Debug(fmt.Sprintf("Log indexed to ES: index=%s, target_id=%d, status=%s",
-		c.indexName, entry.TargetID, entry.Status))
+		index, entry.TargetID, entry.Status))
 
 	return nil
 }
@@ -149,14 +158,23 @@ type SearchQuery struct {
 	Size       int        `json:"size,omitempty"`
 	From       int        `json:"from,omitempty"`
 	QueryText  string     `json:"query_text,omitempty"`
+	Synthetic  *bool      `json:"synthetic,omitempty"` // nil: 全部; true/false: 仅合成/仅真实结果
+	After      *logger.LogCursor `json:"-"`          // 返回该位置之后的日志，代替 From
 }
 
 type SearchResult struct {
-	Total int64       `json:"total"`
-	Hits  []LogEntry  `json:"hits"`
+	Total     int64      `json:"total"`
+	Hits      []LogEntry `json:"hits"`
+	NextAfter string     `json:"next_after,omitempty"` // 可能还有更多日志时为最后一条的位置
+}
+
+// Cursor returns the position of the entry, in the same order as the file logs
+func (e *LogEntry) Cursor() logger.LogCursor {
+	return logger.LogCursor{Timestamp: e.Timestamp, TargetID: int(e.TargetID), Seq: e.Seq}
 }
 
-func (c *Client) SearchLogs(query *SearchQuery) (*SearchResult, error) {
+// SearchLogs 搜索日志，ctx 取消时中止请求
+func (c *Client) SearchLogs(ctx context.Context, query *SearchQuery) (*SearchResult, error) {
 	if c == nil || c.es == nil {
 		return &SearchResult{Total: 0, Hits: []LogEntry{}}, nil
 	}
@@ -216,6 +234,18 @@ func (c *Client) SearchLogs(query *SearchQuery) (*SearchResult, error) {
 
 	boolQuery["bool"].(map[string]interface{})["must"] = mustQueries
 
+	// 合成结果过滤（旧文档没有 synthetic 字段，按真实结果处理）
+	if query.Synthetic != nil {
+		syntheticTerm := map[string]interface{}{
+			"term": map[string]interface{}{"synthetic": true},
+		}
+		if *query.Synthetic {
+			boolQuery["bool"].(map[string]interface{})["filter"] = []map[string]interface{}{syntheticTerm}
+		} else {
+			boolQuery["bool"].(map[string]interface{})["must_not"] = []map[string]interface{}{syntheticTerm}
+		}
+	}
+
 	// 设置分页
 	if query.Size <= 0 {
 		query.Size = 20
@@ -224,14 +254,27 @@ func (c *Client) SearchLogs(query *SearchQuery) (*SearchResult, error) {
 		query.Size = 100 // 最大 100 条
 	}
 
+	// 与文件日志相同的顺序：时间、目标 ID、序号，保证同一时间的日志顺序固定。
+	// 旧文档没有 seq，排在同一时间、同一目标的最后
 	searchBody := map[string]interface{}{
 		"query": boolQuery,
 		"size":  query.Size,
-		"from":  query.From,
 		"sort": []map[string]interface{}{
 			{"@timestamp": map[string]interface{}{"order": "desc"}},
+			{"target_id": map[string]interface{}{"order": "desc"}},
+			{"seq": map[string]interface{}{"order": "desc", "missing": "_last", "unmapped_type": "long"}},
 		},
 	}
+	if query.After != nil {
+		// @timestamp 按毫秒排序；缺少 seq 的文档排序值为 long 的最小值
+		seq := query.After.Seq
+		if seq == 0 {
+			seq = math.MinInt64
+		}
+		searchBody["search_after"] = []interface{}{query.After.Timestamp.UnixMilli(), query.After.TargetID, seq}
+	} else {
+		searchBody["from"] = query.From
+	}
 
 	body, err := json.Marshal(searchBody)
 	if err != nil {
@@ -245,7 +288,7 @@ func (c *Client) SearchLogs(query *SearchQuery) (*SearchResult, error) {
 		Body:  bytes.NewReader(body),
 	}
 
-	res, err := req.Do(context.Background(), c.es)
+	res, err := req.Do(ctx, c.es)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search logs: %w", err)
 	}
@@ -280,6 +323,10 @@ func (c *Client) SearchLogs(query *SearchQuery) (*SearchResult, error) {
 	for _, hit := range response.Hits.Hits {
 		result.Hits = append(result.Hits, hit.Source)
 	}
+	// total 超过 10000 时不准确，所以只要返回了整页就给出下一页的位置
+	if n := len(result.Hits); n > 0 && n == query.Size {
+		result.NextAfter = result.Hits[n-1].Cursor().String()
+	}
 
 	logger.Log.Debug(fmt.Sprintf("Log search completed: total=%d, returned=%d",
 		result.Total, len(result.Hits)))
@@ -287,8 +334,57 @@ func (c *Client) SearchLogs(query *SearchQuery) (*SearchResult, error) {
 	return result, nil
 }
 
-// GetLogStats 获取日志统计信息
-func (c *Client) GetLogStats(targetID uint32, startTime, endTime time.Time) (map[string]interface{}, error) {
+// DeleteSyntheticLogs 删除故障注入产生的合成日志，返回删除的文档数
+func (c *Client) DeleteSyntheticLogs() (int64, error) {
+	if c == nil || c.es == nil {
+		return 0, nil
+	}
+
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{"synthetic": true},
+		},
+	}
+
+	body, err := json.Marshal(query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal delete query: %w", err)
+	}
+
+	refresh := true
+	req := esapi.DeleteByQueryRequest{
+		Index:     []string{fmt.Sprintf("%s-*", c.config.IndexPrefix)},
+		Body:      bytes.NewReader(body),
+		Refresh:   &refresh,
+		Conflicts: "proceed",
+	}
+
+	res, err := req.Do(context.Background(), c.es)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete synthetic logs: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return 0, fmt.Errorf("elasticsearch delete error: %s", res.String())
+	}
+
+	var response struct {
+		Deleted int64 `json:"deleted"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return 0, fmt.Errorf("failed to parse delete response: %w", err)
+	}
+
+	return response.Deleted, nil
+}
+
+// responseTimePercents 日志统计返回的响应时间百分位
+var responseTimePercents = []float64{50, 95, 99}
+
+// GetLogStats 获取日志统计信息。除原始的聚合结果外，response_times 中是 up、warning、
+// degraded 结果的样本数和响应时间百分位（ES 的 TDigest 近似值，没有样本时为 null）
+func (c *Client) GetLogStats(ctx context.Context, targetID uint32, startTime, endTime time.Time) (map[string]interface{}, error) {
 	if c == nil || c.es == nil {
 		return map[string]interface{}{}, nil
 	}
@@ -326,6 +422,22 @@ func (c *Client) GetLogStats(targetID uint32, startTime, endTime time.Time) (map
 					"field": "response_time",
 				},
 			},
+			"response_times": map[string]interface{}{
+				"filter": map[string]interface{}{
+					"terms": map[string]interface{}{
+						"status": []string{"up", "warning", "degraded"},
+					},
+				},
+				"aggs": map[string]interface{}{
+					"percentiles": map[string]interface{}{
+						"percentiles": map[string]interface{}{
+							"field":    "response_time",
+							"percents": responseTimePercents,
+							"keyed":    false,
+						},
+					},
+				},
+			},
 		},
 	}
 
@@ -340,7 +452,7 @@ func (c *Client) GetLogStats(targetID uint32, startTime, endTime time.Time) (map
 		Body:  bytes.NewReader(body),
 	}
 
-	res, err := req.Do(context.Background(), c.es)
+	res, err := req.Do(ctx, c.es)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get log stats: %w", err)
 	}
@@ -354,66 +466,31 @@ func (c *Client) GetLogStats(targetID uint32, startTime, endTime time.Time) (map
 	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
 		return nil, fmt.Errorf("failed to parse stats response: %w", err)
 	}
+	response["response_times"] = responsePercentiles(response)
 
 	return response, nil
 }
 
-// CreateIndexTemplate 创建索引模板（如果不存在）
-func (c *Client) CreateIndexTemplate() error {
-	if c == nil || c.es == nil {
-		return nil
-	}
-
-	templateName := fmt.Sprintf("%s-template", c.config.IndexPrefix)
-
-	template := map[string]interface{}{
-		"index_patterns": []string{fmt.Sprintf("%s-*", c.config.IndexPrefix)},
-		"template": map[string]interface{}{
-			"settings": map[string]interface{}{
-				"number_of_shards":   1,
-				"number_of_replicas": 1,
-				"refresh_interval":   "5s",
-			},
-			"mappings": map[string]interface{}{
-				"properties": map[string]interface{}{
-					"target_id":      map[string]string{"type": "integer"},
-					"target_name":    map[string]string{"type": "keyword"},
-					"target_type":    map[string]string{"type": "keyword"},
-					"address":        map[string]string{"type": "keyword"},
-					"status":         map[string]string{"type": "keyword"},
-					"response_time":  map[string]string{"type": "long"},
-					"message":        map[string]string{"type": "text"},
-					"@timestamp":     map[string]string{"type": "date"},
-					"request":        map[string]string{"type": "object"},
-					"response":       map[string]string{"type": "object"},
-					"error":          map[string]string{"type": "object"},
-					"metadata":       map[string]string{"type": "object"},
-				},
-			},
-		},
-	}
-
-	body, err := json.Marshal(template)
-	if err != nil {
-		return fmt.Errorf("failed to marshal index template: %w", err)
-	}
-
-	req := esapi.IndicesPutIndexTemplateRequest{
-		Name: templateName,
-		Body: bytes.NewReader(body),
-	}
-
-	res, err := req.Do(context.Background(), c.es)
-	if err != nil {
-		return fmt.Errorf("failed to create index template: %w", err)
-	}
-	defer res.Body.Close()
-
-	if res.IsError() && res.StatusCode != 404 { // 404 表示已存在
-		logger.Log.Warn(fmt.Sprintf("Failed to create index template: %s", res.String()))
-	} else {
-		logger.Log.Info(fmt.Sprintf("Index template created: %s", templateName))
+// responsePercentiles 把 response_times 聚合整理为 samples、p50、p95、p99
+func responsePercentiles(response map[string]interface{}) map[string]interface{} {
+	stats := map[string]interface{}{"samples": 0, "p50": nil, "p95": nil, "p99": nil}
+	aggs, _ := response["aggregations"].(map[string]interface{})
+	agg, _ := aggs["response_times"].(map[string]interface{})
+	if agg == nil {
+		return stats
+	}
+	if count, ok := agg["doc_count"].(float64); ok {
+		stats["samples"] = int64(count)
+	}
+	percentiles, _ := agg["percentiles"].(map[string]interface{})
+	values, _ := percentiles["values"].([]interface{})
+	for _, v := range values {
+		entry, _ := v.(map[string]interface{})
+		key, _ := entry["key"].(float64)
+		// value 在没有样本时为 null
+		if value, ok := entry["value"].(float64); ok {
+			stats[fmt.Sprintf("p%d", int(key))] = value
+		}
 	}
-
-	return nil
-}
\ No newline at end of file
+	return stats
+}
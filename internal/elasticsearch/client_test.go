@@ -0,0 +1,26 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestResponsePercentiles(t *testing.T) {
+	var response map[string]interface{}
+	err := json.Unmarshal([]byte(`{"aggregations": {"response_times": {"doc_count": 42, "percentiles": {"values": [
+		{"key": 50.0, "value": 12.5}, {"key": 95.0, "value": 80}, {"key": 99.0, "value": null}
+	]}}}}`), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := responsePercentiles(response)
+	if stats["samples"] != int64(42) || stats["p50"] != 12.5 || stats["p95"] != 80.0 || stats["p99"] != nil {
+		t.Errorf("stats %v", stats)
+	}
+
+	// Without the aggregation every percentile is null
+	stats = responsePercentiles(map[string]interface{}{})
+	if stats["samples"] != 0 || stats["p50"] != nil || len(stats) != 4 {
+		t.Errorf("stats without the aggregation %v", stats)
+	}
+}
@@ -0,0 +1,173 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"monitor/internal/logger"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// purgePageSize 清除时每次读取的文档数
+const purgePageSize = 1000
+
+// PurgeResult ES 清除的统计
+type PurgeResult struct {
+	Scanned int   `json:"scanned"` // 符合目标和时间条件、用正则检查过的文档数
+	Matched int   `json:"matched"`
+	Deleted int64 `json:"deleted"`
+}
+
+// PurgeLogs 删除匹配的日志。message 和 body 是分词字段，ES 的 regexp 查询无法按整段文本匹配，
+// 因此按目标和时间分页读取，用与文件日志相同的正则在本地检查，再按文档 ID 执行 delete_by_query。
+func (c *Client) PurgeLogs(ctx context.Context, filter *logger.PurgeFilter) (PurgeResult, error) {
+	var result PurgeResult
+	if c == nil || c.es == nil {
+		return result, nil
+	}
+
+	filters := []map[string]interface{}{}
+	if filter.TargetID != nil {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{"target_id": *filter.TargetID},
+		})
+	}
+	if filter.StartTime != nil || filter.EndTime != nil {
+		rangeQuery := map[string]interface{}{}
+		if filter.StartTime != nil {
+			rangeQuery["gte"] = filter.StartTime.Format(time.RFC3339Nano)
+		}
+		if filter.EndTime != nil {
+			rangeQuery["lte"] = filter.EndTime.Format(time.RFC3339Nano)
+		}
+		filters = append(filters, map[string]interface{}{
+			"range": map[string]interface{}{"@timestamp": rangeQuery},
+		})
+	}
+
+	var searchAfter []interface{}
+	for {
+		searchBody := map[string]interface{}{
+			"size":    purgePageSize,
+			"_source": []string{"target_name", "address", "message", "request", "response", "error", "metadata"},
+			"query": map[string]interface{}{
+				"bool": map[string]interface{}{"filter": filters},
+			},
+			// 同一目标在同一毫秒内不会有两次检查，两个字段足以稳定翻页
+			"sort": []map[string]interface{}{
+				{"@timestamp": map[string]interface{}{"order": "asc"}},
+				{"target_id": map[string]interface{}{"order": "asc"}},
+			},
+		}
+		if searchAfter != nil {
+			searchBody["search_after"] = searchAfter
+		}
+
+		hits, err := c.searchPurgePage(ctx, searchBody)
+		if err != nil {
+			return result, err
+		}
+		if len(hits) == 0 {
+			return result, nil
+		}
+
+		var ids []string
+		for _, hit := range hits {
+			result.Scanned++
+			if filter.MatchesText(hit.Source) {
+				ids = append(ids, hit.ID)
+			}
+		}
+		result.Matched += len(ids)
+		if len(ids) > 0 {
+			deleted, err := c.deleteByIDs(ctx, ids)
+			result.Deleted += deleted
+			if err != nil {
+				return result, err
+			}
+		}
+
+		if len(hits) < purgePageSize {
+			return result, nil
+		}
+		searchAfter = hits[len(hits)-1].Sort
+	}
+}
+
+type purgeHit struct {
+	ID     string                 `json:"_id"`
+	Source map[string]interface{} `json:"_source"`
+	Sort   []interface{}          `json:"sort"`
+}
+
+func (c *Client) searchPurgePage(ctx context.Context, searchBody map[string]interface{}) ([]purgeHit, error) {
+	body, err := json.Marshal(searchBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal purge query: %w", err)
+	}
+
+	req := esapi.SearchRequest{
+		Index: []string{fmt.Sprintf("%s-*", c.config.IndexPrefix)},
+		Body:  bytes.NewReader(body),
+	}
+	res, err := req.Do(ctx, c.es)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search logs to purge: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("elasticsearch search error: %s", res.String())
+	}
+
+	var response struct {
+		Hits struct {
+			Hits []purgeHit `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to parse search response: %w", err)
+	}
+	return response.Hits.Hits, nil
+}
+
+// deleteByIDs 删除所有索引中这些 ID 的文档
+func (c *Client) deleteByIDs(ctx context.Context, ids []string) (int64, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"query": map[string]interface{}{
+			"ids": map[string]interface{}{"values": ids},
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal delete query: %w", err)
+	}
+
+	refresh := true
+	req := esapi.DeleteByQueryRequest{
+		Index:     []string{fmt.Sprintf("%s-*", c.config.IndexPrefix)},
+		Body:      bytes.NewReader(body),
+		Refresh:   &refresh,
+		Conflicts: "proceed",
+	}
+	res, err := req.Do(ctx, c.es)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete logs: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return 0, fmt.Errorf("elasticsearch delete error: %s", res.String())
+	}
+
+	var response struct {
+		Deleted int64 `json:"deleted"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return 0, fmt.Errorf("failed to parse delete response: %w", err)
+	}
+	return response.Deleted, nil
+}
@@ -0,0 +1,221 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"monitor/internal/logger"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// MaxReindexDays 一次重建的最大天数
+const MaxReindexDays = 31
+
+// 索引重建的结果
+const (
+	ReindexDone    = "reindexed"
+	ReindexMissing = "missing" // 这一天没有索引
+	ReindexFailed  = "failed"
+)
+
+// ReindexResult 一个索引的重建结果
+type ReindexResult struct {
+	Index     string `json:"index"`
+	Status    string `json:"status"`
+	Documents int64  `json:"documents"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ReindexRecent 按当前模板重建今天之前 days 天的日志索引，最近的在前。
+// 每个索引先复制到不匹配模板的临时索引，删除后重新创建（使用当前模板）再复制回来，最后删除临时索引；
+// 重建期间这一天的日志查不到。今天的索引仍在写入，不重建，明天的索引直接使用新模板。
+// 某个索引失败时停止，已复制的数据留在临时索引中，再次执行会从临时索引继续。
+func (c *Client) ReindexRecent(ctx context.Context, days int, now time.Time) ([]ReindexResult, error) {
+	if c == nil || c.es == nil {
+		return nil, nil
+	}
+
+	results := make([]ReindexResult, 0, days)
+	for i := 1; i <= days; i++ {
+		day := now.AddDate(0, 0, -i)
+		result := ReindexResult{Index: c.indexFor(day)}
+		count, found, err := c.reindex(ctx, result.Index, c.reindexTempIndex(day))
+		switch {
+		case err != nil:
+			result.Status = ReindexFailed
+			result.Error = err.Error()
+		case !found:
+			result.Status = ReindexMissing
+		default:
+			result.Status = ReindexDone
+			result.Documents = count
+			logger.Log.Info(fmt.Sprintf("Index reindexed with template version %d: %s, documents=%d",
+				TemplateVersion, result.Index, count))
+		}
+		results = append(results, result)
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// reindexTempIndex 重建时的临时索引，名称不匹配 <prefix>-*，不使用模板也不会被日志查询读到
+func (c *Client) reindexTempIndex(day time.Time) string {
+	return fmt.Sprintf("%s_reindex_%s", c.config.IndexPrefix, day.Local().Format("2006.01.02"))
+}
+
+// reindex 经临时索引重建 index，返回文档数；index 和临时索引都不存在时 found 为 false
+func (c *Client) reindex(ctx context.Context, index, temp string) (int64, bool, error) {
+	exists, err := c.indexExists(ctx, index)
+	if err != nil {
+		return 0, false, err
+	}
+	tempExists, err := c.indexExists(ctx, temp)
+	if err != nil {
+		return 0, false, err
+	}
+	if !exists && !tempExists {
+		return 0, false, nil
+	}
+
+	// 上次在删除原索引后失败时，原索引已不存在，直接从临时索引复制回来
+	if exists {
+		if err := c.copyIndex(ctx, index, temp); err != nil {
+			return 0, true, err
+		}
+		if err := c.checkCopied(ctx, index, temp); err != nil {
+			return 0, true, err
+		}
+		if err := c.deleteIndex(ctx, index); err != nil {
+			return 0, true, err
+		}
+	}
+
+	if err := c.copyIndex(ctx, temp, index); err != nil {
+		return 0, true, fmt.Errorf("%w; the documents are kept in %s", err, temp)
+	}
+	if err := c.checkCopied(ctx, temp, index); err != nil {
+		return 0, true, fmt.Errorf("%w; the documents are kept in %s", err, temp)
+	}
+	count, err := c.countDocuments(ctx, index)
+	if err != nil {
+		return 0, true, err
+	}
+	if err := c.deleteIndex(ctx, temp); err != nil {
+		return count, true, fmt.Errorf("%s was reindexed but %w", index, err)
+	}
+	return count, true, nil
+}
+
+// copyIndex 把 source 的文档复制到 dest，dest 中已有的文档保持不变
+func (c *Client) copyIndex(ctx context.Context, source, dest string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"source":    map[string]interface{}{"index": source},
+		"dest":      map[string]interface{}{"index": dest, "op_type": "create"},
+		"conflicts": "proceed",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal reindex request: %w", err)
+	}
+
+	refresh, wait := true, true
+	req := esapi.ReindexRequest{
+		Body:              bytes.NewReader(body),
+		Refresh:           &refresh,
+		WaitForCompletion: &wait,
+	}
+	res, err := req.Do(ctx, c.es)
+	if err != nil {
+		return fmt.Errorf("failed to reindex %s into %s: %w", source, dest, err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("elasticsearch reindex error: %s", res.String())
+	}
+
+	var response struct {
+		Failures []json.RawMessage `json:"failures"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return fmt.Errorf("failed to parse reindex response: %w", err)
+	}
+	if len(response.Failures) > 0 {
+		return fmt.Errorf("reindex %s into %s: %d failures, first: %s", source, dest, len(response.Failures), response.Failures[0])
+	}
+	return nil
+}
+
+// checkCopied 确认 dest 中的文档不少于 source，之后才能删除 source
+func (c *Client) checkCopied(ctx context.Context, source, dest string) error {
+	want, err := c.countDocuments(ctx, source)
+	if err != nil {
+		return err
+	}
+	got, err := c.countDocuments(ctx, dest)
+	if err != nil {
+		return err
+	}
+	if got < want {
+		return fmt.Errorf("reindex %s into %s copied %d of %d documents", source, dest, got, want)
+	}
+	return nil
+}
+
+func (c *Client) countDocuments(ctx context.Context, index string) (int64, error) {
+	req := esapi.CountRequest{Index: []string{index}}
+	res, err := req.Do(ctx, c.es)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count documents in %s: %w", index, err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return 0, fmt.Errorf("elasticsearch count error: %s", res.String())
+	}
+
+	var response struct {
+		Count int64 `json:"count"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return 0, fmt.Errorf("failed to parse count response: %w", err)
+	}
+	return response.Count, nil
+}
+
+func (c *Client) indexExists(ctx context.Context, index string) (bool, error) {
+	req := esapi.IndicesExistsRequest{Index: []string{index}}
+	res, err := req.Do(ctx, c.es)
+	if err != nil {
+		return false, fmt.Errorf("failed to check index %s: %w", index, err)
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case 200:
+		return true, nil
+	case 404:
+		return false, nil
+	default:
+		return false, fmt.Errorf("elasticsearch index exists error: %s", res.String())
+	}
+}
+
+func (c *Client) deleteIndex(ctx context.Context, index string) error {
+	req := esapi.IndicesDeleteRequest{Index: []string{index}}
+	res, err := req.Do(ctx, c.es)
+	if err != nil {
+		return fmt.Errorf("failed to delete index %s: %w", index, err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("elasticsearch delete index error: %s", res.String())
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"monitor/internal/logger"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// maxBulkErrors 批量写入结果中保留的错误样例数
+const maxBulkErrors = 10
+
+// DocumentID 由检查的目标、完成时间和随机标识生成文档 ID，同一次检查无论写入几次都是同一个文档
+func DocumentID(targetID uint32, completedAt time.Time, nonce string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%s|%s", targetID, completedAt.UTC().Format(time.RFC3339Nano), nonce)))
+	return hex.EncodeToString(sum[:])
+}
+
+// indexFor 返回检查完成日期对应的索引
+func (c *Client) indexFor(t time.Time) string {
+	return fmt.Sprintf("%s-%s", c.config.IndexPrefix, t.Local().Format("2006.01.02"))
+}
+
+// BulkResult 批量写入的统计；已存在的文档计入 Conflicts，不算失败
+type BulkResult struct {
+	Indexed   int      `json:"indexed"`
+	Conflicts int      `json:"conflicts"`
+	Failed    int      `json:"failed"`
+	Retryable int      `json:"retryable,omitempty"` // Failed 中 ES 暂时拒绝的（429、5xx），稍后重试可能成功
+	Errors    []string `json:"errors,omitempty"`    // 最多 maxBulkErrors 条
+}
+
+// Add 累加另一批的统计
+func (r *BulkResult) Add(other BulkResult) {
+	r.Indexed += other.Indexed
+	r.Conflicts += other.Conflicts
+	r.Failed += other.Failed
+	r.Retryable += other.Retryable
+	for _, e := range other.Errors {
+		if len(r.Errors) < maxBulkErrors {
+			r.Errors = append(r.Errors, e)
+		}
+	}
+}
+
+// BulkCreate 用 create 操作批量写入日志，已存在的文档（同一次检查）不会被覆盖
+func (c *Client) BulkCreate(ctx context.Context, entries []*LogEntry) (BulkResult, error) {
+	var result BulkResult
+	if c == nil || c.es == nil || len(entries) == 0 {
+		return result, nil
+	}
+
+	var body bytes.Buffer
+	for _, entry := range entries {
+		entry.Timestamp = entry.Timestamp.UTC()
+		action := map[string]interface{}{
+			"create": map[string]string{
+				"_index": c.indexFor(entry.Timestamp),
+				"_id":    DocumentID(entry.TargetID, entry.Timestamp, entry.Nonce),
+			},
+		}
+		for _, v := range []interface{}{action, entry} {
+			line, err := json.Marshal(v)
+			if err != nil {
+				return result, fmt.Errorf("failed to marshal bulk line: %w", err)
+			}
+			body.Write(line)
+			body.WriteByte('\n')
+		}
+	}
+
+	req := esapi.BulkRequest{
+		Body:    &body,
+		Refresh: "true",
+	}
+	res, err := req.Do(ctx, c.es)
+	if err != nil {
+		return result, fmt.Errorf("failed to bulk index logs: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return result, fmt.Errorf("elasticsearch bulk error: %s", res.String())
+	}
+
+	var response struct {
+		Items []map[string]struct {
+			ID     string `json:"_id"`
+			Status int    `json:"status"`
+			Error  struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return result, fmt.Errorf("failed to parse bulk response: %w", err)
+	}
+
+	for _, item := range response.Items {
+		for _, op := range item {
+			switch {
+			case op.Status >= 200 && op.Status < 300:
+				result.Indexed++
+			case op.Status == 409:
+				result.Conflicts++
+			default:
+				result.Failed++
+				if op.Status == 429 || op.Status >= 500 {
+					result.Retryable++
+				}
+				if len(result.Errors) < maxBulkErrors {
+					result.Errors = append(result.Errors, fmt.Sprintf("%s: %s: %s", op.ID, op.Error.Type, op.Error.Reason))
+				}
+			}
+		}
+	}
+
+	logger.Log.Debug(fmt.Sprintf("Bulk indexed logs: indexed=%d, conflicts=%d, failed=%d",
+		result.Indexed, result.Conflicts, result.Failed))
+
+	return result, nil
+}
+
+// LogEntryFromCheckLog 把文件日志的一行转换为 ES 日志条目，用于重新导入。
+// 文件日志不保存响应体，导入的文档也没有响应体。
+func LogEntryFromCheckLog(e *logger.CheckLogEntry) *LogEntry {
+	entry := &LogEntry{
+		TargetID:     uint32(e.TargetID),
+		TargetName:   e.TargetName,
+		TargetType:   e.Type,
+		Address:      e.Address,
+		Status:       e.Status,
+		ResponseTime: e.ResponseTime,
+		Message:      e.Message,
+		Synthetic:    e.Synthetic,
+		Timestamp:    e.Timestamp,
+		Nonce:        e.Nonce,
+		Seq:          e.Seq,
+		Timings:      e.Timings,
+	}
+
+	entry.Request.Method, _ = e.Request["method"].(string)
+	entry.Request.ResolvedURL, _ = e.Request["url"].(string)
+	entry.Request.Body, _ = e.Request["body"].(string)
+	entry.Request.Headers = stringMap(e.Request["headers"])
+	if errInfo, ok := e.Request["error"].(map[string]interface{}); ok {
+		entry.Error.Type, _ = errInfo["type"].(string)
+		entry.Error.Message, _ = errInfo["message"].(string)
+	}
+
+	if code, ok := e.Response["status_code"].(float64); ok {
+		entry.Response.StatusCode = int(code)
+	}
+	entry.Response.Headers = stringMap(e.Response["headers"])
+	if n, ok := e.Response["content_length"].(float64); ok {
+		entry.Response.ContentLength = int64(n)
+	}
+	if n, ok := e.Response["bytes_received"].(float64); ok {
+		entry.Response.BytesReceived = int64(n)
+	}
+	if n, ok := e.Response["decoded_body_bytes"].(float64); ok {
+		entry.Response.DecodedBodyBytes = int64(n)
+	}
+	return entry
+}
+
+// stringMap 转换从 JSON 读出的 map[string]interface{}，非字符串的值被忽略
+func stringMap(value interface{}) map[string]string {
+	m, ok := value.(map[string]interface{})
+	if !ok || len(m) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(m))
+	for k, v := range m {
+		if s, ok := v.(string); ok {
+			result[k] = s
+		}
+	}
+	return result
+}
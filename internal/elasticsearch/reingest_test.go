@@ -0,0 +1,88 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"monitor/internal/logger"
+)
+
+// A file log line read back is indexed with its timings and details
+func TestLogEntryFromCheckLog(t *testing.T) {
+	var line logger.CheckLogEntry
+	err := json.Unmarshal([]byte(`{"target_id": 3, "type": "http", "status": "up", "response_time": 52,
+		"request": {"method": "GET", "url": "http://a/", "error": {"type": "network_error", "message": "x"}},
+		"response": {"status_code": 200, "headers": {"server": "nginx", "n": 1}, "bytes_received": 11},
+		"timings": {"connect_ms": 1.5, "ttfb_ms": 30, "reused_connection": false}}`), &line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := LogEntryFromCheckLog(&line)
+	if entry.TargetID != 3 || entry.Request.Method != "GET" || entry.Error.Type != "network_error" ||
+		entry.Response.StatusCode != 200 || entry.Response.BytesReceived != 11 || len(entry.Response.Headers) != 1 {
+		t.Errorf("entry %+v", entry)
+	}
+	if entry.Timings["connect_ms"] != 1.5 || entry.Timings["ttfb_ms"] != 30.0 || entry.Timings["reused_connection"] != false {
+		t.Errorf("timings %v", entry.Timings)
+	}
+}
+
+// The document ID depends only on the check: the same instant in another
+// zone gives the same ID, another nonce or target a different one
+func TestDocumentID(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 500, time.UTC)
+	id := DocumentID(3, at, "n1")
+	if got := DocumentID(3, at.In(time.FixedZone("UTC+8", 8*3600)), "n1"); got != id {
+		t.Errorf("ID in another zone %s, want %s", got, id)
+	}
+	for _, other := range []string{DocumentID(3, at, "n2"), DocumentID(4, at, "n1"), DocumentID(3, at.Add(time.Nanosecond), "n1")} {
+		if other == id {
+			t.Errorf("different check got the same ID %s", id)
+		}
+	}
+}
+
+// Importing the same entries twice creates each document once: the second
+// run finds them all as conflicts under the same IDs
+func TestBulkCreateTwice(t *testing.T) {
+	fake, c := newFakeES(t)
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	lines := []*logger.CheckLogEntry{
+		{TargetID: 1, Status: "up", Timestamp: at, Nonce: "a"},
+		{TargetID: 1, Status: "down", Timestamp: at.Add(time.Minute), Nonce: "b"},
+		{TargetID: 2, Status: "up", Timestamp: at, Nonce: "c"},
+	}
+	entries := func() []*LogEntry {
+		var out []*LogEntry
+		for _, line := range lines {
+			out = append(out, LogEntryFromCheckLog(line))
+		}
+		return out
+	}
+
+	first, err := c.BulkCreate(context.Background(), entries())
+	if err != nil {
+		t.Fatalf("first import: %v", err)
+	}
+	second, err := c.BulkCreate(context.Background(), entries())
+	if err != nil {
+		t.Fatalf("second import: %v", err)
+	}
+	if first.Indexed != 3 || first.Conflicts != 0 || second.Indexed != 0 || second.Conflicts != 3 || first.Failed+second.Failed != 0 {
+		t.Errorf("first %+v, second %+v, want 3 indexed then 3 conflicts", first, second)
+	}
+
+	fake.set(func(f *fakeES) {
+		if len(f.docs) != len(lines) {
+			t.Errorf("%d documents, want %d", len(f.docs), len(lines))
+		}
+		for _, line := range lines {
+			key := c.indexFor(line.Timestamp) + "/" + DocumentID(uint32(line.TargetID), line.Timestamp, line.Nonce)
+			if _, ok := f.docs[key]; !ok {
+				t.Errorf("no document %s", key)
+			}
+		}
+	})
+}
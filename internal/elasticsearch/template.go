@@ -0,0 +1,353 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"monitor/internal/logger"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// TemplateVersion 索引模板的版本，修改 indexTemplate 的 mapping 或 settings 时加一。
+// 启动时只在集群中的模板版本较旧（或没有版本）时覆盖
+const TemplateVersion = 2
+
+// templateVersionKey 模板 _meta 中记录版本的字段
+const templateVersionKey = "monitor_template_version"
+
+// 模板协调的结果
+const (
+	TemplateCreated        = "created"
+	TemplateUpgraded       = "upgraded"
+	TemplateReapplied      = "reapplied"
+	TemplateUnchanged      = "unchanged"
+	TemplateNewerInstalled = "newer_installed" // 更新版本的程序写入的模板，不降级
+	TemplateFailed         = "failed"
+)
+
+// TemplateStatus 集群中的索引模板与本程序期望的版本
+type TemplateStatus struct {
+	Name      string    `json:"name"`
+	Installed int       `json:"installed_version"` // 0 表示模板不存在或由没有版本的旧程序写入
+	Expected  int       `json:"expected_version"`
+	Action    string    `json:"action"`
+	Changes   []string  `json:"changes,omitempty"` // 写入时 mapping 的变化：+ 新增、- 删除、~ 类型改变
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// templateState 最近一次协调的结果，在健康检查中返回
+type templateState struct {
+	mu     sync.Mutex
+	status TemplateStatus
+}
+
+func (c *Client) templateName() string {
+	return fmt.Sprintf("%s-template", c.config.IndexPrefix)
+}
+
+// indexTemplate 日志索引的模板
+func (c *Client) indexTemplate() map[string]interface{} {
+	return map[string]interface{}{
+		"index_patterns": []string{fmt.Sprintf("%s-*", c.config.IndexPrefix)},
+		"_meta":          map[string]interface{}{templateVersionKey: TemplateVersion},
+		"template": map[string]interface{}{
+			"settings": map[string]interface{}{
+				"number_of_shards":   1,
+				"number_of_replicas": 1,
+				"refresh_interval":   "5s",
+			},
+			"mappings": map[string]interface{}{
+				"properties": map[string]interface{}{
+					"target_id":     map[string]string{"type": "integer"},
+					"target_name":   map[string]string{"type": "keyword"},
+					"target_type":   map[string]string{"type": "keyword"},
+					"address":       map[string]string{"type": "keyword"},
+					"status":        map[string]string{"type": "keyword"},
+					"response_time": map[string]string{"type": "long"},
+					"message":       map[string]string{"type": "text"},
+					"synthetic":     map[string]string{"type": "boolean"},
+					"clock_skew_ms": map[string]string{"type": "long"},
+					"@timestamp":    map[string]string{"type": "date"},
+					"seq":           map[string]string{"type": "long"},
+					"timings": map[string]interface{}{
+						"properties": map[string]interface{}{
+							"dns_ms":            map[string]string{"type": "double"},
+							"connect_ms":        map[string]string{"type": "double"},
+							"tls_ms":            map[string]string{"type": "double"},
+							"ttfb_ms":           map[string]string{"type": "double"},
+							"transfer_ms":       map[string]string{"type": "double"},
+							"reused_connection": map[string]string{"type": "boolean"},
+						},
+					},
+					"request": map[string]interface{}{
+						"properties": map[string]interface{}{
+							"method":       map[string]string{"type": "keyword"},
+							"resolved_url": map[string]string{"type": "keyword"},
+							"body":         map[string]string{"type": "text"},
+							"headers": map[string]interface{}{
+								"properties": map[string]interface{}{
+									"oid":        map[string]string{"type": "keyword"},
+									"version":    map[string]string{"type": "keyword"},
+									"count":      map[string]string{"type": "keyword"},
+									"size":       map[string]string{"type": "keyword"},
+									"timeout_ms": map[string]string{"type": "keyword"},
+									"mail_from":  map[string]string{"type": "keyword"},
+									"mail_to":    map[string]string{"type": "keyword"},
+								},
+							},
+						},
+					},
+					"response": map[string]interface{}{
+						"properties": map[string]interface{}{
+							"status_code":        map[string]string{"type": "integer"},
+							"content_length":     map[string]string{"type": "long"},
+							"bytes_received":     map[string]string{"type": "long"},
+							"decoded_body_bytes": map[string]string{"type": "long"},
+							"body":               map[string]string{"type": "text"},
+							"headers": map[string]interface{}{
+								"properties": map[string]interface{}{
+									"oid":              map[string]string{"type": "keyword"},
+									"value":            map[string]string{"type": "keyword"},
+									"packet_loss":      map[string]string{"type": "keyword"},
+									"avg_time_ms":      map[string]string{"type": "keyword"},
+									"packets_sent":     map[string]string{"type": "keyword"},
+									"packets_received": map[string]string{"type": "keyword"},
+									"greeting_banner":  map[string]string{"type": "keyword"},
+								},
+							},
+						},
+					},
+					"error":    map[string]string{"type": "object"},
+					"metadata": map[string]string{"type": "object"},
+				},
+			},
+		},
+	}
+}
+
+// TemplateStatus 返回最近一次协调或重新写入的结果
+func (c *Client) TemplateStatus() TemplateStatus {
+	if c == nil {
+		return TemplateStatus{}
+	}
+	c.template.mu.Lock()
+	defer c.template.mu.Unlock()
+	return c.template.status
+}
+
+// ReconcileIndexTemplate 启动时调用：模板不存在或版本较旧时写入当前版本并记录 mapping 的变化，
+// 版本相同时不写入，集群中的版本更新时保留不降级
+func (c *Client) ReconcileIndexTemplate(ctx context.Context) (TemplateStatus, error) {
+	return c.applyIndexTemplate(ctx, false)
+}
+
+// ApplyIndexTemplate 无论集群中的版本如何都写入当前模板，用于模板被手工修改或删除后恢复。
+// 模板只影响之后创建的索引，已有索引的 mapping 需要重建索引才会改变
+func (c *Client) ApplyIndexTemplate(ctx context.Context) (TemplateStatus, error) {
+	return c.applyIndexTemplate(ctx, true)
+}
+
+func (c *Client) applyIndexTemplate(ctx context.Context, force bool) (TemplateStatus, error) {
+	if c == nil || c.es == nil {
+		return TemplateStatus{}, nil
+	}
+
+	status := TemplateStatus{Name: c.templateName(), Expected: TemplateVersion, CheckedAt: time.Now()}
+	status, err := c.reconcileTemplate(ctx, status, force)
+	if err != nil {
+		status.Action = TemplateFailed
+		status.Error = err.Error()
+	}
+
+	c.template.mu.Lock()
+	c.template.status = status
+	c.template.mu.Unlock()
+	return status, err
+}
+
+func (c *Client) reconcileTemplate(ctx context.Context, status TemplateStatus, force bool) (TemplateStatus, error) {
+	expected, err := normalizeTemplate(c.indexTemplate())
+	if err != nil {
+		return status, err
+	}
+
+	installed, found, err := c.getInstalledTemplate(ctx)
+	if err != nil {
+		return status, err
+	}
+	if found {
+		status.Installed = templateVersion(installed)
+	}
+
+	switch {
+	case force:
+		status.Action = TemplateReapplied
+	case !found:
+		status.Action = TemplateCreated
+	case status.Installed == TemplateVersion:
+		status.Action = TemplateUnchanged
+		logger.Log.Info(fmt.Sprintf("Index template is up to date: %s (version %d)", status.Name, TemplateVersion))
+		return status, nil
+	case status.Installed > TemplateVersion:
+		status.Action = TemplateNewerInstalled
+		logger.Log.Warn(fmt.Sprintf("Index template %s has version %d, newer than this build's %d; leaving it unchanged",
+			status.Name, status.Installed, TemplateVersion))
+		return status, nil
+	default:
+		status.Action = TemplateUpgraded
+	}
+
+	if found {
+		status.Changes = mappingChanges(templateMappings(installed), templateMappings(expected))
+	}
+	if err := c.putTemplate(ctx, status.Name, expected); err != nil {
+		return status, err
+	}
+
+	switch status.Action {
+	case TemplateCreated:
+		logger.Log.Info(fmt.Sprintf("Index template created: %s (version %d)", status.Name, TemplateVersion))
+	default:
+		logger.Log.Info(fmt.Sprintf("Index template %s: %s from version %d to %d, mapping changes: %s",
+			status.Name, status.Action, status.Installed, TemplateVersion, describeChanges(status.Changes)))
+	}
+	status.Installed = TemplateVersion
+	return status, nil
+}
+
+// getInstalledTemplate 读取集群中的模板，不存在时 found 为 false
+func (c *Client) getInstalledTemplate(ctx context.Context) (map[string]interface{}, bool, error) {
+	req := esapi.IndicesGetIndexTemplateRequest{Name: c.templateName()}
+	res, err := req.Do(ctx, c.es)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get index template: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, false, nil
+	}
+	if res.IsError() {
+		return nil, false, fmt.Errorf("elasticsearch get index template error: %s", res.String())
+	}
+
+	var response struct {
+		IndexTemplates []struct {
+			IndexTemplate map[string]interface{} `json:"index_template"`
+		} `json:"index_templates"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, false, fmt.Errorf("failed to parse index template response: %w", err)
+	}
+	if len(response.IndexTemplates) == 0 {
+		return nil, false, nil
+	}
+	return response.IndexTemplates[0].IndexTemplate, true, nil
+}
+
+// putTemplate 写入模板，同名模板被覆盖
+func (c *Client) putTemplate(ctx context.Context, name string, template map[string]interface{}) error {
+	body, err := json.Marshal(template)
+	if err != nil {
+		return fmt.Errorf("failed to marshal index template: %w", err)
+	}
+
+	req := esapi.IndicesPutIndexTemplateRequest{
+		Name: name,
+		Body: bytes.NewReader(body),
+	}
+	res, err := req.Do(ctx, c.es)
+	if err != nil {
+		return fmt.Errorf("failed to put index template: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("elasticsearch put index template error: %s", res.String())
+	}
+	return nil
+}
+
+// normalizeTemplate 经过一次 JSON 编解码，得到与从集群读到的模板相同的类型
+func normalizeTemplate(template map[string]interface{}) (map[string]interface{}, error) {
+	body, err := json.Marshal(template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal index template: %w", err)
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(body, &normalized); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal index template: %w", err)
+	}
+	return normalized, nil
+}
+
+// templateVersion 返回模板 _meta 中的版本，没有时为 0
+func templateVersion(template map[string]interface{}) int {
+	meta, _ := template["_meta"].(map[string]interface{})
+	version, _ := meta[templateVersionKey].(float64)
+	return int(version)
+}
+
+// templateMappings 返回模板中 mappings 的 properties
+func templateMappings(template map[string]interface{}) map[string]interface{} {
+	inner, _ := template["template"].(map[string]interface{})
+	mappings, _ := inner["mappings"].(map[string]interface{})
+	properties, _ := mappings["properties"].(map[string]interface{})
+	return properties
+}
+
+// flattenMapping 把嵌套的 properties 展开为字段路径到类型的映射，如 request.method: keyword
+func flattenMapping(prefix string, properties map[string]interface{}, fields map[string]string) {
+	for name, value := range properties {
+		field, _ := value.(map[string]interface{})
+		path := prefix + name
+		typ, _ := field["type"].(string)
+		if nested, ok := field["properties"].(map[string]interface{}); ok {
+			if typ == "" {
+				typ = "object"
+			}
+			flattenMapping(path+".", nested, fields)
+		}
+		fields[path] = typ
+	}
+}
+
+// mappingChanges 比较两个 mapping，按字段路径排序返回变化
+func mappingChanges(installed, expected map[string]interface{}) []string {
+	before := make(map[string]string)
+	after := make(map[string]string)
+	flattenMapping("", installed, before)
+	flattenMapping("", expected, after)
+
+	var changes []string
+	for path, typ := range after {
+		old, ok := before[path]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("+ %s (%s)", path, typ))
+		case old != typ:
+			changes = append(changes, fmt.Sprintf("~ %s (%s -> %s)", path, old, typ))
+		}
+	}
+	for path, typ := range before {
+		if _, ok := after[path]; !ok {
+			changes = append(changes, fmt.Sprintf("- %s (%s)", path, typ))
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i][2:] < changes[j][2:] })
+	return changes
+}
+
+func describeChanges(changes []string) string {
+	if len(changes) == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%d %v", len(changes), changes)
+}
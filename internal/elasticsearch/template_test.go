@@ -0,0 +1,288 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"monitor/internal/config"
+	"monitor/internal/logger"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"go.uber.org/zap"
+)
+
+// fakeES serves the index template and bulk APIs from memory
+type fakeES struct {
+	mu           sync.Mutex
+	templates    map[string]map[string]interface{}
+	docs         map[string]json.RawMessage // "<index>/<id>" -> source
+	templatePuts int
+	failGet      bool // the template GET answers 500
+}
+
+func newFakeES(t *testing.T) (*fakeES, *Client) {
+	t.Helper()
+	logger.Log = zap.NewNop()
+	f := &fakeES{templates: make(map[string]map[string]interface{}), docs: make(map[string]json.RawMessage)}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	es, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{srv.URL}})
+	if err != nil {
+		t.Fatalf("elasticsearch client: %v", err)
+	}
+	return f, &Client{es: es, config: config.ElasticsearchConfig{Enabled: true, IndexPrefix: "monitor-logs"}}
+}
+
+func (f *fakeES) set(change func(f *fakeES)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	change(f)
+}
+
+func (f *fakeES) template(name string) map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.templates[name]
+}
+
+func (f *fakeES) puts() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.templatePuts
+}
+
+func (f *fakeES) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+	reply := func(status int, body interface{}) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}
+
+	switch name, isTemplate := strings.CutPrefix(r.URL.Path, "/_index_template/"); {
+	case isTemplate && r.Method == http.MethodGet:
+		if f.failGet {
+			reply(http.StatusInternalServerError, map[string]interface{}{"error": "cluster unavailable"})
+			return
+		}
+		template, ok := f.templates[name]
+		if !ok {
+			reply(http.StatusNotFound, map[string]interface{}{"error": "index_template_missing_exception"})
+			return
+		}
+		reply(http.StatusOK, map[string]interface{}{
+			"index_templates": []interface{}{map[string]interface{}{"name": name, "index_template": template}},
+		})
+	case isTemplate && r.Method == http.MethodPut:
+		var template map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+			reply(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		f.templates[name] = template
+		f.templatePuts++
+		reply(http.StatusOK, map[string]interface{}{"acknowledged": true})
+	case r.URL.Path == "/_bulk" && r.Method == http.MethodPost:
+		reply(http.StatusOK, map[string]interface{}{"items": f.bulk(r.Body)})
+	default:
+		reply(http.StatusNotFound, map[string]interface{}{"error": r.Method + " " + r.URL.Path})
+	}
+}
+
+// bulk runs create actions: a document whose ID exists is a conflict, as in ES
+func (f *fakeES) bulk(body io.Reader) []interface{} {
+	var items []interface{}
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 1<<20), 1<<20)
+	for scanner.Scan() {
+		var action map[string]struct {
+			Index string `json:"_index"`
+			ID    string `json:"_id"`
+		}
+		json.Unmarshal(scanner.Bytes(), &action)
+		meta := action["create"]
+		scanner.Scan()
+		key := meta.Index + "/" + meta.ID
+		item := map[string]interface{}{"_index": meta.Index, "_id": meta.ID, "status": http.StatusCreated}
+		if _, exists := f.docs[key]; exists {
+			item["status"] = http.StatusConflict
+			item["error"] = map[string]string{"type": "version_conflict_engine_exception", "reason": "document already exists"}
+		} else {
+			f.docs[key] = append(json.RawMessage(nil), scanner.Bytes()...)
+		}
+		items = append(items, map[string]interface{}{"create": item})
+	}
+	return items
+}
+
+// installTemplate stores the expected template with another version and
+// with mappings changed by edit
+func (f *fakeES) installTemplate(t *testing.T, c *Client, version int, edit func(properties map[string]interface{})) {
+	t.Helper()
+	template, err := normalizeTemplate(c.indexTemplate())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version == 0 {
+		delete(template, "_meta")
+	} else {
+		template["_meta"] = map[string]interface{}{templateVersionKey: version}
+	}
+	if edit != nil {
+		edit(templateMappings(template))
+	}
+	f.set(func(f *fakeES) { f.templates[c.templateName()] = template })
+}
+
+func TestTemplateVersion(t *testing.T) {
+	for _, tc := range []struct {
+		template string
+		want     int
+	}{
+		{`{"_meta": {"monitor_template_version": 2}}`, 2},
+		{`{"_meta": {"monitor_template_version": 3, "owner": "ops"}}`, 3},
+		{`{"_meta": {"owner": "ops"}}`, 0},
+		{`{"_meta": {"monitor_template_version": "2"}}`, 0},
+		{`{"index_patterns": ["monitor-logs-*"]}`, 0},
+		{`{}`, 0},
+	} {
+		var template map[string]interface{}
+		if err := json.Unmarshal([]byte(tc.template), &template); err != nil {
+			t.Fatal(err)
+		}
+		if got := templateVersion(template); got != tc.want {
+			t.Errorf("templateVersion(%s) = %d, want %d", tc.template, got, tc.want)
+		}
+	}
+}
+
+func TestMappingChanges(t *testing.T) {
+	for _, tc := range []struct {
+		name                string
+		installed, expected string
+		want                []string
+	}{
+		{"identical", `{"status": {"type": "keyword"}}`, `{"status": {"type": "keyword"}}`, nil},
+		{"added", `{}`, `{"seq": {"type": "long"}}`, []string{"+ seq (long)"}},
+		{"removed", `{"old": {"type": "text"}}`, `{}`, []string{"- old (text)"}},
+		{"type changed", `{"status": {"type": "text"}}`, `{"status": {"type": "keyword"}}`, []string{"~ status (text -> keyword)"}},
+		{"nested field added",
+			`{"request": {"properties": {"method": {"type": "keyword"}}}}`,
+			`{"request": {"properties": {"method": {"type": "keyword"}, "body": {"type": "text"}}}}`,
+			[]string{"+ request.body (text)"}},
+		{"object became a field",
+			`{"error": {"properties": {"type": {"type": "keyword"}}}}`,
+			`{"error": {"type": "keyword"}}`,
+			[]string{"~ error (object -> keyword)", "- error.type (keyword)"}},
+		{"sorted by path",
+			`{"b": {"type": "long"}, "z": {"type": "long"}}`,
+			`{"a": {"type": "long"}, "b": {"type": "integer"}}`,
+			[]string{"+ a (long)", "~ b (long -> integer)", "- z (long)"}},
+	} {
+		var installed, expected map[string]interface{}
+		json.Unmarshal([]byte(tc.installed), &installed)
+		json.Unmarshal([]byte(tc.expected), &expected)
+		if got := mappingChanges(installed, expected); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: changes %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+// The template is written when it is missing, older or forced, and left
+// alone when it is current or newer
+func TestReconcileIndexTemplate(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name      string
+		installed int // version in the cluster; -1 for none
+		edit      func(properties map[string]interface{})
+		force     bool
+		action    string
+		puts      int
+		changes   []string
+	}{
+		{name: "created", installed: -1, action: TemplateCreated, puts: 1},
+		{name: "unchanged", installed: TemplateVersion, action: TemplateUnchanged},
+		{name: "upgraded", installed: TemplateVersion - 1, action: TemplateUpgraded, puts: 1,
+			edit: func(p map[string]interface{}) {
+				delete(p, "seq")
+				p["status"] = map[string]interface{}{"type": "text"}
+				p["legacy"] = map[string]interface{}{"type": "keyword"}
+			},
+			changes: []string{"- legacy (keyword)", "+ seq (long)", "~ status (text -> keyword)"}},
+		{name: "without a version", installed: 0, action: TemplateUpgraded, puts: 1},
+		{name: "newer installed", installed: TemplateVersion + 1, action: TemplateNewerInstalled},
+		{name: "forced", installed: TemplateVersion + 1, force: true, action: TemplateReapplied, puts: 1,
+			edit: func(p map[string]interface{}) { delete(p, "seq") }, changes: []string{"+ seq (long)"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fake, c := newFakeES(t)
+			if tc.installed >= 0 {
+				fake.installTemplate(t, c, tc.installed, tc.edit)
+			}
+			before, _ := json.Marshal(fake.template(c.templateName()))
+
+			apply := c.ReconcileIndexTemplate
+			if tc.force {
+				apply = c.ApplyIndexTemplate
+			}
+			status, err := apply(ctx)
+			if err != nil {
+				t.Fatalf("apply: %v", err)
+			}
+			if status.Action != tc.action || status.Expected != TemplateVersion || !reflect.DeepEqual(status.Changes, tc.changes) {
+				t.Errorf("status %+v, want %s with changes %q", status, tc.action, tc.changes)
+			}
+			if got := c.TemplateStatus(); got.Action != tc.action {
+				t.Errorf("TemplateStatus %+v", got)
+			}
+
+			installed := fake.template(c.templateName())
+			if fake.puts() != tc.puts {
+				t.Fatalf("%d template PUTs, want %d", fake.puts(), tc.puts)
+			}
+			if tc.puts == 0 {
+				if after, _ := json.Marshal(installed); !bytes.Equal(after, before) {
+					t.Error("template changed without a PUT")
+				}
+				if status.Installed != tc.installed {
+					t.Errorf("installed version %d, want %d", status.Installed, tc.installed)
+				}
+				return
+			}
+			expected, _ := normalizeTemplate(c.indexTemplate())
+			if !reflect.DeepEqual(installed, expected) || status.Installed != TemplateVersion {
+				t.Errorf("installed version %d, template %v", status.Installed, installed)
+			}
+		})
+	}
+}
+
+func TestReconcileIndexTemplateFailure(t *testing.T) {
+	fake, c := newFakeES(t)
+	fake.set(func(f *fakeES) { f.failGet = true })
+	status, err := c.ReconcileIndexTemplate(context.Background())
+	if err == nil || status.Action != TemplateFailed || !strings.Contains(status.Error, "500") {
+		t.Errorf("status %+v, err %v, want a failure with the response", status, err)
+	}
+	if c.TemplateStatus().Action != TemplateFailed || fake.puts() != 0 {
+		t.Errorf("TemplateStatus %+v after a failed read, %d PUTs", c.TemplateStatus(), fake.puts())
+	}
+
+	// A nil client, ES disabled, does nothing
+	var disabled *Client
+	if status, err := disabled.ReconcileIndexTemplate(context.Background()); err != nil || status.Action != "" {
+		t.Errorf("disabled client: %+v, %v", status, err)
+	}
+}
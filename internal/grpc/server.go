@@ -10,6 +10,7 @@ import (
 	"monitor/internal/database"
 	"monitor/internal/models"
 	"monitor/internal/monitor"
+	"monitor/internal/version"
 	pb "monitor/proto"
 
 	"google.golang.org/grpc"
@@ -42,6 +43,31 @@ func (s *Server) AddMonitor(ctx context.Context, req *pb.Target) (*pb.MonitorRes
 		metadata = string(bytes)
 	}
 
+	settings := map[string]interface{}{
+		"name":     req.Name,
+		"address":  req.Address,
+		"port":     float64(req.Port),
+		"interval": float64(req.Interval),
+		"enabled":  req.Enabled,
+	}
+	if err := monitor.ValidateSettings(req.Type, settings); err != nil {
+		return &pb.MonitorResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	var fast int64
+	if s.monitorService.IsFast(req.Interval) {
+		fast = 1
+	}
+	if err := s.monitorService.CheckQuota(1, fast); err != nil {
+		return &pb.MonitorResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
 	target := models.MonitorTarget{
 		Name:     req.Name,
 		Type:     req.Type,
@@ -92,6 +118,9 @@ func (s *Server) RemoveMonitor(ctx context.Context, req *pb.MonitorID) (*pb.Moni
 			Message: fmt.Sprintf("Failed to delete target: %v", err),
 		}, nil
 	}
+	// 存档的响应不再有对应的监控，保留期到期前也一并删除
+	db.Where("target_id = ?", req.Id).Delete(&models.ResponseArchive{})
+	db.Where("target_id = ?", req.Id).Delete(&models.CertificateTarget{})
 
 	if err := s.monitorService.RemoveTarget(req.Id); err != nil {
 		return &pb.MonitorResponse{
@@ -168,7 +197,7 @@ func (s *Server) ListMonitors(ctx context.Context, req *pb.Empty) (*pb.TargetLis
 }
 
 func (s *Server) GetMonitorStatus(ctx context.Context, req *pb.MonitorID) (*pb.MonitorStatus, error) {
-	status, err := s.monitorService.GetStatus(req.Id)
+	status, err := s.monitorService.GetStatus(ctx, req.Id)
 	if err != nil {
 		return nil, err
 	}
@@ -180,11 +209,16 @@ func (s *Server) GetMonitorStatus(ctx context.Context, req *pb.MonitorID) (*pb.M
 		Message:          status.Message,
 		CheckedAt:        status.CheckedAt.Unix(),
 		UptimePercentage: int32(status.UptimePercentage),
+		TargetId:         status.TargetID,
+		TargetName:       status.TargetName,
+		TargetType:       status.TargetType,
+		TargetAddress:    status.TargetAddress,
+		TargetDeleted:    status.TargetDeleted,
 	}, nil
 }
 
 func (s *Server) ListMonitorStatus(ctx context.Context, req *pb.Empty) (*pb.MonitorStatusList, error) {
-	statuses := s.monitorService.ListStatus()
+	statuses := s.monitorService.ListStatus(ctx)
 
 	var pbStatuses []*pb.MonitorStatus
 	for _, status := range statuses {
@@ -195,6 +229,11 @@ func (s *Server) ListMonitorStatus(ctx context.Context, req *pb.Empty) (*pb.Moni
 			Message:          status.Message,
 			CheckedAt:        status.CheckedAt.Unix(),
 			UptimePercentage: int32(status.UptimePercentage),
+			TargetId:         status.TargetID,
+			TargetName:       status.TargetName,
+			TargetType:       status.TargetType,
+			TargetAddress:    status.TargetAddress,
+			TargetDeleted:    status.TargetDeleted,
 		})
 	}
 
@@ -203,6 +242,21 @@ func (s *Server) ListMonitorStatus(ctx context.Context, req *pb.Empty) (*pb.Moni
 	}, nil
 }
 
+func (s *Server) GetVersion(ctx context.Context, req *pb.Empty) (*pb.VersionInfo, error) {
+	info := version.Get()
+
+	return &pb.VersionInfo{
+		Version:              info.Version,
+		GitCommit:            info.GitCommit,
+		BuildDate:            info.BuildDate,
+		GoVersion:            info.GoVersion,
+		SchemaVersion:        int32(info.SchemaVersion),
+		ElasticsearchEnabled: info.Features.Elasticsearch,
+		AlertingEnabled:      info.Features.Alerting,
+		GrpcEnabled:          info.Features.GRPC,
+	}, nil
+}
+
 func (s *Server) QueryIPGeo(ctx context.Context, req *pb.IPRequest) (*pb.IPGeoResponse, error) {
 	ipgeoService := NewIPGeoService()
 
@@ -3,18 +3,122 @@ package logger
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"monitor/internal/clock"
+
+	"go.uber.org/zap"
 )
 
 var (
 	logFileMutex sync.Mutex
+	// fileLogClock decides the daily file name and default query range
+	fileLogClock clock.Clock = clock.Real
+)
+
+// SetClock replaces the time source of the file sink
+func SetClock(c clock.Clock) {
+	logFileMutex.Lock()
+	defer logFileMutex.Unlock()
+	fileLogClock = c
+}
+
+// DefaultLogDir is used when no file log directory is configured
+const DefaultLogDir = "logs"
+
+const (
+	// fileLogFailureThreshold consecutive write failures disable the file sink
+	fileLogFailureThreshold = 3
+	// fileLogProbeInterval is how often a disabled sink retries a test write
+	fileLogProbeInterval = 30 * time.Second
 )
 
+// ErrFileLogDisabled is returned while the file sink is disabled
+var ErrFileLogDisabled = errors.New("file log is disabled because the log directory is not writable")
+
+// fileLogState tracks whether the file sink is usable; guarded by logFileMutex
+var fileLogState = struct {
+	dir           string
+	enabled       bool
+	lastError     string
+	disabledSince time.Time
+	failures      int
+	lastProbe     time.Time
+}{dir: DefaultLogDir, enabled: true}
+
+// FileLogStatus describes the state of the file sink
+type FileLogStatus struct {
+	Enabled       bool       `json:"enabled"`
+	Dir           string     `json:"dir"`
+	Error         string     `json:"error,omitempty"`
+	DisabledSince *time.Time `json:"disabled_since,omitempty"`
+}
+
+// GetFileLogStatus returns the current state of the file sink
+func GetFileLogStatus() FileLogStatus {
+	logFileMutex.Lock()
+	defer logFileMutex.Unlock()
+
+	status := FileLogStatus{
+		Enabled: fileLogState.enabled,
+		Dir:     fileLogState.dir,
+		Error:   fileLogState.lastError,
+	}
+	if !fileLogState.enabled {
+		since := fileLogState.disabledSince
+		status.DisabledSince = &since
+	}
+	return status
+}
+
+// probeLogDir checks the directory is writable by creating and removing a file
+func probeLogDir(logDir string) error {
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	file, err := os.CreateTemp(logDir, ".write-test-*")
+	if err != nil {
+		return fmt.Errorf("log directory is not writable: %w", err)
+	}
+	name := file.Name()
+	file.Close()
+	return os.Remove(name)
+}
+
+// disableFileLog switches the sink off; caller holds logFileMutex
+func disableFileLog(err error) {
+	if !fileLogState.enabled {
+		return
+	}
+	fileLogState.enabled = false
+	fileLogState.lastError = err.Error()
+	fileLogState.disabledSince = fileLogClock.Now()
+	fileLogState.lastProbe = fileLogState.disabledSince
+	if Log != nil {
+		Warn("File log disabled, check results will not be written to disk",
+			zap.String("dir", fileLogState.dir), zap.Error(err))
+	}
+}
+
+// enableFileLog switches the sink back on; caller holds logFileMutex
+func enableFileLog() {
+	wasDisabled := !fileLogState.enabled
+	fileLogState.enabled = true
+	fileLogState.lastError = ""
+	fileLogState.failures = 0
+	if wasDisabled && Log != nil {
+		Info("File log re-enabled", zap.String("dir", fileLogState.dir))
+	}
+}
+
 // CheckLogEntry represents a single check log entry
 type CheckLogEntry struct {
 	Timestamp    time.Time              `json:"timestamp"`
@@ -25,37 +129,115 @@ type CheckLogEntry struct {
 	Status       string                 `json:"status"`
 	ResponseTime int64                  `json:"response_time"`
 	Message      string                 `json:"message"`
+	Synthetic    bool                   `json:"synthetic,omitempty"` // Injected by the failure injection endpoint
+	Nonce        string                 `json:"nonce,omitempty"`     // Random per-check ID; with target_id and timestamp it identifies the check
+	Seq          int64                  `json:"seq,omitempty"`       // Increasing in write order; breaks ties between entries with the same timestamp
 	Request      map[string]interface{} `json:"request,omitempty"`
 	Response     map[string]interface{} `json:"response,omitempty"`
+	Timings      map[string]interface{} `json:"timings,omitempty"` // HTTP/HTTPS 各阶段耗时（毫秒）
 }
 
-// InitLogFileLog initializes file-based logging for check results
+// InitLogFileLog initializes file-based logging for check results. If the
+// directory can't be written the sink starts disabled and retries periodically.
 func InitLogFileLog(logDir string) error {
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return fmt.Errorf("failed to create log directory: %w", err)
+	if logDir == "" {
+		logDir = DefaultLogDir
+	}
+
+	logFileMutex.Lock()
+	defer logFileMutex.Unlock()
+
+	fileLogState.dir = logDir
+	if err := probeLogDir(logDir); err != nil {
+		disableFileLog(err)
+		return err
 	}
+	enableFileLog()
 	return nil
 }
 
-// WriteCheckLog writes a check result to the log file
-func WriteCheckLog(logDir string, entry *CheckLogEntry) error {
+// lastCheckLogSeq is the last sequence number handed out; guarded by logFileMutex
+var lastCheckLogSeq int64
+
+// NextCheckLogSeq returns a new sequence number for a check log entry. It
+// starts from the wall clock in nanoseconds, so the numbers keep increasing
+// across restarts as long as the clock does not go back.
+func NextCheckLogSeq() int64 {
+	logFileMutex.Lock()
+	defer logFileMutex.Unlock()
+	return nextCheckLogSeq()
+}
+
+// nextCheckLogSeq caller holds logFileMutex
+func nextCheckLogSeq() int64 {
+	seq := time.Now().UnixNano()
+	if seq <= lastCheckLogSeq {
+		seq = lastCheckLogSeq + 1
+	}
+	lastCheckLogSeq = seq
+	return seq
+}
+
+// WriteCheckLog writes a check result to the log file. An entry without a
+// sequence number gets the next one.
+func WriteCheckLog(entry *CheckLogEntry) error {
 	logFileMutex.Lock()
 	defer logFileMutex.Unlock()
 
+	if !fileLogState.enabled {
+		if fileLogClock.Now().Sub(fileLogState.lastProbe) < fileLogProbeInterval {
+			return ErrFileLogDisabled
+		}
+		fileLogState.lastProbe = fileLogClock.Now()
+		if err := probeLogDir(fileLogState.dir); err != nil {
+			fileLogState.lastError = err.Error()
+			return ErrFileLogDisabled
+		}
+		enableFileLog()
+	}
+
+	if entry.Seq == 0 {
+		entry.Seq = nextCheckLogSeq()
+	}
+	if err := appendCheckLog(fileLogState.dir, entry); err != nil {
+		fileLogState.failures++
+		if fileLogState.failures >= fileLogFailureThreshold {
+			disableFileLog(err)
+		}
+		return err
+	}
+
+	fileLogState.failures = 0
+	return nil
+}
+
+// appendCheckLog appends one entry to the daily log file
+func appendCheckLog(logDir string, entry *CheckLogEntry) error {
 	// Create log file path with date: logs/check-2026-01-14.jsonl
-	date := time.Now().Format("2006-01-02")
+	date := fileLogClock.Now().Format("2006-01-02")
 	logFilePath := filepath.Join(logDir, fmt.Sprintf("check-%s.jsonl", date))
 
 	// Open file in append mode
-	file, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	file, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to open log file: %w", err)
 	}
 	defer file.Close()
 
+	// A write cut short (crash, full disk) leaves a partial last line; end it
+	// so this entry is not joined to it and lost with it
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			if _, err := file.Write([]byte{'\n'}); err != nil {
+				return fmt.Errorf("failed to write log entry: %w", err)
+			}
+		}
+	}
+
 	// Set timestamp
 	if entry.Timestamp.IsZero() {
-		entry.Timestamp = time.Now()
+		entry.Timestamp = fileLogClock.Now()
 	}
 
 	// Marshal to JSON
@@ -74,22 +256,91 @@ func WriteCheckLog(logDir string, entry *CheckLogEntry) error {
 
 // LogQueryRequest represents a log query request
 type LogQueryRequest struct {
-	TargetID   *int       `json:"target_id,omitempty"`
-	Status     string     `json:"status,omitempty"`
-	StartTime  *time.Time `json:"start_time,omitempty"`
-	EndTime    *time.Time `json:"end_time,omitempty"`
-	Limit      int        `json:"limit,omitempty"`
-	Offset     int        `json:"offset,omitempty"`
+	TargetID  *int       `json:"target_id,omitempty"`
+	Status    string     `json:"status,omitempty"`
+	StartTime *time.Time `json:"start_time,omitempty"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+	Limit     int        `json:"limit,omitempty"`
+	Offset    int        `json:"offset,omitempty"`
+	After     *LogCursor `json:"-"`                   // Return the entries after this one; replaces Offset
+	Synthetic *bool      `json:"synthetic,omitempty"` // nil: all entries; true/false: only synthetic/real results
 }
 
 // LogQueryResult represents the result of a log query
 type LogQueryResult struct {
-	Total  int              `json:"total"`
-	Logs   []*CheckLogEntry `json:"logs"`
+	Total     int              `json:"total"`
+	Logs      []*CheckLogEntry `json:"logs"`
+	NextAfter string           `json:"next_after,omitempty"` // Cursor of the last entry when more entries follow
+}
+
+// LogCursor is the position of an entry in query results, which are ordered
+// newest first, then by target ID and sequence number, both descending.
+// Unlike an offset it stays valid while new entries are written.
+type LogCursor struct {
+	Timestamp time.Time
+	TargetID  int
+	Seq       int64
+}
+
+// Cursor returns the position of the entry
+func (e *CheckLogEntry) Cursor() LogCursor {
+	return LogCursor{Timestamp: e.Timestamp, TargetID: e.TargetID, Seq: e.Seq}
+}
+
+// String encodes the cursor as "<unix nanoseconds>_<target_id>_<seq>"
+func (c LogCursor) String() string {
+	return fmt.Sprintf("%d_%d_%d", c.Timestamp.UnixNano(), c.TargetID, c.Seq)
+}
+
+// ParseLogCursor decodes a cursor returned by LogCursor.String
+func ParseLogCursor(s string) (LogCursor, error) {
+	parts := strings.Split(s, "_")
+	if len(parts) != 3 {
+		return LogCursor{}, fmt.Errorf("invalid cursor %q", s)
+	}
+	nanos, err1 := strconv.ParseInt(parts[0], 10, 64)
+	targetID, err2 := strconv.Atoi(parts[1])
+	seq, err3 := strconv.ParseInt(parts[2], 10, 64)
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return LogCursor{}, fmt.Errorf("invalid cursor %q", s)
+	}
+	return LogCursor{Timestamp: time.Unix(0, nanos), TargetID: targetID, Seq: seq}, nil
 }
 
-// QueryCheckLogs queries check logs from files
-func QueryCheckLogs(logDir string, req *LogQueryRequest) (*LogQueryResult, error) {
+// Compare returns a negative number when c comes before o in query order, a
+// positive number when it comes after, and 0 for the same position
+func (c LogCursor) Compare(o LogCursor) int {
+	switch {
+	case !c.Timestamp.Equal(o.Timestamp):
+		if c.Timestamp.After(o.Timestamp) {
+			return -1
+		}
+		return 1
+	case c.TargetID != o.TargetID:
+		if c.TargetID > o.TargetID {
+			return -1
+		}
+		return 1
+	case c.Seq != o.Seq:
+		if c.Seq > o.Seq {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+// QueryCheckLogs queries check logs from files. It returns ErrFileLogDisabled
+// while the sink is disabled, since an empty result would be misleading.
+func QueryCheckLogs(req *LogQueryRequest) (*LogQueryResult, error) {
+	logFileMutex.Lock()
+	logDir, enabled, now := fileLogState.dir, fileLogState.enabled, fileLogClock.Now()
+	logFileMutex.Unlock()
+
+	if !enabled {
+		return nil, ErrFileLogDisabled
+	}
+
 	result := &LogQueryResult{
 		Logs: make([]*CheckLogEntry, 0),
 	}
@@ -99,13 +350,13 @@ func QueryCheckLogs(logDir string, req *LogQueryRequest) (*LogQueryResult, error
 	if req.StartTime != nil {
 		startDate = *req.StartTime
 	} else {
-		startDate = time.Now().AddDate(0, 0, -7) // Default: last 7 days
+		startDate = now.AddDate(0, 0, -7) // Default: last 7 days
 	}
 
 	if req.EndTime != nil {
 		endDate = *req.EndTime
 	} else {
-		endDate = time.Now()
+		endDate = now
 	}
 
 	// Iterate through each day in the range
@@ -134,7 +385,7 @@ func QueryCheckLogs(logDir string, req *LogQueryRequest) (*LogQueryResult, error
 		}
 	}
 
-	// Sort by timestamp descending (newest first)
+	// Sort newest first, ties by target ID and sequence number
 	sortEntries(matchedEntries)
 
 	// Apply pagination
@@ -144,6 +395,11 @@ func QueryCheckLogs(logDir string, req *LogQueryRequest) (*LogQueryResult, error
 	}
 
 	start := req.Offset
+	if req.After != nil {
+		start = sort.Search(len(matchedEntries), func(i int) bool {
+			return matchedEntries[i].Cursor().Compare(*req.After) > 0
+		})
+	}
 	if start > len(matchedEntries) {
 		start = len(matchedEntries)
 	}
@@ -156,10 +412,28 @@ func QueryCheckLogs(logDir string, req *LogQueryRequest) (*LogQueryResult, error
 	if start < end {
 		result.Logs = matchedEntries[start:end]
 	}
+	if end < len(matchedEntries) && end > 0 {
+		result.NextAfter = matchedEntries[end-1].Cursor().String()
+	}
 
 	return result, nil
 }
 
+// ReadCheckLogDay returns the entries of one day's log file, or nil if there is
+// no file for that day. It reads the directory even while the sink is disabled.
+func ReadCheckLogDay(day time.Time) ([]*CheckLogEntry, error) {
+	logFileMutex.Lock()
+	logDir := fileLogState.dir
+	logFileMutex.Unlock()
+
+	logFilePath := filepath.Join(logDir, fmt.Sprintf("check-%s.jsonl", day.Format("2006-01-02")))
+	entries, err := readLogFile(logFilePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return entries, err
+}
+
 // readLogFile reads a log file and returns its entries
 func readLogFile(logFilePath string) ([]*CheckLogEntry, error) {
 	file, err := os.Open(logFilePath)
@@ -179,7 +453,7 @@ func readLogFile(logFilePath string) ([]*CheckLogEntry, error) {
 
 		var entry CheckLogEntry
 		if err := json.Unmarshal([]byte(line), &entry); err != nil {
-			continue // Skip invalid lines
+			continue // Skip invalid lines, such as a partial last line
 		}
 
 		entries = append(entries, &entry)
@@ -200,6 +474,11 @@ func matchesQuery(entry *CheckLogEntry, req *LogQueryRequest) bool {
 		return false
 	}
 
+	// Filter synthetic (injected) results
+	if req.Synthetic != nil && entry.Synthetic != *req.Synthetic {
+		return false
+	}
+
 	// Filter by time range
 	if req.StartTime != nil && entry.Timestamp.Before(*req.StartTime) {
 		return false
@@ -212,15 +491,10 @@ func matchesQuery(entry *CheckLogEntry, req *LogQueryRequest) bool {
 	return true
 }
 
-// sortEntries sorts entries by timestamp (newest first)
+// sortEntries sorts entries in query order (newest first). The sort is
+// stable, so old entries without a sequence number keep their file order.
 func sortEntries(entries []*CheckLogEntry) {
-	// Simple bubble sort (for small datasets)
-	n := len(entries)
-	for i := 0; i < n-1; i++ {
-		for j := 0; j < n-i-1; j++ {
-			if entries[j].Timestamp.Before(entries[j+1].Timestamp) {
-				entries[j], entries[j+1] = entries[j+1], entries[j]
-			}
-		}
-	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Cursor().Compare(entries[j].Cursor()) < 0
+	})
 }
@@ -0,0 +1,166 @@
+package logger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"monitor/internal/clock"
+
+	"go.uber.org/zap"
+)
+
+var epoch = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// useFakeFileLog points the file sink at a fake clock and restores the sink
+// state at the end of the test, without probing the default directory
+func useFakeFileLog(t *testing.T) *clock.Fake {
+	t.Helper()
+	Log = zap.NewNop()
+	fake := clock.NewFake(epoch)
+	SetClock(fake)
+
+	logFileMutex.Lock()
+	saved := fileLogState
+	logFileMutex.Unlock()
+	t.Cleanup(func() {
+		SetClock(clock.Real)
+		logFileMutex.Lock()
+		fileLogState = saved
+		logFileMutex.Unlock()
+	})
+	return fake
+}
+
+// blockedDir returns a path that can't be created, even as root, because its
+// parent is a regular file
+func blockedDir(t *testing.T) (dir, blocker string) {
+	t.Helper()
+	blocker = filepath.Join(t.TempDir(), "blocker")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatalf("write blocker: %v", err)
+	}
+	return filepath.Join(blocker, "logs"), blocker
+}
+
+func TestFileLogStartsDisabledAndRecovers(t *testing.T) {
+	fake := useFakeFileLog(t)
+	dir, blocker := blockedDir(t)
+
+	if err := InitLogFileLog(dir); err == nil {
+		t.Fatal("InitLogFileLog of an unwritable directory succeeded")
+	}
+	status := GetFileLogStatus()
+	if status.Enabled || status.Error == "" || status.DisabledSince == nil || !status.DisabledSince.Equal(epoch) {
+		t.Fatalf("status = %+v, want disabled since %v with the error", status, epoch)
+	}
+	if err := WriteCheckLog(&CheckLogEntry{TargetID: 1, Status: "up"}); !errors.Is(err, ErrFileLogDisabled) {
+		t.Fatalf("write = %v, want ErrFileLogDisabled", err)
+	}
+	if _, err := QueryCheckLogs(&LogQueryRequest{}); !errors.Is(err, ErrFileLogDisabled) {
+		t.Fatalf("query = %v, want ErrFileLogDisabled rather than empty results", err)
+	}
+
+	// The directory becomes writable, but the sink only probes it every 30s
+	if err := os.Remove(blocker); err != nil {
+		t.Fatalf("remove blocker: %v", err)
+	}
+	fake.Advance(fileLogProbeInterval - time.Second)
+	if err := WriteCheckLog(&CheckLogEntry{TargetID: 1, Status: "up"}); !errors.Is(err, ErrFileLogDisabled) {
+		t.Fatalf("write before the probe interval = %v, want ErrFileLogDisabled", err)
+	}
+	fake.Advance(time.Second)
+	if err := WriteCheckLog(&CheckLogEntry{TargetID: 1, Status: "up"}); err != nil {
+		t.Fatalf("write after the probe interval: %v", err)
+	}
+	if status := GetFileLogStatus(); !status.Enabled || status.Error != "" || status.DisabledSince != nil {
+		t.Errorf("status = %+v, want enabled", status)
+	}
+	result, err := QueryCheckLogs(&LogQueryRequest{StartTime: &epoch})
+	if err != nil || result.Total != 1 {
+		t.Errorf("query after recovery: %+v %v, want the entry written", result, err)
+	}
+}
+
+func TestFileLogDisabledAfterConsecutiveFailures(t *testing.T) {
+	useFakeFileLog(t)
+	dir := t.TempDir()
+	if err := InitLogFileLog(dir); err != nil {
+		t.Fatalf("InitLogFileLog: %v", err)
+	}
+
+	// A directory in place of the daily file makes every write fail
+	name := filepath.Join(dir, "check-"+epoch.Format("2006-01-02")+".jsonl")
+	if err := os.Mkdir(name, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	for i := 1; i <= fileLogFailureThreshold; i++ {
+		err := WriteCheckLog(&CheckLogEntry{TargetID: 1, Status: "up"})
+		if err == nil || errors.Is(err, ErrFileLogDisabled) {
+			t.Fatalf("write %d = %v, want the write error", i, err)
+		}
+		if enabled := GetFileLogStatus().Enabled; enabled != (i < fileLogFailureThreshold) {
+			t.Fatalf("after %d failures enabled = %v", i, enabled)
+		}
+	}
+	if err := WriteCheckLog(&CheckLogEntry{TargetID: 1, Status: "up"}); !errors.Is(err, ErrFileLogDisabled) {
+		t.Errorf("write after disabling = %v, want ErrFileLogDisabled", err)
+	}
+}
+
+// A success in between starts the failure count over
+func TestFileLogFailuresMustBeConsecutive(t *testing.T) {
+	useFakeFileLog(t)
+	dir := t.TempDir()
+	if err := InitLogFileLog(dir); err != nil {
+		t.Fatalf("InitLogFileLog: %v", err)
+	}
+	name := filepath.Join(dir, "check-"+epoch.Format("2006-01-02")+".jsonl")
+
+	for round := 0; round < 3; round++ {
+		os.Mkdir(name, 0755)
+		for i := 0; i < fileLogFailureThreshold-1; i++ {
+			WriteCheckLog(&CheckLogEntry{TargetID: 1, Status: "up"})
+		}
+		os.Remove(name)
+		if err := WriteCheckLog(&CheckLogEntry{TargetID: 1, Status: "up"}); err != nil {
+			t.Fatalf("round %d: write: %v", round, err)
+		}
+		os.Remove(name)
+	}
+	if !GetFileLogStatus().Enabled {
+		t.Error("sink disabled by failures that were not consecutive")
+	}
+}
+
+// Lines that don't parse are skipped when reading; an entry written after a
+// partial last line starts a line of its own and is kept
+func TestReadCheckLogDaySkipsBrokenLines(t *testing.T) {
+	useFakeFileLog(t)
+	dir := t.TempDir()
+	if err := InitLogFileLog(dir); err != nil {
+		t.Fatalf("InitLogFileLog: %v", err)
+	}
+	if err := WriteCheckLog(&CheckLogEntry{TargetID: 1, Status: "up", Nonce: "a"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	file, err := os.OpenFile(filepath.Join(dir, "check-"+epoch.Format("2006-01-02")+".jsonl"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("not json\n" + `{"target_id": 2, "status": "do`)
+	file.Close()
+	if err := WriteCheckLog(&CheckLogEntry{TargetID: 3, Status: "down", Nonce: "b"}); err != nil {
+		t.Fatalf("write after a partial line: %v", err)
+	}
+
+	entries, err := ReadCheckLogDay(epoch)
+	if err != nil {
+		t.Fatalf("ReadCheckLogDay: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Nonce != "a" || entries[1].Nonce != "b" {
+		t.Errorf("read %d entries %+v, want the two whole ones", len(entries), entries)
+	}
+}
@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// level 所有日志共用的级别，运行时修改立即生效
+var level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+
+// levelState 记录级别的修改，用于定时恢复和健康检查
+var levelState struct {
+	mu         sync.Mutex
+	configured zapcore.Level // 配置文件中的级别
+	revertTo   zapcore.Level // 定时恢复到的级别
+	revertAt   time.Time
+	timer      *time.Timer
+	changedBy  string
+	changedAt  time.Time
+}
+
+// LevelStatus 当前日志级别
+type LevelStatus struct {
+	Level      string     `json:"level"`
+	Configured string     `json:"configured"`
+	RevertAt   *time.Time `json:"revert_at,omitempty"` // 临时修改恢复的时间
+	RevertTo   string     `json:"revert_to,omitempty"`
+	ChangedBy  string     `json:"changed_by,omitempty"` // api, signal, timer（定时恢复）
+	ChangedAt  *time.Time `json:"changed_at,omitempty"`
+}
+
+// ParseLevel 解析 debug、info、warn、error
+func ParseLevel(s string) (zapcore.Level, error) {
+	switch s {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "warn":
+		return zapcore.WarnLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	}
+	return zapcore.InfoLevel, fmt.Errorf("invalid log level %q, must be debug, info, warn or error", s)
+}
+
+// setConfiguredLevel 由 Init 调用，设置配置文件中的级别
+func setConfiguredLevel(l zapcore.Level) {
+	levelState.mu.Lock()
+	defer levelState.mu.Unlock()
+	levelState.configured = l
+	level.SetLevel(l)
+}
+
+// SetLevel 立即修改日志级别。duration 大于 0 时到期后恢复为修改前的级别；
+// 在临时修改期间再次修改时，仍恢复为第一次临时修改之前的级别。
+// by 和 fields 记录在变更日志中。
+func SetLevel(l zapcore.Level, duration time.Duration, by string, fields ...zap.Field) LevelStatus {
+	levelState.mu.Lock()
+	defer levelState.mu.Unlock()
+
+	// 定时恢复在持有锁时清除 timer，timer 不为空说明临时修改还没有恢复
+	if levelState.timer != nil {
+		levelState.timer.Stop()
+	} else {
+		levelState.revertTo = level.Level()
+	}
+	levelState.timer = nil
+	levelState.revertAt = time.Time{}
+
+	if duration > 0 {
+		revertTo := levelState.revertTo
+		levelState.revertAt = time.Now().Add(duration)
+		var timer *time.Timer
+		timer = time.AfterFunc(duration, func() {
+			levelState.mu.Lock()
+			defer levelState.mu.Unlock()
+			if levelState.timer != timer {
+				return // 已被之后的修改取代
+			}
+			levelState.timer = nil
+			levelState.revertAt = time.Time{}
+			changeLevel(revertTo, "timer")
+		})
+		levelState.timer = timer
+	}
+
+	changeLevel(l, by, append(fields, zap.Duration("duration", duration))...)
+	return statusLocked()
+}
+
+// changeLevel 修改级别并记录变更。日志在两个级别中较详细的那个生效时写出，
+// 调高到 warn 以上之前也能留下记录。调用时持有 levelState.mu。
+func changeLevel(l zapcore.Level, by string, fields ...zap.Field) {
+	from := level.Level()
+	fields = append([]zap.Field{
+		zap.String("from", from.String()),
+		zap.String("to", l.String()),
+		zap.String("changed_by", by),
+	}, fields...)
+
+	if l < from {
+		level.SetLevel(l)
+		logLevelChange(fields)
+	} else {
+		logLevelChange(fields)
+		level.SetLevel(l)
+	}
+	levelState.changedBy = by
+	levelState.changedAt = time.Now()
+}
+
+// logLevelChange 不经过 GetLogger，避免在未初始化时由 Init 重入 levelState.mu
+func logLevelChange(fields []zap.Field) {
+	if Log != nil {
+		Log.Warn("Log level changed", fields...)
+	}
+}
+
+// GetLevelStatus 返回当前日志级别和临时修改的恢复时间
+func GetLevelStatus() LevelStatus {
+	levelState.mu.Lock()
+	defer levelState.mu.Unlock()
+	return statusLocked()
+}
+
+func statusLocked() LevelStatus {
+	status := LevelStatus{
+		Level:      level.Level().String(),
+		Configured: levelState.configured.String(),
+		ChangedBy:  levelState.changedBy,
+	}
+	if !levelState.revertAt.IsZero() {
+		revertAt := levelState.revertAt
+		status.RevertAt = &revertAt
+		status.RevertTo = levelState.revertTo.String()
+	}
+	if !levelState.changedAt.IsZero() {
+		changedAt := levelState.changedAt
+		status.ChangedAt = &changedAt
+	}
+	return status
+}
+
+// ResetLevel 恢复为配置文件中的级别，并取消未到期的定时恢复
+func ResetLevel(by string, fields ...zap.Field) LevelStatus {
+	levelState.mu.Lock()
+	configured := levelState.configured
+	levelState.mu.Unlock()
+	return SetLevel(configured, 0, by, fields...)
+}
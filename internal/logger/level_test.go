@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// useLevel starts the test at the configured level info and restores it
+func useLevel(t *testing.T) {
+	t.Helper()
+	Log = zap.NewNop()
+	setConfiguredLevel(zapcore.InfoLevel)
+	t.Cleanup(func() { ResetLevel("test") })
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, want := range map[string]zapcore.Level{"debug": zapcore.DebugLevel, "info": zapcore.InfoLevel, "warn": zapcore.WarnLevel, "error": zapcore.ErrorLevel} {
+		if got, err := ParseLevel(name); got != want || err != nil {
+			t.Errorf("ParseLevel(%s) = %v, %v", name, got, err)
+		}
+	}
+	if got, err := ParseLevel("DEBUG"); err == nil || got != zapcore.InfoLevel {
+		t.Errorf("ParseLevel(DEBUG) = %v, %v, want info and an error", got, err)
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	useLevel(t)
+
+	status := SetLevel(zapcore.DebugLevel, 0, "api")
+	if status.Level != "debug" || status.Configured != "info" || status.ChangedBy != "api" || status.RevertAt != nil || status.ChangedAt == nil {
+		t.Errorf("status %+v", status)
+	}
+	if !level.Enabled(zapcore.DebugLevel) {
+		t.Error("debug entries still dropped")
+	}
+
+	status = ResetLevel("signal")
+	if status.Level != "info" || status.ChangedBy != "signal" || level.Enabled(zapcore.DebugLevel) {
+		t.Errorf("after reset %+v", status)
+	}
+}
+
+// A temporary change reverts to the level before the first temporary change,
+// even when it is changed again in between
+func TestSetLevelReverts(t *testing.T) {
+	useLevel(t)
+
+	SetLevel(zapcore.DebugLevel, time.Hour, "api")
+	status := SetLevel(zapcore.ErrorLevel, 50*time.Millisecond, "api")
+	if status.Level != "error" || status.RevertTo != "info" || status.RevertAt == nil {
+		t.Fatalf("status %+v", status)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for GetLevelStatus().ChangedBy != "timer" {
+		if time.Now().After(deadline) {
+			t.Fatalf("level not reverted: %+v", GetLevelStatus())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status := GetLevelStatus(); status.Level != "info" || status.RevertAt != nil {
+		t.Errorf("after revert %+v", status)
+	}
+
+	// A permanent change cancels a pending revert
+	SetLevel(zapcore.DebugLevel, 20*time.Millisecond, "api")
+	SetLevel(zapcore.WarnLevel, 0, "api")
+	time.Sleep(100 * time.Millisecond)
+	if status := GetLevelStatus(); status.Level != "warn" || status.ChangedBy != "api" {
+		t.Errorf("permanent change reverted: %+v", status)
+	}
+}
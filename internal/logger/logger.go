@@ -10,21 +10,10 @@ import (
 var Log *zap.Logger
 
 // Init 初始化日志系统
-func Init(level string, output string) error {
-	// 解析日志级别
-	var zapLevel zapcore.Level
-	switch level {
-	case "debug":
-		zapLevel = zapcore.DebugLevel
-	case "info":
-		zapLevel = zapcore.InfoLevel
-	case "warn":
-		zapLevel = zapcore.WarnLevel
-	case "error":
-		zapLevel = zapcore.ErrorLevel
-	default:
-		zapLevel = zapcore.InfoLevel
-	}
+func Init(levelName string, output string) error {
+	// 解析日志级别，无效时使用 info；级别可以在运行时通过 SetLevel 修改
+	zapLevel, _ := ParseLevel(levelName)
+	setConfiguredLevel(zapLevel)
 
 	// 编码器配置
 	encoderConfig := zapcore.EncoderConfig{
@@ -61,7 +50,7 @@ func Init(level string, output string) error {
 	core := zapcore.NewCore(
 		zapcore.NewJSONEncoder(encoderConfig),
 		writer,
-		zapLevel,
+		level,
 	)
 
 	// 创建 Logger
@@ -72,7 +61,10 @@ func Init(level string, output string) error {
 
 // InitDevelopment 初始化开发模式日志（格式化输出）
 func InitDevelopment() {
-	Log, _ = zap.NewDevelopment()
+	setConfiguredLevel(zapcore.DebugLevel)
+	cfg := zap.NewDevelopmentConfig()
+	cfg.Level = level
+	Log, _ = cfg.Build()
 }
 
 // GetLogger 获取日志实例
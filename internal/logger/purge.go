@@ -0,0 +1,251 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// PurgeRedacted replaces the matched text when entries are redacted instead of removed
+const PurgeRedacted = "[REDACTED]"
+
+// PurgeFilter selects the check log entries to purge. An entry matches when it
+// passes the target and time filters and Pattern matches any of its text
+// fields (target name, address, message, request and response).
+type PurgeFilter struct {
+	TargetID  *int
+	StartTime *time.Time
+	EndTime   *time.Time
+	Pattern   *regexp.Regexp // nil matches every entry
+}
+
+// MatchesTime reports whether the target and time filters select an entry
+func (f *PurgeFilter) MatchesTime(targetID int, ts time.Time) bool {
+	if f.TargetID != nil && targetID != *f.TargetID {
+		return false
+	}
+	if f.StartTime != nil && ts.Before(*f.StartTime) {
+		return false
+	}
+	if f.EndTime != nil && ts.After(*f.EndTime) {
+		return false
+	}
+	return true
+}
+
+// MatchesText reports whether Pattern matches any string in the values,
+// descending into maps and slices decoded from JSON
+func (f *PurgeFilter) MatchesText(values ...interface{}) bool {
+	if f.Pattern == nil {
+		return true
+	}
+	for _, v := range values {
+		if matchStrings(f.Pattern, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchStrings(re *regexp.Regexp, v interface{}) bool {
+	switch v := v.(type) {
+	case string:
+		return re.MatchString(v)
+	case map[string]interface{}:
+		for _, item := range v {
+			if matchStrings(re, item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if matchStrings(re, item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func redactStrings(re *regexp.Regexp, v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return re.ReplaceAllString(v, PurgeRedacted)
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = redactStrings(re, item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactStrings(re, item)
+		}
+	}
+	return v
+}
+
+func (f *PurgeFilter) matchesEntry(entry *CheckLogEntry) bool {
+	return f.MatchesTime(entry.TargetID, entry.Timestamp) &&
+		f.MatchesText(entry.TargetName, entry.Address, entry.Message, entry.Request, entry.Response)
+}
+
+// FilePurgeResult counts of a file log purge
+type FilePurgeResult struct {
+	FilesScanned   int      `json:"files_scanned"`
+	FilesRewritten int      `json:"files_rewritten"`
+	Removed        int      `json:"removed"`
+	Redacted       int      `json:"redacted"`
+	Errors         []string `json:"errors,omitempty"`
+}
+
+// PurgeCheckLogs removes (or, with redact, rewrites with the matched text
+// replaced) the matching entries of every daily log file. Each file is
+// replaced atomically while check results are held back, so results written
+// during the purge are kept. Lines that cannot be parsed are only matched
+// against Pattern, whatever the target and time filters.
+func PurgeCheckLogs(ctx context.Context, filter *PurgeFilter, redact bool) (FilePurgeResult, error) {
+	var result FilePurgeResult
+
+	logFileMutex.Lock()
+	logDir := fileLogState.dir
+	logFileMutex.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(logDir, "check-*.jsonl"))
+	if err != nil {
+		return result, err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if !filter.mayContain(path) {
+			continue
+		}
+		result.FilesScanned++
+
+		removed, redacted, err := purgeLogFile(path, filter, redact)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", filepath.Base(path), err))
+			continue
+		}
+		if removed+redacted > 0 {
+			result.FilesRewritten++
+			result.Removed += removed
+			result.Redacted += redacted
+		}
+	}
+	return result, nil
+}
+
+// mayContain skips daily files outside the time filter; a day of margin on
+// each side covers entries written just after midnight
+func (f *PurgeFilter) mayContain(path string) bool {
+	var y, m, d int
+	if _, err := fmt.Sscanf(filepath.Base(path), "check-%04d-%02d-%02d.jsonl", &y, &m, &d); err != nil {
+		return true
+	}
+	day := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.Local)
+	if f.StartTime != nil && day.AddDate(0, 0, 2).Before(*f.StartTime) {
+		return false
+	}
+	if f.EndTime != nil && day.AddDate(0, 0, -1).After(*f.EndTime) {
+		return false
+	}
+	return true
+}
+
+// purgeLogFile rewrites one file holding logFileMutex; nothing is written when no line matches
+func purgeLogFile(path string, filter *PurgeFilter, redact bool) (removed, redacted int, err error) {
+	logFileMutex.Lock()
+	defer logFileMutex.Unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var entry CheckLogEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			// 无法解析的行不知道目标和时间，只要正则匹配原文就处理
+			switch {
+			case filter.Pattern == nil || !filter.Pattern.Match(line):
+				out.Write(line)
+				out.WriteByte('\n')
+			case redact:
+				out.Write(filter.Pattern.ReplaceAll(line, []byte(PurgeRedacted)))
+				out.WriteByte('\n')
+				redacted++
+			default:
+				removed++
+			}
+			continue
+		}
+		if !filter.matchesEntry(&entry) {
+			out.Write(line)
+			out.WriteByte('\n')
+			continue
+		}
+		if !redact {
+			removed++
+			continue
+		}
+
+		if filter.Pattern != nil {
+			entry.TargetName = filter.Pattern.ReplaceAllString(entry.TargetName, PurgeRedacted)
+			entry.Address = filter.Pattern.ReplaceAllString(entry.Address, PurgeRedacted)
+			entry.Message = filter.Pattern.ReplaceAllString(entry.Message, PurgeRedacted)
+			redactStrings(filter.Pattern, entry.Request)
+			redactStrings(filter.Pattern, entry.Response)
+		}
+		rewritten, err := json.Marshal(&entry)
+		if err != nil {
+			return 0, 0, err
+		}
+		out.Write(rewritten)
+		out.WriteByte('\n')
+		redacted++
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+	if removed+redacted == 0 {
+		return 0, 0, nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".purge-*")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(out.Bytes()); err != nil {
+		tmp.Close()
+		return 0, 0, err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return 0, 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, 0, err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return 0, 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, 0, err
+	}
+	return removed, redacted, nil
+}
@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// purgeLines is one day of check logs: target 1 leaks a token once inside and
+// once outside the time filter, target 2 leaks it too, and two lines can't be
+// parsed, one of them with the token
+var purgeLines = []string{
+	`{"timestamp":"2026-03-01T12:00:00Z","target_id":1,"target_name":"api","status":"down","message":"auth failed for token secret-abc"}`,
+	`{"timestamp":"2026-03-01T12:01:00Z","target_id":1,"target_name":"api","status":"up","message":"ok"}`,
+	`{"timestamp":"2026-03-01T15:00:00Z","target_id":1,"target_name":"api","status":"down","message":"auth failed for token secret-def"}`,
+	`{"timestamp":"2026-03-01T12:02:00Z","target_id":2,"target_name":"web","status":"down","message":"token secret-ghi"}`,
+	`{"target_id":1,"message":"secret-jkl"`,
+	`not json`,
+}
+
+func writePurgeFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(strings.Join(purgeLines, "\n")+"\n"), 0644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func readPurgeFile(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func purgeFilter() *PurgeFilter {
+	targetID := 1
+	start := epoch.Add(-time.Hour)
+	end := epoch.Add(time.Hour)
+	return &PurgeFilter{TargetID: &targetID, StartTime: &start, EndTime: &end, Pattern: regexp.MustCompile(`secret-\w+`)}
+}
+
+// Removing drops the matching entry of target 1 in range and the unparseable
+// line with the token; the other lines are kept as they were
+func TestPurgeCheckLogsRemove(t *testing.T) {
+	useFakeFileLog(t)
+	dir := t.TempDir()
+	if err := InitLogFileLog(dir); err != nil {
+		t.Fatalf("InitLogFileLog: %v", err)
+	}
+	path := writePurgeFile(t, dir, "check-2026-03-01.jsonl")
+
+	result, err := PurgeCheckLogs(context.Background(), purgeFilter(), false)
+	if err != nil {
+		t.Fatalf("PurgeCheckLogs: %v", err)
+	}
+	if result.FilesScanned != 1 || result.FilesRewritten != 1 || result.Removed != 2 || result.Redacted != 0 || len(result.Errors) != 0 {
+		t.Errorf("result = %+v, want 2 entries removed from 1 file", result)
+	}
+	want := []string{purgeLines[1], purgeLines[2], purgeLines[3], purgeLines[5]}
+	if got := readPurgeFile(t, path); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("file after remove:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Nothing left to purge: the file is not rewritten
+	result, err = PurgeCheckLogs(context.Background(), purgeFilter(), false)
+	if err != nil || result.FilesRewritten != 0 || result.Removed != 0 {
+		t.Errorf("second purge = %+v, %v, want nothing removed", result, err)
+	}
+}
+
+// Redacting keeps every line and replaces only the matched text, in the
+// parsed entry and in the unparseable line
+func TestPurgeLogFileRedact(t *testing.T) {
+	useFakeFileLog(t)
+	path := writePurgeFile(t, t.TempDir(), "check-2026-03-01.jsonl")
+
+	removed, redacted, err := purgeLogFile(path, purgeFilter(), true)
+	if err != nil {
+		t.Fatalf("purgeLogFile: %v", err)
+	}
+	if removed != 0 || redacted != 2 {
+		t.Errorf("removed %d, redacted %d, want 0 and 2", removed, redacted)
+	}
+	got := readPurgeFile(t, path)
+	if len(got) != len(purgeLines) {
+		t.Fatalf("%d lines after redact, want %d", len(got), len(purgeLines))
+	}
+	if !strings.Contains(got[0], "auth failed for token "+PurgeRedacted) || strings.Contains(got[0], "secret-abc") {
+		t.Errorf("entry in range = %s, want the token redacted", got[0])
+	}
+	if want := `{"target_id":1,"message":"` + PurgeRedacted + `"`; got[4] != want {
+		t.Errorf("unparseable line = %s, want %s", got[4], want)
+	}
+	for _, i := range []int{1, 2, 3, 5} {
+		if got[i] != purgeLines[i] {
+			t.Errorf("line %d = %s, want it unchanged", i, got[i])
+		}
+	}
+}
+
+// Daily files more than a day outside the time filter are not read
+func TestPurgeFilterMayContain(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	end := time.Date(2026, 3, 3, 12, 0, 0, 0, time.Local)
+	filter := &PurgeFilter{StartTime: &start, EndTime: &end}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"check-2026-02-27.jsonl", false},
+		{"check-2026-02-28.jsonl", true},
+		{"check-2026-03-02.jsonl", true},
+		{"check-2026-03-04.jsonl", true},
+		{"check-2026-03-05.jsonl", false},
+		{"check-old.jsonl", true},
+	}
+	for _, tt := range tests {
+		if got := filter.mayContain(tt.name); got != tt.want {
+			t.Errorf("mayContain(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if !(&PurgeFilter{}).mayContain("check-2000-01-01.jsonl") {
+		t.Error("a filter without times skipped a file")
+	}
+}
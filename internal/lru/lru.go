@@ -0,0 +1,72 @@
+package lru
+
+import "container/list"
+
+// Cache is a map bounded to a fixed number of entries. Adding to a full
+// cache evicts the least recently used entry. It is not safe for concurrent
+// use; callers hold their own lock.
+type Cache[K comparable, V any] struct {
+	max   int
+	order *list.List // front is the most recently used
+	items map[K]*list.Element
+}
+
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// New returns a cache holding at most max entries; max must be positive
+func New[K comparable, V any](max int) *Cache[K, V] {
+	if max < 1 {
+		panic("lru: max must be positive")
+	}
+	return &Cache[K, V]{max: max, order: list.New(), items: make(map[K]*list.Element)}
+}
+
+// Get returns the value of key and marks it as recently used
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	elem, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*entry[K, V]).value, true
+}
+
+// Add sets the value of key and marks it as recently used. It reports
+// whether another entry was evicted to make room.
+func (c *Cache[K, V]) Add(key K, value V) (evicted bool) {
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*entry[K, V]).value = value
+		c.order.MoveToFront(elem)
+		return false
+	}
+	c.items[key] = c.order.PushFront(&entry[K, V]{key: key, value: value})
+	if c.order.Len() <= c.max {
+		return false
+	}
+	oldest := c.order.Back()
+	c.order.Remove(oldest)
+	delete(c.items, oldest.Value.(*entry[K, V]).key)
+	return true
+}
+
+// Remove deletes key if present
+func (c *Cache[K, V]) Remove(key K) {
+	if elem, ok := c.items[key]; ok {
+		c.order.Remove(elem)
+		delete(c.items, key)
+	}
+}
+
+// Len returns the number of entries
+func (c *Cache[K, V]) Len() int {
+	return len(c.items)
+}
+
+// Cap returns the maximum number of entries
+func (c *Cache[K, V]) Cap() int {
+	return c.max
+}
@@ -0,0 +1,66 @@
+package lru
+
+import "testing"
+
+func TestEvictsLeastRecentlyUsed(t *testing.T) {
+	c := New[string, int](2)
+	c.Add("a", 1)
+	c.Add("b", 2)
+	// Reading a makes b the least recently used
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %d, %v", v, ok)
+	}
+	if !c.Add("c", 3) {
+		t.Error("Add to a full cache did not evict")
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Error("b was kept, want it evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("a was evicted, want it kept")
+	}
+	if c.Len() != 2 || c.Cap() != 2 {
+		t.Errorf("Len/Cap = %d/%d, want 2/2", c.Len(), c.Cap())
+	}
+}
+
+func TestAddExistingUpdates(t *testing.T) {
+	c := New[int, string](2)
+	c.Add(1, "one")
+	c.Add(2, "two")
+	if c.Add(1, "uno") {
+		t.Error("updating an entry evicted another")
+	}
+	if v, _ := c.Get(1); v != "uno" {
+		t.Errorf("Get(1) = %q, want uno", v)
+	}
+	// The update made 1 recently used, so 2 goes first
+	c.Add(3, "three")
+	if _, ok := c.Get(2); ok {
+		t.Error("2 was kept, want it evicted")
+	}
+}
+
+func TestRemove(t *testing.T) {
+	c := New[int, int](2)
+	c.Add(1, 1)
+	c.Remove(1)
+	c.Remove(2)
+	if _, ok := c.Get(1); ok || c.Len() != 0 {
+		t.Errorf("removed entry still present, Len = %d", c.Len())
+	}
+	c.Add(2, 2)
+	c.Add(3, 3)
+	if c.Len() != 2 {
+		t.Errorf("Len = %d after refilling, want 2", c.Len())
+	}
+}
+
+func TestNewRejectsZero(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("New(0) did not panic")
+		}
+	}()
+	New[int, int](0)
+}
@@ -9,6 +9,11 @@ type AlertChannel struct {
 	Type      string    `gorm:"size:50;not null" json:"type"` // email, webhook, dingtalk, wechat
 	Enabled   bool      `gorm:"default:true" json:"enabled"`
 	Config    string    `gorm:"type:text;not null" json:"config"` // JSON string
+	// Delivery health, maintained by the alert service
+	Health          string     `gorm:"size:20;default:healthy" json:"health"`              // healthy, degraded
+	HealthReason    string     `gorm:"size:255" json:"health_reason,omitempty"`            // why the channel was degraded
+	HealthChangedAt *time.Time `json:"health_changed_at,omitempty"`                        // last change between healthy and degraded
+	SuppressHealth  bool       `gorm:"default:false" json:"suppress_health"`               // never degrade, for receivers that are intermittent on purpose
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -22,19 +27,25 @@ type AlertRule struct {
 	ID             uint   `gorm:"primaryKey" json:"id"`
 	TargetID       uint32 `gorm:"not null" json:"target_id"`           // Associated monitor target
 	ChannelID      uint   `gorm:"not null" json:"channel_id"`           // Alert channel
-	ThresholdType  string `gorm:"size:20" json:"threshold_type"`        // failure_count, response_time
+	ThresholdType  string `gorm:"size:20" json:"threshold_type"`        // failure_count, response_time, status_change, degraded, divergence, content_changed, jitter
 	ThresholdValue int    `json:"threshold_value"`                      // Threshold value
 	Enabled        bool   `gorm:"default:true" json:"enabled"`          // Is enabled
 	// Advanced fields
 	ConditionLogic string `gorm:"type:text" json:"condition_logic"` // JSON: complex conditions with operators
 	CooldownSeconds int   `gorm:"default:300" json:"cooldown_seconds"` // Cooldown between alerts
-	LastAlertTime   time.Time `json:"last_alert_time"`
+	LastAlertTime   *time.Time `gorm:"column:last_alert_time" json:"last_alert_time,omitempty"` // Last dispatched alert, drives the cooldown
+	AlertOpen       bool       `gorm:"default:false" json:"alert_open"`                           // An alert was sent and the target has not recovered yet
+	AlertCount      int        `gorm:"default:0" json:"alert_count"`                              // Alerts sent while the current alert is open
+	SnoozedUntil    *time.Time `json:"snoozed_until,omitempty"`                                   // Alerts are suppressed until then; cleared when the snooze is cancelled
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 
 	// Relationships for loading
 	Conditions []AlertCondition `gorm:"foreignKey:RuleID" json:"conditions,omitempty"`
 	Groups     []AlertRuleGroup  `gorm:"foreignKey:RuleID" json:"groups,omitempty"`
+
+	// Active snooze, filled in by the API
+	Snooze *AlertRuleSnooze `gorm:"-" json:"snooze,omitempty"`
 }
 
 func (AlertRule) TableName() string {
@@ -50,10 +61,29 @@ type AlertHistory struct {
 	Severity    string    `gorm:"size:50" json:"severity"`
 	Status      string    `gorm:"size:50" json:"status"`
 	Message     string    `gorm:"type:text" json:"message"`
+	Synthetic   bool      `gorm:"default:false" json:"synthetic"` // Triggered by an injected synthetic result
+	SnoozeID    uint      `gorm:"default:0" json:"snooze_id,omitempty"` // The snooze that suppressed the alert, or that the event is about
 	SentAt      time.Time `json:"sent_at"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
 func (AlertHistory) TableName() string {
 	return "alert_history"
-}
\ No newline at end of file
+}
+
+// AlertRuleSnooze 告警规则的临时静默；过期或取消后保留作为记录
+type AlertRuleSnooze struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	RuleID      uint       `gorm:"not null;index" json:"rule_id"`
+	TargetID    uint32     `gorm:"not null" json:"target_id"`
+	Note        string     `gorm:"type:text" json:"note,omitempty"`
+	StartsAt    time.Time  `json:"starts_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"` // Ended early, by a cancel or a newer snooze
+	CreatedBy   string     `gorm:"size:100" json:"created_by,omitempty"` // Client IP of the request
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (AlertRuleSnooze) TableName() string {
+	return "alert_rule_snoozes"
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// DiscoveryCandidate 发现任务找到的开放端口，审核通过后才创建监控。
+// 拒绝的候选保留下来，之后的扫描不再列出同一主机和端口。
+type DiscoveryCandidate struct {
+	ID          uint32     `gorm:"primaryKey" json:"id"`
+	ScanID      string     `gorm:"size:16;index" json:"scan_id"`                     // 首次发现它的扫描
+	Fingerprint string     `gorm:"size:300;not null;uniqueIndex" json:"fingerprint"` // 小写的 host:port，用于去重
+	Source      string     `gorm:"size:255" json:"source"`                           // 区域名或网段
+	Name        string     `gorm:"size:255;not null" json:"name"`                    // 来自 PTR 记录或区域中的主机名
+	Type        string     `gorm:"size:20;not null" json:"type"`                     // 按端口和协议标语猜测的监控类型
+	Address     string     `gorm:"size:500;not null" json:"address"`                 // 创建监控时使用的地址
+	Port        int        `json:"port"`
+	IP          string     `gorm:"size:45" json:"ip"` // 探测时连接的地址
+	Banner      string     `gorm:"size:255" json:"banner,omitempty"`
+	Status      string     `gorm:"size:20;not null;default:pending;index" json:"status"` // pending/approved/rejected
+	TargetID    *uint32    `json:"target_id,omitempty"`                                  // 审核通过后创建的监控
+	ReviewedBy  string     `gorm:"size:100" json:"reviewed_by,omitempty"`                // 审核请求的客户端 IP
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (DiscoveryCandidate) TableName() string {
+	return "discovery_candidates"
+}
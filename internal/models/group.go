@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// MonitorGroup 监控分组，监控通过 group_id 归入分组；删除分组时其中的监控变为未分组
+type MonitorGroup struct {
+	ID          uint32    `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:255;not null;uniqueIndex" json:"name"`
+	Description string    `gorm:"size:500" json:"description"`
+	SortOrder   int       `gorm:"default:0" json:"sort_order"` // 小的排在前面，相同时按名称
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (MonitorGroup) TableName() string {
+	return "monitor_groups"
+}
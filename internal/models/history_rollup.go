@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// HistoryRollup aggregates the history of a target over one bucket, for
+// long-range charts. Up counts up results, Degraded warning and degraded,
+// Down down and critical; the response times are those of the up, warning
+// and degraded results, nil without any.
+type HistoryRollup struct {
+	ID              uint      `gorm:"primaryKey" json:"-"`
+	TargetID        uint32    `gorm:"not null;index:,unique,composite:bucket,priority:1" json:"target_id"`
+	BucketStart     time.Time `gorm:"not null;index:,unique,composite:bucket,priority:2" json:"bucket_start"` // UTC hour or day
+	Checks          int64     `json:"checks"`
+	Up              int64     `json:"up"`
+	Down            int64     `json:"down"`
+	Degraded        int64     `json:"degraded"`
+	Samples         int64     `json:"samples"` // results with a response time
+	AvgResponseTime *float64  `json:"avg_response_time"`
+	MinResponseTime *int64    `json:"min_response_time"`
+	MaxResponseTime *int64    `json:"max_response_time"`
+	P50ResponseTime *int64    `json:"p50_response_time"`
+	P95ResponseTime *int64    `json:"p95_response_time"`
+	P99ResponseTime *int64    `json:"p99_response_time"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// MonitorHistoryHourly 每个监控每小时一行，由汇总任务从 monitor_history 计算
+type MonitorHistoryHourly struct {
+	HistoryRollup
+}
+
+func (MonitorHistoryHourly) TableName() string {
+	return "monitor_history_hourly"
+}
+
+// MonitorHistoryDaily 每个监控每天（UTC）一行，由汇总任务从小时汇总计算
+type MonitorHistoryDaily struct {
+	HistoryRollup
+}
+
+func (MonitorHistoryDaily) TableName() string {
+	return "monitor_history_daily"
+}
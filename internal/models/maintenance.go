@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// MaintenanceWindow 维护窗口：窗口内的检查结果记为 maintenance，不计入可用率，也不触发告警。
+// 窗口从 schedule（cron）的每次触发开始；未设置 schedule 时从 weekdays 各天的 start_time 开始。
+// 结束于之后第一个 end_time，end_time 不晚于开始时间时窗口跨过午夜。
+type MaintenanceWindow struct {
+	ID        uint32    `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"size:255;not null" json:"name"`
+	TargetID  *uint32   `gorm:"index" json:"target_id"`          // nil 表示所有监控
+	Schedule  string    `gorm:"size:100" json:"schedule"`        // cron 表达式，如 "0 2 * * sun"；设置后代替 weekdays 和 start_time
+	Weekdays  string    `gorm:"size:100" json:"weekdays"`        // 逗号分隔，如 "saturday,sunday"；留空为每天
+	StartTime string    `gorm:"size:5" json:"start_time"`        // HH:MM
+	EndTime   string    `gorm:"size:5;not null" json:"end_time"` // HH:MM
+	Timezone  string    `gorm:"size:64" json:"timezone"`         // IANA 时区；留空为服务器本地时区
+	Enabled   bool      `gorm:"default:true" json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (MaintenanceWindow) TableName() string {
+	return "maintenance_windows"
+}
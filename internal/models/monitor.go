@@ -13,20 +13,53 @@ type MonitorTarget struct {
 	Interval  int64  `gorm:"default:60" json:"interval"` // seconds
 	Metadata  string `gorm:"type:text" json:"metadata"`  // JSON string
 	Enabled   bool   `gorm:"default:true" json:"enabled"`
+	// Deadline of one check in seconds, at most Interval; 0 for the default of 30
+	TimeoutSeconds int `gorm:"default:0" json:"timeout_seconds"`
+	// Checks run again when one comes back down, before the result is saved
+	RetryCount           int `gorm:"default:0" json:"retry_count"`
+	RetryIntervalSeconds int `gorm:"default:0" json:"retry_interval_seconds"`
 
 	// HTTP/HTTPS specific fields
 	HTTPMethod         string `gorm:"size:10" json:"http_method"`          // GET, POST, PUT, DELETE, etc.
 	HTTPHeaders        string `gorm:"type:text" json:"http_headers"`       // JSON string
 	HTTPBody           string `gorm:"type:text" json:"http_body"`
 	ResolvedHost       string `gorm:"size:255" json:"resolved_host"`       // Custom host resolution
+	UnixSocketPath     string `gorm:"size:500" json:"unix_socket_path"`    // Dial this Unix socket instead of the URL host
+	ProxyURL           string `gorm:"size:500" json:"proxy_url"`           // http, https or socks5 proxy, without its password
+	ProxyPassword      string `gorm:"size:255" json:"-"`                   // Password of the proxy user, never returned by the API
 	FollowRedirects    bool   `gorm:"default:true" json:"follow_redirects"` // Follow 301/302 redirects
 	MaxRedirects       int    `gorm:"default:10" json:"max_redirects"`      // Maximum redirect depth
 	ExpectedStatusCodes string `gorm:"type:text" json:"expected_status_codes"` // Comma-separated status codes (e.g., "200,201,301,302")
+	ExpectedHeaders     string `gorm:"type:text" json:"expected_headers"`      // JSON object, header name to expected value, see monitor.HeaderAssertion
+	// Assertions on the decoded response body; a response failing one is down
+	BodyMustContain    string `gorm:"type:text" json:"body_must_contain"`
+	BodyMustNotContain string `gorm:"type:text" json:"body_must_not_contain"`
+	BodyRegex          string `gorm:"type:text" json:"body_regex"` // RE2 syntax
+	// Assertion on one field of a JSON response body
+	JSONPath          string `gorm:"size:500" json:"json_path"` // e.g. data.items[0].status
+	JSONExpectedValue string `gorm:"type:text" json:"json_expected_value"`
+	JSONOperator      string `gorm:"size:10" json:"json_operator"` // eq, ne, gt, lt, contains
+	// Requests of a multi-step transaction, sent instead of the single request
+	Steps string `gorm:"type:text" json:"steps"` // JSON array, see monitor.HTTPStep
+	// Content change detection: SHA-256 of the body, compared between checks
+	TrackContentHash     bool   `gorm:"default:false" json:"track_content_hash"`
+	ContentHashRegex     string `gorm:"type:text" json:"content_hash_regex"`         // RE2; only the matches are hashed
+	ContentHashNormalize bool   `gorm:"default:false" json:"content_hash_normalize"` // Drop scripts, styles and comments, collapse whitespace
+	// Request authentication; the secrets are never returned by the API
+	AuthType     string `gorm:"size:10" json:"auth_type"` // none, basic, bearer
+	AuthUsername string `gorm:"size:255" json:"auth_username"`
+	AuthPassword string `gorm:"size:255" json:"-"`
+	AuthToken    string `gorm:"type:text" json:"-"`
 
 	// DNS specific fields
 	DNSServer      string `gorm:"size:255" json:"dns_server"`       // DNS server address (e.g., 8.8.8.8:53)
 	DNSServerName  string `gorm:"size:255" json:"dns_server_name"`   // DNS server name (e.g., "Google DNS")
 	DNSServerType  string `gorm:"size:10" json:"dns_server_type"`   // DNS protocol: udp, tcp, doh, dot
+	DNSServers     string `gorm:"type:text" json:"dns_servers"`     // Comma-separated provider IDs or addresses queried together and compared
+	DNSConsensus   string `gorm:"size:20" json:"dns_consensus"`     // all, majority, any, propagation
+	DNSQueryType   string `gorm:"size:10" json:"dns_query_type"`    // A (default), AAAA, CNAME, MX, TXT, NS, SOA, CAA, PTR, SRV
+	DNSExpectedValues string `gorm:"type:text" json:"dns_expected_values"` // JSON array of the records the answer must have, see dns_match_mode
+	DNSMatchMode   string `gorm:"size:10" json:"dns_match_mode"`    // exact (default), subset
 
 	// PING specific fields
 	PingCount  int    `gorm:"default:4" json:"ping_count"`   // Number of ping packets to send
@@ -48,15 +81,66 @@ type MonitorTarget struct {
 	SNMPExpectedValue string `gorm:"size:255" json:"snmp_expected_value"` // Expected value for comparison
 	SNMPOperator     string `gorm:"size:10" json:"snmp_operator"`       // eq, ne, gt, lt, ge, le
 
+	// TCP exchange after the connect
+	TCPSendString   string `gorm:"type:text" json:"tcp_send_string"`
+	TCPExpectString string `gorm:"type:text" json:"tcp_expect_string"` // Text the reply must contain, /.../ for RE2
+	TCPUseTLS       bool   `gorm:"default:false" json:"tcp_use_tls"`   // TLS handshake before the exchange
+
+	// UDP probe: the datagram sent and the reply expected
+	UDPPreset         string `gorm:"size:20" json:"udp_preset"`            // dns, ntp, sip_options; replaces the payload
+	UDPSendPayload    string `gorm:"type:text" json:"udp_send_payload"`    // Encoded in UDPPayloadFormat
+	UDPPayloadFormat  string `gorm:"size:10" json:"udp_payload_format"`    // hex, base64
+	UDPExpectResponse bool   `gorm:"default:false" json:"udp_expect_response"`
+	UDPExpectPattern  string `gorm:"type:text" json:"udp_expect_pattern"` // RE2 the reply must match
+
 	// SSL/TLS certificate specific fields
 	SSLWarnDays    int    `gorm:"default:30" json:"ssl_warn_days"`    // Days before expiration to warn
 	SSLCriticalDays int   `gorm:"default:7" json:"ssl_critical_days"`  // Days before expiration to mark as critical
 	SSLGetChain    bool   `gorm:"default:true" json:"ssl_get_chain"`   // Get certificate chain information
 	SSLCheck       bool   `gorm:"default:false" json:"ssl_check"`     // Enable SSL/TLS certificate monitoring for HTTPS
+	// Client certificate and CA (PEM) for http, https and ssl; the key is never returned by the API
+	TLSClientCertPEM string `gorm:"type:text" json:"tls_client_cert_pem"`
+	TLSClientKeyPEM  string `gorm:"type:text" json:"-"`
+	TLSCACertPEM     string `gorm:"type:text" json:"tls_ca_cert_pem"` // Replaces the system roots
+	TLSSkipVerify    bool   `gorm:"default:false" json:"tls_skip_verify"` // Don't verify the server certificate
+	TLSPinSHA256     string `gorm:"size:64" json:"tls_pin_sha256"`        // Expected SHA-256 of the leaf certificate, lowercase hex
+
+	// Script specific fields
+	ScriptPath    string `gorm:"size:500" json:"script_path"`      // Executable, must be in monitor.script.allowed_paths
+	ScriptArgs    string `gorm:"type:text" json:"script_args"`     // JSON array of arguments, passed without a shell
+	ScriptTimeout int    `gorm:"default:10" json:"script_timeout"` // Seconds
+
+	// Comparison mode (http, https, tcp): SecondaryAddress is checked alongside
+	// Address every interval and the two results are compared
+	SecondaryAddress        string `gorm:"size:500" json:"secondary_address"`          // e.g. the new load balancer during a migration
+	CompareLatencyTolerance int    `gorm:"default:0" json:"compare_latency_tolerance"` // Milliseconds; 0 does not compare latency
+	CompareBody             bool   `gorm:"default:false" json:"compare_body"`          // Also compare the SHA-256 of the response bodies
+
+	// Response time thresholds (http, https, tcp, dns) in milliseconds, 0 for
+	// none: a successful check this slow is stored as degraded or down
+	DegradedThresholdMs int `gorm:"default:0" json:"degraded_threshold_ms"`
+	DownThresholdMs     int `gorm:"default:0" json:"down_threshold_ms"`
 
 	// Alert channels association
 	AlertChannelIDs string `gorm:"type:text" json:"alert_channel_ids"` // JSON array of alert channel IDs
 
+	// Operator notes, shown to whoever gets paged; they do not affect checking
+	Notes      string `gorm:"type:text" json:"notes"`       // Markdown
+	RunbookURL string `gorm:"size:500" json:"runbook_url"` // Link to the runbook
+
+	// Result sinks: "" for all, "none", or comma-separated db_history, es, file
+	Sinks string `gorm:"size:100" json:"sinks"`
+
+	// Tags for filtering and grouping: JSON array, lowercased and sorted
+	Tags string `gorm:"type:text" json:"tags"`
+
+	// Group the monitor belongs to, nil when ungrouped; see MonitorGroup
+	GroupID *uint32 `gorm:"index" json:"group_id"`
+
+	// Monitor this one depends on, e.g. the uplink of a site. While it is
+	// down, down results of this monitor are stored as suppressed and do not alert.
+	DependsOnTargetID *uint32 `gorm:"index" json:"depends_on_target_id"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -73,6 +157,16 @@ type MonitorStatus struct {
 	Message        string `gorm:"type:text" json:"message"`
 	CheckedAt      time.Time `gorm:"index" json:"checked_at"`
 	UptimePercentage int32  `gorm:"default:0" json:"uptime_percentage"`
+	LastStatusChangeAt *time.Time `gorm:"column:last_status_change_at" json:"last_status_change_at,omitempty"` // When the status last flipped
+	Synthetic          bool       `gorm:"default:false" json:"synthetic"`                                        // Current status comes from an injected synthetic result
+	Flapping           bool       `gorm:"default:false" json:"flapping"`                                         // The status keeps changing, see alert.flapping
+	SuppressedBy       *uint32    `json:"suppressed_by,omitempty"`                                               // Down while the monitor it depends on was down; no alert was sent
+
+	// Uptime in percent with two decimals over the last 24 hours, 7 and 30 days;
+	// warning and degraded count as up. uptime_percentage is uptime_30d in whole percent.
+	Uptime24h float64 `gorm:"column:uptime_24h;default:0" json:"uptime_24h"`
+	Uptime7d  float64 `gorm:"column:uptime_7d;default:0" json:"uptime_7d"`
+	Uptime30d float64 `gorm:"column:uptime_30d;default:0" json:"uptime_30d"`
 
 	// SSL Certificate info
 	SSLDaysUntilExpiry *int    `gorm:"column:ssl_days_until_expiry" json:"ssl_days_until_expiry,omitempty"`
@@ -84,10 +178,13 @@ type MonitorStatus struct {
 	DNSRecords *string `gorm:"column:dns_records;type:text" json:"dns_records,omitempty"` // JSON string of DNS records
 	ResolvedIP *string `gorm:"column:resolved_ip;size:64" json:"resolved_ip,omitempty"`  // Resolved IP address
 
+	// SHA-256 of the last body hashed, see track_content_hash
+	ContentHash string `gorm:"column:content_hash;size:64" json:"content_hash,omitempty"`
+
 	// Additional check data (JSON string)
 	Data *string `gorm:"column:data;type:text" json:"data,omitempty"` // Full check result data including certificate chain, etc.
 
-	Target MonitorTarget `gorm:"foreignKey:TargetID" json:"target,omitempty"`
+	Target *MonitorTarget `gorm:"foreignKey:TargetID" json:"-"` // Loaded with Preload("Target"); nil if the target was deleted
 }
 
 func (MonitorStatus) TableName() string {
@@ -96,17 +193,109 @@ func (MonitorStatus) TableName() string {
 
 type MonitorHistory struct {
 	ID         uint   `gorm:"primaryKey" json:"id"`
-	TargetID   uint32 `gorm:"not null;index" json:"target_id"`
+	// (target_id, checked_at) serves the per-target queries by time: uptime, recent statuses, the heatmap
+	TargetID   uint32 `gorm:"not null;index:idx_monitor_history_target_checked,priority:1" json:"target_id"`
 	Status     string `gorm:"size:50;not null" json:"status"`
 	ResponseTime int64 `json:"response_time"`
 	Message    string `gorm:"type:text" json:"message"`
-	CheckedAt  time.Time `gorm:"index" json:"checked_at"`
+	Synthetic  bool   `gorm:"default:false;index" json:"synthetic"` // Injected by the failure injection endpoint
+	Address    string `gorm:"size:500" json:"address,omitempty"`   // Address that produced the result; set in comparison mode only
+	Divergence bool   `gorm:"default:false" json:"divergence"`     // The secondary address disagreed, see comparison mode
+	ContentChanged bool `gorm:"default:false" json:"content_changed"` // The body hash differed from the previous one, see track_content_hash
+	Jitter     *float64 `json:"jitter,omitempty"`                     // ping: mean difference between consecutive round trip times, milliseconds
+	RTTStdDev  *float64 `gorm:"column:rtt_stddev" json:"rtt_stddev,omitempty"` // ping: standard deviation of the round trip times, milliseconds
+	SuppressedBy *uint32 `json:"suppressed_by,omitempty"`          // Monitor it depends on, down at the time; no alert was sent
+	CheckedAt  time.Time `gorm:"index;index:idx_monitor_history_target_checked,priority:2" json:"checked_at"`
+	// Same as the Elasticsearch document ID of the check, so a result replayed
+	// from the spool is not written twice; NULL for rows from before it was added
+	CheckID *string `gorm:"size:64;uniqueIndex" json:"check_id,omitempty"`
 }
 
 func (MonitorHistory) TableName() string {
 	return "monitor_history"
 }
 
+// ResponseArchive is the full response of the check that turned a target from
+// up to down, kept so the error page can be read after the fact
+type ResponseArchive struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	TargetID         uint32    `gorm:"not null;index" json:"target_id"`
+	HistoryID        uint      `gorm:"index" json:"history_id,omitempty"` // History row of the down result; 0 if history is not written
+	CheckedAt        time.Time `gorm:"index" json:"checked_at"`
+	Message          string    `gorm:"type:text" json:"message"`
+	Method           string    `gorm:"size:10" json:"method"`
+	URL              string    `gorm:"size:500" json:"url"`
+	RequestHeaders   string    `gorm:"type:text" json:"-"` // JSON object
+	StatusCode       int       `json:"status_code"`
+	ResponseHeaders  string    `gorm:"type:text" json:"-"` // JSON object
+	ResponseTime     int64     `json:"response_time"`      // milliseconds
+	ContentLength    int64     `json:"content_length"`
+	BytesReceived    int64     `json:"bytes_received"`
+	DecodedBodyBytes int64     `json:"decoded_body_bytes"`
+	Body             []byte    `json:"-"`                              // Decoded body, up to the archive size cap
+	Truncated        bool      `gorm:"default:false" json:"truncated"` // Body was cut at the size cap
+}
+func (ResponseArchive) TableName() string {
+	return "response_archives"
+}
+
+// HistoryExport is one object written by the history archiver. Exports are
+// contiguous ID ranges; rows up to the end of the last verified export may be
+// deleted by the retention sweep.
+type HistoryExport struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Source     string     `gorm:"size:50;not null;index" json:"source"` // exported table, monitor_history
+	FromID     uint       `json:"from_id"`                              // first row ID in the object
+	ToID       uint       `json:"to_id"`                                // last row ID in the object
+	Rows       int        `json:"rows"`
+	ObjectKey  string     `gorm:"size:500" json:"object_key"`
+	Bytes      int64      `json:"bytes"`                               // compressed size
+	SHA256     string     `gorm:"column:sha256;size:64" json:"sha256"` // of the compressed object
+	Status     string     `gorm:"size:20;index" json:"status"`         // verified, failed
+	Error      string     `gorm:"type:text" json:"error,omitempty"`
+	ExportedAt time.Time  `json:"exported_at"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+}
+
+func (HistoryExport) TableName() string {
+	return "history_exports"
+}
+
+// Certificate is a leaf certificate presented to a check, identified by the
+// SHA-256 fingerprint of its DER encoding. Targets that are served the same
+// certificate (e.g. one host on ports 443, 465 and 993) share the row.
+type Certificate struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Fingerprint string    `gorm:"size:64;uniqueIndex;not null" json:"fingerprint"` // lowercase hex
+	Subject     string    `gorm:"size:255" json:"subject"`                         // common name
+	DNSNames    string    `gorm:"column:dns_names;type:text" json:"-"`             // JSON array of the subject alternative names
+	Issuer      string    `gorm:"size:255" json:"issuer"`
+	Serial      string    `gorm:"size:128" json:"serial"`
+	NotBefore   time.Time `json:"not_before"`
+	NotAfter    time.Time `gorm:"index" json:"not_after"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+func (Certificate) TableName() string {
+	return "certificates"
+}
+
+// CertificateTarget records that a target was served a certificate. Current
+// marks the certificate the target presented in its latest check.
+type CertificateTarget struct {
+	ID            uint      `gorm:"primaryKey" json:"-"`
+	CertificateID uint      `gorm:"not null;uniqueIndex:idx_certificate_target" json:"certificate_id"`
+	TargetID      uint32    `gorm:"not null;uniqueIndex:idx_certificate_target;index" json:"target_id"`
+	Current       bool      `gorm:"column:is_current;default:false;index" json:"current"`
+	FirstSeen     time.Time `json:"first_seen"`
+	LastSeen      time.Time `json:"last_seen"`
+}
+
+func (CertificateTarget) TableName() string {
+	return "certificate_targets"
+}
+
 type IPGeoCache struct {
 	ID        uint   `gorm:"primaryKey" json:"id"`
 	IP        string `gorm:"size:45;uniqueIndex;not null" json:"ip"`
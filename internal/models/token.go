@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// APIToken 只能读取一个监控（或带有某个标签的监控）的状态、历史和徽章的令牌，用于嵌入第三方页面
+type APIToken struct {
+	ID         uint32     `gorm:"primaryKey" json:"id"`
+	Name       string     `gorm:"size:255;not null" json:"name"`
+	TokenHash  string     `gorm:"size:64;not null;uniqueIndex" json:"-"` // SHA-256 of the token, which is only shown when created
+	Prefix     string     `gorm:"size:16" json:"prefix"`                 // Start of the token, to tell tokens apart
+	TargetID   uint32     `gorm:"not null;index" json:"target_id"`       // The only target the token can read; 0 for a tag token
+	Tag        string     `gorm:"size:64;index" json:"tag,omitempty"`    // The token reads the targets with this tag instead
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	UseCount   int64      `gorm:"default:0" json:"use_count"`           // Requests accepted with the token
+	CreatedBy  string     `gorm:"size:100" json:"created_by,omitempty"` // Client IP of the request
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
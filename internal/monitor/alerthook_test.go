@@ -0,0 +1,40 @@
+package monitor
+
+import (
+	"sync"
+	"testing"
+)
+
+// The alert handler gets each saved result with the status before it, but
+// not the results of a maintenance window
+func TestAlertHandlerGetsSavedResults(t *testing.T) {
+	s := newTestService(t)
+	var mu sync.Mutex
+	var calls [][2]string
+	s.SetAlertHandler(func(target *MonitorTarget, result *CheckResult, previousStatus string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, [2]string{previousStatus, result.Status})
+	})
+	target := &MonitorTarget{ID: 1, Name: "db", Type: "tcp", Address: "127.0.0.1", Port: 1, Interval: 3600}
+	if err := s.AddTarget(target); err != nil {
+		t.Fatalf("AddTarget: %v", err)
+	}
+	s.SetSinks(target.ID, Sinks{SinkDBHistory})
+
+	for _, status := range []string{"up", "down", StatusMaintenance, "up"} {
+		s.saveResult(target, &CheckResult{Status: status, Message: status})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := [][2]string{{"", "up"}, {"up", "down"}, {StatusMaintenance, "up"}}
+	if len(calls) != len(want) {
+		t.Fatalf("handler calls %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("call %d = %v, want %v", i, calls[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ResponseBodyPolicy controls how much of an HTTP response is kept: a
+// truncated body with every result, and optionally the full response of the
+// check that turned a target from up to down
+type ResponseBodyPolicy struct {
+	MaxStoredBytes      int           // body stored with every result
+	Archive             bool          // archive the full response on an up to down transition
+	ArchiveMaxBytes     int           // body kept in an archive, at most maxDecodedBodyBytes
+	ArchiveRetention    time.Duration // archives older than this are deleted
+	ArchiveMaxPerTarget int           // older archives of a target beyond this are deleted
+}
+
+// DefaultResponseBodyPolicy is used until SetResponseBodyPolicy is called
+var DefaultResponseBodyPolicy = ResponseBodyPolicy{
+	MaxStoredBytes:      100 * 1024,
+	ArchiveMaxBytes:     5 << 20,
+	ArchiveRetention:    30 * 24 * time.Hour,
+	ArchiveMaxPerTarget: 20,
+}
+
+var responseBodyPolicy = DefaultResponseBodyPolicy
+
+// SetResponseBodyPolicy replaces the response body policy. It must be called before checks start.
+func SetResponseBodyPolicy(policy ResponseBodyPolicy) {
+	if policy.MaxStoredBytes <= 0 {
+		policy.MaxStoredBytes = DefaultResponseBodyPolicy.MaxStoredBytes
+	}
+	if policy.ArchiveMaxBytes <= 0 || policy.ArchiveMaxBytes > maxDecodedBodyBytes {
+		policy.ArchiveMaxBytes = maxDecodedBodyBytes
+	}
+	responseBodyPolicy = policy
+}
+
+// responseCapture 失败检查的完整（解码后）响应体，最多 ArchiveMaxBytes
+type responseCapture struct {
+	body      []byte
+	truncated bool
+}
+
+func newResponseCapture(body []byte, maxBytes int) *responseCapture {
+	capture := &responseCapture{body: body}
+	if len(body) > maxBytes {
+		capture.body = body[:maxBytes]
+		capture.truncated = true
+	}
+	return capture
+}
+
+// archiveResponse stores the captured response of a result. The request
+// headers are already redacted by saveResult; the response headers and body
+// are redacted when the archive is read, so a changed redaction rule applies
+// to archives saved before it.
+func (s *Service) archiveResponse(db *gorm.DB, target *MonitorTarget, result *CheckResult, checkedAt time.Time) *models.ResponseArchive {
+	requestHeaders, _ := json.Marshal(result.Request.Headers)
+	responseHeaders, _ := json.Marshal(result.Response.Headers)
+	archive := &models.ResponseArchive{
+		TargetID:         target.ID,
+		CheckedAt:        checkedAt,
+		Message:          result.Message,
+		Method:           result.Request.Method,
+		URL:              result.Request.URL,
+		RequestHeaders:   string(requestHeaders),
+		StatusCode:       result.Response.StatusCode,
+		ResponseHeaders:  string(responseHeaders),
+		ResponseTime:     result.ResponseTime,
+		ContentLength:    result.Response.ContentLength,
+		BytesReceived:    result.Response.BytesReceived,
+		DecodedBodyBytes: result.Response.DecodedBodyBytes,
+		Body:             result.capture.body,
+		Truncated:        result.capture.truncated,
+	}
+	if err := db.Create(archive).Error; err != nil {
+		logger.Warn("Failed to archive response",
+			zap.Uint32("target_id", target.ID),
+			zap.Error(err),
+		)
+		return nil
+	}
+
+	logger.Info("Response archived",
+		zap.Uint32("target_id", target.ID),
+		zap.Uint("archive_id", archive.ID),
+		zap.Int("status_code", archive.StatusCode),
+		zap.Int("body_bytes", len(archive.Body)),
+		zap.Bool("truncated", archive.Truncated),
+	)
+	s.pruneResponseArchives(db, target.ID, checkedAt)
+	return archive
+}
+
+// pruneResponseArchives deletes expired archives and those of the target
+// beyond the per-target limit, newest kept
+func (s *Service) pruneResponseArchives(db *gorm.DB, targetID uint32, now time.Time) {
+	policy := responseBodyPolicy
+
+	if policy.ArchiveRetention > 0 {
+		if err := db.Where("checked_at < ?", now.Add(-policy.ArchiveRetention)).Delete(&models.ResponseArchive{}).Error; err != nil {
+			logger.Warn("Failed to delete expired response archives", zap.Error(err))
+		}
+	}
+
+	if policy.ArchiveMaxPerTarget <= 0 {
+		return
+	}
+	var ids []uint
+	if err := db.Model(&models.ResponseArchive{}).Where("target_id = ?", targetID).
+		Order("checked_at DESC, id DESC").Pluck("id", &ids).Error; err != nil {
+		logger.Warn("Failed to list response archives", zap.Uint32("target_id", targetID), zap.Error(err))
+		return
+	}
+	if len(ids) <= policy.ArchiveMaxPerTarget {
+		return
+	}
+	if err := db.Delete(&models.ResponseArchive{}, ids[policy.ArchiveMaxPerTarget:]).Error; err != nil {
+		logger.Warn("Failed to delete old response archives", zap.Uint32("target_id", targetID), zap.Error(err))
+	}
+}
+
+// ResponseArchiveView is an archive as returned by the API, redacted
+type ResponseArchiveView struct {
+	models.ResponseArchive
+	RequestHeaders  map[string]string `json:"request_headers,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	Body            string            `json:"body"`
+}
+
+// ListResponseArchives lists the archives of a target, newest first, without their bodies
+func (s *Service) ListResponseArchives(ctx context.Context, targetID uint32) ([]models.ResponseArchive, error) {
+	var archives []models.ResponseArchive
+	err := database.GetDB().WithContext(ctx).Omit("Body").
+		Where("target_id = ?", targetID).Order("checked_at DESC, id DESC").Find(&archives).Error
+	return archives, err
+}
+
+// GetResponseArchive returns an archive by its ID, or by the ID of the
+// history row of the down result when id is 0, with the current redaction
+// rules applied. It returns gorm.ErrRecordNotFound if there is none.
+func (s *Service) GetResponseArchive(ctx context.Context, id, historyID uint) (*ResponseArchiveView, error) {
+	query := database.GetDB().WithContext(ctx)
+	if id != 0 {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("history_id = ? AND history_id <> 0", historyID)
+	}
+
+	var archive models.ResponseArchive
+	if err := query.First(&archive).Error; err != nil {
+		return nil, err
+	}
+
+	view := &ResponseArchiveView{ResponseArchive: archive}
+	var requestHeaders, responseHeaders map[string]string
+	json.Unmarshal([]byte(archive.RequestHeaders), &requestHeaders)
+	json.Unmarshal([]byte(archive.ResponseHeaders), &responseHeaders)
+	view.RequestHeaders = s.redactor.RedactHeaders(requestHeaders)
+	view.ResponseHeaders = s.redactor.RedactHeaders(responseHeaders)
+	view.Body = s.redactor.RedactContent(string(archive.Body))
+	return view, nil
+}
+
+// PurgeResponseArchives deletes the archives matching a log purge filter; the
+// pattern is matched against the URL, message, headers and body
+func PurgeResponseArchives(ctx context.Context, filter *logger.PurgeFilter) (int64, error) {
+	db := database.GetDB().WithContext(ctx)
+	var deleted int64
+	var lastID uint
+	for {
+		query := db.Where("id > ?", lastID)
+		if filter.TargetID != nil {
+			query = query.Where("target_id = ?", *filter.TargetID)
+		}
+		if filter.StartTime != nil {
+			query = query.Where("checked_at >= ?", *filter.StartTime)
+		}
+		if filter.EndTime != nil {
+			query = query.Where("checked_at <= ?", *filter.EndTime)
+		}
+
+		var archives []models.ResponseArchive
+		if err := query.Order("id").Limit(200).Find(&archives).Error; err != nil {
+			return deleted, err
+		}
+		if len(archives) == 0 {
+			return deleted, nil
+		}
+
+		var ids []uint
+		for _, archive := range archives {
+			lastID = archive.ID
+			if filter.MatchesText(archive.URL, archive.Message, archive.RequestHeaders, archive.ResponseHeaders, string(archive.Body)) {
+				ids = append(ids, archive.ID)
+			}
+		}
+		if len(ids) > 0 {
+			result := db.Delete(&models.ResponseArchive{}, ids)
+			if result.Error != nil {
+				return deleted, result.Error
+			}
+			deleted += result.RowsAffected
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MaxBodyAssertionLength bounds each of the body assertions of a target
+	MaxBodyAssertionLength = 1000
+	// bodyExcerptLength is how many bytes of the body a failed assertion quotes
+	bodyExcerptLength = 120
+)
+
+// Body assertions of http and https targets, declared in httpFields
+var (
+	bodyMustContainField    = FieldSpec{Name: "body_must_contain", Kind: FieldString, Max: intBound(MaxBodyAssertionLength), Description: "响应体必须包含的文本，不包含时为 down"}
+	bodyMustNotContainField = FieldSpec{Name: "body_must_not_contain", Kind: FieldString, Max: intBound(MaxBodyAssertionLength), Description: "响应体不能包含的文本（如错误页的关键字），包含时为 down"}
+	bodyRegexField          = FieldSpec{Name: "body_regex", Kind: FieldString, Max: intBound(MaxBodyAssertionLength), Description: "响应体必须匹配的正则表达式（RE2 语法），不匹配时为 down"}
+)
+
+// ValidateBodyAssertions checks the body assertions of a target: they are
+// only supported for http and https, and the regex must compile
+func ValidateBodyAssertions(typ, mustContain, mustNotContain, regex string) error {
+	if mustContain == "" && mustNotContain == "" && regex == "" {
+		return nil
+	}
+	if spec, ok := LookupType(typ); ok {
+		typ = spec.Type
+	}
+	if typ != "http" && typ != "https" {
+		return fmt.Errorf("body_must_contain, body_must_not_contain and body_regex are only supported for http and https monitors")
+	}
+	if _, err := ParseBodyRegex(regex); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ParseBodyRegex compiles the body_regex of a target, nil when it is empty
+func ParseBodyRegex(s string) (*regexp.Regexp, error) {
+	if s == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid body_regex: %w", err)
+	}
+	return re, nil
+}
+
+// hasBodyAssertions reports whether the target checks the response body
+func (t *MonitorTarget) hasBodyAssertions() bool {
+	return t.BodyMustContain != "" || t.BodyMustNotContain != "" || t.BodyRegex != nil
+}
+
+// checkBodyAssertions evaluates the body assertions of the target against
+// the decoded body. It returns the assertion that failed, e.g.
+// `must contain "ok"`, and the part of the body to quote, or "" when all hold.
+func checkBodyAssertions(target *MonitorTarget, body []byte) (failed, excerpt string) {
+	if target.BodyMustContain != "" && !bytes.Contains(body, []byte(target.BodyMustContain)) {
+		return fmt.Sprintf("must contain %q", target.BodyMustContain), bodyExcerpt(body, 0)
+	}
+	if target.BodyMustNotContain != "" {
+		if i := bytes.Index(body, []byte(target.BodyMustNotContain)); i >= 0 {
+			// Quote from a little before the match so it is visible in context
+			return fmt.Sprintf("must not contain %q", target.BodyMustNotContain), bodyExcerpt(body, i-bodyExcerptLength/4)
+		}
+	}
+	if target.BodyRegex != nil && !target.BodyRegex.Match(body) {
+		return fmt.Sprintf("must match %q", target.BodyRegex.String()), bodyExcerpt(body, 0)
+	}
+	return "", ""
+}
+
+// bodyExcerpt returns up to bodyExcerptLength bytes of body from start on one
+// line, cut at rune boundaries, with "..." where the body goes on
+func bodyExcerpt(body []byte, start int) string {
+	if start < 0 {
+		start = 0
+	}
+	for start > 0 && start < len(body) && !utf8.RuneStart(body[start]) {
+		start--
+	}
+	end := start + bodyExcerptLength
+	if end > len(body) {
+		end = len(body)
+	}
+	for end < len(body) && end > start && !utf8.RuneStart(body[end]) {
+		end--
+	}
+
+	excerpt := strings.Join(strings.Fields(strings.ToValidUTF8(string(body[start:end]), "�")), " ")
+	if start > 0 {
+		excerpt = "..." + excerpt
+	}
+	if end < len(body) {
+		excerpt += "..."
+	}
+	return excerpt
+}
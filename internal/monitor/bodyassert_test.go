@@ -0,0 +1,93 @@
+package monitor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestValidateBodyAssertions(t *testing.T) {
+	for _, tc := range []struct {
+		typ, contain, regex string
+		ok                  bool
+	}{
+		{"tcp", "", "", true},
+		{"http", "ok", `"status":\s*"ok"`, true},
+		{"https", "", "^<html", true},
+		{"tcp", "ok", "", false},
+		{"http", "", "(", false},
+	} {
+		if err := ValidateBodyAssertions(tc.typ, tc.contain, "", tc.regex); (err == nil) != tc.ok {
+			t.Errorf("ValidateBodyAssertions(%s, %q, %q) = %v", tc.typ, tc.contain, tc.regex, err)
+		}
+	}
+}
+
+func TestCheckBodyAssertions(t *testing.T) {
+	body := []byte(`{"status": "ok", "db": "connected"}`)
+	for _, tc := range []struct {
+		name   string
+		target MonitorTarget
+		failed string
+	}{
+		{"all hold", MonitorTarget{BodyMustContain: `"ok"`, BodyMustNotContain: "error", BodyRegex: regexp.MustCompile(`"db":\s*"connected"`)}, ""},
+		{"missing", MonitorTarget{BodyMustContain: "healthy"}, `must contain "healthy"`},
+		{"forbidden", MonitorTarget{BodyMustNotContain: "connected"}, `must not contain "connected"`},
+		{"no match", MonitorTarget{BodyRegex: regexp.MustCompile(`^\[`)}, `must match "^\\["`},
+	} {
+		if failed, _ := checkBodyAssertions(&tc.target, body); failed != tc.failed {
+			t.Errorf("%s: failed %q, want %q", tc.name, failed, tc.failed)
+		}
+	}
+}
+
+func TestBodyExcerpt(t *testing.T) {
+	if got := bodyExcerpt([]byte("<html>\n  <body>down</body>\n</html>"), 0); got != "<html> <body>down</body> </html>" {
+		t.Errorf("short body: %q", got)
+	}
+	long := []byte(strings.Repeat("a", 200) + "error" + strings.Repeat("b", 200))
+	got := bodyExcerpt(long, 200-bodyExcerptLength/4)
+	if !strings.HasPrefix(got, "...") || !strings.HasSuffix(got, "...") || !strings.Contains(got, "error") || len(got) != bodyExcerptLength+6 {
+		t.Errorf("excerpt in the middle: %q", got)
+	}
+	// Never cut in the middle of a rune
+	if got := bodyExcerpt([]byte(strings.Repeat("错", 100)), 1); !strings.HasPrefix(got, "错") || strings.ContainsRune(got, '�') {
+		t.Errorf("excerpt of multibyte text: %q", got)
+	}
+}
+
+// The assertions hold for the final response of a redirect, and only turn an
+// otherwise up result down
+func TestHTTPCheckBodyAssertions(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/health", http.StatusFound) })
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("database: error")) })
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) { http.Error(w, "ok", http.StatusInternalServerError) })
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	check := func(path string, target MonitorTarget) *CheckResult {
+		t.Helper()
+		target.Name, target.Type, target.Address, target.FollowRedirects = "site", "http", srv.URL+path, true
+		result, err := (&HTTPChecker{}).Check(context.Background(), &target)
+		if err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+		return result
+	}
+
+	if r := check("/old", MonitorTarget{BodyMustContain: "database"}); r.Status != "up" {
+		t.Errorf("redirected body: %s %q", r.Status, r.Message)
+	}
+	r := check("/old", MonitorTarget{BodyMustNotContain: "error"})
+	if r.Status != "down" || r.Data["body_assertion"] != `must not contain "error"` || !strings.Contains(r.Message, `body: "database: error"`) {
+		t.Errorf("forbidden text: %s %q %v", r.Status, r.Message, r.Data)
+	}
+	r = check("/broken", MonitorTarget{BodyMustContain: "ok"})
+	if r.Status != "down" || r.Data["body_assertion"] != nil {
+		t.Errorf("status code failure: %s %q %v", r.Status, r.Message, r.Data)
+	}
+}
@@ -0,0 +1,227 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Field kinds used in the catalog, named after their JSON types
+const (
+	FieldString  = "string"
+	FieldInteger = "integer"
+	FieldBoolean = "boolean"
+	FieldObject  = "object" // map of strings
+	FieldArray   = "array"  // list of strings
+	// list of objects, described by the field's description
+	FieldObjectList = "object_list"
+)
+
+// FieldSpec describes one setting of a monitor type, keyed by its name in the
+// add/update request. Zero values count as unset: they are never checked
+// against Enum, Min or Max, and the checker falls back to Default.
+type FieldSpec struct {
+	Name        string      `json:"name"`
+	Kind        string      `json:"kind"`
+	Required    bool        `json:"required"`
+	Default     interface{} `json:"default,omitempty"`
+	Enum        []string    `json:"enum,omitempty"`
+	Min         *int64      `json:"min,omitempty"`
+	Max         *int64      `json:"max,omitempty"`
+	Description string      `json:"description"`
+}
+
+// ResultField describes something a checker reports besides the status,
+// response time and message every result has
+type ResultField struct {
+	Name        string `json:"name"`
+	In          string `json:"in"` // data, response, response_headers
+	Description string `json:"description"`
+}
+
+// TypeSpec is the catalog entry of a monitor type; the checker registers it
+// together with its factory, so the catalog, validation and NewChecker agree
+type TypeSpec struct {
+	Type        string        `json:"type"`
+	Aliases     []string      `json:"aliases,omitempty"`
+	DisplayName string        `json:"display_name"`
+	Fields      []FieldSpec   `json:"fields"`
+	Results     []ResultField `json:"results"`
+
+	newChecker func() Checker
+}
+
+// commonFields apply to every type and come first in each entry
+var commonFields = []FieldSpec{
+	{Name: "name", Kind: FieldString, Required: true, Description: "监控名称"},
+	{Name: "address", Kind: FieldString, Required: true, Description: "检查地址"},
+	{Name: "interval", Kind: FieldInteger, Default: 60, Min: intBound(1), Description: "检查间隔（秒）"},
+	{Name: "timeout_seconds", Kind: FieldInteger, Default: 30, Min: intBound(1), Description: "单次检查的超时（秒），不能超过检查间隔"},
+	{Name: "retry_count", Kind: FieldInteger, Default: 0, Min: intBound(0), Max: intBound(MaxRetryCount), Description: "检查结果为 down 时重试的次数，只保存最后一次的结果"},
+	{Name: "retry_interval_seconds", Kind: FieldInteger, Default: 0, Min: intBound(0), Description: "两次重试的间隔（秒），所有尝试共用检查超时"},
+	{Name: "enabled", Kind: FieldBoolean, Description: "是否启用"},
+	{Name: "notes", Kind: FieldString, Max: intBound(MaxNotesLength), Description: "运维备注（Markdown），最大字节数见 max"},
+	{Name: "runbook_url", Kind: FieldString, Description: "处理手册链接，http/https 地址"},
+	{Name: "sinks", Kind: FieldString, Description: "写入目的地：留空为全部，none，或逗号分隔的 db_history、es、file"},
+	{Name: "group_id", Kind: FieldInteger, Description: "所属分组的 ID，省略为未分组"},
+	{Name: "depends_on_target_id", Kind: FieldInteger, Description: "依赖的监控 ID（如站点的上联路由器），它故障期间本监控的 down 不发送告警；不能形成循环"},
+	{Name: "tags", Kind: FieldArray, Description: "标签，小写字母、数字和 . _ - : /，最多 20 个"},
+}
+
+var typeRegistry = map[string]*TypeSpec{}
+
+// RegisterType adds a monitor type to the catalog; checkers call it from init
+func RegisterType(spec TypeSpec, newChecker func() Checker) {
+	spec.Fields = append(append([]FieldSpec{}, commonFields...), spec.Fields...)
+	spec.newChecker = newChecker
+	for _, name := range append([]string{spec.Type}, spec.Aliases...) {
+		if _, exists := typeRegistry[name]; exists {
+			panic(fmt.Sprintf("monitor type %q registered twice", name))
+		}
+		typeRegistry[name] = &spec
+	}
+}
+
+// LookupType returns the catalog entry of a type or one of its aliases
+func LookupType(typ string) (*TypeSpec, bool) {
+	spec, ok := typeRegistry[typ]
+	return spec, ok
+}
+
+// Types returns the catalog sorted by type, one entry per type
+func Types() []TypeSpec {
+	specs := make([]TypeSpec, 0, len(typeRegistry))
+	for name, spec := range typeRegistry {
+		if name == spec.Type {
+			specs = append(specs, *spec)
+		}
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Type < specs[j].Type })
+	return specs
+}
+
+// TypeNames lists every accepted type name including aliases, sorted
+func TypeNames() []string {
+	names := make([]string, 0, len(typeRegistry))
+	for name := range typeRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateSettings checks the settings of a target against the catalog entry
+// of its type. settings holds the request fields by JSON name; fields the type
+// does not declare are ignored.
+func ValidateSettings(typ string, settings map[string]interface{}) error {
+	spec, ok := LookupType(typ)
+	if !ok {
+		return fmt.Errorf("unsupported monitor type %q, supported types: %s", typ, strings.Join(TypeNames(), ", "))
+	}
+
+	for _, field := range spec.Fields {
+		value, set := settings[field.Name]
+		if set && isZeroSetting(value) {
+			set = false
+		}
+		if !set {
+			if field.Required {
+				return fmt.Errorf("%s is required for %s monitors", field.Name, spec.Type)
+			}
+			continue
+		}
+
+		if err := field.validate(value); err != nil {
+			return fmt.Errorf("%s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+func (f FieldSpec) validate(value interface{}) error {
+	switch f.Kind {
+	case FieldString:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("must be a string")
+		}
+		if len(f.Enum) > 0 && !containsString(f.Enum, s) {
+			return fmt.Errorf("must be one of %s", strings.Join(f.Enum, ", "))
+		}
+		if f.Max != nil && int64(len(s)) > *f.Max {
+			return fmt.Errorf("must be at most %d bytes", *f.Max)
+		}
+	case FieldInteger:
+		n, ok := value.(float64) // numbers decoded from JSON
+		if !ok || n != float64(int64(n)) {
+			return fmt.Errorf("must be an integer")
+		}
+		if f.Min != nil && int64(n) < *f.Min {
+			return fmt.Errorf("must be at least %d", *f.Min)
+		}
+		if f.Max != nil && int64(n) > *f.Max {
+			return fmt.Errorf("must be at most %d", *f.Max)
+		}
+	case FieldBoolean:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("must be a boolean")
+		}
+	case FieldObject:
+		if _, ok := value.(map[string]interface{}); !ok {
+			return fmt.Errorf("must be an object")
+		}
+	case FieldArray:
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("must be an array of strings")
+		}
+		for _, item := range items {
+			if _, ok := item.(string); !ok {
+				return fmt.Errorf("must be an array of strings")
+			}
+		}
+	case FieldObjectList:
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("must be an array of objects")
+		}
+		for _, item := range items {
+			if _, ok := item.(map[string]interface{}); !ok {
+				return fmt.Errorf("must be an array of objects")
+			}
+		}
+	}
+	return nil
+}
+
+// isZeroSetting reports whether a decoded JSON value is the zero value of its kind
+func isZeroSetting(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case float64:
+		return v == 0
+	case bool:
+		return !v
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func intBound(n int64) *int64 {
+	return &n
+}
@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Every catalog entry builds a checker, lists the common fields first and
+// is reachable through its aliases
+func TestCatalog(t *testing.T) {
+	types := Types()
+	if len(types) == 0 {
+		t.Fatal("empty catalog")
+	}
+	for i, spec := range types {
+		if i > 0 && types[i-1].Type >= spec.Type {
+			t.Errorf("catalog not sorted: %s before %s", types[i-1].Type, spec.Type)
+		}
+		if _, err := NewChecker(spec.Type); err != nil {
+			t.Errorf("NewChecker(%s): %v", spec.Type, err)
+		}
+		if len(spec.Fields) < len(commonFields) || spec.Fields[0].Name != "name" {
+			t.Errorf("%s: common fields missing", spec.Type)
+		}
+		for _, alias := range spec.Aliases {
+			if aliased, ok := LookupType(alias); !ok || aliased.Type != spec.Type {
+				t.Errorf("alias %s does not resolve to %s", alias, spec.Type)
+			}
+		}
+	}
+
+	names := TypeNames()
+	if !slices.IsSorted(names) || !slices.Contains(names, "icmp") || !slices.Contains(names, "tcp") || len(names) <= len(types) {
+		t.Errorf("TypeNames() = %v, want every type and alias sorted", names)
+	}
+	if _, err := NewChecker("gopher"); err == nil {
+		t.Error("NewChecker accepted an unknown type")
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	valid := map[string]interface{}{"name": "db", "address": "127.0.0.1", "port": float64(5432), "interval": float64(60), "tags": []interface{}{"prod"}}
+	if err := ValidateSettings("tcp", valid); err != nil {
+		t.Fatalf("valid tcp settings: %v", err)
+	}
+
+	with := func(key string, value interface{}) map[string]interface{} {
+		settings := make(map[string]interface{}, len(valid)+1)
+		for k, v := range valid {
+			settings[k] = v
+		}
+		settings[key] = value
+		return settings
+	}
+	for _, tc := range []struct {
+		name     string
+		typ      string
+		settings map[string]interface{}
+		errText  string
+	}{
+		{"unknown type", "gopher", valid, "supported types: "},
+		{"missing required", "tcp", with("port", float64(0)), "port is required for tcp monitors"},
+		{"over max", "tcp", with("port", float64(70000)), "port: must be at most 65535"},
+		{"under min", "tcp", with("interval", float64(-5)), "interval: must be at least 1"},
+		{"not an integer", "tcp", with("port", 80.5), "port: must be an integer"},
+		{"wrong kind", "tcp", with("name", float64(1)), "name: must be a string"},
+		{"array of non-strings", "tcp", with("tags", []interface{}{float64(1)}), "tags: must be an array of strings"},
+		{"not in enum", "dns", map[string]interface{}{"name": "ns", "address": "example.com", "dns_server_type": "quic"}, "dns_server_type: must be one of udp, tcp, doh, dot"},
+		{"too long", "tcp", with("notes", strings.Repeat("x", MaxNotesLength+1)), "notes: must be at most"},
+	} {
+		err := ValidateSettings(tc.typ, tc.settings)
+		if err == nil || !strings.Contains(err.Error(), tc.errText) {
+			t.Errorf("%s: err = %v, want %q", tc.name, err, tc.errText)
+		}
+	}
+
+	// Aliases validate against their type, and undeclared fields are ignored
+	ping := map[string]interface{}{"name": "gw", "address": "10.0.0.1", "port": "not checked"}
+	if err := ValidateSettings("icmp", ping); err != nil {
+		t.Errorf("icmp settings: %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package monitor
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/lru"
+	"monitor/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// certificateSeenInterval 同一目标持续出示同一张证书时，last_seen 最多每隔这么久更新一次，避免每次检查都写库
+const certificateSeenInterval = time.Hour
+
+// MaxCertificateCacheEntries bounds the targets remembered by the certificate
+// cache; an evicted target's certificate is written again on its next check
+const MaxCertificateCacheEntries = 10000
+
+// CertificateInfo is the leaf certificate presented to a check
+type CertificateInfo struct {
+	Fingerprint string // SHA-256 of the DER encoding, lowercase hex
+	Subject     string
+	DNSNames    []string
+	Issuer      string
+	Serial      string
+	NotBefore   time.Time
+	NotAfter    time.Time
+}
+
+func newCertificateInfo(cert *x509.Certificate) *CertificateInfo {
+	sum := sha256.Sum256(cert.Raw)
+	return &CertificateInfo{
+		Fingerprint: hex.EncodeToString(sum[:]),
+		Subject:     cert.Subject.CommonName,
+		DNSNames:    cert.DNSNames,
+		Issuer:      cert.Issuer.CommonName,
+		Serial:      formatSerial(cert.SerialNumber),
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+	}
+}
+
+// certificateCache remembers the certificate last recorded for each target
+type certificateCache struct {
+	mu   sync.Mutex
+	seen *lru.Cache[uint32, certificateSeen]
+}
+
+type certificateSeen struct {
+	fingerprint string
+	at          time.Time
+}
+
+func newCertificateCache() *certificateCache {
+	return &certificateCache{seen: lru.New[uint32, certificateSeen](MaxCertificateCacheEntries)}
+}
+
+// due reports whether the certificate must be written for the target: it is
+// new for the target or was last written more than certificateSeenInterval ago.
+// previous is the fingerprint recorded before, empty if none is known.
+func (c *certificateCache) due(targetID uint32, fingerprint string, now time.Time) (due bool, previous string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	seen, ok := c.seen.Get(targetID)
+	if ok && seen.fingerprint == fingerprint && now.Sub(seen.at) < certificateSeenInterval {
+		return false, seen.fingerprint
+	}
+	c.seen.Add(targetID, certificateSeen{fingerprint: fingerprint, at: now})
+	return true, seen.fingerprint
+}
+
+func (c *certificateCache) forget(targetID uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen.Remove(targetID)
+}
+
+// size returns the number of remembered targets
+func (c *certificateCache) size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.seen.Len()
+}
+
+// recordCertificate adds the certificate to the inventory and makes it the
+// current certificate of the target
+func (s *Service) recordCertificate(db *gorm.DB, target *MonitorTarget, info *CertificateInfo, now time.Time) {
+	due, previous := s.certificates.due(target.ID, info.Fingerprint, now)
+	if !due {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		dnsNames, _ := json.Marshal(info.DNSNames)
+		cert := models.Certificate{
+			Fingerprint: info.Fingerprint,
+			Subject:     info.Subject,
+			DNSNames:    string(dnsNames),
+			Issuer:      info.Issuer,
+			Serial:      info.Serial,
+			NotBefore:   info.NotBefore,
+			NotAfter:    info.NotAfter,
+			FirstSeen:   now,
+			LastSeen:    now,
+		}
+		// 多个目标可能同时第一次遇到同一张证书
+		if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "fingerprint"}}, DoNothing: true}).Create(&cert).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("fingerprint = ?", info.Fingerprint).First(&cert).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&cert).Update("last_seen", now).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.CertificateTarget{}).
+			Where("target_id = ? AND certificate_id <> ? AND is_current = ?", target.ID, cert.ID, true).
+			Update("is_current", false).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&models.CertificateTarget{}).
+			Where("target_id = ? AND certificate_id = ?", target.ID, cert.ID).
+			Updates(map[string]interface{}{"is_current": true, "last_seen": now})
+		if result.Error != nil || result.RowsAffected > 0 {
+			return result.Error
+		}
+		return tx.Create(&models.CertificateTarget{
+			CertificateID: cert.ID,
+			TargetID:      target.ID,
+			Current:       true,
+			FirstSeen:     now,
+			LastSeen:      now,
+		}).Error
+	})
+	if err != nil {
+		s.certificates.forget(target.ID)
+		logger.Warn("Failed to record certificate",
+			zap.Uint32("target_id", target.ID),
+			zap.String("fingerprint", info.Fingerprint),
+			zap.Error(err),
+		)
+		return
+	}
+
+	if previous != "" && previous != info.Fingerprint {
+		logger.Info("Target certificate changed",
+			zap.Uint32("target_id", target.ID),
+			zap.String("target", target.Name),
+			zap.String("previous_fingerprint", previous),
+			zap.String("fingerprint", info.Fingerprint),
+			zap.Time("not_after", info.NotAfter),
+		)
+	}
+}
+
+// CertificateQuery filters ListCertificates
+type CertificateQuery struct {
+	ExpiringWithinDays int    // only certificates expiring within this many days, 0 for all
+	TargetID           uint32 // only certificates presented by this target, 0 for all
+	IncludeReplaced    bool   // also certificates that no target presents any more
+}
+
+// CertificateEntry is a certificate of the inventory with the targets that presented it
+type CertificateEntry struct {
+	models.Certificate
+	DNSNames        []string               `json:"dns_names"`
+	DaysUntilExpiry int                    `json:"days_until_expiry"`
+	Targets         []CertificateTargetRef `json:"targets"`
+}
+
+// CertificateTargetRef is a target that presented a certificate, with its own status
+type CertificateTargetRef struct {
+	ID        uint32    `json:"id"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Address   string    `json:"address"`
+	Port      int32     `json:"port,omitempty"`
+	Status    string    `json:"status,omitempty"`
+	Current   bool      `json:"current"` // the target presented this certificate in its latest check
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// ListCertificates returns the certificate inventory, the earliest expiry first
+func (s *Service) ListCertificates(ctx context.Context, q CertificateQuery) ([]CertificateEntry, error) {
+	db := database.GetDB().WithContext(ctx)
+	now := s.clock.Now()
+
+	query := db.Model(&models.Certificate{})
+	if q.ExpiringWithinDays > 0 {
+		query = query.Where("not_after <= ?", now.AddDate(0, 0, q.ExpiringWithinDays))
+	}
+	links := db.Model(&models.CertificateTarget{}).Select("certificate_id")
+	if q.TargetID != 0 {
+		links = links.Where("target_id = ?", q.TargetID)
+	}
+	if !q.IncludeReplaced {
+		links = links.Where("is_current = ?", true)
+	}
+	if q.TargetID != 0 || !q.IncludeReplaced {
+		query = query.Where("id IN (?)", links)
+	}
+
+	var certs []models.Certificate
+	if err := query.Order("not_after").Order("id").Find(&certs).Error; err != nil {
+		return nil, err
+	}
+	entries := make([]CertificateEntry, 0, len(certs))
+	if len(certs) == 0 {
+		return entries, nil
+	}
+
+	ids := make([]uint, 0, len(certs))
+	for _, cert := range certs {
+		ids = append(ids, cert.ID)
+	}
+	var rows []struct {
+		models.CertificateTarget
+		Name    string
+		Type    string
+		Address string
+		Port    int32
+		Status  *string
+	}
+	if err := db.Table("certificate_targets").
+		Select("certificate_targets.*, monitor_targets.name, monitor_targets.type, monitor_targets.address, monitor_targets.port, monitor_status.status").
+		Joins("JOIN monitor_targets ON monitor_targets.id = certificate_targets.target_id").
+		Joins("LEFT JOIN monitor_status ON monitor_status.target_id = certificate_targets.target_id").
+		Where("certificate_targets.certificate_id IN ?", ids).
+		Order("certificate_targets.target_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	targets := make(map[uint][]CertificateTargetRef)
+	for _, row := range rows {
+		ref := CertificateTargetRef{
+			ID:        row.TargetID,
+			Name:      row.Name,
+			Type:      row.Type,
+			Address:   row.Address,
+			Port:      row.Port,
+			Current:   row.Current,
+			FirstSeen: row.FirstSeen,
+			LastSeen:  row.LastSeen,
+		}
+		if row.Status != nil {
+			ref.Status = *row.Status
+		}
+		targets[row.CertificateID] = append(targets[row.CertificateID], ref)
+	}
+
+	for _, cert := range certs {
+		entry := CertificateEntry{
+			Certificate:     cert,
+			DNSNames:        []string{},
+			DaysUntilExpiry: int(cert.NotAfter.Sub(now).Hours() / 24),
+			Targets:         targets[cert.ID],
+		}
+		json.Unmarshal([]byte(cert.DNSNames), &entry.DNSNames)
+		if entry.Targets == nil {
+			entry.Targets = []CertificateTargetRef{}
+		}
+		// 当前仍在使用这张证书的目标排在前面
+		sort.SliceStable(entry.Targets, func(i, j int) bool {
+			return entry.Targets[i].Current && !entry.Targets[j].Current
+		})
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -2,7 +2,12 @@ package monitor
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"net/http"
+	"regexp"
+	"time"
 )
 
 type CheckResult struct {
@@ -17,6 +22,44 @@ type CheckResult struct {
 	Response ResponseDetails
 	// 错误详情
 	Error *ErrorDetails
+
+	// Fabricated by InjectResults rather than produced by a real check
+	Synthetic bool
+
+	// Set by saveResult. Together with the target ID they identify the check,
+	// e.g. for the Elasticsearch document ID, in every sink.
+	CompletedAt time.Time
+	Nonce       string
+	// Orders results completed at the same time, the same in every sink
+	Seq int64
+
+	// Set by saveResult: whether the target is flapping, see SetFlappingPolicy
+	Flapping       bool
+	FlappingChange FlappingChange
+	// Set by saveResult: the monitor the target depends on when the result
+	// is down because of it, see suppressedBy
+	SuppressedBy *uint32
+
+	// Leaf certificate presented over TLS, recorded in the certificate inventory
+	Certificate *CertificateInfo
+
+	// Full response of a failed HTTP check, archived by saveResult when the
+	// target goes from up to down
+	capture *responseCapture
+
+	// SHA-256 of the decoded response body, computed when the target compares bodies
+	bodySHA256 string
+
+	// Decoded response body of a step of a transaction, see checkSteps
+	body []byte
+}
+
+// newCheckNonce returns a random ID that tells apart checks of a target
+// completed at the same instant
+func newCheckNonce() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
 }
 
 // RequestDetails 请求详情
@@ -32,7 +75,10 @@ type ResponseDetails struct {
 	StatusCode    int               `json:"status_code,omitempty"`
 	Headers       map[string]string `json:"headers,omitempty"`
 	Body          string            `json:"body,omitempty"`
-	ContentLength int64             `json:"content_length,omitempty"`
+	ContentLength int64             `json:"content_length,omitempty"` // Content-Length header, -1 if unknown (e.g. chunked)
+
+	BytesReceived    int64 `json:"bytes_received,omitempty"`     // body bytes as received, before decoding
+	DecodedBodyBytes int64 `json:"decoded_body_bytes,omitempty"` // body bytes after Content-Encoding decoding, -1 if it could not be decoded
 }
 
 // ErrorDetails 错误详情
@@ -41,6 +87,23 @@ type ErrorDetails struct {
 	Message string `json:"message,omitempty"`
 }
 
+// detailHeaders converts structured check parameters into the string map
+// stored in RequestDetails/ResponseDetails headers, skipping empty values.
+func detailHeaders(values map[string]interface{}) map[string]string {
+	headers := make(map[string]string, len(values))
+	for key, value := range values {
+		if value == nil {
+			continue
+		}
+		str := fmt.Sprintf("%v", value)
+		if str == "" {
+			continue
+		}
+		headers[key] = str
+	}
+	return headers
+}
+
 type MonitorTarget struct {
 	ID       uint32
 	Name     string
@@ -56,14 +119,48 @@ type MonitorTarget struct {
 	HTTPHeaders         map[string]string // Custom headers
 	HTTPBody            string            // Request body
 	ResolvedHost        string            // Custom host resolution
+	UnixSocketPath      string            // Dial this Unix socket; the URL still sets Host and path
+	ProxyURL            string            // http, https or socks5 proxy, see proxy
+	ProxyPassword       string            // Password of the ProxyURL user
 	FollowRedirects     bool              // Follow 301/302 redirects
 	MaxRedirects        int               // Maximum redirect depth
 	ExpectedStatusCodes []int             // Expected status codes (e.g., [200, 201, 301, 302])
+	// Assertions on the response headers, see checkHeaderAssertions
+	ExpectedHeaders []HeaderAssertion
+	// Assertions on the decoded response body, see checkBodyAssertions
+	BodyMustContain    string
+	BodyMustNotContain string
+	BodyRegex          *regexp.Regexp
+	// Assertion on a field of a JSON body, see checkJSONAssertion
+	JSONPath          *JSONPath
+	JSONExpectedValue string
+	JSONOperator      string
+	// Requests of a transaction sent instead of the single request, see checkSteps
+	Steps []HTTPStep
+	// Hash of the body compared between checks, see contentHash
+	TrackContentHash     bool
+	ContentHashRegex     *regexp.Regexp
+	ContentHashNormalize bool
+	// Set on the targets of the steps: keep the body for later steps and
+	// share cookies between the steps
+	keepBody bool
+	jar      http.CookieJar
+	// Credentials added to the request, see setAuth
+	AuthType     string // none, basic, bearer
+	AuthUsername string
+	AuthPassword string
+	AuthToken    string
 
 	// DNS specific fields
 	DNSServer     string // Custom DNS server (e.g., 8.8.8.8:53)
 	DNSServerName string // DNS server name
 	DNSServerType string // DNS protocol type
+	DNSServers    []DNSServerRef // Servers queried together and compared; DNSServer is ignored when set
+	DNSConsensus  string         // all, majority, any, propagation
+	// Records the answer is compared with, see checkDNSExpectation
+	DNSQueryType      string // A when empty
+	DNSExpectedValues []string
+	DNSMatchMode      string // exact, subset
 
 	// PING specific fields
 	PingCount   int // Number of ping packets
@@ -85,38 +182,73 @@ type MonitorTarget struct {
 	SNMPExpectedValue string // Expected value for comparison
 	SNMPOperator     string // Comparison operator: eq, ne, gt, lt, ge, le
 
+	// TCP specific fields, see tcpExchange
+	TCPSendString string
+	TCPExpect     *TCPExpectation
+	TCPUseTLS     bool
+
+	// UDP specific fields, see UDPChecker
+	UDPPreset         string // Built-in probe replacing UDPPayload, see udpPresets
+	UDPPayload        []byte // Decoded udp_send_payload
+	UDPExpectResponse bool
+	UDPExpectPattern  *regexp.Regexp
+
 	// SSL/TLS specific fields
 	SSLWarnDays    int  // Days before expiration to warn
 	SSLCriticalDays int  // Days before expiration to mark as critical
 	SSLCheck       bool // Enable SSL/TLS certificate monitoring
 	SSLGetChain    bool // Get certificate chain information
+	// Client certificate and CA (PEM) for http, https and ssl; see buildTLSClientConfig
+	TLSClientCertPEM string
+	TLSClientKeyPEM  string
+	TLSCACertPEM     string
+	TLSSkipVerify    bool   // Don't verify the server certificate, see tlsConfig
+	TLSPinSHA256     string // Expected SHA-256 of the leaf certificate
+
+	// Script specific fields
+	ScriptPath    string   // Executable, checked against the script policy
+	ScriptArgs    []string // Passed without a shell
+	ScriptTimeout int      // Seconds
+
+	// Comparison mode, see compare.go
+	SecondaryAddress        string // Checked alongside Address with the same settings
+	CompareLatencyTolerance int    // Milliseconds; 0 does not compare latency
+	CompareBody             bool   // Compare the SHA-256 of the response bodies
+
+	// Response time thresholds in milliseconds, 0 for none; see applyResponseTimeThresholds
+	DegradedThresholdMs int
+	DownThresholdMs     int
+
+	// Deadline of one check in seconds, 0 for checkTimeout; see timeout
+	TimeoutSeconds int
+	// Runs of a check that came back down, see checkWithRetries
+	RetryCount           int
+	RetryIntervalSeconds int
+
+	// Monitor this one depends on; see suppressedBy
+	DependsOnTargetID *uint32
+
+	// Sinks selected when the target was added; later changes go through
+	// Service.SetSinks, so saveResult reads the service's copy instead
+	Sinks Sinks
+
+	// Normalized tags of the target, e.g. for scoping alert rules
+	Tags []string
+
+	// Addresses the check may connect to, enforced when it connects; nil
+	// allows any. Set on the targets of on-demand probes, see NetworkPolicy
+	NetworkPolicy *NetworkPolicy
 }
 
 type Checker interface {
 	Check(ctx context.Context, target *MonitorTarget) (*CheckResult, error)
 }
 
+// NewChecker creates the checker of a type registered in the catalog
 func NewChecker(typ string) (Checker, error) {
-	switch typ {
-	case "http":
-		return &HTTPChecker{}, nil
-	case "https":
-		return &HTTPSChecker{}, nil
-	case "tcp":
-		return &TCPChecker{}, nil
-	case "udp":
-		return &UDPChecker{}, nil
-	case "dns":
-		return &DNSChecker{}, nil
-	case "ping", "icmp":
-		return &PingCheckerWrapper{}, nil
-	case "smtp", "smtps":
-		return &SMTPCheckerWrapper{}, nil
-	case "snmp":
-		return &SNMPCheckerWrapper{}, nil
-	case "ssl", "tls":
-		return &SSLChecker{}, nil
-	default:
+	spec, ok := LookupType(typ)
+	if !ok {
 		return nil, fmt.Errorf("unsupported monitor type: %s", typ)
 	}
+	return spec.newChecker(), nil
 }
\ No newline at end of file
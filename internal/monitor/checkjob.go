@@ -0,0 +1,245 @@
+package monitor
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Manual check job states
+const (
+	CheckJobQueued   = "queued"
+	CheckJobRunning  = "running"
+	CheckJobFinished = "finished"
+)
+
+const (
+	// MaxCheckJobs bounds the job table; finished jobs are evicted first
+	MaxCheckJobs = 1000
+	// CheckJobTTL is how long a finished job and its result stay retrievable
+	CheckJobTTL = 5 * time.Minute
+	// MaxCheckJobSubscribers bounds the open event streams; each buffers
+	// checkJobQueueSize state changes and drops the ones a slow client misses
+	MaxCheckJobSubscribers = 100
+	checkJobQueueSize      = 32
+)
+
+var (
+	ErrTooManyCheckJobs = errors.New("too many manual checks in progress")
+	ErrCheckQueueFull   = errors.New("check queue is full")
+	ErrTooManyStreams   = errors.New("too many check event streams")
+	ErrTargetRemoved    = errors.New("target was removed before its check ran")
+	ErrTargetNotFound   = errors.New("target not found or disabled")
+)
+
+// CheckJob is a snapshot of a manual check started by TriggerCheck
+type CheckJob struct {
+	Token      string          `json:"token"`
+	TargetID   uint32          `json:"target_id"`
+	State      string          `json:"state"`
+	QueuedAt   time.Time       `json:"queued_at"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+	ElapsedMs  int64           `json:"elapsed_ms"` // since queued; frozen once finished
+	Error      string          `json:"error,omitempty"`
+	Result     *CheckJobResult `json:"result,omitempty"`
+}
+
+// CheckJobResult is the CheckResult of a finished job as returned by the API
+type CheckJobResult struct {
+	Status       string                 `json:"status"`
+	ResponseTime int64                  `json:"response_time"`
+	Message      string                 `json:"message"`
+	Data         map[string]interface{} `json:"data,omitempty"`
+	Request      RequestDetails         `json:"request"`
+	Response     ResponseDetails        `json:"response"`
+	Error        *ErrorDetails          `json:"error,omitempty"`
+}
+
+func newCheckJobResult(result *CheckResult) *CheckJobResult {
+	return &CheckJobResult{
+		Status:       result.Status,
+		ResponseTime: result.ResponseTime,
+		Message:      result.Message,
+		Data:         result.Data,
+		Request:      result.Request,
+		Response:     result.Response,
+		Error:        result.Error,
+	}
+}
+
+// checkTask is one entry of the worker queue; job is empty for scheduled checks
+type checkTask struct {
+	target *MonitorTarget
+	job    string
+}
+
+// checkJobTable holds manual check jobs in memory and fans out their state changes
+type checkJobTable struct {
+	mu          sync.Mutex
+	jobs        map[string]*CheckJob
+	active      map[uint32]string // target ID -> token of its queued or running job
+	subscribers map[chan CheckJob]struct{}
+}
+
+func newCheckJobTable() *checkJobTable {
+	return &checkJobTable{
+		jobs:        make(map[string]*CheckJob),
+		active:      make(map[uint32]string),
+		subscribers: make(map[chan CheckJob]struct{}),
+	}
+}
+
+// create registers a queued job for the target. If the target already has a
+// queued or running job that one is returned instead and created is false.
+func (t *checkJobTable) create(targetID uint32, now time.Time) (CheckJob, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if token, ok := t.active[targetID]; ok {
+		return t.snapshot(t.jobs[token], now), false, nil
+	}
+
+	t.evict(now)
+	if len(t.jobs) >= MaxCheckJobs {
+		return CheckJob{}, false, ErrTooManyCheckJobs
+	}
+
+	job := &CheckJob{
+		Token:    newCheckToken(),
+		TargetID: targetID,
+		State:    CheckJobQueued,
+		QueuedAt: now,
+	}
+	t.jobs[job.Token] = job
+	t.active[targetID] = job.Token
+
+	snapshot := t.snapshot(job, now)
+	t.publish(snapshot)
+	return snapshot, true, nil
+}
+
+// remove drops a job that never made it into the queue
+func (t *checkJobTable) remove(token string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if job, ok := t.jobs[token]; ok {
+		delete(t.jobs, token)
+		delete(t.active, job.TargetID)
+	}
+}
+
+func (t *checkJobTable) start(token string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	job, ok := t.jobs[token]
+	if !ok {
+		return
+	}
+	job.State = CheckJobRunning
+	job.StartedAt = &now
+	t.publish(t.snapshot(job, now))
+}
+
+func (t *checkJobTable) finish(token string, now time.Time, result *CheckResult, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	job, ok := t.jobs[token]
+	if !ok {
+		return
+	}
+	job.State = CheckJobFinished
+	job.FinishedAt = &now
+	if err != nil {
+		job.Error = err.Error()
+	}
+	if result != nil {
+		job.Result = newCheckJobResult(result)
+	}
+	delete(t.active, job.TargetID)
+	t.publish(t.snapshot(job, now))
+}
+
+func (t *checkJobTable) get(token string, now time.Time) (CheckJob, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.evict(now)
+	job, ok := t.jobs[token]
+	if !ok {
+		return CheckJob{}, false
+	}
+	return t.snapshot(job, now), true
+}
+
+// subscribe returns a channel receiving every job state change. Slow
+// subscribers miss events rather than blocking the workers.
+func (t *checkJobTable) subscribe() (<-chan CheckJob, func(), error) {
+	ch := make(chan CheckJob, checkJobQueueSize)
+
+	t.mu.Lock()
+	if len(t.subscribers) >= MaxCheckJobSubscribers {
+		t.mu.Unlock()
+		return nil, nil, ErrTooManyStreams
+	}
+	t.subscribers[ch] = struct{}{}
+	t.mu.Unlock()
+
+	return ch, func() {
+		t.mu.Lock()
+		delete(t.subscribers, ch)
+		t.mu.Unlock()
+	}, nil
+}
+
+// sizes returns the number of jobs, of subscribers and of state changes
+// waiting in their queues
+func (t *checkJobTable) sizes() (jobs, subscribers, queued int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for ch := range t.subscribers {
+		queued += len(ch)
+	}
+	return len(t.jobs), len(t.subscribers), queued
+}
+
+// publish must be called with t.mu held
+func (t *checkJobTable) publish(job CheckJob) {
+	for ch := range t.subscribers {
+		select {
+		case ch <- job:
+		default:
+		}
+	}
+}
+
+// evict drops finished jobs older than CheckJobTTL; must be called with t.mu held
+func (t *checkJobTable) evict(now time.Time) {
+	for token, job := range t.jobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > CheckJobTTL {
+			delete(t.jobs, token)
+		}
+	}
+}
+
+// snapshot copies a job so callers never see later updates; must be called with t.mu held
+func (t *checkJobTable) snapshot(job *CheckJob, now time.Time) CheckJob {
+	copied := *job
+	end := now
+	if job.FinishedAt != nil {
+		end = *job.FinishedAt
+	}
+	copied.ElapsedMs = end.Sub(job.QueuedAt).Milliseconds()
+	return copied
+}
+
+func newCheckToken() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,154 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestCheckJobTable(t *testing.T) {
+	table := newCheckJobTable()
+	events, cancel, err := table.subscribe()
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	defer cancel()
+
+	job, created, err := table.create(7, epoch)
+	if err != nil || !created || job.State != CheckJobQueued || len(job.Token) != 32 {
+		t.Fatalf("create = %+v, %v, %v", job, created, err)
+	}
+
+	// A target with a job in flight gets that job back
+	again, created, _ := table.create(7, epoch.Add(time.Second))
+	if created || again.Token != job.Token || again.ElapsedMs != 1000 {
+		t.Errorf("second create = %+v created %v, want the first job", again, created)
+	}
+
+	table.start(job.Token, epoch.Add(2*time.Second))
+	table.finish(job.Token, epoch.Add(3*time.Second), &CheckResult{Status: "up", Message: "ok"}, nil)
+
+	var states []string
+	for len(states) < 3 {
+		select {
+		case event := <-events:
+			states = append(states, event.State)
+		case <-time.After(time.Second):
+			t.Fatalf("events %v, want queued, running and finished", states)
+		}
+	}
+	if states[0] != CheckJobQueued || states[1] != CheckJobRunning || states[2] != CheckJobFinished {
+		t.Errorf("events %v", states)
+	}
+
+	// Elapsed stops at the finish, and the result is kept for CheckJobTTL
+	got, ok := table.get(job.Token, epoch.Add(CheckJobTTL))
+	if !ok || got.ElapsedMs != 3000 || got.Result == nil || got.Result.Status != "up" {
+		t.Errorf("finished job = %+v, %v", got, ok)
+	}
+	if _, ok := table.get(job.Token, epoch.Add(3*time.Second+CheckJobTTL+time.Millisecond)); ok {
+		t.Error("finished job still there after CheckJobTTL")
+	}
+
+	// Once finished the target can be checked again
+	if next, created, _ := table.create(7, epoch.Add(time.Hour)); !created || next.Token == job.Token {
+		t.Errorf("create after finish = %+v created %v, want a new job", next, created)
+	}
+}
+
+func TestCheckJobTableLimits(t *testing.T) {
+	table := newCheckJobTable()
+	for i := 0; i < MaxCheckJobs; i++ {
+		if _, _, err := table.create(uint32(i+1), epoch); err != nil {
+			t.Fatalf("create %d: %v", i, err)
+		}
+	}
+	if _, _, err := table.create(MaxCheckJobs+1, epoch); !errors.Is(err, ErrTooManyCheckJobs) {
+		t.Errorf("create over the limit: err = %v", err)
+	}
+
+	var cancels []func()
+	for i := 0; i < MaxCheckJobSubscribers; i++ {
+		_, cancel, err := table.subscribe()
+		if err != nil {
+			t.Fatalf("subscribe %d: %v", i, err)
+		}
+		cancels = append(cancels, cancel)
+	}
+	if _, _, err := table.subscribe(); !errors.Is(err, ErrTooManyStreams) {
+		t.Errorf("subscribe over the limit: err = %v", err)
+	}
+	cancels[0]()
+	if _, _, err := table.subscribe(); err != nil {
+		t.Errorf("subscribe after a cancel: %v", err)
+	}
+}
+
+func TestTriggerCheck(t *testing.T) {
+	s := newTestService(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	target := &MonitorTarget{ID: 1, Name: "db", Type: "tcp", Address: "127.0.0.1", Port: int32(ln.Addr().(*net.TCPAddr).Port), Interval: 3600}
+	if err := s.AddTarget(target); err != nil {
+		t.Fatalf("AddTarget: %v", err)
+	}
+	s.SetSinks(target.ID, Sinks{SinkDBHistory})
+
+	events, cancel, err := s.SubscribeCheckJobs()
+	if err != nil {
+		t.Fatalf("SubscribeCheckJobs: %v", err)
+	}
+	defer cancel()
+
+	job, err := s.TriggerCheck(target.ID)
+	if err != nil {
+		t.Fatalf("TriggerCheck: %v", err)
+	}
+	for {
+		select {
+		case event := <-events:
+			if event.Token != job.Token || event.State != CheckJobFinished {
+				continue
+			}
+			if event.Result == nil || event.Result.Status != "up" || event.StartedAt == nil {
+				t.Fatalf("finished job %+v, want an up result", event)
+			}
+			if got, ok := s.GetCheckJob(job.Token); !ok || got.Result == nil || got.Result.Status != "up" {
+				t.Errorf("GetCheckJob = %+v, %v", got, ok)
+			}
+			if _, err := s.TriggerCheck(99); err == nil {
+				t.Error("TriggerCheck of an unknown target succeeded")
+			}
+			return
+		case <-time.After(5 * time.Second):
+			t.Fatal("manual check did not finish")
+		}
+	}
+}
+
+// A delayed check is waited for by Stop, which ends the delay early
+func TestTriggerCheckAfterStop(t *testing.T) {
+	s := newTestService(t)
+	target := &MonitorTarget{ID: 1, Name: "db", Type: "tcp", Address: "127.0.0.1", Port: 1, Interval: 3600}
+	if err := s.AddTarget(target); err != nil {
+		t.Fatalf("AddTarget: %v", err)
+	}
+	s.TriggerCheckAfter(target.ID, time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if jobs, _, _ := s.checkJobs.sizes(); jobs != 0 {
+		t.Error("delayed check queued after Stop")
+	}
+
+	// Once stopped nothing more is started
+	s.TriggerCheckAfter(target.ID, 0)
+}
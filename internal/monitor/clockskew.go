@@ -0,0 +1,108 @@
+package monitor
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ClockSkewPolicy controls how HTTP checks report the difference between the
+// Date header of the response and the local clock
+type ClockSkewPolicy struct {
+	Threshold time.Duration // skew above this is noted in the message
+	Warn      bool          // also turn an "up" result into "warning"
+}
+
+// DefaultClockSkewPolicy is used until SetClockSkewPolicy is called
+var DefaultClockSkewPolicy = ClockSkewPolicy{Threshold: 30 * time.Second}
+
+var clockSkewPolicy = DefaultClockSkewPolicy
+
+// SetClockSkewPolicy replaces the clock skew policy. It must be called before checks start.
+func SetClockSkewPolicy(policy ClockSkewPolicy) {
+	clockSkewPolicy = policy
+}
+
+// lastClockSkew remembers the last skew measured per target, so that
+// certificate errors (which have no response) can still mention it
+var lastClockSkew sync.Map // uint32 -> time.Duration
+
+// measureClockSkew returns how far the server clock is ahead of the local one
+// (negative when behind). The Date header has one second resolution and the
+// server set it somewhere between sent and received; a clock that may be right
+// within that window reports 0, otherwise the skew is estimated from the
+// middle of it.
+func measureClockSkew(header http.Header, sent, received time.Time) (time.Duration, bool) {
+	value := header.Get("Date")
+	if value == "" {
+		return 0, false
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	earliest := date.Sub(received)            // server clock at least this far ahead
+	latest := date.Add(time.Second).Sub(sent) // and at most this far
+	if earliest <= 0 && latest >= 0 {
+		return 0, true
+	}
+	return (earliest + latest) / 2, true
+}
+
+// recordClockSkew stores the skew in the result and notes it when it is above the threshold
+func recordClockSkew(target *MonitorTarget, result *CheckResult, skew time.Duration) {
+	if result.Data == nil {
+		result.Data = make(map[string]interface{})
+	}
+	result.Data["clock_skew_ms"] = skew.Milliseconds()
+	if target.ID != 0 {
+		lastClockSkew.Store(target.ID, skew)
+	}
+
+	policy := clockSkewPolicy
+	if policy.Threshold <= 0 || skew.Abs() <= policy.Threshold {
+		return
+	}
+	result.Message = fmt.Sprintf("%s | Clock skew: %s", result.Message, describeClockSkew(skew))
+	if policy.Warn && result.Status == "up" {
+		result.Status = "warning"
+	}
+}
+
+// describeClockSkew formats a skew as "server clock is 72h0m0s ahead of local time"
+func describeClockSkew(skew time.Duration) string {
+	direction := "ahead of"
+	if skew < 0 {
+		direction = "behind"
+	}
+	return fmt.Sprintf("server clock is %s %s local time", skew.Abs().Round(time.Second), direction)
+}
+
+// certTimeHint explains a certificate that is expired or not yet valid: the
+// check compares it with the local clock, so a wrong clock is a likely cause.
+// Returns "" for other errors.
+func certTimeHint(err error, targetID uint32) string {
+	var certErr x509.CertificateInvalidError
+	if !errors.As(err, &certErr) || certErr.Reason != x509.Expired || certErr.Cert == nil {
+		return ""
+	}
+
+	now := time.Now()
+	var hint string
+	if now.Before(certErr.Cert.NotBefore) {
+		hint = fmt.Sprintf("certificate is valid from %s but the local clock reads %s, %s earlier",
+			certErr.Cert.NotBefore.UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339),
+			certErr.Cert.NotBefore.Sub(now).Round(time.Second))
+	} else {
+		hint = fmt.Sprintf("certificate expired at %s, the local clock reads %s",
+			certErr.Cert.NotAfter.UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339))
+	}
+	if skew, ok := lastClockSkew.Load(targetID); ok && targetID != 0 {
+		hint += "; last measured: " + describeClockSkew(skew.(time.Duration))
+	}
+	return hint
+}
@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMeasureClockSkew(t *testing.T) {
+	sent := time.Date(2026, 3, 1, 12, 0, 0, 200e6, time.UTC)
+	received := sent.Add(300 * time.Millisecond)
+	for _, tc := range []struct {
+		date string
+		skew time.Duration
+		ok   bool
+	}{
+		// The Date header truncates to the second, so 12:00:00 is within the window
+		{"Sun, 01 Mar 2026 12:00:00 GMT", 0, true},
+		{"Sun, 01 Mar 2026 13:00:00 GMT", time.Hour + 150*time.Millisecond, true},
+		{"Sun, 01 Mar 2026 11:00:00 GMT", -time.Hour + 150*time.Millisecond, true},
+		{"", 0, false},
+		{"yesterday", 0, false},
+	} {
+		header := http.Header{}
+		if tc.date != "" {
+			header.Set("Date", tc.date)
+		}
+		skew, ok := measureClockSkew(header, sent, received)
+		if skew != tc.skew || ok != tc.ok {
+			t.Errorf("Date %q: skew %v %v, want %v %v", tc.date, skew, ok, tc.skew, tc.ok)
+		}
+	}
+}
+
+func setClockSkewPolicy(t *testing.T, policy ClockSkewPolicy) {
+	t.Helper()
+	previous := clockSkewPolicy
+	SetClockSkewPolicy(policy)
+	t.Cleanup(func() { SetClockSkewPolicy(previous) })
+}
+
+func TestRecordClockSkew(t *testing.T) {
+	setClockSkewPolicy(t, ClockSkewPolicy{Threshold: time.Minute})
+	result := &CheckResult{Status: "up", Message: "HTTP 200"}
+	recordClockSkew(&MonitorTarget{}, result, 30*time.Second)
+	if result.Data["clock_skew_ms"] != int64(30000) || result.Message != "HTTP 200" || result.Status != "up" {
+		t.Errorf("skew below the threshold: %+v", result)
+	}
+
+	recordClockSkew(&MonitorTarget{}, result, -72*time.Hour)
+	if result.Message != "HTTP 200 | Clock skew: server clock is 72h0m0s behind local time" || result.Status != "up" {
+		t.Errorf("skew above the threshold: %+v", result)
+	}
+
+	setClockSkewPolicy(t, ClockSkewPolicy{Threshold: time.Minute, Warn: true})
+	result = &CheckResult{Status: "up"}
+	recordClockSkew(&MonitorTarget{}, result, 2*time.Minute)
+	if result.Status != "warning" {
+		t.Errorf("status %s with warn set, want warning", result.Status)
+	}
+	down := &CheckResult{Status: "down"}
+	recordClockSkew(&MonitorTarget{}, down, 2*time.Minute)
+	if down.Status != "down" {
+		t.Errorf("a down result became %s", down.Status)
+	}
+}
+
+func TestHTTPCheckClockSkew(t *testing.T) {
+	setClockSkewPolicy(t, ClockSkewPolicy{Threshold: time.Minute, Warn: true})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(2*time.Hour).UTC().Format(http.TimeFormat))
+	}))
+	t.Cleanup(srv.Close)
+
+	target := &MonitorTarget{ID: 4242, Name: "skewed", Type: "http", Address: srv.URL}
+	t.Cleanup(func() { lastClockSkew.Delete(target.ID) })
+	result, err := (&HTTPChecker{}).Check(context.Background(), target)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	skew, _ := result.Data["clock_skew_ms"].(int64)
+	if skew < (2*time.Hour-2*time.Second).Milliseconds() || skew > (2*time.Hour+2*time.Second).Milliseconds() {
+		t.Errorf("clock_skew_ms = %v, want about two hours", result.Data["clock_skew_ms"])
+	}
+	if result.Status != "warning" || !strings.Contains(result.Message, "ahead of local time") {
+		t.Errorf("status %s message %q", result.Status, result.Message)
+	}
+
+	// A certificate error afterwards mentions the measured skew
+	leaf := &x509.Certificate{NotBefore: time.Now().Add(time.Hour), NotAfter: time.Now().Add(48 * time.Hour)}
+	hint := certTimeHint(x509.CertificateInvalidError{Cert: leaf, Reason: x509.Expired}, target.ID)
+	if !strings.Contains(hint, "certificate is valid from") || !strings.Contains(hint, "last measured: server clock is 2h0m0s ahead") {
+		t.Errorf("hint %q", hint)
+	}
+	expired := &x509.Certificate{NotBefore: time.Now().Add(-48 * time.Hour), NotAfter: time.Now().Add(-time.Hour)}
+	if hint := certTimeHint(x509.CertificateInvalidError{Cert: expired, Reason: x509.Expired}, 0); !strings.HasPrefix(hint, "certificate expired at ") || strings.Contains(hint, "last measured") {
+		t.Errorf("expired hint %q", hint)
+	}
+	if certTimeHint(x509.CertificateInvalidError{Cert: leaf, Reason: x509.NotAuthorizedToSign}, target.ID) != "" {
+		t.Error("hint for an error that is not about validity")
+	}
+}
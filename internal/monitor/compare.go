@@ -0,0 +1,124 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// comparisonTypes are the types that can check a secondary address
+var comparisonTypes = map[string]bool{"http": true, "https": true, "tcp": true}
+
+// Comparison settings, declared by the catalog entries of comparisonTypes
+var (
+	secondaryAddressField        = FieldSpec{Name: "secondary_address", Kind: FieldString, Max: intBound(500), Description: "对比模式：每次同时检查的第二个地址（如迁移中的新负载均衡），其余设置相同；状态只由主地址决定"}
+	compareLatencyToleranceField = FieldSpec{Name: "compare_latency_tolerance", Kind: FieldInteger, Min: intBound(1), Description: "对比模式：两个地址响应时间之差超过该值（毫秒）视为不一致，0 为不比较"}
+	compareBodyField             = FieldSpec{Name: "compare_body", Kind: FieldBoolean, Description: "对比模式：同时比较响应体的 SHA-256（仅 HTTP）"}
+)
+
+// comparisonResults are returned by targets in comparison mode
+var comparisonResults = []ResultField{
+	{Name: "divergence", In: "data", Description: "对比模式：第二个地址的结果与主地址不一致"},
+	{Name: "comparison", In: "data", Description: "对比模式：不一致的项目及两个地址各自的结果"},
+}
+
+// ValidateComparison checks the comparison settings of a target
+func ValidateComparison(typ, secondaryAddress, unixSocketPath string, latencyTolerance int, compareBody bool) error {
+	if spec, ok := LookupType(typ); ok {
+		typ = spec.Type
+	}
+	if secondaryAddress == "" {
+		if latencyTolerance != 0 || compareBody {
+			return fmt.Errorf("compare_latency_tolerance and compare_body need a secondary_address")
+		}
+		return nil
+	}
+	if !comparisonTypes[typ] {
+		return fmt.Errorf("secondary_address is only supported for http, https and tcp monitors")
+	}
+	if compareBody && typ == "tcp" {
+		return fmt.Errorf("compare_body is only supported for http and https monitors")
+	}
+	if unixSocketPath != "" {
+		return fmt.Errorf("secondary_address is not supported together with unix_socket_path")
+	}
+	if latencyTolerance < 0 {
+		return fmt.Errorf("compare_latency_tolerance must not be negative")
+	}
+	return nil
+}
+
+// Comparison is the verdict of a check in comparison mode, stored in the
+// primary result's data together with both results
+type Comparison struct {
+	Divergence       bool           `json:"divergence"`
+	Differences      []string       `json:"differences"` // status, status_code, latency, body
+	LatencyTolerance int            `json:"latency_tolerance_ms,omitempty"`
+	Primary          ComparedResult `json:"primary"`
+	Secondary        ComparedResult `json:"secondary"`
+}
+
+// ComparedResult is one side of a comparison
+type ComparedResult struct {
+	Address      string `json:"address"`
+	Status       string `json:"status"`
+	StatusCode   int    `json:"status_code,omitempty"`
+	ResponseTime int64  `json:"response_time"`
+	Message      string `json:"message"`
+	BodySHA256   string `json:"body_sha256,omitempty"`
+}
+
+func newComparedResult(address string, result *CheckResult) ComparedResult {
+	return ComparedResult{
+		Address:      address,
+		Status:       result.Status,
+		StatusCode:   result.Response.StatusCode,
+		ResponseTime: result.ResponseTime,
+		Message:      result.Message,
+		BodySHA256:   result.bodySHA256,
+	}
+}
+
+// secondaryTarget is a copy of target that checks its secondary address
+func secondaryTarget(target *MonitorTarget) *MonitorTarget {
+	secondary := *target
+	secondary.Address = target.SecondaryAddress
+	return &secondary
+}
+
+// compareResults compares the results of the primary and the secondary
+// address. The primary result keeps its status; the verdict and both results
+// go into its data, with data.divergence set when they disagree.
+func compareResults(target *MonitorTarget, primary, secondary *CheckResult) *Comparison {
+	comparison := &Comparison{
+		Differences:      []string{},
+		LatencyTolerance: target.CompareLatencyTolerance,
+		Primary:          newComparedResult(target.Address, primary),
+		Secondary:        newComparedResult(target.SecondaryAddress, secondary),
+	}
+	p, s := comparison.Primary, comparison.Secondary
+	if p.Status != s.Status {
+		comparison.Differences = append(comparison.Differences, "status")
+	}
+	if p.StatusCode != s.StatusCode {
+		comparison.Differences = append(comparison.Differences, "status_code")
+	}
+	if tolerance := int64(target.CompareLatencyTolerance); tolerance > 0 {
+		if diff := p.ResponseTime - s.ResponseTime; diff > tolerance || -diff > tolerance {
+			comparison.Differences = append(comparison.Differences, "latency")
+		}
+	}
+	if target.CompareBody && p.BodySHA256 != s.BodySHA256 {
+		comparison.Differences = append(comparison.Differences, "body")
+	}
+	comparison.Divergence = len(comparison.Differences) > 0
+
+	if primary.Data == nil {
+		primary.Data = make(map[string]interface{})
+	}
+	primary.Data["divergence"] = comparison.Divergence
+	primary.Data["comparison"] = comparison
+	if comparison.Divergence {
+		primary.Message = fmt.Sprintf("%s (secondary %s differs: %s)", primary.Message, target.SecondaryAddress, strings.Join(comparison.Differences, ", "))
+	}
+	return comparison
+}
@@ -0,0 +1,118 @@
+package monitor
+
+import (
+	"encoding/json"
+
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// StatusConfigError is the status of an enabled target that is not scheduled
+// because its configuration is invalid
+const StatusConfigError = "config_error"
+
+// Reasons a target cannot be scheduled
+const (
+	ConfigErrorType     = "unsupported_type"      // no checker for the type
+	ConfigErrorSettings = "invalid_settings"      // settings rejected by the type catalog
+	ConfigErrorStored   = "invalid_stored_config" // stored JSON fields or lists do not parse
+)
+
+// TargetConfigError is why a target cannot be scheduled
+type TargetConfigError struct {
+	Reason string // one of the ConfigError* constants
+	Err    error
+}
+
+func (e *TargetConfigError) Error() string { return e.Err.Error() }
+
+func (e *TargetConfigError) Unwrap() error { return e.Err }
+
+// storedJSONFields are stored as JSON strings but declared as objects in the
+// catalog; NewTargetFromModel parses them
+var storedJSONFields = []string{"http_headers", "expected_headers", "metadata", "script_args", "steps", "dns_expected_values"}
+
+// PrepareTarget converts a stored target and checks that it can be scheduled:
+// its type has a checker, its settings pass the catalog of the type, and its
+// stored fields parse. The error is a *TargetConfigError.
+func PrepareTarget(model models.MonitorTarget) (*MonitorTarget, error) {
+	if _, err := NewChecker(model.Type); err != nil {
+		return nil, &TargetConfigError{Reason: ConfigErrorType, Err: err}
+	}
+
+	raw, err := json.Marshal(model)
+	if err != nil {
+		return nil, &TargetConfigError{Reason: ConfigErrorStored, Err: err}
+	}
+	var settings map[string]interface{}
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return nil, &TargetConfigError{Reason: ConfigErrorStored, Err: err}
+	}
+	for _, name := range storedJSONFields {
+		delete(settings, name)
+	}
+	if err := ValidateSettings(model.Type, settings); err != nil {
+		return nil, &TargetConfigError{Reason: ConfigErrorSettings, Err: err}
+	}
+
+	target, err := NewTargetFromModel(model)
+	if err != nil {
+		return nil, &TargetConfigError{Reason: ConfigErrorStored, Err: err}
+	}
+	if model.Type == TypeScript {
+		if err := ValidateScript(target.ScriptPath); err != nil {
+			return nil, &TargetConfigError{Reason: ConfigErrorSettings, Err: err}
+		}
+	}
+	return target, nil
+}
+
+// setConfigError records that a target is not scheduled and saves a
+// config_error status with the error as its message, so the dashboard shows
+// the target as broken instead of a status that never updates
+func (s *Service) setConfigError(targetID uint32, err *TargetConfigError) {
+	s.mu.Lock()
+	s.configErrors[targetID] = err
+	s.mu.Unlock()
+
+	db := database.GetDB()
+	now := s.clock.Now()
+	var status models.MonitorStatus
+	if db.Where("target_id = ?", targetID).First(&status).Error != nil {
+		status = models.MonitorStatus{TargetID: targetID}
+	}
+	if status.Status != StatusConfigError {
+		status.LastStatusChangeAt = &now
+	}
+	status.Status = StatusConfigError
+	status.Message = "Configuration error: " + err.Error()
+	status.ResponseTime = 0
+	status.CheckedAt = now
+	status.Synthetic = false
+	if err := db.Save(&status).Error; err != nil {
+		logger.Warn("Failed to save config error status", zap.Uint32("target_id", targetID), zap.Error(err))
+	}
+	s.InvalidateStatus()
+}
+
+func (s *Service) hasConfigError(targetID uint32) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, exists := s.configErrors[targetID]
+	return exists
+}
+
+// ConfigErrors returns the enabled targets that are not scheduled because of
+// their configuration, by target ID
+func (s *Service) ConfigErrors() map[uint32]*TargetConfigError {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	errs := make(map[uint32]*TargetConfigError, len(s.configErrors))
+	for id, err := range s.configErrors {
+		errs[id] = err
+	}
+	return errs
+}
@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+
+	"monitor/internal/models"
+)
+
+// Content change detection of http and https targets, declared in httpFields
+var (
+	trackContentHashField     = FieldSpec{Name: "track_content_hash", Kind: FieldBoolean, Default: false, Description: "记录响应体的 SHA-256，与上次检查不同时发出 content_changed 事件"}
+	contentHashRegexField     = FieldSpec{Name: "content_hash_regex", Kind: FieldString, Max: intBound(MaxBodyAssertionLength), Description: "只对匹配该正则的部分计算哈希（RE2 语法），有捕获组时取第一个捕获组，多个匹配用换行连接；没有匹配时按空内容计算"}
+	contentHashNormalizeField = FieldSpec{Name: "content_hash_normalize", Kind: FieldBoolean, Default: false, Description: "计算哈希前去掉 HTML 的 script、style 和注释，并合并空白"}
+)
+
+// contentHashResults are the result fields of track_content_hash, declared in httpResults
+var contentHashResults = []ResultField{
+	{Name: "content_hash", In: "data", Description: "响应体（或 content_hash_regex 匹配部分）的 SHA-256；开启 track_content_hash 且检查为 up 时返回"},
+	{Name: "content_changed", In: "data", Description: "content_hash 与上次记录的不同；目标的第一个哈希不算变化"},
+	{Name: "previous_content_hash", In: "data", Description: "变化前的 content_hash，content_changed 为 true 时返回"},
+}
+
+var (
+	// contentNoise is what content_hash_normalize drops: scripts, styles and
+	// comments, which often carry per-request nonces and timestamps
+	contentNoise = regexp.MustCompile(`(?is)<script\b.*?</script\s*>|<style\b.*?</style\s*>|<!--.*?-->`)
+	whitespace   = regexp.MustCompile(`\s+`)
+)
+
+// ValidateContentHash checks the content hash options of a target: they are
+// only supported for http and https, the scoping options need
+// track_content_hash, and the regex must compile
+func ValidateContentHash(typ string, track bool, regex string, normalize bool) error {
+	if !track {
+		if regex != "" || normalize {
+			return fmt.Errorf("content_hash_regex and content_hash_normalize require track_content_hash")
+		}
+		return nil
+	}
+	if spec, ok := LookupType(typ); ok {
+		typ = spec.Type
+	}
+	if typ != "http" && typ != "https" {
+		return fmt.Errorf("track_content_hash is only supported for http and https monitors")
+	}
+	if _, err := ParseContentHashRegex(regex); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ParseContentHashRegex compiles the content_hash_regex of a target, nil when it is empty
+func ParseContentHashRegex(s string) (*regexp.Regexp, error) {
+	if s == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid content_hash_regex: %w", err)
+	}
+	return re, nil
+}
+
+// contentHash returns the hex SHA-256 of the decoded body, scoped by the
+// content_hash_regex of the target and normalized if it asks for it. All
+// matches of the regex are hashed, joined by newlines.
+func contentHash(target *MonitorTarget, body []byte) string {
+	content := body
+	if re := target.ContentHashRegex; re != nil {
+		var parts [][]byte
+		for _, m := range re.FindAllSubmatch(body, -1) {
+			if len(m) > 1 {
+				parts = append(parts, m[1])
+			} else {
+				parts = append(parts, m[0])
+			}
+		}
+		content = bytes.Join(parts, []byte("\n"))
+	}
+	if target.ContentHashNormalize {
+		content = contentNoise.ReplaceAll(content, nil)
+		content = bytes.TrimSpace(whitespace.ReplaceAll(content, []byte(" ")))
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// recordContentHash compares the content_hash of a result with the one
+// stored on the status and keeps the new one. It reports whether the content
+// changed and the previous hash; the first hash of a target is not a change.
+// Results without a hash (down, or from before the option was turned on)
+// leave the stored hash alone.
+func recordContentHash(target *MonitorTarget, status *models.MonitorStatus, result *CheckResult) (changed bool, previous string) {
+	if !target.TrackContentHash {
+		status.ContentHash = ""
+		return false, ""
+	}
+	hash, _ := result.Data["content_hash"].(string)
+	if hash == "" || result.Synthetic {
+		return false, ""
+	}
+	previous = status.ContentHash
+	changed = previous != "" && previous != hash
+	result.Data["content_changed"] = changed
+	if changed {
+		result.Data["previous_content_hash"] = previous
+	}
+	status.ContentHash = hash
+	return changed, previous
+}
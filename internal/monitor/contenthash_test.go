@@ -0,0 +1,142 @@
+package monitor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestContentHash(t *testing.T) {
+	page := "<html><script>var nonce = 1;</script>\n  <p>price: 10</p>  <!-- rendered at 12:00 -->\n<p>stock: 3</p></html>"
+	for _, tc := range []struct {
+		name   string
+		target MonitorTarget
+		want   string
+	}{
+		{"whole body", MonitorTarget{}, sha256Hex(page)},
+		{"matches", MonitorTarget{ContentHashRegex: regexp.MustCompile(`<p>[^<]*</p>`)}, sha256Hex("<p>price: 10</p>\n<p>stock: 3</p>")},
+		{"first group", MonitorTarget{ContentHashRegex: regexp.MustCompile(`price: (\d+)`)}, sha256Hex("10")},
+		{"no match", MonitorTarget{ContentHashRegex: regexp.MustCompile(`absent`)}, sha256Hex("")},
+		{"normalized", MonitorTarget{ContentHashNormalize: true}, sha256Hex("<html> <p>price: 10</p> <p>stock: 3</p></html>")},
+	} {
+		if got := contentHash(&tc.target, []byte(page)); got != tc.want {
+			t.Errorf("%s: hash %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestValidateContentHash(t *testing.T) {
+	for _, tc := range []struct {
+		name, typ, regex string
+		track, normalize bool
+		ok               bool
+	}{
+		{"off", "tcp", "", false, false, true},
+		{"http", "http", `price: (\d+)`, true, true, true},
+		{"tcp", "tcp", "", true, false, false},
+		{"regex without tracking", "http", "x", false, false, false},
+		{"normalize without tracking", "http", "", false, true, false},
+		{"bad regex", "https", "(", true, false, false},
+	} {
+		if err := ValidateContentHash(tc.typ, tc.track, tc.regex, tc.normalize); (err == nil) != tc.ok {
+			t.Errorf("%s: err = %v", tc.name, err)
+		}
+	}
+}
+
+// A changed hash is flagged on the result and the history row and published
+// as a content_changed event; the first hash and down results are not changes
+func TestContentChanged(t *testing.T) {
+	s := newTestService(t)
+	// The request counter changes on every response and is left out by the regex
+	var price atomic.Value
+	price.Store("10")
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "<p>price: %s</p><p>request %d</p>", price.Load(), requests.Add(1))
+	}))
+	defer srv.Close()
+
+	target := &MonitorTarget{ID: 1, Name: "shop", Type: "http", Address: srv.URL, Interval: 3600,
+		TrackContentHash: true, ContentHashRegex: regexp.MustCompile(`price: (\d+)`)}
+	if err := s.AddTarget(target); err != nil {
+		t.Fatalf("AddTarget: %v", err)
+	}
+	s.SetSinks(target.ID, Sinks{SinkDBHistory})
+	events, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+
+	check := func() *CheckResult {
+		t.Helper()
+		result, err := (&HTTPChecker{}).Check(context.Background(), target)
+		if err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+		s.saveResult(target, result)
+		return result
+	}
+
+	if r := check(); r.Data["content_hash"] != sha256Hex("10") || r.Data["content_changed"] != false {
+		t.Errorf("first check data %v", r.Data)
+	}
+	check()
+	s.saveResult(target, &CheckResult{Status: "down", Message: "refused"})
+	price.Store("12")
+	r := check()
+	if r.Data["content_changed"] != true || r.Data["previous_content_hash"] != sha256Hex("10") {
+		t.Errorf("changed check data %v", r.Data)
+	}
+
+	var changes []StatusChangeEvent
+	for _, e := range drain(events) {
+		if e.Type == EventContentChanged {
+			changes = append(changes, e)
+		}
+	}
+	if len(changes) != 1 || changes[0].OldContentHash != sha256Hex("10") || changes[0].NewContentHash != sha256Hex("12") || changes[0].NewStatus != "up" {
+		t.Errorf("content_changed events %+v, want one from 10 to 12", changes)
+	}
+
+	var flagged []bool
+	waitFor(t, func() bool {
+		var rows []models.MonitorHistory
+		database.GetDB().Where("target_id = ?", target.ID).Order("id").Find(&rows)
+		flagged = flagged[:0]
+		for _, row := range rows {
+			flagged = append(flagged, row.ContentChanged)
+		}
+		return len(rows) == 4
+	})
+	if !slices.Equal(flagged, []bool{false, false, false, true}) {
+		t.Errorf("history content_changed %v", flagged)
+	}
+}
+
+func TestLintContentChangedWithoutTracking(t *testing.T) {
+	lint := func(track bool) bool {
+		cfg := &LintConfig{
+			Targets:  []models.MonitorTarget{{ID: 1, Name: "shop", Type: "http", Address: "http://a", Interval: 60, Enabled: true, TrackContentHash: track}},
+			Rules:    []models.AlertRule{{ID: 1, TargetID: 1, ChannelID: 1, Enabled: true, ThresholdType: "content_changed"}},
+			Channels: []models.AlertChannel{{ID: 1, Name: "ops", Type: "wechat", Enabled: true}},
+		}
+		return slices.ContainsFunc(Lint(cfg), func(f LintFinding) bool { return f.Lint == "rule_content_changed_without_tracking" })
+	}
+	if !lint(false) || lint(true) {
+		t.Errorf("finding without tracking %v, with tracking %v", lint(false), lint(true))
+	}
+}
@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"monitor/internal/logger"
+	"monitor/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ValidateDependency checks the monitor a target depends on: it must exist,
+// and following the dependencies from it must not lead back to the target.
+// targetID is 0 for a target not saved yet, which nothing can depend on.
+func ValidateDependency(db *gorm.DB, targetID uint32, dependsOn *uint32) error {
+	if dependsOn == nil {
+		return nil
+	}
+	if targetID != 0 && *dependsOn == targetID {
+		return errors.New("depends_on_target_id: a monitor cannot depend on itself")
+	}
+
+	chain := []string{fmt.Sprint(targetID)}
+	seen := map[uint32]bool{targetID: true}
+	for next := dependsOn; next != nil; {
+		id := *next
+		chain = append(chain, fmt.Sprint(id))
+		if seen[id] {
+			return fmt.Errorf("depends_on_target_id: circular dependency %s", strings.Join(chain, " -> "))
+		}
+		seen[id] = true
+
+		var target models.MonitorTarget
+		if err := db.Select("id", "depends_on_target_id").First(&target, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("depends_on_target_id: monitor %d not found", id)
+			}
+			return err
+		}
+		next = target.DependsOnTargetID
+	}
+	return nil
+}
+
+// suppressedBy returns the monitor a down result of target is blamed on: the
+// one it depends on, if its current status is down. Only down results are
+// suppressed; critical ones, e.g. for a certificate, are not caused by an
+// outage upstream. A dependency checked after the target only suppresses
+// from the target's next check on.
+func suppressedBy(db *gorm.DB, target *MonitorTarget, result *CheckResult) *uint32 {
+	if result.Status != "down" || target.DependsOnTargetID == nil {
+		return nil
+	}
+	var parent models.MonitorStatus
+	err := db.Select("status").Where("target_id = ?", *target.DependsOnTargetID).Take(&parent).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Warn("Failed to read the status of a dependency, not suppressing",
+				zap.Uint32("target_id", target.ID),
+				zap.Uint32("depends_on_target_id", *target.DependsOnTargetID),
+				zap.Error(err))
+		}
+		return nil
+	}
+	if parent.Status != "down" {
+		return nil
+	}
+	id := *target.DependsOnTargetID
+	return &id
+}
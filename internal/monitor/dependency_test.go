@@ -0,0 +1,86 @@
+package monitor
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+func TestValidateDependency(t *testing.T) {
+	newTestService(t)
+	db := database.GetDB()
+	// 1 <- 2 <- 3
+	createTargets(t, 60, 60, 60)
+	one, two := uint32(1), uint32(2)
+	db.Model(&models.MonitorTarget{}).Where("id = ?", 2).Update("depends_on_target_id", one)
+	db.Model(&models.MonitorTarget{}).Where("id = ?", 3).Update("depends_on_target_id", two)
+	three, missing := uint32(3), uint32(99)
+
+	for _, tc := range []struct {
+		targetID  uint32
+		dependsOn *uint32
+		err       string
+	}{
+		{1, nil, ""},
+		{0, &three, ""},
+		{1, &one, "itself"},
+		{0, &missing, "monitor 99 not found"},
+		{1, &three, "circular dependency 1 -> 3 -> 2 -> 1"},
+	} {
+		err := ValidateDependency(db, tc.targetID, tc.dependsOn)
+		if (err == nil) != (tc.err == "") || (err != nil && !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("ValidateDependency(%d, %v) = %v, want %q", tc.targetID, tc.dependsOn, err, tc.err)
+		}
+	}
+}
+
+// A down result of a monitor whose dependency is down is saved as suppressed
+// and not alerted on
+func TestSuppressedByDependency(t *testing.T) {
+	s := newTestService(t)
+	var mu sync.Mutex
+	var alerted []string
+	s.SetAlertHandler(func(target *MonitorTarget, result *CheckResult, previousStatus string) {
+		mu.Lock()
+		defer mu.Unlock()
+		alerted = append(alerted, target.Name+" "+result.Status)
+	})
+	router := &MonitorTarget{ID: 1, Name: "router", Type: "tcp", Address: "127.0.0.1", Port: 1, Interval: 3600}
+	server := &MonitorTarget{ID: 2, Name: "server", Type: "tcp", Address: "127.0.0.1", Port: 2, Interval: 3600, DependsOnTargetID: &router.ID}
+	for _, target := range []*MonitorTarget{router, server} {
+		if err := s.AddTarget(target); err != nil {
+			t.Fatalf("AddTarget: %v", err)
+		}
+		s.SetSinks(target.ID, Sinks{SinkDBHistory})
+	}
+
+	// Without a status of the router nothing is suppressed
+	s.saveResult(server, &CheckResult{Status: "down"})
+	s.saveResult(router, &CheckResult{Status: "down"})
+	s.saveResult(server, &CheckResult{Status: "down"})
+	s.saveResult(server, &CheckResult{Status: "critical"})
+	s.saveResult(router, &CheckResult{Status: "up"})
+	s.saveResult(server, &CheckResult{Status: "down"})
+	s.flushHistory()
+
+	mu.Lock()
+	got := strings.Join(alerted, ", ")
+	mu.Unlock()
+	if want := "server down, router down, server critical, router up, server down"; got != want {
+		t.Errorf("alerted %s, want %s", got, want)
+	}
+
+	var history []models.MonitorHistory
+	database.GetDB().Where("target_id = ?", server.ID).Order("id").Find(&history)
+	if len(history) != 4 {
+		t.Fatalf("%d history rows, want 4", len(history))
+	}
+	for i, h := range history {
+		if suppressed := h.SuppressedBy != nil && *h.SuppressedBy == router.ID; suppressed != (i == 1) {
+			t.Errorf("history %d (%s) suppressed_by %v", i, h.Status, h.SuppressedBy)
+		}
+	}
+}
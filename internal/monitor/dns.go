@@ -3,6 +3,7 @@ package monitor
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"strings"
@@ -14,17 +15,67 @@ import (
 	"go.uber.org/zap"
 )
 
+func init() {
+	RegisterType(TypeSpec{
+		Type:        "dns",
+		DisplayName: "DNS 解析",
+		Fields: []FieldSpec{
+			{Name: "dns_server", Kind: FieldString, Default: "8.8.8.8:53", Description: "DNS 服务器，host:port 或 DoH 地址"},
+			{Name: "dns_server_name", Kind: FieldString, Description: "DNS 服务器显示名称"},
+			{Name: "dns_server_type", Kind: FieldString, Default: "udp", Enum: []string{"udp", "tcp", "doh", "dot"}, Description: "DNS 协议"},
+			{Name: "dns_servers", Kind: FieldString, Description: "同时查询并比较答案的多个服务器，逗号分隔的服务商 ID 或地址（host:port、udp://、tcp://、tls://、https://），设置后忽略 dns_server"},
+			{Name: "dns_consensus", Kind: FieldString, Default: DNSConsensusAll, Enum: []string{DNSConsensusAll, DNSConsensusMajority, DNSConsensusAny, DNSConsensusPropagation}, Description: "多服务器的判定策略：全部一致、多数一致、任一应答，或 propagation（解析传播：全部一致为 up，部分不同为 degraded；dns_servers 为空时查询所有 DNS 服务商）"},
+			{Name: "dns_query_type", Kind: FieldString, Default: "A", Enum: dnsresolver.QueryTypes, Description: "查询的记录类型；PTR 查询的地址可以是 IP"},
+			{Name: "dns_expected_values", Kind: FieldArray, Description: "期望的记录，不区分顺序；名称不区分大小写和末尾的点。答案不符合时为 down，可用于发现 DNS 劫持"},
+			{Name: "dns_match_mode", Kind: FieldString, Default: DNSMatchExact, Enum: []string{DNSMatchExact, DNSMatchSubset}, Description: "exact：记录与期望完全相同；subset：每条记录都在期望之中（如 CDN 从地址池中返回几个）"},
+			tlsClientCertField,
+			tlsClientKeyField,
+			tlsCACertField,
+			tlsSkipVerifyField,
+			tlsPinSHA256Field,
+			degradedThresholdField,
+			downThresholdField,
+		},
+		Results: []ResultField{
+			{Name: "dns_answered_by", In: "data", Description: "实际应答的服务器"},
+			{Name: "dns_protocol", In: "data", Description: "实际使用的协议"},
+			{Name: "dns_fallback", In: "data", Description: "是否回退到其他协议"},
+			{Name: "doh_mode", In: "data", Description: "DoH 请求方式"},
+			{Name: "dns_attempts", In: "data", Description: "每次尝试的记录"},
+			{Name: "dns_servers", In: "data", Description: "多服务器比较时每个服务器的答案、耗时或错误"},
+			{Name: "dns_agreeing", In: "data", Description: "给出共识答案的服务器数"},
+			{Name: "dns_answer_sets", In: "data", Description: "不同答案的数量，大于 1 表示不一致"},
+			{Name: "dns_failed", In: "data", Description: "查询失败的服务器数"},
+			{Name: "dns_differing", In: "data", Description: "答案与共识不同的服务器"},
+			{Name: "dns_query_type", In: "data", Description: "查询的记录类型"},
+			{Name: "dns_rcode", In: "data", Description: "响应码，如 NOERROR、NXDOMAIN、SERVFAIL"},
+			{Name: "dns_authoritative", In: "data", Description: "应答是否来自权威服务器（AA 标志）"},
+			{Name: "dns_ttl", In: "data", Description: "查询类型记录中最小的 TTL（秒）"},
+			{Name: "dns_missing", In: "data", Description: "答案中缺少的期望记录，设置 dns_expected_values 且不符合时返回"},
+			{Name: "dns_unexpected", In: "data", Description: "答案中不在期望之中的记录，设置 dns_expected_values 且不符合时返回"},
+			responseTimeThresholdResult,
+		},
+	}, func() Checker { return &DNSChecker{} })
+}
+
 type DNSChecker struct{}
 
+// DNSRecordInfo is the values of one record type, stored as dns_records.
+// TTL is the lowest TTL of the values and TTLs that of each value.
 type DNSRecordInfo struct {
 	Type  string   `json:"type"`
 	Value []string `json:"value"`
 	TTL   uint32   `json:"ttl,omitempty"`
+	TTLs  []uint32 `json:"ttls,omitempty"`
 }
 
 func (c *DNSChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckResult, error) {
 	start := time.Now()
 
+	if len(target.DNSServers) > 0 || target.DNSConsensus == DNSConsensusPropagation {
+		return c.checkConsensus(ctx, target, start)
+	}
+
 	// Default DNS server configuration
 	dnsServer := target.DNSServer
 	dnsServerType := target.DNSServerType
@@ -48,8 +99,17 @@ func (c *DNSChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckRe
 		zap.String("dns_server_type", dnsServerType),
 	)
 
+	// DoT 服务器按目标的 CA、客户端证书和验证设置握手
+	tlsConfig, err := target.tlsConfig()
+	if err != nil {
+		return tlsConfigResult(err, time.Since(start).Milliseconds()), nil
+	}
+
 	// Create resolver
 	resolver := dnsresolver.NewResolver(dnsServer, dnsresolver.DNSType(dnsServerType))
+	queryType := target.dnsQueryType()
+	resolver.QueryType = queryType
+	resolver.TLSConfig = tlsConfig
 
 	// Perform lookup
 	result, err := resolver.Lookup(ctx, target.Address)
@@ -65,57 +125,46 @@ func (c *DNSChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckRe
 			zap.Error(err),
 		)
 
-		return &CheckResult{
+		checkResult := &CheckResult{
 			Status:       status,
 			ResponseTime: time.Since(start).Milliseconds(),
 			Message:      message,
+			Request: RequestDetails{
+				Method:  "DNS",
+				URL:     target.Address,
+				Headers: map[string]string{"dns_query_type": queryType},
+			},
 			Error: &ErrorDetails{
 				Type:    "dns_error",
 				Message: err.Error(),
 			},
-		}, nil
-	}
+		}
 
-	// Convert result to DNSRecordInfo format
-	allRecords := make([]DNSRecordInfo, 0)
+		var lookupErr *dnsresolver.LookupError
+		if errors.As(err, &lookupErr) && len(lookupErr.Attempts) > 0 {
+			// 最后一次尝试决定错误类型和响应详情
+			last := lookupErr.Attempts[len(lookupErr.Attempts)-1]
+			if details := dnsTLSErrorDetails(last); details != nil {
+				checkResult.Error.Type = details.Type
+			}
+			if last.StatusCode != 0 {
+				checkResult.Error.Type = "doh_http_error"
+				checkResult.Request.URL = last.URL
+				checkResult.Response = ResponseDetails{
+					StatusCode: last.StatusCode,
+					Body:       last.Body,
+				}
+			}
+			checkResult.Data = map[string]interface{}{
+				"dns_attempts": dnsAttempts(lookupErr.Attempts),
+			}
+		}
 
-	if len(result.A) > 0 {
-		allRecords = append(allRecords, DNSRecordInfo{
-			Type:  "A",
-			Value: result.A,
-		})
-	}
-	if len(result.AAAA) > 0 {
-		allRecords = append(allRecords, DNSRecordInfo{
-			Type:  "AAAA",
-			Value: result.AAAA,
-		})
-	}
-	if len(result.CNAME) > 0 {
-		allRecords = append(allRecords, DNSRecordInfo{
-			Type:  "CNAME",
-			Value: result.CNAME,
-		})
-	}
-	if len(result.MX) > 0 {
-		allRecords = append(allRecords, DNSRecordInfo{
-			Type:  "MX",
-			Value: result.MX,
-		})
-	}
-	if len(result.TXT) > 0 {
-		allRecords = append(allRecords, DNSRecordInfo{
-			Type:  "TXT",
-			Value: result.TXT,
-		})
-	}
-	if len(result.NS) > 0 {
-		allRecords = append(allRecords, DNSRecordInfo{
-			Type:  "NS",
-			Value: result.NS,
-		})
+		return checkResult, nil
 	}
 
+	allRecords := dnsRecordInfos(result)
+
 	responseTime := time.Since(start).Milliseconds()
 
 	// Build detailed message
@@ -130,13 +179,31 @@ func (c *DNSChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckRe
 	if target.DNSServerName != "" {
 		message.WriteString(fmt.Sprintf("via %s (%s); ", target.DNSServerName, dnsServerType))
 	} else {
-		message.WriteString(fmt.Sprintf("via %s (%s); ", dnsServer, dnsServerType))
+		message.WriteString(fmt.Sprintf("via %s (%s); ", result.Server, dnsServerType))
+	}
+	if result.Fallback {
+		message.WriteString(fmt.Sprintf("fallback after %d failed server(s); ", len(result.Failures)))
 	}
 
-	// Determine overall status
+	// Determine overall status: an error response code is down, NOERROR
+	// without records of the type (NODATA) a warning; an A or AAAA query
+	// answered with a CNAME only still resolves
 	status := "up"
-	if len(result.A) == 0 && len(result.AAAA) == 0 && len(result.CNAME) == 0 {
+	rcodeFailed := dnsRCodeFailed(result)
+	switch {
+	case rcodeFailed:
+		status = "down"
+	case len(result.Records(queryType)) == 0 && !((queryType == "A" || queryType == "AAAA") && len(result.CNAME) > 0):
 		status = "warning"
+		message.WriteString(fmt.Sprintf("no %s records (NODATA); ", queryType))
+	}
+	var mismatch string
+	var missing, unexpected []string
+	if !rcodeFailed && len(target.DNSExpectedValues) > 0 {
+		mismatch, missing, unexpected = checkDNSExpectation(target, result)
+		if mismatch != "" {
+			status = "down"
+		}
 	}
 
 	logger.Info("DNS lookup completed",
@@ -144,6 +211,8 @@ func (c *DNSChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckRe
 		zap.String("address", target.Address),
 		zap.String("dns_server", dnsServer),
 		zap.String("dns_server_type", dnsServerType),
+		zap.String("answered_by", result.Server),
+		zap.Bool("fallback", result.Fallback),
 		zap.Int("total_records", totalRecords),
 		zap.Int64("response_time", responseTime),
 		zap.String("status", status),
@@ -152,26 +221,133 @@ func (c *DNSChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckRe
 	// Convert records to JSON for storage
 	recordsJSON, _ := json.Marshal(allRecords)
 
-	return &CheckResult{
+	data := map[string]interface{}{
+		"dns_answered_by":   result.Server,
+		"dns_protocol":      string(result.Protocol),
+		"dns_fallback":      result.Fallback,
+		"dns_query_type":    queryType,
+		"dns_authoritative": result.Authoritative,
+	}
+	if result.RCode != "" {
+		data["dns_rcode"] = result.RCode
+	}
+	if ttl, ok := result.MinTTL(queryType); ok {
+		data["dns_ttl"] = ttl
+	}
+	if result.DoHMode != "" {
+		data["doh_mode"] = result.DoHMode
+	}
+	if len(result.Failures) > 0 {
+		data["dns_attempts"] = dnsAttempts(result.Failures)
+	}
+
+	checkResult := &CheckResult{
 		Status:       status,
 		ResponseTime: responseTime,
 		Message:      message.String(),
+		Data:         data,
 		Request: RequestDetails{
-			Method: "DNS",
-			URL:    target.Address,
+			Method:  "DNS",
+			URL:     target.Address,
+			Headers: map[string]string{"dns_query_type": queryType},
 		},
 		Response: ResponseDetails{
 			Headers: map[string]string{
 				"dns_server":      dnsServer,
 				"dns_server_name": target.DNSServerName,
 				"dns_server_type": dnsServerType,
+				"dns_answered_by": result.Server,
+				"dns_rcode":       result.RCode,
 				"a_count":         fmt.Sprintf("%d", len(result.A)),
 				"aaaa_count":       fmt.Sprintf("%d", len(result.AAAA)),
 				"total_types":      fmt.Sprintf("%d", len(allRecords)),
 			},
 			Body: string(recordsJSON),
 		},
-	}, nil
+	}
+	if rcodeFailed {
+		reason := fmt.Sprintf("%s for %s %s", result.RCode, target.Address, queryType)
+		checkResult.Message = reason + "; " + checkResult.Message
+		checkResult.Error = &ErrorDetails{Type: dnsRCodeErrorType(result.RCode), Message: reason}
+	}
+	if mismatch != "" {
+		checkResult.Message = mismatch + "; " + checkResult.Message
+		checkResult.Error = &ErrorDetails{Type: "dns_unexpected_answer", Message: mismatch}
+		if len(missing) > 0 {
+			data["dns_missing"] = missing
+		}
+		if len(unexpected) > 0 {
+			data["dns_unexpected"] = unexpected
+		}
+	}
+	return checkResult, nil
+}
+
+// dnsRecordInfos converts a lookup result to the records stored with the status
+func dnsRecordInfos(result *dnsresolver.DNSQueryResult) []DNSRecordInfo {
+	allRecords := make([]DNSRecordInfo, 0)
+
+	for _, queryType := range dnsresolver.QueryTypes {
+		values := result.Records(queryType)
+		if len(values) == 0 {
+			continue
+		}
+		record := DNSRecordInfo{
+			Type:  queryType,
+			Value: values,
+		}
+		if ttl, ok := result.MinTTL(queryType); ok {
+			record.TTL = ttl
+			record.TTLs = result.TTLs[queryType]
+		}
+		allRecords = append(allRecords, record)
+	}
+
+	return allRecords
+}
+
+// dnsRCodeErrorType is the error type of an answer with an error response
+// code: dns_nxdomain, dns_servfail, dns_refused or dns_rcode_error
+func dnsRCodeErrorType(rcode string) string {
+	switch rcode {
+	case "NXDOMAIN", "SERVFAIL", "REFUSED":
+		return "dns_" + strings.ToLower(rcode)
+	}
+	return "dns_rcode_error"
+}
+
+// dnsRCodeFailed reports whether an answer carries an error response code;
+// answers of the system resolver have none
+func dnsRCodeFailed(result *dnsresolver.DNSQueryResult) bool {
+	return result.RCode != "" && result.RCode != "NOERROR"
+}
+
+// dnsAttempts 将失败的查询转换为 Data 中保存的结构，按尝试顺序排列
+func dnsAttempts(attempts []*dnsresolver.QueryError) []map[string]interface{} {
+	list := make([]map[string]interface{}, 0, len(attempts))
+	for _, attempt := range attempts {
+		entry := map[string]interface{}{
+			"server":   attempt.Server,
+			"protocol": string(attempt.Protocol),
+		}
+		if attempt.Err != nil {
+			entry["error"] = attempt.Err.Error()
+		}
+		if attempt.URL != "" {
+			entry["url"] = attempt.URL
+		}
+		if attempt.Mode != "" {
+			entry["doh_mode"] = attempt.Mode
+		}
+		if attempt.StatusCode != 0 {
+			entry["status_code"] = attempt.StatusCode
+		}
+		if attempt.Body != "" {
+			entry["body"] = attempt.Body
+		}
+		list = append(list, entry)
+	}
+	return list
 }
 
 // Fallback to system DNS if no custom server specified
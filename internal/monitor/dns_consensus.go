@@ -0,0 +1,496 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"monitor/internal/logger"
+	"monitor/internal/models"
+	dnsresolver "monitor/pkg/dns"
+
+	"go.uber.org/zap"
+)
+
+// DNS consensus policies for targets with several dns_servers
+const (
+	DNSConsensusAll      = "all"      // every server answers and all answers are the same
+	DNSConsensusMajority = "majority" // more than half of the servers give the same answer
+	DNSConsensusAny      = "any"      // at least one server answers
+	// DNSConsensusPropagation queries every saved DNS provider when
+	// dns_servers is empty: up when all agree, degraded while some differ
+	DNSConsensusPropagation = "propagation"
+)
+
+// dnsServerTimeout 多服务器比较时每个服务器单独的超时
+const dnsServerTimeout = 10 * time.Second
+
+// dnsServerConcurrency 多服务器比较时同时进行的查询数
+const dnsServerConcurrency = 8
+
+// DNSServerRef is one entry of dns_servers: the ID of a saved DNS provider or
+// a server address
+type DNSServerRef struct {
+	ProviderID uint
+	Server     string // address for the resolver; empty for providers
+	Type       string // udp, tcp, doh, dot; empty for providers
+	Raw        string // the entry as written
+}
+
+// ParseDNSServers parses the comma-separated dns_servers of a target. An entry
+// is a DNS provider ID or an address: host[:port] (UDP), udp://, tcp:// or
+// tls:// followed by host[:port], or an https:// DoH URL.
+func ParseDNSServers(s string) ([]DNSServerRef, error) {
+	var refs []DNSServerRef
+	for _, entry := range dnsresolver.SplitServers(s) {
+		ref, err := parseDNSServerRef(entry)
+		if err != nil {
+			return nil, fmt.Errorf("dns_servers: %w", err)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+func parseDNSServerRef(entry string) (DNSServerRef, error) {
+	ref := DNSServerRef{Raw: entry}
+	if id, err := strconv.ParseUint(entry, 10, 32); err == nil {
+		if id == 0 {
+			return ref, fmt.Errorf("invalid provider ID %q", entry)
+		}
+		ref.ProviderID = uint(id)
+		return ref, nil
+	}
+
+	if strings.HasPrefix(entry, "https://") {
+		ref.Server = entry
+		ref.Type = string(dnsresolver.DNSTypeDoH)
+		return ref, nil
+	}
+
+	hostport, defaultPort := entry, "53"
+	ref.Type = string(dnsresolver.DNSTypeUDP)
+	if scheme, rest, ok := strings.Cut(entry, "://"); ok {
+		switch scheme {
+		case "udp":
+		case "tcp":
+			ref.Type = string(dnsresolver.DNSTypeTCP)
+		case "tls":
+			ref.Type = string(dnsresolver.DNSTypeDoT)
+			defaultPort = "853"
+		default:
+			return ref, fmt.Errorf("unsupported scheme in %q, use udp, tcp, tls or https", entry)
+		}
+		hostport = rest
+	}
+
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host, port = strings.Trim(hostport, "[]"), defaultPort
+	}
+	if host == "" || strings.ContainsAny(host, "/ ") {
+		return ref, fmt.Errorf("invalid DNS server %q", entry)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return ref, fmt.Errorf("invalid port in %q", entry)
+	}
+	ref.Server = net.JoinHostPort(host, port)
+	return ref, nil
+}
+
+// DNSServerSpec is a dns_servers entry with its provider looked up
+type DNSServerSpec struct {
+	Name       string
+	Server     string
+	Type       string
+	ProviderID uint
+}
+
+// ResolveDNSServers looks up the providers referenced by refs. Providers are
+// cached until InvalidateDNSProviders, which the provider API calls after
+// every change, so editing one takes effect without reloading targets.
+func ResolveDNSServers(ctx context.Context, refs []DNSServerRef) ([]DNSServerSpec, error) {
+	specs := make([]DNSServerSpec, 0, len(refs))
+	var providers map[uint]models.DNSProvider
+	for _, ref := range refs {
+		if ref.ProviderID == 0 {
+			specs = append(specs, DNSServerSpec{Name: ref.Raw, Server: ref.Server, Type: ref.Type})
+			continue
+		}
+		if providers == nil {
+			all, err := dnsProviders.get(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load DNS providers: %w", err)
+			}
+			providers = make(map[uint]models.DNSProvider, len(all))
+			for _, provider := range all {
+				providers[provider.ID] = provider
+			}
+		}
+		provider, ok := providers[ref.ProviderID]
+		if !ok {
+			return nil, fmt.Errorf("DNS provider %d not found", ref.ProviderID)
+		}
+		specs = append(specs, dnsProviderSpec(provider))
+	}
+	return specs, nil
+}
+
+// allDNSProviders returns every saved DNS provider in ID order, the servers
+// of a propagation check without dns_servers
+func allDNSProviders(ctx context.Context) ([]DNSServerSpec, error) {
+	providers, err := dnsProviders.get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load DNS providers: %w", err)
+	}
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("no DNS providers configured")
+	}
+	specs := make([]DNSServerSpec, 0, len(providers))
+	for _, provider := range providers {
+		specs = append(specs, dnsProviderSpec(provider))
+	}
+	return specs, nil
+}
+
+// dnsServerAnswer 一个服务器的查询结果
+type dnsServerAnswer struct {
+	spec         DNSServerSpec
+	result       *dnsresolver.DNSQueryResult
+	answers      []string // 查询类型的记录去重排序后的集合，用于比较
+	responseTime int64
+	err          error
+}
+
+// dnsAnswerSet returns the sorted, de-duplicated values of an answer: A, AAAA
+// and CNAME for address queries, the normalized records of the query type
+// otherwise. An error response code is the set "RCODE NXDOMAIN" etc., so
+// NXDOMAIN, SERVFAIL and an empty answer differ. Two servers agree when
+// their sets are equal.
+func dnsAnswerSet(result *dnsresolver.DNSQueryResult, queryType string) []string {
+	if dnsRCodeFailed(result) {
+		return []string{"RCODE " + result.RCode}
+	}
+	if queryType != "A" && queryType != "AAAA" {
+		set := dnsValueSet(queryType, result.Records(queryType))
+		for i, v := range set {
+			set[i] = queryType + " " + v
+		}
+		return set
+	}
+	seen := make(map[string]bool)
+	var set []string
+	add := func(prefix string, values []string) {
+		for _, v := range values {
+			v = prefix + strings.ToLower(strings.TrimSuffix(v, "."))
+			if !seen[v] {
+				seen[v] = true
+				set = append(set, v)
+			}
+		}
+	}
+	add("", result.A)
+	add("", result.AAAA)
+	add("CNAME ", result.CNAME)
+	sort.Strings(set)
+	return set
+}
+
+// dnsAnswerPropagated reports whether an answer is the one a propagation
+// check waits for: not an error response code, and the expected records when
+// dns_expected_values is set
+func dnsAnswerPropagated(target *MonitorTarget, result *dnsresolver.DNSQueryResult) bool {
+	if dnsRCodeFailed(result) {
+		return false
+	}
+	if len(target.DNSExpectedValues) == 0 {
+		return true
+	}
+	mismatch, _, _ := checkDNSExpectation(target, result)
+	return mismatch == ""
+}
+
+// checkConsensus queries every server in dns_servers concurrently and compares
+// the answers according to the target's consensus policy
+func (c *DNSChecker) checkConsensus(ctx context.Context, target *MonitorTarget, start time.Time) (*CheckResult, error) {
+	policy := target.DNSConsensus
+	if policy == "" {
+		policy = DNSConsensusAll
+	}
+	queryType := target.dnsQueryType()
+
+	request := RequestDetails{
+		Method: "DNS",
+		URL:    target.Address,
+		Headers: detailHeaders(map[string]interface{}{
+			"dns_consensus":  policy,
+			"dns_servers":    len(target.DNSServers),
+			"dns_query_type": queryType,
+		}),
+	}
+
+	tlsConfig, err := target.tlsConfig()
+	if err != nil {
+		result := tlsConfigResult(err, time.Since(start).Milliseconds())
+		result.Request = request
+		return result, nil
+	}
+
+	var specs []DNSServerSpec
+	if len(target.DNSServers) == 0 {
+		specs, err = allDNSProviders(ctx)
+	} else {
+		specs, err = ResolveDNSServers(ctx, target.DNSServers)
+	}
+	if err != nil {
+		message := fmt.Sprintf("DNS servers not usable: %v", err)
+		return &CheckResult{
+			Status:       "down",
+			ResponseTime: time.Since(start).Milliseconds(),
+			Message:      message,
+			Request:      request,
+			Error:        &ErrorDetails{Type: "config_error", Message: message},
+		}, nil
+	}
+	request.Headers["dns_servers"] = strconv.Itoa(len(specs))
+
+	// 同时最多查询 dnsServerConcurrency 个服务器，每个服务器单独超时，
+	// 不应答的服务器不会拖住其他服务器
+	answers := make([]*dnsServerAnswer, len(specs))
+	sem := make(chan struct{}, dnsServerConcurrency)
+	var wg sync.WaitGroup
+	for i, spec := range specs {
+		wg.Add(1)
+		go func(i int, spec DNSServerSpec) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			queryCtx, cancel := context.WithTimeout(ctx, dnsServerTimeout)
+			defer cancel()
+
+			resolver := dnsresolver.NewResolver(spec.Server, dnsresolver.DNSType(spec.Type))
+			resolver.Timeout = dnsServerTimeout
+			resolver.QueryType = queryType
+			resolver.TLSConfig = tlsConfig
+			queryStart := time.Now()
+			result, err := resolver.Lookup(queryCtx, target.Address)
+			answer := &dnsServerAnswer{spec: spec, result: result, err: err, responseTime: time.Since(queryStart).Milliseconds()}
+			if err == nil {
+				answer.answers = dnsAnswerSet(result, queryType)
+			}
+			answers[i] = answer
+		}(i, spec)
+	}
+	wg.Wait()
+
+	// 按答案分组，最大的一组作为共识；组的顺序为人数从多到少，再按服务器顺序
+	type answerGroup struct {
+		key     string
+		members []*dnsServerAnswer
+	}
+	var groups []*answerGroup
+	var failed []*dnsServerAnswer
+	for _, answer := range answers {
+		if answer.err != nil {
+			failed = append(failed, answer)
+			continue
+		}
+		key := strings.Join(answer.answers, ",")
+		var group *answerGroup
+		for _, g := range groups {
+			if g.key == key {
+				group = g
+				break
+			}
+		}
+		if group == nil {
+			group = &answerGroup{key: key}
+			groups = append(groups, group)
+		}
+		group.members = append(group.members, answer)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return len(groups[i].members) > len(groups[j].members) })
+
+	// 传播检查中只要有服务器答对就不是 down：答对的最大一组作为共识，即使
+	// 多数服务器还返回错误响应码；没有一组答对时仍以最大的一组为准
+	if policy == DNSConsensusPropagation {
+		for i, group := range groups {
+			if dnsAnswerPropagated(target, group.members[0].result) {
+				copy(groups[1:i+1], groups[:i])
+				groups[0] = group
+				break
+			}
+		}
+	}
+
+	agreeing := 0
+	if len(groups) > 0 {
+		agreeing = len(groups[0].members)
+	}
+	total := len(answers)
+
+	var met bool
+	switch policy {
+	case DNSConsensusMajority:
+		// 人数相同的两组都不算多数
+		met = agreeing*2 > total && (len(groups) < 2 || len(groups[1].members) < agreeing)
+	case DNSConsensusAny, DNSConsensusPropagation:
+		met = agreeing > 0
+	default:
+		met = len(failed) == 0 && len(groups) == 1
+	}
+
+	// 失败类型：全部失败、答案不一致、部分服务器失败
+	var errType string
+	switch {
+	case agreeing == 0:
+		errType = "dns_error"
+	case len(groups) > 1:
+		errType = "dns_mismatch"
+	case len(failed) > 0:
+		errType = "dns_partial_failure"
+	}
+
+	// 共识的答案是错误响应码时为 down
+	if met && dnsRCodeFailed(groups[0].members[0].result) {
+		met = false
+		errType = dnsRCodeErrorType(groups[0].members[0].result.RCode)
+	}
+
+	// 共识的答案还要符合期望的记录
+	var mismatch string
+	var missing, unexpected []string
+	if met && len(target.DNSExpectedValues) > 0 {
+		mismatch, missing, unexpected = checkDNSExpectation(target, groups[0].members[0].result)
+		if mismatch != "" {
+			met = false
+			errType = "dns_unexpected_answer"
+		}
+	}
+
+	status := "up"
+	switch {
+	case !met:
+		status = "down"
+	case errType != "" && policy == DNSConsensusPropagation:
+		status = "degraded"
+	case errType != "" || groups[0].key == "":
+		status = "warning"
+	}
+
+	// 消息：策略和一致数量，然后每组服务器的答案和失败的服务器
+	var message strings.Builder
+	if mismatch != "" {
+		message.WriteString(mismatch + "; ")
+	}
+	message.WriteString(fmt.Sprintf("%d/%d servers agree (policy %s); ", agreeing, total, policy))
+	for _, group := range groups {
+		names := make([]string, 0, len(group.members))
+		for _, member := range group.members {
+			names = append(names, member.spec.Name)
+		}
+		value := group.key
+		if value == "" {
+			value = "no records"
+		}
+		message.WriteString(fmt.Sprintf("%s: %s; ", strings.Join(names, ", "), value))
+	}
+	for _, answer := range failed {
+		message.WriteString(fmt.Sprintf("%s failed: %v; ", answer.spec.Name, answer.err))
+	}
+
+	servers := make([]map[string]interface{}, 0, len(answers))
+	for _, answer := range answers {
+		entry := map[string]interface{}{
+			"name":          answer.spec.Name,
+			"server":        answer.spec.Server,
+			"protocol":      answer.spec.Type,
+			"response_time": answer.responseTime,
+		}
+		if answer.spec.ProviderID != 0 {
+			entry["provider_id"] = answer.spec.ProviderID
+		}
+		if answer.err != nil {
+			entry["error"] = answer.err.Error()
+			if details := dnsTLSErrorDetails(answer.err); details != nil {
+				entry["error_type"] = details.Type
+			}
+		} else {
+			entry["answers"] = answer.answers
+			entry["answered_by"] = answer.result.Server
+			entry["rcode"] = answer.result.RCode
+			entry["authoritative"] = answer.result.Authoritative
+			if ttl, ok := answer.result.MinTTL(queryType); ok {
+				entry["ttl"] = ttl
+			}
+		}
+		servers = append(servers, entry)
+	}
+
+	responseTime := time.Since(start).Milliseconds()
+	result := &CheckResult{
+		Status:       status,
+		ResponseTime: responseTime,
+		Message:      message.String(),
+		Request:      request,
+		Data: map[string]interface{}{
+			"dns_consensus":   policy,
+			"dns_servers":     servers,
+			"dns_agreeing":    agreeing,
+			"dns_answer_sets": len(groups),
+			"dns_failed":      len(failed),
+			"dns_query_type":  queryType,
+		},
+		Response: ResponseDetails{
+			Headers: detailHeaders(map[string]interface{}{
+				"dns_agreeing":    agreeing,
+				"dns_answer_sets": len(groups),
+				"dns_failed":      len(failed),
+			}),
+		},
+	}
+	if len(groups) > 0 {
+		// 共识组的记录作为本次的 DNS 记录保存
+		recordsJSON, _ := json.Marshal(dnsRecordInfos(groups[0].members[0].result))
+		result.Response.Body = string(recordsJSON)
+	}
+	if errType != "" {
+		result.Error = &ErrorDetails{Type: errType, Message: result.Message}
+	}
+	if len(groups) > 1 {
+		// 答案与共识不同的服务器
+		var differing []string
+		for _, group := range groups[1:] {
+			for _, member := range group.members {
+				differing = append(differing, member.spec.Name)
+			}
+		}
+		result.Data["dns_differing"] = differing
+	}
+	if len(missing) > 0 {
+		result.Data["dns_missing"] = missing
+	}
+	if len(unexpected) > 0 {
+		result.Data["dns_unexpected"] = unexpected
+	}
+
+	logger.Info("DNS consensus check completed",
+		zap.String("target", target.Name),
+		zap.String("address", target.Address),
+		zap.String("policy", policy),
+		zap.Int("servers", total),
+		zap.Int("agreeing", agreeing),
+		zap.Int("answer_sets", len(groups)),
+		zap.Int("failed", len(failed)),
+		zap.Int64("response_time", responseTime),
+		zap.String("status", status),
+	)
+
+	return result, nil
+}
@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func checkDNSServers(t *testing.T, target *MonitorTarget, answers ...fakeDNSAnswer) *CheckResult {
+	t.Helper()
+	for _, answer := range answers {
+		server := startDNSServer(t, answer)
+		target.DNSServers = append(target.DNSServers, DNSServerRef{Server: server, Type: "udp", Raw: server})
+	}
+	result, err := (&DNSChecker{}).Check(context.Background(), target)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	return result
+}
+
+var (
+	nxdomain = fakeDNSAnswer{RCode: dnsmessage.RCodeNameError}
+	newIP    = fakeDNSAnswer{A: []string{"192.0.2.10"}}
+	oldIP    = fakeDNSAnswer{A: []string{"192.0.2.1"}}
+)
+
+func TestPropagationDegradedWhenMostServersReturnRCodeError(t *testing.T) {
+	target := &MonitorTarget{Name: "new record", Type: "dns", Address: "new.example.com", DNSConsensus: DNSConsensusPropagation}
+	result := checkDNSServers(t, target, nxdomain, nxdomain, newIP)
+
+	if result.Status != "degraded" {
+		t.Fatalf("status = %q, want degraded (message %q)", result.Status, result.Message)
+	}
+	if result.Error == nil || result.Error.Type != "dns_mismatch" {
+		t.Errorf("error = %+v, want dns_mismatch", result.Error)
+	}
+	if agreeing := result.Data["dns_agreeing"]; agreeing != 1 {
+		t.Errorf("dns_agreeing = %v, want 1, the server with the record", agreeing)
+	}
+}
+
+func TestPropagationDownWithoutCorrectAnswer(t *testing.T) {
+	target := &MonitorTarget{Name: "missing record", Type: "dns", Address: "gone.example.com", DNSConsensus: DNSConsensusPropagation}
+	result := checkDNSServers(t, target, nxdomain, nxdomain)
+
+	if result.Status != "down" {
+		t.Fatalf("status = %q, want down (message %q)", result.Status, result.Message)
+	}
+	if result.Error == nil || result.Error.Type != "dns_nxdomain" {
+		t.Errorf("error = %+v, want dns_nxdomain", result.Error)
+	}
+}
+
+func TestPropagationPrefersExpectedAnswer(t *testing.T) {
+	target := &MonitorTarget{Name: "moved", Type: "dns", Address: "moved.example.com", DNSConsensus: DNSConsensusPropagation,
+		DNSExpectedValues: []string{"192.0.2.10"}}
+	result := checkDNSServers(t, target, oldIP, oldIP, newIP)
+
+	if result.Status != "degraded" {
+		t.Fatalf("status = %q, want degraded (message %q)", result.Status, result.Message)
+	}
+}
+
+func TestMajorityKeepsRCodeConsensus(t *testing.T) {
+	target := &MonitorTarget{Name: "majority", Type: "dns", Address: "new.example.com", DNSConsensus: DNSConsensusMajority}
+	result := checkDNSServers(t, target, nxdomain, nxdomain, newIP)
+
+	if result.Status != "down" {
+		t.Fatalf("status = %q, want down (message %q)", result.Status, result.Message)
+	}
+}
@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// serveDoT answers one length-prefixed query on conn with answer
+func serveDoT(answer fakeDNSAnswer) func(*tls.Conn) {
+	return func(conn *tls.Conn) {
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		lengthBuf := make([]byte, 2)
+		if _, err := io.ReadFull(conn, lengthBuf); err != nil {
+			return
+		}
+		buf := make([]byte, int(lengthBuf[0])<<8|int(lengthBuf[1]))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return
+		}
+		var query dnsmessage.Message
+		if err := query.Unpack(buf); err != nil || len(query.Questions) == 0 {
+			return
+		}
+		resp := fakeDNSResponse(query, answer)
+		packed, err := resp.Pack()
+		if err != nil {
+			return
+		}
+		conn.Write(append([]byte{byte(len(packed) >> 8), byte(len(packed))}, packed...))
+	}
+}
+
+// A dot target handshakes with its own CA, and is down with a certificate
+// error when the server's certificate isn't trusted
+func TestDNSCheckDoTUsesTargetCA(t *testing.T) {
+	cert := newTestCert(t, time.Now().Add(24*time.Hour))
+	addr := startTLSServer(t, cert, serveDoT(fakeDNSAnswer{A: []string{"192.0.2.20"}}))
+
+	target := &MonitorTarget{
+		Name:          "dot",
+		Type:          "dns",
+		Address:       "example.test",
+		DNSServer:     addr,
+		DNSServerType: "dot",
+		TLSCACertPEM:  cert.PEM,
+		// A mismatch would be down, so up means the answer came through
+		DNSExpectedValues: []string{"192.0.2.20"},
+	}
+	result, err := (&DNSChecker{}).Check(context.Background(), target)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if result.Status != "up" || !strings.Contains(result.Message, "(dot)") {
+		t.Fatalf("status %s: %s, want up over dot", result.Status, result.Message)
+	}
+
+	target.TLSCACertPEM = ""
+	result, err = (&DNSChecker{}).Check(context.Background(), target)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if result.Status != "down" || !strings.Contains(result.Message, "certificate") {
+		t.Errorf("status %s: %s, want down with a certificate error", result.Status, result.Message)
+	}
+}
+
+// A DoH server answering with an HTTP error is down with doh_http_error and
+// the response kept; a fallback server's answer records the earlier failure
+func TestDNSCheckDoHFailures(t *testing.T) {
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("upstream unreachable"))
+	}))
+	t.Cleanup(bad.Close)
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Status":0,"Answer":[{"name":"example.test.","type":1,"TTL":60,"data":"192.0.2.40"}]}`))
+	}))
+	t.Cleanup(good.Close)
+
+	target := &MonitorTarget{Name: "doh", Type: "dns", Address: "example.test", DNSServer: bad.URL, DNSServerType: "doh"}
+	result, err := (&DNSChecker{}).Check(context.Background(), target)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if result.Status != "down" || result.Error == nil || result.Error.Type != "doh_http_error" {
+		t.Fatalf("status %s error %+v, want down with doh_http_error", result.Status, result.Error)
+	}
+	if result.Response.StatusCode != http.StatusBadGateway || result.Response.Body != "upstream unreachable" ||
+		result.Request.URL != bad.URL+"?name=example.test&type=A" {
+		t.Errorf("response %d %q request %q", result.Response.StatusCode, result.Response.Body, result.Request.URL)
+	}
+
+	target.DNSServer = bad.URL + "," + good.URL
+	result, err = (&DNSChecker{}).Check(context.Background(), target)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if result.Status != "up" || result.Data["dns_answered_by"] != good.URL || result.Data["dns_fallback"] != true ||
+		result.Data["doh_mode"] != "json" {
+		t.Fatalf("status %s data %v, want up from the fallback", result.Status, result.Data)
+	}
+	attempts, _ := result.Data["dns_attempts"].([]map[string]interface{})
+	if len(attempts) != 1 || attempts[0]["server"] != bad.URL || attempts[0]["status_code"] != http.StatusBadGateway {
+		t.Errorf("dns_attempts = %v", attempts)
+	}
+}
@@ -0,0 +1,173 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	dnsresolver "monitor/pkg/dns"
+)
+
+// MaxDNSExpectedValues bounds the dns_expected_values of a target
+const MaxDNSExpectedValues = 50
+
+// How the records of a dns target are compared with dns_expected_values
+const (
+	// DNSMatchExact requires the records to be exactly the expected values
+	DNSMatchExact = "exact"
+	// DNSMatchSubset requires every record to be one of the expected values,
+	// e.g. for a CDN answering with a few addresses out of a known pool
+	DNSMatchSubset = "subset"
+)
+
+// ValidateDNSExpectation checks the query settings of a target: they are
+// only supported for dns, and the values must be valid for the query type
+func ValidateDNSExpectation(typ, queryType string, values []string, mode string) error {
+	if queryType == "" && len(values) == 0 && mode == "" {
+		return nil
+	}
+	if spec, ok := LookupType(typ); ok {
+		typ = spec.Type
+	}
+	if typ != "dns" {
+		return fmt.Errorf("dns_query_type, dns_expected_values and dns_match_mode are only supported for dns monitors")
+	}
+	queryType, err := dnsresolver.ParseQueryType(queryType)
+	if err != nil {
+		return err
+	}
+	switch mode {
+	case "", DNSMatchExact, DNSMatchSubset:
+	default:
+		return fmt.Errorf("dns_match_mode must be %s or %s", DNSMatchExact, DNSMatchSubset)
+	}
+	if len(values) > MaxDNSExpectedValues {
+		return fmt.Errorf("dns_expected_values: at most %d values, got %d", MaxDNSExpectedValues, len(values))
+	}
+	for _, value := range values {
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("dns_expected_values cannot contain empty values")
+		}
+		ip := net.ParseIP(strings.TrimSpace(value))
+		switch {
+		case queryType == "A" && (ip == nil || ip.To4() == nil):
+			return fmt.Errorf("dns_expected_values: %q is not an IPv4 address", value)
+		case queryType == "AAAA" && (ip == nil || ip.To4() != nil):
+			return fmt.Errorf("dns_expected_values: %q is not an IPv6 address", value)
+		}
+	}
+	return nil
+}
+
+// ParseDNSExpectedValues decodes the stored dns_expected_values JSON array
+func ParseDNSExpectedValues(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var values []string
+	if err := json.Unmarshal([]byte(s), &values); err != nil {
+		return nil, fmt.Errorf("dns_expected_values: %w", err)
+	}
+	return values, nil
+}
+
+// normalizeDNSValue puts a record value in the form records are compared in:
+// addresses in canonical form, and names, which are case-insensitive, in
+// lower case without the trailing dot. TXT and CAA values are kept as they are.
+func normalizeDNSValue(queryType, value string) string {
+	value = strings.TrimSpace(value)
+	switch queryType {
+	case "A", "AAAA":
+		if ip := net.ParseIP(value); ip != nil {
+			return ip.String()
+		}
+		return value
+	case "TXT", "CAA":
+		return value
+	}
+	fields := strings.Fields(strings.ToLower(value))
+	for i, field := range fields {
+		fields[i] = strings.TrimSuffix(field, ".")
+	}
+	return strings.Join(fields, " ")
+}
+
+// dnsValueSet returns the normalized, de-duplicated and sorted values
+func dnsValueSet(queryType string, values []string) []string {
+	seen := make(map[string]bool, len(values))
+	set := make([]string, 0, len(values))
+	for _, value := range values {
+		value = normalizeDNSValue(queryType, value)
+		if !seen[value] {
+			seen[value] = true
+			set = append(set, value)
+		}
+	}
+	sort.Strings(set)
+	return set
+}
+
+// compareDNSRecords compares the records of the query type with the expected
+// values, ignoring order and duplicates. It returns the expected values that
+// are missing (only for DNSMatchExact) and the records that were not expected.
+func compareDNSRecords(queryType string, records, expected []string, mode string) (missing, unexpected []string) {
+	got := dnsValueSet(queryType, records)
+	want := dnsValueSet(queryType, expected)
+	wanted := make(map[string]bool, len(want))
+	for _, value := range want {
+		wanted[value] = true
+	}
+	present := make(map[string]bool, len(got))
+	for _, value := range got {
+		present[value] = true
+		if !wanted[value] {
+			unexpected = append(unexpected, value)
+		}
+	}
+	if mode != DNSMatchSubset {
+		for _, value := range want {
+			if !present[value] {
+				missing = append(missing, value)
+			}
+		}
+	}
+	return missing, unexpected
+}
+
+// checkDNSExpectation compares the answer of a lookup with the expected
+// values of target and returns the reason it does not match, "" when it does.
+// An empty answer never matches.
+func checkDNSExpectation(target *MonitorTarget, result *dnsresolver.DNSQueryResult) (reason string, missing, unexpected []string) {
+	queryType := target.dnsQueryType()
+	records := result.Records(queryType)
+	mode := target.DNSMatchMode
+	if mode == "" {
+		mode = DNSMatchExact
+	}
+	if len(records) == 0 {
+		return fmt.Sprintf("no %s records, expected %s", queryType, strings.Join(dnsValueSet(queryType, target.DNSExpectedValues), ", ")), nil, nil
+	}
+	missing, unexpected = compareDNSRecords(queryType, records, target.DNSExpectedValues, mode)
+	if len(missing) == 0 && len(unexpected) == 0 {
+		return "", nil, nil
+	}
+	var parts []string
+	if len(missing) > 0 {
+		parts = append(parts, "missing "+strings.Join(missing, ", "))
+	}
+	if len(unexpected) > 0 {
+		parts = append(parts, "unexpected "+strings.Join(unexpected, ", "))
+	}
+	return fmt.Sprintf("%s records do not match the expected values (%s): %s", queryType, mode, strings.Join(parts, "; ")), missing, unexpected
+}
+
+// dnsQueryType is the query type of a dns target, A when it is not set
+func (t *MonitorTarget) dnsQueryType() string {
+	queryType, err := dnsresolver.ParseQueryType(t.DNSQueryType)
+	if err != nil {
+		return "A"
+	}
+	return queryType
+}
@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestValidateDNSExpectation(t *testing.T) {
+	for _, tc := range []struct {
+		name, typ, queryType string
+		values               []string
+		mode                 string
+		ok                   bool
+	}{
+		{"nothing", "tcp", "", nil, "", true},
+		{"a", "dns", "", []string{"192.0.2.1"}, "", true},
+		{"mx subset", "dns", "mx", []string{"10 mail.example.com."}, DNSMatchSubset, true},
+		{"other type", "http", "A", nil, "", false},
+		{"unknown query type", "dns", "ANY", nil, "", false},
+		{"unknown mode", "dns", "A", []string{"192.0.2.1"}, "superset", false},
+		{"ipv6 for a", "dns", "A", []string{"2001:db8::1"}, "", false},
+		{"ipv4 for aaaa", "dns", "AAAA", []string{"192.0.2.1"}, "", false},
+		{"empty value", "dns", "TXT", []string{" "}, "", false},
+	} {
+		if err := ValidateDNSExpectation(tc.typ, tc.queryType, tc.values, tc.mode); (err == nil) != tc.ok {
+			t.Errorf("%s: err = %v", tc.name, err)
+		}
+	}
+}
+
+func TestCompareDNSRecords(t *testing.T) {
+	for _, tc := range []struct {
+		name, queryType     string
+		records, expected   []string
+		mode                string
+		missing, unexpected []string
+	}{
+		{"same in another order", "A", []string{"192.0.2.2", "192.0.2.1", "192.0.2.1"}, []string{"192.0.2.1", "192.0.2.2"}, DNSMatchExact, nil, nil},
+		{"addresses normalized", "AAAA", []string{"2001:db8::1"}, []string{"2001:DB8:0::1"}, DNSMatchExact, nil, nil},
+		{"names normalized", "MX", []string{"10 Mail.Example.com."}, []string{"10 mail.example.com"}, DNSMatchExact, nil, nil},
+		{"txt case kept", "TXT", []string{"v=SPF1"}, []string{"v=spf1"}, DNSMatchExact, []string{"v=spf1"}, []string{"v=SPF1"}},
+		{"exact missing", "A", []string{"192.0.2.1"}, []string{"192.0.2.1", "192.0.2.2"}, DNSMatchExact, []string{"192.0.2.2"}, nil},
+		{"subset of the pool", "A", []string{"192.0.2.1"}, []string{"192.0.2.1", "192.0.2.2"}, DNSMatchSubset, nil, nil},
+		{"subset outside the pool", "A", []string{"192.0.2.9"}, []string{"192.0.2.1"}, DNSMatchSubset, nil, []string{"192.0.2.9"}},
+	} {
+		missing, unexpected := compareDNSRecords(tc.queryType, tc.records, tc.expected, tc.mode)
+		if !slices.Equal(missing, tc.missing) || !slices.Equal(unexpected, tc.unexpected) {
+			t.Errorf("%s: missing %q unexpected %q, want %q %q", tc.name, missing, unexpected, tc.missing, tc.unexpected)
+		}
+	}
+}
+
+func TestDNSCheckExpectedValues(t *testing.T) {
+	server := startDNSServer(t, fakeDNSAnswer{A: []string{"192.0.2.1", "192.0.2.2"}})
+	check := func(values []string, mode string) *CheckResult {
+		t.Helper()
+		target := &MonitorTarget{Name: "dns", Type: "dns", Address: "example.test", DNSServer: server, DNSServerType: "udp",
+			DNSQueryType: "A", DNSExpectedValues: values, DNSMatchMode: mode}
+		result, err := (&DNSChecker{}).Check(context.Background(), target)
+		if err != nil {
+			t.Fatalf("check: %v", err)
+		}
+		return result
+	}
+
+	if r := check([]string{"192.0.2.2", "192.0.2.1"}, ""); r.Status != "up" || r.Data["dns_query_type"] != "A" {
+		t.Errorf("matching answer: %s %q", r.Status, r.Message)
+	}
+	if r := check([]string{"192.0.2.1", "192.0.2.2", "192.0.2.3"}, DNSMatchSubset); r.Status != "up" {
+		t.Errorf("answer within the pool: %s %q", r.Status, r.Message)
+	}
+	r := check([]string{"192.0.2.1", "192.0.2.3"}, DNSMatchExact)
+	if r.Status != "down" || r.Error == nil || r.Error.Type != "dns_unexpected_answer" {
+		t.Fatalf("mismatch: %s %q %+v", r.Status, r.Message, r.Error)
+	}
+	if missing, _ := r.Data["dns_missing"].([]string); !slices.Equal(missing, []string{"192.0.2.3"}) {
+		t.Errorf("dns_missing %v", r.Data["dns_missing"])
+	}
+	if unexpected, _ := r.Data["dns_unexpected"].([]string); !slices.Equal(unexpected, []string{"192.0.2.2"}) {
+		t.Errorf("dns_unexpected %v", r.Data["dns_unexpected"])
+	}
+}
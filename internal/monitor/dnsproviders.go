@@ -0,0 +1,112 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+)
+
+// DNSProviderCacheStats counters of the DNS provider cache. Loads is lower
+// than Misses when concurrent misses shared one database read.
+type DNSProviderCacheStats struct {
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Loads   int64 `json:"loads"`
+	Entries int   `json:"entries"`
+}
+
+// dnsProviderCache holds every DNS provider for the checks that reference
+// them. Providers are few and a propagation check reads all of them, so the
+// table is cached as a whole until InvalidateDNSProviders.
+type dnsProviderCache struct {
+	mu        sync.Mutex
+	db        *gorm.DB // the connection providers was read from; another one is not cached yet
+	providers []models.DNSProvider
+	gen       uint64 // bumped by invalidate so that loads started before it are neither stored nor joined
+	group     singleflight.Group
+
+	hits, misses, loads atomic.Int64
+}
+
+var dnsProviders = &dnsProviderCache{}
+
+// get returns the providers in ID order
+func (c *dnsProviderCache) get(ctx context.Context) ([]models.DNSProvider, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	c.mu.Lock()
+	providers, ok := c.providers, c.db == db
+	gen := c.gen
+	c.mu.Unlock()
+	if ok {
+		c.hits.Add(1)
+		return providers, nil
+	}
+	c.misses.Add(1)
+
+	// A burst of checks after an invalidation waits for one read instead of each
+	// querying; the read is shared, so it doesn't end with the first caller's ctx
+	v, err, _ := c.group.Do(fmt.Sprintf("%d:%p", gen, db), func() (interface{}, error) {
+		c.loads.Add(1)
+		providers := []models.DNSProvider{}
+		if err := db.WithContext(context.WithoutCancel(ctx)).Order("id").Find(&providers).Error; err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		if c.gen == gen {
+			c.db, c.providers = db, providers
+		}
+		c.mu.Unlock()
+		return providers, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]models.DNSProvider), nil
+}
+
+func (c *dnsProviderCache) invalidate() {
+	c.mu.Lock()
+	c.db, c.providers = nil, nil
+	c.gen++
+	c.mu.Unlock()
+}
+
+// InvalidateDNSProviders drops the cached DNS providers; call it after a
+// provider is created, updated or deleted
+func InvalidateDNSProviders() {
+	dnsProviders.invalidate()
+}
+
+// GetDNSProviderCacheStats returns the hit/miss counters of the DNS provider cache
+func GetDNSProviderCacheStats() DNSProviderCacheStats {
+	dnsProviders.mu.Lock()
+	entries := len(dnsProviders.providers)
+	dnsProviders.mu.Unlock()
+	return DNSProviderCacheStats{
+		Hits:    dnsProviders.hits.Load(),
+		Misses:  dnsProviders.misses.Load(),
+		Loads:   dnsProviders.loads.Load(),
+		Entries: entries,
+	}
+}
+
+// dnsProviderSpec is the server spec of a provider
+func dnsProviderSpec(provider models.DNSProvider) DNSServerSpec {
+	return DNSServerSpec{
+		Name:       provider.Name,
+		Server:     provider.Server,
+		Type:       provider.ServerType,
+		ProviderID: provider.ID,
+	}
+}
@@ -0,0 +1,54 @@
+package monitor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+// Providers are read once and again only after InvalidateDNSProviders
+func TestDNSProviderCache(t *testing.T) {
+	newTestService(t)
+	db := database.GetDB()
+	db.Where("1 = 1").Delete(&models.DNSProvider{})
+	provider := models.DNSProvider{Name: "primary", Server: "192.0.2.1:53", ServerType: "udp"}
+	if err := db.Create(&provider).Error; err != nil {
+		t.Fatalf("create provider: %v", err)
+	}
+	InvalidateDNSProviders()
+	before := GetDNSProviderCacheStats()
+
+	refs := []DNSServerRef{{ProviderID: provider.ID}, {Raw: "198.51.100.1:53", Server: "198.51.100.1:53", Type: "udp"}}
+	specs, err := ResolveDNSServers(context.Background(), refs)
+	if err != nil || len(specs) != 2 || specs[0].Server != "192.0.2.1:53" || specs[0].Name != "primary" || specs[1].Server != "198.51.100.1:53" {
+		t.Fatalf("ResolveDNSServers = %+v, %v", specs, err)
+	}
+
+	// An edit outside the API is not seen until the cache is invalidated
+	db.Model(&provider).Update("server", "192.0.2.2:53")
+	if specs, _ := allDNSProviders(context.Background()); len(specs) != 1 || specs[0].Server != "192.0.2.1:53" {
+		t.Errorf("allDNSProviders before invalidate = %+v, want the cached server", specs)
+	}
+	stats := GetDNSProviderCacheStats()
+	if stats.Misses-before.Misses != 1 || stats.Loads-before.Loads != 1 || stats.Hits-before.Hits != 1 || stats.Entries != 1 {
+		t.Errorf("stats %+v after a miss and a hit, was %+v", stats, before)
+	}
+
+	InvalidateDNSProviders()
+	if specs, _ := allDNSProviders(context.Background()); len(specs) != 1 || specs[0].Server != "192.0.2.2:53" {
+		t.Errorf("allDNSProviders after invalidate = %+v, want the new server", specs)
+	}
+
+	if _, err := ResolveDNSServers(context.Background(), []DNSServerRef{{ProviderID: provider.ID + 1}}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("unknown provider: err = %v", err)
+	}
+
+	db.Delete(&provider)
+	InvalidateDNSProviders()
+	if _, err := allDNSProviders(context.Background()); err == nil || !strings.Contains(err.Error(), "no DNS providers") {
+		t.Errorf("no providers: err = %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	dnsresolver "monitor/pkg/dns"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestDNSCheckRCodeAndTTL(t *testing.T) {
+	check := func(answer fakeDNSAnswer, queryType string) *CheckResult {
+		t.Helper()
+		target := &MonitorTarget{Name: "dns", Type: "dns", Address: "example.test", DNSServer: startDNSServer(t, answer), DNSServerType: "udp", DNSQueryType: queryType}
+		result, err := (&DNSChecker{}).Check(context.Background(), target)
+		if err != nil {
+			t.Fatalf("check: %v", err)
+		}
+		return result
+	}
+
+	r := check(fakeDNSAnswer{A: []string{"192.0.2.1"}}, "A")
+	if r.Status != "up" || r.Data["dns_rcode"] != "NOERROR" || r.Data["dns_authoritative"] != true || r.Data["dns_ttl"] != uint32(300) {
+		t.Errorf("answer: %s %v", r.Status, r.Data)
+	}
+	var records []DNSRecordInfo
+	if err := json.Unmarshal([]byte(r.Response.Body), &records); err != nil || len(records) != 1 || records[0].TTL != 300 || len(records[0].TTLs) != 1 {
+		t.Errorf("dns_records %s: %v", r.Response.Body, err)
+	}
+
+	// An error response code is down with its own type, not an empty answer
+	for rcode, errType := range map[dnsmessage.RCode]string{
+		dnsmessage.RCodeNameError:      "dns_nxdomain",
+		dnsmessage.RCodeServerFailure:  "dns_servfail",
+		dnsmessage.RCodeRefused:        "dns_refused",
+		dnsmessage.RCodeNotImplemented: "dns_rcode_error",
+	} {
+		r := check(fakeDNSAnswer{RCode: rcode}, "A")
+		if r.Status != "down" || r.Error == nil || r.Error.Type != errType || !strings.HasPrefix(r.Message, dnsresolver.RCodeName(int(rcode))+" for example.test A") {
+			t.Errorf("%s: %s %q %+v", dnsresolver.RCodeName(int(rcode)), r.Status, r.Message, r.Error)
+		}
+	}
+
+	// NOERROR without records of the type is NODATA, a warning
+	if r := check(fakeDNSAnswer{A: []string{"192.0.2.1"}}, "MX"); r.Status != "warning" || !strings.Contains(r.Message, "no MX records (NODATA)") || r.Data["dns_ttl"] != nil {
+		t.Errorf("NODATA: %s %q %v", r.Status, r.Message, r.Data)
+	}
+}
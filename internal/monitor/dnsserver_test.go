@@ -0,0 +1,63 @@
+package monitor
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeDNSAnswer is what a fake DNS server answers to every A query
+type fakeDNSAnswer struct {
+	RCode dnsmessage.RCode
+	A     []string
+}
+
+// startDNSServer serves answer over UDP on a local port until the end of the
+// test and returns its address
+func startDNSServer(t *testing.T, answer fakeDNSAnswer) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) == 0 {
+				continue
+			}
+			resp := fakeDNSResponse(query, answer)
+			packed, err := resp.Pack()
+			if err != nil {
+				t.Errorf("pack response: %v", err)
+				return
+			}
+			conn.WriteTo(packed, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func fakeDNSResponse(query dnsmessage.Message, answer fakeDNSAnswer) dnsmessage.Message {
+	q := query.Questions[0]
+	resp := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true, RCode: answer.RCode},
+		Questions: []dnsmessage.Question{q},
+	}
+	for _, a := range answer.A {
+		resp.Answers = append(resp.Answers, dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 300},
+			Body:   &dnsmessage.AResource{A: netip.MustParseAddr(a).As4()},
+		})
+	}
+	return resp
+}
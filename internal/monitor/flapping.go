@@ -0,0 +1,100 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+)
+
+// FlappingPolicy decides when a target bouncing between statuses is flapping:
+// more than Transitions status changes within Window. A flapping target gets
+// one flapping alert instead of an alert per change, and stops flapping once
+// its status has not changed for Cooldown.
+type FlappingPolicy struct {
+	Enabled     bool
+	Transitions int
+	Window      time.Duration
+	Cooldown    time.Duration
+}
+
+// FlappingChange is how a result changed the flapping state of its target
+type FlappingChange int
+
+const (
+	FlappingUnchanged FlappingChange = iota
+	FlappingStarted
+	FlappingEnded
+)
+
+// flapState is the flapping state of one target
+type flapState struct {
+	last        string      // last status, maintenance results left out
+	transitions []time.Time // status changes within the window, oldest first
+	flapping    bool
+	changedAt   time.Time // last status change
+}
+
+// flapDetector tracks the status changes of each target
+type flapDetector struct {
+	mu      sync.Mutex
+	policy  FlappingPolicy
+	targets map[uint32]*flapState
+}
+
+func newFlapDetector() *flapDetector {
+	return &flapDetector{targets: make(map[uint32]*flapState)}
+}
+
+// record adds a saved result and returns whether the target is flapping and
+// how the result changed that. Results checked during maintenance windows
+// neither count as changes nor end flapping.
+func (d *flapDetector) record(targetID uint32, status string, now time.Time) (bool, FlappingChange) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.policy.Enabled {
+		return false, FlappingUnchanged
+	}
+	st := d.targets[targetID]
+	if st == nil {
+		st = &flapState{}
+		d.targets[targetID] = st
+	}
+	if status == StatusMaintenance {
+		return st.flapping, FlappingUnchanged
+	}
+
+	if st.last != "" && status != st.last {
+		st.changedAt = now
+		st.transitions = append(st.transitions, now)
+	}
+	st.last = status
+
+	cutoff := now.Add(-d.policy.Window)
+	for len(st.transitions) > 0 && !st.transitions[0].After(cutoff) {
+		st.transitions = st.transitions[1:]
+	}
+
+	switch {
+	case !st.flapping && len(st.transitions) > d.policy.Transitions:
+		st.flapping = true
+		return true, FlappingStarted
+	case st.flapping && now.Sub(st.changedAt) >= d.policy.Cooldown:
+		st.flapping = false
+		st.transitions = nil
+		return false, FlappingEnded
+	}
+	return st.flapping, FlappingUnchanged
+}
+
+// forget drops the state of a removed target
+func (d *flapDetector) forget(targetID uint32) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.targets, targetID)
+}
+
+// SetFlappingPolicy enables flapping detection; it must be called before targets are added
+func (s *Service) SetFlappingPolicy(policy FlappingPolicy) {
+	s.flapping.mu.Lock()
+	defer s.flapping.mu.Unlock()
+	s.flapping.policy = policy
+}
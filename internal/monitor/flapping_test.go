@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFlapDetector(t *testing.T) {
+	d := newFlapDetector()
+	d.policy = FlappingPolicy{Enabled: true, Transitions: 2, Window: 10 * time.Minute, Cooldown: 5 * time.Minute}
+	minute := func(m int) time.Time { return epoch.Add(time.Duration(m) * time.Minute) }
+
+	for _, step := range []struct {
+		minute   int
+		status   string
+		flapping bool
+		change   FlappingChange
+	}{
+		{0, "up", false, FlappingUnchanged},
+		{1, "down", false, FlappingUnchanged},
+		{2, "up", false, FlappingUnchanged},
+		// The third change within the window
+		{3, "down", true, FlappingStarted},
+		{4, "up", true, FlappingUnchanged},
+		// Maintenance neither changes the status nor ends flapping
+		{6, StatusMaintenance, true, FlappingUnchanged},
+		{8, "up", true, FlappingUnchanged},
+		// Stable for the cooldown since the change at minute 4
+		{9, "up", false, FlappingEnded},
+		// The changes before it ended no longer count
+		{10, "down", false, FlappingUnchanged},
+	} {
+		flapping, change := d.record(1, step.status, minute(step.minute))
+		if flapping != step.flapping || change != step.change {
+			t.Errorf("minute %d %s: flapping %v change %v, want %v %v", step.minute, step.status, flapping, change, step.flapping, step.change)
+		}
+	}
+
+	// Changes further apart than the window never add up
+	for i, status := range []string{"up", "down", "up", "down", "up"} {
+		if flapping, _ := d.record(2, status, minute(i*6)); flapping {
+			t.Errorf("target 2 flapping at minute %d", i*6)
+		}
+	}
+
+	d.forget(1)
+	if _, ok := d.targets[1]; ok {
+		t.Error("state of a forgotten target kept")
+	}
+
+	// Off by default
+	off := newFlapDetector()
+	for i, status := range []string{"up", "down", "up", "down", "up"} {
+		if flapping, change := off.record(1, status, minute(i)); flapping || change != FlappingUnchanged {
+			t.Errorf("flapping detected with the policy off: %v %v", flapping, change)
+		}
+	}
+}
+
+// saveResult marks the result and the stored status as flapping
+func TestSaveResultFlapping(t *testing.T) {
+	s := newTestService(t)
+	s.SetFlappingPolicy(FlappingPolicy{Enabled: true, Transitions: 1, Window: time.Hour, Cooldown: time.Hour})
+	target := &MonitorTarget{ID: 1, Name: "db", Type: "tcp", Address: "127.0.0.1", Port: 1, Interval: 3600}
+	if err := s.AddTarget(target); err != nil {
+		t.Fatalf("AddTarget: %v", err)
+	}
+	s.SetSinks(target.ID, Sinks{SinkDBHistory})
+
+	var last *CheckResult
+	for _, status := range []string{"up", "down", "up"} {
+		last = &CheckResult{Status: status, Message: status}
+		s.saveResult(target, last)
+	}
+	if !last.Flapping || last.FlappingChange != FlappingStarted {
+		t.Errorf("result flapping %v change %v, want started", last.Flapping, last.FlappingChange)
+	}
+	status, err := s.GetStatus(context.Background(), target.ID)
+	if err != nil || !status.Flapping {
+		t.Errorf("status %+v, %v, want flapping", status, err)
+	}
+}
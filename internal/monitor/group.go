@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"context"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+// rollupRank orders current statuses from best to worst for GroupRollup;
+// statuses not listed rank as unknown
+var rollupRank = map[string]int{
+	"up":              1,
+	StatusMaintenance: 2,
+	"warning":         3,
+	"degraded":        4,
+	"unknown":         5,
+	StatusConfigError: 6,
+	"critical":        7,
+	"down":            8,
+}
+
+func rollupRankOf(status string) int {
+	if rank, ok := rollupRank[status]; ok {
+		return rank
+	}
+	return rollupRank["unknown"]
+}
+
+// GroupRollup is the current status of the enabled monitors of a group
+type GroupRollup struct {
+	GroupID         *uint32        `json:"group_id"` // nil for the monitors without a group
+	Name            string         `json:"name"`     // empty for the monitors without a group
+	SortOrder       int            `json:"sort_order"`
+	Monitors        int            `json:"monitors"`
+	WorstStatus     string         `json:"worst_status"`      // empty without monitors
+	Counts          map[string]int `json:"counts"`            // monitors by status; never checked counts as unknown
+	AvgResponseTime int64          `json:"avg_response_time"` // milliseconds, over monitors that are up, warning or degraded
+}
+
+// rollupRow is one row of the GROUP BY query
+type rollupRow struct {
+	GroupID           *uint32
+	Status            string
+	Count             int
+	TotalResponseTime int64
+}
+
+// GroupRollups returns the rollup of every group, empty ones included, in
+// display order, and that of the monitors without a group last. Members
+// are counted with one GROUP BY query over the current status rows;
+// disabled monitors are left out.
+func (s *Service) GroupRollups(ctx context.Context) ([]GroupRollup, error) {
+	db := database.GetDB().WithContext(ctx)
+
+	var groups []models.MonitorGroup
+	if err := db.Order("sort_order, name").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []rollupRow
+	err := db.Table("monitor_targets AS t").
+		Select("t.group_id AS group_id, COALESCE(s.status, 'unknown') AS status, COUNT(*) AS count, COALESCE(SUM(s.response_time), 0) AS total_response_time").
+		Joins("LEFT JOIN monitor_status AS s ON s.target_id = t.id").
+		Where("t.enabled = ?", true).
+		Group("t.group_id, COALESCE(s.status, 'unknown')").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]GroupRollup, 0, len(groups)+1)
+	index := make(map[uint32]int, len(groups))
+	for _, group := range groups {
+		id := group.ID
+		index[id] = len(result)
+		result = append(result, GroupRollup{GroupID: &id, Name: group.Name, SortOrder: group.SortOrder, Counts: map[string]int{}})
+	}
+	// 分组被删除但监控的 group_id 未清除时也算作未分组
+	result = append(result, GroupRollup{Counts: map[string]int{}})
+	ungrouped := len(result) - 1
+
+	responding := make([]int, len(result))
+	totals := make([]int64, len(result))
+	for _, row := range rows {
+		i := ungrouped
+		if row.GroupID != nil {
+			if j, ok := index[*row.GroupID]; ok {
+				i = j
+			}
+		}
+		rollup := &result[i]
+		rollup.Monitors += row.Count
+		rollup.Counts[row.Status] += row.Count
+		if rollup.WorstStatus == "" || rollupRankOf(row.Status) > rollupRankOf(rollup.WorstStatus) {
+			rollup.WorstStatus = row.Status
+		}
+		if row.Status == "up" || row.Status == "warning" || row.Status == "degraded" {
+			responding[i] += row.Count
+			totals[i] += row.TotalResponseTime
+		}
+	}
+	for i := range result {
+		if responding[i] > 0 {
+			result[i].AvgResponseTime = totals[i] / int64(responding[i])
+		}
+	}
+	return result, nil
+}
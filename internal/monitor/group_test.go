@@ -0,0 +1,78 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+func TestGroupRollups(t *testing.T) {
+	s := newTestService(t)
+	db := database.GetDB()
+	web := models.MonitorGroup{Name: "web", SortOrder: 2}
+	data := models.MonitorGroup{Name: "data", SortOrder: 1}
+	empty := models.MonitorGroup{Name: "empty", SortOrder: 3}
+	for _, g := range []*models.MonitorGroup{&web, &data, &empty} {
+		if err := db.Create(g).Error; err != nil {
+			t.Fatalf("create group: %v", err)
+		}
+	}
+	deleted := uint32(999)
+
+	add := func(group *uint32, status string, responseTime int64) models.MonitorTarget {
+		target := models.MonitorTarget{Name: "t", Type: "tcp", Address: "127.0.0.1", Port: 1, Interval: 60, GroupID: group}
+		if err := db.Create(&target).Error; err != nil {
+			t.Fatalf("create target: %v", err)
+		}
+		if status != "" {
+			db.Create(&models.MonitorStatus{TargetID: target.ID, Status: status, ResponseTime: responseTime})
+		}
+		return target
+	}
+	add(&web.ID, "up", 100)
+	add(&web.ID, "degraded", 300)
+	add(&web.ID, "down", 5000)
+	add(&data.ID, "up", 10)
+	add(&data.ID, "", 0) // never checked
+	add(&data.ID, StatusMaintenance, 0)
+	add(nil, "up", 40)
+	add(&deleted, "critical", 0)
+	// Disabled monitors are left out
+	disabled := add(&data.ID, "down", 0)
+	db.Model(&disabled).Update("enabled", false)
+
+	rollups, err := s.GroupRollups(context.Background())
+	if err != nil {
+		t.Fatalf("GroupRollups: %v", err)
+	}
+	if len(rollups) != 4 || rollups[0].Name != "data" || rollups[1].Name != "web" || rollups[2].Name != "empty" || rollups[3].GroupID != nil {
+		t.Fatalf("rollups %+v, want data, web, empty and the ungrouped monitors", rollups)
+	}
+
+	for i, want := range []struct {
+		monitors int
+		worst    string
+		counts   map[string]int
+		avg      int64
+	}{
+		{3, "unknown", map[string]int{"up": 1, "unknown": 1, StatusMaintenance: 1}, 10},
+		{3, "down", map[string]int{"up": 1, "degraded": 1, "down": 1}, 200},
+		{0, "", map[string]int{}, 0},
+		// A monitor of a deleted group counts as ungrouped
+		{2, "critical", map[string]int{"up": 1, "critical": 1}, 40},
+	} {
+		r := rollups[i]
+		if r.Monitors != want.monitors || r.WorstStatus != want.worst || r.AvgResponseTime != want.avg || len(r.Counts) != len(want.counts) {
+			t.Errorf("rollup %d = %+v, want %+v", i, r, want)
+			continue
+		}
+		for status, n := range want.counts {
+			if r.Counts[status] != n {
+				t.Errorf("rollup %d counts %v, want %v", i, r.Counts, want.counts)
+				break
+			}
+		}
+	}
+}
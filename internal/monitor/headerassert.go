@@ -0,0 +1,115 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MaxExpectedHeaders bounds the header assertions of a target
+const MaxExpectedHeaders = 20
+
+// HeaderRegexPrefix marks an expected_headers value as a regular expression;
+// any other value, /.../ included, is text the header must contain
+const HeaderRegexPrefix = "regex:"
+
+// Header assertions of http and https targets, declared in httpFields
+var expectedHeadersField = FieldSpec{Name: "expected_headers", Kind: FieldObject, Description: "响应头断言，响应头名（不区分大小写）到期望值：空字符串只要求存在，regex: 开头的为正则（RE2 语法，如 regex:^max-age=\\d+$），其他为必须包含的文本；多个同名响应头用 \", \" 连接后比较。不满足时为 down"}
+
+// headerAssertionResult is the result field of expected_headers, declared in httpResults
+var headerAssertionResult = ResultField{Name: "header_assertion", In: "data", Description: "未通过的响应头断言，如 Cache-Control must contain \"no-store\"；断言都通过时不返回"}
+
+// HeaderAssertion is one entry of expected_headers
+type HeaderAssertion struct {
+	Name     string         // canonical header name
+	Contains string         // substring the value must contain; empty only requires the header
+	Regex    *regexp.Regexp // set instead of Contains for regex: values
+}
+
+// ValidateExpectedHeaders checks the expected_headers of a target: they are
+// only supported for http and https, and each entry must parse
+func ValidateExpectedHeaders(typ string, headers map[string]string) error {
+	if len(headers) == 0 {
+		return nil
+	}
+	if spec, ok := LookupType(typ); ok {
+		typ = spec.Type
+	}
+	if typ != "http" && typ != "https" {
+		return fmt.Errorf("expected_headers are only supported for http and https monitors")
+	}
+	_, err := ParseExpectedHeaders(headers)
+	return err
+}
+
+// ParseExpectedHeaders converts the expected_headers of a target, sorted by
+// header name so failures are reported in a stable order. Names are matched
+// case-insensitively; two names that differ only in case are rejected.
+func ParseExpectedHeaders(headers map[string]string) ([]HeaderAssertion, error) {
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	if len(headers) > MaxExpectedHeaders {
+		return nil, fmt.Errorf("expected_headers: at most %d headers, got %d", MaxExpectedHeaders, len(headers))
+	}
+	assertions := make([]HeaderAssertion, 0, len(headers))
+	seen := make(map[string]bool, len(headers))
+	for name, value := range headers {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("expected_headers: empty header name")
+		}
+		canonical := textproto.CanonicalMIMEHeaderKey(name)
+		if seen[canonical] {
+			return nil, fmt.Errorf("expected_headers: %s is given more than once", canonical)
+		}
+		seen[canonical] = true
+		if len(value) > MaxBodyAssertionLength {
+			return nil, fmt.Errorf("expected_headers: %s must be at most %d bytes", canonical, MaxBodyAssertionLength)
+		}
+
+		assertion := HeaderAssertion{Name: canonical}
+		if pattern, ok := strings.CutPrefix(value, HeaderRegexPrefix); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("expected_headers: invalid regex for %s: %w", canonical, err)
+			}
+			assertion.Regex = re
+		} else {
+			assertion.Contains = value
+		}
+		assertions = append(assertions, assertion)
+	}
+	sort.Slice(assertions, func(i, j int) bool { return assertions[i].Name < assertions[j].Name })
+	return assertions, nil
+}
+
+// checkHeaderAssertions evaluates the header assertions against the response
+// headers. Multi-valued headers are joined the way cloneHeaders joins them.
+// It returns the assertion that failed, e.g. `Cache-Control must contain
+// "no-store"`, and the value the response had, or "" when all hold.
+func checkHeaderAssertions(assertions []HeaderAssertion, headers http.Header) (failed, got string) {
+	for _, a := range assertions {
+		values := headers.Values(a.Name)
+		if len(values) == 0 {
+			return fmt.Sprintf("%s must be present", a.Name), ""
+		}
+		value := strings.Join(values, ", ")
+		if a.Regex != nil && !a.Regex.MatchString(value) {
+			return fmt.Sprintf("%s must match %q", a.Name, a.Regex.String()), value
+		}
+		if a.Contains != "" && !strings.Contains(value, a.Contains) {
+			return fmt.Sprintf("%s must contain %q", a.Name, a.Contains), value
+		}
+	}
+	return "", ""
+}
+
+// looksLikeSlashRegex reports whether a text assertion is written as /.../,
+// the way a regex is written elsewhere; it is matched as text
+func looksLikeSlashRegex(a HeaderAssertion) bool {
+	return a.Regex == nil && len(a.Contains) > 2 && strings.HasPrefix(a.Contains, "/") && strings.HasSuffix(a.Contains, "/")
+}
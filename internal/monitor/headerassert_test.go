@@ -0,0 +1,104 @@
+package monitor
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"monitor/internal/models"
+)
+
+func TestParseExpectedHeaders(t *testing.T) {
+	assertions, err := ParseExpectedHeaders(map[string]string{
+		"x-env":                     "regex:^prod(uction)?$",
+		"Cache-Control":             "no-store",
+		"STRICT-TRANSPORT-SECURITY": "",
+		"X-Path":                    "/api/",
+	})
+	if err != nil {
+		t.Fatalf("ParseExpectedHeaders: %v", err)
+	}
+	var names []string
+	for _, a := range assertions {
+		names = append(names, a.Name)
+	}
+	if got := strings.Join(names, " "); got != "Cache-Control Strict-Transport-Security X-Env X-Path" {
+		t.Errorf("names %s, want canonical and sorted", got)
+	}
+	if a := assertions[2]; a.Regex == nil || a.Regex.String() != "^prod(uction)?$" || a.Contains != "" {
+		t.Errorf("regex: value parsed as %+v", a)
+	}
+	// Slashes are text, only the prefix makes a regex
+	if a := assertions[3]; a.Regex != nil || a.Contains != "/api/" {
+		t.Errorf("/api/ parsed as %+v, want text", a)
+	}
+
+	for _, bad := range []map[string]string{
+		{"X-Env": "regex:("},
+		{"X-Env": "a", "x-env": "b"},
+		{" ": "a"},
+		{"X-Env": strings.Repeat("a", MaxBodyAssertionLength+1)},
+	} {
+		if _, err := ParseExpectedHeaders(bad); err == nil {
+			t.Errorf("ParseExpectedHeaders(%q) accepted", bad)
+		}
+	}
+	if err := ValidateExpectedHeaders("tcp", map[string]string{"X-Env": "a"}); err == nil {
+		t.Error("expected_headers accepted for tcp")
+	}
+}
+
+func TestCheckHeaderAssertions(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	headers.Add("Cache-Control", "no-cache")
+	headers.Add("Cache-Control", "no-store")
+	headers.Set("X-Path", "/api/v1")
+
+	for _, tc := range []struct {
+		name     string
+		expected map[string]string
+		failed   string
+		got      string
+	}{
+		{"substring", map[string]string{"content-type": "json"}, "", ""},
+		{"substring missing", map[string]string{"Content-Type": "xml"}, `Content-Type must contain "xml"`, "application/json"},
+		{"regex", map[string]string{"Content-Type": "regex:^application/(json|xml)$"}, "", ""},
+		{"regex no match", map[string]string{"Content-Type": "regex:^text/"}, `Content-Type must match "^text/"`, "application/json"},
+		{"present", map[string]string{"X-Path": ""}, "", ""},
+		{"missing header", map[string]string{"X-Request-Id": ""}, "X-Request-Id must be present", ""},
+		{"missing header with a value", map[string]string{"X-Request-Id": "regex:."}, "X-Request-Id must be present", ""},
+		{"joined values", map[string]string{"Cache-Control": "no-cache, no-store"}, "", ""},
+		{"regex over joined values", map[string]string{"Cache-Control": "regex:^no-cache, no-store$"}, "", ""},
+		{"slashes are text", map[string]string{"X-Path": "/api/"}, "", ""},
+		{"slashes are not a regex", map[string]string{"Content-Type": "/json/"}, `Content-Type must contain "/json/"`, "application/json"},
+		{"first failure by name", map[string]string{"X-Path": "v2", "Cache-Control": "private"}, `Cache-Control must contain "private"`, "no-cache, no-store"},
+	} {
+		assertions, err := ParseExpectedHeaders(tc.expected)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if failed, got := checkHeaderAssertions(assertions, headers); failed != tc.failed || got != tc.got {
+			t.Errorf("%s: failed %q got %q, want %q and %q", tc.name, failed, got, tc.failed, tc.got)
+		}
+	}
+}
+
+func TestLintExpectedHeaderSlashes(t *testing.T) {
+	target := func(id uint32, expected string) models.MonitorTarget {
+		return models.MonitorTarget{ID: id, Name: "site", Type: "http", Address: "http://example.com", Interval: 60, ExpectedHeaders: expected}
+	}
+	findings := Lint(&LintConfig{Targets: []models.MonitorTarget{
+		target(1, `{"X-Env": "/^production$/", "Cache-Control": "no-store"}`),
+		target(2, `{"X-Env": "regex:^production$", "X-Path": "/"}`),
+	}})
+	var got []LintFinding
+	for _, f := range findings {
+		if f.Lint == "expected_header_slashes" {
+			got = append(got, f)
+		}
+	}
+	if len(got) != 1 || got[0].EntityID != 1 || !strings.Contains(got[0].Message, "X-Env") || !strings.Contains(got[0].Fix, HeaderRegexPrefix) {
+		t.Errorf("expected_header_slashes findings %+v, want one for target 1", got)
+	}
+}
@@ -0,0 +1,250 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/lru"
+	"monitor/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// MaxHeatmapDays is the longest period a heatmap covers
+const MaxHeatmapDays = 90
+
+// heatmapCacheDays bounds the cached closed days, about a year of days for
+// each of 500 targets
+const heatmapCacheDays = 500 * 366
+
+// HeatmapDay is the availability of a target over one calendar day
+type HeatmapDay struct {
+	Date                 string   `json:"date"`       // YYYY-MM-DD in the heatmap's timezone
+	UptimePct            *float64 `json:"uptime_pct"` // nil without results
+	Incidents            int      `json:"incidents"`  // times the target went down
+	WorstStatus          string   `json:"worst_status"`
+	TotalDowntimeSeconds int64    `json:"total_downtime_seconds"`
+}
+
+// statusRank orders the statuses counted by the heatmap from best to worst
+var statusRank = map[string]int{"up": 1, "warning": 2, "degraded": 3, "down": 4}
+
+// heatmapKey is a closed day of a target. gen is the target's generation
+// when the day was computed; invalidating a target moves it on.
+type heatmapKey struct {
+	targetID uint32
+	gen      uint64
+	location string
+	date     string
+}
+
+// heatmapCache keeps the days that are over, which only change when history
+// is written late (spool replay) or edited (recompute, purge, retention)
+type heatmapCache struct {
+	mu   sync.Mutex
+	days *lru.Cache[heatmapKey, HeatmapDay]
+	gens map[uint32]uint64
+}
+
+func newHeatmapCache() *heatmapCache {
+	return &heatmapCache{days: lru.New[heatmapKey, HeatmapDay](heatmapCacheDays), gens: make(map[uint32]uint64)}
+}
+
+func (c *heatmapCache) key(targetID uint32, loc *time.Location, date string) heatmapKey {
+	return heatmapKey{targetID: targetID, gen: c.gens[targetID], location: loc.String(), date: date}
+}
+
+// invalidate drops the cached days of a target
+func (c *heatmapCache) invalidate(targetID uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gens[targetID]++
+}
+
+// clear drops every cached day
+func (c *heatmapCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.days = lru.New[heatmapKey, HeatmapDay](heatmapCacheDays)
+}
+
+// dayBucket accumulates the results of one day
+type dayBucket struct {
+	start, end  time.Time
+	checks      int
+	up          int
+	worst       string
+	incidents   int
+	downtime    time.Duration
+	maintenance bool // had results checked during a maintenance window
+}
+
+// Heatmap returns the availability of a target for each of the last days
+// calendar days in loc, oldest first, the current day included. Results
+// checked during maintenance windows are left out, as are synthetic ones
+// unless they count towards uptime. A down result counts as downtime until
+// the next result, at most one check interval. Days that are over are cached.
+func (s *Service) Heatmap(ctx context.Context, targetID uint32, days int, loc *time.Location) ([]HeatmapDay, error) {
+	if days < 1 || days > MaxHeatmapDays {
+		return nil, fmt.Errorf("days must be between 1 and %d", MaxHeatmapDays)
+	}
+	db := database.GetDB().WithContext(ctx)
+
+	var target models.MonitorTarget
+	if err := db.First(&target, targetID).Error; err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	buckets := make([]*dayBucket, days)
+	for i := range buckets {
+		start := today.AddDate(0, 0, i-days+1)
+		buckets[i] = &dayBucket{start: start, end: start.AddDate(0, 0, 1)}
+	}
+
+	// Days over and cached are kept, the rest is computed from the first missing one
+	result := make([]HeatmapDay, days)
+	first := days - 1
+	s.heatmap.mu.Lock()
+	for i := days - 2; i >= 0; i-- {
+		day, ok := s.heatmap.days.Get(s.heatmap.key(targetID, loc, buckets[i].start.Format(time.DateOnly)))
+		if !ok {
+			first = i
+		}
+		result[i] = day
+	}
+	gen := s.heatmap.gens[targetID]
+	s.heatmap.mu.Unlock()
+
+	if err := s.fillHeatmap(db, target, buckets[first:], now); err != nil {
+		return nil, err
+	}
+
+	s.heatmap.mu.Lock()
+	defer s.heatmap.mu.Unlock()
+	for i := first; i < days; i++ {
+		result[i] = buckets[i].day()
+		// A day is cached once over, unless the target was invalidated meanwhile
+		if i < days-1 && s.heatmap.gens[targetID] == gen {
+			s.heatmap.days.Add(s.heatmap.key(targetID, loc, result[i].Date), result[i])
+		}
+	}
+	return result, nil
+}
+
+// fillHeatmap walks the history of the days of buckets, which are consecutive
+func (s *Service) fillHeatmap(db *gorm.DB, target models.MonitorTarget, buckets []*dayBucket, now time.Time) error {
+	from, to := buckets[0].start, buckets[len(buckets)-1].end
+	history := func() *gorm.DB {
+		query := db.Model(&models.MonitorHistory{}).Select("status", "checked_at").Where("target_id = ?", target.ID)
+		if !s.includeSyntheticUptime {
+			query = query.Where("synthetic = ?", false)
+		}
+		return query
+	}
+
+	// The result before the first day: downtime running over midnight, and
+	// whether the first down result of the day starts a new incident
+	var before []models.MonitorHistory
+	if err := history().Where("checked_at < ?", from).Order("checked_at DESC").Limit(1).Find(&before).Error; err != nil {
+		return err
+	}
+	var last []string
+	if err := history().Where("checked_at < ? AND status <> ?", from, StatusMaintenance).
+		Order("checked_at DESC").Limit(1).Pluck("status", &last).Error; err != nil {
+		return err
+	}
+	previous := ""
+	if len(last) > 0 {
+		previous = last[0]
+	}
+
+	var rows []models.MonitorHistory
+	if err := history().Where("checked_at >= ? AND checked_at < ?", from, to).Order("checked_at").Find(&rows).Error; err != nil {
+		return err
+	}
+	rows = append(before, rows...)
+
+	maxDowntime := time.Duration(target.Interval) * time.Second
+	day := 0
+	for i, row := range rows {
+		checkedAt := row.CheckedAt
+		if !checkedAt.Before(from) {
+			for !checkedAt.Before(buckets[day].end) {
+				day++
+			}
+			b := buckets[day]
+			if row.Status == StatusMaintenance {
+				b.maintenance = true
+			} else {
+				b.checks++
+				if row.Status == "up" {
+					b.up++
+				}
+				if statusRank[row.Status] > statusRank[b.worst] {
+					b.worst = row.Status
+				}
+				if row.Status == "down" && previous != "down" {
+					b.incidents++
+				}
+				previous = row.Status
+			}
+		}
+
+		if row.Status != "down" {
+			continue
+		}
+		downUntil := checkedAt.Add(maxDowntime)
+		if i+1 < len(rows) && rows[i+1].CheckedAt.Before(downUntil) {
+			downUntil = rows[i+1].CheckedAt
+		}
+		if now.Before(downUntil) {
+			downUntil = now
+		}
+		addDowntime(buckets, checkedAt, downUntil)
+	}
+	return nil
+}
+
+// addDowntime spreads the downtime between start and end over the days it falls in
+func addDowntime(buckets []*dayBucket, start, end time.Time) {
+	for _, b := range buckets {
+		from, to := start, end
+		if from.Before(b.start) {
+			from = b.start
+		}
+		if b.end.Before(to) {
+			to = b.end
+		}
+		if from.Before(to) {
+			b.downtime += to.Sub(from)
+		}
+	}
+}
+
+func (b *dayBucket) day() HeatmapDay {
+	day := HeatmapDay{
+		Date:                 b.start.Format(time.DateOnly),
+		Incidents:            b.incidents,
+		WorstStatus:          b.worst,
+		TotalDowntimeSeconds: int64(b.downtime / time.Second),
+	}
+	if b.checks == 0 {
+		day.WorstStatus = "unknown"
+		if b.maintenance {
+			day.WorstStatus = StatusMaintenance
+		}
+		return day
+	}
+	uptime := math.Round(float64(b.up)*10000/float64(b.checks)) / 100
+	day.UptimePct = &uptime
+	if day.WorstStatus == "" {
+		day.WorstStatus = "unknown"
+	}
+	return day
+}
@@ -0,0 +1,88 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"monitor/internal/clock"
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+func TestHeatmap(t *testing.T) {
+	s := newTestService(t)
+	s.SetClock(clock.NewFake(epoch))
+	db := database.GetDB()
+	target := models.MonitorTarget{Name: "db", Type: "tcp", Address: "127.0.0.1", Port: 1, Interval: 600}
+	db.Create(&target)
+	at := func(day, hour, min int) time.Time { return time.Date(2026, 3, day, hour, min, 0, 0, time.UTC) }
+	for _, row := range []models.MonitorHistory{
+		// Down over midnight into the first day, which does not start an incident there
+		{Status: "down", CheckedAt: at(28, 23, 55)},
+		{Status: "up", CheckedAt: at(29, 0, 5)},
+		{Status: "down", CheckedAt: at(29, 10, 0)},
+		{Status: "down", CheckedAt: at(29, 10, 3)},
+		{Status: "up", CheckedAt: at(29, 10, 6)},
+		// Nothing but maintenance on the second day
+		{Status: StatusMaintenance, CheckedAt: at(30, 12, 0)},
+		// Synthetic results are left out, and today's downtime stops now
+		{Status: "down", Synthetic: true, CheckedAt: at(31, 11, 0)},
+		{Status: "down", CheckedAt: at(31, 11, 58)},
+	} {
+		row.TargetID = target.ID
+		db.Create(&row)
+	}
+
+	days, err := s.Heatmap(context.Background(), target.ID, 3, time.UTC)
+	if err != nil {
+		t.Fatalf("Heatmap: %v", err)
+	}
+	want := []struct {
+		date      string
+		uptime    float64 // -1 for none
+		incidents int
+		worst     string
+		downtime  int64
+	}{
+		{"2026-03-29", 50, 1, "down", 11 * 60},
+		{"2026-03-30", -1, 0, StatusMaintenance, 0},
+		{"2026-03-31", 0, 1, "down", 2 * 60},
+	}
+	if len(days) != len(want) {
+		t.Fatalf("%d days, want %d", len(days), len(want))
+	}
+	for i, w := range want {
+		d := days[i]
+		uptime := -1.0
+		if d.UptimePct != nil {
+			uptime = *d.UptimePct
+		}
+		if d.Date != w.date || uptime != w.uptime || d.Incidents != w.incidents || d.WorstStatus != w.worst || d.TotalDowntimeSeconds != w.downtime {
+			t.Errorf("day %d = %+v uptime %v, want %+v", i, d, uptime, w)
+		}
+	}
+
+	// Days that are over are cached until the target is invalidated
+	db.Create(&models.MonitorHistory{TargetID: target.ID, Status: "up", CheckedAt: at(30, 13, 0)})
+	if days, _ = s.Heatmap(context.Background(), target.ID, 3, time.UTC); days[1].UptimePct != nil {
+		t.Errorf("cached day recomputed: %+v", days[1])
+	}
+	s.heatmap.invalidate(target.ID)
+	if days, _ = s.Heatmap(context.Background(), target.ID, 3, time.UTC); days[1].UptimePct == nil || *days[1].UptimePct != 100 {
+		t.Errorf("day after invalidation %+v, want 100%% up", days[1])
+	}
+
+	// Days follow the timezone: today in Tokyo started at 15:00 UTC on the 30th
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	if days, _ = s.Heatmap(context.Background(), target.ID, 1, tokyo); days[0].Date != "2026-03-31" || days[0].Incidents != 1 {
+		t.Errorf("Tokyo today %+v", days[0])
+	}
+
+	if _, err := s.Heatmap(context.Background(), target.ID, MaxHeatmapDays+1, time.UTC); err == nil {
+		t.Error("more than MaxHeatmapDays accepted")
+	}
+	if _, err := s.Heatmap(context.Background(), target.ID+1, 1, time.UTC); err == nil {
+		t.Error("heatmap of an unknown target")
+	}
+}
@@ -0,0 +1,197 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// historyBufferBatches bounds the pending history rows to this many batches.
+// Beyond that, e.g. while the database is slow, rows are inserted by the
+// check worker itself, which slows the checks down instead of growing the buffer.
+const historyBufferBatches = 10
+
+// historyWriter batches the history rows of saveResult. The writer goroutine
+// inserts them once batchSize are pending or every flushInterval, and
+// recomputes the uptime of the targets that got rows every uptimeInterval.
+type historyWriter struct {
+	batchSize      int
+	flushInterval  time.Duration
+	uptimeInterval time.Duration
+
+	mu      sync.Mutex
+	pending []pendingHistory
+	// Targets with rows inserted since their uptime was last computed
+	dirty map[uint32]bool
+	// Set once the writer has flushed for the last time; rows are then inserted directly
+	closed bool
+
+	// Signalled when batchSize rows are pending
+	full chan struct{}
+	// Closed once the writer has flushed on Stop
+	done chan struct{}
+}
+
+// pendingHistory is a history row waiting for the next flush, with the
+// response archive to link to it once it has an ID
+type pendingHistory struct {
+	row     *models.MonitorHistory
+	archive *models.ResponseArchive
+}
+
+func newHistoryWriter(batchSize int, flushInterval, uptimeInterval time.Duration) *historyWriter {
+	return &historyWriter{
+		batchSize:      batchSize,
+		flushInterval:  flushInterval,
+		uptimeInterval: uptimeInterval,
+		dirty:          make(map[uint32]bool),
+		full:           make(chan struct{}, 1),
+		done:           make(chan struct{}),
+	}
+}
+
+// size returns the pending rows and their bound, for RuntimeStats
+func (w *historyWriter) size() (int, int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.pending), w.batchSize * historyBufferBatches
+}
+
+// take removes and returns the pending rows
+func (w *historyWriter) take() []pendingHistory {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	batch := w.pending
+	w.pending = nil
+	return batch
+}
+
+// takeDirty removes and returns the targets whose uptime is out of date
+func (w *historyWriter) takeDirty() map[uint32]bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	dirty := w.dirty
+	w.dirty = make(map[uint32]bool)
+	return dirty
+}
+
+// queueHistory adds a history row to the next batch. After Stop, or while
+// the buffer is full, the row is inserted right away.
+func (s *Service) queueHistory(row *models.MonitorHistory, archive *models.ResponseArchive) {
+	w := s.history
+	w.mu.Lock()
+	if w.closed || len(w.pending) >= w.batchSize*historyBufferBatches {
+		closed := w.closed
+		w.mu.Unlock()
+		s.insertHistory([]pendingHistory{{row: row, archive: archive}})
+		// Nothing recomputes the uptime any more after Stop
+		if closed {
+			s.updateUptime(row.TargetID)
+		}
+		return
+	}
+	w.pending = append(w.pending, pendingHistory{row: row, archive: archive})
+	full := len(w.pending) >= w.batchSize
+	w.mu.Unlock()
+
+	if full {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// startHistoryWriter starts the goroutine that flushes the history rows
+func (s *Service) startHistoryWriter() {
+	go func() {
+		defer close(s.history.done)
+		s.historyWriterLoop()
+	}()
+}
+
+func (s *Service) historyWriterLoop() {
+	w := s.history
+	flush := time.NewTicker(w.flushInterval)
+	defer flush.Stop()
+	uptime := time.NewTicker(w.uptimeInterval)
+	defer uptime.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			// Rows queued from now on are inserted by queueHistory, so that
+			// results saved while or after stopping are not lost
+			w.mu.Lock()
+			w.closed = true
+			w.mu.Unlock()
+			s.flushHistory()
+			s.refreshUptime()
+			return
+		case <-w.full:
+			s.flushHistory()
+		case <-flush.C:
+			s.flushHistory()
+		case <-uptime.C:
+			s.refreshUptime()
+		}
+	}
+}
+
+// flushHistory inserts the pending history rows now, for readers that need
+// every result saved so far
+func (s *Service) flushHistory() {
+	s.insertHistory(s.history.take())
+}
+
+// insertHistory writes a batch of history rows. A batch the database does not
+// accept is spooled row by row and written by ReplaySpools.
+func (s *Service) insertHistory(batch []pendingHistory) {
+	if len(batch) == 0 {
+		return
+	}
+	db := database.GetDB()
+	rows := make([]*models.MonitorHistory, len(batch))
+	for i, p := range batch {
+		rows[i] = p.row
+	}
+	if err := db.CreateInBatches(rows, s.history.batchSize).Error; err != nil {
+		for _, row := range rows {
+			row.ID = 0
+			s.spoolHistory(row, err)
+		}
+		return
+	}
+
+	for _, p := range batch {
+		if p.archive != nil {
+			db.Model(p.archive).Update("history_id", p.row.ID)
+		}
+	}
+
+	w := s.history
+	w.mu.Lock()
+	for _, row := range rows {
+		w.dirty[row.TargetID] = true
+	}
+	w.mu.Unlock()
+}
+
+// refreshUptime recomputes the uptime of the targets that got history rows
+// since the last time
+func (s *Service) refreshUptime() {
+	dirty := s.history.takeDirty()
+	if len(dirty) == 0 {
+		return
+	}
+	for targetID := range dirty {
+		s.updateUptime(targetID)
+	}
+	s.InvalidateStatus()
+	logger.Debug("Uptime recomputed", zap.Int("targets", len(dirty)))
+}
@@ -0,0 +1,69 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+func TestHistoryWriter(t *testing.T) {
+	newTestService(t)
+	// Flushed only by a full batch, Stop or flushHistory
+	s := NewService(nil, ServiceOptions{Workers: 1, StartupJitter: time.Hour, HistoryBatchSize: 3, HistoryFlushInterval: time.Hour, UptimeInterval: time.Hour})
+	db := database.GetDB()
+	db.Create(&models.MonitorStatus{TargetID: 1, Status: "up"})
+	queue := func(status string) {
+		s.queueHistory(&models.MonitorHistory{TargetID: 1, Status: status, CheckedAt: time.Now()}, nil)
+	}
+
+	queue("up")
+	queue("down")
+	if n := countHistory(t); n != 0 {
+		t.Errorf("%d rows inserted before the batch is full", n)
+	}
+	if pending, max := s.history.size(); pending != 2 || max != 3*historyBufferBatches {
+		t.Errorf("size = %d, %d", pending, max)
+	}
+	queue("up")
+	waitFor(t, func() bool { return countHistory(t) == 3 })
+
+	queue("up")
+	s.flushHistory()
+	if n := countHistory(t); n != 4 {
+		t.Errorf("%d rows after flushHistory, want 4", n)
+	}
+
+	// Uptime waits for the next uptime pass
+	var status models.MonitorStatus
+	db.Where("target_id = ?", 1).First(&status)
+	if status.UptimePercentage != 0 {
+		t.Errorf("uptime %d before the uptime pass", status.UptimePercentage)
+	}
+	s.refreshUptime()
+	db.Where("target_id = ?", 1).First(&status)
+	if status.UptimePercentage != 75 {
+		t.Errorf("uptime %d, want 75", status.UptimePercentage)
+	}
+
+	// Stop flushes, and rows saved afterwards are inserted right away
+	queue("up")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if n := countHistory(t); n != 5 {
+		t.Errorf("%d rows after Stop, want 5", n)
+	}
+	queue("up")
+	if n := countHistory(t); n != 6 {
+		t.Errorf("%d rows after a save past Stop, want 6", n)
+	}
+	db.Where("target_id = ?", 1).First(&status)
+	if status.UptimePercentage != 83 {
+		t.Errorf("uptime %d after Stop, want 83", status.UptimePercentage)
+	}
+}
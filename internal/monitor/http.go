@@ -1,13 +1,20 @@
 package monitor
 
 import (
+	"bufio"
 	"bytes"
+	"compress/flate"
 	"compress/gzip"
+	"compress/zlib"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	neturl "net/url"
 	"regexp"
 	"strings"
@@ -17,21 +24,93 @@ import (
 	"go.uber.org/zap"
 )
 
+// httpMethodField is also the method of each of the steps
+var httpMethodField = FieldSpec{Name: "http_method", Kind: FieldString, Default: "GET", Enum: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}, Description: "请求方法"}
+
+// httpFields and httpResults are shared by the http and https catalog entries
+var httpFields = []FieldSpec{
+	httpMethodField,
+	{Name: "http_headers", Kind: FieldObject, Description: "自定义请求头"},
+	{Name: "http_body", Kind: FieldString, Description: "请求体"},
+	{Name: "resolved_host", Kind: FieldString, Description: "自定义 Host 请求头"},
+	{Name: "unix_socket_path", Kind: FieldString, Max: intBound(500), Description: "通过该 Unix socket 连接（绝对路径），URL 仍用于 Host 和路径"},
+	{Name: "dns_server", Kind: FieldString, Description: "解析域名使用的 DNS 服务器"},
+	{Name: "follow_redirects", Kind: FieldBoolean, Default: false, Description: "跟随重定向"},
+	{Name: "max_redirects", Kind: FieldInteger, Min: intBound(1), Description: "最大重定向次数，0 为不限"},
+	{Name: "expected_status_codes", Kind: FieldString, Description: "期望的状态码，逗号分隔；留空时 2xx 为正常"},
+	expectedHeadersField,
+	bodyMustContainField,
+	bodyMustNotContainField,
+	bodyRegexField,
+	jsonPathField,
+	jsonExpectedValueField,
+	jsonOperatorField,
+	httpStepsField,
+	trackContentHashField,
+	contentHashRegexField,
+	contentHashNormalizeField,
+	authTypeField,
+	authUsernameField,
+	authPasswordField,
+	authTokenField,
+	tlsClientCertField,
+	tlsClientKeyField,
+	tlsCACertField,
+	tlsSkipVerifyField,
+	tlsPinSHA256Field,
+	proxyURLField,
+	secondaryAddressField,
+	compareLatencyToleranceField,
+	compareBodyField,
+	degradedThresholdField,
+	downThresholdField,
+}
+
+var httpResults = []ResultField{
+	{Name: "status_code", In: "response", Description: "HTTP 状态码"},
+	{Name: "content_length", In: "response", Description: "Content-Length 头，未知时为 -1"},
+	{Name: "bytes_received", In: "response", Description: "收到的响应体字节数（解码前）"},
+	{Name: "decoded_body_bytes", In: "response", Description: "解码后的响应体字节数，无法解码时为 -1"},
+	{Name: "resolved_ip", In: "response_headers", Description: "实际连接的 IP；经 Unix socket 时为 unix:<path>"},
+	{Name: "title", In: "response_headers", Description: "HTML 页面标题"},
+	{Name: "unsupported_encoding", In: "data", Description: "无法解码的 Content-Encoding（如 br），结果为 warning 且未检查响应体断言；能解码时不返回"},
+	{Name: "clock_skew_ms", In: "data", Description: "服务器 Date 头与本机时钟之差（毫秒），服务器快为正；没有 Date 头时不返回"},
+	headerAssertionResult,
+	{Name: "body_assertion", In: "data", Description: "未通过的响应体断言，如 must contain \"ok\"；断言都通过时不返回"},
+	{Name: "json_value", In: "data", Description: "json_path 取出的值；响应体不是 JSON 或字段不存在时不返回"},
+	certificateInfoResult,
+	timingsResult,
+	stepsResult,
+	failedStepResult,
+	contentHashResults[0],
+	contentHashResults[1],
+	contentHashResults[2],
+	comparisonResults[0],
+	comparisonResults[1],
+	responseTimeThresholdResult,
+}
+
+func init() {
+	RegisterType(TypeSpec{
+		Type:        "http",
+		DisplayName: "HTTP",
+		Fields:      httpFields,
+		Results:     httpResults,
+	}, func() Checker { return &HTTPChecker{} })
+}
+
 type HTTPChecker struct{}
 
 func (c *HTTPChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckResult, error) {
+	// 多步事务：每一步都是一次下面的单个请求
+	if len(target.Steps) > 0 {
+		return c.checkSteps(ctx, target)
+	}
+
 	start := time.Now()
 
 	// 构建URL - 支持完整URL（包含路径）
-	url := target.Address
-	// 如果地址不包含协议前缀，添加协议
-	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-		if target.Type == "http" {
-			url = "http://" + url
-		} else if target.Type == "https" {
-			url = "https://" + url
-		}
-	}
+	url := target.httpURL()
 
 	// 确定HTTP方法
 	method := target.HTTPMethod
@@ -76,7 +155,8 @@ func (c *HTTPChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckR
 		req.Header.Set("Accept", "*/*")
 	}
 	if req.Header.Get("Accept-Encoding") == "" {
-		req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+		// br 无法解码，不主动请求
+		req.Header.Set("Accept-Encoding", "gzip, deflate")
 	}
 	if req.Header.Get("Accept-Language") == "" {
 		req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
@@ -85,100 +165,40 @@ func (c *HTTPChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckR
 		req.Header.Set("Connection", "keep-alive")
 	}
 
+	// 认证方式覆盖自定义 Headers 中的 Authorization
+	target.setAuth(req)
+
 	// 设置自定义Host
 	if target.ResolvedHost != "" {
 		req.Host = target.ResolvedHost
 	}
 
-	// Get global HTTP client with connection pooling
-	client := GetHTTPClient()
-
-	// Configure redirect policy
-	if !target.FollowRedirects {
-		// Create a new client that doesn't follow redirects
-		client = &http.Client{
-			Timeout: 30 * time.Second,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				return http.ErrUseLastResponse // Don't follow redirects
-			},
-		}
-	} else if target.MaxRedirects > 0 {
-		// Custom redirect limit
-		client = &http.Client{
-			Timeout: 30 * time.Second,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) >= target.MaxRedirects {
-					return fmt.Errorf("stopped after %d redirects", target.MaxRedirects)
-				}
-				return nil
-			},
-		}
+	// 客户端证书、自定义 CA、证书验证设置、代理、Unix socket 或 DNS 服务器：
+	// 使用该目标自己的 transport，共享的 transport 不被修改
+	tlsConfig, err := target.tlsConfig()
+	if err != nil {
+		return tlsConfigResult(err, time.Since(start).Milliseconds()), nil
 	}
-
-	// Configure custom DNS resolver if needed
-	if target.DNSServer != "" {
-		// Create custom transport with DNS resolver
-		transport := client.Transport.(*http.Transport)
-
-		dialer := &net.Dialer{
-			Timeout:   10 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}
-
-		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
-			// 解析DNS服务器地址
-			dnsServer := target.DNSServer
-			if !strings.Contains(dnsServer, ":") {
-				dnsServer = dnsServer + ":53"
-			}
-
-			// 使用自定义DNS解析器
-			resolver := &net.Resolver{
-				PreferGo: true,
-				Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-					return dialer.DialContext(ctx, "udp", dnsServer)
-				},
-			}
-
-			// 从地址中提取主机名
-			host, _, err := net.SplitHostPort(addr)
-			if err != nil {
-				host = addr
-			}
-
-			// 使用自定义DNS解析
-			ips, err := resolver.LookupHost(ctx, host)
-			if err != nil {
-				logger.Error("DNS resolution failed",
-					zap.String("host", host),
-					zap.String("dns_server", target.DNSServer),
-					zap.Error(err),
-				)
-				return nil, err
-			}
-
-			// 使用第一个IP地址
-			if len(ips) > 0 {
-				ip := ips[0]
-				if _, port, err := net.SplitHostPort(addr); err == nil {
-					addr = net.JoinHostPort(ip, port)
-				} else {
-					addr = net.JoinHostPort(ip, "443")
-				}
-			}
-
-			return dialer.DialContext(ctx, network, addr)
-		}
-
-		// Create client with custom transport
-		client = &http.Client{
-			Timeout:   30 * time.Second,
-			Transport: transport,
-		}
+	proxy, err := target.proxy()
+	if err != nil {
+		return &CheckResult{
+			Status:       "down",
+			ResponseTime: time.Since(start).Milliseconds(),
+			Message:      fmt.Sprintf("Invalid proxy: %v", err),
+			Error:        &ErrorDetails{Type: proxyError, Message: err.Error()},
+		}, nil
 	}
+	client, release := newTargetClient(target, tlsConfig, proxy)
+	defer release()
+
+	// 记录各阶段耗时；请求写完的时间也用于根据 Date 头估算时钟偏差
+	timings := &httpTimings{}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), timings.trace()))
 
 	// 执行请求
+	sent := time.Now()
 	resp, err := client.Do(req)
+	received := time.Now()
 	if err != nil {
 		logger.Warn("HTTP request failed",
 			zap.String("target", target.Name),
@@ -193,12 +213,15 @@ func (c *HTTPChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckR
 			ResponseTime: responseTime,
 			Message:      fmt.Sprintf("Request failed: %v", err),
 		}
+		if hint := certTimeHint(err, target.ID); hint != "" {
+			result.Message = fmt.Sprintf("%s (%s)", result.Message, hint)
+		}
 
 		// 保存请求详情
 		result.Request = RequestDetails{
 			Method:      method,
 			URL:         url,
-			Headers:     cloneHeaders(req.Header),
+			Headers:     requestHeaders(req.Header),
 			Body:        target.HTTPBody,
 		}
 
@@ -206,6 +229,15 @@ func (c *HTTPChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckR
 			Type:    "network_error",
 			Message: err.Error(),
 		}
+		if proxy != nil && isProxyError(err) {
+			result.Message = fmt.Sprintf("Proxy connection failed: %v", err)
+			result.Error.Type = proxyError
+		}
+		if details := pinMismatchDetails(err); details != nil {
+			result.Error = details
+		}
+		// 失败前完成的阶段，用于判断卡在 DNS、连接还是握手
+		result.Data = map[string]interface{}{"timings": timings.data(time.Time{})}
 
 		return result, nil
 	}
@@ -213,12 +245,254 @@ func (c *HTTPChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckR
 
 	responseTime := time.Since(start).Milliseconds()
 
+	// http 地址的请求由代理直接转发，代理认证失败时返回 407 而不是 CONNECT 错误
+	if proxy != nil && resp.StatusCode == http.StatusProxyAuthRequired {
+		message := fmt.Sprintf("proxy responded with %s", resp.Status)
+		return &CheckResult{
+			Status:       "down",
+			ResponseTime: responseTime,
+			Message:      "Proxy connection failed: " + message,
+			Request: RequestDetails{
+				Method:  method,
+				URL:     url,
+				Headers: requestHeaders(req.Header),
+				Body:    target.HTTPBody,
+			},
+			Error: &ErrorDetails{Type: proxyError, Message: message},
+		}, nil
+	}
+
 	logger.Debug("HTTP check completed",
 		zap.String("target", target.Name),
 		zap.Int("status_code", resp.StatusCode),
 		zap.Int64("response_time", responseTime),
 	)
 
+	// 读取响应体（传输中的原始字节，可能是压缩的）
+	rawBody, err := io.ReadAll(resp.Body)
+	bodyDone := time.Now()
+	if err != nil {
+		logger.Warn("Failed to read response body",
+			zap.String("target", target.Name),
+			zap.Error(err),
+		)
+	}
+
+	// Address actually connected to, stored as resolved_ip; looked up after
+	// the body so that the lookup isn't counted in the transfer time
+	resolvedIP := resolvedAddress(target, resp)
+	bytesReceived := int64(len(rawBody))
+
+	// 按 Content-Encoding 解码，Title 提取和保存的响应体都使用解码后的内容
+	decodedBody, decodedBytes, decodeErr := decodeBody(resp.Header.Get("Content-Encoding"), rawBody)
+	if decodeErr != nil {
+		logger.Debug("Failed to decode response body",
+			zap.String("target", target.Name),
+			zap.String("content_encoding", resp.Header.Get("Content-Encoding")),
+			zap.Error(decodeErr),
+		)
+	}
+
+	// 限制保存的响应体大小（避免存储过大的响应）
+	policy := responseBodyPolicy
+	storedBody := decodedBody
+	if len(storedBody) > policy.MaxStoredBytes {
+		storedBody = append(storedBody[:policy.MaxStoredBytes:policy.MaxStoredBytes], []byte("... (truncated)")...)
+	}
+	if err != nil {
+		storedBody = []byte(fmt.Sprintf("Failed to read response body: %v", err))
+	} else if decodeErr != nil {
+		storedBody = []byte(fmt.Sprintf("Failed to decode response body: %v", decodeErr))
+	}
+
+	// Determine status based on expected status codes
+	status := determineStatus(resp.StatusCode, target.ExpectedStatusCodes)
+
+	// 构建结果
+	result := &CheckResult{
+		Status:       status,
+		ResponseTime: responseTime,
+		Message:      fmt.Sprintf("HTTP %d %s", resp.StatusCode, resp.Status),
+		Data:         map[string]interface{}{"timings": timings.data(bodyDone)},
+	}
+
+	// 保存请求详情
+	result.Request = RequestDetails{
+		Method:      method,
+		URL:         url,
+		Headers:     requestHeaders(req.Header),
+		Body:        target.HTTPBody,
+	}
+
+	// 保存响应详情，包含解析的IP
+	result.Response = ResponseDetails{
+		StatusCode:       resp.StatusCode,
+		Headers:          cloneHeaders(resp.Header),
+		Body:             string(storedBody),
+		ContentLength:    resp.ContentLength,
+		BytesReceived:    bytesReceived,
+		DecodedBodyBytes: decodedBytes,
+	}
+
+	// Add resolved IP to headers for storage
+	if result.Response.Headers == nil {
+		result.Response.Headers = make(map[string]string)
+	}
+	result.Response.Headers["resolved_ip"] = resolvedIP
+
+	// 跟随重定向到其他主机时出示证书的不是这个目标，不记录
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 && resp.Request.URL.Host == req.URL.Host {
+		result.Certificate = newCertificateInfo(resp.TLS.PeerCertificates[0])
+		if target.TLSSkipVerify || target.TLSPinSHA256 != "" {
+			if result.Data == nil {
+				result.Data = make(map[string]interface{})
+			}
+			result.Data["certificate_info"] = target.certificateData(resp.TLS.PeerCertificates[0])
+		}
+	}
+
+	// 与服务器 Date 头比较本机时钟；没有或无法解析 Date 头时跳过
+	if wrote := timings.requestWritten(); !wrote.IsZero() {
+		sent = wrote
+	}
+	if skew, ok := measureClockSkew(resp.Header, sent, received); ok {
+		recordClockSkew(target, result, skew)
+	}
+
+	unsupportedEncoding := errors.Is(decodeErr, errUnsupportedEncoding)
+
+	// 状态码正常时检查响应头断言；跟随重定向时针对最终的响应
+	if result.Status == "up" && len(target.ExpectedHeaders) > 0 {
+		if failed, got := checkHeaderAssertions(target.ExpectedHeaders, resp.Header); failed != "" {
+			result.Status = "down"
+			if got == "" {
+				result.Message = fmt.Sprintf("%s, header %s", result.Message, failed)
+			} else {
+				result.Message = fmt.Sprintf("%s, header %s; got %q", result.Message, failed, got)
+			}
+			if result.Data == nil {
+				result.Data = make(map[string]interface{})
+			}
+			result.Data["header_assertion"] = failed
+		}
+	}
+
+	// 状态码正常时检查响应体断言；跟随重定向时针对最终的响应
+	if result.Status == "up" && target.hasBodyAssertions() && !unsupportedEncoding {
+		if err != nil || decodeErr != nil {
+			result.Status = "down"
+			result.Message = fmt.Sprintf("%s, body assertions not checked: %s", result.Message, storedBody)
+		} else if failed, excerpt := checkBodyAssertions(target, decodedBody); failed != "" {
+			result.Status = "down"
+			result.Message = fmt.Sprintf("%s, body %s; body: %q", result.Message, failed, excerpt)
+			if result.Data == nil {
+				result.Data = make(map[string]interface{})
+			}
+			result.Data["body_assertion"] = failed
+		}
+	}
+
+	// JSON 字段断言：取出的值记入 data，响应体不是 JSON 或字段不存在时为 down
+	if result.Status == "up" && target.JSONPath != nil && !unsupportedEncoding {
+		if err != nil || decodeErr != nil {
+			result.Status = "down"
+			result.Message = fmt.Sprintf("%s, json_path not checked: %s", result.Message, storedBody)
+			result.Error = &ErrorDetails{Type: jsonAssertionError, Message: string(storedBody)}
+		} else {
+			value, errType, message := checkJSONAssertion(target, decodedBody)
+			if errType == "" || errType == jsonAssertionFailed {
+				if result.Data == nil {
+					result.Data = make(map[string]interface{})
+				}
+				result.Data["json_value"] = value
+			}
+			if errType != "" {
+				result.Status = "down"
+				result.Message = fmt.Sprintf("%s, %s", result.Message, message)
+				result.Error = &ErrorDetails{Type: errType, Message: message}
+			}
+		}
+	}
+
+	// 不支持的编码（br）不是服务故障：响应体断言未检查，结果为 warning
+	if unsupportedEncoding {
+		if result.Data == nil {
+			result.Data = make(map[string]interface{})
+		}
+		result.Data["unsupported_encoding"] = resp.Header.Get("Content-Encoding")
+		if result.Status == "up" {
+			result.Status = "warning"
+		}
+		result.Message = fmt.Sprintf("%s | Response body not decoded: %v", result.Message, decodeErr)
+	}
+
+	// Extract title from HTML response if content-type is HTML
+	if strings.Contains(resp.Header.Get("Content-Type"), "text/html") {
+		if title := extractTitle(decodedBody); title != "" {
+			result.Response.Headers["title"] = title
+		}
+	}
+
+	// 对比模式下比较两个地址的响应体
+	if target.CompareBody && target.SecondaryAddress != "" && err == nil && decodeErr == nil {
+		sum := sha256.Sum256(decodedBody)
+		result.bodySHA256 = hex.EncodeToString(sum[:])
+	}
+
+	// 内容变化检测：只对正常的响应计算，错误页的变化不算内容变化
+	if target.TrackContentHash && result.Status == "up" && err == nil && decodeErr == nil {
+		if result.Data == nil {
+			result.Data = make(map[string]interface{})
+		}
+		result.Data["content_hash"] = contentHash(target, decodedBody)
+	}
+
+	// 事务的后续步骤可以引用这一步的响应
+	if target.keepBody && err == nil && decodeErr == nil {
+		result.body = decodedBody
+	}
+
+	// 失败时保留完整响应，saveResult 只在 up 变为 down 时存档
+	if policy.Archive && result.Status == "down" && err == nil && decodeErr == nil {
+		result.capture = newResponseCapture(decodedBody, policy.ArchiveMaxBytes)
+	}
+
+	return result, nil
+}
+
+const (
+	// maxDecodedBodyBytes 解码时在内存中保留的上限，超出部分只计数
+	maxDecodedBodyBytes = 10 << 20
+	// maxCountedBodyBytes 解码计数的上限，防止压缩炸弹占满 CPU
+	maxCountedBodyBytes = 1 << 30
+)
+
+// errUnsupportedEncoding 响应使用了无法解码的 Content-Encoding（如 br）。
+// 没有可用的 brotli 解码器，结果标记为 warning 而不是按解码失败处理。
+var errUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// httpURL returns the URL of the target's address, adding the scheme of its
+// type when the address has none
+func (t *MonitorTarget) httpURL() string {
+	url := t.Address
+	// 如果地址不包含协议前缀，添加协议
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		if t.Type == "http" {
+			url = "http://" + url
+		} else if t.Type == "https" {
+			url = "https://" + url
+		}
+	}
+	return url
+}
+
+// resolvedAddress returns the IP the target's host resolves to, or unix:<path>
+// for checks that go through a Unix socket
+func resolvedAddress(target *MonitorTarget, resp *http.Response) string {
+	if target.UnixSocketPath != "" {
+		return "unix:" + target.UnixSocketPath
+	}
+
 	// Get actual resolved IP from DNS lookup
 	resolvedIP := ""
 	host := target.Address
@@ -273,79 +547,60 @@ func (c *HTTPChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckR
 			zap.String("hostname", resolvedIP),
 		)
 	}
+	return resolvedIP
+}
 
-	// 读取响应体
-	responseBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		logger.Warn("Failed to read response body",
-			zap.String("target", target.Name),
-			zap.Error(err),
-		)
-		responseBody = []byte(fmt.Sprintf("Failed to read response body: %v", err))
+// decodeBody 按 Content-Encoding 解码响应体，返回解码后的内容（最多 maxDecodedBodyBytes）
+// 和解码后的总字节数。支持 gzip、deflate 和 identity，多个编码按逆序解码；
+// 无法解码时返回原始字节，解码后字节数为 -1。
+func decodeBody(contentEncoding string, raw []byte) ([]byte, int64, error) {
+	var encodings []string
+	for _, e := range strings.Split(contentEncoding, ",") {
+		if e = strings.ToLower(strings.TrimSpace(e)); e != "" && e != "identity" {
+			encodings = append(encodings, e)
+		}
+	}
+	if len(encodings) == 0 {
+		return raw, int64(len(raw)), nil
 	}
 
-	// 解压gzip响应体（用于提取Title）
-	var htmlBody []byte
-	if strings.Contains(resp.Header.Get("Content-Encoding"), "gzip") {
-		reader, err := gzip.NewReader(bytes.NewReader(responseBody))
-		if err == nil {
-			htmlBody, err = io.ReadAll(reader)
+	var reader io.Reader = bytes.NewReader(raw)
+	for i := len(encodings) - 1; i >= 0; i-- {
+		switch encodings[i] {
+		case "gzip", "x-gzip":
+			gz, err := gzip.NewReader(reader)
 			if err != nil {
-				htmlBody = responseBody // 如果解压失败，使用原始数据
+				return raw, -1, err
 			}
-		} else {
-			htmlBody = responseBody
+			reader = gz
+		case "deflate":
+			reader = deflateReader(reader)
+		default:
+			return raw, -1, fmt.Errorf("%w: %s", errUnsupportedEncoding, encodings[i])
 		}
-	} else {
-		htmlBody = responseBody
 	}
 
-	// 限制响应体大小（避免存储过大的响应）
-	maxBodySize := 1024 * 100 // 100KB
-	if len(responseBody) > maxBodySize {
-		responseBody = append(responseBody[:maxBodySize], []byte("... (truncated)")...)
-	}
-
-	// Determine status based on expected status codes
-	status := determineStatus(resp.StatusCode, target.ExpectedStatusCodes)
-
-	// 构建结果
-	result := &CheckResult{
-		Status:       status,
-		ResponseTime: responseTime,
-		Message:      fmt.Sprintf("HTTP %d %s", resp.StatusCode, resp.Status),
-	}
-
-	// 保存请求详情
-	result.Request = RequestDetails{
-		Method:      method,
-		URL:         url,
-		Headers:     cloneHeaders(req.Header),
-		Body:        target.HTTPBody,
-	}
-
-	// 保存响应详情，包含解析的IP
-	result.Response = ResponseDetails{
-		StatusCode:    resp.StatusCode,
-		Headers:       cloneHeaders(resp.Header),
-		Body:          string(responseBody),
-		ContentLength: resp.ContentLength,
+	decoded, err := io.ReadAll(io.LimitReader(reader, maxDecodedBodyBytes))
+	if err != nil {
+		return raw, -1, err
 	}
-
-	// Add resolved IP to headers for storage
-	if result.Response.Headers == nil {
-		result.Response.Headers = make(map[string]string)
+	rest, err := io.Copy(io.Discard, io.LimitReader(reader, maxCountedBodyBytes))
+	if err != nil {
+		return raw, -1, err
 	}
-	result.Response.Headers["resolved_ip"] = resolvedIP
+	return decoded, int64(len(decoded)) + rest, nil
+}
 
-	// Extract title from HTML response if content-type is HTML
-	if strings.Contains(resp.Header.Get("Content-Type"), "text/html") {
-		if title := extractTitle(htmlBody); title != "" {
-			result.Response.Headers["title"] = title
+// deflateReader 解码 deflate 编码。RFC 9110 的 deflate 是 zlib 格式，但不少服务器发送裸 deflate 流，
+// 按开头两个字节是否为不带预设字典的 zlib 头区分
+func deflateReader(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	if header, err := br.Peek(2); err == nil && header[0]&0x0f == 8 && header[1]&0x20 == 0 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		if zr, err := zlib.NewReader(br); err == nil {
+			return zr
 		}
 	}
-
-	return result, nil
+	return flate.NewReader(br)
 }
 
 // extractTitle extracts the title from HTML content
@@ -1,11 +1,23 @@
 package monitor
 
 import (
+	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
+	"fmt"
 	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"monitor/internal/logger"
+	"monitor/internal/lru"
+
+	"go.uber.org/zap"
 )
 
 // Global HTTP client with connection pooling
@@ -36,9 +48,6 @@ func GetHTTPClient() *http.Client {
 				KeepAlive: 30 * time.Second, // Keep alive timeout
 			}).DialContext,
 
-			// Response header timeout
-			ResponseHeaderTimeout: 30 * time.Second,
-
 			// Expect continue timeout (for 100-Continue)
 			ExpectContinueTimeout: 1 * time.Second,
 
@@ -46,11 +55,223 @@ func GetHTTPClient() *http.Client {
 			ForceAttemptHTTP2: true,
 		}
 
+		// Each check sets its target's timeout on a copy, see HTTPChecker
 		globalHTTPClient = &http.Client{
-			Timeout:   30 * time.Second,
+			Timeout:   checkTimeout,
 			Transport: transport,
 		}
 	})
 
 	return globalHTTPClient
-}
\ No newline at end of file
+}
+
+// MaxTargetTransports bounds the per-target transports kept by
+// targetTransports; an evicted target builds a new one on its next check
+const MaxTargetTransports = 10000
+
+// targetTransports caches the transport of each target whose settings need
+// one of its own (see needsOwnTransport), so that its connections are pooled
+// across checks without touching the shared transport of GetHTTPClient
+var targetTransports = newTransportCache(MaxTargetTransports)
+
+type transportCache struct {
+	mu         sync.Mutex
+	transports *lru.Cache[uint32, cachedTransport]
+}
+
+type cachedTransport struct {
+	key       string // transportKey of the settings it was built from
+	transport *http.Transport
+}
+
+func newTransportCache(max int) *transportCache {
+	return &transportCache{transports: lru.New[uint32, cachedTransport](max)}
+}
+
+// get returns the transport of a target, building it when the target has
+// none yet or its settings changed since
+func (c *transportCache) get(id uint32, key string, build func() *http.Transport) *http.Transport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.transports.Get(id); ok {
+		if cached.key == key {
+			return cached.transport
+		}
+		cached.transport.CloseIdleConnections()
+	}
+	transport := build()
+	c.transports.Add(id, cachedTransport{key: key, transport: transport})
+	return transport
+}
+
+// forget drops the transport of a removed target and closes its idle connections
+func (c *transportCache) forget(id uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.transports.Get(id); ok {
+		cached.transport.CloseIdleConnections()
+		c.transports.Remove(id)
+	}
+}
+
+// newTargetClient returns the client of one check of target: the target's
+// timeout and redirect policy on the shared transport, or on a transport of
+// its own when needsOwnTransport. The shared transport is never modified.
+// Targets without an ID (dry runs) get a transport that release closes;
+// release is a no-op otherwise.
+func newTargetClient(target *MonitorTarget, tlsConfig *tls.Config, proxy *url.URL) (client *http.Client, release func()) {
+	client = &http.Client{
+		Timeout:       target.timeout(),
+		CheckRedirect: redirectPolicy(target),
+		Transport:     GetHTTPClient().Transport,
+		Jar:           target.jar,
+	}
+	release = func() {}
+	if !target.needsOwnTransport(tlsConfig, proxy) {
+		return client, release
+	}
+
+	build := func() *http.Transport { return newTargetTransport(target, tlsConfig, proxy) }
+	if target.ID == 0 {
+		transport := build()
+		client.Transport = transport
+		return client, transport.CloseIdleConnections
+	}
+	client.Transport = targetTransports.get(target.ID, target.transportKey(), build)
+	return client, release
+}
+
+// redirectPolicy returns the CheckRedirect of a target: not following
+// redirects, following at most MaxRedirects, or the net/http default of 10
+func redirectPolicy(target *MonitorTarget) func(req *http.Request, via []*http.Request) error {
+	if !target.FollowRedirects {
+		return func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse // Don't follow redirects
+		}
+	}
+	if max := target.MaxRedirects; max > 0 {
+		return func(req *http.Request, via []*http.Request) error {
+			if len(via) >= max {
+				return fmt.Errorf("stopped after %d redirects", max)
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// needsOwnTransport reports whether the target's connections can't use the
+// shared transport: a client certificate, CA or verification setting, a
+// proxy, a Unix socket, a custom DNS server or a network policy
+func (t *MonitorTarget) needsOwnTransport(tlsConfig *tls.Config, proxy *url.URL) bool {
+	return tlsConfig != nil || proxy != nil || t.UnixSocketPath != "" || t.DNSServer != "" || t.NetworkPolicy != nil
+}
+
+// transportKey identifies the settings a target's transport is built from,
+// so that a changed target gets a new one. It is a hash: the settings
+// include PEM blocks and the proxy password.
+func (t *MonitorTarget) transportKey() string {
+	h := sha256.New()
+	for _, part := range []string{
+		t.UnixSocketPath, t.DNSServer, t.ProxyURL, t.ProxyPassword,
+		t.TLSClientCertPEM, t.TLSClientKeyPEM, t.TLSCACertPEM,
+		strconv.FormatBool(t.TLSSkipVerify), t.TLSPinSHA256,
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// newTargetTransport builds the transport of a target that needs its own:
+// a Unix socket transport, or a clone of the shared transport with the
+// target's TLS settings, proxy, DNS server and network policy. A CONNECT
+// refused by the proxy fails with a proxyStatusError.
+func newTargetTransport(target *MonitorTarget, tlsConfig *tls.Config, proxy *url.URL) *http.Transport {
+	// Unix socket: the URL still provides Host and path, the custom DNS server is not used
+	if target.UnixSocketPath != "" {
+		transport := newUnixSocketTransport(target.UnixSocketPath)
+		if tlsConfig != nil {
+			transport.TLSClientConfig = tlsConfig
+		}
+		return transport
+	}
+
+	transport := GetHTTPClient().Transport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	if proxy != nil {
+		transport.Proxy = http.ProxyURL(proxy)
+		transport.OnProxyConnectResponse = func(_ context.Context, _ *url.URL, _ *http.Request, resp *http.Response) error {
+			if resp.StatusCode != http.StatusOK {
+				return &proxyStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+			}
+			return nil
+		}
+	}
+	// The policy applies to the connections to the target, not to the DNS server
+	dialer := target.dialer(10 * time.Second)
+	dialer.KeepAlive = 30 * time.Second
+	transport.DialContext = dialer.DialContext
+	if target.DNSServer != "" {
+		transport.DialContext = dnsServerDialContext(target.DNSServer, dialer)
+	}
+	return transport
+}
+
+// dnsServerDialContext returns a DialContext resolving host names through
+// server (port 53 unless given) and dialing the first address with dialer
+func dnsServerDialContext(server string, dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dnsDialer := &net.Dialer{Timeout: 10 * time.Second}
+	dnsServer := server
+	if !strings.Contains(dnsServer, ":") {
+		dnsServer = dnsServer + ":53"
+	}
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return dnsDialer.DialContext(ctx, "udp", dnsServer)
+		},
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// 从地址中提取主机名
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			host, port = addr, "443"
+		}
+
+		// 使用自定义DNS解析
+		ips, err := resolver.LookupHost(ctx, host)
+		if err != nil {
+			logger.Error("DNS resolution failed",
+				zap.String("host", host),
+				zap.String("dns_server", server),
+				zap.Error(err),
+			)
+			return nil, err
+		}
+
+		// 使用第一个IP地址
+		if len(ips) > 0 {
+			addr = net.JoinHostPort(ips[0], port)
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
+
+// newUnixSocketTransport returns a transport whose connections all go to the
+// Unix socket at path, whatever host the request URL names
+func newUnixSocketTransport(path string) *http.Transport {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	return &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		},
+		TLSClientConfig: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		},
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
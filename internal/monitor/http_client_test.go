@@ -0,0 +1,141 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+
+	"monitor/internal/logger"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// startNamedHTTPServer serves its name on ip:port until the end of the test
+func startNamedHTTPServer(t *testing.T, ip, port, name string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", net.JoinHostPort(ip, port))
+	if err != nil {
+		t.Skipf("listen on %s: %v", ip, err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, name)
+	})}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+}
+
+// twoResolvers starts "server-a" on 127.0.0.1 and "server-b" on 127.0.0.2,
+// on the same port, and a DNS server for each resolving every name to it.
+// It returns the port and the two DNS servers.
+func twoResolvers(t *testing.T) (port, dnsA, dnsB string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	_, port, _ = net.SplitHostPort(ln.Addr().String())
+	ln.Close()
+	startNamedHTTPServer(t, "127.0.0.1", port, "server-a")
+	startNamedHTTPServer(t, "127.0.0.2", port, "server-b")
+
+	dnsA = startDNSServer(t, fakeDNSAnswer{RCode: dnsmessage.RCodeSuccess, A: []string{"127.0.0.1"}})
+	dnsB = startDNSServer(t, fakeDNSAnswer{RCode: dnsmessage.RCodeSuccess, A: []string{"127.0.0.2"}})
+	return port, dnsA, dnsB
+}
+
+func dialContextOf(client *http.Client) uintptr {
+	return reflect.ValueOf(client.Transport.(*http.Transport).DialContext).Pointer()
+}
+
+// Two targets with different DNS servers checked at the same time must each
+// reach the address their own server resolves, and leave the shared
+// transport alone. Run with -race.
+func TestHTTPTargetsResolveThroughTheirOwnDNSServer(t *testing.T) {
+	port, dnsA, dnsB := twoResolvers(t)
+	sharedDial := dialContextOf(GetHTTPClient())
+	// The logger initializes itself on first use, not concurrently
+	logger.GetLogger()
+
+	url := "http://service.test:" + port + "/"
+	targets := []*MonitorTarget{
+		{ID: 9101, Name: "a", Type: "http", Address: url, DNSServer: dnsA, BodyMustContain: "server-a"},
+		{ID: 9102, Name: "b", Type: "http", Address: url, DNSServer: dnsB, BodyMustContain: "server-b"},
+	}
+	t.Cleanup(func() {
+		for _, target := range targets {
+			targetTransports.forget(target.ID)
+		}
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for _, target := range targets {
+			wg.Add(1)
+			go func(target *MonitorTarget) {
+				defer wg.Done()
+				result, err := (&HTTPChecker{}).Check(context.Background(), target)
+				if err != nil {
+					t.Errorf("%s: %v", target.Name, err)
+					return
+				}
+				if result.Status != "up" {
+					t.Errorf("%s: status = %s (%s), want up from its own server", target.Name, result.Status, result.Message)
+				}
+			}(target)
+		}
+	}
+	wg.Wait()
+
+	if dialContextOf(GetHTTPClient()) != sharedDial {
+		t.Error("the shared transport's DialContext was replaced")
+	}
+}
+
+func TestHTTPTargetTransportFollowsSettings(t *testing.T) {
+	port, dnsA, dnsB := twoResolvers(t)
+	target := &MonitorTarget{ID: 9103, Name: "moved", Type: "http", Address: "http://service.test:" + port + "/", DNSServer: dnsA, BodyMustContain: "server-b"}
+	t.Cleanup(func() { targetTransports.forget(target.ID) })
+
+	result, _ := (&HTTPChecker{}).Check(context.Background(), target)
+	if result.Status == "up" {
+		t.Fatalf("reached server-b through the DNS server of server-a")
+	}
+
+	// A changed DNS server gets a new transport, not the cached one
+	target.DNSServer = dnsB
+	result, _ = (&HTTPChecker{}).Check(context.Background(), target)
+	if result.Status != "up" {
+		t.Errorf("after changing the DNS server: status = %s (%s), want up", result.Status, result.Message)
+	}
+}
+
+func TestHTTPTargetClientTransport(t *testing.T) {
+	shared := GetHTTPClient().Transport
+
+	plain, _ := newTargetClient(&MonitorTarget{ID: 9104, Address: "http://example.com"}, nil, nil)
+	if plain.Transport != shared {
+		t.Error("target without transport settings did not get the shared transport")
+	}
+
+	withDNS := &MonitorTarget{ID: 9105, Address: "http://example.com", DNSServer: "127.0.0.1:53"}
+	t.Cleanup(func() { targetTransports.forget(withDNS.ID) })
+	first, _ := newTargetClient(withDNS, nil, nil)
+	second, _ := newTargetClient(withDNS, nil, nil)
+	if first.Transport == shared || first.Transport != second.Transport {
+		t.Error("target with a DNS server did not get its own cached transport")
+	}
+
+	// Dry runs have no ID and get a transport of their own each time
+	dryRun := &MonitorTarget{Address: "http://example.com", DNSServer: "127.0.0.1:53"}
+	a, releaseA := newTargetClient(dryRun, nil, nil)
+	b, releaseB := newTargetClient(dryRun, nil, nil)
+	defer releaseA()
+	defer releaseB()
+	if a.Transport == b.Transport || a.Transport == shared {
+		t.Error("dry runs share a transport")
+	}
+}
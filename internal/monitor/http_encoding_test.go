@@ -0,0 +1,173 @@
+package monitor
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func encodedServer(t *testing.T, status int, encoding string, body []byte) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", encoding)
+		w.WriteHeader(status)
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHTTPCheckBrotliIsWarning(t *testing.T) {
+	srv := encodedServer(t, http.StatusOK, "br", []byte{0x1b, 0x02, 0x00, 0xf8})
+	target := &MonitorTarget{Name: "br", Type: "http", Address: srv.URL, BodyMustContain: "ok"}
+
+	result, err := (&HTTPChecker{}).Check(context.Background(), target)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if result.Status != "warning" {
+		t.Errorf("status = %q, want warning (message %q)", result.Status, result.Message)
+	}
+	if got := result.Data["unsupported_encoding"]; got != "br" {
+		t.Errorf("data.unsupported_encoding = %v, want br", got)
+	}
+	if _, ok := result.Data["body_assertion"]; ok {
+		t.Error("body assertion reported on a body that was not decoded")
+	}
+	if result.Response.DecodedBodyBytes != -1 {
+		t.Errorf("decoded_body_bytes = %d, want -1", result.Response.DecodedBodyBytes)
+	}
+}
+
+func TestHTTPCheckBrotliKeepsStatusCodeFailure(t *testing.T) {
+	srv := encodedServer(t, http.StatusInternalServerError, "br", []byte{0x1b})
+	result, err := (&HTTPChecker{}).Check(context.Background(), &MonitorTarget{Name: "br", Type: "http", Address: srv.URL})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if result.Status != "down" {
+		t.Errorf("status = %q, want down", result.Status)
+	}
+}
+
+func TestHTTPCheckGzipBodyAssertion(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(`{"status":"ok"}`))
+	gz.Close()
+	srv := encodedServer(t, http.StatusOK, "gzip", buf.Bytes())
+
+	result, err := (&HTTPChecker{}).Check(context.Background(), &MonitorTarget{Name: "gzip", Type: "http", Address: srv.URL, BodyMustContain: `"ok"`})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if result.Status != "up" {
+		t.Errorf("status = %q, want up (message %q)", result.Status, result.Message)
+	}
+	if result.Response.DecodedBodyBytes != int64(len(`{"status":"ok"}`)) {
+		t.Errorf("decoded_body_bytes = %d", result.Response.DecodedBodyBytes)
+	}
+}
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "zlib":
+		w = zlib.NewWriter(&buf)
+	case "flate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	}
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestDecodeBody(t *testing.T) {
+	plain := []byte(strings.Repeat("hello ", 100))
+	for _, tc := range []struct {
+		name, encoding string
+		raw            []byte
+	}{
+		{"identity", "identity", plain},
+		{"none", "", plain},
+		{"gzip", "GZIP", compress(t, "gzip", plain)},
+		{"zlib deflate", "deflate", compress(t, "zlib", plain)},
+		{"raw deflate", "deflate", compress(t, "flate", plain)},
+		// Applied in order, so gzip is undone first
+		{"stacked", "deflate, gzip", compress(t, "gzip", compress(t, "zlib", plain))},
+	} {
+		decoded, n, err := decodeBody(tc.encoding, tc.raw)
+		if err != nil || !bytes.Equal(decoded, plain) || n != int64(len(plain)) {
+			t.Errorf("%s: %d bytes, %v", tc.name, n, err)
+		}
+	}
+
+	raw := []byte("not compressed")
+	if decoded, n, err := decodeBody("br", raw); !errors.Is(err, errUnsupportedEncoding) || n != -1 || !bytes.Equal(decoded, raw) {
+		t.Errorf("br: %d bytes, %v", n, err)
+	}
+	if _, n, err := decodeBody("gzip", raw); err == nil || n != -1 {
+		t.Errorf("corrupt gzip: %d bytes, %v", n, err)
+	}
+}
+
+// Only maxDecodedBodyBytes of a large body is kept, the rest is counted
+func TestDecodeBodyKeepsLimit(t *testing.T) {
+	plain := bytes.Repeat([]byte{'a'}, maxDecodedBodyBytes+1000)
+	decoded, n, err := decodeBody("gzip", compress(t, "gzip", plain))
+	if err != nil || len(decoded) != maxDecodedBodyBytes || n != int64(len(plain)) {
+		t.Errorf("kept %d of %d bytes, %v", len(decoded), n, err)
+	}
+}
+
+func TestHTTPCheckBodySizes(t *testing.T) {
+	page := []byte("<html><head><title>Status page</title></head><body>" + strings.Repeat("ok ", 500) + "</body></html>")
+	wire := compress(t, "gzip", page)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Encoding", "gzip")
+		if r.URL.Path == "/chunked" {
+			w.Write(wire[:10])
+			w.(http.Flusher).Flush()
+			w.Write(wire[10:])
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(wire)))
+		w.Write(wire)
+	}))
+	t.Cleanup(srv.Close)
+
+	result, err := (&HTTPChecker{}).Check(context.Background(), &MonitorTarget{Name: "page", Type: "http", Address: srv.URL})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	resp := result.Response
+	if resp.ContentLength != int64(len(wire)) || resp.BytesReceived != int64(len(wire)) || resp.DecodedBodyBytes != int64(len(page)) {
+		t.Errorf("content_length %d bytes_received %d decoded_body_bytes %d, want %d %d %d",
+			resp.ContentLength, resp.BytesReceived, resp.DecodedBodyBytes, len(wire), len(wire), len(page))
+	}
+	if resp.Body != string(page) || resp.Headers["title"] != "Status page" {
+		t.Errorf("stored body is not the decoded page, title %q", resp.Headers["title"])
+	}
+
+	result, err = (&HTTPChecker{}).Check(context.Background(), &MonitorTarget{Name: "page", Type: "http", Address: srv.URL + "/chunked"})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if result.Response.ContentLength != -1 || result.Response.BytesReceived != int64(len(wire)) {
+		t.Errorf("chunked: content_length %d bytes_received %d", result.Response.ContentLength, result.Response.BytesReceived)
+	}
+}
@@ -0,0 +1,94 @@
+package monitor
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// socketDir is short enough for the sun_path limit, unlike t.TempDir
+func socketDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "sock")
+	if err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// The check connects to the socket; the URL still gives the Host header and path
+func TestHTTPCheckUnixSocket(t *testing.T) {
+	path := filepath.Join(socketDir(t), "app.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("listen on a unix socket: %v", err)
+	}
+	var host, requestPath string
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, requestPath = r.Host, r.URL.Path
+		w.Write([]byte("healthy"))
+	})}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+
+	target := &MonitorTarget{Name: "app", Type: "http", Address: "http://app.invalid/health", UnixSocketPath: path}
+	result, err := (&HTTPChecker{}).Check(context.Background(), target)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if result.Status != "up" || host != "app.invalid" || requestPath != "/health" {
+		t.Errorf("status %s (%s), request Host %q path %q", result.Status, result.Message, host, requestPath)
+	}
+	if got := result.Response.Headers["resolved_ip"]; got != "unix:"+path {
+		t.Errorf("resolved_ip = %v, want unix:%s", got, path)
+	}
+
+	srv.Close()
+	result, _ = (&HTTPChecker{}).Check(context.Background(), target)
+	if result == nil || result.Status != "down" {
+		t.Errorf("check of a closed socket = %+v, want down", result)
+	}
+}
+
+func TestValidateUnixSocketPath(t *testing.T) {
+	dir := socketDir(t)
+	socket := filepath.Join(dir, "s.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("listen on a unix socket: %v", err)
+	}
+	defer ln.Close()
+	file := filepath.Join(dir, "plain")
+	os.WriteFile(file, nil, 0o600)
+
+	for _, tc := range []struct {
+		path     string
+		ssl      bool
+		warning  string
+		errorMsg string
+	}{
+		{path: ""},
+		{path: socket},
+		{path: "run/app.sock", errorMsg: "absolute path"},
+		{path: socket, ssl: true, errorMsg: "ssl_check"},
+		// The service may start later, so these are only warnings
+		{path: filepath.Join(dir, "missing.sock"), warning: "does not exist yet"},
+		{path: file, warning: "is not a unix socket"},
+	} {
+		warning, err := ValidateUnixSocketPath(tc.path, tc.ssl)
+		if tc.errorMsg != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.errorMsg) {
+				t.Errorf("%q: err = %v, want %q", tc.path, err, tc.errorMsg)
+			}
+			continue
+		}
+		if err != nil || (tc.warning == "") != (warning == "") || !strings.Contains(warning, tc.warning) {
+			t.Errorf("%q: warning %q err %v, want warning %q", tc.path, warning, err, tc.warning)
+		}
+	}
+}
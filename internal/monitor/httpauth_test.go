@@ -0,0 +1,65 @@
+package monitor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateHTTPAuth(t *testing.T) {
+	for _, tc := range []struct {
+		typ, authType, username, password, token string
+		ok                                       bool
+	}{
+		{"tcp", "", "", "", "", true},
+		{"http", AuthTypeNone, "", "", "", true},
+		{"http", AuthTypeBasic, "u", "", "", true},
+		{"https", AuthTypeBearer, "", "", "tok", true},
+		{"http", AuthTypeNone, "u", "", "", false},
+		{"http", AuthTypeBasic, "", "p", "", false},
+		{"http", AuthTypeBasic, "u", "p", "tok", false},
+		{"http", AuthTypeBearer, "", "", "", false},
+		{"http", AuthTypeBearer, "u", "", "tok", false},
+		{"http", "digest", "u", "p", "", false},
+		{"tcp", AuthTypeBasic, "u", "p", "", false},
+	} {
+		if err := ValidateHTTPAuth(tc.typ, tc.authType, tc.username, tc.password, tc.token); (err == nil) != tc.ok {
+			t.Errorf("ValidateHTTPAuth(%s, %s, %q, %q, %q) = %v", tc.typ, tc.authType, tc.username, tc.password, tc.token, err)
+		}
+	}
+}
+
+// The credentials replace an Authorization header of http_headers and are
+// masked in the recorded request
+func TestHTTPCheckAuth(t *testing.T) {
+	var received string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		target MonitorTarget
+		want   string
+	}{
+		{MonitorTarget{AuthType: AuthTypeBasic, AuthUsername: "u", AuthPassword: "p"}, "Basic dTpw"},
+		{MonitorTarget{AuthType: AuthTypeBearer, AuthToken: "tok"}, "Bearer tok"},
+		// Set by hand, still masked
+		{MonitorTarget{AuthType: AuthTypeNone}, "Token manual"},
+	} {
+		target := tc.target
+		target.Name, target.Type, target.Address = "api", "http", srv.URL
+		target.HTTPHeaders = map[string]string{"Authorization": "Token manual", "X-Trace": "1"}
+		result, err := (&HTTPChecker{}).Check(context.Background(), &target)
+		if err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+		if received != tc.want {
+			t.Errorf("%s: server got %q, want %q", target.AuthType, received, tc.want)
+		}
+		if h := result.Request.Headers; h["Authorization"] != redactedValue || h["X-Trace"] != "1" {
+			t.Errorf("%s: recorded headers %v", target.AuthType, h)
+		}
+	}
+}
@@ -9,6 +9,30 @@ import (
 	"go.uber.org/zap"
 )
 
+func init() {
+	fields := append(append([]FieldSpec{}, httpFields...),
+		FieldSpec{Name: "ssl_check", Kind: FieldBoolean, Default: false, Description: "同时检查证书"},
+		FieldSpec{Name: "ssl_warn_days", Kind: FieldInteger, Default: DefaultSSLWarnDays, Min: intBound(1), Description: "到期前多少天告警，需大于 ssl_critical_days"},
+		FieldSpec{Name: "ssl_critical_days", Kind: FieldInteger, Default: DefaultSSLCriticalDays, Min: intBound(1), Description: "到期前多少天标记为严重"},
+		FieldSpec{Name: "ssl_get_chain", Kind: FieldBoolean, Description: "获取证书链"},
+	)
+	results := append(append([]ResultField{}, httpResults...),
+		ResultField{Name: "ssl_issuer", In: "response_headers", Description: "证书签发者，启用 ssl_check 时返回"},
+		ResultField{Name: "ssl_subject", In: "response_headers", Description: "证书主题"},
+		ResultField{Name: "ssl_serial", In: "response_headers", Description: "证书序列号"},
+		ResultField{Name: "ssl_fingerprint", In: "response_headers", Description: "证书 SHA-256 指纹"},
+		ResultField{Name: "days_until_expiry", In: "response_headers", Description: "证书剩余天数"},
+		ResultField{Name: "certificate_chain", In: "data", Description: "证书链详情"},
+	)
+
+	RegisterType(TypeSpec{
+		Type:        "https",
+		DisplayName: "HTTPS",
+		Fields:      fields,
+		Results:     results,
+	}, func() Checker { return &HTTPSChecker{} })
+}
+
 // HTTPSChecker combines HTTP and SSL certificate checking
 type HTTPSChecker struct{}
 
@@ -76,6 +100,12 @@ func (c *HTTPSChecker) Check(ctx context.Context, target *MonitorTarget) (*Check
 		if serial, ok := sslResult.Response.Headers["serial"]; ok {
 			httpResult.Response.Headers["ssl_serial"] = serial
 		}
+		if fingerprint, ok := sslResult.Response.Headers["fingerprint"]; ok {
+			httpResult.Response.Headers["ssl_fingerprint"] = fingerprint
+		}
+		if sslResult.Certificate != nil {
+			httpResult.Certificate = sslResult.Certificate
+		}
 
 		// Add days_until_expiry from certificate chain data
 		if certChain, ok := sslResult.Data["certificate_chain"]; ok {
@@ -90,6 +120,10 @@ func (c *HTTPSChecker) Check(ctx context.Context, target *MonitorTarget) (*Check
 		if sslResult.Status == "down" {
 			httpResult.Status = "down"
 			httpResult.Message = fmt.Sprintf("%s (SSL: %s)", httpResult.Message, sslResult.Message)
+		} else if (sslResult.Status == "warning" || sslResult.Status == "critical") && httpResult.Status == "up" {
+			// Surface certificate expiry even though the HTTP response itself is fine
+			httpResult.Status = sslResult.Status
+			httpResult.Message = fmt.Sprintf("%s | SSL: %s", httpResult.Message, sslResult.Message)
 		} else {
 			httpResult.Message = fmt.Sprintf("%s | SSL: %s", httpResult.Message, sslResult.Message)
 		}
@@ -0,0 +1,164 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxInjectCount caps the number of synthetic results a single injection may produce
+	MaxInjectCount = 100
+	// MaxInjectInterval caps the delay between two synthetic results
+	MaxInjectInterval = 10 * time.Minute
+)
+
+var injectStatuses = map[string]bool{"up": true, "down": true, "degraded": true}
+
+// InjectRequest describes a run of synthetic check results for one target
+type InjectRequest struct {
+	TargetID uint32
+	Status   string
+	Count    int
+	Interval time.Duration
+}
+
+// InjectResults fabricates check results for a target so the status and alert
+// pipeline can be rehearsed without breaking anything. The request is validated
+// synchronously; the results are then saved in the background, Interval apart.
+// Every result is marked Synthetic so it can be filtered and purged later.
+func (s *Service) InjectResults(req InjectRequest) error {
+	if !injectStatuses[req.Status] {
+		return fmt.Errorf("invalid status %q: must be up, down or degraded", req.Status)
+	}
+	if req.Count < 1 || req.Count > MaxInjectCount {
+		return fmt.Errorf("count must be between 1 and %d", MaxInjectCount)
+	}
+	if req.Interval < 0 || req.Interval > MaxInjectInterval {
+		return fmt.Errorf("interval must be between 0 and %s", MaxInjectInterval)
+	}
+
+	target, err := s.GetTarget(req.TargetID)
+	if err != nil {
+		return err
+	}
+
+	logger.Warn("Injecting synthetic check results",
+		zap.Uint32("target_id", target.ID),
+		zap.String("target_name", target.Name),
+		zap.String("status", req.Status),
+		zap.Int("count", req.Count),
+		zap.Duration("interval", req.Interval))
+
+	go s.runInjection(target, req)
+	return nil
+}
+
+func (s *Service) runInjection(target *MonitorTarget, req InjectRequest) {
+	for i := 0; i < req.Count; i++ {
+		if i > 0 && req.Interval > 0 {
+			timer := s.clock.NewTimer(req.Interval)
+			select {
+			case <-s.ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
+		}
+
+		result := &CheckResult{
+			Status:  req.Status,
+			Message: fmt.Sprintf("[synthetic] injected %s result %d/%d", req.Status, i+1, req.Count),
+			Request: RequestDetails{
+				Method: "INJECT",
+				URL:    target.Address,
+			},
+			Synthetic: true,
+		}
+		if req.Status != "up" {
+			result.Error = &ErrorDetails{
+				Type:    "synthetic",
+				Message: "injected by the failure injection endpoint",
+			}
+		}
+
+		s.saveResult(target, result)
+	}
+}
+
+// SyntheticPurgeReport summarises what PurgeSynthetic removed
+type SyntheticPurgeReport struct {
+	HistoryDeleted      int64           `json:"history_deleted"`
+	AlertHistoryDeleted int64           `json:"alert_history_deleted"`
+	ESDeleted           int64           `json:"es_deleted"`
+	Recomputed          []*RepairReport `json:"recomputed"`
+}
+
+// PurgeSynthetic deletes every synthetic result from the history tables and
+// Elasticsearch, then rebuilds the status of the affected targets from the real
+// history that remains. File log entries are only tagged; they are not rewritten.
+func (s *Service) PurgeSynthetic() (*SyntheticPurgeReport, error) {
+	// Synthetic rows still buffered would be inserted after the purge
+	s.flushHistory()
+	db := database.GetDB()
+	report := &SyntheticPurgeReport{Recomputed: []*RepairReport{}}
+
+	var historyTargets, statusTargets []uint32
+	if err := db.Model(&models.MonitorHistory{}).Where("synthetic = ?", true).
+		Distinct().Pluck("target_id", &historyTargets).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&models.MonitorStatus{}).Where("synthetic = ?", true).
+		Pluck("target_id", &statusTargets).Error; err != nil {
+		return nil, err
+	}
+
+	result := db.Where("synthetic = ?", true).Delete(&models.MonitorHistory{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	report.HistoryDeleted = result.RowsAffected
+
+	result = db.Where("synthetic = ?", true).Delete(&models.AlertHistory{})
+	if result.Error != nil {
+		return report, result.Error
+	}
+	report.AlertHistoryDeleted = result.RowsAffected
+
+	seen := make(map[uint32]bool)
+	for _, id := range append(historyTargets, statusTargets...) {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		recomputed, err := s.RecomputeTarget(id)
+		if err != nil {
+			return report, err
+		}
+		if len(recomputed.Actions) > 0 {
+			report.Recomputed = append(report.Recomputed, recomputed)
+		}
+	}
+
+	LogRepairs(report.Recomputed, zap.String("source", "synthetic_purge"))
+
+	deleted, err := s.es.DeleteSyntheticLogs()
+	report.ESDeleted = deleted
+	if err != nil {
+		return report, err
+	}
+
+	logger.Info("Synthetic check results purged",
+		zap.Int64("history_deleted", report.HistoryDeleted),
+		zap.Int64("alert_history_deleted", report.AlertHistoryDeleted),
+		zap.Int64("es_deleted", report.ESDeleted),
+		zap.Int("targets_recomputed", len(report.Recomputed)))
+
+	return report, nil
+}
@@ -0,0 +1,111 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"monitor/internal/clock"
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+// countSynthetic flushes the history buffer and counts synthetic rows
+func countSynthetic(t *testing.T, s *Service) int64 {
+	t.Helper()
+	s.flushHistory()
+	var n int64
+	if err := database.GetDB().Model(&models.MonitorHistory{}).Where("synthetic = ?", true).Count(&n).Error; err != nil {
+		t.Fatalf("count history: %v", err)
+	}
+	return n
+}
+
+func TestInjectResultsAreSpacedByInterval(t *testing.T) {
+	s := newTestService(t)
+	fake := clock.NewFake(epoch)
+	s.SetClock(fake)
+	// Not due for a real check while the fake clock moves
+	target := &MonitorTarget{ID: 1, Name: "db", Type: "tcp", Address: "127.0.0.1", Port: 1, Interval: 3600}
+	if err := s.AddTarget(target); err != nil {
+		t.Fatalf("AddTarget: %v", err)
+	}
+	s.SetSinks(target.ID, Sinks{SinkDBHistory})
+
+	if err := s.InjectResults(InjectRequest{TargetID: 1, Status: "down", Count: 3, Interval: time.Minute}); err != nil {
+		t.Fatalf("InjectResults: %v", err)
+	}
+	for want := int64(1); want <= 3; want++ {
+		waitFor(t, func() bool { return countSynthetic(t, s) == want })
+		if want < 3 {
+			waitFor(t, func() bool { return fake.Waiters() > 0 })
+			fake.Advance(time.Minute)
+		}
+	}
+
+	var status models.MonitorStatus
+	database.GetDB().Where("target_id = ?", 1).First(&status)
+	if status.Status != "down" || !status.Synthetic {
+		t.Errorf("status %s synthetic %v, want a synthetic down", status.Status, status.Synthetic)
+	}
+}
+
+func TestInjectResultsValidation(t *testing.T) {
+	s := newTestService(t)
+	if err := s.AddTarget(&MonitorTarget{ID: 1, Name: "db", Type: "tcp", Address: "127.0.0.1", Port: 1, Interval: 60}); err != nil {
+		t.Fatalf("AddTarget: %v", err)
+	}
+	for name, req := range map[string]InjectRequest{
+		"status":   {TargetID: 1, Status: "broken", Count: 1},
+		"count":    {TargetID: 1, Status: "down", Count: MaxInjectCount + 1},
+		"no count": {TargetID: 1, Status: "down"},
+		"interval": {TargetID: 1, Status: "down", Count: 1, Interval: MaxInjectInterval + time.Second},
+		"target":   {TargetID: 2, Status: "down", Count: 1},
+	} {
+		if err := s.InjectResults(req); err == nil {
+			t.Errorf("%s: invalid injection accepted", name)
+		}
+	}
+}
+
+// Purging removes the synthetic rows and puts the status back to the latest
+// real result
+func TestPurgeSyntheticRestoresRealStatus(t *testing.T) {
+	s := newTestService(t)
+	db := database.GetDB()
+	target := models.MonitorTarget{Name: "db", Type: "tcp", Address: "127.0.0.1", Port: 1, Interval: 60}
+	db.Create(&target)
+	now := time.Now().UTC().Truncate(time.Second)
+	db.Create(&models.MonitorHistory{TargetID: target.ID, Status: "up", CheckedAt: now.Add(-2 * time.Minute)})
+	db.Create(&models.MonitorHistory{TargetID: target.ID, Status: "down", Synthetic: true, CheckedAt: now.Add(-time.Minute)})
+	db.Create(&models.MonitorStatus{TargetID: target.ID, Status: "down", Synthetic: true, CheckedAt: now.Add(-time.Minute)})
+	db.Create(&models.AlertHistory{TargetID: target.ID, Status: "sent", Synthetic: true})
+	db.Create(&models.AlertHistory{TargetID: target.ID, Status: "sent"})
+
+	// A target that only ever had injected results loses its status row
+	only := models.MonitorTarget{Name: "new", Type: "tcp", Address: "127.0.0.1", Port: 2, Interval: 60}
+	db.Create(&only)
+	db.Create(&models.MonitorHistory{TargetID: only.ID, Status: "down", Synthetic: true, CheckedAt: now})
+	db.Create(&models.MonitorStatus{TargetID: only.ID, Status: "down", Synthetic: true, CheckedAt: now})
+
+	report, err := s.PurgeSynthetic()
+	if err != nil {
+		t.Fatalf("PurgeSynthetic: %v", err)
+	}
+	if report.HistoryDeleted != 2 || report.AlertHistoryDeleted != 1 || len(report.Recomputed) != 2 {
+		t.Errorf("report = %+v, want 2 history rows, 1 alert and 2 targets recomputed", report)
+	}
+
+	var status models.MonitorStatus
+	if err := db.Where("target_id = ?", target.ID).First(&status).Error; err != nil || status.Status != "up" || status.Synthetic {
+		t.Errorf("status = %+v (%v), want the real up result", status, err)
+	}
+	var left int64
+	db.Model(&models.MonitorStatus{}).Where("target_id = ?", only.ID).Count(&left)
+	if left != 0 {
+		t.Error("synthetic-only status row kept")
+	}
+	db.Model(&models.AlertHistory{}).Count(&left)
+	if left != 1 {
+		t.Errorf("%d alerts left, want the real one", left)
+	}
+}
@@ -0,0 +1,287 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSON assertion operators
+const (
+	JSONOperatorEq       = "eq"
+	JSONOperatorNe       = "ne"
+	JSONOperatorGt       = "gt"
+	JSONOperatorLt       = "lt"
+	JSONOperatorContains = "contains"
+)
+
+// JSON assertion error types, see checkJSONAssertion
+const (
+	// The body is not JSON, the path is missing, or the value cannot be compared
+	jsonAssertionError = "json_assertion_error"
+	// The value was found but the comparison does not hold
+	jsonAssertionFailed = "json_assertion_failed"
+)
+
+// JSON assertion of http and https targets, declared in httpFields
+var (
+	jsonPathField          = FieldSpec{Name: "json_path", Kind: FieldString, Max: intBound(500), Description: "把响应体解析为 JSON 后取出的字段，点号分隔，数组用下标，如 data.items[0].status"}
+	jsonExpectedValueField = FieldSpec{Name: "json_expected_value", Kind: FieldString, Max: intBound(MaxBodyAssertionLength), Description: "与 json_path 取出的值比较的期望值；省略时只要求字段存在"}
+	jsonOperatorField      = FieldSpec{Name: "json_operator", Kind: FieldString, Default: JSONOperatorEq, Enum: []string{JSONOperatorEq, JSONOperatorNe, JSONOperatorGt, JSONOperatorLt, JSONOperatorContains}, Description: "比较方式：gt/lt 按数字比较，contains 对字符串为包含子串、对数组为包含该元素"}
+)
+
+// JSONPath is a parsed json_path: object keys and array indices from the root
+type JSONPath struct {
+	raw   string
+	steps []jsonPathStep
+}
+
+// jsonPathStep is an object key, or an array index when index is not -1
+type jsonPathStep struct {
+	key   string
+	index int
+}
+
+func (p *JSONPath) String() string {
+	return p.raw
+}
+
+// ParseJSONPath parses a path in dot notation with array indices, such as
+// data.items[0].status or [0].id; a leading "$." is allowed. It returns nil
+// for an empty path. Keys containing dots or brackets cannot be addressed.
+func ParseJSONPath(s string) (*JSONPath, error) {
+	raw := strings.TrimSpace(s)
+	if raw == "" {
+		return nil, nil
+	}
+	rest := strings.TrimPrefix(strings.TrimPrefix(raw, "$"), ".")
+	if rest == "" {
+		return nil, fmt.Errorf("invalid json_path %q: no field", raw)
+	}
+
+	p := &JSONPath{raw: raw}
+	for _, part := range strings.Split(rest, ".") {
+		if part == "" {
+			return nil, fmt.Errorf("invalid json_path %q: empty field name", raw)
+		}
+		key := part
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			key = part[:i]
+		}
+		if strings.ContainsRune(key, ']') {
+			return nil, fmt.Errorf("invalid json_path %q: malformed index in %q", raw, part)
+		}
+		if key != "" {
+			p.steps = append(p.steps, jsonPathStep{key: key, index: -1})
+		}
+		indices := part[len(key):]
+		for indices != "" {
+			end := strings.IndexByte(indices, ']')
+			if indices[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("invalid json_path %q: malformed index in %q", raw, part)
+			}
+			n, err := strconv.Atoi(indices[1:end])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid json_path %q: index %q is not a non-negative integer", raw, indices[1:end])
+			}
+			p.steps = append(p.steps, jsonPathStep{index: n})
+			indices = indices[end+1:]
+		}
+	}
+	return p, nil
+}
+
+// ValidateJSONAssertion checks the JSON assertion settings of a target
+func ValidateJSONAssertion(typ, path, expected, operator string) error {
+	if path == "" {
+		if expected != "" || operator != "" {
+			return fmt.Errorf("json_expected_value and json_operator need a json_path")
+		}
+		return nil
+	}
+	if spec, ok := LookupType(typ); ok {
+		typ = spec.Type
+	}
+	if typ != "http" && typ != "https" {
+		return fmt.Errorf("json_path is only supported for http and https monitors")
+	}
+	if _, err := ParseJSONPath(path); err != nil {
+		return err
+	}
+	if operator == JSONOperatorGt || operator == JSONOperatorLt {
+		if _, err := strconv.ParseFloat(strings.TrimSpace(expected), 64); err != nil {
+			return fmt.Errorf("json_operator %s needs a numeric json_expected_value, got %q", operator, expected)
+		}
+	}
+	return nil
+}
+
+// lookup returns the value at the path, or an error naming the first step
+// that is missing
+func (p *JSONPath) lookup(doc interface{}) (interface{}, error) {
+	v := doc
+	for i, step := range p.steps {
+		if step.index < 0 {
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s is %s, not an object", p.prefix(i), jsonKind(v))
+			}
+			if v, ok = obj[step.key]; !ok {
+				return nil, fmt.Errorf("%s not found", p.prefix(i+1))
+			}
+			continue
+		}
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s is %s, not an array", p.prefix(i), jsonKind(v))
+		}
+		if step.index >= len(arr) {
+			return nil, fmt.Errorf("%s not found: the array has %d elements", p.prefix(i+1), len(arr))
+		}
+		v = arr[step.index]
+	}
+	return v, nil
+}
+
+// prefix renders the first n steps of the path, "$" for none
+func (p *JSONPath) prefix(n int) string {
+	var b strings.Builder
+	for _, step := range p.steps[:n] {
+		if step.index >= 0 {
+			fmt.Fprintf(&b, "[%d]", step.index)
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(step.key)
+	}
+	if b.Len() == 0 {
+		return "$"
+	}
+	return b.String()
+}
+
+func jsonKind(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	case string:
+		return "a string"
+	case json.Number:
+		return "a number"
+	case bool:
+		return "a boolean"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// jsonText renders a value for comparison and messages: strings as they
+// are, everything else as compact JSON
+func jsonText(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
+
+// jsonNumber returns a number, or a string holding one, as float64
+func jsonNumber(v interface{}) (float64, bool) {
+	var s string
+	switch n := v.(type) {
+	case json.Number:
+		s = n.String()
+	case string:
+		s = strings.TrimSpace(n)
+	default:
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
+
+// compareJSONValue reports whether actual op expected holds. eq and ne
+// compare numerically when both sides are numbers, as text otherwise.
+func compareJSONValue(actual interface{}, op, expected string) (bool, error) {
+	switch op {
+	case "", JSONOperatorEq, JSONOperatorNe:
+		equal := jsonText(actual) == expected
+		if a, ok := actual.(json.Number); ok {
+			if e, err := strconv.ParseFloat(strings.TrimSpace(expected), 64); err == nil {
+				f, _ := jsonNumber(a)
+				equal = f == e
+			}
+		}
+		return equal == (op != JSONOperatorNe), nil
+	case JSONOperatorGt, JSONOperatorLt:
+		a, ok := jsonNumber(actual)
+		if !ok {
+			return false, fmt.Errorf("%s needs a number, the value is %s", op, jsonKind(actual))
+		}
+		e, err := strconv.ParseFloat(strings.TrimSpace(expected), 64)
+		if err != nil {
+			return false, fmt.Errorf("%s needs a numeric expected value, got %q", op, expected)
+		}
+		if op == JSONOperatorGt {
+			return a > e, nil
+		}
+		return a < e, nil
+	case JSONOperatorContains:
+		switch a := actual.(type) {
+		case string:
+			return strings.Contains(a, expected), nil
+		case []interface{}:
+			for _, item := range a {
+				if ok, _ := compareJSONValue(item, JSONOperatorEq, expected); ok {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		return false, fmt.Errorf("contains needs a string or an array, the value is %s", jsonKind(actual))
+	}
+	return false, fmt.Errorf("unknown json_operator %q", op)
+}
+
+// checkJSONAssertion parses the body as JSON and checks the value at the
+// target's json_path. It returns the extracted value, and on failure the
+// error type and a message.
+func checkJSONAssertion(target *MonitorTarget, body []byte) (value interface{}, errType, message string) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, jsonAssertionError, fmt.Sprintf("body is not JSON: %v", err)
+	}
+
+	path := target.JSONPath
+	value, err := path.lookup(doc)
+	if err != nil {
+		return nil, jsonAssertionError, fmt.Sprintf("json_path %s: %v", path, err)
+	}
+	if target.JSONOperator == "" && target.JSONExpectedValue == "" {
+		return value, "", ""
+	}
+
+	op := target.JSONOperator
+	if op == "" {
+		op = JSONOperatorEq
+	}
+	ok, err := compareJSONValue(value, op, target.JSONExpectedValue)
+	if err != nil {
+		return value, jsonAssertionError, fmt.Sprintf("json_path %s: %v", path, err)
+	}
+	if !ok {
+		// Quoted like in the body, so "3" and 3 can be told apart
+		shown, _ := json.Marshal(value)
+		return value, jsonAssertionFailed, fmt.Sprintf("json_path %s is %s, expected %s %q", path, shown, op, target.JSONExpectedValue)
+	}
+	return value, "", ""
+}
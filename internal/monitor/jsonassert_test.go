@@ -0,0 +1,151 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	for path, want := range map[string]string{
+		"status":                 "status",
+		"$.data.items[0].status": "data.items[0].status",
+		"[1][2].id":              "[1][2].id",
+		"matrix[0][1]":           "matrix[0][1]",
+	} {
+		p, err := ParseJSONPath(path)
+		if err != nil {
+			t.Errorf("ParseJSONPath(%q): %v", path, err)
+			continue
+		}
+		if got := p.prefix(len(p.steps)); got != want {
+			t.Errorf("ParseJSONPath(%q) = %s, want %s", path, got, want)
+		}
+	}
+	if p, err := ParseJSONPath("  "); p != nil || err != nil {
+		t.Errorf("empty path = %v, %v", p, err)
+	}
+	for _, path := range []string{"$", "$.", "a..b", "a[x]", "a[-1]", "a[0", "a]b", "a[0]b"} {
+		if _, err := ParseJSONPath(path); err == nil {
+			t.Errorf("ParseJSONPath(%q) accepted", path)
+		}
+	}
+}
+
+func TestValidateJSONAssertion(t *testing.T) {
+	for _, tc := range []struct {
+		typ, path, expected, op string
+		ok                      bool
+	}{
+		{"tcp", "", "", "", true},
+		{"http", "status", "ok", "", true},
+		{"https", "count", "3", JSONOperatorGt, true},
+		{"http", "", "ok", "", false},
+		{"tcp", "status", "", "", false},
+		{"http", "a..b", "", "", false},
+		{"http", "count", "many", JSONOperatorLt, false},
+	} {
+		if err := ValidateJSONAssertion(tc.typ, tc.path, tc.expected, tc.op); (err == nil) != tc.ok {
+			t.Errorf("ValidateJSONAssertion(%s, %q, %q, %q) = %v", tc.typ, tc.path, tc.expected, tc.op, err)
+		}
+	}
+}
+
+func TestCompareJSONValue(t *testing.T) {
+	for _, tc := range []struct {
+		actual   string // JSON
+		op       string
+		expected string
+		want     bool
+		err      bool
+	}{
+		{`"ok"`, "", "ok", true, false},
+		{`"ok"`, JSONOperatorNe, "ok", false, false},
+		{`3.0`, JSONOperatorEq, "3", true, false},
+		{`true`, JSONOperatorEq, "true", true, false},
+		{`null`, JSONOperatorEq, "null", true, false},
+		{`{"a":1}`, JSONOperatorEq, `{"a":1}`, true, false},
+		{`10`, JSONOperatorGt, "9.5", true, false},
+		{`"10"`, JSONOperatorLt, "9", false, false},
+		{`"high"`, JSONOperatorGt, "1", false, true},
+		{`"healthy"`, JSONOperatorContains, "health", true, false},
+		{`["a", 2]`, JSONOperatorContains, "2", true, false},
+		{`["a", 2]`, JSONOperatorContains, "b", false, false},
+		{`5`, JSONOperatorContains, "5", false, true},
+		{`5`, "like", "5", false, true},
+	} {
+		dec := json.NewDecoder(strings.NewReader(tc.actual))
+		dec.UseNumber()
+		var actual interface{}
+		if err := dec.Decode(&actual); err != nil {
+			t.Fatal(err)
+		}
+		got, err := compareJSONValue(actual, tc.op, tc.expected)
+		if got != tc.want || (err != nil) != tc.err {
+			t.Errorf("%s %s %q = %v, %v", tc.actual, tc.op, tc.expected, got, err)
+		}
+	}
+}
+
+func TestCheckJSONAssertion(t *testing.T) {
+	body := []byte(`{"data": {"items": [{"status": "ok", "lag": 3}]}}`)
+	for _, tc := range []struct {
+		path, op, expected string
+		errType, message   string
+	}{
+		{"data.items[0].status", "", "", "", ""},
+		{"data.items[0].lag", JSONOperatorLt, "5", "", ""},
+		{"data.items[0].status", "", "ok", "", ""},
+		{"data.items[0].lag", JSONOperatorGt, "5", jsonAssertionFailed, `json_path data.items[0].lag is 3, expected gt "5"`},
+		{"data.items[1].status", "", "", jsonAssertionError, "data.items[1] not found: the array has 1 elements"},
+		{"data.items.status", "", "", jsonAssertionError, "data.items is an array, not an object"},
+		{"data.items[0].status", JSONOperatorGt, "1", jsonAssertionError, "gt needs a number"},
+	} {
+		path, _ := ParseJSONPath(tc.path)
+		target := &MonitorTarget{JSONPath: path, JSONOperator: tc.op, JSONExpectedValue: tc.expected}
+		_, errType, message := checkJSONAssertion(target, body)
+		if errType != tc.errType || !strings.Contains(message, tc.message) {
+			t.Errorf("%s %s %q: %s %q, want %s %q", tc.path, tc.op, tc.expected, errType, message, tc.errType, tc.message)
+		}
+	}
+	path, _ := ParseJSONPath("status")
+	if _, errType, _ := checkJSONAssertion(&MonitorTarget{JSONPath: path}, []byte("<html>")); errType != jsonAssertionError {
+		t.Errorf("HTML body: %s", errType)
+	}
+}
+
+func TestHTTPCheckJSONAssertion(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"replicas": {"ready": 2, "wanted": 3}}`))
+	}))
+	defer srv.Close()
+
+	check := func(path, op, expected string) *CheckResult {
+		t.Helper()
+		p, err := ParseJSONPath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		target := &MonitorTarget{Name: "api", Type: "http", Address: srv.URL, JSONPath: p, JSONOperator: op, JSONExpectedValue: expected}
+		result, err := (&HTTPChecker{}).Check(context.Background(), target)
+		if err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+		return result
+	}
+
+	if r := check("replicas.ready", JSONOperatorGt, "1"); r.Status != "up" || r.Data["json_value"] != json.Number("2") {
+		t.Errorf("holding assertion: %s %q %v", r.Status, r.Message, r.Data)
+	}
+	r := check("replicas.ready", JSONOperatorEq, "3")
+	if r.Status != "down" || r.Error == nil || r.Error.Type != jsonAssertionFailed || r.Data["json_value"] != json.Number("2") {
+		t.Errorf("failed assertion: %s %q %+v %v", r.Status, r.Message, r.Error, r.Data)
+	}
+	r = check("replicas.broken", "", "")
+	if _, ok := r.Data["json_value"]; r.Status != "down" || r.Error == nil || r.Error.Type != jsonAssertionError || ok {
+		t.Errorf("missing field: %s %q %+v %v", r.Status, r.Message, r.Error, r.Data)
+	}
+}
@@ -0,0 +1,76 @@
+package monitor
+
+import "fmt"
+
+// latencyThresholdTypes are the types whose response time can degrade the status
+var latencyThresholdTypes = map[string]bool{"http": true, "https": true, "tcp": true, "dns": true}
+
+// Response time thresholds, declared by the catalog entries of latencyThresholdTypes
+var (
+	degradedThresholdField = FieldSpec{Name: "degraded_threshold_ms", Kind: FieldInteger, Min: intBound(0), Description: "响应时间达到该值（毫秒）时成功的检查记为 degraded，0 为不判断"}
+	downThresholdField     = FieldSpec{Name: "down_threshold_ms", Kind: FieldInteger, Min: intBound(0), Description: "响应时间达到该值（毫秒）时成功的检查记为 down，0 为不判断；需大于 degraded_threshold_ms"}
+
+	responseTimeThresholdResult = ResultField{Name: "response_time_threshold", In: "data", Description: "响应时间超过阈值时的状态和阈值（毫秒）；未超过时不返回"}
+)
+
+// ValidateResponseTimeThresholds checks the response time thresholds of a
+// target: only for latencyThresholdTypes, not negative, the down threshold
+// above the degraded one, and both reachable within the check timeout
+func ValidateResponseTimeThresholds(typ string, degradedMs, downMs, timeoutSeconds int) error {
+	if degradedMs == 0 && downMs == 0 {
+		return nil
+	}
+	if spec, ok := LookupType(typ); ok {
+		typ = spec.Type
+	}
+	if !latencyThresholdTypes[typ] {
+		return fmt.Errorf("degraded_threshold_ms and down_threshold_ms are only supported for http, https, tcp and dns monitors")
+	}
+	if degradedMs < 0 || downMs < 0 {
+		return fmt.Errorf("degraded_threshold_ms and down_threshold_ms must not be negative")
+	}
+	if degradedMs > 0 && downMs > 0 && downMs <= degradedMs {
+		return fmt.Errorf("down_threshold_ms (%d) must be greater than degraded_threshold_ms (%d)", downMs, degradedMs)
+	}
+	timeout := (&MonitorTarget{TimeoutSeconds: timeoutSeconds}).timeout()
+	if int64(degradedMs) >= timeout.Milliseconds() {
+		return fmt.Errorf("degraded_threshold_ms must be less than the check timeout (%s), got %d", timeout, degradedMs)
+	}
+	if int64(downMs) >= timeout.Milliseconds() {
+		return fmt.Errorf("down_threshold_ms must be less than the check timeout (%s), got %d", timeout, downMs)
+	}
+	return nil
+}
+
+// applyResponseTimeThresholds downgrades a successful check that took too
+// long: up or warning becomes degraded at DegradedThresholdMs and down at
+// DownThresholdMs. The status never improves, and the threshold that was
+// crossed is appended to the message.
+func applyResponseTimeThresholds(target *MonitorTarget, result *CheckResult) {
+	if result == nil || (result.Status != "up" && result.Status != "warning") {
+		return
+	}
+	status, threshold := "", 0
+	switch {
+	case target.DownThresholdMs > 0 && result.ResponseTime >= int64(target.DownThresholdMs):
+		status, threshold = "down", target.DownThresholdMs
+	case target.DegradedThresholdMs > 0 && result.ResponseTime >= int64(target.DegradedThresholdMs):
+		status, threshold = "degraded", target.DegradedThresholdMs
+	default:
+		return
+	}
+
+	result.Status = status
+	result.Message = fmt.Sprintf("%s, response time %dms >= %s threshold %dms",
+		result.Message, result.ResponseTime, status, threshold)
+	if result.Data == nil {
+		result.Data = make(map[string]interface{})
+	}
+	result.Data["response_time_threshold"] = map[string]interface{}{
+		"status":       status,
+		"threshold_ms": threshold,
+	}
+	if status == "down" && result.Error == nil {
+		result.Error = &ErrorDetails{Type: "response_time_exceeded", Message: result.Message}
+	}
+}
@@ -0,0 +1,104 @@
+package monitor
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"monitor/internal/models"
+)
+
+func TestValidateResponseTimeThresholds(t *testing.T) {
+	for _, tc := range []struct {
+		typ            string
+		degraded, down int
+		timeoutSeconds int
+		ok             bool
+	}{
+		{"ping", 0, 0, 0, true},
+		{"http", 200, 1000, 0, true},
+		{"dns", 0, 500, 1, true},
+		{"ping", 200, 0, 0, false},
+		{"tcp", -1, 0, 0, false},
+		{"https", 500, 500, 0, false},
+		// Both must be reachable within the timeout
+		{"tcp", 1000, 0, 1, false},
+		{"tcp", 0, 2000, 1, false},
+	} {
+		if err := ValidateResponseTimeThresholds(tc.typ, tc.degraded, tc.down, tc.timeoutSeconds); (err == nil) != tc.ok {
+			t.Errorf("ValidateResponseTimeThresholds(%s, %d, %d, %d) = %v", tc.typ, tc.degraded, tc.down, tc.timeoutSeconds, err)
+		}
+	}
+}
+
+func TestApplyResponseTimeThresholds(t *testing.T) {
+	target := &MonitorTarget{DegradedThresholdMs: 100, DownThresholdMs: 200}
+	for _, tc := range []struct {
+		status       string
+		responseTime int64
+		want         string
+	}{
+		{"up", 99, "up"},
+		{"up", 100, "degraded"},
+		{"warning", 150, "degraded"},
+		{"up", 200, "down"},
+		// A failed check is never changed
+		{"down", 500, "down"},
+		{"critical", 500, "critical"},
+	} {
+		result := &CheckResult{Status: tc.status, Message: "ok", ResponseTime: tc.responseTime}
+		applyResponseTimeThresholds(target, result)
+		if result.Status != tc.want {
+			t.Errorf("%s in %dms = %s, want %s", tc.status, tc.responseTime, result.Status, tc.want)
+		}
+		_, marked := result.Data["response_time_threshold"]
+		if changed := tc.want != tc.status; marked != changed || strings.Contains(result.Message, "threshold") != changed {
+			t.Errorf("%s in %dms: message %q data %v", tc.status, tc.responseTime, result.Message, result.Data)
+		}
+	}
+
+	result := &CheckResult{Status: "up", Message: "ok", ResponseTime: 250}
+	applyResponseTimeThresholds(target, result)
+	if result.Error == nil || result.Error.Type != "response_time_exceeded" || result.Message != "ok, response time 250ms >= down threshold 200ms" {
+		t.Errorf("down result %q %+v", result.Message, result.Error)
+	}
+}
+
+// runCheck applies the thresholds to the result of the checker
+func TestRunCheckAppliesThresholds(t *testing.T) {
+	s := &Service{stuck: newStuckChecks(), stopping: make(chan struct{})}
+	slow := checkerFunc(func(ctx context.Context, target *MonitorTarget) (*CheckResult, error) {
+		return &CheckResult{Status: "up", Message: "ok", ResponseTime: 300}, nil
+	})
+	target := &MonitorTarget{ID: 1, Type: "http", DegradedThresholdMs: 100}
+	result, err := s.runCheck(context.Background(), slow, target, time.Second)
+	if err != nil || result.Status != "degraded" {
+		t.Errorf("result %+v, %v, want degraded", result, err)
+	}
+}
+
+func TestLintDegradedRuleWithoutThreshold(t *testing.T) {
+	lints := func(typ string, degradedMs int) []string {
+		cfg := &LintConfig{
+			Targets:  []models.MonitorTarget{{ID: 1, Name: "site", Type: typ, Address: "example.com", Interval: 60, Enabled: true, DegradedThresholdMs: degradedMs}},
+			Rules:    []models.AlertRule{{ID: 1, TargetID: 1, ChannelID: 1, Enabled: true, ThresholdType: "degraded"}},
+			Channels: []models.AlertChannel{{ID: 1, Name: "ops", Type: "wechat", Enabled: true}},
+		}
+		var names []string
+		for _, f := range Lint(cfg) {
+			names = append(names, f.Lint)
+		}
+		return names
+	}
+	if got := lints("tcp", 0); !slices.Contains(got, "rule_degraded_without_threshold") {
+		t.Errorf("tcp without a threshold: %v", got)
+	}
+	// With a threshold, or for a type without thresholds, nothing to report
+	for _, got := range [][]string{lints("tcp", 500), lints("ping", 0)} {
+		if slices.Contains(got, "rule_degraded_without_threshold") {
+			t.Errorf("unexpected finding: %v", got)
+		}
+	}
+}
@@ -0,0 +1,460 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"monitor/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Lint severities, from worst to best
+const (
+	LintError   = "error"   // the setup cannot work as configured
+	LintWarning = "warning" // it works, though most likely not as intended
+	LintInfo    = "info"    // worth a look
+)
+
+var lintSeverityRank = map[string]int{LintError: 0, LintWarning: 1, LintInfo: 2}
+
+// Entities a lint finding is about
+const (
+	LintEntityTarget  = "target"
+	LintEntityRule    = "alert_rule"
+	LintEntityChannel = "alert_channel"
+)
+
+// minCertificateInterval is the shortest sensible interval of a certificate
+// check: certificates change over days, checking more often only adds handshakes
+const minCertificateInterval = 5 * time.Minute
+
+// LintFinding is one problem found in the configuration
+type LintFinding struct {
+	Lint     string `json:"lint"`
+	Severity string `json:"severity"`
+	Entity   string `json:"entity"`
+	EntityID uint32 `json:"entity_id"`
+	TargetID uint32 `json:"target_id,omitempty"` // target the finding concerns, also for alert rules
+	Message  string `json:"message"`
+	Fix      string `json:"fix"`
+}
+
+// LintConfig is the configuration the lints look at
+type LintConfig struct {
+	Targets  []models.MonitorTarget
+	Rules    []models.AlertRule
+	Channels []models.AlertChannel
+	Statuses []models.MonitorStatus // current status of the targets that have one
+}
+
+// LoadLintConfig loads the configuration of every target, or of the target
+// targetID and its alert rules when it is not 0. Channels are always loaded
+// in full since rules of any target may use them.
+func LoadLintConfig(db *gorm.DB, targetID uint32) (*LintConfig, error) {
+	cfg := &LintConfig{}
+	targets, rules, statuses := db, db, db
+	if targetID != 0 {
+		targets = db.Where("id = ?", targetID)
+		rules = db.Where("target_id = ?", targetID)
+		statuses = db.Where("target_id = ?", targetID)
+	}
+	if err := targets.Order("id").Find(&cfg.Targets).Error; err != nil {
+		return nil, err
+	}
+	if err := rules.Order("id").Find(&cfg.Rules).Error; err != nil {
+		return nil, err
+	}
+	if err := statuses.Find(&cfg.Statuses).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Order("id").Find(&cfg.Channels).Error; err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// lintTarget is a target as the lints see it
+type lintTarget struct {
+	models.MonitorTarget
+	runtime *MonitorTarget        // nil if the target does not parse
+	status  *models.MonitorStatus // nil before the first check
+}
+
+// lintIndex gives the lints access to the other entities
+type lintIndex struct {
+	targets      map[uint32]*lintTarget
+	channels     map[uint32]*models.AlertChannel
+	targetRules  map[uint32]int // enabled rules of each target
+	channelRules map[uint32]int // enabled rules of each channel
+}
+
+// lintRule is one entry of lintRules. Exactly one of target, rule and channel
+// is set; it returns the message and suggested fix, or an empty message when
+// the entity is fine.
+type lintRule struct {
+	name     string
+	severity string
+	target   func(ix *lintIndex, t *lintTarget) (message, fix string)
+	rule     func(ix *lintIndex, r *models.AlertRule) (message, fix string)
+	channel  func(ix *lintIndex, ch *models.AlertChannel) (message, fix string)
+}
+
+// lintRules 新的检查加一个函数、在这里加一行即可
+var lintRules = []lintRule{
+	{name: "ssl_thresholds", severity: LintError, target: lintSSLThresholds},
+	{name: "certificate_interval", severity: LintWarning, target: lintCertificateInterval},
+	{name: "redirect_not_followed", severity: LintWarning, target: lintRedirectNotFollowed},
+	{name: "expected_status_codes", severity: LintWarning, target: lintExpectedStatusCodes},
+	{name: "compare_body_without_body", severity: LintWarning, target: lintCompareBodyWithoutBody},
+	{name: "body_assertion_without_body", severity: LintError, target: lintBodyAssertionWithoutBody},
+	{name: "steps_ignore_request", severity: LintWarning, target: lintStepsIgnoreRequest},
+	{name: "expected_header_slashes", severity: LintWarning, target: lintExpectedHeaderSlashes},
+	{name: "no_alert_rules", severity: LintInfo, target: lintNoAlertRules},
+	{name: "rule_target_missing", severity: LintError, rule: lintRuleTargetMissing},
+	{name: "rule_channel_missing", severity: LintError, rule: lintRuleChannelMissing},
+	{name: "rule_channel_disabled", severity: LintWarning, rule: lintRuleChannelDisabled},
+	{name: "rule_threshold_type", severity: LintError, rule: lintRuleThresholdType},
+	{name: "rule_response_time_over_timeout", severity: LintWarning, rule: lintRuleResponseTimeOverTimeout},
+	{name: "rule_divergence_without_comparison", severity: LintWarning, rule: lintRuleDivergenceWithoutComparison},
+	{name: "rule_content_changed_without_tracking", severity: LintWarning, rule: lintRuleContentChangedWithoutTracking},
+	{name: "rule_jitter_not_ping", severity: LintWarning, rule: lintRuleJitterNotPing},
+	{name: "rule_degraded_without_threshold", severity: LintWarning, rule: lintRuleDegradedWithoutThreshold},
+	{name: "rule_target_disabled", severity: LintInfo, rule: lintRuleTargetDisabled},
+	{name: "channel_degraded", severity: LintWarning, channel: lintChannelDegraded},
+	{name: "channel_unused", severity: LintInfo, channel: lintChannelUnused},
+}
+
+// Lint runs every lint over cfg. Findings come worst first, then by entity.
+func Lint(cfg *LintConfig) []LintFinding {
+	ix := newLintIndex(cfg)
+	findings := []LintFinding{}
+	for _, l := range lintRules {
+		add := func(entity string, id, targetID uint32, message, fix string) {
+			if message == "" {
+				return
+			}
+			findings = append(findings, LintFinding{
+				Lint: l.name, Severity: l.severity, Entity: entity, EntityID: id, TargetID: targetID, Message: message, Fix: fix,
+			})
+		}
+		switch {
+		case l.target != nil:
+			for i := range cfg.Targets {
+				t := ix.targets[cfg.Targets[i].ID]
+				message, fix := l.target(ix, t)
+				add(LintEntityTarget, t.ID, t.ID, message, fix)
+			}
+		case l.rule != nil:
+			for i := range cfg.Rules {
+				r := &cfg.Rules[i]
+				message, fix := l.rule(ix, r)
+				add(LintEntityRule, uint32(r.ID), r.TargetID, message, fix)
+			}
+		case l.channel != nil:
+			for i := range cfg.Channels {
+				ch := &cfg.Channels[i]
+				message, fix := l.channel(ix, ch)
+				add(LintEntityChannel, ch.ID, 0, message, fix)
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Severity != b.Severity {
+			return lintSeverityRank[a.Severity] < lintSeverityRank[b.Severity]
+		}
+		if a.Entity != b.Entity {
+			return a.Entity > b.Entity // target, alert_rule, alert_channel
+		}
+		return a.EntityID < b.EntityID
+	})
+	return findings
+}
+
+// LintTarget returns the findings that concern a target
+func LintTarget(findings []LintFinding, targetID uint32) []LintFinding {
+	result := []LintFinding{}
+	for _, f := range findings {
+		if f.TargetID == targetID {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+func newLintIndex(cfg *LintConfig) *lintIndex {
+	ix := &lintIndex{
+		targets:      make(map[uint32]*lintTarget, len(cfg.Targets)),
+		channels:     make(map[uint32]*models.AlertChannel, len(cfg.Channels)),
+		targetRules:  make(map[uint32]int),
+		channelRules: make(map[uint32]int),
+	}
+	for i := range cfg.Targets {
+		t := &lintTarget{MonitorTarget: cfg.Targets[i]}
+		t.runtime, _ = NewTargetFromModel(t.MonitorTarget)
+		ix.targets[t.ID] = t
+	}
+	for i := range cfg.Statuses {
+		if t, ok := ix.targets[cfg.Statuses[i].TargetID]; ok {
+			t.status = &cfg.Statuses[i]
+		}
+	}
+	for i := range cfg.Channels {
+		ix.channels[cfg.Channels[i].ID] = &cfg.Channels[i]
+	}
+	for _, r := range cfg.Rules {
+		if r.Enabled {
+			ix.targetRules[r.TargetID]++
+			ix.channelRules[uint32(r.ChannelID)]++
+		}
+	}
+	return ix
+}
+
+// checksCertificate reports whether the target checks a certificate's expiry
+func (t *lintTarget) checksCertificate() bool {
+	return t.Type == "ssl" || t.Type == "tls" || (t.Type == "https" && t.SSLCheck)
+}
+
+func lintSSLThresholds(ix *lintIndex, t *lintTarget) (string, string) {
+	if !t.checksCertificate() {
+		return "", ""
+	}
+	if err := ValidateSSLThresholds(SSLThresholds(t.SSLWarnDays, t.SSLCriticalDays)); err != nil {
+		return err.Error() + ", so the certificate goes critical without a warning first",
+			"set ssl_warn_days above ssl_critical_days, for example 30 and 7"
+	}
+	return "", ""
+}
+
+func lintCertificateInterval(ix *lintIndex, t *lintTarget) (string, string) {
+	if t.Type != "ssl" && t.Type != "tls" {
+		return "", ""
+	}
+	if interval := time.Duration(t.Interval) * time.Second; interval < minCertificateInterval {
+		return fmt.Sprintf("the certificate is checked every %s, though it changes over days", interval),
+			"raise the interval to an hour or more"
+	}
+	return "", ""
+}
+
+// lintRedirectNotFollowed looks at the last check: the target answered with a
+// redirect that is neither followed nor expected, so it is down for that alone
+func lintRedirectNotFollowed(ix *lintIndex, t *lintTarget) (string, string) {
+	if (t.Type != "http" && t.Type != "https") || t.FollowRedirects || t.runtime == nil || t.status == nil {
+		return "", ""
+	}
+	var code int
+	if _, err := fmt.Sscanf(t.status.Message, "HTTP %d", &code); err != nil || code < 300 || code > 399 {
+		return "", ""
+	}
+	if determineStatus(code, t.runtime.ExpectedStatusCodes) == "up" {
+		return "", ""
+	}
+	return fmt.Sprintf("the last check got HTTP %d and follow_redirects is off, so the target is down", code),
+		fmt.Sprintf("enable follow_redirects, change the address to where it redirects, or add %d to expected_status_codes", code)
+}
+
+func lintExpectedStatusCodes(ix *lintIndex, t *lintTarget) (string, string) {
+	if strings.TrimSpace(t.ExpectedStatusCodes) == "" || t.runtime == nil || len(t.runtime.ExpectedStatusCodes) > 0 {
+		return "", ""
+	}
+	return fmt.Sprintf("expected_status_codes %q has no valid code, any 2xx is expected instead", t.ExpectedStatusCodes),
+		"list codes or ranges such as 200,204 or 200-299, or clear the field"
+}
+
+// lintCompareBodyWithoutBody flags comparing bodies of HEAD responses, which
+// have none: the two bodies always match
+func lintCompareBodyWithoutBody(ix *lintIndex, t *lintTarget) (string, string) {
+	if !t.CompareBody || !strings.EqualFold(t.HTTPMethod, "HEAD") {
+		return "", ""
+	}
+	return "compare_body is set but HEAD responses have no body, so the bodies always match",
+		"use GET, or turn off compare_body"
+}
+
+// lintBodyAssertionWithoutBody flags body assertions on HEAD requests: the
+// response has no body, so must contain, regex and json_path always fail
+func lintBodyAssertionWithoutBody(ix *lintIndex, t *lintTarget) (string, string) {
+	if (t.BodyMustContain == "" && t.BodyRegex == "" && t.JSONPath == "") || !strings.EqualFold(t.HTTPMethod, "HEAD") || t.Steps != "" {
+		return "", ""
+	}
+	return "body_must_contain, body_regex or json_path is set but HEAD responses have no body, so every check is down",
+		"use GET, or clear the body assertions"
+}
+
+// lintStepsIgnoreRequest flags single-request settings of a transaction:
+// the steps are sent instead, so the body and assertions are never used
+func lintStepsIgnoreRequest(ix *lintIndex, t *lintTarget) (string, string) {
+	if t.Steps == "" {
+		return "", ""
+	}
+	var ignored []string
+	for _, f := range []struct{ name, value string }{
+		{"http_body", t.HTTPBody},
+		{"expected_status_codes", t.ExpectedStatusCodes},
+		{"expected_headers", t.ExpectedHeaders},
+		{"body_must_contain", t.BodyMustContain},
+		{"body_must_not_contain", t.BodyMustNotContain},
+		{"body_regex", t.BodyRegex},
+		{"json_path", t.JSONPath},
+	} {
+		if f.value != "" {
+			ignored = append(ignored, f.name)
+		}
+	}
+	if len(ignored) == 0 {
+		return "", ""
+	}
+	return fmt.Sprintf("%s is set but the steps are sent instead, so it is ignored", strings.Join(ignored, ", ")),
+		"move it to the steps, or clear it"
+}
+
+// lintExpectedHeaderSlashes flags expected_headers values written as /.../:
+// they are matched as text, a regex needs the regex: prefix
+func lintExpectedHeaderSlashes(ix *lintIndex, t *lintTarget) (string, string) {
+	if t.runtime == nil {
+		return "", ""
+	}
+	var names []string
+	for _, a := range t.runtime.ExpectedHeaders {
+		if looksLikeSlashRegex(a) {
+			names = append(names, a.Name)
+		}
+	}
+	if len(names) == 0 {
+		return "", ""
+	}
+	return fmt.Sprintf("expected_headers %s is written as /.../ and is matched as text including the slashes", strings.Join(names, ", ")),
+		fmt.Sprintf("write %s<pattern> for a regex", HeaderRegexPrefix)
+}
+
+func lintNoAlertRules(ix *lintIndex, t *lintTarget) (string, string) {
+	if !t.Enabled || ix.targetRules[t.ID] > 0 {
+		return "", ""
+	}
+	return "no enabled alert rule, nobody is notified when the target goes down",
+		"add an alert rule for the target"
+}
+
+func lintRuleTargetMissing(ix *lintIndex, r *models.AlertRule) (string, string) {
+	if _, ok := ix.targets[r.TargetID]; ok {
+		return "", ""
+	}
+	return fmt.Sprintf("the rule's target %d does not exist", r.TargetID), "delete the rule"
+}
+
+func lintRuleChannelMissing(ix *lintIndex, r *models.AlertRule) (string, string) {
+	if _, ok := ix.channels[uint32(r.ChannelID)]; ok {
+		return "", ""
+	}
+	return fmt.Sprintf("the rule's channel %d does not exist, its alerts go nowhere", r.ChannelID),
+		"point the rule at an existing channel or delete it"
+}
+
+func lintRuleChannelDisabled(ix *lintIndex, r *models.AlertRule) (string, string) {
+	ch, ok := ix.channels[uint32(r.ChannelID)]
+	if !r.Enabled || !ok || ch.Enabled {
+		return "", ""
+	}
+	return fmt.Sprintf("the rule sends to channel %q, which is disabled", ch.Name),
+		"enable the channel or move the rule to another one"
+}
+
+func lintRuleThresholdType(ix *lintIndex, r *models.AlertRule) (string, string) {
+	switch r.ThresholdType {
+	case "", "failure_count", "response_time", "status_change", "degraded", "divergence", "content_changed", "jitter":
+		return "", ""
+	}
+	return fmt.Sprintf("unknown threshold_type %q, the rule never fires", r.ThresholdType),
+		"use failure_count, response_time, status_change, degraded, divergence, content_changed or jitter"
+}
+
+// lintRuleResponseTimeOverTimeout flags thresholds the check times out before reaching
+func lintRuleResponseTimeOverTimeout(ix *lintIndex, r *models.AlertRule) (string, string) {
+	t, ok := ix.targets[r.TargetID]
+	if r.ThresholdType != "response_time" || !ok || t.runtime == nil {
+		return "", ""
+	}
+	timeout := t.runtime.timeout()
+	if time.Duration(r.ThresholdValue)*time.Millisecond < timeout {
+		return "", ""
+	}
+	return fmt.Sprintf("the threshold of %d ms is not below the check timeout of %s, a check times out first", r.ThresholdValue, timeout),
+		"lower threshold_value below the timeout, or alert on failure_count instead"
+}
+
+func lintRuleDivergenceWithoutComparison(ix *lintIndex, r *models.AlertRule) (string, string) {
+	t, ok := ix.targets[r.TargetID]
+	if r.ThresholdType != "divergence" || !ok || t.SecondaryAddress != "" {
+		return "", ""
+	}
+	return "the rule alerts on divergence but the target has no secondary_address, so it never fires",
+		"set secondary_address on the target or change the threshold_type"
+}
+
+func lintRuleContentChangedWithoutTracking(ix *lintIndex, r *models.AlertRule) (string, string) {
+	t, ok := ix.targets[r.TargetID]
+	if r.ThresholdType != "content_changed" || !ok || t.TrackContentHash {
+		return "", ""
+	}
+	return "the rule alerts on content_changed but the target does not set track_content_hash, so it never fires",
+		"set track_content_hash on the target or change the threshold_type"
+}
+
+// lintRuleJitterNotPing flags jitter rules on targets that do not measure it
+func lintRuleJitterNotPing(ix *lintIndex, r *models.AlertRule) (string, string) {
+	t, ok := ix.targets[r.TargetID]
+	if r.ThresholdType != "jitter" || !ok {
+		return "", ""
+	}
+	if spec, known := LookupType(t.Type); known && spec.Type == "ping" {
+		return "", ""
+	}
+	return fmt.Sprintf("the rule alerts on jitter but the target is a %s monitor, only ping measures it, so it never fires", t.Type),
+		"move the rule to a ping monitor or change the threshold_type"
+}
+
+// lintRuleDegradedWithoutThreshold flags degraded rules on types that are
+// only degraded by their response time thresholds
+func lintRuleDegradedWithoutThreshold(ix *lintIndex, r *models.AlertRule) (string, string) {
+	t, ok := ix.targets[r.TargetID]
+	if r.ThresholdType != "degraded" || !ok || t.DegradedThresholdMs > 0 {
+		return "", ""
+	}
+	if spec, known := LookupType(t.Type); !known || !latencyThresholdTypes[spec.Type] {
+		return "", ""
+	}
+	return "the rule alerts on degraded but the target has no degraded_threshold_ms, so it never fires",
+		"set degraded_threshold_ms on the target or change the threshold_type"
+}
+
+func lintRuleTargetDisabled(ix *lintIndex, r *models.AlertRule) (string, string) {
+	t, ok := ix.targets[r.TargetID]
+	if !r.Enabled || !ok || t.Enabled {
+		return "", ""
+	}
+	return fmt.Sprintf("the rule's target %q is disabled", t.Name), "enable the target or disable the rule"
+}
+
+func lintChannelDegraded(ix *lintIndex, ch *models.AlertChannel) (string, string) {
+	if !ch.Enabled || ch.Health != "degraded" || ix.channelRules[ch.ID] == 0 {
+		return "", ""
+	}
+	message := "the channel is degraded, alerts sent to it may not arrive"
+	if ch.HealthReason != "" {
+		message += ": " + ch.HealthReason
+	}
+	return message, "fix the channel configuration and send a test alert"
+}
+
+func lintChannelUnused(ix *lintIndex, ch *models.AlertChannel) (string, string) {
+	if !ch.Enabled || ix.channelRules[ch.ID] > 0 {
+		return "", ""
+	}
+	return "no enabled alert rule sends to the channel", "add a rule that uses it, or disable it"
+}
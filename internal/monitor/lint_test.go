@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"monitor/internal/models"
+)
+
+func lintTestConfig() *LintConfig {
+	return &LintConfig{
+		Targets: []models.MonitorTarget{
+			{ID: 1, Name: "cert", Type: "ssl", Address: "example.com", Port: 443, Interval: 60, Enabled: true, SSLWarnDays: 7, SSLCriticalDays: 30},
+			{ID: 2, Name: "site", Type: "http", Address: "http://example.com", Interval: 60, Enabled: true,
+				HTTPMethod: "HEAD", CompareBody: true, ExpectedStatusCodes: "ok"},
+			{ID: 3, Name: "db", Type: "tcp", Address: "127.0.0.1", Port: 5432, Interval: 60},
+		},
+		Rules: []models.AlertRule{
+			{ID: 1, TargetID: 1, ChannelID: 1, Enabled: true, ThresholdType: "response_time", ThresholdValue: 60000},
+			{ID: 2, TargetID: 3, ChannelID: 2, Enabled: true, ThresholdType: "failure_count", ThresholdValue: 3},
+			{ID: 3, TargetID: 99, ChannelID: 77, Enabled: true, ThresholdType: "loudness"},
+		},
+		Channels: []models.AlertChannel{
+			{ID: 1, Name: "ops", Type: "wechat", Enabled: true, Health: "degraded", HealthReason: "HTTP 500"},
+			{ID: 2, Name: "old", Type: "wechat"},
+			{ID: 3, Name: "spare", Type: "wechat", Enabled: true, Health: "healthy"},
+		},
+		Statuses: []models.MonitorStatus{{TargetID: 2, Status: "down", Message: "HTTP 301 Moved Permanently"}},
+	}
+}
+
+func TestLint(t *testing.T) {
+	var got []string
+	for _, f := range Lint(lintTestConfig()) {
+		if f.Message == "" || f.Fix == "" {
+			t.Errorf("finding %+v without a message or fix", f)
+		}
+		got = append(got, fmt.Sprintf("%s %s %d %s", f.Severity, f.Entity, f.EntityID, f.Lint))
+	}
+	// Worst first, then targets, rules and channels by ID
+	want := []string{
+		"error target 1 ssl_thresholds",
+		"error alert_rule 3 rule_target_missing",
+		"error alert_rule 3 rule_channel_missing",
+		"error alert_rule 3 rule_threshold_type",
+		"warning target 1 certificate_interval",
+		"warning target 2 redirect_not_followed",
+		"warning target 2 expected_status_codes",
+		"warning target 2 compare_body_without_body",
+		"warning alert_rule 1 rule_response_time_over_timeout",
+		"warning alert_rule 2 rule_channel_disabled",
+		"warning alert_channel 1 channel_degraded",
+		"info target 2 no_alert_rules",
+		"info alert_rule 2 rule_target_disabled",
+		"info alert_channel 3 channel_unused",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("findings:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestLintFixedConfig(t *testing.T) {
+	cfg := lintTestConfig()
+	cfg.Targets[0].Interval, cfg.Targets[0].SSLWarnDays, cfg.Targets[0].SSLCriticalDays = 3600, 30, 7
+	cfg.Targets[1].HTTPMethod, cfg.Targets[1].ExpectedStatusCodes, cfg.Targets[1].FollowRedirects = "GET", "200-299", true
+	cfg.Targets[2].Enabled = true
+	cfg.Rules[0].ThresholdValue = 2000
+	cfg.Rules = append(cfg.Rules[:1], models.AlertRule{ID: 4, TargetID: 2, ChannelID: 3, Enabled: true},
+		models.AlertRule{ID: 5, TargetID: 3, ChannelID: 1, Enabled: true})
+	cfg.Channels[0].Health = "healthy"
+	cfg.Channels = []models.AlertChannel{cfg.Channels[0], cfg.Channels[2]}
+
+	if findings := Lint(cfg); len(findings) != 0 {
+		t.Errorf("findings for a clean configuration: %+v", findings)
+	}
+}
+
+func TestLintTarget(t *testing.T) {
+	findings := Lint(lintTestConfig())
+	// The findings of target 3 are those of its rule
+	got := LintTarget(findings, 3)
+	if len(got) != 2 || got[0].Lint != "rule_channel_disabled" || got[1].Lint != "rule_target_disabled" {
+		t.Errorf("findings of target 3: %+v", got)
+	}
+	if got := LintTarget(findings, 42); got == nil || len(got) != 0 {
+		t.Errorf("findings of an unknown target: %#v, want an empty list", got)
+	}
+}
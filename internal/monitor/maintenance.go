@@ -0,0 +1,246 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+	"monitor/internal/schedule"
+
+	"go.uber.org/zap"
+)
+
+// StatusMaintenance is the status of a result checked during a maintenance
+// window. Such results are saved but left out of uptime and not alerted on;
+// the status the check found is kept in Data["checked_status"].
+const StatusMaintenance = "maintenance"
+
+// maintenanceLookback bounds the search for the start of a window still
+// open: a window lasts at most a day, an hour more across a DST change
+const maintenanceLookback = 25 * time.Hour
+
+// MaintenanceWindow is a parsed models.MaintenanceWindow
+type MaintenanceWindow struct {
+	ID       uint32
+	TargetID *uint32 // nil for all targets
+	start    *schedule.Schedule
+	end      *schedule.Schedule // daily at end_time
+	loc      *time.Location
+}
+
+// ParseMaintenanceWindow validates a stored window. The window opens at each
+// occurrence of its schedule, or at start_time on its weekdays, and closes at
+// the next end_time after that: an end_time at or before the start time
+// closes it the next day.
+func ParseMaintenanceWindow(m models.MaintenanceWindow) (*MaintenanceWindow, error) {
+	loc := time.Local
+	if m.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(m.Timezone); err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", m.Timezone)
+		}
+	}
+
+	endOffset, err := schedule.ParseTimeOfDay(m.EndTime)
+	if err != nil {
+		return nil, fmt.Errorf("end_time: %w", err)
+	}
+	end, err := schedule.Parse(dailyAt(endOffset, "*"))
+	if err != nil {
+		return nil, err
+	}
+
+	expr := m.Schedule
+	if expr != "" {
+		if m.Weekdays != "" || m.StartTime != "" {
+			return nil, fmt.Errorf("schedule replaces weekdays and start_time, set either one")
+		}
+	} else {
+		startOffset, err := schedule.ParseTimeOfDay(m.StartTime)
+		if err != nil {
+			return nil, fmt.Errorf("start_time: %w", err)
+		}
+		days := "*"
+		if m.Weekdays != "" {
+			var numbers []string
+			for _, name := range strings.Split(m.Weekdays, ",") {
+				day, err := schedule.ParseWeekday(name)
+				if err != nil {
+					return nil, fmt.Errorf("weekdays: %w", err)
+				}
+				numbers = append(numbers, strconv.Itoa(int(day)))
+			}
+			days = strings.Join(numbers, ",")
+		}
+		expr = dailyAt(startOffset, days)
+	}
+	start, err := schedule.Validate(expr, loc, schedule.MinIntervals["maintenance"])
+	if err != nil {
+		return nil, err
+	}
+
+	return &MaintenanceWindow{ID: m.ID, TargetID: m.TargetID, start: start, end: end, loc: loc}, nil
+}
+
+// dailyAt returns the cron expression firing at offset after midnight on days
+func dailyAt(offset time.Duration, days string) string {
+	return fmt.Sprintf("%d %d * * %s", int(offset.Minutes())%60, int(offset.Hours()), days)
+}
+
+// Active reports whether the window is open at t. Only the last opening
+// before t needs to be looked at: an earlier one closed at an end_time no
+// later than the one after the last opening.
+func (w *MaintenanceWindow) Active(t time.Time) bool {
+	t = t.In(w.loc)
+	var opened time.Time
+	for from := t.Add(-maintenanceLookback); ; {
+		next, ok := w.start.Next(from)
+		if !ok || next.After(t) {
+			break
+		}
+		opened, from = next, next
+	}
+	if opened.IsZero() {
+		return false
+	}
+	closes, ok := w.end.Next(opened)
+	return ok && t.Before(closes)
+}
+
+// NextStart returns the first opening of the window after t
+func (w *MaintenanceWindow) NextStart(t time.Time) (time.Time, bool) {
+	return w.start.Next(t.In(w.loc))
+}
+
+// maintenanceState holds the loaded windows and the targets covered by the
+// open ones, recomputed once a minute since windows open and close on minutes
+type maintenanceState struct {
+	mu      sync.Mutex
+	windows []*MaintenanceWindow
+	minute  time.Time // minute all and targets were computed for, zero to recompute
+	all     bool      // a window for all targets is open
+	targets map[uint32]bool
+}
+
+// refresh recomputes the open windows if now is in another minute than the
+// last computation and reports whether the covered targets changed
+func (m *maintenanceState) refresh(now time.Time) bool {
+	minute := now.Truncate(time.Minute)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if minute.Equal(m.minute) {
+		return false
+	}
+	m.minute = minute
+
+	all := false
+	targets := make(map[uint32]bool)
+	// Overlapping windows simply add up
+	for _, w := range m.windows {
+		if !w.Active(now) {
+			continue
+		}
+		if w.TargetID == nil {
+			all = true
+		} else {
+			targets[*w.TargetID] = true
+		}
+	}
+
+	changed := all != m.all || len(targets) != len(m.targets)
+	for id := range targets {
+		if !m.targets[id] {
+			changed = true
+		}
+	}
+	m.all, m.targets = all, targets
+	return changed
+}
+
+func (m *maintenanceState) covers(targetID uint32) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.all || m.targets[targetID]
+}
+
+// InMaintenance reports whether a maintenance window is open for a target
+func (s *Service) InMaintenance(targetID uint32) bool {
+	if s.maintenance.refresh(s.clock.Now()) {
+		s.InvalidateStatus()
+	}
+	return s.maintenance.covers(targetID)
+}
+
+// SetMaintenanceWindows replaces the maintenance windows
+func (s *Service) SetMaintenanceWindows(windows []*MaintenanceWindow) {
+	s.maintenance.mu.Lock()
+	s.maintenance.windows = windows
+	s.maintenance.minute = time.Time{}
+	s.maintenance.mu.Unlock()
+	if s.maintenance.refresh(s.clock.Now()) {
+		s.InvalidateStatus()
+	}
+}
+
+// LoadMaintenanceWindows loads the enabled windows from the database.
+// A stored window that does not parse is skipped with a warning.
+func (s *Service) LoadMaintenanceWindows() error {
+	var rows []models.MaintenanceWindow
+	if err := database.GetDB().Where("enabled = ?", true).Order("id").Find(&rows).Error; err != nil {
+		return err
+	}
+	windows := make([]*MaintenanceWindow, 0, len(rows))
+	for _, row := range rows {
+		w, err := ParseMaintenanceWindow(row)
+		if err != nil {
+			logger.Warn("Ignoring invalid maintenance window", zap.Uint32("window_id", row.ID), zap.Error(err))
+			continue
+		}
+		windows = append(windows, w)
+	}
+	s.SetMaintenanceWindows(windows)
+	return nil
+}
+
+// StartMaintenance loads the maintenance windows, then recomputes the open
+// ones every minute until ctx is done, so that the dashboard sees a window
+// open or close even while no check runs. The windows can be loaded again
+// with LoadMaintenanceWindows if loading them fails.
+func (s *Service) StartMaintenance(ctx context.Context) error {
+	err := s.LoadMaintenanceWindows()
+	go func() {
+		ticker := s.clock.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.stopping:
+				return
+			case <-ticker.C():
+				if s.maintenance.refresh(s.clock.Now()) {
+					s.InvalidateStatus()
+				}
+			}
+		}
+	}()
+	return err
+}
+
+// applyMaintenance marks a result checked during a maintenance window
+func (s *Service) applyMaintenance(target *MonitorTarget, result *CheckResult) {
+	if !s.InMaintenance(target.ID) {
+		return
+	}
+	if result.Data == nil {
+		result.Data = make(map[string]interface{})
+	}
+	result.Data["checked_status"] = result.Status
+	result.Status = StatusMaintenance
+}
@@ -0,0 +1,200 @@
+package monitor
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"monitor/internal/clock"
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+func mustParseWindow(t *testing.T, m models.MaintenanceWindow) *MaintenanceWindow {
+	t.Helper()
+	w, err := ParseMaintenanceWindow(m)
+	if err != nil {
+		t.Fatalf("ParseMaintenanceWindow: %v", err)
+	}
+	return w
+}
+
+func TestMaintenanceWindowStartAndEnd(t *testing.T) {
+	w := mustParseWindow(t, models.MaintenanceWindow{StartTime: "02:00", EndTime: "04:00", Timezone: "UTC"})
+	at := func(hour, min, sec int) time.Time { return time.Date(2026, 3, 4, hour, min, sec, 0, time.UTC) }
+
+	tests := []struct {
+		t    time.Time
+		want bool
+	}{
+		{at(1, 59, 59), false},
+		{at(2, 0, 0), true}, // the start is inside the window
+		{at(3, 59, 59), true},
+		{at(4, 0, 0), false}, // the end is not
+	}
+	for _, tt := range tests {
+		if got := w.Active(tt.t); got != tt.want {
+			t.Errorf("Active(%s) = %v, want %v", tt.t.Format(time.TimeOnly), got, tt.want)
+		}
+	}
+}
+
+func TestMaintenanceWindowAcrossMidnight(t *testing.T) {
+	// Saturday 23:00 to Sunday 01:00 in Shanghai
+	w := mustParseWindow(t, models.MaintenanceWindow{StartTime: "23:00", EndTime: "01:00", Weekdays: "saturday", Timezone: "Asia/Shanghai"})
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	saturday := time.Date(2026, 3, 7, 0, 0, 0, 0, shanghai)
+
+	tests := []struct {
+		offset time.Duration
+		want   bool
+	}{
+		{23*time.Hour - time.Second, false},
+		{23 * time.Hour, true},
+		{25*time.Hour - time.Second, true},
+		{25 * time.Hour, false},
+		{-time.Hour, false}, // Friday 23:00 is not a window day
+	}
+	for _, tt := range tests {
+		at := saturday.Add(tt.offset)
+		if got := w.Active(at.UTC()); got != tt.want {
+			t.Errorf("Active(%s) = %v, want %v", at.Format(time.DateTime), got, tt.want)
+		}
+	}
+}
+
+func TestInMaintenanceFollowsClock(t *testing.T) {
+	s := newTestService(t)
+	start := time.Date(2026, 3, 4, 2, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start.Add(-time.Minute))
+	s.SetClock(fake)
+
+	targetID := uint32(5)
+	s.SetMaintenanceWindows([]*MaintenanceWindow{
+		mustParseWindow(t, models.MaintenanceWindow{TargetID: &targetID, StartTime: "02:00", EndTime: "02:30", Timezone: "UTC"}),
+	})
+
+	if s.InMaintenance(targetID) {
+		t.Fatal("in maintenance a minute before the window")
+	}
+	fake.Set(start)
+	if !s.InMaintenance(targetID) {
+		t.Fatal("not in maintenance at the start of the window")
+	}
+	if s.InMaintenance(targetID + 1) {
+		t.Error("window for one target covers another")
+	}
+	fake.Set(start.Add(30 * time.Minute))
+	if s.InMaintenance(targetID) {
+		t.Error("still in maintenance at the end of the window")
+	}
+}
+
+// A global window and a window of one target overlap: the target stays in
+// maintenance when its own window ends inside the global one, and the other
+// targets follow the global window only
+func TestMaintenanceGlobalAndTargetWindowsOverlap(t *testing.T) {
+	s := newTestService(t)
+	day := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(day)
+	s.SetClock(fake)
+
+	targetID := uint32(5)
+	s.SetMaintenanceWindows([]*MaintenanceWindow{
+		mustParseWindow(t, models.MaintenanceWindow{StartTime: "02:00", EndTime: "04:00", Timezone: "UTC"}),
+		mustParseWindow(t, models.MaintenanceWindow{TargetID: &targetID, StartTime: "01:00", EndTime: "03:00", Timezone: "UTC"}),
+	})
+
+	tests := []struct {
+		at            time.Duration
+		target, other bool
+	}{
+		{time.Hour - time.Minute, false, false},
+		{time.Hour, true, false},
+		{2 * time.Hour, true, true},
+		{3 * time.Hour, true, true}, // the target's window ended, the global one is still open
+		{4 * time.Hour, false, false},
+	}
+	for _, tt := range tests {
+		fake.Set(day.Add(tt.at))
+		if got := s.InMaintenance(targetID); got != tt.target {
+			t.Errorf("at %s: target in maintenance = %v, want %v", fake.Now().Format(time.TimeOnly), got, tt.target)
+		}
+		if got := s.InMaintenance(targetID + 1); got != tt.other {
+			t.Errorf("at %s: other target in maintenance = %v, want %v", fake.Now().Format(time.TimeOnly), got, tt.other)
+		}
+	}
+}
+
+// Of two overlapping windows of a target, the one ending first does not end
+// the maintenance while the other is still open
+func TestMaintenanceOverlappingWindowsOfOneTarget(t *testing.T) {
+	s := newTestService(t)
+	day := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(day.Add(2 * time.Hour))
+	s.SetClock(fake)
+
+	targetID := uint32(5)
+	s.SetMaintenanceWindows([]*MaintenanceWindow{
+		mustParseWindow(t, models.MaintenanceWindow{TargetID: &targetID, StartTime: "02:00", EndTime: "03:00", Timezone: "UTC"}),
+		mustParseWindow(t, models.MaintenanceWindow{TargetID: &targetID, StartTime: "02:30", EndTime: "04:00", Timezone: "UTC"}),
+	})
+
+	for _, at := range []time.Duration{2 * time.Hour, 3 * time.Hour, 4*time.Hour - time.Minute} {
+		fake.Set(day.Add(at))
+		if !s.InMaintenance(targetID) {
+			t.Errorf("not in maintenance at %s", fake.Now().Format(time.TimeOnly))
+		}
+	}
+	fake.Set(day.Add(4 * time.Hour))
+	if s.InMaintenance(targetID) {
+		t.Error("still in maintenance after both windows ended")
+	}
+}
+
+// A check failing during a window is saved as maintenance and left out of
+// the uptime, which only counts the result before the window
+func TestMaintenanceResultsLeftOutOfUptime(t *testing.T) {
+	s := newTestService(t)
+	day := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(day.Add(time.Hour))
+	s.SetClock(fake)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	target := &MonitorTarget{ID: 5, Name: "db", Type: "tcp", Address: "127.0.0.1", Port: int32(ln.Addr().(*net.TCPAddr).Port), Interval: 86400}
+	if err := s.AddTarget(target); err != nil {
+		t.Fatalf("AddTarget: %v", err)
+	}
+	s.SetSinks(target.ID, Sinks{SinkDBHistory})
+	s.SetMaintenanceWindows([]*MaintenanceWindow{
+		mustParseWindow(t, models.MaintenanceWindow{TargetID: &target.ID, StartTime: "02:00", EndTime: "04:00", Timezone: "UTC"}),
+	})
+
+	if result, err := s.checkTarget(context.Background(), target); err != nil || result.Status != "up" {
+		t.Fatalf("check before the window = %+v, %v, want up", result, err)
+	}
+	// The target goes down during the window; its daily scheduled check is not due yet
+	ln.Close()
+	fake.Set(day.Add(3 * time.Hour))
+	for i := 0; i < 3; i++ {
+		result, err := s.checkTarget(context.Background(), target)
+		if err != nil || result.Status != StatusMaintenance || result.Data["checked_status"] != "down" {
+			t.Fatalf("check in the window = %+v, %v, want maintenance of a down result", result, err)
+		}
+	}
+
+	s.flushHistory()
+	s.updateUptime(target.ID)
+	var statuses []string
+	database.GetDB().Model(&models.MonitorHistory{}).Where("target_id = ?", target.ID).Order("id").Pluck("status", &statuses)
+	if len(statuses) != 4 || statuses[0] != "up" || statuses[3] != StatusMaintenance {
+		t.Fatalf("history = %v, want up then 3 maintenance", statuses)
+	}
+	if status := loadStatus(t, target.ID); status.Uptime24h != 100 || status.Uptime30d != 100 {
+		t.Errorf("uptime 24h %v, 30d %v, want 100 from the result before the window", status.Uptime24h, status.Uptime30d)
+	}
+}
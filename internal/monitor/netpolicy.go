@@ -0,0 +1,147 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// cgnat is the carrier-grade NAT range, not covered by net.IP.IsPrivate
+var cgnat = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// NetworkPolicy decides which addresses on-demand probes may connect to, so
+// that a caller can't use them to reach internal services. Stored monitors
+// are configured by operators and are not subject to it.
+type NetworkPolicy struct {
+	allowPrivate bool
+	allowNets    []*net.IPNet
+}
+
+// PolicyError is returned when a target resolves to a forbidden address, or
+// a connection is made to one; Host is empty for a connection
+type PolicyError struct {
+	Host string
+	IP   net.IP
+}
+
+func (e *PolicyError) Error() string {
+	if e.Host == "" {
+		return fmt.Sprintf("connection to %s is not allowed by the network policy", e.IP)
+	}
+	return fmt.Sprintf("target %s resolves to %s, which is not allowed by the network policy", e.Host, e.IP)
+}
+
+// NewNetworkPolicy creates a policy. Loopback, private, link-local, CGNAT,
+// unspecified and multicast addresses are refused unless allowPrivate is set;
+// allowCIDRs are always allowed. Bare addresses count as single hosts.
+func NewNetworkPolicy(allowPrivate bool, allowCIDRs []string) (*NetworkPolicy, error) {
+	p := &NetworkPolicy{allowPrivate: allowPrivate}
+	for _, cidr := range allowCIDRs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed CIDR %q: %w", cidr, err)
+		}
+		p.allowNets = append(p.allowNets, ipNet)
+	}
+	return p, nil
+}
+
+// CheckHost resolves host and returns a *PolicyError if any of its addresses
+// is refused. The checker resolves the name again when it connects; a DNS
+// server that answers differently the second time is caught by Control.
+func (p *NetworkPolicy) CheckHost(ctx context.Context, host string) error {
+	_, err := p.resolveAllowed(ctx, host)
+	return err
+}
+
+// resolveAllowed resolves host like CheckHost and returns its first address
+func (p *NetworkPolicy) resolveAllowed(ctx context.Context, host string) (net.IP, error) {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if host == "" {
+		return nil, fmt.Errorf("target host is empty")
+	}
+
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("failed to resolve %s: no addresses", host)
+	}
+
+	for _, ip := range ips {
+		if !p.Allowed(ip) {
+			return nil, &PolicyError{Host: host, IP: ip}
+		}
+	}
+	return ips[0], nil
+}
+
+// Control is a net.Dialer Control hook that refuses connections to addresses
+// the policy does not allow. It sees the address actually dialed, after name
+// resolution, so it also catches a name that resolves to another address
+// than it did for CheckHost. Unix sockets are not checked.
+func (p *NetworkPolicy) Control(network, address string, _ syscall.RawConn) error {
+	if strings.HasPrefix(network, "unix") {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	host, _, _ = strings.Cut(host, "%")
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("connection to %s: not an IP address", address)
+	}
+	if !p.Allowed(ip) {
+		return &PolicyError{IP: ip}
+	}
+	return nil
+}
+
+// dialer returns a dialer with timeout that enforces the target's network
+// policy, if it has one
+func (t *MonitorTarget) dialer(timeout time.Duration) *net.Dialer {
+	d := &net.Dialer{Timeout: timeout}
+	if t.NetworkPolicy != nil {
+		d.Control = t.NetworkPolicy.Control
+	}
+	return d
+}
+
+// Allowed reports whether the policy allows connecting to ip
+func (p *NetworkPolicy) Allowed(ip net.IP) bool {
+	for _, ipNet := range p.allowNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	if p.allowPrivate {
+		return true
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || cgnat.Contains(ip))
+}
@@ -0,0 +1,182 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestNetworkPolicy(t *testing.T) {
+	p, err := NewNetworkPolicy(false, []string{"10.1.0.0/16", "192.168.1.5", "fd00::1", " "})
+	if err != nil {
+		t.Fatalf("NewNetworkPolicy: %v", err)
+	}
+	for ip, want := range map[string]bool{
+		"1.1.1.1":     true,
+		"2606:4700::": true,
+		"127.0.0.1":   false,
+		"::1":         false,
+		"10.0.0.1":    false,
+		"10.1.2.3":    true, // allowed network
+		"192.168.1.5": true, // bare address is a single host
+		"192.168.1.6": false,
+		"169.254.1.1": false,
+		"100.64.0.1":  false, // CGNAT
+		"100.128.0.1": true,
+		"0.0.0.0":     false,
+		"224.0.0.1":   false,
+		"fd00::1":     true,
+		"fd00::2":     false,
+	} {
+		if got := p.Allowed(net.ParseIP(ip)); got != want {
+			t.Errorf("Allowed(%s) = %v, want %v", ip, got, want)
+		}
+	}
+
+	open, _ := NewNetworkPolicy(true, nil)
+	if !open.Allowed(net.ParseIP("127.0.0.1")) {
+		t.Error("allow_private does not allow loopback")
+	}
+	if _, err := NewNetworkPolicy(false, []string{"10.0.0.0/40"}); err == nil {
+		t.Error("invalid CIDR accepted")
+	}
+}
+
+func TestNetworkPolicyCheckHost(t *testing.T) {
+	p, _ := NewNetworkPolicy(false, nil)
+	ctx := context.Background()
+
+	var policyErr *PolicyError
+	if err := p.CheckHost(ctx, "[::1]"); !errors.As(err, &policyErr) || !policyErr.IP.Equal(net.IPv6loopback) {
+		t.Errorf("CheckHost([::1]) = %v, want a policy error", err)
+	}
+	// localhost resolves to loopback without a DNS server
+	if err := p.CheckHost(ctx, "localhost"); !errors.As(err, &policyErr) || policyErr.Host != "localhost" {
+		t.Errorf("CheckHost(localhost) = %v, want a policy error", err)
+	}
+	if err := p.CheckHost(ctx, "8.8.8.8"); err != nil {
+		t.Errorf("CheckHost(8.8.8.8) = %v", err)
+	}
+	if err := p.CheckHost(ctx, ""); err == nil || errors.As(err, &policyErr) {
+		t.Errorf("CheckHost(\"\") = %v, want a plain error", err)
+	}
+}
+
+func TestNetworkPolicyControl(t *testing.T) {
+	p, _ := NewNetworkPolicy(false, []string{"10.1.0.0/16"})
+
+	var policyErr *PolicyError
+	for network, address := range map[string]string{
+		"tcp":  "127.0.0.1:80",
+		"tcp6": "[::1]:443",
+		"udp":  "[fe80::1%eth0]:53",
+	} {
+		if err := p.Control(network, address, nil); !errors.As(err, &policyErr) || policyErr.Host != "" {
+			t.Errorf("Control(%s, %s) = %v, want a policy error", network, address, err)
+		}
+	}
+	for network, address := range map[string]string{
+		"tcp4": "1.1.1.1:443",
+		"udp":  "10.1.2.3:161",
+		"unix": "/run/app.sock",
+	} {
+		if err := p.Control(network, address, nil); err != nil {
+			t.Errorf("Control(%s, %s) = %v", network, address, err)
+		}
+	}
+}
+
+// startRebindingDNSServer answers the first A query with a public address and
+// every later one with loopback, like a DNS rebinding attack
+func startRebindingDNSServer(t *testing.T) (addr string, resolver *net.Resolver) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		answered := false
+		buf := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) == 0 {
+				continue
+			}
+			var answer fakeDNSAnswer
+			if query.Questions[0].Type == dnsmessage.TypeA {
+				answer.A = []string{"127.0.0.1"}
+				if !answered {
+					answer.A = []string{"203.0.113.10"}
+					answered = true
+				}
+			}
+			resp := fakeDNSResponse(query, answer)
+			packed, err := resp.Pack()
+			if err != nil {
+				t.Errorf("pack response: %v", err)
+				return
+			}
+			conn.WriteTo(packed, from)
+		}
+	}()
+
+	addr = conn.LocalAddr().String()
+	resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "udp", addr)
+		},
+	}
+	return addr, resolver
+}
+
+// A name that passes the policy when it is checked but resolves to loopback
+// when the checker connects is refused at connect time
+func TestNetworkPolicyRebinding(t *testing.T) {
+	var requests atomic.Int32
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer web.Close()
+	_, port, _ := net.SplitHostPort(web.Listener.Addr().String())
+	dnsServer, resolver := startRebindingDNSServer(t)
+	p, _ := NewNetworkPolicy(false, nil)
+
+	// The check before the probe sees the public address
+	ips, err := resolver.LookupIP(context.Background(), "ip4", "rebind.example")
+	if err != nil || len(ips) != 1 || !p.Allowed(ips[0]) {
+		t.Fatalf("first resolution = %v, %v, want an allowed address", ips, err)
+	}
+
+	target := &MonitorTarget{Name: "probe", Type: "http", Address: "http://rebind.example:" + port + "/", DNSServer: dnsServer, NetworkPolicy: p}
+	result, err := (&HTTPChecker{}).Check(context.Background(), target)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if result.Status != "down" || !strings.Contains(result.Message, "not allowed by the network policy") {
+		t.Errorf("result %s: %s, want down by the network policy", result.Status, result.Message)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("%d requests reached the loopback server", n)
+	}
+
+	// Without the policy the second resolution does lead to loopback
+	target.NetworkPolicy = nil
+	if result, err := (&HTTPChecker{}).Check(context.Background(), target); err != nil || result.Status != "up" || requests.Load() != 1 {
+		t.Errorf("check without policy = %+v, %v, want up through loopback", result, err)
+	}
+}
@@ -0,0 +1,82 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/database/dialect"
+	"monitor/internal/models"
+)
+
+// DefaultPercentileWindow is the window of the response time percentiles of a status
+const DefaultPercentileWindow = 24 * time.Hour
+
+// ResponsePercentiles summarizes the response times of the up, warning and
+// degraded results of a target in a window. The percentiles are nil without
+// samples; with few samples p99 is just one of the slowest results.
+type ResponsePercentiles struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Samples int64     `json:"samples"`
+	P50     *int64    `json:"p50"`
+	P95     *int64    `json:"p95"`
+	P99     *int64    `json:"p99"`
+}
+
+// nearestRank returns the p-th percentile of sorted, which must not be empty
+func nearestRank(sorted []int64, p int) int64 {
+	return sorted[(len(sorted)*p+99)/100-1]
+}
+
+// ResponsePercentiles computes the response time percentiles of a target from
+// its history between from and to. PostgreSQL computes them with
+// percentile_disc, which is the nearest rank as well; on MySQL and SQLite the
+// response times are sorted in Go. Rows still waiting in the history buffer
+// are not counted yet.
+func (s *Service) ResponsePercentiles(ctx context.Context, targetID uint32, from, to time.Time) (*ResponsePercentiles, error) {
+	db := database.GetDB().WithContext(ctx)
+	query := db.Model(&models.MonitorHistory{}).
+		Where("target_id = ? AND checked_at >= ? AND checked_at < ?", targetID, from, to).
+		Where("status IN ?", []string{"up", "warning", "degraded"})
+	if !s.includeSyntheticUptime {
+		query = query.Where("synthetic = ?", false)
+	}
+
+	p := &ResponsePercentiles{From: from, To: to}
+	if dialect.Of(db) == dialect.Postgres {
+		var row struct {
+			Samples int64
+			P50     sql.NullInt64
+			P95     sql.NullInt64
+			P99     sql.NullInt64
+		}
+		err := query.Select(`COUNT(*) AS samples,
+			percentile_disc(0.5) WITHIN GROUP (ORDER BY response_time) AS p50,
+			percentile_disc(0.95) WITHIN GROUP (ORDER BY response_time) AS p95,
+			percentile_disc(0.99) WITHIN GROUP (ORDER BY response_time) AS p99`).Scan(&row).Error
+		if err != nil {
+			return nil, err
+		}
+		p.Samples = row.Samples
+		if p.Samples > 0 {
+			p.P50, p.P95, p.P99 = &row.P50.Int64, &row.P95.Int64, &row.P99.Int64
+		}
+		return p, nil
+	}
+
+	var times []int64
+	if err := query.Pluck("response_time", &times).Error; err != nil {
+		return nil, err
+	}
+	p.Samples = int64(len(times))
+	if len(times) == 0 {
+		return p, nil
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	p50, p95, p99 := nearestRank(times, 50), nearestRank(times, 95), nearestRank(times, 99)
+	p.P50, p.P95, p.P99 = &p50, &p95, &p99
+	return p, nil
+}
@@ -0,0 +1,48 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+func TestNearestRank(t *testing.T) {
+	times := []int64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+	for p, want := range map[int]int64{1: 10, 50: 50, 51: 60, 95: 100, 99: 100, 100: 100} {
+		if got := nearestRank(times, p); got != want {
+			t.Errorf("p%d = %d, want %d", p, got, want)
+		}
+	}
+	if got := nearestRank([]int64{7}, 99); got != 7 {
+		t.Errorf("p99 of one sample = %d", got)
+	}
+}
+
+func TestResponsePercentiles(t *testing.T) {
+	s := newTestService(t)
+	for i := int64(1); i <= 20; i++ {
+		addHistory(t, 1, "up", i*10, epoch.Add(-time.Duration(i)*time.Minute))
+	}
+	addHistory(t, 1, "degraded", 1000, epoch.Add(-time.Minute))
+	// Not counted: failures, synthetic results, other targets and results outside the window
+	addHistory(t, 1, "down", 9000, epoch.Add(-time.Minute))
+	database.GetDB().Create(&models.MonitorHistory{TargetID: 1, Status: "up", ResponseTime: 9000, CheckedAt: epoch.Add(-time.Minute), Synthetic: true})
+	addHistory(t, 2, "up", 9000, epoch.Add(-time.Minute))
+	addHistory(t, 1, "up", 9000, epoch.Add(-2*time.Hour))
+
+	p, err := s.ResponsePercentiles(context.Background(), 1, epoch.Add(-time.Hour), epoch)
+	if err != nil {
+		t.Fatalf("ResponsePercentiles: %v", err)
+	}
+	if p.Samples != 21 || *p.P50 != 110 || *p.P95 != 200 || *p.P99 != 1000 {
+		t.Errorf("percentiles samples %d p50 %d p95 %d p99 %d", p.Samples, *p.P50, *p.P95, *p.P99)
+	}
+
+	p, err = s.ResponsePercentiles(context.Background(), 3, epoch.Add(-time.Hour), epoch)
+	if err != nil || p.Samples != 0 || p.P50 != nil || p.P99 != nil {
+		t.Errorf("no samples: %+v, %v", p, err)
+	}
+}
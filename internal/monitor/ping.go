@@ -2,63 +2,121 @@ package monitor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"net"
+	"os"
 	"os/exec"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
-	"monitor/internal/models"
+	"monitor/internal/logger"
+
+	"go.uber.org/zap"
 )
 
 // PingChecker implements ICMP ping monitoring
-type PingChecker struct {
-	target *models.MonitorTarget
+func init() {
+	RegisterType(TypeSpec{
+		Type:        "ping",
+		Aliases:     []string{"icmp"},
+		DisplayName: "Ping (ICMP)",
+		Fields: []FieldSpec{
+			{Name: "ping_count", Kind: FieldInteger, Default: 4, Min: intBound(1), Max: intBound(100), Description: "发送的包数"},
+			{Name: "ping_size", Kind: FieldInteger, Default: 32, Min: intBound(1), Max: intBound(65500), Description: "包大小（字节）"},
+			{Name: "ping_timeout", Kind: FieldInteger, Default: 5000, Min: intBound(1), Description: "超时（毫秒）"},
+		},
+		Results: []ResultField{
+			{Name: "packet_loss", In: "data", Description: "丢包率（百分比）"},
+			{Name: "avg_time", In: "data", Description: "平均往返时间（毫秒）"},
+			{Name: "packets_sent", In: "data", Description: "发送的包数"},
+			{Name: "packets_received", In: "data", Description: "收到的回复数"},
+			{Name: "rtts", In: "data", Description: "每个包的往返时间（毫秒），按发送顺序，丢失的包为 null；执行 ping 命令时只列出收到的回复"},
+			{Name: "min_time", In: "data", Description: "最小往返时间（毫秒），没有回复时不返回"},
+			{Name: "max_time", In: "data", Description: "最大往返时间（毫秒），没有回复时不返回"},
+			{Name: "jitter", In: "data", Description: "抖动：相邻回复往返时间之差的平均值（毫秒），记入检查历史，没有回复时不返回"},
+			{Name: "stddev", In: "data", Description: "往返时间的标准差（毫秒），记入检查历史，没有回复时不返回"},
+			{Name: "method", In: "data", Description: "检查方式：raw（原始 ICMP socket）、datagram（非特权 ICMP socket）或 exec（ping 命令）"},
+		},
+	}, func() Checker { return &PingChecker{} })
 }
 
-// NewPingChecker creates a new ping checker
-func NewPingChecker(target *models.MonitorTarget) *PingChecker {
-	return &PingChecker{target: target}
-}
+type PingChecker struct{}
 
 // Check performs a ping check
-func (p *PingChecker) Check() (*CheckResult, error) {
+func (p *PingChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckResult, error) {
 	// Get ping parameters
-	count := p.target.PingCount
+	count := target.PingCount
 	if count <= 0 {
 		count = 4
 	}
 
-	size := p.target.PingSize
+	size := target.PingSize
 	if size <= 0 {
 		size = 32
 	}
 
-	timeout := time.Duration(p.target.PingTimeout) * time.Millisecond
+	timeout := time.Duration(target.PingTimeout) * time.Millisecond
 	if timeout <= 0 {
 		timeout = 5 * time.Second
 	}
 
-	// Perform ping based on OS
-	var packetLoss int
-	var avgTime time.Duration
-	var err error
+	// ICMP has no dialer to check; with a network policy the address is
+	// resolved and checked once and the resulting IP is pinged
+	address := target.Address
+	var (
+		stats  pingStats
+		method string
+		err    error
+	)
+	if target.NetworkPolicy != nil {
+		var ip net.IP
+		if ip, err = target.NetworkPolicy.resolveAllowed(ctx, target.Address); err == nil {
+			address = ip.String()
+		}
+	}
+	if err == nil {
+		stats, method, err = p.ping(ctx, address, count, size, timeout)
+	}
 
-	if runtime.GOOS == "windows" {
-		packetLoss, avgTime, err = p.pingWindows(count, size, timeout)
-	} else {
-		packetLoss, avgTime, err = p.pingUnix(count, size, timeout)
+	request := RequestDetails{
+		Method: "PING",
+		URL:    target.Address,
+		Headers: detailHeaders(map[string]interface{}{
+			"count":      count,
+			"size":       size,
+			"timeout_ms": timeout.Milliseconds(),
+			"method":     method,
+		}),
 	}
 
 	if err != nil {
+		logger.Warn("Ping failed",
+			zap.Uint32("target_id", target.ID),
+			zap.String("target", target.Name),
+			zap.String("address", target.Address),
+			zap.Error(err),
+		)
+
 		return &CheckResult{
 			Status: "down",
 			Message: fmt.Sprintf("Ping failed: %v", err),
+			Request: request,
+			Error: &ErrorDetails{
+				Type:    "ping_error",
+				Message: err.Error(),
+			},
 		}, err
 	}
 
+	packetLoss := int(math.Ceil(float64(stats.Sent-stats.Received) * 100 / float64(stats.Sent)))
+	avgTime := stats.Avg
+	packetsReceived := stats.Received
+
 	status := "up"
 	message := fmt.Sprintf("Ping successful - Packet loss: %d%%, Avg time: %dms", packetLoss, avgTime.Milliseconds())
 
@@ -71,213 +129,232 @@ func (p *PingChecker) Check() (*CheckResult, error) {
 		message = fmt.Sprintf("Ping degraded - Packet loss: %d%%, Avg time: %dms", packetLoss, avgTime.Milliseconds())
 	}
 
+	logger.Debug("Ping check completed",
+		zap.Uint32("target_id", target.ID),
+		zap.String("target", target.Name),
+		zap.String("address", target.Address),
+		zap.Int("packet_loss", packetLoss),
+		zap.Int64("avg_time_ms", avgTime.Milliseconds()),
+		zap.String("status", status),
+	)
+
 	return &CheckResult{
 		Status:      status,
 		ResponseTime: int64(avgTime.Milliseconds()),
 		Message:     message,
-		Data: map[string]interface{}{
-			"packet_loss": packetLoss,
-			"avg_time":    avgTime.Milliseconds(),
-			"packets_sent": count,
-			"packets_received": count - (count * packetLoss / 100),
+		Data:         stats.data(packetLoss, method),
+		Request: request,
+		Response: ResponseDetails{
+			Headers: detailHeaders(map[string]interface{}{
+				"packet_loss":      packetLoss,
+				"avg_time_ms":      avgTime.Milliseconds(),
+				"packets_sent":     stats.Sent,
+				"packets_received": packetsReceived,
+			}),
 		},
 	}, nil
 }
 
+// pingStats is what we take from the echo replies, or from the ping output:
+// only the reply lines are read, the summary lines are worded differently in
+// every locale
+type pingStats struct {
+	Sent     int
+	Received int
+	Avg      time.Duration
+
+	// Set by summarize
+	Min    time.Duration
+	Max    time.Duration
+	Jitter time.Duration
+	StdDev time.Duration
+	// pingNative: per packet in sending order, -1 when lost. The ping
+	// command only lists the replies, so the lost packets are missing.
+	RTTs []time.Duration
+}
+
+// ping checks address as selected by SetPingMode and returns the method used:
+// "raw" or "datagram" for the ICMP socket of pingNative, "exec" for the ping command
+func (p *PingChecker) ping(ctx context.Context, address string, count, size int, timeout time.Duration) (pingStats, string, error) {
+	if pingMode != PingModeExec {
+		stats, method, err := pingNative(ctx, address, count, size, timeout)
+		if !errors.Is(err, errNoICMPSocket) || pingMode == PingModeNative {
+			return stats, method, err
+		}
+		logPingExecFallback(err)
+	}
+
+	var stats pingStats
+	var err error
+	if runtime.GOOS == "windows" {
+		stats, err = p.pingWindows(ctx, address, count, size, timeout)
+	} else {
+		stats, err = p.pingUnix(ctx, address, count, size, timeout)
+	}
+	return stats, PingModeExec, err
+}
+
+// data is the CheckResult.Data of a ping check
+func (s pingStats) data(packetLoss int, method string) map[string]interface{} {
+	data := map[string]interface{}{
+		"method":           method,
+		"packet_loss":      packetLoss,
+		"avg_time":         s.Avg.Milliseconds(),
+		"packets_sent":     s.Sent,
+		"packets_received": s.Received,
+	}
+	if s.RTTs == nil {
+		return data
+	}
+	rtts := make([]interface{}, len(s.RTTs))
+	for i, rtt := range s.RTTs {
+		if rtt >= 0 {
+			rtts[i] = pingMillis(rtt)
+		}
+	}
+	data["rtts"] = rtts
+	if s.Received > 0 {
+		data["min_time"] = pingMillis(s.Min)
+		data["max_time"] = pingMillis(s.Max)
+		data["jitter"] = pingMillis(s.Jitter)
+		data["stddev"] = pingMillis(s.StdDev)
+	}
+	return data
+}
+
+// pingMillis converts a round trip time to milliseconds with microsecond precision
+func pingMillis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// pingWaitDelay bounds how long CombinedOutput waits for the output pipe after
+// the context kills ping; a child that inherited the pipe would otherwise keep
+// the check running past its timeout
+const pingWaitDelay = time.Second
+
 // pingWindows performs ping on Windows
-func (p *PingChecker) pingWindows(count, size int, timeout time.Duration) (int, time.Duration, error) {
+func (p *PingChecker) pingWindows(ctx context.Context, address string, count, size int, timeout time.Duration) (pingStats, error) {
+	host, v6 := pingHost(address)
 	args := []string{
 		"-n", fmt.Sprintf("%d", count),
 		"-l", fmt.Sprintf("%d", size),
 		"-w", fmt.Sprintf("%d", timeout.Milliseconds()),
-		p.target.Address,
 	}
+	if v6 {
+		args = append(args, "-6")
+	}
+	args = append(args, host)
 
-	cmd := exec.Command("ping", args...)
+	cmd := exec.CommandContext(ctx, "ping", args...)
+	cmd.WaitDelay = pingWaitDelay
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return 100, 0, fmt.Errorf("ping command failed: %w", err)
+		return pingStats{Sent: count}, fmt.Errorf("ping command failed: %w", err)
 	}
 
-	// Parse output
-	return p.parsePingOutput(string(output))
+	// Windows 目标不可达时也返回 0，没有回复行即视为全部丢包
+	return parsePingOutput(string(output), count, true, host, v6)
 }
 
 // pingUnix performs ping on Unix-like systems (Linux, macOS)
-func (p *PingChecker) pingUnix(count, size int, timeout time.Duration) (int, time.Duration, error) {
+func (p *PingChecker) pingUnix(ctx context.Context, address string, count, size int, timeout time.Duration) (pingStats, error) {
+	host, v6 := pingHost(address)
+	name := "ping"
 	args := []string{
 		"-c", fmt.Sprintf("%d", count),
 		"-s", fmt.Sprintf("%d", size),
-		"-W", fmt.Sprintf("%d", int(timeout.Seconds())),
-		p.target.Address,
 	}
+	switch {
+	case v6 && runtime.GOOS == "darwin":
+		// macOS 的 ping 只支持 IPv4，ping6 没有 -W，等待时间由检查超时限制
+		name = "ping6"
+	case v6:
+		args = append(args, "-6", "-W", fmt.Sprintf("%d", int(timeout.Seconds())))
+	default:
+		args = append(args, "-W", fmt.Sprintf("%d", int(timeout.Seconds())))
+	}
+	args = append(args, host)
 
-	cmd := exec.Command("ping", args...)
+	cmd := exec.CommandContext(ctx, name, args...)
+	// 固定为 C locale，避免德语、法语等环境下的逗号小数和翻译后的输出
+	cmd.Env = append(os.Environ(), "LC_ALL=C", "LANG=C")
+	cmd.WaitDelay = pingWaitDelay
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return 100, 0, fmt.Errorf("ping command failed: %w", err)
+		return pingStats{Sent: count}, fmt.Errorf("ping command failed: %w", err)
 	}
 
-	// Parse output
-	return p.parsePingOutput(string(output))
+	// 退出码为 0 说明至少收到一个回复，解析不到回复行就是输出格式不认识
+	return parsePingOutput(string(output), count, false, host, v6)
 }
 
-// parsePingOutput parses ping command output
-func (p *PingChecker) parsePingOutput(output string) (int, time.Duration, error) {
-	lines := strings.Split(output, "\n")
+// pingReplyTime matches the round trip time of a reply line in any locale:
+// "time=12.3 ms", "time<1ms", "时间=14ms", "Zeit=12,3 ms", "temps=12,3 ms"
+var pingReplyTime = regexp.MustCompile(`[=<]\s*(\d+(?:[.,]\d+)?)\s*ms\b`)
+
+// pingHost is the address given to the ping command, without the brackets
+// of an IPv6 literal, and whether it is an IPv6 literal. Host names are left
+// to ping, which resolves them to IPv4 unless told otherwise.
+func pingHost(address string) (string, bool) {
+	host := strings.TrimSuffix(strings.TrimPrefix(address, "["), "]")
+	ip, _, _ := strings.Cut(host, "%")
+	return host, net.ParseIP(ip) != nil && strings.Contains(ip, ":")
+}
 
-	var packetsSent, packetsReceived int
-	var total time.Duration
+// parsePingOutput computes loss and latency statistics from the reply lines
+// (the ones carrying a TTL or hop limit) and the number of packets we asked
+// ping to send. A reply whose time cannot be read is an error rather than a
+// silent zero. When allowNoReplies is false, output without any reply line
+// is an error too. Windows prints IPv6 replies without a TTL, so for v6 a
+// line naming host with a round trip time is a reply as well.
+func parsePingOutput(output string, sent int, allowNoReplies bool, host string, v6 bool) (pingStats, error) {
+	stats := pingStats{Sent: sent}
 
-	for _, line := range lines {
+	for _, line := range strings.Split(output, "\n") {
 		line = strings.TrimSpace(line)
-
-		// Parse packet statistics
-		// Windows: "Packets: Sent = 4, Received = 4, Lost = 0 (0% loss)"
-		// Linux/Unix: "4 packets transmitted, 4 received, 0% packet loss"
-		if strings.Contains(line, "packets") || strings.Contains(line, "Packets") {
-			fields := strings.Fields(line)
-			for i, field := range fields {
-				// Extract sent/received
-				if val, err := extractNumber(field); err == nil {
-					if strings.Contains(strings.ToLower(line), "sent") || i == 0 {
-						packetsSent = int(val)
-					}
-					if strings.Contains(strings.ToLower(line), "received") || i == 1 {
-						packetsReceived = int(val)
-					}
-				}
-			}
+		lower := strings.ToLower(line)
+		// Linux: "64 bytes from 1.1.1.1: icmp_seq=1 ttl=57 time=12.3 ms"
+		// macOS ping6: "16 bytes from ::1, icmp_seq=0 hlim=64 time=0.052 ms"
+		// Windows: "Reply from 1.1.1.1: bytes=32 time=14ms TTL=57"
+		// Windows IPv6: "Reply from ::1: time<1ms", "来自 ::1 的回复: 时间<1ms"
+		// 不可达、超时等行不带 TTL；重复回复 (DUP!) 不计数
+		if strings.Contains(line, "DUP!") {
+			continue
 		}
-
-		// Parse time values
-		// Windows: "Average = 2ms" or "平均 = 2ms"
-		// Linux/Unix: "rtt min/avg/max/mdev = 1.234/2.345/3.456/0.123 ms"
-		if strings.Contains(strings.ToLower(line), "avg") || strings.Contains(line, "Average") || strings.Contains(line, "rtt") {
-			if times := parseTimeValues(line); len(times) > 0 {
-				// Get average (usually the second value in rtt min/avg/max)
-				if len(times) >= 2 {
-					total = times[1]
-				} else if len(times) == 1 {
-					total = times[0]
-				}
+		m := pingReplyTime.FindStringSubmatch(line)
+		if !strings.Contains(lower, "ttl=") && !strings.Contains(lower, "hlim=") {
+			// 汇总行（"Minimum = 0ms"）不含地址，"Pinging ::1 with 32 bytes" 不含时间
+			if !v6 || m == nil || !strings.Contains(line, host) {
+				continue
 			}
 		}
 
-		// Parse individual ping times (for Unix systems)
-		// Example: "64 bytes from 192.168.1.1: icmp_seq=1 ttl=64 time=0.123 ms"
-		if strings.Contains(line, "time=") {
-			if idx := strings.Index(line, "time="); idx != -1 {
-				timeStr := line[idx+5:]
-				timeStr = strings.Fields(timeStr)[0]
-				if dur, err := time.ParseDuration(timeStr + "ms"); err == nil {
-					total += dur
-				}
-			}
+		if m == nil {
+			return stats, fmt.Errorf("cannot read round trip time from ping reply %q", line)
 		}
+		ms, err := strconv.ParseFloat(strings.Replace(m[1], ",", ".", 1), 64)
+		if err != nil {
+			return stats, fmt.Errorf("cannot read round trip time from ping reply %q: %w", line, err)
+		}
+		stats.RTTs = append(stats.RTTs, time.Duration(ms*float64(time.Millisecond)))
+		stats.Received++
 	}
 
-	// Calculate packet loss
-	packetLoss := 0
-	if packetsSent > 0 {
-		packetLoss = int(math.Ceil(float64(packetsSent-packetsReceived) * 100 / float64(packetsSent)))
-	}
-
-	avgTime := total
-	if packetsReceived > 0 {
-		avgTime = total / time.Duration(packetsReceived)
-	}
-
-	return packetLoss, avgTime, nil
-}
-
-// CheckICMP performs a raw ICMP ping (alternative method)
-func CheckICMP(host string, timeout time.Duration) (time.Duration, error) {
-	// Resolve IP address
-	ipAddr, err := net.ResolveIPAddr("ip", host)
-	if err != nil {
-		return 0, fmt.Errorf("failed to resolve address: %w", err)
-	}
-
-	start := time.Now()
-
-	// Create a connection
-	conn, err := net.DialIP("ip:icmp", nil, ipAddr)
-	if err != nil {
-		return 0, fmt.Errorf("failed to create ICMP connection: %w", err)
+	if stats.Received == 0 && !allowNoReplies {
+		return stats, fmt.Errorf("no ping replies found in output: %q", truncatePingOutput(output))
 	}
-	defer conn.Close()
-
-	// Set deadline
-	conn.SetDeadline(time.Now().Add(timeout))
-
-	// Send ICMP packet (simple echo request)
-	packet := make([]byte, 8)
-	packet[0] = 8 // Echo request
-	packet[1] = 0 // Code
-	packet[2] = 0 // Checksum (will be filled)
-	packet[3] = 0
-	packet[4] = 0 // Identifier
-	packet[5] = 0
-	packet[6] = 0 // Sequence
-	packet[7] = 0
-
-	_, err = conn.Write(packet)
-	if err != nil {
-		return 0, fmt.Errorf("failed to send ICMP packet: %w", err)
+	if stats.Received > sent {
+		return stats, fmt.Errorf("ping output has %d replies for %d packets sent", stats.Received, sent)
 	}
-
-	// Read response
-	reply := make([]byte, 1024)
-	_, err = conn.Read(reply)
-	if err != nil {
-		return 0, fmt.Errorf("failed to read ICMP response: %w", err)
-	}
-
-	elapsed := time.Since(start)
-	return elapsed, nil
+	stats.summarize()
+	return stats, nil
 }
 
-// Helper functions
-
-func extractNumber(s string) (int, error) {
-	s = strings.Trim(s, ",=:;()")
-	s = strings.TrimSpace(s)
-	var num int
-	_, err := fmt.Sscanf(s, "%d", &num)
-	return num, err
-}
-
-func parseTimeValues(line string) []time.Duration {
-	var times []time.Duration
-
-	fields := strings.Fields(line)
-	for _, field := range fields {
-		// Look for patterns like "2.345ms" or "2ms"
-		field = strings.Trim(field, ",=:;()/")
-		if strings.HasSuffix(field, "ms") {
-			msStr := strings.TrimSuffix(field, "ms")
-			var ms float64
-			if _, err := fmt.Sscanf(msStr, "%f", &ms); err == nil {
-				times = append(times, time.Duration(ms*float64(time.Millisecond)))
-			}
-		}
+// truncatePingOutput keeps error messages readable
+func truncatePingOutput(output string) string {
+	output = strings.TrimSpace(output)
+	if len(output) > 200 {
+		return output[:200] + "..."
 	}
-
-	return times
+	return output
 }
-
-// PingCheckerWrapper implements the Checker interface for PING monitoring
-type PingCheckerWrapper struct{}
-
-func (w *PingCheckerWrapper) Check(ctx context.Context, target *MonitorTarget) (*CheckResult, error) {
-	modelTarget := &models.MonitorTarget{
-		PingCount:   target.PingCount,
-		PingSize:    target.PingSize,
-		PingTimeout: target.PingTimeout,
-		Address:     target.Address,
-	}
-
-	checker := NewPingChecker(modelTarget)
-	return checker.Check()
-}
\ No newline at end of file
@@ -0,0 +1,194 @@
+package monitor
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+const (
+	pingLinuxDE = `PING 1.1.1.1 (1.1.1.1) 56(84) Bytes an Daten.
+64 Bytes von 1.1.1.1: icmp_seq=1 ttl=57 Zeit=12,3 ms
+64 Bytes von 1.1.1.1: icmp_seq=2 ttl=57 Zeit=14,5 ms
+64 Bytes von 1.1.1.1: icmp_seq=2 ttl=57 Zeit=15,0 ms (DUP!)
+
+--- 1.1.1.1 Ping-Statistiken ---
+3 Pakete übertragen, 2 empfangen, +1 Duplikate, 25% Paketverlust, Zeit 3004ms
+rtt min/avg/max/mdev = 12,300/13,400/14,500/1,100 ms`
+
+	pingLinuxFR = `PING 1.1.1.1 (1.1.1.1) 56(84) octets de données.
+64 octets de 1.1.1.1 : icmp_seq=1 ttl=57 temps=9,8 ms
+
+--- statistiques ping 1.1.1.1 ---
+4 paquets transmis, 1 reçus, 75 % paquets perdus, temps 3004 ms`
+
+	pingMacOS = `PING 1.1.1.1 (1.1.1.1): 56 data bytes
+64 bytes from 1.1.1.1: icmp_seq=0 ttl=57 time=10.512 ms
+64 bytes from 1.1.1.1: icmp_seq=1 ttl=57 time=11.488 ms
+
+--- 1.1.1.1 ping statistics ---
+2 packets transmitted, 2 packets received, 0.0% packet loss`
+
+	pingWindowsEN = "\r\nPinging 1.1.1.1 with 32 bytes of data:\r\n" +
+		"Reply from 1.1.1.1: bytes=32 time=14ms TTL=57\r\n" +
+		"Request timed out.\r\n" +
+		"Reply from 1.1.1.1: bytes=32 time<1ms TTL=57\r\n" +
+		"\r\nPing statistics for 1.1.1.1:\r\n" +
+		"    Packets: Sent = 3, Received = 2, Lost = 1 (33% loss),\r\n" +
+		"Approximate round trip times in milli-seconds:\r\n" +
+		"    Minimum = 0ms, Maximum = 14ms, Average = 7ms\r\n"
+
+	pingWindowsZH = "\r\n正在 Ping 1.1.1.1 具有 32 字节的数据:\r\n" +
+		"来自 1.1.1.1 的回复: 字节=32 时间=20ms TTL=57\r\n" +
+		"1.1.1.1 的 Ping 统计信息:\r\n" +
+		"    数据包: 已发送 = 1，已接收 = 1，丢失 = 0 (0% 丢失)，\r\n" +
+		"    最短 = 20ms，最长 = 20ms，平均 = 20ms\r\n"
+
+	// Windows prints IPv6 replies without a TTL
+	pingWindowsV6 = "\r\nPinging ::1 with 32 bytes of data:\r\n" +
+		"Reply from ::1: time<1ms\r\n" +
+		"Reply from ::1: time=2ms\r\n" +
+		"\r\nPing statistics for ::1:\r\n" +
+		"    Minimum = 0ms, Maximum = 2ms, Average = 1ms\r\n"
+
+	pingWindowsUnreachable = "\r\nPinging 10.9.9.9 with 32 bytes of data:\r\n" +
+		"Reply from 10.0.0.1: Destination host unreachable.\r\n" +
+		"Request timed out.\r\n"
+)
+
+// gbk re-encodes the Chinese words of a Windows fixture as a GBK console would
+func gbk(s string) string {
+	return strings.NewReplacer(
+		"来自", "\xc0\xb4\xd7\xd4", "的回复", "\xb5\xc4\xbb\xd8\xb8\xb4",
+		"字节", "\xd7\xd6\xbd\xda", "时间", "\xca\xb1\xbc\xe4",
+	).Replace(s)
+}
+
+func TestParsePingOutput(t *testing.T) {
+	ms := func(f float64) time.Duration { return time.Duration(f * float64(time.Millisecond)) }
+	for _, tc := range []struct {
+		name   string
+		output string
+		sent   int
+		host   string
+		v6     bool
+		rtts   []time.Duration
+	}{
+		{"linux de", pingLinuxDE, 3, "1.1.1.1", false, []time.Duration{ms(12.3), ms(14.5)}},
+		{"linux fr", pingLinuxFR, 4, "1.1.1.1", false, []time.Duration{ms(9.8)}},
+		{"macos", pingMacOS, 2, "1.1.1.1", false, []time.Duration{ms(10.512), ms(11.488)}},
+		// "time<1ms" counts as 1ms
+		{"windows en", pingWindowsEN, 3, "1.1.1.1", false, []time.Duration{ms(14), ms(1)}},
+		{"windows zh", pingWindowsZH, 1, "1.1.1.1", false, []time.Duration{ms(20)}},
+		{"windows zh gbk", gbk(pingWindowsZH), 1, "1.1.1.1", false, []time.Duration{ms(20)}},
+		{"windows v6", pingWindowsV6, 2, "::1", true, []time.Duration{ms(1), ms(2)}},
+		{"windows unreachable", pingWindowsUnreachable, 2, "10.9.9.9", false, nil},
+	} {
+		stats, err := parsePingOutput(tc.output, tc.sent, true, tc.host, tc.v6)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if stats.Sent != tc.sent || stats.Received != len(tc.rtts) || !slices.Equal(stats.RTTs, tc.rtts) {
+			t.Errorf("%s: sent %d received %d rtts %v, want %d %d %v", tc.name, stats.Sent, stats.Received, stats.RTTs, tc.sent, len(tc.rtts), tc.rtts)
+		}
+	}
+
+	stats, _ := parsePingOutput(pingLinuxDE, 3, false, "1.1.1.1", false)
+	if stats.Avg != ms(13.4) || stats.Min != ms(12.3) || stats.Max != ms(14.5) || stats.Jitter != ms(2.2) {
+		t.Errorf("summary avg %v min %v max %v jitter %v", stats.Avg, stats.Min, stats.Max, stats.Jitter)
+	}
+}
+
+func TestParsePingOutputErrors(t *testing.T) {
+	// A Unix ping that exited 0 printed replies we should have read
+	if _, err := parsePingOutput(pingWindowsUnreachable, 2, false, "10.9.9.9", false); err == nil {
+		t.Error("no replies accepted when replies are required")
+	}
+	if _, err := parsePingOutput("64 bytes from 1.1.1.1: icmp_seq=1 ttl=57 time=? ms", 1, true, "1.1.1.1", false); err == nil ||
+		!strings.Contains(err.Error(), "cannot read round trip time") {
+		t.Errorf("unreadable time: err = %v", err)
+	}
+	if _, err := parsePingOutput(pingMacOS, 1, true, "1.1.1.1", false); err == nil {
+		t.Error("more replies than packets sent accepted")
+	}
+}
+
+func TestPingHost(t *testing.T) {
+	for _, tc := range []struct {
+		address, host string
+		v6            bool
+	}{
+		{"1.1.1.1", "1.1.1.1", false},
+		{"example.com", "example.com", false},
+		{"[2001:db8::1]", "2001:db8::1", true},
+		{"fe80::1%eth0", "fe80::1%eth0", true},
+	} {
+		if host, v6 := pingHost(tc.address); host != tc.host || v6 != tc.v6 {
+			t.Errorf("pingHost(%q) = %q, %v, want %q, %v", tc.address, host, v6, tc.host, tc.v6)
+		}
+	}
+}
+
+func TestPingStatsSummarize(t *testing.T) {
+	ms := time.Millisecond
+	// The lost packet is skipped, jitter is taken between the replies around it
+	stats := pingStats{Sent: 5, Received: 4, RTTs: []time.Duration{10 * ms, 20 * ms, -1, 30 * ms, 20 * ms}}
+	stats.summarize()
+	if stats.Avg != 20*ms || stats.Min != 10*ms || stats.Max != 30*ms || stats.Jitter != 10*ms {
+		t.Errorf("avg %v min %v max %v jitter %v", stats.Avg, stats.Min, stats.Max, stats.Jitter)
+	}
+	// sqrt((100 + 0 + 100 + 0) / 4) ms
+	if stats.StdDev < 7071*time.Microsecond || stats.StdDev > 7072*time.Microsecond {
+		t.Errorf("stddev %v, want about 7.071ms", stats.StdDev)
+	}
+
+	// One reply has no jitter
+	single := pingStats{Sent: 1, Received: 1, RTTs: []time.Duration{5 * ms}}
+	single.summarize()
+	if single.Jitter != 0 || single.StdDev != 0 || single.Avg != 5*ms {
+		t.Errorf("single reply %+v", single)
+	}
+}
+
+// Jitter and standard deviation are kept on the history row of a ping check
+func TestSaveResultPingJitter(t *testing.T) {
+	s := newTestService(t)
+	target := &MonitorTarget{ID: 1, Name: "gw", Type: "ping", Address: "127.0.0.1", Interval: 3600}
+	if err := s.AddTarget(target); err != nil {
+		t.Fatalf("AddTarget: %v", err)
+	}
+	s.SetSinks(target.ID, Sinks{SinkDBHistory})
+	s.saveResult(target, &CheckResult{Status: "up", Data: map[string]interface{}{"jitter": 2.5, "stddev": 1.25}})
+	s.saveResult(target, &CheckResult{Status: "down"})
+
+	var rows []models.MonitorHistory
+	waitFor(t, func() bool {
+		database.GetDB().Where("target_id = ?", target.ID).Order("id").Find(&rows)
+		return len(rows) == 2
+	})
+	if rows[0].Jitter == nil || *rows[0].Jitter != 2.5 || rows[0].RTTStdDev == nil || *rows[0].RTTStdDev != 1.25 {
+		t.Errorf("jitter %v stddev %v", rows[0].Jitter, rows[0].RTTStdDev)
+	}
+	if rows[1].Jitter != nil || rows[1].RTTStdDev != nil {
+		t.Errorf("jitter %v stddev %v without ping data", rows[1].Jitter, rows[1].RTTStdDev)
+	}
+}
+
+func TestLintJitterRuleNotPing(t *testing.T) {
+	lint := func(typ string) bool {
+		cfg := &LintConfig{
+			Targets:  []models.MonitorTarget{{ID: 1, Name: "gw", Type: typ, Address: "127.0.0.1", Port: 80, Interval: 60, Enabled: true}},
+			Rules:    []models.AlertRule{{ID: 1, TargetID: 1, ChannelID: 1, Enabled: true, ThresholdType: "jitter", ThresholdValue: 10}},
+			Channels: []models.AlertChannel{{ID: 1, Name: "ops", Type: "wechat", Enabled: true}},
+		}
+		return slices.ContainsFunc(Lint(cfg), func(f LintFinding) bool { return f.Lint == "rule_jitter_not_ping" })
+	}
+	if !lint("tcp") || lint("ping") {
+		t.Errorf("finding on tcp %v, on ping %v", lint("tcp"), lint("ping"))
+	}
+}
@@ -0,0 +1,301 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"monitor/internal/logger"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// How ping targets are checked, see SetPingMode
+const (
+	// PingModeAuto sends the echo requests itself and runs the ping command
+	// when no ICMP socket can be opened
+	PingModeAuto = "auto"
+	// PingModeNative only sends the echo requests itself
+	PingModeNative = "native"
+	// PingModeExec runs the ping command of the host
+	PingModeExec = "exec"
+)
+
+// pingInterval is the time between two echo requests of a check, shortened
+// when count requests would not fit in the deadline of the check
+const pingInterval = time.Second
+
+// Protocol numbers given to icmp.ParseMessage
+const (
+	protocolICMP     = 1
+	protocolIPv6ICMP = 58
+)
+
+var pingMode = PingModeAuto
+
+// SetPingMode selects how ping targets are checked: PingModeAuto,
+// PingModeNative or PingModeExec. It must be called before targets are loaded.
+func SetPingMode(mode string) {
+	switch mode {
+	case PingModeNative, PingModeExec:
+		pingMode = mode
+	default:
+		pingMode = PingModeAuto
+	}
+}
+
+// errNoICMPSocket is returned by listenICMP when neither a raw nor a datagram
+// ICMP socket may be opened, e.g. without CAP_NET_RAW and outside
+// net.ipv4.ping_group_range, or on Windows without administrator rights
+var errNoICMPSocket = errors.New("cannot open an ICMP socket")
+
+// pingExecFallback logs the first time PingModeAuto runs the ping command
+var pingExecFallback sync.Once
+
+// icmpSocket is an ICMP socket of one check
+type icmpSocket struct {
+	conn *icmp.PacketConn
+	// Datagram sockets (udp4/udp6) are unprivileged: the kernel picks the
+	// echo identifier and only delivers replies to our own requests
+	datagram bool
+	proto    int
+	echo     icmp.Type
+	reply    icmp.Type
+}
+
+// listenICMP opens a raw ICMP socket, or a datagram one when raw sockets need
+// privileges the process does not have
+func listenICMP(v6 bool) (*icmpSocket, error) {
+	s := &icmpSocket{proto: protocolICMP, echo: ipv4.ICMPTypeEcho, reply: ipv4.ICMPTypeEchoReply}
+	raw, datagram, local := "ip4:icmp", "udp4", "0.0.0.0"
+	if v6 {
+		s.proto, s.echo, s.reply = protocolIPv6ICMP, ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+		raw, datagram, local = "ip6:ipv6-icmp", "udp6", "::"
+	}
+	conn, err := icmp.ListenPacket(raw, local)
+	if err == nil {
+		s.conn = conn
+		return s, nil
+	}
+	if !isPermissionError(err) {
+		return nil, fmt.Errorf("failed to open ICMP socket: %w", err)
+	}
+	conn, err = icmp.ListenPacket(datagram, local)
+	if err != nil {
+		if isPermissionError(err) || errors.Is(err, syscall.EPROTONOSUPPORT) || errors.Is(err, syscall.EAFNOSUPPORT) {
+			return nil, fmt.Errorf("%w: %v", errNoICMPSocket, err)
+		}
+		return nil, fmt.Errorf("failed to open ICMP socket: %w", err)
+	}
+	s.conn = conn
+	s.datagram = true
+	return s, nil
+}
+
+func isPermissionError(err error) bool {
+	return errors.Is(err, os.ErrPermission) || errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES)
+}
+
+// socketName describes the kind of socket in the request details
+func (s *icmpSocket) socketName() string {
+	if s.datagram {
+		return "datagram"
+	}
+	return "raw"
+}
+
+// pingNative sends count echo requests of size bytes to address, one every
+// pingInterval, and waits up to timeout after the last one for the replies.
+// Replies are matched by sequence number and a random token at the start of
+// the payload, so replies to other checks on a raw socket are ignored.
+func pingNative(ctx context.Context, address string, count, size int, timeout time.Duration) (pingStats, string, error) {
+	var stats pingStats
+	host, _ := pingHost(address)
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return stats, "", fmt.Errorf("failed to resolve address: %w", err)
+	}
+	if len(addrs) == 0 {
+		return stats, "", fmt.Errorf("failed to resolve address: no addresses for %s", host)
+	}
+	dst := addrs[0]
+	v6 := dst.IP.To4() == nil
+
+	sock, err := listenICMP(v6)
+	if err != nil {
+		return stats, "", err
+	}
+	defer sock.conn.Close()
+
+	var peer net.Addr = &dst
+	if sock.datagram {
+		peer = &net.UDPAddr{IP: dst.IP, Zone: dst.Zone}
+	}
+
+	idBytes := make([]byte, 2)
+	rand.Read(idBytes)
+	id := int(binary.BigEndian.Uint16(idBytes))
+	token := make([]byte, 8)
+	rand.Read(token)
+	if size < len(token) {
+		token = token[:size]
+	}
+	payload := make([]byte, size)
+	copy(payload, token)
+
+	start := time.Now()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = start.Add(checkTimeout)
+	}
+	interval := pingInterval
+	if fit := time.Until(deadline) - timeout; count > 1 && fit < interval*time.Duration(count-1) {
+		interval = max(fit/time.Duration(count-1), 10*time.Millisecond)
+	}
+
+	stats.RTTs = make([]time.Duration, count)
+	sentAt := make([]time.Time, count)
+	next := start
+	var final time.Time
+	buf := make([]byte, size+512)
+
+	for {
+		now := time.Now()
+		if stats.Sent < count && !now.Before(next) && now.Before(deadline) {
+			seq := stats.Sent
+			msg := icmp.Message{Type: sock.echo, Body: &icmp.Echo{ID: id, Seq: seq, Data: payload}}
+			b, err := msg.Marshal(nil)
+			if err != nil {
+				return stats, sock.socketName(), fmt.Errorf("failed to build echo request: %w", err)
+			}
+			stats.RTTs[seq] = -1
+			sentAt[seq] = time.Now()
+			if _, err := sock.conn.WriteTo(b, peer); err != nil {
+				return stats, sock.socketName(), fmt.Errorf("failed to send echo request: %w", err)
+			}
+			stats.Sent++
+			next = sentAt[seq].Add(interval)
+			if stats.Sent == count {
+				final = sentAt[seq].Add(timeout)
+			}
+			continue
+		}
+		if (stats.Sent == count && (stats.Received == count || !now.Before(final))) || !now.Before(deadline) {
+			break
+		}
+
+		wake := next
+		if stats.Sent == count {
+			wake = final
+		}
+		if deadline.Before(wake) {
+			wake = deadline
+		}
+		sock.conn.SetReadDeadline(wake)
+		n, from, err := sock.conn.ReadFrom(buf)
+		received := time.Now()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return stats, sock.socketName(), fmt.Errorf("failed to read echo reply: %w", err)
+		}
+		if !sameIP(from, dst.IP) {
+			continue
+		}
+		reply, err := icmp.ParseMessage(sock.proto, buf[:n])
+		if err != nil || reply.Type != sock.reply {
+			continue
+		}
+		echo, ok := reply.Body.(*icmp.Echo)
+		if !ok || (!sock.datagram && echo.ID != id) || !bytes.HasPrefix(echo.Data, token) {
+			continue
+		}
+		// 重复的回复不计数
+		if echo.Seq < 0 || echo.Seq >= stats.Sent || stats.RTTs[echo.Seq] >= 0 {
+			continue
+		}
+		stats.RTTs[echo.Seq] = received.Sub(sentAt[echo.Seq])
+		stats.Received++
+	}
+
+	stats.RTTs = stats.RTTs[:stats.Sent]
+	if stats.Sent == 0 {
+		return stats, sock.socketName(), fmt.Errorf("no echo request sent before the check deadline")
+	}
+	stats.summarize()
+	return stats, sock.socketName(), nil
+}
+
+// sameIP reports whether a reply came from ip
+func sameIP(from net.Addr, ip net.IP) bool {
+	switch a := from.(type) {
+	case *net.IPAddr:
+		return a.IP.Equal(ip)
+	case *net.UDPAddr:
+		return a.IP.Equal(ip)
+	}
+	return false
+}
+
+// summarize computes min, avg, max, jitter and standard deviation from the
+// RTTs of the replies. Jitter is the mean difference between the RTTs of
+// consecutive replies.
+func (s *pingStats) summarize() {
+	var total, diffs time.Duration
+	var squares float64
+	var prev time.Duration = -1
+	pairs := 0
+	for _, rtt := range s.RTTs {
+		if rtt < 0 {
+			continue
+		}
+		total += rtt
+		squares += float64(rtt) * float64(rtt)
+		if s.Min == 0 || rtt < s.Min {
+			s.Min = rtt
+		}
+		if rtt > s.Max {
+			s.Max = rtt
+		}
+		if prev >= 0 {
+			d := rtt - prev
+			if d < 0 {
+				d = -d
+			}
+			diffs += d
+			pairs++
+		}
+		prev = rtt
+	}
+	if s.Received > 0 {
+		s.Avg = total / time.Duration(s.Received)
+		mean := float64(total) / float64(s.Received)
+		s.StdDev = time.Duration(math.Sqrt(max(squares/float64(s.Received)-mean*mean, 0)))
+	}
+	if pairs > 0 {
+		s.Jitter = diffs / time.Duration(pairs)
+	}
+}
+
+// logPingExecFallback records once that PingModeAuto uses the ping command
+func logPingExecFallback(err error) {
+	pingExecFallback.Do(func() {
+		logger.Warn("Cannot open an ICMP socket, ping targets use the ping command; grant CAP_NET_RAW or add the group to net.ipv4.ping_group_range to send echo requests directly",
+			zap.Error(err),
+		)
+	})
+}
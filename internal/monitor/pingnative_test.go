@@ -0,0 +1,69 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSetPingMode(t *testing.T) {
+	defer SetPingMode(PingModeAuto)
+	for mode, want := range map[string]string{PingModeNative: PingModeNative, PingModeExec: PingModeExec, "": PingModeAuto, "icmp": PingModeAuto} {
+		if SetPingMode(mode); pingMode != want {
+			t.Errorf("SetPingMode(%q) = %q, want %q", mode, pingMode, want)
+		}
+	}
+}
+
+func TestSameIP(t *testing.T) {
+	ip := net.ParseIP("10.0.0.1")
+	if !sameIP(&net.IPAddr{IP: ip}, ip) || !sameIP(&net.UDPAddr{IP: ip}, ip) {
+		t.Error("reply from the target not matched")
+	}
+	if sameIP(&net.IPAddr{IP: net.ParseIP("10.0.0.2")}, ip) || sameIP(&net.TCPAddr{IP: ip}, ip) {
+		t.Error("reply from elsewhere matched")
+	}
+}
+
+// Lost packets keep their place in rtts as null
+func TestPingStatsData(t *testing.T) {
+	stats := pingStats{Sent: 3, Received: 2, RTTs: []time.Duration{1500 * time.Microsecond, -1, 2500 * time.Microsecond}}
+	stats.summarize()
+	data := stats.data(33, "raw")
+	rtts, _ := data["rtts"].([]interface{})
+	if len(rtts) != 3 || rtts[0] != 1.5 || rtts[1] != nil || rtts[2] != 2.5 {
+		t.Errorf("rtts %v", data["rtts"])
+	}
+	if data["min_time"] != 1.5 || data["max_time"] != 2.5 || data["avg_time"] != int64(2) || data["method"] != "raw" || data["packet_loss"] != 33 {
+		t.Errorf("data %v", data)
+	}
+
+	// Without replies there is nothing to summarize
+	lost := pingStats{Sent: 2, RTTs: []time.Duration{-1, -1}}
+	lost.summarize()
+	if data := lost.data(100, "datagram"); data["min_time"] != nil || data["jitter"] != nil {
+		t.Errorf("data without replies %v", data)
+	}
+}
+
+// Needs an ICMP socket: raw with CAP_NET_RAW, or datagram within
+// net.ipv4.ping_group_range
+func TestPingNativeLoopback(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	stats, method, err := pingNative(ctx, "127.0.0.1", 3, 56, 500*time.Millisecond)
+	if errors.Is(err, errNoICMPSocket) {
+		t.Skipf("no ICMP socket: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("pingNative: %v", err)
+	}
+	if method != "raw" && method != "datagram" {
+		t.Errorf("method %q", method)
+	}
+	if stats.Sent != 3 || stats.Received != 3 || len(stats.RTTs) != 3 || stats.Min <= 0 || stats.Max < stats.Min {
+		t.Errorf("stats %+v", stats)
+	}
+}
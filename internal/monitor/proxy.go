@@ -0,0 +1,130 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// proxyError is the error type of a check that failed because its proxy
+// could not be reached or refused the connection, as opposed to the target
+const proxyError = "proxy_error"
+
+// Proxy of http and https targets, declared in httpFields
+var proxyURLField = FieldSpec{Name: "proxy_url", Kind: FieldString, Max: intBound(500), Description: "通过该代理发送请求（http://、https:// 或 socks5://），可以带用户名和密码；密码不在查询结果中返回，更新时省略密码保留原值"}
+
+// proxySchemes are the proxy schemes net/http dials itself
+var proxySchemes = map[string]bool{"http": true, "https": true, "socks5": true, "socks5h": true}
+
+// ValidateProxyURL checks the proxy_url of a target: only for http and https
+// targets not using a Unix socket, with a supported scheme and a host
+func ValidateProxyURL(typ, rawURL, unixSocketPath string) error {
+	if rawURL == "" {
+		return nil
+	}
+	if spec, ok := LookupType(typ); ok {
+		typ = spec.Type
+	}
+	if typ != "http" && typ != "https" {
+		return fmt.Errorf("proxy_url is only supported for http and https monitors")
+	}
+	if unixSocketPath != "" {
+		return fmt.Errorf("proxy_url cannot be used with unix_socket_path")
+	}
+	_, err := parseProxyURL(rawURL)
+	return err
+}
+
+// parseProxyURL parses a proxy URL. The error does not repeat the URL, which
+// may hold a password.
+func parseProxyURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy_url")
+	}
+	if !proxySchemes[strings.ToLower(u.Scheme)] {
+		return nil, fmt.Errorf("proxy_url scheme must be http, https or socks5, got %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("proxy_url needs a host")
+	}
+	if u.Path != "" && u.Path != "/" || u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("proxy_url must not have a path, query or fragment")
+	}
+	return u, nil
+}
+
+// SplitProxyURL separates the password from a proxy URL, so that the URL can
+// be returned by the API. A URL that does not parse is returned as is.
+func SplitProxyURL(rawURL string) (withoutPassword, password string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL, ""
+	}
+	password, ok := u.User.Password()
+	if !ok {
+		return rawURL, ""
+	}
+	u.User = url.User(u.User.Username())
+	return u.String(), password
+}
+
+// proxy returns the proxy of the target with its password, nil without one
+func (t *MonitorTarget) proxy() (*url.URL, error) {
+	if t.ProxyURL == "" {
+		return nil, nil
+	}
+	u, err := parseProxyURL(t.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.User != nil && t.ProxyPassword != "" {
+		u.User = url.UserPassword(u.User.Username(), t.ProxyPassword)
+	}
+	return u, nil
+}
+
+// proxyStatusError is a CONNECT request refused by an HTTP proxy, see
+// newTargetTransport
+type proxyStatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *proxyStatusError) Error() string {
+	return "proxy responded to CONNECT with " + e.Status
+}
+
+// socksTargetErrors are the SOCKS5 replies of a proxy that reached the
+// target's network but not the target
+var socksTargetErrors = []string{"network unreachable", "host unreachable", "connection refused", "TTL expired"}
+
+// isProxyError reports whether a request failed at its proxy: the proxy could
+// not be dialed, its TLS handshake or authentication failed, or it refused
+// the connection. A proxy reporting that the target is unreachable (CONNECT
+// 502 or 504, or the matching SOCKS5 replies) is a failure of the target.
+func isProxyError(err error) bool {
+	var statusErr *proxyStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode != http.StatusBadGateway && statusErr.StatusCode != http.StatusGatewayTimeout
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+	switch opErr.Op {
+	case "proxyconnect":
+		return true
+	case "socks connect":
+		for _, reply := range socksTargetErrors {
+			if strings.HasSuffix(opErr.Err.Error(), reply) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
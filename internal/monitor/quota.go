@@ -0,0 +1,98 @@
+package monitor
+
+import (
+	"fmt"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// Limits caps the number of monitors; a negative maximum disables that limit
+type Limits struct {
+	MaxTargets     int
+	MaxFastTargets int
+	FastInterval   int64 // targets checked more often than this (seconds) count as fast
+}
+
+// DefaultLimits are used until SetLimits is called
+var DefaultLimits = Limits{MaxTargets: 5000, MaxFastTargets: 200, FastInterval: 15}
+
+// QuotaUsage reports usage against the limits
+type QuotaUsage struct {
+	Targets        int64 `json:"targets"`
+	MaxTargets     int   `json:"max_targets"`
+	FastTargets    int64 `json:"fast_targets"`
+	MaxFastTargets int   `json:"max_fast_targets"`
+	FastInterval   int64 `json:"fast_interval"`
+	Loaded         int   `json:"loaded"`     // targets being checked
+	NotLoaded      int   `json:"not_loaded"` // enabled targets skipped at startup because of max_targets
+}
+
+// QuotaError is returned when creating or changing monitors would exceed a limit
+type QuotaError struct {
+	Limit     string `json:"limit"` // max_targets, max_fast_targets
+	Max       int    `json:"max"`
+	Current   int64  `json:"current"`
+	Requested int64  `json:"requested"`
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s limit reached: %d in use, %d requested, limit is %d", e.Limit, e.Current, e.Requested, e.Max)
+}
+
+// SetLimits replaces the monitor limits. It must be called before targets are loaded.
+func (s *Service) SetLimits(limits Limits) {
+	s.limits = limits
+}
+
+// IsFast reports whether a check interval counts towards max_fast_targets
+func (s *Service) IsFast(interval int64) bool {
+	return interval < s.limits.FastInterval
+}
+
+// Quota counts the stored monitors against the limits
+func (s *Service) Quota() (QuotaUsage, error) {
+	db := database.GetDB()
+	usage := QuotaUsage{
+		MaxTargets:     s.limits.MaxTargets,
+		MaxFastTargets: s.limits.MaxFastTargets,
+		FastInterval:   s.limits.FastInterval,
+	}
+
+	if err := db.Model(&models.MonitorTarget{}).Count(&usage.Targets).Error; err != nil {
+		return usage, err
+	}
+	// interval is a reserved word in MySQL, let gorm quote it for the dialect
+	fastClause := clause.Lt{Column: clause.Column{Name: "interval"}, Value: s.limits.FastInterval}
+	if err := db.Model(&models.MonitorTarget{}).Where(fastClause).Count(&usage.FastTargets).Error; err != nil {
+		return usage, err
+	}
+
+	s.mu.RLock()
+	usage.Loaded = len(s.targets)
+	usage.NotLoaded = s.notLoaded
+	s.mu.RUnlock()
+	return usage, nil
+}
+
+// CheckQuota returns a *QuotaError if adding targets monitors, fast of which are
+// fast, would exceed a limit
+func (s *Service) CheckQuota(targets, fast int64) error {
+	if targets <= 0 && fast <= 0 {
+		return nil
+	}
+
+	usage, err := s.Quota()
+	if err != nil {
+		return err
+	}
+	if targets > 0 && s.limits.MaxTargets >= 0 && usage.Targets+targets > int64(s.limits.MaxTargets) {
+		return &QuotaError{Limit: "max_targets", Max: s.limits.MaxTargets, Current: usage.Targets, Requested: targets}
+	}
+	if fast > 0 && s.limits.MaxFastTargets >= 0 && usage.FastTargets+fast > int64(s.limits.MaxFastTargets) {
+		return &QuotaError{Limit: "max_fast_targets", Max: s.limits.MaxFastTargets, Current: usage.FastTargets, Requested: fast}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package monitor
+
+import (
+	"errors"
+	"testing"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+func createTargets(t *testing.T, intervals ...int64) {
+	t.Helper()
+	for i, interval := range intervals {
+		target := models.MonitorTarget{Name: "t", Type: "tcp", Address: "127.0.0.1", Port: int32(i + 1), Interval: interval}
+		if err := database.GetDB().Create(&target).Error; err != nil {
+			t.Fatalf("create target: %v", err)
+		}
+	}
+}
+
+func TestCheckQuota(t *testing.T) {
+	s := newTestService(t)
+	s.SetLimits(Limits{MaxTargets: 3, MaxFastTargets: 1, FastInterval: 15})
+	createTargets(t, 60, 5)
+
+	usage, err := s.Quota()
+	if err != nil {
+		t.Fatalf("Quota: %v", err)
+	}
+	if usage.Targets != 2 || usage.FastTargets != 1 || usage.MaxTargets != 3 || usage.MaxFastTargets != 1 {
+		t.Errorf("usage %+v", usage)
+	}
+
+	if err := s.CheckQuota(1, 0); err != nil {
+		t.Errorf("one more slow target: %v", err)
+	}
+	var quotaErr *QuotaError
+	if err := s.CheckQuota(2, 0); !errors.As(err, &quotaErr) || quotaErr.Limit != "max_targets" || quotaErr.Current != 2 || quotaErr.Requested != 2 {
+		t.Errorf("two more targets: err = %v", err)
+	}
+	if err := s.CheckQuota(1, 1); !errors.As(err, &quotaErr) || quotaErr.Limit != "max_fast_targets" {
+		t.Errorf("one more fast target: err = %v", err)
+	}
+	// Making a fast target slow frees a slot, and nothing is ever over quota
+	if err := s.CheckQuota(0, -1); err != nil {
+		t.Errorf("fewer fast targets: %v", err)
+	}
+
+	if !s.IsFast(14) || s.IsFast(15) {
+		t.Error("IsFast does not treat intervals below fast_interval as fast")
+	}
+
+	// A negative maximum turns the limit off
+	s.SetLimits(Limits{MaxTargets: -1, MaxFastTargets: -1, FastInterval: 15})
+	if err := s.CheckQuota(100, 100); err != nil {
+		t.Errorf("unlimited: %v", err)
+	}
+}
+
+// Over max_targets at startup only the oldest targets are loaded, and the
+// rest are reported
+func TestLoadTargetsFromDBOverLimit(t *testing.T) {
+	s := newTestService(t)
+	s.SetLimits(Limits{MaxTargets: 2, MaxFastTargets: -1, FastInterval: 15})
+	createTargets(t, 60, 60, 60)
+
+	if err := s.LoadTargetsFromDB(); err != nil {
+		t.Fatalf("LoadTargetsFromDB: %v", err)
+	}
+	usage, _ := s.Quota()
+	if usage.Loaded != 2 || usage.NotLoaded != 1 {
+		t.Errorf("loaded %d not loaded %d, want 2 and 1", usage.Loaded, usage.NotLoaded)
+	}
+	if _, err := s.GetTarget(3); err == nil {
+		t.Error("the newest target was loaded")
+	}
+}
@@ -0,0 +1,198 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// redactedValue replaces any value considered sensitive
+const redactedValue = "***"
+
+// DefaultMaxRequestBodyBytes is how much of a request body is stored when not configured
+const DefaultMaxRequestBodyBytes = 4096
+
+// defaultSensitiveKeys matches JSON keys, form fields and header names holding credentials
+var defaultSensitiveKeys = []string{
+	`(?i)passw(or)?d`, `(?i)secret`, `(?i)token`, `(?i)api[_-]?key`,
+	`(?i)authorization`, `(?i)credential`, `(?i)private[_-]?key`, `(?i)cookie`,
+}
+
+// Redactor masks credentials in stored request details and bounds the body size.
+// It is applied once in saveResult, before the result reaches any sink.
+type Redactor struct {
+	keys         []*regexp.Regexp
+	patterns     []*regexp.Regexp
+	maxBodyBytes int
+}
+
+// NewRedactor builds a redactor from extra key regexes (added to the defaults),
+// regexes applied to the raw body, and the body size cap (<= 0 uses the default).
+func NewRedactor(keys, patterns []string, maxBodyBytes int) (*Redactor, error) {
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = DefaultMaxRequestBodyBytes
+	}
+	r := &Redactor{maxBodyBytes: maxBodyBytes}
+
+	for _, expr := range append(append([]string{}, defaultSensitiveKeys...), keys...) {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction key pattern %q: %w", expr, err)
+		}
+		r.keys = append(r.keys, re)
+	}
+	for _, expr := range patterns {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", expr, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	return r, nil
+}
+
+// defaultRedactor is used until the service is configured
+func defaultRedactor() *Redactor {
+	r, _ := NewRedactor(nil, nil, 0)
+	return r
+}
+
+// Apply redacts the request details of a check result in place
+func (r *Redactor) Apply(result *CheckResult) {
+	if result == nil {
+		return
+	}
+	result.Request.Body = r.RedactBody(result.Request.Body)
+	result.Request.Headers = r.RedactHeaders(result.Request.Headers)
+}
+
+// RedactHeaders returns a copy of headers with sensitive values masked
+func (r *Redactor) RedactHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return headers
+	}
+	redacted := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if r.sensitiveKey(name) {
+			value = redactedValue
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+// RedactBody masks sensitive fields of a JSON or form body, applies the
+// configured patterns and truncates the result to the size cap.
+func (r *Redactor) RedactBody(body string) string {
+	return truncateBody(r.RedactContent(body), r.maxBodyBytes)
+}
+
+// RedactContent masks a body like RedactBody without the size cap; archived
+// responses go through it when they are read
+func (r *Redactor) RedactContent(body string) string {
+	if body == "" {
+		return body
+	}
+
+	trimmed := strings.TrimSpace(body)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		var doc interface{}
+		if err := json.Unmarshal([]byte(trimmed), &doc); err == nil {
+			var buf strings.Builder
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false)
+			if err := enc.Encode(r.redactJSON(doc)); err == nil {
+				body = strings.TrimSuffix(buf.String(), "\n")
+			}
+		}
+	} else if strings.Contains(body, "=") {
+		body = r.redactForm(body)
+	}
+
+	for _, re := range r.patterns {
+		body = redactMatches(re, body)
+	}
+
+	return body
+}
+
+func (r *Redactor) sensitiveKey(key string) bool {
+	for _, re := range r.keys {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) redactJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if r.sensitiveKey(key) {
+				v[key] = redactedValue
+			} else {
+				v[key] = r.redactJSON(child)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = r.redactJSON(child)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// redactForm masks values of sensitive fields in key=value&key=value bodies
+func (r *Redactor) redactForm(body string) string {
+	pairs := strings.Split(body, "&")
+	for i, pair := range pairs {
+		key, _, found := strings.Cut(pair, "=")
+		if found && r.sensitiveKey(key) {
+			pairs[i] = key + "=" + redactedValue
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// redactMatches replaces the capture groups of each match, or the whole match
+// when the pattern has no groups, e.g. `<password>(.*?)</password>`
+func redactMatches(re *regexp.Regexp, body string) string {
+	if re.NumSubexp() == 0 {
+		return re.ReplaceAllString(body, redactedValue)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringSubmatchIndex(body, -1) {
+		for g := 1; g <= re.NumSubexp(); g++ {
+			start, end := loc[2*g], loc[2*g+1]
+			if start < last || start < 0 {
+				continue
+			}
+			b.WriteString(body[last:start])
+			b.WriteString(redactedValue)
+			last = end
+		}
+	}
+	b.WriteString(body[last:])
+	return b.String()
+}
+
+// truncateBody cuts body to at most max bytes on a rune boundary and appends a marker
+func truncateBody(body string, max int) string {
+	if len(body) <= max {
+		return body
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s... (truncated, %d bytes total)", body[:cut], len(body))
+}
@@ -0,0 +1,132 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"monitor/internal/database"
+	"monitor/internal/logger"
+)
+
+const testSecret = "hunter2-s3cr3t"
+
+// secretResult is a check result carrying testSecret in every place the
+// default rules cover
+func secretResult() *CheckResult {
+	return &CheckResult{
+		Status:  "up",
+		Message: "ok",
+		Request: RequestDetails{
+			Method: "POST",
+			URL:    "https://api.example.com/login",
+			Headers: map[string]string{
+				"Authorization": "Bearer " + testSecret,
+				"X-Api-Key":     testSecret,
+				"Cookie":        "session=" + testSecret,
+				"Accept":        "application/json",
+			},
+			Body: `{"user":"admin","password":"` + testSecret + `","nested":[{"client_secret":"` + testSecret + `"}]}`,
+		},
+	}
+}
+
+func TestRedactorMasksDefaultKeys(t *testing.T) {
+	result := secretResult()
+	defaultRedactor().Apply(result)
+
+	serialized, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if strings.Contains(string(serialized), testSecret) {
+		t.Fatalf("secret left in the result: %s", serialized)
+	}
+	if result.Request.Headers["Accept"] != "application/json" {
+		t.Errorf("Accept header = %q, want it untouched", result.Request.Headers["Accept"])
+	}
+	if !strings.Contains(result.Request.Body, `"user":"admin"`) {
+		t.Errorf("body = %s, want the user kept", result.Request.Body)
+	}
+}
+
+func TestRedactorFormAndPatterns(t *testing.T) {
+	r, err := NewRedactor([]string{`(?i)^pin$`}, []string{`<pass>(.*?)</pass>`, `sk_live_[0-9a-z]+`}, 0)
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+	for body, want := range map[string]string{
+		"user=admin&passwd=" + testSecret + "&pin=1234":  "user=admin&passwd=***&pin=***",
+		"<login><pass>" + testSecret + "</pass></login>": "<login><pass>***</pass></login>",
+		"charge with sk_live_abc123 now":                 "charge with *** now",
+	} {
+		if got := r.RedactBody(body); got != want {
+			t.Errorf("RedactBody(%q) = %q, want %q", body, got, want)
+		}
+	}
+
+	if _, err := NewRedactor([]string{"("}, nil, 0); err == nil {
+		t.Error("invalid key pattern accepted")
+	}
+}
+
+func TestRedactorTruncatesOnRuneBoundary(t *testing.T) {
+	r, _ := NewRedactor(nil, nil, 5)
+	got := r.RedactBody("ab监控xyz")
+	if want := "ab监... (truncated, 11 bytes total)"; got != want {
+		t.Errorf("RedactBody = %q, want %q", got, want)
+	}
+}
+
+// Every sink gets the redacted copy: the raw secret must not reach the
+// database or the file log in any column or field
+func TestSaveResultNeverStoresSecrets(t *testing.T) {
+	s := newTestService(t)
+	// Restoring the default log dir creates it in the working directory
+	t.Chdir(t.TempDir())
+	logDir := t.TempDir()
+	if err := logger.InitLogFileLog(logDir); err != nil {
+		t.Fatalf("init file log: %v", err)
+	}
+	t.Cleanup(func() { logger.InitLogFileLog(logger.DefaultLogDir) })
+
+	target := &MonitorTarget{ID: 1, Name: "login", Type: "http", Address: "https://api.example.com/login", Interval: 60}
+	if err := s.AddTarget(target); err != nil {
+		t.Fatalf("AddTarget: %v", err)
+	}
+	s.SetSinks(target.ID, Sinks{SinkDBHistory, SinkFile})
+	s.saveResult(target, secretResult())
+	s.flushHistory()
+
+	var stored []string
+	for _, table := range []string{"monitor_history", "monitor_status"} {
+		var rows []map[string]interface{}
+		if err := database.GetDB().Table(table).Find(&rows).Error; err != nil {
+			t.Fatalf("read %s: %v", table, err)
+		}
+		if len(rows) == 0 {
+			t.Fatalf("nothing stored in %s", table)
+		}
+		stored = append(stored, fmt.Sprint(rows))
+	}
+	files, _ := filepath.Glob(filepath.Join(logDir, "*"))
+	if len(files) == 0 {
+		t.Fatal("nothing written to the file log")
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("read file log: %v", err)
+		}
+		stored = append(stored, string(data))
+	}
+
+	for _, content := range stored {
+		if strings.Contains(content, testSecret) {
+			t.Errorf("secret stored: %s", content)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package monitor
+
+import (
+	"errors"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// RepairReport describes what a recompute or consistency pass changed for a target
+type RepairReport struct {
+	TargetID uint32   `json:"target_id"`
+	Actions  []string `json:"actions"`
+}
+
+// RecomputeTarget rebuilds the stored status of a target from its MonitorHistory:
+// latest result, uptime percentage and last status change time.
+func (s *Service) RecomputeTarget(targetID uint32) (*RepairReport, error) {
+	return s.repairTarget(targetID, true)
+}
+
+// repairTarget brings the status row of a target in line with its history;
+// full also recomputes the uptime
+func (s *Service) repairTarget(targetID uint32, full bool) (*RepairReport, error) {
+	s.flushHistory()
+	db := database.GetDB()
+
+	report := &RepairReport{TargetID: targetID, Actions: []string{}}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return s.recomputeStatus(tx, targetID, report, full)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.InvalidateStatus()
+	// History may have been edited by hand, the cached heatmap days with it
+	s.heatmap.invalidate(targetID)
+	return report, nil
+}
+
+// RecomputeAll recomputes the status of every target and returns the
+// reports of the targets that needed a change.
+func (s *Service) RecomputeAll() ([]*RepairReport, error) {
+	db := database.GetDB()
+
+	var targetIDs []uint32
+	if err := db.Model(&models.MonitorTarget{}).Pluck("id", &targetIDs).Error; err != nil {
+		return nil, err
+	}
+
+	reports := make([]*RepairReport, 0)
+	for _, id := range targetIDs {
+		report, err := s.RecomputeTarget(id)
+		if err != nil {
+			return reports, err
+		}
+		if len(report.Actions) > 0 {
+			reports = append(reports, report)
+		}
+	}
+
+	return reports, nil
+}
+
+// RepairConsistency detects and repairs obvious mismatches between the status
+// table and the rest of the data. It is run once at startup, so it only
+// removes status rows of deleted targets and resets a status that disagrees
+// with the latest history row; uptime is left to the periodic pass.
+func (s *Service) RepairConsistency() ([]*RepairReport, error) {
+	db := database.GetDB()
+
+	// Status rows pointing at a deleted target
+	var orphans []models.MonitorStatus
+	if err := db.Where("target_id NOT IN (?)", db.Model(&models.MonitorTarget{}).Select("id")).
+		Find(&orphans).Error; err != nil {
+		return nil, err
+	}
+
+	reports := make([]*RepairReport, 0)
+	for _, orphan := range orphans {
+		if err := db.Delete(&orphan).Error; err != nil {
+			return reports, err
+		}
+		reports = append(reports, &RepairReport{
+			TargetID: orphan.TargetID,
+			Actions:  []string{"removed status row of deleted target"},
+		})
+	}
+
+	var targetIDs []uint32
+	if err := db.Model(&models.MonitorTarget{}).Pluck("id", &targetIDs).Error; err != nil {
+		return reports, err
+	}
+	for _, id := range targetIDs {
+		report, err := s.repairTarget(id, false)
+		if err != nil {
+			return reports, err
+		}
+		if len(report.Actions) > 0 {
+			reports = append(reports, report)
+		}
+	}
+	return reports, nil
+}
+
+// recomputeStatus brings the status row of a target in line with its history;
+// full also recomputes the uptime
+func (s *Service) recomputeStatus(tx *gorm.DB, targetID uint32, report *RepairReport, full bool) error {
+	var latest models.MonitorHistory
+	err := tx.Where("target_id = ?", targetID).Order("checked_at DESC").First(&latest).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	hasHistory := err == nil
+
+	var status models.MonitorStatus
+	err = tx.Where("target_id = ?", targetID).First(&status).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if !hasHistory {
+			return nil
+		}
+		status = models.MonitorStatus{TargetID: targetID}
+		report.Actions = append(report.Actions, "created missing status row")
+	} else if err != nil {
+		return err
+	}
+
+	if !hasHistory && status.Synthetic {
+		// Only injected results were ever recorded and they have been purged
+		report.Actions = append(report.Actions, "removed synthetic status row")
+		return tx.Delete(&status).Error
+	}
+
+	// The config_error status of a target that is not scheduled is not based on history
+	if hasHistory && !s.hasConfigError(targetID) {
+		// The status row should never be newer than the latest history row;
+		// if it is, the history it was based on has been removed.
+		statusChanged := status.Status != latest.Status
+		stale := statusChanged || status.Synthetic != latest.Synthetic ||
+			status.CheckedAt.Sub(latest.CheckedAt) > time.Second
+		if stale {
+			status.Status = latest.Status
+			status.ResponseTime = latest.ResponseTime
+			status.Message = latest.Message
+			status.Synthetic = latest.Synthetic
+			status.CheckedAt = latest.CheckedAt
+			report.Actions = append(report.Actions, "reset status to latest history entry")
+		}
+
+		changedAt, exact, err := lastStatusChange(tx, targetID, latest.Status)
+		if err != nil {
+			return err
+		}
+		// Without an earlier row of another status the run may have started
+		// before the oldest row kept, so a stored time up to that row stands
+		keep := !exact && !statusChanged && status.LastStatusChangeAt != nil &&
+			!status.LastStatusChangeAt.After(changedAt)
+		if !keep && (status.LastStatusChangeAt == nil || !status.LastStatusChangeAt.Equal(changedAt)) {
+			status.LastStatusChangeAt = &changedAt
+			report.Actions = append(report.Actions, "recomputed last_status_change_at")
+		}
+	}
+
+	if full {
+		uptime, err := s.computeUptime(tx, targetID)
+		if err != nil {
+			return err
+		}
+		if uptime.apply(&status) {
+			report.Actions = append(report.Actions, "recomputed uptime")
+		}
+	}
+
+	if len(report.Actions) == 0 {
+		return nil
+	}
+	return tx.Save(&status).Error
+}
+
+// lastStatusChange finds the start of the most recent run of the given status.
+// exact is false when no earlier row has another status: the run starts at
+// the oldest row kept, or before it if older history was deleted.
+func lastStatusChange(tx *gorm.DB, targetID uint32, current string) (changedAt time.Time, exact bool, err error) {
+	query := tx.Where("target_id = ? AND status = ?", targetID, current)
+
+	var previous models.MonitorHistory
+	err = tx.Where("target_id = ? AND status <> ?", targetID, current).
+		Order("checked_at DESC").First(&previous).Error
+	if err == nil {
+		exact = true
+		query = query.Where("checked_at > ?", previous.CheckedAt)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, false, err
+	}
+
+	var first models.MonitorHistory
+	if err := query.Order("checked_at ASC").First(&first).Error; err != nil {
+		return time.Time{}, false, err
+	}
+	return first.CheckedAt, exact, nil
+}
+
+// LogRepairs records each repair at warn, as the audit record of the pass;
+// fields say who started it, e.g. source and client_ip
+func LogRepairs(reports []*RepairReport, fields ...zap.Field) {
+	for _, report := range reports {
+		if len(report.Actions) == 0 {
+			continue
+		}
+		logger.Warn("Monitor status repaired", append([]zap.Field{
+			zap.Uint32("target_id", report.TargetID),
+			zap.Strings("actions", report.Actions),
+		}, fields...)...)
+	}
+}
@@ -0,0 +1,198 @@
+package monitor
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+// seedHistory stores a target with one history row per status, a minute
+// apart and ending a minute ago, and returns the target ID
+func seedHistory(t *testing.T, statuses ...string) (uint32, []time.Time) {
+	t.Helper()
+	db := database.GetDB()
+	target := models.MonitorTarget{Name: "repair", Type: "tcp", Address: "127.0.0.1", Port: 1, Interval: 60}
+	if err := db.Create(&target).Error; err != nil {
+		t.Fatalf("create target: %v", err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	times := make([]time.Time, len(statuses))
+	for i, status := range statuses {
+		times[i] = now.Add(time.Duration(i-len(statuses)) * time.Minute)
+		row := models.MonitorHistory{TargetID: target.ID, Status: status, CheckedAt: times[i]}
+		if err := db.Create(&row).Error; err != nil {
+			t.Fatalf("create history: %v", err)
+		}
+	}
+	return target.ID, times
+}
+
+func loadStatus(t *testing.T, targetID uint32) *models.MonitorStatus {
+	t.Helper()
+	var status models.MonitorStatus
+	if err := database.GetDB().Where("target_id = ?", targetID).First(&status).Error; err != nil {
+		return nil
+	}
+	return &status
+}
+
+func TestRecomputeTargetResetsStaleStatus(t *testing.T) {
+	s := newTestService(t)
+	id, times := seedHistory(t, "up", "down", "down")
+
+	// A status row newer than any history, as left behind by deleted rows
+	stale := models.MonitorStatus{TargetID: id, Status: "up", CheckedAt: time.Now().UTC()}
+	if err := database.GetDB().Create(&stale).Error; err != nil {
+		t.Fatalf("create status: %v", err)
+	}
+
+	report, err := s.RecomputeTarget(id)
+	if err != nil {
+		t.Fatalf("RecomputeTarget: %v", err)
+	}
+	for _, action := range []string{"reset status to latest history entry", "recomputed last_status_change_at", "recomputed uptime"} {
+		if !slices.Contains(report.Actions, action) {
+			t.Errorf("actions %v, want %q", report.Actions, action)
+		}
+	}
+
+	status := loadStatus(t, id)
+	if status.Status != "down" || !status.CheckedAt.Equal(times[2]) {
+		t.Errorf("status %s at %s, want down at %s", status.Status, status.CheckedAt, times[2])
+	}
+	if status.LastStatusChangeAt == nil || !status.LastStatusChangeAt.Equal(times[1]) {
+		t.Errorf("last status change %v, want %s", status.LastStatusChangeAt, times[1])
+	}
+
+	// A second pass finds nothing to do
+	report, err = s.RecomputeTarget(id)
+	if err != nil {
+		t.Fatalf("RecomputeTarget: %v", err)
+	}
+	if len(report.Actions) != 0 {
+		t.Errorf("second recompute actions %v, want none", report.Actions)
+	}
+}
+
+func TestRecomputeTargetCreatesMissingStatus(t *testing.T) {
+	s := newTestService(t)
+	id, times := seedHistory(t, "down", "up")
+
+	report, err := s.RecomputeTarget(id)
+	if err != nil {
+		t.Fatalf("RecomputeTarget: %v", err)
+	}
+	if !slices.Contains(report.Actions, "created missing status row") {
+		t.Errorf("actions %v, want the status row created", report.Actions)
+	}
+	status := loadStatus(t, id)
+	if status == nil || status.Status != "up" || status.LastStatusChangeAt == nil || !status.LastStatusChangeAt.Equal(times[1]) {
+		t.Fatalf("status %+v, want up since %s", status, times[1])
+	}
+	if status.Uptime24h != 50 {
+		t.Errorf("uptime_24h = %v, want 50", status.Uptime24h)
+	}
+
+	// Without history there is nothing to build a status from
+	empty, _ := seedHistory(t)
+	if report, err := s.RecomputeTarget(empty); err != nil || len(report.Actions) != 0 {
+		t.Errorf("target without history: actions %v err %v, want none", report.Actions, err)
+	}
+	if loadStatus(t, empty) != nil {
+		t.Error("status row created for a target without history")
+	}
+}
+
+func TestRepairConsistencyRemovesOrphanedStatus(t *testing.T) {
+	s := newTestService(t)
+	id, _ := seedHistory(t, "up")
+	orphan := models.MonitorStatus{TargetID: id + 100, Status: "up", CheckedAt: time.Now().UTC()}
+	if err := database.GetDB().Create(&orphan).Error; err != nil {
+		t.Fatalf("create status: %v", err)
+	}
+
+	reports, err := s.RepairConsistency()
+	if err != nil {
+		t.Fatalf("RepairConsistency: %v", err)
+	}
+	repaired := map[uint32]bool{}
+	for _, report := range reports {
+		repaired[report.TargetID] = true
+	}
+	if !repaired[orphan.TargetID] || !repaired[id] {
+		t.Errorf("repaired %v, want the orphan %d and the target %d", repaired, orphan.TargetID, id)
+	}
+	if loadStatus(t, orphan.TargetID) != nil {
+		t.Error("status row of a deleted target kept")
+	}
+	if status := loadStatus(t, id); status == nil || status.Status != "up" {
+		t.Errorf("status %+v, want up", status)
+	}
+}
+
+// History trimmed by retention holds no earlier row of another status, so
+// the stored last_status_change_at from before the oldest row is kept; one
+// after the oldest row of the run can't be right and is reset
+func TestRepairKeepsLastChangeOfTrimmedHistory(t *testing.T) {
+	s := newTestService(t)
+	id, times := seedHistory(t, "up", "up", "up")
+	since := times[0].AddDate(0, 0, -90)
+	status := models.MonitorStatus{TargetID: id, Status: "up", CheckedAt: times[2], LastStatusChangeAt: &since}
+	if err := database.GetDB().Create(&status).Error; err != nil {
+		t.Fatalf("create status: %v", err)
+	}
+
+	reports, err := s.RepairConsistency()
+	if err != nil {
+		t.Fatalf("RepairConsistency: %v", err)
+	}
+	if len(reports) != 0 {
+		t.Errorf("startup repairs %+v, want none", reports[0])
+	}
+	if got := loadStatus(t, id).LastStatusChangeAt; got == nil || !got.Equal(since) {
+		t.Errorf("last status change %v after the startup pass, want %s", got, since)
+	}
+	if _, err := s.RecomputeTarget(id); err != nil {
+		t.Fatalf("RecomputeTarget: %v", err)
+	}
+	if got := loadStatus(t, id).LastStatusChangeAt; got == nil || !got.Equal(since) {
+		t.Errorf("last status change %v after a recompute, want %s", got, since)
+	}
+
+	later := times[1]
+	database.GetDB().Model(&models.MonitorStatus{}).Where("target_id = ?", id).Update("last_status_change_at", later)
+	report, err := s.RecomputeTarget(id)
+	if err != nil {
+		t.Fatalf("RecomputeTarget: %v", err)
+	}
+	if got := loadStatus(t, id).LastStatusChangeAt; got == nil || !got.Equal(times[0]) || !slices.Contains(report.Actions, "recomputed last_status_change_at") {
+		t.Errorf("last status change %v, actions %v, want the oldest row %s", got, report.Actions, times[0])
+	}
+}
+
+// The startup pass fixes the status only; uptime is left to the periodic
+// pass and the recompute endpoint
+func TestRepairConsistencyLeavesUptime(t *testing.T) {
+	s := newTestService(t)
+	id, times := seedHistory(t, "down", "up")
+	since := times[1]
+	status := models.MonitorStatus{TargetID: id, Status: "down", CheckedAt: times[0], LastStatusChangeAt: &times[0], Uptime24h: 12}
+	if err := database.GetDB().Create(&status).Error; err != nil {
+		t.Fatalf("create status: %v", err)
+	}
+
+	reports, err := s.RepairConsistency()
+	if err != nil {
+		t.Fatalf("RepairConsistency: %v", err)
+	}
+	if len(reports) != 1 || !slices.Equal(reports[0].Actions, []string{"reset status to latest history entry", "recomputed last_status_change_at"}) {
+		t.Fatalf("startup repairs %+v, want the status reset", reports)
+	}
+	got := loadStatus(t, id)
+	if got.Status != "up" || got.LastStatusChangeAt == nil || !got.LastStatusChangeAt.Equal(since) || got.Uptime24h != 12 {
+		t.Errorf("status %+v, want up since %s with the uptime untouched", got, since)
+	}
+}
@@ -0,0 +1,92 @@
+package monitor
+
+import (
+	"context"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// historySweepBatch 每次删除的历史记录数，避免长时间锁表
+const historySweepBatch = 5000
+
+// HistoryRetention controls how long check history is kept in the database
+type HistoryRetention struct {
+	Retention time.Duration // rows checked before now - Retention are deleted; 0 keeps everything
+	Interval  time.Duration // time between two sweeps
+	// DeletableThrough returns the highest history ID that may be deleted,
+	// e.g. the end of the archived rows; nil allows every expired row
+	DeletableThrough func(ctx context.Context) (uint, error)
+}
+
+// StartHistoryRetention sweeps expired history now and then every Interval
+// until ctx is done. It does nothing when Retention is 0.
+func (s *Service) StartHistoryRetention(ctx context.Context, policy HistoryRetention) {
+	if policy.Retention <= 0 || policy.Interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := s.clock.NewTicker(policy.Interval)
+		defer ticker.Stop()
+		for {
+			if _, err := s.SweepHistory(ctx, policy); err != nil {
+				logger.Warn("Failed to delete expired monitor history", zap.Error(err))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+			}
+		}
+	}()
+}
+
+// SweepHistory deletes the history rows older than the retention, in batches,
+// and returns how many were deleted. Rows above DeletableThrough are kept
+// even if they are expired.
+func (s *Service) SweepHistory(ctx context.Context, policy HistoryRetention) (int64, error) {
+	if policy.Retention <= 0 {
+		return 0, nil
+	}
+	db := database.GetDB().WithContext(ctx)
+	cutoff := s.clock.Now().Add(-policy.Retention)
+
+	query := db.Model(&models.MonitorHistory{}).Where("checked_at < ?", cutoff)
+	if policy.DeletableThrough != nil {
+		maxID, err := policy.DeletableThrough(ctx)
+		if err != nil {
+			return 0, err
+		}
+		query = query.Where("id <= ?", maxID)
+	}
+
+	var deleted int64
+	for {
+		var ids []uint
+		if err := query.Session(&gorm.Session{}).Order("id").Limit(historySweepBatch).Pluck("id", &ids).Error; err != nil {
+			return deleted, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		result := db.Delete(&models.MonitorHistory{}, ids)
+		if result.Error != nil {
+			return deleted, result.Error
+		}
+		deleted += result.RowsAffected
+	}
+
+	if deleted > 0 {
+		s.heatmap.clear()
+		logger.Info("Expired monitor history deleted",
+			zap.Int64("rows", deleted),
+			zap.Time("cutoff", cutoff),
+		)
+	}
+	return deleted, nil
+}
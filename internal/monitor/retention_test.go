@@ -0,0 +1,88 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"monitor/internal/clock"
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+func countHistory(t *testing.T) int64 {
+	t.Helper()
+	var n int64
+	if err := database.GetDB().Model(&models.MonitorHistory{}).Count(&n).Error; err != nil {
+		t.Fatalf("count history: %v", err)
+	}
+	return n
+}
+
+func TestSweepHistoryDeletesInBatches(t *testing.T) {
+	s := newTestService(t)
+	s.SetClock(clock.NewFake(epoch))
+
+	rows := make([]models.MonitorHistory, 0, historySweepBatch+10)
+	for i := 0; i < historySweepBatch+5; i++ {
+		rows = append(rows, models.MonitorHistory{TargetID: 1, Status: "up", CheckedAt: epoch.AddDate(0, 0, -31)})
+	}
+	for i := 0; i < 5; i++ {
+		rows = append(rows, models.MonitorHistory{TargetID: 1, Status: "up", CheckedAt: epoch.AddDate(0, 0, -29)})
+	}
+	if err := database.GetDB().CreateInBatches(rows, 500).Error; err != nil {
+		t.Fatalf("create history: %v", err)
+	}
+
+	deleted, err := s.SweepHistory(context.Background(), HistoryRetention{Retention: 30 * 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("SweepHistory: %v", err)
+	}
+	if deleted != historySweepBatch+5 {
+		t.Errorf("deleted %d rows, want %d", deleted, historySweepBatch+5)
+	}
+	if left := countHistory(t); left != 5 {
+		t.Errorf("%d rows left, want the 5 within the retention", left)
+	}
+}
+
+func TestHistoryRetentionRunsOnInterval(t *testing.T) {
+	s := newTestService(t)
+	fake := clock.NewFake(epoch)
+	s.SetClock(fake)
+
+	// One row expired at the start, one expiring an hour later
+	rows := []models.MonitorHistory{
+		{TargetID: 1, Status: "up", CheckedAt: epoch.AddDate(0, 0, -31)},
+		{TargetID: 1, Status: "up", CheckedAt: epoch.AddDate(0, 0, -30).Add(time.Hour)},
+	}
+	if err := database.GetDB().Create(&rows).Error; err != nil {
+		t.Fatalf("create history: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.StartHistoryRetention(ctx, HistoryRetention{Retention: 30 * 24 * time.Hour, Interval: 24 * time.Hour})
+
+	// The first sweep runs at the start
+	waitFor(t, func() bool { return countHistory(t) == 1 })
+	waitFor(t, func() bool { return fake.Waiters() == 1 })
+	fake.Advance(23 * time.Hour)
+	if countHistory(t) != 1 {
+		t.Fatal("history deleted before the daily sweep")
+	}
+	fake.Advance(time.Hour)
+	waitFor(t, func() bool { return countHistory(t) == 0 })
+}
+
+// waitFor polls cond until it holds, for work done by another goroutine
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 5s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
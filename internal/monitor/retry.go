@@ -0,0 +1,76 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// MaxRetryCount caps the retries of a failed check
+const MaxRetryCount = 5
+
+// minRetryBudget is the least time left of the target's timeout for a retry
+// to be worth making
+const minRetryBudget = time.Second
+
+// ValidateRetries checks the retry settings of a target: at most
+// MaxRetryCount retries, spaced less than the check timeout apart so that at
+// least one of them fits in it. A timeout of 0 stands for the default.
+func ValidateRetries(retryCount, retryIntervalSeconds, timeoutSeconds int) error {
+	if retryCount < 0 || retryCount > MaxRetryCount {
+		return fmt.Errorf("retry_count must be between 0 and %d, got %d", MaxRetryCount, retryCount)
+	}
+	if retryIntervalSeconds < 0 {
+		return fmt.Errorf("retry_interval_seconds must not be negative, got %d", retryIntervalSeconds)
+	}
+	timeout := (&MonitorTarget{TimeoutSeconds: timeoutSeconds}).timeout()
+	if retryCount > 0 && time.Duration(retryIntervalSeconds)*time.Second+minRetryBudget > timeout {
+		return fmt.Errorf("retry_interval_seconds must be less than the check timeout (%s) minus %s, got %d",
+			timeout, minRetryBudget, retryIntervalSeconds)
+	}
+	return nil
+}
+
+// checkWithRetries runs a check and, while it comes back down, runs it again
+// up to RetryCount times, RetryIntervalSeconds apart. The attempts and the
+// waits between them share the target's timeout: a retry is only made while
+// at least minRetryBudget of it is left, and gets what is left as its own
+// deadline. Only the last result is returned; its message counts the attempts.
+func (s *Service) checkWithRetries(ctx context.Context, checker Checker, target *MonitorTarget) (*CheckResult, error) {
+	deadline := time.Now().Add(target.timeout())
+	result, err := s.runCheck(ctx, checker, target, target.timeout())
+
+	attempts := 1
+	interval := time.Duration(target.RetryIntervalSeconds) * time.Second
+	for ; attempts <= target.RetryCount; attempts++ {
+		if err != nil || result.Status != "down" {
+			break
+		}
+		budget := time.Until(deadline) - interval
+		if budget < minRetryBudget {
+			break
+		}
+		if interval > 0 {
+			timer := time.NewTimer(interval)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return result, err
+			case <-s.stopping:
+				timer.Stop()
+				return result, err
+			}
+		}
+		result, err = s.runCheck(ctx, checker, target, budget)
+	}
+
+	if err == nil && attempts > 1 {
+		result.Message = fmt.Sprintf("%s (%d attempts)", result.Message, attempts)
+		if result.Data == nil {
+			result.Data = make(map[string]interface{})
+		}
+		result.Data["attempts"] = attempts
+	}
+	return result, err
+}
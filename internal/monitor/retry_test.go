@@ -0,0 +1,88 @@
+package monitor
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestValidateRetries(t *testing.T) {
+	for _, tc := range []struct {
+		count, interval, timeout int
+		ok                       bool
+	}{
+		{0, 0, 0, true},
+		{MaxRetryCount, 5, 10, true},
+		{MaxRetryCount + 1, 0, 10, false},
+		{-1, 0, 10, false},
+		{1, -1, 10, false},
+		// The interval and a second of budget must fit in the timeout
+		{1, 9, 10, true},
+		{1, 10, 10, false},
+		// Without retries the interval does not matter
+		{0, 60, 10, true},
+	} {
+		if err := ValidateRetries(tc.count, tc.interval, tc.timeout); (err == nil) != tc.ok {
+			t.Errorf("ValidateRetries(%d, %d, %d) = %v, want ok %v", tc.count, tc.interval, tc.timeout, err, tc.ok)
+		}
+	}
+}
+
+// failingChecker is down for its first failures calls, then up
+func failingChecker(failures int32, calls *atomic.Int32) Checker {
+	return checkerFunc(func(ctx context.Context, target *MonitorTarget) (*CheckResult, error) {
+		if calls.Add(1) <= failures {
+			return &CheckResult{Status: "down", Message: "refused"}, nil
+		}
+		return &CheckResult{Status: "up", Message: "ok"}, nil
+	})
+}
+
+func TestCheckWithRetries(t *testing.T) {
+	s := &Service{stuck: newStuckChecks(), stopping: make(chan struct{})}
+	target := &MonitorTarget{ID: 1, Type: "tcp", RetryCount: 3, TimeoutSeconds: 10}
+
+	// Up on the third attempt
+	var calls atomic.Int32
+	result, err := s.checkWithRetries(context.Background(), failingChecker(2, &calls), target)
+	if err != nil || result.Status != "up" || calls.Load() != 3 || result.Data["attempts"] != 3 || result.Message != "ok (3 attempts)" {
+		t.Errorf("result %+v, %v after %d calls, want up on the third attempt", result, err, calls.Load())
+	}
+
+	// Down on every attempt: the last result is kept
+	calls.Store(0)
+	result, _ = s.checkWithRetries(context.Background(), failingChecker(10, &calls), target)
+	if result.Status != "down" || calls.Load() != 4 || !strings.HasSuffix(result.Message, "(4 attempts)") {
+		t.Errorf("result %+v after %d calls, want down after 4 attempts", result, calls.Load())
+	}
+
+	// Up the first time: no retry, and the message is left alone
+	calls.Store(0)
+	result, _ = s.checkWithRetries(context.Background(), failingChecker(0, &calls), target)
+	if calls.Load() != 1 || result.Message != "ok" || result.Data != nil {
+		t.Errorf("result %+v after %d calls, want a single attempt", result, calls.Load())
+	}
+
+	// No retry once less than a second of the timeout is left
+	calls.Store(0)
+	short := &MonitorTarget{ID: 2, Type: "tcp", RetryCount: 3, TimeoutSeconds: 1}
+	if result, _ = s.checkWithRetries(context.Background(), failingChecker(10, &calls), short); calls.Load() != 1 || result.Status != "down" {
+		t.Errorf("%d attempts with a one second timeout, want 1", calls.Load())
+	}
+}
+
+// Stopping the service ends the wait before a retry
+func TestCheckWithRetriesStops(t *testing.T) {
+	s := &Service{stuck: newStuckChecks(), stopping: make(chan struct{})}
+	target := &MonitorTarget{ID: 1, Type: "tcp", RetryCount: 1, RetryIntervalSeconds: 30, TimeoutSeconds: 60}
+	var calls atomic.Int32
+	time.AfterFunc(50*time.Millisecond, func() { close(s.stopping) })
+
+	start := time.Now()
+	result, _ := s.checkWithRetries(context.Background(), failingChecker(10, &calls), target)
+	if result.Status != "down" || calls.Load() != 1 || time.Since(start) > 5*time.Second {
+		t.Errorf("result %+v after %d calls in %s, want the first result without waiting", result, calls.Load(), time.Since(start))
+	}
+}
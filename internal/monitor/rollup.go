@@ -0,0 +1,434 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Granularities of a history series
+const (
+	GranularityAuto   = "auto"
+	GranularityRaw    = "raw"
+	GranularityHourly = "hourly"
+	GranularityDaily  = "daily"
+)
+
+// Longest range of each granularity. Auto picks raw up to AutoRawRange,
+// hourly up to AutoHourlyRange and daily beyond.
+const (
+	AutoRawRange    = 48 * time.Hour
+	AutoHourlyRange = 31 * 24 * time.Hour
+	MaxRawRange     = 7 * 24 * time.Hour
+	MaxHourlyRange  = HourlyRollupRetention
+	MaxSeriesRange  = 2 * 366 * 24 * time.Hour
+)
+
+// HourlyRollupRetention is how long hourly rollups are kept; daily ones are kept forever
+const HourlyRollupRetention = 90 * 24 * time.Hour
+
+// rollupLookback is redone on every run on top of the hours since the last
+// one, so that rows written late (batched history, a replayed spool) are counted
+const rollupLookback = time.Hour
+
+// maxRollupBackfill bounds the history aggregated by the first run
+const maxRollupBackfill = HourlyRollupRetention
+
+// rollupState is where the next rollup run starts
+type rollupState struct {
+	mu   sync.Mutex
+	from time.Time // zero until the first run
+}
+
+// stale moves the next run back to the hour of t, e.g. for replayed history
+func (r *rollupState) stale(t time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	hour := t.UTC().Truncate(time.Hour)
+	if !r.from.IsZero() && hour.Before(r.from) {
+		r.from = hour
+	}
+}
+
+// StartHistoryRollup aggregates the history into the hourly and daily rollups
+// now and then every interval until ctx is done
+func (s *Service) StartHistoryRollup(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := s.clock.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := s.RollupHistory(ctx); err != nil {
+				logger.Warn("Failed to roll up monitor history", zap.Error(err))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+			}
+		}
+	}()
+}
+
+// RollupHistory recomputes the hourly rollups from the hour of the last run
+// (less rollupLookback) through the current hour, then the daily rollups of
+// the days they fall in, and deletes the hourly rollups past their retention.
+// A bucket is always recomputed whole and replaces the stored one, so running
+// it again, or over a bucket that was still filling up, does not count twice.
+func (s *Service) RollupHistory(ctx context.Context) error {
+	db := database.GetDB().WithContext(ctx)
+	now := s.clock.Now().UTC()
+	current := now.Truncate(time.Hour)
+
+	s.rollup.mu.Lock()
+	from := s.rollup.from
+	s.rollup.mu.Unlock()
+	if from.IsZero() {
+		var err error
+		if from, err = firstRollupHour(db, now); err != nil {
+			return err
+		}
+	}
+	if from.After(current) {
+		from = current
+	}
+
+	// Results still buffered belong to the current hour
+	s.flushHistory()
+
+	hours := 0
+	for hour := from; !hour.After(current); hour = hour.Add(time.Hour) {
+		if err := s.rollupHour(db, hour); err != nil {
+			return fmt.Errorf("rollup of %s: %w", hour.Format(time.RFC3339), err)
+		}
+		hours++
+	}
+	days := 0
+	for day := utcDay(from); !day.After(current); day = day.AddDate(0, 0, 1) {
+		if err := rollupDay(db, day); err != nil {
+			return fmt.Errorf("rollup of %s: %w", day.Format(time.DateOnly), err)
+		}
+		days++
+	}
+
+	cutoff := now.Add(-HourlyRollupRetention)
+	if err := db.Where("bucket_start < ?", cutoff).Delete(&models.MonitorHistoryHourly{}).Error; err != nil {
+		return err
+	}
+
+	s.rollup.mu.Lock()
+	// Unless a stale call moved it back meanwhile
+	if s.rollup.from.IsZero() || !s.rollup.from.Before(from) {
+		s.rollup.from = current.Add(-rollupLookback)
+	}
+	s.rollup.mu.Unlock()
+
+	logger.Debug("Monitor history rolled up", zap.Int("hours", hours), zap.Int("days", days))
+	return nil
+}
+
+// firstRollupHour is where the first run starts: after the last hourly
+// rollup, or at the oldest history within maxRollupBackfill
+func firstRollupHour(db *gorm.DB, now time.Time) (time.Time, error) {
+	var last models.MonitorHistoryHourly
+	err := db.Order("bucket_start DESC").Limit(1).Take(&last).Error
+	if err == nil {
+		return last.BucketStart.UTC().Add(-rollupLookback), nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, err
+	}
+
+	earliest := now.Add(-maxRollupBackfill)
+	var oldest models.MonitorHistory
+	err = db.Select("checked_at").Where("checked_at >= ?", earliest).Order("checked_at").Limit(1).Take(&oldest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return now.Truncate(time.Hour), nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return oldest.CheckedAt.UTC().Truncate(time.Hour), nil
+}
+
+// rollupBucket accumulates the results of one target in one bucket
+type rollupBucket struct {
+	models.HistoryRollup
+	times []int64
+}
+
+// add counts one result in the bucket
+func (b *rollupBucket) add(status string, responseTime int64) {
+	b.Checks++
+	switch status {
+	case "up":
+		b.Up++
+	case "warning", "degraded":
+		b.Degraded++
+	case "down", "critical":
+		b.Down++
+	}
+	switch status {
+	case "up", "warning", "degraded":
+		b.times = append(b.times, responseTime)
+	}
+}
+
+// finish computes the response time statistics of the bucket
+func (b *rollupBucket) finish() models.HistoryRollup {
+	r := b.HistoryRollup
+	r.Samples = int64(len(b.times))
+	if len(b.times) == 0 {
+		return r
+	}
+	sort.Slice(b.times, func(i, j int) bool { return b.times[i] < b.times[j] })
+	var sum int64
+	for _, t := range b.times {
+		sum += t
+	}
+	avg := float64(sum) / float64(len(b.times))
+	lowest, highest := b.times[0], b.times[len(b.times)-1]
+	p50, p95, p99 := nearestRank(b.times, 50), nearestRank(b.times, 95), nearestRank(b.times, 99)
+	r.AvgResponseTime, r.MinResponseTime, r.MaxResponseTime = &avg, &lowest, &highest
+	r.P50ResponseTime, r.P95ResponseTime, r.P99ResponseTime = &p50, &p95, &p99
+	return r
+}
+
+// rollupHour recomputes the hourly rollups of every target for the hour
+// starting at hour, replacing the stored ones in one transaction
+func (s *Service) rollupHour(db *gorm.DB, hour time.Time) error {
+	query := db.Model(&models.MonitorHistory{}).Select("target_id", "status", "response_time").
+		Where("checked_at >= ? AND checked_at < ?", hour, hour.Add(time.Hour))
+	if !s.includeSyntheticUptime {
+		query = query.Where("synthetic = ?", false)
+	}
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	buckets := make(map[uint32]*rollupBucket)
+	for rows.Next() {
+		var (
+			targetID     uint32
+			status       string
+			responseTime sql.NullInt64
+		)
+		if err := rows.Scan(&targetID, &status, &responseTime); err != nil {
+			rows.Close()
+			return err
+		}
+		b, ok := buckets[targetID]
+		if !ok {
+			b = &rollupBucket{HistoryRollup: models.HistoryRollup{TargetID: targetID, BucketStart: hour}}
+			buckets[targetID] = b
+		}
+		b.add(status, responseTime.Int64)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	hourly := make([]models.MonitorHistoryHourly, 0, len(buckets))
+	for _, b := range buckets {
+		hourly = append(hourly, models.MonitorHistoryHourly{HistoryRollup: b.finish()})
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("bucket_start = ?", hour).Delete(&models.MonitorHistoryHourly{}).Error; err != nil {
+			return err
+		}
+		if len(hourly) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(hourly, 500).Error
+	})
+}
+
+// rollupDay recomputes the daily rollups of the UTC day starting at day from
+// its hourly rollups. The counts, average, minimum and maximum are exact; each
+// percentile is the highest hourly one, an upper bound of the day's.
+func rollupDay(db *gorm.DB, day time.Time) error {
+	var rows []struct {
+		TargetID        uint32
+		Checks          int64
+		Up              int64
+		Down            int64
+		Degraded        int64
+		Samples         int64
+		AvgResponseTime *float64
+		MinResponseTime *int64
+		MaxResponseTime *int64
+		P50ResponseTime *int64
+		P95ResponseTime *int64
+		P99ResponseTime *int64
+	}
+	err := db.Model(&models.MonitorHistoryHourly{}).
+		Select(`target_id, SUM(checks) AS checks, SUM(up) AS up, SUM(down) AS down, SUM(degraded) AS degraded,
+			SUM(samples) AS samples, SUM(avg_response_time * samples) / NULLIF(SUM(samples), 0) AS avg_response_time,
+			MIN(min_response_time) AS min_response_time, MAX(max_response_time) AS max_response_time,
+			MAX(p50_response_time) AS p50_response_time, MAX(p95_response_time) AS p95_response_time,
+			MAX(p99_response_time) AS p99_response_time`).
+		Where("bucket_start >= ? AND bucket_start < ?", day, day.AddDate(0, 0, 1)).
+		Group("target_id").Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	daily := make([]models.MonitorHistoryDaily, 0, len(rows))
+	for _, row := range rows {
+		daily = append(daily, models.MonitorHistoryDaily{HistoryRollup: models.HistoryRollup{
+			TargetID:        row.TargetID,
+			BucketStart:     day,
+			Checks:          row.Checks,
+			Up:              row.Up,
+			Down:            row.Down,
+			Degraded:        row.Degraded,
+			Samples:         row.Samples,
+			AvgResponseTime: row.AvgResponseTime,
+			MinResponseTime: row.MinResponseTime,
+			MaxResponseTime: row.MaxResponseTime,
+			P50ResponseTime: row.P50ResponseTime,
+			P95ResponseTime: row.P95ResponseTime,
+			P99ResponseTime: row.P99ResponseTime,
+		}})
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("bucket_start = ?", day).Delete(&models.MonitorHistoryDaily{}).Error; err != nil {
+			return err
+		}
+		if len(daily) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(daily, 500).Error
+	})
+}
+
+func utcDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// SeriesPoint is one point of a history series: a single result for raw
+// series, a rollup bucket otherwise
+type SeriesPoint struct {
+	Time            time.Time `json:"time"`
+	Status          string    `json:"status,omitempty"` // raw series only
+	Checks          int64     `json:"checks"`
+	Up              int64     `json:"up"`
+	Down            int64     `json:"down"`
+	Degraded        int64     `json:"degraded"`
+	Samples         int64     `json:"samples"` // results with a response time
+	AvgResponseTime *float64  `json:"avg_response_time"`
+	MinResponseTime *int64    `json:"min_response_time"`
+	MaxResponseTime *int64    `json:"max_response_time"`
+	P50ResponseTime *int64    `json:"p50_response_time"`
+	P95ResponseTime *int64    `json:"p95_response_time"`
+	P99ResponseTime *int64    `json:"p99_response_time"`
+}
+
+func newSeriesPoint(r models.HistoryRollup) SeriesPoint {
+	return SeriesPoint{
+		Time: r.BucketStart, Checks: r.Checks, Up: r.Up, Down: r.Down, Degraded: r.Degraded, Samples: r.Samples,
+		AvgResponseTime: r.AvgResponseTime, MinResponseTime: r.MinResponseTime, MaxResponseTime: r.MaxResponseTime,
+		P50ResponseTime: r.P50ResponseTime, P95ResponseTime: r.P95ResponseTime, P99ResponseTime: r.P99ResponseTime,
+	}
+}
+
+// SeriesGranularity checks the range of a series and resolves auto (or "")
+// to the granularity for its length
+func SeriesGranularity(granularity string, from, to time.Time) (string, error) {
+	span := to.Sub(from)
+	if span <= 0 {
+		return "", fmt.Errorf("from must be before to")
+	}
+	if span > MaxSeriesRange {
+		return "", fmt.Errorf("the range must be at most %d days", int(MaxSeriesRange/(24*time.Hour)))
+	}
+	switch granularity {
+	case "", GranularityAuto:
+		switch {
+		case span <= AutoRawRange:
+			return GranularityRaw, nil
+		case span <= AutoHourlyRange:
+			return GranularityHourly, nil
+		}
+		return GranularityDaily, nil
+	case GranularityRaw:
+		if span > MaxRawRange {
+			return "", fmt.Errorf("raw series cover at most %d days, use hourly or daily", int(MaxRawRange/(24*time.Hour)))
+		}
+	case GranularityHourly:
+		if span > MaxHourlyRange {
+			return "", fmt.Errorf("hourly series cover at most %d days, use daily", int(MaxHourlyRange/(24*time.Hour)))
+		}
+	case GranularityDaily:
+	default:
+		return "", fmt.Errorf("granularity must be auto, raw, hourly or daily, got %q", granularity)
+	}
+	return granularity, nil
+}
+
+// HistorySeries returns the history of a target between from and to, oldest
+// first, at a granularity resolved by SeriesGranularity. Rollup buckets are
+// included from the one containing from. Synthetic results are left out
+// unless they count towards uptime.
+func (s *Service) HistorySeries(ctx context.Context, targetID uint32, from, to time.Time, granularity string) ([]SeriesPoint, error) {
+	db := database.GetDB().WithContext(ctx)
+	var target models.MonitorTarget
+	if err := db.Select("id").First(&target, targetID).Error; err != nil {
+		return nil, err
+	}
+
+	points := []SeriesPoint{}
+	switch granularity {
+	case GranularityRaw:
+		query := db.Model(&models.MonitorHistory{}).Select("status", "response_time", "checked_at").
+			Where("target_id = ? AND checked_at >= ? AND checked_at < ?", targetID, from, to)
+		if !s.includeSyntheticUptime {
+			query = query.Where("synthetic = ?", false)
+		}
+		var history []models.MonitorHistory
+		if err := query.Order("checked_at").Find(&history).Error; err != nil {
+			return nil, err
+		}
+		for _, h := range history {
+			b := rollupBucket{}
+			b.add(h.Status, h.ResponseTime)
+			r := b.finish()
+			point := newSeriesPoint(r)
+			point.Time, point.Status = h.CheckedAt, h.Status
+			points = append(points, point)
+		}
+		return points, nil
+	case GranularityHourly, GranularityDaily:
+	default:
+		return nil, fmt.Errorf("unknown granularity %q", granularity)
+	}
+
+	start := from.UTC().Truncate(time.Hour)
+	var model interface{} = &models.MonitorHistoryHourly{}
+	if granularity == GranularityDaily {
+		start, model = utcDay(from), &models.MonitorHistoryDaily{}
+	}
+	var rollups []models.HistoryRollup
+	if err := db.Model(model).Where("target_id = ? AND bucket_start >= ? AND bucket_start < ?", targetID, start, to).
+		Order("bucket_start").Find(&rollups).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rollups {
+		points = append(points, newSeriesPoint(r))
+	}
+	return points, nil
+}
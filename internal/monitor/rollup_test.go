@@ -0,0 +1,144 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"monitor/internal/clock"
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+func addHistory(t *testing.T, targetID uint32, status string, responseTime int64, checkedAt time.Time) {
+	t.Helper()
+	row := models.MonitorHistory{TargetID: targetID, Status: status, ResponseTime: responseTime, CheckedAt: checkedAt}
+	if err := database.GetDB().Create(&row).Error; err != nil {
+		t.Fatalf("create history: %v", err)
+	}
+}
+
+func rollupAt(t *testing.T, model interface{}, targetID uint32, bucket time.Time) models.HistoryRollup {
+	t.Helper()
+	var r models.HistoryRollup
+	if err := database.GetDB().Model(model).Where("target_id = ? AND bucket_start = ?", targetID, bucket).Take(&r).Error; err != nil {
+		t.Fatalf("rollup of %s: %v", bucket, err)
+	}
+	return r
+}
+
+func TestRollupHistory(t *testing.T) {
+	s := newTestService(t)
+	fake := clock.NewFake(epoch)
+	s.SetClock(fake)
+	db := database.GetDB()
+	ten := epoch.Add(-2 * time.Hour)
+	eleven := epoch.Add(-time.Hour)
+	yesterday := epoch.Add(-13 * time.Hour)
+
+	addHistory(t, 1, "up", 100, ten.Add(time.Minute))
+	addHistory(t, 1, "up", 300, ten.Add(2*time.Minute))
+	addHistory(t, 1, "degraded", 200, ten.Add(3*time.Minute))
+	addHistory(t, 1, "down", 5000, ten.Add(4*time.Minute))
+	addHistory(t, 1, "up", 50, eleven.Add(time.Minute))
+	addHistory(t, 1, "up", 10, yesterday)
+	// Synthetic results are left out
+	db.Create(&models.MonitorHistory{TargetID: 1, Status: "down", CheckedAt: ten, Synthetic: true})
+
+	for run := 0; run < 2; run++ {
+		if err := s.RollupHistory(context.Background()); err != nil {
+			t.Fatalf("RollupHistory: %v", err)
+		}
+		hour := rollupAt(t, &models.MonitorHistoryHourly{}, 1, ten)
+		if hour.Checks != 4 || hour.Up != 2 || hour.Degraded != 1 || hour.Down != 1 || hour.Samples != 3 ||
+			*hour.AvgResponseTime != 200 || *hour.MinResponseTime != 100 || *hour.MaxResponseTime != 300 || *hour.P95ResponseTime != 300 {
+			t.Errorf("run %d: hour %+v", run, hour)
+		}
+		day := rollupAt(t, &models.MonitorHistoryDaily{}, 1, utcDay(epoch))
+		if day.Checks != 5 || day.Up != 3 || day.Samples != 4 || *day.AvgResponseTime != 162.5 || *day.MinResponseTime != 50 || *day.MaxResponseTime != 300 {
+			t.Errorf("run %d: day %+v", run, day)
+		}
+		if day := rollupAt(t, &models.MonitorHistoryDaily{}, 1, utcDay(yesterday)); day.Checks != 1 {
+			t.Errorf("run %d: first run did not backfill the day before: %+v", run, day)
+		}
+	}
+
+	// A row written late to the previous hour is counted on the next run
+	fake.Advance(10 * time.Minute)
+	addHistory(t, 1, "down", 0, eleven.Add(59*time.Minute))
+	if err := s.RollupHistory(context.Background()); err != nil {
+		t.Fatalf("RollupHistory: %v", err)
+	}
+	if hour := rollupAt(t, &models.MonitorHistoryHourly{}, 1, eleven); hour.Checks != 2 || hour.Down != 1 {
+		t.Errorf("late row not counted: %+v", hour)
+	}
+	if day := rollupAt(t, &models.MonitorHistoryDaily{}, 1, utcDay(epoch)); day.Checks != 6 {
+		t.Errorf("day %+v, want 6 checks", day)
+	}
+
+	// Replayed history moves the next run back to its hour
+	s.rollup.stale(ten)
+	addHistory(t, 1, "up", 100, ten.Add(30*time.Minute))
+	if err := s.RollupHistory(context.Background()); err != nil {
+		t.Fatalf("RollupHistory: %v", err)
+	}
+	if hour := rollupAt(t, &models.MonitorHistoryHourly{}, 1, ten); hour.Checks != 5 {
+		t.Errorf("replayed row not counted: %+v", hour)
+	}
+}
+
+func TestSeriesGranularity(t *testing.T) {
+	for _, tc := range []struct {
+		granularity string
+		span        time.Duration
+		want        string
+	}{
+		{"", time.Hour, GranularityRaw},
+		{GranularityAuto, AutoRawRange, GranularityRaw},
+		{GranularityAuto, AutoRawRange + time.Hour, GranularityHourly},
+		{"", AutoHourlyRange + time.Hour, GranularityDaily},
+		{GranularityDaily, time.Hour, GranularityDaily},
+		{GranularityRaw, MaxRawRange + time.Hour, ""},
+		{GranularityHourly, MaxHourlyRange + time.Hour, ""},
+		{GranularityDaily, MaxSeriesRange + time.Hour, ""},
+		{"minutely", time.Hour, ""},
+		{"", 0, ""},
+		{"", -time.Hour, ""},
+	} {
+		got, err := SeriesGranularity(tc.granularity, epoch, epoch.Add(tc.span))
+		if got != tc.want || (err != nil) != (tc.want == "") {
+			t.Errorf("SeriesGranularity(%q, %v) = %q, %v, want %q", tc.granularity, tc.span, got, err, tc.want)
+		}
+	}
+}
+
+func TestHistorySeries(t *testing.T) {
+	s := newTestService(t)
+	s.SetClock(clock.NewFake(epoch))
+	createTargets(t, 60)
+	addHistory(t, 1, "up", 100, epoch.Add(-90*time.Minute))
+	addHistory(t, 1, "down", 0, epoch.Add(-80*time.Minute))
+	addHistory(t, 1, "up", 40, epoch.Add(-10*time.Minute))
+	if err := s.RollupHistory(context.Background()); err != nil {
+		t.Fatalf("RollupHistory: %v", err)
+	}
+	ctx := context.Background()
+
+	raw, err := s.HistorySeries(ctx, 1, epoch.Add(-85*time.Minute), epoch, GranularityRaw)
+	if err != nil || len(raw) != 2 || raw[0].Status != "down" || raw[0].Samples != 0 || raw[1].Status != "up" || *raw[1].AvgResponseTime != 40 {
+		t.Errorf("raw series %+v, %v", raw, err)
+	}
+	// The bucket containing from is included
+	hourly, err := s.HistorySeries(ctx, 1, epoch.Add(-85*time.Minute), epoch, GranularityHourly)
+	if err != nil || len(hourly) != 2 || !hourly[0].Time.Equal(epoch.Add(-2*time.Hour)) || hourly[0].Checks != 2 || hourly[1].Checks != 1 {
+		t.Errorf("hourly series %+v, %v", hourly, err)
+	}
+	daily, err := s.HistorySeries(ctx, 1, epoch.Add(-time.Hour), epoch, GranularityDaily)
+	if err != nil || len(daily) != 1 || daily[0].Checks != 3 || *daily[0].MaxResponseTime != 100 {
+		t.Errorf("daily series %+v, %v", daily, err)
+	}
+
+	if _, err := s.HistorySeries(ctx, 2, epoch.Add(-time.Hour), epoch, GranularityRaw); err == nil {
+		t.Error("series of an unknown target")
+	}
+}
@@ -0,0 +1,162 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"monitor/internal/logger"
+	"monitor/internal/spool"
+
+	"go.uber.org/zap"
+)
+
+// memoryRecoveredRatio 内存回落到阈值的这个比例以下才发送恢复通知，避免在阈值附近反复通知
+const memoryRecoveredRatio = 0.9
+
+// RuntimeStats is the memory and goroutine usage of the process together
+// with the size of the service's in-memory buffers
+type RuntimeStats struct {
+	Goroutines    int           `json:"goroutines"`
+	HeapInUse     uint64        `json:"heap_in_use_bytes"`
+	HeapObjects   uint64        `json:"heap_objects"`
+	RuntimeMemory uint64        `json:"runtime_memory_bytes"`         // memory held by the Go runtime, what the soft limit applies to
+	MemoryLimit   int64         `json:"memory_limit_bytes,omitempty"` // soft limit, omitted when not set
+	NumGC         uint32        `json:"num_gc"`
+	Buffers       []BufferStats `json:"buffers"`
+	Spools        []spool.Stats `json:"spools,omitempty"` // results waiting on disk for the history or ES, see StartSpool
+}
+
+// BufferStats is the size of an in-memory structure against its cap
+type BufferStats struct {
+	Name string `json:"name"`
+	Len  int    `json:"len"`
+	Cap  int    `json:"cap"` // 0 when bounded only by another cap, e.g. targets by the quota, or by the code
+}
+
+// readRuntimeMemory returns the memory stats and the memory held by the runtime
+func readRuntimeMemory() (runtime.MemStats, uint64) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return mem, mem.Sys - mem.HeapReleased
+}
+
+// RuntimeStats returns the current usage; it briefly stops the world to read
+// the memory stats
+func (s *Service) RuntimeStats() RuntimeStats {
+	mem, held := readRuntimeMemory()
+	stats := RuntimeStats{
+		Goroutines:    runtime.NumGoroutine(),
+		HeapInUse:     mem.HeapInuse,
+		HeapObjects:   mem.HeapObjects,
+		RuntimeMemory: held,
+		NumGC:         mem.NumGC,
+	}
+	if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 {
+		stats.MemoryLimit = limit
+	}
+
+	jobs, subscribers, queued := s.checkJobs.sizes()
+	statusSubscribers, statusQueued := s.statusEvents.sizes()
+	s.mu.RLock()
+	targets, configErrors := len(s.targets), len(s.configErrors)
+	maxTargets := s.limits.MaxTargets
+	s.mu.RUnlock()
+	if maxTargets < 0 {
+		maxTargets = 0
+	}
+	historyPending, historyCap := s.history.size()
+
+	stats.Buffers = []BufferStats{
+		{Name: "check_queue", Len: len(s.checkQueue), Cap: cap(s.checkQueue)},
+		{Name: "es_buffer", Len: len(s.esBuffer), Cap: cap(s.esBuffer)},
+		{Name: "history_buffer", Len: historyPending, Cap: historyCap},
+		{Name: "targets", Len: targets, Cap: maxTargets},
+		{Name: "config_errors", Len: configErrors, Cap: maxTargets},
+		{Name: "check_jobs", Len: jobs, Cap: MaxCheckJobs},
+		{Name: "check_event_streams", Len: subscribers, Cap: MaxCheckJobSubscribers},
+		{Name: "check_event_queues", Len: queued, Cap: subscribers * checkJobQueueSize},
+		{Name: "status_event_subscribers", Len: statusSubscribers},
+		{Name: "status_event_queues", Len: statusQueued, Cap: statusSubscribers * statusEventQueueSize},
+		{Name: "certificate_cache", Len: s.certificates.size(), Cap: MaxCertificateCacheEntries},
+	}
+	stats.Spools = s.SpoolStats()
+	return stats
+}
+
+// WorkerStats is the load of the worker pool. A queue that stays close to
+// QueueSize with every worker busy means the pool is saturated and scheduled
+// checks are being skipped.
+type WorkerStats struct {
+	Workers       int `json:"workers"`
+	BusyWorkers   int `json:"busy_workers"`
+	QueueDepth    int `json:"queue_depth"`
+	QueueSize     int `json:"queue_size"`
+	ESBufferDepth int `json:"es_buffer_depth"`
+	ESBufferSize  int `json:"es_buffer_size"`
+}
+
+// Stats returns the current load of the worker pool and the ES write buffer
+func (s *Service) Stats() WorkerStats {
+	return WorkerStats{
+		Workers:       int(s.workerPool),
+		BusyWorkers:   int(s.busyWorkers.Load()),
+		QueueDepth:    len(s.checkQueue),
+		QueueSize:     cap(s.checkQueue),
+		ESBufferDepth: len(s.esBuffer),
+		ESBufferSize:  cap(s.esBuffer),
+	}
+}
+
+// MemoryPolicy notifies the operators when the memory held by the runtime
+// exceeds Threshold, and again once it is back below 90% of it
+type MemoryPolicy struct {
+	Threshold uint64 // bytes; 0 disables the watch
+	Interval  time.Duration
+}
+
+// StartMemoryWatch samples the memory every Interval until ctx is done and
+// calls notify when it crosses the threshold. notify may be nil, the
+// crossings are logged either way.
+func (s *Service) StartMemoryWatch(ctx context.Context, policy MemoryPolicy, notify func(title, message string)) {
+	if policy.Threshold == 0 || policy.Interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := s.clock.NewTicker(policy.Interval)
+		defer ticker.Stop()
+		over := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+			}
+
+			_, held := readRuntimeMemory()
+			switch {
+			case !over && held > policy.Threshold:
+				over = true
+				message := fmt.Sprintf("Go 运行时占用内存 %d MB，超过阈值 %d MB；当前 goroutine 数 %d。各缓冲区的大小见 GET /api/v1/stats/runtime",
+					held>>20, policy.Threshold>>20, runtime.NumGoroutine())
+				logger.Warn("Memory usage exceeds the alert threshold",
+					zap.Uint64("runtime_memory_bytes", held),
+					zap.Uint64("threshold_bytes", policy.Threshold))
+				if notify != nil {
+					notify("监控服务内存占用过高", message)
+				}
+			case over && float64(held) < float64(policy.Threshold)*memoryRecoveredRatio:
+				over = false
+				logger.Info("Memory usage is back below the alert threshold",
+					zap.Uint64("runtime_memory_bytes", held),
+					zap.Uint64("threshold_bytes", policy.Threshold))
+				if notify != nil {
+					notify("监控服务内存占用已恢复", fmt.Sprintf("Go 运行时占用内存 %d MB，已低于阈值 %d MB", held>>20, policy.Threshold>>20))
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,115 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"monitor/internal/database"
+)
+
+// slowServer answers once released and records how many requests it held
+// at once
+type slowServer struct {
+	mu                sync.Mutex
+	inFlight, maxSeen int
+	served            int
+	release           chan struct{}
+}
+
+func (s *slowServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.inFlight++
+	s.maxSeen = max(s.maxSeen, s.inFlight)
+	s.mu.Unlock()
+	<-s.release
+	s.mu.Lock()
+	s.inFlight--
+	s.served++
+	s.mu.Unlock()
+}
+
+func (s *slowServer) counts() (inFlight, maxSeen, served int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inFlight, s.maxSeen, s.served
+}
+
+// ServiceOptions.Workers caps the checks in flight: the other queued checks
+// wait in the queue, and Stats reports both
+func TestWorkersCapChecksInFlight(t *testing.T) {
+	for _, workers := range []int{1, 3} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			if err := database.InitMemoryDB(t.Name()); err != nil {
+				t.Fatalf("InitMemoryDB: %v", err)
+			}
+			t.Cleanup(func() {
+				if sqlDB, err := database.GetDB().DB(); err == nil {
+					sqlDB.Close()
+				}
+			})
+			s := NewService(nil, ServiceOptions{Workers: workers, QueueSize: 20, StartupJitter: time.Hour})
+			t.Cleanup(func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				s.Stop(ctx)
+			})
+
+			backend := &slowServer{release: make(chan struct{})}
+			srv := httptest.NewServer(backend)
+			t.Cleanup(srv.Close)
+			released := false
+			t.Cleanup(func() {
+				if !released {
+					close(backend.release)
+				}
+			})
+
+			const checks = 10
+			var jobs []CheckJob
+			for id := uint32(1); id <= checks; id++ {
+				if err := s.AddTarget(&MonitorTarget{ID: id, Name: "slow", Type: "http", Address: srv.URL, Interval: 60, TimeoutSeconds: 10}); err != nil {
+					t.Fatalf("AddTarget: %v", err)
+				}
+				job, err := s.TriggerCheck(id)
+				if err != nil {
+					t.Fatalf("TriggerCheck: %v", err)
+				}
+				jobs = append(jobs, job)
+			}
+
+			waitFor(t, func() bool {
+				inFlight, _, _ := backend.counts()
+				return inFlight == workers && s.Stats().BusyWorkers == workers
+			})
+			// No further check starts while the workers are busy
+			time.Sleep(50 * time.Millisecond)
+			stats := s.Stats()
+			if inFlight, _, _ := backend.counts(); inFlight != workers {
+				t.Errorf("%d checks in flight, want %d", inFlight, workers)
+			}
+			if stats.Workers != workers || stats.BusyWorkers != workers || stats.QueueDepth != checks-workers || stats.QueueSize != 20 {
+				t.Errorf("Stats = %+v, want %d busy and %d queued", stats, workers, checks-workers)
+			}
+
+			close(backend.release)
+			released = true
+			for _, job := range jobs {
+				waitFor(t, func() bool {
+					got, ok := s.GetCheckJob(job.Token)
+					return ok && got.State == CheckJobFinished
+				})
+			}
+			if _, maxSeen, served := backend.counts(); maxSeen != workers || served != checks {
+				t.Errorf("%d checks served, at most %d at once, want %d and %d", served, maxSeen, checks, workers)
+			}
+			if stats := s.Stats(); stats.BusyWorkers != 0 || stats.QueueDepth != 0 {
+				t.Errorf("Stats after the checks = %+v", stats)
+			}
+		})
+	}
+}
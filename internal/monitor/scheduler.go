@@ -0,0 +1,204 @@
+package monitor
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"monitor/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// schedulerRetryDelay is how soon the scheduler tries again to queue the
+// checks that are due while the check queue is full
+const schedulerRetryDelay = 100 * time.Millisecond
+
+// noFirstCheck makes a new target wait a full interval for its first check
+const noFirstCheck time.Duration = -1
+
+// scheduleEntry is a scheduled target and when its next check is due
+type scheduleEntry struct {
+	target *MonitorTarget
+	next   time.Time
+	index  int // position in the heap
+}
+
+// scheduleHeap orders the entries by next check, earliest first
+type scheduleHeap []*scheduleEntry
+
+func (h scheduleHeap) Len() int           { return len(h) }
+func (h scheduleHeap) Less(i, j int) bool { return h[i].next.Before(h[j].next) }
+func (h scheduleHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *scheduleHeap) Push(x any) {
+	e := x.(*scheduleEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *scheduleHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
+
+// checkSchedule is the next check of every scheduled target. A single goroutine,
+// started with the first target, queues the checks as they come due. The
+// entries are guarded by Service.mu.
+type checkSchedule struct {
+	entries map[uint32]*scheduleEntry
+	due     scheduleHeap
+	// Signalled when an entry is added, moved or removed
+	wake  chan struct{}
+	start sync.Once
+	// Set while due checks wait for room in the check queue
+	queueFull bool
+}
+
+func newCheckSchedule() *checkSchedule {
+	return &checkSchedule{
+		entries: make(map[uint32]*scheduleEntry),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+func (sc *checkSchedule) notify() {
+	select {
+	case sc.wake <- struct{}{}:
+	default:
+	}
+}
+
+// interval returns the time between two scheduled checks of the target
+func (t *MonitorTarget) interval() time.Duration {
+	if t.Interval <= 0 {
+		return DefaultInterval * time.Second
+	}
+	return time.Duration(t.Interval) * time.Second
+}
+
+// scheduleTarget schedules a target, or updates the schedule of the one
+// already scheduled under its ID. A new target is first checked after
+// firstCheck, or after a full interval for noFirstCheck. An updated target
+// keeps its schedule; a new interval counts from its previous check and a
+// check it makes overdue is queued right away. Called with mu held.
+func (s *Service) scheduleTarget(target *MonitorTarget, firstCheck time.Duration) {
+	sc := s.schedule
+	sc.start.Do(func() {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.runScheduler()
+		}()
+	})
+
+	now := s.clock.Now()
+	if e, ok := sc.entries[target.ID]; ok {
+		if previous := e.target.interval(); previous != target.interval() {
+			e.next = e.next.Add(target.interval() - previous)
+			if e.next.Before(now) {
+				e.next = now
+			}
+			heap.Fix(&sc.due, e.index)
+		}
+		e.target = target
+		sc.notify()
+		return
+	}
+
+	if firstCheck < 0 {
+		firstCheck = target.interval()
+	}
+	e := &scheduleEntry{target: target, next: now.Add(firstCheck)}
+	sc.entries[target.ID] = e
+	heap.Push(&sc.due, e)
+	sc.notify()
+}
+
+// unscheduleTarget removes a target from the schedule, if it is in it.
+// Called with mu held.
+func (s *Service) unscheduleTarget(id uint32) {
+	sc := s.schedule
+	if e, ok := sc.entries[id]; ok {
+		heap.Remove(&sc.due, e.index)
+		delete(sc.entries, id)
+		sc.notify()
+	}
+}
+
+// runScheduler queues the checks as they come due until Stop
+func (s *Service) runScheduler() {
+	timer := s.clock.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		// nil while nothing is scheduled, a receive from it blocks forever
+		var fire <-chan time.Time
+		if wait, ok := s.queueDueChecks(); ok {
+			timer.Stop()
+			timer.Reset(wait)
+			fire = timer.C()
+		}
+
+		select {
+		case <-s.stopping:
+			return
+		case <-s.ctx.Done():
+			return
+		case <-s.schedule.wake:
+		case <-fire:
+		}
+	}
+}
+
+// queueDueChecks queues the checks that are due, earliest first, and moves
+// each target to its next check: one interval later, or one interval from
+// now when it is more than an interval late. While the check queue is full
+// the due checks wait for room instead of being skipped. It returns how long
+// until the next check is due, false when nothing is scheduled.
+func (s *Service) queueDueChecks() (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc := s.schedule
+
+	now := s.clock.Now()
+	for len(sc.due) > 0 {
+		e := sc.due[0]
+		if e.next.After(now) {
+			return e.next.Sub(now), true
+		}
+
+		select {
+		case s.checkQueue <- checkTask{target: e.target}:
+		default:
+			if !sc.queueFull {
+				sc.queueFull = true
+				logger.Warn("Check queue full, delaying scheduled checks until workers catch up",
+					zap.Int("queue_size", cap(s.checkQueue)),
+					zap.Uint32("target_id", e.target.ID),
+					zap.Duration("late", now.Sub(e.next)))
+			}
+			return schedulerRetryDelay, true
+		}
+		if sc.queueFull {
+			sc.queueFull = false
+			logger.Info("Check queue has room again, scheduled checks resumed",
+				zap.Duration("late", now.Sub(e.next)))
+		}
+
+		interval := e.target.interval()
+		e.next = e.next.Add(interval)
+		if !e.next.After(now) {
+			e.next = now.Add(interval)
+		}
+		heap.Fix(&sc.due, 0)
+	}
+	return 0, false
+}
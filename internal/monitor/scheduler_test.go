@@ -0,0 +1,196 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"monitor/internal/clock"
+)
+
+// newScheduleService returns a service with only a schedule and a check
+// queue of queueSize. The scheduler goroutine is not started; the tests call
+// queueDueChecks themselves.
+func newScheduleService(fake *clock.Fake, queueSize int) *Service {
+	s := &Service{
+		schedule:   newCheckSchedule(),
+		checkQueue: make(chan checkTask, queueSize),
+		clock:      fake,
+	}
+	s.schedule.start.Do(func() {})
+	return s
+}
+
+func (s *Service) addToSchedule(target *MonitorTarget, firstCheck time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scheduleTarget(target, firstCheck)
+}
+
+// queued drains the check queue and returns the IDs in queue order
+func (s *Service) queued() []uint32 {
+	var ids []uint32
+	for {
+		select {
+		case task := <-s.checkQueue:
+			ids = append(ids, task.target.ID)
+		default:
+			return ids
+		}
+	}
+}
+
+func equalIDs(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSchedulerQueuesEarliestFirst(t *testing.T) {
+	fake := clock.NewFake(epoch)
+	s := newScheduleService(fake, 10)
+	firstChecks := map[uint32]time.Duration{1: 5 * time.Second, 2: time.Second, 3: 4 * time.Second, 4: 2 * time.Second, 5: 3 * time.Second}
+	for id := uint32(1); id <= 5; id++ {
+		s.addToSchedule(&MonitorTarget{ID: id, Interval: 60}, firstChecks[id])
+	}
+	if wait, ok := s.queueDueChecks(); !ok || wait != time.Second {
+		t.Fatalf("next check in %s (%v), want 1s", wait, ok)
+	}
+
+	// All overdue at once: queued in the order they came due
+	fake.Advance(10 * time.Second)
+	s.queueDueChecks()
+	if got, want := s.queued(), []uint32{2, 4, 5, 3, 1}; !equalIDs(got, want) {
+		t.Errorf("queued %v, want %v", got, want)
+	}
+	// Each moved one interval on from when it was due
+	if wait, _ := s.queueDueChecks(); wait != 51*time.Second {
+		t.Errorf("next check in %s, want 51s", wait)
+	}
+}
+
+func TestSchedulerSkipsMissedRounds(t *testing.T) {
+	fake := clock.NewFake(epoch)
+	s := newScheduleService(fake, 10)
+	s.addToSchedule(&MonitorTarget{ID: 1, Interval: 10}, noFirstCheck)
+
+	// Far more than an interval late: one check, then a full interval from now
+	fake.Advance(time.Hour)
+	s.queueDueChecks()
+	if got := s.queued(); !equalIDs(got, []uint32{1}) {
+		t.Errorf("queued %v after an hour, want a single check", got)
+	}
+	if wait, _ := s.queueDueChecks(); wait != 10*time.Second {
+		t.Errorf("next check in %s, want a full interval", wait)
+	}
+}
+
+func TestSchedulerIntervalChange(t *testing.T) {
+	fake := clock.NewFake(epoch)
+	s := newScheduleService(fake, 10)
+	s.addToSchedule(&MonitorTarget{ID: 1, Interval: 60}, noFirstCheck)
+	fake.Advance(20 * time.Second)
+
+	// A shorter interval counts from the previous check: due 40s from it, 20s from now
+	s.addToSchedule(&MonitorTarget{ID: 1, Interval: 40}, noFirstCheck)
+	if wait, _ := s.queueDueChecks(); wait != 20*time.Second {
+		t.Errorf("next check in %s after shortening to 40s, want 20s", wait)
+	}
+
+	// A change that makes the check overdue queues it right away
+	s.addToSchedule(&MonitorTarget{ID: 1, Interval: 10}, noFirstCheck)
+	s.queueDueChecks()
+	if got := s.queued(); !equalIDs(got, []uint32{1}) {
+		t.Fatalf("queued %v after shortening to 10s, want the overdue check", got)
+	}
+	if wait, _ := s.queueDueChecks(); wait != 10*time.Second {
+		t.Errorf("next check in %s, want the new interval", wait)
+	}
+
+	// A longer interval pushes the next check back
+	s.addToSchedule(&MonitorTarget{ID: 1, Interval: 300}, noFirstCheck)
+	if wait, _ := s.queueDueChecks(); wait != 300*time.Second {
+		t.Errorf("next check in %s after lengthening to 300s, want 300s", wait)
+	}
+
+	// The updated target is what gets queued
+	fake.Advance(300 * time.Second)
+	s.queueDueChecks()
+	if task := <-s.checkQueue; task.target.Interval != 300 {
+		t.Errorf("queued the target with interval %d, want the updated one", task.target.Interval)
+	}
+}
+
+func TestSchedulerFirstCheckAndRemoval(t *testing.T) {
+	fake := clock.NewFake(epoch)
+	s := newScheduleService(fake, 10)
+	s.addToSchedule(&MonitorTarget{ID: 1, Interval: 60}, 5*time.Second)
+	s.addToSchedule(&MonitorTarget{ID: 2, Interval: 60}, 0)
+
+	s.queueDueChecks()
+	if got := s.queued(); !equalIDs(got, []uint32{2}) {
+		t.Errorf("queued %v at once, want the target with no first check delay", got)
+	}
+	if wait, _ := s.queueDueChecks(); wait != 5*time.Second {
+		t.Errorf("next check in %s, want the first check delay", wait)
+	}
+
+	s.mu.Lock()
+	s.unscheduleTarget(1)
+	s.unscheduleTarget(2)
+	s.mu.Unlock()
+	if _, ok := s.queueDueChecks(); ok {
+		t.Error("checks still scheduled after removing every target")
+	}
+}
+
+func TestSchedulerWaitsForQueueRoom(t *testing.T) {
+	fake := clock.NewFake(epoch)
+	s := newScheduleService(fake, 1)
+	s.addToSchedule(&MonitorTarget{ID: 1, Interval: 10}, 0)
+	s.addToSchedule(&MonitorTarget{ID: 2, Interval: 10}, time.Second)
+	fake.Advance(time.Second)
+
+	// The queue holds one: 2 waits instead of being skipped
+	if wait, _ := s.queueDueChecks(); wait != schedulerRetryDelay {
+		t.Errorf("retry in %s with a full queue, want %s", wait, schedulerRetryDelay)
+	}
+	if got := s.queued(); !equalIDs(got, []uint32{1}) {
+		t.Fatalf("queued %v, want 1", got)
+	}
+	s.queueDueChecks()
+	if got := s.queued(); !equalIDs(got, []uint32{2}) {
+		t.Errorf("queued %v once there was room, want the delayed check of 2", got)
+	}
+}
+
+// BenchmarkScheduler10k runs the schedule of 10,000 targets with a 60s
+// interval, spread over the interval. One op is a second of it: about 170
+// checks queued and moved on, plus one target rescheduled with a new
+// interval the way an update does.
+func BenchmarkScheduler10k(b *testing.B) {
+	const targets = 10000
+	fake := clock.NewFake(epoch)
+	s := newScheduleService(fake, targets)
+	for id := uint32(1); id <= targets; id++ {
+		s.addToSchedule(&MonitorTarget{ID: id, Interval: 60}, time.Duration(id)*60*time.Second/targets)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	queued := 0
+	for i := 0; i < b.N; i++ {
+		fake.Advance(time.Second)
+		s.queueDueChecks()
+		queued += len(s.queued())
+
+		id := uint32(i%targets + 1)
+		s.addToSchedule(&MonitorTarget{ID: id, Interval: int64(30 + i%2*30)}, noFirstCheck)
+	}
+	b.ReportMetric(float64(queued)/float64(b.N), "checks/op")
+}
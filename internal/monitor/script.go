@@ -0,0 +1,263 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"monitor/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// TypeScript is the type of targets checked by running a local program
+const TypeScript = "script"
+
+// Script timeouts in seconds; the maximum stays below checkTimeout. A shorter
+// timeout_seconds on the target ends the script first.
+const (
+	defaultScriptTimeout = 10
+	maxScriptTimeout     = 25
+)
+
+// scriptWaitDelay 超时杀掉进程后等待输出管道关闭的时间，子进程继承了管道时不会一直等下去
+const scriptWaitDelay = 2 * time.Second
+
+// scriptEnvPath 脚本的 PATH，环境变量不从服务进程继承
+const scriptEnvPath = "/usr/local/bin:/usr/bin:/bin"
+
+func init() {
+	RegisterType(TypeSpec{
+		Type:        TypeScript,
+		DisplayName: "自定义脚本",
+		Fields: []FieldSpec{
+			{Name: "script_path", Kind: FieldString, Required: true, Max: intBound(500), Description: "可执行程序的绝对路径，必须与 monitor.script.allowed_paths 中的一项完全相同"},
+			{Name: "script_args", Kind: FieldArray, Description: "命令行参数，不经过 shell 解释"},
+			{Name: "script_timeout", Kind: FieldInteger, Default: defaultScriptTimeout, Min: intBound(1), Max: intBound(maxScriptTimeout), Description: "超时（秒），超时后结束进程并记为 down"},
+		},
+		Results: []ResultField{
+			{Name: "exit_code", In: "data", Description: "退出码：0 为 up，1 为 down，2 为 warning，其他为 down"},
+			{Name: "output", In: "data", Description: "标准输出是 JSON 对象时解析后的内容"},
+			{Name: "output_truncated", In: "data", Description: "标准输出超过 max_output_bytes 被截断"},
+		},
+	}, func() Checker { return &ScriptChecker{} })
+}
+
+// ScriptPolicy is the server side control of script targets
+type ScriptPolicy struct {
+	Enabled        bool
+	AllowedPaths   []string // exact executable paths
+	MaxOutputBytes int      // stdout kept, and stderr kept for the message
+}
+
+// DefaultScriptPolicy is used until SetScriptPolicy is called: scripts are disabled
+var DefaultScriptPolicy = ScriptPolicy{MaxOutputBytes: 64 << 10}
+
+var scriptPolicy = DefaultScriptPolicy
+
+// SetScriptPolicy replaces the script policy. It must be called before targets are loaded.
+func SetScriptPolicy(policy ScriptPolicy) {
+	if policy.MaxOutputBytes <= 0 {
+		policy.MaxOutputBytes = DefaultScriptPolicy.MaxOutputBytes
+	}
+	scriptPolicy = policy
+}
+
+// ValidateScript checks that scripts are enabled and that path is allowed. A
+// script target that fails it is not scheduled, so removing a path from the
+// allow-list stops its targets at the next restart or reload.
+func ValidateScript(path string) error {
+	policy := scriptPolicy
+	if !policy.Enabled {
+		return fmt.Errorf("script monitors are disabled, set monitor.script.enabled")
+	}
+	for _, allowed := range policy.AllowedPaths {
+		if path == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("script_path %q is not in monitor.script.allowed_paths", path)
+}
+
+// ParseScriptArgs decodes the stored script_args JSON array
+func ParseScriptArgs(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var args []string
+	if err := json.Unmarshal([]byte(s), &args); err != nil {
+		return nil, fmt.Errorf("script_args: %w", err)
+	}
+	return args, nil
+}
+
+// ScriptChecker runs the target's program without a shell. The exit code is
+// the status and stdout the message; a JSON object on stdout is also stored
+// in the result data.
+type ScriptChecker struct{}
+
+func (c *ScriptChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckResult, error) {
+	start := time.Now()
+	request := RequestDetails{
+		Method:  "EXEC",
+		URL:     target.ScriptPath,
+		Headers: detailHeaders(map[string]interface{}{"args": strings.Join(target.ScriptArgs, " ")}),
+	}
+
+	// 允许列表在每次执行前再检查一次，配置修改后不会执行已移出列表的程序
+	if err := ValidateScript(target.ScriptPath); err != nil {
+		return &CheckResult{
+			Status:  "down",
+			Message: err.Error(),
+			Request: request,
+			Error:   &ErrorDetails{Type: "config_error", Message: err.Error()},
+		}, nil
+	}
+
+	timeout := time.Duration(target.ScriptTimeout) * time.Second
+	if target.ScriptTimeout <= 0 || target.ScriptTimeout > maxScriptTimeout {
+		timeout = defaultScriptTimeout * time.Second
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	policy := scriptPolicy
+	stdout := &boundedBuffer{max: policy.MaxOutputBytes}
+	stderr := &boundedBuffer{max: policy.MaxOutputBytes}
+	cmd := exec.CommandContext(runCtx, target.ScriptPath, target.ScriptArgs...)
+	cmd.Env = scriptEnv(target)
+	cmd.Dir = filepath.Dir(target.ScriptPath)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = scriptWaitDelay
+
+	err := cmd.Run()
+	responseTime := time.Since(start).Milliseconds()
+
+	exitCode := -1
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		exitCode = 0
+	case errors.As(err, &exitErr) && runCtx.Err() == nil:
+		exitCode = exitErr.ExitCode()
+	}
+
+	output := strings.TrimSpace(stdout.String())
+	result := &CheckResult{
+		ResponseTime: responseTime,
+		Request:      request,
+		Response: ResponseDetails{
+			Body:          stdout.String(),
+			BytesReceived: stdout.total,
+		},
+		Data: map[string]interface{}{
+			"exit_code":        exitCode,
+			"output_truncated": stdout.truncated(),
+		},
+	}
+
+	// 标准输出是 JSON 对象时放进 data，消息取其中的 message 字段
+	message := output
+	if strings.HasPrefix(output, "{") && !stdout.truncated() {
+		var parsed map[string]interface{}
+		if json.Unmarshal([]byte(output), &parsed) == nil {
+			result.Data["output"] = parsed
+			message, _ = parsed["message"].(string)
+		}
+	}
+	if message == "" {
+		message = strings.TrimSpace(stderr.String())
+	}
+
+	switch {
+	case runCtx.Err() == context.DeadlineExceeded:
+		result.Status = "down"
+		result.Message = fmt.Sprintf("Script timed out after %s", timeout)
+		result.Error = &ErrorDetails{Type: "timeout", Message: result.Message}
+	case exitCode == -1:
+		result.Status = "down"
+		result.Message = fmt.Sprintf("Script could not be run: %v", err)
+		result.Error = &ErrorDetails{Type: "exec_error", Message: result.Message}
+	case exitCode == 0:
+		result.Status = "up"
+		result.Message = message
+	case exitCode == 2:
+		result.Status = "warning"
+		result.Message = message
+	default:
+		result.Status = "down"
+		result.Message = message
+		if exitCode != 1 {
+			result.Message = fmt.Sprintf("Script exited with unexpected code %d: %s", exitCode, message)
+		}
+		result.Error = &ErrorDetails{Type: "script_failed", Message: result.Message}
+	}
+	if result.Message == "" {
+		result.Message = fmt.Sprintf("Script exited with code %d", exitCode)
+	}
+
+	// 审计：记录每一次执行
+	logger.Info("Script check executed",
+		zap.Uint32("target_id", target.ID),
+		zap.String("target", target.Name),
+		zap.String("script_path", target.ScriptPath),
+		zap.Strings("script_args", target.ScriptArgs),
+		zap.Int("exit_code", exitCode),
+		zap.Int64("duration_ms", responseTime),
+		zap.Int64("stdout_bytes", stdout.total),
+		zap.String("status", result.Status),
+	)
+	return result, nil
+}
+
+// envNameUnsafe 元数据键中不能出现在环境变量名里的字符
+var envNameUnsafe = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// scriptEnv is the whole environment of a script: a fixed PATH and the
+// target's ID, name, address and metadata. Nothing is inherited from the
+// server process, so its credentials do not leak into scripts.
+func scriptEnv(target *MonitorTarget) []string {
+	env := []string{
+		"PATH=" + scriptEnvPath,
+		"LANG=C.UTF-8",
+		fmt.Sprintf("ARROWGO_TARGET_ID=%d", target.ID),
+		"ARROWGO_TARGET_NAME=" + target.Name,
+		"ARROWGO_TARGET_ADDRESS=" + target.Address,
+	}
+	for key, value := range target.Metadata {
+		name := envNameUnsafe.ReplaceAllString(strings.ToUpper(key), "_")
+		env = append(env, "ARROWGO_META_"+name+"="+value)
+	}
+	return env
+}
+
+// boundedBuffer keeps the first max bytes written to it and counts the rest
+type boundedBuffer struct {
+	buf   bytes.Buffer
+	max   int
+	total int64
+}
+
+func (b *boundedBuffer) Write(p []byte) (int, error) {
+	b.total += int64(len(p))
+	if room := b.max - b.buf.Len(); room > 0 {
+		if len(p) > room {
+			b.buf.Write(p[:room])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (b *boundedBuffer) String() string { return b.buf.String() }
+
+func (b *boundedBuffer) truncated() bool { return b.total > int64(b.buf.Len()) }
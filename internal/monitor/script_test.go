@@ -0,0 +1,129 @@
+package monitor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeScript writes an executable shell script and allows it in the script policy
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "check.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0o755); err != nil {
+		t.Fatalf("write script: %v", err)
+	}
+	allowScripts(t, DefaultScriptPolicy.MaxOutputBytes, path)
+	return path
+}
+
+func allowScripts(t *testing.T, maxOutput int, paths ...string) {
+	t.Helper()
+	previous := scriptPolicy
+	SetScriptPolicy(ScriptPolicy{Enabled: true, AllowedPaths: paths, MaxOutputBytes: maxOutput})
+	t.Cleanup(func() { scriptPolicy = previous })
+}
+
+func runScript(t *testing.T, target *MonitorTarget) *CheckResult {
+	t.Helper()
+	target.Type = TypeScript
+	result, err := (&ScriptChecker{}).Check(context.Background(), target)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	return result
+}
+
+func TestScriptCheckExitCodes(t *testing.T) {
+	for _, tc := range []struct {
+		body      string
+		status    string
+		message   string
+		errorType string
+	}{
+		{`echo '{"message": "3 jobs queued", "queued": 3}'`, "up", "3 jobs queued", ""},
+		{"echo backlog growing; exit 2", "warning", "backlog growing", ""},
+		{"echo 'queue stuck' >&2; exit 1", "down", "queue stuck", "script_failed"},
+		{"echo odd; exit 3", "down", "Script exited with unexpected code 3: odd", "script_failed"},
+		{"exit 0", "up", "Script exited with code 0", ""},
+	} {
+		result := runScript(t, &MonitorTarget{Name: "queue", ScriptPath: writeScript(t, tc.body)})
+		errorType := ""
+		if result.Error != nil {
+			errorType = result.Error.Type
+		}
+		if result.Status != tc.status || result.Message != tc.message || errorType != tc.errorType {
+			t.Errorf("%q: %s %q %q, want %s %q %q", tc.body, result.Status, result.Message, errorType, tc.status, tc.message, tc.errorType)
+		}
+	}
+
+	result := runScript(t, &MonitorTarget{Name: "queue", ScriptPath: writeScript(t, `echo '{"queued": 3}'`)})
+	output, _ := result.Data["output"].(map[string]interface{})
+	if result.Data["exit_code"] != 0 || output["queued"] != float64(3) {
+		t.Errorf("data %+v, want the parsed output", result.Data)
+	}
+}
+
+// Only allowed paths run, and the allow-list is checked again at each run
+func TestScriptCheckPolicy(t *testing.T) {
+	path := writeScript(t, "exit 0")
+	allowScripts(t, 0, "/usr/local/bin/other")
+	result := runScript(t, &MonitorTarget{ScriptPath: path})
+	if result.Status != "down" || result.Error == nil || result.Error.Type != "config_error" {
+		t.Errorf("path outside the allow-list: %+v", result)
+	}
+
+	SetScriptPolicy(ScriptPolicy{AllowedPaths: []string{path}})
+	if err := ValidateScript(path); err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Errorf("ValidateScript with scripts disabled: %v", err)
+	}
+}
+
+// Scripts get a fixed PATH and the target's details, nothing from the server
+func TestScriptEnvironment(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "hunter2")
+	path := writeScript(t, `env; echo "args: $*"; pwd`)
+	result := runScript(t, &MonitorTarget{ID: 7, Name: "queue", Address: "10.0.0.5", ScriptPath: path,
+		ScriptArgs: []string{"--queue", "a b"}, Metadata: map[string]string{"rack-id": "r1"}})
+
+	lines := strings.Split(strings.TrimSpace(result.Response.Body), "\n")
+	for _, want := range []string{"PATH=" + scriptEnvPath, "ARROWGO_TARGET_ID=7", "ARROWGO_TARGET_NAME=queue",
+		"ARROWGO_TARGET_ADDRESS=10.0.0.5", "ARROWGO_META_RACK_ID=r1", "args: --queue a b", filepath.Dir(path)} {
+		if !slices.Contains(lines, want) {
+			t.Errorf("output has no line %q:\n%s", want, result.Response.Body)
+		}
+	}
+	if strings.Contains(result.Response.Body, "hunter2") {
+		t.Error("the server environment leaked into the script")
+	}
+}
+
+func TestScriptCheckLimits(t *testing.T) {
+	path := writeScript(t, "exec sleep 5")
+	result := runScript(t, &MonitorTarget{ScriptPath: path, ScriptTimeout: 1})
+	if result.Status != "down" || result.Error == nil || result.Error.Type != "timeout" || result.Message != "Script timed out after 1s" {
+		t.Errorf("timed out script: %s %q", result.Status, result.Message)
+	}
+
+	path = writeScript(t, `echo '{"message": "this output is too long"}'`)
+	allowScripts(t, 16, path)
+	result = runScript(t, &MonitorTarget{ScriptPath: path})
+	if result.Data["output_truncated"] != true || len(result.Response.Body) != 16 || result.Response.BytesReceived != 39 || result.Data["output"] != nil {
+		t.Errorf("truncated output: body %q bytes %d data %+v", result.Response.Body, result.Response.BytesReceived, result.Data)
+	}
+}
+
+func TestParseScriptArgs(t *testing.T) {
+	if args, err := ParseScriptArgs(`["-v","a b"]`); err != nil || !slices.Equal(args, []string{"-v", "a b"}) {
+		t.Errorf("ParseScriptArgs = %q, %v", args, err)
+	}
+	if args, err := ParseScriptArgs(""); args != nil || err != nil {
+		t.Errorf("empty args = %q, %v", args, err)
+	}
+	if _, err := ParseScriptArgs(`-v`); err == nil {
+		t.Error("invalid JSON accepted")
+	}
+}
@@ -3,16 +3,20 @@ package monitor
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"strings"
+	"math/rand/v2"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"monitor/internal/clock"
 	"monitor/internal/database"
 	"monitor/internal/elasticsearch"
 	"monitor/internal/logger"
 	"monitor/internal/models"
+	"monitor/internal/spool"
 
 	"go.uber.org/zap"
 )
@@ -25,33 +29,178 @@ type Service struct {
 	es        *elasticsearch.Client
 
 	// Worker pool for high concurrency
-	checkQueue chan *MonitorTarget
+	checkQueue chan checkTask
 	workerPool int32
+	// Workers running a check, see Stats
+	busyWorkers atomic.Int32
+	// Window over which the first checks of the targets loaded at startup are spread
+	startupJitter time.Duration
+
+	// Manual checks started by TriggerCheck
+	checkJobs *checkJobTable
+	// Workers, the scheduler and TriggerCheckAfter waits, waited for by Stop
 	wg         sync.WaitGroup
 
-	// Async ES writes
+	// Closed by Stop: nothing is queued any more and workers exit once idle
+	stopping chan struct{}
+	stopOnce sync.Once
+
+	// Async ES writes; esDone is closed once the writer has flushed esBuffer
 	esBuffer chan *esWriteTask
+	esDone   chan struct{}
+
+	// Batched history inserts and the periodic uptime recomputation
+	history *historyWriter
+
+	// Where the next hourly/daily rollup starts, see RollupHistory
+	rollup *rollupState
+
+	// Masks credentials in request details before results are stored
+	redactor *Redactor
+
+	// Source of wall-clock time for check timestamps and uptime windows
+	clock clock.Clock
+
+	// Count injected synthetic results towards uptime
+	includeSyntheticUptime bool
+
+	// Monitor count limits; notLoaded is how many enabled targets LoadTargetsFromDB skipped
+	limits    Limits
+	notLoaded int
+
+	// Per-target sink selection, see SetSinks; guarded by mu
+	sinks map[uint32]Sinks
+
+	// Bumped on every status change, see StatusVersion
+	statusVersion *statusVersion
+
+	// Checks abandoned by the watchdog in checkTarget
+	stuck *stuckChecks
+
+	// Enabled targets not scheduled because of their configuration; guarded by mu
+	configErrors map[uint32]*TargetConfigError
+
+	// Next check of each target in targets, see scheduleTarget; guarded by mu
+	schedule *checkSchedule
+
+	// Certificates last recorded per target, see recordCertificate
+	certificates *certificateCache
+
+	// Results the history or ES did not accept, see StartSpool; nil when disabled
+	historySpool *spool.Spool
+	esSpool      *spool.Spool
+
+	// Called with every saved result, see SetAlertHandler
+	alertHandler AlertHandler
+
+	// Subscribers to status changes, see Subscribe
+	statusEvents *statusEvents
+
+	// Maintenance windows and the targets they currently cover
+	maintenance *maintenanceState
+
+	// Closed days of the availability heatmaps
+	heatmap *heatmapCache
+
+	// Status changes per target, see SetFlappingPolicy
+	flapping *flapDetector
 }
 
+// AlertHandler receives each check result once its status is saved.
+// previousStatus is the status before this result, empty for the first
+// result of a target.
+type AlertHandler func(target *MonitorTarget, result *CheckResult, previousStatus string)
+
 type esWriteTask struct {
 	target *MonitorTarget
 	result *CheckResult
 }
 
-func NewService(esClient *elasticsearch.Client) *Service {
-	ctx, cancel := context.WithCancel(context.Background())
+// ServiceOptions size the worker pool and its buffers; zero fields take the
+// value from DefaultServiceOptions
+type ServiceOptions struct {
+	Workers      int // concurrent checks
+	QueueSize    int // checks waiting for a worker; beyond that scheduled checks wait for room
+	ESBufferSize int // results waiting to be written to Elasticsearch; beyond that they are dropped
+	// Targets loaded by LoadTargetsFromDB are first checked at a random
+	// time within this window instead of after a full interval
+	StartupJitter time.Duration
+	// History rows are inserted in batches of HistoryBatchSize, or every
+	// HistoryFlushInterval when fewer are pending
+	HistoryBatchSize     int
+	HistoryFlushInterval time.Duration
+	// How often the uptime of the targets with new history is recomputed
+	UptimeInterval time.Duration
+}
+
+// DefaultServiceOptions are used for the zero fields of ServiceOptions
+var DefaultServiceOptions = ServiceOptions{
+	Workers:              100,
+	QueueSize:            1000,
+	ESBufferSize:         500,
+	StartupJitter:        10 * time.Second,
+	HistoryBatchSize:     200,
+	HistoryFlushInterval: 2 * time.Second,
+	UptimeInterval:       time.Minute,
+}
 
-	// Get worker count from config (default 100 workers)
-	workerCount := int32(100)
+func (o ServiceOptions) withDefaults() ServiceOptions {
+	if o.Workers <= 0 {
+		o.Workers = DefaultServiceOptions.Workers
+	}
+	if o.QueueSize <= 0 {
+		o.QueueSize = DefaultServiceOptions.QueueSize
+	}
+	if o.ESBufferSize <= 0 {
+		o.ESBufferSize = DefaultServiceOptions.ESBufferSize
+	}
+	if o.StartupJitter <= 0 {
+		o.StartupJitter = DefaultServiceOptions.StartupJitter
+	}
+	if o.HistoryBatchSize <= 0 {
+		o.HistoryBatchSize = DefaultServiceOptions.HistoryBatchSize
+	}
+	if o.HistoryFlushInterval <= 0 {
+		o.HistoryFlushInterval = DefaultServiceOptions.HistoryFlushInterval
+	}
+	if o.UptimeInterval <= 0 {
+		o.UptimeInterval = DefaultServiceOptions.UptimeInterval
+	}
+	return o
+}
+
+func NewService(esClient *elasticsearch.Client, opts ServiceOptions) *Service {
+	ctx, cancel := context.WithCancel(context.Background())
+	opts = opts.withDefaults()
 
 	s := &Service{
 		targets:    make(map[uint32]*MonitorTarget),
 		ctx:        ctx,
 		cancel:     cancel,
 		es:         esClient,
-		checkQueue: make(chan *MonitorTarget, 1000), // Buffered queue
-		workerPool: workerCount,
-		esBuffer:   make(chan *esWriteTask, 500), // Buffer for ES writes
+		checkQueue: make(chan checkTask, opts.QueueSize),
+		workerPool: int32(opts.Workers),
+		checkJobs:  newCheckJobTable(),
+		stopping:   make(chan struct{}),
+		esBuffer:   make(chan *esWriteTask, opts.ESBufferSize),
+		esDone:     make(chan struct{}),
+		history:    newHistoryWriter(opts.HistoryBatchSize, opts.HistoryFlushInterval, opts.UptimeInterval),
+		rollup:     &rollupState{},
+		redactor:   defaultRedactor(),
+		clock:      clock.Real,
+		limits:     DefaultLimits,
+		sinks:      make(map[uint32]Sinks),
+
+		statusVersion: newStatusVersion(),
+		stuck:         newStuckChecks(),
+		configErrors:  make(map[uint32]*TargetConfigError),
+		schedule:      newCheckSchedule(),
+		certificates:  newCertificateCache(),
+		maintenance:   &maintenanceState{},
+		heatmap:       newHeatmapCache(),
+		flapping:      newFlapDetector(),
+		statusEvents:  newStatusEvents(),
+		startupJitter: opts.StartupJitter,
 	}
 
 	// Start worker pool
@@ -60,33 +209,136 @@ func NewService(esClient *elasticsearch.Client) *Service {
 	// Start async ES writer
 	s.startAsyncESWriter()
 
+	// Start batched history writer
+	s.startHistoryWriter()
+
 	return s
 }
 
+// AddTarget schedules a target. A target with the same ID already scheduled
+// is replaced and keeps its schedule, see scheduleTarget.
 func (s *Service) AddTarget(target *MonitorTarget) error {
+	// A target without a checker is not scheduled; its status says why
+	if _, err := NewChecker(target.Type); err != nil {
+		configErr := &TargetConfigError{Reason: ConfigErrorType, Err: err}
+		s.setConfigError(target.ID, configErr)
+		return configErr
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.stopped() {
+		return ErrServiceStopped
+	}
+
+	_, hadConfigError := s.configErrors[target.ID]
+	delete(s.configErrors, target.ID)
 	s.targets[target.ID] = target
-	go s.monitorTarget(target)
+	s.sinks[target.ID] = target.Sinks
+	s.InvalidateStatus()
+	s.scheduleTarget(target, noFirstCheck)
+
+	// The config_error status stays until the first check of the fixed target
+	if hadConfigError {
+		select {
+		case s.checkQueue <- checkTask{target: target}:
+		default:
+		}
+	}
 
 	return nil
 }
 
-// TriggerCheck manually triggers an immediate check for a target
-func (s *Service) TriggerCheck(targetID uint32) error {
+// TriggerCheck queues an immediate check for a target and returns its job.
+// If the target already has a manual check queued or running, that job is returned.
+func (s *Service) TriggerCheck(targetID uint32) (CheckJob, error) {
+	// Held until the task is queued, so that Stop sees it when dropping the queue
 	s.mu.RLock()
-	target, exists := s.targets[targetID]
-	s.mu.RUnlock()
+	defer s.mu.RUnlock()
 
+	target, exists := s.targets[targetID]
 	if !exists {
-		return fmt.Errorf("target not found")
+		return CheckJob{}, fmt.Errorf("target not found")
+	}
+	if s.stopped() {
+		return CheckJob{}, ErrServiceStopped
 	}
 
-	// Trigger check in background
-	go s.checkTarget(target)
+	job, created, err := s.checkJobs.create(targetID, s.clock.Now())
+	if err != nil || !created {
+		return job, err
+	}
 
-	return nil
+	select {
+	case s.checkQueue <- checkTask{target: target, job: job.Token}:
+	default:
+		s.checkJobs.remove(job.Token)
+		return CheckJob{}, ErrCheckQueueFull
+	}
+
+	return job, nil
+}
+
+// TriggerCheckAfter queues an immediate check of a target once delay has
+// passed. Stop ends the wait early and waits for it, so nothing outlives the
+// service; a failure to queue is logged.
+func (s *Service) TriggerCheckAfter(targetID uint32, delay time.Duration) {
+	// Under mu so that the wait is either counted before Stop waits or not started
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.stopped() {
+		return
+	}
+
+	timer := s.clock.NewTimer(delay)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer timer.Stop()
+		select {
+		case <-timer.C():
+		case <-s.stopping:
+			return
+		}
+		if _, err := s.TriggerCheck(targetID); err != nil && !errors.Is(err, ErrServiceStopped) {
+			logger.Warn("Failed to trigger initial check",
+				zap.Uint32("target_id", targetID),
+				zap.Error(err),
+			)
+		}
+	}()
+}
+
+// RunCheck checks a scheduled target on the caller's goroutine, outside the
+// worker queue, and saves the result like a scheduled check. The check ends
+// at the target's timeout or when ctx is done, whichever comes first; one
+// still running then comes back down with a timeout error.
+func (s *Service) RunCheck(ctx context.Context, targetID uint32) (*CheckJobResult, error) {
+	target, ok := s.scheduled(targetID)
+	if !ok {
+		return nil, ErrTargetNotFound
+	}
+	if s.stopped() {
+		return nil, ErrServiceStopped
+	}
+	result, err := s.checkTarget(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	return newCheckJobResult(result), nil
+}
+
+// GetCheckJob returns a manual check job; finished jobs are kept for CheckJobTTL
+func (s *Service) GetCheckJob(token string) (CheckJob, bool) {
+	return s.checkJobs.get(token, s.clock.Now())
+}
+
+// SubscribeCheckJobs streams manual check state changes until the returned
+// cancel function is called. At most MaxCheckJobSubscribers streams are open
+// at a time, beyond that it returns ErrTooManyStreams.
+func (s *Service) SubscribeCheckJobs() (<-chan CheckJob, func(), error) {
+	return s.checkJobs.subscribe()
 }
 
 // startWorkerPool starts the worker pool for concurrent checks
@@ -102,23 +354,51 @@ func (s *Service) startWorkerPool() {
 	}
 }
 
-// checkWorker processes checks from the queue
+// checkWorker processes checks from the queue until Stop
 func (s *Service) checkWorker(workerID int32) {
 	for {
+		// Once stopping, finish the current check but take no new one
+		if s.stopped() {
+			return
+		}
 		select {
+		case <-s.stopping:
+			return
 		case <-s.ctx.Done():
 			return
-		case target := <-s.checkQueue:
-			s.checkTarget(target)
+		case task := <-s.checkQueue:
+			s.busyWorkers.Add(1)
+			s.runTask(task)
+			s.busyWorkers.Add(-1)
+		}
+	}
+}
+
+// runTask runs a queued check and keeps its manual check job, if any, up to date
+func (s *Service) runTask(task checkTask) {
+	// The target may have been removed or updated while the task was queued
+	current, ok := s.scheduled(task.target.ID)
+	if task.job == "" {
+		// An updated target is queued again by the scheduler, so only the current one is checked
+		if ok && current == task.target {
+			s.checkTarget(s.ctx, task.target)
 		}
+		return
+	}
+
+	if !ok {
+		s.checkJobs.finish(task.job, s.clock.Now(), nil, ErrTargetRemoved)
+		return
 	}
+	s.checkJobs.start(task.job, s.clock.Now())
+	result, err := s.checkTarget(s.ctx, current)
+	s.checkJobs.finish(task.job, s.clock.Now(), result, err)
 }
 
 // startAsyncESWriter starts the async Elasticsearch writer
 func (s *Service) startAsyncESWriter() {
-	s.wg.Add(1)
 	go func() {
-		defer s.wg.Done()
+		defer close(s.esDone)
 		s.esWriter()
 	}()
 }
@@ -128,11 +408,16 @@ func (s *Service) esWriter() {
 	for {
 		select {
 		case <-s.ctx.Done():
-			// Flush remaining writes
-			for task := range s.esBuffer {
-				s.writeToElasticsearch(task.target, task.result)
+			// Flush remaining writes; esBuffer is never closed because
+			// saveResult may still run, e.g. for injected results
+			for {
+				select {
+				case task := <-s.esBuffer:
+					s.writeToElasticsearch(task.target, task.result)
+				default:
+					return
+				}
 			}
-			return
 		case task := <-s.esBuffer:
 			s.writeToElasticsearch(task.target, task.result)
 		}
@@ -140,16 +425,43 @@ func (s *Service) esWriter() {
 }
 
 func (s *Service) RemoveTarget(id uint32) error {
+	s.certificates.forget(id)
+	s.heatmap.invalidate(id)
+	s.flapping.forget(id)
+	targetTransports.forget(id)
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if _, exists := s.targets[id]; exists {
+		s.unscheduleTarget(id)
 		delete(s.targets, id)
+		delete(s.sinks, id)
+		s.InvalidateStatus()
+		return nil
+	}
+	// Not scheduled, but counts as loaded so that an update re-validates it
+	if _, exists := s.configErrors[id]; exists {
+		delete(s.configErrors, id)
+		s.InvalidateStatus()
 		return nil
 	}
 	return fmt.Errorf("target not found")
 }
 
+// ReplaceTarget reschedules a loaded target with new settings. It returns an error if the target is not loaded,
+// e.g. because it is disabled.
+func (s *Service) ReplaceTarget(target *MonitorTarget) error {
+	s.mu.RLock()
+	_, scheduled := s.targets[target.ID]
+	_, hasConfigError := s.configErrors[target.ID]
+	s.mu.RUnlock()
+	if !scheduled && !hasConfigError {
+		return fmt.Errorf("target not found")
+	}
+	return s.AddTarget(target)
+}
+
 func (s *Service) GetTarget(id uint32) (*MonitorTarget, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -172,51 +484,213 @@ func (s *Service) ListTargets() []*MonitorTarget {
 	return targets
 }
 
-func (s *Service) monitorTarget(target *MonitorTarget) {
-	ticker := time.NewTicker(time.Duration(target.Interval) * time.Second)
-	defer ticker.Stop()
+// scheduled returns the target currently scheduled under id
+func (s *Service) scheduled(id uint32) (*MonitorTarget, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	target, ok := s.targets[id]
+	return target, ok
+}
 
-	for {
-		select {
-		case <-s.ctx.Done():
-			return
-		case <-ticker.C:
-			// Send to worker pool queue instead of executing directly
-			select {
-			case s.checkQueue <- target:
-				// Successfully queued
-			default:
-				// Queue full, log warning and skip this check
-				logger.Warn("Check queue full, skipping check",
-					zap.Uint32("target_id", target.ID),
-					zap.String("target_name", target.Name))
+// startupDelay spreads the first checks of the loaded targets over the
+// startup jitter window so they don't all hit the queue at once
+func (s *Service) startupDelay() time.Duration {
+	if s.startupJitter <= 0 {
+		return 0
+	}
+	return rand.N(s.startupJitter)
+}
+
+// checkTarget checks a target and saves the result. A check whose ctx was
+// cancelled (the caller went away, or Stop stopped waiting) is not saved:
+// its down result says nothing about the target.
+func (s *Service) checkTarget(ctx context.Context, target *MonitorTarget) (*CheckResult, error) {
+	checker, err := NewChecker(target.Type)
+	if err != nil {
+		logger.Error("Failed to create checker", zap.Uint32("target_id", target.ID), zap.Error(err))
+		s.setConfigError(target.ID, &TargetConfigError{Reason: ConfigErrorType, Err: err})
+		return nil, err
+	}
+
+	result, err := s.probeTarget(ctx, checker, target)
+	if err != nil {
+		log.Printf("Check failed for target %d: %v", target.ID, err)
+		return nil, err
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return result, ctx.Err()
+	}
+	s.applyMaintenance(target, result)
+	s.saveResult(target, result)
+	return result, nil
+}
+
+// probeTarget checks target with its retries, and its secondary address in
+// comparison mode. Nothing is saved.
+func (s *Service) probeTarget(ctx context.Context, checker Checker, target *MonitorTarget) (*CheckResult, error) {
+	// 对比模式：第二个地址在同一个 worker 里并发检查，不另占调度
+	var secondary chan *CheckResult
+	if target.SecondaryAddress != "" {
+		secondary = make(chan *CheckResult, 1)
+		go func() {
+			// 第二个地址不重试，失败即作为比较结果
+			result, err := s.runCheck(ctx, checker, secondaryTarget(target), target.timeout())
+			if err != nil {
+				result = &CheckResult{Status: "down", Message: err.Error()}
 			}
-		}
+			secondary <- result
+		}()
+	}
+
+	result, err := s.checkWithRetries(ctx, checker, target)
+	if err != nil {
+		return nil, err
 	}
+	if secondary != nil {
+		compareResults(target, result, <-secondary)
+	}
+	return result, nil
 }
 
-func (s *Service) checkTarget(target *MonitorTarget) {
+// DryRunCheck checks a target that is not saved, once and with its retries,
+// to try out a configuration. Nothing is written: no status row, history, ES
+// document or file log, and no alert is evaluated.
+func (s *Service) DryRunCheck(ctx context.Context, target *MonitorTarget) (*CheckJobResult, error) {
+	if s.stopped() {
+		return nil, ErrServiceStopped
+	}
 	checker, err := NewChecker(target.Type)
 	if err != nil {
-		log.Printf("Failed to create checker for target %d: %v", target.ID, err)
-		return
+		return nil, err
 	}
+	result, err := s.probeTarget(ctx, checker, target)
+	if err != nil {
+		return nil, err
+	}
+	s.redactor.Apply(result)
+	result.capture = nil
+	return newCheckJobResult(result), nil
+}
 
-	ctx, cancel := context.WithTimeout(s.ctx, 30*time.Second)
+// runCheck runs one check under timeout, or until parent is done. A check
+// still running at its deadline is down with a timeout error, and one that
+// does not return in time is replaced by stuckResult.
+func (s *Service) runCheck(parent context.Context, checker Checker, target *MonitorTarget, timeout time.Duration) (*CheckResult, error) {
+	ctx, cancel := context.WithTimeout(parent, timeout)
 	defer cancel()
 
-	result, err := checker.Check(ctx, target)
-	if err != nil {
-		log.Printf("Check failed for target %d: %v", target.ID, err)
-		return
+	// Some checkers can block past the deadline (a command that ignores the
+	// kill, a library call without context). The watchdog then records a
+	// "down" result so the target does not look frozen and frees the worker;
+	// the checker goroutine is left to finish on its own, or leak.
+	type outcome struct {
+		result *CheckResult
+		err    error
 	}
+	done := make(chan outcome, 1)
+	start := time.Now()
+	// mu decides the race between the checker returning and the watchdog firing
+	var (
+		mu        sync.Mutex
+		finished  bool
+		abandoned bool
+	)
+	go func() {
+		result, err := checker.Check(ctx, target)
+		mu.Lock()
+		if abandoned {
+			mu.Unlock()
+			s.stuck.returned(target.Type)
+			logger.Warn("Abandoned check returned",
+				zap.Uint32("target_id", target.ID),
+				zap.String("type", target.Type),
+				zap.Duration("elapsed", time.Since(start)))
+			return
+		}
+		finished = true
+		mu.Unlock()
+		done <- outcome{result, err}
+	}()
 
-	s.saveResult(target, result)
+	watchdog := time.NewTimer(timeout + stuckCheckGrace)
+	defer watchdog.Stop()
+
+	var o outcome
+	select {
+	case o = <-done:
+	case <-watchdog.C:
+		mu.Lock()
+		if !finished {
+			abandoned = true
+			s.stuck.abandoned(target.Type)
+		}
+		mu.Unlock()
+		if !abandoned {
+			o = <-done
+		}
+	}
+
+	if !abandoned {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return timedOut(target, o.result, o.err, time.Since(start)), nil
+		}
+		if o.err == nil {
+			applyResponseTimeThresholds(target, o.result)
+		}
+		return o.result, o.err
+	}
+
+	elapsed := time.Since(start)
+	logger.Error("Check exceeded its timeout, recording it as stuck",
+		zap.Uint32("target_id", target.ID),
+		zap.String("target_name", target.Name),
+		zap.String("type", target.Type),
+		zap.String("address", target.Address),
+		zap.Duration("elapsed", elapsed))
+	return stuckResult(target, elapsed), nil
+}
+
+// SetRedactor replaces the request detail redaction rules
+func (s *Service) SetRedactor(r *Redactor) {
+	s.redactor = r
+}
+
+// SetClock replaces the time source. It must be called before targets are added.
+func (s *Service) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// SetAlertHandler sets the handler that evaluates alert rules on each saved
+// result, synthetic ones included. It runs on the check worker, so it should
+// not block. It must be called before targets are added.
+func (s *Service) SetAlertHandler(h AlertHandler) {
+	s.alertHandler = h
+}
+
+// SetIncludeSyntheticInUptime controls whether injected synthetic results count
+// towards the uptime percentage. They are excluded by default.
+func (s *Service) SetIncludeSyntheticInUptime(include bool) {
+	s.includeSyntheticUptime = include
 }
 
 func (s *Service) saveResult(target *MonitorTarget, result *CheckResult) {
 	db := database.GetDB()
 
+	// Redact once here so every sink below (DB, ES, file log) gets the same masked copy
+	s.redactor.Apply(result)
+	now := s.clock.Now()
+	if result.CompletedAt.IsZero() {
+		result.CompletedAt = now
+	}
+	if result.Nonce == "" {
+		result.Nonce = newCheckNonce()
+	}
+	if result.Seq == 0 {
+		result.Seq = logger.NextCheckLogSeq()
+	}
+	// The current status row is always saved; history, ES and file log follow the target's selection
+	sinks := s.sinksFor(target.ID)
+
 	var status models.MonitorStatus
 	err := db.Where("target_id = ?", target.ID).First(&status).Error
 	if err != nil {
@@ -225,10 +699,41 @@ func (s *Service) saveResult(target *MonitorTarget, result *CheckResult) {
 		}
 	}
 
+	previousStatus := status.Status
+	if status.Status != result.Status {
+		changedAt := now
+		status.LastStatusChangeAt = &changedAt
+	}
+
+	// up 变为 down 时存档完整响应，ID 随 data 写入各个存储
+	var archive *models.ResponseArchive
+	if result.capture != nil && status.Status == "up" && result.Status == "down" && !result.Synthetic {
+		if archive = s.archiveResponse(db, target, result, now); archive != nil {
+			if result.Data == nil {
+				result.Data = make(map[string]interface{})
+			}
+			result.Data["response_archive_id"] = archive.ID
+		}
+	}
+	result.capture = nil
+
 	status.Status = result.Status
 	status.ResponseTime = result.ResponseTime
 	status.Message = result.Message
-	status.CheckedAt = time.Now()
+	status.CheckedAt = now
+	status.Synthetic = result.Synthetic
+	status.Flapping, result.FlappingChange = s.flapping.record(target.ID, result.Status, now)
+	result.Flapping = status.Flapping
+
+	// 依赖的监控 down 时，本监控的 down 记为被它抑制，ID 随 data 写入 ES 和文件日志
+	result.SuppressedBy = suppressedBy(db, target, result)
+	status.SuppressedBy = result.SuppressedBy
+	if result.SuppressedBy != nil {
+		if result.Data == nil {
+			result.Data = make(map[string]interface{})
+		}
+		result.Data["suppressed_by"] = *result.SuppressedBy
+	}
 
 	// Save SSL certificate info if available (for HTTPS, SSL, TLS)
 	if target.Type == "https" || target.Type == "ssl" || target.Type == "tls" {
@@ -249,6 +754,11 @@ func (s *Service) saveResult(target *MonitorTarget, result *CheckResult) {
 		}
 	}
 
+	// 证书清单：记录目标当前出示的证书，共用证书的目标由此关联起来
+	if result.Certificate != nil && !result.Synthetic {
+		s.recordCertificate(db, target, result.Certificate, now)
+	}
+
 	// Save resolved IP if available
 	if resolvedIP, ok := result.Response.Headers["resolved_ip"]; ok {
 		status.ResolvedIP = &resolvedIP
@@ -256,6 +766,9 @@ func (s *Service) saveResult(target *MonitorTarget, result *CheckResult) {
 		status.ResolvedIP = &resolvedIP
 	}
 
+	// Compare the content hash with the last one before the data is saved
+	contentChanged, previousContentHash := recordContentHash(target, &status, result)
+
 	// Save full check result data as JSON
 	if len(result.Data) > 0 {
 		dataJSON, err := json.Marshal(result.Data)
@@ -276,40 +789,99 @@ func (s *Service) saveResult(target *MonitorTarget, result *CheckResult) {
 		Status:       result.Status,
 		ResponseTime: result.ResponseTime,
 		Message:      result.Message,
-		CheckedAt:    time.Now(),
+		Synthetic:    result.Synthetic,
+		SuppressedBy: result.SuppressedBy,
+		CheckedAt:    now,
+	}
+	if target.SecondaryAddress != "" {
+		history.Address = target.Address
+		history.Divergence, _ = result.Data["divergence"].(bool)
 	}
+	history.ContentChanged = contentChanged
+	if jitter, ok := result.Data["jitter"].(float64); ok {
+		history.Jitter = &jitter
+	}
+	if stddev, ok := result.Data["stddev"].(float64); ok {
+		history.RTTStdDev = &stddev
+	}
+	checkID := elasticsearch.DocumentID(target.ID, result.CompletedAt, result.Nonce)
+	history.CheckID = &checkID
 
-	if err := db.Save(&status).Error; err != nil {
+	// The uptime columns are written by updateUptime
+	if err := db.Omit("uptime_percentage", "uptime_24h", "uptime_7d", "uptime_30d").Save(&status).Error; err != nil {
 		log.Printf("Failed to save status for target %d: %v", target.ID, err)
 	}
 
-	if err := db.Create(&history).Error; err != nil {
-		log.Printf("Failed to save history for target %d: %v", target.ID, err)
+	// Inserted with the next batch; uptime is recomputed from history
+	// periodically, it stays as it was while history is off
+	if sinks.Has(SinkDBHistory) {
+		s.queueHistory(&history, archive)
+	}
+	s.InvalidateStatus()
+
+	if previousStatus != result.Status {
+		s.statusEvents.publish(StatusChangeEvent{
+			Type:      EventStatusChange,
+			TargetID:  target.ID,
+			OldStatus: previousStatus,
+			NewStatus: result.Status,
+			Message:   result.Message,
+			ChangedAt: now,
+		})
+	}
+	if contentChanged {
+		s.statusEvents.publish(StatusChangeEvent{
+			Type:           EventContentChanged,
+			TargetID:       target.ID,
+			OldStatus:      previousStatus,
+			NewStatus:      result.Status,
+			Message:        result.Message,
+			ChangedAt:      now,
+			OldContentHash: previousContentHash,
+			NewContentHash: status.ContentHash,
+		})
 	}
-
-	s.updateUptimePercentage(target.ID)
 
 	// Async save to Elasticsearch
-	select {
-	case s.esBuffer <- &esWriteTask{target: target, result: result}:
-		// Successfully queued for ES write
-	default:
-		// Buffer full, log warning but don't block
-		logger.Warn("ES buffer full, dropping log",
-			zap.Uint32("target_id", target.ID))
+	if sinks.Has(SinkES) && s.es != nil {
+		select {
+		case s.esBuffer <- &esWriteTask{target: target, result: result}:
+			// Successfully queued for ES write
+		default:
+			// Buffer full, e.g. while ES is slow or down: spool rather than block
+			s.spoolLog(newLogEntry(target, result), errESBufferFull)
+		}
+	}
+
+	// Write to file log (non-blocking, independent of ES)
+	if sinks.Has(SinkFile) {
+		s.writeFileLog(target, result)
 	}
 
-	// Always write to file log (non-blocking, independent of ES)
-	s.writeFileLog(target, result)
+	// 维护窗口内的结果和被依赖抑制的结果不触发告警
+	if s.alertHandler != nil && result.Status != StatusMaintenance && result.SuppressedBy == nil {
+		s.alertHandler(target, result, previousStatus)
+	}
 }
 
+// errESBufferFull is the reason a log is spooled when esBuffer is full
+var errESBufferFull = errors.New("ES buffer full")
+
 // writeToElasticsearch actually writes to ES
 func (s *Service) writeToElasticsearch(target *MonitorTarget, result *CheckResult) {
 	if s.es == nil {
 		return // ES 未启用
 	}
 
-	// 构建 ES 日志条目
+	// 索引到 ES，失败时暂存，ES 恢复后补写
+	entry := newLogEntry(target, result)
+	if err := s.es.IndexLog(entry); err != nil {
+		s.spoolLog(entry, err)
+	}
+}
+
+// newLogEntry 构建 ES 日志条目
+func newLogEntry(target *MonitorTarget, result *CheckResult) *elasticsearch.LogEntry {
 	entry := &elasticsearch.LogEntry{
 		TargetID:     target.ID,
 		TargetName:   target.Name,
@@ -318,7 +890,15 @@ func (s *Service) writeToElasticsearch(target *MonitorTarget, result *CheckResul
 		Status:       result.Status,
 		ResponseTime: result.ResponseTime,
 		Message:      result.Message,
+		Synthetic:    result.Synthetic,
+		Timestamp:    result.CompletedAt,
+		Nonce:        result.Nonce,
+		Seq:          result.Seq,
 	}
+	if skew, ok := result.Data["clock_skew_ms"].(int64); ok {
+		entry.ClockSkewMs = &skew
+	}
+	entry.Timings, _ = result.Data["timings"].(map[string]interface{})
 
 	// 填充请求信息
 	entry.Request.Method = result.Request.Method
@@ -333,6 +913,8 @@ func (s *Service) writeToElasticsearch(target *MonitorTarget, result *CheckResul
 	entry.Response.Headers = result.Response.Headers
 	entry.Response.Body = result.Response.Body
 	entry.Response.ContentLength = result.Response.ContentLength
+	entry.Response.BytesReceived = result.Response.BytesReceived
+	entry.Response.DecodedBodyBytes = result.Response.DecodedBodyBytes
 
 	// 填充错误信息
 	if result.Error != nil {
@@ -340,16 +922,13 @@ func (s *Service) writeToElasticsearch(target *MonitorTarget, result *CheckResul
 		entry.Error.Message = result.Error.Message
 	}
 
-	// 索引到 ES
-	if err := s.es.IndexLog(entry); err != nil {
-		logger.Log.Error(fmt.Sprintf("Failed to index log to ES: target_id=%d, error=%v",
-			target.ID, err))
-	}
+	return entry
 }
 
 // writeFileLog writes check result to file-based log
 func (s *Service) writeFileLog(target *MonitorTarget, result *CheckResult) {
 	entry := &logger.CheckLogEntry{
+		Timestamp:    result.CompletedAt,
 		TargetID:     int(target.ID),
 		TargetName:   target.Name,
 		Type:         target.Type,
@@ -357,7 +936,11 @@ func (s *Service) writeFileLog(target *MonitorTarget, result *CheckResult) {
 		Status:       result.Status,
 		ResponseTime: result.ResponseTime,
 		Message:      result.Message,
+		Synthetic:    result.Synthetic,
+		Nonce:        result.Nonce,
+		Seq:          result.Seq,
 	}
+	entry.Timings, _ = result.Data["timings"].(map[string]interface{})
 
 	// Add request details if available
 	if result.Request.Method != "" || result.Request.URL != "" {
@@ -384,13 +967,17 @@ func (s *Service) writeFileLog(target *MonitorTarget, result *CheckResult) {
 		if len(result.Response.Headers) > 0 {
 			entry.Response["headers"] = result.Response.Headers
 		}
-		// Don't save body content, only size
-		if result.Response.Body != "" {
-			entry.Response["body_size"] = len(result.Response.Body)
-		}
-		if result.Response.ContentLength > 0 {
+		// Don't save body content, only sizes: the Content-Length header (-1 if
+		// unknown), bytes received on the wire and bytes after decoding
+		if result.Response.ContentLength != 0 {
 			entry.Response["content_length"] = result.Response.ContentLength
 		}
+		if result.Response.BytesReceived != 0 {
+			entry.Response["bytes_received"] = result.Response.BytesReceived
+		}
+		if result.Response.DecodedBodyBytes != 0 {
+			entry.Response["decoded_body_bytes"] = result.Response.DecodedBodyBytes
+		}
 	}
 
 	// Add error details if available
@@ -405,7 +992,8 @@ func (s *Service) writeFileLog(target *MonitorTarget, result *CheckResult) {
 	}
 
 	// Write to log file
-	if err := logger.WriteCheckLog("logs", entry); err != nil {
+	// A disabled sink already reported its state change once; don't log per check
+	if err := logger.WriteCheckLog(entry); err != nil && !errors.Is(err, logger.ErrFileLogDisabled) {
 		logger.Log.Warn("Failed to write check log to file",
 			zap.Int("target_id", int(target.ID)),
 			zap.Error(err),
@@ -413,125 +1001,157 @@ func (s *Service) writeFileLog(target *MonitorTarget, result *CheckResult) {
 	}
 }
 
-func (s *Service) updateUptimePercentage(targetID uint32) {
-	db := database.GetDB()
-
-	var historyCount int64
-	var upCount int64
-
-	db.Model(&models.MonitorHistory{}).
-		Where("target_id = ? AND checked_at >= ?", targetID, time.Now().AddDate(0, 0, -30)).
-		Count(&historyCount)
-
-	db.Model(&models.MonitorHistory{}).
-		Where("target_id = ? AND status = ? AND checked_at >= ?", targetID, "up", time.Now().AddDate(0, 0, -30)).
-		Count(&upCount)
-
-	var status models.MonitorStatus
-	if err := db.Where("target_id = ?", targetID).First(&status).Error; err == nil {
-		if historyCount > 0 {
-			status.UptimePercentage = int32((upCount * 100) / historyCount)
-		} else {
-			status.UptimePercentage = 0
-		}
-		db.Save(&status)
-	}
-}
-
 func (s *Service) LoadTargetsFromDB() error {
 	db := database.GetDB()
 
 	var dbTargets []models.MonitorTarget
-	if err := db.Find(&dbTargets).Error; err != nil {
+	if err := db.Where("enabled = ?", true).Order("id").Find(&dbTargets).Error; err != nil {
 		return err
 	}
 
-	for _, dbTarget := range dbTargets {
-		if !dbTarget.Enabled {
-			continue
-		}
-
-		var metadata map[string]string
-		if dbTarget.Metadata != "" {
-			if err := json.Unmarshal([]byte(dbTarget.Metadata), &metadata); err != nil {
-				metadata = make(map[string]string)
-			}
-		}
-
-		var httpHeaders map[string]string
-		if dbTarget.HTTPHeaders != "" {
-			if err := json.Unmarshal([]byte(dbTarget.HTTPHeaders), &httpHeaders); err != nil {
-				httpHeaders = make(map[string]string)
-			}
-		}
+	// Over the limit, load the oldest targets rather than running out of memory
+	if max := s.limits.MaxTargets; max >= 0 && len(dbTargets) > max {
+		logger.Error("Too many enabled monitor targets, only the first ones are loaded; raise monitor.limits.max_targets or remove targets",
+			zap.Int("enabled_targets", len(dbTargets)),
+			zap.Int("max_targets", max),
+			zap.Int("not_loaded", len(dbTargets)-max),
+			zap.Uint32("first_skipped_id", dbTargets[max].ID))
+		s.mu.Lock()
+		s.notLoaded = len(dbTargets) - max
+		s.mu.Unlock()
+		dbTargets = dbTargets[:max]
+	}
 
-		// Parse expected status codes
-		var expectedStatusCodes []int
-		if dbTarget.ExpectedStatusCodes != "" {
-			codesStr := strings.Split(dbTarget.ExpectedStatusCodes, ",")
-			for _, codeStr := range codesStr {
-				codeStr = strings.TrimSpace(codeStr)
-				if codeStr != "" {
-					var code int
-					if _, err := fmt.Sscanf(codeStr, "%d", &code); err == nil {
-						expectedStatusCodes = append(expectedStatusCodes, code)
-					}
-				}
-			}
-		}
+	loaded := 0
+	failed := make(map[string]int) // config errors by reason
+	for _, dbTarget := range dbTargets {
 
-		target := &MonitorTarget{
-			ID:       dbTarget.ID,
-			Name:     dbTarget.Name,
-			Type:     dbTarget.Type,
-			Address:  dbTarget.Address,
-			Port:     dbTarget.Port,
-			Interval: dbTarget.Interval,
-			Metadata: metadata,
-			Enabled:  dbTarget.Enabled,
-			// HTTP/HTTPS specific fields
-			HTTPMethod:          dbTarget.HTTPMethod,
-			HTTPHeaders:         httpHeaders,
-			HTTPBody:            dbTarget.HTTPBody,
-			ResolvedHost:        dbTarget.ResolvedHost,
-			FollowRedirects:     dbTarget.FollowRedirects,
-			MaxRedirects:        dbTarget.MaxRedirects,
-			ExpectedStatusCodes: expectedStatusCodes,
-			// DNS specific fields
-			DNSServer: dbTarget.DNSServer,
-			// SSL/TLS specific fields
-			SSLWarnDays:    dbTarget.SSLWarnDays,
-			SSLCriticalDays: dbTarget.SSLCriticalDays,
-			SSLCheck:       dbTarget.SSLCheck,
-			SSLGetChain:    dbTarget.SSLGetChain,
+		target, err := PrepareTarget(dbTarget)
+		if err != nil {
+			configErr := err.(*TargetConfigError)
+			logger.Warn("Not scheduling target with invalid configuration",
+				zap.Uint32("target_id", dbTarget.ID),
+				zap.String("target_name", dbTarget.Name),
+				zap.String("reason", configErr.Reason),
+				zap.Error(err))
+			s.setConfigError(dbTarget.ID, configErr)
+			failed[configErr.Reason]++
+			continue
 		}
 
 		s.mu.Lock()
+		if s.stopped() {
+			s.mu.Unlock()
+			return ErrServiceStopped
+		}
 		s.targets[target.ID] = target
+		s.sinks[target.ID] = target.Sinks
+		// Check right away rather than showing a stale status for a full interval
+		s.scheduleTarget(target, s.startupDelay())
 		s.mu.Unlock()
 
-		go s.monitorTarget(target)
+		loaded++
+	}
+
+	if len(failed) > 0 {
+		logger.Warn("Monitor targets loaded, some have configuration errors",
+			zap.Int("loaded", loaded),
+			zap.Int("config_errors", len(dbTargets)-loaded),
+			zap.Any("config_errors_by_reason", failed))
+	} else {
+		logger.Info("Monitor targets loaded", zap.Int("loaded", loaded))
 	}
 
 	return nil
 }
 
-func (s *Service) GetStatus(targetID uint32) (*models.MonitorStatus, error) {
-	db := database.GetDB()
+// StatusWithTarget is a MonitorStatus with the owning target's identity inlined,
+// so clients don't have to join against the monitor list themselves.
+type StatusWithTarget struct {
+	models.MonitorStatus
+	TargetName    string `json:"target_name"`
+	TargetType    string `json:"target_type"`
+	TargetAddress string `json:"target_address"`
+	TargetDeleted bool   `json:"target_deleted"` // The target no longer exists; name/type/address are empty
+	InMaintenance bool   `json:"in_maintenance"` // A maintenance window is open for the target
+	// Only set by the status/get API, see ResponsePercentiles
+	ResponseTimes *ResponsePercentiles `json:"response_times,omitempty"`
+}
+
+func newStatusWithTarget(status models.MonitorStatus) StatusWithTarget {
+	view := StatusWithTarget{MonitorStatus: status}
+	if status.Target == nil || status.Target.ID == 0 {
+		view.TargetDeleted = true
+		return view
+	}
+	view.TargetName = status.Target.Name
+	view.TargetType = status.Target.Type
+	view.TargetAddress = status.Target.Address
+	return view
+}
+
+func (s *Service) GetStatus(ctx context.Context, targetID uint32) (*StatusWithTarget, error) {
+	db := database.GetDB().WithContext(ctx)
 
 	var status models.MonitorStatus
-	if err := db.Where("target_id = ?", targetID).First(&status).Error; err != nil {
+	if err := db.Preload("Target").Where("target_id = ?", targetID).Order("checked_at DESC").First(&status).Error; err != nil {
 		return nil, err
 	}
 
-	return &status, nil
+	view := newStatusWithTarget(status)
+	view.InMaintenance = s.InMaintenance(targetID)
+	return &view, nil
 }
 
-func (s *Service) ListStatus() []models.MonitorStatus {
-	db := database.GetDB()
+// ListStatus returns the latest status row of every target
+func (s *Service) ListStatus(ctx context.Context) []StatusWithTarget {
+	statuses, err := s.QueryStatus(ctx, nil, nil, 0)
+	if err != nil {
+		logger.Warn("Failed to list monitor status", zap.Error(err))
+	}
+	return statuses
+}
+
+// QueryStatus returns status rows newest first. Without a target filter only the
+// latest row per target is returned; with one, all rows of that target are.
+// Tags (normalized) keep only targets that have all of them. A limit <= 0
+// means no limit.
+func (s *Service) QueryStatus(ctx context.Context, targetID *uint32, tags []string, limit int) ([]StatusWithTarget, error) {
+	db := database.GetDB().WithContext(ctx)
+
+	query := db.Preload("Target").Order("checked_at DESC")
+	if targetID != nil {
+		query = query.Where("target_id = ?", *targetID)
+		if limit > 0 {
+			query = query.Limit(limit)
+		}
+	}
 
 	var statuses []models.MonitorStatus
-	db.Find(&statuses)
+	if err := query.Find(&statuses).Error; err != nil {
+		return nil, err
+	}
 
-	return statuses
+	result := make([]StatusWithTarget, 0, len(statuses))
+	seen := make(map[uint32]bool, len(statuses))
+	for _, status := range statuses {
+		if targetID == nil {
+			// Rows are ordered newest first, so the first row per target is the latest
+			if seen[status.TargetID] {
+				continue
+			}
+			seen[status.TargetID] = true
+		}
+		if len(tags) > 0 && (status.Target == nil || !HasTags(status.Target.Tags, tags)) {
+			continue
+		}
+		view := newStatusWithTarget(status)
+		view.InMaintenance = s.InMaintenance(status.TargetID)
+		result = append(result, view)
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+
+	return result, nil
 }
\ No newline at end of file
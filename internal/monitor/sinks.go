@@ -0,0 +1,143 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+
+	"monitor/internal/logger"
+)
+
+// Sinks a check result can be written to besides the current status row
+const (
+	SinkDBHistory = "db_history" // monitor_history rows, also the source of uptime
+	SinkES        = "es"
+	SinkFile      = "file"
+)
+
+// AllSinks lists the sinks in the order they are stored
+var AllSinks = []string{SinkDBHistory, SinkES, SinkFile}
+
+// sinksNone is how an empty selection is stored; "" means every sink
+const sinksNone = "none"
+
+// Sinks is the per-target sink selection. nil selects every sink, an empty
+// non-nil slice selects none.
+type Sinks []string
+
+// ParseSinks parses the stored or requested selection: "" for every sink,
+// "none", or a comma-separated list such as "db_history,file"
+func ParseSinks(value string) (Sinks, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if strings.EqualFold(value, sinksNone) {
+		return Sinks{}, nil
+	}
+
+	selected := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !isSink(name) {
+			return nil, fmt.Errorf("unknown sink %q, valid sinks: %s", name, strings.Join(AllSinks, ", "))
+		}
+		selected[name] = true
+	}
+
+	sinks := Sinks{}
+	for _, name := range AllSinks {
+		if selected[name] {
+			sinks = append(sinks, name)
+		}
+	}
+	return sinks, nil
+}
+
+// String returns the stored form of the selection
+func (s Sinks) String() string {
+	if s == nil {
+		return ""
+	}
+	if len(s) == 0 {
+		return sinksNone
+	}
+	return strings.Join(s, ",")
+}
+
+// Has reports whether the selection includes the sink
+func (s Sinks) Has(name string) bool {
+	if s == nil {
+		return true
+	}
+	for _, sink := range s {
+		if sink == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isSink(name string) bool {
+	for _, sink := range AllSinks {
+		if sink == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SinkState is the effective resolution of one sink for a target
+type SinkState struct {
+	Name      string `json:"name"`
+	Selected  bool   `json:"selected"`  // chosen by the target setting
+	Available bool   `json:"available"` // enabled globally
+	Active    bool   `json:"active"`    // selected and available: results are written here
+}
+
+// SetSinks changes which sinks a target writes to. It takes effect with the
+// next saved result and does not restart the target.
+func (s *Service) SetSinks(targetID uint32, sinks Sinks) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.targets[targetID]; ok {
+		s.sinks[targetID] = sinks
+	}
+}
+
+// sinksFor returns the current selection of a target
+func (s *Service) sinksFor(targetID uint32) Sinks {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sinks[targetID]
+}
+
+// sinkAvailable reports whether a sink is enabled globally
+func (s *Service) sinkAvailable(name string) bool {
+	switch name {
+	case SinkES:
+		return s.es != nil
+	case SinkFile:
+		return logger.GetFileLogStatus().Enabled
+	default:
+		return true
+	}
+}
+
+// ResolveSinks intersects a target selection with the globally available sinks
+func (s *Service) ResolveSinks(sinks Sinks) []SinkState {
+	states := make([]SinkState, 0, len(AllSinks))
+	for _, name := range AllSinks {
+		state := SinkState{
+			Name:      name,
+			Selected:  sinks.Has(name),
+			Available: s.sinkAvailable(name),
+		}
+		state.Active = state.Selected && state.Available
+		states = append(states, state)
+	}
+	return states
+}
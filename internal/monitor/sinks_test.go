@@ -0,0 +1,110 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+// newTestService returns a service with a fresh in-memory database, stopped
+// at the end of the test
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	if err := database.InitMemoryDB(t.Name()); err != nil {
+		t.Fatalf("InitMemoryDB: %v", err)
+	}
+	// The database lives as long as a connection to it, close it for -count
+	t.Cleanup(func() {
+		if sqlDB, err := database.GetDB().DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	s := NewService(nil, ServiceOptions{Workers: 1, StartupJitter: time.Hour})
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.Stop(ctx)
+	})
+	return s
+}
+
+func TestLoadTargetsFromDBKeepsSinkSelection(t *testing.T) {
+	s := newTestService(t)
+
+	targets := []models.MonitorTarget{
+		{Name: "file only", Type: "tcp", Address: "127.0.0.1", Port: 1, Interval: 60, Enabled: true, Sinks: "file"},
+		{Name: "no sinks", Type: "tcp", Address: "127.0.0.1", Port: 2, Interval: 60, Enabled: true, Sinks: "none"},
+		{Name: "all sinks", Type: "tcp", Address: "127.0.0.1", Port: 3, Interval: 60, Enabled: true},
+	}
+	for i := range targets {
+		if err := database.GetDB().Create(&targets[i]).Error; err != nil {
+			t.Fatalf("create target: %v", err)
+		}
+	}
+
+	if err := s.LoadTargetsFromDB(); err != nil {
+		t.Fatalf("LoadTargetsFromDB: %v", err)
+	}
+	if loaded := len(s.ListTargets()); loaded != len(targets) {
+		t.Fatalf("loaded %d targets, want %d", loaded, len(targets))
+	}
+
+	fileOnly := s.sinksFor(targets[0].ID)
+	if fileOnly == nil || !fileOnly.Has(SinkFile) || fileOnly.Has(SinkES) || fileOnly.Has(SinkDBHistory) {
+		t.Errorf("file only target: got sinks %#v, want [file]", fileOnly)
+	}
+	if none := s.sinksFor(targets[1].ID); none == nil || len(none) != 0 {
+		t.Errorf("no sinks target: got sinks %#v, want none", none)
+	}
+	all := s.sinksFor(targets[2].ID)
+	for _, sink := range AllSinks {
+		if !all.Has(sink) {
+			t.Errorf("all sinks target: %s not selected", sink)
+		}
+	}
+}
+
+func TestSetSinksDoesNotAffectOtherTargets(t *testing.T) {
+	s := newTestService(t)
+
+	for _, id := range []uint32{1, 2} {
+		if err := s.AddTarget(&MonitorTarget{ID: id, Name: "t", Type: "tcp", Address: "127.0.0.1", Port: 1, Interval: 60}); err != nil {
+			t.Fatalf("AddTarget: %v", err)
+		}
+	}
+	s.SetSinks(1, Sinks{SinkDBHistory, SinkFile})
+
+	if s.sinksFor(1).Has(SinkES) {
+		t.Error("target 1 still writes to es")
+	}
+	if !s.sinksFor(2).Has(SinkES) {
+		t.Error("disabling es for target 1 disabled it for target 2")
+	}
+}
+
+func TestParseSinks(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"none", "none", false},
+		{"file, ES", "es,file", false},
+		{"file,db_history,file", "db_history,file", false},
+		{"webhook", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSinks(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSinks(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.String() != tt.want {
+			t.Errorf("ParseSinks(%q) = %q, want %q", tt.in, got.String(), tt.want)
+		}
+	}
+}
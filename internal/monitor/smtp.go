@@ -1,30 +1,85 @@
 package monitor
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"fmt"
 	"net"
 	"net/smtp"
+	"strconv"
 	"strings"
 	"time"
 
-	"monitor/internal/models"
+	"monitor/internal/logger"
+
+	"go.uber.org/zap"
 )
 
 // SMTPChecker implements SMTP server monitoring
-type SMTPChecker struct {
-	target *models.MonitorTarget
+func init() {
+	RegisterType(TypeSpec{
+		Type:        "smtp",
+		Aliases:     []string{"smtps"},
+		DisplayName: "SMTP 邮件服务器",
+		Fields: []FieldSpec{
+			{Name: "port", Kind: FieldInteger, Default: 25, Min: intBound(1), Max: intBound(65535), Description: "端口，启用 TLS 时默认 465"},
+			{Name: "smtp_use_tls", Kind: FieldBoolean, Default: false, Description: "直接使用 TLS 连接"},
+			{Name: "smtp_check_starttls", Kind: FieldBoolean, Description: "检查 STARTTLS 支持"},
+			{Name: "smtp_username", Kind: FieldString, Description: "认证用户名"},
+			{Name: "smtp_password", Kind: FieldString, Description: "认证密码"},
+			{Name: "smtp_mail_from", Kind: FieldString, Description: "测试发件地址"},
+			{Name: "smtp_mail_to", Kind: FieldString, Description: "测试收件地址"},
+		},
+		Results: []ResultField{
+			{Name: "host", In: "data", Description: "连接的服务器"},
+			{Name: "tls", In: "data", Description: "是否使用 TLS 连接"},
+			{Name: "starttls_supported", In: "data", Description: "是否支持 STARTTLS"},
+			{Name: "authenticated", In: "data", Description: "是否认证成功"},
+		},
+	}, func() Checker { return &SMTPChecker{} })
+}
+
+type SMTPChecker struct{}
+
+// smtpSession holds the state of a single SMTP check
+type smtpSession struct {
+	target      *MonitorTarget
+	greeting    string           // Server greeting captured during the check
+	certificate *CertificateInfo // Leaf certificate after STARTTLS or of the SMTPS connection
 }
 
-// NewSMTPChecker creates a new SMTP checker
-func NewSMTPChecker(target *models.MonitorTarget) *SMTPChecker {
-	return &SMTPChecker{target: target}
+// bannerConn records the first line read from the server so the SMTP
+// greeting can be reported alongside the check result.
+type bannerConn struct {
+	net.Conn
+	banner []byte
+	done   bool
+}
+
+func (c *bannerConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if !c.done && n > 0 {
+		c.banner = append(c.banner, b[:n]...)
+		if idx := bytes.IndexByte(c.banner, '\n'); idx >= 0 {
+			c.banner = c.banner[:idx]
+			c.done = true
+		} else if len(c.banner) > 512 {
+			c.done = true
+		}
+	}
+	return n, err
+}
+
+// Banner returns the captured greeting line
+func (c *bannerConn) Banner() string {
+	return strings.TrimSpace(string(c.banner))
 }
 
 // Check performs an SMTP check
-func (s *SMTPChecker) Check() (*CheckResult, error) {
+func (c *SMTPChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckResult, error) {
 	start := time.Now()
+	s := &smtpSession{target: target}
 
 	// Build address
 	host := s.target.Address
@@ -36,14 +91,41 @@ func (s *SMTPChecker) Check() (*CheckResult, error) {
 			port = 25 // Default SMTP port
 		}
 	}
-	address := fmt.Sprintf("%s:%d", host, port)
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+
+	method := "SMTP"
+	if s.target.SMTPUseTLS {
+		method = "SMTPS"
+	}
+	request := RequestDetails{
+		Method: method,
+		URL:    address,
+		Headers: detailHeaders(map[string]interface{}{
+			"mail_from":      s.target.SMTPMailFrom,
+			"mail_to":        s.target.SMTPMailTo,
+			"check_starttls": s.target.SMTPCheckStartTLS && !s.target.SMTPUseTLS,
+			"authenticated":  s.target.SMTPUsername != "",
+		}),
+	}
 
 	// Check basic TCP connection first
-	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	dialer := target.dialer(5 * time.Second)
+	conn, err := dialer.DialContext(ctx, "tcp", address)
 	if err != nil {
+		logger.Warn("SMTP connection failed",
+			zap.Uint32("target_id", target.ID),
+			zap.String("target", target.Name),
+			zap.String("address", address),
+			zap.Error(err),
+		)
 		return &CheckResult{
 			Status: "down",
 			Message: fmt.Sprintf("Connection failed: %v", err),
+			Request: request,
+			Error: &ErrorDetails{
+				Type:    "network_error",
+				Message: err.Error(),
+			},
 		}, err
 	}
 	conn.Close()
@@ -52,24 +134,68 @@ func (s *SMTPChecker) Check() (*CheckResult, error) {
 	// Perform SMTP handshake
 	var result *CheckResult
 	if s.target.SMTPUseTLS {
-		result, err = s.checkSMTPS(address, host)
+		result, err = s.checkSMTPS(ctx, address, host)
 	} else {
-		result, err = s.checkSMTP(address, host)
+		result, err = s.checkSMTP(ctx, address, host)
+	}
+
+	if result != nil {
+		result.Request = request
+		result.Response.Headers = detailHeaders(map[string]interface{}{
+			"greeting_banner": s.greeting,
+			"tls":             s.target.SMTPUseTLS,
+		})
+		result.Certificate = s.certificate
 	}
 
 	if err != nil {
+		logger.Warn("SMTP check failed",
+			zap.Uint32("target_id", target.ID),
+			zap.String("target", target.Name),
+			zap.String("address", address),
+			zap.Error(err),
+		)
 		return result, err
 	}
 
+	logger.Debug("SMTP check completed",
+		zap.Uint32("target_id", target.ID),
+		zap.String("target", target.Name),
+		zap.String("address", address),
+		zap.Int64("response_time", elapsed.Milliseconds()),
+	)
+
 	result.ResponseTime = int64(elapsed.Milliseconds())
 	return result, nil
 }
 
+// withDeadline bounds every read and write of the session by the check's
+// context; net/smtp has no context support and would otherwise wait forever
+// on a server that accepts the connection but never answers
+func withDeadline(ctx context.Context, conn net.Conn) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+}
+
 // checkSMTP performs plain SMTP check
-func (s *SMTPChecker) checkSMTP(address, host string) (*CheckResult, error) {
+func (s *smtpSession) checkSMTP(ctx context.Context, address, host string) (*CheckResult, error) {
 	// Connect to SMTP server
-	client, err := smtp.Dial(address)
+	dialer := s.target.dialer(10 * time.Second)
+	rawConn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return &CheckResult{
+			Status:  "down",
+			Message: fmt.Sprintf("SMTP connection failed: %v", err),
+		}, err
+	}
+	withDeadline(ctx, rawConn)
+	conn := &bannerConn{Conn: rawConn}
+
+	client, err := smtp.NewClient(conn, host)
+	s.greeting = conn.Banner()
 	if err != nil {
+		conn.Close()
 		return &CheckResult{
 			Status:  "down",
 			Message: fmt.Sprintf("SMTP connection failed: %v", err),
@@ -97,6 +223,9 @@ func (s *SMTPChecker) checkSMTP(address, host string) (*CheckResult, error) {
 				Message: fmt.Sprintf("STARTTLS upgrade failed: %v", err),
 			}, err
 		}
+		if state, ok := client.TLSConnectionState(); ok && len(state.PeerCertificates) > 0 {
+			s.certificate = newCertificateInfo(state.PeerCertificates[0])
+		}
 	}
 
 	// Authenticate if credentials provided
@@ -169,27 +298,32 @@ func (s *SMTPChecker) checkSMTP(address, host string) (*CheckResult, error) {
 }
 
 // checkSMTPS performs SMTP over TLS/SSL check
-func (s *SMTPChecker) checkSMTPS(address, host string) (*CheckResult, error) {
+func (s *smtpSession) checkSMTPS(ctx context.Context, address, host string) (*CheckResult, error) {
 	// Create TLS connection
-	conn, err := tls.DialWithDialer(
-		&net.Dialer{Timeout: 10 * time.Second},
-		"tcp",
-		address,
-		&tls.Config{
+	tlsDialer := &tls.Dialer{
+		NetDialer: s.target.dialer(10 * time.Second),
+		Config: &tls.Config{
 			InsecureSkipVerify: false,
 			ServerName:         host,
 		},
-	)
+	}
+	tlsConn, err := tlsDialer.DialContext(ctx, "tcp", address)
 	if err != nil {
 		return &CheckResult{
 			Status:  "down",
 			Message: fmt.Sprintf("SMTPS connection failed: %v", err),
 		}, err
 	}
+	withDeadline(ctx, tlsConn)
+	if state := tlsConn.(*tls.Conn).ConnectionState(); len(state.PeerCertificates) > 0 {
+		s.certificate = newCertificateInfo(state.PeerCertificates[0])
+	}
+	conn := &bannerConn{Conn: tlsConn}
 	defer conn.Close()
 
 	// Create SMTP client
 	client, err := smtp.NewClient(conn, host)
+	s.greeting = conn.Banner()
 	if err != nil {
 		return &CheckResult{
 			Status:  "down",
@@ -309,23 +443,4 @@ func ValidateSMTPAddress(address string) bool {
 		return false
 	}
 	return true
-}
-
-// SMTPCheckerWrapper implements the Checker interface for SMTP monitoring
-type SMTPCheckerWrapper struct{}
-
-func (w *SMTPCheckerWrapper) Check(ctx context.Context, target *MonitorTarget) (*CheckResult, error) {
-	modelTarget := &models.MonitorTarget{
-		Address:           target.Address,
-		Port:              target.Port,
-		SMTPUsername:      target.SMTPUsername,
-		SMTPPassword:      target.SMTPPassword,
-		SMTPUseTLS:        target.SMTPUseTLS,
-		SMTPMailFrom:      target.SMTPMailFrom,
-		SMTPMailTo:        target.SMTPMailTo,
-		SMTPCheckStartTLS: target.SMTPCheckStartTLS,
-	}
-
-	checker := NewSMTPChecker(modelTarget)
-	return checker.Check()
 }
\ No newline at end of file
@@ -0,0 +1,124 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startSMTPServer runs a minimal SMTP server on a local port until the end
+// of the test; RCPT TO is answered with rcptReply. It returns the port.
+func startSMTPServer(t *testing.T, rcptReply string) int32 {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.SetDeadline(time.Now().Add(5 * time.Second))
+				r := bufio.NewReader(conn)
+				reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+				reply("220 mx.example.test ESMTP ready")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					cmd, _, _ := strings.Cut(strings.TrimSpace(line), " ")
+					switch strings.ToUpper(cmd) {
+					case "EHLO", "HELO":
+						reply("250 mx.example.test")
+					case "RCPT":
+						reply(rcptReply)
+					case "QUIT":
+						reply("221 bye")
+						return
+					default:
+						reply("250 ok")
+					}
+				}
+			}()
+		}
+	}()
+	return int32(ln.Addr().(*net.TCPAddr).Port)
+}
+
+func TestSMTPCheckDetails(t *testing.T) {
+	port := startSMTPServer(t, "250 ok")
+	result, err := (&SMTPChecker{}).Check(context.Background(), &MonitorTarget{
+		Name:         "mail",
+		Type:         "smtp",
+		Address:      "127.0.0.1",
+		Port:         port,
+		SMTPMailFrom: "probe@example.test",
+		SMTPMailTo:   "postmaster@example.test",
+	})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if result.Status != "up" {
+		t.Fatalf("status %s: %s, want up", result.Status, result.Message)
+	}
+
+	want := "127.0.0.1:" + strconv.Itoa(int(port))
+	if result.Request.Method != "SMTP" || result.Request.URL != want {
+		t.Errorf("request %s %s, want SMTP %s", result.Request.Method, result.Request.URL, want)
+	}
+	for key, value := range map[string]string{
+		"mail_from":     "probe@example.test",
+		"mail_to":       "postmaster@example.test",
+		"authenticated": "false",
+	} {
+		if got := result.Request.Headers[key]; got != value {
+			t.Errorf("request header %s = %q, want %q", key, got, value)
+		}
+	}
+	if got := result.Response.Headers["greeting_banner"]; got != "220 mx.example.test ESMTP ready" {
+		t.Errorf("greeting_banner = %q", got)
+	}
+}
+
+// A failed check still reports what was asked for
+func TestSMTPCheckDetailsOnFailure(t *testing.T) {
+	port := startSMTPServer(t, "550 no such user")
+	result, _ := (&SMTPChecker{}).Check(context.Background(), &MonitorTarget{
+		Name:       "mail",
+		Type:       "smtp",
+		Address:    "127.0.0.1",
+		Port:       port,
+		SMTPMailTo: "nobody@example.test",
+	})
+	if result.Status != "degraded" || !strings.Contains(result.Message, "RCPT TO") {
+		t.Fatalf("status %s: %s, want degraded on RCPT TO", result.Status, result.Message)
+	}
+	if result.Request.Headers["mail_to"] != "nobody@example.test" || result.Response.Headers["greeting_banner"] == "" {
+		t.Errorf("request %v response %v, want mail_to and the greeting", result.Request.Headers, result.Response.Headers)
+	}
+
+	// Nothing listens on port 1
+	result, _ = (&SMTPChecker{}).Check(context.Background(), &MonitorTarget{
+		Name:    "mail",
+		Type:    "smtp",
+		Address: "127.0.0.1",
+		Port:    1,
+	})
+	if result.Status != "down" || result.Error == nil || result.Error.Type != "network_error" {
+		t.Fatalf("status %s error %+v, want down with a network_error", result.Status, result.Error)
+	}
+	if result.Request.URL != "127.0.0.1:1" {
+		t.Errorf("request URL = %q, want 127.0.0.1:1", result.Request.URL)
+	}
+}
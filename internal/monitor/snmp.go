@@ -3,22 +3,40 @@ package monitor
 import (
 	"context"
 	"fmt"
+	"net"
 	"strconv"
 	"time"
 
 	"github.com/gosnmp/gosnmp"
-	"monitor/internal/models"
+	"go.uber.org/zap"
+	"monitor/internal/logger"
 )
 
 // SNMPChecker implements SNMP monitoring
-type SNMPChecker struct {
-	target *models.MonitorTarget
+func init() {
+	RegisterType(TypeSpec{
+		Type:        "snmp",
+		DisplayName: "SNMP",
+		Fields: []FieldSpec{
+			{Name: "port", Kind: FieldInteger, Default: 161, Min: intBound(1), Max: intBound(65535), Description: "SNMP 端口"},
+			{Name: "snmp_community", Kind: FieldString, Default: "public", Description: "Community"},
+			{Name: "snmp_oid", Kind: FieldString, Default: "1.3.6.1.2.1.1.1.0", Description: "查询的 OID，默认 sysDescr.0"},
+			{Name: "snmp_version", Kind: FieldString, Default: "v1", Enum: []string{"v1", "v2", "v2c", "v3"}, Description: "SNMP 版本"},
+			{Name: "snmp_expected_value", Kind: FieldString, Description: "期望值，与 snmp_operator 一起使用"},
+			{Name: "snmp_operator", Kind: FieldString, Enum: []string{"eq", "ne", "gt", "lt", "ge", "le"}, Description: "比较方式，非数字值只支持 eq/ne"},
+			{Name: "ping_timeout", Kind: FieldInteger, Min: intBound(1), Description: "超时（毫秒）"},
+		},
+		Results: []ResultField{
+			{Name: "oid", In: "data", Description: "查询的 OID"},
+			{Name: "value", In: "data", Description: "返回值"},
+			{Name: "type", In: "data", Description: "返回值的 ASN.1 类型"},
+			{Name: "community", In: "data", Description: "使用的 community"},
+			{Name: "version", In: "data", Description: "使用的 SNMP 版本"},
+		},
+	}, func() Checker { return &SNMPChecker{} })
 }
 
-// NewSNMPChecker creates a new SNMP checker
-func NewSNMPChecker(target *models.MonitorTarget) *SNMPChecker {
-	return &SNMPChecker{target: target}
-}
+type SNMPChecker struct{}
 
 // Check performs an SNMP check
 func (s *SNMPChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckResult, error) {
@@ -58,22 +76,59 @@ func (s *SNMPChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckR
 	if client.Port == 0 {
 		client.Port = 161 // Default SNMP port
 	}
+	if target.NetworkPolicy != nil {
+		client.Control = target.NetworkPolicy.Control
+	}
+
+	request := RequestDetails{
+		Method: "SNMP GET",
+		URL:    net.JoinHostPort(client.Target, strconv.Itoa(int(client.Port))),
+		Headers: detailHeaders(map[string]interface{}{
+			"oid":      oid,
+			"version":  target.SNMPVersion,
+			"operator": target.SNMPOperator,
+			"expected": target.SNMPExpectedValue,
+		}),
+	}
 
 	// Perform SNMP GET
 	oids := []string{oid}
 	result, err := client.Get(oids)
 	if err != nil {
+		logger.Warn("SNMP query failed",
+			zap.Uint32("target_id", target.ID),
+			zap.String("target", target.Name),
+			zap.String("address", request.URL),
+			zap.String("oid", oid),
+			zap.Error(err),
+		)
 		return &CheckResult{
 			Status:  "down",
 			Message: fmt.Sprintf("SNMP query failed: %v", err),
+			Request: request,
+			Error: &ErrorDetails{
+				Type:    "snmp_error",
+				Message: err.Error(),
+			},
 		}, err
 	}
 
 	// Check if we got results
 	if len(result.Variables) == 0 {
+		logger.Warn("SNMP query returned no variables",
+			zap.Uint32("target_id", target.ID),
+			zap.String("target", target.Name),
+			zap.String("address", request.URL),
+			zap.String("oid", oid),
+		)
 		return &CheckResult{
 			Status:  "down",
 			Message: "No SNMP response received",
+			Request: request,
+			Error: &ErrorDetails{
+				Type:    "snmp_error",
+				Message: "empty SNMP response",
+			},
 		}, fmt.Errorf("empty SNMP response")
 	}
 
@@ -159,6 +214,15 @@ func (s *SNMPChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckR
 
 	elapsed := time.Since(start)
 
+	logger.Debug("SNMP check completed",
+		zap.Uint32("target_id", target.ID),
+		zap.String("target", target.Name),
+		zap.String("address", request.URL),
+		zap.String("oid", oid),
+		zap.String("value", actualValue),
+		zap.String("status", status),
+	)
+
 	return &CheckResult{
 		Status:       status,
 		ResponseTime: int64(elapsed.Milliseconds()),
@@ -170,24 +234,13 @@ func (s *SNMPChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckR
 			"community":  community,
 			"version":    target.SNMPVersion,
 		},
+		Request: request,
+		Response: ResponseDetails{
+			Headers: detailHeaders(map[string]interface{}{
+				"oid":   oid,
+				"value": actualValue,
+				"type":  variable.Type.String(),
+			}),
+		},
 	}, nil
-}
-
-// SNMPCheckerWrapper implements the Checker interface for SNMP monitoring
-type SNMPCheckerWrapper struct{}
-
-func (w *SNMPCheckerWrapper) Check(ctx context.Context, target *MonitorTarget) (*CheckResult, error) {
-	modelTarget := &models.MonitorTarget{
-		Address:       target.Address,
-		Port:          target.Port,
-		SNMPCommunity: target.SNMPCommunity,
-		SNMPOID:       target.SNMPOID,
-		SNMPVersion:   target.SNMPVersion,
-		SNMPExpectedValue: target.SNMPExpectedValue,
-		SNMPOperator:  target.SNMPOperator,
-		PingTimeout:   target.PingTimeout,
-	}
-
-	checker := NewSNMPChecker(modelTarget)
-	return checker.Check(ctx, target)
-}
+}
\ No newline at end of file
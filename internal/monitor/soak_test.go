@@ -0,0 +1,142 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+const soakTargets = 5000
+
+// goroutinesSettle waits for the goroutine count to drop to at most max
+func goroutinesSettle(max int) int {
+	deadline := time.Now().Add(5 * time.Second)
+	n := runtime.NumGoroutine()
+	for n > max && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		n = runtime.NumGoroutine()
+	}
+	return n
+}
+
+func heapInUse() uint64 {
+	runtime.GC()
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return mem.HeapInuse
+}
+
+// Thousands of targets checked every second must not grow goroutines with
+// the number of targets, and the service must give everything back on Stop
+func TestSoakFiveThousandTargets(t *testing.T) {
+	if testing.Short() {
+		t.Skip("soak test")
+	}
+	if err := database.InitMemoryDB(t.Name()); err != nil {
+		t.Fatalf("InitMemoryDB: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := database.GetDB().DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	baseline := runtime.NumGoroutine()
+	heapBefore := heapInUse()
+
+	const workers = 20
+	s := NewService(nil, ServiceOptions{Workers: workers, StartupJitter: time.Hour})
+	for id := uint32(1); id <= soakTargets; id++ {
+		// Port 1 on loopback refuses at once, the checks cost no waiting;
+		// no sinks keeps the file log out of the package directory
+		target := &MonitorTarget{ID: id, Name: fmt.Sprintf("soak-%d", id), Type: "tcp", Address: "127.0.0.1", Port: 1, Interval: 1, Sinks: Sinks{}}
+		if err := s.AddTarget(target); err != nil {
+			t.Fatalf("AddTarget %d: %v", id, err)
+		}
+	}
+
+	deadline := time.Now().Add(20 * time.Second)
+	var checked int64
+	for checked < soakTargets && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+		database.GetDB().Model(&models.MonitorStatus{}).Count(&checked)
+	}
+	if checked < soakTargets {
+		t.Fatalf("only %d of %d targets checked", checked, soakTargets)
+	}
+
+	// One scheduler for every target, not a goroutine each
+	if n := runtime.NumGoroutine(); n > baseline+workers+50 {
+		t.Errorf("%d goroutines with %d targets, baseline %d", n, soakTargets, baseline)
+	}
+	stats := s.RuntimeStats()
+	for _, buffer := range stats.Buffers {
+		if buffer.Name == "targets" && buffer.Len != soakTargets {
+			t.Errorf("runtime stats report %d targets, want %d", buffer.Len, soakTargets)
+		}
+		if buffer.Cap > 0 && buffer.Len > buffer.Cap {
+			t.Errorf("buffer %s over its cap: %d > %d", buffer.Name, buffer.Len, buffer.Cap)
+		}
+	}
+	if grown := heapInUse() - heapBefore; grown > 64<<20 {
+		t.Errorf("heap grew by %d MB for %d targets", grown>>20, soakTargets)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if n := goroutinesSettle(baseline + 5); n > baseline+5 {
+		t.Errorf("%d goroutines left after Stop, baseline %d", n, baseline)
+	}
+}
+
+func TestCertificateCacheIsBounded(t *testing.T) {
+	c := newCertificateCache()
+	now := time.Now()
+	for id := uint32(1); id <= 2*MaxCertificateCacheEntries; id++ {
+		c.due(id, "fingerprint", now)
+	}
+	if n := c.size(); n != MaxCertificateCacheEntries {
+		t.Errorf("cache holds %d targets, want the cap %d", n, MaxCertificateCacheEntries)
+	}
+	// The newest targets are kept, the oldest were evicted
+	if due, _ := c.due(2*MaxCertificateCacheEntries, "fingerprint", now); due {
+		t.Error("recent target was evicted")
+	}
+	if due, _ := c.due(1, "fingerprint", now); !due {
+		t.Error("oldest target was kept")
+	}
+}
+
+func TestCheckEventStreamsAreBounded(t *testing.T) {
+	s := newTestService(t)
+	var cancels []func()
+	for i := 0; i < MaxCheckJobSubscribers; i++ {
+		_, cancel, err := s.SubscribeCheckJobs()
+		if err != nil {
+			t.Fatalf("stream %d: %v", i+1, err)
+		}
+		cancels = append(cancels, cancel)
+	}
+	if _, _, err := s.SubscribeCheckJobs(); !errors.Is(err, ErrTooManyStreams) {
+		t.Fatalf("stream over the cap: %v, want ErrTooManyStreams", err)
+	}
+
+	cancels[0]()
+	_, cancel, err := s.SubscribeCheckJobs()
+	if err != nil {
+		t.Fatalf("stream after one closed: %v", err)
+	}
+	cancel()
+	for _, cancel := range cancels[1:] {
+		cancel()
+	}
+}
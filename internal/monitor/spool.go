@@ -0,0 +1,239 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/elasticsearch"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+	"monitor/internal/spool"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SpoolPolicy keeps the results that could not be written to the database
+// history or to Elasticsearch on disk and writes them once the store is back
+type SpoolPolicy struct {
+	Dir            string        // a history and an elasticsearch directory are created in it
+	MaxBytes       int64         // per store; beyond it the oldest records are dropped
+	ReplayInterval time.Duration // time between two attempts to write the spooled records
+}
+
+// spooledLog is an ES log entry waiting in the spool. The nonce is not part
+// of the document, only of its ID, so it is kept next to it.
+type spooledLog struct {
+	Entry *elasticsearch.LogEntry `json:"entry"`
+	Nonce string                  `json:"nonce"`
+}
+
+// StartSpool opens the spools, then replays them now and every
+// ReplayInterval until ctx is done. It must be called before checks start;
+// records left by a previous run are replayed first.
+func (s *Service) StartSpool(ctx context.Context, policy SpoolPolicy) error {
+	history, err := spool.Open("history", filepath.Join(policy.Dir, "history"), policy.MaxBytes)
+	if err != nil {
+		return err
+	}
+	s.historySpool = history
+	if s.es != nil {
+		if s.esSpool, err = spool.Open("elasticsearch", filepath.Join(policy.Dir, "elasticsearch"), policy.MaxBytes); err != nil {
+			return err
+		}
+	}
+
+	for _, sp := range s.spools() {
+		if n := sp.Len(); n > 0 {
+			logger.Info("Spooled results left from the last run", zap.String("spool", sp.Name()), zap.Int("records", n))
+		}
+	}
+
+	go func() {
+		ticker := s.clock.NewTicker(policy.ReplayInterval)
+		defer ticker.Stop()
+		for {
+			s.ReplaySpools()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *Service) spools() []*spool.Spool {
+	var spools []*spool.Spool
+	for _, sp := range []*spool.Spool{s.historySpool, s.esSpool} {
+		if sp != nil {
+			spools = append(spools, sp)
+		}
+	}
+	return spools
+}
+
+// SpoolStats returns the state of each spool, nil when the spool is disabled
+func (s *Service) SpoolStats() []spool.Stats {
+	var stats []spool.Stats
+	for _, sp := range s.spools() {
+		stats = append(stats, sp.Stats())
+	}
+	return stats
+}
+
+// ReplaySpools writes the spooled records to their store, oldest first. A
+// spool stops at the first batch its store does not accept and is retried at
+// the next interval.
+func (s *Service) ReplaySpools() {
+	if s.historySpool != nil {
+		s.replay(s.historySpool, s.replayHistory)
+	}
+	if s.esSpool != nil {
+		s.replay(s.esSpool, s.replayLogs)
+	}
+}
+
+func (s *Service) replay(sp *spool.Spool, write func([]json.RawMessage) error) {
+	if sp.Len() == 0 {
+		return
+	}
+	n, err := sp.Replay(write)
+	if n > 0 {
+		logger.Info("Spooled results written", zap.String("spool", sp.Name()), zap.Int("records", n), zap.Int("remaining", sp.Len()))
+	}
+	if err != nil {
+		logger.Warn("Failed to write spooled results, will retry",
+			zap.String("spool", sp.Name()), zap.Int("remaining", sp.Len()), zap.Error(err))
+	}
+}
+
+// spoolHistory keeps a history row the database did not accept
+func (s *Service) spoolHistory(history *models.MonitorHistory, cause error) {
+	if s.historySpool == nil {
+		logger.Warn("Failed to save history", zap.Uint32("target_id", history.TargetID), zap.Error(cause))
+		return
+	}
+	if err := s.historySpool.Append(history); err != nil {
+		logger.Error("Failed to save history, spooling failed too",
+			zap.Uint32("target_id", history.TargetID), zap.NamedError("cause", cause), zap.Error(err))
+		return
+	}
+	logger.Warn("Failed to save history, result spooled", zap.Uint32("target_id", history.TargetID), zap.Error(cause))
+}
+
+// spoolLog keeps a log entry that could not be written to Elasticsearch
+func (s *Service) spoolLog(entry *elasticsearch.LogEntry, cause error) {
+	if s.esSpool == nil {
+		logger.Warn("Failed to index log to ES", zap.Uint32("target_id", entry.TargetID), zap.Error(cause))
+		return
+	}
+	if err := s.esSpool.Append(spooledLog{Entry: entry, Nonce: entry.Nonce}); err != nil {
+		logger.Error("Failed to index log to ES, spooling failed too",
+			zap.Uint32("target_id", entry.TargetID), zap.NamedError("cause", cause), zap.Error(err))
+		return
+	}
+	logger.Warn("Failed to index log to ES, log spooled", zap.Uint32("target_id", entry.TargetID), zap.Error(cause))
+}
+
+// replayHistory inserts spooled history rows. check_id is unique, so rows
+// already written before a failure or a restart are skipped.
+func (s *Service) replayHistory(records []json.RawMessage) error {
+	rows := make([]models.MonitorHistory, 0, len(records))
+	for _, raw := range records {
+		var row models.MonitorHistory
+		if err := json.Unmarshal(raw, &row); err != nil {
+			logger.Warn("Dropping unreadable spooled history record", zap.Error(err))
+			continue
+		}
+		row.ID = 0
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	db := database.GetDB()
+	insert := func(rows []models.MonitorHistory) error {
+		return db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "check_id"}}, DoNothing: true}).Create(&rows).Error
+	}
+	if err := insert(rows); err != nil {
+		if !databaseReachable(db) {
+			return err
+		}
+		// The database is up but rejects the batch, e.g. a row of a target
+		// deleted meanwhile: write the rows one by one and drop the rejected
+		// ones rather than blocking the spool
+		for i := range rows {
+			if err := insert(rows[i : i+1]); err != nil {
+				if !databaseReachable(db) {
+					return err
+				}
+				logger.Warn("Dropping spooled history record the database rejects",
+					zap.Uint32("target_id", rows[i].TargetID), zap.Time("checked_at", rows[i].CheckedAt), zap.Error(err))
+			}
+		}
+	}
+
+	targets := make(map[uint32]bool)
+	for _, row := range rows {
+		if !targets[row.TargetID] {
+			targets[row.TargetID] = true
+			s.updateUptime(row.TargetID)
+			// The replayed rows may fall in days already cached as over
+			s.heatmap.invalidate(row.TargetID)
+		}
+		// and in hours already rolled up
+		s.rollup.stale(row.CheckedAt)
+	}
+	s.InvalidateStatus()
+	return nil
+}
+
+func databaseReachable(db *gorm.DB) bool {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return sqlDB.PingContext(ctx) == nil
+}
+
+// replayLogs writes spooled log entries with create operations, so entries
+// already indexed count as conflicts. Entries ES rejects for good, e.g. a
+// mapping error, are dropped; a batch with entries rejected for now, e.g. on
+// a full write queue, is retried as a whole.
+func (s *Service) replayLogs(records []json.RawMessage) error {
+	entries := make([]*elasticsearch.LogEntry, 0, len(records))
+	for _, raw := range records {
+		var record spooledLog
+		if err := json.Unmarshal(raw, &record); err != nil || record.Entry == nil {
+			logger.Warn("Dropping unreadable spooled log record", zap.Error(err))
+			continue
+		}
+		record.Entry.Nonce = record.Nonce
+		entries = append(entries, record.Entry)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, time.Minute)
+	defer cancel()
+	result, err := s.es.BulkCreate(ctx, entries)
+	if err != nil {
+		return err
+	}
+	if result.Retryable > 0 {
+		return fmt.Errorf("elasticsearch rejected %d of %d spooled logs for now: %v", result.Retryable, len(entries), result.Errors)
+	}
+	if result.Failed > 0 {
+		logger.Warn("Dropping spooled logs Elasticsearch rejects",
+			zap.Int("failed", result.Failed), zap.Strings("errors", result.Errors))
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+// History rows the database does not accept are spooled and written once
+// it is back, and a row replayed twice is written once
+func TestSpoolHistory(t *testing.T) {
+	s := newTestService(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := s.StartSpool(ctx, SpoolPolicy{Dir: t.TempDir(), MaxBytes: 1 << 20, ReplayInterval: time.Hour}); err != nil {
+		t.Fatalf("StartSpool: %v", err)
+	}
+
+	db := database.GetDB()
+	if err := db.Exec("ALTER TABLE monitor_history RENAME TO monitor_history_away").Error; err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	checkID := "1-check"
+	row := &models.MonitorHistory{TargetID: 1, Status: "down", Message: "refused", CheckedAt: epoch, CheckID: &checkID}
+	s.insertHistory([]pendingHistory{{row: row}})
+	if stats := s.SpoolStats(); len(stats) != 1 || stats[0].Name != "history" || stats[0].Records != 1 {
+		t.Fatalf("spool stats %+v, want one history record", stats)
+	}
+
+	if err := db.Exec("ALTER TABLE monitor_history_away RENAME TO monitor_history").Error; err != nil {
+		t.Fatalf("rename back: %v", err)
+	}
+	// The same check spooled again, as after a restart mid replay
+	dup := *row
+	s.spoolHistory(&dup, nil)
+	s.ReplaySpools()
+	if stats := s.SpoolStats(); stats[0].Records != 0 || stats[0].Replayed != 2 || stats[0].LastError != "" {
+		t.Errorf("spool stats after replay %+v", stats)
+	}
+
+	var rows []models.MonitorHistory
+	db.Find(&rows)
+	if len(rows) != 1 || rows[0].Status != "down" || rows[0].CheckID == nil || *rows[0].CheckID != checkID || !rows[0].CheckedAt.Equal(epoch) {
+		t.Errorf("history rows %+v, want the spooled row once", rows)
+	}
+}
@@ -15,6 +15,66 @@ import (
 	"go.uber.org/zap"
 )
 
+// Default certificate expiry thresholds, used when a target leaves them unset
+const (
+	DefaultSSLWarnDays     = 30
+	DefaultSSLCriticalDays = 7
+)
+
+// SSLThresholds returns the warn/critical days with zero values replaced by the defaults
+func SSLThresholds(warnDays, criticalDays int) (int, int) {
+	if warnDays == 0 {
+		warnDays = DefaultSSLWarnDays
+	}
+	if criticalDays == 0 {
+		criticalDays = DefaultSSLCriticalDays
+	}
+	return warnDays, criticalDays
+}
+
+// ValidateSSLThresholds checks that warn > critical > 0
+func ValidateSSLThresholds(warnDays, criticalDays int) error {
+	if criticalDays <= 0 {
+		return fmt.Errorf("ssl_critical_days must be greater than 0")
+	}
+	if warnDays <= criticalDays {
+		return fmt.Errorf("ssl_warn_days (%d) must be greater than ssl_critical_days (%d)", warnDays, criticalDays)
+	}
+	return nil
+}
+
+func init() {
+	RegisterType(TypeSpec{
+		Type:        "ssl",
+		Aliases:     []string{"tls"},
+		DisplayName: "SSL/TLS 证书",
+		Fields: []FieldSpec{
+			{Name: "port", Kind: FieldInteger, Default: 443, Min: intBound(1), Max: intBound(65535), Description: "端口，也可以写在地址里"},
+			{Name: "ssl_warn_days", Kind: FieldInteger, Default: DefaultSSLWarnDays, Min: intBound(1), Description: "到期前多少天告警，需大于 ssl_critical_days"},
+			{Name: "ssl_critical_days", Kind: FieldInteger, Default: DefaultSSLCriticalDays, Min: intBound(1), Description: "到期前多少天标记为严重"},
+			{Name: "ssl_get_chain", Kind: FieldBoolean, Description: "获取证书链"},
+			tlsClientCertField,
+			tlsClientKeyField,
+			tlsCACertField,
+			tlsSkipVerifyField,
+			tlsPinSHA256Field,
+		},
+		Results: []ResultField{
+			{Name: "issuer", In: "response_headers", Description: "证书签发者"},
+			{Name: "subject", In: "response_headers", Description: "证书主题"},
+			{Name: "serial", In: "response_headers", Description: "证书序列号"},
+			{Name: "fingerprint", In: "response_headers", Description: "证书 SHA-256 指纹，用于关联共用证书的目标"},
+			{Name: "not_before", In: "response_headers", Description: "生效时间"},
+			{Name: "not_after", In: "response_headers", Description: "到期时间"},
+			{Name: "days_until_expiry", In: "response_headers", Description: "剩余天数"},
+			{Name: "chain_count", In: "response_headers", Description: "证书链长度"},
+			{Name: "chain_summary", In: "response_headers", Description: "证书链摘要"},
+			{Name: "certificate_chain", In: "data", Description: "证书链详情，启用 ssl_get_chain 时返回"},
+			certificateInfoResult,
+		},
+	}, func() Checker { return &SSLChecker{} })
+}
+
 type SSLChecker struct{}
 
 func (c *SSLChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckResult, error) {
@@ -66,11 +126,20 @@ func (c *SSLChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckRe
 		zap.String("final_address", address),
 	)
 
+	// The target's CA replaces the system roots, its client certificate is
+	// offered to servers requiring mutual TLS; the certificate is verified
+	// unless tls_skip_verify is set, and compared to tls_pin_sha256
+	tlsConfig, err := target.tlsConfig()
+	if err != nil {
+		return tlsConfigResult(err, time.Since(start).Milliseconds()), nil
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+
 	// Create TLS connection
-	dialer := &net.Dialer{Timeout: 10 * time.Second}
-	conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{
-		InsecureSkipVerify: false, // We want to verify the certificate
-	})
+	dialer := target.dialer(10 * time.Second)
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
 
 	if err != nil {
 		logger.Warn("SSL/TLS connection failed",
@@ -80,14 +149,22 @@ func (c *SSLChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckRe
 		)
 		responseTime := time.Since(start).Milliseconds()
 
+		message := fmt.Sprintf("SSL/TLS connection failed: %v", err)
+		if hint := certTimeHint(err, target.ID); hint != "" {
+			message = fmt.Sprintf("%s (%s)", message, hint)
+		}
+		details := pinMismatchDetails(err)
+		if details == nil {
+			details = &ErrorDetails{
+				Type:    "ssl_error",
+				Message: err.Error(),
+			}
+		}
 		return &CheckResult{
 			Status:       "down",
 			ResponseTime: responseTime,
-			Message:      fmt.Sprintf("SSL/TLS connection failed: %v", err),
-			Error: &ErrorDetails{
-				Type:    "ssl_error",
-				Message: err.Error(),
-			},
+			Message:      message,
+			Error:        details,
 		}, nil
 	}
 	defer conn.Close()
@@ -111,16 +188,17 @@ func (c *SSLChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckRe
 	daysUntilExpiry := int(time.Until(leafCert.NotAfter).Hours() / 24)
 
 	// Determine status based on certificate expiry
+	warnDays, criticalDays := SSLThresholds(target.SSLWarnDays, target.SSLCriticalDays)
 	status := "up"
 	message := fmt.Sprintf("Certificate expires in %d days", daysUntilExpiry)
 
 	if daysUntilExpiry < 0 {
 		status = "down"
 		message = fmt.Sprintf("Certificate expired %d days ago", -daysUntilExpiry)
-	} else if daysUntilExpiry <= target.SSLCriticalDays {
+	} else if daysUntilExpiry <= criticalDays {
 		status = "critical"
 		message = fmt.Sprintf("Certificate expires in %d days (CRITICAL)", daysUntilExpiry)
-	} else if daysUntilExpiry <= target.SSLWarnDays {
+	} else if daysUntilExpiry <= warnDays {
 		status = "warning"
 		message = fmt.Sprintf("Certificate expires in %d days (WARNING)", daysUntilExpiry)
 	}
@@ -201,12 +279,15 @@ func (c *SSLChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckRe
 	)
 
 	// Prepare response headers with certificate info
+	certificate := newCertificateInfo(leafCert)
 	headers := map[string]string{
+		"fingerprint":   certificate.Fingerprint,
 		"issuer":        leafCert.Issuer.CommonName,
 		"subject":       leafCert.Subject.CommonName,
 		"serial":        formatSerial(leafCert.SerialNumber),
 		"not_before":    leafCert.NotBefore.Format(time.RFC3339),
 		"not_after":     leafCert.NotAfter.Format(time.RFC3339),
+		"days_until_expiry": fmt.Sprintf("%d", daysUntilExpiry),
 		"chain_count":   fmt.Sprintf("%d", len(certs)),
 		"chain_summary": chainSummary,
 	}
@@ -216,6 +297,9 @@ func (c *SSLChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckRe
 	if len(chainInfo) > 0 {
 		data["certificate_chain"] = chainInfo
 	}
+	if target.TLSSkipVerify || target.TLSPinSHA256 != "" {
+		data["certificate_info"] = target.certificateData(leafCert)
+	}
 
 	return &CheckResult{
 		Status:       status,
@@ -229,7 +313,8 @@ func (c *SSLChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckRe
 			StatusCode: daysUntilExpiry,
 			Headers:    headers,
 		},
-		Data: data,
+		Data:        data,
+		Certificate: certificate,
 	}, nil
 }
 
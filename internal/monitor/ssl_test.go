@@ -0,0 +1,90 @@
+package monitor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// checkCertExpiry runs the SSL checker against a local server whose
+// certificate expires in days (plus a margin so the count doesn't round down)
+func checkCertExpiry(t *testing.T, days, warnDays, criticalDays int) *CheckResult {
+	t.Helper()
+	cert := newTestCert(t, time.Now().Add(time.Duration(days)*24*time.Hour+time.Hour))
+	addr := startTLSServer(t, cert, nil)
+
+	result, err := (&SSLChecker{}).Check(context.Background(), &MonitorTarget{
+		Name:            "cert",
+		Type:            "ssl",
+		Address:         addr,
+		TLSCACertPEM:    cert.PEM,
+		SSLWarnDays:     warnDays,
+		SSLCriticalDays: criticalDays,
+	})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	return result
+}
+
+// Targets saved before the defaults existed have 0 thresholds; a certificate
+// close to expiry must still reach warning and critical
+func TestSSLExpiryDefaultThresholds(t *testing.T) {
+	for _, tc := range []struct {
+		days int
+		want string
+	}{
+		{1, "critical"},
+		{DefaultSSLCriticalDays, "critical"},
+		{DefaultSSLCriticalDays + 1, "warning"},
+		{DefaultSSLWarnDays, "warning"},
+		{DefaultSSLWarnDays + 1, "up"},
+	} {
+		result := checkCertExpiry(t, tc.days, 0, 0)
+		if result.Status != tc.want {
+			t.Errorf("%d days left: status = %s (%s), want %s", tc.days, result.Status, result.Message, tc.want)
+		}
+	}
+}
+
+func TestSSLExpiryCustomThresholds(t *testing.T) {
+	if result := checkCertExpiry(t, 60, 90, 45); result.Status != "warning" {
+		t.Errorf("60 days left with warn 90: status = %s, want warning", result.Status)
+	}
+	if result := checkCertExpiry(t, 10, 5, 2); result.Status != "up" {
+		t.Errorf("10 days left with warn 5: status = %s, want up", result.Status)
+	}
+}
+
+func TestSSLExpiredCertificate(t *testing.T) {
+	result := checkCertExpiry(t, -3, 0, 0)
+	if result.Status != "down" || !strings.Contains(result.Message, "expired") {
+		t.Errorf("expired certificate: status = %s (%s), want down", result.Status, result.Message)
+	}
+}
+
+func TestSSLThresholds(t *testing.T) {
+	warn, critical := SSLThresholds(0, 0)
+	if warn != DefaultSSLWarnDays || critical != DefaultSSLCriticalDays {
+		t.Errorf("SSLThresholds(0, 0) = %d, %d", warn, critical)
+	}
+	if warn, critical := SSLThresholds(14, 0); warn != 14 || critical != DefaultSSLCriticalDays {
+		t.Errorf("SSLThresholds(14, 0) = %d, %d", warn, critical)
+	}
+
+	for _, tc := range []struct {
+		warn, critical int
+		ok             bool
+	}{
+		{30, 7, true},
+		{8, 7, true},
+		{7, 7, false},
+		{5, 7, false},
+		{30, 0, false},
+	} {
+		if err := ValidateSSLThresholds(tc.warn, tc.critical); (err == nil) != tc.ok {
+			t.Errorf("ValidateSSLThresholds(%d, %d) = %v", tc.warn, tc.critical, err)
+		}
+	}
+}
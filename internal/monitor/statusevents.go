@@ -0,0 +1,113 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+
+	"monitor/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// statusEventQueueSize is how many status changes each subscriber buffers;
+// beyond that it misses them
+const statusEventQueueSize = 64
+
+// Types of StatusChangeEvent
+const (
+	// EventStatusChange is a change of the status of a target
+	EventStatusChange = "status_change"
+	// EventContentChanged is a change of the body hash of a target with
+	// track_content_hash; the status may be unchanged
+	EventContentChanged = "content_changed"
+)
+
+// StatusChangeEvent is published when a saved result changes the status of
+// a target, or the content it tracks
+type StatusChangeEvent struct {
+	Type      string    `json:"type"` // EventStatusChange or EventContentChanged
+	TargetID  uint32    `json:"target_id"`
+	OldStatus string    `json:"old_status"` // empty for the first result of a target
+	NewStatus string    `json:"new_status"`
+	Message   string    `json:"message"`
+	ChangedAt time.Time `json:"changed_at"`
+	// Set on content_changed events
+	OldContentHash string `json:"old_content_hash,omitempty"`
+	NewContentHash string `json:"new_content_hash,omitempty"`
+}
+
+// statusSubscriber is one channel returned by Subscribe
+type statusSubscriber struct {
+	ch chan StatusChangeEvent
+	// Events missed since the subscriber last kept up
+	dropped int
+}
+
+// statusEvents fans out status changes to the subscribers
+type statusEvents struct {
+	mu          sync.Mutex
+	subscribers map[*statusSubscriber]struct{}
+}
+
+func newStatusEvents() *statusEvents {
+	return &statusEvents{subscribers: make(map[*statusSubscriber]struct{})}
+}
+
+// Subscribe returns a channel receiving every status and content change
+// saved after the call, and a function that unsubscribes and closes the channel. Events are
+// sent from the check path without waiting: a subscriber that falls more
+// than statusEventQueueSize events behind misses the newer ones.
+func (s *Service) Subscribe() (<-chan StatusChangeEvent, func()) {
+	e := s.statusEvents
+	sub := &statusSubscriber{ch: make(chan StatusChangeEvent, statusEventQueueSize)}
+
+	e.mu.Lock()
+	e.subscribers[sub] = struct{}{}
+	e.mu.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			e.mu.Lock()
+			delete(e.subscribers, sub)
+			e.mu.Unlock()
+			close(sub.ch)
+		})
+	}
+}
+
+// publish sends an event to every subscriber without blocking. A dropped
+// event is logged once per run of drops, not once per event.
+func (e *statusEvents) publish(event StatusChangeEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for sub := range e.subscribers {
+		select {
+		case sub.ch <- event:
+			if sub.dropped > 0 {
+				logger.Info("Status change subscriber caught up",
+					zap.Int("dropped", sub.dropped))
+				sub.dropped = 0
+			}
+		default:
+			if sub.dropped == 0 {
+				logger.Warn("Status change subscriber is not keeping up, dropping events",
+					zap.Int("queue_size", cap(sub.ch)),
+					zap.Uint32("target_id", event.TargetID),
+					zap.String("new_status", event.NewStatus))
+			}
+			sub.dropped++
+		}
+	}
+}
+
+// sizes returns the number of subscribers and of events waiting in their queues
+func (e *statusEvents) sizes() (subscribers, queued int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for sub := range e.subscribers {
+		queued += len(sub.ch)
+	}
+	return len(e.subscribers), queued
+}
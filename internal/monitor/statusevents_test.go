@@ -0,0 +1,80 @@
+package monitor
+
+import "testing"
+
+// drain returns the events waiting in ch
+func drain(ch <-chan StatusChangeEvent) []StatusChangeEvent {
+	var events []StatusChangeEvent
+	for {
+		select {
+		case event := <-ch:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	s := newTestService(t)
+	target := &MonitorTarget{ID: 1, Name: "db", Type: "tcp", Address: "127.0.0.1", Port: 1, Interval: 3600}
+	if err := s.AddTarget(target); err != nil {
+		t.Fatalf("AddTarget: %v", err)
+	}
+	s.SetSinks(target.ID, Sinks{SinkDBHistory})
+
+	first, unsubscribeFirst := s.Subscribe()
+	second, unsubscribeSecond := s.Subscribe()
+	defer unsubscribeSecond()
+	for _, status := range []string{"up", "up", "down", "down", "up"} {
+		s.saveResult(target, &CheckResult{Status: status, Message: status})
+	}
+
+	for _, ch := range []<-chan StatusChangeEvent{first, second} {
+		events := drain(ch)
+		if len(events) != 3 {
+			t.Fatalf("events %+v, want the three changes", events)
+		}
+		for i, want := range [][2]string{{"", "up"}, {"up", "down"}, {"down", "up"}} {
+			if e := events[i]; e.Type != EventStatusChange || e.TargetID != 1 || e.OldStatus != want[0] || e.NewStatus != want[1] || e.Message != want[1] || e.ChangedAt.IsZero() {
+				t.Errorf("event %d = %+v, want %s to %s", i, e, want[0], want[1])
+			}
+		}
+	}
+
+	// Unsubscribing closes the channel once, and later changes skip it
+	unsubscribeFirst()
+	unsubscribeFirst()
+	if _, ok := <-first; ok {
+		t.Error("channel still open after unsubscribe")
+	}
+	if subscribers, _ := s.statusEvents.sizes(); subscribers != 1 {
+		t.Errorf("%d subscribers after unsubscribe, want 1", subscribers)
+	}
+}
+
+// A subscriber that is not read misses the events past its queue, and
+// publishing does not wait for it
+func TestSubscriberFallsBehind(t *testing.T) {
+	e := newStatusEvents()
+	s := &Service{statusEvents: e}
+	slow, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+
+	for i := 0; i < statusEventQueueSize+10; i++ {
+		e.publish(StatusChangeEvent{TargetID: uint32(i)})
+	}
+	if subscribers, queued := e.sizes(); subscribers != 1 || queued != statusEventQueueSize {
+		t.Errorf("sizes = %d, %d, want 1 and a full queue", subscribers, queued)
+	}
+	events := drain(slow)
+	if len(events) != statusEventQueueSize || events[len(events)-1].TargetID != statusEventQueueSize-1 {
+		t.Errorf("%d events, want the first %d", len(events), statusEventQueueSize)
+	}
+
+	// Once read it gets events again
+	e.publish(StatusChangeEvent{TargetID: 1000})
+	if events := drain(slow); len(events) != 1 || events[0].TargetID != 1000 {
+		t.Errorf("events after catching up %+v", events)
+	}
+}
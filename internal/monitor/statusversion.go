@@ -0,0 +1,32 @@
+package monitor
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// statusVersion counts changes to what QueryStatus returns: status rows and
+// the target name, type and address shown with them. The epoch keeps versions
+// from one process run from matching those of another.
+type statusVersion struct {
+	epoch   string
+	counter atomic.Uint64
+}
+
+func newStatusVersion() *statusVersion {
+	return &statusVersion{epoch: strconv.FormatInt(time.Now().UnixNano(), 36)}
+}
+
+// StatusVersion returns an opaque version of the stored statuses. Read it
+// before querying: a change committed during the query then only costs the
+// client one more full response.
+func (s *Service) StatusVersion() string {
+	return s.statusVersion.epoch + "." + strconv.FormatUint(s.statusVersion.counter.Load(), 10)
+}
+
+// InvalidateStatus marks the stored statuses as changed. Call it after the
+// change is committed, never before.
+func (s *Service) InvalidateStatus() {
+	s.statusVersion.counter.Add(1)
+}
@@ -0,0 +1,395 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	neturl "net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxHTTPSteps bounds the steps of a transaction
+const MaxHTTPSteps = 10
+
+// stepVariableError is the error type of a step that was not sent because a
+// placeholder could not be filled from an earlier step's response
+const stepVariableError = "step_variable_error"
+
+// Steps of http and https targets, declared in httpFields
+var httpStepsField = FieldSpec{Name: "steps", Kind: FieldObjectList, Description: "按顺序发送的请求（事务），每步有 name、method、url、headers、body 和与监控相同的断言字段（包括 expected_headers）；url 为空时使用地址，相对路径按地址解析。url、headers 和 body 中的 {{steps.N.json.<path>}}、{{steps.N.header.<name>}}、{{steps.N.status_code}} 替换为第 N 步（从 0 开始）的响应。设置后忽略 http_method、http_body、expected_status_codes、expected_headers 和响应体断言"}
+
+// Results of a transaction, declared in httpResults
+var (
+	stepsResult      = ResultField{Name: "steps", In: "data", Description: "事务每一步的 index、name、method、url、status、status_code、response_time、message 和 timings，到失败的一步为止"}
+	failedStepResult = ResultField{Name: "failed_step", In: "data", Description: "失败的步骤下标（从 0 开始），所有步骤都通过时不返回"}
+)
+
+// HTTPStep is one request of a transaction, stored as a JSON array in the
+// steps column. The target's headers, authentication, TLS, proxy and
+// redirect settings apply to every step.
+type HTTPStep struct {
+	Name                string            `json:"name,omitempty"`
+	Method              string            `json:"method,omitempty"` // GET when empty
+	URL                 string            `json:"url,omitempty"`    // Absolute, or resolved against the target's address
+	Headers             map[string]string `json:"headers,omitempty"`
+	Body                string            `json:"body,omitempty"`
+	ExpectedStatusCodes string            `json:"expected_status_codes,omitempty"`
+	ExpectedHeaders     map[string]string `json:"expected_headers,omitempty"`
+	BodyMustContain     string            `json:"body_must_contain,omitempty"`
+	BodyMustNotContain  string            `json:"body_must_not_contain,omitempty"`
+	BodyRegex           string            `json:"body_regex,omitempty"`
+	JSONPath            string            `json:"json_path,omitempty"`
+	JSONExpectedValue   string            `json:"json_expected_value,omitempty"`
+	JSONOperator        string            `json:"json_operator,omitempty"`
+
+	// Parsed by ParseHTTPSteps
+	expectedStatusCodes []int
+	expectedHeaders     []HeaderAssertion
+	bodyRegex           *regexp.Regexp
+	jsonPath            *JSONPath
+}
+
+// ValidateHTTPSteps checks the stored steps of a target: only for http and
+// https, not together with comparison mode, and each step as ParseHTTPSteps
+func ValidateHTTPSteps(typ, steps, secondaryAddress string) error {
+	if steps == "" {
+		return nil
+	}
+	if spec, ok := LookupType(typ); ok {
+		typ = spec.Type
+	}
+	if typ != "http" && typ != "https" {
+		return fmt.Errorf("steps are only supported for http and https monitors")
+	}
+	if secondaryAddress != "" {
+		return fmt.Errorf("steps cannot be used with secondary_address")
+	}
+	_, err := ParseHTTPSteps(steps)
+	return err
+}
+
+// ParseHTTPSteps decodes the stored steps JSON array and checks each step:
+// its method, status codes and assertions, and that its placeholders refer
+// to earlier steps. It returns nil for an empty string.
+func ParseHTTPSteps(s string) ([]HTTPStep, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var steps []HTTPStep
+	if err := json.Unmarshal([]byte(s), &steps); err != nil {
+		return nil, fmt.Errorf("steps: %w", err)
+	}
+	if len(steps) > MaxHTTPSteps {
+		return nil, fmt.Errorf("steps: at most %d steps, got %d", MaxHTTPSteps, len(steps))
+	}
+	for i := range steps {
+		if err := steps[i].parse(i); err != nil {
+			return nil, fmt.Errorf("steps[%d]: %w", i, err)
+		}
+	}
+	return steps, nil
+}
+
+func (s *HTTPStep) parse(index int) error {
+	if s.Method != "" {
+		if err := httpMethodField.validate(s.Method); err != nil {
+			return fmt.Errorf("method: %w", err)
+		}
+	}
+	if err := ValidateExpectedStatusCodes(s.ExpectedStatusCodes); err != nil {
+		return err
+	}
+	s.expectedStatusCodes, _ = parseExpectedStatusCodes(s.ExpectedStatusCodes)
+	var err error
+	if s.expectedHeaders, err = ParseExpectedHeaders(s.ExpectedHeaders); err != nil {
+		return err
+	}
+
+	for _, text := range []string{s.BodyMustContain, s.BodyMustNotContain, s.BodyRegex, s.JSONExpectedValue} {
+		if len(text) > MaxBodyAssertionLength {
+			return fmt.Errorf("assertions must be at most %d bytes", MaxBodyAssertionLength)
+		}
+	}
+	if s.bodyRegex, err = ParseBodyRegex(s.BodyRegex); err != nil {
+		return err
+	}
+	if s.JSONOperator != "" {
+		if err := jsonOperatorField.validate(s.JSONOperator); err != nil {
+			return fmt.Errorf("json_operator: %w", err)
+		}
+	}
+	if err := ValidateJSONAssertion("http", strings.TrimSpace(s.JSONPath), s.JSONExpectedValue, s.JSONOperator); err != nil {
+		return err
+	}
+	if s.jsonPath, err = ParseJSONPath(s.JSONPath); err != nil {
+		return err
+	}
+
+	texts := []string{s.URL, s.Body}
+	for _, value := range s.Headers {
+		texts = append(texts, value)
+	}
+	for _, text := range texts {
+		for _, placeholder := range stepPlaceholder.FindAllString(text, -1) {
+			ref, err := parseStepRef(placeholder)
+			if err != nil {
+				return err
+			}
+			if ref.step >= index {
+				return fmt.Errorf("%s: only earlier steps can be referred to", placeholder)
+			}
+		}
+	}
+	return nil
+}
+
+// stepPlaceholder matches a reference to an earlier step's response, see parseStepRef
+var stepPlaceholder = regexp.MustCompile(`\{\{\s*steps\.[^}]*\}\}`)
+
+// stepRef is a parsed placeholder: {{steps.N.json.<path>}},
+// {{steps.N.header.<name>}} or {{steps.N.status_code}}
+type stepRef struct {
+	step int
+	kind string // json, header, status_code
+	path *JSONPath
+	name string
+}
+
+func parseStepRef(placeholder string) (stepRef, error) {
+	inner := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(placeholder, "{{"), "}}"))
+	parts := strings.SplitN(strings.TrimPrefix(inner, "steps."), ".", 3)
+	n, err := strconv.Atoi(parts[0])
+	if err != nil || n < 0 {
+		return stepRef{}, fmt.Errorf("%s: the step must be a non-negative index", placeholder)
+	}
+	ref := stepRef{step: n}
+	if len(parts) > 1 {
+		ref.kind = parts[1]
+	}
+	arg := ""
+	if len(parts) > 2 {
+		arg = parts[2]
+	}
+	switch {
+	case ref.kind == "json" && arg != "":
+		if ref.path, err = ParseJSONPath(arg); err != nil {
+			return stepRef{}, fmt.Errorf("%s: %w", placeholder, err)
+		}
+	case ref.kind == "header" && arg != "":
+		ref.name = arg
+	case ref.kind == "status_code" && arg == "":
+	default:
+		return stepRef{}, fmt.Errorf("%s: use steps.N.json.<path>, steps.N.header.<name> or steps.N.status_code", placeholder)
+	}
+	return ref, nil
+}
+
+// stepResponse is what later steps can refer to of a step's response
+type stepResponse struct {
+	statusCode int
+	headers    map[string]string
+	body       []byte // decoded; nil when it could not be read or decoded
+
+	doc    interface{} // body parsed as JSON on first use
+	docErr error
+	parsed bool
+}
+
+// value returns the text a placeholder stands for
+func (r *stepResponse) value(ref stepRef) (string, error) {
+	switch ref.kind {
+	case "status_code":
+		return strconv.Itoa(r.statusCode), nil
+	case "header":
+		value, ok := r.headers[http.CanonicalHeaderKey(ref.name)]
+		if !ok {
+			return "", fmt.Errorf("no %s header in the response", ref.name)
+		}
+		return value, nil
+	}
+	if !r.parsed {
+		r.parsed = true
+		if r.body == nil {
+			r.docErr = fmt.Errorf("the body could not be read")
+		} else {
+			dec := json.NewDecoder(bytes.NewReader(r.body))
+			dec.UseNumber()
+			if err := dec.Decode(&r.doc); err != nil {
+				r.docErr = fmt.Errorf("body is not JSON: %v", err)
+			}
+		}
+	}
+	if r.docErr != nil {
+		return "", r.docErr
+	}
+	v, err := ref.path.lookup(r.doc)
+	if err != nil {
+		return "", err
+	}
+	return jsonText(v), nil
+}
+
+// expand replaces the placeholders of text with values from the earlier responses
+func expand(text string, responses []*stepResponse) (string, error) {
+	var firstErr error
+	expanded := stepPlaceholder.ReplaceAllStringFunc(text, func(placeholder string) string {
+		if firstErr != nil {
+			return ""
+		}
+		ref, err := parseStepRef(placeholder)
+		if err == nil && ref.step >= len(responses) {
+			err = fmt.Errorf("step %d has not run", ref.step)
+		}
+		var value string
+		if err == nil {
+			value, err = responses[ref.step].value(ref)
+		}
+		if err != nil {
+			firstErr = fmt.Errorf("%s: %w", placeholder, err)
+		}
+		return value
+	})
+	return expanded, firstErr
+}
+
+// stepTarget is a copy of target sending the step's request with the
+// placeholders filled in. The steps share jar, so cookies set by one step
+// are sent by the next.
+func (s *HTTPStep) stepTarget(target *MonitorTarget, responses []*stepResponse, jar http.CookieJar) (*MonitorTarget, error) {
+	rawURL, err := expand(s.URL, responses)
+	if err != nil {
+		return nil, err
+	}
+	base, err := neturl.Parse(target.httpURL())
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %v", err)
+	}
+	ref, err := neturl.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %v", err)
+	}
+	body, err := expand(s.Body, responses)
+	if err != nil {
+		return nil, err
+	}
+	headers := make(map[string]string, len(target.HTTPHeaders)+len(s.Headers))
+	for key, value := range target.HTTPHeaders {
+		headers[key] = value
+	}
+	for key, value := range s.Headers {
+		if headers[key], err = expand(value, responses); err != nil {
+			return nil, err
+		}
+	}
+
+	step := *target
+	step.Steps = nil
+	step.Address = base.ResolveReference(ref).String()
+	step.HTTPMethod = s.Method
+	step.HTTPHeaders = headers
+	step.HTTPBody = body
+	step.ExpectedStatusCodes = s.expectedStatusCodes
+	step.ExpectedHeaders = s.expectedHeaders
+	step.BodyMustContain = s.BodyMustContain
+	step.BodyMustNotContain = s.BodyMustNotContain
+	step.BodyRegex = s.bodyRegex
+	step.JSONPath = s.jsonPath
+	step.JSONExpectedValue = s.JSONExpectedValue
+	step.JSONOperator = s.JSONOperator
+	step.SecondaryAddress = ""
+	step.CompareBody = false
+	step.keepBody = true
+	step.jar = jar
+	return &step, nil
+}
+
+// stepSummary is one entry of data.steps
+type stepSummary struct {
+	Index        int                    `json:"index"`
+	Name         string                 `json:"name,omitempty"`
+	Method       string                 `json:"method"`
+	URL          string                 `json:"url,omitempty"`
+	Status       string                 `json:"status"`
+	StatusCode   int                    `json:"status_code,omitempty"`
+	ResponseTime int64                  `json:"response_time"`
+	Message      string                 `json:"message"`
+	Timings      map[string]interface{} `json:"timings,omitempty"`
+}
+
+// checkSteps runs the steps of a transaction in order and stops at the
+// first one that is not up. The result is that of the last step run, with
+// the response time of the whole transaction, a summary of every step run in
+// data.steps, and the failed step in data.failed_step.
+func (c *HTTPChecker) checkSteps(ctx context.Context, target *MonitorTarget) (*CheckResult, error) {
+	start := time.Now()
+	jar, _ := cookiejar.New(nil)
+	responses := make([]*stepResponse, 0, len(target.Steps))
+	summaries := make([]stepSummary, 0, len(target.Steps))
+
+	var result *CheckResult
+	for i := range target.Steps {
+		step := &target.Steps[i]
+		summary := stepSummary{Index: i, Name: step.Name, Method: step.Method}
+		if summary.Method == "" {
+			summary.Method = "GET"
+		}
+
+		stepTarget, err := step.stepTarget(target, responses, jar)
+		if err != nil {
+			result = &CheckResult{
+				Status:  "down",
+				Message: err.Error(),
+				Error:   &ErrorDetails{Type: stepVariableError, Message: err.Error()},
+			}
+		} else {
+			summary.URL = stepTarget.Address
+			if result, err = c.Check(ctx, stepTarget); err != nil {
+				return result, err
+			}
+		}
+
+		summary.Status = result.Status
+		summary.StatusCode = result.Response.StatusCode
+		summary.ResponseTime = result.ResponseTime
+		summary.Message = result.Message
+		if timings, ok := result.Data["timings"].(map[string]interface{}); ok {
+			summary.Timings = timings
+		}
+		summaries = append(summaries, summary)
+
+		label := fmt.Sprintf("steps[%d]", i)
+		if step.Name != "" {
+			label = fmt.Sprintf("%s %q", label, step.Name)
+		}
+		if result.Status != "up" {
+			result.Message = fmt.Sprintf("%s failed: %s", label, result.Message)
+			result.Data = map[string]interface{}{"steps": summaries, "failed_step": i}
+			break
+		}
+		if i == len(target.Steps)-1 {
+			result.Message = fmt.Sprintf("%d steps passed, %s: %s", len(target.Steps), label, result.Message)
+			data := map[string]interface{}{"steps": summaries}
+			// The content hash of a transaction is that of its last response
+			if hash, ok := result.Data["content_hash"]; ok {
+				data["content_hash"] = hash
+			}
+			result.Data = data
+		}
+		responses = append(responses, &stepResponse{
+			statusCode: result.Response.StatusCode,
+			headers:    result.Response.Headers,
+			body:       result.body,
+		})
+		result.body = nil
+	}
+
+	result.ResponseTime = time.Since(start).Milliseconds()
+	result.body = nil
+	return result, nil
+}
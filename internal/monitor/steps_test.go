@@ -0,0 +1,164 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"monitor/internal/models"
+)
+
+func TestParseHTTPSteps(t *testing.T) {
+	tooMany := "[" + strings.TrimSuffix(strings.Repeat(`{},`, MaxHTTPSteps+1), ",") + "]"
+	for _, tc := range []struct {
+		name, steps string
+		ok          bool
+	}{
+		{"none", ``, true},
+		{"login then fetch", `[{"method": "POST", "url": "/login"}, {"url": "/me?code={{steps.0.status_code}}",
+			"headers": {"Authorization": "Bearer {{ steps.0.json.token }}"}, "body": "{{steps.0.header.X-Session}}"}]`, true},
+		{"not json", `{"url": "/"}`, false},
+		{"too many", tooMany, false},
+		{"bad method", `[{"method": "FETCH"}]`, false},
+		{"bad status codes", `[{"expected_status_codes": "abc"}]`, false},
+		{"bad regex", `[{"body_regex": "("}]`, false},
+		{"refers to itself", `[{"url": "/{{steps.0.status_code}}"}]`, false},
+		{"refers to a later step", `[{}, {"body": "{{steps.2.status_code}}"}, {}]`, false},
+		{"malformed placeholder", `[{}, {"url": "/{{steps.0.cookie.id}}"}]`, false},
+		{"negative step", `[{}, {"url": "/{{steps.-1.status_code}}"}]`, false},
+		{"header without a name", `[{}, {"url": "/{{steps.0.header.}}"}]`, false},
+	} {
+		if _, err := ParseHTTPSteps(tc.steps); (err == nil) != tc.ok {
+			t.Errorf("%s: err = %v", tc.name, err)
+		}
+	}
+
+	if err := ValidateHTTPSteps("tcp", `[{}]`, ""); err == nil {
+		t.Error("steps accepted on a tcp monitor")
+	}
+	if err := ValidateHTTPSteps("https", `[{}]`, "https://b.example"); err == nil {
+		t.Error("steps accepted with secondary_address")
+	}
+}
+
+// loginServer answers POST /login with a token, a header and a cookie, and
+// GET /me only with all three
+func loginServer(t *testing.T) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.Method+" "+r.URL.RequestURI())
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "c1"})
+			w.Header().Set("X-Session", "s1")
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"token": "abc", "user": {"id": 7}}`)
+		case "/me":
+			cookie, _ := r.Cookie("sid")
+			if r.Header.Get("Authorization") != "Bearer abc" || r.Header.Get("X-Session") != "s1" || cookie == nil || cookie.Value != "c1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(w, "user %s", r.URL.Query().Get("id"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(paths)
+	}
+}
+
+func TestHTTPCheckSteps(t *testing.T) {
+	srv, requests := loginServer(t)
+	check := func(steps string) *CheckResult {
+		t.Helper()
+		parsed, err := ParseHTTPSteps(steps)
+		if err != nil {
+			t.Fatalf("ParseHTTPSteps: %v", err)
+		}
+		target := &MonitorTarget{Name: "login", Type: "http", Address: srv.URL + "/app", Steps: parsed}
+		result, err := (&HTTPChecker{}).Check(context.Background(), target)
+		if err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+		return result
+	}
+	summaries := func(r *CheckResult) []stepSummary {
+		t.Helper()
+		steps, _ := r.Data["steps"].([]stepSummary)
+		return steps
+	}
+
+	r := check(`[{"name": "login", "method": "POST", "url": "/login", "json_path": "$.user.id", "json_expected_value": "7"},
+		{"name": "me", "url": "/me?id={{steps.0.json.user.id}}", "body_must_contain": "user 7",
+		 "headers": {"Authorization": "Bearer {{steps.0.json.token}}", "X-Session": "{{steps.0.header.X-Session}}"}}]`)
+	steps := summaries(r)
+	if r.Status != "up" || !strings.HasPrefix(r.Message, `2 steps passed, steps[1] "me"`) || len(steps) != 2 || r.Data["failed_step"] != nil {
+		t.Fatalf("transaction: %s %q %+v", r.Status, r.Message, r.Data)
+	}
+	if steps[0].Method != "POST" || steps[1].URL != srv.URL+"/me?id=7" || steps[1].StatusCode != 200 || steps[1].Timings == nil {
+		t.Errorf("steps %+v", steps)
+	}
+
+	// A failing step stops the transaction
+	before := len(requests())
+	r = check(`[{"url": "/nope"}, {"url": "/me"}]`)
+	if r.Status != "down" || !strings.HasPrefix(r.Message, "steps[0] failed") || r.Data["failed_step"] != 0 || len(summaries(r)) != 1 {
+		t.Errorf("failed first step: %s %q %+v", r.Status, r.Message, r.Data)
+	}
+	if sent := requests()[before:]; !slices.Equal(sent, []string{"GET /nope"}) {
+		t.Errorf("requests after a failed step: %v", sent)
+	}
+
+	// A placeholder that can't be filled fails its step without sending it
+	for _, steps := range []string{
+		`[{"method": "POST", "url": "/login"}, {"url": "/me", "headers": {"X-Session": "{{steps.0.header.X-Missing}}"}}]`,
+		`[{"url": "/me", "expected_status_codes": "401"}, {"url": "/me?id={{steps.0.json.id}}"}]`,
+	} {
+		before := len(requests())
+		r = check(steps)
+		if r.Status != "down" || r.Error == nil || r.Error.Type != stepVariableError || r.Data["failed_step"] != 1 {
+			t.Errorf("%s: %s %q %+v", steps, r.Status, r.Message, r.Error)
+		}
+		if sent := requests()[before:]; len(sent) != 1 {
+			t.Errorf("%s: requests %v, want only the first step", steps, sent)
+		}
+	}
+}
+
+func TestLintStepsIgnoreRequest(t *testing.T) {
+	lint := func(target models.MonitorTarget) []LintFinding {
+		target.ID, target.Name, target.Type, target.Address, target.Interval, target.Enabled = 1, "login", "http", "http://a", 60, true
+		var found []LintFinding
+		for _, f := range Lint(&LintConfig{Targets: []models.MonitorTarget{target}}) {
+			if f.Lint == "steps_ignore_request" {
+				found = append(found, f)
+			}
+		}
+		return found
+	}
+	steps, _ := json.Marshal([]HTTPStep{{URL: "/login"}})
+	if found := lint(models.MonitorTarget{Steps: string(steps), BodyMustContain: "ok", HTTPBody: "x"}); len(found) != 1 ||
+		!strings.Contains(found[0].Message, "http_body, body_must_contain") {
+		t.Errorf("findings %+v", found)
+	}
+	if found := lint(models.MonitorTarget{Steps: string(steps)}); len(found) != 0 {
+		t.Errorf("findings without ignored settings %+v", found)
+	}
+	if found := lint(models.MonitorTarget{BodyMustContain: "ok"}); len(found) != 0 {
+		t.Errorf("findings without steps %+v", found)
+	}
+}
@@ -0,0 +1,96 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+
+	"monitor/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// ErrServiceStopped is returned for targets and checks added after Stop
+var ErrServiceStopped = errors.New("monitor service is stopped")
+
+// Stop shuts the service down. No new checks are queued, the checks already
+// running finish and their results are saved, and queued Elasticsearch writes
+// are flushed. Checks queued but not started are dropped; their manual check
+// jobs finish with ErrServiceStopped.
+//
+// If ctx ends first, the running checks are cancelled and Stop returns
+// ctx.Err() without waiting for them. Calling Stop again is harmless.
+func (s *Service) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() {
+		// Under mu so that AddTarget and TriggerCheck either finish queueing
+		// before this or see the service stopped
+		s.mu.Lock()
+		close(s.stopping)
+		s.mu.Unlock()
+		logger.Info("Stopping monitor service")
+	})
+
+	workersDone := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(workersDone)
+	}()
+	select {
+	case <-workersDone:
+	case <-ctx.Done():
+		s.cancel()
+		logger.Warn("Monitor service did not stop in time, cancelling running checks", zap.Error(ctx.Err()))
+		return ctx.Err()
+	}
+	s.dropQueuedChecks()
+
+	// Every check has returned; the ES and history writers flush what they queued
+	s.cancel()
+	select {
+	case <-s.esDone:
+	case <-ctx.Done():
+		logger.Warn("Monitor service did not flush Elasticsearch writes in time",
+			zap.Int("pending", len(s.esBuffer)),
+			zap.Error(ctx.Err()))
+		return ctx.Err()
+	}
+	select {
+	case <-s.history.done:
+	case <-ctx.Done():
+		pending, _ := s.history.size()
+		logger.Warn("Monitor service did not flush history in time",
+			zap.Int("pending", pending),
+			zap.Error(ctx.Err()))
+		return ctx.Err()
+	}
+	logger.Info("Monitor service stopped")
+	return nil
+}
+
+// stopped reports whether Stop has been called
+func (s *Service) stopped() bool {
+	select {
+	case <-s.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// dropQueuedChecks empties the check queue after the workers have exited
+func (s *Service) dropQueuedChecks() {
+	dropped := 0
+	for {
+		select {
+		case task := <-s.checkQueue:
+			dropped++
+			if task.job != "" {
+				s.checkJobs.finish(task.job, s.clock.Now(), nil, ErrServiceStopped)
+			}
+		default:
+			if dropped > 0 {
+				logger.Info("Dropped queued checks on stop", zap.Int("count", dropped))
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"monitor/internal/config"
+	"monitor/internal/database"
+	"monitor/internal/elasticsearch"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// slowES answers the info request and indexes each document after a delay,
+// so that writes are still queued when Stop is called
+type slowES struct {
+	mu  sync.Mutex
+	ids map[string]bool
+}
+
+func (f *slowES) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+	if !strings.Contains(r.URL.Path, "/_doc/") {
+		json.NewEncoder(w).Encode(map[string]interface{}{"version": map[string]string{"number": "8.11.0"}})
+		return
+	}
+	time.Sleep(10 * time.Millisecond)
+	f.mu.Lock()
+	f.ids[r.URL.Path] = true
+	f.mu.Unlock()
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"result": "created"})
+}
+
+func (f *slowES) indexed() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.ids)
+}
+
+// Stop lets the running check finish, flushes every queued ES, file and
+// history write, refuses new targets and checks, and leaves no goroutine behind
+func TestStopFlushesQueuedWrites(t *testing.T) {
+	logger.Log = zap.NewNop()
+	if err := database.InitMemoryDB(t.Name()); err != nil {
+		t.Fatalf("InitMemoryDB: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := database.GetDB().DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	// The default log directory, in a working directory removed with the test
+	t.Chdir(t.TempDir())
+	if err := logger.InitLogFileLog(logger.DefaultLogDir); err != nil {
+		t.Fatalf("init file log: %v", err)
+	}
+
+	fake := &slowES{ids: make(map[string]bool)}
+	esServer := httptest.NewServer(fake)
+	t.Cleanup(esServer.Close)
+	es, err := elasticsearch.NewClient(config.ElasticsearchConfig{Enabled: true, Addresses: []string{esServer.URL}, IndexPrefix: "monitor-logs"})
+	if err != nil {
+		t.Fatalf("elasticsearch client: %v", err)
+	}
+	// The check in flight when Stop is called
+	release := make(chan struct{})
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Connection", "close")
+	}))
+	t.Cleanup(web.Close)
+	esServer.CloseClientConnections()
+	baseline := runtime.NumGoroutine()
+
+	s := NewService(es, ServiceOptions{Workers: 1, StartupJitter: time.Hour})
+	slow := &MonitorTarget{ID: 1, Name: "web", Type: "http", Address: web.URL, Interval: 3600}
+	if err := s.AddTarget(slow); err != nil {
+		t.Fatalf("AddTarget: %v", err)
+	}
+	job, err := s.TriggerCheck(slow.ID)
+	if err != nil {
+		t.Fatalf("TriggerCheck: %v", err)
+	}
+	waitFor(t, func() bool {
+		got, _ := s.GetCheckJob(job.Token)
+		return got.State == CheckJobRunning
+	})
+
+	const queued = 20
+	saved := &MonitorTarget{ID: 2, Name: "db", Type: "tcp", Address: "127.0.0.1", Port: 1, Interval: 3600}
+	if err := s.AddTarget(saved); err != nil {
+		t.Fatalf("AddTarget: %v", err)
+	}
+	now := time.Now()
+	for i := 0; i < queued; i++ {
+		s.saveResult(saved, &CheckResult{Status: "up", CompletedAt: now.Add(time.Duration(i) * time.Millisecond), Nonce: fmt.Sprint(i)})
+	}
+
+	stopped := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stopped <- s.Stop(ctx)
+	}()
+	waitFor(t, s.stopped)
+	if err := s.AddTarget(&MonitorTarget{ID: 3, Name: "late", Type: "tcp", Address: "127.0.0.1", Port: 1}); !errors.Is(err, ErrServiceStopped) {
+		t.Errorf("AddTarget after Stop: err = %v, want ErrServiceStopped", err)
+	}
+	if _, err := s.TriggerCheck(saved.ID); !errors.Is(err, ErrServiceStopped) {
+		t.Errorf("TriggerCheck after Stop: err = %v, want ErrServiceStopped", err)
+	}
+	close(release)
+	if err := <-stopped; err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	if got, _ := s.GetCheckJob(job.Token); got.Result == nil || got.Error != "" {
+		t.Errorf("running check %+v, want a result", got)
+	}
+	if got := fake.indexed(); got != queued+1 {
+		t.Errorf("%d documents indexed, want %d", got, queued+1)
+	}
+	entries, err := logger.ReadCheckLogDay(now)
+	if err != nil {
+		t.Fatalf("ReadCheckLogDay: %v", err)
+	}
+	if len(entries) != queued+1 {
+		t.Errorf("%d file log entries, want %d", len(entries), queued+1)
+	}
+	var rows int64
+	database.GetDB().Model(&models.MonitorHistory{}).Count(&rows)
+	if rows != queued+1 {
+		t.Errorf("%d history rows, want %d", rows, queued+1)
+	}
+
+	esServer.CloseClientConnections()
+	web.CloseClientConnections()
+	waitFor(t, func() bool { return runtime.NumGoroutine() <= baseline })
+}
@@ -0,0 +1,95 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Limits of the tags of one target
+const (
+	MaxTags      = 20
+	MaxTagLength = 64
+)
+
+// NormalizeTags trims and lowercases tags, drops duplicates and sorts them.
+// A tag is made of letters, digits and . _ - : / such as "prod" or "team:payments".
+func NormalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > MaxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d bytes", tag, MaxTagLength)
+		}
+		for _, r := range tag {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || strings.ContainsRune("._-:/", r)) {
+				return nil, fmt.Errorf("tag %q may only contain letters, digits and . _ - : /", tag)
+			}
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	if len(result) > MaxTags {
+		return nil, fmt.Errorf("a monitor can have at most %d tags, got %d", MaxTags, len(result))
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// EncodeTags normalizes tags into the stored JSON array, "" without tags
+func EncodeTags(tags []string) (string, error) {
+	tags, err := NormalizeTags(tags)
+	if err != nil || len(tags) == 0 {
+		return "", err
+	}
+	bytes, err := json.Marshal(tags)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
+}
+
+// ParseTags decodes the stored tags JSON array
+func ParseTags(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var tags []string
+	if err := json.Unmarshal([]byte(s), &tags); err != nil {
+		return nil, fmt.Errorf("tags: %w", err)
+	}
+	return tags, nil
+}
+
+// HasTags reports whether the stored tags include every tag of want;
+// want must be normalized
+func HasTags(stored string, want []string) bool {
+	if len(want) == 0 {
+		return true
+	}
+	tags, err := ParseTags(stored)
+	if err != nil {
+		return false
+	}
+	for _, tag := range want {
+		found := false
+		for _, t := range tags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
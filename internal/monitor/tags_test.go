@@ -0,0 +1,71 @@
+package monitor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := NormalizeTags([]string{" Prod ", "team:payments", "prod", "", "eu-west/1"})
+	if err != nil || !slices.Equal(tags, []string{"eu-west/1", "prod", "team:payments"}) {
+		t.Errorf("NormalizeTags = %q, %v", tags, err)
+	}
+	if tags, err := NormalizeTags(nil); tags != nil || err != nil {
+		t.Errorf("no tags: %q, %v", tags, err)
+	}
+
+	many := make([]string, MaxTags+1)
+	for i := range many {
+		many[i] = fmt.Sprintf("t%d", i)
+	}
+	for name, tags := range map[string][]string{
+		"space":     {"two words"},
+		"character": {"prod!"},
+		"unicode":   {"生产"},
+		"too long":  {strings.Repeat("a", MaxTagLength+1)},
+		"too many":  many,
+	} {
+		if _, err := NormalizeTags(tags); err == nil {
+			t.Errorf("%s: %q accepted", name, tags)
+		}
+	}
+	// Duplicates do not count towards the limit
+	if _, err := NormalizeTags(append(many[:MaxTags:MaxTags], "T0")); err != nil {
+		t.Errorf("%d tags and a duplicate: %v", MaxTags, err)
+	}
+}
+
+func TestEncodeAndMatchTags(t *testing.T) {
+	stored, err := EncodeTags([]string{"Team:DB", "prod"})
+	if err != nil || stored != `["prod","team:db"]` {
+		t.Fatalf("EncodeTags = %s, %v", stored, err)
+	}
+	if stored, err := EncodeTags([]string{" "}); stored != "" || err != nil {
+		t.Errorf("EncodeTags of a blank tag = %q, %v, want empty", stored, err)
+	}
+	if tags, err := ParseTags(stored); err != nil || !slices.Equal(tags, []string{"prod", "team:db"}) {
+		t.Errorf("ParseTags = %q, %v", tags, err)
+	}
+	if _, err := ParseTags("prod"); err == nil {
+		t.Error("ParseTags accepted a value that is not a JSON array")
+	}
+
+	for _, tc := range []struct {
+		stored string
+		want   []string
+		match  bool
+	}{
+		{stored, nil, true},
+		{stored, []string{"prod"}, true},
+		{stored, []string{"prod", "team:db"}, true},
+		{stored, []string{"prod", "staging"}, false},
+		{"", []string{"prod"}, false},
+		{"broken", []string{"prod"}, false},
+	} {
+		if got := HasTags(tc.stored, tc.want); got != tc.match {
+			t.Errorf("HasTags(%s, %q) = %v, want %v", tc.stored, tc.want, got, tc.match)
+		}
+	}
+}
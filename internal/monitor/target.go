@@ -0,0 +1,349 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"monitor/internal/models"
+)
+
+// NewTargetFromModel builds the runtime monitor target from its database row.
+// It is the only place the model fields are copied, so new fields go here.
+func NewTargetFromModel(target models.MonitorTarget) (*MonitorTarget, error) {
+	var metadata map[string]string
+	if target.Metadata != "" {
+		if err := json.Unmarshal([]byte(target.Metadata), &metadata); err != nil {
+			return nil, err
+		}
+	}
+
+	// Parse HTTP headers
+	var httpHeaders map[string]string
+	if target.HTTPHeaders != "" {
+		if err := json.Unmarshal([]byte(target.HTTPHeaders), &httpHeaders); err != nil {
+			return nil, err
+		}
+	}
+
+	// Parse expected status codes; invalid entries are skipped (see ValidateExpectedStatusCodes)
+	expectedStatusCodes, _ := parseExpectedStatusCodes(target.ExpectedStatusCodes)
+
+	sslWarnDays, sslCriticalDays := SSLThresholds(target.SSLWarnDays, target.SSLCriticalDays)
+
+	sinks, err := ParseSinks(target.Sinks)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := ParseTags(target.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	dnsServers, err := ParseDNSServers(target.DNSServers)
+	if err != nil {
+		return nil, err
+	}
+
+	scriptArgs, err := ParseScriptArgs(target.ScriptArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	dnsExpectedValues, err := ParseDNSExpectedValues(target.DNSExpectedValues)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse expected headers
+	var expectedHeaderMap map[string]string
+	if target.ExpectedHeaders != "" {
+		if err := json.Unmarshal([]byte(target.ExpectedHeaders), &expectedHeaderMap); err != nil {
+			return nil, err
+		}
+	}
+	expectedHeaders, err := ParseExpectedHeaders(expectedHeaderMap)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyRegex, err := ParseBodyRegex(target.BodyRegex)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonPath, err := ParseJSONPath(target.JSONPath)
+	if err != nil {
+		return nil, err
+	}
+
+	steps, err := ParseHTTPSteps(target.Steps)
+	if err != nil {
+		return nil, err
+	}
+
+	contentHashRegex, err := ParseContentHashRegex(target.ContentHashRegex)
+	if err != nil {
+		return nil, err
+	}
+
+	tcpExpect, err := ParseTCPExpectation(target.TCPExpectString)
+	if err != nil {
+		return nil, err
+	}
+
+	udpPayload, err := ParseUDPPayload(target.UDPSendPayload, target.UDPPayloadFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	udpExpectPattern, err := ParseUDPExpectPattern(target.UDPExpectPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	monitorTarget := &MonitorTarget{
+		ID:       target.ID,
+		Name:     target.Name,
+		Type:     target.Type,
+		Address:  target.Address,
+		Port:     target.Port,
+		Interval: target.Interval,
+		Metadata: metadata,
+		Enabled:  target.Enabled,
+		// Deadline of one check
+		TimeoutSeconds: target.TimeoutSeconds,
+		// Retries of a failed check
+		RetryCount:           target.RetryCount,
+		RetryIntervalSeconds: target.RetryIntervalSeconds,
+		// HTTP/HTTPS specific fields
+		HTTPMethod:          target.HTTPMethod,
+		HTTPHeaders:         httpHeaders,
+		HTTPBody:            target.HTTPBody,
+		ResolvedHost:        target.ResolvedHost,
+		UnixSocketPath:      target.UnixSocketPath,
+		ProxyURL:            target.ProxyURL,
+		ProxyPassword:       target.ProxyPassword,
+		FollowRedirects:     target.FollowRedirects,
+		MaxRedirects:        target.MaxRedirects,
+		ExpectedStatusCodes: expectedStatusCodes,
+		ExpectedHeaders:     expectedHeaders,
+		BodyMustContain:     target.BodyMustContain,
+		BodyMustNotContain:  target.BodyMustNotContain,
+		BodyRegex:           bodyRegex,
+		JSONPath:            jsonPath,
+		JSONExpectedValue:   target.JSONExpectedValue,
+		JSONOperator:        target.JSONOperator,
+		Steps:               steps,
+		AuthType:            target.AuthType,
+		AuthUsername:        target.AuthUsername,
+		AuthPassword:        target.AuthPassword,
+		AuthToken:           target.AuthToken,
+		// Content change detection
+		TrackContentHash:     target.TrackContentHash,
+		ContentHashRegex:     contentHashRegex,
+		ContentHashNormalize: target.ContentHashNormalize,
+		// DNS specific fields
+		DNSServer:         target.DNSServer,
+		DNSServerName:     target.DNSServerName,
+		DNSServerType:     target.DNSServerType,
+		DNSServers:        dnsServers,
+		DNSConsensus:      target.DNSConsensus,
+		DNSQueryType:      target.DNSQueryType,
+		DNSExpectedValues: dnsExpectedValues,
+		DNSMatchMode:      target.DNSMatchMode,
+		// PING specific fields
+		PingCount:   target.PingCount,
+		PingSize:    target.PingSize,
+		PingTimeout: target.PingTimeout,
+		// SMTP specific fields
+		SMTPUsername:      target.SMTPUsername,
+		SMTPPassword:      target.SMTPPassword,
+		SMTPUseTLS:        target.SMTPUseTLS,
+		SMTPMailFrom:      target.SMTPMailFrom,
+		SMTPMailTo:        target.SMTPMailTo,
+		SMTPCheckStartTLS: target.SMTPCheckStartTLS,
+		// SNMP specific fields
+		SNMPCommunity:     target.SNMPCommunity,
+		SNMPOID:           target.SNMPOID,
+		SNMPVersion:       target.SNMPVersion,
+		SNMPExpectedValue: target.SNMPExpectedValue,
+		SNMPOperator:      target.SNMPOperator,
+		// TCP specific fields
+		TCPSendString: target.TCPSendString,
+		TCPExpect:     tcpExpect,
+		TCPUseTLS:     target.TCPUseTLS,
+		// UDP specific fields
+		UDPPreset:         target.UDPPreset,
+		UDPPayload:        udpPayload,
+		UDPExpectResponse: target.UDPExpectResponse,
+		UDPExpectPattern:  udpExpectPattern,
+		// SSL/TLS specific fields
+		SSLWarnDays:     sslWarnDays,
+		SSLCriticalDays: sslCriticalDays,
+		SSLCheck:        target.SSLCheck,
+		SSLGetChain:     target.SSLGetChain,
+		// Client certificate and CA
+		TLSClientCertPEM: target.TLSClientCertPEM,
+		TLSClientKeyPEM:  target.TLSClientKeyPEM,
+		TLSCACertPEM:     target.TLSCACertPEM,
+		TLSSkipVerify:    target.TLSSkipVerify,
+		TLSPinSHA256:     target.TLSPinSHA256,
+		// Script specific fields
+		ScriptPath:    target.ScriptPath,
+		ScriptArgs:    scriptArgs,
+		ScriptTimeout: target.ScriptTimeout,
+		// Comparison mode
+		SecondaryAddress:        target.SecondaryAddress,
+		CompareLatencyTolerance: target.CompareLatencyTolerance,
+		CompareBody:             target.CompareBody,
+		// Response time thresholds
+		DegradedThresholdMs: target.DegradedThresholdMs,
+		DownThresholdMs:     target.DownThresholdMs,
+		Sinks:               sinks,
+		Tags:                tags,
+		// Dependency suppression
+		DependsOnTargetID: target.DependsOnTargetID,
+	}
+
+	return monitorTarget, nil
+}
+
+// parseExpectedStatusCodes expands a comma-separated list of codes and ranges
+// such as "200,204,300-399". Entries that are not a code or range from 100 to
+// 999 are skipped and returned in invalid.
+func parseExpectedStatusCodes(s string) (codes []int, invalid []string) {
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		fromStr, toStr, isRange := strings.Cut(entry, "-")
+		from, err1 := strconv.Atoi(strings.TrimSpace(fromStr))
+		to, err2 := from, error(nil)
+		if isRange {
+			to, err2 = strconv.Atoi(strings.TrimSpace(toStr))
+		}
+		if err1 != nil || err2 != nil || from < 100 || to > 999 || from > to {
+			invalid = append(invalid, entry)
+			continue
+		}
+		for code := from; code <= to; code++ {
+			codes = append(codes, code)
+		}
+	}
+	return codes, invalid
+}
+
+// ValidateExpectedStatusCodes checks that every entry of expected_status_codes
+// is a status code or a range of them. Saved targets skip invalid entries.
+func ValidateExpectedStatusCodes(s string) error {
+	if _, invalid := parseExpectedStatusCodes(s); len(invalid) > 0 {
+		return fmt.Errorf("expected_status_codes: %q is not a status code or range such as 200 or 200-299", invalid[0])
+	}
+	return nil
+}
+
+// DefaultInterval is the check interval of a target added without one, in seconds
+const DefaultInterval = 60
+
+// ValidateTimeout checks the timeout_seconds of a target: 0 for the default,
+// otherwise at least 1 and at most the interval, so that a check always ends
+// before the next one is due. An interval of 0 stands for DefaultInterval.
+func ValidateTimeout(timeoutSeconds int, interval int64) error {
+	if timeoutSeconds == 0 {
+		return nil
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if timeoutSeconds < 1 || int64(timeoutSeconds) > interval {
+		return fmt.Errorf("timeout_seconds must be between 1 and the check interval (%d), got %d", interval, timeoutSeconds)
+	}
+	return nil
+}
+
+// MaxNotesLength caps the size of a target's operator notes in bytes
+const MaxNotesLength = 8192
+
+// ValidateNotes checks the operator notes and runbook link of a target.
+// Both are optional; the link must be an absolute http(s) URL.
+func ValidateNotes(notes, runbookURL string) error {
+	if len(notes) > MaxNotesLength {
+		return fmt.Errorf("notes must be at most %d bytes", MaxNotesLength)
+	}
+	if runbookURL == "" {
+		return nil
+	}
+	u, err := url.Parse(runbookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("runbook_url must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// ValidateUnixSocketPath checks the unix_socket_path of an HTTP target. The
+// path must be absolute; a socket that doesn't exist yet only produces a
+// warning, since the service behind it may start after the monitor is saved.
+// The certificate check dials the URL host directly and can't go through it.
+func ValidateUnixSocketPath(path string, sslCheck bool) (warning string, err error) {
+	if path == "" {
+		return "", nil
+	}
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("unix_socket_path must be an absolute path")
+	}
+	if sslCheck {
+		return "", fmt.Errorf("ssl_check is not supported together with unix_socket_path")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Sprintf("unix socket %s does not exist yet", path), nil
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Sprintf("%s is not a unix socket", path), nil
+	}
+	return "", nil
+}
+
+// typeFamilies groups monitor types that check the same thing, so a target
+// may switch between them without its history changing meaning.
+var typeFamilies = map[string]string{
+	"http":  "http",
+	"https": "http",
+	"ping":  "ping",
+	"icmp":  "ping",
+	"smtp":  "smtp",
+	"smtps": "smtp",
+	"ssl":   "ssl",
+	"tls":   "ssl",
+}
+
+// TypeChangeError is returned when an update would switch a target to a type
+// that measures something different.
+type TypeChangeError struct {
+	From string
+	To   string
+}
+
+func (e *TypeChangeError) Error() string {
+	return fmt.Sprintf("cannot change monitor type from %s to %s: create a new monitor instead", e.From, e.To)
+}
+
+// ValidateTypeChange allows an update to keep the type or move within the same
+// family (e.g. http to https). Other changes would leave the old type's settings
+// behind and mix incompatible results in the target's history.
+func ValidateTypeChange(from, to string) error {
+	if from == to {
+		return nil
+	}
+	if family, ok := typeFamilies[from]; ok && family == typeFamilies[to] {
+		return nil
+	}
+	return &TypeChangeError{From: from, To: to}
+}
@@ -0,0 +1,127 @@
+package monitor
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"monitor/internal/models"
+)
+
+// Every model field the runtime target has under the same name and type must
+// be copied by NewTargetFromModel; the checkers read nothing else
+func TestNewTargetFromModelCopiesFields(t *testing.T) {
+	// Adjusted on the way, see SSLThresholds
+	adjusted := map[string]bool{"SSLWarnDays": true, "SSLCriticalDays": true}
+
+	var model models.MonitorTarget
+	modelValue := reflect.ValueOf(&model).Elem()
+	runtimeType := reflect.TypeOf(MonitorTarget{})
+	var copied []string
+	for i := 0; i < runtimeType.NumField(); i++ {
+		field := runtimeType.Field(i)
+		src := modelValue.FieldByName(field.Name)
+		if adjusted[field.Name] || !src.IsValid() || src.Type() != field.Type {
+			continue
+		}
+		if !setNonZero(src, i+1) {
+			continue
+		}
+		copied = append(copied, field.Name)
+	}
+	if len(copied) < 50 {
+		t.Fatalf("only %d fields compared, the model and runtime target no longer line up", len(copied))
+	}
+
+	target, err := NewTargetFromModel(model)
+	if err != nil {
+		t.Fatalf("NewTargetFromModel: %v", err)
+	}
+	targetValue := reflect.ValueOf(target).Elem()
+	for _, name := range copied {
+		want, got := modelValue.FieldByName(name).Interface(), targetValue.FieldByName(name).Interface()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %v, want %v copied from the model", name, got, want)
+		}
+	}
+}
+
+// setNonZero sets v to a value derived from n; false for kinds it can't set
+func setNonZero(v reflect.Value, n int) bool {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString("value " + strconv.Itoa(n))
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		v.SetUint(uint64(n))
+	case reflect.Float64:
+		v.SetFloat(float64(n) + 0.5)
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if !setNonZero(elem.Elem(), n) {
+			return false
+		}
+		v.Set(elem)
+	default:
+		return false
+	}
+	return true
+}
+
+func TestValidateNotes(t *testing.T) {
+	for _, link := range []string{"", "https://wiki.example.com/runbooks/db", "http://10.0.0.1/runbook"} {
+		if err := ValidateNotes("restart the **primary**", link); err != nil {
+			t.Errorf("runbook %q: %v", link, err)
+		}
+	}
+	for _, link := range []string{"wiki/runbooks/db", "javascript:alert(1)", "ftp://example.com/runbook", "https://"} {
+		if err := ValidateNotes("", link); err == nil {
+			t.Errorf("runbook %q accepted", link)
+		}
+	}
+	if err := ValidateNotes(strings.Repeat("x", MaxNotesLength), ""); err != nil {
+		t.Errorf("notes of the maximum length: %v", err)
+	}
+	if err := ValidateNotes(strings.Repeat("x", MaxNotesLength+1), ""); err == nil {
+		t.Error("notes over the maximum length accepted")
+	}
+}
+
+func TestValidateTypeChange(t *testing.T) {
+	for _, change := range [][2]string{{"tcp", "tcp"}, {"http", "https"}, {"https", "http"}, {"icmp", "ping"}, {"smtps", "smtp"}, {"ssl", "tls"}} {
+		if err := ValidateTypeChange(change[0], change[1]); err != nil {
+			t.Errorf("%s to %s: %v", change[0], change[1], err)
+		}
+	}
+	for _, change := range [][2]string{{"http", "snmp"}, {"tcp", "udp"}, {"ping", "http"}, {"ssl", "https"}, {"script", "smtp"}} {
+		err := ValidateTypeChange(change[0], change[1])
+		var typeErr *TypeChangeError
+		if !errors.As(err, &typeErr) || typeErr.From != change[0] || typeErr.To != change[1] {
+			t.Errorf("%s to %s: err = %v, want a *TypeChangeError", change[0], change[1], err)
+		}
+	}
+}
+
+func TestParseExpectedStatusCodes(t *testing.T) {
+	codes, invalid := parseExpectedStatusCodes(" 200, 204-206 ,,301")
+	if !reflect.DeepEqual(codes, []int{200, 204, 205, 206, 301}) || len(invalid) != 0 {
+		t.Errorf("codes %v invalid %v", codes, invalid)
+	}
+	// Saved targets keep the valid entries
+	codes, invalid = parseExpectedStatusCodes("2x0,200,300-200,99,1000")
+	if !reflect.DeepEqual(codes, []int{200}) || !reflect.DeepEqual(invalid, []string{"2x0", "300-200", "99", "1000"}) {
+		t.Errorf("codes %v invalid %v", codes, invalid)
+	}
+
+	if err := ValidateExpectedStatusCodes("200-299, 301"); err != nil {
+		t.Errorf("valid codes: %v", err)
+	}
+	if err := ValidateExpectedStatusCodes("200,2x0"); err == nil || !strings.Contains(err.Error(), `"2x0"`) {
+		t.Errorf("invalid code: err = %v", err)
+	}
+}
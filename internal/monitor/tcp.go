@@ -1,22 +1,116 @@
 package monitor
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
+const (
+	// MaxTCPExchangeLength bounds tcp_send_string and tcp_expect_string
+	MaxTCPExchangeLength = 1000
+	// tcpBannerMaxBytes is how much of the reply is read looking for
+	// tcp_expect_string
+	tcpBannerMaxBytes = 4096
+	// tcpBannerStoredBytes is how much of the reply the result keeps
+	tcpBannerStoredBytes = 1024
+)
+
+// Error types of the TCP exchange, told apart from the network_error of the connect
+const (
+	tcpReadTimeout    = "read_timeout"
+	tcpUnexpectedData = "unexpected_reply"
+)
+
+func init() {
+	RegisterType(TypeSpec{
+		Type:        "tcp",
+		DisplayName: "TCP 端口",
+		Fields: []FieldSpec{
+			{Name: "port", Kind: FieldInteger, Required: true, Min: intBound(1), Max: intBound(65535), Description: "TCP 端口"},
+			{Name: "tcp_send_string", Kind: FieldString, Max: intBound(MaxTCPExchangeLength), Description: "连接后发送的内容（如 \"PING\\r\\n\"），原样发送"},
+			{Name: "tcp_expect_string", Kind: FieldString, Max: intBound(MaxTCPExchangeLength), Description: "回复（或服务器先发送的欢迎信息）的前 4096 字节必须包含的文本，/.../ 为正则（RE2 语法）；不满足或超时前没有收到时为 down"},
+			{Name: "tcp_use_tls", Kind: FieldBoolean, Default: false, Description: "连接后先进行 TLS 握手（SNI 为地址），再发送和读取"},
+			secondaryAddressField,
+			compareLatencyToleranceField,
+			degradedThresholdField,
+			downThresholdField,
+		},
+		Results: append(append([]ResultField{
+			{Name: "body", In: "response", Description: "读到的回复，最多 1024 字节；设置 tcp_expect_string 时返回"},
+			{Name: "tls_version", In: "response_headers", Description: "协商的 TLS 版本，设置 tcp_use_tls 时返回"},
+		}, comparisonResults...), responseTimeThresholdResult),
+	}, func() Checker { return &TCPChecker{} })
+}
+
+// TCPExpectation is the parsed tcp_expect_string of a target
+type TCPExpectation struct {
+	Contains string
+	Regex    *regexp.Regexp // set instead of Contains for /.../ values
+}
+
+// matches reports whether the data read so far satisfies the expectation
+func (e *TCPExpectation) matches(data []byte) bool {
+	if e.Regex != nil {
+		return e.Regex.Match(data)
+	}
+	return bytes.Contains(data, []byte(e.Contains))
+}
+
+func (e *TCPExpectation) String() string {
+	if e.Regex != nil {
+		return fmt.Sprintf("must match %q", e.Regex.String())
+	}
+	return fmt.Sprintf("must contain %q", e.Contains)
+}
+
+// ValidateTCPExchange checks the exchange settings of a target: they are
+// only supported for tcp, and the expectation must parse
+func ValidateTCPExchange(typ, send, expect string, useTLS bool) error {
+	if send == "" && expect == "" && !useTLS {
+		return nil
+	}
+	if spec, ok := LookupType(typ); ok {
+		typ = spec.Type
+	}
+	if typ != "tcp" {
+		return fmt.Errorf("tcp_send_string, tcp_expect_string and tcp_use_tls are only supported for tcp monitors")
+	}
+	_, err := ParseTCPExpectation(expect)
+	return err
+}
+
+// ParseTCPExpectation converts the tcp_expect_string of a target, nil when it is empty
+func ParseTCPExpectation(s string) (*TCPExpectation, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if len(s) >= 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+		re, err := regexp.Compile(s[1 : len(s)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid tcp_expect_string regex: %w", err)
+		}
+		return &TCPExpectation{Regex: re}, nil
+	}
+	return &TCPExpectation{Contains: s}, nil
+}
+
 type TCPChecker struct{}
 
 func (c *TCPChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckResult, error) {
 	start := time.Now()
 
-	address := fmt.Sprintf("%s:%d", target.Address, target.Port)
+	address := net.JoinHostPort(target.Address, strconv.Itoa(int(target.Port)))
 
-	dialer := &net.Dialer{
-		Timeout: 10 * time.Second,
-	}
+	dialer := target.dialer(10 * time.Second)
 
 	conn, err := dialer.DialContext(ctx, "tcp", address)
 	if err != nil {
@@ -24,15 +118,143 @@ func (c *TCPChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckRe
 			Status:       "down",
 			ResponseTime: time.Since(start).Milliseconds(),
 			Message:      fmt.Sprintf("TCP connection failed: %v", err),
+			Error:        &ErrorDetails{Type: "network_error", Message: err.Error()},
 		}, nil
 	}
 	defer conn.Close()
 
-	responseTime := time.Since(start).Milliseconds()
+	if target.TCPSendString == "" && target.TCPExpect == nil && !target.TCPUseTLS {
+		responseTime := time.Since(start).Milliseconds()
+
+		return &CheckResult{
+			Status:       "up",
+			ResponseTime: responseTime,
+			Message:      "TCP connection successful",
+		}, nil
+	}
+	return target.tcpExchange(ctx, conn, start), nil
+}
+
+// tcpExchange runs the TLS handshake, send and expect steps of a target on
+// an established connection. Failures after the connect are reported as
+// such: a read that times out is a read_timeout, not a connection error.
+func (t *MonitorTarget) tcpExchange(ctx context.Context, conn net.Conn, start time.Time) *CheckResult {
+	result := &CheckResult{
+		Request: RequestDetails{
+			Method: "TCP",
+			URL:    conn.RemoteAddr().String(),
+			Body:   t.TCPSendString,
+		},
+		Response: ResponseDetails{Headers: map[string]string{}},
+	}
+	down := func(errType, format string, args ...interface{}) *CheckResult {
+		result.Status = "down"
+		result.ResponseTime = time.Since(start).Milliseconds()
+		result.Message = fmt.Sprintf(format, args...)
+		result.Error = &ErrorDetails{Type: errType, Message: result.Message}
+		return result
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = start.Add(checkTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	if t.TCPUseTLS {
+		cfg, err := t.tlsConfig()
+		if err != nil {
+			return tlsConfigResult(err, time.Since(start).Milliseconds())
+		}
+		if cfg == nil {
+			cfg = &tls.Config{}
+		}
+		cfg.ServerName = t.Address
+		tlsConn := tls.Client(conn, cfg)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			message := fmt.Sprintf("TLS handshake failed after TCP connect: %v", err)
+			if hint := certTimeHint(err, t.ID); hint != "" {
+				message = fmt.Sprintf("%s (%s)", message, hint)
+			}
+			result = down("ssl_error", "%s", message)
+			if details := pinMismatchDetails(err); details != nil {
+				result.Error = details
+			}
+			return result
+		}
+		state := tlsConn.ConnectionState()
+		result.Response.Headers["tls_version"] = tls.VersionName(state.Version)
+		if len(state.PeerCertificates) > 0 {
+			result.Certificate = newCertificateInfo(state.PeerCertificates[0])
+		}
+		conn = tlsConn
+	}
+
+	if t.TCPSendString != "" {
+		if _, err := conn.Write([]byte(t.TCPSendString)); err != nil {
+			return down("network_error", "TCP send failed: %v", err)
+		}
+	}
+
+	if t.TCPExpect != nil {
+		reply, err := readUntil(conn, t.TCPExpect)
+		result.Response.Body = tcpBanner(reply)
+		if !t.TCPExpect.matches(reply) {
+			var netErr net.Error
+			switch {
+			case errors.As(err, &netErr) && netErr.Timeout():
+				return down(tcpReadTimeout, "TCP read timed out after %s, reply %s; got %q", time.Since(start).Round(time.Millisecond), t.TCPExpect, tcpBanner(reply))
+			case err != nil && !errors.Is(err, errTCPReadLimit) && !errors.Is(err, io.EOF):
+				return down("network_error", "TCP read failed: %v; reply %s, got %q", err, t.TCPExpect, tcpBanner(reply))
+			default:
+				return down(tcpUnexpectedData, "TCP reply %s; got %q", t.TCPExpect, tcpBanner(reply))
+			}
+		}
+	}
+
+	result.Status = "up"
+	result.ResponseTime = time.Since(start).Milliseconds()
+	switch {
+	case t.TCPExpect != nil:
+		result.Message = fmt.Sprintf("TCP reply matched (%d bytes)", len(result.Response.Body))
+	case t.TCPSendString != "":
+		result.Message = "TCP connection successful, data sent"
+	default:
+		result.Message = "TCP connection successful"
+	}
+	if t.TCPUseTLS {
+		result.Message = fmt.Sprintf("%s over %s", result.Message, result.Response.Headers["tls_version"])
+	}
+	return result
+}
+
+// errTCPReadLimit ends readUntil once tcpBannerMaxBytes were read
+var errTCPReadLimit = errors.New("read limit reached")
 
-	return &CheckResult{
-		Status:       "up",
-		ResponseTime: responseTime,
-		Message:      "TCP connection successful",
-	}, nil
-}
\ No newline at end of file
+// readUntil reads from conn until the data satisfies expect, the peer closes
+// the connection, tcpBannerMaxBytes were read or the deadline passes. It
+// returns what was read and the error that stopped it, nil on a match.
+func readUntil(conn net.Conn, expect *TCPExpectation) ([]byte, error) {
+	buf := make([]byte, 0, tcpBannerMaxBytes)
+	for {
+		n, err := conn.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if expect.matches(buf) {
+			return buf, nil
+		}
+		if err != nil {
+			return buf, err
+		}
+		if len(buf) == cap(buf) {
+			return buf, errTCPReadLimit
+		}
+	}
+}
+
+// tcpBanner is the part of a reply kept in the result
+func tcpBanner(reply []byte) string {
+	if len(reply) > tcpBannerStoredBytes {
+		reply = reply[:tcpBannerStoredBytes]
+	}
+	return strings.ToValidUTF8(string(reply), "�")
+}
@@ -0,0 +1,161 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTCPExpectation(t *testing.T) {
+	for _, tc := range []struct {
+		expect, data string
+		match        bool
+	}{
+		{"+PONG", "+PONG\r\n", true},
+		{"+PONG", "-ERR\r\n", false},
+		{`/^\* OK .*IMAP/`, "* OK [CAPABILITY IMAP4rev1] ready\r\n", true},
+		// A lone slash is text
+		{"/", "a/b", true},
+	} {
+		e, err := ParseTCPExpectation(tc.expect)
+		if err != nil {
+			t.Fatalf("ParseTCPExpectation(%q): %v", tc.expect, err)
+		}
+		if got := e.matches([]byte(tc.data)); got != tc.match {
+			t.Errorf("%q matches %q = %v", tc.expect, tc.data, got)
+		}
+	}
+	if _, err := ParseTCPExpectation("/(/"); err == nil {
+		t.Error("invalid regex accepted")
+	}
+	if err := ValidateTCPExchange("http", "PING", "", false); err == nil {
+		t.Error("exchange accepted on an http monitor")
+	}
+	if err := ValidateTCPExchange("tcp", "PING\r\n", "+PONG", true); err != nil {
+		t.Errorf("tcp exchange: %v", err)
+	}
+}
+
+// startTCPServer runs serve on each accepted connection and returns the port
+func startTCPServer(t *testing.T, serve func(net.Conn)) int32 {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				serve(conn)
+			}()
+		}
+	}()
+	return int32(ln.Addr().(*net.TCPAddr).Port)
+}
+
+func TestTCPCheckExchange(t *testing.T) {
+	redis := startTCPServer(t, func(conn net.Conn) {
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		if line == "PING\r\n" {
+			conn.Write([]byte("+PONG\r\n"))
+		} else {
+			conn.Write([]byte("-ERR unknown command\r\n"))
+		}
+		time.Sleep(time.Second)
+	})
+	imap := startTCPServer(t, func(conn net.Conn) {
+		conn.Write([]byte("* OK [CAPABILITY IMAP4rev1] ready\r\n"))
+		time.Sleep(time.Second)
+	})
+	wedged := startTCPServer(t, func(conn net.Conn) { time.Sleep(time.Second) })
+	hangup := startTCPServer(t, func(conn net.Conn) { conn.Write([]byte("-ERR max clients\r\n")) })
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	closed := int32(ln.Addr().(*net.TCPAddr).Port)
+	ln.Close()
+
+	for _, tc := range []struct {
+		name          string
+		port          int32
+		send, expect  string
+		status, error string
+	}{
+		{"connect only", redis, "", "", "up", ""},
+		{"ping", redis, "PING\r\n", "+PONG", "up", ""},
+		{"wrong reply", redis, "HELLO\r\n", "/^\\+PONG/", "down", tcpReadTimeout},
+		{"banner", imap, "", `/^\* OK .*IMAP4rev1/`, "up", ""},
+		{"wedged", wedged, "PING\r\n", "+PONG", "down", tcpReadTimeout},
+		{"closed without a match", hangup, "", "+PONG", "down", tcpUnexpectedData},
+		{"refused", closed, "PING\r\n", "+PONG", "down", "network_error"},
+	} {
+		expect, _ := ParseTCPExpectation(tc.expect)
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		r, err := (&TCPChecker{}).Check(ctx, &MonitorTarget{Name: "tcp", Type: "tcp", Address: "127.0.0.1", Port: tc.port, TCPSendString: tc.send, TCPExpect: expect})
+		cancel()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		errType := ""
+		if r.Error != nil {
+			errType = r.Error.Type
+		}
+		if r.Status != tc.status || errType != tc.error {
+			t.Errorf("%s: %s %q %s", tc.name, r.Status, r.Message, errType)
+		}
+	}
+}
+
+// A server that sends more than tcpBannerMaxBytes before closing; the
+// kept reply is cut to tcpBannerStoredBytes
+func TestTCPCheckReadLimit(t *testing.T) {
+	port := startTCPServer(t, func(conn net.Conn) {
+		conn.Write([]byte(strings.Repeat("x", 5000)))
+		time.Sleep(time.Second)
+	})
+	expect, _ := ParseTCPExpectation("+PONG")
+	r, _ := (&TCPChecker{}).Check(context.Background(), &MonitorTarget{Name: "tcp", Type: "tcp", Address: "127.0.0.1", Port: port, TCPExpect: expect})
+	if r.Status != "down" || r.Error == nil || r.Error.Type != tcpUnexpectedData || len(r.Response.Body) != tcpBannerStoredBytes {
+		t.Errorf("%s %+v, body of %d bytes", r.Status, r.Error, len(r.Response.Body))
+	}
+}
+
+func TestTCPCheckTLS(t *testing.T) {
+	cert := newTestCert(t, time.Now().AddDate(1, 0, 0))
+	addr := startTLSServer(t, cert, func(conn *tls.Conn) {
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte("echo " + line))
+	})
+	_, portText, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portText)
+	expect, _ := ParseTCPExpectation("echo PING")
+
+	check := func(ca string) *CheckResult {
+		t.Helper()
+		r, err := (&TCPChecker{}).Check(context.Background(), &MonitorTarget{Name: "tls", Type: "tcp", Address: "127.0.0.1", Port: int32(port),
+			TCPUseTLS: true, TCPSendString: "PING\n", TCPExpect: expect, TLSCACertPEM: ca})
+		if err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+		return r
+	}
+	r := check(cert.PEM)
+	if r.Status != "up" || r.Response.Headers["tls_version"] != "TLS 1.3" || r.Certificate == nil || !strings.HasSuffix(r.Message, "over TLS 1.3") {
+		t.Errorf("with the CA: %s %q %v", r.Status, r.Message, r.Response.Headers)
+	}
+	if r := check(""); r.Status != "down" || r.Error == nil || r.Error.Type != "ssl_error" {
+		t.Errorf("without the CA: %s %q %+v", r.Status, r.Message, r.Error)
+	}
+}
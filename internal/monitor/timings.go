@@ -0,0 +1,111 @@
+package monitor
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// timingsResult is the phase breakdown of an HTTP check, see httpTimings
+var timingsResult = ResultField{Name: "timings", In: "data", Description: "各阶段耗时（毫秒）：dns_ms、connect_ms、tls_ms、ttfb_ms、transfer_ms；复用连接时没有 dns/connect/tls，reused_connection 为 true"}
+
+// httpTimings records the phases of an HTTP request through an
+// httptrace.ClientTrace. The hooks may run on the transport's dial
+// goroutines, hence the mutex. Each request of a redirect chain starts over,
+// so the phases are those of the final request.
+type httpTimings struct {
+	mu sync.Mutex
+	p  httpPhases
+}
+
+// httpPhases are the hook times of one request
+type httpPhases struct {
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	wroteRequest, firstByte   time.Time
+	reused                    bool
+}
+
+// trace returns the hooks recording into t
+func (t *httpTimings) trace() *httptrace.ClientTrace {
+	record := func(f func()) {
+		t.mu.Lock()
+		f()
+		t.mu.Unlock()
+	}
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			record(func() {
+				t.p = httpPhases{}
+			})
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			record(func() { t.p.reused = info.Reused })
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			record(func() { t.p.dnsStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			record(func() { t.p.dnsDone = time.Now() })
+		},
+		// 同时尝试多个地址时从第一次开始连接算到最后一次连接完成
+		ConnectStart: func(string, string) {
+			record(func() {
+				if t.p.connectStart.IsZero() {
+					t.p.connectStart = time.Now()
+				}
+			})
+		},
+		ConnectDone: func(string, string, error) {
+			record(func() { t.p.connectDone = time.Now() })
+		},
+		TLSHandshakeStart: func() {
+			record(func() { t.p.tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			record(func() { t.p.tlsDone = time.Now() })
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			record(func() { t.p.wroteRequest = time.Now() })
+		},
+		GotFirstResponseByte: func() {
+			record(func() { t.p.firstByte = time.Now() })
+		},
+	}
+}
+
+// requestWritten returns when the final request was written, zero if it
+// wasn't
+func (t *httpTimings) requestWritten() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.p.wroteRequest
+}
+
+// data returns the phases for data.timings; bodyDone is when the body was
+// read, zero when the request failed before. Phases that didn't complete
+// are left out, and a reused connection has no dns, connect or tls phase.
+func (t *httpTimings) data(bodyDone time.Time) map[string]interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	timings := map[string]interface{}{"reused_connection": t.p.reused}
+	phase := func(name string, start, end time.Time) {
+		if !start.IsZero() && !end.IsZero() && !end.Before(start) {
+			timings[name] = durationMs(end.Sub(start))
+		}
+	}
+	phase("dns_ms", t.p.dnsStart, t.p.dnsDone)
+	phase("connect_ms", t.p.connectStart, t.p.connectDone)
+	phase("tls_ms", t.p.tlsStart, t.p.tlsDone)
+	phase("ttfb_ms", t.p.wroteRequest, t.p.firstByte)
+	phase("transfer_ms", t.p.firstByte, bodyDone)
+	return timings
+}
+
+// durationMs returns d in milliseconds with microsecond precision
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,86 @@
+package monitor
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPTimingsData(t *testing.T) {
+	timings := &httpTimings{p: httpPhases{
+		connectStart: epoch, connectDone: epoch.Add(1500 * time.Microsecond),
+		wroteRequest: epoch.Add(2 * time.Millisecond), firstByte: epoch.Add(32 * time.Millisecond),
+		// A handshake that never finished is left out
+		tlsStart: epoch.Add(time.Millisecond),
+	}}
+	data := timings.data(epoch.Add(52 * time.Millisecond))
+	want := map[string]interface{}{"reused_connection": false, "connect_ms": 1.5, "ttfb_ms": 30.0, "transfer_ms": 20.0}
+	if len(data) != len(want) {
+		t.Errorf("data = %v, want %v", data, want)
+	}
+	for k, v := range want {
+		if data[k] != v {
+			t.Errorf("%s = %v, want %v", k, data[k], v)
+		}
+	}
+	if _, ok := timings.data(time.Time{})["transfer_ms"]; ok {
+		t.Error("transfer_ms without a body read")
+	}
+}
+
+func TestHTTPCheckTimings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("second"))
+	}))
+	defer srv.Close()
+
+	checker := &HTTPChecker{}
+	check := func(address string) map[string]interface{} {
+		t.Helper()
+		result, err := checker.Check(context.Background(), &MonitorTarget{Name: "slow", Type: "http", Address: address})
+		if err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+		timings, ok := result.Data["timings"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("%s: no timings in %v", result.Status, result.Data)
+		}
+		return timings
+	}
+	ms := func(timings map[string]interface{}, name string) float64 {
+		v, _ := timings[name].(float64)
+		return v
+	}
+
+	first := check(srv.URL)
+	if ms(first, "ttfb_ms") < 25 || ms(first, "transfer_ms") < 15 || first["connect_ms"] == nil || first["reused_connection"] != false {
+		t.Errorf("first check timings %v", first)
+	}
+	// The target is an address, there is no lookup
+	if _, ok := first["dns_ms"]; ok {
+		t.Errorf("dns_ms for an IP address: %v", first)
+	}
+
+	// The second check reuses the connection and has no connect phase
+	if second := check(srv.URL); second["reused_connection"] != true || second["connect_ms"] != nil || ms(second, "ttfb_ms") < 25 {
+		t.Errorf("second check timings %v", second)
+	}
+
+	// A refused connection still reports how long the connect took
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	dead := ln.Addr().String()
+	ln.Close()
+	if refused := check("http://" + dead); refused["connect_ms"] == nil || refused["ttfb_ms"] != nil {
+		t.Errorf("refused connection timings %v", refused)
+	}
+}
@@ -0,0 +1,216 @@
+package monitor
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	dnsresolver "monitor/pkg/dns"
+)
+
+// MaxPEMLength bounds each of the PEM fields of a target
+const MaxPEMLength = 64 * 1024
+
+// tlsConfigError is the error type of a check whose client certificate or
+// CA does not parse
+const tlsConfigError = "tls_config_error"
+
+// dnsTLSVerifyError is the error type of a DoT check whose server
+// certificate failed verification
+const dnsTLSVerifyError = "dns_tls_verify_error"
+
+// certificatePinMismatch is the error type of a check whose server presented
+// a leaf certificate other than the pinned one
+const certificatePinMismatch = "certificate_pin_mismatch"
+
+// Client certificate and CA of http, https and ssl targets, and of dns
+// targets querying DoT servers
+var (
+	tlsClientCertField = FieldSpec{Name: "tls_client_cert_pem", Kind: FieldString, Max: intBound(MaxPEMLength), Description: "客户端证书（PEM，可以带中间证书），用于要求双向 TLS 的服务"}
+	tlsClientKeyField  = FieldSpec{Name: "tls_client_key_pem", Kind: FieldString, Max: intBound(MaxPEMLength), Description: "客户端证书的私钥（PEM），不在查询结果中返回；更新时留空保留原值"}
+	tlsCACertField     = FieldSpec{Name: "tls_ca_cert_pem", Kind: FieldString, Max: intBound(MaxPEMLength), Description: "验证服务器证书使用的 CA（PEM），设置后替代系统根证书"}
+	tlsSkipVerifyField = FieldSpec{Name: "tls_skip_verify", Kind: FieldBoolean, Default: false, Description: "不验证服务器证书（如开发环境的自签名证书），仍记录出示的证书"}
+	tlsPinSHA256Field  = FieldSpec{Name: "tls_pin_sha256", Kind: FieldString, Description: "服务器证书（终端证书）的 SHA-256 指纹，十六进制，可以带冒号；不一致时检查失败"}
+
+	certificateInfoResult = ResultField{Name: "certificate_info", In: "data", Description: "服务器出示的证书及是否经过验证，启用 tls_skip_verify 或 tls_pin_sha256 时返回"}
+)
+
+// ValidateTLSClientConfig checks the client certificate and CA settings of
+// a target: only for http, https, ssl and dns, the certificate and key
+// together, and all of them must parse
+func ValidateTLSClientConfig(typ, certPEM, keyPEM, caPEM string) error {
+	if certPEM == "" && keyPEM == "" && caPEM == "" {
+		return nil
+	}
+	if spec, ok := LookupType(typ); ok {
+		typ = spec.Type
+	}
+	if typ != "http" && typ != "https" && typ != "ssl" && typ != "dns" {
+		return fmt.Errorf("tls_client_cert_pem, tls_client_key_pem and tls_ca_cert_pem are only supported for http, https, ssl and dns monitors")
+	}
+	_, err := buildTLSClientConfig(certPEM, keyPEM, caPEM)
+	return err
+}
+
+// buildTLSClientConfig returns the TLS settings of a target, nil when it has
+// neither a client certificate nor a CA
+func buildTLSClientConfig(certPEM, keyPEM, caPEM string) (*tls.Config, error) {
+	if certPEM == "" && keyPEM == "" && caPEM == "" {
+		return nil, nil
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if certPEM != "" || keyPEM != "" {
+		if certPEM == "" || keyPEM == "" {
+			return nil, fmt.Errorf("tls_client_cert_pem and tls_client_key_pem must be set together")
+		}
+		cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if caPEM != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(caPEM)) {
+			return nil, fmt.Errorf("invalid tls_ca_cert_pem: no certificate found")
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// NormalizeTLSPin returns a certificate fingerprint as lowercase hex without
+// separators, the form of CertificateInfo.Fingerprint
+func NormalizeTLSPin(pin string) string {
+	pin = strings.NewReplacer(":", "", " ", "").Replace(strings.TrimSpace(pin))
+	return strings.ToLower(pin)
+}
+
+// ValidateTLSVerification checks the tls_skip_verify and tls_pin_sha256
+// settings of a target: only for http, https, ssl and dns, the pin a normalized
+// SHA-256 fingerprint, and no CA when verification is skipped
+func ValidateTLSVerification(typ string, skipVerify bool, pin, caPEM string) error {
+	if !skipVerify && pin == "" {
+		return nil
+	}
+	if spec, ok := LookupType(typ); ok {
+		typ = spec.Type
+	}
+	if typ != "http" && typ != "https" && typ != "ssl" && typ != "dns" {
+		return fmt.Errorf("tls_skip_verify and tls_pin_sha256 are only supported for http, https, ssl and dns monitors")
+	}
+	if skipVerify && caPEM != "" {
+		return fmt.Errorf("tls_ca_cert_pem has no effect with tls_skip_verify")
+	}
+	if pin != "" {
+		if decoded, err := hex.DecodeString(NormalizeTLSPin(pin)); err != nil || len(decoded) != sha256.Size {
+			return fmt.Errorf("tls_pin_sha256 must be a SHA-256 fingerprint (64 hex characters, colons allowed)")
+		}
+	}
+	return nil
+}
+
+// pinMismatchError fails the handshake of a target whose server presented a
+// leaf certificate other than the pinned one
+type pinMismatchError struct {
+	Fingerprint string
+}
+
+func (e *pinMismatchError) Error() string {
+	return fmt.Sprintf("certificate fingerprint %s does not match tls_pin_sha256", e.Fingerprint)
+}
+
+// tlsConfig returns the TLS settings of the target, nil when it uses the
+// defaults: its client certificate and CA, and whether the server
+// certificate is verified or pinned. The pin is checked whether or not the
+// chain is verified.
+func (t *MonitorTarget) tlsConfig() (*tls.Config, error) {
+	cfg, err := buildTLSClientConfig(t.TLSClientCertPEM, t.TLSClientKeyPEM, t.TLSCACertPEM)
+	if err != nil || (!t.TLSSkipVerify && t.TLSPinSHA256 == "") {
+		return cfg, err
+	}
+	if cfg == nil {
+		cfg = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	cfg.InsecureSkipVerify = t.TLSSkipVerify
+	if t.TLSPinSHA256 != "" {
+		pin := NormalizeTLSPin(t.TLSPinSHA256)
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return fmt.Errorf("no certificate presented to check tls_pin_sha256")
+			}
+			sum := sha256.Sum256(cs.PeerCertificates[0].Raw)
+			if fingerprint := hex.EncodeToString(sum[:]); fingerprint != pin {
+				return &pinMismatchError{Fingerprint: fingerprint}
+			}
+			return nil
+		}
+	}
+	return cfg, nil
+}
+
+// certificateData describes the leaf certificate presented to a target that
+// skips verification or pins it, stored as data.certificate_info
+func (t *MonitorTarget) certificateData(cert *x509.Certificate) map[string]interface{} {
+	info := newCertificateInfo(cert)
+	return map[string]interface{}{
+		"subject":     info.Subject,
+		"issuer":      info.Issuer,
+		"serial":      info.Serial,
+		"fingerprint": info.Fingerprint,
+		"dns_names":   info.DNSNames,
+		"not_before":  info.NotBefore.Format(time.RFC3339),
+		"not_after":   info.NotAfter.Format(time.RFC3339),
+		"verified":    !t.TLSSkipVerify,
+		"pinned":      t.TLSPinSHA256 != "",
+	}
+}
+
+// pinMismatchDetails returns the error details of a handshake that failed on
+// tls_pin_sha256, nil for other errors
+func pinMismatchDetails(err error) *ErrorDetails {
+	var pinErr *pinMismatchError
+	if !errors.As(err, &pinErr) {
+		return nil
+	}
+	return &ErrorDetails{Type: certificatePinMismatch, Message: pinErr.Error()}
+}
+
+// dnsTLSErrorDetails returns the error details of a DoT query whose handshake
+// failed on the server certificate or tls_pin_sha256, nil for other errors
+func dnsTLSErrorDetails(err error) *ErrorDetails {
+	if details := pinMismatchDetails(err); details != nil {
+		return details
+	}
+	var verifyErr *dnsresolver.TLSVerifyError
+	if !errors.As(err, &verifyErr) {
+		return nil
+	}
+	return &ErrorDetails{Type: dnsTLSVerifyError, Message: verifyErr.Error()}
+}
+
+// hasTLSClientConfig reports whether the target has its own TLS settings
+func (t *MonitorTarget) hasTLSClientConfig() bool {
+	return t.TLSClientCertPEM != "" || t.TLSClientKeyPEM != "" || t.TLSCACertPEM != ""
+}
+
+// tlsConfigResult is the down result of a check whose TLS settings do not parse
+func tlsConfigResult(err error, elapsed int64) *CheckResult {
+	message := fmt.Sprintf("Invalid TLS client configuration: %v", err)
+	return &CheckResult{
+		Status:       "down",
+		ResponseTime: elapsed,
+		Message:      message,
+		Error: &ErrorDetails{
+			Type:    tlsConfigError,
+			Message: err.Error(),
+		},
+	}
+}
@@ -0,0 +1,84 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// keyPEM encodes the private key of cert for tls_client_key_pem
+func keyPEM(t *testing.T, cert testCert) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(cert.TLS.PrivateKey)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func TestValidateTLSClientConfig(t *testing.T) {
+	client := newTestCert(t, time.Now().AddDate(1, 0, 0))
+	other := newTestCert(t, time.Now().AddDate(1, 0, 0))
+	for _, tc := range []struct {
+		name               string
+		typ, cert, key, ca string
+		ok                 bool
+	}{
+		{"nothing", "tcp", "", "", "", true},
+		{"ca only", "ssl", "", "", client.PEM, true},
+		{"client certificate", "https", client.PEM, keyPEM(t, client), "", true},
+		{"other type", "tcp", "", "", client.PEM, false},
+		{"certificate without key", "http", client.PEM, "", "", false},
+		{"key without certificate", "http", "", keyPEM(t, client), "", false},
+		{"key of another certificate", "http", client.PEM, keyPEM(t, other), "", false},
+		{"garbage ca", "http", "", "", "not a certificate", false},
+	} {
+		if err := ValidateTLSClientConfig(tc.typ, tc.cert, tc.key, tc.ca); (err == nil) != tc.ok {
+			t.Errorf("%s: err = %v", tc.name, err)
+		}
+	}
+}
+
+func TestHTTPCheckClientCertificate(t *testing.T) {
+	server := newTestCert(t, time.Now().AddDate(1, 0, 0))
+	client := newTestCert(t, time.Now().AddDate(1, 0, 0))
+	var seen string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.TLS.PeerCertificates[0].Subject.CommonName
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{server.TLS}, ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	check := func(target MonitorTarget) *CheckResult {
+		t.Helper()
+		target.Name, target.Type, target.Address = "mtls", "http", srv.URL
+		result, err := (&HTTPChecker{}).Check(context.Background(), &target)
+		if err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+		return result
+	}
+
+	if r := check(MonitorTarget{}); r.Status != "down" || !strings.Contains(r.Message, "unknown authority") {
+		t.Errorf("without the CA: %s %q", r.Status, r.Message)
+	}
+	if r := check(MonitorTarget{TLSCACertPEM: server.PEM}); r.Status != "down" {
+		t.Errorf("without a client certificate: %s %q", r.Status, r.Message)
+	}
+	r := check(MonitorTarget{TLSCACertPEM: server.PEM, TLSClientCertPEM: client.PEM, TLSClientKeyPEM: keyPEM(t, client)})
+	if r.Status != "up" || seen != "monitor test" {
+		t.Errorf("with a client certificate: %s %q, server saw %q", r.Status, r.Message, seen)
+	}
+	// Stored PEM that no longer parses fails the check
+	r = check(MonitorTarget{TLSCACertPEM: "garbage"})
+	if r.Status != "down" || r.Error == nil || r.Error.Type != tlsConfigError {
+		t.Errorf("invalid PEM: %s %q %+v", r.Status, r.Message, r.Error)
+	}
+}
@@ -0,0 +1,78 @@
+package monitor
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// testCert is a self-signed certificate for 127.0.0.1, usable as its own CA
+type testCert struct {
+	TLS tls.Certificate
+	PEM string // certificate only, for tls_ca_cert_pem
+}
+
+func newTestCert(t *testing.T, notAfter time.Time) testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "monitor test"},
+		NotBefore:             notAfter.AddDate(-1, 0, 0),
+		NotAfter:              notAfter,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	return testCert{
+		TLS: tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key},
+		PEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+	}
+}
+
+// startTLSServer accepts TLS connections with cert on a local port until the
+// end of the test and hands each one to serve after the handshake; a nil
+// serve closes the connection. It returns the listener address.
+func startTLSServer(t *testing.T, cert testCert, serve func(*tls.Conn)) string {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert.TLS}})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				tlsConn := conn.(*tls.Conn)
+				if err := tlsConn.Handshake(); err != nil || serve == nil {
+					return
+				}
+				serve(tlsConn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
@@ -0,0 +1,101 @@
+package monitor
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fingerprint returns the SHA-256 of the certificate as a tls_pin_sha256
+func fingerprint(cert testCert) string {
+	sum := sha256.Sum256(cert.TLS.Certificate[0])
+	return hex.EncodeToString(sum[:])
+}
+
+func TestValidateTLSVerification(t *testing.T) {
+	pin := strings.Repeat("ab", 32)
+	colons := strings.TrimSuffix(strings.Repeat("AB:", 32), ":")
+	for _, tc := range []struct {
+		name, typ, pin, ca string
+		skip               bool
+		ok                 bool
+	}{
+		{"nothing", "tcp", "", "", false, true},
+		{"skip", "https", "", "", true, true},
+		{"pin", "ssl", pin, "", false, true},
+		{"pin with colons", "http", colons, "", false, true},
+		{"pin with a ca", "http", pin, "CA", false, true},
+		{"other type", "tcp", "", "", true, false},
+		{"skip with a ca", "http", "", "CA", true, false},
+		{"short pin", "http", "abcd", "", false, false},
+		{"not hex", "http", strings.Repeat("zz", 32), "", false, false},
+	} {
+		if err := ValidateTLSVerification(tc.typ, tc.skip, tc.pin, tc.ca); (err == nil) != tc.ok {
+			t.Errorf("%s: err = %v", tc.name, err)
+		}
+	}
+	if got := NormalizeTLSPin(" AB:CD:EF "); got != "abcdef" {
+		t.Errorf("NormalizeTLSPin = %q", got)
+	}
+}
+
+func TestTLSSkipVerifyAndPin(t *testing.T) {
+	cert := newTestCert(t, time.Now().AddDate(1, 0, 0))
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert.TLS}}
+	srv.StartTLS()
+	defer srv.Close()
+	addr := startTLSServer(t, cert, nil)
+
+	wrongPin := strings.Repeat("00", 32)
+	for _, tc := range []struct {
+		name    string
+		target  MonitorTarget
+		status  string
+		errType string
+	}{
+		{"default", MonitorTarget{}, "down", ""},
+		{"skip verify", MonitorTarget{TLSSkipVerify: true}, "up", ""},
+		{"pin", MonitorTarget{TLSCACertPEM: cert.PEM, TLSPinSHA256: fingerprint(cert)}, "up", ""},
+		{"pin without verification", MonitorTarget{TLSSkipVerify: true, TLSPinSHA256: fingerprint(cert)}, "up", ""},
+		{"wrong pin", MonitorTarget{TLSSkipVerify: true, TLSPinSHA256: wrongPin}, "down", certificatePinMismatch},
+		// The pin is checked even when the chain verifies
+		{"wrong pin with the ca", MonitorTarget{TLSCACertPEM: cert.PEM, TLSPinSHA256: wrongPin}, "down", certificatePinMismatch},
+	} {
+		for _, checker := range []struct {
+			typ, address string
+			check        func(context.Context, *MonitorTarget) (*CheckResult, error)
+		}{
+			{"http", srv.URL, (&HTTPChecker{}).Check},
+			{"ssl", addr, (&SSLChecker{}).Check},
+		} {
+			target := tc.target
+			target.Name, target.Type, target.Address = "pinned", checker.typ, checker.address
+			r, err := checker.check(context.Background(), &target)
+			if err != nil {
+				t.Fatalf("%s %s: %v", tc.name, checker.typ, err)
+			}
+			errType := ""
+			if r.Error != nil {
+				errType = r.Error.Type
+			}
+			if r.Status != tc.status || (tc.errType != "" && errType != tc.errType) {
+				t.Errorf("%s %s: %s %q %s", tc.name, checker.typ, r.Status, r.Message, errType)
+				continue
+			}
+			if tc.status != "up" {
+				continue
+			}
+			info, ok := r.Data["certificate_info"].(map[string]interface{})
+			if !ok || info["fingerprint"] != fingerprint(cert) || info["verified"] != !target.TLSSkipVerify || info["pinned"] != (target.TLSPinSHA256 != "") {
+				t.Errorf("%s %s: certificate_info %v", tc.name, checker.typ, r.Data["certificate_info"])
+			}
+		}
+	}
+}
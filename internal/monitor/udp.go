@@ -2,33 +2,235 @@ package monitor
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 )
 
+const (
+	// MaxUDPPayloadBytes bounds udp_send_payload after decoding, so the
+	// datagram fits in one packet on a common MTU
+	MaxUDPPayloadBytes = 1400
+	// udpUnreachableWait is how long a check that expects no reply waits for
+	// an ICMP port unreachable before it reports the port as open
+	udpUnreachableWait = 2 * time.Second
+	// udpReplyExcerptLength is how many bytes of the reply a result quotes
+	udpReplyExcerptLength = 120
+)
+
+// Payload formats of udp_send_payload
+const (
+	UDPPayloadHex    = "hex"
+	UDPPayloadBase64 = "base64"
+)
+
+func init() {
+	RegisterType(TypeSpec{
+		Type:        "udp",
+		DisplayName: "UDP 端口",
+		Fields: []FieldSpec{
+			{Name: "port", Kind: FieldInteger, Required: true, Min: intBound(1), Max: intBound(65535), Description: "UDP 端口"},
+			{Name: "udp_preset", Kind: FieldString, Enum: []string{UDPPresetDNS, UDPPresetNTP, UDPPresetSIPOptions}, Description: "内置探测：dns（查询根域 NS 记录）、ntp（客户端请求）、sip_options（SIP OPTIONS）；发送对应的请求并检查回复的格式，不能与 udp_send_payload 同时使用"},
+			{Name: "udp_send_payload", Kind: FieldString, Description: "发送的数据报内容，按 udp_payload_format 解码，最多 1400 字节；为空时发送空数据报"},
+			{Name: "udp_payload_format", Kind: FieldString, Default: UDPPayloadHex, Enum: []string{UDPPayloadHex, UDPPayloadBase64}, Description: "udp_send_payload 的编码"},
+			{Name: "udp_expect_response", Kind: FieldBoolean, Default: false, Description: "要求在超时前收到回复，否则为 down；不要求时只有收到端口不可达才为 down"},
+			{Name: "udp_expect_pattern", Kind: FieldString, Max: intBound(MaxBodyAssertionLength), Description: "回复必须匹配的正则表达式（RE2 语法），设置后要求收到回复"},
+		},
+		Results: []ResultField{
+			{Name: "reply_bytes", In: "data", Description: "收到的回复字节数，没有回复时不返回"},
+			{Name: "reply", In: "data", Description: "回复的开头：可打印文本原样返回，否则为十六进制"},
+		},
+	}, func() Checker { return &UDPChecker{} })
+}
+
+// ValidateUDPProbe checks the probe settings of a target: they are only
+// supported for udp, a preset replaces the payload, and the payload and
+// pattern must parse
+func ValidateUDPProbe(typ, preset, payload, format string, expectResponse bool, pattern string) error {
+	if preset == "" && payload == "" && !expectResponse && pattern == "" {
+		return nil
+	}
+	if spec, ok := LookupType(typ); ok {
+		typ = spec.Type
+	}
+	if typ != "udp" {
+		return fmt.Errorf("udp_preset, udp_send_payload, udp_expect_response and udp_expect_pattern are only supported for udp monitors")
+	}
+	if preset != "" {
+		if _, ok := udpPresets[preset]; !ok {
+			return fmt.Errorf("unknown udp_preset %q", preset)
+		}
+		if payload != "" {
+			return fmt.Errorf("udp_send_payload cannot be used with udp_preset")
+		}
+	}
+	if _, err := ParseUDPPayload(payload, format); err != nil {
+		return err
+	}
+	if _, err := ParseUDPExpectPattern(pattern); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ParseUDPPayload decodes the udp_send_payload of a target in format, hex
+// when it is empty. Whitespace and colons between hex bytes are ignored.
+func ParseUDPPayload(s, format string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var payload []byte
+	var err error
+	switch format {
+	case "", UDPPayloadHex:
+		payload, err = hex.DecodeString(strings.NewReplacer(" ", "", ":", "", "\n", "", "\t", "").Replace(s))
+	case UDPPayloadBase64:
+		payload, err = base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	default:
+		return nil, fmt.Errorf("unknown udp_payload_format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid udp_send_payload: %w", err)
+	}
+	if len(payload) > MaxUDPPayloadBytes {
+		return nil, fmt.Errorf("udp_send_payload must be at most %d bytes, got %d", MaxUDPPayloadBytes, len(payload))
+	}
+	return payload, nil
+}
+
+// ParseUDPExpectPattern compiles the udp_expect_pattern of a target, nil when it is empty
+func ParseUDPExpectPattern(s string) (*regexp.Regexp, error) {
+	if s == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid udp_expect_pattern: %w", err)
+	}
+	return re, nil
+}
+
 type UDPChecker struct{}
 
+// Check sends one datagram and waits for the reply. UDP has no handshake, so
+// a target that expects no reply is only down when the host answers with an
+// ICMP port unreachable, which a connected socket reports as a refused read.
 func (c *UDPChecker) Check(ctx context.Context, target *MonitorTarget) (*CheckResult, error) {
 	start := time.Now()
 
-	address := fmt.Sprintf("%s:%d", target.Address, target.Port)
+	address := net.JoinHostPort(target.Address, strconv.Itoa(int(target.Port)))
+	expectReply := target.UDPExpectResponse || target.UDPExpectPattern != nil || target.UDPPreset != ""
+
+	result := &CheckResult{
+		Request: RequestDetails{
+			Method: "UDP",
+			URL:    address,
+			Headers: detailHeaders(map[string]interface{}{
+				"preset":       target.UDPPreset,
+				"expect_reply": expectReply,
+			}),
+		},
+	}
+	down := func(errType, format string, args ...interface{}) (*CheckResult, error) {
+		result.Status = "down"
+		result.ResponseTime = time.Since(start).Milliseconds()
+		result.Message = fmt.Sprintf(format, args...)
+		result.Error = &ErrorDetails{Type: errType, Message: result.Message}
+		return result, nil
+	}
 
-	conn, err := net.DialTimeout("udp", address, 10*time.Second)
+	conn, err := target.dialer(0).DialContext(ctx, "udp", address)
 	if err != nil {
-		return &CheckResult{
-			Status:       "down",
-			ResponseTime: time.Since(start).Milliseconds(),
-			Message:      fmt.Sprintf("UDP connection failed: %v", err),
-		}, nil
+		return down("network_error", "UDP dial failed: %v", err)
 	}
 	defer conn.Close()
 
-	responseTime := time.Since(start).Milliseconds()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = start.Add(checkTimeout)
+	}
+	conn.SetDeadline(deadline)
 
-	return &CheckResult{
-		Status:       "up",
-		ResponseTime: responseTime,
-		Message:      "UDP connection successful",
-	}, nil
-}
\ No newline at end of file
+	payload := target.UDPPayload
+	var verify func(reply []byte) error
+	if preset, ok := udpPresets[target.UDPPreset]; ok {
+		payload, verify = preset(target, conn)
+	}
+	result.Request.Body = udpExcerpt(payload)
+	if _, err := conn.Write(payload); err != nil {
+		return down("network_error", "UDP send failed: %v", err)
+	}
+
+	if !expectReply {
+		if wait := time.Now().Add(udpUnreachableWait); wait.Before(deadline) {
+			conn.SetReadDeadline(wait)
+		}
+	}
+	buf := make([]byte, 64<<10)
+	n, err := conn.Read(buf)
+	if err != nil {
+		var netErr net.Error
+		switch {
+		case errors.Is(err, syscall.ECONNREFUSED):
+			return down("port_unreachable", "UDP port unreachable (ICMP port unreachable received)")
+		case errors.As(err, &netErr) && netErr.Timeout() && expectReply:
+			return down("timeout", "No UDP reply within %s", time.Since(start).Round(time.Millisecond))
+		case errors.As(err, &netErr) && netErr.Timeout():
+			result.Status = "up"
+			result.ResponseTime = time.Since(start).Milliseconds()
+			result.Message = fmt.Sprintf("UDP datagram sent, no port unreachable within %s", time.Since(start).Round(time.Millisecond))
+			return result, nil
+		default:
+			return down("network_error", "UDP read failed: %v", err)
+		}
+	}
+	reply := buf[:n]
+	result.ResponseTime = time.Since(start).Milliseconds()
+	result.Response = ResponseDetails{Body: udpExcerpt(reply)}
+	result.Data = map[string]interface{}{
+		"reply_bytes": n,
+		"reply":       udpExcerpt(reply),
+	}
+
+	if verify != nil {
+		if err := verify(reply); err != nil {
+			return down("unexpected_reply", "Unexpected %s reply: %v", target.UDPPreset, err)
+		}
+	}
+	if target.UDPExpectPattern != nil && !target.UDPExpectPattern.Match(reply) {
+		return down("unexpected_reply", "UDP reply does not match %q; reply: %q", target.UDPExpectPattern.String(), udpExcerpt(reply))
+	}
+
+	result.Status = "up"
+	result.Message = fmt.Sprintf("UDP reply received (%d bytes)", n)
+	return result, nil
+}
+
+// udpExcerpt quotes the start of a datagram: as text when it is printable,
+// otherwise as hex
+func udpExcerpt(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	text := true
+	for _, c := range b {
+		if (c < 0x20 || c > 0x7e) && c != '\r' && c != '\n' && c != '\t' {
+			text = false
+			break
+		}
+	}
+	if text {
+		return bodyExcerpt(b, 0)
+	}
+	if len(b) > udpReplyExcerptLength/2 {
+		return hex.EncodeToString(b[:udpReplyExcerptLength/2]) + "..."
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,195 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseUDPPayload(t *testing.T) {
+	for _, tc := range []struct {
+		payload, format string
+		want            []byte
+		ok              bool
+	}{
+		{"", "", nil, true},
+		{"de:ad be\nef", "", []byte{0xde, 0xad, 0xbe, 0xef}, true},
+		{"cGluZw==", UDPPayloadBase64, []byte("ping"), true},
+		{"xyz", UDPPayloadHex, nil, false},
+		{"!!", UDPPayloadBase64, nil, false},
+		{"00", "octal", nil, false},
+		{strings.Repeat("00", MaxUDPPayloadBytes+1), "", nil, false},
+	} {
+		got, err := ParseUDPPayload(tc.payload, tc.format)
+		if (err == nil) != tc.ok || !bytes.Equal(got, tc.want) {
+			t.Errorf("ParseUDPPayload(%q, %q) = %x, %v", tc.payload, tc.format, got, err)
+		}
+	}
+}
+
+func TestValidateUDPProbe(t *testing.T) {
+	for _, tc := range []struct {
+		name, typ, preset, payload, pattern string
+		expect                              bool
+		ok                                  bool
+	}{
+		{"nothing", "tcp", "", "", "", false, true},
+		{"preset", "udp", UDPPresetNTP, "", "", false, true},
+		{"payload and pattern", "udp", "", "00ff", "^pong", false, true},
+		{"other type", "tcp", "", "", "", true, false},
+		{"unknown preset", "udp", "quic", "", "", false, false},
+		{"preset and payload", "udp", UDPPresetDNS, "00", "", false, false},
+		{"bad payload", "udp", "", "zz", "", false, false},
+		{"bad pattern", "udp", "", "", "(", false, false},
+	} {
+		if err := ValidateUDPProbe(tc.typ, tc.preset, tc.payload, "", tc.expect, tc.pattern); (err == nil) != tc.ok {
+			t.Errorf("%s: err = %v", tc.name, err)
+		}
+	}
+}
+
+// startUDPServer answers each datagram with reply(datagram); a nil reply
+// sends nothing
+func startUDPServer(t *testing.T, reply func([]byte) []byte) int32 {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 64<<10)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if out := reply(buf[:n]); out != nil {
+				conn.WriteTo(out, addr)
+			}
+		}
+	}()
+	return int32(conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+func checkUDP(t *testing.T, target MonitorTarget, timeout time.Duration) *CheckResult {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	target.Name, target.Type, target.Address = "udp", "udp", "127.0.0.1"
+	result, err := (&UDPChecker{}).Check(ctx, &target)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	return result
+}
+
+func TestUDPCheck(t *testing.T) {
+	echo := startUDPServer(t, func(b []byte) []byte { return append([]byte("pong "), b...) })
+	silent := startUDPServer(t, func([]byte) []byte { return nil })
+	ln, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	closed := int32(ln.LocalAddr().(*net.UDPAddr).Port)
+	ln.Close()
+
+	errType := func(r *CheckResult) string {
+		if r.Error == nil {
+			return ""
+		}
+		return r.Error.Type
+	}
+	for _, tc := range []struct {
+		name    string
+		target  MonitorTarget
+		status  string
+		errType string
+	}{
+		{"echo", MonitorTarget{Port: echo, UDPPayload: []byte("ping"), UDPExpectResponse: true}, "up", ""},
+		{"pattern", MonitorTarget{Port: echo, UDPPayload: []byte("ping"), UDPExpectPattern: regexp.MustCompile(`^pong ping$`)}, "up", ""},
+		{"pattern mismatch", MonitorTarget{Port: echo, UDPExpectPattern: regexp.MustCompile(`^ok`)}, "down", "unexpected_reply"},
+		{"no reply", MonitorTarget{Port: silent, UDPExpectResponse: true}, "down", "timeout"},
+		// Without an expected reply silence means the port is open
+		{"silent", MonitorTarget{Port: silent}, "up", ""},
+		{"port unreachable", MonitorTarget{Port: closed}, "down", "port_unreachable"},
+	} {
+		r := checkUDP(t, tc.target, 300*time.Millisecond)
+		if r.Status != tc.status || errType(r) != tc.errType {
+			t.Errorf("%s: %s %q %s", tc.name, r.Status, r.Message, errType(r))
+		}
+	}
+
+	r := checkUDP(t, MonitorTarget{Port: echo, UDPPayload: []byte("ping"), UDPExpectResponse: true}, time.Second)
+	if r.Data["reply_bytes"] != 9 || r.Data["reply"] != "pong ping" || r.Request.Body != "ping" {
+		t.Errorf("echo data %v request body %q", r.Data, r.Request.Body)
+	}
+}
+
+func TestUDPPresets(t *testing.T) {
+	dns := func(rcode byte, sameID bool) func([]byte) []byte {
+		return func(query []byte) []byte {
+			reply := append([]byte(nil), query...)
+			reply[2] |= 0x80
+			reply[3] |= rcode
+			if !sameID {
+				reply[0] ^= 0xff
+			}
+			return reply
+		}
+	}
+	ntp := func(stratum byte) func([]byte) []byte {
+		return func([]byte) []byte {
+			reply := make([]byte, 48)
+			reply[0], reply[1] = 0x1c, stratum
+			copy(reply[12:], "RATE")
+			return reply
+		}
+	}
+	sipRequests := make(chan []byte, 1)
+	sip := func(request []byte) []byte {
+		sipRequests <- append([]byte(nil), request...)
+		return []byte("SIP/2.0 404 Not Found\r\n\r\n")
+	}
+
+	for _, tc := range []struct {
+		name, preset string
+		reply        func([]byte) []byte
+		status       string
+	}{
+		{"dns", UDPPresetDNS, dns(0, true), "up"},
+		{"dns refused", UDPPresetDNS, dns(5, true), "up"},
+		{"dns other id", UDPPresetDNS, dns(0, false), "down"},
+		{"dns query echoed", UDPPresetDNS, func(b []byte) []byte { return b }, "down"},
+		{"ntp", UDPPresetNTP, ntp(2), "up"},
+		{"ntp kiss-o'-death", UDPPresetNTP, ntp(0), "down"},
+		{"ntp client packet", UDPPresetNTP, func(b []byte) []byte { return b }, "down"},
+		{"sip", UDPPresetSIPOptions, sip, "up"},
+		{"not sip", UDPPresetSIPOptions, func([]byte) []byte { return []byte("HTTP/1.1 400") }, "down"},
+	} {
+		port := startUDPServer(t, tc.reply)
+		r := checkUDP(t, MonitorTarget{Port: port, UDPPreset: tc.preset}, time.Second)
+		if r.Status != tc.status {
+			t.Errorf("%s: %s %q", tc.name, r.Status, r.Message)
+		}
+	}
+	if sipRequest := <-sipRequests; !bytes.HasPrefix(sipRequest, []byte("OPTIONS sip:127.0.0.1:")) || !bytes.Contains(sipRequest, []byte("CSeq: 1 OPTIONS\r\n")) {
+		t.Errorf("SIP request %q", sipRequest)
+	}
+}
+
+func TestUDPExcerpt(t *testing.T) {
+	if got := udpExcerpt([]byte("SIP/2.0 200 OK\r\n")); got != "SIP/2.0 200 OK" {
+		t.Errorf("text excerpt %q", got)
+	}
+	if got := udpExcerpt([]byte{0x1c, 0x02}); got != "1c02" {
+		t.Errorf("binary excerpt %q", got)
+	}
+	if got := udpExcerpt(make([]byte, 100)); got != strings.Repeat("00", udpReplyExcerptLength/2)+"..." {
+		t.Errorf("long binary excerpt %q", got)
+	}
+}
@@ -0,0 +1,105 @@
+package monitor
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+)
+
+// Built-in probes of udp targets, selected by udp_preset
+const (
+	UDPPresetDNS        = "dns"
+	UDPPresetNTP        = "ntp"
+	UDPPresetSIPOptions = "sip_options"
+)
+
+// udpPreset builds the datagram of a built-in probe for a connected socket,
+// and the check of the reply
+type udpPreset func(target *MonitorTarget, conn net.Conn) (payload []byte, verify func(reply []byte) error)
+
+var udpPresets = map[string]udpPreset{
+	UDPPresetDNS:        dnsPreset,
+	UDPPresetNTP:        ntpPreset,
+	UDPPresetSIPOptions: sipOptionsPreset,
+}
+
+// dnsPreset asks for the NS records of the root zone, which any resolver or
+// authoritative server answers, even if only with REFUSED. The reply must be
+// a response to the query ID.
+func dnsPreset(target *MonitorTarget, conn net.Conn) ([]byte, func([]byte) error) {
+	id := make([]byte, 2)
+	rand.Read(id)
+	query := append(id,
+		0x01, 0x00, // RD
+		0x00, 0x01, // QDCOUNT
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // ANCOUNT, NSCOUNT, ARCOUNT
+		0x00,       // root name
+		0x00, 0x02, // QTYPE NS
+		0x00, 0x01, // QCLASS IN
+	)
+	return query, func(reply []byte) error {
+		if len(reply) < 12 {
+			return fmt.Errorf("%d bytes, shorter than a DNS header", len(reply))
+		}
+		if !bytes.Equal(reply[:2], id) {
+			return fmt.Errorf("query ID %s, sent %s", hex.EncodeToString(reply[:2]), hex.EncodeToString(id))
+		}
+		if reply[2]&0x80 == 0 {
+			return fmt.Errorf("not a DNS response")
+		}
+		return nil
+	}
+}
+
+// ntpPreset sends an NTPv3 client request. The reply must be a server
+// packet; an unsynchronized server (stratum 0, a kiss-o'-death) is reported.
+func ntpPreset(target *MonitorTarget, conn net.Conn) ([]byte, func([]byte) error) {
+	request := make([]byte, 48)
+	request[0] = 0x1b // LI 0, version 3, mode 3 (client)
+	return request, func(reply []byte) error {
+		if len(reply) < 48 {
+			return fmt.Errorf("%d bytes, shorter than an NTP packet", len(reply))
+		}
+		if mode := reply[0] & 0x07; mode != 4 {
+			return fmt.Errorf("mode %d, expected 4 (server)", mode)
+		}
+		if reply[1] == 0 {
+			return fmt.Errorf("stratum 0, kiss code %q", reply[12:16])
+		}
+		return nil
+	}
+}
+
+// sipOptionsPreset sends a SIP OPTIONS request to the target. Any SIP
+// response counts, since servers often answer OPTIONS from unknown peers
+// with an error status; the status line is in the reply excerpt.
+func sipOptionsPreset(target *MonitorTarget, conn net.Conn) ([]byte, func([]byte) error) {
+	token := make([]byte, 8)
+	rand.Read(token)
+	tag := hex.EncodeToString(token)
+	local := conn.LocalAddr().String()
+	host := target.Address
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		host = "[" + host + "]"
+	}
+	uri := fmt.Sprintf("sip:%s:%d", host, target.Port)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "OPTIONS %s SIP/2.0\r\n", uri)
+	fmt.Fprintf(&b, "Via: SIP/2.0/UDP %s;branch=z9hG4bK%s;rport\r\n", local, tag)
+	b.WriteString("Max-Forwards: 70\r\n")
+	fmt.Fprintf(&b, "To: <%s>\r\n", uri)
+	fmt.Fprintf(&b, "From: <sip:monitor@%s>;tag=%s\r\n", local, tag)
+	fmt.Fprintf(&b, "Call-ID: %s@%s\r\n", tag, local)
+	b.WriteString("CSeq: 1 OPTIONS\r\n")
+	b.WriteString("Accept: application/sdp\r\n")
+	b.WriteString("Content-Length: 0\r\n\r\n")
+	return b.Bytes(), func(reply []byte) error {
+		if !bytes.HasPrefix(reply, []byte("SIP/2.0 ")) {
+			return fmt.Errorf("not a SIP response")
+		}
+		return nil
+	}
+}
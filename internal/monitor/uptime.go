@@ -0,0 +1,98 @@
+package monitor
+
+import (
+	"math"
+	"time"
+
+	"monitor/internal/database"
+	"monitor/internal/logger"
+	"monitor/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Statuses counted by uptime. A warning or degraded target still serves, so
+// it counts as up; down and critical count as down, other statuses (unknown,
+// maintenance) are left out.
+var (
+	uptimeUpStatuses = []string{"up", "warning", "degraded"}
+	uptimeStatuses   = []string{"up", "warning", "degraded", "down", "critical"}
+)
+
+// Uptime is the share of up results of a target over the last 24 hours, 7
+// days and 30 days, in percent rounded to two decimals; 0 without results
+type Uptime struct {
+	Day   float64
+	Week  float64
+	Month float64
+}
+
+// uptimeCounts is the result of the uptime query
+type uptimeCounts struct {
+	Up24h, Total24h int64
+	Up7d, Total7d   int64
+	Up30d, Total30d int64
+}
+
+// computeUptime counts the results of the three windows in one query
+func (s *Service) computeUptime(db *gorm.DB, targetID uint32) (Uptime, error) {
+	now := s.clock.Now()
+	day, week, month := now.Add(-24*time.Hour), now.AddDate(0, 0, -7), now.AddDate(0, 0, -30)
+
+	query := db.Model(&models.MonitorHistory{}).
+		Select(`COALESCE(SUM(CASE WHEN checked_at >= ? AND status IN ? THEN 1 ELSE 0 END), 0) AS up24h,
+			COALESCE(SUM(CASE WHEN checked_at >= ? THEN 1 ELSE 0 END), 0) AS total24h,
+			COALESCE(SUM(CASE WHEN checked_at >= ? AND status IN ? THEN 1 ELSE 0 END), 0) AS up7d,
+			COALESCE(SUM(CASE WHEN checked_at >= ? THEN 1 ELSE 0 END), 0) AS total7d,
+			COALESCE(SUM(CASE WHEN status IN ? THEN 1 ELSE 0 END), 0) AS up30d,
+			COUNT(*) AS total30d`,
+			day, uptimeUpStatuses, day, week, uptimeUpStatuses, week, uptimeUpStatuses).
+		Where("target_id = ? AND checked_at >= ? AND status IN ?", targetID, month, uptimeStatuses)
+	if !s.includeSyntheticUptime {
+		query = query.Where("synthetic = ?", false)
+	}
+
+	var counts uptimeCounts
+	if err := query.Scan(&counts).Error; err != nil {
+		return Uptime{}, err
+	}
+	return Uptime{
+		Day:   uptimePercent(counts.Up24h, counts.Total24h),
+		Week:  uptimePercent(counts.Up7d, counts.Total7d),
+		Month: uptimePercent(counts.Up30d, counts.Total30d),
+	}, nil
+}
+
+func uptimePercent(up, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(up)*10000/float64(total)) / 100
+}
+
+// apply stores the uptime on a status row and reports whether it changed.
+// uptime_percentage keeps the 30-day value in whole percent for older clients.
+func (u Uptime) apply(status *models.MonitorStatus) bool {
+	changed := status.Uptime24h != u.Day || status.Uptime7d != u.Week || status.Uptime30d != u.Month
+	status.Uptime24h, status.Uptime7d, status.Uptime30d = u.Day, u.Week, u.Month
+	status.UptimePercentage = int32(u.Month)
+	return changed
+}
+
+// updateUptime recomputes the uptime of a target from its history. Only the
+// uptime columns are written, the rest of the status row belongs to saveResult.
+func (s *Service) updateUptime(targetID uint32) {
+	db := database.GetDB()
+
+	uptime, err := s.computeUptime(db, targetID)
+	if err != nil {
+		logger.Warn("Failed to compute uptime", zap.Uint32("target_id", targetID), zap.Error(err))
+		return
+	}
+	var status models.MonitorStatus
+	uptime.apply(&status)
+	db.Model(&models.MonitorStatus{}).Where("target_id = ?", targetID).
+		Select("uptime_percentage", "uptime_24h", "uptime_7d", "uptime_30d").
+		Updates(&status)
+}
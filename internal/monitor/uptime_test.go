@@ -0,0 +1,88 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"monitor/internal/clock"
+	"monitor/internal/database"
+	"monitor/internal/models"
+)
+
+var epoch = time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+
+func TestComputeUptimeWindowEdges(t *testing.T) {
+	s := newTestService(t)
+	s.SetClock(clock.NewFake(epoch))
+
+	day, week, month := epoch.Add(-24*time.Hour), epoch.AddDate(0, 0, -7), epoch.AddDate(0, 0, -30)
+	history := []models.MonitorHistory{
+		// On each window start the result is counted, just before it it belongs to the next window
+		{Status: "up", CheckedAt: day},
+		{Status: "down", CheckedAt: day.Add(-time.Second)},
+		{Status: "down", CheckedAt: week},
+		{Status: "up", CheckedAt: week.Add(-time.Second)},
+		{Status: "up", CheckedAt: month},
+		{Status: "down", CheckedAt: month.Add(-time.Second)},
+		// Not counted at all
+		{Status: "maintenance", CheckedAt: epoch},
+		{Status: "down", CheckedAt: epoch, Synthetic: true},
+	}
+	for i := range history {
+		history[i].TargetID = 1
+		if err := database.GetDB().Create(&history[i]).Error; err != nil {
+			t.Fatalf("create history: %v", err)
+		}
+	}
+
+	got, err := s.computeUptime(database.GetDB(), 1)
+	if err != nil {
+		t.Fatalf("computeUptime: %v", err)
+	}
+	// day: up; week: + down, down; month: + up, up
+	want := Uptime{Day: 100, Week: 33.33, Month: 60}
+	if got != want {
+		t.Errorf("computeUptime = %+v, want %+v", got, want)
+	}
+}
+
+func TestComputeUptimeWindowMovesWithClock(t *testing.T) {
+	s := newTestService(t)
+	fake := clock.NewFake(epoch)
+	s.SetClock(fake)
+
+	if err := database.GetDB().Create(&models.MonitorHistory{TargetID: 1, Status: "up", CheckedAt: epoch}).Error; err != nil {
+		t.Fatalf("create history: %v", err)
+	}
+
+	fake.Advance(24 * time.Hour)
+	if got, _ := s.computeUptime(database.GetDB(), 1); got.Day != 100 {
+		t.Errorf("24h after the result: day uptime %v, want 100", got.Day)
+	}
+	fake.Advance(time.Second)
+	if got, _ := s.computeUptime(database.GetDB(), 1); got.Day != 0 || got.Week != 100 {
+		t.Errorf("just over 24h after the result: uptime %+v, want day 0 and week 100", got)
+	}
+}
+
+// Degraded and warning results count as up and critical as down; only the
+// uptime columns of the status are written
+func TestUpdateUptime(t *testing.T) {
+	s := newTestService(t)
+	s.SetClock(clock.NewFake(epoch))
+	db := database.GetDB()
+	for _, status := range []string{"up", "warning", "degraded", "critical", "unknown"} {
+		db.Create(&models.MonitorHistory{TargetID: 1, Status: status, CheckedAt: epoch.Add(-time.Hour)})
+	}
+	db.Create(&models.MonitorStatus{TargetID: 1, Status: "down", Message: "refused", CheckedAt: epoch})
+
+	s.updateUptime(1)
+	var status models.MonitorStatus
+	db.Where("target_id = ?", 1).First(&status)
+	if status.Uptime24h != 75 || status.Uptime7d != 75 || status.Uptime30d != 75 || status.UptimePercentage != 75 {
+		t.Errorf("uptime %v %v %v %d, want 75 everywhere", status.Uptime24h, status.Uptime7d, status.Uptime30d, status.UptimePercentage)
+	}
+	if status.Status != "down" || status.Message != "refused" {
+		t.Errorf("status %s %q changed by updateUptime", status.Status, status.Message)
+	}
+}
@@ -0,0 +1,119 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Variables rather than constants so that tests can shorten them
+var (
+	// checkTimeout is the context deadline of a check whose target does not
+	// set timeout_seconds
+	checkTimeout = 30 * time.Second
+	// stuckCheckGrace is how long past checkTimeout a checker may take to
+	// notice the deadline before the watchdog records a result for it
+	stuckCheckGrace = 15 * time.Second
+)
+
+// timeout returns the context deadline of one check of the target
+func (t *MonitorTarget) timeout() time.Duration {
+	if t.TimeoutSeconds <= 0 {
+		return checkTimeout
+	}
+	return time.Duration(t.TimeoutSeconds) * time.Second
+}
+
+// StuckCheckStats counts the checks of one type abandoned by the watchdog
+type StuckCheckStats struct {
+	Type    string `json:"type"`
+	Total   int64  `json:"total"`   // since startup
+	Running int64  `json:"running"` // abandoned checks whose goroutine has not returned yet
+}
+
+// stuckChecks counts abandoned checks by target type
+type stuckChecks struct {
+	mu     sync.Mutex
+	byType map[string]*StuckCheckStats
+}
+
+func newStuckChecks() *stuckChecks {
+	return &stuckChecks{byType: make(map[string]*StuckCheckStats)}
+}
+
+func (s *stuckChecks) abandoned(typ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, ok := s.byType[typ]
+	if !ok {
+		stats = &StuckCheckStats{Type: typ}
+		s.byType[typ] = stats
+	}
+	stats.Total++
+	stats.Running++
+}
+
+func (s *stuckChecks) returned(typ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stats, ok := s.byType[typ]; ok {
+		stats.Running--
+	}
+}
+
+func (s *stuckChecks) snapshot() []StuckCheckStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]StuckCheckStats, 0, len(s.byType))
+	for _, stats := range s.byType {
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Type < result[j].Type })
+	return result
+}
+
+// StuckChecks returns, per target type, how many checks the watchdog has
+// abandoned. A Running count that does not go back to zero means goroutines
+// of that checker are blocked for good.
+func (s *Service) StuckChecks() []StuckCheckStats {
+	return s.stuck.snapshot()
+}
+
+// stuckResult is recorded in place of a check that did not return in time
+func stuckResult(target *MonitorTarget, elapsed time.Duration) *CheckResult {
+	message := fmt.Sprintf("Check did not finish within %s (%s checker), result recorded by the watchdog",
+		elapsed.Round(time.Second), target.Type)
+	return &CheckResult{
+		Status:       "down",
+		ResponseTime: elapsed.Milliseconds(),
+		Message:      message,
+		Request: RequestDetails{
+			Method: target.Type,
+			URL:    target.Address,
+		},
+		Error: &ErrorDetails{
+			Type:    "check_stuck",
+			Message: message,
+		},
+	}
+}
+
+// timedOut marks the outcome of a check still running at its deadline: it is
+// down with a timeout error, keeping whatever the checker filled in
+func timedOut(target *MonitorTarget, result *CheckResult, err error, elapsed time.Duration) *CheckResult {
+	message := fmt.Sprintf("Check did not finish within %s", elapsed.Round(time.Millisecond))
+	if result == nil {
+		result = &CheckResult{
+			ResponseTime: elapsed.Milliseconds(),
+			Message:      message,
+			Request:      RequestDetails{Method: target.Type, URL: target.Address},
+		}
+		if err != nil {
+			result.Message = fmt.Sprintf("%s: %v", message, err)
+		}
+	}
+	result.Status = "down"
+	result.Error = &ErrorDetails{Type: "timeout", Message: message}
+	return result
+}
@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// checkerFunc adapts a function to the Checker interface
+type checkerFunc func(ctx context.Context, target *MonitorTarget) (*CheckResult, error)
+
+func (f checkerFunc) Check(ctx context.Context, target *MonitorTarget) (*CheckResult, error) {
+	return f(ctx, target)
+}
+
+func shortenStuckCheckGrace(t *testing.T) {
+	t.Helper()
+	previous := stuckCheckGrace
+	stuckCheckGrace = 50 * time.Millisecond
+	t.Cleanup(func() { stuckCheckGrace = previous })
+}
+
+// A checker that ignores its deadline is abandoned and recorded as stuck,
+// and counted as running until it returns
+func TestRunCheckWatchdog(t *testing.T) {
+	shortenStuckCheckGrace(t)
+	s := &Service{stuck: newStuckChecks()}
+	target := &MonitorTarget{ID: 1, Name: "hung", Type: "script", Address: "/bin/hang"}
+
+	release := make(chan struct{})
+	returned := make(chan struct{})
+	hung := checkerFunc(func(ctx context.Context, target *MonitorTarget) (*CheckResult, error) {
+		defer close(returned)
+		<-release
+		return &CheckResult{Status: "up"}, nil
+	})
+
+	result, err := s.runCheck(context.Background(), hung, target, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("runCheck: %v", err)
+	}
+	if result.Status != "down" || result.Error == nil || result.Error.Type != "check_stuck" || result.Request.URL != "/bin/hang" {
+		t.Errorf("stuck result %+v", result)
+	}
+	if stats := s.StuckChecks(); len(stats) != 1 || stats[0] != (StuckCheckStats{Type: "script", Total: 1, Running: 1}) {
+		t.Errorf("stuck checks %+v", stats)
+	}
+
+	close(release)
+	<-returned
+	deadline := time.Now().Add(5 * time.Second)
+	for s.StuckChecks()[0].Running != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("abandoned check still counted as running: %+v", s.StuckChecks())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// A checker that returns at its deadline is down with a timeout error and
+// keeps what it filled in
+func TestRunCheckTimeout(t *testing.T) {
+	shortenStuckCheckGrace(t)
+	s := &Service{stuck: newStuckChecks()}
+	target := &MonitorTarget{ID: 1, Name: "slow", Type: "tcp", Address: "127.0.0.1"}
+
+	partial := checkerFunc(func(ctx context.Context, target *MonitorTarget) (*CheckResult, error) {
+		<-ctx.Done()
+		return &CheckResult{Status: "up", Message: "connected, no banner"}, nil
+	})
+	result, err := s.runCheck(context.Background(), partial, target, 20*time.Millisecond)
+	if err != nil || result.Status != "down" || result.Message != "connected, no banner" || result.Error == nil || result.Error.Type != "timeout" {
+		t.Errorf("partial result %+v, %v", result, err)
+	}
+
+	failed := checkerFunc(func(ctx context.Context, target *MonitorTarget) (*CheckResult, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	result, err = s.runCheck(context.Background(), failed, target, 20*time.Millisecond)
+	if err != nil || result.Status != "down" || result.Error == nil || result.Error.Type != "timeout" {
+		t.Errorf("failed result %+v, %v", result, err)
+	}
+	if len(s.StuckChecks()) != 0 {
+		t.Errorf("checks that returned in time counted as stuck: %+v", s.StuckChecks())
+	}
+
+	// Errors of a check that finishes in time are passed through
+	broken := errors.New("broken")
+	_, err = s.runCheck(context.Background(), checkerFunc(func(context.Context, *MonitorTarget) (*CheckResult, error) {
+		return nil, broken
+	}), target, time.Second)
+	if !errors.Is(err, broken) {
+		t.Errorf("err = %v, want the checker's error", err)
+	}
+}
+
+func TestTargetTimeout(t *testing.T) {
+	if got := (&MonitorTarget{}).timeout(); got != checkTimeout {
+		t.Errorf("default timeout %v, want %v", got, checkTimeout)
+	}
+	if got := (&MonitorTarget{TimeoutSeconds: 5}).timeout(); got != 5*time.Second {
+		t.Errorf("timeout %v, want 5s", got)
+	}
+}
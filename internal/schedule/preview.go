@@ -0,0 +1,166 @@
+package schedule
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MinIntervals is the shortest allowed time between two runs of each feature
+// that accepts a schedule
+var MinIntervals = map[string]time.Duration{
+	"digest":      time.Hour,
+	"maintenance": time.Hour,
+}
+
+// Features returns the names of the features in MinIntervals
+func Features() []string {
+	names := make([]string, 0, len(MinIntervals))
+	for name := range MinIntervals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// scanOccurrences bounds how many occurrences are looked at for warnings
+const scanOccurrences = 1000
+
+// Validate parses a schedule for a feature: it must fire, and never twice
+// within minInterval in loc. Features validate through it so that the errors
+// are the same everywhere.
+func Validate(expr string, loc *time.Location, minInterval time.Duration) (*Schedule, error) {
+	s, err := Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	scan := s.scan(time.Now().In(loc), minInterval)
+	if scan.never {
+		return nil, fmt.Errorf("schedule %q never fires", s.expr)
+	}
+	if scan.tooOften {
+		return nil, fmt.Errorf("schedule %q fires %s after %s, more often than the minimum of %s",
+			s.expr, formatDuration(scan.gap), scan.gapAt.Format(time.RFC3339), formatDuration(minInterval))
+	}
+	return s, nil
+}
+
+// Preview is the next occurrences of a schedule and what may surprise about it
+type Preview struct {
+	Expression string      `json:"expression"`
+	Timezone   string      `json:"timezone"`
+	Next       []time.Time `json:"next"`
+	Warnings   []string    `json:"warnings"`
+}
+
+// NewPreview returns the first count occurrences after now in now's location.
+// minInterval is the feature's minimum, 0 for none.
+func NewPreview(s *Schedule, now time.Time, count int, minInterval time.Duration) Preview {
+	preview := Preview{
+		Expression: s.expr,
+		Timezone:   now.Location().String(),
+		Next:       s.NextN(now, count),
+		Warnings:   []string{},
+	}
+	if preview.Next == nil {
+		preview.Next = []time.Time{}
+	}
+
+	scan := s.scan(now, minInterval)
+	if scan.never {
+		preview.Warnings = append(preview.Warnings, "the schedule never fires")
+		return preview
+	}
+	if scan.tooOften {
+		preview.Warnings = append(preview.Warnings, fmt.Sprintf("fires %s after %s, more often than the minimum of %s",
+			formatDuration(scan.gap), scan.gapAt.Format(time.RFC3339), formatDuration(minInterval)))
+	}
+	if !s.domStar && !s.dowStar {
+		preview.Warnings = append(preview.Warnings, "both day-of-month and day-of-week are set: it fires on days matching either, not both")
+	}
+	if !scan.skipped.IsZero() {
+		preview.Warnings = append(preview.Warnings, fmt.Sprintf("%s does not exist in %s (DST starts), that run fires at %s instead",
+			scan.skippedWall, preview.Timezone, scan.skipped.Format(time.RFC3339)))
+	}
+	if !scan.repeated.IsZero() {
+		preview.Warnings = append(preview.Warnings, fmt.Sprintf("%s occurs twice in %s (DST ends), it fires only the first time, at %s",
+			scan.repeated.Format("2006-01-02 15:04"), preview.Timezone, scan.repeated.Format(time.RFC3339)))
+	}
+	return preview
+}
+
+type scanResult struct {
+	never    bool
+	tooOften bool
+	gap      time.Duration
+	gapAt    time.Time
+	// First run in the scanned range moved by DST, with the wall-clock time it was meant for
+	skipped     time.Time
+	skippedWall string
+	repeated    time.Time
+}
+
+// scan looks at the occurrences within a year after now, up to
+// scanOccurrences, for runs closer than minInterval and DST adjustments
+func (s *Schedule) scan(now time.Time, minInterval time.Duration) scanResult {
+	var result scanResult
+	end := now.AddDate(1, 0, 0)
+	prev := now
+	for i := 0; i < scanOccurrences; i++ {
+		next, adj, ok := s.next(prev)
+		if !ok {
+			result.never = i == 0
+			break
+		}
+		if next.After(end) && i > 0 {
+			break
+		}
+		if i > 0 && minInterval > 0 && !result.tooOften && next.Sub(prev) < minInterval {
+			result.tooOften, result.gap, result.gapAt = true, next.Sub(prev), prev
+		}
+		switch {
+		case adj == adjustSkipped && result.skipped.IsZero():
+			result.skipped = next
+			result.skippedWall = s.skippedWall(next)
+		case adj == adjustRepeated && result.repeated.IsZero():
+			result.repeated = next
+		}
+		prev = next
+	}
+	return result
+}
+
+// skippedWall names the wall-clock times of the run's day that do not exist
+// and were moved to the run
+func (s *Schedule) skippedWall(run time.Time) string {
+	loc := run.Location()
+	y, m, d := run.Date()
+	candidates := []string{}
+	for hours := s.hour; hours != 0; hours &= hours - 1 {
+		h := bits.TrailingZeros64(hours)
+		for minutes := s.minute; minutes != 0; minutes &= minutes - 1 {
+			min := bits.TrailingZeros64(minutes)
+			if at, adj := resolve(y, m, d, h, min, loc); adj == adjustSkipped && at.Equal(run) {
+				candidates = append(candidates, fmt.Sprintf("%04d-%02d-%02d %02d:%02d", y, m, d, h, min))
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return run.Format("2006-01-02 15:04")
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// formatDuration formats d without zero minutes and seconds, e.g. 1h and 20m
+func formatDuration(d time.Duration) string {
+	text := d.String()
+	if d%time.Minute == 0 {
+		text = strings.TrimSuffix(text, "0s")
+	}
+	if d%time.Hour == 0 {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
@@ -0,0 +1,81 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	if _, err := Validate("0 9 * * mon", time.UTC, time.Hour); err != nil {
+		t.Errorf("weekly schedule: %v", err)
+	}
+	if _, err := Validate("*/30 * * * *", time.UTC, time.Hour); err == nil || !strings.Contains(err.Error(), "fires 30m after") ||
+		!strings.Contains(err.Error(), "more often than the minimum of 1h") {
+		t.Errorf("every 30 minutes: err = %v", err)
+	}
+	if _, err := Validate("0 0 31 2 *", time.UTC, time.Hour); err == nil || !strings.Contains(err.Error(), "never fires") {
+		t.Errorf("February 31: err = %v", err)
+	}
+	if _, err := Validate("0 0 * *", time.UTC, time.Hour); err == nil {
+		t.Error("invalid expression accepted")
+	}
+}
+
+func hasWarning(warnings []string, text string) bool {
+	for _, w := range warnings {
+		if strings.Contains(w, text) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestNewPreview(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, berlin)
+
+	s, _ := Parse("30 2 * * *")
+	preview := NewPreview(s, now, 3, time.Hour)
+	if preview.Timezone != "Europe/Berlin" || len(preview.Next) != 3 || preview.Expression != "30 2 * * *" {
+		t.Errorf("preview %+v", preview)
+	}
+	if !hasWarning(preview.Warnings, "2026-03-29 02:30 does not exist in Europe/Berlin (DST starts), that run fires at 2026-03-29T03:30:00+02:00 instead") {
+		t.Errorf("no DST start warning in %q", preview.Warnings)
+	}
+	if !hasWarning(preview.Warnings, "2026-10-25 02:30 occurs twice in Europe/Berlin (DST ends), it fires only the first time, at 2026-10-25T02:30:00+02:00") {
+		t.Errorf("no DST end warning in %q", preview.Warnings)
+	}
+
+	s, _ = Parse("0 9 1 * mon")
+	if preview := NewPreview(s, now, 1, 0); !hasWarning(preview.Warnings, "both day-of-month and day-of-week are set") || len(preview.Warnings) != 1 {
+		t.Errorf("day fields warning %q", preview.Warnings)
+	}
+
+	s, _ = Parse("*/10 * * * *")
+	if preview := NewPreview(s, now.In(time.UTC), 1, time.Hour); !hasWarning(preview.Warnings, "fires 10m after") {
+		t.Errorf("minimum interval warning %q", preview.Warnings)
+	}
+
+	s, _ = Parse("0 0 30 2 *")
+	if preview := NewPreview(s, now, 5, 0); len(preview.Next) != 0 || preview.Next == nil || len(preview.Warnings) != 1 || preview.Warnings[0] != "the schedule never fires" {
+		t.Errorf("never firing preview %+v", preview)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		time.Hour:        "1h",
+		20 * time.Minute: "20m",
+		90 * time.Minute: "1h30m",
+		90 * time.Second: "1m30s",
+		36 * time.Hour:   "36h",
+	} {
+		if got := formatDuration(d); got != want {
+			t.Errorf("formatDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
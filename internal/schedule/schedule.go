@@ -0,0 +1,347 @@
+// Package schedule parses and validates the cron expressions and
+// weekday/time settings accepted by scheduled features, so that they all
+// report errors the same way.
+package schedule
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	// 预览接口按名称加载任意时区，不依赖系统的时区数据库
+	_ "time/tzdata"
+)
+
+// Schedule is a parsed five-field cron expression: minute, hour, day of
+// month, month and day of week. Times are wall-clock times in the location
+// of the time passed to Next.
+type Schedule struct {
+	expr   string
+	minute uint64 // bit i set: minute i matches
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	// As in Vixie cron, when both day fields are restricted a day matches
+	// either of them; a field starting with * does not restrict
+	domStar, dowStar bool
+}
+
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var fields = [5]field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day-of-month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	// 7 is Sunday as well
+	{name: "day-of-week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a cron expression such as "0 9 * * mon-fri" or a macro such
+// as "@daily". A schedule that parses may still never fire, e.g.
+// "0 0 31 2 *"; Validate rejects those.
+func Parse(expr string) (*Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	if strings.HasPrefix(spec, "@") {
+		expanded, ok := macros[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("unknown schedule macro %q", spec)
+		}
+		spec = expanded
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("schedule %q has %d fields, expected 5: minute hour day-of-month month day-of-week", expr, len(parts))
+	}
+
+	s := &Schedule{expr: strings.TrimSpace(expr)}
+	sets := [5]*uint64{&s.minute, &s.hour, &s.dom, &s.month, &s.dow}
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, err
+		}
+		*sets[i] = set
+	}
+	// Sunday may be written as 7
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domStar = strings.HasPrefix(parts[2], "*")
+	s.dowStar = strings.HasPrefix(parts[4], "*")
+	return s, nil
+}
+
+// parseField parses a comma-separated list of *, values, ranges and steps
+func parseField(spec string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(spec, ",") {
+		rangePart, step := item, 1
+		if i := strings.IndexByte(item, '/'); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("%s field %q: step must be a positive number", f.name, item)
+			}
+			rangePart, step = item[:i], n
+		}
+
+		var lo, hi int
+		switch {
+		case rangePart == "*":
+			lo, hi = f.min, f.max
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = f.value(bounds[0], item); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(bounds[1], item); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("%s field %q: range start is after its end", f.name, item)
+			}
+		default:
+			v, err := f.value(rangePart, item)
+			if err != nil {
+				return 0, err
+			}
+			// "5/15" means from 5 to the end in steps of 15
+			lo, hi = v, v
+			if strings.Contains(item, "/") {
+				hi = f.max
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+func (f field) value(s, item string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%s field %q: %q is not a number", f.name, item, s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s field %q: %d is out of range %d-%d", f.name, item, v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Weekly returns the schedule firing once a week at a time of day, e.g.
+// "monday" and "09:00"
+func Weekly(weekday, at string) (*Schedule, error) {
+	day, err := ParseWeekday(weekday)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := ParseTimeOfDay(at)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(fmt.Sprintf("%d %d * * %d", int(offset.Minutes())%60, int(offset.Hours()), int(day)))
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// ParseWeekday parses an English weekday name such as "monday"
+func ParseWeekday(s string) (time.Weekday, error) {
+	day, ok := weekdays[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return 0, fmt.Errorf("invalid weekday %q, expected a name such as monday", s)
+	}
+	return day, nil
+}
+
+// ParseTimeOfDay parses "HH:MM" and returns the offset from midnight
+func ParseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// String returns the expression the schedule was parsed from
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+// searchYears bounds the search for the next occurrence; a schedule that only
+// fires on February 29 fires at least every 8 years
+const searchYears = 8
+
+// DST adjustments of an occurrence
+type adjustment int
+
+const (
+	adjustNone     adjustment = iota
+	adjustSkipped             // the wall-clock time was skipped when DST started, moved forward by the gap
+	adjustRepeated            // the wall-clock time occurred twice when DST ended, fired at the first
+)
+
+// Next returns the first occurrence after t in t's location. ok is false if
+// the schedule never fires.
+//
+// A time skipped when DST starts fires that much later (02:30 becomes 03:30
+// when the clocks go from 02:00 to 03:00), and a time that occurs twice when
+// DST ends fires only the first time.
+func (s *Schedule) Next(t time.Time) (time.Time, bool) {
+	next, _, ok := s.next(t)
+	return next, ok
+}
+
+// NextN returns up to n occurrences after t
+func (s *Schedule) NextN(t time.Time, n int) []time.Time {
+	var times []time.Time
+	for len(times) < n {
+		next, ok := s.Next(t)
+		if !ok {
+			break
+		}
+		times = append(times, next)
+		t = next
+	}
+	return times
+}
+
+func (s *Schedule) next(t time.Time) (time.Time, adjustment, bool) {
+	loc := t.Location()
+	y, m, d := t.Date()
+	end := y + searchYears + 1
+	for day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC); day.Year() < end; day = day.AddDate(0, 0, 1) {
+		best, adj, ok := s.firstOnDay(day, t, loc)
+		if !ok {
+			continue
+		}
+		// A time moved forward by a DST gap can land on the next day, after
+		// that day's first occurrence
+		if adj == adjustSkipped {
+			if following, followingAdj, ok := s.firstOnDay(day.AddDate(0, 0, 1), t, loc); ok && following.Before(best) {
+				best, adj = following, followingAdj
+			}
+		}
+		return best, adj, true
+	}
+	return time.Time{}, adjustNone, false
+}
+
+// firstOnDay returns the earliest occurrence after t among the wall-clock
+// times of day (a date at UTC midnight)
+func (s *Schedule) firstOnDay(day, t time.Time, loc *time.Location) (time.Time, adjustment, bool) {
+	if !s.matchesDay(day) {
+		return time.Time{}, adjustNone, false
+	}
+
+	var best time.Time
+	var bestAdj adjustment
+	found := false
+	for hours := s.hour; hours != 0; hours &= hours - 1 {
+		h := bits.TrailingZeros64(hours)
+		for minutes := s.minute; minutes != 0; minutes &= minutes - 1 {
+			min := bits.TrailingZeros64(minutes)
+			at, adj := resolve(day.Year(), day.Month(), day.Day(), h, min, loc)
+			if !at.After(t) {
+				continue
+			}
+			if !found || at.Before(best) {
+				best, bestAdj, found = at, adj, true
+			}
+			// Later wall-clock times are later instants than an unadjusted
+			// one, but not necessarily than a time moved forward by a gap
+			if adj != adjustSkipped {
+				return best, bestAdj, true
+			}
+		}
+	}
+	return best, bestAdj, found
+}
+
+func (s *Schedule) matchesDay(day time.Time) bool {
+	if s.month&(1<<uint(day.Month())) == 0 {
+		return false
+	}
+	domMatch := s.dom&(1<<uint(day.Day())) != 0
+	dowMatch := s.dow&(1<<uint(day.Weekday())) != 0
+	switch {
+	case s.domStar && s.dowStar:
+		return true
+	case s.domStar:
+		return dowMatch
+	case s.dowStar:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
+
+// resolve returns the instant of a wall-clock time in loc
+func resolve(y int, m time.Month, d, h, min int, loc *time.Location) (time.Time, adjustment) {
+	wall := time.Date(y, m, d, h, min, 0, 0, time.UTC)
+	approx := time.Date(y, m, d, h, min, 0, 0, loc)
+	// The offsets before and after a DST change around this time
+	_, before := approx.Add(-3 * time.Hour).Zone()
+	_, after := approx.Add(3 * time.Hour).Zone()
+
+	var valid []time.Time
+	for _, offset := range []int{before, after} {
+		at := wall.Add(-time.Duration(offset) * time.Second).In(loc)
+		ay, am, ad := at.Date()
+		if ay == y && am == m && ad == d && at.Hour() == h && at.Minute() == min {
+			if len(valid) == 0 || !valid[0].Equal(at) {
+				valid = append(valid, at)
+			}
+		}
+	}
+
+	switch len(valid) {
+	case 0:
+		// Skipped: read with the offset from before the change, it lands
+		// after the change by the same distance as into the gap
+		return wall.Add(-time.Duration(before) * time.Second).In(loc), adjustSkipped
+	case 1:
+		return valid[0], adjustNone
+	default:
+		first := valid[0]
+		if valid[1].Before(first) {
+			first = valid[1]
+		}
+		return first, adjustRepeated
+	}
+}
@@ -0,0 +1,109 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	for expr, want := range map[string]string{
+		"* * *":         "has 3 fields, expected 5",
+		"60 * * * *":    `minute field "60": 60 is out of range 0-59`,
+		"*/0 * * * *":   "step must be a positive number",
+		"5-1 * * * *":   "range start is after its end",
+		"0 0 * foo *":   `month field "foo": "foo" is not a number`,
+		"0 0 0 * *":     "day-of-month field",
+		"@fortnightly":  "unknown schedule macro",
+		"0 24 * * *":    "hour field",
+		"0 0 * * mon-8": "out of range 0-7",
+	} {
+		if _, err := Parse(expr); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q): err = %v, want %q", expr, err, want)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	at := func(s string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+	for _, tc := range []struct {
+		expr, from, want string
+	}{
+		{"0 9 * * mon-fri", "2026-02-28 10:00", "2026-03-02 09:00"},
+		{"*/15 * * * *", "2026-03-02 12:07", "2026-03-02 12:15"},
+		{"5/20 * * * *", "2026-03-02 12:05", "2026-03-02 12:25"},
+		{"0 12 * * 7", "2026-03-02 00:00", "2026-03-08 12:00"},
+		{"0 0 1,15 jan-mar *", "2026-03-16 00:00", "2027-01-01 00:00"},
+		// Both day fields restricted: either matches
+		{"0 0 1 * sun", "2026-03-02 00:00", "2026-03-08 00:00"},
+		{"0 0 1 * sun", "2026-03-29 12:00", "2026-04-01 00:00"},
+		{"@daily", "2026-03-02 00:00", "2026-03-03 00:00"},
+		{"@hourly", "2026-03-02 10:59", "2026-03-02 11:00"},
+		{"0 0 29 2 *", "2026-03-01 00:00", "2028-02-29 00:00"},
+	} {
+		s, err := Parse(tc.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tc.expr, err)
+		}
+		if got, ok := s.Next(at(tc.from)); !ok || !got.Equal(at(tc.want)) {
+			t.Errorf("%q after %s = %v, want %s", tc.expr, tc.from, got, tc.want)
+		}
+	}
+
+	never, _ := Parse("0 0 31 2 *")
+	if _, ok := never.Next(at("2026-01-01 00:00")); ok {
+		t.Error("February 31 fires")
+	}
+	hourly, _ := Parse("@hourly")
+	if times := hourly.NextN(at("2026-03-02 10:30"), 3); len(times) != 3 || !times[2].Equal(at("2026-03-02 13:00")) {
+		t.Errorf("NextN = %v", times)
+	}
+}
+
+// A time skipped when DST starts fires that much later, and a time that
+// occurs twice when DST ends fires once, the first time
+func TestNextDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	s, _ := Parse("30 2 * * *")
+
+	spring := s.NextN(time.Date(2026, 3, 28, 12, 0, 0, 0, berlin), 2)
+	if want := time.Date(2026, 3, 29, 1, 30, 0, 0, time.UTC); !spring[0].Equal(want) {
+		t.Errorf("run on the day DST starts = %v, want %v", spring[0], want.In(berlin))
+	}
+	if want := time.Date(2026, 3, 30, 2, 30, 0, 0, berlin); !spring[1].Equal(want) {
+		t.Errorf("next run = %v, want %v", spring[1], want)
+	}
+
+	autumn := s.NextN(time.Date(2026, 10, 24, 12, 0, 0, 0, berlin), 2)
+	if want := time.Date(2026, 10, 25, 0, 30, 0, 0, time.UTC); !autumn[0].Equal(want) {
+		t.Errorf("run on the day DST ends = %v, want the first 02:30 (%v)", autumn[0], want.In(berlin))
+	}
+	if want := time.Date(2026, 10, 26, 2, 30, 0, 0, berlin); !autumn[1].Equal(want) {
+		t.Errorf("next run = %v, want %v", autumn[1], want)
+	}
+}
+
+func TestWeekly(t *testing.T) {
+	s, err := Weekly("Monday", "09:30")
+	if err != nil || s.String() != "30 9 * * 1" {
+		t.Fatalf("Weekly = %v, %v", s, err)
+	}
+	if _, err := Weekly("mondays", "09:30"); err == nil {
+		t.Error("invalid weekday accepted")
+	}
+	if _, err := Weekly("monday", "9.30"); err == nil {
+		t.Error("invalid time accepted")
+	}
+	if d, err := ParseTimeOfDay("23:59"); err != nil || d != 23*time.Hour+59*time.Minute {
+		t.Errorf("ParseTimeOfDay(23:59) = %v, %v", d, err)
+	}
+}
@@ -0,0 +1,363 @@
+// Package spool keeps records that could not be written to their store in
+// size-capped files on disk, in order, until they are replayed.
+package spool
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxSegmentBytes caps the size of one segment file; the oldest segment is
+// what gets dropped when the spool is full
+const maxSegmentBytes = 1 << 20
+
+// ReplayBatch is the most records passed to one call of the replay function
+const ReplayBatch = 100
+
+const segmentExt = ".jsonl"
+
+// ErrRecordTooLarge is returned by Append for a record larger than the spool
+var ErrRecordTooLarge = errors.New("record is larger than the spool")
+
+// Spool is an append-only queue of JSON records in a directory. Records are
+// appended to the newest segment file and replayed from the oldest; once the
+// spool exceeds its size, the oldest segments are deleted. It is safe for
+// concurrent use, but only one Replay runs at a time.
+type Spool struct {
+	name        string
+	dir         string
+	maxBytes    int64
+	segmentSize int64
+
+	mu       sync.Mutex
+	segments []*segment // oldest first, the last one is appended to
+	active   *os.File   // open last segment, nil until the next Append
+	dropped  int64
+	replayed int64
+	// Result of the last Replay that had records to write
+	lastReplayAt time.Time
+	lastError    string
+
+	replaying sync.Mutex
+}
+
+type segment struct {
+	seq     uint64
+	bytes   int64
+	records int
+	// Records of the segment already replayed, and where the next one starts
+	done   int
+	offset int64
+}
+
+// Stats is the state of a spool
+type Stats struct {
+	Name         string     `json:"name"`
+	Dir          string     `json:"dir"`
+	Records      int        `json:"records"` // waiting to be replayed
+	Bytes        int64      `json:"bytes"`
+	MaxBytes     int64      `json:"max_bytes"`
+	Dropped      int64      `json:"dropped"`  // deleted unreplayed because the spool was full, since startup
+	Replayed     int64      `json:"replayed"` // since startup
+	LastReplayAt *time.Time `json:"last_replay_at,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+}
+
+// Open opens the spool in dir, creating the directory, and picks up the
+// records left there by a previous run. name identifies it in logs and stats.
+func Open(name, dir string, maxBytes int64) (*Spool, error) {
+	if maxBytes < 1 {
+		return nil, fmt.Errorf("spool %s: max size must be positive", name)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("spool %s: %w", name, err)
+	}
+
+	s := &Spool{name: name, dir: dir, maxBytes: maxBytes, segmentSize: maxSegmentBytes}
+	// At least four segments, so that dropping one does not empty the spool
+	if s.segmentSize > maxBytes/4 {
+		s.segmentSize = max(maxBytes/4, 1)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("spool %s: %w", name, err)
+	}
+	for _, entry := range entries {
+		seq, ok := parseSegmentName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		seg := &segment{seq: seq}
+		if seg.bytes, seg.records, err = countRecords(s.path(seq)); err != nil {
+			return nil, fmt.Errorf("spool %s: %w", name, err)
+		}
+		s.segments = append(s.segments, seg)
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].seq < s.segments[j].seq })
+	return s, nil
+}
+
+func parseSegmentName(name string) (uint64, bool) {
+	if !strings.HasSuffix(name, segmentExt) {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+	return seq, err == nil
+}
+
+func (s *Spool) path(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%016d%s", seq, segmentExt))
+}
+
+// countRecords returns the size of a segment and its number of complete
+// lines; a line cut short by a crash is ignored by Replay
+func countRecords(path string) (int64, int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int64(len(data)), strings.Count(string(data), "\n"), nil
+}
+
+// Name returns the name the spool was opened with
+func (s *Spool) Name() string {
+	return s.name
+}
+
+// Append marshals record as one JSON line and writes it to disk before
+// returning. When the spool is full, the oldest segments are deleted.
+func (s *Spool) Append(record interface{}) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("spool %s: %w", s.name, err)
+	}
+	line = append(line, '\n')
+	if int64(len(line)) > s.maxBytes {
+		return fmt.Errorf("spool %s: %w", s.name, ErrRecordTooLarge)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last := s.last()
+	if last == nil || last.bytes+int64(len(line)) > s.segmentSize || s.active == nil {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+		last = s.last()
+	}
+	if _, err := s.active.Write(line); err != nil {
+		return fmt.Errorf("spool %s: %w", s.name, err)
+	}
+	if err := s.active.Sync(); err != nil {
+		return fmt.Errorf("spool %s: %w", s.name, err)
+	}
+	last.bytes += int64(len(line))
+	last.records++
+
+	for s.size() > s.maxBytes && len(s.segments) > 1 {
+		s.dropOldest()
+	}
+	return nil
+}
+
+func (s *Spool) last() *segment {
+	if len(s.segments) == 0 {
+		return nil
+	}
+	return s.segments[len(s.segments)-1]
+}
+
+// rotate closes the active segment and starts a new one. Called with mu held.
+func (s *Spool) rotate() error {
+	if s.active != nil {
+		s.active.Close()
+		s.active = nil
+	}
+	seq := uint64(1)
+	if last := s.last(); last != nil {
+		seq = last.seq + 1
+	}
+	f, err := os.OpenFile(s.path(seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("spool %s: %w", s.name, err)
+	}
+	s.active = f
+	s.segments = append(s.segments, &segment{seq: seq})
+	return nil
+}
+
+// dropOldest deletes the oldest segment. Called with mu held.
+func (s *Spool) dropOldest() {
+	oldest := s.segments[0]
+	s.segments = s.segments[1:]
+	os.Remove(s.path(oldest.seq))
+	s.dropped += int64(oldest.records - oldest.done)
+}
+
+func (s *Spool) size() int64 {
+	var total int64
+	for _, seg := range s.segments {
+		total += seg.bytes
+	}
+	return total
+}
+
+// Len returns the number of records waiting to be replayed
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending()
+}
+
+func (s *Spool) pending() int {
+	n := 0
+	for _, seg := range s.segments {
+		n += seg.records - seg.done
+	}
+	return n
+}
+
+// Stats returns the current state of the spool
+func (s *Spool) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := Stats{
+		Name:      s.name,
+		Dir:       s.dir,
+		Records:   s.pending(),
+		Bytes:     s.size(),
+		MaxBytes:  s.maxBytes,
+		Dropped:   s.dropped,
+		Replayed:  s.replayed,
+		LastError: s.lastError,
+	}
+	if !s.lastReplayAt.IsZero() {
+		at := s.lastReplayAt
+		stats.LastReplayAt = &at
+	}
+	return stats
+}
+
+// Replay passes the records to write, oldest first and in batches of at
+// most ReplayBatch, until the spool is empty or write fails. A segment is
+// deleted once all its records were written. After a failure or a restart
+// the batch is passed again, so write must skip records it already has.
+// It returns the number of records written.
+func (s *Spool) Replay(write func(records []json.RawMessage) error) (int, error) {
+	s.replaying.Lock()
+	defer s.replaying.Unlock()
+
+	written := 0
+	for {
+		s.mu.Lock()
+		if len(s.segments) == 0 {
+			s.mu.Unlock()
+			return written, nil
+		}
+		seg := s.segments[0]
+		if seg == s.last() && s.active != nil {
+			if seg.done == seg.records {
+				s.mu.Unlock()
+				return written, nil
+			}
+			// New records go to the next segment while this one is replayed
+			s.active.Close()
+			s.active = nil
+		}
+		s.mu.Unlock()
+
+		n, err := s.replaySegment(seg, write)
+		written += n
+		s.mu.Lock()
+		s.lastReplayAt = time.Now()
+		if err != nil {
+			s.lastError = err.Error()
+			s.mu.Unlock()
+			return written, err
+		}
+		s.lastError = ""
+		// The segment may have been dropped meanwhile if the spool filled up
+		if len(s.segments) > 0 && s.segments[0] == seg {
+			s.segments = s.segments[1:]
+			os.Remove(s.path(seg.seq))
+		}
+		s.mu.Unlock()
+	}
+}
+
+// replaySegment writes the records of seg from its offset on
+func (s *Spool) replaySegment(seg *segment, write func(records []json.RawMessage) error) (int, error) {
+	f, err := os.Open(s.path(seg.seq))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("spool %s: %w", s.name, err)
+	}
+	defer f.Close()
+
+	s.mu.Lock()
+	offset := seg.offset
+	s.mu.Unlock()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("spool %s: %w", s.name, err)
+	}
+
+	reader := bufio.NewReader(f)
+	written := 0
+	for {
+		var batch []json.RawMessage
+		var batchBytes int64
+		for len(batch) < ReplayBatch {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				// io.EOF, possibly after a line cut short by a crash
+				break
+			}
+			batchBytes += int64(len(line))
+			batch = append(batch, json.RawMessage(line[:len(line)-1]))
+		}
+		if len(batch) == 0 {
+			return written, nil
+		}
+		if err := write(batch); err != nil {
+			return written, err
+		}
+		written += len(batch)
+
+		s.mu.Lock()
+		seg.offset += batchBytes
+		seg.done += len(batch)
+		s.replayed += int64(len(batch))
+		// Dropped by Append meanwhile because the spool filled up
+		dropped := len(s.segments) == 0 || s.segments[0] != seg
+		s.mu.Unlock()
+		if dropped {
+			return written, nil
+		}
+	}
+}
+
+// Close closes the segment being appended to; the records stay on disk
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active == nil {
+		return nil
+	}
+	err := s.active.Close()
+	s.active = nil
+	return err
+}
@@ -0,0 +1,172 @@
+package spool
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+type record struct {
+	N int `json:"n"`
+}
+
+func appendRecords(t *testing.T, s *Spool, from, to int) {
+	t.Helper()
+	for n := from; n <= to; n++ {
+		if err := s.Append(record{N: n}); err != nil {
+			t.Fatalf("Append %d: %v", n, err)
+		}
+	}
+}
+
+// replayAll replays s and returns the record numbers written, batch by batch
+func replayAll(t *testing.T, s *Spool) [][]int {
+	t.Helper()
+	var batches [][]int
+	if _, err := s.Replay(func(records []json.RawMessage) error {
+		var batch []int
+		for _, raw := range records {
+			var r record
+			if err := json.Unmarshal(raw, &r); err != nil {
+				t.Fatalf("unmarshal %q: %v", raw, err)
+			}
+			batch = append(batch, r.N)
+		}
+		batches = append(batches, batch)
+		return nil
+	}); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	return batches
+}
+
+func TestAppendAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open("history", dir, 1<<20)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	appendRecords(t, s, 1, ReplayBatch+5)
+	if stats := s.Stats(); stats.Records != ReplayBatch+5 || stats.Bytes == 0 || stats.Name != "history" {
+		t.Errorf("stats %+v", stats)
+	}
+
+	batches := replayAll(t, s)
+	if len(batches) != 2 || len(batches[0]) != ReplayBatch || len(batches[1]) != 5 || batches[0][0] != 1 || batches[1][4] != ReplayBatch+5 {
+		t.Fatalf("batches of %d records, want %d and 5 in order", len(batches), ReplayBatch)
+	}
+	stats := s.Stats()
+	if stats.Records != 0 || stats.Bytes != 0 || stats.Replayed != ReplayBatch+5 || stats.LastReplayAt == nil {
+		t.Errorf("stats after replay %+v", stats)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("%d segment files left after replay", len(entries))
+	}
+
+	// Records appended after a replay go to a new segment
+	appendRecords(t, s, 1, 2)
+	if batches := replayAll(t, s); len(batches) != 1 || !slices.Equal(batches[0], []int{1, 2}) {
+		t.Errorf("second replay %v", batches)
+	}
+}
+
+// A failed batch is passed again on the next Replay, and the error is kept
+// in the stats until a replay succeeds
+func TestReplayRetriesFailedBatch(t *testing.T) {
+	s, err := Open("elasticsearch", t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	appendRecords(t, s, 1, 3)
+
+	down := errors.New("store is down")
+	n, err := s.Replay(func([]json.RawMessage) error { return down })
+	if n != 0 || !errors.Is(err, down) {
+		t.Errorf("Replay = %d, %v, want 0 and the write error", n, err)
+	}
+	if stats := s.Stats(); stats.Records != 3 || stats.LastError != down.Error() {
+		t.Errorf("stats after a failure %+v", stats)
+	}
+
+	appendRecords(t, s, 4, 4)
+	if batches := replayAll(t, s); len(batches) != 2 || !slices.Equal(batches[0], []int{1, 2, 3}) || !slices.Equal(batches[1], []int{4}) {
+		t.Errorf("replay after the failure %v", batches)
+	}
+	if stats := s.Stats(); stats.Records != 0 || stats.LastError != "" {
+		t.Errorf("stats after recovery %+v", stats)
+	}
+}
+
+// Records left by a previous run are replayed after a restart, without a line
+// cut short by a crash
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open("history", dir, 1<<20)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	appendRecords(t, s, 1, 2)
+	s.Close()
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if len(segments) != 1 {
+		t.Fatalf("segments %v, want one", segments)
+	}
+	f, err := os.OpenFile(segments[0], os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("open segment: %v", err)
+	}
+	f.WriteString(`{"n":`)
+	f.Close()
+	// Not a segment, left alone
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x\n"), 0o644)
+
+	s, err = Open("history", dir, 1<<20)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s.Close()
+	if n := s.Len(); n != 2 {
+		t.Errorf("Len after reopen = %d, want 2", n)
+	}
+	appendRecords(t, s, 3, 3)
+	if batches := replayAll(t, s); len(batches) != 2 || !slices.Equal(batches[0], []int{1, 2}) || !slices.Equal(batches[1], []int{3}) {
+		t.Errorf("replay after reopen %v", batches)
+	}
+}
+
+// Once over its size the spool drops its oldest records
+func TestFullSpoolDropsOldest(t *testing.T) {
+	// Each record is 8 bytes, `{"n":1}` and a newline, and fills a segment of 10
+	s, err := Open("history", t.TempDir(), 40)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	appendRecords(t, s, 1, 8)
+	stats := s.Stats()
+	if stats.Records != 5 || stats.Dropped != 3 || stats.Bytes > stats.MaxBytes {
+		t.Errorf("stats %+v, want 5 records and 3 dropped", stats)
+	}
+	var replayed []int
+	for _, batch := range replayAll(t, s) {
+		replayed = append(replayed, batch...)
+	}
+	if !slices.Equal(replayed, []int{4, 5, 6, 7, 8}) {
+		t.Errorf("replayed %v, want the newest five", replayed)
+	}
+
+	if err := s.Append(map[string]string{"message": string(make([]byte, 40))}); !errors.Is(err, ErrRecordTooLarge) {
+		t.Errorf("Append of a record larger than the spool: err = %v", err)
+	}
+	if _, err := Open("history", t.TempDir(), 0); err == nil {
+		t.Error("Open accepted a zero size")
+	}
+}
@@ -0,0 +1,63 @@
+package version
+
+import (
+	"runtime"
+	"sync"
+
+	"monitor/internal/database"
+)
+
+// 以下变量在构建时通过 -ldflags 注入，例如:
+//
+//	go build -ldflags "-X monitor/internal/version.Version=1.2.0 \
+//	  -X monitor/internal/version.GitCommit=$(git rev-parse --short HEAD) \
+//	  -X monitor/internal/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/server
+var (
+	Version   = "1.0.0"
+	GitCommit = "unknown"
+	BuildDate = "unknown"
+)
+
+// Features 各子系统的启用状态
+type Features struct {
+	Elasticsearch bool `json:"elasticsearch"`
+	Alerting      bool `json:"alerting"`
+	GRPC          bool `json:"grpc"`
+}
+
+// Info 构建与运行信息
+type Info struct {
+	Version       string   `json:"version"`
+	GitCommit     string   `json:"git_commit"`
+	BuildDate     string   `json:"build_date"`
+	GoVersion     string   `json:"go_version"`
+	SchemaVersion int      `json:"schema_version"`
+	Features      Features `json:"features"`
+}
+
+var (
+	mu       sync.RWMutex
+	features Features
+)
+
+// SetFeatures 记录启动时启用的子系统，HTTP 与 gRPC 接口都从这里读取
+func SetFeatures(f Features) {
+	mu.Lock()
+	features = f
+	mu.Unlock()
+}
+
+// Get 返回当前的版本信息
+func Get() Info {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return Info{
+		Version:       Version,
+		GitCommit:     GitCommit,
+		BuildDate:     BuildDate,
+		GoVersion:     runtime.Version(),
+		SchemaVersion: database.SchemaVersion,
+		Features:      features,
+	}
+}
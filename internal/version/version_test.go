@@ -0,0 +1,28 @@
+package version
+
+import (
+	"runtime"
+	"testing"
+
+	"monitor/internal/database"
+)
+
+func TestGet(t *testing.T) {
+	t.Cleanup(func() { SetFeatures(Features{}) })
+
+	info := Get()
+	if info.Version != Version || info.GitCommit != GitCommit || info.BuildDate != BuildDate {
+		t.Errorf("build info = %+v, want the injected variables", info)
+	}
+	if info.GoVersion != runtime.Version() || info.SchemaVersion != database.SchemaVersion {
+		t.Errorf("go %s schema %d, want %s and %d", info.GoVersion, info.SchemaVersion, runtime.Version(), database.SchemaVersion)
+	}
+	if info.Features != (Features{}) {
+		t.Errorf("features before SetFeatures = %+v, want all disabled", info.Features)
+	}
+
+	SetFeatures(Features{Alerting: true, GRPC: true})
+	if got := Get().Features; got != (Features{Alerting: true, GRPC: true}) {
+		t.Errorf("features = %+v, want alerting and grpc", got)
+	}
+}
@@ -0,0 +1,578 @@
+// Package apitypes holds the request and response bodies of the HTTP API.
+// The server binds and returns these types and pkg/client sends and decodes
+// them, so the two cannot drift apart. Responses are the /api/v2 shapes.
+package apitypes
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ErrorResponse is returned with every 4xx/5xx status
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// MessageResponse is returned by updates and deletes
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// CreatedResponse is returned with 201 by the add endpoints
+type CreatedResponse struct {
+	ID       uint32   `json:"id"`
+	Message  string   `json:"message"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// IDRequest selects one object by ID
+type IDRequest struct {
+	ID uint32 `json:"id" binding:"required"`
+}
+
+// AddMonitorRequest creates a monitor; /monitor/update takes the same fields plus id
+type AddMonitorRequest struct {
+	Name     string            `json:"name" binding:"required"`
+	Type     string            `json:"type" binding:"required"` // see GET /api/v1/monitor/types
+	Address  string            `json:"address" binding:"required"`
+	Port     int32             `json:"port"`
+	Interval int64             `json:"interval"`
+	Metadata map[string]string `json:"metadata"`
+	Enabled  bool              `json:"enabled"`
+	// Deadline of one check in seconds, between 1 and interval; 0 for the default of 30
+	TimeoutSeconds int `json:"timeout_seconds"`
+	// Runs of a check that comes back down, at most 5, within timeout_seconds
+	RetryCount           int `json:"retry_count"`
+	RetryIntervalSeconds int `json:"retry_interval_seconds"`
+
+	// HTTP/HTTPS specific fields
+	HTTPMethod          string            `json:"http_method"`           // GET, POST, PUT, DELETE, etc.
+	HTTPHeaders         map[string]string `json:"http_headers"`          // Custom headers
+	HTTPBody            string            `json:"http_body"`             // Request body
+	ResolvedHost        string            `json:"resolved_host"`         // Custom host resolution
+	UnixSocketPath      string            `json:"unix_socket_path"`      // Absolute path of a Unix socket to connect through
+	ProxyURL            string            `json:"proxy_url"`             // http, https or socks5 proxy; a password left out on update is kept
+	FollowRedirects     bool              `json:"follow_redirects"`      // Follow 301/302 redirects
+	MaxRedirects        int               `json:"max_redirects"`         // Maximum redirect depth
+	ExpectedStatusCodes string            `json:"expected_status_codes"` // Comma-separated status codes
+	ExpectedHeaders     map[string]string `json:"expected_headers"`      // Header name to "" (present), "regex:<RE2>" or text the value must contain
+	BodyMustContain     string            `json:"body_must_contain"`     // Text the decoded response body must contain
+	BodyMustNotContain  string            `json:"body_must_not_contain"` // Text it must not contain, e.g. from an error page
+	BodyRegex           string            `json:"body_regex"`            // RE2 pattern it must match
+	JSONPath            string            `json:"json_path"`             // Field of a JSON body, e.g. data.items[0].status
+	JSONExpectedValue   string            `json:"json_expected_value"`   // Compared with the field; empty only requires it to exist
+	JSONOperator        string            `json:"json_operator"`         // eq (default), ne, gt, lt, contains
+	Steps               []HTTPStep        `json:"steps"`                 // Transaction sent instead of the single request, at most 10 steps
+	AuthType            string            `json:"auth_type"`             // none (default), basic, bearer
+	AuthUsername        string            `json:"auth_username"`         // basic
+	AuthPassword        string            `json:"auth_password"`         // basic; kept when empty on update
+	AuthToken           string            `json:"auth_token"`            // bearer, without the "Bearer " prefix; kept when empty on update
+
+	// Content change detection: compare the SHA-256 of the body between checks
+	TrackContentHash     bool   `json:"track_content_hash"`
+	ContentHashRegex     string `json:"content_hash_regex"`     // RE2 pattern scoping the hashed part; capture group 1 if it has one
+	ContentHashNormalize bool   `json:"content_hash_normalize"` // Drop scripts, styles and comments and collapse whitespace before hashing
+
+	// DNS specific fields
+	DNSServer         string   `json:"dns_server"`          // Custom DNS server (e.g., 8.8.8.8:53)
+	DNSServerName     string   `json:"dns_server_name"`     // DNS server name (e.g., "Google DNS")
+	DNSServerType     string   `json:"dns_server_type"`     // DNS protocol: udp, tcp, doh, dot
+	DNSServers        string   `json:"dns_servers"`         // Comma-separated provider IDs or addresses queried together and compared
+	DNSConsensus      string   `json:"dns_consensus"`       // all (default), majority, any, propagation
+	DNSQueryType      string   `json:"dns_query_type"`      // A (default), AAAA, CNAME, MX, TXT, NS, SOA, CAA, PTR, SRV
+	DNSExpectedValues []string `json:"dns_expected_values"` // Records the answer must have, order-insensitive
+	DNSMatchMode      string   `json:"dns_match_mode"`      // exact (default): the records are the expected values; subset: every record is one of them
+
+	// PING specific fields
+	PingCount   int `json:"ping_count"`   // Number of ping packets (default: 4)
+	PingSize    int `json:"ping_size"`    // Size of ping packet in bytes (default: 32)
+	PingTimeout int `json:"ping_timeout"` // Timeout in milliseconds (default: 5000)
+
+	// SMTP specific fields
+	SMTPUsername      string `json:"smtp_username"`       // SMTP authentication username
+	SMTPPassword      string `json:"smtp_password"`       // SMTP authentication password
+	SMTPUseTLS        bool   `json:"smtp_use_tls"`        // Use TLS/SSL (default: false)
+	SMTPMailFrom      string `json:"smtp_mail_from"`      // From address for test
+	SMTPMailTo        string `json:"smtp_mail_to"`        // To address for test
+	SMTPCheckStartTLS bool   `json:"smtp_check_starttls"` // Check STARTTLS support (default: true)
+
+	// SNMP specific fields
+	SNMPCommunity     string `json:"snmp_community"`      // SNMP community string (default: public)
+	SNMPOID           string `json:"snmp_oid"`            // SNMP OID to query
+	SNMPVersion       string `json:"snmp_version"`        // SNMP version: v1, v2c, v3
+	SNMPExpectedValue string `json:"snmp_expected_value"` // Expected value for comparison
+	SNMPOperator      string `json:"snmp_operator"`       // eq, ne, gt, lt, ge, le
+
+	// TCP specific fields
+	TCPSendString   string `json:"tcp_send_string"`   // Sent as is after the connect, e.g. "PING\r\n"
+	TCPExpectString string `json:"tcp_expect_string"` // Text the reply must contain, or /regex/
+	TCPUseTLS       bool   `json:"tcp_use_tls"`       // TLS handshake before sending, SNI from the address
+
+	// UDP specific fields
+	UDPPreset         string `json:"udp_preset"`          // Built-in probe: dns, ntp, sip_options
+	UDPSendPayload    string `json:"udp_send_payload"`    // Datagram to send, encoded as udp_payload_format
+	UDPPayloadFormat  string `json:"udp_payload_format"`  // hex (default) or base64
+	UDPExpectResponse bool   `json:"udp_expect_response"` // Down when no reply arrives before the timeout
+	UDPExpectPattern  string `json:"udp_expect_pattern"`  // RE2 pattern the reply must match
+
+	// SSL/TLS specific fields
+	SSLWarnDays     int  `json:"ssl_warn_days"`     // Days before expiration to warn (default: 30)
+	SSLCriticalDays int  `json:"ssl_critical_days"` // Days before expiration to mark as critical (default: 7)
+	SSLCheck        bool `json:"ssl_check"`         // Enable SSL/TLS certificate monitoring
+	SSLGetChain     bool `json:"ssl_get_chain"`     // Get certificate chain information
+
+	// Mutual TLS (http, https, ssl)
+	TLSClientCertPEM string `json:"tls_client_cert_pem"` // Client certificate, intermediates may follow
+	TLSClientKeyPEM  string `json:"tls_client_key_pem"`  // Its private key; kept when empty on update
+	TLSCACertPEM     string `json:"tls_ca_cert_pem"`     // CA verifying the server, replacing the system roots
+	TLSSkipVerify    bool   `json:"tls_skip_verify"`     // Don't verify the server certificate
+	TLSPinSHA256     string `json:"tls_pin_sha256"`      // Expected SHA-256 of the leaf certificate, hex
+
+	// Script specific fields; adding or updating a script monitor needs the admin token
+	ScriptPath    string   `json:"script_path"`    // Absolute path listed in monitor.script.allowed_paths
+	ScriptArgs    []string `json:"script_args"`    // Arguments, passed without a shell
+	ScriptTimeout int      `json:"script_timeout"` // Seconds (default: 10, at most 25)
+
+	// Comparison mode (http, https, tcp): the secondary address is checked with
+	// the same settings and compared; the primary address decides the status
+	SecondaryAddress        string `json:"secondary_address"`         // Empty turns comparison mode off
+	CompareLatencyTolerance int    `json:"compare_latency_tolerance"` // Milliseconds; 0 does not compare latency
+	CompareBody             bool   `json:"compare_body"`              // Compare the SHA-256 of the response bodies (http, https)
+
+	// Response time thresholds (http, https, tcp, dns) in milliseconds, 0 for
+	// none: a successful check this slow is degraded, or down
+	DegradedThresholdMs int `json:"degraded_threshold_ms"`
+	DownThresholdMs     int `json:"down_threshold_ms"` // Greater than degraded_threshold_ms
+
+	// Operator notes
+	Notes      string `json:"notes"`       // Markdown, at most monitor.MaxNotesLength bytes
+	RunbookURL string `json:"runbook_url"` // http(s) link to the runbook
+
+	// Result sinks: "" for all, "none", or comma-separated db_history, es, file
+	Sinks string `json:"sinks"`
+
+	// Tags such as "prod" or "team:payments", at most monitor.MaxTags
+	Tags []string `json:"tags"`
+
+	// Monitor group, omitted or null for none; see /group/add
+	GroupID *uint32 `json:"group_id"`
+
+	// Monitor this one depends on, e.g. the site's uplink; while it is down,
+	// down results of this monitor do not alert. Omitted or null for none.
+	DependsOnTargetID *uint32 `json:"depends_on_target_id"`
+}
+
+// HTTPStep is one request of a transaction. The URL, header values and body
+// may contain {{steps.N.json.<path>}}, {{steps.N.header.<name>}} and
+// {{steps.N.status_code}}, filled in from the response of the earlier step N
+// (counted from 0). The monitor's headers, authentication, TLS, proxy and
+// redirect settings apply to every step.
+type HTTPStep struct {
+	Name                string            `json:"name,omitempty"`
+	Method              string            `json:"method,omitempty"` // GET when empty
+	URL                 string            `json:"url,omitempty"`    // Absolute, or resolved against the address; empty for the address
+	Headers             map[string]string `json:"headers,omitempty"`
+	Body                string            `json:"body,omitempty"`
+	ExpectedStatusCodes string            `json:"expected_status_codes,omitempty"` // As on the monitor; empty for any 2xx
+	ExpectedHeaders     map[string]string `json:"expected_headers,omitempty"`      // As on the monitor
+	BodyMustContain     string            `json:"body_must_contain,omitempty"`
+	BodyMustNotContain  string            `json:"body_must_not_contain,omitempty"`
+	BodyRegex           string            `json:"body_regex,omitempty"`
+	JSONPath            string            `json:"json_path,omitempty"`
+	JSONExpectedValue   string            `json:"json_expected_value,omitempty"`
+	JSONOperator        string            `json:"json_operator,omitempty"`
+}
+
+// UpdateMonitorRequest replaces every field of a monitor
+type UpdateMonitorRequest struct {
+	IDRequest
+	AddMonitorRequest
+}
+
+// ListMonitorsRequest optionally filters /monitor/list; an empty body lists every monitor
+type ListMonitorsRequest struct {
+	Type        string `json:"type,omitempty"`
+	Enabled     *bool  `json:"enabled,omitempty"`
+	ConfigError *bool  `json:"config_error,omitempty"` // true 只返回因配置错误未调度的监控，false 排除它们
+
+	Tags []string `json:"tags,omitempty"` // 只返回带有全部这些标签的监控
+}
+
+// MonitorResponse v2 的监控对象，类型相关的字段只在对应类型下返回。
+// 不返回 smtp_password，更新 SMTP 监控时需要重新提供。
+// 同样不返回 auth_password、auth_token 和 tls_client_key_pem，只返回是否已设置。
+type MonitorResponse struct {
+	ID       uint32            `json:"id"`
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	Address  string            `json:"address"`
+	Port     int32             `json:"port,omitempty"`
+	Interval int64             `json:"interval"`
+	Enabled  bool              `json:"enabled"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Omitted when the default of 30 applies
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+	// Omitted when failed checks are not retried
+	RetryCount           int `json:"retry_count,omitempty"`
+	RetryIntervalSeconds int `json:"retry_interval_seconds,omitempty"`
+
+	// http, https
+	HTTPMethod          string            `json:"http_method,omitempty"`
+	HTTPHeaders         map[string]string `json:"http_headers,omitempty"`
+	HTTPBody            string            `json:"http_body,omitempty"`
+	ResolvedHost        string            `json:"resolved_host,omitempty"`
+	UnixSocketPath      string            `json:"unix_socket_path,omitempty"`
+	ProxyURL            string            `json:"proxy_url,omitempty"` // Without the password
+	ProxyPasswordSet    *bool             `json:"proxy_password_set,omitempty"`
+	FollowRedirects     *bool             `json:"follow_redirects,omitempty"`
+	MaxRedirects        int               `json:"max_redirects,omitempty"`
+	ExpectedStatusCodes string            `json:"expected_status_codes,omitempty"`
+	ExpectedHeaders     map[string]string `json:"expected_headers,omitempty"`
+	BodyMustContain     string            `json:"body_must_contain,omitempty"`
+	BodyMustNotContain  string            `json:"body_must_not_contain,omitempty"`
+	BodyRegex           string            `json:"body_regex,omitempty"`
+	JSONPath            string            `json:"json_path,omitempty"`
+	JSONExpectedValue   string            `json:"json_expected_value,omitempty"`
+	JSONOperator        string            `json:"json_operator,omitempty"`
+	Steps               []HTTPStep        `json:"steps,omitempty"`
+	AuthType            string            `json:"auth_type,omitempty"`
+	AuthUsername        string            `json:"auth_username,omitempty"`
+	AuthPasswordSet     *bool             `json:"auth_password_set,omitempty"`
+	AuthTokenSet        *bool             `json:"auth_token_set,omitempty"`
+
+	TrackContentHash     bool   `json:"track_content_hash,omitempty"`
+	ContentHashRegex     string `json:"content_hash_regex,omitempty"`
+	ContentHashNormalize bool   `json:"content_hash_normalize,omitempty"`
+
+	// dns
+	DNSServer         string   `json:"dns_server,omitempty"`
+	DNSServerName     string   `json:"dns_server_name,omitempty"`
+	DNSServerType     string   `json:"dns_server_type,omitempty"`
+	DNSServers        string   `json:"dns_servers,omitempty"`
+	DNSConsensus      string   `json:"dns_consensus,omitempty"`
+	DNSQueryType      string   `json:"dns_query_type,omitempty"`
+	DNSExpectedValues []string `json:"dns_expected_values,omitempty"`
+	DNSMatchMode      string   `json:"dns_match_mode,omitempty"`
+
+	// ping
+	PingCount   int `json:"ping_count,omitempty"`
+	PingSize    int `json:"ping_size,omitempty"`
+	PingTimeout int `json:"ping_timeout,omitempty"`
+
+	// smtp
+	SMTPUsername      string `json:"smtp_username,omitempty"`
+	SMTPPasswordSet   *bool  `json:"smtp_password_set,omitempty"`
+	SMTPUseTLS        *bool  `json:"smtp_use_tls,omitempty"`
+	SMTPMailFrom      string `json:"smtp_mail_from,omitempty"`
+	SMTPMailTo        string `json:"smtp_mail_to,omitempty"`
+	SMTPCheckStartTLS *bool  `json:"smtp_check_starttls,omitempty"`
+
+	// snmp
+	SNMPCommunity     string `json:"snmp_community,omitempty"`
+	SNMPOID           string `json:"snmp_oid,omitempty"`
+	SNMPVersion       string `json:"snmp_version,omitempty"`
+	SNMPExpectedValue string `json:"snmp_expected_value,omitempty"`
+	SNMPOperator      string `json:"snmp_operator,omitempty"`
+
+	// tcp
+	TCPSendString   string `json:"tcp_send_string,omitempty"`
+	TCPExpectString string `json:"tcp_expect_string,omitempty"`
+	TCPUseTLS       *bool  `json:"tcp_use_tls,omitempty"`
+
+	// udp
+	UDPPreset         string `json:"udp_preset,omitempty"`
+	UDPSendPayload    string `json:"udp_send_payload,omitempty"`
+	UDPPayloadFormat  string `json:"udp_payload_format,omitempty"`
+	UDPExpectResponse *bool  `json:"udp_expect_response,omitempty"`
+	UDPExpectPattern  string `json:"udp_expect_pattern,omitempty"`
+
+	// https, ssl
+	SSLWarnDays     int   `json:"ssl_warn_days,omitempty"`
+	SSLCriticalDays int   `json:"ssl_critical_days,omitempty"`
+	SSLCheck        *bool `json:"ssl_check,omitempty"`
+	SSLGetChain     *bool `json:"ssl_get_chain,omitempty"`
+
+	// http, https, ssl
+	TLSClientCertPEM   string `json:"tls_client_cert_pem,omitempty"`
+	TLSClientKeyPEMSet *bool  `json:"tls_client_key_pem_set,omitempty"`
+	TLSCACertPEM       string `json:"tls_ca_cert_pem,omitempty"`
+	TLSSkipVerify      *bool  `json:"tls_skip_verify,omitempty"`
+	TLSPinSHA256       string `json:"tls_pin_sha256,omitempty"`
+
+	// script
+	ScriptPath    string   `json:"script_path,omitempty"`
+	ScriptArgs    []string `json:"script_args,omitempty"`
+	ScriptTimeout int      `json:"script_timeout,omitempty"`
+
+	// http, https, tcp in comparison mode
+	SecondaryAddress        string `json:"secondary_address,omitempty"`
+	CompareLatencyTolerance int    `json:"compare_latency_tolerance,omitempty"`
+	CompareBody             *bool  `json:"compare_body,omitempty"`
+
+	// http, https, tcp, dns
+	DegradedThresholdMs int `json:"degraded_threshold_ms,omitempty"`
+	DownThresholdMs     int `json:"down_threshold_ms,omitempty"`
+
+	Notes      string `json:"notes,omitempty"`
+	RunbookURL string `json:"runbook_url,omitempty"`
+	Sinks      string `json:"sinks,omitempty"` // 省略表示写入所有目的地
+
+	Tags              []string `json:"tags,omitempty"`
+	GroupID           *uint32  `json:"group_id,omitempty"`
+	DependsOnTargetID *uint32  `json:"depends_on_target_id,omitempty"`
+
+	// 监控未被调度时的原因，正常调度时省略
+	NotScheduled *NotScheduled `json:"not_scheduled,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NotScheduled is why a monitor is not checked. Reason is "disabled" or, for
+// an enabled monitor whose configuration is invalid, "unsupported_type",
+// "invalid_settings" or "invalid_stored_config".
+type NotScheduled struct {
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+}
+
+// ListMonitorsResponse is returned by /monitor/list
+type ListMonitorsResponse struct {
+	Targets []MonitorResponse `json:"targets"`
+}
+
+// TagCount is a tag and the number of monitors that have it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// ListTagsResponse is returned by /tag/list, sorted by tag
+type ListTagsResponse struct {
+	Tags []TagCount `json:"tags"`
+}
+
+// TriggerCheckRequest starts a check with /monitor/check. With Wait the check
+// runs during the request and the response carries its result.
+type TriggerCheckRequest struct {
+	IDRequest
+	Wait bool `json:"wait,omitempty"`
+}
+
+// TriggerCheckResponse is returned with 202 by /monitor/check. Poll
+// StatusURL (/monitor/check/status/:token) for the result.
+type TriggerCheckResponse struct {
+	Token     string `json:"token"`
+	State     string `json:"state"`
+	StatusURL string `json:"status_url"`
+}
+
+// ListStatusRequest filters /monitor/status/list. Without a target it returns
+// the latest status of every target; limit bounds the rows returned.
+type ListStatusRequest struct {
+	TargetID *uint32  `json:"target_id,omitempty"`
+	Limit    *int     `json:"limit,omitempty"`
+	Tags     []string `json:"tags,omitempty"` // 只返回带有全部这些标签的监控的状态
+}
+
+// StatusResponse v2 的监控状态，证书和 DNS 信息只在有值时返回
+type StatusResponse struct {
+	TargetID           uint32     `json:"target_id"`
+	TargetName         string     `json:"target_name,omitempty"`
+	TargetType         string     `json:"target_type,omitempty"`
+	TargetAddress      string     `json:"target_address,omitempty"`
+	TargetDeleted      bool       `json:"target_deleted"`
+	InMaintenance      bool       `json:"in_maintenance"` // 有维护窗口正在生效
+	Status             string     `json:"status"`
+	ResponseTime       int64      `json:"response_time"`
+	Message            string     `json:"message,omitempty"`
+	CheckedAt          time.Time  `json:"checked_at"`
+	UptimePercentage   int32      `json:"uptime_percentage"` // uptime_30d 取整，保留给旧客户端
+	Uptime24h          float64    `json:"uptime_24h"`        // 百分比，两位小数；warning、degraded 计为正常
+	Uptime7d           float64    `json:"uptime_7d"`
+	Uptime30d          float64    `json:"uptime_30d"`
+	LastStatusChangeAt *time.Time `json:"last_status_change_at,omitempty"`
+	Synthetic          bool       `json:"synthetic"`
+	Flapping           bool       `json:"flapping"`                // 状态频繁变化，单次告警被抑制
+	SuppressedBy       *uint32    `json:"suppressed_by,omitempty"` // 依赖的监控故障期间的 down，没有发送告警
+
+	ResponseTimes *ResponsePercentiles `json:"response_times,omitempty"` // 只有 /monitor/status/get 返回
+	ContentHash   string               `json:"content_hash,omitempty"`   // 最近一次计算的响应体哈希，见 track_content_hash
+
+	SSL        *StatusSSL      `json:"ssl,omitempty"`
+	ResolvedIP string          `json:"resolved_ip,omitempty"`
+	DNSRecords json.RawMessage `json:"dns_records,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"` // 完整的检查结果数据，如证书链
+}
+
+// GetStatusRequest reads /monitor/status/get. The response time percentiles
+// cover the last WindowHours hours, 24 by default and at most 168.
+type GetStatusRequest struct {
+	IDRequest
+	WindowHours int `json:"window_hours,omitempty" binding:"gte=0,lte=168"`
+}
+
+// ResponsePercentiles 窗口内 up、warning、degraded 结果的响应时间百分位（毫秒，最近秩），
+// 没有样本时为 null；样本少时 p99 只是最慢的几次结果之一
+type ResponsePercentiles struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Samples int64     `json:"samples"`
+	P50     *int64    `json:"p50"`
+	P95     *int64    `json:"p95"`
+	P99     *int64    `json:"p99"`
+}
+
+// StatusSSL 最近一次检查得到的证书信息
+type StatusSSL struct {
+	DaysUntilExpiry *int   `json:"days_until_expiry,omitempty"`
+	Issuer          string `json:"issuer,omitempty"`
+	Subject         string `json:"subject,omitempty"`
+	Serial          string `json:"serial,omitempty"`
+}
+
+// ListStatusResponse is returned by /monitor/status/list
+type ListStatusResponse struct {
+	Statuses []StatusResponse `json:"statuses"`
+}
+
+// LogSearchRequest filters /logs/search
+type LogSearchRequest struct {
+	TargetID  *uint32 `json:"target_id,omitempty"`
+	Status    string  `json:"status,omitempty"`
+	StartTime *int64  `json:"start_time,omitempty"` // Unix timestamp
+	EndTime   *int64  `json:"end_time,omitempty"`   // Unix timestamp
+	Size      int     `json:"size,omitempty"`
+	From      int     `json:"from,omitempty"`
+	After     string  `json:"after,omitempty"` // next_after of the previous page; stable while new logs arrive, cannot be combined with from
+	QueryText string  `json:"query_text,omitempty"`
+	Synthetic *bool   `json:"synthetic,omitempty"` // Only synthetic (true) or only real (false) results
+}
+
+// LogSearchResponse is returned by /logs/search from Elasticsearch or the file logs
+type LogSearchResponse struct {
+	Total     int64            `json:"total"`
+	Hits      []LogHitResponse `json:"hits"`
+	NextAfter string           `json:"next_after,omitempty"` // Pass as after to get the next page; empty on the last page
+}
+
+// LogHitResponse v2 的日志条目，ES 和文件日志返回同样的结构
+type LogHitResponse struct {
+	TargetID     uint32      `json:"target_id"`
+	TargetName   string      `json:"target_name"`
+	TargetType   string      `json:"target_type"`
+	Address      string      `json:"address"`
+	Status       string      `json:"status"`
+	ResponseTime int64       `json:"response_time"`
+	Message      string      `json:"message"`
+	Synthetic    bool        `json:"synthetic"`
+	ClockSkewMs  *int64      `json:"clock_skew_ms,omitempty"`
+	Timings      interface{} `json:"timings,omitempty"` // HTTP/HTTPS phases in milliseconds, see data.timings
+	CheckedAt    time.Time   `json:"checked_at"`
+	Seq          int64       `json:"seq,omitempty"` // Orders logs with the same checked_at; absent in old logs
+	Request      interface{} `json:"request,omitempty"`
+	Response     interface{} `json:"response,omitempty"`
+	Error        interface{} `json:"error,omitempty"`
+}
+
+// DNSProviderResponse v2 的 DNS 服务器
+type DNSProviderResponse struct {
+	ID         uint      `json:"id"`
+	Name       string    `json:"name"`
+	Server     string    `json:"server"`
+	ServerType string    `json:"server_type"`
+	IsDefault  bool      `json:"is_default"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// AlertChannelRequest creates an alert channel; config is a JSON string whose
+// fields depend on the type
+type AlertChannelRequest struct {
+	Name    string `json:"name" binding:"required"`
+	Type    string `json:"type" binding:"required"`
+	Enabled bool   `json:"enabled"`
+	Config  string `json:"config" binding:"required"`
+	// SuppressHealth keeps the channel from being marked degraded, for
+	// receivers that are intermittent on purpose
+	SuppressHealth bool `json:"suppress_health"`
+}
+
+// UpdateAlertChannelRequest replaces every field of an alert channel
+type UpdateAlertChannelRequest struct {
+	IDRequest
+	AlertChannelRequest
+}
+
+// AlertChannelResponse v2 的告警渠道，config 与添加请求一样是 JSON 字符串
+type AlertChannelResponse struct {
+	ID        uint32    `json:"id"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Enabled   bool      `json:"enabled"`
+	Config    string    `json:"config"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Health          string               `json:"health"` // healthy, degraded
+	HealthReason    string               `json:"health_reason,omitempty"`
+	HealthChangedAt *time.Time           `json:"health_changed_at,omitempty"`
+	SuppressHealth  bool                 `json:"suppress_health"`
+	Delivery        AlertChannelDelivery `json:"delivery"`
+}
+
+// AlertChannelDelivery 渠道在健康检测窗口内的发送统计
+type AlertChannelDelivery struct {
+	Attempts    int64   `json:"attempts"`
+	Failures    int64   `json:"failures"`
+	SuccessRate float64 `json:"success_rate"` // 百分比，没有发送时为 100
+}
+
+// ListAlertChannelsResponse is returned by /alert/channel/list
+type ListAlertChannelsResponse struct {
+	Channels []AlertChannelResponse `json:"channels"`
+}
+
+// AlertRuleResponse v2 的告警规则；不返回未加载的 conditions/groups 关联
+type AlertRuleResponse struct {
+	ID              uint             `json:"id"`
+	TargetID        uint32           `json:"target_id"`
+	ChannelID       uint             `json:"channel_id"`
+	ThresholdType   string           `json:"threshold_type"`
+	ThresholdValue  int              `json:"threshold_value"`
+	Enabled         bool             `json:"enabled"`
+	ConditionLogic  string           `json:"condition_logic,omitempty"`
+	CooldownSeconds int              `json:"cooldown_seconds"`
+	AlertOpen       bool             `json:"alert_open"`
+	AlertCount      int              `json:"alert_count"`
+	LastAlertTime   *time.Time       `json:"last_alert_time,omitempty"`
+	Snooze          *AlertRuleSnooze `json:"snooze,omitempty"` // 静默中时返回
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+}
+
+// SnoozeAlertRuleRequest silences one rule for a while; the other rules of the
+// target keep alerting. Snoozing a snoozed rule replaces its snooze.
+type SnoozeAlertRuleRequest struct {
+	RuleID   uint   `json:"rule_id" binding:"required"`
+	Duration string `json:"duration" binding:"required"` // 如 2h，最长 168h
+	Note     string `json:"note,omitempty"`
+}
+
+// CancelRuleSnoozeRequest ends the snooze of a rule before it expires
+type CancelRuleSnoozeRequest struct {
+	RuleID uint `json:"rule_id" binding:"required"`
+}
+
+// AlertRuleSnooze a snooze of an alert rule, returned by /alert/rule/snooze
+// and /alert/rule/snooze/cancel
+type AlertRuleSnooze struct {
+	ID          uint       `json:"id"`
+	RuleID      uint       `json:"rule_id"`
+	TargetID    uint32     `json:"target_id"`
+	Note        string     `json:"note,omitempty"`
+	StartsAt    time.Time  `json:"starts_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+	CreatedBy   string     `json:"created_by,omitempty"`
+}
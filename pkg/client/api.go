@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"monitor/pkg/apitypes"
+)
+
+// CreateMonitor adds a monitor and returns its ID. Warnings (e.g. a Unix
+// socket path that does not exist yet) do not fail the request.
+func (c *Client) CreateMonitor(ctx context.Context, req apitypes.AddMonitorRequest) (*apitypes.CreatedResponse, error) {
+	var resp apitypes.CreatedResponse
+	if err := c.do(ctx, http.MethodPost, "/monitor/add", notIdempotent, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListMonitors lists monitors, optionally filtered by type, enabled state and tags
+func (c *Client) ListMonitors(ctx context.Context, opts apitypes.ListMonitorsRequest) ([]apitypes.MonitorResponse, error) {
+	var resp apitypes.ListMonitorsResponse
+	if err := c.do(ctx, http.MethodPost, "/monitor/list", idempotent, opts, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Targets, nil
+}
+
+// ListTags returns every tag in use and how many monitors have it
+func (c *Client) ListTags(ctx context.Context) ([]apitypes.TagCount, error) {
+	var resp apitypes.ListTagsResponse
+	if err := c.do(ctx, http.MethodPost, "/tag/list", idempotent, struct{}{}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Tags, nil
+}
+
+// UpdateMonitor replaces every field of a monitor
+func (c *Client) UpdateMonitor(ctx context.Context, req apitypes.UpdateMonitorRequest) error {
+	return c.do(ctx, http.MethodPost, "/monitor/update", idempotent, req, nil)
+}
+
+// DeleteMonitor removes a monitor
+func (c *Client) DeleteMonitor(ctx context.Context, id uint32) error {
+	return c.do(ctx, http.MethodPost, "/monitor/remove", idempotent, apitypes.IDRequest{ID: id}, nil)
+}
+
+// GetStatus returns the latest status of a monitor
+func (c *Client) GetStatus(ctx context.Context, targetID uint32) (*apitypes.StatusResponse, error) {
+	var resp apitypes.StatusResponse
+	if err := c.do(ctx, http.MethodPost, "/monitor/status/get", idempotent, apitypes.IDRequest{ID: targetID}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListStatus returns the latest status of every monitor, or the recent
+// statuses of one monitor when opts.TargetID is set
+func (c *Client) ListStatus(ctx context.Context, opts apitypes.ListStatusRequest) ([]apitypes.StatusResponse, error) {
+	var resp apitypes.ListStatusResponse
+	if err := c.do(ctx, http.MethodPost, "/monitor/status/list", idempotent, opts, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Statuses, nil
+}
+
+// SearchLogs searches check results in Elasticsearch, or in the file logs
+// when Elasticsearch is disabled
+func (c *Client) SearchLogs(ctx context.Context, req apitypes.LogSearchRequest) (*apitypes.LogSearchResponse, error) {
+	var resp apitypes.LogSearchResponse
+	if err := c.do(ctx, http.MethodPost, "/logs/search", idempotent, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// TriggerCheck queues an immediate check of a monitor. A check already
+// queued or running for the monitor is returned instead of a new one.
+func (c *Client) TriggerCheck(ctx context.Context, targetID uint32) (*apitypes.TriggerCheckResponse, error) {
+	var resp apitypes.TriggerCheckResponse
+	if err := c.do(ctx, http.MethodPost, "/monitor/check", notIdempotent, apitypes.IDRequest{ID: targetID}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateAlertChannel adds an alert channel and returns its ID
+func (c *Client) CreateAlertChannel(ctx context.Context, req apitypes.AlertChannelRequest) (*apitypes.CreatedResponse, error) {
+	var resp apitypes.CreatedResponse
+	if err := c.do(ctx, http.MethodPost, "/alert/channel/add", notIdempotent, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListAlertChannels lists all alert channels
+func (c *Client) ListAlertChannels(ctx context.Context) ([]apitypes.AlertChannelResponse, error) {
+	var resp apitypes.ListAlertChannelsResponse
+	if err := c.do(ctx, http.MethodPost, "/alert/channel/list", idempotent, struct{}{}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Channels, nil
+}
+
+// GetAlertChannel returns one alert channel
+func (c *Client) GetAlertChannel(ctx context.Context, id uint32) (*apitypes.AlertChannelResponse, error) {
+	var resp apitypes.AlertChannelResponse
+	if err := c.do(ctx, http.MethodPost, "/alert/channel/get", idempotent, apitypes.IDRequest{ID: id}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UpdateAlertChannel replaces every field of an alert channel
+func (c *Client) UpdateAlertChannel(ctx context.Context, req apitypes.UpdateAlertChannelRequest) error {
+	return c.do(ctx, http.MethodPost, "/alert/channel/update", idempotent, req, nil)
+}
+
+// DeleteAlertChannel removes an alert channel
+func (c *Client) DeleteAlertChannel(ctx context.Context, id uint32) error {
+	return c.do(ctx, http.MethodPost, "/alert/channel/remove", idempotent, apitypes.IDRequest{ID: id}, nil)
+}
+
+// TestAlertChannel sends a test notification through an alert channel
+func (c *Client) TestAlertChannel(ctx context.Context, id uint32) error {
+	return c.do(ctx, http.MethodPost, "/alert/channel/test", notIdempotent, apitypes.IDRequest{ID: id}, nil)
+}
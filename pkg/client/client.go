@@ -0,0 +1,198 @@
+// Package client is a Go client for the monitor HTTP API. It talks to the
+// /api/v2 endpoints and uses the request and response types of pkg/apitypes,
+// which the server binds and returns as well.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"monitor/pkg/apitypes"
+)
+
+const (
+	// DefaultMaxRetries is how many times a failed request is retried
+	DefaultMaxRetries = 3
+	// DefaultRetryWait is the wait before the first retry; it doubles for each further retry
+	DefaultRetryWait = 200 * time.Millisecond
+	// maxRetryWait caps the wait between retries, including Retry-After
+	maxRetryWait = 10 * time.Second
+)
+
+// Client calls the API of one monitor server. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+	maxRetries int
+	retryWait  time.Duration
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient replaces the default http.Client (30s timeout)
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithRetries sets how many times a failed request is retried and the wait
+// before the first retry. Zero retries disables retrying.
+func WithRetries(maxRetries int, wait time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.retryWait = wait
+	}
+}
+
+// NewClient returns a client for the server at baseURL, e.g.
+// "http://localhost:8080". apiKey is sent as a bearer token when not empty;
+// the admin endpoints require it to be debug.admin_token.
+func NewClient(baseURL, apiKey string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		maxRetries: DefaultMaxRetries,
+		retryWait:  DefaultRetryWait,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is returned for responses with a 4xx or 5xx status
+type APIError struct {
+	StatusCode int
+	Message    string // the "error" field of the response, or the body if it has none
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("monitor API: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsNotFound reports whether err is an APIError with status 404
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// retryPolicy decides which failures are retried. 429 and 503 mean the
+// request was not processed and are always retried; other 5xx statuses and
+// network errors only for requests that can safely run twice.
+type retryPolicy int
+
+const (
+	idempotent retryPolicy = iota
+	notIdempotent
+)
+
+// retry reports whether a failed attempt should be retried; status is 0 when
+// no response was received
+func (p retryPolicy) retry(status int) bool {
+	switch {
+	case status == 0:
+		return p == idempotent
+	case status == http.StatusTooManyRequests, status == http.StatusServiceUnavailable:
+		return true
+	case status >= 500:
+		return p == idempotent
+	}
+	return false
+}
+
+// do sends body as JSON to /api/v2 + path and decodes the response into out (if not nil)
+func (c *Client) do(ctx context.Context, method, path string, policy retryPolicy, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("monitor API: encode request: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		status, retryAfter, err := c.send(ctx, method, path, payload, out)
+		if err == nil {
+			return nil
+		}
+		if attempt >= c.maxRetries || ctx.Err() != nil || !policy.retry(status) {
+			return err
+		}
+
+		wait := c.retryWait << attempt
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		if wait > maxRetryWait {
+			wait = maxRetryWait
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// send makes one attempt and returns the status (0 if there was no response)
+// and the Retry-After of error responses
+func (c *Client) send(ctx context.Context, method, path string, payload []byte, out interface{}) (int, time.Duration, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/api/v2"+path, reader)
+	if err != nil {
+		return 0, 0, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if resp.StatusCode >= 400 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+		var errResp apitypes.ErrorResponse
+		if json.Unmarshal(data, &errResp) == nil && errResp.Error != "" {
+			apiErr.Message = errResp.Error
+		}
+		var retryAfter time.Duration
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			retryAfter = time.Duration(secs) * time.Second
+		}
+		return resp.StatusCode, retryAfter, apiErr
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp.StatusCode, 0, fmt.Errorf("monitor API: decode %s response: %w", path, err)
+		}
+	}
+	return resp.StatusCode, 0, nil
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"monitor/pkg/apitypes"
+)
+
+// flakyServer answers with statuses in turn, then 200 with body
+func flakyServer(t *testing.T, body string, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1)) - 1
+		if n < len(statuses) {
+			w.WriteHeader(statuses[n])
+			w.Write([]byte(`{"error":"try again"}`))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &calls
+}
+
+func TestRetriesIdempotentRequests(t *testing.T) {
+	ts, calls := flakyServer(t, `{"tags":[{"tag":"prod","count":2}]}`, http.StatusBadGateway, http.StatusServiceUnavailable)
+	c := NewClient(ts.URL, "", WithRetries(3, time.Millisecond))
+
+	tags, err := c.ListTags(context.Background())
+	if err != nil {
+		t.Fatalf("ListTags: %v", err)
+	}
+	if calls.Load() != 3 || len(tags) != 1 || tags[0] != (apitypes.TagCount{Tag: "prod", Count: 2}) {
+		t.Errorf("ListTags = %+v after %d calls, want the tags after 3", tags, calls.Load())
+	}
+}
+
+func TestDoesNotRetryNonIdempotentRequests(t *testing.T) {
+	ts, calls := flakyServer(t, `{"id":1}`, http.StatusBadGateway)
+	c := NewClient(ts.URL, "", WithRetries(3, time.Millisecond))
+
+	_, err := c.CreateMonitor(context.Background(), apitypes.AddMonitorRequest{Name: "db", Type: "tcp", Address: "127.0.0.1", Port: 1})
+	apiErr, ok := err.(*APIError)
+	if !ok || apiErr.StatusCode != http.StatusBadGateway || apiErr.Message != "try again" {
+		t.Errorf("CreateMonitor: %v, want the 502 APIError", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("create sent %d times, want once", calls.Load())
+	}
+
+	// 429 means the request was not processed, so even a create is retried
+	ts, calls = flakyServer(t, `{"id":1}`, http.StatusTooManyRequests)
+	c = NewClient(ts.URL, "", WithRetries(3, time.Millisecond))
+	created, err := c.CreateMonitor(context.Background(), apitypes.AddMonitorRequest{Name: "db", Type: "tcp", Address: "127.0.0.1", Port: 1})
+	if err != nil || created.ID != 1 || calls.Load() != 2 {
+		t.Errorf("CreateMonitor after a 429 = %+v, %v after %d calls", created, err, calls.Load())
+	}
+}
+
+func TestClientErrorsAreNotRetried(t *testing.T) {
+	ts, calls := flakyServer(t, `{}`, http.StatusNotFound)
+	c := NewClient(ts.URL, "", WithRetries(3, time.Millisecond))
+
+	if _, err := c.GetStatus(context.Background(), 1); !IsNotFound(err) {
+		t.Errorf("GetStatus: %v, want not found", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("404 sent %d times, want once", calls.Load())
+	}
+}
+
+func TestRetryAfterIsHonoured(t *testing.T) {
+	var calls atomic.Int32
+	var first time.Time
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if waited := time.Since(first); waited < time.Second {
+			t.Errorf("retried after %s, want at least the Retry-After of 1s", waited)
+		}
+		w.Write([]byte(`{"tags":[]}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, "", WithRetries(1, time.Millisecond))
+	if _, err := c.ListTags(context.Background()); err != nil {
+		t.Fatalf("ListTags: %v", err)
+	}
+}
+
+func TestSendsAPIKeyToV2(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/tag/list" || r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("request %s with Authorization %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"tags":[]}`))
+	}))
+	defer ts.Close()
+
+	if _, err := NewClient(ts.URL+"/", "key").ListTags(context.Background()); err != nil {
+		t.Fatalf("ListTags: %v", err)
+	}
+}
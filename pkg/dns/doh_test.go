@@ -0,0 +1,123 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startDoHServer serves handler until the end of the test and returns its URL
+func startDoHServer(t *testing.T, handler http.HandlerFunc) string {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server.URL + "/resolve"
+}
+
+func answerJSON(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/dns-json")
+	w.Write([]byte(`{"Status":0,"AA":false,"Answer":[` +
+		`{"name":"example.test.","type":1,"TTL":300,"data":"192.0.2.30"},` +
+		`{"name":"example.test.","type":16,"TTL":60,"data":"\"v=spf1 \" \"-all\""}]}`))
+}
+
+func newDoHResolver(server string) *Resolver {
+	r := NewResolver(server, DNSTypeDoH)
+	r.Timeout = 5 * time.Second
+	return r
+}
+
+func TestDoHLookup(t *testing.T) {
+	var query, accept string
+	server := startDoHServer(t, func(w http.ResponseWriter, r *http.Request) {
+		query, accept = r.URL.RawQuery, r.Header.Get("Accept")
+		answerJSON(w, r)
+	})
+
+	result, err := newDoHResolver(server).Lookup(context.Background(), "example.test")
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if query != "name=example.test&type=A" || accept != "application/dns-json" {
+		t.Errorf("request query %q accept %q", query, accept)
+	}
+	if !slices.Equal(result.A, []string{"192.0.2.30"}) || !slices.Equal(result.Records("TXT"), []string{"v=spf1 -all"}) {
+		t.Errorf("A %v TXT %v", result.A, result.Records("TXT"))
+	}
+	if result.Protocol != DNSTypeDoH || result.DoHMode != DoHModeJSON || result.Server != server || result.Fallback {
+		t.Errorf("answered by %s %s mode %q fallback %v", result.Protocol, result.Server, result.DoHMode, result.Fallback)
+	}
+}
+
+// A non-2xx response is a failure carrying the status, URL and a truncated
+// body, not an empty answer
+func TestDoHLookupHTTPError(t *testing.T) {
+	server := startDoHServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(strings.Repeat("x", 600)))
+	})
+
+	_, err := newDoHResolver(server).Lookup(context.Background(), "example.test")
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) {
+		t.Fatalf("err = %v, want a *QueryError", err)
+	}
+	if queryErr.StatusCode != http.StatusServiceUnavailable || queryErr.Mode != DoHModeJSON || queryErr.URL != server+"?name=example.test&type=A" {
+		t.Errorf("status %d mode %q url %q", queryErr.StatusCode, queryErr.Mode, queryErr.URL)
+	}
+	if len(queryErr.Body) != maxErrorBodyBytes+len("...(truncated)") || !strings.HasSuffix(queryErr.Body, "...(truncated)") {
+		t.Errorf("body of %d bytes, want it truncated to %d", len(queryErr.Body), maxErrorBodyBytes)
+	}
+	if !strings.Contains(err.Error(), "doh query to "+server+" failed with HTTP 503: Service Unavailable") {
+		t.Errorf("message %q", err.Error())
+	}
+}
+
+func TestDoHLookupInvalidJSON(t *testing.T) {
+	server := startDoHServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>captive portal</html>"))
+	})
+
+	_, err := newDoHResolver(server).Lookup(context.Background(), "example.test")
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) || queryErr.StatusCode != http.StatusOK || queryErr.Body != "<html>captive portal</html>" {
+		t.Fatalf("err = %v, want a *QueryError with the body", err)
+	}
+}
+
+// Servers are tried in order; the answer records the fallback and the
+// earlier failure, and when all fail every attempt is listed
+func TestDoHLookupFallback(t *testing.T) {
+	bad := startDoHServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	good := startDoHServer(t, answerJSON)
+
+	result, err := newDoHResolver(bad+", "+good).Lookup(context.Background(), "example.test")
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if result.Server != good || !result.Fallback || len(result.Failures) != 1 || result.Failures[0].StatusCode != http.StatusInternalServerError {
+		t.Fatalf("answered by %s fallback %v failures %v", result.Server, result.Fallback, result.Failures)
+	}
+
+	_, err = newDoHResolver(bad+","+bad).Lookup(context.Background(), "example.test")
+	var lookupErr *LookupError
+	if !errors.As(err, &lookupErr) || len(lookupErr.Attempts) != 2 {
+		t.Fatalf("err = %v, want a *LookupError with two attempts", err)
+	}
+	if !strings.HasPrefix(err.Error(), "all 2 DNS servers failed for example.test: ") {
+		t.Errorf("message %q", err.Error())
+	}
+}
+
+func TestSplitServers(t *testing.T) {
+	if got := SplitServers(" 1.1.1.1 ,, 8.8.8.8:53,"); !slices.Equal(got, []string{"1.1.1.1", "8.8.8.8:53"}) {
+		t.Errorf("SplitServers = %q", got)
+	}
+}
@@ -0,0 +1,92 @@
+package dns
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxErrorBodyBytes limits how much of a failed DoH response body is kept
+const maxErrorBodyBytes = 512
+
+// DoH query modes
+const (
+	DoHModeJSON = "json" // application/dns-json (Google/Cloudflare JSON API)
+)
+
+// QueryError describes a failed query against a single DNS server
+type QueryError struct {
+	Server     string  // Server address as configured
+	Protocol   DNSType // Protocol used for the attempt
+	URL        string  // DoH only: the request URL
+	Mode       string  // DoH only: query mode
+	StatusCode int     // DoH only: HTTP status code, 0 if no response was received
+	Body       string  // DoH only: response body, truncated
+	Err        error
+}
+
+func (e *QueryError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s query to %s failed", e.Protocol, e.Server)
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, " with HTTP %d", e.StatusCode)
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&b, ": %v", e.Err)
+	}
+	if e.Body != "" {
+		fmt.Fprintf(&b, " (body: %s)", e.Body)
+	}
+	return b.String()
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// TLSVerifyError is returned when the certificate of a DoT server fails
+// verification: expired, issued by an unknown authority or for another name
+type TLSVerifyError struct {
+	ServerName string // Name the certificate was verified against
+	Err        error
+}
+
+func (e *TLSVerifyError) Error() string {
+	return fmt.Sprintf("TLS certificate verification for %s failed: %v", e.ServerName, e.Err)
+}
+
+func (e *TLSVerifyError) Unwrap() error {
+	return e.Err
+}
+
+// LookupError is returned when every configured server failed; attempts are in the order tried
+type LookupError struct {
+	Domain   string
+	Attempts []*QueryError
+}
+
+func (e *LookupError) Error() string {
+	if len(e.Attempts) == 1 {
+		return e.Attempts[0].Error()
+	}
+	msgs := make([]string, len(e.Attempts))
+	for i, attempt := range e.Attempts {
+		msgs[i] = attempt.Error()
+	}
+	return fmt.Sprintf("all %d DNS servers failed for %s: %s", len(e.Attempts), e.Domain, strings.Join(msgs, "; "))
+}
+
+func (e *LookupError) Unwrap() []error {
+	errs := make([]error, len(e.Attempts))
+	for i, attempt := range e.Attempts {
+		errs[i] = attempt
+	}
+	return errs
+}
+
+// truncateBody keeps the first maxErrorBodyBytes of a response body
+func truncateBody(body []byte) string {
+	if len(body) <= maxErrorBodyBytes {
+		return string(body)
+	}
+	return string(body[:maxErrorBodyBytes]) + "...(truncated)"
+}
@@ -0,0 +1,174 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"slices"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestParseQueryType(t *testing.T) {
+	for in, want := range map[string]string{"": "A", "mx": "MX", "Srv": "SRV", "CAA": "CAA"} {
+		if got, err := ParseQueryType(in); err != nil || got != want {
+			t.Errorf("ParseQueryType(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseQueryType("ANY"); err == nil {
+		t.Error("ANY accepted")
+	}
+}
+
+func TestRCodeName(t *testing.T) {
+	for rcode, want := range map[int]string{0: "NOERROR", 3: "NXDOMAIN", 5: "REFUSED", 23: "RCODE23"} {
+		if got := RCodeName(rcode); got != want {
+			t.Errorf("RCodeName(%d) = %q, want %q", rcode, got, want)
+		}
+	}
+}
+
+func TestDNSReverseName(t *testing.T) {
+	for addr, want := range map[string]string{
+		"192.0.2.1":   "1.2.0.192.in-addr.arpa.",
+		"2001:db8::1": "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+	} {
+		if got, err := dnsReverseName(addr); err != nil || got != want {
+			t.Errorf("dnsReverseName(%q) = %q, %v, want %q", addr, got, err, want)
+		}
+	}
+	if _, err := dnsReverseName("example.com"); err == nil {
+		t.Error("reverse name of a host name")
+	}
+}
+
+func mustName(s string) dnsmessage.Name {
+	return dnsmessage.MustNewName(s)
+}
+
+// recordAnswers holds one record of every supported type but A
+func recordAnswers(name dnsmessage.Name) []dnsmessage.Resource {
+	header := func(typ dnsmessage.Type, ttl uint32) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Type: typ, Class: dnsmessage.ClassINET, TTL: ttl}
+	}
+	return []dnsmessage.Resource{
+		{Header: header(dnsmessage.TypeAAAA, 60), Body: &dnsmessage.AAAAResource{AAAA: netip.MustParseAddr("2001:db8::1").As16()}},
+		{Header: header(dnsmessage.TypeMX, 300), Body: &dnsmessage.MXResource{Pref: 10, MX: mustName("mail.example.com.")}},
+		{Header: header(dnsmessage.TypeMX, 200), Body: &dnsmessage.MXResource{Pref: 20, MX: mustName("backup.example.com.")}},
+		{Header: header(dnsmessage.TypeTXT, 300), Body: &dnsmessage.TXTResource{TXT: []string{"v=spf1 ", "-all"}}},
+		{Header: header(dnsmessage.TypeSOA, 3600), Body: &dnsmessage.SOAResource{NS: mustName("ns1.example.com."), MBox: mustName("hostmaster.example.com."),
+			Serial: 2024010101, Refresh: 7200, Retry: 900, Expire: 1209600, MinTTL: 300}},
+		{Header: header(typeCAA, 300), Body: &dnsmessage.UnknownResource{Type: typeCAA, Data: append([]byte{0, 5}, "issueletsencrypt.org"...)}},
+		{Header: header(dnsmessage.TypePTR, 300), Body: &dnsmessage.PTRResource{PTR: mustName("host.example.com.")}},
+		{Header: header(dnsmessage.TypeSRV, 300), Body: &dnsmessage.SRVResource{Priority: 10, Weight: 5, Port: 5060, Target: mustName("sip.example.com.")}},
+	}
+}
+
+func TestParseDNSResponse(t *testing.T) {
+	result := (&Resolver{}).parseDNSResponse(dnsmessage.Message{Answers: recordAnswers(mustName("example.com."))})
+	for typ, want := range map[string][]string{
+		"AAAA": {"2001:db8::1"},
+		"MX":   {"10 mail.example.com.", "20 backup.example.com."},
+		"TXT":  {"v=spf1 -all"},
+		"SOA":  {"ns1.example.com. hostmaster.example.com. 2024010101 7200 900 1209600 300"},
+		"CAA":  {`0 issue "letsencrypt.org"`},
+		"PTR":  {"host.example.com."},
+		"SRV":  {"10 5 5060 sip.example.com."},
+	} {
+		if got := result.Records(typ); !slices.Equal(got, want) {
+			t.Errorf("%s = %q, want %q", typ, got, want)
+		}
+	}
+	if ttl, ok := result.MinTTL("mx"); !ok || ttl != 200 {
+		t.Errorf("MinTTL(MX) = %d, %v, want 200", ttl, ok)
+	}
+	if _, ok := result.MinTTL("A"); ok {
+		t.Error("MinTTL of a type without records")
+	}
+}
+
+func TestParseDoHResponse(t *testing.T) {
+	result, err := (&Resolver{}).parseDoHResponse([]byte(`{"Status": 0, "Answer": [
+		{"type": 16, "TTL": 60, "data": "\"v=spf1 \" \"-all\""},
+		{"type": 257, "TTL": 60, "data": "\\# 22 00 05 69 73 73 75 65 6c 65 74 73 65 6e 63 72 79 70 74 2e 6f 72 67"},
+		{"type": 257, "TTL": 60, "data": "0 iodef \"mailto:ops@example.com\""},
+		{"type": 15, "TTL": 60, "data": "10 mail.example.com."}]}`))
+	if err != nil {
+		t.Fatalf("parseDoHResponse: %v", err)
+	}
+	if !slices.Equal(result.TXT, []string{"v=spf1 -all"}) || !slices.Equal(result.MX, []string{"10 mail.example.com."}) {
+		t.Errorf("TXT %q MX %q", result.TXT, result.MX)
+	}
+	if want := []string{`0 issue "letsencrypt.org"`, `0 iodef "mailto:ops@example.com"`}; !slices.Equal(result.CAA, want) {
+		t.Errorf("CAA %q, want %q", result.CAA, want)
+	}
+	if result.RCode != "NOERROR" || result.Authoritative {
+		t.Errorf("rcode %q authoritative %v", result.RCode, result.Authoritative)
+	}
+
+	// The JSON Status is the response code, AA the authoritative flag
+	result, err = (&Resolver{}).parseDoHResponse([]byte(`{"Status": 3, "AA": true}`))
+	if err != nil || result.RCode != "NXDOMAIN" || !result.Authoritative || len(result.A) != 0 {
+		t.Errorf("NXDOMAIN answer %+v, %v", result, err)
+	}
+}
+
+// The query asks for the resolver's type, and a PTR query for an address
+// asks for its reverse name
+func TestLookupQueryType(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	questions := make(chan dnsmessage.Question, 10)
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) == 0 {
+				continue
+			}
+			q := query.Questions[0]
+			questions <- q
+			resp := dnsmessage.Message{Header: dnsmessage.Header{ID: query.ID, Response: true}, Questions: query.Questions}
+			for _, answer := range recordAnswers(q.Name) {
+				if answer.Header.Type == q.Type {
+					resp.Answers = append(resp.Answers, answer)
+				}
+			}
+			packed, _ := resp.Pack()
+			conn.WriteTo(packed, addr)
+		}
+	}()
+
+	for _, tc := range []struct {
+		queryType, domain, name string
+		want                    string
+	}{
+		{"MX", "example.com", "example.com.", "10 mail.example.com."},
+		{"SRV", "_sip._udp.example.com.", "_sip._udp.example.com.", "10 5 5060 sip.example.com."},
+		{"PTR", "192.0.2.1", "1.2.0.192.in-addr.arpa.", "host.example.com."},
+	} {
+		r := NewResolver(conn.LocalAddr().String(), DNSTypeUDP)
+		r.Timeout = 2 * time.Second
+		r.QueryType = tc.queryType
+		result, err := r.Lookup(context.Background(), tc.domain)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.queryType, err)
+		}
+		q := <-questions
+		if q.Name.String() != tc.name || q.Type.String() != "Type"+tc.queryType {
+			t.Errorf("%s: asked %s %s", tc.queryType, q.Name, q.Type)
+		}
+		if records := result.Records(tc.queryType); len(records) == 0 || records[0] != tc.want {
+			t.Errorf("%s: records %q, want %q first", tc.queryType, records, tc.want)
+		}
+	}
+}
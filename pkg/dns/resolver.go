@@ -2,11 +2,16 @@ package dns
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -23,7 +28,43 @@ const (
 	DNSTypeDoT DNSType = "dot" // DNS over TLS
 )
 
-// DNSQueryResult represents DNS query results
+// Query types a Resolver can ask for, see Resolver.QueryType
+var QueryTypes = []string{"A", "AAAA", "CNAME", "MX", "TXT", "NS", "SOA", "CAA", "PTR", "SRV"}
+
+// typeCAA is not defined by dnsmessage; CAA records arrive as UnknownResource
+const typeCAA dnsmessage.Type = 257
+
+var queryTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"MX":    dnsmessage.TypeMX,
+	"TXT":   dnsmessage.TypeTXT,
+	"NS":    dnsmessage.TypeNS,
+	"SOA":   dnsmessage.TypeSOA,
+	"CAA":   typeCAA,
+	"PTR":   dnsmessage.TypePTR,
+	"SRV":   dnsmessage.TypeSRV,
+}
+
+// ParseQueryType checks a query type name, case-insensitively, and returns
+// it in upper case; empty means A
+func ParseQueryType(s string) (string, error) {
+	if s == "" {
+		return "A", nil
+	}
+	s = strings.ToUpper(s)
+	if _, ok := queryTypes[s]; !ok {
+		return "", fmt.Errorf("unsupported DNS query type %q, use one of %s", s, strings.Join(QueryTypes, ", "))
+	}
+	return s, nil
+}
+
+// DNSQueryResult represents DNS query results. Values are in zone file
+// presentation: MX "10 mail.example.com.", SRV "10 5 5060 sip.example.com.",
+// SOA "ns1.example.com. hostmaster.example.com. serial refresh retry expire
+// minimum", CAA `0 issue "letsencrypt.org"`; the strings of one TXT record
+// are joined.
 type DNSQueryResult struct {
 	A     []string `json:"a"`
 	AAAA  []string `json:"aaaa"`
@@ -31,63 +72,240 @@ type DNSQueryResult struct {
 	MX    []string `json:"mx"`
 	TXT   []string `json:"txt"`
 	NS    []string `json:"ns"`
+	SOA   []string `json:"soa,omitempty"`
+	CAA   []string `json:"caa,omitempty"`
+	PTR   []string `json:"ptr,omitempty"`
+	SRV   []string `json:"srv,omitempty"`
+	// TTLs holds the TTL in seconds of each value, by record type and in the
+	// order of the values, e.g. TTLs["A"][0] is the TTL of A[0]
+	TTLs map[string][]uint32 `json:"ttls,omitempty"`
+
+	// RCode is the response code, e.g. NOERROR, NXDOMAIN or SERVFAIL; empty
+	// for the system resolver, which does not report it
+	RCode string `json:"rcode,omitempty"`
+	// Authoritative is the AA flag: the server is authoritative for the name
+	Authoritative bool `json:"authoritative"`
+
+	// Which server answered
+	Server   string  `json:"server"`
+	Protocol DNSType `json:"protocol"`
+	DoHMode  string  `json:"doh_mode,omitempty"` // DoH only: query mode that produced the answer
+	Fallback bool    `json:"fallback"`           // answered by a server other than the first one
+	// Failures of the servers tried before the one that answered
+	Failures []*QueryError `json:"-"`
+}
+
+// Response codes (RFC 1035, RFC 6895) by number
+var rcodeNames = map[int]string{
+	0:  "NOERROR",
+	1:  "FORMERR",
+	2:  "SERVFAIL",
+	3:  "NXDOMAIN",
+	4:  "NOTIMP",
+	5:  "REFUSED",
+	6:  "YXDOMAIN",
+	7:  "YXRRSET",
+	8:  "NXRRSET",
+	9:  "NOTAUTH",
+	10: "NOTZONE",
+}
+
+// RCodeName returns the mnemonic of a response code, RCODE<n> when unknown
+func RCodeName(rcode int) string {
+	if name, ok := rcodeNames[rcode]; ok {
+		return name
+	}
+	return fmt.Sprintf("RCODE%d", rcode)
+}
+
+// add appends a value of a record type with its TTL
+func (r *DNSQueryResult) add(queryType, value string, ttl uint32) {
+	switch queryType {
+	case "A":
+		r.A = append(r.A, value)
+	case "AAAA":
+		r.AAAA = append(r.AAAA, value)
+	case "CNAME":
+		r.CNAME = append(r.CNAME, value)
+	case "MX":
+		r.MX = append(r.MX, value)
+	case "TXT":
+		r.TXT = append(r.TXT, value)
+	case "NS":
+		r.NS = append(r.NS, value)
+	case "SOA":
+		r.SOA = append(r.SOA, value)
+	case "CAA":
+		r.CAA = append(r.CAA, value)
+	case "PTR":
+		r.PTR = append(r.PTR, value)
+	case "SRV":
+		r.SRV = append(r.SRV, value)
+	default:
+		return
+	}
+	if r.TTLs == nil {
+		r.TTLs = make(map[string][]uint32)
+	}
+	r.TTLs[queryType] = append(r.TTLs[queryType], ttl)
+}
+
+// MinTTL returns the lowest TTL of the values of a record type; ok is false
+// when there are none
+func (r *DNSQueryResult) MinTTL(queryType string) (ttl uint32, ok bool) {
+	for i, t := range r.TTLs[strings.ToUpper(queryType)] {
+		if i == 0 || t < ttl {
+			ttl = t
+		}
+		ok = true
+	}
+	return ttl, ok
+}
+
+// Records returns the values of one record type, e.g. "MX"
+func (r *DNSQueryResult) Records(queryType string) []string {
+	switch strings.ToUpper(queryType) {
+	case "", "A":
+		return r.A
+	case "AAAA":
+		return r.AAAA
+	case "CNAME":
+		return r.CNAME
+	case "MX":
+		return r.MX
+	case "TXT":
+		return r.TXT
+	case "NS":
+		return r.NS
+	case "SOA":
+		return r.SOA
+	case "CAA":
+		return r.CAA
+	case "PTR":
+		return r.PTR
+	case "SRV":
+		return r.SRV
+	}
+	return nil
 }
 
 // Resolver represents a DNS resolver
 type Resolver struct {
-	Server     string // DNS server address (e.g., 8.8.8.8:53, https://dns.google/resolve)
+	Server     string   // DNS server address (e.g., 8.8.8.8:53, https://dns.google/resolve)
+	Servers    []string // All server addresses, tried in order; Server is the first one
 	ServerType DNSType
 	Timeout    time.Duration
+	// QueryType is the type asked for, one of QueryTypes; empty means A.
+	// A PTR query for an IP address asks for its in-addr.arpa or ip6.arpa name.
+	QueryType string
+	// TLSConfig holds the DoT settings, e.g. RootCAs, InsecureSkipVerify or a
+	// client certificate; nil verifies against the system roots. ServerName
+	// defaults to the host of the server address.
+	TLSConfig *tls.Config
 }
 
-// NewResolver creates a new DNS resolver
+// NewResolver creates a new DNS resolver.
+// server may list several comma-separated addresses, which are tried in order.
 func NewResolver(server string, dnsType DNSType) *Resolver {
 	if dnsType == "" {
 		dnsType = DNSTypeUDP
 	}
 
+	servers := SplitServers(server)
+	first := server
+	if len(servers) > 0 {
+		first = servers[0]
+	}
+
 	return &Resolver{
-		Server:     server,
+		Server:     first,
+		Servers:    servers,
 		ServerType: dnsType,
 		Timeout:    10 * time.Second,
 	}
 }
 
-// Lookup performs DNS lookup based on the resolver type
+// SplitServers splits a comma-separated server list, dropping empty entries
+func SplitServers(server string) []string {
+	var servers []string
+	for _, s := range strings.Split(server, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			servers = append(servers, s)
+		}
+	}
+	return servers
+}
+
+// Lookup performs DNS lookup based on the resolver type.
+// Servers are tried in order until one answers; if all fail a *LookupError is returned.
 func (r *Resolver) Lookup(ctx context.Context, domain string) (*DNSQueryResult, error) {
+	servers := r.Servers
+	if len(servers) == 0 {
+		servers = []string{r.Server}
+	}
+
+	lookupErr := &LookupError{Domain: domain}
+	for i, server := range servers {
+		if i > 0 && ctx.Err() != nil {
+			break
+		}
+
+		result, err := r.lookupServer(ctx, server, domain)
+		if err != nil {
+			lookupErr.Attempts = append(lookupErr.Attempts, err)
+			continue
+		}
+
+		result.Server = server
+		result.Protocol = r.protocol()
+		result.Fallback = i > 0
+		result.Failures = lookupErr.Attempts
+		return result, nil
+	}
+
+	return nil, lookupErr
+}
+
+// protocol returns the effective protocol, unknown types fall back to UDP
+func (r *Resolver) protocol() DNSType {
 	switch r.ServerType {
-	case DNSTypeUDP:
-		return r.lookupUDP(ctx, domain)
+	case DNSTypeTCP, DNSTypeDoH, DNSTypeDoT:
+		return r.ServerType
+	default:
+		return DNSTypeUDP
+	}
+}
+
+// lookupServer queries a single server and wraps failures in a *QueryError
+func (r *Resolver) lookupServer(ctx context.Context, server, domain string) (*DNSQueryResult, *QueryError) {
+	var result *DNSQueryResult
+	var err error
+	switch r.protocol() {
 	case DNSTypeTCP:
-		return r.lookupTCP(ctx, domain)
+		result, err = r.lookupTCP(ctx, server, domain)
 	case DNSTypeDoH:
-		return r.lookupDoH(ctx, domain)
+		return r.lookupDoH(ctx, server, domain)
 	case DNSTypeDoT:
-		return r.lookupDoT(ctx, domain)
+		result, err = r.lookupDoT(ctx, server, domain)
 	default:
-		return r.lookupUDP(ctx, domain)
+		result, err = r.lookupUDP(ctx, server, domain)
+	}
+	if err != nil {
+		return nil, &QueryError{Server: server, Protocol: r.protocol(), Err: err}
 	}
+	return result, nil
 }
 
 // lookupUDP performs traditional UDP DNS lookup
-func (r *Resolver) lookupUDP(ctx context.Context, domain string) (*DNSQueryResult, error) {
-	// Create DNS message
-	msg := dnsmessage.Message{
-		Header: dnsmessage.Header{
-			RecursionDesired: true,
-		},
-		Questions: []dnsmessage.Question{
-			{
-				Name:  dnsmessage.MustNewName(domain + "."),
-				Type:  dnsmessage.TypeA,
-				Class: dnsmessage.ClassINET,
-			},
-		},
+func (r *Resolver) lookupUDP(ctx context.Context, server, domain string) (*DNSQueryResult, error) {
+	msg, err := r.newQuery(domain)
+	if err != nil {
+		return nil, err
 	}
 
 	// Send query
 	client := &net.Dialer{Timeout: r.Timeout}
-	conn, err := client.DialContext(ctx, "udp", r.Server)
+	conn, err := client.DialContext(ctx, "udp", server)
 	if err != nil {
 		return nil, fmt.Errorf("UDP dial failed: %w", err)
 	}
@@ -126,25 +344,83 @@ func (r *Resolver) lookupUDP(ctx context.Context, domain string) (*DNSQueryResul
 	return r.parseDNSResponse(respMsg), nil
 }
 
-// lookupTCP performs DNS over TCP lookup
-func (r *Resolver) lookupTCP(ctx context.Context, domain string) (*DNSQueryResult, error) {
-	// TCP DNS is similar to UDP but uses TCP for transport
-	// Most modern DNS resolvers support TCP
-	msg := dnsmessage.Message{
+// queryType returns the type asked for, A when QueryType is empty or unknown
+func (r *Resolver) queryType() (string, dnsmessage.Type) {
+	name, err := ParseQueryType(r.QueryType)
+	if err != nil {
+		return "A", dnsmessage.TypeA
+	}
+	return name, queryTypes[name]
+}
+
+// queryName is the name asked for: domain as a fully qualified name, or the
+// reverse lookup name of an IP address for PTR queries
+func (r *Resolver) queryName(domain string) string {
+	if name, _ := r.queryType(); name == "PTR" {
+		if reverse, err := dnsReverseName(domain); err == nil {
+			return reverse
+		}
+	}
+	return strings.TrimSuffix(domain, ".") + "."
+}
+
+// dnsReverseName returns the in-addr.arpa or ip6.arpa name of an IP address
+func dnsReverseName(addr string) (string, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return "", fmt.Errorf("%q is not an IP address", addr)
+	}
+	var b strings.Builder
+	if v4 := ip.To4(); v4 != nil {
+		for i := len(v4) - 1; i >= 0; i-- {
+			fmt.Fprintf(&b, "%d.", v4[i])
+		}
+		b.WriteString("in-addr.arpa.")
+		return b.String(), nil
+	}
+	const hexDigits = "0123456789abcdef"
+	for i := len(ip) - 1; i >= 0; i-- {
+		b.WriteByte(hexDigits[ip[i]&0x0f])
+		b.WriteByte('.')
+		b.WriteByte(hexDigits[ip[i]>>4])
+		b.WriteByte('.')
+	}
+	b.WriteString("ip6.arpa.")
+	return b.String(), nil
+}
+
+// newQuery builds the recursive query for domain with the resolver's query type
+func (r *Resolver) newQuery(domain string) (dnsmessage.Message, error) {
+	name, err := dnsmessage.NewName(r.queryName(domain))
+	if err != nil {
+		return dnsmessage.Message{}, fmt.Errorf("invalid domain %q: %w", domain, err)
+	}
+	_, qtype := r.queryType()
+	return dnsmessage.Message{
 		Header: dnsmessage.Header{
 			RecursionDesired: true,
 		},
 		Questions: []dnsmessage.Question{
 			{
-				Name:  dnsmessage.MustNewName(domain + "."),
-				Type:  dnsmessage.TypeA,
+				Name:  name,
+				Type:  qtype,
 				Class: dnsmessage.ClassINET,
 			},
 		},
+	}, nil
+}
+
+// lookupTCP performs DNS over TCP lookup
+func (r *Resolver) lookupTCP(ctx context.Context, server, domain string) (*DNSQueryResult, error) {
+	// TCP DNS is similar to UDP but uses TCP for transport
+	// Most modern DNS resolvers support TCP
+	msg, err := r.newQuery(domain)
+	if err != nil {
+		return nil, err
 	}
 
 	client := &net.Dialer{Timeout: r.Timeout}
-	conn, err := client.DialContext(ctx, "tcp", r.Server)
+	conn, err := client.DialContext(ctx, "tcp", server)
 	if err != nil {
 		return nil, fmt.Errorf("TCP dial failed: %w", err)
 	}
@@ -189,13 +465,15 @@ func (r *Resolver) lookupTCP(ctx context.Context, domain string) (*DNSQueryResul
 }
 
 // lookupDoH performs DNS over HTTPS lookup (RFC 8484)
-func (r *Resolver) lookupDoH(ctx context.Context, domain string) (*DNSQueryResult, error) {
+func (r *Resolver) lookupDoH(ctx context.Context, server, domain string) (*DNSQueryResult, *QueryError) {
 	// DoH uses GET or POST to an HTTPS endpoint
 	// Google DoH: https://dns.google/resolve
 	// Cloudflare DoH: https://1.1.1.1/dns-query
 
 	// Build URL for GET request
-	url := r.buildDoHURL(domain)
+	queryType, _ := r.queryType()
+	url := buildDoHURL(server, strings.TrimSuffix(r.queryName(domain), "."), queryType)
+	queryErr := &QueryError{Server: server, Protocol: DNSTypeDoH, URL: url, Mode: DoHModeJSON}
 
 	// Create HTTP client with timeout
 	client := &http.Client{
@@ -205,7 +483,8 @@ func (r *Resolver) lookupDoH(ctx context.Context, domain string) (*DNSQueryResul
 	// Create request
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("create request failed: %w", err)
+		queryErr.Err = fmt.Errorf("create request failed: %w", err)
+		return nil, queryErr
 	}
 
 	req.Header.Set("Accept", "application/dns-json")
@@ -213,29 +492,45 @@ func (r *Resolver) lookupDoH(ctx context.Context, domain string) (*DNSQueryResul
 	// Send request
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("DoH request failed: %w", err)
+		queryErr.Err = fmt.Errorf("DoH request failed: %w", err)
+		return nil, queryErr
 	}
 	defer resp.Body.Close()
 
+	queryErr.StatusCode = resp.StatusCode
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("read response failed: %w", err)
+		queryErr.Err = fmt.Errorf("read response failed: %w", err)
+		return nil, queryErr
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		queryErr.Err = fmt.Errorf("%s", http.StatusText(resp.StatusCode))
+		queryErr.Body = truncateBody(body)
+		return nil, queryErr
 	}
 
 	// Parse JSON response
-	return r.parseDoHResponse(body)
+	result, err := r.parseDoHResponse(body)
+	if err != nil {
+		queryErr.Err = err
+		queryErr.Body = truncateBody(body)
+		return nil, queryErr
+	}
+	result.DoHMode = DoHModeJSON
+	return result, nil
 }
 
 // lookupDoT performs DNS over TLS lookup (RFC 7858)
-func (r *Resolver) lookupDoT(ctx context.Context, domain string) (*DNSQueryResult, error) {
+func (r *Resolver) lookupDoT(ctx context.Context, server, domain string) (*DNSQueryResult, error) {
 	// DoT uses TLS on port 853
 	// Similar to TCP DNS but with TLS wrapper
 
 	// Extract host and port from server
-	host, port, err := net.SplitHostPort(r.Server)
+	host, port, err := net.SplitHostPort(server)
 	if err != nil {
 		// Default to port 853 for DoT
-		host = r.Server
+		host = server
 		port = "853"
 	}
 
@@ -248,29 +543,22 @@ func (r *Resolver) lookupDoT(ctx context.Context, domain string) (*DNSQueryResul
 		return nil, fmt.Errorf("TCP dial failed: %w", err)
 	}
 
+	// The handshake and the exchange share one deadline
+	deadline := time.Now().Add(r.Timeout)
+	conn.SetDeadline(deadline)
+
 	// Upgrade to TLS
-	tlsConn, err := tlsClient(conn, host)
+	tlsConn, err := r.tlsClient(ctx, conn, host)
 	if err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("TLS handshake failed: %w", err)
+		return nil, err
 	}
 	defer tlsConn.Close()
 
-	deadline := time.Now().Add(r.Timeout)
-	tlsConn.SetDeadline(deadline)
-
 	// Create and send DNS message (same as TCP)
-	msg := dnsmessage.Message{
-		Header: dnsmessage.Header{
-			RecursionDesired: true,
-		},
-		Questions: []dnsmessage.Question{
-			{
-				Name:  dnsmessage.MustNewName(domain + "."),
-				Type:  dnsmessage.TypeA,
-				Class: dnsmessage.ClassINET,
-			},
-		},
+	msg, err := r.newQuery(domain)
+	if err != nil {
+		return nil, err
 	}
 
 	buf, err := msg.Pack()
@@ -308,20 +596,22 @@ func (r *Resolver) lookupDoT(ctx context.Context, domain string) (*DNSQueryResul
 }
 
 // buildDoHURL constructs a DoH query URL
-func (r *Resolver) buildDoHURL(domain string) string {
-	baseURL := strings.TrimSuffix(r.Server, "/")
+func buildDoHURL(server, domain, queryType string) string {
+	baseURL := strings.TrimSuffix(server, "/")
 
 	// Add query parameters
-	return fmt.Sprintf("%s?name=%s&type=A", baseURL, domain)
+	return fmt.Sprintf("%s?name=%s&type=%s", baseURL, domain, queryType)
 }
 
 // parseDoHResponse parses DoH JSON response
 func (r *Resolver) parseDoHResponse(body []byte) (*DNSQueryResult, error) {
 	var dohResp struct {
-		Status int `json:"Status"`
+		Status int  `json:"Status"`
+		AA     bool `json:"AA"`
 		Answer []struct {
 			Name string `json:"name"`
 			Type int    `json:"type"`
+			TTL  uint32 `json:"TTL"`
 			Data string `json:"data"`
 		} `json:"Answer"`
 	}
@@ -330,16 +620,33 @@ func (r *Resolver) parseDoHResponse(body []byte) (*DNSQueryResult, error) {
 		return nil, fmt.Errorf("parse DoH response failed: %w", err)
 	}
 
-	result := &DNSQueryResult{}
+	result := &DNSQueryResult{
+		RCode:         RCodeName(dohResp.Status),
+		Authoritative: dohResp.AA,
+	}
 
 	for _, ans := range dohResp.Answer {
-		switch ans.Type {
-		case 1: // A
-			result.A = append(result.A, ans.Data)
-		case 28: // AAAA
-			result.AAAA = append(result.AAAA, ans.Data)
-		case 5: // CNAME
-			result.CNAME = append(result.CNAME, ans.Data)
+		switch dnsmessage.Type(ans.Type) {
+		case dnsmessage.TypeA:
+			result.add("A", ans.Data, ans.TTL)
+		case dnsmessage.TypeAAAA:
+			result.add("AAAA", ans.Data, ans.TTL)
+		case dnsmessage.TypeCNAME:
+			result.add("CNAME", ans.Data, ans.TTL)
+		case dnsmessage.TypeMX:
+			result.add("MX", ans.Data, ans.TTL)
+		case dnsmessage.TypeTXT:
+			result.add("TXT", unquoteTXT(ans.Data), ans.TTL)
+		case dnsmessage.TypeNS:
+			result.add("NS", ans.Data, ans.TTL)
+		case dnsmessage.TypeSOA:
+			result.add("SOA", ans.Data, ans.TTL)
+		case dnsmessage.TypePTR:
+			result.add("PTR", ans.Data, ans.TTL)
+		case dnsmessage.TypeSRV:
+			result.add("SRV", ans.Data, ans.TTL)
+		case typeCAA:
+			result.add("CAA", dohCAA(ans.Data), ans.TTL)
 		}
 	}
 
@@ -348,35 +655,39 @@ func (r *Resolver) parseDoHResponse(body []byte) (*DNSQueryResult, error) {
 
 // parseDNSResponse parses DNS message response
 func (r *Resolver) parseDNSResponse(msg dnsmessage.Message) *DNSQueryResult {
-	result := &DNSQueryResult{}
+	result := &DNSQueryResult{
+		RCode:         RCodeName(int(msg.Header.RCode)),
+		Authoritative: msg.Header.Authoritative,
+	}
 
 	for _, ans := range msg.Answers {
-		switch ans.Header.Type {
-		case dnsmessage.TypeA:
-			if a, ok := ans.Body.(*dnsmessage.AResource); ok {
-				result.A = append(result.A, net.IP(a.A[:]).String())
-			}
-		case dnsmessage.TypeAAAA:
-			if aaaa, ok := ans.Body.(*dnsmessage.AAAAResource); ok {
-				result.AAAA = append(result.AAAA, net.IP(aaaa.AAAA[:]).String())
-			}
-		case dnsmessage.TypeCNAME:
-			if cname, ok := ans.Body.(*dnsmessage.CNAMEResource); ok {
-				result.CNAME = append(result.CNAME, cname.CNAME.String())
-			}
-		case dnsmessage.TypeMX:
-			if mx, ok := ans.Body.(*dnsmessage.MXResource); ok {
-				result.MX = append(result.MX, fmt.Sprintf("%d %s", mx.Pref, mx.MX.String()))
-			}
-		case dnsmessage.TypeTXT:
-			if txt, ok := ans.Body.(*dnsmessage.TXTResource); ok {
-				for _, s := range txt.TXT {
-					result.TXT = append(result.TXT, s)
-				}
+		ttl := ans.Header.TTL
+		switch body := ans.Body.(type) {
+		case *dnsmessage.AResource:
+			result.add("A", net.IP(body.A[:]).String(), ttl)
+		case *dnsmessage.AAAAResource:
+			result.add("AAAA", net.IP(body.AAAA[:]).String(), ttl)
+		case *dnsmessage.CNAMEResource:
+			result.add("CNAME", body.CNAME.String(), ttl)
+		case *dnsmessage.MXResource:
+			result.add("MX", fmt.Sprintf("%d %s", body.Pref, body.MX.String()), ttl)
+		case *dnsmessage.TXTResource:
+			result.add("TXT", strings.Join(body.TXT, ""), ttl)
+		case *dnsmessage.NSResource:
+			result.add("NS", body.NS.String(), ttl)
+		case *dnsmessage.SOAResource:
+			result.add("SOA", fmt.Sprintf("%s %s %d %d %d %d %d",
+				body.NS.String(), body.MBox.String(), body.Serial, body.Refresh, body.Retry, body.Expire, body.MinTTL), ttl)
+		case *dnsmessage.PTRResource:
+			result.add("PTR", body.PTR.String(), ttl)
+		case *dnsmessage.SRVResource:
+			result.add("SRV", fmt.Sprintf("%d %d %d %s", body.Priority, body.Weight, body.Port, body.Target.String()), ttl)
+		case *dnsmessage.UnknownResource:
+			if ans.Header.Type != typeCAA {
+				continue
 			}
-		case dnsmessage.TypeNS:
-			if ns, ok := ans.Body.(*dnsmessage.NSResource); ok {
-				result.NS = append(result.NS, ns.NS.String())
+			if value, ok := formatCAA(body.Data); ok {
+				result.add("CAA", value, ttl)
 			}
 		}
 	}
@@ -384,9 +695,89 @@ func (r *Resolver) parseDNSResponse(msg dnsmessage.Message) *DNSQueryResult {
 	return result
 }
 
-// tlsClient creates a TLS connection (simplified, without full cert validation for DoT)
-func tlsClient(conn net.Conn, server string) (net.Conn, error) {
-	// For production use, proper TLS configuration should be implemented
-	// This is a simplified version
-	return conn, nil
-}
\ No newline at end of file
+// formatCAA converts the RDATA of a CAA record (RFC 8659: flags, tag length,
+// tag, value) to its presentation format
+func formatCAA(data []byte) (string, bool) {
+	if len(data) < 2 || len(data) < 2+int(data[1]) {
+		return "", false
+	}
+	tag := string(data[2 : 2+int(data[1])])
+	value := string(data[2+int(data[1]):])
+	return fmt.Sprintf("%d %s %q", data[0], tag, value), true
+}
+
+// dohCAA normalizes a CAA answer of a DoH JSON API: providers that do not
+// know the type send the RFC 3597 form "\# 15 00 05 69 73 73 75 65 ..."
+func dohCAA(data string) string {
+	fields := strings.Fields(data)
+	if len(fields) < 2 || fields[0] != `\#` {
+		return data
+	}
+	raw, err := hex.DecodeString(strings.Join(fields[2:], ""))
+	if err != nil {
+		return data
+	}
+	if value, ok := formatCAA(raw); ok {
+		return value
+	}
+	return data
+}
+
+// unquoteTXT joins the quoted strings of a TXT answer of a DoH JSON API,
+// e.g. `"v=spf1 " "-all"` becomes "v=spf1 -all"
+func unquoteTXT(data string) string {
+	if !strings.HasPrefix(data, `"`) {
+		return data
+	}
+	var b strings.Builder
+	for rest := data; rest != ""; {
+		rest = strings.TrimLeft(rest, " ")
+		s, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			return data
+		}
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			return data
+		}
+		b.WriteString(unquoted)
+		rest = rest[len(s):]
+	}
+	return b.String()
+}
+
+// tlsClient performs the TLS handshake of a DoT connection to host, which
+// the certificate is verified against unless TLSConfig sets a ServerName.
+// A certificate that fails verification is reported as a *TLSVerifyError.
+func (r *Resolver) tlsClient(ctx context.Context, conn net.Conn, host string) (*tls.Conn, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if r.TLSConfig != nil {
+		cfg = r.TLSConfig.Clone()
+		if cfg.MinVersion == 0 {
+			cfg.MinVersion = tls.VersionTLS12
+		}
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = strings.Trim(host, "[]")
+	}
+
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		if isCertificateError(err) {
+			return nil, &TLSVerifyError{ServerName: cfg.ServerName, Err: err}
+		}
+		return nil, fmt.Errorf("TLS handshake failed: %w", err)
+	}
+	return tlsConn, nil
+}
+
+// isCertificateError reports whether a handshake failed because the server
+// certificate could not be verified
+func isCertificateError(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	return errors.As(err, &verifyErr) || errors.As(err, &unknownAuthority) ||
+		errors.As(err, &hostname) || errors.As(err, &invalid)
+}
@@ -0,0 +1,250 @@
+package dns
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/netip"
+	"slices"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// newTestCert returns a self-signed certificate for 127.0.0.1 and a pool
+// holding it as the only root
+func newTestCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "dot test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+// startDoTServer answers every A query for a name with addr, over TLS with
+// cert on a local port until the end of the test, and returns its address
+func startDoTServer(t *testing.T, cert tls.Certificate, addr string) string {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.SetDeadline(time.Now().Add(5 * time.Second))
+				serveTCPQuery(conn, addr)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// serveTCPQuery reads one length-prefixed query from conn and answers it
+func serveTCPQuery(conn net.Conn, addr string) {
+	lengthBuf := make([]byte, 2)
+	if _, err := io.ReadFull(conn, lengthBuf); err != nil {
+		return
+	}
+	buf := make([]byte, int(lengthBuf[0])<<8|int(lengthBuf[1]))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return
+	}
+	var query dnsmessage.Message
+	if err := query.Unpack(buf); err != nil || len(query.Questions) == 0 {
+		return
+	}
+
+	q := query.Questions[0]
+	resp := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true},
+		Questions: []dnsmessage.Question{q},
+		Answers: []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+			Body:   &dnsmessage.AResource{A: netip.MustParseAddr(addr).As4()},
+		}},
+	}
+	packed, err := resp.Pack()
+	if err != nil {
+		return
+	}
+	conn.Write(append([]byte{byte(len(packed) >> 8), byte(len(packed))}, packed...))
+}
+
+func newDoTResolver(server string, cfg *tls.Config) *Resolver {
+	r := NewResolver(server, DNSTypeDoT)
+	r.Timeout = 5 * time.Second
+	r.TLSConfig = cfg
+	return r
+}
+
+func TestDoTLookup(t *testing.T) {
+	cert, pool := newTestCert(t)
+	server := startDoTServer(t, cert, "192.0.2.10")
+
+	result, err := newDoTResolver(server, &tls.Config{RootCAs: pool}).Lookup(context.Background(), "example.test")
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if !slices.Equal(result.A, []string{"192.0.2.10"}) {
+		t.Errorf("A = %v, want [192.0.2.10]", result.A)
+	}
+	if result.Protocol != DNSTypeDoT || result.Server != server {
+		t.Errorf("answered by %s %s, want dot %s", result.Protocol, result.Server, server)
+	}
+	if result.RCode != "NOERROR" || !result.Authoritative {
+		t.Errorf("rcode %q authoritative %v, want NOERROR and true", result.RCode, result.Authoritative)
+	}
+}
+
+// A certificate from an unknown authority fails verification, reported as a
+// *TLSVerifyError naming the server the certificate was checked against
+func TestDoTLookupUnknownAuthority(t *testing.T) {
+	cert, _ := newTestCert(t)
+	server := startDoTServer(t, cert, "192.0.2.10")
+
+	_, err := newDoTResolver(server, nil).Lookup(context.Background(), "example.test")
+	var verifyErr *TLSVerifyError
+	if !errors.As(err, &verifyErr) {
+		t.Fatalf("err = %v, want a *TLSVerifyError", err)
+	}
+	if verifyErr.ServerName != "127.0.0.1" {
+		t.Errorf("ServerName = %q, want 127.0.0.1", verifyErr.ServerName)
+	}
+}
+
+func TestDoTLookupServerNameMismatch(t *testing.T) {
+	cert, pool := newTestCert(t)
+	server := startDoTServer(t, cert, "192.0.2.10")
+
+	_, err := newDoTResolver(server, &tls.Config{RootCAs: pool, ServerName: "dns.example.test"}).
+		Lookup(context.Background(), "example.test")
+	var verifyErr *TLSVerifyError
+	if !errors.As(err, &verifyErr) || verifyErr.ServerName != "dns.example.test" {
+		t.Fatalf("err = %v, want a *TLSVerifyError for dns.example.test", err)
+	}
+}
+
+func TestDoTLookupSkipVerify(t *testing.T) {
+	cert, _ := newTestCert(t)
+	server := startDoTServer(t, cert, "192.0.2.11")
+
+	result, err := newDoTResolver(server, &tls.Config{InsecureSkipVerify: true}).Lookup(context.Background(), "example.test")
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if !slices.Equal(result.A, []string{"192.0.2.11"}) {
+		t.Errorf("A = %v, want [192.0.2.11]", result.A)
+	}
+}
+
+// A plain DNS-over-TCP server can't complete the handshake; that is a
+// handshake failure, not a certificate one
+func TestDoTLookupPlaintextServer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				// Answer the ClientHello with bytes that are not a TLS record
+				conn.Read(make([]byte, 512))
+				conn.Write([]byte{0, 12, 0, 1, 0x81, 0x80, 0, 0, 0, 0, 0, 0, 0, 0})
+			}()
+		}
+	}()
+
+	_, err = newDoTResolver(ln.Addr().String(), &tls.Config{InsecureSkipVerify: true}).
+		Lookup(context.Background(), "example.test")
+	if err == nil {
+		t.Fatal("lookup against a plaintext server succeeded")
+	}
+	var verifyErr *TLSVerifyError
+	if errors.As(err, &verifyErr) {
+		t.Errorf("err = %v, want a handshake failure, not a *TLSVerifyError", err)
+	}
+	var lookupErr *LookupError
+	if !errors.As(err, &lookupErr) || len(lookupErr.Attempts) != 1 || lookupErr.Attempts[0].Protocol != DNSTypeDoT {
+		t.Errorf("err = %v, want a *LookupError with one dot attempt", err)
+	}
+}
+
+// The resolver must not change the caller's config while filling in the
+// server name and minimum version
+func TestDoTLookupLeavesConfigAlone(t *testing.T) {
+	cert, pool := newTestCert(t)
+	server := startDoTServer(t, cert, "192.0.2.10")
+
+	cfg := &tls.Config{RootCAs: pool}
+	if _, err := newDoTResolver(server, cfg).Lookup(context.Background(), "example.test"); err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if cfg.ServerName != "" || cfg.MinVersion != 0 {
+		t.Errorf("config changed: ServerName %q MinVersion %#x", cfg.ServerName, cfg.MinVersion)
+	}
+}
+
+// Falls back to the next server when the first one's certificate is rejected
+func TestDoTLookupFallback(t *testing.T) {
+	cert, pool := newTestCert(t)
+	other, _ := newTestCert(t)
+	bad := startDoTServer(t, other, "192.0.2.1")
+	good := startDoTServer(t, cert, "192.0.2.2")
+
+	result, err := newDoTResolver(bad+","+good, &tls.Config{RootCAs: pool}).Lookup(context.Background(), "example.test")
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if result.Server != good || !result.Fallback || len(result.Failures) != 1 {
+		t.Fatalf("answered by %s fallback %v failures %d, want %s true 1", result.Server, result.Fallback, len(result.Failures), good)
+	}
+	var verifyErr *TLSVerifyError
+	if !errors.As(result.Failures[0], &verifyErr) {
+		t.Errorf("failure = %v, want a *TLSVerifyError", result.Failures[0])
+	}
+}
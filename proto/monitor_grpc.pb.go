@@ -25,6 +25,7 @@ const (
 	MonitorService_ListMonitors_FullMethodName      = "/monitor.MonitorService/ListMonitors"
 	MonitorService_GetMonitorStatus_FullMethodName  = "/monitor.MonitorService/GetMonitorStatus"
 	MonitorService_ListMonitorStatus_FullMethodName = "/monitor.MonitorService/ListMonitorStatus"
+	MonitorService_GetVersion_FullMethodName        = "/monitor.MonitorService/GetVersion"
 )
 
 // MonitorServiceClient is the client API for MonitorService service.
@@ -37,6 +38,7 @@ type MonitorServiceClient interface {
 	ListMonitors(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*TargetList, error)
 	GetMonitorStatus(ctx context.Context, in *MonitorID, opts ...grpc.CallOption) (*MonitorStatus, error)
 	ListMonitorStatus(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*MonitorStatusList, error)
+	GetVersion(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*VersionInfo, error)
 }
 
 type monitorServiceClient struct {
@@ -101,6 +103,15 @@ func (c *monitorServiceClient) ListMonitorStatus(ctx context.Context, in *Empty,
 	return out, nil
 }
 
+func (c *monitorServiceClient) GetVersion(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*VersionInfo, error) {
+	out := new(VersionInfo)
+	err := c.cc.Invoke(ctx, MonitorService_GetVersion_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MonitorServiceServer is the server API for MonitorService service.
 // All implementations must embed UnimplementedMonitorServiceServer
 // for forward compatibility
@@ -111,6 +122,7 @@ type MonitorServiceServer interface {
 	ListMonitors(context.Context, *Empty) (*TargetList, error)
 	GetMonitorStatus(context.Context, *MonitorID) (*MonitorStatus, error)
 	ListMonitorStatus(context.Context, *Empty) (*MonitorStatusList, error)
+	GetVersion(context.Context, *Empty) (*VersionInfo, error)
 	mustEmbedUnimplementedMonitorServiceServer()
 }
 
@@ -136,6 +148,9 @@ func (UnimplementedMonitorServiceServer) GetMonitorStatus(context.Context, *Moni
 func (UnimplementedMonitorServiceServer) ListMonitorStatus(context.Context, *Empty) (*MonitorStatusList, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListMonitorStatus not implemented")
 }
+func (UnimplementedMonitorServiceServer) GetVersion(context.Context, *Empty) (*VersionInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVersion not implemented")
+}
 func (UnimplementedMonitorServiceServer) mustEmbedUnimplementedMonitorServiceServer() {}
 
 // UnsafeMonitorServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -257,6 +272,24 @@ func _MonitorService_ListMonitorStatus_Handler(srv interface{}, ctx context.Cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MonitorService_GetVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MonitorServiceServer).GetVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MonitorService_GetVersion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MonitorServiceServer).GetVersion(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MonitorService_ServiceDesc is the grpc.ServiceDesc for MonitorService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -288,6 +321,10 @@ var MonitorService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListMonitorStatus",
 			Handler:    _MonitorService_ListMonitorStatus_Handler,
 		},
+		{
+			MethodName: "GetVersion",
+			Handler:    _MonitorService_GetVersion_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/monitor.proto",
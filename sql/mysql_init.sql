@@ -20,6 +20,9 @@ CREATE TABLE `monitor_targets` (
     `address` VARCHAR(500) NOT NULL COMMENT '监控地址',
     `port` INT DEFAULT NULL COMMENT '端口号',
     `interval` BIGINT DEFAULT 60 COMMENT '检查间隔（秒）',
+    `timeout_seconds` INT DEFAULT 0 COMMENT '单次检查的超时（秒），不超过 interval；0 为默认的 30 秒',
+    `retry_count` INT DEFAULT 0 COMMENT '检查结果为 down 时的重试次数，最多 5 次',
+    `retry_interval_seconds` INT DEFAULT 0 COMMENT '重试间隔（秒），所有尝试共用检查超时',
     `metadata` TEXT COMMENT '元数据（JSON）',
     `enabled` TINYINT(1) DEFAULT 1 COMMENT '是否启用',
 
@@ -28,14 +31,36 @@ CREATE TABLE `monitor_targets` (
     `http_headers` TEXT COMMENT 'HTTP请求头（JSON）',
     `http_body` TEXT COMMENT 'HTTP请求体',
     `resolved_host` VARCHAR(255) DEFAULT NULL COMMENT '自定义Host头',
+    `proxy_url` VARCHAR(500) DEFAULT NULL COMMENT 'http、https 或 socks5 代理，不含密码',
+    `proxy_password` VARCHAR(255) DEFAULT NULL COMMENT '代理密码，接口不返回',
     `follow_redirects` TINYINT(1) DEFAULT 1 COMMENT '是否跟随重定向',
     `max_redirects` INT DEFAULT 10 COMMENT '最大重定向次数',
     `expected_status_codes` TEXT COMMENT '期望的状态码（逗号分隔）',
+    `expected_headers` TEXT COMMENT '响应头断言（JSON），响应头名到期望值',
+    `body_must_contain` TEXT COMMENT '响应体必须包含的文本',
+    `body_must_not_contain` TEXT COMMENT '响应体不能包含的文本',
+    `body_regex` TEXT COMMENT '响应体必须匹配的正则表达式',
+    `json_path` VARCHAR(500) DEFAULT NULL COMMENT 'JSON 响应体中断言的字段，如 data.items[0].status',
+    `json_expected_value` TEXT COMMENT 'JSON 字段的期望值',
+    `json_operator` VARCHAR(10) DEFAULT NULL COMMENT 'JSON 字段的比较方式: eq, ne, gt, lt, contains',
+    `steps` TEXT COMMENT '多步事务的请求（JSON数组），设置后代替单个请求',
+    `track_content_hash` TINYINT(1) DEFAULT 0 COMMENT '记录响应体哈希，变化时发出 content_changed 事件',
+    `content_hash_regex` TEXT COMMENT '只对匹配的部分计算哈希（RE2）',
+    `content_hash_normalize` TINYINT(1) DEFAULT 0 COMMENT '计算哈希前去掉 script、style、注释并合并空白',
+    `auth_type` VARCHAR(10) DEFAULT NULL COMMENT '请求认证方式: none, basic, bearer',
+    `auth_username` VARCHAR(255) DEFAULT NULL COMMENT 'basic 认证的用户名',
+    `auth_password` VARCHAR(255) DEFAULT NULL COMMENT 'basic 认证的密码，接口不返回',
+    `auth_token` TEXT COMMENT 'bearer 认证的令牌，接口不返回',
 
     -- DNS 专用字段
     `dns_server` VARCHAR(255) DEFAULT NULL COMMENT 'DNS服务器地址',
     `dns_server_name` VARCHAR(255) DEFAULT NULL COMMENT 'DNS服务器名称',
     `dns_server_type` VARCHAR(10) DEFAULT NULL COMMENT 'DNS协议: udp, tcp, doh, dot',
+    `dns_servers` TEXT COMMENT '同时查询比较的服务器: 逗号分隔的供应商ID或地址',
+    `dns_consensus` VARCHAR(20) DEFAULT NULL COMMENT '多服务器判定策略: all, majority, any',
+    `dns_query_type` VARCHAR(10) DEFAULT NULL COMMENT '查询的记录类型，默认 A',
+    `dns_expected_values` TEXT COMMENT '期望的记录（JSON数组），见 dns_match_mode',
+    `dns_match_mode` VARCHAR(10) DEFAULT NULL COMMENT '与期望记录的比较方式: exact, subset',
 
     -- PING 专用字段
     `ping_count` INT DEFAULT 4 COMMENT 'PING次数',
@@ -57,21 +82,60 @@ CREATE TABLE `monitor_targets` (
     `snmp_expected_value` VARCHAR(255) DEFAULT NULL COMMENT '期望值',
     `snmp_operator` VARCHAR(10) DEFAULT NULL COMMENT '操作符: eq, ne, gt, lt',
 
+    -- TCP 特定字段
+    `tcp_send_string` TEXT COMMENT '连接后发送的内容',
+    `tcp_expect_string` TEXT COMMENT '回复必须包含的文本，/.../ 为正则',
+    `tcp_use_tls` TINYINT(1) DEFAULT 0 COMMENT '连接后先进行 TLS 握手',
+
+    -- UDP 特定字段
+    `udp_preset` VARCHAR(20) DEFAULT NULL COMMENT '内置探测: dns, ntp, sip_options',
+    `udp_send_payload` TEXT COMMENT '发送的数据报内容（编码见 udp_payload_format）',
+    `udp_payload_format` VARCHAR(10) DEFAULT NULL COMMENT 'udp_send_payload 的编码: hex, base64',
+    `udp_expect_response` TINYINT(1) DEFAULT 0 COMMENT '要求收到回复',
+    `udp_expect_pattern` TEXT COMMENT '回复必须匹配的正则表达式',
+
     -- SSL/TLS 证书专用字段
     `ssl_warn_days` INT DEFAULT 30 COMMENT 'SSL证书警告天数',
     `ssl_critical_days` INT DEFAULT 7 COMMENT 'SSL证书严重天数',
     `ssl_get_chain` TINYINT(1) DEFAULT 1 COMMENT '获取证书链',
     `ssl_check` TINYINT(1) DEFAULT 0 COMMENT '启用SSL证书监控',
+    `tls_client_cert_pem` TEXT COMMENT '双向 TLS 的客户端证书（PEM）',
+    `tls_client_key_pem` TEXT COMMENT '客户端证书的私钥（PEM），接口不返回',
+    `tls_ca_cert_pem` TEXT COMMENT '验证服务器证书的 CA（PEM），替代系统根证书',
+    `tls_skip_verify` TINYINT(1) DEFAULT 0 COMMENT '不验证服务器证书',
+    `tls_pin_sha256` VARCHAR(64) DEFAULT NULL COMMENT '服务器证书的 SHA-256 指纹',
+
+    -- 自定义脚本专用字段
+    `script_path` VARCHAR(500) DEFAULT NULL COMMENT '可执行程序路径，必须在 monitor.script.allowed_paths 中',
+    `script_args` TEXT COMMENT '参数（JSON数组），不经过 shell',
+    `script_timeout` INT DEFAULT 10 COMMENT '超时（秒）',
+
+    -- 对比模式（http, https, tcp）
+    `secondary_address` VARCHAR(500) DEFAULT NULL COMMENT '同时检查并对比的第二个地址，空为关闭',
+    `compare_latency_tolerance` INT DEFAULT 0 COMMENT '响应时间容差（毫秒），0 为不比较',
+    `compare_body` TINYINT(1) DEFAULT 0 COMMENT '比较响应体的 SHA-256',
+
+    -- 响应时间阈值（http, https, tcp, dns）
+    `degraded_threshold_ms` INT DEFAULT 0 COMMENT '成功的检查达到该响应时间（毫秒）记为 degraded，0 为不判断',
+    `down_threshold_ms` INT DEFAULT 0 COMMENT '成功的检查达到该响应时间（毫秒）记为 down，0 为不判断',
 
     -- 告警渠道关联
     `alert_channel_ids` TEXT COMMENT '告警渠道ID列表（JSON数组）',
+    `notes` TEXT COMMENT '运维备注（Markdown）',
+    `runbook_url` VARCHAR(500) COMMENT '处理手册链接',
+    `sinks` VARCHAR(100) DEFAULT NULL COMMENT '写入目的地: 空为全部, none, 或 db_history,es,file',
+    `tags` TEXT COMMENT '标签 JSON 数组',
+    `group_id` INT UNSIGNED DEFAULT NULL COMMENT '所属分组，NULL 为未分组',
+    `depends_on_target_id` INT UNSIGNED DEFAULT NULL COMMENT '依赖的监控，它 down 时本监控的 down 不告警',
 
     `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 
     PRIMARY KEY (`id`),
     KEY `idx_type` (`type`),
-    KEY `idx_enabled` (`enabled`)
+    KEY `idx_enabled` (`enabled`),
+    KEY `idx_group_id` (`group_id`),
+    KEY `idx_depends_on_target_id` (`depends_on_target_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='监控目标表';
 
 -- ============================================
@@ -86,6 +150,13 @@ CREATE TABLE `monitor_status` (
     `message` TEXT COMMENT '状态消息',
     `checked_at` TIMESTAMP NULL DEFAULT NULL COMMENT '检查时间',
     `uptime_percentage` INT DEFAULT 0 COMMENT '正常运行时间百分比',
+    `last_status_change_at` TIMESTAMP NULL DEFAULT NULL COMMENT '最近一次状态变化时间',
+    `synthetic` TINYINT(1) DEFAULT 0 COMMENT '当前状态是否来自故障注入的合成结果',
+    `flapping` TINYINT(1) DEFAULT 0 COMMENT '状态是否频繁变化（抖动）',
+    `suppressed_by` INT UNSIGNED DEFAULT NULL COMMENT '当前的 down 被依赖的哪个监控抑制',
+    `uptime_24h` DOUBLE DEFAULT 0 COMMENT '24 小时可用率百分比',
+    `uptime_7d` DOUBLE DEFAULT 0 COMMENT '7 天可用率百分比',
+    `uptime_30d` DOUBLE DEFAULT 0 COMMENT '30 天可用率百分比',
 
     -- SSL 证书信息
     `ssl_days_until_expiry` INT DEFAULT NULL COMMENT 'SSL证书剩余天数',
@@ -97,6 +168,9 @@ CREATE TABLE `monitor_status` (
     `dns_records` TEXT COMMENT 'DNS记录（JSON）',
     `resolved_ip` VARCHAR(64) DEFAULT NULL COMMENT '解析的IP地址',
 
+    -- 内容变化检测
+    `content_hash` VARCHAR(64) DEFAULT NULL COMMENT '最近一次计算的响应体 SHA-256',
+
     -- 额外的检查数据
     `data` TEXT COMMENT '完整检查结果数据（JSON）',
 
@@ -117,10 +191,20 @@ CREATE TABLE `monitor_history` (
     `status` VARCHAR(50) NOT NULL COMMENT '状态: up, down',
     `response_time` BIGINT DEFAULT NULL COMMENT '响应时间（毫秒）',
     `message` TEXT COMMENT '消息',
+    `synthetic` TINYINT(1) DEFAULT 0 COMMENT '是否为故障注入的合成结果',
+    `address` VARCHAR(500) DEFAULT NULL COMMENT '产生结果的地址，仅对比模式记录',
+    `divergence` TINYINT(1) DEFAULT 0 COMMENT '对比模式下第二个地址的结果是否不一致',
+    `content_changed` TINYINT(1) DEFAULT 0 COMMENT '响应体哈希是否与上次不同',
+    `jitter` DOUBLE DEFAULT NULL COMMENT 'ping 往返时间的抖动（毫秒）',
+    `rtt_stddev` DOUBLE DEFAULT NULL COMMENT 'ping 往返时间的标准差（毫秒）',
+    `suppressed_by` INT UNSIGNED DEFAULT NULL COMMENT '被依赖的哪个监控抑制，未告警',
     `checked_at` TIMESTAMP NULL DEFAULT NULL COMMENT '检查时间',
+    `check_id` VARCHAR(64) DEFAULT NULL COMMENT '检查的 ID（与 ES 文档 ID 相同），补写暂存的结果时去重',
     PRIMARY KEY (`id`),
-    KEY `idx_target_id` (`target_id`),
+    KEY `idx_monitor_history_target_checked` (`target_id`, `checked_at`),
     KEY `idx_checked_at` (`checked_at`),
+    KEY `idx_synthetic` (`synthetic`),
+    UNIQUE KEY `idx_monitor_history_check_id` (`check_id`),
     CONSTRAINT `fk_monitor_history_target` FOREIGN KEY (`target_id`) REFERENCES `monitor_targets` (`id`) ON DELETE CASCADE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='监控历史表';
 
@@ -169,6 +253,10 @@ CREATE TABLE `alert_channels` (
     `type` VARCHAR(50) NOT NULL COMMENT '渠道类型: email, webhook, dingtalk, wechat',
     `enabled` TINYINT(1) DEFAULT 1 COMMENT '是否启用',
     `config` TEXT NOT NULL COMMENT '渠道配置（JSON）',
+    `health` VARCHAR(20) DEFAULT 'healthy' COMMENT '发送健康状态: healthy, degraded',
+    `health_reason` VARCHAR(255) DEFAULT NULL COMMENT '标记为 degraded 的原因',
+    `health_changed_at` TIMESTAMP NULL DEFAULT NULL COMMENT '最近一次健康状态变化时间',
+    `suppress_health` TINYINT(1) DEFAULT 0 COMMENT '不因发送失败标记为 degraded',
     `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     PRIMARY KEY (`id`)
@@ -190,6 +278,9 @@ CREATE TABLE `alert_rules` (
     `condition_logic` TEXT COMMENT '条件逻辑（JSON）',
     `cooldown_seconds` INT DEFAULT 300 COMMENT '冷却时间（秒）',
     `last_alert_time` TIMESTAMP NULL DEFAULT NULL COMMENT '最后告警时间',
+    `alert_open` TINYINT(1) DEFAULT 0 COMMENT '是否存在未恢复的告警',
+    `alert_count` INT DEFAULT 0 COMMENT '当前未恢复告警已发送次数',
+    `snoozed_until` TIMESTAMP NULL DEFAULT NULL COMMENT '静默到期时间，期间不发送告警',
 
     `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
@@ -247,14 +338,263 @@ CREATE TABLE `alert_history` (
     `severity` VARCHAR(50) DEFAULT NULL COMMENT '严重程度',
     `status` VARCHAR(50) DEFAULT NULL COMMENT '状态',
     `message` TEXT COMMENT '消息',
+    `synthetic` TINYINT(1) DEFAULT 0 COMMENT '是否由故障注入触发',
+    `snooze_id` BIGINT UNSIGNED DEFAULT 0 COMMENT '因静默未发送的告警，或静默事件，对应的静默',
     `sent_at` TIMESTAMP NULL DEFAULT NULL COMMENT '发送时间',
     `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (`id`),
     KEY `idx_rule_id` (`rule_id`),
     KEY `idx_target_id` (`target_id`),
+    KEY `idx_channel_id` (`channel_id`),
     KEY `idx_sent_at` (`sent_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='告警历史表';
 
+-- ============================================
+-- 11. 数据迁移记录表 (schema_migrations)
+-- ============================================
+DROP TABLE IF EXISTS `schema_migrations`;
+CREATE TABLE `schema_migrations` (
+    `name` VARCHAR(100) NOT NULL COMMENT '迁移名称',
+    `applied_at` TIMESTAMP NULL DEFAULT NULL COMMENT '执行时间',
+    PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='数据迁移记录表';
+
+-- ============================================
+-- 12. 响应存档表 (response_archives)
+-- ============================================
+DROP TABLE IF EXISTS `response_archives`;
+CREATE TABLE `response_archives` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `target_id` INT UNSIGNED NOT NULL,
+    `history_id` BIGINT UNSIGNED DEFAULT NULL COMMENT 'down 结果的历史记录，未写历史时为 0',
+    `checked_at` TIMESTAMP NULL DEFAULT NULL COMMENT '检查时间',
+    `message` TEXT COMMENT '消息',
+    `method` VARCHAR(10) DEFAULT NULL COMMENT '请求方法',
+    `url` VARCHAR(500) DEFAULT NULL COMMENT '请求地址',
+    `request_headers` TEXT COMMENT '请求头（JSON，已脱敏）',
+    `status_code` INT DEFAULT NULL COMMENT 'HTTP 状态码',
+    `response_headers` TEXT COMMENT '响应头（JSON，读取时脱敏）',
+    `response_time` BIGINT DEFAULT NULL COMMENT '响应时间（毫秒）',
+    `content_length` BIGINT DEFAULT NULL,
+    `bytes_received` BIGINT DEFAULT NULL,
+    `decoded_body_bytes` BIGINT DEFAULT NULL,
+    `body` LONGBLOB COMMENT '解码后的响应体，最多 archive.max_bytes',
+    `truncated` TINYINT(1) DEFAULT 0 COMMENT '响应体是否被截断',
+    PRIMARY KEY (`id`),
+    KEY `idx_target_id` (`target_id`),
+    KEY `idx_history_id` (`history_id`),
+    KEY `idx_checked_at` (`checked_at`),
+    CONSTRAINT `fk_response_archives_target` FOREIGN KEY (`target_id`) REFERENCES `monitor_targets` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='响应存档表';
+
+-- ============================================
+-- 13. 告警规则静默表 (alert_rule_snoozes)
+-- ============================================
+DROP TABLE IF EXISTS `alert_rule_snoozes`;
+CREATE TABLE `alert_rule_snoozes` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `rule_id` BIGINT UNSIGNED NOT NULL COMMENT '规则ID',
+    `target_id` INT UNSIGNED NOT NULL COMMENT '目标ID',
+    `note` TEXT COMMENT '备注',
+    `starts_at` TIMESTAMP NULL DEFAULT NULL COMMENT '开始时间',
+    `expires_at` TIMESTAMP NULL DEFAULT NULL COMMENT '到期时间',
+    `cancelled_at` TIMESTAMP NULL DEFAULT NULL COMMENT '提前结束时间（取消或被新的静默替换）',
+    `created_by` VARCHAR(100) DEFAULT NULL COMMENT '请求的客户端 IP',
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `idx_rule_id` (`rule_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='告警规则静默表';
+
+-- ============================================
+-- 14. 历史归档记录表 (history_exports)
+-- ============================================
+DROP TABLE IF EXISTS `history_exports`;
+CREATE TABLE `history_exports` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `source` VARCHAR(50) NOT NULL COMMENT '导出的表，monitor_history',
+    `from_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '对象中的第一条记录ID',
+    `to_id` BIGINT UNSIGNED DEFAULT NULL COMMENT '对象中的最后一条记录ID',
+    `rows` BIGINT DEFAULT NULL COMMENT '记录数',
+    `object_key` VARCHAR(500) DEFAULT NULL COMMENT '对象键',
+    `bytes` BIGINT DEFAULT NULL COMMENT '压缩后的大小',
+    `sha256` VARCHAR(64) DEFAULT NULL COMMENT '压缩后对象的 SHA-256',
+    `status` VARCHAR(20) DEFAULT NULL COMMENT 'verified, failed',
+    `error` TEXT COMMENT '失败原因',
+    `exported_at` TIMESTAMP NULL DEFAULT NULL COMMENT '导出时间',
+    `verified_at` TIMESTAMP NULL DEFAULT NULL COMMENT '验证时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_source` (`source`),
+    KEY `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='历史归档记录表';
+
+-- ============================================
+-- 15. 证书清单 (certificates, certificate_targets)
+-- ============================================
+DROP TABLE IF EXISTS `certificates`;
+CREATE TABLE `certificates` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `fingerprint` VARCHAR(64) NOT NULL COMMENT 'DER 编码的 SHA-256，小写十六进制',
+    `subject` VARCHAR(255) DEFAULT NULL COMMENT '主题 CN',
+    `dns_names` TEXT COMMENT 'SAN 中的域名，JSON 数组',
+    `issuer` VARCHAR(255) DEFAULT NULL COMMENT '签发者 CN',
+    `serial` VARCHAR(128) DEFAULT NULL COMMENT '序列号',
+    `not_before` TIMESTAMP NULL DEFAULT NULL COMMENT '生效时间',
+    `not_after` TIMESTAMP NULL DEFAULT NULL COMMENT '到期时间',
+    `first_seen` TIMESTAMP NULL DEFAULT NULL COMMENT '首次发现时间',
+    `last_seen` TIMESTAMP NULL DEFAULT NULL COMMENT '最近发现时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_certificates_fingerprint` (`fingerprint`),
+    KEY `idx_not_after` (`not_after`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='证书清单';
+
+DROP TABLE IF EXISTS `certificate_targets`;
+CREATE TABLE `certificate_targets` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `certificate_id` BIGINT UNSIGNED NOT NULL COMMENT '证书ID',
+    `target_id` INT UNSIGNED NOT NULL COMMENT '目标ID',
+    `is_current` TINYINT(1) DEFAULT 0 COMMENT '目标最近一次检查出示的证书',
+    `first_seen` TIMESTAMP NULL DEFAULT NULL COMMENT '首次发现时间',
+    `last_seen` TIMESTAMP NULL DEFAULT NULL COMMENT '最近发现时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_certificate_target` (`certificate_id`, `target_id`),
+    KEY `idx_target_id` (`target_id`),
+    KEY `idx_is_current` (`is_current`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='证书与监控目标的关联表';
+
+-- ============================================
+-- 16. 嵌入令牌表 (api_tokens)
+-- ============================================
+DROP TABLE IF EXISTS `api_tokens`;
+CREATE TABLE `api_tokens` (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `name` VARCHAR(255) NOT NULL COMMENT '名称',
+    `token_hash` VARCHAR(64) NOT NULL COMMENT '令牌的 SHA-256，令牌本身只在创建时返回',
+    `prefix` VARCHAR(16) DEFAULT NULL COMMENT '令牌开头，用于区分令牌',
+    `target_id` INT UNSIGNED NOT NULL COMMENT '只能读取的目标ID，按标签授权时为 0',
+    `tag` VARCHAR(64) DEFAULT NULL COMMENT '只能读取带有这个标签的监控',
+    `expires_at` TIMESTAMP NULL DEFAULT NULL COMMENT '到期时间',
+    `revoked_at` TIMESTAMP NULL DEFAULT NULL COMMENT '吊销时间',
+    `last_used_at` TIMESTAMP NULL DEFAULT NULL COMMENT '最近使用时间',
+    `use_count` BIGINT DEFAULT 0 COMMENT '使用次数',
+    `created_by` VARCHAR(100) DEFAULT NULL COMMENT '请求的客户端 IP',
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_api_tokens_token_hash` (`token_hash`),
+    KEY `idx_target_id` (`target_id`),
+    KEY `idx_api_tokens_tag` (`tag`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='嵌入令牌表';
+
+-- ============================================
+-- 17. 维护窗口表 (maintenance_windows)
+-- ============================================
+DROP TABLE IF EXISTS `maintenance_windows`;
+CREATE TABLE `maintenance_windows` (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `name` VARCHAR(255) NOT NULL COMMENT '名称',
+    `target_id` INT UNSIGNED DEFAULT NULL COMMENT '监控目标ID，NULL 表示所有监控',
+    `schedule` VARCHAR(100) DEFAULT NULL COMMENT 'cron 表达式，设置后代替 weekdays 和 start_time',
+    `weekdays` VARCHAR(100) DEFAULT NULL COMMENT '逗号分隔的星期，留空为每天',
+    `start_time` VARCHAR(5) DEFAULT NULL COMMENT '开始时间 HH:MM',
+    `end_time` VARCHAR(5) NOT NULL COMMENT '结束时间 HH:MM，不晚于开始时间时跨过午夜',
+    `timezone` VARCHAR(64) DEFAULT NULL COMMENT 'IANA 时区，留空为服务器本地时区',
+    `enabled` TINYINT(1) DEFAULT 1 COMMENT '是否启用',
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `idx_target_id` (`target_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='维护窗口表';
+
+-- ============================================
+-- 18. 发现候选表 (discovery_candidates)
+-- ============================================
+DROP TABLE IF EXISTS `discovery_candidates`;
+CREATE TABLE `discovery_candidates` (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `scan_id` VARCHAR(16) DEFAULT NULL COMMENT '首次发现它的扫描',
+    `fingerprint` VARCHAR(300) NOT NULL COMMENT '小写的 host:port，用于去重',
+    `source` VARCHAR(255) DEFAULT NULL COMMENT '区域名或网段',
+    `name` VARCHAR(255) NOT NULL COMMENT '名称，来自 PTR 记录或主机名',
+    `type` VARCHAR(20) NOT NULL COMMENT '猜测的监控类型',
+    `address` VARCHAR(500) NOT NULL COMMENT '创建监控时使用的地址',
+    `port` INT DEFAULT NULL COMMENT '端口',
+    `ip` VARCHAR(45) DEFAULT NULL COMMENT '探测时连接的地址',
+    `banner` VARCHAR(255) DEFAULT NULL COMMENT '协议标语',
+    `status` VARCHAR(20) NOT NULL DEFAULT 'pending' COMMENT 'pending/approved/rejected',
+    `target_id` INT UNSIGNED DEFAULT NULL COMMENT '审核通过后创建的监控',
+    `reviewed_by` VARCHAR(100) DEFAULT NULL COMMENT '审核请求的客户端 IP',
+    `reviewed_at` TIMESTAMP NULL DEFAULT NULL COMMENT '审核时间',
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_fingerprint` (`fingerprint`),
+    KEY `idx_scan_id` (`scan_id`),
+      KEY `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='发现候选表';
+
+-- ============================================
+-- 19. 监控分组表 (monitor_groups)
+-- ============================================
+DROP TABLE IF EXISTS `monitor_groups`;
+CREATE TABLE `monitor_groups` (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `name` VARCHAR(255) NOT NULL COMMENT '分组名称，唯一',
+    `description` VARCHAR(500) DEFAULT NULL COMMENT '说明',
+    `sort_order` INT NOT NULL DEFAULT 0 COMMENT '显示顺序，小的在前',
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='监控分组表';
+
+-- ============================================
+-- 20. 历史小时汇总表 (monitor_history_hourly)
+-- ============================================
+DROP TABLE IF EXISTS `monitor_history_hourly`;
+CREATE TABLE `monitor_history_hourly` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `target_id` INT UNSIGNED NOT NULL,
+    `bucket_start` TIMESTAMP NOT NULL COMMENT 'UTC 整点，每个监控每小时一行',
+    `checks` BIGINT DEFAULT NULL,
+    `up` BIGINT DEFAULT NULL COMMENT 'up 的结果数',
+    `down` BIGINT DEFAULT NULL COMMENT 'down、critical 的结果数',
+    `degraded` BIGINT DEFAULT NULL COMMENT 'warning、degraded 的结果数',
+    `samples` BIGINT DEFAULT NULL COMMENT '计入响应时间的结果数（up、warning、degraded）',
+    `avg_response_time` DOUBLE DEFAULT NULL,
+    `min_response_time` BIGINT DEFAULT NULL,
+    `max_response_time` BIGINT DEFAULT NULL,
+    `p50_response_time` BIGINT DEFAULT NULL,
+    `p95_response_time` BIGINT DEFAULT NULL,
+    `p99_response_time` BIGINT DEFAULT NULL,
+    `updated_at` TIMESTAMP NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_monitor_history_hourly_bucket` (`target_id`, `bucket_start`),
+    CONSTRAINT `fk_monitor_history_hourly_target` FOREIGN KEY (`target_id`) REFERENCES `monitor_targets` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='历史小时汇总表';
+
+-- ============================================
+-- 21. 历史天汇总表 (monitor_history_daily)
+-- ============================================
+DROP TABLE IF EXISTS `monitor_history_daily`;
+CREATE TABLE `monitor_history_daily` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `target_id` INT UNSIGNED NOT NULL,
+    `bucket_start` TIMESTAMP NOT NULL COMMENT 'UTC 零点，每个监控每天一行',
+    `checks` BIGINT DEFAULT NULL,
+    `up` BIGINT DEFAULT NULL COMMENT 'up 的结果数',
+    `down` BIGINT DEFAULT NULL COMMENT 'down、critical 的结果数',
+    `degraded` BIGINT DEFAULT NULL COMMENT 'warning、degraded 的结果数',
+    `samples` BIGINT DEFAULT NULL COMMENT '计入响应时间的结果数（up、warning、degraded）',
+    `avg_response_time` DOUBLE DEFAULT NULL,
+    `min_response_time` BIGINT DEFAULT NULL,
+    `max_response_time` BIGINT DEFAULT NULL,
+    `p50_response_time` BIGINT DEFAULT NULL,
+    `p95_response_time` BIGINT DEFAULT NULL,
+    `p99_response_time` BIGINT DEFAULT NULL,
+    `updated_at` TIMESTAMP NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_monitor_history_daily_bucket` (`target_id`, `bucket_start`),
+    CONSTRAINT `fk_monitor_history_daily_target` FOREIGN KEY (`target_id`) REFERENCES `monitor_targets` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='历史天汇总表';
+
 -- ============================================
 -- 初始化数据
 -- ============================================
@@ -268,6 +608,10 @@ INSERT INTO `dns_providers` (`name`, `server`, `server_type`, `is_default`) VALU
     ('114 DNS', '114.114.114.114:53', 'udp', 0)
 ON DUPLICATE KEY UPDATE `name` = VALUES(`name`);
 
+-- 新库的时间戳从一开始就是 UTC，无需转换
+INSERT INTO `schema_migrations` (`name`, `applied_at`) VALUES ('utc_timestamps', CURRENT_TIMESTAMP)
+ON DUPLICATE KEY UPDATE `name` = VALUES(`name`);
+
 -- ============================================
 -- 恢复外键检查
 -- ============================================
@@ -277,7 +621,7 @@ SET FOREIGN_KEY_CHECKS = 1;
 -- 初始化完成
 -- ============================================
 -- 数据库初始化成功完成！
--- 表总数: 10
+-- 表总数: 11
 -- 索引: 已创建
 -- 外键: 已创建
 -- 默认数据: 已插入
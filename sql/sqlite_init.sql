@@ -18,6 +18,9 @@ CREATE TABLE IF NOT EXISTS monitor_targets (
     address VARCHAR(500) NOT NULL,
     port INTEGER,
     interval INTEGER DEFAULT 60,         -- 检查间隔（秒）
+    timeout_seconds INTEGER DEFAULT 0,   -- 单次检查的超时（秒），不超过 interval；0 为默认的 30 秒
+    retry_count INTEGER DEFAULT 0,       -- 检查结果为 down 时的重试次数，最多 5 次
+    retry_interval_seconds INTEGER DEFAULT 0, -- 重试间隔（秒），所有尝试共用检查超时
     metadata TEXT,                       -- JSON 字符串
     enabled BOOLEAN DEFAULT 1,
 
@@ -26,14 +29,36 @@ CREATE TABLE IF NOT EXISTS monitor_targets (
     http_headers TEXT,                   -- JSON 字符串
     http_body TEXT,
     resolved_host VARCHAR(255),          -- 自定义 host 解析
+    proxy_url VARCHAR(500),              -- http、https 或 socks5 代理，不含密码
+    proxy_password VARCHAR(255),         -- 代理密码，接口不返回
     follow_redirects BOOLEAN DEFAULT 1,
     max_redirects INTEGER DEFAULT 10,
     expected_status_codes TEXT,          -- 逗号分隔的状态码
+    expected_headers TEXT,               -- 响应头断言（JSON），响应头名到期望值
+    body_must_contain TEXT,              -- 响应体必须包含的文本
+    body_must_not_contain TEXT,          -- 响应体不能包含的文本
+    body_regex TEXT,                     -- 响应体必须匹配的正则表达式
+    json_path VARCHAR(500),              -- JSON 响应体中断言的字段，如 data.items[0].status
+    json_expected_value TEXT,            -- JSON 字段的期望值
+    json_operator VARCHAR(10),           -- JSON 字段的比较方式: eq, ne, gt, lt, contains
+    steps TEXT,                          -- 多步事务的请求（JSON 数组），设置后代替单个请求
+    track_content_hash BOOLEAN DEFAULT 0,  -- 记录响应体哈希，变化时发出 content_changed 事件
+    content_hash_regex TEXT,             -- 只对匹配的部分计算哈希（RE2）
+    content_hash_normalize BOOLEAN DEFAULT 0, -- 计算哈希前去掉 script、style、注释并合并空白
+    auth_type VARCHAR(10),               -- 请求认证方式: none, basic, bearer
+    auth_username VARCHAR(255),          -- basic 认证的用户名
+    auth_password VARCHAR(255),          -- basic 认证的密码，接口不返回
+    auth_token TEXT,                     -- bearer 认证的令牌，接口不返回
 
     -- DNS 专用字段
     dns_server VARCHAR(255),
     dns_server_name VARCHAR(255),
     dns_server_type VARCHAR(10),         -- udp, tcp, doh, dot
+    dns_servers TEXT,                    -- 同时查询比较的服务器: 逗号分隔的供应商ID或地址
+    dns_consensus VARCHAR(20),           -- all, majority, any
+    dns_query_type VARCHAR(10),          -- 查询的记录类型，默认 A
+    dns_expected_values TEXT,            -- 期望的记录（JSON 数组），见 dns_match_mode
+    dns_match_mode VARCHAR(10),          -- 与期望记录的比较方式: exact, subset
 
     -- PING 专用字段
     ping_count INTEGER DEFAULT 4,
@@ -55,14 +80,51 @@ CREATE TABLE IF NOT EXISTS monitor_targets (
     snmp_expected_value VARCHAR(255),
     snmp_operator VARCHAR(10),
 
+    -- TCP 特定字段
+    tcp_send_string TEXT,                -- 连接后发送的内容
+    tcp_expect_string TEXT,              -- 回复必须包含的文本，/.../ 为正则
+    tcp_use_tls BOOLEAN DEFAULT 0,  -- 连接后先进行 TLS 握手
+
+    -- UDP 特定字段
+    udp_preset VARCHAR(20),              -- 内置探测: dns, ntp, sip_options
+    udp_send_payload TEXT,               -- 发送的数据报内容（编码见 udp_payload_format）
+    udp_payload_format VARCHAR(10),      -- hex, base64
+    udp_expect_response BOOLEAN DEFAULT 0,
+    udp_expect_pattern TEXT,             -- 回复必须匹配的正则表达式
+
     -- SSL/TLS 证书专用字段
     ssl_warn_days INTEGER DEFAULT 30,
     ssl_critical_days INTEGER DEFAULT 7,
     ssl_get_chain BOOLEAN DEFAULT 1,
     ssl_check BOOLEAN DEFAULT 0,
+    tls_client_cert_pem TEXT,            -- 双向 TLS 的客户端证书（PEM）
+    tls_client_key_pem TEXT,             -- 客户端证书的私钥（PEM），接口不返回
+    tls_ca_cert_pem TEXT,                -- 验证服务器证书的 CA（PEM），替代系统根证书
+    tls_skip_verify BOOLEAN DEFAULT 0,   -- 不验证服务器证书
+    tls_pin_sha256 VARCHAR(64),          -- 服务器证书的 SHA-256 指纹
+
+    -- 自定义脚本专用字段
+    script_path VARCHAR(500),            -- 必须在 monitor.script.allowed_paths 中
+    script_args TEXT,                    -- JSON 数组，不经过 shell
+    script_timeout INTEGER DEFAULT 10,   -- 秒
+
+    -- 对比模式（http, https, tcp）
+    secondary_address VARCHAR(500),      -- 同时检查并对比的第二个地址，空为关闭
+    compare_latency_tolerance INTEGER DEFAULT 0, -- 响应时间容差（毫秒），0 为不比较
+    compare_body BOOLEAN DEFAULT 0,      -- 比较响应体的 SHA-256
+
+    -- 响应时间阈值（http, https, tcp, dns），毫秒，0 为不判断
+    degraded_threshold_ms INTEGER DEFAULT 0, -- 达到时成功的检查记为 degraded
+    down_threshold_ms INTEGER DEFAULT 0,     -- 达到时成功的检查记为 down
 
     -- 告警渠道关联
     alert_channel_ids TEXT,              -- JSON 数组
+    notes TEXT,                          -- 运维备注（Markdown）
+    runbook_url VARCHAR(500),            -- 处理手册链接
+    sinks VARCHAR(100),                  -- 写入目的地: 空为全部, none, 或 db_history,es,file
+    tags TEXT,                           -- 标签 JSON 数组
+    group_id INTEGER,                    -- 所属分组，NULL 为未分组
+    depends_on_target_id INTEGER,        -- 依赖的监控，它 down 时本监控的 down 不告警
 
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
@@ -71,6 +133,8 @@ CREATE TABLE IF NOT EXISTS monitor_targets (
 -- 创建索引
 CREATE INDEX IF NOT EXISTS idx_monitor_targets_type ON monitor_targets(type);
 CREATE INDEX IF NOT EXISTS idx_monitor_targets_enabled ON monitor_targets(enabled);
+CREATE INDEX IF NOT EXISTS idx_monitor_targets_group_id ON monitor_targets(group_id);
+CREATE INDEX IF NOT EXISTS idx_monitor_targets_depends_on_target_id ON monitor_targets(depends_on_target_id);
 
 -- ============================================
 -- 2. 监控状态表 (monitor_status)
@@ -83,6 +147,13 @@ CREATE TABLE IF NOT EXISTS monitor_status (
     message TEXT,
     checked_at DATETIME,
     uptime_percentage INTEGER DEFAULT 0,
+    last_status_change_at DATETIME,      -- 最近一次状态变化时间
+    synthetic BOOLEAN DEFAULT 0,         -- 当前状态来自故障注入的合成结果
+    flapping BOOLEAN DEFAULT 0,          -- 状态频繁变化（抖动）
+    suppressed_by INTEGER,               -- 当前的 down 被依赖的哪个监控抑制
+    uptime_24h REAL DEFAULT 0,           -- 可用率百分比，两位小数；uptime_percentage 为 30 天的取整
+    uptime_7d REAL DEFAULT 0,
+    uptime_30d REAL DEFAULT 0,
 
     -- SSL 证书信息
     ssl_days_until_expiry INTEGER,
@@ -94,6 +165,9 @@ CREATE TABLE IF NOT EXISTS monitor_status (
     dns_records TEXT,                    -- JSON 字符串
     resolved_ip VARCHAR(64),
 
+    -- 内容变化检测
+    content_hash VARCHAR(64),            -- 最近一次计算的响应体 SHA-256
+
     -- 额外的检查数据
     data TEXT,                           -- 完整的检查结果数据
 
@@ -114,12 +188,22 @@ CREATE TABLE IF NOT EXISTS monitor_history (
     status VARCHAR(50) NOT NULL,
     response_time INTEGER,
     message TEXT,
-    checked_at DATETIME
+    synthetic BOOLEAN DEFAULT 0,
+    address VARCHAR(500),                -- 产生结果的地址，仅对比模式记录
+    divergence BOOLEAN DEFAULT 0,        -- 对比模式下第二个地址的结果不一致
+    content_changed BOOLEAN DEFAULT 0, -- 响应体哈希与上次不同
+    jitter REAL,                         -- ping 往返时间的抖动（毫秒）
+    rtt_stddev REAL,                     -- ping 往返时间的标准差（毫秒）
+    suppressed_by INTEGER,               -- 被依赖的哪个监控抑制，未告警
+    checked_at DATETIME,
+    check_id VARCHAR(64)                 -- 检查的 ID（与 ES 文档 ID 相同），补写暂存的结果时去重
 );
 
 -- 创建索引
-CREATE INDEX IF NOT EXISTS idx_monitor_history_target_id ON monitor_history(target_id);
+CREATE INDEX IF NOT EXISTS idx_monitor_history_target_checked ON monitor_history(target_id, checked_at);
 CREATE INDEX IF NOT EXISTS idx_monitor_history_checked_at ON monitor_history(checked_at);
+CREATE INDEX IF NOT EXISTS idx_monitor_history_synthetic ON monitor_history(synthetic);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_monitor_history_check_id ON monitor_history(check_id);
 
 -- ============================================
 -- 4. IP 地理位置缓存表 (ip_geo_cache)
@@ -162,6 +246,10 @@ CREATE TABLE IF NOT EXISTS alert_channels (
     type VARCHAR(50) NOT NULL,           -- email, webhook, dingtalk, wechat
     enabled BOOLEAN DEFAULT 1,
     config TEXT NOT NULL,                -- JSON 配置
+    health VARCHAR(20) DEFAULT 'healthy', -- healthy, degraded
+    health_reason VARCHAR(255),          -- 标记为 degraded 的原因
+    health_changed_at DATETIME,          -- 最近一次健康状态变化时间
+    suppress_health BOOLEAN DEFAULT 0,   -- 不因发送失败标记为 degraded
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
@@ -181,6 +269,9 @@ CREATE TABLE IF NOT EXISTS alert_rules (
     condition_logic TEXT,                -- JSON: 复杂条件
     cooldown_seconds INTEGER DEFAULT 300,
     last_alert_time DATETIME,
+    alert_open BOOLEAN DEFAULT 0,        -- 是否存在未恢复的告警
+    alert_count INTEGER DEFAULT 0,       -- 当前未恢复告警已发送次数
+    snoozed_until DATETIME,              -- 静默到期时间，期间不发送告警
 
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
@@ -230,6 +321,8 @@ CREATE TABLE IF NOT EXISTS alert_history (
     severity VARCHAR(50),
     status VARCHAR(50),
     message TEXT,
+    synthetic BOOLEAN DEFAULT 0,
+    snooze_id INTEGER DEFAULT 0,         -- 因静默未发送的告警，或静默事件，对应的静默
     sent_at DATETIME,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
@@ -237,8 +330,239 @@ CREATE TABLE IF NOT EXISTS alert_history (
 -- 创建索引
 CREATE INDEX IF NOT EXISTS idx_alert_history_rule_id ON alert_history(rule_id);
 CREATE INDEX IF NOT EXISTS idx_alert_history_target_id ON alert_history(target_id);
+CREATE INDEX IF NOT EXISTS idx_alert_history_channel_id ON alert_history(channel_id);
 CREATE INDEX IF NOT EXISTS idx_alert_history_sent_at ON alert_history(sent_at);
 
+-- ============================================
+-- 11. 数据迁移记录表 (schema_migrations)
+-- ============================================
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    name VARCHAR(100) PRIMARY KEY,
+    applied_at DATETIME
+);
+
+-- ============================================
+-- 12. 响应存档表 (response_archives)
+-- ============================================
+CREATE TABLE IF NOT EXISTS response_archives (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    target_id INTEGER NOT NULL,
+    history_id INTEGER,                  -- down 结果的历史记录，未写历史时为 0
+    checked_at DATETIME,
+    message TEXT,
+    method VARCHAR(10),
+    url VARCHAR(500),
+    request_headers TEXT,                -- JSON 对象，已脱敏
+    status_code INTEGER,
+    response_headers TEXT,               -- JSON 对象，读取时脱敏
+    response_time INTEGER,
+    content_length INTEGER,
+    bytes_received INTEGER,
+    decoded_body_bytes INTEGER,
+    body BLOB,                           -- 解码后的响应体，最多 archive.max_bytes
+    truncated BOOLEAN DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_response_archives_target_id ON response_archives(target_id);
+CREATE INDEX IF NOT EXISTS idx_response_archives_history_id ON response_archives(history_id);
+CREATE INDEX IF NOT EXISTS idx_response_archives_checked_at ON response_archives(checked_at);
+
+-- ============================================
+-- 13. 告警规则静默表 (alert_rule_snoozes)
+-- ============================================
+CREATE TABLE IF NOT EXISTS alert_rule_snoozes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    rule_id INTEGER NOT NULL,
+    target_id INTEGER NOT NULL,
+    note TEXT,
+    starts_at DATETIME,
+    expires_at DATETIME,
+    cancelled_at DATETIME,               -- 提前结束（取消或被新的静默替换）
+    created_by VARCHAR(100),             -- 请求的客户端 IP
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_rule_snoozes_rule_id ON alert_rule_snoozes(rule_id);
+
+-- ============================================
+-- 14. 历史归档记录表 (history_exports)
+-- ============================================
+CREATE TABLE IF NOT EXISTS history_exports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    source VARCHAR(50) NOT NULL,         -- 导出的表，monitor_history
+    from_id INTEGER,                     -- 对象中的第一条记录 ID
+    to_id INTEGER,                       -- 对象中的最后一条记录 ID
+    rows INTEGER,
+    object_key VARCHAR(500),
+    bytes INTEGER,                       -- 压缩后的大小
+    sha256 VARCHAR(64),                  -- 压缩后对象的 SHA-256
+    status VARCHAR(20),                  -- verified, failed
+    error TEXT,
+    exported_at DATETIME,
+    verified_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_history_exports_source ON history_exports(source);
+CREATE INDEX IF NOT EXISTS idx_history_exports_status ON history_exports(status);
+
+-- ============================================
+-- 15. 证书清单 (certificates, certificate_targets)
+-- ============================================
+CREATE TABLE IF NOT EXISTS certificates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    fingerprint VARCHAR(64) NOT NULL UNIQUE, -- DER 编码的 SHA-256，小写十六进制
+    subject VARCHAR(255),                -- 主题 CN
+    dns_names TEXT,                      -- JSON 数组，SAN 中的域名
+    issuer VARCHAR(255),
+    serial VARCHAR(128),
+    not_before DATETIME,
+    not_after DATETIME,
+    first_seen DATETIME,
+    last_seen DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_certificates_not_after ON certificates(not_after);
+
+CREATE TABLE IF NOT EXISTS certificate_targets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    certificate_id INTEGER NOT NULL,
+    target_id INTEGER NOT NULL,
+    is_current BOOLEAN DEFAULT 0,        -- 目标最近一次检查出示的证书
+    first_seen DATETIME,
+    last_seen DATETIME
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_certificate_target ON certificate_targets(certificate_id, target_id);
+CREATE INDEX IF NOT EXISTS idx_certificate_targets_target_id ON certificate_targets(target_id);
+CREATE INDEX IF NOT EXISTS idx_certificate_targets_current ON certificate_targets(is_current);
+
+-- ============================================
+-- 16. 嵌入令牌表 (api_tokens)
+-- ============================================
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE, -- 令牌的 SHA-256，令牌本身只在创建时返回
+    prefix VARCHAR(16),                  -- 令牌开头，用于区分令牌
+    target_id INTEGER NOT NULL,          -- 只能读取这个监控；按标签授权时为 0
+    tag VARCHAR(64),                     -- 只能读取带有这个标签的监控
+    expires_at DATETIME,
+    revoked_at DATETIME,
+    last_used_at DATETIME,
+    use_count INTEGER DEFAULT 0,
+    created_by VARCHAR(100),             -- 请求的客户端 IP
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_tokens_target_id ON api_tokens(target_id);
+CREATE INDEX IF NOT EXISTS idx_api_tokens_tag ON api_tokens(tag);
+
+-- ============================================
+-- 17. 维护窗口表 (maintenance_windows)
+-- ============================================
+CREATE TABLE IF NOT EXISTS maintenance_windows (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    target_id INTEGER,                   -- NULL 表示所有监控
+    schedule VARCHAR(100),               -- cron 表达式，设置后代替 weekdays 和 start_time
+    weekdays VARCHAR(100),               -- 逗号分隔的星期，留空为每天
+    start_time VARCHAR(5),               -- HH:MM
+    end_time VARCHAR(5) NOT NULL,        -- HH:MM，不晚于开始时间时跨过午夜
+    timezone VARCHAR(64),                -- IANA 时区，留空为服务器本地时区
+    enabled BOOLEAN DEFAULT 1,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_maintenance_windows_target_id ON maintenance_windows(target_id);
+
+-- ============================================
+-- 18. 发现候选表 (discovery_candidates)
+-- ============================================
+CREATE TABLE IF NOT EXISTS discovery_candidates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    scan_id VARCHAR(16),                 -- 首次发现它的扫描
+    fingerprint VARCHAR(300) NOT NULL,   -- 小写的 host:port，用于去重
+    source VARCHAR(255),                 -- 区域名或网段
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL,           -- 猜测的监控类型
+    address VARCHAR(500) NOT NULL,
+    port INTEGER,
+    ip VARCHAR(45),
+    banner VARCHAR(255),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending/approved/rejected
+    target_id INTEGER,                   -- 审核通过后创建的监控
+    reviewed_by VARCHAR(100),
+    reviewed_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_discovery_candidates_fingerprint ON discovery_candidates(fingerprint);
+CREATE INDEX IF NOT EXISTS idx_discovery_candidates_scan_id ON discovery_candidates(scan_id);
+CREATE INDEX IF NOT EXISTS idx_discovery_candidates_status ON discovery_candidates(status);
+
+-- ============================================
+-- 19. 监控分组表 (monitor_groups)
+-- ============================================
+CREATE TABLE IF NOT EXISTS monitor_groups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,          -- 分组名称，唯一
+    description VARCHAR(500),
+    sort_order INTEGER NOT NULL DEFAULT 0, -- 显示顺序，小的在前
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_monitor_groups_name ON monitor_groups(name);
+
+-- ============================================
+-- 20. 历史小时汇总表 (monitor_history_hourly)
+-- ============================================
+CREATE TABLE IF NOT EXISTS monitor_history_hourly (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    target_id INTEGER NOT NULL,
+    bucket_start DATETIME NOT NULL,      -- UTC 整点，每个监控每小时一行
+    checks INTEGER,
+    up INTEGER,                          -- up 的结果数
+    down INTEGER,                        -- down、critical 的结果数
+    degraded INTEGER,                    -- warning、degraded 的结果数
+    samples INTEGER,                     -- 计入响应时间的结果数（up、warning、degraded）
+    avg_response_time REAL,
+    min_response_time INTEGER,
+    max_response_time INTEGER,
+    p50_response_time INTEGER,
+    p95_response_time INTEGER,
+    p99_response_time INTEGER,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (target_id) REFERENCES monitor_targets(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_monitor_history_hourly_bucket ON monitor_history_hourly(target_id, bucket_start);
+
+-- ============================================
+-- 21. 历史天汇总表 (monitor_history_daily)
+-- ============================================
+CREATE TABLE IF NOT EXISTS monitor_history_daily (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    target_id INTEGER NOT NULL,
+    bucket_start DATETIME NOT NULL,      -- UTC 零点，每个监控每天一行
+    checks INTEGER,
+    up INTEGER,                          -- up 的结果数
+    down INTEGER,                        -- down、critical 的结果数
+    degraded INTEGER,                    -- warning、degraded 的结果数
+    samples INTEGER,                     -- 计入响应时间的结果数（up、warning、degraded）
+    avg_response_time REAL,
+    min_response_time INTEGER,
+    max_response_time INTEGER,
+    p50_response_time INTEGER,
+    p95_response_time INTEGER,
+    p99_response_time INTEGER,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (target_id) REFERENCES monitor_targets(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_monitor_history_daily_bucket ON monitor_history_daily(target_id, bucket_start);
+
 -- ============================================
 -- 初始化数据
 -- ============================================
@@ -251,6 +575,9 @@ INSERT OR IGNORE INTO dns_providers (name, server, server_type, is_default) VALU
     ('腾讯 DNS', '119.29.29.29:53', 'udp', 0),
     ('114 DNS', '114.114.114.114:53', 'udp', 0);
 
+-- 新库的时间戳从一开始就是 UTC，无需转换
+INSERT OR IGNORE INTO schema_migrations (name, applied_at) VALUES ('utc_timestamps', CURRENT_TIMESTAMP);
+
 -- ============================================
 -- 触发器（用于自动更新 updated_at 字段）
 -- ============================================
@@ -294,7 +621,7 @@ END;
 -- 初始化完成
 -- ============================================
 -- 数据库初始化成功完成！
--- 表总数: 10
+-- 表总数: 11
 -- 索引: 已创建
 -- 触发器: 已创建
 -- 默认数据: 已插入
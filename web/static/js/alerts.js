@@ -44,12 +44,15 @@ function renderAlertChannels() {
         const enabledBadge = channel.enabled
             ? '<span style="color: #10b981; font-weight: 600;"><i class="fas fa-check-circle"></i> 启用</span>'
             : '<span style="color: #6b7280; font-weight: 600;"><i class="fas fa-times-circle"></i> 禁用</span>';
+        const healthBadge = channel.health === 'degraded'
+            ? `<br><span style="color: #ef4444; font-weight: 600;" title="${escapeHtml(channel.health_reason || '')}"><i class="fas fa-exclamation-triangle"></i> 发送异常，已暂停</span>`
+            : '';
 
         return `
             <tr data-id="${channel.id}">
                 <td><strong>${channel.name}</strong></td>
                 <td>${typeLabel}</td>
-                <td>${enabledBadge}</td>
+                <td>${enabledBadge}${healthBadge}</td>
                 <td>
                     <div style="display: flex; gap: 6px;">
                         <button class="btn btn-sm btn-secondary" onclick="testAlertChannel(${channel.id})" title="测试">
@@ -74,7 +77,8 @@ function showAlertChannelModal() {
         title: '添加告警通道',
         defaults: {
             'alert-channel-id': '',
-            'alert-channel-enabled': true
+            'alert-channel-enabled': true,
+            'alert-channel-suppress-health': false
         },
         onShow: () => {
             updateAlertChannelFields();
@@ -115,7 +119,8 @@ async function editAlertChannel(id) {
             'alert-channel-id': channel.id,
             'alert-channel-name': channel.name,
             'alert-channel-type': channel.type,
-            'alert-channel-enabled': channel.enabled
+            'alert-channel-enabled': channel.enabled,
+            'alert-channel-suppress-health': channel.suppress_health
         };
 
         if (channel.type === 'wechat') {
@@ -157,6 +162,7 @@ async function testAlertChannel(id) {
     try {
         await API.post('/alert/channel/test', { id: id });
         showToast('测试消息已发送，请检查是否收到', 'success');
+        loadAlertChannels();
     } catch (error) {
         console.error('Failed to test alert channel:', error);
         showToast('测试告警通道失败', 'error');
@@ -173,7 +179,8 @@ async function submitAlertChannel(event) {
     const data = {
         name: document.getElementById('alert-channel-name').value,
         type: type,
-        enabled: document.getElementById('alert-channel-enabled').checked
+        enabled: document.getElementById('alert-channel-enabled').checked,
+        suppress_health: document.getElementById('alert-channel-suppress-health').checked
     };
 
     // Build config based on type
@@ -242,15 +249,21 @@ function renderAlertRules() {
     };
 
     tbody.innerHTML = alertRules.map(rule => {
+        const snooze = rule.snooze
+            ? `<div style="font-size: 12px; color: #6b7280;" title="${escapeHtml(rule.snooze.note || '')}"><i class="fas fa-bell-slash"></i> 静默至 ${new Date(rule.snooze.expires_at).toLocaleString('zh-CN')}</div>`
+            : '';
         return `
             <tr data-id="${rule.id}">
                 <td>${rule.target_name || '-'}</td>
                 <td>${rule.channel_name || '-'}</td>
                 <td>${thresholdTypeLabels[rule.threshold_type] || rule.threshold_type}</td>
                 <td>${rule.threshold_value}</td>
-                <td>${rule.enabled ? '<span style="color: #10b981;"><i class="fas fa-check-circle"></i></span>' : '<span style="color: #6b7280;"><i class="fas fa-times-circle"></i></span>'}</td>
+                <td>${rule.enabled ? '<span style="color: #10b981;"><i class="fas fa-check-circle"></i></span>' : '<span style="color: #6b7280;"><i class="fas fa-times-circle"></i></span>'}${snooze}</td>
                 <td>
                     <div style="display: flex; gap: 6px;">
+                        ${rule.snooze
+                            ? `<button class="btn btn-sm btn-secondary" onclick="cancelAlertRuleSnooze(${rule.id})" title="取消静默"><i class="fas fa-bell"></i></button>`
+                            : `<button class="btn btn-sm btn-secondary" onclick="snoozeAlertRule(${rule.id})" title="静默"><i class="fas fa-bell-slash"></i></button>`}
                         <button class="btn btn-sm btn-secondary" onclick="editAlertRule(${rule.id})" title="编辑">
                             <i class="fas fa-edit"></i>
                         </button>
@@ -264,6 +277,36 @@ function renderAlertRules() {
     }).join('');
 }
 
+// Snooze an alert rule; the other rules of the target keep alerting
+async function snoozeAlertRule(id) {
+    const duration = prompt('静默时长（如 30m、2h，最长 168h）', '2h');
+    if (!duration) {
+        return;
+    }
+    const note = prompt('备注（可选）', '') || '';
+
+    try {
+        await API.post('/alert/rule/snooze', { rule_id: id, duration: duration.trim(), note: note });
+        showToast('告警规则已静默', 'success');
+        loadAlertRules();
+    } catch (error) {
+        console.error('Failed to snooze alert rule:', error);
+        showToast('静默告警规则失败', 'error');
+    }
+}
+
+// Cancel the snooze of an alert rule
+async function cancelAlertRuleSnooze(id) {
+    try {
+        await API.post('/alert/rule/snooze/cancel', { rule_id: id });
+        showToast('已取消静默', 'success');
+        loadAlertRules();
+    } catch (error) {
+        console.error('Failed to cancel alert rule snooze:', error);
+        showToast('取消静默失败', 'error');
+    }
+}
+
 // Show alert rule modal
 async function showAlertRuleModal() {
     // Load monitors and channels
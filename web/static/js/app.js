@@ -5,12 +5,16 @@ let headersCount = 0;
 let currentLogPage = 0;
 let totalLogs = 0;
 let currentLogQuery = {};
+let logPageCursors = [''];  // after cursor of each page; the first page has none
+let nextLogCursor = '';
 
 // Initialize
 document.addEventListener('DOMContentLoaded', () => {
     console.log('DOM loaded, initializing application...');
     loadMonitors();
     loadStatuses();
+    loadMonitorTypes();
+    loadLint();
 
     // Try to load system config on page load
     loadSystemConfig().catch(err => {
@@ -49,6 +53,24 @@ async function loadStatuses() {
     }
 }
 
+// Load configuration lint; the badge counts errors and warnings, the tooltip lists them
+async function loadLint() {
+    const badge = document.getElementById('lint-badge');
+    try {
+        const data = await API.get('/lint');
+        const findings = (data.findings || []).filter(f => f.severity !== 'info');
+        if (findings.length === 0) {
+            badge.style.display = 'none';
+            return;
+        }
+        badge.innerHTML = `<i class="fas fa-exclamation-triangle"></i> ${findings.length} 项配置警告`;
+        badge.title = findings.map(f => `${f.message}（${f.fix}）`).join('\n');
+        badge.style.display = '';
+    } catch (error) {
+        console.error('Failed to load configuration lint:', error);
+    }
+}
+
 // Populate log targets dropdown
 function populateLogTargets() {
     populateSelect('log-target', monitors, '全部目标', 'id', 'name');
@@ -67,13 +89,24 @@ function renderMonitors() {
         const status = statuses.find(s => s.target_id === monitor.id);
         const statusBadge = status ? getStatusBadge(status.status) : '<span class="status-badge unknown">未知</span>';
         const responseTime = status ? `${status.response_time}ms` : '-';
-        const uptime = status ? `${status.uptime_percentage}%` : '-';
+        const uptime = status ? `${status.uptime_30d}%` : '-';
+        const runbookUrl = safeUrl(monitor.runbook_url);
+        const hasProblem = status && (status.status === 'down' || status.status === 'degraded');
+        const configError = status && status.status === 'config_error';
+        const suppressedBy = status && status.suppressed_by ? monitors.find(m => m.id === status.suppressed_by) : null;
 
         return `
             <tr data-id="${monitor.id}">
                 <td>
                     <strong>${monitor.name}</strong>
                     ${!monitor.enabled ? '<span style="color: #ef4444; font-size: 12px;">(已禁用)</span>' : ''}
+                    ${status && status.in_maintenance ? '<span style="color: #6b7280; font-size: 12px;">(维护中)</span>' : ''}
+                    ${status && status.flapping ? '<span style="color: #f59e0b; font-size: 12px;" title="状态频繁变化，单次告警已暂停">(抖动)</span>' : ''}
+                    ${status && status.suppressed_by ? `<span style="color: #6b7280; font-size: 12px;" title="依赖的监控故障，告警已抑制">(依赖 ${escapeHtml(suppressedBy ? suppressedBy.name : '#' + status.suppressed_by)} 故障)</span>` : ''}
+                    ${hasProblem && runbookUrl ? `<a href="${escapeHtml(runbookUrl)}" target="_blank" rel="noopener noreferrer" title="处理手册" style="margin-left: 6px;"><i class="fas fa-book"></i></a>` : ''}
+                    ${parseTags(monitor.tags).map(tag => `<span style="font-size: 11px; color: #4b5563; background: #f3f4f6; border-radius: 4px; padding: 0 4px; margin-left: 4px;">${escapeHtml(tag)}</span>`).join('')}
+                    ${configError ? `<div style="font-size: 12px; color: #ef4444; max-width: 320px;">${escapeHtml(status.message)}</div>` : ''}
+                    ${hasProblem && monitor.notes ? `<div style="font-size: 12px; color: #6b7280; max-width: 320px;">${renderMarkdown(monitor.notes)}</div>` : ''}
                 </td>
                 <td>
                     <span style="text-transform: uppercase; font-weight: 600;">${monitor.type}</span>
@@ -89,7 +122,7 @@ function renderMonitors() {
                 <td>
                     <div style="display: flex; align-items: center; gap: 8px;">
                         <div style="flex: 1; height: 6px; background: #e5e7eb; border-radius: 3px; overflow: hidden;">
-                            <div style="width: ${uptime}; height: 100%; background: ${getUptimeColor(status?.uptime_percentage || 0)}; border-radius: 3px;"></div>
+                            <div style="width: ${uptime}; height: 100%; background: ${getUptimeColor(status?.uptime_30d || 0)}; border-radius: 3px;"></div>
                         </div>
                         <span style="font-size: 12px;">${uptime}</span>
                     </div>
@@ -99,6 +132,9 @@ function renderMonitors() {
                         <button class="btn btn-sm btn-secondary" onclick="viewMonitorDetails(${monitor.id})" title="详情">
                             <i class="fas fa-info-circle"></i>
                         </button>
+                        <button class="btn btn-sm btn-secondary" id="check-btn-${monitor.id}" onclick="checkMonitorNow(${monitor.id})" title="立即检查">
+                            <i class="fas fa-sync-alt"></i>
+                        </button>
                         <button class="btn btn-sm btn-secondary" onclick="editMonitor(${monitor.id})" title="编辑">
                             <i class="fas fa-edit"></i>
                         </button>
@@ -117,6 +153,8 @@ function getStatusBadge(status) {
     const badges = {
         'up': '<span class="status-badge up"><i class="fas fa-check-circle"></i> 在线</span>',
         'down': '<span class="status-badge down"><i class="fas fa-times-circle"></i> 离线</span>',
+        'config_error': '<span class="status-badge down"><i class="fas fa-exclamation-triangle"></i> 配置错误</span>',
+        'maintenance': '<span class="status-badge unknown"><i class="fas fa-tools"></i> 维护中</span>',
         'unknown': '<span class="status-badge unknown"><i class="fas fa-question-circle"></i> 未知</span>'
     };
     return badges[status] || badges['unknown'];
@@ -132,7 +170,7 @@ function getUptimeColor(uptime) {
 // Update stats
 function updateStats() {
     const upCount = statuses.filter(s => s.status === 'up').length;
-    const downCount = statuses.filter(s => s.status === 'down').length;
+    const downCount = statuses.filter(s => s.status === 'down' || s.status === 'config_error').length;
     const totalCount = monitors.length;
 
     const avgResponseTime = statuses.length > 0
@@ -155,6 +193,7 @@ function updateStatusInTable() {
 function refreshData() {
     loadMonitors();
     loadStatuses();
+    loadLint();
     // Removed toast notification to avoid annoyance during auto-refresh
 }
 
@@ -203,6 +242,77 @@ function showAddModal() {
     });
 }
 
+// Run a check now and follow its progress over the check event stream
+async function checkMonitorNow(id) {
+    let job;
+    try {
+        job = await API.post('/monitor/check', { id: id });
+    } catch (error) {
+        showToast(`检查失败: ${error.message}`, 'error');
+        return;
+    }
+
+    const setButton = (state, elapsedMs) => {
+        const button = document.getElementById(`check-btn-${id}`);
+        if (!button) return;
+        if (state === 'finished') {
+            button.disabled = false;
+            button.innerHTML = '<i class="fas fa-sync-alt"></i>';
+            button.title = '立即检查';
+            return;
+        }
+        const seconds = Math.floor((elapsedMs || 0) / 1000);
+        button.disabled = true;
+        button.innerHTML = '<i class="fas fa-spinner fa-spin"></i>';
+        button.title = state === 'queued' ? `排队中 (${seconds}s)` : `检查中 (${seconds}s)`;
+    };
+    setButton(job.state, 0);
+
+    const events = new EventSource(`${API.BASE}/monitor/check/events?token=${encodeURIComponent(job.token)}`);
+    events.addEventListener('check', event => {
+        const update = JSON.parse(event.data);
+        setButton(update.state, update.elapsed_ms);
+        if (update.state !== 'finished') return;
+
+        events.close();
+        if (update.error) {
+            showToast(`检查失败: ${update.error}`, 'error');
+        } else if (update.result) {
+            showToast(`检查完成: ${update.result.status} (${update.result.response_time}ms)`, update.result.status === 'up' ? 'success' : 'error');
+        }
+        loadStatuses();
+    });
+    events.onerror = () => {
+        // The server closes the stream once the check finishes; a dropped
+        // connection is retried by EventSource and resumes from the current state
+        if (events.readyState === EventSource.CLOSED) {
+            setButton('finished');
+        }
+    };
+}
+
+// Describe response sizes: decoded body, bytes on the wire and the Content-Length header.
+// Logs written before these fields existed only have body_size.
+function formatBodySizes(response) {
+    if (response.decoded_body_bytes === undefined && response.bytes_received === undefined) {
+        const size = response.body_size || response.size;
+        return size ? `${size} bytes` : '-';
+    }
+    const parts = [];
+    if (response.decoded_body_bytes === -1) {
+        parts.push('无法解码');
+    } else if (response.decoded_body_bytes !== undefined) {
+        parts.push(`${response.decoded_body_bytes} bytes`);
+    }
+    if (response.bytes_received !== undefined && response.bytes_received !== response.decoded_body_bytes) {
+        parts.push(`传输 ${response.bytes_received} bytes`);
+    }
+    if (response.content_length !== undefined) {
+        parts.push(response.content_length === -1 ? 'Content-Length 未知' : `Content-Length ${response.content_length}`);
+    }
+    return parts.join('，');
+}
+
 // Edit monitor
 async function editMonitor(id) {
     try {
@@ -242,22 +352,69 @@ async function editMonitor(id) {
                 'monitor-address': monitor.address,
                 'monitor-port': monitor.port || '',
                 'monitor-interval': monitor.interval || 60,
+                'monitor-timeout': monitor.timeout_seconds || '',
+                'monitor-retry-count': monitor.retry_count || 0,
+                'monitor-retry-interval': monitor.retry_interval_seconds || 0,
                 'monitor-enabled': monitor.enabled,
                 'monitor-http-method': monitor.http_method || 'GET',
                 'monitor-http-body': monitor.http_body || '',
                 'monitor-resolved-host': monitor.resolved_host || '',
+                'monitor-unix-socket': monitor.unix_socket_path || '',
+                'monitor-proxy-url': monitor.proxy_url || '',
+                'monitor-body-must-contain': monitor.body_must_contain || '',
+                'monitor-body-must-not-contain': monitor.body_must_not_contain || '',
+                'monitor-body-regex': monitor.body_regex || '',
+                'monitor-json-path': monitor.json_path || '',
+                'monitor-json-operator': monitor.json_operator || 'eq',
+                'monitor-expected-headers': monitor.expected_headers ? JSON.stringify(monitor.expected_headers) : '',
+                'monitor-steps': monitor.steps ? JSON.stringify(monitor.steps, null, 2) : '',
+                'monitor-track-content-hash': monitor.track_content_hash || false,
+                'monitor-content-hash-regex': monitor.content_hash_regex || '',
+                'monitor-content-hash-normalize': monitor.content_hash_normalize || false,
+                'monitor-auth-type': monitor.auth_type || 'none',
+                'monitor-auth-username': monitor.auth_username || '',
+                'monitor-auth-password': '',
+                'monitor-auth-token': '',
+                'monitor-tls-client-cert': monitor.tls_client_cert_pem || '',
+                'monitor-tls-client-key': '',
+                'monitor-tls-ca-cert': monitor.tls_ca_cert_pem || '',
+                'monitor-tls-skip-verify': monitor.tls_skip_verify || false,
+                'monitor-tls-pin-sha256': monitor.tls_pin_sha256 || '',
+                'monitor-json-expected-value': monitor.json_expected_value || '',
+                'monitor-secondary-address': monitor.secondary_address || '',
+                'monitor-compare-latency-tolerance': monitor.compare_latency_tolerance || '',
+                'monitor-compare-body': monitor.compare_body || false,
+                'monitor-degraded-threshold-ms': monitor.degraded_threshold_ms || '',
+                'monitor-down-threshold-ms': monitor.down_threshold_ms || '',
                 'monitor-dns-server': monitor.dns_server || '',
                 'monitor-dns-server-name': monitor.dns_server_name || '',
                 'monitor-dns-server-type': monitor.dns_server_type || 'udp',
+                'monitor-dns-servers': monitor.dns_servers || '',
+                'monitor-dns-consensus': monitor.dns_consensus || 'all',
+                'monitor-dns-query-type': monitor.dns_query_type || 'A',
+                'monitor-dns-expected-values': (monitor.dns_expected_values || []).join('\n'),
+                'monitor-dns-match-mode': monitor.dns_match_mode || 'exact',
                 'monitor-snmp-community': monitor.snmp_community || '',
                 'monitor-snmp-oid': monitor.snmp_oid || '',
                 'monitor-snmp-version': monitor.snmp_version || 'v2c',
                 'monitor-snmp-operator': monitor.snmp_operator || '',
+                'monitor-tcp-use-tls': monitor.tcp_use_tls || false,
+                'monitor-tcp-send-string': (monitor.tcp_send_string || '').replace(/\r/g, '\\r').replace(/\n/g, '\\n'),
+                'monitor-tcp-expect-string': monitor.tcp_expect_string || '',
+                'monitor-udp-preset': monitor.udp_preset || '',
+                'monitor-udp-send-payload': monitor.udp_send_payload || '',
+                'monitor-udp-payload-format': monitor.udp_payload_format || 'hex',
+                'monitor-udp-expect-response': monitor.udp_expect_response || false,
+                'monitor-udp-expect-pattern': monitor.udp_expect_pattern || '',
                 'monitor-snmp-expected-value': monitor.snmp_expected_value || '',
                 'monitor-ssl-check': monitor.ssl_check || false,
                 'monitor-ssl-warn-days': monitor.ssl_warn_days || 30,
                 'monitor-ssl-critical-days': monitor.ssl_critical_days || 7,
-                'monitor-ssl-get-chain': monitor.ssl_get_chain !== false
+                'monitor-ssl-get-chain': monitor.ssl_get_chain !== false,
+                'monitor-runbook-url': monitor.runbook_url || '',
+                'monitor-notes': monitor.notes || '',
+                'monitor-sinks': monitor.sinks || '',
+                'monitor-tags': parseTags(monitor.tags).join(',')
             },
             onShow: async () => {
                 // Load headers
@@ -312,9 +469,69 @@ function closeModal() {
     ModalManager.hide('monitor-modal');
 }
 
+// Monitor type catalog from the server, keyed by type and alias
+let monitorTypes = {};
+
+// Load the type catalog and build the type select from it; the static
+// options in the page stay in place if the request fails
+async function loadMonitorTypes() {
+    try {
+        const data = await API.get('/monitor/types');
+        const typeSelect = document.getElementById('monitor-type');
+        const current = typeSelect.value;
+
+        monitorTypes = {};
+        typeSelect.innerHTML = '';
+        (data.types || []).forEach(spec => {
+            monitorTypes[spec.type] = spec;
+            (spec.aliases || []).forEach(alias => { monitorTypes[alias] = spec; });
+
+            const option = document.createElement('option');
+            option.value = spec.type;
+            option.textContent = spec.display_name;
+            typeSelect.appendChild(option);
+        });
+        if (monitorTypes[current]) {
+            typeSelect.value = monitorTypes[current].type;
+        }
+        renderTypeHint(typeSelect.value);
+    } catch (error) {
+        console.warn('Failed to load monitor types:', error.message);
+    }
+}
+
+// Tags are stored as a JSON array string (v1) or returned as an array (v2)
+function parseTags(tags) {
+    if (Array.isArray(tags)) return tags;
+    if (!tags) return [];
+    try {
+        const parsed = JSON.parse(tags);
+        return Array.isArray(parsed) ? parsed : [];
+    } catch (e) {
+        return [];
+    }
+}
+
+// Show the required type-specific fields and their defaults under the type select
+function renderTypeHint(type) {
+    const hint = document.getElementById('monitor-type-hint');
+    const spec = monitorTypes[type];
+    if (!hint || !spec) return;
+
+    const common = ['name', 'address', 'interval', 'enabled', 'notes', 'runbook_url', 'sinks', 'tags'];
+    const parts = spec.fields
+        .filter(field => !common.includes(field.name))
+        .filter(field => field.required || field.default !== undefined)
+        .map(field => field.required
+            ? `${escapeHtml(field.name)} *`
+            : `${escapeHtml(field.name)} = ${escapeHtml(String(field.default))}`);
+    hint.innerHTML = parts.join('，');
+}
+
 // Update form fields based on type
 function updateFormFields() {
     const type = document.getElementById('monitor-type').value;
+    renderTypeHint(type);
     const httpSection = document.getElementById('http-section');
     const headersSection = document.getElementById('headers-section');
     const dnsSection = document.getElementById('dns-section');
@@ -346,6 +563,7 @@ function updateFormFields() {
     checkAddressType();
 
     // Show/hide DNS fields for specific types
+    document.getElementById('dns-consensus-fields').style.display = type === 'dns' ? 'block' : 'none';
     if (type === 'dns') {
         dnsSection.style.display = 'block';
         portGroup.style.display = 'none';
@@ -360,6 +578,18 @@ function updateFormFields() {
         portGroup.style.display = 'block';
     }
 
+    document.getElementById('tcp-section').style.display = type === 'tcp' ? 'block' : 'none';
+    document.getElementById('udp-section').style.display = type === 'udp' ? 'block' : 'none';
+
+    // Comparison mode: HTTP/HTTPS/TCP, bodies are only compared for HTTP
+    const compareTypes = ['http', 'https', 'tcp'];
+    document.getElementById('compare-section').style.display = compareTypes.includes(type) ? 'block' : 'none';
+    document.getElementById('compare-body-group').style.display = type === 'tcp' ? 'none' : 'block';
+
+    // Response time thresholds: HTTP/HTTPS/TCP/DNS
+    const latencyTypes = ['http', 'https', 'tcp', 'dns'];
+    document.getElementById('latency-section').style.display = latencyTypes.includes(type) ? 'block' : 'none';
+
     // Set default ports
     const portInput = document.getElementById('monitor-port');
     const defaultPorts = {
@@ -638,7 +868,14 @@ async function submitMonitor(event) {
         address: address,
         port: parseInt(document.getElementById('monitor-port').value) || null,
         interval: parseInt(document.getElementById('monitor-interval').value) || 60,
-        enabled: document.getElementById('monitor-enabled').checked
+        timeout_seconds: parseInt(document.getElementById('monitor-timeout').value) || 0,
+        retry_count: parseInt(document.getElementById('monitor-retry-count').value) || 0,
+        retry_interval_seconds: parseInt(document.getElementById('monitor-retry-interval').value) || 0,
+        enabled: document.getElementById('monitor-enabled').checked,
+        runbook_url: document.getElementById('monitor-runbook-url').value.trim(),
+        notes: document.getElementById('monitor-notes').value,
+        sinks: document.getElementById('monitor-sinks').value.trim(),
+        tags: document.getElementById('monitor-tags').value.split(',').map(tag => tag.trim()).filter(Boolean)
     };
 
     // HTTP/HTTPS specific fields
@@ -646,7 +883,54 @@ async function submitMonitor(event) {
         data.http_method = document.getElementById('monitor-http-method').value;
         data.http_body = document.getElementById('monitor-http-body').value;
         data.resolved_host = document.getElementById('monitor-resolved-host').value;
+        data.unix_socket_path = document.getElementById('monitor-unix-socket').value.trim();
+        data.proxy_url = document.getElementById('monitor-proxy-url').value.trim();
+        data.body_must_contain = document.getElementById('monitor-body-must-contain').value;
+        data.body_must_not_contain = document.getElementById('monitor-body-must-not-contain').value;
+        data.body_regex = document.getElementById('monitor-body-regex').value;
+        data.json_path = document.getElementById('monitor-json-path').value.trim();
+        if (data.json_path) {
+            data.json_expected_value = document.getElementById('monitor-json-expected-value').value;
+            if (data.json_expected_value) {
+                data.json_operator = document.getElementById('monitor-json-operator').value;
+            }
+        }
+        data.auth_type = document.getElementById('monitor-auth-type').value;
+        if (data.auth_type === 'basic') {
+            data.auth_username = document.getElementById('monitor-auth-username').value.trim();
+            data.auth_password = document.getElementById('monitor-auth-password').value;
+        } else if (data.auth_type === 'bearer') {
+            data.auth_token = document.getElementById('monitor-auth-token').value.trim();
+        }
+        data.tls_client_cert_pem = document.getElementById('monitor-tls-client-cert').value.trim();
+        data.tls_client_key_pem = document.getElementById('monitor-tls-client-key').value.trim();
+        data.tls_ca_cert_pem = document.getElementById('monitor-tls-ca-cert').value.trim();
+        data.tls_skip_verify = document.getElementById('monitor-tls-skip-verify').checked;
+        data.tls_pin_sha256 = document.getElementById('monitor-tls-pin-sha256').value.trim();
         data.http_headers = collectHeaders();
+        const expectedHeaders = document.getElementById('monitor-expected-headers').value.trim();
+        if (expectedHeaders) {
+            try {
+                data.expected_headers = JSON.parse(expectedHeaders);
+            } catch (e) {
+                showToast('响应头断言不是有效的 JSON', 'error');
+                return;
+            }
+        }
+        const steps = document.getElementById('monitor-steps').value.trim();
+        if (steps) {
+            try {
+                data.steps = JSON.parse(steps);
+            } catch (e) {
+                showToast('多步事务不是有效的 JSON', 'error');
+                return;
+            }
+        }
+        data.track_content_hash = document.getElementById('monitor-track-content-hash').checked;
+        if (data.track_content_hash) {
+            data.content_hash_regex = document.getElementById('monitor-content-hash-regex').value;
+            data.content_hash_normalize = document.getElementById('monitor-content-hash-normalize').checked;
+        }
 
         // SSL/TLS specific fields (only for HTTPS)
         if (type === 'https') {
@@ -659,12 +943,34 @@ async function submitMonitor(event) {
         }
     }
 
+    // Comparison mode
+    if (type === 'http' || type === 'https' || type === 'tcp') {
+        data.secondary_address = document.getElementById('monitor-secondary-address').value.trim();
+        if (data.secondary_address) {
+            data.compare_latency_tolerance = parseInt(document.getElementById('monitor-compare-latency-tolerance').value) || 0;
+            data.compare_body = type !== 'tcp' && document.getElementById('monitor-compare-body').checked;
+        }
+    }
+
+    // Response time thresholds
+    if (['http', 'https', 'tcp', 'dns'].includes(type)) {
+        data.degraded_threshold_ms = parseInt(document.getElementById('monitor-degraded-threshold-ms').value) || 0;
+        data.down_threshold_ms = parseInt(document.getElementById('monitor-down-threshold-ms').value) || 0;
+    }
+
     // DNS specific fields
     if (type === 'dns' || type === 'http' || type === 'https') {
         data.dns_server = document.getElementById('monitor-dns-server').value;
         data.dns_server_name = document.getElementById('monitor-dns-server-name').value;
         data.dns_server_type = document.getElementById('monitor-dns-server-type').value;
     }
+    if (type === 'dns') {
+        data.dns_servers = document.getElementById('monitor-dns-servers').value.trim();
+        data.dns_consensus = document.getElementById('monitor-dns-consensus').value;
+        data.dns_query_type = document.getElementById('monitor-dns-query-type').value;
+        data.dns_expected_values = document.getElementById('monitor-dns-expected-values').value.split('\n').map(v => v.trim()).filter(Boolean);
+        data.dns_match_mode = document.getElementById('monitor-dns-match-mode').value;
+    }
 
     // SNMP specific fields
     if (type === 'snmp') {
@@ -675,6 +981,24 @@ async function submitMonitor(event) {
         data.snmp_expected_value = document.getElementById('monitor-snmp-expected-value').value;
     }
 
+    // TCP specific fields
+    if (type === 'tcp') {
+        data.tcp_use_tls = document.getElementById('monitor-tcp-use-tls').checked;
+        data.tcp_send_string = document.getElementById('monitor-tcp-send-string').value.replace(/\\r/g, '\r').replace(/\\n/g, '\n');
+        data.tcp_expect_string = document.getElementById('monitor-tcp-expect-string').value;
+    }
+
+    // UDP specific fields
+    if (type === 'udp') {
+        data.udp_preset = document.getElementById('monitor-udp-preset').value;
+        if (!data.udp_preset) {
+            data.udp_send_payload = document.getElementById('monitor-udp-send-payload').value.trim();
+            data.udp_payload_format = document.getElementById('monitor-udp-payload-format').value;
+        }
+        data.udp_expect_response = document.getElementById('monitor-udp-expect-response').checked;
+        data.udp_expect_pattern = document.getElementById('monitor-udp-expect-pattern').value;
+    }
+
     // Alert channels
     const alertChannelSelect = document.getElementById('monitor-alert-channels');
     const selectedChannels = Array.from(alertChannelSelect.selectedOptions).map(option => parseInt(option.value));
@@ -684,10 +1008,13 @@ async function submitMonitor(event) {
         const endpoint = id ? '/monitor/update' : '/monitor/add';
         const body = id ? { ...data, id: parseInt(id) } : data;
 
-        await API.post(endpoint, body);
+        const result = await API.post(endpoint, body);
 
         // Show success message with longer duration for create/update actions
-        const message = id ? '监控已更新' : '监控已创建成功';
+        let message = id ? '监控已更新' : '监控已创建成功';
+        if (result && result.warnings && result.warnings.length) {
+            message += `（注意: ${result.warnings.join('; ')}）`;
+        }
         const toast = document.getElementById('toast');
         toast.textContent = message;
         toast.className = 'toast success active';
@@ -700,7 +1027,7 @@ async function submitMonitor(event) {
         loadStatuses();
     } catch (error) {
         console.error('Failed to submit monitor:', error);
-        showToast('保存监控失败', 'error');
+        showToast(`保存监控失败: ${error.message}`, 'error');
     }
 }
 
@@ -755,28 +1082,33 @@ async function searchLogs() {
         target_id: targetId ? parseInt(targetId) : null,
         status: status,
         query_text: queryText,
-        size: size,
-        from: 0
+        size: size
     };
 
     currentLogPage = 0;
+    logPageCursors = [''];
     await loadLogs();
 }
 
-// Load logs
+// Load logs. Pages follow the cursor of the previous page, so logs written
+// meanwhile don't shift entries between pages.
 async function loadLogs(direction) {
     if (direction === 'prev' && currentLogPage > 0) {
-        currentLogQuery.from -= currentLogQuery.size;
         currentLogPage--;
     } else if (direction === 'next') {
-        currentLogQuery.from += currentLogQuery.size;
+        if (!nextLogCursor) {
+            return;
+        }
         currentLogPage++;
+        logPageCursors[currentLogPage] = nextLogCursor;
     }
+    currentLogQuery.after = logPageCursors[currentLogPage] || undefined;
 
     try {
         const data = await API.post('/logs/search', currentLogQuery);
 
         totalLogs = data.total || 0;
+        nextLogCursor = data.next_after || '';
         renderLogs(data.hits || []);
         updateLogPagination();
     } catch (error) {
@@ -833,6 +1165,7 @@ function renderLogs(hits) {
                 <div style="margin: 10px 0; font-size: 14px;">
                     ${hit.message}
                 </div>
+                ${renderTimings(hit.timings)}
                 <div class="log-entry-details">
                     <pre class="log-json">${JSON.stringify(hit, null, 2)}</pre>
                 </div>
@@ -841,6 +1174,34 @@ function renderLogs(hits) {
     }).join('');
 }
 
+// Render the phases of an HTTP check as a stacked bar; a reused connection has no dns/connect/tls phase
+function renderTimings(timings) {
+    if (!timings) {
+        return '';
+    }
+    const phases = [
+        ['dns_ms', 'DNS', '#a78bfa'],
+        ['connect_ms', '连接', '#60a5fa'],
+        ['tls_ms', 'TLS', '#34d399'],
+        ['ttfb_ms', '等待响应', '#fbbf24'],
+        ['transfer_ms', '传输', '#f87171']
+    ].filter(([key]) => timings[key] !== undefined);
+    const total = phases.reduce((sum, [key]) => sum + timings[key], 0);
+    if (total <= 0) {
+        return '';
+    }
+    const bar = phases.map(([key, label, color]) =>
+        `<div title="${label} ${timings[key]}ms" style="width: ${timings[key] / total * 100}%; background: ${color};"></div>`
+    ).join('');
+    const legend = phases.map(([key, label]) => `${label} ${timings[key]}ms`).join(' · ');
+    return `
+        <div style="margin: 0 0 10px 0; font-size: 12px; color: #6b7280;">
+            <div style="display: flex; height: 8px; border-radius: 4px; overflow: hidden; margin-bottom: 4px;">${bar}</div>
+            ${legend}${timings.reused_connection ? ' · 复用连接' : ''}
+        </div>
+    `;
+}
+
 // Toggle log entry
 function toggleLogEntry(element) {
     element.classList.toggle('expanded');
@@ -857,7 +1218,7 @@ function updateLogPagination() {
     }
 
     pagination.style.display = 'block';
-    const start = currentLogQuery.from + 1;
+    const start = currentLogPage * currentLogQuery.size + 1;
     const end = Math.min(start + currentLogQuery.size - 1, totalLogs);
     pageInfo.textContent = `显示 ${start}-${end} / 共 ${totalLogs} 条`;
 }
@@ -873,7 +1234,83 @@ function closeLogModal() {
     ModalManager.hide('log-modal');
 }
 
+// Render where check results of a monitor are written: its selection intersected with the globally enabled sinks
+function renderSinksSection(sinks) {
+    if (sinks.length === 0) {
+        return '';
+    }
+    const names = { db_history: '历史记录', es: 'Elasticsearch', file: '文件日志' };
+    const items = sinks.map(sink => {
+        let state;
+        if (sink.active) {
+            state = '<span style="color: var(--color-success-600);">写入</span>';
+        } else if (!sink.selected) {
+            state = '<span style="color: var(--color-gray-500);">未选择</span>';
+        } else {
+            state = '<span style="color: var(--color-warning-600);">已选择，但全局未启用</span>';
+        }
+        return `<p><strong>${escapeHtml(names[sink.name] || sink.name)}:</strong> ${state}</p>`;
+    }).join('');
+
+    return `
+        <div class="form-section">
+            <h3><i class="fas fa-database"></i> 写入目的地</h3>
+            ${items}
+        </div>
+    `;
+}
+
 // View monitor details
+// Render the alert coverage of a monitor: which rules and channels would notify if it failed now
+function renderAlertingSection(rules) {
+    if (rules.length === 0) {
+        return `
+            <div class="form-section">
+                <h3><i class="fas fa-bell"></i> 告警覆盖</h3>
+                <p style="color: var(--color-danger-600);">没有告警规则，目标故障时不会发送通知</p>
+            </div>
+        `;
+    }
+
+    const rows = rules.map(rule => {
+        const channels = rule.channels.map(ch => ch.missing
+            ? `<span style="color: var(--color-danger-600);">#${ch.id}（已删除）</span>`
+            : `${escapeHtml(ch.name)} (${escapeHtml(ch.type)})${ch.enabled ? '' : ' <span style="color: var(--color-gray-500);">已禁用</span>'}`
+        ).join('<br>');
+        const delivery = rule.last_delivery
+            ? `${new Date(rule.last_delivery.sent_at).toLocaleString('zh-CN')} ${{ sent: '送达', snoozed: '已静默', skipped: '已跳过' }[rule.last_delivery.status] || '<span style="color: var(--color-danger-600);">失败</span>'}${rule.last_delivery.synthetic ? '（演练）' : ''}`
+            : '从未发送';
+        const state = rule.would_notify
+            ? '<span style="color: var(--color-success-600);">会通知</span>'
+            : `<span style="color: var(--color-danger-600);">不会通知</span>: ${rule.blockers.map(escapeHtml).join('，')}`;
+        const next = rule.next_eligible_at ? `<br>冷却至 ${new Date(rule.next_eligible_at).toLocaleString('zh-CN')}` : '';
+        const snooze = rule.snooze
+            ? `<br>静默至 ${new Date(rule.snooze.expires_at).toLocaleString('zh-CN')}${rule.snooze.note ? `（${escapeHtml(rule.snooze.note)}）` : ''}`
+            : '';
+        return `
+            <tr>
+                <td>#${rule.rule_id}</td>
+                <td>${escapeHtml(rule.summary)}</td>
+                <td>${channels}</td>
+                <td>${delivery}</td>
+                <td>${state}${next}${snooze}</td>
+            </tr>
+        `;
+    }).join('');
+
+    return `
+        <div class="form-section">
+            <h3><i class="fas fa-bell"></i> 告警覆盖</h3>
+            <table class="table">
+                <thead>
+                    <tr><th>规则</th><th>触发条件</th><th>渠道</th><th>最近一次告警</th><th>当前</th></tr>
+                </thead>
+                <tbody>${rows}</tbody>
+            </table>
+        </div>
+    `;
+}
+
 async function viewMonitorDetails(id) {
     try {
         // Load monitor info
@@ -915,6 +1352,20 @@ async function viewMonitorDetails(id) {
                 </div>
         `;
 
+        const runbookUrl = safeUrl(monitor.runbook_url);
+        if (runbookUrl || monitor.notes) {
+            html += `
+                <div class="form-section">
+                    <h3><i class="fas fa-book"></i> 处理手册</h3>
+                    ${runbookUrl ? `<p><strong>手册链接:</strong> <a href="${escapeHtml(runbookUrl)}" target="_blank" rel="noopener noreferrer">${escapeHtml(runbookUrl)}</a></p>` : ''}
+                    ${monitor.notes ? `<div>${renderMarkdown(monitor.notes)}</div>` : ''}
+                </div>
+            `;
+        }
+
+        html += renderAlertingSection(monitor.alerting || []);
+        html += renderSinksSection(monitor.effective_sinks || []);
+
         if (status) {
             const statusBadge = getStatusBadge(status.status);
 
@@ -956,7 +1407,7 @@ async function viewMonitorDetails(id) {
                         <p><strong>状态:</strong> ${statusBadge}</p>
                         <p><strong>响应时间:</strong> ${status.response_time}ms</p>
                         <p><strong>检查时间:</strong> ${new Date(status.checked_at).toLocaleString('zh-CN')}</p>
-                        <p><strong>正常运行时间:</strong> ${status.uptime_percentage}%</p>
+                        <p><strong>正常运行时间:</strong> 24小时 ${status.uptime_24h}% / 7天 ${status.uptime_7d}% / 30天 ${status.uptime_30d}%</p>
                     </div>
                     ${monitor.type !== 'https' ? `<p style="margin-top: var(--spacing-3);"><strong>消息:</strong> ${status.message}</p>` : ''}
                 </div>
@@ -1063,6 +1514,9 @@ async function viewMonitorDetails(id) {
                     <div style="display: grid; grid-template-columns: repeat(2, 1fr); gap: var(--spacing-3);">
                         <p><strong>方法:</strong> ${monitor.http_method || 'GET'}</p>
                         ${monitor.resolved_host ? `<p><strong>自定义Host:</strong> ${monitor.resolved_host}</p>` : ''}
+                        ${monitor.unix_socket_path ? `<p><strong>Unix Socket:</strong> ${escapeHtml(monitor.unix_socket_path)}</p>` : ''}
+                        ${monitor.proxy_url ? `<p><strong>代理:</strong> ${escapeHtml(monitor.proxy_url)}</p>` : ''}
+                        ${monitor.secondary_address ? `<p><strong>对比地址:</strong> ${escapeHtml(monitor.secondary_address)}</p>` : ''}
                         ${monitor.dns_server_name ? `<p><strong>DNS供应商:</strong> ${monitor.dns_server_name} (${monitor.dns_server})</p>` : ''}
                     </div>
                     ${monitor.http_headers ? `<p style="margin-top: var(--spacing-2);"><strong>请求头:</strong> <code style="font-size: 12px;">${Object.keys(JSON.parse(monitor.http_headers || '{}')).join(', ')}</code></p>` : ''}
@@ -1282,7 +1736,7 @@ function showCheckLogDetail(log) {
                 <h3>响应详情</h3>
                 <div style="display: grid; grid-template-columns: repeat(2, 1fr); gap: var(--spacing-3);">
                     <p><strong>状态码:</strong> ${log.response.status_code !== undefined ? log.response.status_code : '-'}</p>
-                    <p><strong>大小:</strong> ${log.response.body_size || log.response.size || '-'} bytes</p>
+                    <p><strong>大小:</strong> ${formatBodySizes(log.response)}</p>
                 </div>
         `;
 
@@ -1777,7 +2231,8 @@ function renderAlertRules() {
     tbody.innerHTML = alertRules.map(rule => {
         const thresholdTypeLabels = {
             'failure_count': '故障次数',
-            'response_time': '响应时间'
+            'response_time': '响应时间',
+            'jitter': '抖动'
         };
 
         return `
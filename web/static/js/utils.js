@@ -263,6 +263,55 @@ function renderEmptyState(tbody, colSpan, message, icon = 'fa-inbox') {
     `;
 }
 
+// 转义 HTML 特殊字符
+function escapeHtml(text) {
+    return String(text ?? '')
+        .replace(/&/g, '&amp;')
+        .replace(/</g, '&lt;')
+        .replace(/>/g, '&gt;')
+        .replace(/"/g, '&quot;')
+        .replace(/'/g, '&#39;');
+}
+
+// 只允许 http/https 链接，其余返回空字符串
+function safeUrl(url) {
+    try {
+        const parsed = new URL(url);
+        return parsed.protocol === 'http:' || parsed.protocol === 'https:' ? parsed.href : '';
+    } catch (e) {
+        return '';
+    }
+}
+
+// 渲染运维备注：先整体转义，再支持行内代码、粗体、链接和列表，不会产生任意 HTML
+function renderMarkdown(text) {
+    if (!text) return '';
+
+    const inline = line => escapeHtml(line)
+        .replace(/`([^`]+)`/g, '<code>$1</code>')
+        .replace(/\*\*([^*]+)\*\*/g, '<strong>$1</strong>')
+        .replace(/\[([^\]]+)\]\(([^)\s]+)\)/g, (match, label, url) => {
+            const href = safeUrl(url.replace(/&amp;/g, '&'));
+            return href ? `<a href="${escapeHtml(href)}" target="_blank" rel="noopener noreferrer">${label}</a>` : match;
+        });
+
+    let html = '';
+    let inList = false;
+    for (const line of String(text).split('\n')) {
+        const item = line.match(/^\s*[-*]\s+(.*)$/);
+        if (item) {
+            if (!inList) { html += '<ul>'; inList = true; }
+            html += `<li>${inline(item[1])}</li>`;
+            continue;
+        }
+        if (inList) { html += '</ul>'; inList = false; }
+        html += line.trim() ? `<p>${inline(line)}</p>` : '';
+    }
+    if (inList) html += '</ul>';
+
+    return html;
+}
+
 // 格式化时间
 function formatTime(timestamp) {
     if (!timestamp) return '-';